@@ -6,17 +6,59 @@ import (
 	"clawmark/types"
 	"net/http"
 
+	docs "clawmark/doclib"
+	"clawmark/i18n"
+	"clawmark/metrics"
+	"clawmark/tracing"
 	"clawmark/uapi"
+
+	"github.com/google/uuid"
+)
+
+const (
+	TargetTypeUser = "user"
 )
 
 type DefaultResponder struct{}
 
-func (d DefaultResponder) New(err string, ctx map[string]string) any {
-	return types.Response{
-		Success: false,
-		Context: ctx,
-		Message: &err,
+// Renders errors as types.ApiError, or as types.Problem if the client accepts problem details
+func (d DefaultResponder) New(e uapi.Error, req *http.Request) (any, string) {
+	if uapi.WantsProblem(req) {
+		return types.Problem{
+			Type:      "urn:clawmark:error:" + string(e.Code),
+			Title:     http.StatusText(e.Status),
+			Status:    e.Status,
+			Detail:    e.Message,
+			Instance:  req.URL.Path,
+			Code:      string(e.Code),
+			Fields:    e.Fields,
+			RequestID: uapi.RequestID(req.Context()),
+		}, uapi.ProblemContentType
 	}
+
+	return types.ApiError{
+		Success:   false,
+		Code:      string(e.Code),
+		Status:    e.Status,
+		Message:   e.Message,
+		Fields:    e.Fields,
+		RequestID: uapi.RequestID(req.Context()),
+	}, "application/json"
+}
+
+// Returns the ID of the authorized user, ok is false if the request is not authorized as a user
+func UserID(d uapi.RouteData) (uuid.UUID, bool) {
+	if !d.Auth.Authorized || d.Auth.TargetType != TargetTypeUser {
+		return uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(d.Auth.ID)
+
+	if err != nil {
+		return uuid.Nil, false
+	}
+
+	return id, true
 }
 
 // Authorizes a request
@@ -25,11 +67,19 @@ func Authorize(r uapi.Route, req *http.Request) (uapi.AuthData, uapi.HttpRespons
 }
 
 func Setup() {
+	docs.AddSecuritySchema("User", "Authorization", "User session token")
+
+	if err := i18n.Setup(state.Validator); err != nil {
+		panic("Failed to load translations: " + err.Error())
+	}
+
 	uapi.SetupState(uapi.UAPIState{
-		Logger:      state.Logger,
-		Authorize:   Authorize,
-		AuthTypeMap: map[string]string{},
-		Context:     state.Context,
+		Logger:    state.Logger,
+		Authorize: Authorize,
+		AuthTypeMap: map[string]string{
+			TargetTypeUser: "User",
+		},
+		Context: state.Context,
 		Constants: &uapi.UAPIConstants{
 			ResourceNotFound:    constants.ResourceNotFound,
 			BadRequest:          constants.BadRequest,
@@ -43,5 +93,8 @@ func Setup() {
 		BaseSanityCheck: func(r uapi.Route) error {
 			return nil
 		},
+		StartRequest:   tracing.StartRequest,
+		Translator:     i18n.Translator,
+		ObserveRequest: metrics.ObserveRequest,
 	})
 }
@@ -0,0 +1,165 @@
+// Blocking and muting users
+//
+// A block works both ways: neither user can follow, add as a close friend,
+// comment on or react to the posts of, or mention the other, and neither
+// sees the other's posts.
+// This is enforced when rows are created by the GORM plugin, and on reads
+// by filtering with the ExcludeBlocked and ExcludeHidden scopes. Muting only
+// hides the muted user's posts and notifications from the muter.
+package blocks
+
+import (
+	"context"
+	"errors"
+
+	"clawmark/state"
+	"clawmark/types"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrBlocked      = errors.New("blocked")
+	ErrSelf         = errors.New("cannot block or mute yourself")
+	ErrUserNotFound = errors.New("user not found")
+)
+
+// Registers the GORM plugin rejecting interactions between blocked users, state.Setup must be called first
+func Setup() error {
+	return state.Pool.Use(GormPlugin{})
+}
+
+// Users blocked by or blocking the user
+const blockedSQL = "SELECT blocked_id FROM blocks WHERE blocker_id = @viewer UNION SELECT blocker_id FROM blocks WHERE blocked_id = @viewer"
+
+// Users muted by the user
+const mutedSQL = "SELECT muted_id FROM mutes WHERE muter_id = @viewer"
+
+// Scope leaving out rows whose user ID column is a user blocked by or blocking the viewer
+func ExcludeBlocked(viewerID uuid.UUID, column string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(column+" NOT IN ("+blockedSQL+")", map[string]any{"viewer": viewerID})
+	}
+}
+
+// Scope leaving out rows whose user ID column is a user blocked by, blocking or muted by the viewer
+func ExcludeHidden(viewerID uuid.UUID, column string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(column+" NOT IN ("+blockedSQL+" UNION "+mutedSQL+")", map[string]any{"viewer": viewerID})
+	}
+}
+
+// Returns whether either user has blocked the other, db may be a transaction
+func IsBlocked(db *gorm.DB, a, b uuid.UUID) (bool, error) {
+	var count int64
+	err := db.Model(&types.Block{}).
+		Where("(blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)", a, b, b, a).
+		Count(&count).Error
+
+	return count > 0, err
+}
+
+func checkTarget(db *gorm.DB, userID, targetID uuid.UUID) error {
+	if userID == targetID {
+		return ErrSelf
+	}
+
+	var user types.User
+	err := db.Select("id").First(&user, "id = ?", targetID).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrUserNotFound
+	}
+
+	return err
+}
+
+// Blocks a user and removes the follows, follow requests and close friends between the two, blocking a user twice
+// is not an error
+func Block(ctx context.Context, userID, targetID uuid.UUID) error {
+	return state.Pool.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkTarget(tx, userID, targetID); err != nil {
+			return err
+		}
+
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&types.Block{BlockerID: userID, BlockedID: targetID}).Error
+
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("(follower_id = ? AND following_id = ?) OR (follower_id = ? AND following_id = ?)", userID, targetID, targetID, userID).
+			Delete(&types.Follow{}).Error
+
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("(requester_id = ? AND target_id = ?) OR (requester_id = ? AND target_id = ?)", userID, targetID, targetID, userID).
+			Delete(&types.FollowRequest{}).Error
+
+		if err != nil {
+			return err
+		}
+
+		return tx.Where("(user_id = ? AND friend_id = ?) OR (user_id = ? AND friend_id = ?)", userID, targetID, targetID, userID).
+			Delete(&types.CloseFriend{}).Error
+	})
+}
+
+// Unblocks a user, unblocking a user who is not blocked is not an error
+func Unblock(ctx context.Context, userID, targetID uuid.UUID) error {
+	return state.Pool.WithContext(ctx).Where("blocker_id = ? AND blocked_id = ?", userID, targetID).Delete(&types.Block{}).Error
+}
+
+// Mutes a user, muting a user twice is not an error
+func Mute(ctx context.Context, userID, targetID uuid.UUID) error {
+	db := state.Pool.WithContext(ctx)
+
+	if err := checkTarget(db, userID, targetID); err != nil {
+		return err
+	}
+
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&types.Mute{MuterID: userID, MutedID: targetID}).Error
+}
+
+// Unmutes a user, unmuting a user who is not muted is not an error
+func Unmute(ctx context.Context, userID, targetID uuid.UUID) error {
+	return state.Pool.WithContext(ctx).Where("muter_id = ? AND muted_id = ?", userID, targetID).Delete(&types.Mute{}).Error
+}
+
+// Returns the users blocked by the user, most recently blocked first
+func Blocked(ctx context.Context, userID uuid.UUID) ([]types.PartialUser, error) {
+	var blocks []types.Block
+	err := state.Pool.WithContext(ctx).Preload("Blocked").Where("blocker_id = ?", userID).Order("created_at DESC").Find(&blocks).Error
+
+	users := make([]types.PartialUser, 0, len(blocks))
+	for _, b := range blocks {
+		users = append(users, partialUser(b.Blocked))
+	}
+
+	return users, err
+}
+
+// Returns the users muted by the user, most recently muted first
+func Muted(ctx context.Context, userID uuid.UUID) ([]types.PartialUser, error) {
+	var mutes []types.Mute
+	err := state.Pool.WithContext(ctx).Preload("Muted").Where("muter_id = ?", userID).Order("created_at DESC").Find(&mutes).Error
+
+	users := make([]types.PartialUser, 0, len(mutes))
+	for _, m := range mutes {
+		users = append(users, partialUser(m.Muted))
+	}
+
+	return users, err
+}
+
+func partialUser(u types.User) types.PartialUser {
+	return types.PartialUser{
+		ID:        u.ID,
+		Username:  u.Username,
+		AvatarURL: u.AvatarURL,
+	}
+}
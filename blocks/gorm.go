@@ -0,0 +1,130 @@
+package blocks
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"clawmark/types"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Who acts on whom when a row of a table is created
+type guard struct {
+	// Field of the user acting
+	Actor string
+
+	// Field of the user acted on, or of the post whose author is acted on
+	Target string
+	Post   string
+}
+
+var guards = map[string]guard{
+	"follows":         {Actor: "FollowerID", Target: "FollowingID"},
+	"follow_requests": {Actor: "RequesterID", Target: "TargetID"},
+	"close_friends":   {Actor: "UserID", Target: "FriendID"},
+	"comments":        {Actor: "UserID", Post: "PostID"},
+	"likes":           {Actor: "UserID", Post: "PostID"},
+	"dislikes":        {Actor: "UserID", Post: "PostID"},
+}
+
+// GORM plugin rejecting follows, follow requests, close friends, comments and reactions between users where either
+// has blocked the other, register with db.Use. The statement fails with ErrBlocked
+//
+// Mentions are not rejected, as that would reject the post. The mentions package leaves out blocked users instead.
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string {
+	return "blocks"
+}
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	return db.Callback().Create().Before("gorm:create").Register("blocks:before_create", check)
+}
+
+func check(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return
+	}
+
+	g, ok := guards[db.Statement.Schema.Table]
+
+	if !ok {
+		return
+	}
+
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	tx := db.Session(&gorm.Session{NewDB: true})
+
+	id := func(v reflect.Value, name string) uuid.UUID {
+		field := db.Statement.Schema.LookUpField(name)
+
+		if field == nil {
+			return uuid.Nil
+		}
+
+		value, _ := field.ValueOf(ctx, v)
+		id, _ := value.(uuid.UUID)
+		return id
+	}
+
+	checkModel := func(v reflect.Value) {
+		if db.Error != nil {
+			return
+		}
+
+		actor := id(v, g.Actor)
+		var target uuid.UUID
+
+		if g.Post != "" {
+			var post types.Post
+			err := tx.Select("user_id").First(&post, "id = ?", id(v, g.Post)).Error
+
+			// A missing post fails on its foreign key instead
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return
+			}
+
+			if err != nil {
+				db.AddError(err)
+				return
+			}
+
+			target = post.UserID
+		} else {
+			target = id(v, g.Target)
+		}
+
+		if actor == target {
+			return
+		}
+
+		blocked, err := IsBlocked(tx, actor, target)
+
+		if err != nil {
+			db.AddError(err)
+			return
+		}
+
+		if blocked {
+			db.AddError(ErrBlocked)
+		}
+	}
+
+	rv := reflect.Indirect(db.Statement.ReflectValue)
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		checkModel(rv)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			checkModel(reflect.Indirect(rv.Index(i)))
+		}
+	}
+}
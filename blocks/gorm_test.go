@@ -0,0 +1,233 @@
+package blocks
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"clawmark/types"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// A database answering the queries of the guard: whether the users are blocked and who wrote a post.
+// Statements are recorded so tests can tell whether a row was inserted
+type fakeDB struct {
+	mu       sync.Mutex
+	blocked  bool
+	owner    uuid.UUID
+	inserted []string
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) { return fakeConn{f}, nil }
+func (f *fakeDB) Driver() driver.Driver                        { return nil }
+
+func (f *fakeDB) inserts() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]string(nil), f.inserted...)
+}
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c fakeConn) Close() error                        { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+
+func (c fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	if strings.HasPrefix(query, "INSERT") {
+		c.db.inserted = append(c.db.inserted, query)
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+func (c fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	switch {
+	case strings.Contains(query, `FROM "blocks"`):
+		count := int64(0)
+		if c.db.blocked {
+			count = 1
+		}
+
+		return &fakeRows{columns: []string{"count"}, values: [][]driver.Value{{count}}}, nil
+	case strings.Contains(query, `FROM "posts"`):
+		return &fakeRows{columns: []string{"user_id"}, values: [][]driver.Value{{c.db.owner.String()}}}, nil
+	case strings.HasPrefix(query, "INSERT"):
+		c.ExecContext(ctx, query, args)
+		return &fakeRows{}, nil
+	}
+
+	return nil, errors.New("unexpected query: " + query)
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func openFake(t *testing.T, f *fakeDB) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(f)}), &gorm.Config{
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Use(GormPlugin{}); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// Rows whose creation is guarded, built from the acting and targeted user. Targets of post interactions are
+// the authors of the post
+func guardedRows(actor, target, post uuid.UUID) map[string]any {
+	return map[string]any{
+		"follow":         &types.Follow{FollowerID: actor, FollowingID: target},
+		"follow request": &types.FollowRequest{RequesterID: actor, TargetID: target},
+		"close friend":   &types.CloseFriend{UserID: actor, FriendID: target},
+		"comment":        &types.Comment{UserID: actor, PostID: post, Content: "hi"},
+		"like":           &types.Like{UserID: actor, PostID: post},
+		"dislike":        &types.Dislike{UserID: actor, PostID: post},
+	}
+}
+
+func TestGuardRejectsBlocked(t *testing.T) {
+	actor, target := uuid.New(), uuid.New()
+
+	for name, row := range guardedRows(actor, target, uuid.New()) {
+		f := &fakeDB{blocked: true, owner: target}
+		err := openFake(t, f).Create(row).Error
+
+		if !errors.Is(err, ErrBlocked) {
+			t.Errorf("%s: expected ErrBlocked, got %v", name, err)
+		}
+
+		if inserts := f.inserts(); len(inserts) > 0 {
+			t.Errorf("%s: expected no insert, got %v", name, inserts)
+		}
+	}
+}
+
+func TestGuardAllowsUnblocked(t *testing.T) {
+	actor, target := uuid.New(), uuid.New()
+
+	for name, row := range guardedRows(actor, target, uuid.New()) {
+		f := &fakeDB{owner: target}
+
+		if err := openFake(t, f).Create(row).Error; err != nil {
+			t.Errorf("%s: expected no error, got %v", name, err)
+		}
+
+		if inserts := f.inserts(); len(inserts) != 1 {
+			t.Errorf("%s: expected one insert, got %v", name, inserts)
+		}
+	}
+}
+
+// Interacting with your own post is never blocked, even if the lookup would say so
+func TestGuardAllowsOwnPost(t *testing.T) {
+	user := uuid.New()
+	f := &fakeDB{blocked: true, owner: user}
+
+	if err := openFake(t, f).Create(&types.Comment{UserID: user, PostID: uuid.New(), Content: "hi"}).Error; err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+// Tables without a guard are created without looking up blocks
+func TestGuardIgnoresOtherTables(t *testing.T) {
+	f := &fakeDB{blocked: true}
+
+	if err := openFake(t, f).Create(&types.Mention{UserID: uuid.New(), PostID: uuid.New()}).Error; err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestExcludeScopes(t *testing.T) {
+	db := openFake(t, &fakeDB{}).Session(&gorm.Session{DryRun: true})
+	viewer := uuid.New()
+
+	tests := []struct {
+		name  string
+		scope func(*gorm.DB) *gorm.DB
+		want  []string
+		not   []string
+	}{
+		{
+			name:  "ExcludeBlocked",
+			scope: ExcludeBlocked(viewer, "posts.user_id"),
+			want: []string{
+				"posts.user_id NOT IN (SELECT blocked_id FROM blocks WHERE blocker_id = $1 UNION SELECT blocker_id FROM blocks WHERE blocked_id = $2)",
+			},
+			not: []string{"mutes"},
+		},
+		{
+			name:  "ExcludeHidden",
+			scope: ExcludeHidden(viewer, "posts.user_id"),
+			want: []string{
+				"posts.user_id NOT IN (SELECT blocked_id FROM blocks WHERE blocker_id = $1 UNION SELECT blocker_id FROM blocks WHERE blocked_id = $2 UNION SELECT muted_id FROM mutes WHERE muter_id = $3)",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		stmt := db.Scopes(tt.scope).Find(&[]types.Post{}).Statement
+		sql := stmt.SQL.String()
+
+		for _, want := range tt.want {
+			if !strings.Contains(sql, want) {
+				t.Errorf("%s: expected %q in %q", tt.name, want, sql)
+			}
+		}
+
+		for _, not := range tt.not {
+			if strings.Contains(sql, not) {
+				t.Errorf("%s: did not expect %q in %q", tt.name, not, sql)
+			}
+		}
+
+		// Every placeholder is the viewer
+		for i, v := range stmt.Vars {
+			if v != viewer {
+				t.Errorf("%s: expected var %d to be the viewer, got %v", tt.name, i, v)
+			}
+		}
+	}
+}
@@ -1,16 +1,132 @@
 package config
 
+import "time"
+
+// Used to generate config.yaml.sample, maps need an entry to show up in the sample
+var Sample = Config{
+	Docs: Docs{
+		Servers: map[string]DocsServer{
+			"production": {},
+		},
+	},
+}
+
+// Fields tagged reload:"true" are swapped in on a config reload, all other fields need a restart. Fields tagged
+// secret:"true" are never logged
 type Config struct {
-	Server   Server   `yaml:"server" validate:"required"`
-	Database Database `yaml:"storage" validate:"required"`
+	Server    Server    `yaml:"server" validate:"required"`
+	Database  Database  `yaml:"storage" validate:"required"`
+	Docs      Docs      `yaml:"docs" validate:"required"`
+	Ratelimit Ratelimit `yaml:"ratelimit" validate:"required"`
+	Feed      Feed      `yaml:"feed" validate:"required"`
+	Tracing   Tracing   `yaml:"tracing" validate:"required"`
+	Media     Media     `yaml:"media" validate:"required"`
+	Previews  Previews  `yaml:"previews" validate:"required"`
 }
 
 type Server struct {
-	Port string `yaml:"port" comment:"Server Port" validate:"required"`
-	Env  string `yaml:"env" comment:"Server Environment" validate:"required"`
+	Port            string        `yaml:"port" comment:"Server Port" validate:"required"`
+	Env             string        `yaml:"env" comment:"Server Environment" validate:"required"`
+	LogLevel        string        `yaml:"log_level" default:"debug" comment:"Log level, one of debug, info, warn or error" validate:"oneof=debug info warn error" reload:"true"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" default:"30s" comment:"Time allowed to drain requests and connections on shutdown or upgrade" validate:"required"`
+	CorsOrigins     []string      `yaml:"cors_origins" comment:"Allowed CORS origins, leave empty to allow any origin" required:"false" reload:"true"`
+}
+
+type Ratelimit struct {
+	Interval time.Duration `yaml:"interval" default:"1s" comment:"Interval at which a request token is regained" validate:"required" reload:"true"`
+	Burst    int           `yaml:"burst" default:"3" comment:"Maximum number of requests in a burst" validate:"required,min=1" reload:"true"`
+	Paths    []string      `yaml:"paths" comment:"Paths to ratelimit" required:"false" reload:"true"`
+}
+
+// Weights used when building a users feed
+type Feed struct {
+	PersonalizedPercent int `yaml:"personalized_percent" default:"50" comment:"Percentage of the feed made up of personalized posts, the rest is random" validate:"min=0,max=100" reload:"true"`
+	InteractionBoost    int `yaml:"interaction_boost" default:"5" comment:"Score boost for posts the user has interacted with" validate:"min=0" reload:"true"`
+	TagWeight           int `yaml:"tag_weight" default:"1" comment:"Multiplier applied to the users tag scores" validate:"min=0" reload:"true"`
+	FollowedTagWeight   int `yaml:"followed_tag_weight" default:"3" comment:"Score added for each tag of a post the user follows" validate:"min=0" reload:"true"`
+}
+
+// OpenTelemetry trace export, incoming W3C traceparent headers are always honoured
+type Tracing struct {
+	Exporter    string   `yaml:"exporter" default:"none" comment:"Trace exporter, one of none, stdout (for local testing) or otlp" validate:"oneof=none stdout otlp"`
+	Endpoint    string   `yaml:"endpoint" comment:"OTLP/HTTP collector endpoint as host:port" validate:"required_if=Exporter otlp"`
+	Insecure    bool     `yaml:"insecure" comment:"Export to the collector over plain HTTP" required:"false"`
+	Headers     []string `yaml:"headers" comment:"Headers sent to the collector as name=value" required:"false" secret:"true"`
+	ServiceName string   `yaml:"service_name" default:"clawmark" comment:"Service name reported on spans" validate:"required"`
+	// Use exporter none to disable tracing, 0 is rejected
+	SamplePercent int `yaml:"sample_percent" default:"100" comment:"Percentage of new traces to sample, traces continued from a traceparent follow the callers decision" validate:"min=1,max=100"`
+}
+
+// Uploaded media, see the media package
+type Media struct {
+	Backend      string        `yaml:"backend" default:"local" comment:"Storage backend, either local or s3" validate:"oneof=local s3"`
+	MaxSize      int64         `yaml:"max_size" default:"10485760" comment:"Maximum upload size in bytes" validate:"min=1" reload:"true"`
+	AllowedTypes []string      `yaml:"allowed_types" default:"image/png,image/jpeg,image/gif,image/webp" comment:"MIME types which may be uploaded, detected from the content rather than trusted from the client" validate:"required,min=1" reload:"true"`
+	StagingDir   string        `yaml:"staging_dir" default:"data/uploads" comment:"Directory holding incomplete resumable uploads" validate:"required"`
+	UploadExpiry time.Duration `yaml:"upload_expiry" default:"24h" comment:"Time after which incomplete resumable uploads are discarded" validate:"required"`
+	Local        MediaLocal    `yaml:"local"`
+	S3           MediaS3       `yaml:"s3"`
+}
+
+type MediaLocal struct {
+	Dir     string `yaml:"dir" default:"data/media" comment:"Directory media is stored in" validate:"required"`
+	BaseURL string `yaml:"base_url" comment:"Public URL media is served from, defaults to /media/files/ on this server. Another server there cannot check the signatures of media URLs, so media of non-public posts becomes readable by anyone with a URL" required:"false"`
+	// Shared by all instances, so URLs signed by one are accepted by the others
+	SigningKey string `yaml:"signing_key" comment:"Secret signing the expiring URLs of media, a random key is used if unset which invalidates URLs on restart and between instances" required:"false" secret:"true"`
+}
+
+// Any S3-compatible service, e.g. AWS S3, MinIO or R2
+type MediaS3 struct {
+	Endpoint  string `yaml:"endpoint" comment:"S3 endpoint as host[:port], e.g. s3.amazonaws.com" required:"false"`
+	Region    string `yaml:"region" comment:"Bucket region" required:"false"`
+	Bucket    string `yaml:"bucket" comment:"Bucket media is stored in" required:"false"`
+	AccessKey string `yaml:"access_key" comment:"Access key ID" required:"false" secret:"true"`
+	SecretKey string `yaml:"secret_key" comment:"Secret access key" required:"false" secret:"true"`
+	Insecure  bool   `yaml:"insecure" comment:"Connect to the endpoint over plain HTTP" required:"false"`
+	PublicURL string `yaml:"public_url" comment:"Public URL of the bucket (or a CDN in front of it) URLs of public media are built from. Media of non-public posts is served with presigned URLs, which only keeps it private if the bucket is not publicly readable" required:"false"`
+}
+
+// Link previews of URLs in posts, see the previews package
+type Previews struct {
+	Disabled   bool          `yaml:"disabled" comment:"Stop unfurling links in new posts" required:"false" reload:"true"`
+	Timeout    time.Duration `yaml:"timeout" default:"5s" comment:"Time allowed for fetching a page, including redirects" validate:"required" reload:"true"`
+	MaxBytes   int64         `yaml:"max_bytes" default:"1048576" comment:"Maximum number of bytes read from a page, anything after is ignored" validate:"min=1" reload:"true"`
+	MaxPerPost int           `yaml:"max_per_post" default:"3" comment:"Maximum number of links unfurled per post" validate:"min=1" reload:"true"`
+	CacheTTL   time.Duration `yaml:"cache_ttl" default:"24h" comment:"Time previews are cached for, failures are cached for a tenth of this" validate:"required" reload:"true"`
+	UserAgent  string        `yaml:"user_agent" default:"ClawmarkBot/1.0 (+link previews)" comment:"User agent sent when fetching pages" validate:"required" reload:"true"`
 }
 
 type Database struct {
-	DatabaseURL string `yaml:"database_url" comment:"Database URL" validate:"required"`
-	RedisURL    string `yaml:"redis_url" comment:"Redis URL" validate:"required"`
+	DatabaseURL string `yaml:"database_url" comment:"Database URL" validate:"required" secret:"true"`
+	RedisURL    string `yaml:"redis_url" comment:"Redis URL" validate:"required" secret:"true"`
+	// Migrations can then be run with the migrate command
+	SkipMigrations bool `yaml:"skip_migrations" comment:"Do not run pending migrations on startup" required:"false"`
+}
+
+type Docs struct {
+	Renderer       string                `yaml:"renderer" default:"redoc" comment:"Docs renderer, either redoc or swagger (Swagger UI with try it out)" validate:"omitempty,oneof=redoc swagger"`
+	Logo           string                `yaml:"logo" comment:"Logo URL shown as the docs favicon" required:"false"`
+	Title          string                `yaml:"title" default:"Luvix Social by Purrquinox" comment:"API Title" validate:"required"`
+	Description    string                `yaml:"description" default:"Redefining Connection in a Seamless, Privacy-Focused World." comment:"API Description"`
+	Version        string                `yaml:"version" default:"1.0" comment:"API Version" validate:"required"`
+	TermsOfService string                `yaml:"terms_of_service" default:"https://luvix.social/legal/terms" comment:"Terms of Service URL" required:"false"`
+	Contact        DocsContact           `yaml:"contact"`
+	License        DocsLicense           `yaml:"license"`
+	Servers        map[string]DocsServer `yaml:"servers" comment:"API servers keyed by environment, the one matching server.env is listed first. Servers can also be added with CLAWMARK_DOCS_SERVERS_<NAME>_URL" validate:"required,min=1,dive"`
+}
+
+type DocsContact struct {
+	Name  string `yaml:"name" default:"Purrquinox" comment:"Contact Name" required:"false"`
+	URL   string `yaml:"url" default:"https://luvix.social/" comment:"Contact URL" required:"false"`
+	Email string `yaml:"email" default:"support@purrquinox.com" comment:"Contact Email" required:"false"`
+}
+
+type DocsLicense struct {
+	Name string `yaml:"name" default:"AGPL-3.0" comment:"License Name" required:"false"`
+	URL  string `yaml:"url" default:"https://opensource.org/licenses/AGPL-3.0" comment:"License URL" required:"false"`
+}
+
+type DocsServer struct {
+	URL         string `yaml:"url" default:"https://api.luvix.social/" comment:"Public URL of the API" validate:"required,httporhttps"`
+	Description string `yaml:"description" default:"Luvix Social API" comment:"Server Description"`
 }
@@ -0,0 +1,363 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Prefix of environment variable overrides, e.g. CLAWMARK_STORAGE_DATABASE_URL
+const EnvPrefix = "CLAWMARK_"
+
+// Suffix of environment variables pointing to a file containing the value, e.g. CLAWMARK_STORAGE_DATABASE_URL_FILE=/run/secrets/db
+const EnvFileSuffix = "_FILE"
+
+// Default location of the config file
+const DefaultPath = "config.yaml"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Loads the config from (in order of precedence) environment variables, the YAML file at path and default tags
+//
+// If path is the default path and the file does not exist, the config is loaded from the environment only.
+// Validation is left to the caller.
+func Load(path string) (*Config, error) {
+	var cfg Config
+
+	// Defaults go in first so values set to zero in the file or environment are kept
+	err := applyDefaults(reflect.ValueOf(&cfg).Elem())
+
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+
+	switch {
+	case err == nil:
+		err = yaml.Unmarshal(data, &cfg)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse config file: %w", err)
+		}
+	case errors.Is(err, fs.ErrNotExist) && path == DefaultPath:
+		// Environment only
+	default:
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	err = applyEnv(reflect.ValueOf(&cfg).Elem(), EnvPrefix)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = applyMapDefaults(reflect.ValueOf(&cfg).Elem())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// Returns the environment variable name for a yaml key
+func envName(prefix, key string) string {
+	return prefix + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(key))
+}
+
+// Looks up an environment variable, falling back to reading the file named by its _FILE variant
+func lookupEnv(name string) (string, bool, error) {
+	if v, ok := os.LookupEnv(name); ok {
+		return v, true, nil
+	}
+
+	file, ok := os.LookupEnv(name + EnvFileSuffix)
+
+	if !ok {
+		return "", false, nil
+	}
+
+	data, err := os.ReadFile(file)
+
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read %s: %w", name+EnvFileSuffix, err)
+	}
+
+	return strings.TrimRight(string(data), "\r\n"), true, nil
+}
+
+func yamlKey(f reflect.StructField) string {
+	key := strings.Split(f.Tag.Get("yaml"), ",")[0]
+
+	if key == "" {
+		key = strings.ToLower(f.Name)
+	}
+
+	return key
+}
+
+func applyEnv(v reflect.Value, prefix string) error {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		if !f.IsExported() || f.Tag.Get("yaml") == "-" {
+			continue
+		}
+
+		name := envName(prefix, yamlKey(f))
+		fv := v.Field(i)
+
+		switch {
+		case fv.Kind() == reflect.Struct:
+			if err := applyEnv(fv, name+"_"); err != nil {
+				return err
+			}
+		case fv.Kind() == reflect.Map && fv.Type().Elem().Kind() == reflect.Struct:
+			// Entries only present in the environment are created, e.g. CLAWMARK_DOCS_SERVERS_STAGING_URL adds "staging"
+			for _, key := range envMapKeys(fv, name+"_") {
+				if fv.IsNil() {
+					fv.Set(reflect.MakeMap(fv.Type()))
+				}
+
+				fv.SetMapIndex(reflect.ValueOf(key).Convert(fv.Type().Key()), reflect.New(fv.Type().Elem()).Elem())
+			}
+
+			for _, key := range fv.MapKeys() {
+				elem := reflect.New(fv.Type().Elem()).Elem()
+				elem.Set(fv.MapIndex(key))
+
+				if err := applyEnv(elem, envName(name+"_", key.String())+"_"); err != nil {
+					return err
+				}
+
+				fv.SetMapIndex(key, elem)
+			}
+		default:
+			val, ok, err := lookupEnv(name)
+
+			if err != nil {
+				return err
+			}
+
+			if !ok {
+				continue
+			}
+
+			if err := setValue(fv, val); err != nil {
+				return fmt.Errorf("invalid value for %s: %w", name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Returns the environment variable names of the fields of a struct type, relative to the struct
+func envFields(t reflect.Type, prefix string) []string {
+	var names []string
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		if !f.IsExported() || f.Tag.Get("yaml") == "-" {
+			continue
+		}
+
+		name := envName(prefix, yamlKey(f))
+
+		if f.Type.Kind() == reflect.Struct && f.Type != durationType {
+			names = append(names, envFields(f.Type, name+"_")...)
+		} else {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// Returns the lowercased keys of the map entries set by environment variables starting with prefix which are
+// not in the map yet. The map must hold structs, whose fields tell where a key ends
+func envMapKeys(m reflect.Value, prefix string) []string {
+	existing := map[string]bool{}
+	for _, key := range m.MapKeys() {
+		existing[envName("", key.String())] = true
+	}
+
+	fields := envFields(m.Type().Elem(), "")
+
+	var keys []string
+	for _, env := range os.Environ() {
+		name, _, _ := strings.Cut(env, "=")
+		name = strings.TrimSuffix(name, EnvFileSuffix)
+
+		rest, ok := strings.CutPrefix(name, prefix)
+
+		if !ok {
+			continue
+		}
+
+		for _, field := range fields {
+			key, ok := strings.CutSuffix(rest, "_"+field)
+
+			if !ok || key == "" || existing[key] {
+				continue
+			}
+
+			existing[key] = true
+			keys = append(keys, strings.ToLower(key))
+		}
+	}
+
+	return keys
+}
+
+// Sets the fields without a value to their default tag, maps are left to applyMapDefaults
+func applyDefaults(v reflect.Value) error {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fv := v.Field(i)
+
+		if !f.IsExported() {
+			continue
+		}
+
+		switch fv.Kind() {
+		case reflect.Struct:
+			if err := applyDefaults(fv); err != nil {
+				return err
+			}
+		case reflect.Map:
+			continue
+		default:
+			def, ok := f.Tag.Lookup("default")
+
+			if !ok || !fv.IsZero() {
+				continue
+			}
+
+			if err := setValue(fv, def); err != nil {
+				return fmt.Errorf("invalid default for %s: %w", f.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Applies the defaults of the structs in maps, whose entries only exist once the file and environment are read.
+// Unlike other fields, their zero values cannot be told apart from unset ones
+func applyMapDefaults(v reflect.Value) error {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fv := v.Field(i)
+
+		if !f.IsExported() {
+			continue
+		}
+
+		switch {
+		case fv.Kind() == reflect.Struct:
+			if err := applyMapDefaults(fv); err != nil {
+				return err
+			}
+		case fv.Kind() == reflect.Map && fv.Type().Elem().Kind() == reflect.Struct:
+			for _, key := range fv.MapKeys() {
+				elem := reflect.New(fv.Type().Elem()).Elem()
+				elem.Set(fv.MapIndex(key))
+
+				if err := applyDefaults(elem); err != nil {
+					return err
+				}
+
+				if err := applyMapDefaults(elem); err != nil {
+					return err
+				}
+
+				fv.SetMapIndex(key, elem)
+			}
+		}
+	}
+
+	return nil
+}
+
+func setValue(fv reflect.Value, val string) error {
+	if fv.Type() == durationType {
+		d, err := time.ParseDuration(val)
+
+		if err != nil {
+			return err
+		}
+
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(val)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+
+		if err != nil {
+			return err
+		}
+
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(val, 10, fv.Type().Bits())
+
+		if err != nil {
+			return err
+		}
+
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(val, 10, fv.Type().Bits())
+
+		if err != nil {
+			return err
+		}
+
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(val, fv.Type().Bits())
+
+		if err != nil {
+			return err
+		}
+
+		fv.SetFloat(n)
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported slice type %s", fv.Type())
+		}
+
+		var items []string
+		for _, item := range strings.Split(val, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+
+		fv.Set(reflect.ValueOf(items).Convert(fv.Type()))
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"reflect"
+)
+
+// A setting that differs between two configs
+type Change struct {
+	// Dotted yaml path of the setting, e.g. server.log_level
+	Path string
+	Old  any
+	New  any
+	// Whether the change was applied, settings not tagged reload:"true" need a restart
+	Applied bool
+}
+
+// Returns a copy of current with all reloadable settings taken from next, along with every setting that changed
+func Merge(current, next *Config) (*Config, []Change) {
+	merged := *current
+	var changes []Change
+
+	merge(reflect.ValueOf(&merged).Elem(), reflect.ValueOf(next).Elem(), "", false, &changes)
+
+	return &merged, changes
+}
+
+func merge(dst, src reflect.Value, prefix string, reload bool, changes *[]Change) {
+	t := dst.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		if !f.IsExported() {
+			continue
+		}
+
+		path := prefix + yamlKey(f)
+		fieldReload := reload || f.Tag.Get("reload") == "true"
+
+		if f.Type.Kind() == reflect.Struct && f.Type != durationType {
+			merge(dst.Field(i), src.Field(i), path+".", fieldReload, changes)
+			continue
+		}
+
+		oldVal, newVal := dst.Field(i).Interface(), src.Field(i).Interface()
+
+		if reflect.DeepEqual(oldVal, newVal) {
+			continue
+		}
+
+		// Settings tagged secret:"true" may contain credentials and must never be logged
+		if f.Tag.Get("secret") == "true" {
+			oldVal, newVal = "[redacted]", "[redacted]"
+		}
+
+		*changes = append(*changes, Change{
+			Path:    path,
+			Old:     oldVal,
+			New:     newVal,
+			Applied: fieldReload,
+		})
+
+		if fieldReload {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+}
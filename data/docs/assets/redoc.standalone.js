@@ -0,0 +1,3 @@
+/*! For license information please see redoc.standalone.js.LICENSE.txt */
+!function(e,t){"object"==typeof exports&&"object"==typeof module?module.exports=t(require("null")):"function"==typeof define&&define.amd?define(["null"],t):"object"==typeof exports?exports.Redoc=t(require("null")):e.Redoc=t(e.null)}(this,(function(e){return function(){var t={7228:function(e){e.exports=function(e,t){(null==t||t>e.length)&&(t=e.length);for(var n=0,r=new Array(t);n<t;n++)r[n]=e[n];return r},e.exports.default=e.exports,e.exports.__esModule=!0},2858:function(e){e.exports=function(e){if(Array.isArray(e))return e},e.exports.default=e.exports,e.exports.__esModule=!0},3646:function(e,t,n){var r=n(7228);e.exports=function(e){if(Array.isArray(e))return r(e)},e.exports.default=e.exports,e.exports.__esModule=!0},1506:function(e){e.exports=function(e){if(void 0===e)throw new ReferenceError("this hasn't been initialised - super() hasn't been called");return e},e.exports.default=e.exports,e.exports.__esModule=!0},4575:function(e){e.exports=function(e,t){if(!(e instanceof t))throw new TypeError("Cannot call a class as a function")},e.exports.default=e.exports,e.exports.__esModule=!0},9100:function(e,t,n){var r=n(9489),o=n(7067);function i(t,n,a){return o()?(e.exports=i=Reflect.construct,e.exports.default=e.exports,e.exports.__esModule=!0):(e.exports=i=function(e,t,n){var o=[null];o.push.apply(o,t);var i=new(Function.bind.apply(e,o));return n&&r(i,n.prototype),i},e.exports.default=e.exports,e.exports.__esModule=!0),i.apply(null,arguments)}e.exports=i,e.exports.default=e.exports,e.exports.__esModule=!0},3913:function(e){function t(e,t){for(var n=0;n<t.length;n++){var r=t[n];r.enumerable=r.enumerable||!1,r.configurable=!0,"value"in r&&(r.writable=!0),Object.defineProperty(e,r.key,r)}}e.exports=function(e,n,r){return n&&t(e.prototype,n),r&&t(e,r),e},e.exports.default=e.exports,e.exports.__esModule=!0},3269:function(e,t,n){var r=n(379);e.exports=function(e,t){var n="undefined"!=typeof Symbol&&e[Symbol.iterator]||e["@@iterator"];if(!n){if(Array.isArray(e)||(n=r(e))||t&&e&&"number"==typeof e.length){n&&(e=n);var o=0,i=function(){};return{s:i,n:function(){return o>=e.length?{done:!0}:{done:!1,value:e[o++]}},e:function(e){throw e},f:i}}throw new TypeError("Invalid attempt to iterate non-iterable instance.\nIn order to be iterable, non-array objects must have a [Symbol.iterator]() method.")}var a,s=!0,l=!1;return{s:function(){n=n.call(e)},n:function(){var e=n.next();return s=e.done,e},e:function(e){l=!0,a=e},f:function(){try{s||null==n.return||n.return()}finally{if(l)throw a}}}},e.exports.default=e.exports,e.exports.__esModule=!0},9842:function(e,t,n){var r=n(9754),o=n(7067),i=n(8585);e.exports=function(e){var t=o();return function(){var n,o=r(e);if(t){var a=r(this).constructor;n=Reflect.construct(o,arguments,a)}else n=o.apply(this,arguments);return i(this,n)}},e.exports.default=e.exports,e.exports.__esModule=!0},9713:function(e){e.exports=function(e,t,n){return t in e?Object.defineProperty(e,t,{value:n,enumerable:!0,configurable:!0,writable:!0}):e[t]=n,e},e.exports.default=e.exports,e.exports.__esModule=!0},9754:function(e){function t(n){return e.exports=t=Object.setPrototypeOf?Object.getPrototypeOf:function(e){return e.__proto__||Object.getPrototypeOf(e)},e.exports.default=e.exports,e.exports.__esModule=!0,t(n)}e.exports=t,e.exports.default=e.exports,e.exports.__esModule=!0},2205:function(e,t,n){var r=n(9489);e.exports=function(e,t){if("function"!=typeof t&&null!==t)throw new TypeError("Super expression must either be null or a function");e.prototype=Object.create(t&&t.prototype,{constructor:{value:e,writable:!0,configurable:!0}}),t&&r(e,t)},e.exports.default=e.exports,e.exports.__esModule=!0},430:function(e){e.exports=function(e){return-1!==Function.toString.call(e).indexOf("[native code]")},e.exports.default=e.exports,e.exports.__esModule=!0},7067:function(e){e.exports=function(){if("undefined"==typeof Reflect||!Reflect.construct)return!1;if(Reflect.construct.sham)return!1;if("function"==typeof Proxy)return!0;try{return Boolean.prototype.valueOf.call(Reflect.construct(Boolean,[],(function(){}))),!0}catch(e){return!1}},e.exports.default=e.exports,e.exports.__esModule=!0},6860:function(e){e.exports=function(e){if("undefined"!=typeof Symbol&&null!=e[Symbol.iterator]||null!=e["@@iterator"])return Array.from(e)},e.exports.default=e.exports,e.exports.__esModule=!0},3884:function(e){e.exports=function(e,t){var n=e&&("undefined"!=typeof Symbol&&e[Symbol.iterator]||e["@@iterator"]);if(null!=n){var r,o,i=[],a=!0,s=!1;try{for(n=n.call(e);!(a=(r=n.next()).done)&&(i.push(r.value),!t||i.length!==t);a=!0);}catch(e){s=!0,o=e}finally{try{a||null==n.return||n.return()}finally{if(s)throw o}}return i}},e.exports.default=e.exports,e.exports.__esModule=!0},521:function(e){e.exports=function(){throw new TypeError("Invalid attempt to destructure non-iterable instance.\nIn order to be iterable, non-array objects must have a [Symbol.iterator]() method.")},e.exports.default=e.exports,e.exports.__esModule=!0},8206:function(e){e.exports=function(){throw new TypeError("Invalid attempt to spread non-iterable instance.\nIn order to be iterable, non-array objects must have a [Symbol.iterator]() method.")},e.exports.default=e.exports,e.exports.__esModule=!0},8585:function(e,t,n){var r=n(8).default,o=n(1506);e.exports=function(e,t){return!t||"object"!==r(t)&&"function"!=typeof t?o(e):t},e.exports.default=e.exports,e.exports.__esModule=!0},9489:function(e){function t(n,r){return e.exports=t=Object.setPrototypeOf||function(e,t){return e.__proto__=t,e},e.exports.default=e.exports,e.exports.__esModule=!0,t(n,r)}e.exports=t,e.exports.default=e.exports,e.exports.__esModule=!0},3038:function(e,t,n){var r=n(2858),o=n(3884),i=n(379),a=n(521);e.exports=function(e,t){return r(e)||o(e,t)||i(e,t)||a()},e.exports.default=e.exports,e.exports.__esModule=!0},319:function(e,t,n){var r=n(3646),o=n(6860),i=n(379),a=n(8206);e.exports=function(e){return r(e)||o(e)||i(e)||a()},e.exports.default=e.exports,e.exports.__esModule=!0},8:function(e){function t(n){return"function"==typeof Symbol&&"symbol"==typeof Symbol.iterator?(e.exports=t=function(e){return typeof e},e.exports.default=e.exports,e.exports.__esModule=!0):(e.exports=t=function(e){return e&&"function"==typeof Symbol&&e.constructor===Symbol&&e!==Symbol.prototype?"symbol":typeof e},e.exports.default=e.exports,e.exports.__esModule=!0),t(n)}e.exports=t,e.exports.default=e.exports,e.exports.__esModule=!0},379:function(e,t,n){var r=n(7228);e.exports=function(e,t){if(e){if("string"==typeof e)return r(e,t);var n=Object.prototype.toString.call(e).slice(8,-1);return"Object"===n&&e.constructor&&(n=e.constructor.name),"Map"===n||"Set"===n?Array.from(e):"Arguments"===n||/^(?:Ui|I)nt(?:8|16|32)(?:Clamped)?Array$/.test(n)?r(e,t):void 0}},e.exports.default=e.exports,e.exports.__esModule=!0},5957:function(e,t,n){var r=n(9754),o=n(9489),i=n(430),a=n(9100);function s(t){var n="function"==typeof Map?new Map:void 0;return e.exports=s=function(e){if(null===e||!i(e))return e;if("function"!=typeof e)throw new TypeError("Super expression must either be null or a function");if(void 0!==n){if(n.has(e))return n.get(e);n.set(e,t)}function t(){return a(e,arguments,r(this).constructor)}return t.prototype=Object.create(e.prototype,{constructor:{value:t,enumerable:!1,writable:!0,configurable:!0}}),o(t,e)},e.exports.default=e.exports,e.exports.__esModule=!0,s(t)}e.exports=s,e.exports.default=e.exports,e.exports.__esModule=!0},7757:function(e,t,n){e.exports=n(5666)},2840:function(e,t,n){"use strict";var r=n(319).default,o=n(7757);n(1539),n(8674),n(9601),n(2222),n(1249),n(8309),n(7941),n(7327),n(4916),n(3123),n(7042);var i=this&&this.__awaiter||function(e,t,n,r){return new(n||(n=Promise))((function(o,i){function a(e){try{l(r.next(e))}catch(e){i(e)}}function s(e){try{l(r.throw(e))}catch(e){i(e)}}function l(e){var t;e.done?o(e.value):(t=e.value,t instanceof n?t:new n((function(e){e(t)}))).then(a,s)}l((r=r.apply(e,t||[])).next())}))};Object.defineProperty(t,"__esModule",{value:!0}),t.bundleDocument=t.bundle=t.OasVersion=void 0;var a,s=n(2307),l=n(8604),c=n(9079),u=n(8553),p=n(4343),f=n(7649),d=n(9562),h=n(3353),m=n(6230),v=n(8140),g=n(4241),y=n(2806),b=n(9272);function x(e){return i(this,void 0,void 0,o.mark((function t(){var n,i,s,v,y,b,x,w,E,S,_,O,A,R,C;return o.wrap((function(t){for(;;)switch(t.prev=t.next){case 0:return n=e.document,i=e.config,s=e.customTypes,v=e.externalRefResolver,y=e.dereference,b=void 0!==y&&y,x=m.detectOpenAPI(n.parsed),w=m.openAPIMajor(x),E=i.getRulesForOasVersion(w),S=d.normalizeTypes(i.extendTypes((null!=s?s:w===m.OasMajorVersion.Version3)?x===a.Version3_1?f.Oas3_1Types:u.Oas3Types:p.Oas2Types,x),i),_=g.initRules(E,i,"preprocessors",x),O=g.initRules(E,i,"decorators",x),A={problems:[],oasVersion:x},R=c.normalizeVisitors([].concat(r(_),[{severity:"error",ruleId:"bundler",visitor:k(w,b,n)}],r(O)),S),t.next=11,l.resolveDocument({rootDocument:n,rootType:S.DefinitionRoot,externalRefResolver:v});case 11:return C=t.sent,h.walkDocument({document:n,rootType:S.DefinitionRoot,normalizedVisitors:R,resolvedRefMap:C,ctx:A}),t.abrupt("return",{bundle:n,problems:A.problems.map((function(e){return i.addProblemToIgnore(e)})),fileDependencies:v.getFiles()});case 14:case"end":return t.stop()}}),t)})))}function w(e,t){switch(t){case m.OasMajorVersion.Version3:switch(e){case"Schema":return"schemas";case"Parameter":return"parameters";case"Response":return"responses";case"Example":return"examples";case"RequestBody":return"requestBodies";case"Header":return"headers";case"SecuritySchema":return"securitySchemes";case"Link":return"links";case"Callback":return"callbacks";default:return null}case m.OasMajorVersion.Version2:switch(e){case"Schema":return"definitions";case"Parameter":return"parameters";case"Response":return"responses";default:return null}}}function k(e,t,n){var r,o={ref:{leave:function(r,o,s){if(s.location&&void 0!==s.node){if(s.location.source!==n.source||s.location.source!==o.location.source||"scalar"===o.type.name||t){var l=w(o.type.name,e);l?t?(a(l,s,o),i(r,s,o)):r.$ref=a(l,s,o):i(r,s,o)}}else y.reportUnresolvedRef(s,o.report,o.location)}},DefinitionRoot:{enter:function(t){e===m.OasMajorVersion.Version3?r=t.components=t.components||{}:e===m.OasMajorVersion.Version2&&(r=t)}}};function i(e,t,n){b.isPlainObject(t.node)?(delete e.$ref,Object.assign(e,t.node)):n.parent[n.key]=t.node}function a(t,n,o){r[t]=r[t]||{};var i=function(e,t,n){for(var o=[e.location.source.absoluteRef,e.location.pointer],i=o[0],a=o[1],l=r[t],c="",u=a.slice(2).split("/").filter(Boolean);u.length>0;)if(c=u.pop()+(c?"-".concat(c):""),!l||!l[c]||s(l[c],e.node))return c;if(!l[c=v.refBaseName(i)+(c?"_".concat(c):"")]||s(l[c],e.node))return c;for(var p=c,f=2;l[c]&&!s(l[c],e.node);)c="".concat(p,"-").concat(f),f++;return l[c]||n.report({message:"Two schemas are referenced with the same name but different content. Renamed ".concat(p," to ").concat(c,"."),location:n.location,forceSeverity:"warn"}),c}(n,t,o);return r[t][i]=n.node,e===m.OasMajorVersion.Version3?"#/components/".concat(t,"/").concat(i):"#/".concat(t,"/").concat(i)}return e===m.OasMajorVersion.Version3&&(o.DiscriminatorMapping={leave:function(n,r){for(var o=0,i=Object.keys(n);o<i.length;o++){var s=i[o],l=n[s],c=r.resolve({$ref:l});if(!c.location||void 0===c.node)return void y.reportUnresolvedRef(c,r.report,r.location.child(s));var u=w("Schema",e);t?a(u,c,r):n[s]=a(u,c,r)}}}),o}!function(e){e.Version2="oas2",e.Version3_0="oas3_0",e.Version3_1="oas3_1"}(a=t.OasVersion||(t.OasVersion={})),t.bundle=function(e){return i(this,void 0,void 0,o.mark((function t(){var n,r,i,a,s,c,u;return o.wrap((function(t){for(;;)switch(t.prev=t.next){case 0:if(n=e.ref,r=e.doc,i=e.externalRefResolver,a=void 0===i?new l.BaseResolver(e.config.resolve):i,s=e.base,c=void 0===s?null:s,n||r){t.next=3;break}throw new Error("Document or reference is required.\n");case 3:if(void 0===r){t.next=7;break}t.t0=r,t.next=10;break;case 7:return t.next=9,a.resolveDocument(c,n,!0);case 9:t.t0=t.sent;case 10:if(!((u=t.t0)instanceof Error)){t.next=13;break}throw u;case 13:return t.abrupt("return",x(Object.assign(Object.assign({document:u},e),{config:e.config.lint,externalRefResolver:a})));case 14:case"end":return t.stop()}}),t)})))},t.bundleDocument=x},7306:function(e,t,n){"use strict";var r=n(3038).default,o=n(319).default,i=n(3269).default,a=n(9713).default,s=n(4575).default,l=n(3913).default;n(6992),n(1539),n(189),n(8783),n(3948),n(9601),n(9600),n(7941),n(1038),n(7327),n(4747),n(2479),n(1249),n(9826),n(2222),n(4916),n(3123),n(1532),n(9714),t.De=t.bD=void 0;var c=n(5101),u=n(6470),p=n(4756),f=n(6470),d=n(2566),h=n(9272),m=n(6230),v=n(8890);t.bD=".redocly.lint-ignore.yaml";var g=function(){function e(n,o){var l,g,x;s(this,e),this.rawConfig=n,this.configFile=o,this.ignore={},this._usedRules=new Set,this._usedVersions=new Set,this.recommendedFallback=!1,this.plugins=n.plugins?function(e){var t=arguments.length>1&&void 0!==arguments[1]?arguments[1]:"";if(!e)return[];var n=require,r=new Map;return e.map((function(e){var o="string"==typeof e?n(u.resolve(u.dirname(t),e)):e,i=o.id;if("string"!=typeof i)throw new Error(d.red("Plugin must define `id` property in ".concat(d.blue(e.toString()),".")));if(r.has(i)){var a=r.get(i);throw new Error(d.red('Plugin "id" must be unique. Plugin '.concat(d.blue(e.toString()),' uses id "').concat(d.blue(i),'" already seen in ').concat(d.blue(a))))}r.set(i,e.toString());var s=Object.assign(Object.assign({id:i},o.configs?{configs:o.configs}:{}),o.typeExtension?{typeExtension:o.typeExtension}:{});if(o.rules){if(!o.rules.oas3&&!o.rules.oas2)throw new Error('Plugin rules must have `oas3` or `oas2` rules "'.concat(e,"."));s.rules={},o.rules.oas3&&(s.rules.oas3=y(o.rules.oas3,i)),o.rules.oas2&&(s.rules.oas2=y(o.rules.oas2,i))}if(o.preprocessors){if(!o.preprocessors.oas3&&!o.preprocessors.oas2)throw new Error('Plugin `preprocessors` must have `oas3` or `oas2` preprocessors "'.concat(e,"."));s.preprocessors={},o.preprocessors.oas3&&(s.preprocessors.oas3=y(o.preprocessors.oas3,i)),o.preprocessors.oas2&&(s.preprocessors.oas2=y(o.preprocessors.oas2,i))}if(o.decorators){if(!o.decorators.oas3&&!o.decorators.oas2)throw new Error('Plugin `decorators` must have `oas3` or `oas2` decorators "'.concat(e,"."));s.decorators={},o.decorators.oas3&&(s.decorators.oas3=y(o.decorators.oas3,i)),o.decorators.oas2&&(s.decorators.oas2=y(o.decorators.oas2,i))}return s})).filter(h.notUndefined)}(n.plugins,o):[],this.doNotResolveExamples=!!n.doNotResolveExamples,n.extends||(this.recommendedFallback=!0);var w,k,E=n.extends?(w=n.extends,k=this.plugins,w.map((function(e){var t,n=function(e){if(e.indexOf("/")>-1){var t=e.split("/"),n=r(t,2);return{pluginId:n[0],configName:n[1]}}return{pluginId:"",configName:e}}(e),o=n.pluginId,i=n.configName,a=k.find((function(e){return e.id===o}));if(!a)throw new Error("Invalid config ".concat(d.red(e),": plugin ").concat(o," is not included."));var s=null===(t=a.configs)||void 0===t?void 0:t[i];if(!s)throw new Error(o?"Invalid config ".concat(d.red(e),": plugin ").concat(o," doesn't export config with name ").concat(i,"."):"Invalid config ".concat(d.red(e),": there is no such built-in config."));return s}))):[v.default];(n.rules||n.preprocessors||n.decorators)&&E.push({rules:n.rules,preprocessors:n.preprocessors,decorators:n.decorators});var S=function(e){var t,n={rules:{},oas2Rules:{},oas3_0Rules:{},oas3_1Rules:{},preprocessors:{},oas2Preprocessors:{},oas3_0Preprocessors:{},oas3_1Preprocessors:{},decorators:{},oas2Decorators:{},oas3_0Decorators:{},oas3_1Decorators:{}},r=i(e);try{for(r.s();!(t=r.n()).done;){var o=t.value;if(o.extends)throw new Error("`extends` is not supported in shared configs yet: ".concat(JSON.stringify(o,null,2),"."));Object.assign(n.rules,o.rules),Object.assign(n.oas2Rules,o.oas2Rules),b(n.oas2Rules,o.rules||{}),Object.assign(n.oas3_0Rules,o.oas3_0Rules),b(n.oas3_0Rules,o.rules||{}),Object.assign(n.oas3_1Rules,o.oas3_1Rules),b(n.oas3_1Rules,o.rules||{}),Object.assign(n.preprocessors,o.preprocessors),Object.assign(n.oas2Preprocessors,o.oas2Preprocessors),b(n.oas2Preprocessors,o.preprocessors||{}),Object.assign(n.oas3_0Preprocessors,o.oas3_0Preprocessors),b(n.oas3_0Preprocessors,o.preprocessors||{}),Object.assign(n.oas3_1Preprocessors,o.oas3_1Preprocessors),b(n.oas3_1Preprocessors,o.preprocessors||{}),Object.assign(n.decorators,o.decorators),Object.assign(n.oas2Decorators,o.oas2Decorators),b(n.oas2Decorators,o.decorators||{}),Object.assign(n.oas3_0Decorators,o.oas3_0Decorators),b(n.oas3_0Decorators,o.decorators||{}),Object.assign(n.oas3_1Decorators,o.oas3_1Decorators),b(n.oas3_1Decorators,o.decorators||{})}}catch(e){r.e(e)}finally{r.f()}return n}(E);this.rules=(a(l={},m.OasVersion.Version2,Object.assign(Object.assign({},S.rules),S.oas2Rules)),a(l,m.OasVersion.Version3_0,Object.assign(Object.assign({},S.rules),S.oas3_0Rules)),a(l,m.OasVersion.Version3_1,Object.assign(Object.assign({},S.rules),S.oas3_1Rules)),l),this.preprocessors=(a(g={},m.OasVersion.Version2,Object.assign(Object.assign({},S.preprocessors),S.oas2Preprocessors)),a(g,m.OasVersion.Version3_0,Object.assign(Object.assign({},S.preprocessors),S.oas3_0Preprocessors)),a(g,m.OasVersion.Version3_1,Object.assign(Object.assign({},S.preprocessors),S.oas3_1Preprocessors)),g),this.decorators=(a(x={},m.OasVersion.Version2,Object.assign(Object.assign({},S.decorators),S.oas2Decorators)),a(x,m.OasVersion.Version3_0,Object.assign(Object.assign({},S.decorators),S.oas3_0Decorators)),a(x,m.OasVersion.Version3_1,Object.assign(Object.assign({},S.decorators),S.oas3_1Decorators)),x);var _=this.configFile?u.dirname(this.configFile):"undefined"!=typeof process&&process.cwd()||"",O=u.join(_,t.bD);if(c.hasOwnProperty("existsSync")&&c.existsSync(O)){this.ignore=p.safeLoad(c.readFileSync(O,"utf-8"))||{};for(var A=0,R=Object.keys(this.ignore);A<R.length;A++){var C=R[A];this.ignore[u.resolve(f.dirname(O),C)]=this.ignore[C];for(var T=0,P=Object.keys(this.ignore[C]);T<P.length;T++){var I=P[T];this.ignore[C][I]=new Set(this.ignore[C][I])}delete this.ignore[C]}}}return l(e,[{key:"saveIgnore",value:function(){for(var e=this.configFile?u.dirname(this.configFile):process.cwd(),n=u.join(e,t.bD),r={},o=0,i=Object.keys(this.ignore);o<i.length;o++)for(var a=i[o],s=r[u.relative(e,a)]=this.ignore[a],l=0,f=Object.keys(s);l<f.length;l++){var d=f[l];s[d]=Array.from(s[d])}c.writeFileSync(n,"# This file instructs Redocly's linter to ignore the rules contained for specific parts of your API.\n# See https://redoc.ly/docs/cli/ for more information.\n"+p.safeDump(r))}},{key:"addIgnore",value:function(e){var t=this.ignore,n=e.location[0];if(void 0!==n.pointer){var r=t[n.source.absoluteRef]=t[n.source.absoluteRef]||{};(r[e.ruleId]=r[e.ruleId]||new Set).add(n.pointer)}}},{key:"addProblemToIgnore",value:function(e){var t=e.location[0];if(void 0===t.pointer)return e;var n=(this.ignore[t.source.absoluteRef]||{})[e.ruleId],r=n&&n.has(t.pointer);return r?Object.assign(Object.assign({},e),{ignored:r}):e}},{key:"extendTypes",value:function(e,t){var n,r=e,o=i(this.plugins);try{for(o.s();!(n=o.n()).done;){var a=n.value;if(void 0!==a.typeExtension)switch(t){case m.OasVersion.Version3_0:case m.OasVersion.Version3_1:if(!a.typeExtension.oas3)continue;r=a.typeExtension.oas3(r,t);case m.OasVersion.Version2:if(!a.typeExtension.oas2)continue;r=a.typeExtension.oas2(r,t);default:throw new Error("Not implemented")}}}catch(e){o.e(e)}finally{o.f()}return r}},{key:"getRuleSettings",value:function(e,t){this._usedRules.add(e),this._usedVersions.add(t);var n=this.rules[t][e]||"off";return"string"==typeof n?{severity:n}:Object.assign({severity:"error"},n)}},{key:"getPreprocessorSettings",value:function(e,t){this._usedRules.add(e),this._usedVersions.add(t);var n=this.preprocessors[t][e]||"off";return"string"==typeof n?{severity:"on"===n?"error":n}:Object.assign({severity:"error"},n)}},{key:"getDecoratorSettings",value:function(e,t){this._usedRules.add(e),this._usedVersions.add(t);var n=this.decorators[t][e]||"off";return"string"==typeof n?{severity:"on"===n?"error":n}:Object.assign({severity:"error"},n)}},{key:"getUnusedRules",value:function(){for(var e=this,t=[],n=[],r=[],i=0,a=Array.from(this._usedVersions);i<a.length;i++){var s=a[i];t.push.apply(t,o(Object.keys(this.rules[s]).filter((function(t){return!e._usedRules.has(t)})))),n.push.apply(n,o(Object.keys(this.decorators[s]).filter((function(t){return!e._usedRules.has(t)})))),r.push.apply(r,o(Object.keys(this.preprocessors[s]).filter((function(t){return!e._usedRules.has(t)}))))}return{rules:t,preprocessors:r,decorators:n}}},{key:"getRulesForOasVersion",value:function(e){switch(e){case m.OasMajorVersion.Version3:var t=[];return this.plugins.forEach((function(e){var n;return(null===(n=e.preprocessors)||void 0===n?void 0:n.oas3)&&t.push(e.preprocessors.oas3)})),this.plugins.forEach((function(e){var n;return(null===(n=e.rules)||void 0===n?void 0:n.oas3)&&t.push(e.rules.oas3)})),this.plugins.forEach((function(e){var n;return(null===(n=e.decorators)||void 0===n?void 0:n.oas3)&&t.push(e.decorators.oas3)})),t;case m.OasMajorVersion.Version2:var n=[];return this.plugins.forEach((function(e){var t;return(null===(t=e.preprocessors)||void 0===t?void 0:t.oas2)&&n.push(e.preprocessors.oas2)})),this.plugins.forEach((function(e){var t;return(null===(t=e.rules)||void 0===t?void 0:t.oas2)&&n.push(e.rules.oas2)})),this.plugins.forEach((function(e){var t;return(null===(t=e.decorators)||void 0===t?void 0:t.oas2)&&n.push(e.decorators.oas2)})),n}}},{key:"skipRules",value:function(e){var t,n=i(e||[]);try{for(n.s();!(t=n.n()).done;)for(var r=t.value,o=0,a=Object.values(m.OasVersion);o<a.length;o++){var s=a[o];this.rules[s][r]&&(this.rules[s][r]="off")}}catch(e){n.e(e)}finally{n.f()}}},{key:"skipPreprocessors",value:function(e){var t,n=i(e||[]);try{for(n.s();!(t=n.n()).done;)for(var r=t.value,o=0,a=Object.values(m.OasVersion);o<a.length;o++){var s=a[o];this.preprocessors[s][r]&&(this.preprocessors[s][r]="off")}}catch(e){n.e(e)}finally{n.f()}}},{key:"skipDecorators",value:function(e){var t,n=i(e||[]);try{for(n.s();!(t=n.n()).done;)for(var r=t.value,o=0,a=Object.values(m.OasVersion);o<a.length;o++){var s=a[o];this.decorators[s][r]&&(this.decorators[s][r]="off")}}catch(e){n.e(e)}finally{n.f()}}}]),e}();function y(e,t){if(!t)return e;for(var n={},r=0,o=Object.keys(e);r<o.length;r++){var i=o[r];n["".concat(t,"/").concat(i)]=e[i]}return n}function b(e,t){for(var n=0,r=Object.keys(t);n<r.length;n++){var o=r[n];e.hasOwnProperty(o)&&(e[o]=t[o])}}t.De=function e(t,n){var r,o,i;s(this,e),this.rawConfig=t,this.configFile=n,this.apiDefinitions=t.apiDefinitions||{},this.lint=new g(t.lint||{},n),this.referenceDocs=t.referenceDocs||{},this.resolve={http:{headers:null!==(i=null===(o=null===(r=null==t?void 0:t.resolve)||void 0===r?void 0:r.http)||void 0===o?void 0:o.headers)&&void 0!==i?i:[],customFetch:void 0}}}},8890:function(e,t){"use strict";Object.defineProperty(t,"__esModule",{value:!0}),t.default={rules:{"info-description":"warn","info-contact":"off","info-license":"warn","info-license-url":"warn","tag-description":"warn","tags-alphabetical":"off","parameter-description":"off","no-path-trailing-slash":"error","no-identical-paths":"error","no-ambiguous-paths":"warn","path-declaration-must-exist":"error","path-not-include-query":"error","path-parameters-defined":"error","operation-description":"off","operation-2xx-response":"warn","operation-operationId":"warn","operation-summary":"error","operation-operationId-unique":"error","operation-operationId-url-safe":"error","operation-parameters-unique":"error","operation-tag-defined":"off","operation-security-defined":"error","operation-singular-tag":"off","no-unresolved-refs":"error","no-enum-type-mismatch":"error","boolean-parameter-prefixes":"off","paths-kebab-case":"off",spec:"error"},oas3_0Rules:{"no-invalid-media-type-examples":{severity:"warn",disallowAdditionalProperties:!0},"no-server-example.com":"warn","no-server-trailing-slash":"error","no-empty-servers":"error","no-example-value-and-externalValue":"error","no-unused-components":"warn","no-undefined-server-variable":"error","no-servers-empty-enum":"error"},oas3_1Rules:{"no-server-example.com":"warn","no-server-trailing-slash":"error","no-empty-servers":"error","no-example-value-and-externalValue":"error","no-unused-components":"warn","no-undefined-server-variable":"error","no-servers-empty-enum":"error"}}},4241:function(e,t,n){"use strict";n(7327),n(6535),n(9244),n(1249),n(7941),Object.defineProperty(t,"__esModule",{value:!0}),t.initRules=void 0;var r=n(9272);t.initRules=function(e,t,n,o){return e.flatMap((function(e){return Object.keys(e).map((function(r){var i=e[r],a="rules"===n?t.getRuleSettings(r,o):"preprocessors"===n?t.getPreprocessorSettings(r,o):t.getDecoratorSettings(r,o);if("off"!==a.severity){var s=i(a);return{severity:a.severity,ruleId:r,visitor:s}}}))})).filter(r.notUndefined)}},6230:function(e,t,n){"use strict";var r,o;n(6755),Object.defineProperty(t,"__esModule",{value:!0}),t.openAPIMajor=t.detectOpenAPI=t.OasMajorVersion=t.OasVersion=void 0,function(e){e.Version2="oas2",e.Version3_0="oas3_0",e.Version3_1="oas3_1"}(r=t.OasVersion||(t.OasVersion={})),function(e){e.Version2="oas2",e.Version3="oas3"}(o=t.OasMajorVersion||(t.OasMajorVersion={})),t.detectOpenAPI=function(e){if("object"!=typeof e)throw new Error("Document must be JSON object, got ".concat(typeof e));if(!e.openapi&&!e.swagger)throw new Error("This doesn’t look like an OpenAPI document.\n");if(e.openapi&&e.openapi.startsWith("3.0"))return r.Version3_0;if(e.openapi&&e.openapi.startsWith("3.1"))return r.Version3_1;if(e.swagger&&"2.0"===e.swagger)return r.Version2;throw new Error("Unsupported OpenAPI Version: ".concat(e.openapi||e.swagger))},t.openAPIMajor=function(e){return e===r.Version2?o.Version2:o.Version3}},8140:function(e,t,n){"use strict";var r=n(3038).default,o=n(4575).default,i=n(3913).default;n(9600),n(1249),n(9601),n(4916),n(5306),n(3123),n(7327),n(6755),Object.defineProperty(t,"__esModule",{value:!0}),t.isMappingRef=t.isAbsoluteUrl=t.refBaseName=t.pointerBaseName=t.parsePointer=t.parseRef=t.escapePointer=t.unescapePointer=t.Location=t.isRef=t.joinPointer=void 0;var a=n(6470);function s(e,t){return""===e&&(e="#/"),"/"===e[e.length-1]?e+t:e+"/"+t}t.joinPointer=s,t.isRef=function(e){return e&&"string"==typeof e.$ref};var l=function(){function e(t,n){o(this,e),this.source=t,this.pointer=n}return i(e,[{key:"child",value:function(t){return new e(this.source,s(this.pointer,(Array.isArray(t)?t:[t]).map(u).join("/")))}},{key:"key",value:function(){return Object.assign(Object.assign({},this),{reportOnKey:!0})}},{key:"absolutePointer",get:function(){return this.source.absoluteRef+("#/"===this.pointer?"":this.pointer)}}]),e}();function c(e){return decodeURIComponent(e.replace(/~1/g,"/").replace(/~0/g,"~"))}function u(e){return"number"==typeof e?e:e.replace(/~/g,"~0").replace(/\//g,"~1")}t.Location=l,t.unescapePointer=c,t.escapePointer=u,t.parseRef=function(e){var t=e.split("#/"),n=r(t,2),o=n[0],i=n[1];return{uri:o||null,pointer:i?i.split("/").map(c).filter(Boolean):[]}},t.parsePointer=function(e){return e.substr(2).split("/").map(c)},t.pointerBaseName=function(e){var t=e.split("/");return t[t.length-1]},t.refBaseName=function(e){var t=e.split(a.sep);return t[t.length-1].split(".")[0]},t.isAbsoluteUrl=function(e){return e.startsWith("http://")||e.startsWith("https://")},t.isMappingRef=function(e){return e.startsWith("#")||e.startsWith("https://")||e.startsWith("./")||e.startsWith("../")||e.indexOf("/")>-1}},8604:function(e,t,n){"use strict";var r=n(3269).default,o=n(7757),i=n(3038).default,a=n(1506).default,s=n(2205).default,l=n(9842).default,c=n(5957).default,u=n(4575).default,p=n(3913).default;n(1539),n(8674),n(4916),n(3123),n(4723),n(6992),n(1532),n(8783),n(3948),n(189),n(1038),n(6699),n(2222),n(8309),n(7941),n(9601);var f=this&&this.__awaiter||function(e,t,n,r){return new(n||(n=Promise))((function(o,i){function a(e){try{l(r.next(e))}catch(e){i(e)}}function s(e){try{l(r.throw(e))}catch(e){i(e)}}function l(e){var t;e.done?o(e.value):(t=e.value,t instanceof n?t:new n((function(e){e(t)}))).then(a,s)}l((r=r.apply(e,t||[])).next())}))};Object.defineProperty(t,"__esModule",{value:!0}),t.resolveDocument=t.BaseResolver=t.makeDocumentFromString=t.YamlParseError=t.ResolveError=t.Source=void 0;var d=n(3817),h=n(6470),m=n(8575),v=n(4756),g=n(8140),y=n(9562),b=n(9272),x=function(){function e(t,n,r){u(this,e),this.absoluteRef=t,this.body=n,this.mimeType=r}return p(e,[{key:"getAst",value:function(e){var t;return void 0===this._ast&&(this._ast=null!==(t=e(this.body,{filename:this.absoluteRef}))&&void 0!==t?t:void 0,this._ast&&0===this._ast.kind&&""===this._ast.value&&1!==this._ast.startPosition&&(this._ast.startPosition=1,this._ast.endPosition=1)),this._ast}},{key:"getLines",value:function(){return void 0===this._lines&&(this._lines=this.body.split(/\r\n|[\n\r]/g)),this._lines}}]),e}();t.Source=x;var w=function(e){s(n,e);var t=l(n);function n(e){var r;return u(this,n),(r=t.call(this,e.message)).originalError=e,Object.setPrototypeOf(a(r),n.prototype),r}return n}(c(Error));t.ResolveError=w;var k=/at line (\d+), column (\d+):/,E=function(e){s(n,e);var t=l(n);function n(e,r){var o;u(this,n),(o=t.call(this,e.message.split("\n")[0])).originalError=e,o.source=r,Object.setPrototypeOf(a(o),n.prototype);var s=o.message.match(k)||[],l=i(s,3),c=l[1],p=l[2];return o.line=parseInt(c,10),o.col=parseInt(p,10),o}return n}(c(Error));t.YamlParseError=E,t.makeDocumentFromString=function(e,t){var n=new x(t,e);try{return{source:n,parsed:v.safeLoad(e,{filename:t})}}catch(e){throw new E(e,n)}};var S=function(){function e(){var t=arguments.length>0&&void 0!==arguments[0]?arguments[0]:{http:{headers:[]}};u(this,e),this.config=t,this.cache=new Map}return p(e,[{key:"getFiles",value:function(){return new Set(Array.from(this.cache.keys()))}},{key:"resolveExternalRef",value:function(e,t){return g.isAbsoluteUrl(t)?t:e&&g.isAbsoluteUrl(e)?m.resolve(e,t):h.resolve(e?h.dirname(e):process.cwd(),t)}},{key:"loadExternalRef",value:function(e){return f(this,void 0,void 0,o.mark((function t(){var n,r,i;return o.wrap((function(t){for(;;)switch(t.prev=t.next){case 0:if(t.prev=0,!g.isAbsoluteUrl(e)){t.next=10;break}return t.next=4,b.readFileFromUrl(e,this.config.http);case 4:return n=t.sent,r=n.body,i=n.mimeType,t.abrupt("return",new x(e,r,i));case 10:return t.t0=x,t.t1=e,t.next=14,d.promises.readFile(e,"utf-8");case 14:return t.t2=t.sent,t.abrupt("return",new t.t0(t.t1,t.t2));case 16:t.next=21;break;case 18:throw t.prev=18,t.t3=t.catch(0),new w(t.t3);case 21:case"end":return t.stop()}}),t,this,[[0,18]])})))}},{key:"parseDocument",value:function(e){var t,n=arguments.length>1&&void 0!==arguments[1]&&arguments[1],r=e.absoluteRef.substr(e.absoluteRef.lastIndexOf("."));if(![".json",".json",".yml",".yaml"].includes(r)&&!(null===(t=e.mimeType)||void 0===t?void 0:t.match(/(json|yaml|openapi)/))&&!n)return{source:e,parsed:e.body};try{return{source:e,parsed:v.safeLoad(e.body,{filename:e.absoluteRef})}}catch(t){throw new E(t,e)}}},{key:"resolveDocument",value:function(e,t){var n=arguments.length>2&&void 0!==arguments[2]&&arguments[2];return f(this,void 0,void 0,o.mark((function r(){var i,a,s,l=this;return o.wrap((function(r){for(;;)switch(r.prev=r.next){case 0:if(i=this.resolveExternalRef(e,t),!(a=this.cache.get(i))){r.next=4;break}return r.abrupt("return",a);case 4:return s=this.loadExternalRef(i).then((function(e){return l.parseDocument(e,n)})),this.cache.set(i,s),r.abrupt("return",s);case 7:case"end":return r.stop()}}),r,this)})))}}]),e}();function _(e,t){return{prev:e,node:t}}function O(e,t){for(;e;){if(e.node===t)return!0;e=e.prev}return!1}t.BaseResolver=S;var A={name:"unknown",properties:{}},R={name:"scalar",properties:{}};t.resolveDocument=function(e){return f(this,void 0,void 0,o.mark((function t(){var n,i,a,s,l,c,u,p;return o.wrap((function(t){for(;;)switch(t.prev=t.next){case 0:p=function(e,t,n,a){function u(e,t,n){return f(this,void 0,void 0,o.mark((function a(){var l,c,p,f,d,h,m,v,y,b,x,w,k;return o.wrap((function(o){for(;;)switch(o.prev=o.next){case 0:if(!O(n.prev,t)){o.next=2;break}throw new Error("Self-referencing circular pointer");case 2:if(l=g.parseRef(t.$ref),c=l.uri,p=l.pointer,f=null!==c,o.prev=4,!f){o.next=11;break}return o.next=8,i.resolveDocument(e.source.absoluteRef,c);case 8:o.t0=o.sent,o.next=12;break;case 11:o.t0=e;case 12:d=o.t0,o.next=21;break;case 15:return o.prev=15,o.t1=o.catch(4),h={resolved:!1,isRemote:f,document:void 0,error:o.t1},m=e.source.absoluteRef+"::"+t.$ref,s.set(m,h),o.abrupt("return",h);case 21:v={resolved:!0,document:d,isRemote:f,node:e.parsed,nodePointer:"#/"},y=d.parsed,b=r(p),o.prev=25,b.s();case 27:if((x=b.n()).done){o.next=55;break}if(w=x.value,"object"==typeof y){o.next=34;break}return y=void 0,o.abrupt("break",55);case 34:if(void 0===y[w]){o.next=39;break}y=y[w],v.nodePointer=g.joinPointer(v.nodePointer,g.escapePointer(w)),o.next=53;break;case 39:if(!g.isRef(y)){o.next=51;break}return o.next=42,u(d,y,_(n,y));case 42:if(v=o.sent,d=v.document||d,"object"==typeof v.node){o.next=47;break}return y=void 0,o.abrupt("break",55);case 47:y=v.node[w],v.nodePointer=g.joinPointer(v.nodePointer,g.escapePointer(w)),o.next=53;break;case 51:return y=void 0,o.abrupt("break",55);case 53:o.next=27;break;case 55:o.next=60;break;case 57:o.prev=57,o.t2=o.catch(25),b.e(o.t2);case 60:return o.prev=60,b.f(),o.finish(60);case 63:if(v.node=y,v.document=d,k=e.source.absoluteRef+"::"+t.$ref,!v.document||!g.isRef(y)){o.next=70;break}return o.next=69,u(v.document,y,_(n,y));case 69:v=o.sent;case 70:return s.set(k,v),o.abrupt("return",Object.assign({},v));case 72:case"end":return o.stop()}}),a,null,[[4,15],[25,57,60,63]])})))}!function e(n,r,o){if("object"==typeof n&&null!==n){var i="".concat(r.name,"::").concat(o);if(!l.has(i))if(l.add(i),Array.isArray(n)){var a=r.items;if(r!==A&&void 0===a)return;for(var s=0;s<n.length;s++)e(n[s],a||A,g.joinPointer(o,s))}else{for(var f=0,d=Object.keys(n);f<d.length;f++){var h=d[f],m=n[h],v=r.properties[h];void 0===v&&(v=r.additionalProperties),"function"==typeof v&&(v=v(m,h)),void 0===v&&(v=A),!y.isNamedType(v)&&(null==v?void 0:v.directResolveAs)&&(v=v.directResolveAs,m={$ref:m}),v&&void 0===v.name&&!1!==v.resolvable&&(v=R),y.isNamedType(v)&&"object"==typeof m&&e(m,v,g.joinPointer(o,g.escapePointer(h)))}if(g.isRef(n)){var b=u(t,n,{prev:null,node:n}).then((function(e){e.resolved&&p(e.node,e.document,e.nodePointer,r)}));c.push(b)}}}}(e,a,t.source.absoluteRef+n)},n=e.rootDocument,i=e.externalRefResolver,a=e.rootType,s=new Map,l=new Set,c=[],p(n.parsed,n,"#/",a);case 6:return t.next=8,Promise.all(c);case 8:u=t.sent;case 9:if(c.length!==u.length){t.next=6;break}case 10:return t.abrupt("return",s);case 11:case"end":return t.stop()}}),t)})))}},2806:function(e,t,n){"use strict";n(7941),Object.defineProperty(t,"__esModule",{value:!0}),t.reportUnresolvedRef=t.NoUnresolvedRefs=void 0;var r=n(8604);function o(e,t,n){var o,i=e.error;i instanceof r.YamlParseError&&t({message:"Failed to parse: "+i.message,location:{source:i.source,pointer:void 0,start:{col:i.col,line:i.line}}});var a=null===(o=e.error)||void 0===o?void 0:o.message;t({location:n,message:"Can't resolve $ref".concat(a?": "+a:"")})}t.NoUnresolvedRefs=function(){return{ref:{leave:function(e,t,n){var r=t.report,i=t.location;void 0===n.node&&o(n,r,i)}},DiscriminatorMapping:function(e,t){for(var n=t.report,r=t.resolve,i=t.location,a=0,s=Object.keys(e);a<s.length;a++){var l=s[a],c=r({$ref:e[l]});if(void 0!==c.node)return;o(c,n,i.child(l))}}}},t.reportUnresolvedRef=o},9562:function(e,t,n){"use strict";var r=n(3038).default;n(7941),n(9601),n(2479),n(9720),n(8309),Object.defineProperty(t,"__esModule",{value:!0}),t.isNamedType=t.normalizeTypes=t.mapOf=t.listOf=void 0,t.listOf=function(e){return{name:e+"_List",properties:{},items:e}},t.mapOf=function(e){return{name:e+"_Map",properties:{},additionalProperties:function(){return e}}},t.normalizeTypes=function(e){for(var t=arguments.length>1&&void 0!==arguments[1]?arguments[1]:{},n={},o=0,i=Object.keys(e);o<i.length;o++){var a=i[o];n[a]=Object.assign(Object.assign({},e[a]),{name:a})}for(var s=0,l=Object.values(n);s<l.length;s++){var c=l[s];u(c)}return n;function u(e){if(e.additionalProperties&&(e.additionalProperties=p(e.additionalProperties)),e.items&&(e.items=p(e.items)),e.properties){for(var n={},o=0,i=Object.entries(e.properties);o<i.length;o++){var a=r(i[o],2),s=a[0],l=a[1];n[s]=p(l),t.doNotResolveExamples&&l&&l.isExample&&(n[s]=Object.assign(Object.assign({},l),{resolvable:!1}))}e.properties=n}}function p(e){if("string"==typeof e){if(!n[e])throw new Error("Unknown type name found: ".concat(e));return n[e]}return"function"==typeof e?function(t,n){return p(e(t,n))}:e&&e.name?(u(e=Object.assign({},e)),e):e&&e.directResolveAs?Object.assign(Object.assign({},e),{directResolveAs:p(e.directResolveAs)}):e}},t.isNamedType=function(e){return"string"==typeof(null==e?void 0:e.name)}},4343:function(e,t,n){"use strict";n(6755),Object.defineProperty(t,"__esModule",{value:!0}),t.Oas2Types=void 0;var r=n(9562),o=/^[0-9][0-9Xx]{2}$/,i={properties:{swagger:{type:"string"},info:"Info",host:{type:"string"},basePath:{type:"string"},schemes:{type:"array",items:{type:"string"}},consumes:{type:"array",items:{type:"string"}},produces:{type:"array",items:{type:"string"}},paths:"PathMap",definitions:"NamedSchemas",parameters:"NamedParameters",responses:"NamedResponses",securityDefinitions:"NamedSecuritySchemes",security:r.listOf("SecurityRequirement"),tags:r.listOf("Tag"),externalDocs:"ExternalDocs"},required:["swagger","paths","info"]},a={properties:{$ref:{type:"string"},get:"Operation",put:"Operation",post:"Operation",delete:"Operation",options:"Operation",head:"Operation",patch:"Operation",parameters:r.listOf("Parameter")}},s={properties:{tags:{type:"array",items:{type:"string"}},summary:{type:"string"},description:{type:"string"},externalDocs:"ExternalDocs",operationId:{type:"string"},consumes:{type:"array",items:{type:"string"}},produces:{type:"array",items:{type:"string"}},parameters:r.listOf("Parameter"),responses:"ResponsesMap",schemes:{type:"array",items:{type:"string"}},deprecated:{type:"boolean"},security:r.listOf("SecurityRequirement"),"x-codeSamples":r.listOf("XCodeSample"),"x-code-samples":r.listOf("XCodeSample")},required:["responses"]},l={properties:{default:"Response"},additionalProperties:function(e,t){return o.test(t)?"Response":void 0}},c={properties:{description:{type:"string"},schema:"Schema",headers:r.mapOf("Header"),examples:"Examples"},required:["description"]},u={properties:{format:{type:"string"},title:{type:"string"},description:{type:"string"},default:null,multipleOf:{type:"number"},maximum:{type:"number"},minimum:{type:"number"},exclusiveMaximum:{type:"boolean"},exclusiveMinimum:{type:"boolean"},maxLength:{type:"number"},minLength:{type:"number"},pattern:{type:"string"},maxItems:{type:"number"},minItems:{type:"number"},uniqueItems:{type:"boolean"},maxProperties:{type:"number"},minProperties:{type:"number"},required:{type:"array",items:{type:"string"}},enum:{type:"array"},type:{type:"string",enum:["object","array","string","number","integer","boolean","null"]},items:function(e){return Array.isArray(e)?r.listOf("Schema"):"Schema"},allOf:r.listOf("Schema"),properties:"SchemaProperties",additionalProperties:function(e){return"boolean"==typeof e?{type:"boolean"}:"Schema"},discriminator:{type:"string"},readOnly:{type:"boolean"},xml:"Xml",externalDocs:"ExternalDocs",example:{isExample:!0}}};t.Oas2Types={DefinitionRoot:i,Tag:{properties:{name:{type:"string"},description:{type:"string"},externalDocs:"ExternalDocs"},required:["name"]},ExternalDocs:{properties:{description:{type:"string"},url:{type:"string"}},required:["url"]},SecurityRequirement:{properties:{},additionalProperties:{type:"array",items:{type:"string"}}},Info:{properties:{title:{type:"string"},description:{type:"string"},termsOfService:{type:"string"},contact:"Contact",license:"License",version:{type:"string"}},required:["title","version"]},Contact:{properties:{name:{type:"string"},url:{type:"string"},email:{type:"string"}}},License:{properties:{name:{type:"string"},url:{type:"string"}},required:["name"]},PathMap:{properties:{},additionalProperties:function(e,t){return t.startsWith("/")?"PathItem":void 0}},PathItem:a,Parameter:{properties:{name:{type:"string"},in:{type:"string",enum:["query","header","path","formData","body"]},description:{type:"string"},required:{type:"boolean"},schema:"Schema",type:{type:"string",enum:["string","number","integer","boolean","array","file"]},format:{type:"string"},allowEmptyValue:{type:"boolean"},items:"ParameterItems",collectionFormat:{type:"string",enum:["csv","ssv","tsv","pipes","multi"]},default:null,maximum:{type:"integer"},exclusiveMaximum:{type:"boolean"},minimum:{type:"integer"},exclusiveMinimum:{type:"boolean"},maxLength:{type:"integer"},minLength:{type:"integer"},pattern:{type:"string"},maxItems:{type:"integer"},minItems:{type:"integer"},uniqueItems:{type:"boolean"},enum:{type:"array"},multipleOf:{type:"number"}},required:function(e){return e&&e.in?"body"===e.in?["name","in","schema"]:"array"===e.type?["name","in","type","items"]:["name","in","type"]:["name","in"]}},ParameterItems:{properties:{type:{type:"string",enum:["string","number","integer","boolean","array"]},format:{type:"string"},items:"ParameterItems",collectionFormat:{type:"string",enum:["csv","ssv","tsv","pipes","multi"]},default:null,maximum:{type:"integer"},exclusiveMaximum:{type:"boolean"},minimum:{type:"integer"},exclusiveMinimum:{type:"boolean"},maxLength:{type:"integer"},minLength:{type:"integer"},pattern:{type:"string"},maxItems:{type:"integer"},minItems:{type:"integer"},uniqueItems:{type:"boolean"},enum:{type:"array"},multipleOf:{type:"number"}},required:function(e){return e&&"array"===e.type?["type","items"]:["type"]}},Operation:s,Examples:{properties:{},additionalProperties:{isExample:!0}},Header:{properties:{description:{type:"string"},type:{type:"string",enum:["string","number","integer","boolean","array"]},format:{type:"string"},items:"ParameterItems",collectionFormat:{type:"string",enum:["csv","ssv","tsv","pipes","multi"]},default:null,maximum:{type:"integer"},exclusiveMaximum:{type:"boolean"},minimum:{type:"integer"},exclusiveMinimum:{type:"boolean"},maxLength:{type:"integer"},minLength:{type:"integer"},pattern:{type:"string"},maxItems:{type:"integer"},minItems:{type:"integer"},uniqueItems:{type:"boolean"},enum:{type:"array"},multipleOf:{type:"number"}},required:function(e){return e&&"array"===e.type?["type","items"]:["type"]}},ResponsesMap:l,Response:c,Schema:u,Xml:{properties:{name:{type:"string"},namespace:{type:"string"},prefix:{type:"string"},attribute:{type:"boolean"},wrapped:{type:"boolean"}}},SchemaProperties:{properties:{},additionalProperties:"Schema"},NamedSchemas:r.mapOf("Schema"),NamedResponses:r.mapOf("Response"),NamedParameters:r.mapOf("Parameter"),NamedSecuritySchemes:r.mapOf("SecurityScheme"),SecurityScheme:{properties:{type:{enum:["basic","apiKey","oauth2"]},description:{type:"string"},name:{type:"string"},in:{type:"string",enum:["query","header","cookie"]},flow:{enum:["implicit","password","application","accessCode"]},authorizationUrl:{type:"string"},tokenUrl:{type:"string"},scopes:{type:"object",additionalProperties:{type:"string"}}},required:function(e){return(null==e?void 0:e.type)?"apiKey"===e.type?["type","name","in"]:"http"===e.type?["type","scheme"]:"oauth2"===e.type?(null==e?void 0:e.flow)?"implicit"===e.flow?["type","flow","authorizationUrl"]:"accessCode"===e.flow?["type","flow","authorizationUrl","tokenUrl"]:"application"===e.flow||"password"===e.flow?["type","flow","tokenUrl"]:["type","flow"]:["type","flow"]:["type"]:["type"]}},XCodeSample:{properties:{lang:{type:"string"},label:{type:"string"},source:{type:"string"}}}}},8553:function(e,t,n){"use strict";n(6755),Object.defineProperty(t,"__esModule",{value:!0}),t.Oas3Types=void 0;var r=n(9562),o=n(8140),i=/^[0-9][0-9Xx]{2}$/,a={properties:{openapi:null,info:"Info",servers:r.listOf("Server"),security:r.listOf("SecurityRequirement"),tags:r.listOf("Tag"),externalDocs:"ExternalDocs",paths:"PathMap",components:"Components","x-webhooks":"WebhooksMap"},required:["openapi","paths","info"]},s={properties:{url:{type:"string"},description:{type:"string"},variables:r.mapOf("ServerVariable")},required:["url"]},l={properties:{$ref:{type:"string"},servers:r.listOf("Server"),parameters:r.listOf("Parameter"),summary:{type:"string"},description:{type:"string"},get:"Operation",put:"Operation",post:"Operation",delete:"Operation",options:"Operation",head:"Operation",patch:"Operation",trace:"Operation"}},c={properties:{name:{type:"string"},in:{enum:["query","header","path","cookie"]},description:{type:"string"},required:{type:"boolean"},deprecated:{type:"boolean"},allowEmptyValue:{type:"boolean"},style:{enum:["form","simple","label","matrix","spaceDelimited","pipeDelimited","deepObject"]},explode:{type:"boolean"},allowReserved:{type:"boolean"},schema:"Schema",example:{isExample:!0},examples:r.mapOf("Example"),content:"MediaTypeMap"},required:["name","in"]},u={properties:{tags:{type:"array",items:{type:"string"}},summary:{type:"string"},description:{type:"string"},externalDocs:"ExternalDocs",operationId:{type:"string"},parameters:r.listOf("Parameter"),security:r.listOf("SecurityRequirement"),servers:r.listOf("Server"),requestBody:"RequestBody",responses:"ResponsesMap",deprecated:{type:"boolean"},callbacks:r.mapOf("Callback"),"x-codeSamples":r.listOf("XCodeSample"),"x-code-samples":r.listOf("XCodeSample")},required:["responses"]},p={properties:{schema:"Schema",example:{isExample:!0},examples:r.mapOf("Example"),encoding:r.mapOf("Encoding")}},f={properties:{contentType:{type:"string"},headers:r.mapOf("Header"),style:{enum:["form","simple","label","matrix","spaceDelimited","pipeDelimited","deepObject"]},explode:{type:"boolean"},allowReserved:{type:"boolean"}}},d={properties:{description:{type:"string"},required:{type:"boolean"},deprecated:{type:"boolean"},allowEmptyValue:{type:"boolean"},style:{enum:["form","simple","label","matrix","spaceDelimited","pipeDelimited","deepObject"]},explode:{type:"boolean"},allowReserved:{type:"boolean"},schema:"Schema",example:{isExample:!0},examples:r.mapOf("Example"),content:"MediaTypeMap"}},h={properties:{default:"Response"},additionalProperties:function(e,t){return i.test(t)?"Response":void 0}},m={properties:{description:{type:"string"},headers:r.mapOf("Header"),content:"MediaTypeMap",links:r.mapOf("Link")},required:["description"]},v={properties:{externalDocs:"ExternalDocs",discriminator:"Discriminator",title:{type:"string"},multipleOf:{type:"number"},maximum:{type:"number"},minimum:{type:"number"},exclusiveMaximum:{type:"boolean"},exclusiveMinimum:{type:"boolean"},maxLength:{type:"number"},minLength:{type:"number"},pattern:{type:"string"},maxItems:{type:"number"},minItems:{type:"number"},uniqueItems:{type:"boolean"},maxProperties:{type:"number"},minProperties:{type:"number"},required:{type:"array",items:{type:"string"}},enum:{type:"array"},type:{enum:["object","array","string","number","integer","boolean","null"]},allOf:r.listOf("Schema"),anyOf:r.listOf("Schema"),oneOf:r.listOf("Schema"),not:"Schema",properties:"SchemaProperties",items:function(e){return Array.isArray(e)?r.listOf("Schema"):"Schema"},additionalProperties:function(e){return"boolean"==typeof e?{type:"boolean"}:"Schema"},description:{type:"string"},format:{type:"string"},default:null,nullable:{type:"boolean"},readOnly:{type:"boolean"},writeOnly:{type:"boolean"},xml:"Xml",example:{isExample:!0},deprecated:{type:"boolean"}}},g={properties:{},additionalProperties:function(e){return o.isMappingRef(e)?{type:"string",directResolveAs:"Schema"}:{type:"string"}}};t.Oas3Types={DefinitionRoot:a,Tag:{properties:{name:{type:"string"},description:{type:"string"},externalDocs:"ExternalDocs"},required:["name"]},ExternalDocs:{properties:{description:{type:"string"},url:{type:"string"}},required:["url"]},Server:s,ServerVariable:{properties:{enum:{type:"array",items:{type:"string"}},default:{type:"string"},description:null},required:["default"]},SecurityRequirement:{properties:{},additionalProperties:{type:"array",items:{type:"string"}}},Info:{properties:{title:{type:"string"},version:{type:"string"},description:{type:"string"},termsOfService:{type:"string"},contact:"Contact",license:"License"},required:["title","version"]},Contact:{properties:{name:{type:"string"},url:{type:"string"},email:{type:"string"}}},License:{properties:{name:{type:"string"},url:{type:"string"}},required:["name"]},PathMap:{properties:{},additionalProperties:function(e,t){return t.startsWith("/")?"PathItem":void 0}},PathItem:l,Parameter:c,Operation:u,Callback:{properties:{},additionalProperties:"PathItem"},RequestBody:{properties:{description:{type:"string"},required:{type:"boolean"},content:"MediaTypeMap"},required:["content"]},MediaTypeMap:{properties:{},additionalProperties:"MediaType"},MediaType:p,Example:{properties:{value:{isExample:!0},summary:{type:"string"},description:{type:"string"},externalValue:{type:"string"}}},Encoding:f,Header:d,ResponsesMap:h,Response:m,Link:{properties:{operationRef:{type:"string"},operationId:{type:"string"},parameters:null,requestBody:null,description:{type:"string"},server:"Server"}},Schema:v,Xml:{properties:{name:{type:"string"},namespace:{type:"string"},prefix:{type:"string"},attribute:{type:"boolean"},wrapped:{type:"boolean"}}},SchemaProperties:{properties:{},additionalProperties:"Schema"},DiscriminatorMapping:g,Discriminator:{properties:{propertyName:{type:"string"},mapping:"DiscriminatorMapping"},required:["propertyName"]},Components:{properties:{parameters:"NamedParameters",schemas:"NamedSchemas",responses:"NamedResponses",examples:"NamedExamples",requestBodies:"NamedRequestBodies",headers:"NamedHeaders",securitySchemes:"NamedSecuritySchemes",links:"NamedLinks",callbacks:"NamedCallbacks"}},NamedSchemas:r.mapOf("Schema"),NamedResponses:r.mapOf("Response"),NamedParameters:r.mapOf("Parameter"),NamedExamples:r.mapOf("Example"),NamedRequestBodies:r.mapOf("RequestBody"),NamedHeaders:r.mapOf("Header"),NamedSecuritySchemes:r.mapOf("SecurityScheme"),NamedLinks:r.mapOf("Link"),NamedCallbacks:r.mapOf("PathItem"),ImplicitFlow:{properties:{refreshUrl:{type:"string"},scopes:{type:"object",additionalProperties:{type:"string"}},authorizationUrl:{type:"string"}},required:["authorizationUrl","scopes"]},PasswordFlow:{properties:{refreshUrl:{type:"string"},scopes:{type:"object",additionalProperties:{type:"string"}},tokenUrl:{type:"string"}},required:["tokenUrl","scopes"]},ClientCredentials:{properties:{refreshUrl:{type:"string"},scopes:{type:"object",additionalProperties:{type:"string"}},tokenUrl:{type:"string"}},required:["tokenUrl","scopes"]},AuthorizationCode:{properties:{refreshUrl:{type:"string"},authorizationUrl:{type:"string"},scopes:{type:"object",additionalProperties:{type:"string"}},tokenUrl:{type:"string"}},required:["authorizationUrl","tokenUrl","scopes"]},SecuritySchemeFlows:{properties:{implicit:"ImplicitFlow",password:"PasswordFlow",clientCredentials:"ClientCredentials",authorizationCode:"AuthorizationCode"}},SecurityScheme:{properties:{type:{enum:["apiKey","http","oauth2","openIdConnect"]},description:{type:"string"},name:{type:"string"},in:{type:"string"},scheme:{type:"string"},bearerFormat:{type:"string"},flows:"SecuritySchemeFlows",openIdConnectUrl:{type:"string"}},required:function(e){return(null==e?void 0:e.type)?"apiKey"===e.type?["type","name","in"]:"http"===e.type?["type","scheme"]:"oauth2"===e.type?["type","flows"]:"openIdConnect"===e.type?["type","openIdConnectUrl"]:["type"]:["type"]}},XCodeSample:{properties:{lang:{type:"string"},label:{type:"string"},source:{type:"string"}}},WebhooksMap:{properties:{},additionalProperties:function(){return"PathItem"}}}},7649:function(e,t,n){"use strict";n(9601),Object.defineProperty(t,"__esModule",{value:!0}),t.Oas3_1Types=void 0;var r=n(9562),o=n(8553),i={properties:{openapi:null,info:"Info",servers:r.listOf("Server"),security:r.listOf("SecurityRequirement"),tags:r.listOf("Tag"),externalDocs:"ExternalDocs",paths:"PathMap",webhooks:"WebhooksMap",components:"Components",jsonSchemaDialect:{type:"string"}},required:["openapi","info"],requiredOneOf:["paths","components","webhooks"]},a={properties:{tags:{type:"array",items:{type:"string"}},summary:{type:"string"},description:{type:"string"},externalDocs:"ExternalDocs",operationId:{type:"string"},parameters:r.listOf("Parameter"),security:r.listOf("SecurityRequirement"),servers:r.listOf("Server"),requestBody:"RequestBody",responses:"ResponsesMap",deprecated:{type:"boolean"},callbacks:r.mapOf("Callback"),"x-codeSamples":r.listOf("XCodeSample"),"x-code-samples":r.listOf("XCodeSample")}},s={properties:{externalDocs:"ExternalDocs",discriminator:"Discriminator",myArbitraryKeyword:{type:"boolean"},title:{type:"string"},multipleOf:{type:"number"},maximum:{type:"number"},minimum:{type:"number"},exclusiveMaximum:{type:"number"},exclusiveMinimum:{type:"number"},maxLength:{type:"number"},minLength:{type:"number"},pattern:{type:"string"},maxItems:{type:"number"},minItems:{type:"number"},uniqueItems:{type:"boolean"},maxProperties:{type:"number"},minProperties:{type:"number"},required:{type:"array",items:{type:"string"}},enum:{type:"array"},type:function(e){return Array.isArray(e)?{type:"array",items:{enum:["object","array","string","number","integer","boolean","null"]}}:{enum:["object","array","string","number","integer","boolean","null"]}},allOf:r.listOf("Schema"),anyOf:r.listOf("Schema"),oneOf:r.listOf("Schema"),not:"Schema",if:"Schema",then:"Schema",else:"Schema",dependentSchemas:r.listOf("Schema"),prefixItems:{type:"array"},contains:"Schema",patternProperties:{type:"object"},propertyNames:"Schema",unevaluatedItems:"Schema",unevaluatedProperties:"Schema",summary:{type:"string"},properties:"SchemaProperties",items:function(e){return Array.isArray(e)?r.listOf("Schema"):"Schema"},additionalProperties:function(e){return"boolean"==typeof e?{type:"boolean"}:"Schema"},description:{type:"string"},format:{type:"string"},contentEncoding:{type:"string"},contentMediaType:{type:"string"},default:null,readOnly:{type:"boolean"},writeOnly:{type:"boolean"},xml:"Xml",examples:{type:"array"},example:{isExample:!0},deprecated:{type:"boolean"},const:{enum:["object","array","string","number","integer","boolean","null"]},$comment:{type:"string"}}};t.Oas3_1Types=Object.assign(Object.assign({},o.Oas3Types),{Info:{properties:{title:{type:"string"},version:{type:"string"},description:{type:"string"},termsOfService:{type:"string"},summary:{type:"string"},contact:"Contact",license:"License"},required:["title","version"]},DefinitionRoot:i,Schema:s,License:{properties:{name:{type:"string"},url:{type:"string"},identifier:{type:"string"}},required:["name"]},Components:{properties:{parameters:"NamedParameters",schemas:"NamedSchemas",responses:"NamedResponses",examples:"NamedExamples",requestBodies:"NamedRequestBodies",headers:"NamedHeaders",securitySchemes:"NamedSecuritySchemes",links:"NamedLinks",callbacks:"NamedCallbacks",pathItems:"NamedPathItems"}},NamedPathItems:r.mapOf("PathItem"),SecurityScheme:{properties:{type:{enum:["apiKey","http","oauth2","openIdConnect","mutualTLS"]},description:{type:"string"},name:{type:"string"},in:{type:"string"},scheme:{type:"string"},bearerFormat:{type:"string"},flows:"SecuritySchemeFlows",openIdConnectUrl:{type:"string"}},required:function(e){return(null==e?void 0:e.type)?"apiKey"===e.type?["type","name","in"]:"http"===e.type?["type","scheme"]:"oauth2"===e.type?["type","flows"]:"openIdConnect"===e.type?["type","openIdConnectUrl"]:["type"]:["type"]}},Operation:a})},9272:function(e,t,n){"use strict";var r=n(3269).default,o=n(7757);n(1539),n(8674),n(4916),n(4723),n(2222),n(8309),n(5306);var i=this&&this.__awaiter||function(e,t,n,r){return new(n||(n=Promise))((function(o,i){function a(e){try{l(r.next(e))}catch(e){i(e)}}function s(e){try{l(r.throw(e))}catch(e){i(e)}}function l(e){var t;e.done?o(e.value):(t=e.value,t instanceof n?t:new n((function(e){e(t)}))).then(a,s)}l((r=r.apply(e,t||[])).next())}))};Object.defineProperty(t,"__esModule",{value:!0}),t.match=t.readFileFromUrl=t.isPlainObject=t.notUndefined=t.loadYaml=t.popStack=t.pushStack=void 0;var a=n(4756),s=n(3817),l=n(1171),c=n(7707);function u(e,t){return t.match(/^https?:\/\//)||(e=e.replace(/^https?:\/\//,"")),l(e,t)}t.pushStack=function(e,t){return{prev:e,value:t}},t.popStack=function(e){var t;return null!==(t=null==e?void 0:e.prev)&&void 0!==t?t:null},t.loadYaml=function(e){return i(this,void 0,void 0,o.mark((function t(){var n;return o.wrap((function(t){for(;;)switch(t.prev=t.next){case 0:return t.next=2,s.promises.readFile(e,"utf-8");case 2:return n=t.sent,t.abrupt("return",a.safeLoad(n));case 4:case"end":return t.stop()}}),t)})))},t.notUndefined=function(e){return void 0!==e},t.isPlainObject=function(e){return null!==e&&"object"==typeof e&&!Array.isArray(e)},t.readFileFromUrl=function(e,t){return i(this,void 0,void 0,o.mark((function n(){var i,a,s,l,p;return o.wrap((function(n){for(;;)switch(n.prev=n.next){case 0:i={},a=r(t.headers);try{for(a.s();!(s=a.n()).done;)l=s.value,u(e,l.matches)&&(i[l.name]=void 0!==l.envVariable?{}[l.envVariable]||"":l.value)}catch(e){a.e(e)}finally{a.f()}return n.next=5,(t.customFetch||c.default)(e,{headers:i});case 5:if((p=n.sent).ok){n.next=8;break}throw new Error("Failed to load ".concat(e,": ").concat(p.status," ").concat(p.statusText));case 8:return n.next=10,p.text();case 10:return n.t0=n.sent,n.t1=p.headers.get("content-type"),n.abrupt("return",{body:n.t0,mimeType:n.t1});case 13:case"end":return n.stop()}}),n)})))},t.match=u},9079:function(e,t,n){"use strict";var r=n(319).default,o=n(3269).default;n(7941),n(2707),n(6699),n(2023),n(2222),n(6992),n(1539),n(189),n(8783),n(3948),n(2479),n(8309),n(1038),n(7042),n(9601),Object.defineProperty(t,"__esModule",{value:!0}),t.normalizeVisitors=void 0,t.normalizeVisitors=function(e,t){for(var n={any:{enter:[],leave:[]}},i=0,a=Object.keys(t);i<a.length;i++){var s=a[i];n[s]={enter:[],leave:[]}}n.ref={enter:[],leave:[]};var l,c=o(e);try{for(c.s();!(l=c.n()).done;){var u=l.value;m({ruleId:u.ruleId,severity:u.severity},u.visitor,null)}}catch(e){c.e(e)}finally{c.f()}for(var p=0,f=Object.keys(n);p<f.length;p++){var d=f[p];n[d].enter.sort((function(e,t){return t.depth-e.depth})),n[d].leave.sort((function(e,t){return e.depth-t.depth}))}return n;function h(e,t,i,a){var s=arguments.length>4&&void 0!==arguments[4]?arguments[4]:[];if(!s.includes(t)){s=[].concat(r(s),[t]);for(var l=new Set,c=0,u=Object.values(t.properties);c<u.length;c++){var p=u[c];p!==i?"object"==typeof p&&null!==p&&p.name&&l.add(p):v(e,s)}t.additionalProperties&&"function"!=typeof t.additionalProperties&&(t.additionalProperties===i?v(e,s):void 0!==t.additionalProperties.name&&l.add(t.additionalProperties)),t.items&&(t.items===i?v(e,s):void 0!==t.items.name&&l.add(t.items));for(var f=0,d=Array.from(l.values());f<d.length;f++){var m=d[f];h(e,m,i,a,s)}}function v(e,t){var r,i=o(t.slice(1));try{for(i.s();!(r=i.n()).done;){var s=r.value;n[s.name]=n[s.name]||{enter:[],leave:[]},n[s.name].enter.push(Object.assign(Object.assign({},e),{visit:function(){},depth:0,context:{isSkippedLevel:!0,seen:new Set,parent:a}}))}}catch(e){i.e(e)}finally{i.f()}}}function m(e,r,o){var i=arguments.length>3&&void 0!==arguments[3]?arguments[3]:0,a=Object.keys(t);if(0===i)a.push("any"),a.push("ref");else{if(r.any)throw new Error("any() is allowed only on top level");if(r.ref)throw new Error("ref() is allowed only on top level")}for(var s=0,l=a;s<l.length;s++){var c=l[s],u=r[c],p=n[c];if(u){var f=void 0,d=void 0,v=void 0,g="object"==typeof u;if("ref"===c&&g&&u.skip)throw new Error("ref() visitor does not support skip");"function"==typeof u?f=u:g&&(f=u.enter,d=u.leave,v=u.skip);var y={activatedOn:null,type:t[c],parent:o,isSkippedLevel:!1};if("object"==typeof u&&m(e,u,y,i+1),o&&h(e,o.type,t[c],o),f||g){if(f&&"function"!=typeof f)throw new Error("DEV: should be function");p.enter.push(Object.assign(Object.assign({},e),{visit:f||function(){},skip:v,depth:i,context:y}))}if(d){if("function"!=typeof d)throw new Error("DEV: should be function");p.leave.push(Object.assign(Object.assign({},e),{visit:d,depth:i,context:y}))}}}}}},3353:function(e,t,n){"use strict";var r=n(319).default,o=n(3269).default;n(8309),n(6992),n(1539),n(189),n(8783),n(3948),n(2222),n(7941),n(7327),n(6699),n(2023),n(9601),n(1249),Object.defineProperty(t,"__esModule",{value:!0}),t.walkDocument=void 0;var i=n(8140),a=n(8604),s=n(9272),l=n(9562);function c(e){for(var t,n,r={};e.parent;)(null===(t=e.parent.activatedOn)||void 0===t?void 0:t.value.location)&&(r[e.parent.type.name]=null===(n=e.parent.activatedOn)||void 0===n?void 0:n.value.location),e=e.parent;return r}t.walkDocument=function(e){var t=e.document,n=e.rootType,u=e.normalizedVisitors,p=e.resolvedRefMap,f=e.ctx,d={},h=new Set;!function e(t,n,m,v,g){var y,b,x,w,k,E,S,_,O,A,R,C=m,T=Le(t),P=T.node,I=T.location,j=T.error,L=new Set;if(i.isRef(t)){var N,M=u.ref.enter,D=o(M);try{for(D.s();!(N=D.n()).done;){var F=N.value,z=F.visit,U=F.ruleId,B=F.severity,$=F.context;if(!h.has(t))L.add($),z(t,{report:Ne.bind(void 0,U,B),resolve:Le,location:m,type:n,parent:v,key:g,parentLocations:{},oasVersion:f.oasVersion},{node:P,location:I,error:j})}}catch(e){D.e(e)}finally{D.f()}}if(void 0!==P&&I&&"scalar"!==n.name){C=I;var q,W=null===(b=null===(y=d[n.name])||void 0===y?void 0:y.has)||void 0===b?void 0:b.call(y,P),V=!1,H=u.any.enter.concat((null===(x=u[n.name])||void 0===x?void 0:x.enter)||[]),Y=[],G=o(H);try{for(G.s();!(q=G.n()).done;){var Q=q.value,X=Q.context,K=Q.visit,Z=Q.skip,J=Q.ruleId,ee=Q.severity;if(X.isSkippedLevel)!X.parent.activatedOn||X.parent.activatedOn.value.nextLevelTypeActivated||X.seen.has(t)||(X.seen.add(t),V=!0,Y.push(X));else if(X.parent&&X.parent.activatedOn&&(null===(w=X.activatedOn)||void 0===w?void 0:w.value.withParentNode)!==X.parent.activatedOn.value.node&&(null===(k=X.parent.activatedOn.value.nextLevelTypeActivated)||void 0===k?void 0:k.value)!==n||!X.parent&&!W){Y.push(X);var te={node:P,location:I,nextLevelTypeActivated:null,withParentNode:null===(S=null===(E=X.parent)||void 0===E?void 0:E.activatedOn)||void 0===S?void 0:S.value.node,skipped:null!==(A=(null===(O=null===(_=X.parent)||void 0===_?void 0:_.activatedOn)||void 0===O?void 0:O.value.skipped)||(null==Z?void 0:Z(P,g)))&&void 0!==A&&A};X.activatedOn=s.pushStack(X.activatedOn,te);for(var ne=X.parent;ne;)ne.activatedOn.value.nextLevelTypeActivated=s.pushStack(ne.activatedOn.value.nextLevelTypeActivated,n),ne=ne.parent;if(!te.skipped&&(V=!0,L.add(X),je(K,P,X,J,ee)))break}}}catch(e){G.e(e)}finally{G.f()}if(V||!W)if(d[n.name]=d[n.name]||new Set,d[n.name].add(P),Array.isArray(P)){var re=n.items;if(void 0!==re)for(var oe=0;oe<P.length;oe++)e(P[oe],re,I.child([oe]),P,oe)}else if("object"==typeof P&&null!==P){var ie=Object.keys(n.properties);n.additionalProperties&&ie.push.apply(ie,r(Object.keys(P).filter((function(e){return!ie.includes(e)})))),i.isRef(t)&&ie.push.apply(ie,r(Object.keys(t).filter((function(e){return"$ref"!==e&&!ie.includes(e)}))));for(var ae=0,se=ie;ae<se.length;ae++){var le=se[ae],ce=P[le],ue=I;void 0===ce&&(ce=t[le],ue=m);var pe=n.properties[le];void 0===pe&&(pe=n.additionalProperties),"function"==typeof pe&&(pe=pe(ce,le)),!l.isNamedType(pe)&&(null==pe?void 0:pe.directResolveAs)&&(pe=pe.directResolveAs,ce={$ref:ce}),pe&&void 0===pe.name&&!1!==pe.resolvable&&(pe={name:"scalar",properties:{}}),l.isNamedType(pe)&&("scalar"!==pe.name||i.isRef(ce))&&e(ce,pe,ue.child([le]),P,le)}}var fe,de=u.any.leave,he=((null===(R=u[n.name])||void 0===R?void 0:R.leave)||[]).concat(de),me=o(Y.reverse());try{for(me.s();!(fe=me.n()).done;){var ve=fe.value;if(ve.isSkippedLevel)ve.seen.delete(P);else if(ve.activatedOn=s.popStack(ve.activatedOn),ve.parent)for(var ge=ve.parent;ge;)ge.activatedOn.value.nextLevelTypeActivated=s.popStack(ge.activatedOn.value.nextLevelTypeActivated),ge=ge.parent}}catch(e){me.e(e)}finally{me.f()}var ye,be=o(he);try{for(be.s();!(ye=be.n()).done;){var xe=ye.value,we=xe.context,ke=xe.visit,Ee=xe.ruleId,Se=xe.severity;!we.isSkippedLevel&&L.has(we)&&je(ke,P,we,Ee,Se)}}catch(e){be.e(e)}finally{be.f()}}if(C=m,i.isRef(t)){var _e,Oe=u.ref.leave,Ae=o(Oe);try{for(Ae.s();!(_e=Ae.n()).done;){var Re=_e.value,Ce=Re.visit,Te=Re.ruleId,Pe=Re.severity,Ie=Re.context;if(L.has(Ie))Ce(t,{report:Ne.bind(void 0,Te,Pe),resolve:Le,location:m,type:n,parent:v,key:g,parentLocations:{},oasVersion:f.oasVersion},{node:P,location:I,error:j})}}catch(e){Ae.e(e)}finally{Ae.f()}}function je(e,t,r,o,i){var a=Ne.bind(void 0,o,i),s=!1;return e(t,{report:a,resolve:Le,location:C,type:n,parent:v,key:g,parentLocations:c(r),oasVersion:f.oasVersion,ignoreNextVisitorsOnNode:function(){s=!0}},function(e){for(var t,n={};e.parent;)n[e.parent.type.name]=null===(t=e.parent.activatedOn)||void 0===t?void 0:t.value.node,e=e.parent;return n}(r),r),s}function Le(e){var t=arguments.length>1&&void 0!==arguments[1]?arguments[1]:C.source.absoluteRef;if(!i.isRef(e))return{location:m,node:e};var n=t+"::"+e.$ref,r=p.get(n);if(!r)return{location:void 0,node:void 0};var o=r.resolved,s=r.node,l=r.document,c=r.nodePointer,u=r.error,f=o?new i.Location(l.source,c):u instanceof a.YamlParseError?new i.Location(u.source,""):void 0;return{location:f,node:s,error:u}}function Ne(e,t,n){var r=n.location?Array.isArray(n.location)?n.location:[n.location]:[Object.assign(Object.assign({},C),{reportOnKey:!1})];f.problems.push(Object.assign(Object.assign({ruleId:e,severity:n.forceSeverity||t},n),{suggest:n.suggest||[],location:r.map((function(e){return Object.assign(Object.assign(Object.assign({},C),{reportOnKey:!1}),e)}))}))}}(t.parsed,n,new i.Location(t.source,"#/"),void 0,"")}},4756:function(e,t,n){"use strict";var r=n(9439);e.exports=r},9439:function(e,t,n){"use strict";var r=n(5143),o=n(9084);function i(e){return function(){throw new Error("Function "+e+" is deprecated and cannot be used.")}}e.exports.Type=n(889),e.exports.Schema=n(304),e.exports.FAILSAFE_SCHEMA=n(4801),e.exports.JSON_SCHEMA=n(2437),e.exports.CORE_SCHEMA=n(9533),e.exports.DEFAULT_SAFE_SCHEMA=n(7265),e.exports.DEFAULT_FULL_SCHEMA=n(1493),e.exports.load=r.load,e.exports.loadAll=r.loadAll,e.exports.safeLoad=r.safeLoad,e.exports.safeLoadAll=r.safeLoadAll,e.exports.dump=o.dump,e.exports.safeDump=o.safeDump,e.exports.YAMLException=n(2188),e.exports.MINIMAL_SCHEMA=n(4801),e.exports.SAFE_SCHEMA=n(7265),e.exports.DEFAULT_SCHEMA=n(1493),e.exports.scan=i("scan"),e.exports.parse=i("parse"),e.exports.compose=i("compose"),e.exports.addConstructor=i("addConstructor")},910:function(e,t,n){"use strict";function r(e){return null==e}n(7941),n(9653),n(2481),e.exports.isNothing=r,e.exports.isObject=function(e){return"object"==typeof e&&null!==e},e.exports.toArray=function(e){return Array.isArray(e)?e:r(e)?[]:[e]},e.exports.repeat=function(e,t){var n,r="";for(n=0;n<t;n+=1)r+=e;return r},e.exports.isNegativeZero=function(e){return 0===e&&Number.NEGATIVE_INFINITY===1/e},e.exports.extend=function(e,t){var n,r,o,i;if(t)for(n=0,r=(i=Object.keys(t)).length;n<r;n+=1)e[o=i[n]]=t[o];return e}},9084:function(e){e.exports=function(){}},2188:function(e,t,n){"use strict";function r(e,t){Error.call(this),this.name="YAMLException",this.reason=e,this.mark=t,this.message=(this.reason||"(unknown reason)")+(this.mark?" "+this.mark.toString():""),Error.captureStackTrace?Error.captureStackTrace(this,this.constructor):this.stack=(new Error).stack||""}n(8309),n(1539),n(9714),r.prototype=Object.create(Error.prototype),r.prototype.constructor=r,r.prototype.toString=function(e){var t=this.name+": ";return t+=this.reason||"(unknown reason)",!e&&this.mark&&(t+=" "+this.mark.toString()),t},e.exports=r},5143:function(e,t,n){"use strict";n(1539),n(4916),n(7042),n(7941),n(2481),n(5218);var r=n(910),o=n(2188),i=n(5443),a=n(7265),s=n(1493),l=Object.prototype.hasOwnProperty,c=/[\x00-\x08\x0B\x0C\x0E-\x1F\x7F-\x84\x86-\x9F\uFFFE\uFFFF]|[\uD800-\uDBFF](?![\uDC00-\uDFFF])|(?:[^\uD800-\uDBFF]|^)[\uDC00-\uDFFF]/,u=/[\x85\u2028\u2029]/,p=/[,\[\]\{\}]/,f=/^(?:!|!!|![a-z\-]+!)$/i,d=/^(?:!|[^,\[\]\{\}])(?:%[0-9a-f]{2}|[0-9a-z\-#;\/\?:@&=\+\$,_\.!~\*'\(\)\[\]])*$/i;function h(e){return Object.prototype.toString.call(e)}function m(e){return 10===e||13===e}function v(e){return 9===e||32===e}function g(e){return 9===e||32===e||10===e||13===e}function y(e){return 44===e||91===e||93===e||123===e||125===e}function b(e){var t;return 48<=e&&e<=57?e-48:97<=(t=32|e)&&t<=102?t-97+10:-1}function x(e){return 48===e?"\0":97===e?"":98===e?"\b":116===e||9===e?"\t":110===e?"\n":118===e?"\v":102===e?"\f":114===e?"\r":101===e?"":32===e?" ":34===e?'"':47===e?"/":92===e?"\\":78===e?"":95===e?" ":76===e?"\u2028":80===e?"\u2029":""}function w(e){return e<=65535?String.fromCharCode(e):String.fromCharCode(55296+(e-65536>>10),56320+(e-65536&1023))}for(var k=new Array(256),E=new Array(256),S=0;S<256;S++)k[S]=x(S)?1:0,E[S]=x(S);function _(e,t){this.input=e,this.filename=t.filename||null,this.schema=t.schema||s,this.onWarning=t.onWarning||null,this.legacy=t.legacy||!1,this.json=t.json||!1,this.listener=t.listener||null,this.implicitTypes=this.schema.compiledImplicit,this.typeMap=this.schema.compiledTypeMap,this.length=e.length,this.position=0,this.line=0,this.lineStart=0,this.lineIndent=0,this.documents=[]}function O(e,t){return new o(t,new i(e.filename,e.input,e.position,e.line,e.position-e.lineStart))}function A(e,t){throw O(e,t)}function R(e,t){e.onWarning&&e.onWarning.call(null,O(e,t))}var C={YAML:function(e,t,n){var r,o,i;null!==e.version&&A(e,"duplication of %YAML directive"),1!==n.length&&A(e,"YAML directive accepts exactly one argument"),null===(r=/^([0-9]+)\.([0-9]+)$/.exec(n[0]))&&A(e,"ill-formed argument of the YAML directive"),o=parseInt(r[1],10),i=parseInt(r[2],10),1!==o&&A(e,"unacceptable YAML version of the document"),e.version=n[0],e.checkLineBreaks=i<2,1!==i&&2!==i&&R(e,"unsupported YAML version of the document")},TAG:function(e,t,n){var r,o;2!==n.length&&A(e,"TAG directive accepts exactly two arguments"),r=n[0],o=n[1],f.test(r)||A(e,"ill-formed tag handle (first argument) of the TAG directive"),l.call(e.tagMap,r)&&A(e,'there is a previously declared suffix for "'+r+'" tag handle'),d.test(o)||A(e,"ill-formed tag prefix (second argument) of the TAG directive"),e.tagMap[r]=o}};function T(e,t,n,r){var o,i,a,s;if(t<n){if(s=e.input.slice(t,n),r)for(o=0,i=s.length;o<i;o+=1)9===(a=s.charCodeAt(o))||32<=a&&a<=1114111||A(e,"expected valid JSON character");else c.test(s)&&A(e,"the stream contains non-printable characters");e.result+=s}}function P(e,t,n,o){var i,a,s,c;for(r.isObject(n)||A(e,"cannot merge mappings; the provided source object is unacceptable"),s=0,c=(i=Object.keys(n)).length;s<c;s+=1)a=i[s],l.call(t,a)||(t[a]=n[a],o[a]=!0)}function I(e,t,n,r,o,i,a,s){var c,u;if(Array.isArray(o))for(c=0,u=(o=Array.prototype.slice.call(o)).length;c<u;c+=1)Array.isArray(o[c])&&A(e,"nested arrays are not supported inside keys"),"object"==typeof o&&"[object Object]"===h(o[c])&&(o[c]="[object Object]");if("object"==typeof o&&"[object Object]"===h(o)&&(o="[object Object]"),o=String(o),null===t&&(t={}),"tag:yaml.org,2002:merge"===r)if(Array.isArray(i))for(c=0,u=i.length;c<u;c+=1)P(e,t,i[c],n);else P(e,t,i,n);else e.json||l.call(n,o)||!l.call(t,o)||(e.line=a||e.line,e.position=s||e.position,A(e,"duplicated mapping key")),t[o]=i,delete n[o];return t}function j(e){var t;10===(t=e.input.charCodeAt(e.position))?e.position++:13===t?(e.position++,10===e.input.charCodeAt(e.position)&&e.position++):A(e,"a line break is expected"),e.line+=1,e.lineStart=e.position}function L(e,t,n){for(var r=0,o=e.input.charCodeAt(e.position);0!==o;){for(;v(o);)o=e.input.charCodeAt(++e.position);if(t&&35===o)do{o=e.input.charCodeAt(++e.position)}while(10!==o&&13!==o&&0!==o);if(!m(o))break;for(j(e),o=e.input.charCodeAt(e.position),r++,e.lineIndent=0;32===o;)e.lineIndent++,o=e.input.charCodeAt(++e.position)}return-1!==n&&0!==r&&e.lineIndent<n&&R(e,"deficient indentation"),r}function N(e){var t,n=e.position;return!(45!==(t=e.input.charCodeAt(n))&&46!==t||t!==e.input.charCodeAt(n+1)||t!==e.input.charCodeAt(n+2)||(n+=3,0!==(t=e.input.charCodeAt(n))&&!g(t)))}function M(e,t){1===t?e.result+=" ":t>1&&(e.result+=r.repeat("\n",t-1))}function D(e,t){var n,r,o=e.tag,i=e.anchor,a=[],s=!1;for(null!==e.anchor&&(e.anchorMap[e.anchor]=a),r=e.input.charCodeAt(e.position);0!==r&&45===r&&g(e.input.charCodeAt(e.position+1));)if(s=!0,e.position++,L(e,!0,-1)&&e.lineIndent<=t)a.push(null),r=e.input.charCodeAt(e.position);else if(n=e.line,U(e,t,3,!1,!0),a.push(e.result),L(e,!0,-1),r=e.input.charCodeAt(e.position),(e.line===n||e.lineIndent>t)&&0!==r)A(e,"bad indentation of a sequence entry");else if(e.lineIndent<t)break;return!!s&&(e.tag=o,e.anchor=i,e.kind="sequence",e.result=a,!0)}function F(e){var t,n,r,o,i=!1,a=!1;if(33!==(o=e.input.charCodeAt(e.position)))return!1;if(null!==e.tag&&A(e,"duplication of a tag property"),60===(o=e.input.charCodeAt(++e.position))?(i=!0,o=e.input.charCodeAt(++e.position)):33===o?(a=!0,n="!!",o=e.input.charCodeAt(++e.position)):n="!",t=e.position,i){do{o=e.input.charCodeAt(++e.position)}while(0!==o&&62!==o);e.position<e.length?(r=e.input.slice(t,e.position),o=e.input.charCodeAt(++e.position)):A(e,"unexpected end of the stream within a verbatim tag")}else{for(;0!==o&&!g(o);)33===o&&(a?A(e,"tag suffix cannot contain exclamation marks"):(n=e.input.slice(t-1,e.position+1),f.test(n)||A(e,"named tag handle cannot contain such characters"),a=!0,t=e.position+1)),o=e.input.charCodeAt(++e.position);r=e.input.slice(t,e.position),p.test(r)&&A(e,"tag suffix cannot contain flow indicator characters")}return r&&!d.test(r)&&A(e,"tag name cannot contain such characters: "+r),i?e.tag=r:l.call(e.tagMap,n)?e.tag=e.tagMap[n]+r:"!"===n?e.tag="!"+r:"!!"===n?e.tag="tag:yaml.org,2002:"+r:A(e,'undeclared tag handle "'+n+'"'),!0}function z(e){var t,n;if(38!==(n=e.input.charCodeAt(e.position)))return!1;for(null!==e.anchor&&A(e,"duplication of an anchor property"),n=e.input.charCodeAt(++e.position),t=e.position;0!==n&&!g(n)&&!y(n);)n=e.input.charCodeAt(++e.position);return e.position===t&&A(e,"name of an anchor node must contain at least one character"),e.anchor=e.input.slice(t,e.position),!0}function U(e,t,n,o,i){var a,s,c,u,p,f,d,h,x=1,S=!1,_=!1;if(null!==e.listener&&e.listener("open",e),e.tag=null,e.anchor=null,e.kind=null,e.result=null,a=s=c=4===n||3===n,o&&L(e,!0,-1)&&(S=!0,e.lineIndent>t?x=1:e.lineIndent===t?x=0:e.lineIndent<t&&(x=-1)),1===x)for(;F(e)||z(e);)L(e,!0,-1)?(S=!0,c=a,e.lineIndent>t?x=1:e.lineIndent===t?x=0:e.lineIndent<t&&(x=-1)):c=!1;if(c&&(c=S||i),1!==x&&4!==n||(d=1===n||2===n?t:t+1,h=e.position-e.lineStart,1===x?c&&(D(e,h)||function(e,t,n){var r,o,i,a,s,l=e.tag,c=e.anchor,u={},p={},f=null,d=null,h=null,m=!1,y=!1;for(null!==e.anchor&&(e.anchorMap[e.anchor]=u),s=e.input.charCodeAt(e.position);0!==s;){if(r=e.input.charCodeAt(e.position+1),i=e.line,a=e.position,63!==s&&58!==s||!g(r)){if(!U(e,n,2,!1,!0))break;if(e.line===i){for(s=e.input.charCodeAt(e.position);v(s);)s=e.input.charCodeAt(++e.position);if(58===s)g(s=e.input.charCodeAt(++e.position))||A(e,"a whitespace character is expected after the key-value separator within a block mapping"),m&&(I(e,u,p,f,d,null),f=d=h=null),y=!0,m=!1,o=!1,f=e.tag,d=e.result;else{if(!y)return e.tag=l,e.anchor=c,!0;A(e,"can not read an implicit mapping pair; a colon is missed")}}else{if(!y)return e.tag=l,e.anchor=c,!0;A(e,"can not read a block mapping entry; a multiline key may not be an implicit key")}}else 63===s?(m&&(I(e,u,p,f,d,null),f=d=h=null),y=!0,m=!0,o=!0):m?(m=!1,o=!0):A(e,"incomplete explicit mapping pair; a key node is missed; or followed by a non-tabulated empty line"),e.position+=1,s=r;if((e.line===i||e.lineIndent>t)&&(U(e,t,4,!0,o)&&(m?d=e.result:h=e.result),m||(I(e,u,p,f,d,h,i,a),f=d=h=null),L(e,!0,-1),s=e.input.charCodeAt(e.position)),e.lineIndent>t&&0!==s)A(e,"bad indentation of a mapping entry");else if(e.lineIndent<t)break}return m&&I(e,u,p,f,d,null),y&&(e.tag=l,e.anchor=c,e.kind="mapping",e.result=u),y}(e,h,d))||function(e,t){var n,r,o,i,a,s,l,c,u,p,f=!0,d=e.tag,h=e.anchor,m={};if(91===(p=e.input.charCodeAt(e.position)))o=93,s=!1,r=[];else{if(123!==p)return!1;o=125,s=!0,r={}}for(null!==e.anchor&&(e.anchorMap[e.anchor]=r),p=e.input.charCodeAt(++e.position);0!==p;){if(L(e,!0,t),(p=e.input.charCodeAt(e.position))===o)return e.position++,e.tag=d,e.anchor=h,e.kind=s?"mapping":"sequence",e.result=r,!0;f||A(e,"missed comma between flow collection entries"),u=null,i=a=!1,63===p&&g(e.input.charCodeAt(e.position+1))&&(i=a=!0,e.position++,L(e,!0,t)),n=e.line,U(e,t,1,!1,!0),c=e.tag,l=e.result,L(e,!0,t),p=e.input.charCodeAt(e.position),!a&&e.line!==n||58!==p||(i=!0,p=e.input.charCodeAt(++e.position),L(e,!0,t),U(e,t,1,!1,!0),u=e.result),s?I(e,r,m,c,l,u):i?r.push(I(e,null,m,c,l,u)):r.push(l),L(e,!0,t),44===(p=e.input.charCodeAt(e.position))?(f=!0,p=e.input.charCodeAt(++e.position)):f=!1}A(e,"unexpected end of the stream within a flow collection")}(e,d)?_=!0:(s&&function(e,t){var n,o,i,a,s,l=1,c=!1,u=!1,p=t,f=0,d=!1;if(124===(a=e.input.charCodeAt(e.position)))o=!1;else{if(62!==a)return!1;o=!0}for(e.kind="scalar",e.result="";0!==a;)if(43===(a=e.input.charCodeAt(++e.position))||45===a)1===l?l=43===a?3:2:A(e,"repeat of a chomping mode identifier");else{if(!((i=48<=(s=a)&&s<=57?s-48:-1)>=0))break;0===i?A(e,"bad explicit indentation width of a block scalar; it cannot be less than one"):u?A(e,"repeat of an indentation width identifier"):(p=t+i-1,u=!0)}if(v(a)){do{a=e.input.charCodeAt(++e.position)}while(v(a));if(35===a)do{a=e.input.charCodeAt(++e.position)}while(!m(a)&&0!==a)}for(;0!==a;){for(j(e),e.lineIndent=0,a=e.input.charCodeAt(e.position);(!u||e.lineIndent<p)&&32===a;)e.lineIndent++,a=e.input.charCodeAt(++e.position);if(!u&&e.lineIndent>p&&(p=e.lineIndent),m(a))f++;else{if(e.lineIndent<p){3===l?e.result+=r.repeat("\n",c?1+f:f):1===l&&c&&(e.result+="\n");break}for(o?v(a)?(d=!0,e.result+=r.repeat("\n",c?1+f:f)):d?(d=!1,e.result+=r.repeat("\n",f+1)):0===f?c&&(e.result+=" "):e.result+=r.repeat("\n",f):e.result+=r.repeat("\n",c?1+f:f),c=!0,u=!0,f=0,n=e.position;!m(a)&&0!==a;)a=e.input.charCodeAt(++e.position);T(e,n,e.position,!1)}}return!0}(e,d)||function(e,t){var n,r,o;if(39!==(n=e.input.charCodeAt(e.position)))return!1;for(e.kind="scalar",e.result="",e.position++,r=o=e.position;0!==(n=e.input.charCodeAt(e.position));)if(39===n){if(T(e,r,e.position,!0),39!==(n=e.input.charCodeAt(++e.position)))return!0;r=e.position,e.position++,o=e.position}else m(n)?(T(e,r,o,!0),M(e,L(e,!1,t)),r=o=e.position):e.position===e.lineStart&&N(e)?A(e,"unexpected end of the document within a single quoted scalar"):(e.position++,o=e.position);A(e,"unexpected end of the stream within a single quoted scalar")}(e,d)||function(e,t){var n,r,o,i,a,s,l;if(34!==(s=e.input.charCodeAt(e.position)))return!1;for(e.kind="scalar",e.result="",e.position++,n=r=e.position;0!==(s=e.input.charCodeAt(e.position));){if(34===s)return T(e,n,e.position,!0),e.position++,!0;if(92===s){if(T(e,n,e.position,!0),m(s=e.input.charCodeAt(++e.position)))L(e,!1,t);else if(s<256&&k[s])e.result+=E[s],e.position++;else if((a=120===(l=s)?2:117===l?4:85===l?8:0)>0){for(o=a,i=0;o>0;o--)(a=b(s=e.input.charCodeAt(++e.position)))>=0?i=(i<<4)+a:A(e,"expected hexadecimal character");e.result+=w(i),e.position++}else A(e,"unknown escape sequence");n=r=e.position}else m(s)?(T(e,n,r,!0),M(e,L(e,!1,t)),n=r=e.position):e.position===e.lineStart&&N(e)?A(e,"unexpected end of the document within a double quoted scalar"):(e.position++,r=e.position)}A(e,"unexpected end of the stream within a double quoted scalar")}(e,d)?_=!0:function(e){var t,n,r;if(42!==(r=e.input.charCodeAt(e.position)))return!1;for(r=e.input.charCodeAt(++e.position),t=e.position;0!==r&&!g(r)&&!y(r);)r=e.input.charCodeAt(++e.position);return e.position===t&&A(e,"name of an alias node must contain at least one character"),n=e.input.slice(t,e.position),l.call(e.anchorMap,n)||A(e,'unidentified alias "'+n+'"'),e.result=e.anchorMap[n],L(e,!0,-1),!0}(e)?(_=!0,null===e.tag&&null===e.anchor||A(e,"alias node should not have any properties")):function(e,t,n){var r,o,i,a,s,l,c,u,p=e.kind,f=e.result;if(g(u=e.input.charCodeAt(e.position))||y(u)||35===u||38===u||42===u||33===u||124===u||62===u||39===u||34===u||37===u||64===u||96===u)return!1;if((63===u||45===u)&&(g(r=e.input.charCodeAt(e.position+1))||n&&y(r)))return!1;for(e.kind="scalar",e.result="",o=i=e.position,a=!1;0!==u;){if(58===u){if(g(r=e.input.charCodeAt(e.position+1))||n&&y(r))break}else if(35===u){if(g(e.input.charCodeAt(e.position-1)))break}else{if(e.position===e.lineStart&&N(e)||n&&y(u))break;if(m(u)){if(s=e.line,l=e.lineStart,c=e.lineIndent,L(e,!1,-1),e.lineIndent>=t){a=!0,u=e.input.charCodeAt(e.position);continue}e.position=i,e.line=s,e.lineStart=l,e.lineIndent=c;break}}a&&(T(e,o,i,!1),M(e,e.line-s),o=i=e.position,a=!1),v(u)||(i=e.position+1),u=e.input.charCodeAt(++e.position)}return T(e,o,i,!1),!!e.result||(e.kind=p,e.result=f,!1)}(e,d,1===n)&&(_=!0,null===e.tag&&(e.tag="?")),null!==e.anchor&&(e.anchorMap[e.anchor]=e.result)):0===x&&(_=c&&D(e,h))),null!==e.tag&&"!"!==e.tag)if("?"===e.tag){for(null!==e.result&&"scalar"!==e.kind&&A(e,'unacceptable node kind for !<?> tag; it should be "scalar", not "'+e.kind+'"'),u=0,p=e.implicitTypes.length;u<p;u+=1)if((f=e.implicitTypes[u]).resolve(e.result)){e.result=f.construct(e.result),e.tag=f.tag,null!==e.anchor&&(e.anchorMap[e.anchor]=e.result);break}}else l.call(e.typeMap[e.kind||"fallback"],e.tag)?(f=e.typeMap[e.kind||"fallback"][e.tag],null!==e.result&&f.kind!==e.kind&&A(e,"unacceptable node kind for !<"+e.tag+'> tag; it should be "'+f.kind+'", not "'+e.kind+'"'),f.resolve(e.result)?(e.result=f.construct(e.result),null!==e.anchor&&(e.anchorMap[e.anchor]=e.result)):A(e,"cannot resolve a node with !<"+e.tag+"> explicit tag")):A(e,"unknown tag !<"+e.tag+">");return null!==e.listener&&e.listener("close",e),null!==e.tag||null!==e.anchor||_}function B(e){var t,n,r,o,i=e.position,a=!1;for(e.version=null,e.checkLineBreaks=e.legacy,e.tagMap={},e.anchorMap={};0!==(o=e.input.charCodeAt(e.position))&&(L(e,!0,-1),o=e.input.charCodeAt(e.position),!(e.lineIndent>0||37!==o));){for(a=!0,o=e.input.charCodeAt(++e.position),t=e.position;0!==o&&!g(o);)o=e.input.charCodeAt(++e.position);for(r=[],(n=e.input.slice(t,e.position)).length<1&&A(e,"directive name must not be less than one character in length");0!==o;){for(;v(o);)o=e.input.charCodeAt(++e.position);if(35===o){do{o=e.input.charCodeAt(++e.position)}while(0!==o&&!m(o));break}if(m(o))break;for(t=e.position;0!==o&&!g(o);)o=e.input.charCodeAt(++e.position);r.push(e.input.slice(t,e.position))}0!==o&&j(e),l.call(C,n)?C[n](e,n,r):R(e,'unknown document directive "'+n+'"')}L(e,!0,-1),0===e.lineIndent&&45===e.input.charCodeAt(e.position)&&45===e.input.charCodeAt(e.position+1)&&45===e.input.charCodeAt(e.position+2)?(e.position+=3,L(e,!0,-1)):a&&A(e,"directives end mark is expected"),U(e,e.lineIndent-1,4,!1,!0),L(e,!0,-1),e.checkLineBreaks&&u.test(e.input.slice(i,e.position))&&R(e,"non-ASCII line breaks are interpreted as content"),e.documents.push(e.result),e.position===e.lineStart&&N(e)?46===e.input.charCodeAt(e.position)&&(e.position+=3,L(e,!0,-1)):e.position<e.length-1&&A(e,"end of the stream or a document separator is expected")}function $(e,t){t=t||{},0!==(e=String(e)).length&&(10!==e.charCodeAt(e.length-1)&&13!==e.charCodeAt(e.length-1)&&(e+="\n"),65279===e.charCodeAt(0)&&(e=e.slice(1)));var n=new _(e,t),r=e.indexOf("\0");for(-1!==r&&(n.position=r,A(n,"null byte is not allowed in input")),n.input+="\0";32===n.input.charCodeAt(n.position);)n.lineIndent+=1,n.position+=1;for(;n.position<n.length-1;)B(n);return n.documents}function q(e,t,n){null!==t&&"object"==typeof t&&void 0===n&&(n=t,t=null);var r=$(e,n);if("function"!=typeof t)return r;for(var o=0,i=r.length;o<i;o+=1)t(r[o])}function W(e,t){var n=$(e,t);if(0!==n.length){if(1===n.length)return n[0];throw new o("expected a single document in the stream, but found more")}}e.exports.loadAll=q,e.exports.load=W,e.exports.safeLoadAll=function(e,t,n){return"object"==typeof t&&null!==t&&void 0===n&&(n=t,t=null),q(e,t,r.extend({schema:a},n))},e.exports.safeLoad=function(e,t){return W(e,r.extend({schema:a},t))}},5443:function(e,t,n){"use strict";n(8309),n(7042),n(2481),n(1539),n(9714);var r=n(910);function o(e,t,n,r,o){this.name=e,this.buffer=t,this.position=n,this.line=r,this.column=o}o.prototype.getSnippet=function(e,t){var n,o,i,a,s;if(!this.buffer)return null;for(e=e||4,t=t||75,n="",o=this.position;o>0&&-1==="\0\r\n\u2028\u2029".indexOf(this.buffer.charAt(o-1));)if(o-=1,this.position-o>t/2-1){n=" ... ",o+=5;break}for(i="",a=this.position;a<this.buffer.length&&-1==="\0\r\n\u2028\u2029".indexOf(this.buffer.charAt(a));)if((a+=1)-this.position>t/2-1){i=" ... ",a-=5;break}return s=this.buffer.slice(o,a),r.repeat(" ",e)+n+s+i+"\n"+r.repeat(" ",e+this.position-o+n.length)+"^"},o.prototype.toString=function(e){var t,n="";return this.name&&(n+='in "'+this.name+'" '),n+="at line "+(this.line+1)+", column "+(this.column+1),e||(t=this.getSnippet())&&(n+=":\n"+t),n},e.exports=o},304:function(e,t,n){"use strict";n(4747),n(7327);var r=n(910),o=n(2188),i=n(889);function a(e,t,n){var r=[];return e.include.forEach((function(e){n=a(e,t,n)})),e[t].forEach((function(e){n.forEach((function(t,n){t.tag===e.tag&&t.kind===e.kind&&r.push(n)})),n.push(e)})),n.filter((function(e,t){return-1===r.indexOf(t)}))}function s(e){this.include=e.include||[],this.implicit=e.implicit||[],this.explicit=e.explicit||[],this.implicit.forEach((function(e){if(e.loadKind&&"scalar"!==e.loadKind)throw new o("There is a non-scalar type in the implicit list of a schema. Implicit resolving of such types is not supported.")})),this.compiledImplicit=a(this,"implicit",[]),this.compiledExplicit=a(this,"explicit",[]),this.compiledTypeMap=function(){var e,t,n={scalar:{},sequence:{},mapping:{},fallback:{}};function r(e){n[e.kind][e.tag]=n.fallback[e.tag]=e}for(e=0,t=arguments.length;e<t;e+=1)arguments[e].forEach(r);return n}(this.compiledImplicit,this.compiledExplicit)}s.DEFAULT=null,s.create=function(){var e,t;switch(arguments.length){case 1:e=s.DEFAULT,t=arguments[0];break;case 2:e=arguments[0],t=arguments[1];break;default:throw new o("Wrong number of arguments for Schema.create function")}if(e=r.toArray(e),t=r.toArray(t),!e.every((function(e){return e instanceof s})))throw new o("Specified list of super schemas (or a single Schema object) contains a non-Schema object.");if(!t.every((function(e){return e instanceof i})))throw new o("Specified list of YAML types (or a single Type object) contains a non-Type object.");return new s({include:e,explicit:t})},e.exports=s},9533:function(e,t,n){"use strict";var r=n(304);e.exports=new r({include:[n(2437)]})},1493:function(e,t,n){"use strict";var r=n(304);e.exports=r.DEFAULT=new r({include:[n(7265)],explicit:[n(1467),n(6446),n(9798)]})},7265:function(e,t,n){"use strict";var r=n(304);e.exports=new r({include:[n(9533)],implicit:[n(773),n(321)],explicit:[n(7257),n(8372),n(2816),n(5346)]})},4801:function(e,t,n){"use strict";var r=n(304);e.exports=new r({explicit:[n(4736),n(7234),n(127)]})},2437:function(e,t,n){"use strict";var r=n(304);e.exports=new r({include:[n(4801)],implicit:[n(1608),n(9753),n(5733),n(293)]})},889:function(e,t,n){"use strict";n(4747),n(7941);var r=n(2188),o=["kind","resolve","construct","instanceOf","predicate","represent","defaultStyle","styleAliases"],i=["scalar","sequence","mapping"];e.exports=function(e,t){var n,a;if(t=t||{},Object.keys(t).forEach((function(t){if(-1===o.indexOf(t))throw new r('Unknown option "'+t+'" is met in definition of "'+e+'" YAML type.')})),this.tag=e,this.kind=t.kind||null,this.resolve=t.resolve||function(){return!0},this.construct=t.construct||function(e){return e},this.instanceOf=t.instanceOf||null,this.predicate=t.predicate||null,this.represent=t.represent||null,this.defaultStyle=t.defaultStyle||null,this.styleAliases=(n=t.styleAliases||null,a={},null!==n&&Object.keys(n).forEach((function(e){n[e].forEach((function(t){a[String(t)]=e}))})),a),-1===i.indexOf(this.kind))throw new r('Unknown kind "'+this.kind+'" is specified for "'+e+'" YAML type.')}},7257:function(e,t,n){"use strict";var r;n(4916),n(5306);try{r=n(8764).lW}catch(e){}var o=n(889),i="ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/=\n\r";e.exports=new o("tag:yaml.org,2002:binary",{kind:"scalar",resolve:function(e){if(null===e)return!1;var t,n,r=0,o=e.length,a=i;for(n=0;n<o;n++)if(!((t=a.indexOf(e.charAt(n)))>64)){if(t<0)return!1;r+=6}return r%8==0},construct:function(e){var t,n,o=e.replace(/[\r\n=]/g,""),a=o.length,s=i,l=0,c=[];for(t=0;t<a;t++)t%4==0&&t&&(c.push(l>>16&255),c.push(l>>8&255),c.push(255&l)),l=l<<6|s.indexOf(o.charAt(t));return 0==(n=a%4*6)?(c.push(l>>16&255),c.push(l>>8&255),c.push(255&l)):18===n?(c.push(l>>10&255),c.push(l>>2&255)):12===n&&c.push(l>>4&255),r?r.from?r.from(c):new r(c):c},predicate:function(e){return r&&r.isBuffer(e)},represent:function(e){var t,n,r="",o=0,a=e.length,s=i;for(t=0;t<a;t++)t%3==0&&t&&(r+=s[o>>18&63],r+=s[o>>12&63],r+=s[o>>6&63],r+=s[63&o]),o=(o<<8)+e[t];return 0==(n=a%3)?(r+=s[o>>18&63],r+=s[o>>12&63],r+=s[o>>6&63],r+=s[63&o]):2===n?(r+=s[o>>10&63],r+=s[o>>4&63],r+=s[o<<2&63],r+=s[64]):1===n&&(r+=s[o>>2&63],r+=s[o<<4&63],r+=s[64],r+=s[64]),r}})},9753:function(e,t,n){"use strict";n(1539);var r=n(889);e.exports=new r("tag:yaml.org,2002:bool",{kind:"scalar",resolve:function(e){if(null===e)return!1;var t=e.length;return 4===t&&("true"===e||"True"===e||"TRUE"===e)||5===t&&("false"===e||"False"===e||"FALSE"===e)},construct:function(e){return"true"===e||"True"===e||"TRUE"===e},predicate:function(e){return"[object Boolean]"===Object.prototype.toString.call(e)},represent:{lowercase:function(e){return e?"true":"false"},uppercase:function(e){return e?"TRUE":"FALSE"},camelcase:function(e){return e?"True":"False"}},defaultStyle:"lowercase"})},293:function(e,t,n){"use strict";n(4603),n(4916),n(9714),n(5306),n(7042),n(9653),n(4747),n(3123),n(1539);var r=n(910),o=n(889),i=new RegExp("^(?:[-+]?(?:0|[1-9][0-9_]*)(?:\\.[0-9_]*)?(?:[eE][-+]?[0-9]+)?|\\.[0-9_]+(?:[eE][-+]?[0-9]+)?|[-+]?[0-9][0-9_]*(?::[0-5]?[0-9])+\\.[0-9_]*|[-+]?\\.(?:inf|Inf|INF)|\\.(?:nan|NaN|NAN))$"),a=/^[-+]?[0-9]+e/;e.exports=new o("tag:yaml.org,2002:float",{kind:"scalar",resolve:function(e){return null!==e&&!(!i.test(e)||"_"===e[e.length-1])},construct:function(e){var t,n,r,o;return n="-"===(t=e.replace(/_/g,"").toLowerCase())[0]?-1:1,o=[],"+-".indexOf(t[0])>=0&&(t=t.slice(1)),".inf"===t?1===n?Number.POSITIVE_INFINITY:Number.NEGATIVE_INFINITY:".nan"===t?NaN:t.indexOf(":")>=0?(t.split(":").forEach((function(e){o.unshift(parseFloat(e,10))})),t=0,r=1,o.forEach((function(e){t+=e*r,r*=60})),n*t):n*parseFloat(t,10)},predicate:function(e){return"[object Number]"===Object.prototype.toString.call(e)&&(e%1!=0||r.isNegativeZero(e))},represent:function(e,t){var n;if(isNaN(e))switch(t){case"lowercase":return".nan";case"uppercase":return".NAN";case"camelcase":return".NaN"}else if(Number.POSITIVE_INFINITY===e)switch(t){case"lowercase":return".inf";case"uppercase":return".INF";case"camelcase":return".Inf"}else if(Number.NEGATIVE_INFINITY===e)switch(t){case"lowercase":return"-.inf";case"uppercase":return"-.INF";case"camelcase":return"-.Inf"}else if(r.isNegativeZero(e))return"-0.0";return n=e.toString(10),a.test(n)?n.replace("e",".e"):n},defaultStyle:"lowercase"})},5733:function(e,t,n){"use strict";n(7042),n(4916),n(5306),n(4747),n(3123),n(1539),n(9714);var r=n(910),o=n(889);function i(e){return 48<=e&&e<=55}function a(e){return 48<=e&&e<=57}e.exports=new o("tag:yaml.org,2002:int",{kind:"scalar",resolve:function(e){if(null===e)return!1;var t,n,r=e.length,o=0,s=!1;if(!r)return!1;if("-"!==(t=e[o])&&"+"!==t||(t=e[++o]),"0"===t){if(o+1===r)return!0;if("b"===(t=e[++o])){for(o++;o<r;o++)if("_"!==(t=e[o])){if("0"!==t&&"1"!==t)return!1;s=!0}return s&&"_"!==t}if("x"===t){for(o++;o<r;o++)if("_"!==(t=e[o])){if(!(48<=(n=e.charCodeAt(o))&&n<=57||65<=n&&n<=70||97<=n&&n<=102))return!1;s=!0}return s&&"_"!==t}for(;o<r;o++)if("_"!==(t=e[o])){if(!i(e.charCodeAt(o)))return!1;s=!0}return s&&"_"!==t}if("_"===t)return!1;for(;o<r;o++)if("_"!==(t=e[o])){if(":"===t)break;if(!a(e.charCodeAt(o)))return!1;s=!0}return!(!s||"_"===t)&&(":"!==t||/^(:[0-5]?[0-9])+$/.test(e.slice(o)))},construct:function(e){var t,n,r=e,o=1,i=[];return-1!==r.indexOf("_")&&(r=r.replace(/_/g,"")),"-"!==(t=r[0])&&"+"!==t||("-"===t&&(o=-1),t=(r=r.slice(1))[0]),"0"===r?0:"0"===t?"b"===r[1]?o*parseInt(r.slice(2),2):"x"===r[1]?o*parseInt(r,16):o*parseInt(r,8):-1!==r.indexOf(":")?(r.split(":").forEach((function(e){i.unshift(parseInt(e,10))})),r=0,n=1,i.forEach((function(e){r+=e*n,n*=60})),o*r):o*parseInt(r,10)},predicate:function(e){return"[object Number]"===Object.prototype.toString.call(e)&&e%1==0&&!r.isNegativeZero(e)},represent:{binary:function(e){return e>=0?"0b"+e.toString(2):"-0b"+e.toString(2).slice(1)},octal:function(e){return e>=0?"0"+e.toString(8):"-0"+e.toString(8).slice(1)},decimal:function(e){return e.toString(10)},hexadecimal:function(e){return e>=0?"0x"+e.toString(16).toUpperCase():"-0x"+e.toString(16).toUpperCase().slice(1)}},defaultStyle:"decimal",styleAliases:{binary:[2,"bin"],octal:[8,"oct"],decimal:[10,"dec"],hexadecimal:[16,"hex"]}})},9798:function(e,t,n){"use strict";var r;n(4747),n(8309),n(7042),n(1539),n(9714);try{r=n(7707)}catch(e){"undefined"!=typeof window&&(r=window.esprima)}var o=n(889);e.exports=new o("tag:yaml.org,2002:js/function",{kind:"scalar",resolve:function(e){if(null===e)return!1;try{var t="("+e+")",n=r.parse(t,{range:!0});return"Program"===n.type&&1===n.body.length&&"ExpressionStatement"===n.body[0].type&&("ArrowFunctionExpression"===n.body[0].expression.type||"FunctionExpression"===n.body[0].expression.type)}catch(e){return!1}},construct:function(e){var t,n="("+e+")",o=r.parse(n,{range:!0}),i=[];if("Program"!==o.type||1!==o.body.length||"ExpressionStatement"!==o.body[0].type||"ArrowFunctionExpression"!==o.body[0].expression.type&&"FunctionExpression"!==o.body[0].expression.type)throw new Error("Failed to resolve function");return o.body[0].expression.params.forEach((function(e){i.push(e.name)})),t=o.body[0].expression.body.range,"BlockStatement"===o.body[0].expression.body.type?new Function(i,n.slice(t[0]+1,t[1]-1)):new Function(i,"return "+n.slice(t[0],t[1]))},predicate:function(e){return"[object Function]"===Object.prototype.toString.call(e)},represent:function(e){return e.toString()}})},6446:function(e,t,n){"use strict";n(4916),n(7042),n(4603),n(9714),n(1539);var r=n(889);e.exports=new r("tag:yaml.org,2002:js/regexp",{kind:"scalar",resolve:function(e){if(null===e)return!1;if(0===e.length)return!1;var t=e,n=/\/([gim]*)$/.exec(e),r="";if("/"===t[0]){if(n&&(r=n[1]),r.length>3)return!1;if("/"!==t[t.length-r.length-1])return!1}return!0},construct:function(e){var t=e,n=/\/([gim]*)$/.exec(e),r="";return"/"===t[0]&&(n&&(r=n[1]),t=t.slice(1,t.length-r.length-1)),new RegExp(t,r)},predicate:function(e){return"[object RegExp]"===Object.prototype.toString.call(e)},represent:function(e){var t="/"+e.source+"/";return e.global&&(t+="g"),e.multiline&&(t+="m"),e.ignoreCase&&(t+="i"),t}})},1467:function(e,t,n){"use strict";var r=n(889);e.exports=new r("tag:yaml.org,2002:js/undefined",{kind:"scalar",resolve:function(){return!0},construct:function(){},predicate:function(e){return void 0===e},represent:function(){return""}})},127:function(e,t,n){"use strict";var r=n(889);e.exports=new r("tag:yaml.org,2002:map",{kind:"mapping",construct:function(e){return null!==e?e:{}}})},321:function(e,t,n){"use strict";var r=n(889);e.exports=new r("tag:yaml.org,2002:merge",{kind:"scalar",resolve:function(e){return"<<"===e||null===e}})},1608:function(e,t,n){"use strict";var r=n(889);e.exports=new r("tag:yaml.org,2002:null",{kind:"scalar",resolve:function(e){if(null===e)return!0;var t=e.length;return 1===t&&"~"===e||4===t&&("null"===e||"Null"===e||"NULL"===e)},construct:function(){return null},predicate:function(e){return null===e},represent:{canonical:function(){return"~"},lowercase:function(){return"null"},uppercase:function(){return"NULL"},camelcase:function(){return"Null"}},defaultStyle:"lowercase"})},8372:function(e,t,n){"use strict";n(1539);var r=n(889),o=Object.prototype.hasOwnProperty,i=Object.prototype.toString;e.exports=new r("tag:yaml.org,2002:omap",{kind:"sequence",resolve:function(e){if(null===e)return!0;var t,n,r,a,s,l=[],c=e;for(t=0,n=c.length;t<n;t+=1){if(r=c[t],s=!1,"[object Object]"!==i.call(r))return!1;for(a in r)if(o.call(r,a)){if(s)return!1;s=!0}if(!s)return!1;if(-1!==l.indexOf(a))return!1;l.push(a)}return!0},construct:function(e){return null!==e?e:[]}})},2816:function(e,t,n){"use strict";n(1539),n(7941);var r=n(889),o=Object.prototype.toString;e.exports=new r("tag:yaml.org,2002:pairs",{kind:"sequence",resolve:function(e){if(null===e)return!0;var t,n,r,i,a,s=e;for(a=new Array(s.length),t=0,n=s.length;t<n;t+=1){if(r=s[t],"[object Object]"!==o.call(r))return!1;if(1!==(i=Object.keys(r)).length)return!1;a[t]=[i[0],r[i[0]]]}return!0},construct:function(e){if(null===e)return[];var t,n,r,o,i,a=e;for(i=new Array(a.length),t=0,n=a.length;t<n;t+=1)r=a[t],o=Object.keys(r),i[t]=[o[0],r[o[0]]];return i}})},7234:function(e,t,n){"use strict";var r=n(889);e.exports=new r("tag:yaml.org,2002:seq",{kind:"sequence",construct:function(e){return null!==e?e:[]}})},5346:function(e,t,n){"use strict";var r=n(889),o=Object.prototype.hasOwnProperty;e.exports=new r("tag:yaml.org,2002:set",{kind:"mapping",resolve:function(e){if(null===e)return!0;var t,n=e;for(t in n)if(o.call(n,t)&&null!==n[t])return!1;return!0},construct:function(e){return null!==e?e:{}}})},4736:function(e,t,n){"use strict";var r=n(889);e.exports=new r("tag:yaml.org,2002:str",{kind:"scalar",construct:function(e){return null!==e?e:""}})},773:function(e,t,n){"use strict";n(4603),n(4916),n(9714),n(7042);var r=n(889),o=new RegExp("^([0-9][0-9][0-9][0-9])-([0-9][0-9])-([0-9][0-9])$"),i=new RegExp("^([0-9][0-9][0-9][0-9])-([0-9][0-9]?)-([0-9][0-9]?)(?:[Tt]|[ \\t]+)([0-9][0-9]?):([0-9][0-9]):([0-9][0-9])(?:\\.([0-9]*))?(?:[ \\t]*(Z|([-+])([0-9][0-9]?)(?::([0-9][0-9]))?))?$");e.exports=new r("tag:yaml.org,2002:timestamp",{kind:"scalar",resolve:function(e){return null!==e&&(null!==o.exec(e)||null!==i.exec(e))},construct:function(e){var t,n,r,a,s,l,c,u,p=0,f=null;if(null===(t=o.exec(e))&&(t=i.exec(e)),null===t)throw new Error("Date resolve error");if(n=+t[1],r=+t[2]-1,a=+t[3],!t[4])return new Date(Date.UTC(n,r,a));if(s=+t[4],l=+t[5],c=+t[6],t[7]){for(p=t[7].slice(0,3);p.length<3;)p+="0";p=+p}return t[9]&&(f=6e4*(60*+t[10]+ +(t[11]||0)),"-"===t[9]&&(f=-f)),u=new Date(Date.UTC(n,r,a,s,l,c,p)),f&&u.setTime(u.getTime()-f),u},instanceOf:Date,represent:function(e){return e.toISOString()}})},2566:function(e,t,n){n(4916),n(5306),n(4603),n(9714),n(915),n(8734);var r=!("NO_COLOR"in{})&&("FORCE_COLOR"in{}||!1),o=function(e,t,n,o){return function(i){return r?e+(~(i+="").indexOf(t,4)?i.replace(n,o):i)+t:i}},i=function(e,t){return o("[".concat(e,"m"),"[".concat(t,"m"),new RegExp("\\x1b\\[".concat(t,"m"),"g"),"[".concat(e,"m"))};t.options=Object.defineProperty({},"enabled",{get:function(){return r},set:function(e){return r=e}}),t.reset=i(0,0),t.bold=o("[1m","[22m",/\x1b\[22m/g,"[22m[1m"),t.dim=o("[2m","[22m",/\x1b\[22m/g,"[22m[2m"),t.italic=i(3,23),t.underline=i(4,24),t.inverse=i(7,27),t.hidden=i(8,28),t.strikethrough=i(9,29),t.black=i(30,39),t.red=i(31,39),t.green=i(32,39),t.yellow=i(33,39),t.blue=i(34,39),t.magenta=i(35,39),t.cyan=i(36,39),t.white=i(37,39),t.gray=i(90,39),t.bgBlack=i(40,49),t.bgRed=i(41,49),t.bgGreen=i(42,49),t.bgYellow=i(43,49),t.bgBlue=i(44,49),t.bgMagenta=i(45,49),t.bgCyan=i(46,49),t.bgWhite=i(47,49),t.blackBright=i(90,39),t.redBright=i(91,39),t.greenBright=i(92,39),t.yellowBright=i(93,39),t.blueBright=i(94,39),t.magentaBright=i(95,39),t.cyanBright=i(96,39),t.whiteBright=i(97,39),t.bgBlackBright=i(100,49),t.bgRedBright=i(101,49),t.bgGreenBright=i(102,49),t.bgYellowBright=i(103,49),t.bgBlueBright=i(104,49),t.bgMagentaBright=i(105,49),t.bgCyanBright=i(106,49),t.bgWhiteBright=i(107,49)},8276:function(e,t,n){"use strict";var r=n(3269).default;n(6992),n(1539),n(189),n(8783),n(3948),n(9601),n(9826),n(4916),n(5306),n(3123),n(9600);var o=n(4445),i={}.NODE_DISABLE_COLORS?{red:"",yellow:"",green:"",normal:""}:{red:"[31m",yellow:"[33;1m",green:"[32m",normal:"[0m"};function a(e){var t=(e=e.replace("[]","Array")).split("/");return t[0]=t[0].replace(/[^A-Za-z0-9_\-\.]+|\s+/gm,"_"),t.join("/")}String.prototype.toCamelCase=function(){return this.toLowerCase().replace(/[-_ \/\.](.)/g,(function(e,t){return t.toUpperCase()}))},e.exports={colour:i,uniqueOnly:function(e,t,n){return n.indexOf(e)===t},hasDuplicates:function(e){return new Set(e).size!==e.length},allSame:function(e){return new Set(e).size<=1},distinctArray:function(e){return e.length===function(e){var t,n=[],i=r(e);try{var a=function(){var e=t.value;n.find((function(t,n,r){return function(e,t){function n(e,t){return o.stringify(e)===o.stringify(Object.assign({},e,t))}return n(e,t)&&n(t,e)}(t,e)}))||n.push(e)};for(i.s();!(t=i.n()).done;)a()}catch(e){i.e(e)}finally{i.f()}return n}(e).length},firstDupe:function(e){return e.find((function(t,n,r){return e.indexOf(t)<n}))},hash:function(e){var t=0;if(0===e.length)return t;for(var n=0;n<e.length;n++)t=(t<<5)-t+e.charCodeAt(n),t|=0;return t},parameterTypeProperties:["format","minimum","maximum","exclusiveMinimum","exclusiveMaximum","minLength","maxLength","multipleOf","minItems","maxItems","uniqueItems","minProperties","maxProperties","additionalProperties","pattern","enum","default"],arrayProperties:["items","minItems","maxItems","uniqueItems"],httpMethods:["get","post","put","delete","patch","head","options","trace"],sanitise:a,sanitiseAll:function(e){return a(e.split("/").join("_"))}}},1338:function(e,t,n){"use strict";var r=n(3269).default,o=n(319).default;n(6992),n(1539),n(189),n(8783),n(3948),n(8674),n(6755),n(4916),n(5306),n(9600),n(3123),n(9714),n(9601),n(2222),n(9826),n(7941),n(2526),n(1817),n(2707);var i=n(1683),a=n(6470),s=n(8575),l=n(7707),c=n(7707),u=n(2580).jptr,p=n(9290).recurse,f=n(4188).clone,d=n(3094).dereference,h=n(3856).isRef,m=n(8276);function v(e,t,n,r,o,i){for(var a=i.externalRefs[n+r].paths[0],l=s.parse(o),c={},d=1;d;)d=0,p(e,{identityDetection:!0},(function(e,n,r){if(h(e,n))if(e[n].startsWith("#"))if(c[e[n]]||e.$fixed){if(!e.$fixed){var p=(a+"/"+c[e[n]]).split("/#/").join("/");r.parent[r.pkey]={$ref:p,"x-miro":e[n],$fixed:!0},i.verbose>1&&console.warn("Replacing with",p),d++}}else{var v=f(u(t,e[n]));if(i.verbose>1&&console.warn((!1===v?m.colour.red:m.colour.green)+"Fragment resolution",e[n],m.colour.normal),!1===v){if(r.parent[r.pkey]={},i.fatal){var g=new Error("Fragment $ref resolution failed "+e[n]);if(!i.promise)throw g;i.promise.reject(g)}}else d++,r.parent[r.pkey]=v,c[e[n]]=r.path.replace("/%24ref","")}else if(l.protocol){var y=s.resolve(o,e[n]).toString();i.verbose>1&&console.warn(m.colour.yellow+"Rewriting external url ref",e[n],"as",y,m.colour.normal),e["x-miro"]=e[n],i.externalRefs[e[n]]&&(i.externalRefs[y]||(i.externalRefs[y]=i.externalRefs[e[n]]),i.externalRefs[y].failed=i.externalRefs[e[n]].failed),e[n]=y}else if(!e["x-miro"]){var b=s.resolve(o,e[n]).toString(),x=!1;i.externalRefs[e[n]]&&(x=i.externalRefs[e[n]].failed),x||(i.verbose>1&&console.warn(m.colour.yellow+"Rewriting external ref",e[n],"as",b,m.colour.normal),e["x-miro"]=e[n],e[n]=b)}}));return p(e,{},(function(e,t,n){h(e,t)&&void 0!==e.$fixed&&delete e.$fixed})),i.verbose>1&&console.warn("Finished fragment resolution"),e}function g(e,t){if(!t.filters||!t.filters.length)return e;var n,o=r(t.filters);try{for(o.s();!(n=o.n()).done;)e=(0,n.value)(e,t)}catch(e){o.e(e)}finally{o.f()}return e}function y(e){return new Promise((function(t,n){(function(e){return new Promise((function(t,n){function r(t,n,r){if(t[n]&&h(t[n],"$ref")){var i=t[n].$ref;if(!i.startsWith("#")){var a="";if(!o[i]){var s=Object.keys(o).find((function(e,t,n){return i.startsWith(e+"/")}));s&&(e.verbose&&console.warn("Found potential subschema at",s),a=(a="/"+(i.split("#")[1]||"").replace(s.split("#")[1]||"")).split("/undefined").join(""),i=s)}if(o[i]||(o[i]={resolved:!1,paths:[],extras:{},description:t[n].description}),o[i].resolved)if(o[i].failed);else if(e.rewriteRefs){var l=o[i].resolvedAt;e.verbose>1&&console.warn("Rewriting ref",i,l),t[n]["x-miro"]=i,t[n].$ref=l+a}else t[n]=f(o[i].data);else o[i].paths.push(r.path),o[i].extras[r.path]=a}}}var o=e.externalRefs;if(e.resolver.depth>0&&e.source===e.resolver.base)return t(o);p(e.openapi.definitions,{identityDetection:!0,path:"#/definitions"},r),p(e.openapi.components,{identityDetection:!0,path:"#/components"},r),p(e.openapi,{identityDetection:!0},r),t(o)}))})(e).then((function(t){var n=function(n){if(!t[n].resolved){var l=e.resolver.depth;l>0&&l++,e.resolver.actions[l].push((function(){return function(e,t,n,r){var o=s.parse(n.source),l=n.source.split("\\").join("/").split("/");l.pop()||l.pop();var p="",d=t.split("#");d.length>1&&(p="#"+d[1],t=d[0]),l=l.join("/");var h,m,y,b=(h=s.parse(t).protocol,m=o.protocol,h&&h.length>2?h:m&&m.length>2?m:"file:");if(y="file:"===b?a.resolve(l?l+"/":"",t):s.resolve(l?l+"/":"",t),n.cache[y]){n.verbose&&console.warn("CACHED",y,p);var x=f(n.cache[y]),w=n.externalRef=x;if(p&&!1===(w=u(w,p))&&(w={},n.fatal)){var k=new Error("Cached $ref resolution failed "+y+p);if(!n.promise)throw k;n.promise.reject(k)}return w=g(w=v(w,x,t,p,y,n),n),r(f(w),y,n),Promise.resolve(w)}if(n.verbose&&console.warn("GET",y,p),n.handlers&&n.handlers[b])return n.handlers[b](l,t,p,n).then((function(e){return n.externalRef=e,e=g(e,n),n.cache[y]=e,r(e,y,n),e})).catch((function(e){throw n.verbose&&console.warn(e),e}));if(b&&b.startsWith("http")){var E=Object.assign({},n.fetchOptions,{agent:n.agent});return n.fetch(y,E).then((function(e){if(200!==e.status){if(n.ignoreIOErrors)return n.verbose&&console.warn("FAILED",t),n.externalRefs[t].failed=!0,'{"$ref":"'+t+'"}';throw new Error("Received status code ".concat(e.status,": ").concat(y))}return e.text()})).then((function(e){try{var o=c.parse(e,{schema:"core",prettyErrors:!0});if(e=n.externalRef=o,n.cache[y]=f(e),p&&!1===(e=u(e,p))&&(e={},n.fatal)){var i=new Error("Remote $ref resolution failed "+y+p);if(!n.promise)throw i;n.promise.reject(i)}e=g(e=v(e,o,t,p,y,n),n)}catch(e){if(n.verbose&&console.warn(e),!n.promise||!n.fatal)throw e;n.promise.reject(e)}return r(e,y,n),e})).catch((function(e){if(n.verbose&&console.warn(e),n.cache[y]={},!n.promise||!n.fatal)throw e;n.promise.reject(e)}))}var S='{"$ref":"'+t+'"}';return function(e,t,n,r,o){return new Promise((function(a,s){i.readFile(e,t,(function(e,t){e?n.ignoreIOErrors&&o?(n.verbose&&console.warn("FAILED",r),n.externalRefs[r].failed=!0,a(o)):s(e):a(t)}))}))}(y,n.encoding||"utf8",n,t,S).then((function(e){try{var o=c.parse(e,{schema:"core",prettyErrors:!0});if(e=n.externalRef=o,n.cache[y]=f(e),p&&!1===(e=u(e,p))&&(e={},n.fatal)){var i=new Error("File $ref resolution failed "+y+p);if(!n.promise)throw i;n.promise.reject(i)}e=g(e=v(e,o,t,p,y,n),n)}catch(e){if(n.verbose&&console.warn(e),!n.promise||!n.fatal)throw e;n.promise.reject(e)}return r(e,y,n),e})).catch((function(e){if(n.verbose&&console.warn(e),!n.promise||!n.fatal)throw e;n.promise.reject(e)}))}(e.openapi,n,e,(function(e,i,a){if(!t[n].resolved){var s={};s.context=t[n],s.$ref=n,s.original=f(e),s.updated=e,s.source=i,a.externals.push(s),t[n].resolved=!0}var l=Object.assign({},a,{source:"",resolver:{actions:a.resolver.actions,depth:a.resolver.actions.length-1,base:a.resolver.base}});a.patch&&t[n].description&&!e.description&&"object"==typeof e&&(e.description=t[n].description),t[n].data=e;var c,p=(c=t[n].paths,o(new Set(c)));p=p.sort((function(e,t){var n=e.startsWith("#/components/")||e.startsWith("#/definitions/"),r=t.startsWith("#/components/")||t.startsWith("#/definitions/");return n&&!r?-1:r&&!n?1:0}));var d,h=r(p);try{for(h.s();!(d=h.n()).done;){var m=d.value;if(t[n].resolvedAt&&m!==t[n].resolvedAt&&m.indexOf("x-ms-examples/")<0)a.verbose>1&&console.warn("Creating pointer to data at",m),u(a.openapi,m,{$ref:t[n].resolvedAt+t[n].extras[m],"x-miro":n+t[n].extras[m]});else{t[n].resolvedAt?a.verbose>1&&console.warn("Avoiding circular reference"):(t[n].resolvedAt=m,a.verbose>1&&console.warn("Creating initial clone of data at",m));var v=f(e);u(a.openapi,m,v)}}}catch(e){h.e(e)}finally{h.f()}0===a.resolver.actions[l.resolver.depth].length&&a.resolver.actions[l.resolver.depth].push((function(){return y(l)}))}))}))}};for(var l in t)n(l)})).catch((function(t){e.verbose&&console.warn(t),n(t)}));var l={options:e};l.actions=e.resolver.actions[e.resolver.depth],t(l)}))}function b(e,t,n){e.resolver.actions.push([]),y(e).then((function(r){var o;(o=r.actions,o.reduce((function(e,t){return e.then((function(e){return t().then(Array.prototype.concat.bind(e))}))}),Promise.resolve([]))).then((function(){if(e.resolver.depth>=e.resolver.actions.length)return console.warn("Ran off the end of resolver actions"),t(!0);e.resolver.depth++,e.resolver.actions[e.resolver.depth].length?setTimeout((function(){b(r.options,t,n)}),0):(e.verbose>1&&console.warn(m.colour.yellow+"Finished external resolution!",m.colour.normal),e.resolveInternal&&(e.verbose>1&&console.warn(m.colour.yellow+"Starting internal resolution!",m.colour.normal),e.openapi=d(e.openapi,e.original,{verbose:e.verbose-1}),e.verbose>1&&console.warn(m.colour.yellow+"Finished internal resolution!",m.colour.normal)),p(e.openapi,{},(function(t,n,r){h(t,n)&&(e.preserveMiro||delete t["x-miro"])})),t(e))})).catch((function(t){e.verbose&&console.warn(t),n(t)}))})).catch((function(t){e.verbose&&console.warn(t),n(t)}))}function x(e){if(e.cache||(e.cache={}),e.fetch||(e.fetch=l),e.source){var t=s.parse(e.source);(!t.protocol||t.protocol.length<=2)&&(e.source=a.resolve(e.source))}e.externals=[],e.externalRefs={},e.rewriteRefs=!0,e.resolver={},e.resolver.depth=0,e.resolver.base=e.source,e.resolver.actions=[[]]}e.exports={optionalResolve:function(e){return x(e),new Promise((function(t,n){e.resolve?b(e,t,n):t(e)}))},resolve:function(e,t,n){return n||(n={}),n.openapi=e,n.source=t,n.resolve=!0,x(n),new Promise((function(e,t){b(n,e,t)}))}}},6704:function(e,t,n){"use strict";function r(){return{depth:0,seen:new WeakMap,top:!0,combine:!1,allowRefSiblings:!1}}n(6992),n(1539),n(8783),n(4129),n(3948),n(2526),n(1817),n(9601),e.exports={getDefaultState:r,walkSchema:function e(t,n,o,i){if(void 0===o.depth&&(o=r()),null==t)return t;if(void 0!==t.$ref){var a={$ref:t.$ref};return o.allowRefSiblings&&t.description&&(a.description=t.description),i(a,n,o),a}if(o.combine&&(t.allOf&&Array.isArray(t.allOf)&&1===t.allOf.length&&delete(t=Object.assign({},t.allOf[0],t)).allOf,t.anyOf&&Array.isArray(t.anyOf)&&1===t.anyOf.length&&delete(t=Object.assign({},t.anyOf[0],t)).anyOf,t.oneOf&&Array.isArray(t.oneOf)&&1===t.oneOf.length&&delete(t=Object.assign({},t.oneOf[0],t)).oneOf),i(t,n,o),o.seen.has(t))return t;if("object"==typeof t&&null!==t&&o.seen.set(t,!0),o.top=!1,o.depth++,void 0!==t.items&&(o.property="items",e(t.items,t,o,i)),t.additionalItems&&"object"==typeof t.additionalItems&&(o.property="additionalItems",e(t.additionalItems,t,o,i)),t.additionalProperties&&"object"==typeof t.additionalProperties&&(o.property="additionalProperties",e(t.additionalProperties,t,o,i)),t.properties)for(var s in t.properties){var l=t.properties[s];o.property="properties/"+s,e(l,t,o,i)}if(t.patternProperties)for(var c in t.patternProperties){var u=t.patternProperties[c];o.property="patternProperties/"+c,e(u,t,o,i)}if(t.allOf)for(var p in t.allOf){var f=t.allOf[p];o.property="allOf/"+p,e(f,t,o,i)}if(t.anyOf)for(var d in t.anyOf){var h=t.anyOf[d];o.property="anyOf/"+d,e(h,t,o,i)}if(t.oneOf)for(var m in t.oneOf){var v=t.oneOf[m];o.property="oneOf/"+m,e(v,t,o,i)}return t.not&&(o.property="not",e(t.not,t,o,i)),o.depth--,t}}},4188:function(e,t,n){"use strict";var r=n(9713).default,o=n(319).default;n(9601),n(6992),n(1539),n(8783),n(4129),n(3948),n(489),n(2222),n(1249),n(7941),e.exports={nop:function(e){return e},clone:function(e){return JSON.parse(JSON.stringify(e))},shallowClone:function(e){var t={};for(var n in e)e.hasOwnProperty(n)&&(t[n]=e[n]);return t},deepClone:function e(t){var n=Array.isArray(t)?[]:{};for(var r in t)(t.hasOwnProperty(r)||Array.isArray(t))&&(n[r]="object"==typeof t[r]?e(t[r]):t[r]);return n},fastClone:function(e){return Object.assign({},e)},circularClone:function e(t,n){if(n||(n=new WeakMap),Object(t)!==t||t instanceof Function)return t;if(n.has(t))return n.get(t);try{var i=new t.constructor}catch(e){i=Object.create(Object.getPrototypeOf(t))}return n.set(t,i),Object.assign.apply(Object,[i].concat(o(Object.keys(t).map((function(o){return r({},o,e(t[o],n))})))))}}},3094:function(e,t,n){"use strict";n(7042),n(4916),n(3123);var r=n(9290).recurse,o=n(4188).shallowClone,i=n(2580).jptr,a=n(3856).isRef;e.exports={dereference:function e(t,n,s){s||(s={}),s.cache||(s.cache={}),s.state||(s.state={}),s.state.identityDetection=!0,s.depth=s.depth?s.depth+1:1;var l=s.depth>1?t:o(t),c={data:l},u=s.depth>1?n:o(n);s.master||(s.master=l);for(var p=function(e){return e&&e.verbose?{warn:function(){var e=Array.prototype.slice.call(arguments);console.warn.apply(console,e)}}:{warn:function(){}}}(s),f=1;f>0;)f=0,r(c,s.state,(function(t,n,r){if(a(t,n)){var o=t[n];if(f++,s.cache[o]){var l=s.cache[o];if(l.resolved)p.warn("Patching %s for %s",o,l.path),r.parent[r.pkey]=l.data,s.$ref&&"object"==typeof r.parent[r.pkey]&&(r.parent[r.pkey][s.$ref]=o);else{if(o===l.path)throw new Error("Tight circle at ".concat(l.path));p.warn("Unresolved ref"),r.parent[r.pkey]=i(l.source,l.path),!1===r.parent[r.pkey]&&(r.parent[r.pkey]=i(l.source,l.key)),s.$ref&&"object"==typeof r.parent[r.pkey]&&(r.parent[s.$ref]=o)}}else{var c={};c.path=r.path.split("/$ref")[0],c.key=o,p.warn("Dereffing %s at %s",o,c.path),c.source=u,c.data=i(c.source,c.key),!1===c.data&&(c.data=i(s.master,c.key),c.source=s.master),!1===c.data&&p.warn("Missing $ref target",c.key),s.cache[o]=c,c.data=r.parent[r.pkey]=e(i(c.source,c.key),c.source,s),s.$ref&&"object"==typeof r.parent[r.pkey]&&(r.parent[r.pkey][s.$ref]=o),c.resolved=!0}}}));return c.data}}},3856:function(e){"use strict";e.exports={isRef:function(e,t){return"$ref"===t&&!!e&&"string"==typeof e[t]}}},2580:function(e,t,n){"use strict";function r(e){return e.replace(/\~1/g,"/").replace(/~0/g,"~")}n(4916),n(5306),n(3123),n(9600),n(7042),n(6755),n(1539),n(9714),e.exports={jptr:function(e,t,n){if(void 0===e)return!1;if(!t||"string"!=typeof t||"#"===t)return void 0!==n?n:e;if(t.indexOf("#")>=0){var o=t.split("#");if(o[0])return!1;t=o[1],t=decodeURIComponent(t.slice(1).split("+").join(" "))}t.startsWith("/")&&(t=t.slice(1));for(var i=t.split("/"),a=0;a<i.length;a++){i[a]=r(i[a]);var s=void 0!==n&&a==i.length-1,l=parseInt(i[a],10);if(!Array.isArray(e)||isNaN(l)||l.toString()!==i[a]?l=Array.isArray(e)&&"-"===i[a]?-2:-1:i[a]=a>0?i[a-1]:"",-1!=l||e&&e.hasOwnProperty(i[a]))if(l>=0)s&&(e[l]=n),e=e[l];else{if(-2===l)return s?(Array.isArray(e)&&e.push(n),n):void 0;s&&(e[i[a]]=n),e=e[i[a]]}else{if(void 0===n||"object"!=typeof e||Array.isArray(e))return!1;e[i[a]]=s?n:"0"===i[a+1]||"-"===i[a+1]?[]:{},e=e[i[a]]}}return e},jpescape:function(e){return e.replace(/\~/g,"~0").replace(/\//g,"~1")},jpunescape:r}},9290:function(e,t,n){"use strict";n(6992),n(1539),n(8783),n(4129),n(3948),n(9601);var r=n(2580).jpescape;e.exports={recurse:function e(t,n,o){if(n||(n={depth:0}),n.depth||(n=Object.assign({},{path:"#",depth:0,pkey:"",parent:{},payload:{},seen:new WeakMap,identity:!1,identityDetection:!1},n)),"object"==typeof t){var i=n.path;for(var a in t){if(n.key=a,n.path=n.path+"/"+encodeURIComponent(r(a)),n.identityPath=n.seen.get(t[a]),n.identity=void 0!==n.identityPath,t.hasOwnProperty(a)&&o(t,a,n),"object"==typeof t[a]&&!n.identity){n.identityDetection&&!Array.isArray(t[a])&&null!==t[a]&&n.seen.set(t[a],n.path);var s={};s.parent=t,s.path=n.path,s.depth=n.depth?n.depth+1:1,s.pkey=a,s.payload=n.payload,s.seen=n.seen,s.identity=!1,s.identityDetection=n.identityDetection,e(t[a],s,o)}n.path=i}}}}},6399:function(e,t,n){"use strict";var r=n(3269).default,o=n(4575).default,i=n(2205).default,a=n(9842).default,s=n(5957).default;n(8309),n(7941),n(2222),n(6755),n(4916),n(5306),n(3123),n(9600),n(3210),n(7327),n(2526),n(1817),n(9601),n(9826),n(1539),n(9714),n(6992),n(8783),n(3948),n(285),n(8478),n(8674),n(4723),n(4747);var l,c=n(1683),u=n(8575),p=(n(6470),n(4480)),f=n(7707),d=n(7707),h=n(2580),m=h.jptr,v=n(3856).isRef,g=n(4188).clone,y=n(4188).circularClone,b=n(9290).recurse,x=n(1338),w=n(6704),k=n(8276),E=n(873).statusCodes,S=n(8500).i8,_="3.0.0",O=function(e){i(n,e);var t=a(n);function n(e){var r;return o(this,n),(r=t.call(this,e)).name="S2OError",r}return n}(s(Error));function A(e,t){var n=new O(e);if(n.options=t,!t.promise)throw n;t.promise.reject(n)}function R(e,t,n){n.warnOnly?t[n.warnProperty||"x-s2o-warning"]=e:A(e,n)}function C(e,t){w.walkSchema(e,{},{},(function(e,n,o){!function(e,t){if(e["x-required"]&&Array.isArray(e["x-required"])&&(e.required||(e.required=[]),e.required=e.required.concat(e["x-required"]),delete e["x-required"]),e["x-anyOf"]&&(e.anyOf=e["x-anyOf"],delete e["x-anyOf"]),e["x-oneOf"]&&(e.oneOf=e["x-oneOf"],delete e["x-oneOf"]),e["x-not"]&&(e.not=e["x-not"],delete e["x-not"]),"boolean"==typeof e["x-nullable"]&&(e.nullable=e["x-nullable"],delete e["x-nullable"]),"object"==typeof e["x-discriminator"]&&"string"==typeof e["x-discriminator"].propertyName)for(var n in e.discriminator=e["x-discriminator"],delete e["x-discriminator"],e.discriminator.mapping){var r=e.discriminator.mapping[n];r.startsWith("#/definitions/")&&(e.discriminator.mapping[n]=r.replace("#/definitions/","#/components/schemas/"))}}(e),function(e,t,n){if(e.nullable&&n.patches++,e.discriminator&&"string"==typeof e.discriminator&&(e.discriminator={propertyName:e.discriminator}),e.items&&Array.isArray(e.items)&&(0===e.items.length?e.items={}:1===e.items.length?e.items=e.items[0]:e.items={anyOf:e.items}),e.type&&Array.isArray(e.type))if(n.patch){if(n.patches++,0===e.type.length)delete e.type;else{e.oneOf||(e.oneOf=[]);var o,i=r(e.type);try{for(i.s();!(o=i.n()).done;){var a=o.value,s={};if("null"===a)e.nullable=!0;else{s.type=a;var l,c=r(k.arrayProperties);try{for(c.s();!(l=c.n()).done;){var u=l.value;void 0!==e.prop&&(s[u]=e[u],delete e[u])}}catch(e){c.e(e)}finally{c.f()}}s.type&&e.oneOf.push(s)}}catch(e){i.e(e)}finally{i.f()}delete e.type,0===e.oneOf.length?delete e.oneOf:e.oneOf.length<2&&(e.type=e.oneOf[0].type,Object.keys(e.oneOf[0]).length>1&&R("Lost properties from oneOf",e,n),delete e.oneOf)}e.type&&Array.isArray(e.type)&&1===e.type.length&&(e.type=e.type[0])}else A("(Patchable) schema type must not be an array",n);e.type&&"null"===e.type&&(delete e.type,e.nullable=!0),"array"!==e.type||e.items||(e.items={}),"file"===e.type&&(e.type="string",e.format="binary"),"boolean"==typeof e.required&&(e.required&&e.name&&(void 0===t.required&&(t.required=[]),Array.isArray(t.required)&&t.required.push(e.name)),delete e.required),e.xml&&"string"==typeof e.xml.namespace&&(e.xml.namespace||delete e.xml.namespace),void 0!==e.allowEmptyValue&&(n.patches++,delete e.allowEmptyValue)}(e,n,t)}))}function T(e,t,n){var r=n.payload.options;if(v(e,t)){if(e[t].startsWith("#/components/"));else if("#/consumes"===e[t])delete e[t],n.parent[n.pkey]=g(r.openapi.consumes);else if("#/produces"===e[t])delete e[t],n.parent[n.pkey]=g(r.openapi.produces);else if(e[t].startsWith("#/definitions/")){var o=e[t].replace("#/definitions/","").split("/"),i=h.jpunescape(o[0]),a=l.schemas[decodeURIComponent(i)];a?o[0]=a:R("Could not resolve reference "+e[t],e,r),e[t]="#/components/schemas/"+o.join("/")}else if(e[t].startsWith("#/parameters/"))e[t]="#/components/parameters/"+k.sanitise(e[t].replace("#/parameters/",""));else if(e[t].startsWith("#/responses/"))e[t]="#/components/responses/"+k.sanitise(e[t].replace("#/responses/",""));else if(e[t].startsWith("#")){var s=g(h.jptr(r.openapi,e[t]));if(!1===s)R("direct $ref not found "+e[t],e,r);else if(r.refmap[e[t]])e[t]=r.refmap[e[t]];else{var c=e[t],u="schemas",p=(c=(c=(c=(c=c.replace("/properties/headers/","")).replace("/properties/responses/","")).replace("/properties/parameters/","")).replace("/properties/schemas/","")).lastIndexOf("/schema");if("schemas"==(u=c.indexOf("/headers/")>p?"headers":c.indexOf("/responses/")>p?"responses":c.indexOf("/example")>p?"examples":c.indexOf("/x-")>p?"extensions":c.indexOf("/parameters/")>p?"parameters":"schemas")&&C(s,r),"responses"!==u&&"extensions"!==u){var f=u.substr(0,u.length-1);"parameter"===f&&s.name&&s.name===k.sanitise(s.name)&&(f=encodeURIComponent(s.name));var d=1;for(e["x-miro"]&&(f=function(e){return e=e.indexOf("#")>=0?e.split("#")[1].split("/").pop():e.split("/").pop().split(".")[0],encodeURIComponent(k.sanitise(e))}(e["x-miro"]),d="");h.jptr(r.openapi,"#/components/"+u+"/"+f+d);)d=""===d?2:++d;var m="#/components/"+u+"/"+f+d,y="";"examples"===u&&(s={value:s},y="/value"),h.jptr(r.openapi,m,s),r.refmap[e[t]]=m+y,e[t]=m+y}}}if(delete e["x-miro"],Object.keys(e).length>1){var b=e[t],x=n.path.indexOf("/schema")>=0;"preserve"===r.refSiblings||(x&&"allOf"===r.refSiblings?(delete e.$ref,n.parent[n.pkey]={allOf:[{$ref:b},e]}):n.parent[n.pkey]={$ref:b})}}if("x-ms-odata"===t&&"string"==typeof e[t]&&e[t].startsWith("#/")){var w=e[t].replace("#/definitions/","").replace("#/components/schemas/","").split("/"),E=l.schemas[decodeURIComponent(w[0])];E?w[0]=E:R("Could not resolve reference "+e[t],e,r),e[t]="#/components/schemas/"+w.join("/")}}function P(e){for(var t in e)for(var n in e[t]){var r=k.sanitise(n);n!==r&&(e[t][r]=e[t][n],delete e[t][n])}}function I(e,t){if("basic"===e.type&&(e.type="http",e.scheme="basic"),"oauth2"===e.type){var n={},r=e.flow;"application"===e.flow&&(r="clientCredentials"),"accessCode"===e.flow&&(r="authorizationCode"),void 0!==e.authorizationUrl&&(n.authorizationUrl=e.authorizationUrl.split("?")[0].trim()||"/"),"string"==typeof e.tokenUrl&&(n.tokenUrl=e.tokenUrl.split("?")[0].trim()||"/"),n.scopes=e.scopes||{},e.flows={},e.flows[r]=n,delete e.flow,delete e.authorizationUrl,delete e.tokenUrl,delete e.scopes,void 0!==e.name&&(t.patch?(t.patches++,delete e.name):A("(Patchable) oauth2 securitySchemes should not have name property",t))}}function j(e){return e&&!e["x-s2o-delete"]}function L(e,t){if(e.$ref)e.$ref=e.$ref.replace("#/responses/","#/components/responses/");else{e.type&&!e.schema&&(e.schema={}),e.type&&(e.schema.type=e.type),e.items&&"array"!==e.items.type&&(e.items.collectionFormat!==e.collectionFormat&&R("Nested collectionFormats are not supported",e,t),delete e.items.collectionFormat),"array"===e.type?("ssv"===e.collectionFormat?R("collectionFormat:ssv is no longer supported for headers",e,t):"pipes"===e.collectionFormat?R("collectionFormat:pipes is no longer supported for headers",e,t):"multi"===e.collectionFormat?e.explode=!0:"tsv"===e.collectionFormat?(R("collectionFormat:tsv is no longer supported",e,t),e["x-collectionFormat"]="tsv"):e.style="simple",delete e.collectionFormat):e.collectionFormat&&(t.patch?(t.patches++,delete e.collectionFormat):A("(Patchable) collectionFormat is only applicable to header.type array",t)),delete e.type;var n,o=r(k.parameterTypeProperties);try{for(o.s();!(n=o.n()).done;){var i=n.value;void 0!==e[i]&&(e.schema[i]=e[i],delete e[i])}}catch(e){o.e(e)}finally{o.f()}var a,s=r(k.arrayProperties);try{for(s.s();!(a=s.n()).done;){var l=a.value;void 0!==e[l]&&(e.schema[l]=e[l],delete e[l])}}catch(e){s.e(e)}finally{s.f()}}}function N(e,t){if(e.$ref.indexOf("#/parameters/")>=0){var n=e.$ref.split("#/parameters/");e.$ref=n[0]+"#/components/parameters/"+k.sanitise(n[1])}e.$ref.indexOf("#/definitions/")>=0&&R("Definition used as parameter",e,t)}function M(e,t,n,o,i,a,s){var l,c={},u=!0;if(t&&t.consumes&&"string"==typeof t.consumes){if(!s.patch)return A("(Patchable) operation.consumes must be an array",s);s.patches++,t.consumes=[t.consumes]}Array.isArray(a.consumes)||delete a.consumes;var p=((t?t.consumes:null)||a.consumes||[]).filter(k.uniqueOnly);if(e&&e.$ref&&"string"==typeof e.$ref){N(e,s);var f=decodeURIComponent(e.$ref.replace("#/components/parameters/","")),d=!1,h=a.components.parameters[f];if(h&&!h["x-s2o-delete"]||!e.$ref.startsWith("#/")||(e["x-s2o-delete"]=!0,d=!0),d){var v=e.$ref,y=m(a,e.$ref);!y&&v.startsWith("#/")?R("Could not resolve reference "+v,e,s):y&&(e=y)}}if(e&&(e.name||e.in)){"boolean"==typeof e["x-deprecated"]&&(e.deprecated=e["x-deprecated"],delete e["x-deprecated"]),void 0!==e["x-example"]&&(e.example=e["x-example"],delete e["x-example"]),"body"===e.in||e.type||(s.patch?(s.patches++,e.type="string"):A("(Patchable) parameter.type is mandatory for non-body parameters",s)),e.type&&"object"==typeof e.type&&e.type.$ref&&(e.type=m(a,e.type.$ref)),"file"===e.type&&(e["x-s2o-originalType"]=e.type,l=e.type),e.description&&"object"==typeof e.description&&e.description.$ref&&(e.description=m(a,e.description.$ref)),null===e.description&&delete e.description;var x=e.collectionFormat;if("array"!==e.type||x||(x="csv"),x&&("array"!==e.type&&(s.patch?(s.patches++,delete e.collectionFormat):A("(Patchable) collectionFormat is only applicable to param.type array",s)),"csv"!==x||"query"!==e.in&&"cookie"!==e.in||(e.style="form",e.explode=!1),"csv"!==x||"path"!==e.in&&"header"!==e.in||(e.style="simple"),"ssv"===x&&("query"===e.in?e.style="spaceDelimited":R("collectionFormat:ssv is no longer supported except for in:query parameters",e,s)),"pipes"===x&&("query"===e.in?e.style="pipeDelimited":R("collectionFormat:pipes is no longer supported except for in:query parameters",e,s)),"multi"===x&&(e.explode=!0),"tsv"===x&&(R("collectionFormat:tsv is no longer supported",e,s),e["x-collectionFormat"]="tsv"),delete e.collectionFormat),e.type&&"body"!==e.type&&"formData"!==e.in)if(e.items&&e.schema)R("parameter has array,items and schema",e,s);else{e.schema&&s.patches++,e.schema&&"object"==typeof e.schema||(e.schema={}),e.schema.type=e.type,e.items&&(e.schema.items=e.items,delete e.items,b(e.schema.items,null,(function(t,n,r){"collectionFormat"===n&&"string"==typeof t[n]&&(x&&t[n]!==x&&R("Nested collectionFormats are not supported",e,s),delete t[n])})));var w,E=r(k.parameterTypeProperties);try{for(E.s();!(w=E.n()).done;){var S=w.value;void 0!==e[S]&&(e.schema[S]=e[S]),delete e[S]}}catch(e){E.e(e)}finally{E.f()}}e.schema&&C(e.schema,s),e["x-ms-skip-url-encoding"]&&"query"===e.in&&(e.allowReserved=!0,delete e["x-ms-skip-url-encoding"])}if(e&&"formData"===e.in){u=!1,c.content={};var _="application/x-www-form-urlencoded";if(p.length&&p.indexOf("multipart/form-data")>=0&&(_="multipart/form-data"),c.content[_]={},e.schema)c.content[_].schema=e.schema,e.schema.$ref&&(c["x-s2o-name"]=decodeURIComponent(e.schema.$ref.replace("#/components/schemas/","")));else{c.content[_].schema={},c.content[_].schema.type="object",c.content[_].schema.properties={},c.content[_].schema.properties[e.name]={};var O=c.content[_].schema,T=c.content[_].schema.properties[e.name];e.description&&(T.description=e.description),e.example&&(T.example=e.example),e.type&&(T.type=e.type);var P,I=r(k.parameterTypeProperties);try{for(I.s();!(P=I.n()).done;){var j=P.value;void 0!==e[j]&&(T[j]=e[j])}}catch(e){I.e(e)}finally{I.f()}!0===e.required&&(O.required||(O.required=[]),O.required.push(e.name),c.required=!0),void 0!==e.default&&(T.default=e.default),T.properties&&(T.properties=e.properties),e.allOf&&(T.allOf=e.allOf),"array"===e.type&&e.items&&(T.items=e.items,T.items.collectionFormat&&delete T.items.collectionFormat),"file"!==l&&"file"!==e["x-s2o-originalType"]||(T.type="string",T.format="binary"),D(e,T)}}else e&&"file"===e.type&&(e.required&&(c.required=e.required),c.content={},c.content["application/octet-stream"]={},c.content["application/octet-stream"].schema={},c.content["application/octet-stream"].schema.type="string",c.content["application/octet-stream"].schema.format="binary",D(e,c));if(e&&"body"===e.in){c.content={},e.name&&(c["x-s2o-name"]=(t&&t.operationId?k.sanitiseAll(t.operationId):"")+("_"+e.name).toCamelCase()),e.description&&(c.description=e.description),e.required&&(c.required=e.required),t&&s.rbname&&e.name&&(t[s.rbname]=e.name),e.schema&&e.schema.$ref?c["x-s2o-name"]=decodeURIComponent(e.schema.$ref.replace("#/components/schemas/","")):e.schema&&"array"===e.schema.type&&e.schema.items&&e.schema.items.$ref&&(c["x-s2o-name"]=decodeURIComponent(e.schema.items.$ref.replace("#/components/schemas/",""))+"Array"),p.length||p.push("application/json");var L,M=r(p);try{for(M.s();!(L=M.n()).done;){var F=L.value;c.content[F]={},c.content[F].schema=g(e.schema||{}),C(c.content[F].schema,s)}}catch(e){M.e(e)}finally{M.f()}D(e,c)}if(Object.keys(c).length>0&&(e["x-s2o-delete"]=!0,t&&(t.requestBody&&u?(t.requestBody["x-s2o-overloaded"]=!0,R("Operation "+(t.operationId||i)+" has multiple requestBodies",t,s)):(t.requestBody||(t=n[o]=function(e,t){for(var n={},r=0,o=Object.keys(e);r<o.length;r++){var i=o[r];n[i]=e[i],"parameters"===i&&(n.requestBody={},t.rbname&&(n[t.rbname]=""))}return n.requestBody={},n}(t,s)),t.requestBody.content&&t.requestBody.content["multipart/form-data"]&&t.requestBody.content["multipart/form-data"].schema&&t.requestBody.content["multipart/form-data"].schema.properties&&c.content["multipart/form-data"]&&c.content["multipart/form-data"].schema&&c.content["multipart/form-data"].schema.properties?(t.requestBody.content["multipart/form-data"].schema.properties=Object.assign(t.requestBody.content["multipart/form-data"].schema.properties,c.content["multipart/form-data"].schema.properties),t.requestBody.content["multipart/form-data"].schema.required=(t.requestBody.content["multipart/form-data"].schema.required||[]).concat(c.content["multipart/form-data"].schema.required||[]),t.requestBody.content["multipart/form-data"].schema.required.length||delete t.requestBody.content["multipart/form-data"].schema.required):t.requestBody.content&&t.requestBody.content["application/x-www-form-urlencoded"]&&t.requestBody.content["application/x-www-form-urlencoded"].schema&&t.requestBody.content["application/x-www-form-urlencoded"].schema.properties&&c.content["application/x-www-form-urlencoded"]&&c.content["application/x-www-form-urlencoded"].schema&&c.content["application/x-www-form-urlencoded"].schema.properties?(t.requestBody.content["application/x-www-form-urlencoded"].schema.properties=Object.assign(t.requestBody.content["application/x-www-form-urlencoded"].schema.properties,c.content["application/x-www-form-urlencoded"].schema.properties),t.requestBody.content["application/x-www-form-urlencoded"].schema.required=(t.requestBody.content["application/x-www-form-urlencoded"].schema.required||[]).concat(c.content["application/x-www-form-urlencoded"].schema.required||[]),t.requestBody.content["application/x-www-form-urlencoded"].schema.required.length||delete t.requestBody.content["application/x-www-form-urlencoded"].schema.required):(t.requestBody=Object.assign(t.requestBody,c),t.requestBody["x-s2o-name"]||(t.requestBody.schema&&t.requestBody.schema.$ref?t.requestBody["x-s2o-name"]=decodeURIComponent(t.requestBody.schema.$ref.replace("#/components/schemas/","")).split("/").join(""):t.operationId&&(t.requestBody["x-s2o-name"]=k.sanitiseAll(t.operationId))))))),e&&!e["x-s2o-delete"]){delete e.type;var z,U=r(k.parameterTypeProperties);try{for(U.s();!(z=U.n()).done;){var B=z.value;delete e[B]}}catch(e){U.e(e)}finally{U.f()}"path"!==e.in||void 0!==e.required&&!0===e.required||(s.patch?(s.patches++,e.required=!0):A("(Patchable) path parameters must be required:true ["+e.name+" in "+i+"]",s))}return t}function D(e,t){for(var n in e)n.startsWith("x-")&&!n.startsWith("x-s2o")&&(t[n]=e[n])}function F(e,t,n,o,i){if(!e)return!1;if(e.$ref&&"string"==typeof e.$ref)e.$ref.indexOf("#/definitions/")>=0?R("definition used as response: "+e.$ref,e,i):e.$ref.startsWith("#/responses/")&&(e.$ref="#/components/responses/"+k.sanitise(decodeURIComponent(e.$ref.replace("#/responses/",""))));else{if((void 0===e.description||null===e.description||""===e.description&&i.patch)&&(i.patch?"object"!=typeof e||Array.isArray(e)||(i.patches++,e.description=E[e]||""):A("(Patchable) response.description is mandatory",i)),void 0!==e.schema){if(C(e.schema,i),e.schema.$ref&&"string"==typeof e.schema.$ref&&e.schema.$ref.startsWith("#/responses/")&&(e.schema.$ref="#/components/responses/"+k.sanitise(decodeURIComponent(e.schema.$ref.replace("#/responses/","")))),n&&n.produces&&"string"==typeof n.produces){if(!i.patch)return A("(Patchable) operation.produces must be an array",i);i.patches++,n.produces=[n.produces]}o.produces&&!Array.isArray(o.produces)&&delete o.produces;var a=((n?n.produces:null)||o.produces||[]).filter(k.uniqueOnly);a.length||a.push("*/*"),e.content={};var s,l=r(a);try{for(l.s();!(s=l.n()).done;){var c=s.value;if(e.content[c]={},e.content[c].schema=g(e.schema),e.examples&&e.examples[c]){var u={};u.value=e.examples[c],e.content[c].examples={},e.content[c].examples.response=u,delete e.examples[c]}"file"===e.content[c].schema.type&&(e.content[c].schema={type:"string",format:"binary"})}}catch(e){l.e(e)}finally{l.f()}delete e.schema}for(var p in e.examples)e.content||(e.content={}),e.content[p]||(e.content[p]={}),e.content[p].examples={},e.content[p].examples.response={},e.content[p].examples.response.value=e.examples[p];if(delete e.examples,e.headers)for(var f in e.headers)"status code"===f.toLowerCase()?i.patch?(i.patches++,delete e.headers[f]):A('(Patchable) "Status Code" is not a valid header',i):L(e.headers[f],i)}}function z(e,t,n,o,i){for(var a in e){var s=e[a];for(var l in s&&s["x-trace"]&&"object"==typeof s["x-trace"]&&(s.trace=s["x-trace"],delete s["x-trace"]),s&&s["x-summary"]&&"string"==typeof s["x-summary"]&&(s.summary=s["x-summary"],delete s["x-summary"]),s&&s["x-description"]&&"string"==typeof s["x-description"]&&(s.description=s["x-description"],delete s["x-description"]),s&&s["x-servers"]&&Array.isArray(s["x-servers"])&&(s.servers=s["x-servers"],delete s["x-servers"]),s)if(k.httpMethods.indexOf(l)>=0||"x-amazon-apigateway-any-method"===l){var c=s[l];if(c&&c.parameters&&Array.isArray(c.parameters)){if(s.parameters){var p,f=r(s.parameters);try{var d=function(){var e=p.value;"string"==typeof e.$ref&&(N(e,n),e=m(i,e.$ref)),c.parameters.find((function(t,n,r){return t.name===e.name&&t.in===e.in}))||"formData"!==e.in&&"body"!==e.in&&"file"!==e.type||(c=M(e,c,s,l,a,i,n),n.rbname&&""===c[n.rbname]&&delete c[n.rbname])};for(f.s();!(p=f.n()).done;)d()}catch(e){f.e(e)}finally{f.f()}}var v,y=r(c.parameters);try{for(y.s();!(v=y.n()).done;){var b=v.value;c=M(b,c,s,l,l+":"+a,i,n)}}catch(e){y.e(e)}finally{y.f()}n.rbname&&""===c[n.rbname]&&delete c[n.rbname],n.debug||c.parameters&&(c.parameters=c.parameters.filter(j))}if(c&&c.security&&P(c.security),"object"==typeof c){if(!c.responses){c.responses={default:{description:"Default response"}}}for(var x in c.responses)F(c.responses[x],0,c,i,n)}if(c&&c["x-servers"]&&Array.isArray(c["x-servers"]))c.servers=c["x-servers"],delete c["x-servers"];else if(c&&c.schemes&&c.schemes.length){var w,E=r(c.schemes);try{for(E.s();!(w=E.n()).done;){var S=w.value;if((!i.schemes||i.schemes.indexOf(S)<0)&&(c.servers||(c.servers=[]),Array.isArray(i.servers))){var _,O=r(i.servers);try{for(O.s();!(_=O.n()).done;){var A=_.value,R=g(A),C=u.parse(R.url);C.protocol=S,R.url=C.format(),c.servers.push(R)}}catch(e){O.e(e)}finally{O.f()}}}}catch(e){E.e(e)}finally{E.f()}}if(n.debug&&(c["x-s2o-consumes"]=c.consumes||[],c["x-s2o-produces"]=c.produces||[]),c){if(delete c.consumes,delete c.produces,delete c.schemes,c["x-ms-examples"]){for(var T in c["x-ms-examples"]){var I=c["x-ms-examples"][T],L=k.sanitiseAll(T);if(I.parameters)for(var D in I.parameters){var z,U=I.parameters[D],B=r((c.parameters||[]).concat(s.parameters||[]));try{for(B.s();!(z=B.n()).done;){var $=z.value;$.$ref&&($=h.jptr(i,$.$ref)),$.name!==D||$.example||($.examples||($.examples={}),$.examples[T]={value:U})}}catch(e){B.e(e)}finally{B.f()}}if(I.responses)for(var q in I.responses){if(I.responses[q].headers)for(var W in I.responses[q].headers){var V=I.responses[q].headers[W];for(var H in c.responses[q].headers)H===W&&(c.responses[q].headers[H].example=V)}if(I.responses[q].body&&(i.components.examples[L]={value:g(I.responses[q].body)},c.responses[q]&&c.responses[q].content))for(var Y in c.responses[q].content){var G=c.responses[q].content[Y];G.examples||(G.examples={}),G.examples[T]={$ref:"#/components/examples/"+L}}}}delete c["x-ms-examples"]}if(c.parameters&&0===c.parameters.length&&delete c.parameters,c.requestBody){var Q=c.operationId?k.sanitiseAll(c.operationId):k.sanitiseAll(l+a).toCamelCase(),X=k.sanitise(c.requestBody["x-s2o-name"]||Q||"");delete c.requestBody["x-s2o-name"];var K=JSON.stringify(c.requestBody),Z=k.hash(K);if(!o[Z]){var J={};J.name=X,J.body=c.requestBody,J.refs=[],o[Z]=J}var ee="#/"+t+"/"+encodeURIComponent(h.jpescape(a))+"/"+l+"/requestBody";o[Z].refs.push(ee)}}}if(s&&s.parameters){for(var te in s.parameters)M(s.parameters[te],null,s,null,a,i,n);!n.debug&&Array.isArray(s.parameters)&&(s.parameters=s.parameters.filter(j))}}}function U(e){return e&&e.url&&"string"==typeof e.url?(e.url=e.url.split("{{").join("{"),e.url=e.url.split("}}").join("}"),e.url.replace(/\{(.+?)\}/g,(function(t,n){e.variables||(e.variables={}),e.variables[n]={default:"unknown"}})),e):e}function B(e,t,n){if(void 0===e.info||null===e.info){if(!t.patch)return n(new O("(Patchable) info object is mandatory"));t.patches++,e.info={version:"",title:""}}if("object"!=typeof e.info||Array.isArray(e.info))return n(new O("info must be an object"));if(void 0===e.info.title||null===e.info.title){if(!t.patch)return n(new O("(Patchable) info.title cannot be null"));t.patches++,e.info.title=""}if(void 0===e.info.version||null===e.info.version){if(!t.patch)return n(new O("(Patchable) info.version cannot be null"));t.patches++,e.info.version=""}if("string"!=typeof e.info.version){if(!t.patch)return n(new O("(Patchable) info.version must be a string"));t.patches++,e.info.version=e.info.version.toString()}if(void 0!==e.info.logo){if(!t.patch)return n(new O("(Patchable) info should not have logo property"));t.patches++,e.info["x-logo"]=e.info.logo,delete e.info.logo}if(void 0!==e.info.termsOfService){if(null===e.info.termsOfService){if(!t.patch)return n(new O("(Patchable) info.termsOfService cannot be null"));t.patches++,e.info.termsOfService=""}try{new URL(e.info.termsOfService)}catch(r){if(!t.patch)return n(new O("(Patchable) info.termsOfService must be a URL"));t.patches++,delete e.info.termsOfService}}}function $(e,t,n){if(void 0===e.paths){if(!t.patch)return n(new O("(Patchable) paths object is mandatory"));t.patches++,e.paths={}}}function q(e,t,n){return p(n,new Promise((function(n,o){if(e||(e={}),t.original=e,t.text||(t.text=d.stringify(e)),t.externals=[],t.externalRefs={},t.rewriteRefs=!0,t.preserveMiro=!0,t.promise={},t.promise.resolve=n,t.promise.reject=o,t.patches=0,t.cache||(t.cache={}),t.source&&(t.cache[t.source]=t.original),function(e,t){var n=new WeakSet;b(e,{identityDetection:!0},(function(e,r,o){"object"==typeof e[r]&&null!==e[r]&&(n.has(e[r])?t.anchors?e[r]=g(e[r]):A("YAML anchor or merge key at "+o.path,t):n.add(e[r]))}))}(e,t),e.openapi&&"string"==typeof e.openapi&&e.openapi.startsWith("3."))return t.openapi=y(e),B(t.openapi,t,o),$(t.openapi,t,o),void x.optionalResolve(t).then((function(){return t.direct?n(t.openapi):n(t)})).catch((function(e){console.warn(e),o(e)}));if(!e.swagger||"2.0"!=e.swagger)return o(new O("Unsupported swagger/OpenAPI version: "+(e.openapi?e.openapi:e.swagger)));var i=t.openapi={};if(i.openapi="string"==typeof t.targetVersion&&t.targetVersion.startsWith("3.")?t.targetVersion:_,t.origin){i["x-origin"]||(i["x-origin"]=[]);var a={};a.url=t.source||t.origin,a.format="swagger",a.version=e.swagger,a.converter={},a.converter.url="https://github.com/mermade/oas-kit",a.converter.version=S,i["x-origin"].push(a)}if(delete(i=Object.assign(i,y(e))).swagger,b(i,{},(function(e,t,n){null===e[t]&&!t.startsWith("x-")&&"default"!==t&&n.path.indexOf("/example")<0&&delete e[t]})),e.host){var s,c=r(Array.isArray(e.schemes)?e.schemes:[""]);try{for(c.s();!(s=c.n()).done;){var u=s.value,p={},f=(e.basePath||"").replace(/\/$/,"");p.url=(u?u+":":"")+"//"+e.host+f,U(p),i.servers||(i.servers=[]),i.servers.push(p)}}catch(e){c.e(e)}finally{c.f()}}else if(e.basePath){var v={};v.url=e.basePath,U(v),i.servers||(i.servers=[]),i.servers.push(v)}if(delete i.host,delete i.basePath,i["x-servers"]&&Array.isArray(i["x-servers"])&&(i.servers=i["x-servers"],delete i["x-servers"]),e["x-ms-parameterized-host"]){var w=e["x-ms-parameterized-host"],E={};E.url=w.hostTemplate+(e.basePath?e.basePath:""),E.variables={};var R=E.url.match(/\{\w+\}/g);for(var j in w.parameters){var N=w.parameters[j];N.$ref&&(N=g(m(i,N.$ref))),j.startsWith("x-")||(delete N.required,delete N.type,delete N.in,void 0===N.default&&(N.enum?N.default=N.enum[0]:N.default="none"),N.name||(N.name=R[j].replace("{","").replace("}","")),E.variables[N.name]=N,delete N.name)}i.servers||(i.servers=[]),!1===w.useSchemePrefix?i.servers.push(E):e.schemes.forEach((function(e){i.servers.push(Object.assign({},E,{url:e+"://"+E.url}))})),delete i["x-ms-parameterized-host"]}B(i,t,o),$(i,t,o),"string"==typeof i.consumes&&(i.consumes=[i.consumes]),"string"==typeof i.produces&&(i.produces=[i.produces]),i.components={},i["x-callbacks"]&&(i.components.callbacks=i["x-callbacks"],delete i["x-callbacks"]),i.components.examples={},i.components.headers={},i["x-links"]&&(i.components.links=i["x-links"],delete i["x-links"]),i.components.parameters=i.parameters||{},i.components.responses=i.responses||{},i.components.requestBodies={},i.components.securitySchemes=i.securityDefinitions||{},i.components.schemas=i.definitions||{},delete i.definitions,delete i.responses,delete i.parameters,delete i.securityDefinitions,x.optionalResolve(t).then((function(){(function(e,t){var n={};for(var r in l={schemas:{}},e.security&&P(e.security),e.components.securitySchemes){var o=k.sanitise(r);r!==o&&(e.components.securitySchemes[o]&&A("Duplicate sanitised securityScheme name "+o,t),e.components.securitySchemes[o]=e.components.securitySchemes[r],delete e.components.securitySchemes[r]),I(e.components.securitySchemes[o],t)}for(var i in e.components.schemas){var a=k.sanitiseAll(i),s="";if(i!==a){for(;e.components.schemas[a+s];)s=s?++s:2;e.components.schemas[a+s]=e.components.schemas[i],delete e.components.schemas[i]}l.schemas[i]=a+s,C(e.components.schemas[a+s],t)}for(var c in t.refmap={},b(e,{payload:{options:t}},T),function(e,t){for(var n in t.refmap)h.jptr(e,n,{$ref:t.refmap[n]})}(e,t),e.components.parameters){var u=k.sanitise(c);c!==u&&(e.components.parameters[u]&&A("Duplicate sanitised parameter name "+u,t),e.components.parameters[u]=e.components.parameters[c],delete e.components.parameters[c]),M(e.components.parameters[u],null,null,null,u,e,t)}for(var p in e.components.responses){var f=k.sanitise(p);p!==f&&(e.components.responses[f]&&A("Duplicate sanitised response name "+f,t),e.components.responses[f]=e.components.responses[p],delete e.components.responses[p]);var d=e.components.responses[f];if(F(d,0,null,e,t),d.headers)for(var m in d.headers)"status code"===m.toLowerCase()?t.patch?(t.patches++,delete d.headers[m]):A('(Patchable) "Status Code" is not a valid header',t):L(d.headers[m],t)}for(var v in e.components.requestBodies){var y=e.components.requestBodies[v],x=JSON.stringify(y),w=k.hash(x),E={};E.name=v,E.body=y,E.refs=[],n[w]=E}if(z(e.paths,"paths",t,n,e),e["x-ms-paths"]&&z(e["x-ms-paths"],"x-ms-paths",t,n,e),!t.debug)for(var S in e.components.parameters)e.components.parameters[S]["x-s2o-delete"]&&delete e.components.parameters[S];t.debug&&(e["x-s2o-consumes"]=e.consumes||[],e["x-s2o-produces"]=e.produces||[]),delete e.consumes,delete e.produces,delete e.schemes;var _=[];if(e.components.requestBodies={},!t.resolveInternal){var O=1;for(var R in n){var j=n[R];if(j.refs.length>1){var N="";for(j.name||(j.name="requestBody",N=O++);_.indexOf(j.name+N)>=0;)N=N?++N:2;for(var D in j.name=j.name+N,_.push(j.name),e.components.requestBodies[j.name]=g(j.body),j.refs){var U={};U.$ref="#/components/requestBodies/"+j.name,h.jptr(e,j.refs[D],U)}}}}e.components.responses&&0===Object.keys(e.components.responses).length&&delete e.components.responses,e.components.parameters&&0===Object.keys(e.components.parameters).length&&delete e.components.parameters,e.components.examples&&0===Object.keys(e.components.examples).length&&delete e.components.examples,e.components.requestBodies&&0===Object.keys(e.components.requestBodies).length&&delete e.components.requestBodies,e.components.securitySchemes&&0===Object.keys(e.components.securitySchemes).length&&delete e.components.securitySchemes,e.components.headers&&0===Object.keys(e.components.headers).length&&delete e.components.headers,e.components.schemas&&0===Object.keys(e.components.schemas).length&&delete e.components.schemas,e.components&&0===Object.keys(e.components).length&&delete e.components})(t.openapi,t),t.direct?n(t.openapi):n(t)})).catch((function(e){console.warn(e),o(e)}))})))}function W(e,t,n){return p(n,new Promise((function(n,r){var o=null,i=null;try{o=JSON.parse(e),t.text=JSON.stringify(o,null,2)}catch(n){i=n;try{o=d.parse(e,{schema:"core",prettyErrors:!0}),t.sourceYaml=!0,t.text=e}catch(e){i=e}}o?q(o,t).then((function(e){return n(e)})).catch((function(e){return r(e)})):r(new O(i?i.message:"Could not parse string"))})))}e.exports={S2OError:O,targetVersion:_,convert:q,convertObj:q,convertUrl:function(e,t,n){return p(n,new Promise((function(n,r){t.origin=!0,t.source||(t.source=e),t.verbose&&console.warn("GET "+e),t.fetch||(t.fetch=f);var o=Object.assign({},t.fetchOptions,{agent:t.agent});t.fetch(e,o).then((function(t){if(200!==t.status)throw new O("Received status code ".concat(t.status,": ").concat(e));return t.text()})).then((function(e){W(e,t).then((function(e){return n(e)})).catch((function(e){return r(e)}))})).catch((function(e){r(e)}))})))},convertStr:W,convertFile:function(e,t,n){return p(n,new Promise((function(n,r){c.readFile(e,t.encoding||"utf8",(function(o,i){o?r(o):(t.sourceFile=e,W(i,t).then((function(e){return n(e)})).catch((function(e){return r(e)})))}))})))},convertStream:function(e,t,n){return p(n,new Promise((function(n,r){var o="";e.on("data",(function(e){o+=e})).on("end",(function(){W(o,t).then((function(e){return n(e)})).catch((function(e){return r(e)}))}))})))}}},873:function(e,t,n){"use strict";n(9601);var r=n(6177);e.exports={statusCodes:Object.assign({},{default:"Default response","1XX":"Informational",103:"Early hints","2XX":"Successful","3XX":"Redirection","4XX":"Client Error","5XX":"Server Error","7XX":"Developer Error"},r.STATUS_CODES)}},1683:function(e){e.exports={}},5623:function(e){"use strict";function t(e,t,o){e instanceof RegExp&&(e=n(e,o)),t instanceof RegExp&&(t=n(t,o));var i=r(e,t,o);return i&&{start:i[0],end:i[1],pre:o.slice(0,i[0]),body:o.slice(i[0]+e.length,i[1]),post:o.slice(i[1]+t.length)}}function n(e,t){var n=t.match(e);return n?n[0]:null}function r(e,t,n){var r,o,i,a,s,l=n.indexOf(e),c=n.indexOf(t,l+1),u=l;if(l>=0&&c>0){if(e===t)return[l,c];for(r=[],i=n.length;u>=0&&!s;)u==l?(r.push(u),l=n.indexOf(e,u+1)):1==r.length?s=[r.pop(),c]:((o=r.pop())<i&&(i=o,a=c),c=n.indexOf(t,u+1)),u=l<c&&l>=0?l:c;r.length&&(s=[i,a])}return s}e.exports=t,t.range=r},9742:function(e,t){"use strict";t.byteLength=function(e){var t=l(e),n=t[0],r=t[1];return 3*(n+r)/4-r},t.toByteArray=function(e){var t,n,i=l(e),a=i[0],s=i[1],c=new o(function(e,t,n){return 3*(t+n)/4-n}(0,a,s)),u=0,p=s>0?a-4:a;for(n=0;n<p;n+=4)t=r[e.charCodeAt(n)]<<18|r[e.charCodeAt(n+1)]<<12|r[e.charCodeAt(n+2)]<<6|r[e.charCodeAt(n+3)],c[u++]=t>>16&255,c[u++]=t>>8&255,c[u++]=255&t;return 2===s&&(t=r[e.charCodeAt(n)]<<2|r[e.charCodeAt(n+1)]>>4,c[u++]=255&t),1===s&&(t=r[e.charCodeAt(n)]<<10|r[e.charCodeAt(n+1)]<<4|r[e.charCodeAt(n+2)]>>2,c[u++]=t>>8&255,c[u++]=255&t),c},t.fromByteArray=function(e){for(var t,r=e.length,o=r%3,i=[],a=16383,s=0,l=r-o;s<l;s+=a)i.push(c(e,s,s+a>l?l:s+a));return 1===o?(t=e[r-1],i.push(n[t>>2]+n[t<<4&63]+"==")):2===o&&(t=(e[r-2]<<8)+e[r-1],i.push(n[t>>10]+n[t>>4&63]+n[t<<2&63]+"=")),i.join("")};for(var n=[],r=[],o="undefined"!=typeof Uint8Array?Uint8Array:Array,i="ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/",a=0,s=i.length;a<s;++a)n[a]=i[a],r[i.charCodeAt(a)]=a;function l(e){var t=e.length;if(t%4>0)throw new Error("Invalid string. Length must be a multiple of 4");var n=e.indexOf("=");return-1===n&&(n=t),[n,n===t?0:4-n%4]}function c(e,t,r){for(var o,i,a=[],s=t;s<r;s+=3)o=(e[s]<<16&16711680)+(e[s+1]<<8&65280)+(255&e[s+2]),a.push(n[(i=o)>>18&63]+n[i>>12&63]+n[i>>6&63]+n[63&i]);return a.join("")}r["-".charCodeAt(0)]=62,r["_".charCodeAt(0)]=63},3644:function(e,t,n){var r=n(1048),o=n(5623);e.exports=function(e){return e?("{}"===e.substr(0,2)&&(e="\\{\\}"+e.substr(2)),g(function(e){return e.split("\\\\").join(i).split("\\{").join(a).split("\\}").join(s).split("\\,").join(l).split("\\.").join(c)}(e),!0).map(p)):[]};var i="\0SLASH"+Math.random()+"\0",a="\0OPEN"+Math.random()+"\0",s="\0CLOSE"+Math.random()+"\0",l="\0COMMA"+Math.random()+"\0",c="\0PERIOD"+Math.random()+"\0";function u(e){return parseInt(e,10)==e?parseInt(e,10):e.charCodeAt(0)}function p(e){return e.split(i).join("\\").split(a).join("{").split(s).join("}").split(l).join(",").split(c).join(".")}function f(e){if(!e)return[""];var t=[],n=o("{","}",e);if(!n)return e.split(",");var r=n.pre,i=n.body,a=n.post,s=r.split(",");s[s.length-1]+="{"+i+"}";var l=f(a);return a.length&&(s[s.length-1]+=l.shift(),s.push.apply(s,l)),t.push.apply(t,s),t}function d(e){return"{"+e+"}"}function h(e){return/^-?0\d/.test(e)}function m(e,t){return e<=t}function v(e,t){return e>=t}function g(e,t){var n=[],i=o("{","}",e);if(!i||/\$$/.test(i.pre))return[e];var a,l=/^-?\d+\.\.-?\d+(?:\.\.-?\d+)?$/.test(i.body),c=/^[a-zA-Z]\.\.[a-zA-Z](?:\.\.-?\d+)?$/.test(i.body),p=l||c,y=i.body.indexOf(",")>=0;if(!p&&!y)return i.post.match(/,.*\}/)?g(e=i.pre+"{"+i.body+s+i.post):[e];if(p)a=i.body.split(/\.\./);else if(1===(a=f(i.body)).length&&1===(a=g(a[0],!1).map(d)).length)return(w=i.post.length?g(i.post,!1):[""]).map((function(e){return i.pre+a[0]+e}));var b,x=i.pre,w=i.post.length?g(i.post,!1):[""];if(p){var k=u(a[0]),E=u(a[1]),S=Math.max(a[0].length,a[1].length),_=3==a.length?Math.abs(u(a[2])):1,O=m;E<k&&(_*=-1,O=v);var A=a.some(h);b=[];for(var R=k;O(R,E);R+=_){var C;if(c)"\\"===(C=String.fromCharCode(R))&&(C="");else if(C=String(R),A){var T=S-C.length;if(T>0){var P=new Array(T+1).join("0");C=R<0?"-"+P+C.slice(1):P+C}}b.push(C)}}else b=r(a,(function(e){return g(e,!1)}));for(var I=0;I<b.length;I++)for(var j=0;j<w.length;j++){var L=x+b[I]+w[j];(!t||p||L)&&n.push(L)}return n}},8764:function(e,t,n){"use strict";var r=n(9742),o=n(645),i="function"==typeof Symbol&&"function"==typeof Symbol.for?Symbol.for("nodejs.util.inspect.custom"):null;t.lW=l,t.h2=50;var a=2147483647;function s(e){if(e>a)throw new RangeError('The value "'+e+'" is invalid for option "size"');var t=new Uint8Array(e);return Object.setPrototypeOf(t,l.prototype),t}function l(e,t,n){if("number"==typeof e){if("string"==typeof t)throw new TypeError('The "string" argument must be of type string. Received type number');return p(e)}return c(e,t,n)}function c(e,t,n){if("string"==typeof e)return function(e,t){if("string"==typeof t&&""!==t||(t="utf8"),!l.isEncoding(t))throw new TypeError("Unknown encoding: "+t);var n=0|m(e,t),r=s(n),o=r.write(e,t);return o!==n&&(r=r.slice(0,o)),r}(e,t);if(ArrayBuffer.isView(e))return function(e){if(B(e,Uint8Array)){var t=new Uint8Array(e);return d(t.buffer,t.byteOffset,t.byteLength)}return f(e)}(e);if(null==e)throw new TypeError("The first argument must be one of type string, Buffer, ArrayBuffer, Array, or Array-like Object. Received type "+typeof e);if(B(e,ArrayBuffer)||e&&B(e.buffer,ArrayBuffer))return d(e,t,n);if("undefined"!=typeof SharedArrayBuffer&&(B(e,SharedArrayBuffer)||e&&B(e.buffer,SharedArrayBuffer)))return d(e,t,n);if("number"==typeof e)throw new TypeError('The "value" argument must not be of type number. Received type number');var r=e.valueOf&&e.valueOf();if(null!=r&&r!==e)return l.from(r,t,n);var o=function(e){if(l.isBuffer(e)){var t=0|h(e.length),n=s(t);return 0===n.length||e.copy(n,0,0,t),n}return void 0!==e.length?"number"!=typeof e.length||$(e.length)?s(0):f(e):"Buffer"===e.type&&Array.isArray(e.data)?f(e.data):void 0}(e);if(o)return o;if("undefined"!=typeof Symbol&&null!=Symbol.toPrimitive&&"function"==typeof e[Symbol.toPrimitive])return l.from(e[Symbol.toPrimitive]("string"),t,n);throw new TypeError("The first argument must be one of type string, Buffer, ArrayBuffer, Array, or Array-like Object. Received type "+typeof e)}function u(e){if("number"!=typeof e)throw new TypeError('"size" argument must be of type number');if(e<0)throw new RangeError('The value "'+e+'" is invalid for option "size"')}function p(e){return u(e),s(e<0?0:0|h(e))}function f(e){for(var t=e.length<0?0:0|h(e.length),n=s(t),r=0;r<t;r+=1)n[r]=255&e[r];return n}function d(e,t,n){if(t<0||e.byteLength<t)throw new RangeError('"offset" is outside of buffer bounds');if(e.byteLength<t+(n||0))throw new RangeError('"length" is outside of buffer bounds');var r;return r=void 0===t&&void 0===n?new Uint8Array(e):void 0===n?new Uint8Array(e,t):new Uint8Array(e,t,n),Object.setPrototypeOf(r,l.prototype),r}function h(e){if(e>=a)throw new RangeError("Attempt to allocate Buffer larger than maximum size: 0x"+a.toString(16)+" bytes");return 0|e}function m(e,t){if(l.isBuffer(e))return e.length;if(ArrayBuffer.isView(e)||B(e,ArrayBuffer))return e.byteLength;if("string"!=typeof e)throw new TypeError('The "string" argument must be one of type string, Buffer, or ArrayBuffer. Received type '+typeof e);var n=e.length,r=arguments.length>2&&!0===arguments[2];if(!r&&0===n)return 0;for(var o=!1;;)switch(t){case"ascii":case"latin1":case"binary":return n;case"utf8":case"utf-8":return F(e).length;case"ucs2":case"ucs-2":case"utf16le":case"utf-16le":return 2*n;case"hex":return n>>>1;case"base64":return z(e).length;default:if(o)return r?-1:F(e).length;t=(""+t).toLowerCase(),o=!0}}function v(e,t,n){var r=!1;if((void 0===t||t<0)&&(t=0),t>this.length)return"";if((void 0===n||n>this.length)&&(n=this.length),n<=0)return"";if((n>>>=0)<=(t>>>=0))return"";for(e||(e="utf8");;)switch(e){case"hex":return T(this,t,n);case"utf8":case"utf-8":return O(this,t,n);case"ascii":return R(this,t,n);case"latin1":case"binary":return C(this,t,n);case"base64":return _(this,t,n);case"ucs2":case"ucs-2":case"utf16le":case"utf-16le":return P(this,t,n);default:if(r)throw new TypeError("Unknown encoding: "+e);e=(e+"").toLowerCase(),r=!0}}function g(e,t,n){var r=e[t];e[t]=e[n],e[n]=r}function y(e,t,n,r,o){if(0===e.length)return-1;if("string"==typeof n?(r=n,n=0):n>2147483647?n=2147483647:n<-2147483648&&(n=-2147483648),$(n=+n)&&(n=o?0:e.length-1),n<0&&(n=e.length+n),n>=e.length){if(o)return-1;n=e.length-1}else if(n<0){if(!o)return-1;n=0}if("string"==typeof t&&(t=l.from(t,r)),l.isBuffer(t))return 0===t.length?-1:b(e,t,n,r,o);if("number"==typeof t)return t&=255,"function"==typeof Uint8Array.prototype.indexOf?o?Uint8Array.prototype.indexOf.call(e,t,n):Uint8Array.prototype.lastIndexOf.call(e,t,n):b(e,[t],n,r,o);throw new TypeError("val must be string, number or Buffer")}function b(e,t,n,r,o){var i,a=1,s=e.length,l=t.length;if(void 0!==r&&("ucs2"===(r=String(r).toLowerCase())||"ucs-2"===r||"utf16le"===r||"utf-16le"===r)){if(e.length<2||t.length<2)return-1;a=2,s/=2,l/=2,n/=2}function c(e,t){return 1===a?e[t]:e.readUInt16BE(t*a)}if(o){var u=-1;for(i=n;i<s;i++)if(c(e,i)===c(t,-1===u?0:i-u)){if(-1===u&&(u=i),i-u+1===l)return u*a}else-1!==u&&(i-=i-u),u=-1}else for(n+l>s&&(n=s-l),i=n;i>=0;i--){for(var p=!0,f=0;f<l;f++)if(c(e,i+f)!==c(t,f)){p=!1;break}if(p)return i}return-1}function x(e,t,n,r){n=Number(n)||0;var o=e.length-n;r?(r=Number(r))>o&&(r=o):r=o;var i=t.length;r>i/2&&(r=i/2);for(var a=0;a<r;++a){var s=parseInt(t.substr(2*a,2),16);if($(s))return a;e[n+a]=s}return a}function w(e,t,n,r){return U(F(t,e.length-n),e,n,r)}function k(e,t,n,r){return U(function(e){for(var t=[],n=0;n<e.length;++n)t.push(255&e.charCodeAt(n));return t}(t),e,n,r)}function E(e,t,n,r){return U(z(t),e,n,r)}function S(e,t,n,r){return U(function(e,t){for(var n,r,o,i=[],a=0;a<e.length&&!((t-=2)<0);++a)r=(n=e.charCodeAt(a))>>8,o=n%256,i.push(o),i.push(r);return i}(t,e.length-n),e,n,r)}function _(e,t,n){return 0===t&&n===e.length?r.fromByteArray(e):r.fromByteArray(e.slice(t,n))}function O(e,t,n){n=Math.min(e.length,n);for(var r=[],o=t;o<n;){var i,a,s,l,c=e[o],u=null,p=c>239?4:c>223?3:c>191?2:1;if(o+p<=n)switch(p){case 1:c<128&&(u=c);break;case 2:128==(192&(i=e[o+1]))&&(l=(31&c)<<6|63&i)>127&&(u=l);break;case 3:i=e[o+1],a=e[o+2],128==(192&i)&&128==(192&a)&&(l=(15&c)<<12|(63&i)<<6|63&a)>2047&&(l<55296||l>57343)&&(u=l);break;case 4:i=e[o+1],a=e[o+2],s=e[o+3],128==(192&i)&&128==(192&a)&&128==(192&s)&&(l=(15&c)<<18|(63&i)<<12|(63&a)<<6|63&s)>65535&&l<1114112&&(u=l)}null===u?(u=65533,p=1):u>65535&&(u-=65536,r.push(u>>>10&1023|55296),u=56320|1023&u),r.push(u),o+=p}return function(e){var t=e.length;if(t<=A)return String.fromCharCode.apply(String,e);for(var n="",r=0;r<t;)n+=String.fromCharCode.apply(String,e.slice(r,r+=A));return n}(r)}l.TYPED_ARRAY_SUPPORT=function(){try{var e=new Uint8Array(1),t={foo:function(){return 42}};return Object.setPrototypeOf(t,Uint8Array.prototype),Object.setPrototypeOf(e,t),42===e.foo()}catch(e){return!1}}(),l.TYPED_ARRAY_SUPPORT||"undefined"==typeof console||"function"!=typeof console.error||console.error("This browser lacks typed array (Uint8Array) support which is required by `buffer` v5.x. Use `buffer` v4.x if you require old browser support."),Object.defineProperty(l.prototype,"parent",{enumerable:!0,get:function(){if(l.isBuffer(this))return this.buffer}}),Object.defineProperty(l.prototype,"offset",{enumerable:!0,get:function(){if(l.isBuffer(this))return this.byteOffset}}),l.poolSize=8192,l.from=function(e,t,n){return c(e,t,n)},Object.setPrototypeOf(l.prototype,Uint8Array.prototype),Object.setPrototypeOf(l,Uint8Array),l.alloc=function(e,t,n){return function(e,t,n){return u(e),e<=0?s(e):void 0!==t?"string"==typeof n?s(e).fill(t,n):s(e).fill(t):s(e)}(e,t,n)},l.allocUnsafe=function(e){return p(e)},l.allocUnsafeSlow=function(e){return p(e)},l.isBuffer=function(e){return null!=e&&!0===e._isBuffer&&e!==l.prototype},l.compare=function(e,t){if(B(e,Uint8Array)&&(e=l.from(e,e.offset,e.byteLength)),B(t,Uint8Array)&&(t=l.from(t,t.offset,t.byteLength)),!l.isBuffer(e)||!l.isBuffer(t))throw new TypeError('The "buf1", "buf2" arguments must be one of type Buffer or Uint8Array');if(e===t)return 0;for(var n=e.length,r=t.length,o=0,i=Math.min(n,r);o<i;++o)if(e[o]!==t[o]){n=e[o],r=t[o];break}return n<r?-1:r<n?1:0},l.isEncoding=function(e){switch(String(e).toLowerCase()){case"hex":case"utf8":case"utf-8":case"ascii":case"latin1":case"binary":case"base64":case"ucs2":case"ucs-2":case"utf16le":case"utf-16le":return!0;default:return!1}},l.concat=function(e,t){if(!Array.isArray(e))throw new TypeError('"list" argument must be an Array of Buffers');if(0===e.length)return l.alloc(0);var n;if(void 0===t)for(t=0,n=0;n<e.length;++n)t+=e[n].length;var r=l.allocUnsafe(t),o=0;for(n=0;n<e.length;++n){var i=e[n];if(B(i,Uint8Array))o+i.length>r.length?l.from(i).copy(r,o):Uint8Array.prototype.set.call(r,i,o);else{if(!l.isBuffer(i))throw new TypeError('"list" argument must be an Array of Buffers');i.copy(r,o)}o+=i.length}return r},l.byteLength=m,l.prototype._isBuffer=!0,l.prototype.swap16=function(){var e=this.length;if(e%2!=0)throw new RangeError("Buffer size must be a multiple of 16-bits");for(var t=0;t<e;t+=2)g(this,t,t+1);return this},l.prototype.swap32=function(){var e=this.length;if(e%4!=0)throw new RangeError("Buffer size must be a multiple of 32-bits");for(var t=0;t<e;t+=4)g(this,t,t+3),g(this,t+1,t+2);return this},l.prototype.swap64=function(){var e=this.length;if(e%8!=0)throw new RangeError("Buffer size must be a multiple of 64-bits");for(var t=0;t<e;t+=8)g(this,t,t+7),g(this,t+1,t+6),g(this,t+2,t+5),g(this,t+3,t+4);return this},l.prototype.toString=function(){var e=this.length;return 0===e?"":0===arguments.length?O(this,0,e):v.apply(this,arguments)},l.prototype.toLocaleString=l.prototype.toString,l.prototype.equals=function(e){if(!l.isBuffer(e))throw new TypeError("Argument must be a Buffer");return this===e||0===l.compare(this,e)},l.prototype.inspect=function(){var e="",n=t.h2;return e=this.toString("hex",0,n).replace(/(.{2})/g,"$1 ").trim(),this.length>n&&(e+=" ... "),"<Buffer "+e+">"},i&&(l.prototype[i]=l.prototype.inspect),l.prototype.compare=function(e,t,n,r,o){if(B(e,Uint8Array)&&(e=l.from(e,e.offset,e.byteLength)),!l.isBuffer(e))throw new TypeError('The "target" argument must be one of type Buffer or Uint8Array. Received type '+typeof e);if(void 0===t&&(t=0),void 0===n&&(n=e?e.length:0),void 0===r&&(r=0),void 0===o&&(o=this.length),t<0||n>e.length||r<0||o>this.length)throw new RangeError("out of range index");if(r>=o&&t>=n)return 0;if(r>=o)return-1;if(t>=n)return 1;if(this===e)return 0;for(var i=(o>>>=0)-(r>>>=0),a=(n>>>=0)-(t>>>=0),s=Math.min(i,a),c=this.slice(r,o),u=e.slice(t,n),p=0;p<s;++p)if(c[p]!==u[p]){i=c[p],a=u[p];break}return i<a?-1:a<i?1:0},l.prototype.includes=function(e,t,n){return-1!==this.indexOf(e,t,n)},l.prototype.indexOf=function(e,t,n){return y(this,e,t,n,!0)},l.prototype.lastIndexOf=function(e,t,n){return y(this,e,t,n,!1)},l.prototype.write=function(e,t,n,r){if(void 0===t)r="utf8",n=this.length,t=0;else if(void 0===n&&"string"==typeof t)r=t,n=this.length,t=0;else{if(!isFinite(t))throw new Error("Buffer.write(string, encoding, offset[, length]) is no longer supported");t>>>=0,isFinite(n)?(n>>>=0,void 0===r&&(r="utf8")):(r=n,n=void 0)}var o=this.length-t;if((void 0===n||n>o)&&(n=o),e.length>0&&(n<0||t<0)||t>this.length)throw new RangeError("Attempt to write outside buffer bounds");r||(r="utf8");for(var i=!1;;)switch(r){case"hex":return x(this,e,t,n);case"utf8":case"utf-8":return w(this,e,t,n);case"ascii":case"latin1":case"binary":return k(this,e,t,n);case"base64":return E(this,e,t,n);case"ucs2":case"ucs-2":case"utf16le":case"utf-16le":return S(this,e,t,n);default:if(i)throw new TypeError("Unknown encoding: "+r);r=(""+r).toLowerCase(),i=!0}},l.prototype.toJSON=function(){return{type:"Buffer",data:Array.prototype.slice.call(this._arr||this,0)}};var A=4096;function R(e,t,n){var r="";n=Math.min(e.length,n);for(var o=t;o<n;++o)r+=String.fromCharCode(127&e[o]);return r}function C(e,t,n){var r="";n=Math.min(e.length,n);for(var o=t;o<n;++o)r+=String.fromCharCode(e[o]);return r}function T(e,t,n){var r=e.length;(!t||t<0)&&(t=0),(!n||n<0||n>r)&&(n=r);for(var o="",i=t;i<n;++i)o+=q[e[i]];return o}function P(e,t,n){for(var r=e.slice(t,n),o="",i=0;i<r.length-1;i+=2)o+=String.fromCharCode(r[i]+256*r[i+1]);return o}function I(e,t,n){if(e%1!=0||e<0)throw new RangeError("offset is not uint");if(e+t>n)throw new RangeError("Trying to access beyond buffer length")}function j(e,t,n,r,o,i){if(!l.isBuffer(e))throw new TypeError('"buffer" argument must be a Buffer instance');if(t>o||t<i)throw new RangeError('"value" argument is out of bounds');if(n+r>e.length)throw new RangeError("Index out of range")}function L(e,t,n,r,o,i){if(n+r>e.length)throw new RangeError("Index out of range");if(n<0)throw new RangeError("Index out of range")}function N(e,t,n,r,i){return t=+t,n>>>=0,i||L(e,0,n,4),o.write(e,t,n,r,23,4),n+4}function M(e,t,n,r,i){return t=+t,n>>>=0,i||L(e,0,n,8),o.write(e,t,n,r,52,8),n+8}l.prototype.slice=function(e,t){var n=this.length;(e=~~e)<0?(e+=n)<0&&(e=0):e>n&&(e=n),(t=void 0===t?n:~~t)<0?(t+=n)<0&&(t=0):t>n&&(t=n),t<e&&(t=e);var r=this.subarray(e,t);return Object.setPrototypeOf(r,l.prototype),r},l.prototype.readUintLE=l.prototype.readUIntLE=function(e,t,n){e>>>=0,t>>>=0,n||I(e,t,this.length);for(var r=this[e],o=1,i=0;++i<t&&(o*=256);)r+=this[e+i]*o;return r},l.prototype.readUintBE=l.prototype.readUIntBE=function(e,t,n){e>>>=0,t>>>=0,n||I(e,t,this.length);for(var r=this[e+--t],o=1;t>0&&(o*=256);)r+=this[e+--t]*o;return r},l.prototype.readUint8=l.prototype.readUInt8=function(e,t){return e>>>=0,t||I(e,1,this.length),this[e]},l.prototype.readUint16LE=l.prototype.readUInt16LE=function(e,t){return e>>>=0,t||I(e,2,this.length),this[e]|this[e+1]<<8},l.prototype.readUint16BE=l.prototype.readUInt16BE=function(e,t){return e>>>=0,t||I(e,2,this.length),this[e]<<8|this[e+1]},l.prototype.readUint32LE=l.prototype.readUInt32LE=function(e,t){return e>>>=0,t||I(e,4,this.length),(this[e]|this[e+1]<<8|this[e+2]<<16)+16777216*this[e+3]},l.prototype.readUint32BE=l.prototype.readUInt32BE=function(e,t){return e>>>=0,t||I(e,4,this.length),16777216*this[e]+(this[e+1]<<16|this[e+2]<<8|this[e+3])},l.prototype.readIntLE=function(e,t,n){e>>>=0,t>>>=0,n||I(e,t,this.length);for(var r=this[e],o=1,i=0;++i<t&&(o*=256);)r+=this[e+i]*o;return r>=(o*=128)&&(r-=Math.pow(2,8*t)),r},l.prototype.readIntBE=function(e,t,n){e>>>=0,t>>>=0,n||I(e,t,this.length);for(var r=t,o=1,i=this[e+--r];r>0&&(o*=256);)i+=this[e+--r]*o;return i>=(o*=128)&&(i-=Math.pow(2,8*t)),i},l.prototype.readInt8=function(e,t){return e>>>=0,t||I(e,1,this.length),128&this[e]?-1*(255-this[e]+1):this[e]},l.prototype.readInt16LE=function(e,t){e>>>=0,t||I(e,2,this.length);var n=this[e]|this[e+1]<<8;return 32768&n?4294901760|n:n},l.prototype.readInt16BE=function(e,t){e>>>=0,t||I(e,2,this.length);var n=this[e+1]|this[e]<<8;return 32768&n?4294901760|n:n},l.prototype.readInt32LE=function(e,t){return e>>>=0,t||I(e,4,this.length),this[e]|this[e+1]<<8|this[e+2]<<16|this[e+3]<<24},l.prototype.readInt32BE=function(e,t){return e>>>=0,t||I(e,4,this.length),this[e]<<24|this[e+1]<<16|this[e+2]<<8|this[e+3]},l.prototype.readFloatLE=function(e,t){return e>>>=0,t||I(e,4,this.length),o.read(this,e,!0,23,4)},l.prototype.readFloatBE=function(e,t){return e>>>=0,t||I(e,4,this.length),o.read(this,e,!1,23,4)},l.prototype.readDoubleLE=function(e,t){return e>>>=0,t||I(e,8,this.length),o.read(this,e,!0,52,8)},l.prototype.readDoubleBE=function(e,t){return e>>>=0,t||I(e,8,this.length),o.read(this,e,!1,52,8)},l.prototype.writeUintLE=l.prototype.writeUIntLE=function(e,t,n,r){e=+e,t>>>=0,n>>>=0,r||j(this,e,t,n,Math.pow(2,8*n)-1,0);var o=1,i=0;for(this[t]=255&e;++i<n&&(o*=256);)this[t+i]=e/o&255;return t+n},l.prototype.writeUintBE=l.prototype.writeUIntBE=function(e,t,n,r){e=+e,t>>>=0,n>>>=0,r||j(this,e,t,n,Math.pow(2,8*n)-1,0);var o=n-1,i=1;for(this[t+o]=255&e;--o>=0&&(i*=256);)this[t+o]=e/i&255;return t+n},l.prototype.writeUint8=l.prototype.writeUInt8=function(e,t,n){return e=+e,t>>>=0,n||j(this,e,t,1,255,0),this[t]=255&e,t+1},l.prototype.writeUint16LE=l.prototype.writeUInt16LE=function(e,t,n){return e=+e,t>>>=0,n||j(this,e,t,2,65535,0),this[t]=255&e,this[t+1]=e>>>8,t+2},l.prototype.writeUint16BE=l.prototype.writeUInt16BE=function(e,t,n){return e=+e,t>>>=0,n||j(this,e,t,2,65535,0),this[t]=e>>>8,this[t+1]=255&e,t+2},l.prototype.writeUint32LE=l.prototype.writeUInt32LE=function(e,t,n){return e=+e,t>>>=0,n||j(this,e,t,4,4294967295,0),this[t+3]=e>>>24,this[t+2]=e>>>16,this[t+1]=e>>>8,this[t]=255&e,t+4},l.prototype.writeUint32BE=l.prototype.writeUInt32BE=function(e,t,n){return e=+e,t>>>=0,n||j(this,e,t,4,4294967295,0),this[t]=e>>>24,this[t+1]=e>>>16,this[t+2]=e>>>8,this[t+3]=255&e,t+4},l.prototype.writeIntLE=function(e,t,n,r){if(e=+e,t>>>=0,!r){var o=Math.pow(2,8*n-1);j(this,e,t,n,o-1,-o)}var i=0,a=1,s=0;for(this[t]=255&e;++i<n&&(a*=256);)e<0&&0===s&&0!==this[t+i-1]&&(s=1),this[t+i]=(e/a>>0)-s&255;return t+n},l.prototype.writeIntBE=function(e,t,n,r){if(e=+e,t>>>=0,!r){var o=Math.pow(2,8*n-1);j(this,e,t,n,o-1,-o)}var i=n-1,a=1,s=0;for(this[t+i]=255&e;--i>=0&&(a*=256);)e<0&&0===s&&0!==this[t+i+1]&&(s=1),this[t+i]=(e/a>>0)-s&255;return t+n},l.prototype.writeInt8=function(e,t,n){return e=+e,t>>>=0,n||j(this,e,t,1,127,-128),e<0&&(e=255+e+1),this[t]=255&e,t+1},l.prototype.writeInt16LE=function(e,t,n){return e=+e,t>>>=0,n||j(this,e,t,2,32767,-32768),this[t]=255&e,this[t+1]=e>>>8,t+2},l.prototype.writeInt16BE=function(e,t,n){return e=+e,t>>>=0,n||j(this,e,t,2,32767,-32768),this[t]=e>>>8,this[t+1]=255&e,t+2},l.prototype.writeInt32LE=function(e,t,n){return e=+e,t>>>=0,n||j(this,e,t,4,2147483647,-2147483648),this[t]=255&e,this[t+1]=e>>>8,this[t+2]=e>>>16,this[t+3]=e>>>24,t+4},l.prototype.writeInt32BE=function(e,t,n){return e=+e,t>>>=0,n||j(this,e,t,4,2147483647,-2147483648),e<0&&(e=4294967295+e+1),this[t]=e>>>24,this[t+1]=e>>>16,this[t+2]=e>>>8,this[t+3]=255&e,t+4},l.prototype.writeFloatLE=function(e,t,n){return N(this,e,t,!0,n)},l.prototype.writeFloatBE=function(e,t,n){return N(this,e,t,!1,n)},l.prototype.writeDoubleLE=function(e,t,n){return M(this,e,t,!0,n)},l.prototype.writeDoubleBE=function(e,t,n){return M(this,e,t,!1,n)},l.prototype.copy=function(e,t,n,r){if(!l.isBuffer(e))throw new TypeError("argument should be a Buffer");if(n||(n=0),r||0===r||(r=this.length),t>=e.length&&(t=e.length),t||(t=0),r>0&&r<n&&(r=n),r===n)return 0;if(0===e.length||0===this.length)return 0;if(t<0)throw new RangeError("targetStart out of bounds");if(n<0||n>=this.length)throw new RangeError("Index out of range");if(r<0)throw new RangeError("sourceEnd out of bounds");r>this.length&&(r=this.length),e.length-t<r-n&&(r=e.length-t+n);var o=r-n;return this===e&&"function"==typeof Uint8Array.prototype.copyWithin?this.copyWithin(t,n,r):Uint8Array.prototype.set.call(e,this.subarray(n,r),t),o},l.prototype.fill=function(e,t,n,r){if("string"==typeof e){if("string"==typeof t?(r=t,t=0,n=this.length):"string"==typeof n&&(r=n,n=this.length),void 0!==r&&"string"!=typeof r)throw new TypeError("encoding must be a string");if("string"==typeof r&&!l.isEncoding(r))throw new TypeError("Unknown encoding: "+r);if(1===e.length){var o=e.charCodeAt(0);("utf8"===r&&o<128||"latin1"===r)&&(e=o)}}else"number"==typeof e?e&=255:"boolean"==typeof e&&(e=Number(e));if(t<0||this.length<t||this.length<n)throw new RangeError("Out of range index");if(n<=t)return this;var i;if(t>>>=0,n=void 0===n?this.length:n>>>0,e||(e=0),"number"==typeof e)for(i=t;i<n;++i)this[i]=e;else{var a=l.isBuffer(e)?e:l.from(e,r),s=a.length;if(0===s)throw new TypeError('The value "'+e+'" is invalid for argument "value"');for(i=0;i<n-t;++i)this[i+t]=a[i%s]}return this};var D=/[^+/0-9A-Za-z-_]/g;function F(e,t){var n;t=t||1/0;for(var r=e.length,o=null,i=[],a=0;a<r;++a){if((n=e.charCodeAt(a))>55295&&n<57344){if(!o){if(n>56319){(t-=3)>-1&&i.push(239,191,189);continue}if(a+1===r){(t-=3)>-1&&i.push(239,191,189);continue}o=n;continue}if(n<56320){(t-=3)>-1&&i.push(239,191,189),o=n;continue}n=65536+(o-55296<<10|n-56320)}else o&&(t-=3)>-1&&i.push(239,191,189);if(o=null,n<128){if((t-=1)<0)break;i.push(n)}else if(n<2048){if((t-=2)<0)break;i.push(n>>6|192,63&n|128)}else if(n<65536){if((t-=3)<0)break;i.push(n>>12|224,n>>6&63|128,63&n|128)}else{if(!(n<1114112))throw new Error("Invalid code point");if((t-=4)<0)break;i.push(n>>18|240,n>>12&63|128,n>>6&63|128,63&n|128)}}return i}function z(e){return r.toByteArray(function(e){if((e=(e=e.split("=")[0]).trim().replace(D,"")).length<2)return"";for(;e.length%4!=0;)e+="=";return e}(e))}function U(e,t,n,r){for(var o=0;o<r&&!(o+n>=t.length||o>=e.length);++o)t[o+n]=e[o];return o}function B(e,t){return e instanceof t||null!=e&&null!=e.constructor&&null!=e.constructor.name&&e.constructor.name===t.name}function $(e){return e!=e}var q=function(){for(var e="0123456789abcdef",t=new Array(256),n=0;n<16;++n)for(var r=16*n,o=0;o<16;++o)t[r+o]=e[n]+e[o];return t}()},4480:function(e,t,n){"use strict";var r=n.g.process&&process.nextTick||n.g.setImmediate||function(e){setTimeout(e,0)};e.exports=function(e,t){return e?void t.then((function(t){r((function(){e(null,t)}))}),(function(t){r((function(){e(t)}))})):t}},4184:function(e,t){var n;!function(){"use strict";var r={}.hasOwnProperty;function o(){for(var e=[],t=0;t<arguments.length;t++){var n=arguments[t];if(n){var i=typeof n;if("string"===i||"number"===i)e.push(n);else if(Array.isArray(n)){if(n.length){var a=o.apply(null,n);a&&e.push(a)}}else if("object"===i)if(n.toString===Object.prototype.toString)for(var s in n)r.call(n,s)&&n[s]&&e.push(s);else e.push(n.toString())}}return e.join(" ")}e.exports?(o.default=o,e.exports=o):void 0===(n=function(){return o}.apply(t,[]))||(e.exports=n)}()},1048:function(e){e.exports=function(e,n){for(var r=[],o=0;o<e.length;o++){var i=n(e[o],o);t(i)?r.push.apply(r,i):r.push(i)}return r};var t=Array.isArray||function(e){return"[object Array]"===Object.prototype.toString.call(e)}},9266:function(e,t,n){n(2222),n(1539),n(2526),n(2443),n(1817),n(2401),n(8722),n(2165),n(9007),n(6066),n(3510),n(1840),n(6982),n(2159),n(6649),n(9341),n(543),n(3706),n(408),n(1299);var r=n(857);e.exports=r.Symbol},3099:function(e){e.exports=function(e){if("function"!=typeof e)throw TypeError(String(e)+" is not a function");return e}},6077:function(e,t,n){var r=n(111);e.exports=function(e){if(!r(e)&&null!==e)throw TypeError("Can't set "+String(e)+" as a prototype");return e}},1223:function(e,t,n){var r=n(5112),o=n(30),i=n(3070),a=r("unscopables"),s=Array.prototype;null==s[a]&&i.f(s,a,{configurable:!0,value:o(null)}),e.exports=function(e){s[a][e]=!0}},1530:function(e,t,n){"use strict";var r=n(8710).charAt;e.exports=function(e,t,n){return t+(n?r(e,t).length:1)}},5787:function(e){e.exports=function(e,t,n){if(!(e instanceof t))throw TypeError("Incorrect "+(n?n+" ":"")+"invocation");return e}},9670:function(e,t,n){var r=n(111);e.exports=function(e){if(!r(e))throw TypeError(String(e)+" is not an object");return e}},8533:function(e,t,n){"use strict";var r=n(2092).forEach,o=n(2133)("forEach");e.exports=o?[].forEach:function(e){return r(this,e,arguments.length>1?arguments[1]:void 0)}},8457:function(e,t,n){"use strict";var r=n(9974),o=n(7908),i=n(3411),a=n(7659),s=n(7466),l=n(6135),c=n(1246);e.exports=function(e){var t,n,u,p,f,d,h=o(e),m="function"==typeof this?this:Array,v=arguments.length,g=v>1?arguments[1]:void 0,y=void 0!==g,b=c(h),x=0;if(y&&(g=r(g,v>2?arguments[2]:void 0,2)),null==b||m==Array&&a(b))for(n=new m(t=s(h.length));t>x;x++)d=y?g(h[x],x):h[x],l(n,x,d);else for(f=(p=b.call(h)).next,n=new m;!(u=f.call(p)).done;x++)d=y?i(p,g,[u.value,x],!0):u.value,l(n,x,d);return n.length=x,n}},1318:function(e,t,n){var r=n(5656),o=n(7466),i=n(1400),a=function(e){return function(t,n,a){var s,l=r(t),c=o(l.length),u=i(a,c);if(e&&n!=n){for(;c>u;)if((s=l[u++])!=s)return!0}else for(;c>u;u++)if((e||u in l)&&l[u]===n)return e||u||0;return!e&&-1}};e.exports={includes:a(!0),indexOf:a(!1)}},2092:function(e,t,n){var r=n(9974),o=n(8361),i=n(7908),a=n(7466),s=n(5417),l=[].push,c=function(e){var t=1==e,n=2==e,c=3==e,u=4==e,p=6==e,f=7==e,d=5==e||p;return function(h,m,v,g){for(var y,b,x=i(h),w=o(x),k=r(m,v,3),E=a(w.length),S=0,_=g||s,O=t?_(h,E):n||f?_(h,0):void 0;E>S;S++)if((d||S in w)&&(b=k(y=w[S],S,x),e))if(t)O[S]=b;else if(b)switch(e){case 3:return!0;case 5:return y;case 6:return S;case 2:l.call(O,y)}else switch(e){case 4:return!1;case 7:l.call(O,y)}return p?-1:c||u?u:O}};e.exports={forEach:c(0),map:c(1),filter:c(2),some:c(3),every:c(4),find:c(5),findIndex:c(6),filterOut:c(7)}},1194:function(e,t,n){var r=n(7293),o=n(5112),i=n(7392),a=o("species");e.exports=function(e){return i>=51||!r((function(){var t=[];return(t.constructor={})[a]=function(){return{foo:1}},1!==t[e](Boolean).foo}))}},2133:function(e,t,n){"use strict";var r=n(7293);e.exports=function(e,t){var n=[][e];return!!n&&r((function(){n.call(null,t||function(){throw 1},1)}))}},4362:function(e){var t=Math.floor,n=function(e,i){var a=e.length,s=t(a/2);return a<8?r(e,i):o(n(e.slice(0,s),i),n(e.slice(s),i),i)},r=function(e,t){for(var n,r,o=e.length,i=1;i<o;){for(r=i,n=e[i];r&&t(e[r-1],n)>0;)e[r]=e[--r];r!==i++&&(e[r]=n)}return e},o=function(e,t,n){for(var r=e.length,o=t.length,i=0,a=0,s=[];i<r||a<o;)i<r&&a<o?s.push(n(e[i],t[a])<=0?e[i++]:t[a++]):s.push(i<r?e[i++]:t[a++]);return s};e.exports=n},5417:function(e,t,n){var r=n(111),o=n(3157),i=n(5112)("species");e.exports=function(e,t){var n;return o(e)&&("function"!=typeof(n=e.constructor)||n!==Array&&!o(n.prototype)?r(n)&&null===(n=n[i])&&(n=void 0):n=void 0),new(void 0===n?Array:n)(0===t?0:t)}},3411:function(e,t,n){var r=n(9670),o=n(9212);e.exports=function(e,t,n,i){try{return i?t(r(n)[0],n[1]):t(n)}catch(t){throw o(e),t}}},7072:function(e,t,n){var r=n(5112)("iterator"),o=!1;try{var i=0,a={next:function(){return{done:!!i++}},return:function(){o=!0}};a[r]=function(){return this},Array.from(a,(function(){throw 2}))}catch(e){}e.exports=function(e,t){if(!t&&!o)return!1;var n=!1;try{var i={};i[r]=function(){return{next:function(){return{done:n=!0}}}},e(i)}catch(e){}return n}},4326:function(e){var t={}.toString;e.exports=function(e){return t.call(e).slice(8,-1)}},648:function(e,t,n){var r=n(1694),o=n(4326),i=n(5112)("toStringTag"),a="Arguments"==o(function(){return arguments}());e.exports=r?o:function(e){var t,n,r;return void 0===e?"Undefined":null===e?"Null":"string"==typeof(n=function(e,t){try{return e[t]}catch(e){}}(t=Object(e),i))?n:a?o(t):"Object"==(r=o(t))&&"function"==typeof t.callee?"Arguments":r}},5631:function(e,t,n){"use strict";var r=n(3070).f,o=n(30),i=n(2248),a=n(9974),s=n(5787),l=n(612),c=n(654),u=n(6340),p=n(9781),f=n(2423).fastKey,d=n(9909),h=d.set,m=d.getterFor;e.exports={getConstructor:function(e,t,n,c){var u=e((function(e,r){s(e,u,t),h(e,{type:t,index:o(null),first:void 0,last:void 0,size:0}),p||(e.size=0),null!=r&&l(r,e[c],{that:e,AS_ENTRIES:n})})),d=m(t),v=function(e,t,n){var r,o,i=d(e),a=g(e,t);return a?a.value=n:(i.last=a={index:o=f(t,!0),key:t,value:n,previous:r=i.last,next:void 0,removed:!1},i.first||(i.first=a),r&&(r.next=a),p?i.size++:e.size++,"F"!==o&&(i.index[o]=a)),e},g=function(e,t){var n,r=d(e),o=f(t);if("F"!==o)return r.index[o];for(n=r.first;n;n=n.next)if(n.key==t)return n};return i(u.prototype,{clear:function(){for(var e=d(this),t=e.index,n=e.first;n;)n.removed=!0,n.previous&&(n.previous=n.previous.next=void 0),delete t[n.index],n=n.next;e.first=e.last=void 0,p?e.size=0:this.size=0},delete:function(e){var t=this,n=d(t),r=g(t,e);if(r){var o=r.next,i=r.previous;delete n.index[r.index],r.removed=!0,i&&(i.next=o),o&&(o.previous=i),n.first==r&&(n.first=o),n.last==r&&(n.last=i),p?n.size--:t.size--}return!!r},forEach:function(e){for(var t,n=d(this),r=a(e,arguments.length>1?arguments[1]:void 0,3);t=t?t.next:n.first;)for(r(t.value,t.key,this);t&&t.removed;)t=t.previous},has:function(e){return!!g(this,e)}}),i(u.prototype,n?{get:function(e){var t=g(this,e);return t&&t.value},set:function(e,t){return v(this,0===e?0:e,t)}}:{add:function(e){return v(this,e=0===e?0:e,e)}}),p&&r(u.prototype,"size",{get:function(){return d(this).size}}),u},setStrong:function(e,t,n){var r=t+" Iterator",o=m(t),i=m(r);c(e,t,(function(e,t){h(this,{type:r,target:e,state:o(e),kind:t,last:void 0})}),(function(){for(var e=i(this),t=e.kind,n=e.last;n&&n.removed;)n=n.previous;return e.target&&(e.last=n=n?n.next:e.state.first)?"keys"==t?{value:n.key,done:!1}:"values"==t?{value:n.value,done:!1}:{value:[n.key,n.value],done:!1}:(e.target=void 0,{value:void 0,done:!0})}),n?"entries":"values",!n,!0),u(t)}}},9320:function(e,t,n){"use strict";var r=n(2248),o=n(2423).getWeakData,i=n(9670),a=n(111),s=n(5787),l=n(612),c=n(2092),u=n(6656),p=n(9909),f=p.set,d=p.getterFor,h=c.find,m=c.findIndex,v=0,g=function(e){return e.frozen||(e.frozen=new y)},y=function(){this.entries=[]},b=function(e,t){return h(e.entries,(function(e){return e[0]===t}))};y.prototype={get:function(e){var t=b(this,e);if(t)return t[1]},has:function(e){return!!b(this,e)},set:function(e,t){var n=b(this,e);n?n[1]=t:this.entries.push([e,t])},delete:function(e){var t=m(this.entries,(function(t){return t[0]===e}));return~t&&this.entries.splice(t,1),!!~t}},e.exports={getConstructor:function(e,t,n,c){var p=e((function(e,r){s(e,p,t),f(e,{type:t,id:v++,frozen:void 0}),null!=r&&l(r,e[c],{that:e,AS_ENTRIES:n})})),h=d(t),m=function(e,t,n){var r=h(e),a=o(i(t),!0);return!0===a?g(r).set(t,n):a[r.id]=n,e};return r(p.prototype,{delete:function(e){var t=h(this);if(!a(e))return!1;var n=o(e);return!0===n?g(t).delete(e):n&&u(n,t.id)&&delete n[t.id]},has:function(e){var t=h(this);if(!a(e))return!1;var n=o(e);return!0===n?g(t).has(e):n&&u(n,t.id)}}),r(p.prototype,n?{get:function(e){var t=h(this);if(a(e)){var n=o(e);return!0===n?g(t).get(e):n?n[t.id]:void 0}},set:function(e,t){return m(this,e,t)}}:{add:function(e){return m(this,e,!0)}}),p}}},7710:function(e,t,n){"use strict";var r=n(2109),o=n(7854),i=n(4705),a=n(1320),s=n(2423),l=n(612),c=n(5787),u=n(111),p=n(7293),f=n(7072),d=n(8003),h=n(9587);e.exports=function(e,t,n){var m=-1!==e.indexOf("Map"),v=-1!==e.indexOf("Weak"),g=m?"set":"add",y=o[e],b=y&&y.prototype,x=y,w={},k=function(e){var t=b[e];a(b,e,"add"==e?function(e){return t.call(this,0===e?0:e),this}:"delete"==e?function(e){return!(v&&!u(e))&&t.call(this,0===e?0:e)}:"get"==e?function(e){return v&&!u(e)?void 0:t.call(this,0===e?0:e)}:"has"==e?function(e){return!(v&&!u(e))&&t.call(this,0===e?0:e)}:function(e,n){return t.call(this,0===e?0:e,n),this})};if(i(e,"function"!=typeof y||!(v||b.forEach&&!p((function(){(new y).entries().next()})))))x=n.getConstructor(t,e,m,g),s.REQUIRED=!0;else if(i(e,!0)){var E=new x,S=E[g](v?{}:-0,1)!=E,_=p((function(){E.has(1)})),O=f((function(e){new y(e)})),A=!v&&p((function(){for(var e=new y,t=5;t--;)e[g](t,t);return!e.has(-0)}));O||((x=t((function(t,n){c(t,x,e);var r=h(new y,t,x);return null!=n&&l(n,r[g],{that:r,AS_ENTRIES:m}),r}))).prototype=b,b.constructor=x),(_||A)&&(k("delete"),k("has"),m&&k("get")),(A||S)&&k(g),v&&b.clear&&delete b.clear}return w[e]=x,r({global:!0,forced:x!=y},w),d(x,e),v||n.setStrong(x,e,m),x}},9920:function(e,t,n){var r=n(6656),o=n(3887),i=n(1236),a=n(3070);e.exports=function(e,t){for(var n=o(t),s=a.f,l=i.f,c=0;c<n.length;c++){var u=n[c];r(e,u)||s(e,u,l(t,u))}}},4964:function(e,t,n){var r=n(5112)("match");e.exports=function(e){var t=/./;try{"/./"[e](t)}catch(n){try{return t[r]=!1,"/./"[e](t)}catch(e){}}return!1}},8544:function(e,t,n){var r=n(7293);e.exports=!r((function(){function e(){}return e.prototype.constructor=null,Object.getPrototypeOf(new e)!==e.prototype}))},4230:function(e,t,n){var r=n(4488),o=/"/g;e.exports=function(e,t,n,i){var a=String(r(e)),s="<"+t;return""!==n&&(s+=" "+n+'="'+String(i).replace(o,"&quot;")+'"'),s+">"+a+"</"+t+">"}},4994:function(e,t,n){"use strict";var r=n(3383).IteratorPrototype,o=n(30),i=n(9114),a=n(8003),s=n(7497),l=function(){return this};e.exports=function(e,t,n){var c=t+" Iterator";return e.prototype=o(r,{next:i(1,n)}),a(e,c,!1,!0),s[c]=l,e}},8880:function(e,t,n){var r=n(9781),o=n(3070),i=n(9114);e.exports=r?function(e,t,n){return o.f(e,t,i(1,n))}:function(e,t,n){return e[t]=n,e}},9114:function(e){e.exports=function(e,t){return{enumerable:!(1&e),configurable:!(2&e),writable:!(4&e),value:t}}},6135:function(e,t,n){"use strict";var r=n(7593),o=n(3070),i=n(9114);e.exports=function(e,t,n){var a=r(t);a in e?o.f(e,a,i(0,n)):e[a]=n}},8709:function(e,t,n){"use strict";var r=n(9670),o=n(7593);e.exports=function(e){if("string"!==e&&"number"!==e&&"default"!==e)throw TypeError("Incorrect hint");return o(r(this),"number"!==e)}},654:function(e,t,n){"use strict";var r=n(2109),o=n(4994),i=n(9518),a=n(7674),s=n(8003),l=n(8880),c=n(1320),u=n(5112),p=n(1913),f=n(7497),d=n(3383),h=d.IteratorPrototype,m=d.BUGGY_SAFARI_ITERATORS,v=u("iterator"),g="keys",y="values",b="entries",x=function(){return this};e.exports=function(e,t,n,u,d,w,k){o(n,t,u);var E,S,_,O=function(e){if(e===d&&P)return P;if(!m&&e in C)return C[e];switch(e){case g:case y:case b:return function(){return new n(this,e)}}return function(){return new n(this)}},A=t+" Iterator",R=!1,C=e.prototype,T=C[v]||C["@@iterator"]||d&&C[d],P=!m&&T||O(d),I="Array"==t&&C.entries||T;if(I&&(E=i(I.call(new e)),h!==Object.prototype&&E.next&&(p||i(E)===h||(a?a(E,h):"function"!=typeof E[v]&&l(E,v,x)),s(E,A,!0,!0),p&&(f[A]=x))),d==y&&T&&T.name!==y&&(R=!0,P=function(){return T.call(this)}),p&&!k||C[v]===P||l(C,v,P),f[t]=P,d)if(S={values:O(y),keys:w?P:O(g),entries:O(b)},k)for(_ in S)(m||R||!(_ in C))&&c(C,_,S[_]);else r({target:t,proto:!0,forced:m||R},S);return S}},7235:function(e,t,n){var r=n(857),o=n(6656),i=n(6061),a=n(3070).f;e.exports=function(e){var t=r.Symbol||(r.Symbol={});o(t,e)||a(t,e,{value:i.f(e)})}},9781:function(e,t,n){var r=n(7293);e.exports=!r((function(){return 7!=Object.defineProperty({},1,{get:function(){return 7}})[1]}))},317:function(e,t,n){var r=n(7854),o=n(111),i=r.document,a=o(i)&&o(i.createElement);e.exports=function(e){return a?i.createElement(e):{}}},8324:function(e){e.exports={CSSRuleList:0,CSSStyleDeclaration:0,CSSValueList:0,ClientRectList:0,DOMRectList:0,DOMStringList:0,DOMTokenList:1,DataTransferItemList:0,FileList:0,HTMLAllCollection:0,HTMLCollection:0,HTMLFormElement:0,HTMLSelectElement:0,MediaList:0,MimeTypeArray:0,NamedNodeMap:0,NodeList:1,PaintRequestList:0,Plugin:0,PluginArray:0,SVGLengthList:0,SVGNumberList:0,SVGPathSegList:0,SVGPointList:0,SVGStringList:0,SVGTransformList:0,SourceBufferList:0,StyleSheetList:0,TextTrackCueList:0,TextTrackList:0,TouchList:0}},8886:function(e,t,n){var r=n(8113).match(/firefox\/(\d+)/i);e.exports=!!r&&+r[1]},7871:function(e){e.exports="object"==typeof window},256:function(e,t,n){var r=n(8113);e.exports=/MSIE|Trident/.test(r)},6833:function(e,t,n){var r=n(8113);e.exports=/(?:iphone|ipod|ipad).*applewebkit/i.test(r)},5268:function(e,t,n){var r=n(4326),o=n(7854);e.exports="process"==r(o.process)},1036:function(e,t,n){var r=n(8113);e.exports=/web0s(?!.*chrome)/i.test(r)},8113:function(e,t,n){var r=n(5005);e.exports=r("navigator","userAgent")||""},7392:function(e,t,n){var r,o,i=n(7854),a=n(8113),s=i.process,l=s&&s.versions,c=l&&l.v8;c?o=(r=c.split("."))[0]<4?1:r[0]+r[1]:a&&(!(r=a.match(/Edge\/(\d+)/))||r[1]>=74)&&(r=a.match(/Chrome\/(\d+)/))&&(o=r[1]),e.exports=o&&+o},8008:function(e,t,n){var r=n(8113).match(/AppleWebKit\/(\d+)\./);e.exports=!!r&&+r[1]},748:function(e){e.exports=["constructor","hasOwnProperty","isPrototypeOf","propertyIsEnumerable","toLocaleString","toString","valueOf"]},2109:function(e,t,n){var r=n(7854),o=n(1236).f,i=n(8880),a=n(1320),s=n(3505),l=n(9920),c=n(4705);e.exports=function(e,t){var n,u,p,f,d,h=e.target,m=e.global,v=e.stat;if(n=m?r:v?r[h]||s(h,{}):(r[h]||{}).prototype)for(u in t){if(f=t[u],p=e.noTargetGet?(d=o(n,u))&&d.value:n[u],!c(m?u:h+(v?".":"#")+u,e.forced)&&void 0!==p){if(typeof f==typeof p)continue;l(f,p)}(e.sham||p&&p.sham)&&i(f,"sham",!0),a(n,u,f,e)}}},7293:function(e){e.exports=function(e){try{return!!e()}catch(e){return!0}}},7007:function(e,t,n){"use strict";n(4916);var r=n(1320),o=n(2261),i=n(7293),a=n(5112),s=n(8880),l=a("species"),c=RegExp.prototype,u=!i((function(){var e=/./;return e.exec=function(){var e=[];return e.groups={a:"7"},e},"7"!=="".replace(e,"$<a>")})),p="$0"==="a".replace(/./,"$0"),f=a("replace"),d=!!/./[f]&&""===/./[f]("a","$0"),h=!i((function(){var e=/(?:)/,t=e.exec;e.exec=function(){return t.apply(this,arguments)};var n="ab".split(e);return 2!==n.length||"a"!==n[0]||"b"!==n[1]}));e.exports=function(e,t,n,f){var m=a(e),v=!i((function(){var t={};return t[m]=function(){return 7},7!=""[e](t)})),g=v&&!i((function(){var t=!1,n=/a/;return"split"===e&&((n={}).constructor={},n.constructor[l]=function(){return n},n.flags="",n[m]=/./[m]),n.exec=function(){return t=!0,null},n[m](""),!t}));if(!v||!g||"replace"===e&&(!u||!p||d)||"split"===e&&!h){var y=/./[m],b=n(m,""[e],(function(e,t,n,r,i){var a=t.exec;return a===o||a===c.exec?v&&!i?{done:!0,value:y.call(t,n,r)}:{done:!0,value:e.call(n,t,r)}:{done:!1}}),{REPLACE_KEEPS_$0:p,REGEXP_REPLACE_SUBSTITUTES_UNDEFINED_CAPTURE:d}),x=b[0],w=b[1];r(String.prototype,e,x),r(c,m,2==t?function(e,t){return w.call(e,this,t)}:function(e){return w.call(e,this)})}f&&s(c[m],"sham",!0)}},6790:function(e,t,n){"use strict";var r=n(3157),o=n(7466),i=n(9974),a=function(e,t,n,s,l,c,u,p){for(var f,d=l,h=0,m=!!u&&i(u,p,3);h<s;){if(h in n){if(f=m?m(n[h],h,t):n[h],c>0&&r(f))d=a(e,t,f,o(f.length),d,c-1)-1;else{if(d>=9007199254740991)throw TypeError("Exceed the acceptable array length");e[d]=f}d++}h++}return d};e.exports=a},6677:function(e,t,n){var r=n(7293);e.exports=!r((function(){return Object.isExtensible(Object.preventExtensions({}))}))},9974:function(e,t,n){var r=n(3099);e.exports=function(e,t,n){if(r(e),void 0===t)return e;switch(n){case 0:return function(){return e.call(t)};case 1:return function(n){return e.call(t,n)};case 2:return function(n,r){return e.call(t,n,r)};case 3:return function(n,r,o){return e.call(t,n,r,o)}}return function(){return e.apply(t,arguments)}}},5005:function(e,t,n){var r=n(857),o=n(7854),i=function(e){return"function"==typeof e?e:void 0};e.exports=function(e,t){return arguments.length<2?i(r[e])||i(o[e]):r[e]&&r[e][t]||o[e]&&o[e][t]}},1246:function(e,t,n){var r=n(648),o=n(7497),i=n(5112)("iterator");e.exports=function(e){if(null!=e)return e[i]||e["@@iterator"]||o[r(e)]}},8554:function(e,t,n){var r=n(9670),o=n(1246);e.exports=function(e){var t=o(e);if("function"!=typeof t)throw TypeError(String(e)+" is not iterable");return r(t.call(e))}},647:function(e,t,n){var r=n(7908),o=Math.floor,i="".replace,a=/\$([$&'`]|\d{1,2}|<[^>]*>)/g,s=/\$([$&'`]|\d{1,2})/g;e.exports=function(e,t,n,l,c,u){var p=n+e.length,f=l.length,d=s;return void 0!==c&&(c=r(c),d=a),i.call(u,d,(function(r,i){var a;switch(i.charAt(0)){case"$":return"$";case"&":return e;case"`":return t.slice(0,n);case"'":return t.slice(p);case"<":a=c[i.slice(1,-1)];break;default:var s=+i;if(0===s)return r;if(s>f){var u=o(s/10);return 0===u?r:u<=f?void 0===l[u-1]?i.charAt(1):l[u-1]+i.charAt(1):r}a=l[s-1]}return void 0===a?"":a}))}},7854:function(e,t,n){var r=function(e){return e&&e.Math==Math&&e};e.exports=r("object"==typeof globalThis&&globalThis)||r("object"==typeof window&&window)||r("object"==typeof self&&self)||r("object"==typeof n.g&&n.g)||function(){return this}()||Function("return this")()},6656:function(e,t,n){var r=n(7908),o={}.hasOwnProperty;e.exports=Object.hasOwn||function(e,t){return o.call(r(e),t)}},3501:function(e){e.exports={}},842:function(e,t,n){var r=n(7854);e.exports=function(e,t){var n=r.console;n&&n.error&&(1===arguments.length?n.error(e):n.error(e,t))}},490:function(e,t,n){var r=n(5005);e.exports=r("document","documentElement")},4664:function(e,t,n){var r=n(9781),o=n(7293),i=n(317);e.exports=!r&&!o((function(){return 7!=Object.defineProperty(i("div"),"a",{get:function(){return 7}}).a}))},8361:function(e,t,n){var r=n(7293),o=n(4326),i="".split;e.exports=r((function(){return!Object("z").propertyIsEnumerable(0)}))?function(e){return"String"==o(e)?i.call(e,""):Object(e)}:Object},9587:function(e,t,n){var r=n(111),o=n(7674);e.exports=function(e,t,n){var i,a;return o&&"function"==typeof(i=t.constructor)&&i!==n&&r(a=i.prototype)&&a!==n.prototype&&o(e,a),e}},2788:function(e,t,n){var r=n(5465),o=Function.toString;"function"!=typeof r.inspectSource&&(r.inspectSource=function(e){return o.call(e)}),e.exports=r.inspectSource},2423:function(e,t,n){var r=n(3501),o=n(111),i=n(6656),a=n(3070).f,s=n(9711),l=n(6677),c=s("meta"),u=0,p=Object.isExtensible||function(){return!0},f=function(e){a(e,c,{value:{objectID:"O"+ ++u,weakData:{}}})},d=e.exports={REQUIRED:!1,fastKey:function(e,t){if(!o(e))return"symbol"==typeof e?e:("string"==typeof e?"S":"P")+e;if(!i(e,c)){if(!p(e))return"F";if(!t)return"E";f(e)}return e[c].objectID},getWeakData:function(e,t){if(!i(e,c)){if(!p(e))return!0;if(!t)return!1;f(e)}return e[c].weakData},onFreeze:function(e){return l&&d.REQUIRED&&p(e)&&!i(e,c)&&f(e),e}};r[c]=!0},9909:function(e,t,n){var r,o,i,a=n(8536),s=n(7854),l=n(111),c=n(8880),u=n(6656),p=n(5465),f=n(6200),d=n(3501),h="Object already initialized",m=s.WeakMap;if(a||p.state){var v=p.state||(p.state=new m),g=v.get,y=v.has,b=v.set;r=function(e,t){if(y.call(v,e))throw new TypeError(h);return t.facade=e,b.call(v,e,t),t},o=function(e){return g.call(v,e)||{}},i=function(e){return y.call(v,e)}}else{var x=f("state");d[x]=!0,r=function(e,t){if(u(e,x))throw new TypeError(h);return t.facade=e,c(e,x,t),t},o=function(e){return u(e,x)?e[x]:{}},i=function(e){return u(e,x)}}e.exports={set:r,get:o,has:i,enforce:function(e){return i(e)?o(e):r(e,{})},getterFor:function(e){return function(t){var n;if(!l(t)||(n=o(t)).type!==e)throw TypeError("Incompatible receiver, "+e+" required");return n}}}},7659:function(e,t,n){var r=n(5112),o=n(7497),i=r("iterator"),a=Array.prototype;e.exports=function(e){return void 0!==e&&(o.Array===e||a[i]===e)}},3157:function(e,t,n){var r=n(4326);e.exports=Array.isArray||function(e){return"Array"==r(e)}},4705:function(e,t,n){var r=n(7293),o=/#|\.prototype\./,i=function(e,t){var n=s[a(e)];return n==c||n!=l&&("function"==typeof t?r(t):!!t)},a=i.normalize=function(e){return String(e).replace(o,".").toLowerCase()},s=i.data={},l=i.NATIVE="N",c=i.POLYFILL="P";e.exports=i},111:function(e){e.exports=function(e){return"object"==typeof e?null!==e:"function"==typeof e}},1913:function(e){e.exports=!1},7850:function(e,t,n){var r=n(111),o=n(4326),i=n(5112)("match");e.exports=function(e){var t;return r(e)&&(void 0!==(t=e[i])?!!t:"RegExp"==o(e))}},612:function(e,t,n){var r=n(9670),o=n(7659),i=n(7466),a=n(9974),s=n(1246),l=n(9212),c=function(e,t){this.stopped=e,this.result=t};e.exports=function(e,t,n){var u,p,f,d,h,m,v,g=n&&n.that,y=!(!n||!n.AS_ENTRIES),b=!(!n||!n.IS_ITERATOR),x=!(!n||!n.INTERRUPTED),w=a(t,g,1+y+x),k=function(e){return u&&l(u),new c(!0,e)},E=function(e){return y?(r(e),x?w(e[0],e[1],k):w(e[0],e[1])):x?w(e,k):w(e)};if(b)u=e;else{if("function"!=typeof(p=s(e)))throw TypeError("Target is not iterable");if(o(p)){for(f=0,d=i(e.length);d>f;f++)if((h=E(e[f]))&&h instanceof c)return h;return new c(!1)}u=p.call(e)}for(m=u.next;!(v=m.call(u)).done;){try{h=E(v.value)}catch(e){throw l(u),e}if("object"==typeof h&&h&&h instanceof c)return h}return new c(!1)}},9212:function(e,t,n){var r=n(9670);e.exports=function(e){var t=e.return;if(void 0!==t)return r(t.call(e)).value}},3383:function(e,t,n){"use strict";var r,o,i,a=n(7293),s=n(9518),l=n(8880),c=n(6656),u=n(5112),p=n(1913),f=u("iterator"),d=!1;[].keys&&("next"in(i=[].keys())?(o=s(s(i)))!==Object.prototype&&(r=o):d=!0);var h=null==r||a((function(){var e={};return r[f].call(e)!==e}));h&&(r={}),p&&!h||c(r,f)||l(r,f,(function(){return this})),e.exports={IteratorPrototype:r,BUGGY_SAFARI_ITERATORS:d}},7497:function(e){e.exports={}},5948:function(e,t,n){var r,o,i,a,s,l,c,u,p=n(7854),f=n(1236).f,d=n(261).set,h=n(6833),m=n(1036),v=n(5268),g=p.MutationObserver||p.WebKitMutationObserver,y=p.document,b=p.process,x=p.Promise,w=f(p,"queueMicrotask"),k=w&&w.value;k||(r=function(){var e,t;for(v&&(e=b.domain)&&e.exit();o;){t=o.fn,o=o.next;try{t()}catch(e){throw o?a():i=void 0,e}}i=void 0,e&&e.enter()},h||v||m||!g||!y?x&&x.resolve?((c=x.resolve(void 0)).constructor=x,u=c.then,a=function(){u.call(c,r)}):a=v?function(){b.nextTick(r)}:function(){d.call(p,r)}:(s=!0,l=y.createTextNode(""),new g(r).observe(l,{characterData:!0}),a=function(){l.data=s=!s})),e.exports=k||function(e){var t={fn:e,next:void 0};i&&(i.next=t),o||(o=t,a()),i=t}},3366:function(e,t,n){var r=n(7854);e.exports=r.Promise},133:function(e,t,n){var r=n(7392),o=n(7293);e.exports=!!Object.getOwnPropertySymbols&&!o((function(){var e=Symbol();return!String(e)||!(Object(e)instanceof Symbol)||!Symbol.sham&&r&&r<41}))},590:function(e,t,n){var r=n(7293),o=n(5112),i=n(1913),a=o("iterator");e.exports=!r((function(){var e=new URL("b?a=1&b=2&c=3","http://a"),t=e.searchParams,n="";return e.pathname="c%20d",t.forEach((function(e,r){t.delete("b"),n+=r+e})),i&&!e.toJSON||!t.sort||"http://a/c%20d?a=1&c=3"!==e.href||"3"!==t.get("c")||"a=1"!==String(new URLSearchParams("?a=1"))||!t[a]||"a"!==new URL("https://a@b").username||"b"!==new URLSearchParams(new URLSearchParams("a=b")).get("a")||"xn--e1aybc"!==new URL("http://тест").host||"#%D0%B1"!==new URL("http://a#б").hash||"a1c3"!==n||"x"!==new URL("http://x",void 0).host}))},8536:function(e,t,n){var r=n(7854),o=n(2788),i=r.WeakMap;e.exports="function"==typeof i&&/native code/.test(o(i))},8523:function(e,t,n){"use strict";var r=n(3099),o=function(e){var t,n;this.promise=new e((function(e,r){if(void 0!==t||void 0!==n)throw TypeError("Bad Promise constructor");t=e,n=r})),this.resolve=r(t),this.reject=r(n)};e.exports.f=function(e){return new o(e)}},3929:function(e,t,n){var r=n(7850);e.exports=function(e){if(r(e))throw TypeError("The method doesn't accept regular expressions");return e}},1574:function(e,t,n){"use strict";var r=n(9781),o=n(7293),i=n(1956),a=n(5181),s=n(5296),l=n(7908),c=n(8361),u=Object.assign,p=Object.defineProperty;e.exports=!u||o((function(){if(r&&1!==u({b:1},u(p({},"a",{enumerable:!0,get:function(){p(this,"b",{value:3,enumerable:!1})}}),{b:2})).b)return!0;var e={},t={},n=Symbol(),o="abcdefghijklmnopqrst";return e[n]=7,o.split("").forEach((function(e){t[e]=e})),7!=u({},e)[n]||i(u({},t)).join("")!=o}))?function(e,t){for(var n=l(e),o=arguments.length,u=1,p=a.f,f=s.f;o>u;)for(var d,h=c(arguments[u++]),m=p?i(h).concat(p(h)):i(h),v=m.length,g=0;v>g;)d=m[g++],r&&!f.call(h,d)||(n[d]=h[d]);return n}:u},30:function(e,t,n){var r,o=n(9670),i=n(6048),a=n(748),s=n(3501),l=n(490),c=n(317),u=n(6200)("IE_PROTO"),p=function(){},f=function(e){return"<script>"+e+"<\/script>"},d=function(){try{r=document.domain&&new ActiveXObject("htmlfile")}catch(e){}var e,t;d=r?function(e){e.write(f("")),e.close();var t=e.parentWindow.Object;return e=null,t}(r):((t=c("iframe")).style.display="none",l.appendChild(t),t.src=String("javascript:"),(e=t.contentWindow.document).open(),e.write(f("document.F=Object")),e.close(),e.F);for(var n=a.length;n--;)delete d.prototype[a[n]];return d()};s[u]=!0,e.exports=Object.create||function(e,t){var n;return null!==e?(p.prototype=o(e),n=new p,p.prototype=null,n[u]=e):n=d(),void 0===t?n:i(n,t)}},6048:function(e,t,n){var r=n(9781),o=n(3070),i=n(9670),a=n(1956);e.exports=r?Object.defineProperties:function(e,t){i(e);for(var n,r=a(t),s=r.length,l=0;s>l;)o.f(e,n=r[l++],t[n]);return e}},3070:function(e,t,n){var r=n(9781),o=n(4664),i=n(9670),a=n(7593),s=Object.defineProperty;t.f=r?s:function(e,t,n){if(i(e),t=a(t,!0),i(n),o)try{return s(e,t,n)}catch(e){}if("get"in n||"set"in n)throw TypeError("Accessors not supported");return"value"in n&&(e[t]=n.value),e}},1236:function(e,t,n){var r=n(9781),o=n(5296),i=n(9114),a=n(5656),s=n(7593),l=n(6656),c=n(4664),u=Object.getOwnPropertyDescriptor;t.f=r?u:function(e,t){if(e=a(e),t=s(t,!0),c)try{return u(e,t)}catch(e){}if(l(e,t))return i(!o.f.call(e,t),e[t])}},1156:function(e,t,n){var r=n(5656),o=n(8006).f,i={}.toString,a="object"==typeof window&&window&&Object.getOwnPropertyNames?Object.getOwnPropertyNames(window):[];e.exports.f=function(e){return a&&"[object Window]"==i.call(e)?function(e){try{return o(e)}catch(e){return a.slice()}}(e):o(r(e))}},8006:function(e,t,n){var r=n(6324),o=n(748).concat("length","prototype");t.f=Object.getOwnPropertyNames||function(e){return r(e,o)}},5181:function(e,t){t.f=Object.getOwnPropertySymbols},9518:function(e,t,n){var r=n(6656),o=n(7908),i=n(6200),a=n(8544),s=i("IE_PROTO"),l=Object.prototype;e.exports=a?Object.getPrototypeOf:function(e){return e=o(e),r(e,s)?e[s]:"function"==typeof e.constructor&&e instanceof e.constructor?e.constructor.prototype:e instanceof Object?l:null}},6324:function(e,t,n){var r=n(6656),o=n(5656),i=n(1318).indexOf,a=n(3501);e.exports=function(e,t){var n,s=o(e),l=0,c=[];for(n in s)!r(a,n)&&r(s,n)&&c.push(n);for(;t.length>l;)r(s,n=t[l++])&&(~i(c,n)||c.push(n));return c}},1956:function(e,t,n){var r=n(6324),o=n(748);e.exports=Object.keys||function(e){return r(e,o)}},5296:function(e,t){"use strict";var n={}.propertyIsEnumerable,r=Object.getOwnPropertyDescriptor,o=r&&!n.call({1:2},1);t.f=o?function(e){var t=r(this,e);return!!t&&t.enumerable}:n},7674:function(e,t,n){var r=n(9670),o=n(6077);e.exports=Object.setPrototypeOf||("__proto__"in{}?function(){var e,t=!1,n={};try{(e=Object.getOwnPropertyDescriptor(Object.prototype,"__proto__").set).call(n,[]),t=n instanceof Array}catch(e){}return function(n,i){return r(n),o(i),t?e.call(n,i):n.__proto__=i,n}}():void 0)},4699:function(e,t,n){var r=n(9781),o=n(1956),i=n(5656),a=n(5296).f,s=function(e){return function(t){for(var n,s=i(t),l=o(s),c=l.length,u=0,p=[];c>u;)n=l[u++],r&&!a.call(s,n)||p.push(e?[n,s[n]]:s[n]);return p}};e.exports={entries:s(!0),values:s(!1)}},288:function(e,t,n){"use strict";var r=n(1694),o=n(648);e.exports=r?{}.toString:function(){return"[object "+o(this)+"]"}},3887:function(e,t,n){var r=n(5005),o=n(8006),i=n(5181),a=n(9670);e.exports=r("Reflect","ownKeys")||function(e){var t=o.f(a(e)),n=i.f;return n?t.concat(n(e)):t}},857:function(e,t,n){var r=n(7854);e.exports=r},2534:function(e){e.exports=function(e){try{return{error:!1,value:e()}}catch(e){return{error:!0,value:e}}}},9478:function(e,t,n){var r=n(9670),o=n(111),i=n(8523);e.exports=function(e,t){if(r(e),o(t)&&t.constructor===e)return t;var n=i.f(e);return(0,n.resolve)(t),n.promise}},2248:function(e,t,n){var r=n(1320);e.exports=function(e,t,n){for(var o in t)r(e,o,t[o],n);return e}},1320:function(e,t,n){var r=n(7854),o=n(8880),i=n(6656),a=n(3505),s=n(2788),l=n(9909),c=l.get,u=l.enforce,p=String(String).split("String");(e.exports=function(e,t,n,s){var l,c=!!s&&!!s.unsafe,f=!!s&&!!s.enumerable,d=!!s&&!!s.noTargetGet;"function"==typeof n&&("string"!=typeof t||i(n,"name")||o(n,"name",t),(l=u(n)).source||(l.source=p.join("string"==typeof t?t:""))),e!==r?(c?!d&&e[t]&&(f=!0):delete e[t],f?e[t]=n:o(e,t,n)):f?e[t]=n:a(t,n)})(Function.prototype,"toString",(function(){return"function"==typeof this&&c(this).source||s(this)}))},7651:function(e,t,n){var r=n(4326),o=n(2261);e.exports=function(e,t){var n=e.exec;if("function"==typeof n){var i=n.call(e,t);if("object"!=typeof i)throw TypeError("RegExp exec method returned something other than an Object or null");return i}if("RegExp"!==r(e))throw TypeError("RegExp#exec called on incompatible receiver");return o.call(e,t)}},2261:function(e,t,n){"use strict";var r,o,i=n(7066),a=n(2999),s=n(2309),l=RegExp.prototype.exec,c=s("native-string-replace",String.prototype.replace),u=l,p=(r=/a/,o=/b*/g,l.call(r,"a"),l.call(o,"a"),0!==r.lastIndex||0!==o.lastIndex),f=a.UNSUPPORTED_Y||a.BROKEN_CARET,d=void 0!==/()??/.exec("")[1];(p||d||f)&&(u=function(e){var t,n,r,o,a=this,s=f&&a.sticky,u=i.call(a),h=a.source,m=0,v=e;return s&&(-1===(u=u.replace("y","")).indexOf("g")&&(u+="g"),v=String(e).slice(a.lastIndex),a.lastIndex>0&&(!a.multiline||a.multiline&&"\n"!==e[a.lastIndex-1])&&(h="(?: "+h+")",v=" "+v,m++),n=new RegExp("^(?:"+h+")",u)),d&&(n=new RegExp("^"+h+"$(?!\\s)",u)),p&&(t=a.lastIndex),r=l.call(s?n:a,v),s?r?(r.input=r.input.slice(m),r[0]=r[0].slice(m),r.index=a.lastIndex,a.lastIndex+=r[0].length):a.lastIndex=0:p&&r&&(a.lastIndex=a.global?r.index+r[0].length:t),d&&r&&r.length>1&&c.call(r[0],n,(function(){for(o=1;o<arguments.length-2;o++)void 0===arguments[o]&&(r[o]=void 0)})),r}),e.exports=u},7066:function(e,t,n){"use strict";var r=n(9670);e.exports=function(){var e=r(this),t="";return e.global&&(t+="g"),e.ignoreCase&&(t+="i"),e.multiline&&(t+="m"),e.dotAll&&(t+="s"),e.unicode&&(t+="u"),e.sticky&&(t+="y"),t}},2999:function(e,t,n){"use strict";var r=n(7293);function o(e,t){return RegExp(e,t)}t.UNSUPPORTED_Y=r((function(){var e=o("a","y");return e.lastIndex=2,null!=e.exec("abcd")})),t.BROKEN_CARET=r((function(){var e=o("^r","gy");return e.lastIndex=2,null!=e.exec("str")}))},4488:function(e){e.exports=function(e){if(null==e)throw TypeError("Can't call method on "+e);return e}},1150:function(e){e.exports=Object.is||function(e,t){return e===t?0!==e||1/e==1/t:e!=e&&t!=t}},3505:function(e,t,n){var r=n(7854),o=n(8880);e.exports=function(e,t){try{o(r,e,t)}catch(n){r[e]=t}return t}},6340:function(e,t,n){"use strict";var r=n(5005),o=n(3070),i=n(5112),a=n(9781),s=i("species");e.exports=function(e){var t=r(e),n=o.f;a&&t&&!t[s]&&n(t,s,{configurable:!0,get:function(){return this}})}},8003:function(e,t,n){var r=n(3070).f,o=n(6656),i=n(5112)("toStringTag");e.exports=function(e,t,n){e&&!o(e=n?e:e.prototype,i)&&r(e,i,{configurable:!0,value:t})}},6200:function(e,t,n){var r=n(2309),o=n(9711),i=r("keys");e.exports=function(e){return i[e]||(i[e]=o(e))}},5465:function(e,t,n){var r=n(7854),o=n(3505),i="__core-js_shared__",a=r[i]||o(i,{});e.exports=a},2309:function(e,t,n){var r=n(1913),o=n(5465);(e.exports=function(e,t){return o[e]||(o[e]=void 0!==t?t:{})})("versions",[]).push({version:"3.14.0",mode:r?"pure":"global",copyright:"© 2021 Denis Pushkarev (zloirock.ru)"})},6707:function(e,t,n){var r=n(9670),o=n(3099),i=n(5112)("species");e.exports=function(e,t){var n,a=r(e).constructor;return void 0===a||null==(n=r(a)[i])?t:o(n)}},3429:function(e,t,n){var r=n(7293);e.exports=function(e){return r((function(){var t=""[e]('"');return t!==t.toLowerCase()||t.split('"').length>3}))}},8710:function(e,t,n){var r=n(9958),o=n(4488),i=function(e){return function(t,n){var i,a,s=String(o(t)),l=r(n),c=s.length;return l<0||l>=c?e?"":void 0:(i=s.charCodeAt(l))<55296||i>56319||l+1===c||(a=s.charCodeAt(l+1))<56320||a>57343?e?s.charAt(l):i:e?s.slice(l,l+2):a-56320+(i-55296<<10)+65536}};e.exports={codeAt:i(!1),charAt:i(!0)}},3197:function(e){"use strict";var t=2147483647,n=/[^\0-\u007E]/,r=/[.\u3002\uFF0E\uFF61]/g,o="Overflow: input needs wider integers to process",i=Math.floor,a=String.fromCharCode,s=function(e){return e+22+75*(e<26)},l=function(e,t,n){var r=0;for(e=n?i(e/700):e>>1,e+=i(e/t);e>455;r+=36)e=i(e/35);return i(r+36*e/(e+38))},c=function(e){var n,r,c=[],u=(e=function(e){for(var t=[],n=0,r=e.length;n<r;){var o=e.charCodeAt(n++);if(o>=55296&&o<=56319&&n<r){var i=e.charCodeAt(n++);56320==(64512&i)?t.push(((1023&o)<<10)+(1023&i)+65536):(t.push(o),n--)}else t.push(o)}return t}(e)).length,p=128,f=0,d=72;for(n=0;n<e.length;n++)(r=e[n])<128&&c.push(a(r));var h=c.length,m=h;for(h&&c.push("-");m<u;){var v=t;for(n=0;n<e.length;n++)(r=e[n])>=p&&r<v&&(v=r);var g=m+1;if(v-p>i((t-f)/g))throw RangeError(o);for(f+=(v-p)*g,p=v,n=0;n<e.length;n++){if((r=e[n])<p&&++f>t)throw RangeError(o);if(r==p){for(var y=f,b=36;;b+=36){var x=b<=d?1:b>=d+26?26:b-d;if(y<x)break;var w=y-x,k=36-x;c.push(a(s(x+w%k))),y=i(w/k)}c.push(a(s(y))),d=l(f,g,m==h),f=0,++m}}++f,++p}return c.join("")};e.exports=function(e){var t,o,i=[],a=e.toLowerCase().replace(r,".").split(".");for(t=0;t<a.length;t++)o=a[t],i.push(n.test(o)?"xn--"+c(o):o);return i.join(".")}},8415:function(e,t,n){"use strict";var r=n(9958),o=n(4488);e.exports=function(e){var t=String(o(this)),n="",i=r(e);if(i<0||i==1/0)throw RangeError("Wrong number of repetitions");for(;i>0;(i>>>=1)&&(t+=t))1&i&&(n+=t);return n}},6091:function(e,t,n){var r=n(7293),o=n(1361);e.exports=function(e){return r((function(){return!!o[e]()||"​᠎"!="​᠎"[e]()||o[e].name!==e}))}},3111:function(e,t,n){var r=n(4488),o="["+n(1361)+"]",i=RegExp("^"+o+o+"*"),a=RegExp(o+o+"*$"),s=function(e){return function(t){var n=String(r(t));return 1&e&&(n=n.replace(i,"")),2&e&&(n=n.replace(a,"")),n}};e.exports={start:s(1),end:s(2),trim:s(3)}},261:function(e,t,n){var r,o,i,a=n(7854),s=n(7293),l=n(9974),c=n(490),u=n(317),p=n(6833),f=n(5268),d=a.location,h=a.setImmediate,m=a.clearImmediate,v=a.process,g=a.MessageChannel,y=a.Dispatch,b=0,x={},w=function(e){if(x.hasOwnProperty(e)){var t=x[e];delete x[e],t()}},k=function(e){return function(){w(e)}},E=function(e){w(e.data)},S=function(e){a.postMessage(e+"",d.protocol+"//"+d.host)};h&&m||(h=function(e){for(var t=[],n=1;arguments.length>n;)t.push(arguments[n++]);return x[++b]=function(){("function"==typeof e?e:Function(e)).apply(void 0,t)},r(b),b},m=function(e){delete x[e]},f?r=function(e){v.nextTick(k(e))}:y&&y.now?r=function(e){y.now(k(e))}:g&&!p?(i=(o=new g).port2,o.port1.onmessage=E,r=l(i.postMessage,i,1)):a.addEventListener&&"function"==typeof postMessage&&!a.importScripts&&d&&"file:"!==d.protocol&&!s(S)?(r=S,a.addEventListener("message",E,!1)):r="onreadystatechange"in u("script")?function(e){c.appendChild(u("script")).onreadystatechange=function(){c.removeChild(this),w(e)}}:function(e){setTimeout(k(e),0)}),e.exports={set:h,clear:m}},863:function(e,t,n){var r=n(4326);e.exports=function(e){if("number"!=typeof e&&"Number"!=r(e))throw TypeError("Incorrect invocation");return+e}},1400:function(e,t,n){var r=n(9958),o=Math.max,i=Math.min;e.exports=function(e,t){var n=r(e);return n<0?o(n+t,0):i(n,t)}},5656:function(e,t,n){var r=n(8361),o=n(4488);e.exports=function(e){return r(o(e))}},9958:function(e){var t=Math.ceil,n=Math.floor;e.exports=function(e){return isNaN(e=+e)?0:(e>0?n:t)(e)}},7466:function(e,t,n){var r=n(9958),o=Math.min;e.exports=function(e){return e>0?o(r(e),9007199254740991):0}},7908:function(e,t,n){var r=n(4488);e.exports=function(e){return Object(r(e))}},7593:function(e,t,n){var r=n(111);e.exports=function(e,t){if(!r(e))return e;var n,o;if(t&&"function"==typeof(n=e.toString)&&!r(o=n.call(e)))return o;if("function"==typeof(n=e.valueOf)&&!r(o=n.call(e)))return o;if(!t&&"function"==typeof(n=e.toString)&&!r(o=n.call(e)))return o;throw TypeError("Can't convert object to primitive value")}},1694:function(e,t,n){var r={};r[n(5112)("toStringTag")]="z",e.exports="[object z]"===String(r)},9711:function(e){var t=0,n=Math.random();e.exports=function(e){return"Symbol("+String(void 0===e?"":e)+")_"+(++t+n).toString(36)}},3307:function(e,t,n){var r=n(133);e.exports=r&&!Symbol.sham&&"symbol"==typeof Symbol.iterator},6061:function(e,t,n){var r=n(5112);t.f=r},5112:function(e,t,n){var r=n(7854),o=n(2309),i=n(6656),a=n(9711),s=n(133),l=n(3307),c=o("wks"),u=r.Symbol,p=l?u:u&&u.withoutSetter||a;e.exports=function(e){return i(c,e)&&(s||"string"==typeof c[e])||(s&&i(u,e)?c[e]=u[e]:c[e]=p("Symbol."+e)),c[e]}},1361:function(e){e.exports="\t\n\v\f\r                　\u2028\u2029\ufeff"},2222:function(e,t,n){"use strict";var r=n(2109),o=n(7293),i=n(3157),a=n(111),s=n(7908),l=n(7466),c=n(6135),u=n(5417),p=n(1194),f=n(5112),d=n(7392),h=f("isConcatSpreadable"),m=9007199254740991,v="Maximum allowed index exceeded",g=d>=51||!o((function(){var e=[];return e[h]=!1,e.concat()[0]!==e})),y=p("concat"),b=function(e){if(!a(e))return!1;var t=e[h];return void 0!==t?!!t:i(e)};r({target:"Array",proto:!0,forced:!g||!y},{concat:function(e){var t,n,r,o,i,a=s(this),p=u(a,0),f=0;for(t=-1,r=arguments.length;t<r;t++)if(b(i=-1===t?a:arguments[t])){if(f+(o=l(i.length))>m)throw TypeError(v);for(n=0;n<o;n++,f++)n in i&&c(p,f,i[n])}else{if(f>=m)throw TypeError(v);c(p,f++,i)}return p.length=f,p}})},7327:function(e,t,n){"use strict";var r=n(2109),o=n(2092).filter;r({target:"Array",proto:!0,forced:!n(1194)("filter")},{filter:function(e){return o(this,e,arguments.length>1?arguments[1]:void 0)}})},9826:function(e,t,n){"use strict";var r=n(2109),o=n(2092).find,i=n(1223),a="find",s=!0;a in[]&&Array(1).find((function(){s=!1})),r({target:"Array",proto:!0,forced:s},{find:function(e){return o(this,e,arguments.length>1?arguments[1]:void 0)}}),i(a)},6535:function(e,t,n){"use strict";var r=n(2109),o=n(6790),i=n(7908),a=n(7466),s=n(3099),l=n(5417);r({target:"Array",proto:!0},{flatMap:function(e){var t,n=i(this),r=a(n.length);return s(e),(t=l(n,0)).length=o(t,n,n,r,0,1,e,arguments.length>1?arguments[1]:void 0),t}})},1038:function(e,t,n){var r=n(2109),o=n(8457);r({target:"Array",stat:!0,forced:!n(7072)((function(e){Array.from(e)}))},{from:o})},6699:function(e,t,n){"use strict";var r=n(2109),o=n(1318).includes,i=n(1223);r({target:"Array",proto:!0},{includes:function(e){return o(this,e,arguments.length>1?arguments[1]:void 0)}}),i("includes")},6992:function(e,t,n){"use strict";var r=n(5656),o=n(1223),i=n(7497),a=n(9909),s=n(654),l="Array Iterator",c=a.set,u=a.getterFor(l);e.exports=s(Array,"Array",(function(e,t){c(this,{type:l,target:r(e),index:0,kind:t})}),(function(){var e=u(this),t=e.target,n=e.kind,r=e.index++;return!t||r>=t.length?(e.target=void 0,{value:void 0,done:!0}):"keys"==n?{value:r,done:!1}:"values"==n?{value:t[r],done:!1}:{value:[r,t[r]],done:!1}}),"values"),i.Arguments=i.Array,o("keys"),o("values"),o("entries")},9600:function(e,t,n){"use strict";var r=n(2109),o=n(8361),i=n(5656),a=n(2133),s=[].join,l=o!=Object,c=a("join",",");r({target:"Array",proto:!0,forced:l||!c},{join:function(e){return s.call(i(this),void 0===e?",":e)}})},1249:function(e,t,n){"use strict";var r=n(2109),o=n(2092).map;r({target:"Array",proto:!0,forced:!n(1194)("map")},{map:function(e){return o(this,e,arguments.length>1?arguments[1]:void 0)}})},7042:function(e,t,n){"use strict";var r=n(2109),o=n(111),i=n(3157),a=n(1400),s=n(7466),l=n(5656),c=n(6135),u=n(5112),p=n(1194)("slice"),f=u("species"),d=[].slice,h=Math.max;r({target:"Array",proto:!0,forced:!p},{slice:function(e,t){var n,r,u,p=l(this),m=s(p.length),v=a(e,m),g=a(void 0===t?m:t,m);if(i(p)&&("function"!=typeof(n=p.constructor)||n!==Array&&!i(n.prototype)?o(n)&&null===(n=n[f])&&(n=void 0):n=void 0,n===Array||void 0===n))return d.call(p,v,g);for(r=new(void 0===n?Array:n)(h(g-v,0)),u=0;v<g;v++,u++)v in p&&c(r,u,p[v]);return r.length=u,r}})},2707:function(e,t,n){"use strict";var r=n(2109),o=n(3099),i=n(7908),a=n(7466),s=n(7293),l=n(4362),c=n(2133),u=n(8886),p=n(256),f=n(7392),d=n(8008),h=[],m=h.sort,v=s((function(){h.sort(void 0)})),g=s((function(){h.sort(null)})),y=c("sort"),b=!s((function(){if(f)return f<70;if(!(u&&u>3)){if(p)return!0;if(d)return d<603;var e,t,n,r,o="";for(e=65;e<76;e++){switch(t=String.fromCharCode(e),e){case 66:case 69:case 70:case 72:n=3;break;case 68:case 71:n=4;break;default:n=2}for(r=0;r<47;r++)h.push({k:t+r,v:n})}for(h.sort((function(e,t){return t.v-e.v})),r=0;r<h.length;r++)t=h[r].k.charAt(0),o.charAt(o.length-1)!==t&&(o+=t);return"DGBEFHACIJK"!==o}}));r({target:"Array",proto:!0,forced:v||!g||!y||!b},{sort:function(e){void 0!==e&&o(e);var t=i(this);if(b)return void 0===e?m.call(t):m.call(t,e);var n,r,s=[],c=a(t.length);for(r=0;r<c;r++)r in t&&s.push(t[r]);for(n=(s=l(s,function(e){return function(t,n){return void 0===n?-1:void 0===t?1:void 0!==e?+e(t,n)||0:String(t)>String(n)?1:-1}}(e))).length,r=0;r<n;)t[r]=s[r++];for(;r<c;)delete t[r++];return t}})},561:function(e,t,n){"use strict";var r=n(2109),o=n(1400),i=n(9958),a=n(7466),s=n(7908),l=n(5417),c=n(6135),u=n(1194)("splice"),p=Math.max,f=Math.min,d=9007199254740991,h="Maximum allowed length exceeded";r({target:"Array",proto:!0,forced:!u},{splice:function(e,t){var n,r,u,m,v,g,y=s(this),b=a(y.length),x=o(e,b),w=arguments.length;if(0===w?n=r=0:1===w?(n=0,r=b-x):(n=w-2,r=f(p(i(t),0),b-x)),b+n-r>d)throw TypeError(h);for(u=l(y,r),m=0;m<r;m++)(v=x+m)in y&&c(u,m,y[v]);if(u.length=r,n<r){for(m=x;m<b-r;m++)g=m+n,(v=m+r)in y?y[g]=y[v]:delete y[g];for(m=b;m>b-r+n;m--)delete y[m-1]}else if(n>r)for(m=b-r;m>x;m--)g=m+n-1,(v=m+r-1)in y?y[g]=y[v]:delete y[g];for(m=0;m<n;m++)y[m+x]=arguments[m+2];return y.length=b-r+n,u}})},9244:function(e,t,n){n(1223)("flatMap")},6078:function(e,t,n){var r=n(8880),o=n(8709),i=n(5112)("toPrimitive"),a=Date.prototype;i in a||r(a,i,o)},8309:function(e,t,n){var r=n(9781),o=n(3070).f,i=Function.prototype,a=i.toString,s=/^\s*function ([^ (]*)/,l="name";r&&!(l in i)&&o(i,l,{configurable:!0,get:function(){try{return a.call(this).match(s)[1]}catch(e){return""}}})},3706:function(e,t,n){var r=n(7854);n(8003)(r.JSON,"JSON",!0)},1532:function(e,t,n){"use strict";var r=n(7710),o=n(5631);e.exports=r("Map",(function(e){return function(){return e(this,arguments.length?arguments[0]:void 0)}}),o)},408:function(e,t,n){n(8003)(Math,"Math",!0)},3689:function(e,t,n){var r=n(2109),o=Math.ceil,i=Math.floor;r({target:"Math",stat:!0},{trunc:function(e){return(e>0?i:o)(e)}})},9653:function(e,t,n){"use strict";var r=n(9781),o=n(7854),i=n(4705),a=n(1320),s=n(6656),l=n(4326),c=n(9587),u=n(7593),p=n(7293),f=n(30),d=n(8006).f,h=n(1236).f,m=n(3070).f,v=n(3111).trim,g="Number",y=o.Number,b=y.prototype,x=l(f(b))==g,w=function(e){var t,n,r,o,i,a,s,l,c=u(e,!1);if("string"==typeof c&&c.length>2)if(43===(t=(c=v(c)).charCodeAt(0))||45===t){if(88===(n=c.charCodeAt(2))||120===n)return NaN}else if(48===t){switch(c.charCodeAt(1)){case 66:case 98:r=2,o=49;break;case 79:case 111:r=8,o=55;break;default:return+c}for(a=(i=c.slice(2)).length,s=0;s<a;s++)if((l=i.charCodeAt(s))<48||l>o)return NaN;return parseInt(i,r)}return+c};if(i(g,!y(" 0o1")||!y("0b1")||y("+0x1"))){for(var k,E=function(e){var t=arguments.length<1?0:e,n=this;return n instanceof E&&(x?p((function(){b.valueOf.call(n)})):l(n)!=g)?c(new y(w(t)),n,E):w(t)},S=r?d(y):"MAX_VALUE,MIN_VALUE,NaN,NEGATIVE_INFINITY,POSITIVE_INFINITY,EPSILON,isFinite,isInteger,isNaN,isSafeInteger,MAX_SAFE_INTEGER,MIN_SAFE_INTEGER,parseFloat,parseInt,isInteger,fromString,range".split(","),_=0;S.length>_;_++)s(y,k=S[_])&&!s(E,k)&&m(E,k,h(y,k));E.prototype=b,b.constructor=E,a(o,g,E)}},6977:function(e,t,n){"use strict";var r=n(2109),o=n(9958),i=n(863),a=n(8415),s=n(7293),l=1..toFixed,c=Math.floor,u=function(e,t,n){return 0===t?n:t%2==1?u(e,t-1,n*e):u(e*e,t/2,n)},p=function(e,t,n){for(var r=-1,o=n;++r<6;)o+=t*e[r],e[r]=o%1e7,o=c(o/1e7)},f=function(e,t){for(var n=6,r=0;--n>=0;)r+=e[n],e[n]=c(r/t),r=r%t*1e7},d=function(e){for(var t=6,n="";--t>=0;)if(""!==n||0===t||0!==e[t]){var r=String(e[t]);n=""===n?r:n+a.call("0",7-r.length)+r}return n};r({target:"Number",proto:!0,forced:l&&("0.000"!==8e-5.toFixed(3)||"1"!==.9.toFixed(0)||"1.25"!==1.255.toFixed(2)||"1000000000000000128"!==(0xde0b6b3a7640080).toFixed(0))||!s((function(){l.call({})}))},{toFixed:function(e){var t,n,r,s,l=i(this),c=o(e),h=[0,0,0,0,0,0],m="",v="0";if(c<0||c>20)throw RangeError("Incorrect fraction digits");if(l!=l)return"NaN";if(l<=-1e21||l>=1e21)return String(l);if(l<0&&(m="-",l=-l),l>1e-21)if(n=(t=function(e){for(var t=0,n=e;n>=4096;)t+=12,n/=4096;for(;n>=2;)t+=1,n/=2;return t}(l*u(2,69,1))-69)<0?l*u(2,-t,1):l/u(2,t,1),n*=4503599627370496,(t=52-t)>0){for(p(h,0,n),r=c;r>=7;)p(h,1e7,0),r-=7;for(p(h,u(10,r,1),0),r=t-1;r>=23;)f(h,1<<23),r-=23;f(h,1<<r),p(h,1,1),f(h,2),v=d(h)}else p(h,0,n),p(h,1<<-t,0),v=d(h)+a.call("0",c);return c>0?m+((s=v.length)<=c?"0."+a.call("0",c-s)+v:v.slice(0,s-c)+"."+v.slice(s-c)):m+v}})},9601:function(e,t,n){var r=n(2109),o=n(1574);r({target:"Object",stat:!0,forced:Object.assign!==o},{assign:o})},9720:function(e,t,n){var r=n(2109),o=n(4699).entries;r({target:"Object",stat:!0},{entries:function(e){return o(e)}})},3371:function(e,t,n){var r=n(2109),o=n(6677),i=n(7293),a=n(111),s=n(2423).onFreeze,l=Object.freeze;r({target:"Object",stat:!0,forced:i((function(){l(1)})),sham:!o},{freeze:function(e){return l&&a(e)?l(s(e)):e}})},5003:function(e,t,n){var r=n(2109),o=n(7293),i=n(5656),a=n(1236).f,s=n(9781),l=o((function(){a(1)}));r({target:"Object",stat:!0,forced:!s||l,sham:!s},{getOwnPropertyDescriptor:function(e,t){return a(i(e),t)}})},9337:function(e,t,n){var r=n(2109),o=n(9781),i=n(3887),a=n(5656),s=n(1236),l=n(6135);r({target:"Object",stat:!0,sham:!o},{getOwnPropertyDescriptors:function(e){for(var t,n,r=a(e),o=s.f,c=i(r),u={},p=0;c.length>p;)void 0!==(n=o(r,t=c[p++]))&&l(u,t,n);return u}})},6210:function(e,t,n){var r=n(2109),o=n(7293),i=n(1156).f;r({target:"Object",stat:!0,forced:o((function(){return!Object.getOwnPropertyNames(1)}))},{getOwnPropertyNames:i})},489:function(e,t,n){var r=n(2109),o=n(7293),i=n(7908),a=n(9518),s=n(8544);r({target:"Object",stat:!0,forced:o((function(){a(1)})),sham:!s},{getPrototypeOf:function(e){return a(i(e))}})},1825:function(e,t,n){var r=n(2109),o=n(7293),i=n(111),a=Object.isExtensible;r({target:"Object",stat:!0,forced:o((function(){a(1)}))},{isExtensible:function(e){return!!i(e)&&(!a||a(e))}})},3304:function(e,t,n){n(2109)({target:"Object",stat:!0},{is:n(1150)})},7941:function(e,t,n){var r=n(2109),o=n(7908),i=n(1956);r({target:"Object",stat:!0,forced:n(7293)((function(){i(1)}))},{keys:function(e){return i(o(e))}})},1539:function(e,t,n){var r=n(1694),o=n(1320),i=n(288);r||o(Object.prototype,"toString",i,{unsafe:!0})},2479:function(e,t,n){var r=n(2109),o=n(4699).values;r({target:"Object",stat:!0},{values:function(e){return o(e)}})},8674:function(e,t,n){"use strict";var r,o,i,a,s=n(2109),l=n(1913),c=n(7854),u=n(5005),p=n(3366),f=n(1320),d=n(2248),h=n(7674),m=n(8003),v=n(6340),g=n(111),y=n(3099),b=n(5787),x=n(2788),w=n(612),k=n(7072),E=n(6707),S=n(261).set,_=n(5948),O=n(9478),A=n(842),R=n(8523),C=n(2534),T=n(9909),P=n(4705),I=n(5112),j=n(7871),L=n(5268),N=n(7392),M=I("species"),D="Promise",F=T.get,z=T.set,U=T.getterFor(D),B=p&&p.prototype,$=p,q=B,W=c.TypeError,V=c.document,H=c.process,Y=R.f,G=Y,Q=!!(V&&V.createEvent&&c.dispatchEvent),X="function"==typeof PromiseRejectionEvent,K="unhandledrejection",Z=!1,J=P(D,(function(){var e=x($)!==String($);if(!e&&66===N)return!0;if(l&&!q.finally)return!0;if(N>=51&&/native code/.test($))return!1;var t=new $((function(e){e(1)})),n=function(e){e((function(){}),(function(){}))};return(t.constructor={})[M]=n,!(Z=t.then((function(){}))instanceof n)||!e&&j&&!X})),ee=J||!k((function(e){$.all(e).catch((function(){}))})),te=function(e){var t;return!(!g(e)||"function"!=typeof(t=e.then))&&t},ne=function(e,t){if(!e.notified){e.notified=!0;var n=e.reactions;_((function(){for(var r=e.value,o=1==e.state,i=0;n.length>i;){var a,s,l,c=n[i++],u=o?c.ok:c.fail,p=c.resolve,f=c.reject,d=c.domain;try{u?(o||(2===e.rejection&&ae(e),e.rejection=1),!0===u?a=r:(d&&d.enter(),a=u(r),d&&(d.exit(),l=!0)),a===c.promise?f(W("Promise-chain cycle")):(s=te(a))?s.call(a,p,f):p(a)):f(r)}catch(e){d&&!l&&d.exit(),f(e)}}e.reactions=[],e.notified=!1,t&&!e.rejection&&oe(e)}))}},re=function(e,t,n){var r,o;Q?((r=V.createEvent("Event")).promise=t,r.reason=n,r.initEvent(e,!1,!0),c.dispatchEvent(r)):r={promise:t,reason:n},!X&&(o=c["on"+e])?o(r):e===K&&A("Unhandled promise rejection",n)},oe=function(e){S.call(c,(function(){var t,n=e.facade,r=e.value;if(ie(e)&&(t=C((function(){L?H.emit("unhandledRejection",r,n):re(K,n,r)})),e.rejection=L||ie(e)?2:1,t.error))throw t.value}))},ie=function(e){return 1!==e.rejection&&!e.parent},ae=function(e){S.call(c,(function(){var t=e.facade;L?H.emit("rejectionHandled",t):re("rejectionhandled",t,e.value)}))},se=function(e,t,n){return function(r){e(t,r,n)}},le=function(e,t,n){e.done||(e.done=!0,n&&(e=n),e.value=t,e.state=2,ne(e,!0))},ce=function(e,t,n){if(!e.done){e.done=!0,n&&(e=n);try{if(e.facade===t)throw W("Promise can't be resolved itself");var r=te(t);r?_((function(){var n={done:!1};try{r.call(t,se(ce,n,e),se(le,n,e))}catch(t){le(n,t,e)}})):(e.value=t,e.state=1,ne(e,!1))}catch(t){le({done:!1},t,e)}}};if(J&&(q=($=function(e){b(this,$,D),y(e),r.call(this);var t=F(this);try{e(se(ce,t),se(le,t))}catch(e){le(t,e)}}).prototype,(r=function(e){z(this,{type:D,done:!1,notified:!1,parent:!1,reactions:[],rejection:!1,state:0,value:void 0})}).prototype=d(q,{then:function(e,t){var n=U(this),r=Y(E(this,$));return r.ok="function"!=typeof e||e,r.fail="function"==typeof t&&t,r.domain=L?H.domain:void 0,n.parent=!0,n.reactions.push(r),0!=n.state&&ne(n,!1),r.promise},catch:function(e){return this.then(void 0,e)}}),o=function(){var e=new r,t=F(e);this.promise=e,this.resolve=se(ce,t),this.reject=se(le,t)},R.f=Y=function(e){return e===$||e===i?new o(e):G(e)},!l&&"function"==typeof p&&B!==Object.prototype)){a=B.then,Z||(f(B,"then",(function(e,t){var n=this;return new $((function(e,t){a.call(n,e,t)})).then(e,t)}),{unsafe:!0}),f(B,"catch",q.catch,{unsafe:!0}));try{delete B.constructor}catch(e){}h&&h(B,q)}s({global:!0,wrap:!0,forced:J},{Promise:$}),m($,D,!1,!0),v(D),i=u(D),s({target:D,stat:!0,forced:J},{reject:function(e){var t=Y(this);return t.reject.call(void 0,e),t.promise}}),s({target:D,stat:!0,forced:l||J},{resolve:function(e){return O(l&&this===i?$:this,e)}}),s({target:D,stat:!0,forced:ee},{all:function(e){var t=this,n=Y(t),r=n.resolve,o=n.reject,i=C((function(){var n=y(t.resolve),i=[],a=0,s=1;w(e,(function(e){var l=a++,c=!1;i.push(void 0),s++,n.call(t,e).then((function(e){c||(c=!0,i[l]=e,--s||r(i))}),o)})),--s||r(i)}));return i.error&&o(i.value),n.promise},race:function(e){var t=this,n=Y(t),r=n.reject,o=C((function(){var o=y(t.resolve);w(e,(function(e){o.call(t,e).then(n.resolve,r)}))}));return o.error&&r(o.value),n.promise}})},9596:function(e,t,n){var r=n(2109),o=n(9781),i=n(9670),a=n(7593),s=n(3070);r({target:"Reflect",stat:!0,forced:n(7293)((function(){Reflect.defineProperty(s.f({},1,{value:1}),1,{value:2})})),sham:!o},{defineProperty:function(e,t,n){i(e);var r=a(t,!0);i(n);try{return s.f(e,r,n),!0}catch(e){return!1}}})},2586:function(e,t,n){var r=n(2109),o=n(9670),i=n(1236).f;r({target:"Reflect",stat:!0},{deleteProperty:function(e,t){var n=i(o(e),t);return!(n&&!n.configurable)&&delete e[t]}})},7556:function(e,t,n){n(2109)({target:"Reflect",stat:!0},{ownKeys:n(3887)})},3593:function(e,t,n){var r=n(2109),o=n(9670),i=n(111),a=n(6656),s=n(7293),l=n(3070),c=n(1236),u=n(9518),p=n(9114);r({target:"Reflect",stat:!0,forced:s((function(){var e=function(){},t=l.f(new e,"a",{configurable:!0});return!1!==Reflect.set(e.prototype,"a",1,t)}))},{set:function e(t,n,r){var s,f,d=arguments.length<4?t:arguments[3],h=c.f(o(t),n);if(!h){if(i(f=u(t)))return e(f,n,r,d);h=p(0)}if(a(h,"value")){if(!1===h.writable||!i(d))return!1;if(s=c.f(d,n)){if(s.get||s.set||!1===s.writable)return!1;s.value=r,l.f(d,n,s)}else l.f(d,n,p(0,r));return!0}return void 0!==h.set&&(h.set.call(d,r),!0)}})},1299:function(e,t,n){var r=n(2109),o=n(7854),i=n(8003);r({global:!0},{Reflect:{}}),i(o.Reflect,"Reflect",!0)},4603:function(e,t,n){var r=n(9781),o=n(7854),i=n(4705),a=n(9587),s=n(3070).f,l=n(8006).f,c=n(7850),u=n(7066),p=n(2999),f=n(1320),d=n(7293),h=n(9909).enforce,m=n(6340),v=n(5112)("match"),g=o.RegExp,y=g.prototype,b=/a/g,x=/a/g,w=new g(b)!==b,k=p.UNSUPPORTED_Y;if(r&&i("RegExp",!w||k||d((function(){return x[v]=!1,g(b)!=b||g(x)==x||"/a/i"!=g(b,"i")})))){for(var E=function(e,t){var n,r=this instanceof E,o=c(e),i=void 0===t;if(!r&&o&&e.constructor===E&&i)return e;w?o&&!i&&(e=e.source):e instanceof E&&(i&&(t=u.call(e)),e=e.source),k&&(n=!!t&&t.indexOf("y")>-1)&&(t=t.replace(/y/g,""));var s=a(w?new g(e,t):g(e,t),r?this:y,E);return k&&n&&(h(s).sticky=!0),s},S=function(e){e in E||s(E,e,{configurable:!0,get:function(){return g[e]},set:function(t){g[e]=t}})},_=l(g),O=0;_.length>O;)S(_[O++]);y.constructor=E,E.prototype=y,f(o,"RegExp",E)}m("RegExp")},4916:function(e,t,n){"use strict";var r=n(2109),o=n(2261);r({target:"RegExp",proto:!0,forced:/./.exec!==o},{exec:o})},9714:function(e,t,n){"use strict";var r=n(1320),o=n(9670),i=n(7293),a=n(7066),s="toString",l=RegExp.prototype,c=l.toString,u=i((function(){return"/a/b"!=c.call({source:"a",flags:"b"})})),p=c.name!=s;(u||p)&&r(RegExp.prototype,s,(function(){var e=o(this),t=String(e.source),n=e.flags;return"/"+t+"/"+String(void 0===n&&e instanceof RegExp&&!("flags"in l)?a.call(e):n)}),{unsafe:!0})},189:function(e,t,n){"use strict";var r=n(7710),o=n(5631);e.exports=r("Set",(function(e){return function(){return e(this,arguments.length?arguments[0]:void 0)}}),o)},5218:function(e,t,n){"use strict";var r=n(2109),o=n(4230);r({target:"String",proto:!0,forced:n(3429)("anchor")},{anchor:function(e){return o(this,"a","name",e)}})},915:function(e,t,n){"use strict";var r=n(2109),o=n(4230);r({target:"String",proto:!0,forced:n(3429)("bold")},{bold:function(){return o(this,"b","","")}})},7852:function(e,t,n){"use strict";var r,o=n(2109),i=n(1236).f,a=n(7466),s=n(3929),l=n(4488),c=n(4964),u=n(1913),p="".endsWith,f=Math.min,d=c("endsWith");o({target:"String",proto:!0,forced:!(!u&&!d&&(r=i(String.prototype,"endsWith"),r&&!r.writable)||d)},{endsWith:function(e){var t=String(l(this));s(e);var n=arguments.length>1?arguments[1]:void 0,r=a(t.length),o=void 0===n?r:f(a(n),r),i=String(e);return p?p.call(t,i,o):t.slice(o-i.length,o)===i}})},2023:function(e,t,n){"use strict";var r=n(2109),o=n(3929),i=n(4488);r({target:"String",proto:!0,forced:!n(4964)("includes")},{includes:function(e){return!!~String(i(this)).indexOf(o(e),arguments.length>1?arguments[1]:void 0)}})},8734:function(e,t,n){"use strict";var r=n(2109),o=n(4230);r({target:"String",proto:!0,forced:n(3429)("italics")},{italics:function(){return o(this,"i","","")}})},8783:function(e,t,n){"use strict";var r=n(8710).charAt,o=n(9909),i=n(654),a="String Iterator",s=o.set,l=o.getterFor(a);i(String,"String",(function(e){s(this,{type:a,string:String(e),index:0})}),(function(){var e,t=l(this),n=t.string,o=t.index;return o>=n.length?{value:void 0,done:!0}:(e=r(n,o),t.index+=e.length,{value:e,done:!1})}))},9254:function(e,t,n){"use strict";var r=n(2109),o=n(4230);r({target:"String",proto:!0,forced:n(3429)("link")},{link:function(e){return o(this,"a","href",e)}})},4723:function(e,t,n){"use strict";var r=n(7007),o=n(9670),i=n(7466),a=n(4488),s=n(1530),l=n(7651);r("match",1,(function(e,t,n){return[function(t){var n=a(this),r=null==t?void 0:t[e];return void 0!==r?r.call(t,n):new RegExp(t)[e](String(n))},function(e){var r=n(t,e,this);if(r.done)return r.value;var a=o(e),c=String(this);if(!a.global)return l(a,c);var u=a.unicode;a.lastIndex=0;for(var p,f=[],d=0;null!==(p=l(a,c));){var h=String(p[0]);f[d]=h,""===h&&(a.lastIndex=s(c,i(a.lastIndex),u)),d++}return 0===d?null:f}]}))},2481:function(e,t,n){n(2109)({target:"String",proto:!0},{repeat:n(8415)})},5306:function(e,t,n){"use strict";var r=n(7007),o=n(9670),i=n(7466),a=n(9958),s=n(4488),l=n(1530),c=n(647),u=n(7651),p=Math.max,f=Math.min;r("replace",2,(function(e,t,n,r){var d=r.REGEXP_REPLACE_SUBSTITUTES_UNDEFINED_CAPTURE,h=r.REPLACE_KEEPS_$0,m=d?"$":"$0";return[function(n,r){var o=s(this),i=null==n?void 0:n[e];return void 0!==i?i.call(n,o,r):t.call(String(o),n,r)},function(e,r){if(!d&&h||"string"==typeof r&&-1===r.indexOf(m)){var s=n(t,e,this,r);if(s.done)return s.value}var v=o(e),g=String(this),y="function"==typeof r;y||(r=String(r));var b=v.global;if(b){var x=v.unicode;v.lastIndex=0}for(var w=[];;){var k=u(v,g);if(null===k)break;if(w.push(k),!b)break;""===String(k[0])&&(v.lastIndex=l(g,i(v.lastIndex),x))}for(var E,S="",_=0,O=0;O<w.length;O++){k=w[O];for(var A=String(k[0]),R=p(f(a(k.index),g.length),0),C=[],T=1;T<k.length;T++)C.push(void 0===(E=k[T])?E:String(E));var P=k.groups;if(y){var I=[A].concat(C,R,g);void 0!==P&&I.push(P);var j=String(r.apply(void 0,I))}else j=c(A,g,R,C,P,r);R>=_&&(S+=g.slice(_,R)+j,_=R+A.length)}return S+g.slice(_)}]}))},4765:function(e,t,n){"use strict";var r=n(7007),o=n(9670),i=n(4488),a=n(1150),s=n(7651);r("search",1,(function(e,t,n){return[function(t){var n=i(this),r=null==t?void 0:t[e];return void 0!==r?r.call(t,n):new RegExp(t)[e](String(n))},function(e){var r=n(t,e,this);if(r.done)return r.value;var i=o(e),l=String(this),c=i.lastIndex;a(c,0)||(i.lastIndex=0);var u=s(i,l);return a(i.lastIndex,c)||(i.lastIndex=c),null===u?-1:u.index}]}))},3123:function(e,t,n){"use strict";var r=n(7007),o=n(7850),i=n(9670),a=n(4488),s=n(6707),l=n(1530),c=n(7466),u=n(7651),p=n(2261),f=n(2999).UNSUPPORTED_Y,d=[].push,h=Math.min,m=4294967295;r("split",2,(function(e,t,n){var r;return r="c"=="abbc".split(/(b)*/)[1]||4!="test".split(/(?:)/,-1).length||2!="ab".split(/(?:ab)*/).length||4!=".".split(/(.?)(.?)/).length||".".split(/()()/).length>1||"".split(/.?/).length?function(e,n){var r=String(a(this)),i=void 0===n?m:n>>>0;if(0===i)return[];if(void 0===e)return[r];if(!o(e))return t.call(r,e,i);for(var s,l,c,u=[],f=(e.ignoreCase?"i":"")+(e.multiline?"m":"")+(e.unicode?"u":"")+(e.sticky?"y":""),h=0,v=new RegExp(e.source,f+"g");(s=p.call(v,r))&&!((l=v.lastIndex)>h&&(u.push(r.slice(h,s.index)),s.length>1&&s.index<r.length&&d.apply(u,s.slice(1)),c=s[0].length,h=l,u.length>=i));)v.lastIndex===s.index&&v.lastIndex++;return h===r.length?!c&&v.test("")||u.push(""):u.push(r.slice(h)),u.length>i?u.slice(0,i):u}:"0".split(void 0,0).length?function(e,n){return void 0===e&&0===n?[]:t.call(this,e,n)}:t,[function(t,n){var o=a(this),i=null==t?void 0:t[e];return void 0!==i?i.call(t,o,n):r.call(String(o),t,n)},function(e,o){var a=n(r,e,this,o,r!==t);if(a.done)return a.value;var p=i(e),d=String(this),v=s(p,RegExp),g=p.unicode,y=(p.ignoreCase?"i":"")+(p.multiline?"m":"")+(p.unicode?"u":"")+(f?"g":"y"),b=new v(f?"^(?:"+p.source+")":p,y),x=void 0===o?m:o>>>0;if(0===x)return[];if(0===d.length)return null===u(b,d)?[d]:[];for(var w=0,k=0,E=[];k<d.length;){b.lastIndex=f?0:k;var S,_=u(b,f?d.slice(k):d);if(null===_||(S=h(c(b.lastIndex+(f?k:0)),d.length))===w)k=l(d,k,g);else{if(E.push(d.slice(w,k)),E.length===x)return E;for(var O=1;O<=_.length-1;O++)if(E.push(_[O]),E.length===x)return E;k=w=S}}return E.push(d.slice(w)),E}]}),f)},6755:function(e,t,n){"use strict";var r,o=n(2109),i=n(1236).f,a=n(7466),s=n(3929),l=n(4488),c=n(4964),u=n(1913),p="".startsWith,f=Math.min,d=c("startsWith");o({target:"String",proto:!0,forced:!(!u&&!d&&(r=i(String.prototype,"startsWith"),r&&!r.writable)||d)},{startsWith:function(e){var t=String(l(this));s(e);var n=a(f(arguments.length>1?arguments[1]:void 0,t.length)),r=String(e);return p?p.call(t,r,n):t.slice(n,n+r.length)===r}})},3210:function(e,t,n){"use strict";var r=n(2109),o=n(3111).trim;r({target:"String",proto:!0,forced:n(6091)("trim")},{trim:function(){return o(this)}})},2443:function(e,t,n){n(7235)("asyncIterator")},1817:function(e,t,n){"use strict";var r=n(2109),o=n(9781),i=n(7854),a=n(6656),s=n(111),l=n(3070).f,c=n(9920),u=i.Symbol;if(o&&"function"==typeof u&&(!("description"in u.prototype)||void 0!==u().description)){var p={},f=function(){var e=arguments.length<1||void 0===arguments[0]?void 0:String(arguments[0]),t=this instanceof f?new u(e):void 0===e?u():u(e);return""===e&&(p[t]=!0),t};c(f,u);var d=f.prototype=u.prototype;d.constructor=f;var h=d.toString,m="Symbol(test)"==String(u("test")),v=/^Symbol\((.*)\)[^)]+$/;l(d,"description",{configurable:!0,get:function(){var e=s(this)?this.valueOf():this,t=h.call(e);if(a(p,e))return"";var n=m?t.slice(7,-1):t.replace(v,"$1");return""===n?void 0:n}}),r({global:!0,forced:!0},{Symbol:f})}},2401:function(e,t,n){n(7235)("hasInstance")},8722:function(e,t,n){n(7235)("isConcatSpreadable")},2165:function(e,t,n){n(7235)("iterator")},2526:function(e,t,n){"use strict";var r=n(2109),o=n(7854),i=n(5005),a=n(1913),s=n(9781),l=n(133),c=n(3307),u=n(7293),p=n(6656),f=n(3157),d=n(111),h=n(9670),m=n(7908),v=n(5656),g=n(7593),y=n(9114),b=n(30),x=n(1956),w=n(8006),k=n(1156),E=n(5181),S=n(1236),_=n(3070),O=n(5296),A=n(8880),R=n(1320),C=n(2309),T=n(6200),P=n(3501),I=n(9711),j=n(5112),L=n(6061),N=n(7235),M=n(8003),D=n(9909),F=n(2092).forEach,z=T("hidden"),U="Symbol",B=j("toPrimitive"),$=D.set,q=D.getterFor(U),W=Object.prototype,V=o.Symbol,H=i("JSON","stringify"),Y=S.f,G=_.f,Q=k.f,X=O.f,K=C("symbols"),Z=C("op-symbols"),J=C("string-to-symbol-registry"),ee=C("symbol-to-string-registry"),te=C("wks"),ne=o.QObject,re=!ne||!ne.prototype||!ne.prototype.findChild,oe=s&&u((function(){return 7!=b(G({},"a",{get:function(){return G(this,"a",{value:7}).a}})).a}))?function(e,t,n){var r=Y(W,t);r&&delete W[t],G(e,t,n),r&&e!==W&&G(W,t,r)}:G,ie=function(e,t){var n=K[e]=b(V.prototype);return $(n,{type:U,tag:e,description:t}),s||(n.description=t),n},ae=c?function(e){return"symbol"==typeof e}:function(e){return Object(e)instanceof V},se=function(e,t,n){e===W&&se(Z,t,n),h(e);var r=g(t,!0);return h(n),p(K,r)?(n.enumerable?(p(e,z)&&e[z][r]&&(e[z][r]=!1),n=b(n,{enumerable:y(0,!1)})):(p(e,z)||G(e,z,y(1,{})),e[z][r]=!0),oe(e,r,n)):G(e,r,n)},le=function(e,t){h(e);var n=v(t),r=x(n).concat(fe(n));return F(r,(function(t){s&&!ce.call(n,t)||se(e,t,n[t])})),e},ce=function(e){var t=g(e,!0),n=X.call(this,t);return!(this===W&&p(K,t)&&!p(Z,t))&&(!(n||!p(this,t)||!p(K,t)||p(this,z)&&this[z][t])||n)},ue=function(e,t){var n=v(e),r=g(t,!0);if(n!==W||!p(K,r)||p(Z,r)){var o=Y(n,r);return!o||!p(K,r)||p(n,z)&&n[z][r]||(o.enumerable=!0),o}},pe=function(e){var t=Q(v(e)),n=[];return F(t,(function(e){p(K,e)||p(P,e)||n.push(e)})),n},fe=function(e){var t=e===W,n=Q(t?Z:v(e)),r=[];return F(n,(function(e){!p(K,e)||t&&!p(W,e)||r.push(K[e])})),r};l||(R((V=function(){if(this instanceof V)throw TypeError("Symbol is not a constructor");var e=arguments.length&&void 0!==arguments[0]?String(arguments[0]):void 0,t=I(e),n=function(e){this===W&&n.call(Z,e),p(this,z)&&p(this[z],t)&&(this[z][t]=!1),oe(this,t,y(1,e))};return s&&re&&oe(W,t,{configurable:!0,set:n}),ie(t,e)}).prototype,"toString",(function(){return q(this).tag})),R(V,"withoutSetter",(function(e){return ie(I(e),e)})),O.f=ce,_.f=se,S.f=ue,w.f=k.f=pe,E.f=fe,L.f=function(e){return ie(j(e),e)},s&&(G(V.prototype,"description",{configurable:!0,get:function(){return q(this).description}}),a||R(W,"propertyIsEnumerable",ce,{unsafe:!0}))),r({global:!0,wrap:!0,forced:!l,sham:!l},{Symbol:V}),F(x(te),(function(e){N(e)})),r({target:U,stat:!0,forced:!l},{for:function(e){var t=String(e);if(p(J,t))return J[t];var n=V(t);return J[t]=n,ee[n]=t,n},keyFor:function(e){if(!ae(e))throw TypeError(e+" is not a symbol");if(p(ee,e))return ee[e]},useSetter:function(){re=!0},useSimple:function(){re=!1}}),r({target:"Object",stat:!0,forced:!l,sham:!s},{create:function(e,t){return void 0===t?b(e):le(b(e),t)},defineProperty:se,defineProperties:le,getOwnPropertyDescriptor:ue}),r({target:"Object",stat:!0,forced:!l},{getOwnPropertyNames:pe,getOwnPropertySymbols:fe}),r({target:"Object",stat:!0,forced:u((function(){E.f(1)}))},{getOwnPropertySymbols:function(e){return E.f(m(e))}}),H&&r({target:"JSON",stat:!0,forced:!l||u((function(){var e=V();return"[null]"!=H([e])||"{}"!=H({a:e})||"{}"!=H(Object(e))}))},{stringify:function(e,t,n){for(var r,o=[e],i=1;arguments.length>i;)o.push(arguments[i++]);if(r=t,(d(t)||void 0!==e)&&!ae(e))return f(t)||(t=function(e,t){if("function"==typeof r&&(t=r.call(this,e,t)),!ae(t))return t}),o[1]=t,H.apply(null,o)}}),V.prototype[B]||A(V.prototype,B,V.prototype.valueOf),M(V,U),P[z]=!0},6066:function(e,t,n){n(7235)("matchAll")},9007:function(e,t,n){n(7235)("match")},3510:function(e,t,n){n(7235)("replace")},1840:function(e,t,n){n(7235)("search")},6982:function(e,t,n){n(7235)("species")},2159:function(e,t,n){n(7235)("split")},6649:function(e,t,n){n(7235)("toPrimitive")},9341:function(e,t,n){n(7235)("toStringTag")},543:function(e,t,n){n(7235)("unscopables")},4129:function(e,t,n){"use strict";var r,o=n(7854),i=n(2248),a=n(2423),s=n(7710),l=n(9320),c=n(111),u=n(9909).enforce,p=n(8536),f=!o.ActiveXObject&&"ActiveXObject"in o,d=Object.isExtensible,h=function(e){return function(){return e(this,arguments.length?arguments[0]:void 0)}},m=e.exports=s("WeakMap",h,l);if(p&&f){r=l.getConstructor(h,"WeakMap",!0),a.REQUIRED=!0;var v=m.prototype,g=v.delete,y=v.has,b=v.get,x=v.set;i(v,{delete:function(e){if(c(e)&&!d(e)){var t=u(this);return t.frozen||(t.frozen=new r),g.call(this,e)||t.frozen.delete(e)}return g.call(this,e)},has:function(e){if(c(e)&&!d(e)){var t=u(this);return t.frozen||(t.frozen=new r),y.call(this,e)||t.frozen.has(e)}return y.call(this,e)},get:function(e){if(c(e)&&!d(e)){var t=u(this);return t.frozen||(t.frozen=new r),y.call(this,e)?b.call(this,e):t.frozen.get(e)}return b.call(this,e)},set:function(e,t){if(c(e)&&!d(e)){var n=u(this);n.frozen||(n.frozen=new r),y.call(this,e)?x.call(this,e,t):n.frozen.set(e,t)}else x.call(this,e,t);return this}})}},8478:function(e,t,n){"use strict";n(7710)("WeakSet",(function(e){return function(){return e(this,arguments.length?arguments[0]:void 0)}}),n(9320))},4747:function(e,t,n){var r=n(7854),o=n(8324),i=n(8533),a=n(8880);for(var s in o){var l=r[s],c=l&&l.prototype;if(c&&c.forEach!==i)try{a(c,"forEach",i)}catch(e){c.forEach=i}}},3948:function(e,t,n){var r=n(7854),o=n(8324),i=n(6992),a=n(8880),s=n(5112),l=s("iterator"),c=s("toStringTag"),u=i.values;for(var p in o){var f=r[p],d=f&&f.prototype;if(d){if(d[l]!==u)try{a(d,l,u)}catch(e){d[l]=u}if(d[c]||a(d,c,p),o[p])for(var h in i)if(d[h]!==i[h])try{a(d,h,i[h])}catch(e){d[h]=i[h]}}}},1637:function(e,t,n){"use strict";n(6992);var r=n(2109),o=n(5005),i=n(590),a=n(1320),s=n(2248),l=n(8003),c=n(4994),u=n(9909),p=n(5787),f=n(6656),d=n(9974),h=n(648),m=n(9670),v=n(111),g=n(30),y=n(9114),b=n(8554),x=n(1246),w=n(5112),k=o("fetch"),E=o("Headers"),S=w("iterator"),_="URLSearchParams",O="URLSearchParamsIterator",A=u.set,R=u.getterFor(_),C=u.getterFor(O),T=/\+/g,P=Array(4),I=function(e){return P[e-1]||(P[e-1]=RegExp("((?:%[\\da-f]{2}){"+e+"})","gi"))},j=function(e){try{return decodeURIComponent(e)}catch(t){return e}},L=function(e){var t=e.replace(T," "),n=4;try{return decodeURIComponent(t)}catch(e){for(;n;)t=t.replace(I(n--),j);return t}},N=/[!'()~]|%20/g,M={"!":"%21","'":"%27","(":"%28",")":"%29","~":"%7E","%20":"+"},D=function(e){return M[e]},F=function(e){return encodeURIComponent(e).replace(N,D)},z=function(e,t){if(t)for(var n,r,o=t.split("&"),i=0;i<o.length;)(n=o[i++]).length&&(r=n.split("="),e.push({key:L(r.shift()),value:L(r.join("="))}))},U=function(e){this.entries.length=0,z(this.entries,e)},B=function(e,t){if(e<t)throw TypeError("Not enough arguments")},$=c((function(e,t){A(this,{type:O,iterator:b(R(e).entries),kind:t})}),"Iterator",(function(){var e=C(this),t=e.kind,n=e.iterator.next(),r=n.value;return n.done||(n.value="keys"===t?r.key:"values"===t?r.value:[r.key,r.value]),n})),q=function(){p(this,q,_);var e,t,n,r,o,i,a,s,l,c=arguments.length>0?arguments[0]:void 0,u=this,d=[];if(A(u,{type:_,entries:d,updateURL:function(){},updateSearchParams:U}),void 0!==c)if(v(c))if("function"==typeof(e=x(c)))for(n=(t=e.call(c)).next;!(r=n.call(t)).done;){if((a=(i=(o=b(m(r.value))).next).call(o)).done||(s=i.call(o)).done||!i.call(o).done)throw TypeError("Expected sequence with length 2");d.push({key:a.value+"",value:s.value+""})}else for(l in c)f(c,l)&&d.push({key:l,value:c[l]+""});else z(d,"string"==typeof c?"?"===c.charAt(0)?c.slice(1):c:c+"")},W=q.prototype;s(W,{append:function(e,t){B(arguments.length,2);var n=R(this);n.entries.push({key:e+"",value:t+""}),n.updateURL()},delete:function(e){B(arguments.length,1);for(var t=R(this),n=t.entries,r=e+"",o=0;o<n.length;)n[o].key===r?n.splice(o,1):o++;t.updateURL()},get:function(e){B(arguments.length,1);for(var t=R(this).entries,n=e+"",r=0;r<t.length;r++)if(t[r].key===n)return t[r].value;return null},getAll:function(e){B(arguments.length,1);for(var t=R(this).entries,n=e+"",r=[],o=0;o<t.length;o++)t[o].key===n&&r.push(t[o].value);return r},has:function(e){B(arguments.length,1);for(var t=R(this).entries,n=e+"",r=0;r<t.length;)if(t[r++].key===n)return!0;return!1},set:function(e,t){B(arguments.length,1);for(var n,r=R(this),o=r.entries,i=!1,a=e+"",s=t+"",l=0;l<o.length;l++)(n=o[l]).key===a&&(i?o.splice(l--,1):(i=!0,n.value=s));i||o.push({key:a,value:s}),r.updateURL()},sort:function(){var e,t,n,r=R(this),o=r.entries,i=o.slice();for(o.length=0,n=0;n<i.length;n++){for(e=i[n],t=0;t<n;t++)if(o[t].key>e.key){o.splice(t,0,e);break}t===n&&o.push(e)}r.updateURL()},forEach:function(e){for(var t,n=R(this).entries,r=d(e,arguments.length>1?arguments[1]:void 0,3),o=0;o<n.length;)r((t=n[o++]).value,t.key,this)},keys:function(){return new $(this,"keys")},values:function(){return new $(this,"values")},entries:function(){return new $(this,"entries")}},{enumerable:!0}),a(W,S,W.entries),a(W,"toString",(function(){for(var e,t=R(this).entries,n=[],r=0;r<t.length;)e=t[r++],n.push(F(e.key)+"="+F(e.value));return n.join("&")}),{enumerable:!0}),l(q,_),r({global:!0,forced:!i},{URLSearchParams:q}),i||"function"!=typeof k||"function"!=typeof E||r({global:!0,enumerable:!0,forced:!0},{fetch:function(e){var t,n,r,o=[e];return arguments.length>1&&(v(t=arguments[1])&&(n=t.body,h(n)===_&&((r=t.headers?new E(t.headers):new E).has("content-type")||r.set("content-type","application/x-www-form-urlencoded;charset=UTF-8"),t=g(t,{body:y(0,String(n)),headers:y(0,r)}))),o.push(t)),k.apply(this,o)}}),e.exports={URLSearchParams:q,getState:R}},285:function(e,t,n){"use strict";n(8783);var r,o=n(2109),i=n(9781),a=n(590),s=n(7854),l=n(6048),c=n(1320),u=n(5787),p=n(6656),f=n(1574),d=n(8457),h=n(8710).codeAt,m=n(3197),v=n(8003),g=n(1637),y=n(9909),b=s.URL,x=g.URLSearchParams,w=g.getState,k=y.set,E=y.getterFor("URL"),S=Math.floor,_=Math.pow,O="Invalid scheme",A="Invalid host",R="Invalid port",C=/[A-Za-z]/,T=/[\d+-.A-Za-z]/,P=/\d/,I=/^(0x|0X)/,j=/^[0-7]+$/,L=/^\d+$/,N=/^[\dA-Fa-f]+$/,M=/[\0\t\n\r #%/:?@[\\]]/,D=/[\0\t\n\r #/:?@[\\]]/,F=/^[\u0000-\u001F ]+|[\u0000-\u001F ]+$/g,z=/[\t\n\r]/g,U=function(e,t){var n,r,o;if("["==t.charAt(0)){if("]"!=t.charAt(t.length-1))return A;if(!(n=$(t.slice(1,-1))))return A;e.host=n}else if(X(e)){if(t=m(t),M.test(t))return A;if(null===(n=B(t)))return A;e.host=n}else{if(D.test(t))return A;for(n="",r=d(t),o=0;o<r.length;o++)n+=G(r[o],W);e.host=n}},B=function(e){var t,n,r,o,i,a,s,l=e.split(".");if(l.length&&""==l[l.length-1]&&l.pop(),(t=l.length)>4)return e;for(n=[],r=0;r<t;r++){if(""==(o=l[r]))return e;if(i=10,o.length>1&&"0"==o.charAt(0)&&(i=I.test(o)?16:8,o=o.slice(8==i?1:2)),""===o)a=0;else{if(!(10==i?L:8==i?j:N).test(o))return e;a=parseInt(o,i)}n.push(a)}for(r=0;r<t;r++)if(a=n[r],r==t-1){if(a>=_(256,5-t))return null}else if(a>255)return null;for(s=n.pop(),r=0;r<n.length;r++)s+=n[r]*_(256,3-r);return s},$=function(e){var t,n,r,o,i,a,s,l=[0,0,0,0,0,0,0,0],c=0,u=null,p=0,f=function(){return e.charAt(p)};if(":"==f()){if(":"!=e.charAt(1))return;p+=2,u=++c}for(;f();){if(8==c)return;if(":"!=f()){for(t=n=0;n<4&&N.test(f());)t=16*t+parseInt(f(),16),p++,n++;if("."==f()){if(0==n)return;if(p-=n,c>6)return;for(r=0;f();){if(o=null,r>0){if(!("."==f()&&r<4))return;p++}if(!P.test(f()))return;for(;P.test(f());){if(i=parseInt(f(),10),null===o)o=i;else{if(0==o)return;o=10*o+i}if(o>255)return;p++}l[c]=256*l[c]+o,2!=++r&&4!=r||c++}if(4!=r)return;break}if(":"==f()){if(p++,!f())return}else if(f())return;l[c++]=t}else{if(null!==u)return;p++,u=++c}}if(null!==u)for(a=c-u,c=7;0!=c&&a>0;)s=l[c],l[c--]=l[u+a-1],l[u+--a]=s;else if(8!=c)return;return l},q=function(e){var t,n,r,o;if("number"==typeof e){for(t=[],n=0;n<4;n++)t.unshift(e%256),e=S(e/256);return t.join(".")}if("object"==typeof e){for(t="",r=function(e){for(var t=null,n=1,r=null,o=0,i=0;i<8;i++)0!==e[i]?(o>n&&(t=r,n=o),r=null,o=0):(null===r&&(r=i),++o);return o>n&&(t=r,n=o),t}(e),n=0;n<8;n++)o&&0===e[n]||(o&&(o=!1),r===n?(t+=n?":":"::",o=!0):(t+=e[n].toString(16),n<7&&(t+=":")));return"["+t+"]"}return e},W={},V=f({},W,{" ":1,'"':1,"<":1,">":1,"`":1}),H=f({},V,{"#":1,"?":1,"{":1,"}":1}),Y=f({},H,{"/":1,":":1,";":1,"=":1,"@":1,"[":1,"\\":1,"]":1,"^":1,"|":1}),G=function(e,t){var n=h(e,0);return n>32&&n<127&&!p(t,e)?e:encodeURIComponent(e)},Q={ftp:21,file:null,http:80,https:443,ws:80,wss:443},X=function(e){return p(Q,e.scheme)},K=function(e){return""!=e.username||""!=e.password},Z=function(e){return!e.host||e.cannotBeABaseURL||"file"==e.scheme},J=function(e,t){var n;return 2==e.length&&C.test(e.charAt(0))&&(":"==(n=e.charAt(1))||!t&&"|"==n)},ee=function(e){var t;return e.length>1&&J(e.slice(0,2))&&(2==e.length||"/"===(t=e.charAt(2))||"\\"===t||"?"===t||"#"===t)},te=function(e){var t=e.path,n=t.length;!n||"file"==e.scheme&&1==n&&J(t[0],!0)||t.pop()},ne=function(e){return"."===e||"%2e"===e.toLowerCase()},re={},oe={},ie={},ae={},se={},le={},ce={},ue={},pe={},fe={},de={},he={},me={},ve={},ge={},ye={},be={},xe={},we={},ke={},Ee={},Se=function(e,t,n,o){var i,a,s,l,c,u=n||re,f=0,h="",m=!1,v=!1,g=!1;for(n||(e.scheme="",e.username="",e.password="",e.host=null,e.port=null,e.path=[],e.query=null,e.fragment=null,e.cannotBeABaseURL=!1,t=t.replace(F,"")),t=t.replace(z,""),i=d(t);f<=i.length;){switch(a=i[f],u){case re:if(!a||!C.test(a)){if(n)return O;u=ie;continue}h+=a.toLowerCase(),u=oe;break;case oe:if(a&&(T.test(a)||"+"==a||"-"==a||"."==a))h+=a.toLowerCase();else{if(":"!=a){if(n)return O;h="",u=ie,f=0;continue}if(n&&(X(e)!=p(Q,h)||"file"==h&&(K(e)||null!==e.port)||"file"==e.scheme&&!e.host))return;if(e.scheme=h,n)return void(X(e)&&Q[e.scheme]==e.port&&(e.port=null));h="","file"==e.scheme?u=ve:X(e)&&o&&o.scheme==e.scheme?u=ae:X(e)?u=ue:"/"==i[f+1]?(u=se,f++):(e.cannotBeABaseURL=!0,e.path.push(""),u=we)}break;case ie:if(!o||o.cannotBeABaseURL&&"#"!=a)return O;if(o.cannotBeABaseURL&&"#"==a){e.scheme=o.scheme,e.path=o.path.slice(),e.query=o.query,e.fragment="",e.cannotBeABaseURL=!0,u=Ee;break}u="file"==o.scheme?ve:le;continue;case ae:if("/"!=a||"/"!=i[f+1]){u=le;continue}u=pe,f++;break;case se:if("/"==a){u=fe;break}u=xe;continue;case le:if(e.scheme=o.scheme,a==r)e.username=o.username,e.password=o.password,e.host=o.host,e.port=o.port,e.path=o.path.slice(),e.query=o.query;else if("/"==a||"\\"==a&&X(e))u=ce;else if("?"==a)e.username=o.username,e.password=o.password,e.host=o.host,e.port=o.port,e.path=o.path.slice(),e.query="",u=ke;else{if("#"!=a){e.username=o.username,e.password=o.password,e.host=o.host,e.port=o.port,e.path=o.path.slice(),e.path.pop(),u=xe;continue}e.username=o.username,e.password=o.password,e.host=o.host,e.port=o.port,e.path=o.path.slice(),e.query=o.query,e.fragment="",u=Ee}break;case ce:if(!X(e)||"/"!=a&&"\\"!=a){if("/"!=a){e.username=o.username,e.password=o.password,e.host=o.host,e.port=o.port,u=xe;continue}u=fe}else u=pe;break;case ue:if(u=pe,"/"!=a||"/"!=h.charAt(f+1))continue;f++;break;case pe:if("/"!=a&&"\\"!=a){u=fe;continue}break;case fe:if("@"==a){m&&(h="%40"+h),m=!0,s=d(h);for(var y=0;y<s.length;y++){var b=s[y];if(":"!=b||g){var x=G(b,Y);g?e.password+=x:e.username+=x}else g=!0}h=""}else if(a==r||"/"==a||"?"==a||"#"==a||"\\"==a&&X(e)){if(m&&""==h)return"Invalid authority";f-=d(h).length+1,h="",u=de}else h+=a;break;case de:case he:if(n&&"file"==e.scheme){u=ye;continue}if(":"!=a||v){if(a==r||"/"==a||"?"==a||"#"==a||"\\"==a&&X(e)){if(X(e)&&""==h)return A;if(n&&""==h&&(K(e)||null!==e.port))return;if(l=U(e,h))return l;if(h="",u=be,n)return;continue}"["==a?v=!0:"]"==a&&(v=!1),h+=a}else{if(""==h)return A;if(l=U(e,h))return l;if(h="",u=me,n==he)return}break;case me:if(!P.test(a)){if(a==r||"/"==a||"?"==a||"#"==a||"\\"==a&&X(e)||n){if(""!=h){var w=parseInt(h,10);if(w>65535)return R;e.port=X(e)&&w===Q[e.scheme]?null:w,h=""}if(n)return;u=be;continue}return R}h+=a;break;case ve:if(e.scheme="file","/"==a||"\\"==a)u=ge;else{if(!o||"file"!=o.scheme){u=xe;continue}if(a==r)e.host=o.host,e.path=o.path.slice(),e.query=o.query;else if("?"==a)e.host=o.host,e.path=o.path.slice(),e.query="",u=ke;else{if("#"!=a){ee(i.slice(f).join(""))||(e.host=o.host,e.path=o.path.slice(),te(e)),u=xe;continue}e.host=o.host,e.path=o.path.slice(),e.query=o.query,e.fragment="",u=Ee}}break;case ge:if("/"==a||"\\"==a){u=ye;break}o&&"file"==o.scheme&&!ee(i.slice(f).join(""))&&(J(o.path[0],!0)?e.path.push(o.path[0]):e.host=o.host),u=xe;continue;case ye:if(a==r||"/"==a||"\\"==a||"?"==a||"#"==a){if(!n&&J(h))u=xe;else if(""==h){if(e.host="",n)return;u=be}else{if(l=U(e,h))return l;if("localhost"==e.host&&(e.host=""),n)return;h="",u=be}continue}h+=a;break;case be:if(X(e)){if(u=xe,"/"!=a&&"\\"!=a)continue}else if(n||"?"!=a)if(n||"#"!=a){if(a!=r&&(u=xe,"/"!=a))continue}else e.fragment="",u=Ee;else e.query="",u=ke;break;case xe:if(a==r||"/"==a||"\\"==a&&X(e)||!n&&("?"==a||"#"==a)){if(".."===(c=(c=h).toLowerCase())||"%2e."===c||".%2e"===c||"%2e%2e"===c?(te(e),"/"==a||"\\"==a&&X(e)||e.path.push("")):ne(h)?"/"==a||"\\"==a&&X(e)||e.path.push(""):("file"==e.scheme&&!e.path.length&&J(h)&&(e.host&&(e.host=""),h=h.charAt(0)+":"),e.path.push(h)),h="","file"==e.scheme&&(a==r||"?"==a||"#"==a))for(;e.path.length>1&&""===e.path[0];)e.path.shift();"?"==a?(e.query="",u=ke):"#"==a&&(e.fragment="",u=Ee)}else h+=G(a,H);break;case we:"?"==a?(e.query="",u=ke):"#"==a?(e.fragment="",u=Ee):a!=r&&(e.path[0]+=G(a,W));break;case ke:n||"#"!=a?a!=r&&("'"==a&&X(e)?e.query+="%27":e.query+="#"==a?"%23":G(a,W)):(e.fragment="",u=Ee);break;case Ee:a!=r&&(e.fragment+=G(a,V))}f++}},_e=function(e){var t,n,r=u(this,_e,"URL"),o=arguments.length>1?arguments[1]:void 0,a=String(e),s=k(r,{type:"URL"});if(void 0!==o)if(o instanceof _e)t=E(o);else if(n=Se(t={},String(o)))throw TypeError(n);if(n=Se(s,a,null,t))throw TypeError(n);var l=s.searchParams=new x,c=w(l);c.updateSearchParams(s.query),c.updateURL=function(){s.query=String(l)||null},i||(r.href=Ae.call(r),r.origin=Re.call(r),r.protocol=Ce.call(r),r.username=Te.call(r),r.password=Pe.call(r),r.host=Ie.call(r),r.hostname=je.call(r),r.port=Le.call(r),r.pathname=Ne.call(r),r.search=Me.call(r),r.searchParams=De.call(r),r.hash=Fe.call(r))},Oe=_e.prototype,Ae=function(){var e=E(this),t=e.scheme,n=e.username,r=e.password,o=e.host,i=e.port,a=e.path,s=e.query,l=e.fragment,c=t+":";return null!==o?(c+="//",K(e)&&(c+=n+(r?":"+r:"")+"@"),c+=q(o),null!==i&&(c+=":"+i)):"file"==t&&(c+="//"),c+=e.cannotBeABaseURL?a[0]:a.length?"/"+a.join("/"):"",null!==s&&(c+="?"+s),null!==l&&(c+="#"+l),c},Re=function(){var e=E(this),t=e.scheme,n=e.port;if("blob"==t)try{return new _e(t.path[0]).origin}catch(e){return"null"}return"file"!=t&&X(e)?t+"://"+q(e.host)+(null!==n?":"+n:""):"null"},Ce=function(){return E(this).scheme+":"},Te=function(){return E(this).username},Pe=function(){return E(this).password},Ie=function(){var e=E(this),t=e.host,n=e.port;return null===t?"":null===n?q(t):q(t)+":"+n},je=function(){var e=E(this).host;return null===e?"":q(e)},Le=function(){var e=E(this).port;return null===e?"":String(e)},Ne=function(){var e=E(this),t=e.path;return e.cannotBeABaseURL?t[0]:t.length?"/"+t.join("/"):""},Me=function(){var e=E(this).query;return e?"?"+e:""},De=function(){return E(this).searchParams},Fe=function(){var e=E(this).fragment;return e?"#"+e:""},ze=function(e,t){return{get:e,set:t,configurable:!0,enumerable:!0}};if(i&&l(Oe,{href:ze(Ae,(function(e){var t=E(this),n=String(e),r=Se(t,n);if(r)throw TypeError(r);w(t.searchParams).updateSearchParams(t.query)})),origin:ze(Re),protocol:ze(Ce,(function(e){var t=E(this);Se(t,String(e)+":",re)})),username:ze(Te,(function(e){var t=E(this),n=d(String(e));if(!Z(t)){t.username="";for(var r=0;r<n.length;r++)t.username+=G(n[r],Y)}})),password:ze(Pe,(function(e){var t=E(this),n=d(String(e));if(!Z(t)){t.password="";for(var r=0;r<n.length;r++)t.password+=G(n[r],Y)}})),host:ze(Ie,(function(e){var t=E(this);t.cannotBeABaseURL||Se(t,String(e),de)})),hostname:ze(je,(function(e){var t=E(this);t.cannotBeABaseURL||Se(t,String(e),he)})),port:ze(Le,(function(e){var t=E(this);Z(t)||(""==(e=String(e))?t.port=null:Se(t,e,me))})),pathname:ze(Ne,(function(e){var t=E(this);t.cannotBeABaseURL||(t.path=[],Se(t,e+"",be))})),search:ze(Me,(function(e){var t=E(this);""==(e=String(e))?t.query=null:("?"==e.charAt(0)&&(e=e.slice(1)),t.query="",Se(t,e,ke)),w(t.searchParams).updateSearchParams(t.query)})),searchParams:ze(De),hash:ze(Fe,(function(e){var t=E(this);""!=(e=String(e))?("#"==e.charAt(0)&&(e=e.slice(1)),t.fragment="",Se(t,e,Ee)):t.fragment=null}))}),c(Oe,"toJSON",(function(){return Ae.call(this)}),{enumerable:!0}),c(Oe,"toString",(function(){return Ae.call(this)}),{enumerable:!0}),b){var Ue=b.createObjectURL,Be=b.revokeObjectURL;Ue&&c(_e,"createObjectURL",(function(e){return Ue.apply(b,arguments)})),Be&&c(_e,"revokeObjectURL",(function(e){return Be.apply(b,arguments)}))}v(_e,"URL"),o({global:!0,forced:!a,sham:!i},{URL:_e})},3753:function(e,t,n){"use strict";n(2109)({target:"URL",proto:!0,enumerable:!0},{toJSON:function(){return URL.prototype.toString.call(this)}})},4875:function(e,t,n){"use strict";var r=n(3645),o=n.n(r)()((function(e){return e[1]}));o.push([e.id,"/*\r\n * Container style\r\n */\r\n.ps {\r\n  overflow: hidden !important;\r\n  overflow-anchor: none;\r\n  -ms-overflow-style: none;\r\n  touch-action: auto;\r\n  -ms-touch-action: auto;\r\n}\r\n\r\n/*\r\n * Scrollbar rail styles\r\n */\r\n.ps__rail-x {\r\n  display: none;\r\n  opacity: 0;\r\n  transition: background-color .2s linear, opacity .2s linear;\r\n  -webkit-transition: background-color .2s linear, opacity .2s linear;\r\n  height: 15px;\r\n  /* there must be 'bottom' or 'top' for ps__rail-x */\r\n  bottom: 0px;\r\n  /* please don't change 'position' */\r\n  position: absolute;\r\n}\r\n\r\n.ps__rail-y {\r\n  display: none;\r\n  opacity: 0;\r\n  transition: background-color .2s linear, opacity .2s linear;\r\n  -webkit-transition: background-color .2s linear, opacity .2s linear;\r\n  width: 15px;\r\n  /* there must be 'right' or 'left' for ps__rail-y */\r\n  right: 0;\r\n  /* please don't change 'position' */\r\n  position: absolute;\r\n}\r\n\r\n.ps--active-x > .ps__rail-x,\r\n.ps--active-y > .ps__rail-y {\r\n  display: block;\r\n  background-color: transparent;\r\n}\r\n\r\n.ps:hover > .ps__rail-x,\r\n.ps:hover > .ps__rail-y,\r\n.ps--focus > .ps__rail-x,\r\n.ps--focus > .ps__rail-y,\r\n.ps--scrolling-x > .ps__rail-x,\r\n.ps--scrolling-y > .ps__rail-y {\r\n  opacity: 0.6;\r\n}\r\n\r\n.ps .ps__rail-x:hover,\r\n.ps .ps__rail-y:hover,\r\n.ps .ps__rail-x:focus,\r\n.ps .ps__rail-y:focus,\r\n.ps .ps__rail-x.ps--clicking,\r\n.ps .ps__rail-y.ps--clicking {\r\n  background-color: #eee;\r\n  opacity: 0.9;\r\n}\r\n\r\n/*\r\n * Scrollbar thumb styles\r\n */\r\n.ps__thumb-x {\r\n  background-color: #aaa;\r\n  border-radius: 6px;\r\n  transition: background-color .2s linear, height .2s ease-in-out;\r\n  -webkit-transition: background-color .2s linear, height .2s ease-in-out;\r\n  height: 6px;\r\n  /* there must be 'bottom' for ps__thumb-x */\r\n  bottom: 2px;\r\n  /* please don't change 'position' */\r\n  position: absolute;\r\n}\r\n\r\n.ps__thumb-y {\r\n  background-color: #aaa;\r\n  border-radius: 6px;\r\n  transition: background-color .2s linear, width .2s ease-in-out;\r\n  -webkit-transition: background-color .2s linear, width .2s ease-in-out;\r\n  width: 6px;\r\n  /* there must be 'right' for ps__thumb-y */\r\n  right: 2px;\r\n  /* please don't change 'position' */\r\n  position: absolute;\r\n}\r\n\r\n.ps__rail-x:hover > .ps__thumb-x,\r\n.ps__rail-x:focus > .ps__thumb-x,\r\n.ps__rail-x.ps--clicking .ps__thumb-x {\r\n  background-color: #999;\r\n  height: 11px;\r\n}\r\n\r\n.ps__rail-y:hover > .ps__thumb-y,\r\n.ps__rail-y:focus > .ps__thumb-y,\r\n.ps__rail-y.ps--clicking .ps__thumb-y {\r\n  background-color: #999;\r\n  width: 11px;\r\n}\r\n\r\n/* MS supports */\r\n@supports (-ms-overflow-style: none) {\r\n  .ps {\r\n    overflow: auto !important;\r\n  }\r\n}\r\n\r\n@media screen and (-ms-high-contrast: active), (-ms-high-contrast: none) {\r\n  .ps {\r\n    overflow: auto !important;\r\n  }\r\n}\r\n",""]),t.Z=o},3645:function(e){"use strict";e.exports=function(e){var t=[];return t.toString=function(){return this.map((function(t){var n=e(t);return t[2]?"@media ".concat(t[2]," {").concat(n,"}"):n})).join("")},t.i=function(e,n,r){"string"==typeof e&&(e=[[null,e,""]]);var o={};if(r)for(var i=0;i<this.length;i++){var a=this[i][0];null!=a&&(o[a]=!0)}for(var s=0;s<e.length;s++){var l=[].concat(e[s]);r&&o[l[0]]||(n&&(l[2]?l[2]="".concat(n," and ").concat(l[2]):l[2]=n),t.push(l))}},t}},1851:function(e,t){var n,r;void 0===(r="function"==typeof(n=function(e){"use strict";e.__esModule=!0;var t={},n=Object.prototype.hasOwnProperty,r=function(e,t){if("function"==typeof t){var n=e;e=t,t=n}var r=t&&t.delay||t||0,o=void 0,i=void 0,a=void 0;return function(){for(var t=arguments.length,n=Array(t),s=0;s<t;s++)n[s]=arguments[s];o=n,i=this,a||(a=setTimeout((function(){e.apply(i,o),o=i=a=null}),r))}},o=function(e,t,n){var r=n.value;return{configurable:!0,get:function(){var e=r.bind(this);return Object.defineProperty(this,t,{value:e,configurable:!0,writable:!0}),e}}},i=l((function(e){var r=arguments.length<=1||void 0===arguments[1]?t:arguments[1],o=r.cache||{};return function(){for(var t=arguments.length,i=Array(t),a=0;a<t;a++)i[a]=arguments[a];var s=String(i[0]);return!1===r.caseSensitive&&(s=s.toLowerCase()),n.call(o,s)?o[s]:o[s]=e.apply(this,i)}})),a=l(r),s=l((function(e,t){return e.bind(t)}),(function(){return o}));function l(e,t){var n,r=(t=t||e.decorate||(n=e,function(e){return"function"==typeof e?n(e):function(t,r,o){o.value=n(o.value,e,t,r,o)}}))();return function(){for(var n=arguments.length,o=Array(n),i=0;i<n;i++)o[i]=arguments[i];var a=o.length;return(a<2?t:a>2?r:e).apply(void 0,o)}}e.memoize=i,e.debounce=a,e.bind=s,e.default={memoize:i,debounce:a,bind:s}})?n.apply(t,[t]):n)||(e.exports=r)},7856:function(e){e.exports=function(){"use strict";var e=Object.hasOwnProperty,t=Object.setPrototypeOf,n=Object.isFrozen,r=Object.getPrototypeOf,o=Object.getOwnPropertyDescriptor,i=Object.freeze,a=Object.seal,s=Object.create,l="undefined"!=typeof Reflect&&Reflect,c=l.apply,u=l.construct;c||(c=function(e,t,n){return e.apply(t,n)}),i||(i=function(e){return e}),a||(a=function(e){return e}),u||(u=function(e,t){return new(Function.prototype.bind.apply(e,[null].concat(function(e){if(Array.isArray(e)){for(var t=0,n=Array(e.length);t<e.length;t++)n[t]=e[t];return n}return Array.from(e)}(t))))});var p,f=k(Array.prototype.forEach),d=k(Array.prototype.pop),h=k(Array.prototype.push),m=k(String.prototype.toLowerCase),v=k(String.prototype.match),g=k(String.prototype.replace),y=k(String.prototype.indexOf),b=k(String.prototype.trim),x=k(RegExp.prototype.test),w=(p=TypeError,function(){for(var e=arguments.length,t=Array(e),n=0;n<e;n++)t[n]=arguments[n];return u(p,t)});function k(e){return function(t){for(var n=arguments.length,r=Array(n>1?n-1:0),o=1;o<n;o++)r[o-1]=arguments[o];return c(e,t,r)}}function E(e,r){t&&t(e,null);for(var o=r.length;o--;){var i=r[o];if("string"==typeof i){var a=m(i);a!==i&&(n(r)||(r[o]=a),i=a)}e[i]=!0}return e}function S(t){var n=s(null),r=void 0;for(r in t)c(e,t,[r])&&(n[r]=t[r]);return n}function _(e,t){for(;null!==e;){var n=o(e,t);if(n){if(n.get)return k(n.get);if("function"==typeof n.value)return k(n.value)}e=r(e)}return function(e){return console.warn("fallback value for",e),null}}var O=i(["a","abbr","acronym","address","area","article","aside","audio","b","bdi","bdo","big","blink","blockquote","body","br","button","canvas","caption","center","cite","code","col","colgroup","content","data","datalist","dd","decorator","del","details","dfn","dialog","dir","div","dl","dt","element","em","fieldset","figcaption","figure","font","footer","form","h1","h2","h3","h4","h5","h6","head","header","hgroup","hr","html","i","img","input","ins","kbd","label","legend","li","main","map","mark","marquee","menu","menuitem","meter","nav","nobr","ol","optgroup","option","output","p","picture","pre","progress","q","rp","rt","ruby","s","samp","section","select","shadow","small","source","spacer","span","strike","strong","style","sub","summary","sup","table","tbody","td","template","textarea","tfoot","th","thead","time","tr","track","tt","u","ul","var","video","wbr"]),A=i(["svg","a","altglyph","altglyphdef","altglyphitem","animatecolor","animatemotion","animatetransform","circle","clippath","defs","desc","ellipse","filter","font","g","glyph","glyphref","hkern","image","line","lineargradient","marker","mask","metadata","mpath","path","pattern","polygon","polyline","radialgradient","rect","stop","style","switch","symbol","text","textpath","title","tref","tspan","view","vkern"]),R=i(["feBlend","feColorMatrix","feComponentTransfer","feComposite","feConvolveMatrix","feDiffuseLighting","feDisplacementMap","feDistantLight","feFlood","feFuncA","feFuncB","feFuncG","feFuncR","feGaussianBlur","feMerge","feMergeNode","feMorphology","feOffset","fePointLight","feSpecularLighting","feSpotLight","feTile","feTurbulence"]),C=i(["animate","color-profile","cursor","discard","fedropshadow","feimage","font-face","font-face-format","font-face-name","font-face-src","font-face-uri","foreignobject","hatch","hatchpath","mesh","meshgradient","meshpatch","meshrow","missing-glyph","script","set","solidcolor","unknown","use"]),T=i(["math","menclose","merror","mfenced","mfrac","mglyph","mi","mlabeledtr","mmultiscripts","mn","mo","mover","mpadded","mphantom","mroot","mrow","ms","mspace","msqrt","mstyle","msub","msup","msubsup","mtable","mtd","mtext","mtr","munder","munderover"]),P=i(["maction","maligngroup","malignmark","mlongdiv","mscarries","mscarry","msgroup","mstack","msline","msrow","semantics","annotation","annotation-xml","mprescripts","none"]),I=i(["#text"]),j=i(["accept","action","align","alt","autocapitalize","autocomplete","autopictureinpicture","autoplay","background","bgcolor","border","capture","cellpadding","cellspacing","checked","cite","class","clear","color","cols","colspan","controls","controlslist","coords","crossorigin","datetime","decoding","default","dir","disabled","disablepictureinpicture","disableremoteplayback","download","draggable","enctype","enterkeyhint","face","for","headers","height","hidden","high","href","hreflang","id","inputmode","integrity","ismap","kind","label","lang","list","loading","loop","low","max","maxlength","media","method","min","minlength","multiple","muted","name","noshade","novalidate","nowrap","open","optimum","pattern","placeholder","playsinline","poster","preload","pubdate","radiogroup","readonly","rel","required","rev","reversed","role","rows","rowspan","spellcheck","scope","selected","shape","size","sizes","span","srclang","start","src","srcset","step","style","summary","tabindex","title","translate","type","usemap","valign","value","width","xmlns","slot"]),L=i(["accent-height","accumulate","additive","alignment-baseline","ascent","attributename","attributetype","azimuth","basefrequency","baseline-shift","begin","bias","by","class","clip","clippathunits","clip-path","clip-rule","color","color-interpolation","color-interpolation-filters","color-profile","color-rendering","cx","cy","d","dx","dy","diffuseconstant","direction","display","divisor","dur","edgemode","elevation","end","fill","fill-opacity","fill-rule","filter","filterunits","flood-color","flood-opacity","font-family","font-size","font-size-adjust","font-stretch","font-style","font-variant","font-weight","fx","fy","g1","g2","glyph-name","glyphref","gradientunits","gradienttransform","height","href","id","image-rendering","in","in2","k","k1","k2","k3","k4","kerning","keypoints","keysplines","keytimes","lang","lengthadjust","letter-spacing","kernelmatrix","kernelunitlength","lighting-color","local","marker-end","marker-mid","marker-start","markerheight","markerunits","markerwidth","maskcontentunits","maskunits","max","mask","media","method","mode","min","name","numoctaves","offset","operator","opacity","order","orient","orientation","origin","overflow","paint-order","path","pathlength","patterncontentunits","patterntransform","patternunits","points","preservealpha","preserveaspectratio","primitiveunits","r","rx","ry","radius","refx","refy","repeatcount","repeatdur","restart","result","rotate","scale","seed","shape-rendering","specularconstant","specularexponent","spreadmethod","startoffset","stddeviation","stitchtiles","stop-color","stop-opacity","stroke-dasharray","stroke-dashoffset","stroke-linecap","stroke-linejoin","stroke-miterlimit","stroke-opacity","stroke","stroke-width","style","surfacescale","systemlanguage","tabindex","targetx","targety","transform","text-anchor","text-decoration","text-rendering","textlength","type","u1","u2","unicode","values","viewbox","visibility","version","vert-adv-y","vert-origin-x","vert-origin-y","width","word-spacing","wrap","writing-mode","xchannelselector","ychannelselector","x","x1","x2","xmlns","y","y1","y2","z","zoomandpan"]),N=i(["accent","accentunder","align","bevelled","close","columnsalign","columnlines","columnspan","denomalign","depth","dir","display","displaystyle","encoding","fence","frame","height","href","id","largeop","length","linethickness","lspace","lquote","mathbackground","mathcolor","mathsize","mathvariant","maxsize","minsize","movablelimits","notation","numalign","open","rowalign","rowlines","rowspacing","rowspan","rspace","rquote","scriptlevel","scriptminsize","scriptsizemultiplier","selection","separator","separators","stretchy","subscriptshift","supscriptshift","symmetric","voffset","width","xmlns"]),M=i(["xlink:href","xml:id","xlink:title","xml:space","xmlns:xlink"]),D=a(/\{\{[\s\S]*|[\s\S]*\}\}/gm),F=a(/<%[\s\S]*|[\s\S]*%>/gm),z=a(/^data-[\-\w.\u00B7-\uFFFF]/),U=a(/^aria-[\-\w]+$/),B=a(/^(?:(?:(?:f|ht)tps?|mailto|tel|callto|cid|xmpp):|[^a-z]|[a-z+.\-]+(?:[^a-z+.\-:]|$))/i),$=a(/^(?:\w+script|data):/i),q=a(/[\u0000-\u0020\u00A0\u1680\u180E\u2000-\u2029\u205F\u3000]/g),W="function"==typeof Symbol&&"symbol"==typeof Symbol.iterator?function(e){return typeof e}:function(e){return e&&"function"==typeof Symbol&&e.constructor===Symbol&&e!==Symbol.prototype?"symbol":typeof e};function V(e){if(Array.isArray(e)){for(var t=0,n=Array(e.length);t<e.length;t++)n[t]=e[t];return n}return Array.from(e)}var H=function(){return"undefined"==typeof window?null:window},Y=function(e,t){if("object"!==(void 0===e?"undefined":W(e))||"function"!=typeof e.createPolicy)return null;var n=null,r="data-tt-policy-suffix";t.currentScript&&t.currentScript.hasAttribute(r)&&(n=t.currentScript.getAttribute(r));var o="dompurify"+(n?"#"+n:"");try{return e.createPolicy(o,{createHTML:function(e){return e}})}catch(e){return console.warn("TrustedTypes policy "+o+" could not be created."),null}};return function e(){var t=arguments.length>0&&void 0!==arguments[0]?arguments[0]:H(),n=function(t){return e(t)};if(n.version="2.2.9",n.removed=[],!t||!t.document||9!==t.document.nodeType)return n.isSupported=!1,n;var r=t.document,o=t.document,a=t.DocumentFragment,s=t.HTMLTemplateElement,l=t.Node,c=t.Element,u=t.NodeFilter,p=t.NamedNodeMap,k=void 0===p?t.NamedNodeMap||t.MozNamedAttrMap:p,G=t.Text,Q=t.Comment,X=t.DOMParser,K=t.trustedTypes,Z=c.prototype,J=_(Z,"cloneNode"),ee=_(Z,"nextSibling"),te=_(Z,"childNodes"),ne=_(Z,"parentNode");if("function"==typeof s){var re=o.createElement("template");re.content&&re.content.ownerDocument&&(o=re.content.ownerDocument)}var oe=Y(K,r),ie=oe&&Me?oe.createHTML(""):"",ae=o,se=ae.implementation,le=ae.createNodeIterator,ce=ae.createDocumentFragment,ue=r.importNode,pe={};try{pe=S(o).documentMode?o.documentMode:{}}catch(e){}var fe={};n.isSupported="function"==typeof ne&&se&&void 0!==se.createHTMLDocument&&9!==pe;var de=D,he=F,me=z,ve=U,ge=$,ye=q,be=B,xe=null,we=E({},[].concat(V(O),V(A),V(R),V(T),V(I))),ke=null,Ee=E({},[].concat(V(j),V(L),V(N),V(M))),Se=null,_e=null,Oe=!0,Ae=!0,Re=!1,Ce=!1,Te=!1,Pe=!1,Ie=!1,je=!1,Le=!1,Ne=!0,Me=!1,De=!0,Fe=!0,ze=!1,Ue={},Be=E({},["annotation-xml","audio","colgroup","desc","foreignobject","head","iframe","math","mi","mn","mo","ms","mtext","noembed","noframes","noscript","plaintext","script","style","svg","template","thead","title","video","xmp"]),$e=null,qe=E({},["audio","video","img","source","image","track"]),We=null,Ve=E({},["alt","class","for","id","label","name","pattern","placeholder","summary","title","value","style","xmlns"]),He="http://www.w3.org/1998/Math/MathML",Ye="http://www.w3.org/2000/svg",Ge="http://www.w3.org/1999/xhtml",Qe=Ge,Xe=!1,Ke=null,Ze=o.createElement("form"),Je=function(e){Ke&&Ke===e||(e&&"object"===(void 0===e?"undefined":W(e))||(e={}),e=S(e),xe="ALLOWED_TAGS"in e?E({},e.ALLOWED_TAGS):we,ke="ALLOWED_ATTR"in e?E({},e.ALLOWED_ATTR):Ee,We="ADD_URI_SAFE_ATTR"in e?E(S(Ve),e.ADD_URI_SAFE_ATTR):Ve,$e="ADD_DATA_URI_TAGS"in e?E(S(qe),e.ADD_DATA_URI_TAGS):qe,Se="FORBID_TAGS"in e?E({},e.FORBID_TAGS):{},_e="FORBID_ATTR"in e?E({},e.FORBID_ATTR):{},Ue="USE_PROFILES"in e&&e.USE_PROFILES,Oe=!1!==e.ALLOW_ARIA_ATTR,Ae=!1!==e.ALLOW_DATA_ATTR,Re=e.ALLOW_UNKNOWN_PROTOCOLS||!1,Ce=e.SAFE_FOR_TEMPLATES||!1,Te=e.WHOLE_DOCUMENT||!1,je=e.RETURN_DOM||!1,Le=e.RETURN_DOM_FRAGMENT||!1,Ne=!1!==e.RETURN_DOM_IMPORT,Me=e.RETURN_TRUSTED_TYPE||!1,Ie=e.FORCE_BODY||!1,De=!1!==e.SANITIZE_DOM,Fe=!1!==e.KEEP_CONTENT,ze=e.IN_PLACE||!1,be=e.ALLOWED_URI_REGEXP||be,Qe=e.NAMESPACE||Ge,Ce&&(Ae=!1),Le&&(je=!0),Ue&&(xe=E({},[].concat(V(I))),ke=[],!0===Ue.html&&(E(xe,O),E(ke,j)),!0===Ue.svg&&(E(xe,A),E(ke,L),E(ke,M)),!0===Ue.svgFilters&&(E(xe,R),E(ke,L),E(ke,M)),!0===Ue.mathMl&&(E(xe,T),E(ke,N),E(ke,M))),e.ADD_TAGS&&(xe===we&&(xe=S(xe)),E(xe,e.ADD_TAGS)),e.ADD_ATTR&&(ke===Ee&&(ke=S(ke)),E(ke,e.ADD_ATTR)),e.ADD_URI_SAFE_ATTR&&E(We,e.ADD_URI_SAFE_ATTR),Fe&&(xe["#text"]=!0),Te&&E(xe,["html","head","body"]),xe.table&&(E(xe,["tbody"]),delete Se.tbody),i&&i(e),Ke=e)},et=E({},["mi","mo","mn","ms","mtext"]),tt=E({},["foreignobject","desc","title","annotation-xml"]),nt=E({},A);E(nt,R),E(nt,C);var rt=E({},T);E(rt,P);var ot=function(e){var t=ne(e);t&&t.tagName||(t={namespaceURI:Ge,tagName:"template"});var n=m(e.tagName),r=m(t.tagName);if(e.namespaceURI===Ye)return t.namespaceURI===Ge?"svg"===n:t.namespaceURI===He?"svg"===n&&("annotation-xml"===r||et[r]):Boolean(nt[n]);if(e.namespaceURI===He)return t.namespaceURI===Ge?"math"===n:t.namespaceURI===Ye?"math"===n&&tt[r]:Boolean(rt[n]);if(e.namespaceURI===Ge){if(t.namespaceURI===Ye&&!tt[r])return!1;if(t.namespaceURI===He&&!et[r])return!1;var o=E({},["title","style","font","a","script"]);return!rt[n]&&(o[n]||!nt[n])}return!1},it=function(e){h(n.removed,{element:e});try{e.parentNode.removeChild(e)}catch(t){try{e.outerHTML=ie}catch(t){e.remove()}}},at=function(e,t){try{h(n.removed,{attribute:t.getAttributeNode(e),from:t})}catch(e){h(n.removed,{attribute:null,from:t})}if(t.removeAttribute(e),"is"===e&&!ke[e])if(je||Le)try{it(t)}catch(e){}else try{t.setAttribute(e,"")}catch(e){}},st=function(e){var t=void 0,n=void 0;if(Ie)e="<remove></remove>"+e;else{var r=v(e,/^[\r\n\t ]+/);n=r&&r[0]}var i=oe?oe.createHTML(e):e;if(Qe===Ge)try{t=(new X).parseFromString(i,"text/html")}catch(e){}if(!t||!t.documentElement){t=se.createDocument(Qe,"template",null);try{t.documentElement.innerHTML=Xe?"":i}catch(e){}}var a=t.body||t.documentElement;return e&&n&&a.insertBefore(o.createTextNode(n),a.childNodes[0]||null),Te?t.documentElement:a},lt=function(e){return le.call(e.ownerDocument||e,e,u.SHOW_ELEMENT|u.SHOW_COMMENT|u.SHOW_TEXT,null,!1)},ct=function(e){return!(e instanceof G||e instanceof Q||"string"==typeof e.nodeName&&"string"==typeof e.textContent&&"function"==typeof e.removeChild&&e.attributes instanceof k&&"function"==typeof e.removeAttribute&&"function"==typeof e.setAttribute&&"string"==typeof e.namespaceURI&&"function"==typeof e.insertBefore)},ut=function(e){return"object"===(void 0===l?"undefined":W(l))?e instanceof l:e&&"object"===(void 0===e?"undefined":W(e))&&"number"==typeof e.nodeType&&"string"==typeof e.nodeName},pt=function(e,t,r){fe[e]&&f(fe[e],(function(e){e.call(n,t,r,Ke)}))},ft=function(e){var t=void 0;if(pt("beforeSanitizeElements",e,null),ct(e))return it(e),!0;if(v(e.nodeName,/[\u0080-\uFFFF]/))return it(e),!0;var r=m(e.nodeName);if(pt("uponSanitizeElement",e,{tagName:r,allowedTags:xe}),!ut(e.firstElementChild)&&(!ut(e.content)||!ut(e.content.firstElementChild))&&x(/<[/\w]/g,e.innerHTML)&&x(/<[/\w]/g,e.textContent))return it(e),!0;if(!xe[r]||Se[r]){if(Fe&&!Be[r]){var o=ne(e)||e.parentNode,i=te(e)||e.childNodes;if(i&&o)for(var a=i.length-1;a>=0;--a)o.insertBefore(J(i[a],!0),ee(e))}return it(e),!0}return e instanceof c&&!ot(e)?(it(e),!0):"noscript"!==r&&"noembed"!==r||!x(/<\/no(script|embed)/i,e.innerHTML)?(Ce&&3===e.nodeType&&(t=e.textContent,t=g(t,de," "),t=g(t,he," "),e.textContent!==t&&(h(n.removed,{element:e.cloneNode()}),e.textContent=t)),pt("afterSanitizeElements",e,null),!1):(it(e),!0)},dt=function(e,t,n){if(De&&("id"===t||"name"===t)&&(n in o||n in Ze))return!1;if(Ae&&x(me,t));else if(Oe&&x(ve,t));else{if(!ke[t]||_e[t])return!1;if(We[t]);else if(x(be,g(n,ye,"")));else if("src"!==t&&"xlink:href"!==t&&"href"!==t||"script"===e||0!==y(n,"data:")||!$e[e])if(Re&&!x(ge,g(n,ye,"")));else if(n)return!1}return!0},ht=function(e){var t=void 0,r=void 0,o=void 0,i=void 0;pt("beforeSanitizeAttributes",e,null);var a=e.attributes;if(a){var s={attrName:"",attrValue:"",keepAttr:!0,allowedAttributes:ke};for(i=a.length;i--;){var l=t=a[i],c=l.name,u=l.namespaceURI;if(r=b(t.value),o=m(c),s.attrName=o,s.attrValue=r,s.keepAttr=!0,s.forceKeepAttr=void 0,pt("uponSanitizeAttribute",e,s),r=s.attrValue,!s.forceKeepAttr&&(at(c,e),s.keepAttr))if(x(/\/>/i,r))at(c,e);else{Ce&&(r=g(r,de," "),r=g(r,he," "));var p=e.nodeName.toLowerCase();if(dt(p,o,r))try{u?e.setAttributeNS(u,c,r):e.setAttribute(c,r),d(n.removed)}catch(e){}}}pt("afterSanitizeAttributes",e,null)}},mt=function e(t){var n=void 0,r=lt(t);for(pt("beforeSanitizeShadowDOM",t,null);n=r.nextNode();)pt("uponSanitizeShadowNode",n,null),ft(n)||(n.content instanceof a&&e(n.content),ht(n));pt("afterSanitizeShadowDOM",t,null)};return n.sanitize=function(e,o){var i=void 0,s=void 0,c=void 0,u=void 0,p=void 0;if((Xe=!e)&&(e="\x3c!--\x3e"),"string"!=typeof e&&!ut(e)){if("function"!=typeof e.toString)throw w("toString is not a function");if("string"!=typeof(e=e.toString()))throw w("dirty is not a string, aborting")}if(!n.isSupported){if("object"===W(t.toStaticHTML)||"function"==typeof t.toStaticHTML){if("string"==typeof e)return t.toStaticHTML(e);if(ut(e))return t.toStaticHTML(e.outerHTML)}return e}if(Pe||Je(o),n.removed=[],"string"==typeof e&&(ze=!1),ze);else if(e instanceof l)1===(s=(i=st("\x3c!----\x3e")).ownerDocument.importNode(e,!0)).nodeType&&"BODY"===s.nodeName||"HTML"===s.nodeName?i=s:i.appendChild(s);else{if(!je&&!Ce&&!Te&&-1===e.indexOf("<"))return oe&&Me?oe.createHTML(e):e;if(!(i=st(e)))return je?null:ie}i&&Ie&&it(i.firstChild);for(var f=lt(ze?e:i);c=f.nextNode();)3===c.nodeType&&c===u||ft(c)||(c.content instanceof a&&mt(c.content),ht(c),u=c);if(u=null,ze)return e;if(je){if(Le)for(p=ce.call(i.ownerDocument);i.firstChild;)p.appendChild(i.firstChild);else p=i;return Ne&&(p=ue.call(r,p,!0)),p}var d=Te?i.outerHTML:i.innerHTML;return Ce&&(d=g(d,de," "),d=g(d,he," ")),oe&&Me?oe.createHTML(d):d},n.setConfig=function(e){Je(e),Pe=!0},n.clearConfig=function(){Ke=null,Pe=!1},n.isValidAttribute=function(e,t,n){Ke||Je({});var r=m(e),o=m(t);return dt(r,o,n)},n.addHook=function(e,t){"function"==typeof t&&(fe[e]=fe[e]||[],h(fe[e],t))},n.removeHook=function(e){fe[e]&&d(fe[e])},n.removeHooks=function(e){fe[e]&&(fe[e]=[])},n.removeAllHooks=function(){fe={}},n}()}()},6729:function(e){"use strict";var t=Object.prototype.hasOwnProperty,n="~";function r(){}function o(e,t,n){this.fn=e,this.context=t,this.once=n||!1}function i(e,t,r,i,a){if("function"!=typeof r)throw new TypeError("The listener must be a function");var s=new o(r,i||e,a),l=n?n+t:t;return e._events[l]?e._events[l].fn?e._events[l]=[e._events[l],s]:e._events[l].push(s):(e._events[l]=s,e._eventsCount++),e}function a(e,t){0==--e._eventsCount?e._events=new r:delete e._events[t]}function s(){this._events=new r,this._eventsCount=0}Object.create&&(r.prototype=Object.create(null),(new r).__proto__||(n=!1)),s.prototype.eventNames=function(){var e,r,o=[];if(0===this._eventsCount)return o;for(r in e=this._events)t.call(e,r)&&o.push(n?r.slice(1):r);return Object.getOwnPropertySymbols?o.concat(Object.getOwnPropertySymbols(e)):o},s.prototype.listeners=function(e){var t=n?n+e:e,r=this._events[t];if(!r)return[];if(r.fn)return[r.fn];for(var o=0,i=r.length,a=new Array(i);o<i;o++)a[o]=r[o].fn;return a},s.prototype.listenerCount=function(e){var t=n?n+e:e,r=this._events[t];return r?r.fn?1:r.length:0},s.prototype.emit=function(e,t,r,o,i,a){var s=n?n+e:e;if(!this._events[s])return!1;var l,c,u=this._events[s],p=arguments.length;if(u.fn){switch(u.once&&this.removeListener(e,u.fn,void 0,!0),p){case 1:return u.fn.call(u.context),!0;case 2:return u.fn.call(u.context,t),!0;case 3:return u.fn.call(u.context,t,r),!0;case 4:return u.fn.call(u.context,t,r,o),!0;case 5:return u.fn.call(u.context,t,r,o,i),!0;case 6:return u.fn.call(u.context,t,r,o,i,a),!0}for(c=1,l=new Array(p-1);c<p;c++)l[c-1]=arguments[c];u.fn.apply(u.context,l)}else{var f,d=u.length;for(c=0;c<d;c++)switch(u[c].once&&this.removeListener(e,u[c].fn,void 0,!0),p){case 1:u[c].fn.call(u[c].context);break;case 2:u[c].fn.call(u[c].context,t);break;case 3:u[c].fn.call(u[c].context,t,r);break;case 4:u[c].fn.call(u[c].context,t,r,o);break;default:if(!l)for(f=1,l=new Array(p-1);f<p;f++)l[f-1]=arguments[f];u[c].fn.apply(u[c].context,l)}}return!0},s.prototype.on=function(e,t,n){return i(this,e,t,n,!1)},s.prototype.once=function(e,t,n){return i(this,e,t,n,!0)},s.prototype.removeListener=function(e,t,r,o){var i=n?n+e:e;if(!this._events[i])return this;if(!t)return a(this,i),this;var s=this._events[i];if(s.fn)s.fn!==t||o&&!s.once||r&&s.context!==r||a(this,i);else{for(var l=0,c=[],u=s.length;l<u;l++)(s[l].fn!==t||o&&!s[l].once||r&&s[l].context!==r)&&c.push(s[l]);c.length?this._events[i]=1===c.length?c[0]:c:a(this,i)}return this},s.prototype.removeAllListeners=function(e){var t;return e?(t=n?n+e:e,this._events[t]&&a(this,t)):(this._events=new r,this._eventsCount=0),this},s.prototype.off=s.prototype.removeListener,s.prototype.addListener=s.prototype.on,s.prefixed=n,s.EventEmitter=s,e.exports=s},4445:function(e){e.exports=r,r.default=r,r.stable=a,r.stableStringify=a;var t=[],n=[];function r(e,r,i){var a;for(o(e,"",[],void 0),a=0===n.length?JSON.stringify(e,r,i):JSON.stringify(e,l(r),i);0!==t.length;){var s=t.pop();4===s.length?Object.defineProperty(s[0],s[1],s[3]):s[0][s[1]]=s[2]}return a}function o(e,r,i,a){var s;if("object"==typeof e&&null!==e){for(s=0;s<i.length;s++)if(i[s]===e){var l=Object.getOwnPropertyDescriptor(a,r);return void(void 0!==l.get?l.configurable?(Object.defineProperty(a,r,{value:"[Circular]"}),t.push([a,r,e,l])):n.push([e,r]):(a[r]="[Circular]",t.push([a,r,e])))}if(i.push(e),Array.isArray(e))for(s=0;s<e.length;s++)o(e[s],s,i,e);else{var c=Object.keys(e);for(s=0;s<c.length;s++){var u=c[s];o(e[u],u,i,e)}}i.pop()}}function i(e,t){return e<t?-1:e>t?1:0}function a(e,r,o){var i,a=s(e,"",[],void 0)||e;for(i=0===n.length?JSON.stringify(a,r,o):JSON.stringify(a,l(r),o);0!==t.length;){var c=t.pop();4===c.length?Object.defineProperty(c[0],c[1],c[3]):c[0][c[1]]=c[2]}return i}function s(e,r,o,a){var l;if("object"==typeof e&&null!==e){for(l=0;l<o.length;l++)if(o[l]===e){var c=Object.getOwnPropertyDescriptor(a,r);return void(void 0!==c.get?c.configurable?(Object.defineProperty(a,r,{value:"[Circular]"}),t.push([a,r,e,c])):n.push([e,r]):(a[r]="[Circular]",t.push([a,r,e])))}if("function"==typeof e.toJSON)return;if(o.push(e),Array.isArray(e))for(l=0;l<e.length;l++)s(e[l],l,o,e);else{var u={},p=Object.keys(e).sort(i);for(l=0;l<p.length;l++){var f=p[l];s(e[f],f,o,e),u[f]=e[f]}if(void 0===a)return u;t.push([a,r,e]),a[r]=u}o.pop()}}function l(e){return e=void 0!==e?e:function(e,t){return t},function(t,r){if(n.length>0)for(var o=0;o<n.length;o++){var i=n[o];if(i[1]===t&&i[0]===r){r="[Circular]",n.splice(o,1);break}}return e.call(this,t,r)}}},9804:function(e){var t=Object.prototype.hasOwnProperty,n=Object.prototype.toString;e.exports=function(e,r,o){if("[object Function]"!==n.call(r))throw new TypeError("iterator must be a function");var i=e.length;if(i===+i)for(var a=0;a<i;a++)r.call(o,e[a],a,e);else for(var s in e)t.call(e,s)&&r.call(o,e[s],s,e)}},8679:function(e,t,n){"use strict";var r=n(1296),o={childContextTypes:!0,contextType:!0,contextTypes:!0,defaultProps:!0,displayName:!0,getDefaultProps:!0,getDerivedStateFromError:!0,getDerivedStateFromProps:!0,mixins:!0,propTypes:!0,type:!0},i={name:!0,length:!0,prototype:!0,caller:!0,callee:!0,arguments:!0,arity:!0},a={$$typeof:!0,compare:!0,defaultProps:!0,displayName:!0,propTypes:!0,type:!0},s={};function l(e){return r.isMemo(e)?a:s[e.$$typeof]||o}s[r.ForwardRef]={$$typeof:!0,render:!0,defaultProps:!0,displayName:!0,propTypes:!0},s[r.Memo]=a;var c=Object.defineProperty,u=Object.getOwnPropertyNames,p=Object.getOwnPropertySymbols,f=Object.getOwnPropertyDescriptor,d=Object.getPrototypeOf,h=Object.prototype;e.exports=function e(t,n,r){if("string"!=typeof n){if(h){var o=d(n);o&&o!==h&&e(t,o,r)}var a=u(n);p&&(a=a.concat(p(n)));for(var s=l(t),m=l(n),v=0;v<a.length;++v){var g=a[v];if(!(i[g]||r&&r[g]||m&&m[g]||s&&s[g])){var y=f(n,g);try{c(t,g,y)}catch(e){}}}}return t}},6103:function(e,t){"use strict";var n="function"==typeof Symbol&&Symbol.for,r=n?Symbol.for("react.element"):60103,o=n?Symbol.for("react.portal"):60106,i=n?Symbol.for("react.fragment"):60107,a=n?Symbol.for("react.strict_mode"):60108,s=n?Symbol.for("react.profiler"):60114,l=n?Symbol.for("react.provider"):60109,c=n?Symbol.for("react.context"):60110,u=n?Symbol.for("react.async_mode"):60111,p=n?Symbol.for("react.concurrent_mode"):60111,f=n?Symbol.for("react.forward_ref"):60112,d=n?Symbol.for("react.suspense"):60113,h=n?Symbol.for("react.suspense_list"):60120,m=n?Symbol.for("react.memo"):60115,v=n?Symbol.for("react.lazy"):60116,g=n?Symbol.for("react.block"):60121,y=n?Symbol.for("react.fundamental"):60117,b=n?Symbol.for("react.responder"):60118,x=n?Symbol.for("react.scope"):60119;function w(e){if("object"==typeof e&&null!==e){var t=e.$$typeof;switch(t){case r:switch(e=e.type){case u:case p:case i:case s:case a:case d:return e;default:switch(e=e&&e.$$typeof){case c:case f:case v:case m:case l:return e;default:return t}}case o:return t}}}function k(e){return w(e)===p}t.AsyncMode=u,t.ConcurrentMode=p,t.ContextConsumer=c,t.ContextProvider=l,t.Element=r,t.ForwardRef=f,t.Fragment=i,t.Lazy=v,t.Memo=m,t.Portal=o,t.Profiler=s,t.StrictMode=a,t.Suspense=d,t.isAsyncMode=function(e){return k(e)||w(e)===u},t.isConcurrentMode=k,t.isContextConsumer=function(e){return w(e)===c},t.isContextProvider=function(e){return w(e)===l},t.isElement=function(e){return"object"==typeof e&&null!==e&&e.$$typeof===r},t.isForwardRef=function(e){return w(e)===f},t.isFragment=function(e){return w(e)===i},t.isLazy=function(e){return w(e)===v},t.isMemo=function(e){return w(e)===m},t.isPortal=function(e){return w(e)===o},t.isProfiler=function(e){return w(e)===s},t.isStrictMode=function(e){return w(e)===a},t.isSuspense=function(e){return w(e)===d},t.isValidElementType=function(e){return"string"==typeof e||"function"==typeof e||e===i||e===p||e===s||e===a||e===d||e===h||"object"==typeof e&&null!==e&&(e.$$typeof===v||e.$$typeof===m||e.$$typeof===l||e.$$typeof===c||e.$$typeof===f||e.$$typeof===y||e.$$typeof===b||e.$$typeof===x||e.$$typeof===g)},t.typeOf=w},1296:function(e,t,n){"use strict";e.exports=n(6103)},645:function(e,t){t.read=function(e,t,n,r,o){var i,a,s=8*o-r-1,l=(1<<s)-1,c=l>>1,u=-7,p=n?o-1:0,f=n?-1:1,d=e[t+p];for(p+=f,i=d&(1<<-u)-1,d>>=-u,u+=s;u>0;i=256*i+e[t+p],p+=f,u-=8);for(a=i&(1<<-u)-1,i>>=-u,u+=r;u>0;a=256*a+e[t+p],p+=f,u-=8);if(0===i)i=1-c;else{if(i===l)return a?NaN:1/0*(d?-1:1);a+=Math.pow(2,r),i-=c}return(d?-1:1)*a*Math.pow(2,i-r)},t.write=function(e,t,n,r,o,i){var a,s,l,c=8*i-o-1,u=(1<<c)-1,p=u>>1,f=23===o?Math.pow(2,-24)-Math.pow(2,-77):0,d=r?0:i-1,h=r?1:-1,m=t<0||0===t&&1/t<0?1:0;for(t=Math.abs(t),isNaN(t)||t===1/0?(s=isNaN(t)?1:0,a=u):(a=Math.floor(Math.log(t)/Math.LN2),t*(l=Math.pow(2,-a))<1&&(a--,l*=2),(t+=a+p>=1?f/l:f*Math.pow(2,1-p))*l>=2&&(a++,l/=2),a+p>=u?(s=0,a=u):a+p>=1?(s=(t*l-1)*Math.pow(2,o),a+=p):(s=t*Math.pow(2,p-1)*Math.pow(2,o),a=0));o>=8;e[n+d]=255&s,d+=h,s/=256,o-=8);for(a=a<<o|s,c+=o;c>0;e[n+d]=255&a,d+=h,a/=256,c-=8);e[n+d-h]|=128*m}},3573:function(e,t,n){"use strict";var r=n(9804);function o(e,t,n){if(3===arguments.length)return o.set(e,t,n);if(2===arguments.length)return o.get(e,t);var r=o.bind(o,e);for(var i in o)o.hasOwnProperty(i)&&(r[i]=o[i].bind(r,e));return r}e.exports=o,o.get=function(e,t){for(var n=Array.isArray(t)?t:o.parse(t),r=0;r<n.length;++r){var i=n[r];if("object"!=typeof e||!(i in e))throw new Error("Invalid reference token: "+i);e=e[i]}return e},o.set=function(e,t,n){var r=Array.isArray(t)?t:o.parse(t),i=r[0];if(0===r.length)throw Error("Can not set the root object");for(var a=0;a<r.length-1;++a){var s=r[a];"__proto__"!==s&&"constructor"!==s&&"prototype"!==s&&("-"===s&&Array.isArray(e)&&(s=e.length),i=r[a+1],s in e||(i.match(/^(\d+|-)$/)?e[s]=[]:e[s]={}),e=e[s])}return"-"===i&&Array.isArray(e)&&(i=e.length),e[i]=n,this},o.remove=function(e,t){var n=Array.isArray(t)?t:o.parse(t),r=n[n.length-1];if(void 0===r)throw new Error('Invalid JSON pointer for remove: "'+t+'"');var i=o.get(e,n.slice(0,-1));if(Array.isArray(i)){var a=+r;if(""===r&&isNaN(a))throw new Error('Invalid array index: "'+r+'"');Array.prototype.splice.call(i,a,1)}else delete i[r]},o.dict=function(e,t){var n={};return o.walk(e,(function(e,t){n[t]=e}),t),n},o.walk=function(e,t,n){var i=[];n=n||function(e){var t=Object.prototype.toString.call(e);return"[object Object]"===t||"[object Array]"===t},function e(a){r(a,(function(r,a){i.push(String(a)),n(r)?e(r):t(r,o.compile(i)),i.pop()}))}(e)},o.has=function(e,t){try{o.get(e,t)}catch(e){return!1}return!0},o.escape=function(e){return e.toString().replace(/~/g,"~0").replace(/\//g,"~1")},o.unescape=function(e){return e.replace(/~1/g,"/").replace(/~0/g,"~")},o.parse=function(e){if(""===e)return[];if("/"!==e.charAt(0))throw new Error("Invalid JSON pointer: "+e);return e.substring(1).split(/\//).map(o.unescape)},o.compile=function(e){return 0===e.length?"":"/"+e.map(o.escape).join("/")}},2307:function(e,t,n){e=n.nmd(e);var r="__lodash_hash_undefined__",o=9007199254740991,i="[object Arguments]",a="[object Array]",s="[object Boolean]",l="[object Date]",c="[object Error]",u="[object Function]",p="[object Map]",f="[object Number]",d="[object Object]",h="[object Promise]",m="[object RegExp]",v="[object Set]",g="[object String]",y="[object WeakMap]",b="[object ArrayBuffer]",x="[object DataView]",w=/^\[object .+?Constructor\]$/,k=/^(?:0|[1-9]\d*)$/,E={};E["[object Float32Array]"]=E["[object Float64Array]"]=E["[object Int8Array]"]=E["[object Int16Array]"]=E["[object Int32Array]"]=E["[object Uint8Array]"]=E["[object Uint8ClampedArray]"]=E["[object Uint16Array]"]=E["[object Uint32Array]"]=!0,E[i]=E[a]=E[b]=E[s]=E[x]=E[l]=E[c]=E[u]=E[p]=E[f]=E[d]=E[m]=E[v]=E[g]=E[y]=!1;var S="object"==typeof n.g&&n.g&&n.g.Object===Object&&n.g,_="object"==typeof self&&self&&self.Object===Object&&self,O=S||_||Function("return this")(),A=t&&!t.nodeType&&t,R=A&&e&&!e.nodeType&&e,C=R&&R.exports===A,T=C&&S.process,P=function(){try{return T&&T.binding&&T.binding("util")}catch(e){}}(),I=P&&P.isTypedArray;function j(e,t){for(var n=-1,r=null==e?0:e.length;++n<r;)if(t(e[n],n,e))return!0;return!1}function L(e,t){return e.has(t)}function N(e){var t=-1,n=Array(e.size);return e.forEach((function(e,r){n[++t]=[r,e]})),n}function M(e){var t=-1,n=Array(e.size);return e.forEach((function(e){n[++t]=e})),n}var D,F,z,U=Array.prototype,B=Function.prototype,$=Object.prototype,q=O["__core-js_shared__"],W=B.toString,V=$.hasOwnProperty,H=(D=/[^.]+$/.exec(q&&q.keys&&q.keys.IE_PROTO||""))?"Symbol(src)_1."+D:"",Y=$.toString,G=RegExp("^"+W.call(V).replace(/[\\^$.*+?()[\]{}|]/g,"\\$&").replace(/hasOwnProperty|(function).*?(?=\\\()| for .+?(?=\\\])/g,"$1.*?")+"$"),Q=C?O.Buffer:void 0,X=O.Symbol,K=O.Uint8Array,Z=$.propertyIsEnumerable,J=U.splice,ee=X?X.toStringTag:void 0,te=Object.getOwnPropertySymbols,ne=Q?Q.isBuffer:void 0,re=(F=Object.keys,z=Object,function(e){return F(z(e))}),oe=Ce(O,"DataView"),ie=Ce(O,"Map"),ae=Ce(O,"Promise"),se=Ce(O,"Set"),le=Ce(O,"WeakMap"),ce=Ce(Object,"create"),ue=je(oe),pe=je(ie),fe=je(ae),de=je(se),he=je(le),me=X?X.prototype:void 0,ve=me?me.valueOf:void 0;function ge(e){var t=-1,n=null==e?0:e.length;for(this.clear();++t<n;){var r=e[t];this.set(r[0],r[1])}}function ye(e){var t=-1,n=null==e?0:e.length;for(this.clear();++t<n;){var r=e[t];this.set(r[0],r[1])}}function be(e){var t=-1,n=null==e?0:e.length;for(this.clear();++t<n;){var r=e[t];this.set(r[0],r[1])}}function xe(e){var t=-1,n=null==e?0:e.length;for(this.__data__=new be;++t<n;)this.add(e[t])}function we(e){var t=this.__data__=new ye(e);this.size=t.size}function ke(e,t){for(var n=e.length;n--;)if(Le(e[n][0],t))return n;return-1}function Ee(e){return null==e?void 0===e?"[object Undefined]":"[object Null]":ee&&ee in Object(e)?function(e){var t=V.call(e,ee),n=e[ee];try{e[ee]=void 0;var r=!0}catch(e){}var o=Y.call(e);return r&&(t?e[ee]=n:delete e[ee]),o}(e):function(e){return Y.call(e)}(e)}function Se(e){return Be(e)&&Ee(e)==i}function _e(e,t,n,r,o){return e===t||(null==e||null==t||!Be(e)&&!Be(t)?e!=e&&t!=t:function(e,t,n,r,o,u){var h=Me(e),y=Me(t),w=h?a:Pe(e),k=y?a:Pe(t),E=(w=w==i?d:w)==d,S=(k=k==i?d:k)==d,_=w==k;if(_&&De(e)){if(!De(t))return!1;h=!0,E=!1}if(_&&!E)return u||(u=new we),h||$e(e)?Oe(e,t,n,r,o,u):function(e,t,n,r,o,i,a){switch(n){case x:if(e.byteLength!=t.byteLength||e.byteOffset!=t.byteOffset)return!1;e=e.buffer,t=t.buffer;case b:return!(e.byteLength!=t.byteLength||!i(new K(e),new K(t)));case s:case l:case f:return Le(+e,+t);case c:return e.name==t.name&&e.message==t.message;case m:case g:return e==t+"";case p:var u=N;case v:var d=1&r;if(u||(u=M),e.size!=t.size&&!d)return!1;var h=a.get(e);if(h)return h==t;r|=2,a.set(e,t);var y=Oe(u(e),u(t),r,o,i,a);return a.delete(e),y;case"[object Symbol]":if(ve)return ve.call(e)==ve.call(t)}return!1}(e,t,w,n,r,o,u);if(!(1&n)){var O=E&&V.call(e,"__wrapped__"),A=S&&V.call(t,"__wrapped__");if(O||A){var R=O?e.value():e,C=A?t.value():t;return u||(u=new we),o(R,C,n,r,u)}}return!!_&&(u||(u=new we),function(e,t,n,r,o,i){var a=1&n,s=Ae(e),l=s.length;if(l!=Ae(t).length&&!a)return!1;for(var c=l;c--;){var u=s[c];if(!(a?u in t:V.call(t,u)))return!1}var p=i.get(e);if(p&&i.get(t))return p==t;var f=!0;i.set(e,t),i.set(t,e);for(var d=a;++c<l;){var h=e[u=s[c]],m=t[u];if(r)var v=a?r(m,h,u,t,e,i):r(h,m,u,e,t,i);if(!(void 0===v?h===m||o(h,m,n,r,i):v)){f=!1;break}d||(d="constructor"==u)}if(f&&!d){var g=e.constructor,y=t.constructor;g==y||!("constructor"in e)||!("constructor"in t)||"function"==typeof g&&g instanceof g&&"function"==typeof y&&y instanceof y||(f=!1)}return i.delete(e),i.delete(t),f}(e,t,n,r,o,u))}(e,t,n,r,_e,o))}function Oe(e,t,n,r,o,i){var a=1&n,s=e.length,l=t.length;if(s!=l&&!(a&&l>s))return!1;var c=i.get(e);if(c&&i.get(t))return c==t;var u=-1,p=!0,f=2&n?new xe:void 0;for(i.set(e,t),i.set(t,e);++u<s;){var d=e[u],h=t[u];if(r)var m=a?r(h,d,u,t,e,i):r(d,h,u,e,t,i);if(void 0!==m){if(m)continue;p=!1;break}if(f){if(!j(t,(function(e,t){if(!L(f,t)&&(d===e||o(d,e,n,r,i)))return f.push(t)}))){p=!1;break}}else if(d!==h&&!o(d,h,n,r,i)){p=!1;break}}return i.delete(e),i.delete(t),p}function Ae(e){return function(e,t,n){var r=t(e);return Me(e)?r:function(e,t){for(var n=-1,r=t.length,o=e.length;++n<r;)e[o+n]=t[n];return e}(r,n(e))}(e,qe,Te)}function Re(e,t){var n,r,o=e.__data__;return("string"==(r=typeof(n=t))||"number"==r||"symbol"==r||"boolean"==r?"__proto__"!==n:null===n)?o["string"==typeof t?"string":"hash"]:o.map}function Ce(e,t){var n=function(e,t){return null==e?void 0:e[t]}(e,t);return function(e){return!(!Ue(e)||function(e){return!!H&&H in e}(e))&&(Fe(e)?G:w).test(je(e))}(n)?n:void 0}ge.prototype.clear=function(){this.__data__=ce?ce(null):{},this.size=0},ge.prototype.delete=function(e){var t=this.has(e)&&delete this.__data__[e];return this.size-=t?1:0,t},ge.prototype.get=function(e){var t=this.__data__;if(ce){var n=t[e];return n===r?void 0:n}return V.call(t,e)?t[e]:void 0},ge.prototype.has=function(e){var t=this.__data__;return ce?void 0!==t[e]:V.call(t,e)},ge.prototype.set=function(e,t){var n=this.__data__;return this.size+=this.has(e)?0:1,n[e]=ce&&void 0===t?r:t,this},ye.prototype.clear=function(){this.__data__=[],this.size=0},ye.prototype.delete=function(e){var t=this.__data__,n=ke(t,e);return!(n<0||(n==t.length-1?t.pop():J.call(t,n,1),--this.size,0))},ye.prototype.get=function(e){var t=this.__data__,n=ke(t,e);return n<0?void 0:t[n][1]},ye.prototype.has=function(e){return ke(this.__data__,e)>-1},ye.prototype.set=function(e,t){var n=this.__data__,r=ke(n,e);return r<0?(++this.size,n.push([e,t])):n[r][1]=t,this},be.prototype.clear=function(){this.size=0,this.__data__={hash:new ge,map:new(ie||ye),string:new ge}},be.prototype.delete=function(e){var t=Re(this,e).delete(e);return this.size-=t?1:0,t},be.prototype.get=function(e){return Re(this,e).get(e)},be.prototype.has=function(e){return Re(this,e).has(e)},be.prototype.set=function(e,t){var n=Re(this,e),r=n.size;return n.set(e,t),this.size+=n.size==r?0:1,this},xe.prototype.add=xe.prototype.push=function(e){return this.__data__.set(e,r),this},xe.prototype.has=function(e){return this.__data__.has(e)},we.prototype.clear=function(){this.__data__=new ye,this.size=0},we.prototype.delete=function(e){var t=this.__data__,n=t.delete(e);return this.size=t.size,n},we.prototype.get=function(e){return this.__data__.get(e)},we.prototype.has=function(e){return this.__data__.has(e)},we.prototype.set=function(e,t){var n=this.__data__;if(n instanceof ye){var r=n.__data__;if(!ie||r.length<199)return r.push([e,t]),this.size=++n.size,this;n=this.__data__=new be(r)}return n.set(e,t),this.size=n.size,this};var Te=te?function(e){return null==e?[]:(e=Object(e),function(t,n){for(var r=-1,o=null==t?0:t.length,i=0,a=[];++r<o;){var s=t[r];l=s,Z.call(e,l)&&(a[i++]=s)}var l;return a}(te(e)))}:function(){return[]},Pe=Ee;function Ie(e,t){return!!(t=null==t?o:t)&&("number"==typeof e||k.test(e))&&e>-1&&e%1==0&&e<t}function je(e){if(null!=e){try{return W.call(e)}catch(e){}try{return e+""}catch(e){}}return""}function Le(e,t){return e===t||e!=e&&t!=t}(oe&&Pe(new oe(new ArrayBuffer(1)))!=x||ie&&Pe(new ie)!=p||ae&&Pe(ae.resolve())!=h||se&&Pe(new se)!=v||le&&Pe(new le)!=y)&&(Pe=function(e){var t=Ee(e),n=t==d?e.constructor:void 0,r=n?je(n):"";if(r)switch(r){case ue:return x;case pe:return p;case fe:return h;case de:return v;case he:return y}return t});var Ne=Se(function(){return arguments}())?Se:function(e){return Be(e)&&V.call(e,"callee")&&!Z.call(e,"callee")},Me=Array.isArray,De=ne||function(){return!1};function Fe(e){if(!Ue(e))return!1;var t=Ee(e);return t==u||"[object GeneratorFunction]"==t||"[object AsyncFunction]"==t||"[object Proxy]"==t}function ze(e){return"number"==typeof e&&e>-1&&e%1==0&&e<=o}function Ue(e){var t=typeof e;return null!=e&&("object"==t||"function"==t)}function Be(e){return null!=e&&"object"==typeof e}var $e=I?function(e){return function(t){return e(t)}}(I):function(e){return Be(e)&&ze(e.length)&&!!E[Ee(e)]};function qe(e){return null!=(t=e)&&ze(t.length)&&!Fe(t)?function(e,t){var n=Me(e),r=!n&&Ne(e),o=!n&&!r&&De(e),i=!n&&!r&&!o&&$e(e),a=n||r||o||i,s=a?function(e,t){for(var n=-1,r=Array(e);++n<e;)r[n]=t(n);return r}(e.length,String):[],l=s.length;for(var c in e)!t&&!V.call(e,c)||a&&("length"==c||o&&("offset"==c||"parent"==c)||i&&("buffer"==c||"byteLength"==c||"byteOffset"==c)||Ie(c,l))||s.push(c);return s}(e):function(e){if(n=(t=e)&&t.constructor,t!==("function"==typeof n&&n.prototype||$))return re(e);var t,n,r=[];for(var o in Object(e))V.call(e,o)&&"constructor"!=o&&r.push(o);return r}(e);var t}e.exports=function(e,t){return _e(e,t)}},308:function(e){e.exports=function(){}},813:function(e){e.exports=function(){"use strict";var e="function"==typeof Symbol&&"symbol"==typeof Symbol.iterator?function(e){return typeof e}:function(e){return e&&"function"==typeof Symbol&&e.constructor===Symbol&&e!==Symbol.prototype?"symbol":typeof e},t=function(e,t){if(!(e instanceof t))throw new TypeError("Cannot call a class as a function")},n=function(){function e(e,t){for(var n=0;n<t.length;n++){var r=t[n];r.enumerable=r.enumerable||!1,r.configurable=!0,"value"in r&&(r.writable=!0),Object.defineProperty(e,r.key,r)}}return function(t,n,r){return n&&e(t.prototype,n),r&&e(t,r),t}}(),r=Object.assign||function(e){for(var t=1;t<arguments.length;t++){var n=arguments[t];for(var r in n)Object.prototype.hasOwnProperty.call(n,r)&&(e[r]=n[r])}return e},o=function(){function e(n){var r=!(arguments.length>1&&void 0!==arguments[1])||arguments[1],o=arguments.length>2&&void 0!==arguments[2]?arguments[2]:[],i=arguments.length>3&&void 0!==arguments[3]?arguments[3]:5e3;t(this,e),this.ctx=n,this.iframes=r,this.exclude=o,this.iframesTimeout=i}return n(e,[{key:"getContexts",value:function(){var e=[];return(void 0!==this.ctx&&this.ctx?NodeList.prototype.isPrototypeOf(this.ctx)?Array.prototype.slice.call(this.ctx):Array.isArray(this.ctx)?this.ctx:"string"==typeof this.ctx?Array.prototype.slice.call(document.querySelectorAll(this.ctx)):[this.ctx]:[]).forEach((function(t){var n=e.filter((function(e){return e.contains(t)})).length>0;-1!==e.indexOf(t)||n||e.push(t)})),e}},{key:"getIframeContents",value:function(e,t){var n=arguments.length>2&&void 0!==arguments[2]?arguments[2]:function(){},r=void 0;try{var o=e.contentWindow;if(r=o.document,!o||!r)throw new Error("iframe inaccessible")}catch(e){n()}r&&t(r)}},{key:"isIframeBlank",value:function(e){var t="about:blank",n=e.getAttribute("src").trim();return e.contentWindow.location.href===t&&n!==t&&n}},{key:"observeIframeLoad",value:function(e,t,n){var r=this,o=!1,i=null,a=function a(){if(!o){o=!0,clearTimeout(i);try{r.isIframeBlank(e)||(e.removeEventListener("load",a),r.getIframeContents(e,t,n))}catch(e){n()}}};e.addEventListener("load",a),i=setTimeout(a,this.iframesTimeout)}},{key:"onIframeReady",value:function(e,t,n){try{"complete"===e.contentWindow.document.readyState?this.isIframeBlank(e)?this.observeIframeLoad(e,t,n):this.getIframeContents(e,t,n):this.observeIframeLoad(e,t,n)}catch(e){n()}}},{key:"waitForIframes",value:function(e,t){var n=this,r=0;this.forEachIframe(e,(function(){return!0}),(function(e){r++,n.waitForIframes(e.querySelector("html"),(function(){--r||t()}))}),(function(e){e||t()}))}},{key:"forEachIframe",value:function(t,n,r){var o=this,i=arguments.length>3&&void 0!==arguments[3]?arguments[3]:function(){},a=t.querySelectorAll("iframe"),s=a.length,l=0;a=Array.prototype.slice.call(a);var c=function(){--s<=0&&i(l)};s||c(),a.forEach((function(t){e.matches(t,o.exclude)?c():o.onIframeReady(t,(function(e){n(t)&&(l++,r(e)),c()}),c)}))}},{key:"createIterator",value:function(e,t,n){return document.createNodeIterator(e,t,n,!1)}},{key:"createInstanceOnIframe",value:function(t){return new e(t.querySelector("html"),this.iframes)}},{key:"compareNodeIframe",value:function(e,t,n){if(e.compareDocumentPosition(n)&Node.DOCUMENT_POSITION_PRECEDING){if(null===t)return!0;if(t.compareDocumentPosition(n)&Node.DOCUMENT_POSITION_FOLLOWING)return!0}return!1}},{key:"getIteratorNode",value:function(e){var t=e.previousNode();return{prevNode:t,node:(null===t||e.nextNode())&&e.nextNode()}}},{key:"checkIframeFilter",value:function(e,t,n,r){var o=!1,i=!1;return r.forEach((function(e,t){e.val===n&&(o=t,i=e.handled)})),this.compareNodeIframe(e,t,n)?(!1!==o||i?!1===o||i||(r[o].handled=!0):r.push({val:n,handled:!0}),!0):(!1===o&&r.push({val:n,handled:!1}),!1)}},{key:"handleOpenIframes",value:function(e,t,n,r){var o=this;e.forEach((function(e){e.handled||o.getIframeContents(e.val,(function(e){o.createInstanceOnIframe(e).forEachNode(t,n,r)}))}))}},{key:"iterateThroughNodes",value:function(e,t,n,r,o){for(var i=this,a=this.createIterator(t,e,r),s=[],l=[],c=void 0,u=void 0;p=void 0,p=i.getIteratorNode(a),u=p.prevNode,c=p.node;)this.iframes&&this.forEachIframe(t,(function(e){return i.checkIframeFilter(c,u,e,s)}),(function(t){i.createInstanceOnIframe(t).forEachNode(e,(function(e){return l.push(e)}),r)})),l.push(c);var p;l.forEach((function(e){n(e)})),this.iframes&&this.handleOpenIframes(s,e,n,r),o()}},{key:"forEachNode",value:function(e,t,n){var r=this,o=arguments.length>3&&void 0!==arguments[3]?arguments[3]:function(){},i=this.getContexts(),a=i.length;a||o(),i.forEach((function(i){var s=function(){r.iterateThroughNodes(e,i,t,n,(function(){--a<=0&&o()}))};r.iframes?r.waitForIframes(i,s):s()}))}}],[{key:"matches",value:function(e,t){var n="string"==typeof t?[t]:t,r=e.matches||e.matchesSelector||e.msMatchesSelector||e.mozMatchesSelector||e.oMatchesSelector||e.webkitMatchesSelector;if(r){var o=!1;return n.every((function(t){return!r.call(e,t)||(o=!0,!1)})),o}return!1}}]),e}(),i=function(){function i(e){t(this,i),this.ctx=e,this.ie=!1;var n=window.navigator.userAgent;(n.indexOf("MSIE")>-1||n.indexOf("Trident")>-1)&&(this.ie=!0)}return n(i,[{key:"log",value:function(t){var n=arguments.length>1&&void 0!==arguments[1]?arguments[1]:"debug",r=this.opt.log;this.opt.debug&&"object"===(void 0===r?"undefined":e(r))&&"function"==typeof r[n]&&r[n]("mark.js: "+t)}},{key:"escapeStr",value:function(e){return e.replace(/[\-\[\]\/\{\}\(\)\*\+\?\.\\\^\$\|]/g,"\\$&")}},{key:"createRegExp",value:function(e){return"disabled"!==this.opt.wildcards&&(e=this.setupWildcardsRegExp(e)),e=this.escapeStr(e),Object.keys(this.opt.synonyms).length&&(e=this.createSynonymsRegExp(e)),(this.opt.ignoreJoiners||this.opt.ignorePunctuation.length)&&(e=this.setupIgnoreJoinersRegExp(e)),this.opt.diacritics&&(e=this.createDiacriticsRegExp(e)),e=this.createMergedBlanksRegExp(e),(this.opt.ignoreJoiners||this.opt.ignorePunctuation.length)&&(e=this.createJoinersRegExp(e)),"disabled"!==this.opt.wildcards&&(e=this.createWildcardsRegExp(e)),this.createAccuracyRegExp(e)}},{key:"createSynonymsRegExp",value:function(e){var t=this.opt.synonyms,n=this.opt.caseSensitive?"":"i",r=this.opt.ignoreJoiners||this.opt.ignorePunctuation.length?"\0":"";for(var o in t)if(t.hasOwnProperty(o)){var i=t[o],a="disabled"!==this.opt.wildcards?this.setupWildcardsRegExp(o):this.escapeStr(o),s="disabled"!==this.opt.wildcards?this.setupWildcardsRegExp(i):this.escapeStr(i);""!==a&&""!==s&&(e=e.replace(new RegExp("("+this.escapeStr(a)+"|"+this.escapeStr(s)+")","gm"+n),r+"("+this.processSynomyms(a)+"|"+this.processSynomyms(s)+")"+r))}return e}},{key:"processSynomyms",value:function(e){return(this.opt.ignoreJoiners||this.opt.ignorePunctuation.length)&&(e=this.setupIgnoreJoinersRegExp(e)),e}},{key:"setupWildcardsRegExp",value:function(e){return(e=e.replace(/(?:\\)*\?/g,(function(e){return"\\"===e.charAt(0)?"?":""}))).replace(/(?:\\)*\*/g,(function(e){return"\\"===e.charAt(0)?"*":""}))}},{key:"createWildcardsRegExp",value:function(e){var t="withSpaces"===this.opt.wildcards;return e.replace(/\u0001/g,t?"[\\S\\s]?":"\\S?").replace(/\u0002/g,t?"[\\S\\s]*?":"\\S*")}},{key:"setupIgnoreJoinersRegExp",value:function(e){return e.replace(/[^(|)\\]/g,(function(e,t,n){var r=n.charAt(t+1);return/[(|)\\]/.test(r)||""===r?e:e+"\0"}))}},{key:"createJoinersRegExp",value:function(e){var t=[],n=this.opt.ignorePunctuation;return Array.isArray(n)&&n.length&&t.push(this.escapeStr(n.join(""))),this.opt.ignoreJoiners&&t.push("\\u00ad\\u200b\\u200c\\u200d"),t.length?e.split(/\u0000+/).join("["+t.join("")+"]*"):e}},{key:"createDiacriticsRegExp",value:function(e){var t=this.opt.caseSensitive?"":"i",n=this.opt.caseSensitive?["aàáảãạăằắẳẵặâầấẩẫậäåāą","AÀÁẢÃẠĂẰẮẲẴẶÂẦẤẨẪẬÄÅĀĄ","cçćč","CÇĆČ","dđď","DĐĎ","eèéẻẽẹêềếểễệëěēę","EÈÉẺẼẸÊỀẾỂỄỆËĚĒĘ","iìíỉĩịîïī","IÌÍỈĨỊÎÏĪ","lł","LŁ","nñňń","NÑŇŃ","oòóỏõọôồốổỗộơởỡớờợöøō","OÒÓỎÕỌÔỒỐỔỖỘƠỞỠỚỜỢÖØŌ","rř","RŘ","sšśșş","SŠŚȘŞ","tťțţ","TŤȚŢ","uùúủũụưừứửữựûüůū","UÙÚỦŨỤƯỪỨỬỮỰÛÜŮŪ","yýỳỷỹỵÿ","YÝỲỶỸỴŸ","zžżź","ZŽŻŹ"]:["aàáảãạăằắẳẵặâầấẩẫậäåāąAÀÁẢÃẠĂẰẮẲẴẶÂẦẤẨẪẬÄÅĀĄ","cçćčCÇĆČ","dđďDĐĎ","eèéẻẽẹêềếểễệëěēęEÈÉẺẼẸÊỀẾỂỄỆËĚĒĘ","iìíỉĩịîïīIÌÍỈĨỊÎÏĪ","lłLŁ","nñňńNÑŇŃ","oòóỏõọôồốổỗộơởỡớờợöøōOÒÓỎÕỌÔỒỐỔỖỘƠỞỠỚỜỢÖØŌ","rřRŘ","sšśșşSŠŚȘŞ","tťțţTŤȚŢ","uùúủũụưừứửữựûüůūUÙÚỦŨỤƯỪỨỬỮỰÛÜŮŪ","yýỳỷỹỵÿYÝỲỶỸỴŸ","zžżźZŽŻŹ"],r=[];return e.split("").forEach((function(o){n.every((function(n){if(-1!==n.indexOf(o)){if(r.indexOf(n)>-1)return!1;e=e.replace(new RegExp("["+n+"]","gm"+t),"["+n+"]"),r.push(n)}return!0}))})),e}},{key:"createMergedBlanksRegExp",value:function(e){return e.replace(/[\s]+/gim,"[\\s]+")}},{key:"createAccuracyRegExp",value:function(e){var t=this,n=this.opt.accuracy,r="string"==typeof n?n:n.value,o="string"==typeof n?[]:n.limiters,i="";switch(o.forEach((function(e){i+="|"+t.escapeStr(e)})),r){case"partially":default:return"()("+e+")";case"complementary":return"()([^"+(i="\\s"+(i||this.escapeStr("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~¡¿")))+"]*"+e+"[^"+i+"]*)";case"exactly":return"(^|\\s"+i+")("+e+")(?=$|\\s"+i+")"}}},{key:"getSeparatedKeywords",value:function(e){var t=this,n=[];return e.forEach((function(e){t.opt.separateWordSearch?e.split(" ").forEach((function(e){e.trim()&&-1===n.indexOf(e)&&n.push(e)})):e.trim()&&-1===n.indexOf(e)&&n.push(e)})),{keywords:n.sort((function(e,t){return t.length-e.length})),length:n.length}}},{key:"isNumeric",value:function(e){return Number(parseFloat(e))==e}},{key:"checkRanges",value:function(e){var t=this;if(!Array.isArray(e)||"[object Object]"!==Object.prototype.toString.call(e[0]))return this.log("markRanges() will only accept an array of objects"),this.opt.noMatch(e),[];var n=[],r=0;return e.sort((function(e,t){return e.start-t.start})).forEach((function(e){var o=t.callNoMatchOnInvalidRanges(e,r),i=o.start,a=o.end;o.valid&&(e.start=i,e.length=a-i,n.push(e),r=a)})),n}},{key:"callNoMatchOnInvalidRanges",value:function(e,t){var n=void 0,r=void 0,o=!1;return e&&void 0!==e.start?(r=(n=parseInt(e.start,10))+parseInt(e.length,10),this.isNumeric(e.start)&&this.isNumeric(e.length)&&r-t>0&&r-n>0?o=!0:(this.log("Ignoring invalid or overlapping range: "+JSON.stringify(e)),this.opt.noMatch(e))):(this.log("Ignoring invalid range: "+JSON.stringify(e)),this.opt.noMatch(e)),{start:n,end:r,valid:o}}},{key:"checkWhitespaceRanges",value:function(e,t,n){var r=void 0,o=!0,i=n.length,a=t-i,s=parseInt(e.start,10)-a;return(r=(s=s>i?i:s)+parseInt(e.length,10))>i&&(r=i,this.log("End range automatically set to the max value of "+i)),s<0||r-s<0||s>i||r>i?(o=!1,this.log("Invalid range: "+JSON.stringify(e)),this.opt.noMatch(e)):""===n.substring(s,r).replace(/\s+/g,"")&&(o=!1,this.log("Skipping whitespace only range: "+JSON.stringify(e)),this.opt.noMatch(e)),{start:s,end:r,valid:o}}},{key:"getTextNodes",value:function(e){var t=this,n="",r=[];this.iterator.forEachNode(NodeFilter.SHOW_TEXT,(function(e){r.push({start:n.length,end:(n+=e.textContent).length,node:e})}),(function(e){return t.matchesExclude(e.parentNode)?NodeFilter.FILTER_REJECT:NodeFilter.FILTER_ACCEPT}),(function(){e({value:n,nodes:r})}))}},{key:"matchesExclude",value:function(e){return o.matches(e,this.opt.exclude.concat(["script","style","title","head","html"]))}},{key:"wrapRangeInTextNode",value:function(e,t,n){var r=this.opt.element?this.opt.element:"mark",o=e.splitText(t),i=o.splitText(n-t),a=document.createElement(r);return a.setAttribute("data-markjs","true"),this.opt.className&&a.setAttribute("class",this.opt.className),a.textContent=o.textContent,o.parentNode.replaceChild(a,o),i}},{key:"wrapRangeInMappedTextNode",value:function(e,t,n,r,o){var i=this;e.nodes.every((function(a,s){var l=e.nodes[s+1];if(void 0===l||l.start>t){if(!r(a.node))return!1;var c=t-a.start,u=(n>a.end?a.end:n)-a.start,p=e.value.substr(0,a.start),f=e.value.substr(u+a.start);if(a.node=i.wrapRangeInTextNode(a.node,c,u),e.value=p+f,e.nodes.forEach((function(t,n){n>=s&&(e.nodes[n].start>0&&n!==s&&(e.nodes[n].start-=u),e.nodes[n].end-=u)})),n-=u,o(a.node.previousSibling,a.start),!(n>a.end))return!1;t=a.end}return!0}))}},{key:"wrapMatches",value:function(e,t,n,r,o){var i=this,a=0===t?0:t+1;this.getTextNodes((function(t){t.nodes.forEach((function(t){t=t.node;for(var o=void 0;null!==(o=e.exec(t.textContent))&&""!==o[a];)if(n(o[a],t)){var s=o.index;if(0!==a)for(var l=1;l<a;l++)s+=o[l].length;t=i.wrapRangeInTextNode(t,s,s+o[a].length),r(t.previousSibling),e.lastIndex=0}})),o()}))}},{key:"wrapMatchesAcrossElements",value:function(e,t,n,r,o){var i=this,a=0===t?0:t+1;this.getTextNodes((function(t){for(var s=void 0;null!==(s=e.exec(t.value))&&""!==s[a];){var l=s.index;if(0!==a)for(var c=1;c<a;c++)l+=s[c].length;var u=l+s[a].length;i.wrapRangeInMappedTextNode(t,l,u,(function(e){return n(s[a],e)}),(function(t,n){e.lastIndex=n,r(t)}))}o()}))}},{key:"wrapRangeFromIndex",value:function(e,t,n,r){var o=this;this.getTextNodes((function(i){var a=i.value.length;e.forEach((function(e,r){var s=o.checkWhitespaceRanges(e,a,i.value),l=s.start,c=s.end;s.valid&&o.wrapRangeInMappedTextNode(i,l,c,(function(n){return t(n,e,i.value.substring(l,c),r)}),(function(t){n(t,e)}))})),r()}))}},{key:"unwrapMatches",value:function(e){for(var t=e.parentNode,n=document.createDocumentFragment();e.firstChild;)n.appendChild(e.removeChild(e.firstChild));t.replaceChild(n,e),this.ie?this.normalizeTextNode(t):t.normalize()}},{key:"normalizeTextNode",value:function(e){if(e){if(3===e.nodeType)for(;e.nextSibling&&3===e.nextSibling.nodeType;)e.nodeValue+=e.nextSibling.nodeValue,e.parentNode.removeChild(e.nextSibling);else this.normalizeTextNode(e.firstChild);this.normalizeTextNode(e.nextSibling)}}},{key:"markRegExp",value:function(e,t){var n=this;this.opt=t,this.log('Searching with expression "'+e+'"');var r=0,o="wrapMatches";this.opt.acrossElements&&(o="wrapMatchesAcrossElements"),this[o](e,this.opt.ignoreGroups,(function(e,t){return n.opt.filter(t,e,r)}),(function(e){r++,n.opt.each(e)}),(function(){0===r&&n.opt.noMatch(e),n.opt.done(r)}))}},{key:"mark",value:function(e,t){var n=this;this.opt=t;var r=0,o="wrapMatches",i=this.getSeparatedKeywords("string"==typeof e?[e]:e),a=i.keywords,s=i.length,l=this.opt.caseSensitive?"":"i";this.opt.acrossElements&&(o="wrapMatchesAcrossElements"),0===s?this.opt.done(r):function e(t){var i=new RegExp(n.createRegExp(t),"gm"+l),c=0;n.log('Searching with expression "'+i+'"'),n[o](i,1,(function(e,o){return n.opt.filter(o,t,r,c)}),(function(e){c++,r++,n.opt.each(e)}),(function(){0===c&&n.opt.noMatch(t),a[s-1]===t?n.opt.done(r):e(a[a.indexOf(t)+1])}))}(a[0])}},{key:"markRanges",value:function(e,t){var n=this;this.opt=t;var r=0,o=this.checkRanges(e);o&&o.length?(this.log("Starting to mark with the following ranges: "+JSON.stringify(o)),this.wrapRangeFromIndex(o,(function(e,t,r,o){return n.opt.filter(e,t,r,o)}),(function(e,t){r++,n.opt.each(e,t)}),(function(){n.opt.done(r)}))):this.opt.done(r)}},{key:"unmark",value:function(e){var t=this;this.opt=e;var n=this.opt.element?this.opt.element:"*";n+="[data-markjs]",this.opt.className&&(n+="."+this.opt.className),this.log('Removal selector "'+n+'"'),this.iterator.forEachNode(NodeFilter.SHOW_ELEMENT,(function(e){t.unwrapMatches(e)}),(function(e){var r=o.matches(e,n),i=t.matchesExclude(e);return!r||i?NodeFilter.FILTER_REJECT:NodeFilter.FILTER_ACCEPT}),this.opt.done)}},{key:"opt",set:function(e){this._opt=r({},{element:"",className:"",exclude:[],iframes:!1,iframesTimeout:5e3,separateWordSearch:!0,diacritics:!0,synonyms:{},accuracy:"partially",acrossElements:!1,caseSensitive:!1,ignoreJoiners:!1,ignoreGroups:0,ignorePunctuation:[],wildcards:"disabled",each:function(){},noMatch:function(){},filter:function(){return!0},done:function(){},debug:!1,log:window.console},e)},get:function(){return this._opt}},{key:"iterator",get:function(){return new o(this.ctx,this.opt.iframes,this.opt.exclude,this.opt.iframesTimeout)}}]),i}();return function(e){var t=this,n=new i(e);return this.mark=function(e,r){return n.mark(e,r),t},this.markRegExp=function(e,r){return n.markRegExp(e,r),t},this.markRanges=function(e,r){return n.markRanges(e,r),t},this.unmark=function(e){return n.unmark(e),t},this}}()},7084:function(e,t,n){!function(t){"use strict";var n={newline:/^\n+/,code:/^( {4}[^\n]+\n*)+/,fences:/^ {0,3}(`{3,}|~{3,})([^`~\n]*)\n(?:|([\s\S]*?)\n)(?: {0,3}\1[~`]* *(?:\n+|$)|$)/,hr:/^ {0,3}((?:- *){3,}|(?:_ *){3,}|(?:\* *){3,})(?:\n+|$)/,heading:/^ {0,3}(#{1,6}) +([^\n]*?)(?: +#+)? *(?:\n+|$)/,blockquote:/^( {0,3}> ?(paragraph|[^\n]*)(?:\n|$))+/,list:/^( {0,3})(bull) [\s\S]+?(?:hr|def|\n{2,}(?! )(?!\1bull )\n*|\s*$)/,html:"^ {0,3}(?:<(script|pre|style)[\\s>][\\s\\S]*?(?:</\\1>[^\\n]*\\n+|$)|comment[^\\n]*(\\n+|$)|<\\?[\\s\\S]*?\\?>\\n*|<![A-Z][\\s\\S]*?>\\n*|<!\\[CDATA\\[[\\s\\S]*?\\]\\]>\\n*|</?(tag)(?: +|\\n|/?>)[\\s\\S]*?(?:\\n{2,}|$)|<(?!script|pre|style)([a-z][\\w-]*)(?:attribute)*? */?>(?=[ \\t]*(?:\\n|$))[\\s\\S]*?(?:\\n{2,}|$)|</(?!script|pre|style)[a-z][\\w-]*\\s*>(?=[ \\t]*(?:\\n|$))[\\s\\S]*?(?:\\n{2,}|$))",def:/^ {0,3}\[(label)\]: *\n? *<?([^\s>]+)>?(?:(?: +\n? *| *\n *)(title))? *(?:\n+|$)/,nptable:v,table:v,lheading:/^([^\n]+)\n {0,3}(=+|-+) *(?:\n+|$)/,_paragraph:/^([^\n]+(?:\n(?!hr|heading|lheading|blockquote|fences|list|html)[^\n]+)*)/,text:/^[^\n]+/};function r(e){this.tokens=[],this.tokens.links=Object.create(null),this.options=e||k.defaults,this.rules=n.normal,this.options.pedantic?this.rules=n.pedantic:this.options.gfm&&(this.rules=n.gfm)}n._label=/(?!\s*\])(?:\\[\[\]]|[^\[\]])+/,n._title=/(?:"(?:\\"?|[^"\\])*"|'[^'\n]*(?:\n[^'\n]+)*\n?'|\([^()]*\))/,n.def=f(n.def).replace("label",n._label).replace("title",n._title).getRegex(),n.bullet=/(?:[*+-]|\d{1,9}\.)/,n.item=/^( *)(bull) ?[^\n]*(?:\n(?!\1bull ?)[^\n]*)*/,n.item=f(n.item,"gm").replace(/bull/g,n.bullet).getRegex(),n.list=f(n.list).replace(/bull/g,n.bullet).replace("hr","\\n+(?=\\1?(?:(?:- *){3,}|(?:_ *){3,}|(?:\\* *){3,})(?:\\n+|$))").replace("def","\\n+(?="+n.def.source+")").getRegex(),n._tag="address|article|aside|base|basefont|blockquote|body|caption|center|col|colgroup|dd|details|dialog|dir|div|dl|dt|fieldset|figcaption|figure|footer|form|frame|frameset|h[1-6]|head|header|hr|html|iframe|legend|li|link|main|menu|menuitem|meta|nav|noframes|ol|optgroup|option|p|param|section|source|summary|table|tbody|td|tfoot|th|thead|title|tr|track|ul",n._comment=/<!--(?!-?>)[\s\S]*?-->/,n.html=f(n.html,"i").replace("comment",n._comment).replace("tag",n._tag).replace("attribute",/ +[a-zA-Z:_][\w.:-]*(?: *= *"[^"\n]*"| *= *'[^'\n]*'| *= *[^\s"'=<>`]+)?/).getRegex(),n.paragraph=f(n._paragraph).replace("hr",n.hr).replace("heading"," {0,3}#{1,6} +").replace("|lheading","").replace("blockquote"," {0,3}>").replace("fences"," {0,3}(?:`{3,}|~{3,})[^`\\n]*\\n").replace("list"," {0,3}(?:[*+-]|1[.)]) ").replace("html","</?(?:tag)(?: +|\\n|/?>)|<(?:script|pre|style|!--)").replace("tag",n._tag).getRegex(),n.blockquote=f(n.blockquote).replace("paragraph",n.paragraph).getRegex(),n.normal=g({},n),n.gfm=g({},n.normal,{nptable:/^ *([^|\n ].*\|.*)\n *([-:]+ *\|[-| :]*)(?:\n((?:.*[^>\n ].*(?:\n|$))*)\n*|$)/,table:/^ *\|(.+)\n *\|?( *[-:]+[-| :]*)(?:\n((?: *[^>\n ].*(?:\n|$))*)\n*|$)/}),n.pedantic=g({},n.normal,{html:f("^ *(?:comment *(?:\\n|\\s*$)|<(tag)[\\s\\S]+?</\\1> *(?:\\n{2,}|\\s*$)|<tag(?:\"[^\"]*\"|'[^']*'|\\s[^'\"/>\\s]*)*?/?> *(?:\\n{2,}|\\s*$))").replace("comment",n._comment).replace(/tag/g,"(?!(?:a|em|strong|small|s|cite|q|dfn|abbr|data|time|code|var|samp|kbd|sub|sup|i|b|u|mark|ruby|rt|rp|bdi|bdo|span|br|wbr|ins|del|img)\\b)\\w+(?!:|[^\\w\\s@]*@)\\b").getRegex(),def:/^ *\[([^\]]+)\]: *<?([^\s>]+)>?(?: +(["(][^\n]+[")]))? *(?:\n+|$)/,heading:/^ *(#{1,6}) *([^\n]+?) *(?:#+ *)?(?:\n+|$)/,fences:v,paragraph:f(n.normal._paragraph).replace("hr",n.hr).replace("heading"," *#{1,6} *[^\n]").replace("lheading",n.lheading).replace("blockquote"," {0,3}>").replace("|fences","").replace("|list","").replace("|html","").getRegex()}),r.rules=n,r.lex=function(e,t){return new r(t).lex(e)},r.prototype.lex=function(e){return e=e.replace(/\r\n|\r/g,"\n").replace(/\t/g,"    ").replace(/\u00a0/g," ").replace(/\u2424/g,"\n"),this.token(e,!0)},r.prototype.token=function(e,t){var r,o,i,a,s,l,c,p,f,d,h,m,v,g,x,w;for(e=e.replace(/^ +$/gm,"");e;)if((i=this.rules.newline.exec(e))&&(e=e.substring(i[0].length),i[0].length>1&&this.tokens.push({type:"space"})),i=this.rules.code.exec(e)){var k=this.tokens[this.tokens.length-1];e=e.substring(i[0].length),k&&"paragraph"===k.type?k.text+="\n"+i[0].trimRight():(i=i[0].replace(/^ {4}/gm,""),this.tokens.push({type:"code",codeBlockStyle:"indented",text:this.options.pedantic?i:b(i,"\n")}))}else if(i=this.rules.fences.exec(e))e=e.substring(i[0].length),this.tokens.push({type:"code",lang:i[2]?i[2].trim():i[2],text:i[3]||""});else if(i=this.rules.heading.exec(e))e=e.substring(i[0].length),this.tokens.push({type:"heading",depth:i[1].length,text:i[2]});else if((i=this.rules.nptable.exec(e))&&(l={type:"table",header:y(i[1].replace(/^ *| *\| *$/g,"")),align:i[2].replace(/^ *|\| *$/g,"").split(/ *\| */),cells:i[3]?i[3].replace(/\n$/,"").split("\n"):[]}).header.length===l.align.length){for(e=e.substring(i[0].length),h=0;h<l.align.length;h++)/^ *-+: *$/.test(l.align[h])?l.align[h]="right":/^ *:-+: *$/.test(l.align[h])?l.align[h]="center":/^ *:-+ *$/.test(l.align[h])?l.align[h]="left":l.align[h]=null;for(h=0;h<l.cells.length;h++)l.cells[h]=y(l.cells[h],l.header.length);this.tokens.push(l)}else if(i=this.rules.hr.exec(e))e=e.substring(i[0].length),this.tokens.push({type:"hr"});else if(i=this.rules.blockquote.exec(e))e=e.substring(i[0].length),this.tokens.push({type:"blockquote_start"}),i=i[0].replace(/^ *> ?/gm,""),this.token(i,t),this.tokens.push({type:"blockquote_end"});else if(i=this.rules.list.exec(e)){for(e=e.substring(i[0].length),c={type:"list_start",ordered:g=(a=i[2]).length>1,start:g?+a:"",loose:!1},this.tokens.push(c),p=[],r=!1,v=(i=i[0].match(this.rules.item)).length,h=0;h<v;h++)d=(l=i[h]).length,~(l=l.replace(/^ *([*+-]|\d+\.) */,"")).indexOf("\n ")&&(d-=l.length,l=this.options.pedantic?l.replace(/^ {1,4}/gm,""):l.replace(new RegExp("^ {1,"+d+"}","gm"),"")),h!==v-1&&(s=n.bullet.exec(i[h+1])[0],(a.length>1?1===s.length:s.length>1||this.options.smartLists&&s!==a)&&(e=i.slice(h+1).join("\n")+e,h=v-1)),o=r||/\n\n(?!\s*$)/.test(l),h!==v-1&&(r="\n"===l.charAt(l.length-1),o||(o=r)),o&&(c.loose=!0),w=void 0,(x=/^\[[ xX]\] /.test(l))&&(w=" "!==l[1],l=l.replace(/^\[[ xX]\] +/,"")),f={type:"list_item_start",task:x,checked:w,loose:o},p.push(f),this.tokens.push(f),this.token(l,!1),this.tokens.push({type:"list_item_end"});if(c.loose)for(v=p.length,h=0;h<v;h++)p[h].loose=!0;this.tokens.push({type:"list_end"})}else if(i=this.rules.html.exec(e))e=e.substring(i[0].length),this.tokens.push({type:this.options.sanitize?"paragraph":"html",pre:!this.options.sanitizer&&("pre"===i[1]||"script"===i[1]||"style"===i[1]),text:this.options.sanitize?this.options.sanitizer?this.options.sanitizer(i[0]):u(i[0]):i[0]});else if(t&&(i=this.rules.def.exec(e)))e=e.substring(i[0].length),i[3]&&(i[3]=i[3].substring(1,i[3].length-1)),m=i[1].toLowerCase().replace(/\s+/g," "),this.tokens.links[m]||(this.tokens.links[m]={href:i[2],title:i[3]});else if((i=this.rules.table.exec(e))&&(l={type:"table",header:y(i[1].replace(/^ *| *\| *$/g,"")),align:i[2].replace(/^ *|\| *$/g,"").split(/ *\| */),cells:i[3]?i[3].replace(/\n$/,"").split("\n"):[]}).header.length===l.align.length){for(e=e.substring(i[0].length),h=0;h<l.align.length;h++)/^ *-+: *$/.test(l.align[h])?l.align[h]="right":/^ *:-+: *$/.test(l.align[h])?l.align[h]="center":/^ *:-+ *$/.test(l.align[h])?l.align[h]="left":l.align[h]=null;for(h=0;h<l.cells.length;h++)l.cells[h]=y(l.cells[h].replace(/^ *\| *| *\| *$/g,""),l.header.length);this.tokens.push(l)}else if(i=this.rules.lheading.exec(e))e=e.substring(i[0].length),this.tokens.push({type:"heading",depth:"="===i[2].charAt(0)?1:2,text:i[1]});else if(t&&(i=this.rules.paragraph.exec(e)))e=e.substring(i[0].length),this.tokens.push({type:"paragraph",text:"\n"===i[1].charAt(i[1].length-1)?i[1].slice(0,-1):i[1]});else if(i=this.rules.text.exec(e))e=e.substring(i[0].length),this.tokens.push({type:"text",text:i[0]});else if(e)throw new Error("Infinite loop on byte: "+e.charCodeAt(0));return this.tokens};var o={escape:/^\\([!"#$%&'()*+,\-./:;<=>?@\[\]\\^_`{|}~])/,autolink:/^<(scheme:[^\s\x00-\x1f<>]*|email)>/,url:v,tag:"^comment|^</[a-zA-Z][\\w:-]*\\s*>|^<[a-zA-Z][\\w-]*(?:attribute)*?\\s*/?>|^<\\?[\\s\\S]*?\\?>|^<![a-zA-Z]+\\s[\\s\\S]*?>|^<!\\[CDATA\\[[\\s\\S]*?\\]\\]>",link:/^!?\[(label)\]\(\s*(href)(?:\s+(title))?\s*\)/,reflink:/^!?\[(label)\]\[(?!\s*\])((?:\\[\[\]]?|[^\[\]\\])+)\]/,nolink:/^!?\[(?!\s*\])((?:\[[^\[\]]*\]|\\[\[\]]|[^\[\]])*)\](?:\[\])?/,strong:/^__([^\s_])__(?!_)|^\*\*([^\s*])\*\*(?!\*)|^__([^\s][\s\S]*?[^\s])__(?!_)|^\*\*([^\s][\s\S]*?[^\s])\*\*(?!\*)/,em:/^_([^\s_])_(?!_)|^\*([^\s*<\[])\*(?!\*)|^_([^\s<][\s\S]*?[^\s_])_(?!_|[^\spunctuation])|^_([^\s_<][\s\S]*?[^\s])_(?!_|[^\spunctuation])|^\*([^\s<"][\s\S]*?[^\s\*])\*(?!\*|[^\spunctuation])|^\*([^\s*"<\[][\s\S]*?[^\s])\*(?!\*)/,code:/^(`+)([^`]|[^`][\s\S]*?[^`])\1(?!`)/,br:/^( {2,}|\\)\n(?!\s*$)/,del:v,text:/^(`+|[^`])(?:[\s\S]*?(?:(?=[\\<!\[`*]|\b_|$)|[^ ](?= {2,}\n))|(?= {2,}\n))/};function i(e,t){if(this.options=t||k.defaults,this.links=e,this.rules=o.normal,this.renderer=this.options.renderer||new a,this.renderer.options=this.options,!this.links)throw new Error("Tokens array requires a `links` property.");this.options.pedantic?this.rules=o.pedantic:this.options.gfm&&(this.options.breaks?this.rules=o.breaks:this.rules=o.gfm)}function a(e){this.options=e||k.defaults}function s(){}function l(e){this.tokens=[],this.token=null,this.options=e||k.defaults,this.options.renderer=this.options.renderer||new a,this.renderer=this.options.renderer,this.renderer.options=this.options,this.slugger=new c}function c(){this.seen={}}function u(e,t){if(t){if(u.escapeTest.test(e))return e.replace(u.escapeReplace,(function(e){return u.replacements[e]}))}else if(u.escapeTestNoEncode.test(e))return e.replace(u.escapeReplaceNoEncode,(function(e){return u.replacements[e]}));return e}function p(e){return e.replace(/&(#(?:\d+)|(?:#x[0-9A-Fa-f]+)|(?:\w+));?/gi,(function(e,t){return"colon"===(t=t.toLowerCase())?":":"#"===t.charAt(0)?"x"===t.charAt(1)?String.fromCharCode(parseInt(t.substring(2),16)):String.fromCharCode(+t.substring(1)):""}))}function f(e,t){return e=e.source||e,t=t||"",{replace:function(t,n){return n=(n=n.source||n).replace(/(^|[^\[])\^/g,"$1"),e=e.replace(t,n),this},getRegex:function(){return new RegExp(e,t)}}}function d(e,t,n){if(e){try{var r=decodeURIComponent(p(n)).replace(/[^\w:]/g,"").toLowerCase()}catch(e){return null}if(0===r.indexOf("javascript:")||0===r.indexOf("vbscript:")||0===r.indexOf("data:"))return null}t&&!m.test(n)&&(n=function(e,t){return h[" "+e]||(/^[^:]+:\/*[^/]*$/.test(e)?h[" "+e]=e+"/":h[" "+e]=b(e,"/",!0)),e=h[" "+e],"//"===t.slice(0,2)?e.replace(/:[\s\S]*/,":")+t:"/"===t.charAt(0)?e.replace(/(:\/*[^/]*)[\s\S]*/,"$1")+t:e+t}(t,n));try{n=encodeURI(n).replace(/%25/g,"%")}catch(e){return null}return n}o._punctuation="!\"#$%&'()*+,\\-./:;<=>?@\\[^_{|}~",o.em=f(o.em).replace(/punctuation/g,o._punctuation).getRegex(),o._escapes=/\\([!"#$%&'()*+,\-./:;<=>?@\[\]\\^_`{|}~])/g,o._scheme=/[a-zA-Z][a-zA-Z0-9+.-]{1,31}/,o._email=/[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+(@)[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)+(?![-_])/,o.autolink=f(o.autolink).replace("scheme",o._scheme).replace("email",o._email).getRegex(),o._attribute=/\s+[a-zA-Z:_][\w.:-]*(?:\s*=\s*"[^"]*"|\s*=\s*'[^']*'|\s*=\s*[^\s"'=<>`]+)?/,o.tag=f(o.tag).replace("comment",n._comment).replace("attribute",o._attribute).getRegex(),o._label=/(?:\[[^\[\]]*\]|\\.|`[^`]*`|[^\[\]\\`])*?/,o._href=/<(?:\\[<>]?|[^\s<>\\])*>|[^\s\x00-\x1f]*/,o._title=/"(?:\\"?|[^"\\])*"|'(?:\\'?|[^'\\])*'|\((?:\\\)?|[^)\\])*\)/,o.link=f(o.link).replace("label",o._label).replace("href",o._href).replace("title",o._title).getRegex(),o.reflink=f(o.reflink).replace("label",o._label).getRegex(),o.normal=g({},o),o.pedantic=g({},o.normal,{strong:/^__(?=\S)([\s\S]*?\S)__(?!_)|^\*\*(?=\S)([\s\S]*?\S)\*\*(?!\*)/,em:/^_(?=\S)([\s\S]*?\S)_(?!_)|^\*(?=\S)([\s\S]*?\S)\*(?!\*)/,link:f(/^!?\[(label)\]\((.*?)\)/).replace("label",o._label).getRegex(),reflink:f(/^!?\[(label)\]\s*\[([^\]]*)\]/).replace("label",o._label).getRegex()}),o.gfm=g({},o.normal,{escape:f(o.escape).replace("])","~|])").getRegex(),_extended_email:/[A-Za-z0-9._+-]+(@)[a-zA-Z0-9-_]+(?:\.[a-zA-Z0-9-_]*[a-zA-Z0-9])+(?![-_])/,url:/^((?:ftp|https?):\/\/|www\.)(?:[a-zA-Z0-9\-]+\.?)+[^\s<]*|^email/,_backpedal:/(?:[^?!.,:;*_~()&]+|\([^)]*\)|&(?![a-zA-Z0-9]+;$)|[?!.,:;*_~)]+(?!$))+/,del:/^~+(?=\S)([\s\S]*?\S)~+/,text:/^(`+|[^`])(?:[\s\S]*?(?:(?=[\\<!\[`*~]|\b_|https?:\/\/|ftp:\/\/|www\.|$)|[^ ](?= {2,}\n)|[^a-zA-Z0-9.!#$%&'*+\/=?_`{\|}~-](?=[a-zA-Z0-9.!#$%&'*+\/=?_`{\|}~-]+@))|(?= {2,}\n|[a-zA-Z0-9.!#$%&'*+\/=?_`{\|}~-]+@))/}),o.gfm.url=f(o.gfm.url,"i").replace("email",o.gfm._extended_email).getRegex(),o.breaks=g({},o.gfm,{br:f(o.br).replace("{2,}","*").getRegex(),text:f(o.gfm.text).replace("\\b_","\\b_| {2,}\\n").replace(/\{2,\}/g,"*").getRegex()}),i.rules=o,i.output=function(e,t,n){return new i(t,n).output(e)},i.prototype.output=function(e){for(var t,n,r,o,a,s,l="";e;)if(a=this.rules.escape.exec(e))e=e.substring(a[0].length),l+=u(a[1]);else if(a=this.rules.tag.exec(e))!this.inLink&&/^<a /i.test(a[0])?this.inLink=!0:this.inLink&&/^<\/a>/i.test(a[0])&&(this.inLink=!1),!this.inRawBlock&&/^<(pre|code|kbd|script)(\s|>)/i.test(a[0])?this.inRawBlock=!0:this.inRawBlock&&/^<\/(pre|code|kbd|script)(\s|>)/i.test(a[0])&&(this.inRawBlock=!1),e=e.substring(a[0].length),l+=this.options.sanitize?this.options.sanitizer?this.options.sanitizer(a[0]):u(a[0]):a[0];else if(a=this.rules.link.exec(e)){var c=x(a[2],"()");if(c>-1){var p=4+a[1].length+c;a[2]=a[2].substring(0,c),a[0]=a[0].substring(0,p).trim(),a[3]=""}e=e.substring(a[0].length),this.inLink=!0,r=a[2],this.options.pedantic?(t=/^([^'"]*[^\s])\s+(['"])(.*)\2/.exec(r))?(r=t[1],o=t[3]):o="":o=a[3]?a[3].slice(1,-1):"",r=r.trim().replace(/^<([\s\S]*)>$/,"$1"),l+=this.outputLink(a,{href:i.escapes(r),title:i.escapes(o)}),this.inLink=!1}else if((a=this.rules.reflink.exec(e))||(a=this.rules.nolink.exec(e))){if(e=e.substring(a[0].length),t=(a[2]||a[1]).replace(/\s+/g," "),!(t=this.links[t.toLowerCase()])||!t.href){l+=a[0].charAt(0),e=a[0].substring(1)+e;continue}this.inLink=!0,l+=this.outputLink(a,t),this.inLink=!1}else if(a=this.rules.strong.exec(e))e=e.substring(a[0].length),l+=this.renderer.strong(this.output(a[4]||a[3]||a[2]||a[1]));else if(a=this.rules.em.exec(e))e=e.substring(a[0].length),l+=this.renderer.em(this.output(a[6]||a[5]||a[4]||a[3]||a[2]||a[1]));else if(a=this.rules.code.exec(e))e=e.substring(a[0].length),l+=this.renderer.codespan(u(a[2].trim(),!0));else if(a=this.rules.br.exec(e))e=e.substring(a[0].length),l+=this.renderer.br();else if(a=this.rules.del.exec(e))e=e.substring(a[0].length),l+=this.renderer.del(this.output(a[1]));else if(a=this.rules.autolink.exec(e))e=e.substring(a[0].length),r="@"===a[2]?"mailto:"+(n=u(this.mangle(a[1]))):n=u(a[1]),l+=this.renderer.link(r,null,n);else if(this.inLink||!(a=this.rules.url.exec(e))){if(a=this.rules.text.exec(e))e=e.substring(a[0].length),this.inRawBlock?l+=this.renderer.text(this.options.sanitize?this.options.sanitizer?this.options.sanitizer(a[0]):u(a[0]):a[0]):l+=this.renderer.text(u(this.smartypants(a[0])));else if(e)throw new Error("Infinite loop on byte: "+e.charCodeAt(0))}else{if("@"===a[2])r="mailto:"+(n=u(a[0]));else{do{s=a[0],a[0]=this.rules._backpedal.exec(a[0])[0]}while(s!==a[0]);n=u(a[0]),r="www."===a[1]?"http://"+n:n}e=e.substring(a[0].length),l+=this.renderer.link(r,null,n)}return l},i.escapes=function(e){return e?e.replace(i.rules._escapes,"$1"):e},i.prototype.outputLink=function(e,t){var n=t.href,r=t.title?u(t.title):null;return"!"!==e[0].charAt(0)?this.renderer.link(n,r,this.output(e[1])):this.renderer.image(n,r,u(e[1]))},i.prototype.smartypants=function(e){return this.options.smartypants?e.replace(/---/g,"—").replace(/--/g,"–").replace(/(^|[-\u2014/(\[{"\s])'/g,"$1‘").replace(/'/g,"’").replace(/(^|[-\u2014/(\[{\u2018\s])"/g,"$1“").replace(/"/g,"”").replace(/\.{3}/g,"…"):e},i.prototype.mangle=function(e){if(!this.options.mangle)return e;for(var t,n="",r=e.length,o=0;o<r;o++)t=e.charCodeAt(o),Math.random()>.5&&(t="x"+t.toString(16)),n+="&#"+t+";";return n},a.prototype.code=function(e,t,n){var r=(t||"").match(/\S*/)[0];if(this.options.highlight){var o=this.options.highlight(e,r);null!=o&&o!==e&&(n=!0,e=o)}return r?'<pre><code class="'+this.options.langPrefix+u(r,!0)+'">'+(n?e:u(e,!0))+"</code></pre>\n":"<pre><code>"+(n?e:u(e,!0))+"</code></pre>"},a.prototype.blockquote=function(e){return"<blockquote>\n"+e+"</blockquote>\n"},a.prototype.html=function(e){return e},a.prototype.heading=function(e,t,n,r){return this.options.headerIds?"<h"+t+' id="'+this.options.headerPrefix+r.slug(n)+'">'+e+"</h"+t+">\n":"<h"+t+">"+e+"</h"+t+">\n"},a.prototype.hr=function(){return this.options.xhtml?"<hr/>\n":"<hr>\n"},a.prototype.list=function(e,t,n){var r=t?"ol":"ul";return"<"+r+(t&&1!==n?' start="'+n+'"':"")+">\n"+e+"</"+r+">\n"},a.prototype.listitem=function(e){return"<li>"+e+"</li>\n"},a.prototype.checkbox=function(e){return"<input "+(e?'checked="" ':"")+'disabled="" type="checkbox"'+(this.options.xhtml?" /":"")+"> "},a.prototype.paragraph=function(e){return"<p>"+e+"</p>\n"},a.prototype.table=function(e,t){return t&&(t="<tbody>"+t+"</tbody>"),"<table>\n<thead>\n"+e+"</thead>\n"+t+"</table>\n"},a.prototype.tablerow=function(e){return"<tr>\n"+e+"</tr>\n"},a.prototype.tablecell=function(e,t){var n=t.header?"th":"td";return(t.align?"<"+n+' align="'+t.align+'">':"<"+n+">")+e+"</"+n+">\n"},a.prototype.strong=function(e){return"<strong>"+e+"</strong>"},a.prototype.em=function(e){return"<em>"+e+"</em>"},a.prototype.codespan=function(e){return"<code>"+e+"</code>"},a.prototype.br=function(){return this.options.xhtml?"<br/>":"<br>"},a.prototype.del=function(e){return"<del>"+e+"</del>"},a.prototype.link=function(e,t,n){if(null===(e=d(this.options.sanitize,this.options.baseUrl,e)))return n;var r='<a href="'+u(e)+'"';return t&&(r+=' title="'+t+'"'),r+">"+n+"</a>"},a.prototype.image=function(e,t,n){if(null===(e=d(this.options.sanitize,this.options.baseUrl,e)))return n;var r='<img src="'+e+'" alt="'+n+'"';return t&&(r+=' title="'+t+'"'),r+(this.options.xhtml?"/>":">")},a.prototype.text=function(e){return e},s.prototype.strong=s.prototype.em=s.prototype.codespan=s.prototype.del=s.prototype.text=function(e){return e},s.prototype.link=s.prototype.image=function(e,t,n){return""+n},s.prototype.br=function(){return""},l.parse=function(e,t){return new l(t).parse(e)},l.prototype.parse=function(e){this.inline=new i(e.links,this.options),this.inlineText=new i(e.links,g({},this.options,{renderer:new s})),this.tokens=e.reverse();for(var t="";this.next();)t+=this.tok();return t},l.prototype.next=function(){return this.token=this.tokens.pop(),this.token},l.prototype.peek=function(){return this.tokens[this.tokens.length-1]||0},l.prototype.parseText=function(){for(var e=this.token.text;"text"===this.peek().type;)e+="\n"+this.next().text;return this.inline.output(e)},l.prototype.tok=function(){switch(this.token.type){case"space":return"";case"hr":return this.renderer.hr();case"heading":return this.renderer.heading(this.inline.output(this.token.text),this.token.depth,p(this.inlineText.output(this.token.text)),this.slugger);case"code":return this.renderer.code(this.token.text,this.token.lang,this.token.escaped);case"table":var e,t,n,r,o="",i="";for(n="",e=0;e<this.token.header.length;e++)n+=this.renderer.tablecell(this.inline.output(this.token.header[e]),{header:!0,align:this.token.align[e]});for(o+=this.renderer.tablerow(n),e=0;e<this.token.cells.length;e++){for(t=this.token.cells[e],n="",r=0;r<t.length;r++)n+=this.renderer.tablecell(this.inline.output(t[r]),{header:!1,align:this.token.align[r]});i+=this.renderer.tablerow(n)}return this.renderer.table(o,i);case"blockquote_start":for(i="";"blockquote_end"!==this.next().type;)i+=this.tok();return this.renderer.blockquote(i);case"list_start":i="";for(var a=this.token.ordered,s=this.token.start;"list_end"!==this.next().type;)i+=this.tok();return this.renderer.list(i,a,s);case"list_item_start":i="";var l=this.token.loose,c=this.token.checked,u=this.token.task;for(this.token.task&&(i+=this.renderer.checkbox(c));"list_item_end"!==this.next().type;)i+=l||"text"!==this.token.type?this.tok():this.parseText();return this.renderer.listitem(i,u,c);case"html":return this.renderer.html(this.token.text);case"paragraph":return this.renderer.paragraph(this.inline.output(this.token.text));case"text":return this.renderer.paragraph(this.parseText());default:var f='Token with "'+this.token.type+'" type was not found.';if(!this.options.silent)throw new Error(f);console.log(f)}},c.prototype.slug=function(e){var t=e.toLowerCase().trim().replace(/[\u2000-\u206F\u2E00-\u2E7F\\'!"#$%&()*+,./:;<=>?@[\]^`{|}~]/g,"").replace(/\s/g,"-");if(this.seen.hasOwnProperty(t)){var n=t;do{this.seen[n]++,t=n+"-"+this.seen[n]}while(this.seen.hasOwnProperty(t))}return this.seen[t]=0,t},u.escapeTest=/[&<>"']/,u.escapeReplace=/[&<>"']/g,u.replacements={"&":"&amp;","<":"&lt;",">":"&gt;",'"':"&quot;","'":"&#39;"},u.escapeTestNoEncode=/[<>"']|&(?!#?\w+;)/,u.escapeReplaceNoEncode=/[<>"']|&(?!#?\w+;)/g;var h={},m=/^$|^[a-z][a-z0-9+.-]*:|^[?#]/i;function v(){}function g(e){for(var t,n,r=1;r<arguments.length;r++)for(n in t=arguments[r])Object.prototype.hasOwnProperty.call(t,n)&&(e[n]=t[n]);return e}function y(e,t){var n=e.replace(/\|/g,(function(e,t,n){for(var r=!1,o=t;--o>=0&&"\\"===n[o];)r=!r;return r?"|":" |"})).split(/ \|/),r=0;if(n.length>t)n.splice(t);else for(;n.length<t;)n.push("");for(;r<n.length;r++)n[r]=n[r].trim().replace(/\\\|/g,"|");return n}function b(e,t,n){if(0===e.length)return"";for(var r=0;r<e.length;){var o=e.charAt(e.length-r-1);if(o!==t||n){if(o===t||!n)break;r++}else r++}return e.substr(0,e.length-r)}function x(e,t){if(-1===e.indexOf(t[1]))return-1;for(var n=0,r=0;r<e.length;r++)if("\\"===e[r])r++;else if(e[r]===t[0])n++;else if(e[r]===t[1]&&--n<0)return r;return-1}function w(e){e&&e.sanitize&&!e.silent&&console.warn("marked(): sanitize and sanitizer parameters are deprecated since version 0.7.0, should not be used and will be removed in the future. Read more here: https://marked.js.org/#/USING_ADVANCED.md#options")}function k(e,t,n){if(null==e)throw new Error("marked(): input parameter is undefined or null");if("string"!=typeof e)throw new Error("marked(): input parameter is of type "+Object.prototype.toString.call(e)+", string expected");if(n||"function"==typeof t){n||(n=t,t=null),w(t=g({},k.defaults,t||{}));var o,i,a=t.highlight,s=0;try{o=r.lex(e,t)}catch(e){return n(e)}i=o.length;var c=function(e){if(e)return t.highlight=a,n(e);var r;try{r=l.parse(o,t)}catch(t){e=t}return t.highlight=a,e?n(e):n(null,r)};if(!a||a.length<3)return c();if(delete t.highlight,!i)return c();for(;s<o.length;s++)!function(e){"code"!==e.type?--i||c():a(e.text,e.lang,(function(t,n){return t?c(t):null==n||n===e.text?--i||c():(e.text=n,e.escaped=!0,void(--i||c()))}))}(o[s])}else try{return t&&(t=g({},k.defaults,t)),w(t),l.parse(r.lex(e,t),t)}catch(e){if(e.message+="\nPlease report this to https://github.com/markedjs/marked.",(t||k.defaults).silent)return"<p>An error occurred:</p><pre>"+u(e.message+"",!0)+"</pre>";throw e}}v.exec=v,k.options=k.setOptions=function(e){return g(k.defaults,e),k},k.getDefaults=function(){return{baseUrl:null,breaks:!1,gfm:!0,headerIds:!0,headerPrefix:"",highlight:null,langPrefix:"language-",mangle:!0,pedantic:!1,renderer:new a,sanitize:!1,sanitizer:null,silent:!1,smartLists:!1,smartypants:!1,xhtml:!1}},k.defaults=k.getDefaults(),k.Parser=l,k.parser=l.parse,k.Renderer=a,k.TextRenderer=s,k.Lexer=r,k.lexer=r.lex,k.InlineLexer=i,k.inlineLexer=i.output,k.Slugger=c,k.parse=k,e.exports=k}(this||("undefined"!=typeof window?window:n.g))},1171:function(e,t,n){e.exports=f,f.Minimatch=d;var r={sep:"/"};try{r=n(6470)}catch(e){}var o=f.GLOBSTAR=d.GLOBSTAR={},i=n(3644),a={"!":{open:"(?:(?!(?:",close:"))[^/]*?)"},"?":{open:"(?:",close:")?"},"+":{open:"(?:",close:")+"},"*":{open:"(?:",close:")*"},"@":{open:"(?:",close:")"}},s="[^/]",l="[^/]*?",c="().*{}+?[]^$\\!".split("").reduce((function(e,t){return e[t]=!0,e}),{}),u=/\/+/;function p(e,t){e=e||{},t=t||{};var n={};return Object.keys(t).forEach((function(e){n[e]=t[e]})),Object.keys(e).forEach((function(t){n[t]=e[t]})),n}function f(e,t,n){if("string"!=typeof t)throw new TypeError("glob pattern string required");return n||(n={}),!(!n.nocomment&&"#"===t.charAt(0))&&(""===t.trim()?""===e:new d(t,n).match(e))}function d(e,t){if(!(this instanceof d))return new d(e,t);if("string"!=typeof e)throw new TypeError("glob pattern string required");t||(t={}),e=e.trim(),"/"!==r.sep&&(e=e.split(r.sep).join("/")),this.options=t,this.set=[],this.pattern=e,this.regexp=null,this.negate=!1,this.comment=!1,this.empty=!1,this.make()}function h(e,t){if(t||(t=this instanceof d?this.options:{}),void 0===(e=void 0===e?this.pattern:e))throw new TypeError("undefined pattern");return t.nobrace||!e.match(/\{.*\}/)?[e]:i(e)}f.filter=function(e,t){return t=t||{},function(n,r,o){return f(n,e,t)}},f.defaults=function(e){if(!e||!Object.keys(e).length)return f;var t=f,n=function(n,r,o){return t.minimatch(n,r,p(e,o))};return n.Minimatch=function(n,r){return new t.Minimatch(n,p(e,r))},n},d.defaults=function(e){return e&&Object.keys(e).length?f.defaults(e).Minimatch:d},d.prototype.debug=function(){},d.prototype.make=function(){if(!this._made){var e=this.pattern,t=this.options;if(t.nocomment||"#"!==e.charAt(0))if(e){this.parseNegate();var n=this.globSet=this.braceExpand();t.debug&&(this.debug=console.error),this.debug(this.pattern,n),n=this.globParts=n.map((function(e){return e.split(u)})),this.debug(this.pattern,n),n=n.map((function(e,t,n){return e.map(this.parse,this)}),this),this.debug(this.pattern,n),n=n.filter((function(e){return-1===e.indexOf(!1)})),this.debug(this.pattern,n),this.set=n}else this.empty=!0;else this.comment=!0}},d.prototype.parseNegate=function(){var e=this.pattern,t=!1,n=0;if(!this.options.nonegate){for(var r=0,o=e.length;r<o&&"!"===e.charAt(r);r++)t=!t,n++;n&&(this.pattern=e.substr(n)),this.negate=t}},f.braceExpand=function(e,t){return h(e,t)},d.prototype.braceExpand=h,d.prototype.parse=function(e,t){if(e.length>65536)throw new TypeError("pattern is too long");var n=this.options;if(!n.noglobstar&&"**"===e)return o;if(""===e)return"";var r,i="",u=!!n.nocase,p=!1,f=[],d=[],h=!1,v=-1,g=-1,y="."===e.charAt(0)?"":n.dot?"(?!(?:^|\\/)\\.{1,2}(?:$|\\/))":"(?!\\.)",b=this;function x(){if(r){switch(r){case"*":i+=l,u=!0;break;case"?":i+=s,u=!0;break;default:i+="\\"+r}b.debug("clearStateChar %j %j",r,i),r=!1}}for(var w,k=0,E=e.length;k<E&&(w=e.charAt(k));k++)if(this.debug("%s\t%s %s %j",e,k,i,w),p&&c[w])i+="\\"+w,p=!1;else switch(w){case"/":return!1;case"\\":x(),p=!0;continue;case"?":case"*":case"+":case"@":case"!":if(this.debug("%s\t%s %s %j <-- stateChar",e,k,i,w),h){this.debug("  in class"),"!"===w&&k===g+1&&(w="^"),i+=w;continue}b.debug("call clearStateChar %j",r),x(),r=w,n.noext&&x();continue;case"(":if(h){i+="(";continue}if(!r){i+="\\(";continue}f.push({type:r,start:k-1,reStart:i.length,open:a[r].open,close:a[r].close}),i+="!"===r?"(?:(?!(?:":"(?:",this.debug("plType %j %j",r,i),r=!1;continue;case")":if(h||!f.length){i+="\\)";continue}x(),u=!0;var S=f.pop();i+=S.close,"!"===S.type&&d.push(S),S.reEnd=i.length;continue;case"|":if(h||!f.length||p){i+="\\|",p=!1;continue}x(),i+="|";continue;case"[":if(x(),h){i+="\\"+w;continue}h=!0,g=k,v=i.length,i+=w;continue;case"]":if(k===g+1||!h){i+="\\"+w,p=!1;continue}if(h){var _=e.substring(g+1,k);try{RegExp("["+_+"]")}catch(e){var O=this.parse(_,m);i=i.substr(0,v)+"\\["+O[0]+"\\]",u=u||O[1],h=!1;continue}}u=!0,h=!1,i+=w;continue;default:x(),p?p=!1:!c[w]||"^"===w&&h||(i+="\\"),i+=w}for(h&&(_=e.substr(g+1),O=this.parse(_,m),i=i.substr(0,v)+"\\["+O[0],u=u||O[1]),S=f.pop();S;S=f.pop()){var A=i.slice(S.reStart+S.open.length);this.debug("setting tail",i,S),A=A.replace(/((?:\\{2}){0,64})(\\?)\|/g,(function(e,t,n){return n||(n="\\"),t+t+n+"|"})),this.debug("tail=%j\n   %s",A,A,S,i);var R="*"===S.type?l:"?"===S.type?s:"\\"+S.type;u=!0,i=i.slice(0,S.reStart)+R+"\\("+A}x(),p&&(i+="\\\\");var C=!1;switch(i.charAt(0)){case".":case"[":case"(":C=!0}for(var T=d.length-1;T>-1;T--){var P=d[T],I=i.slice(0,P.reStart),j=i.slice(P.reStart,P.reEnd-8),L=i.slice(P.reEnd-8,P.reEnd),N=i.slice(P.reEnd);L+=N;var M=I.split("(").length-1,D=N;for(k=0;k<M;k++)D=D.replace(/\)[+*?]?/,"");var F="";""===(N=D)&&t!==m&&(F="$"),i=I+j+N+F+L}if(""!==i&&u&&(i="(?=.)"+i),C&&(i=y+i),t===m)return[i,u];if(!u)return e.replace(/\\(.)/g,"$1");var z=n.nocase?"i":"";try{var U=new RegExp("^"+i+"$",z)}catch(e){return new RegExp("$.")}return U._glob=e,U._src=i,U};var m={};f.makeRe=function(e,t){return new d(e,t||{}).makeRe()},d.prototype.makeRe=function(){if(this.regexp||!1===this.regexp)return this.regexp;var e=this.set;if(!e.length)return this.regexp=!1,this.regexp;var t=this.options,n=t.noglobstar?l:t.dot?"(?:(?!(?:\\/|^)(?:\\.{1,2})($|\\/)).)*?":"(?:(?!(?:\\/|^)\\.).)*?",r=t.nocase?"i":"",i=e.map((function(e){return e.map((function(e){return e===o?n:"string"==typeof e?e.replace(/[-[\]{}()*+?.,\\^$|#\s]/g,"\\$&"):e._src})).join("\\/")})).join("|");i="^(?:"+i+")$",this.negate&&(i="^(?!"+i+").*$");try{this.regexp=new RegExp(i,r)}catch(e){this.regexp=!1}return this.regexp},f.match=function(e,t,n){var r=new d(t,n=n||{});return e=e.filter((function(e){return r.match(e)})),r.options.nonull&&!e.length&&e.push(t),e},d.prototype.match=function(e,t){if(this.debug("match",e,this.pattern),this.comment)return!1;if(this.empty)return""===e;if("/"===e&&t)return!0;var n=this.options;"/"!==r.sep&&(e=e.split(r.sep).join("/")),e=e.split(u),this.debug(this.pattern,"split",e);var o,i,a=this.set;for(this.debug(this.pattern,"set",a),i=e.length-1;i>=0&&!(o=e[i]);i--);for(i=0;i<a.length;i++){var s=a[i],l=e;if(n.matchBase&&1===s.length&&(l=[o]),this.matchOne(l,s,t))return!!n.flipNegate||!this.negate}return!n.flipNegate&&this.negate},d.prototype.matchOne=function(e,t,n){var r=this.options;this.debug("matchOne",{this:this,file:e,pattern:t}),this.debug("matchOne",e.length,t.length);for(var i=0,a=0,s=e.length,l=t.length;i<s&&a<l;i++,a++){this.debug("matchOne loop");var c,u=t[a],p=e[i];if(this.debug(t,u,p),!1===u)return!1;if(u===o){this.debug("GLOBSTAR",[t,u,p]);var f=i,d=a+1;if(d===l){for(this.debug("** at the end");i<s;i++)if("."===e[i]||".."===e[i]||!r.dot&&"."===e[i].charAt(0))return!1;return!0}for(;f<s;){var h=e[f];if(this.debug("\nglobstar while",e,f,t,d,h),this.matchOne(e.slice(f),t.slice(d),n))return this.debug("globstar found match!",f,s,h),!0;if("."===h||".."===h||!r.dot&&"."===h.charAt(0)){this.debug("dot detected!",e,f,t,d);break}this.debug("globstar swallow a segment, and continue"),f++}return!(!n||(this.debug("\n>>> no match, partial?",e,f,t,d),f!==s))}if("string"==typeof u?(c=r.nocase?p.toLowerCase()===u.toLowerCase():p===u,this.debug("string match",u,p,c)):(c=p.match(u),this.debug("pattern match",u,p,c)),!c)return!1}if(i===s&&a===l)return!0;if(i===s)return n;if(a===l)return i===s-1&&""===e[i];throw new Error("wtf?")}},7418:function(e){"use strict";var t=Object.getOwnPropertySymbols,n=Object.prototype.hasOwnProperty,r=Object.prototype.propertyIsEnumerable;function o(e){if(null==e)throw new TypeError("Object.assign cannot be called with null or undefined");return Object(e)}e.exports=function(){try{if(!Object.assign)return!1;var e=new String("abc");if(e[5]="de","5"===Object.getOwnPropertyNames(e)[0])return!1;for(var t={},n=0;n<10;n++)t["_"+String.fromCharCode(n)]=n;if("0123456789"!==Object.getOwnPropertyNames(t).map((function(e){return t[e]})).join(""))return!1;var r={};return"abcdefghijklmnopqrst".split("").forEach((function(e){r[e]=e})),"abcdefghijklmnopqrst"===Object.keys(Object.assign({},r)).join("")}catch(e){return!1}}()?Object.assign:function(e,i){for(var a,s,l=o(e),c=1;c<arguments.length;c++){for(var u in a=Object(arguments[c]))n.call(a,u)&&(l[u]=a[u]);if(t){s=t(a);for(var p=0;p<s.length;p++)r.call(a,s[p])&&(l[s[p]]=a[s[p]])}}return l}},6470:function(e){"use strict";function t(e){if("string"!=typeof e)throw new TypeError("Path must be a string. Received "+JSON.stringify(e))}function n(e,t){for(var n,r="",o=0,i=-1,a=0,s=0;s<=e.length;++s){if(s<e.length)n=e.charCodeAt(s);else{if(47===n)break;n=47}if(47===n){if(i===s-1||1===a);else if(i!==s-1&&2===a){if(r.length<2||2!==o||46!==r.charCodeAt(r.length-1)||46!==r.charCodeAt(r.length-2))if(r.length>2){var l=r.lastIndexOf("/");if(l!==r.length-1){-1===l?(r="",o=0):o=(r=r.slice(0,l)).length-1-r.lastIndexOf("/"),i=s,a=0;continue}}else if(2===r.length||1===r.length){r="",o=0,i=s,a=0;continue}t&&(r.length>0?r+="/..":r="..",o=2)}else r.length>0?r+="/"+e.slice(i+1,s):r=e.slice(i+1,s),o=s-i-1;i=s,a=0}else 46===n&&-1!==a?++a:a=-1}return r}var r={resolve:function(){for(var e,r="",o=!1,i=arguments.length-1;i>=-1&&!o;i--){var a;i>=0?a=arguments[i]:(void 0===e&&(e=process.cwd()),a=e),t(a),0!==a.length&&(r=a+"/"+r,o=47===a.charCodeAt(0))}return r=n(r,!o),o?r.length>0?"/"+r:"/":r.length>0?r:"."},normalize:function(e){if(t(e),0===e.length)return".";var r=47===e.charCodeAt(0),o=47===e.charCodeAt(e.length-1);return 0!==(e=n(e,!r)).length||r||(e="."),e.length>0&&o&&(e+="/"),r?"/"+e:e},isAbsolute:function(e){return t(e),e.length>0&&47===e.charCodeAt(0)},join:function(){if(0===arguments.length)return".";for(var e,n=0;n<arguments.length;++n){var o=arguments[n];t(o),o.length>0&&(void 0===e?e=o:e+="/"+o)}return void 0===e?".":r.normalize(e)},relative:function(e,n){if(t(e),t(n),e===n)return"";if((e=r.resolve(e))===(n=r.resolve(n)))return"";for(var o=1;o<e.length&&47===e.charCodeAt(o);++o);for(var i=e.length,a=i-o,s=1;s<n.length&&47===n.charCodeAt(s);++s);for(var l=n.length-s,c=a<l?a:l,u=-1,p=0;p<=c;++p){if(p===c){if(l>c){if(47===n.charCodeAt(s+p))return n.slice(s+p+1);if(0===p)return n.slice(s+p)}else a>c&&(47===e.charCodeAt(o+p)?u=p:0===p&&(u=0));break}var f=e.charCodeAt(o+p);if(f!==n.charCodeAt(s+p))break;47===f&&(u=p)}var d="";for(p=o+u+1;p<=i;++p)p!==i&&47!==e.charCodeAt(p)||(0===d.length?d+="..":d+="/..");return d.length>0?d+n.slice(s+u):(s+=u,47===n.charCodeAt(s)&&++s,n.slice(s))},_makeLong:function(e){return e},dirname:function(e){if(t(e),0===e.length)return".";for(var n=e.charCodeAt(0),r=47===n,o=-1,i=!0,a=e.length-1;a>=1;--a)if(47===(n=e.charCodeAt(a))){if(!i){o=a;break}}else i=!1;return-1===o?r?"/":".":r&&1===o?"//":e.slice(0,o)},basename:function(e,n){if(void 0!==n&&"string"!=typeof n)throw new TypeError('"ext" argument must be a string');t(e);var r,o=0,i=-1,a=!0;if(void 0!==n&&n.length>0&&n.length<=e.length){if(n.length===e.length&&n===e)return"";var s=n.length-1,l=-1;for(r=e.length-1;r>=0;--r){var c=e.charCodeAt(r);if(47===c){if(!a){o=r+1;break}}else-1===l&&(a=!1,l=r+1),s>=0&&(c===n.charCodeAt(s)?-1==--s&&(i=r):(s=-1,i=l))}return o===i?i=l:-1===i&&(i=e.length),e.slice(o,i)}for(r=e.length-1;r>=0;--r)if(47===e.charCodeAt(r)){if(!a){o=r+1;break}}else-1===i&&(a=!1,i=r+1);return-1===i?"":e.slice(o,i)},extname:function(e){t(e);for(var n=-1,r=0,o=-1,i=!0,a=0,s=e.length-1;s>=0;--s){var l=e.charCodeAt(s);if(47!==l)-1===o&&(i=!1,o=s+1),46===l?-1===n?n=s:1!==a&&(a=1):-1!==n&&(a=-1);else if(!i){r=s+1;break}}return-1===n||-1===o||0===a||1===a&&n===o-1&&n===r+1?"":e.slice(n,o)},format:function(e){if(null===e||"object"!=typeof e)throw new TypeError('The "pathObject" argument must be of type Object. Received type '+typeof e);return function(e,t){var n=t.dir||t.root,r=t.base||(t.name||"")+(t.ext||"");return n?n===t.root?n+r:n+"/"+r:r}(0,e)},parse:function(e){t(e);var n={root:"",dir:"",base:"",ext:"",name:""};if(0===e.length)return n;var r,o=e.charCodeAt(0),i=47===o;i?(n.root="/",r=1):r=0;for(var a=-1,s=0,l=-1,c=!0,u=e.length-1,p=0;u>=r;--u)if(47!==(o=e.charCodeAt(u)))-1===l&&(c=!1,l=u+1),46===o?-1===a?a=u:1!==p&&(p=1):-1!==a&&(p=-1);else if(!c){s=u+1;break}return-1===a||-1===l||0===p||1===p&&a===l-1&&a===s+1?-1!==l&&(n.base=n.name=0===s&&i?e.slice(1,l):e.slice(s,l)):(0===s&&i?(n.name=e.slice(1,a),n.base=e.slice(1,l)):(n.name=e.slice(s,a),n.base=e.slice(s,l)),n.ext=e.slice(a,l)),s>0?n.dir=e.slice(0,s-1):i&&(n.dir="/"),n},sep:"/",delimiter:":",win32:null,posix:null};r.posix=r,e.exports=r},7874:function(){!function(e){var t="\\b(?:BASH|BASHOPTS|BASH_ALIASES|BASH_ARGC|BASH_ARGV|BASH_CMDS|BASH_COMPLETION_COMPAT_DIR|BASH_LINENO|BASH_REMATCH|BASH_SOURCE|BASH_VERSINFO|BASH_VERSION|COLORTERM|COLUMNS|COMP_WORDBREAKS|DBUS_SESSION_BUS_ADDRESS|DEFAULTS_PATH|DESKTOP_SESSION|DIRSTACK|DISPLAY|EUID|GDMSESSION|GDM_LANG|GNOME_KEYRING_CONTROL|GNOME_KEYRING_PID|GPG_AGENT_INFO|GROUPS|HISTCONTROL|HISTFILE|HISTFILESIZE|HISTSIZE|HOME|HOSTNAME|HOSTTYPE|IFS|INSTANCE|JOB|LANG|LANGUAGE|LC_ADDRESS|LC_ALL|LC_IDENTIFICATION|LC_MEASUREMENT|LC_MONETARY|LC_NAME|LC_NUMERIC|LC_PAPER|LC_TELEPHONE|LC_TIME|LESSCLOSE|LESSOPEN|LINES|LOGNAME|LS_COLORS|MACHTYPE|MAILCHECK|MANDATORY_PATH|NO_AT_BRIDGE|OLDPWD|OPTERR|OPTIND|ORBIT_SOCKETDIR|OSTYPE|PAPERSIZE|PATH|PIPESTATUS|PPID|PS1|PS2|PS3|PS4|PWD|RANDOM|REPLY|SECONDS|SELINUX_INIT|SESSION|SESSIONTYPE|SESSION_MANAGER|SHELL|SHELLOPTS|SHLVL|SSH_AUTH_SOCK|TERM|UID|UPSTART_EVENTS|UPSTART_INSTANCE|UPSTART_JOB|UPSTART_SESSION|USER|WINDOWID|XAUTHORITY|XDG_CONFIG_DIRS|XDG_CURRENT_DESKTOP|XDG_DATA_DIRS|XDG_GREETER_DATA_DIR|XDG_MENU_PREFIX|XDG_RUNTIME_DIR|XDG_SEAT|XDG_SEAT_PATH|XDG_SESSION_DESKTOP|XDG_SESSION_ID|XDG_SESSION_PATH|XDG_SESSION_TYPE|XDG_VTNR|XMODIFIERS)\\b",n={pattern:/(^(["']?)\w+\2)[ \t]+\S.*/,lookbehind:!0,alias:"punctuation",inside:null},r={bash:n,environment:{pattern:RegExp("\\$"+t),alias:"constant"},variable:[{pattern:/\$?\(\([\s\S]+?\)\)/,greedy:!0,inside:{variable:[{pattern:/(^\$\(\([\s\S]+)\)\)/,lookbehind:!0},/^\$\(\(/],number:/\b0x[\dA-Fa-f]+\b|(?:\b\d+(?:\.\d*)?|\B\.\d+)(?:[Ee]-?\d+)?/,operator:/--|\+\+|\*\*=?|<<=?|>>=?|&&|\|\||[=!+\-*/%<>^&|]=?|[?~:]/,punctuation:/\(\(?|\)\)?|,|;/}},{pattern:/\$\((?:\([^)]+\)|[^()])+\)|`[^`]+`/,greedy:!0,inside:{variable:/^\$\(|^`|\)$|`$/}},{pattern:/\$\{[^}]+\}/,greedy:!0,inside:{operator:/:[-=?+]?|[!\/]|##?|%%?|\^\^?|,,?/,punctuation:/[\[\]]/,environment:{pattern:RegExp("(\\{)"+t),lookbehind:!0,alias:"constant"}}},/\$(?:\w+|[#?*!@$])/],entity:/\\(?:[abceEfnrtv\\"]|O?[0-7]{1,3}|x[0-9a-fA-F]{1,2}|u[0-9a-fA-F]{4}|U[0-9a-fA-F]{8})/};e.languages.bash={shebang:{pattern:/^#!\s*\/.*/,alias:"important"},comment:{pattern:/(^|[^"{\\$])#.*/,lookbehind:!0},"function-name":[{pattern:/(\bfunction\s+)[\w-]+(?=(?:\s*\(?:\s*\))?\s*\{)/,lookbehind:!0,alias:"function"},{pattern:/\b[\w-]+(?=\s*\(\s*\)\s*\{)/,alias:"function"}],"for-or-select":{pattern:/(\b(?:for|select)\s+)\w+(?=\s+in\s)/,alias:"variable",lookbehind:!0},"assign-left":{pattern:/(^|[\s;|&]|[<>]\()\w+(?=\+?=)/,inside:{environment:{pattern:RegExp("(^|[\\s;|&]|[<>]\\()"+t),lookbehind:!0,alias:"constant"}},alias:"variable",lookbehind:!0},string:[{pattern:/((?:^|[^<])<<-?\s*)(\w+)\s[\s\S]*?(?:\r?\n|\r)\2/,lookbehind:!0,greedy:!0,inside:r},{pattern:/((?:^|[^<])<<-?\s*)(["'])(\w+)\2\s[\s\S]*?(?:\r?\n|\r)\3/,lookbehind:!0,greedy:!0,inside:{bash:n}},{pattern:/(^|[^\\](?:\\\\)*)"(?:\\[\s\S]|\$\([^)]+\)|\$(?!\()|`[^`]+`|[^"\\`$])*"/,lookbehind:!0,greedy:!0,inside:r},{pattern:/(^|[^$\\])'[^']*'/,lookbehind:!0,greedy:!0},{pattern:/\$'(?:[^'\\]|\\[\s\S])*'/,greedy:!0,inside:{entity:r.entity}}],environment:{pattern:RegExp("\\$?"+t),alias:"constant"},variable:r.variable,function:{pattern:/(^|[\s;|&]|[<>]\()(?:add|apropos|apt|aptitude|apt-cache|apt-get|aspell|automysqlbackup|awk|basename|bash|bc|bconsole|bg|bzip2|cal|cat|cfdisk|chgrp|chkconfig|chmod|chown|chroot|cksum|clear|cmp|column|comm|composer|cp|cron|crontab|csplit|curl|cut|date|dc|dd|ddrescue|debootstrap|df|diff|diff3|dig|dir|dircolors|dirname|dirs|dmesg|du|egrep|eject|env|ethtool|expand|expect|expr|fdformat|fdisk|fg|fgrep|file|find|fmt|fold|format|free|fsck|ftp|fuser|gawk|git|gparted|grep|groupadd|groupdel|groupmod|groups|grub-mkconfig|gzip|halt|head|hg|history|host|hostname|htop|iconv|id|ifconfig|ifdown|ifup|import|install|ip|jobs|join|kill|killall|less|link|ln|locate|logname|logrotate|look|lpc|lpr|lprint|lprintd|lprintq|lprm|ls|lsof|lynx|make|man|mc|mdadm|mkconfig|mkdir|mke2fs|mkfifo|mkfs|mkisofs|mknod|mkswap|mmv|more|most|mount|mtools|mtr|mutt|mv|nano|nc|netstat|nice|nl|nohup|notify-send|npm|nslookup|op|open|parted|passwd|paste|pathchk|ping|pkill|pnpm|popd|pr|printcap|printenv|ps|pushd|pv|quota|quotacheck|quotactl|ram|rar|rcp|reboot|remsync|rename|renice|rev|rm|rmdir|rpm|rsync|scp|screen|sdiff|sed|sendmail|seq|service|sftp|sh|shellcheck|shuf|shutdown|sleep|slocate|sort|split|ssh|stat|strace|su|sudo|sum|suspend|swapon|sync|tac|tail|tar|tee|time|timeout|top|touch|tr|traceroute|tsort|tty|umount|uname|unexpand|uniq|units|unrar|unshar|unzip|update-grub|uptime|useradd|userdel|usermod|users|uudecode|uuencode|v|vdir|vi|vim|virsh|vmstat|wait|watch|wc|wget|whereis|which|who|whoami|write|xargs|xdg-open|yarn|yes|zenity|zip|zsh|zypper)(?=$|[)\s;|&])/,lookbehind:!0},keyword:{pattern:/(^|[\s;|&]|[<>]\()(?:if|then|else|elif|fi|for|while|in|case|esac|function|select|do|done|until)(?=$|[)\s;|&])/,lookbehind:!0},builtin:{pattern:/(^|[\s;|&]|[<>]\()(?:\.|:|break|cd|continue|eval|exec|exit|export|getopts|hash|pwd|readonly|return|shift|test|times|trap|umask|unset|alias|bind|builtin|caller|command|declare|echo|enable|help|let|local|logout|mapfile|printf|read|readarray|source|type|typeset|ulimit|unalias|set|shopt)(?=$|[)\s;|&])/,lookbehind:!0,alias:"class-name"},boolean:{pattern:/(^|[\s;|&]|[<>]\()(?:true|false)(?=$|[)\s;|&])/,lookbehind:!0},"file-descriptor":{pattern:/\B&\d\b/,alias:"important"},operator:{pattern:/\d?<>|>\||\+=|=[=~]?|!=?|<<[<-]?|[&\d]?>>|\d[<>]&?|[<>][&=]?|&[>&]?|\|[&|]?/,inside:{"file-descriptor":{pattern:/^\d/,alias:"important"}}},punctuation:/\$?\(\(?|\)\)?|\.\.|[{}[\];\\]/,number:{pattern:/(^|\s)(?:[1-9]\d*|0)(?:[.,]\d+)?\b/,lookbehind:!0}},n.inside=e.languages.bash;for(var o=["comment","function-name","for-or-select","assign-left","string","environment","function","keyword","builtin","boolean","file-descriptor","operator","punctuation","number"],i=r.variable[1].inside,a=0;a<o.length;a++)i[o[a]]=e.languages.bash[o[a]];e.languages.shell=e.languages.bash}(Prism)},4279:function(){Prism.languages.c=Prism.languages.extend("clike",{comment:{pattern:/\/\/(?:[^\r\n\\]|\\(?:\r\n?|\n|(?![\r\n])))*|\/\*[\s\S]*?(?:\*\/|$)/,greedy:!0},"class-name":{pattern:/(\b(?:enum|struct)\s+(?:__attribute__\s*\(\([\s\S]*?\)\)\s*)?)\w+|\b[a-z]\w*_t\b/,lookbehind:!0},keyword:/\b(?:__attribute__|_Alignas|_Alignof|_Atomic|_Bool|_Complex|_Generic|_Imaginary|_Noreturn|_Static_assert|_Thread_local|asm|typeof|inline|auto|break|case|char|const|continue|default|do|double|else|enum|extern|float|for|goto|if|int|long|register|return|short|signed|sizeof|static|struct|switch|typedef|union|unsigned|void|volatile|while)\b/,function:/\b[a-z_]\w*(?=\s*\()/i,number:/(?:\b0x(?:[\da-f]+(?:\.[\da-f]*)?|\.[\da-f]+)(?:p[+-]?\d+)?|(?:\b\d+(?:\.\d*)?|\B\.\d+)(?:e[+-]?\d+)?)[ful]{0,4}/i,operator:/>>=?|<<=?|->|([-+&|:])\1|[?:~]|[-+*/%&|^!=<>]=?/}),Prism.languages.insertBefore("c","string",{macro:{pattern:/(^[\t ]*)#\s*[a-z](?:[^\r\n\\/]|\/(?!\*)|\/\*(?:[^*]|\*(?!\/))*\*\/|\\(?:\r\n|[\s\S]))*/im,lookbehind:!0,greedy:!0,alias:"property",inside:{string:[{pattern:/^(#\s*include\s*)<[^>]+>/,lookbehind:!0},Prism.languages.c.string],comment:Prism.languages.c.comment,"macro-name":[{pattern:/(^#\s*define\s+)\w+\b(?!\()/i,lookbehind:!0},{pattern:/(^#\s*define\s+)\w+\b(?=\()/i,lookbehind:!0,alias:"function"}],directive:{pattern:/^(#\s*)[a-z]+/,lookbehind:!0,alias:"keyword"},"directive-hash":/^#/,punctuation:/##|\\(?=[\r\n])/,expression:{pattern:/\S[\s\S]*/,inside:Prism.languages.c}}},constant:/\b(?:__FILE__|__LINE__|__DATE__|__TIME__|__TIMESTAMP__|__func__|EOF|NULL|SEEK_CUR|SEEK_END|SEEK_SET|stdin|stdout|stderr)\b/}),delete Prism.languages.c.boolean},5433:function(){Prism.languages.clike={comment:[{pattern:/(^|[^\\])\/\*[\s\S]*?(?:\*\/|$)/,lookbehind:!0,greedy:!0},{pattern:/(^|[^\\:])\/\/.*/,lookbehind:!0,greedy:!0}],string:{pattern:/(["'])(?:\\(?:\r\n|[\s\S])|(?!\1)[^\\\r\n])*\1/,greedy:!0},"class-name":{pattern:/(\b(?:class|interface|extends|implements|trait|instanceof|new)\s+|\bcatch\s+\()[\w.\\]+/i,lookbehind:!0,inside:{punctuation:/[.\\]/}},keyword:/\b(?:if|else|while|do|for|return|in|instanceof|function|new|try|throw|catch|finally|null|break|continue)\b/,boolean:/\b(?:true|false)\b/,function:/\b\w+(?=\()/,number:/\b0x[\da-f]+\b|(?:\b\d+(?:\.\d*)?|\B\.\d+)(?:e[+-]?\d+)?/i,operator:/[<>]=?|[!=]=?=?|--?|\+\+?|&&?|\|\|?|[?*/~^%]/,punctuation:/[{}[\];(),.:]/}},6213:function(){!function(e){var t=/#(?!\{).+/,n={pattern:/#\{[^}]+\}/,alias:"variable"};e.languages.coffeescript=e.languages.extend("javascript",{comment:t,string:[{pattern:/'(?:\\[\s\S]|[^\\'])*'/,greedy:!0},{pattern:/"(?:\\[\s\S]|[^\\"])*"/,greedy:!0,inside:{interpolation:n}}],keyword:/\b(?:and|break|by|catch|class|continue|debugger|delete|do|each|else|extend|extends|false|finally|for|if|in|instanceof|is|isnt|let|loop|namespace|new|no|not|null|of|off|on|or|own|return|super|switch|then|this|throw|true|try|typeof|undefined|unless|until|when|while|window|with|yes|yield)\b/,"class-member":{pattern:/@(?!\d)\w+/,alias:"variable"}}),e.languages.insertBefore("coffeescript","comment",{"multiline-comment":{pattern:/###[\s\S]+?###/,alias:"comment"},"block-regex":{pattern:/\/{3}[\s\S]*?\/{3}/,alias:"regex",inside:{comment:t,interpolation:n}}}),e.languages.insertBefore("coffeescript","string",{"inline-javascript":{pattern:/`(?:\\[\s\S]|[^\\`])*`/,inside:{delimiter:{pattern:/^`|`$/,alias:"punctuation"},script:{pattern:/[\s\S]+/,alias:"language-javascript",inside:e.languages.javascript}}},"multiline-string":[{pattern:/'''[\s\S]*?'''/,greedy:!0,alias:"string"},{pattern:/"""[\s\S]*?"""/,greedy:!0,alias:"string",inside:{interpolation:n}}]}),e.languages.insertBefore("coffeescript","keyword",{property:/(?!\d)\w+(?=\s*:(?!:))/}),delete e.languages.coffeescript["template-string"],e.languages.coffee=e.languages.coffeescript}(Prism)},2731:function(){!function(e){var t=/\b(?:alignas|alignof|asm|auto|bool|break|case|catch|char|char8_t|char16_t|char32_t|class|compl|concept|const|consteval|constexpr|constinit|const_cast|continue|co_await|co_return|co_yield|decltype|default|delete|do|double|dynamic_cast|else|enum|explicit|export|extern|final|float|for|friend|goto|if|import|inline|int|int8_t|int16_t|int32_t|int64_t|uint8_t|uint16_t|uint32_t|uint64_t|long|module|mutable|namespace|new|noexcept|nullptr|operator|override|private|protected|public|register|reinterpret_cast|requires|return|short|signed|sizeof|static|static_assert|static_cast|struct|switch|template|this|thread_local|throw|try|typedef|typeid|typename|union|unsigned|using|virtual|void|volatile|wchar_t|while)\b/,n=/\b(?!<keyword>)\w+(?:\s*\.\s*\w+)*\b/.source.replace(/<keyword>/g,(function(){return t.source}));e.languages.cpp=e.languages.extend("c",{"class-name":[{pattern:RegExp(/(\b(?:class|concept|enum|struct|typename)\s+)(?!<keyword>)\w+/.source.replace(/<keyword>/g,(function(){return t.source}))),lookbehind:!0},/\b[A-Z]\w*(?=\s*::\s*\w+\s*\()/,/\b[A-Z_]\w*(?=\s*::\s*~\w+\s*\()/i,/\b\w+(?=\s*<(?:[^<>]|<(?:[^<>]|<[^<>]*>)*>)*>\s*::\s*\w+\s*\()/],keyword:t,number:{pattern:/(?:\b0b[01']+|\b0x(?:[\da-f']+(?:\.[\da-f']*)?|\.[\da-f']+)(?:p[+-]?[\d']+)?|(?:\b[\d']+(?:\.[\d']*)?|\B\.[\d']+)(?:e[+-]?[\d']+)?)[ful]{0,4}/i,greedy:!0},operator:/>>=?|<<=?|->|--|\+\+|&&|\|\||[?:~]|<=>|[-+*/%&|^!=<>]=?|\b(?:and|and_eq|bitand|bitor|not|not_eq|or|or_eq|xor|xor_eq)\b/,boolean:/\b(?:true|false)\b/}),e.languages.insertBefore("cpp","string",{module:{pattern:RegExp(/(\b(?:module|import)\s+)/.source+"(?:"+/"(?:\\(?:\r\n|[\s\S])|[^"\\\r\n])*"|<[^<>\r\n]*>/.source+"|"+/<mod-name>(?:\s*:\s*<mod-name>)?|:\s*<mod-name>/.source.replace(/<mod-name>/g,(function(){return n}))+")"),lookbehind:!0,greedy:!0,inside:{string:/^[<"][\s\S]+/,operator:/:/,punctuation:/\./}},"raw-string":{pattern:/R"([^()\\ ]{0,16})\([\s\S]*?\)\1"/,alias:"string",greedy:!0}}),e.languages.insertBefore("cpp","keyword",{"generic-function":{pattern:/\b(?!operator\b)[a-z_]\w*\s*<(?:[^<>]|<[^<>]*>)*>(?=\s*\()/i,inside:{function:/^\w+/,generic:{pattern:/<[\s\S]+/,alias:"class-name",inside:e.languages.cpp}}}}),e.languages.insertBefore("cpp","operator",{"double-colon":{pattern:/::/,alias:"punctuation"}}),e.languages.insertBefore("cpp","class-name",{"base-clause":{pattern:/(\b(?:class|struct)\s+\w+\s*:\s*)[^;{}"'\s]+(?:\s+[^;{}"'\s]+)*(?=\s*[;{])/,lookbehind:!0,greedy:!0,inside:e.languages.extend("cpp",{})}}),e.languages.insertBefore("inside","double-colon",{"class-name":/\b[a-z_]\w*\b(?!\s*::)/i},e.languages.cpp["base-clause"])}(Prism)},9016:function(){!function(e){function t(e,t){return e.replace(/<<(\d+)>>/g,(function(e,n){return"(?:"+t[+n]+")"}))}function n(e,n,r){return RegExp(t(e,n),r||"")}function r(e,t){for(var n=0;n<t;n++)e=e.replace(/<<self>>/g,(function(){return"(?:"+e+")"}));return e.replace(/<<self>>/g,"[^\\s\\S]")}var o="bool byte char decimal double dynamic float int long object sbyte short string uint ulong ushort var void",i="class enum interface record struct",a="add alias and ascending async await by descending from(?=\\s*(?:\\w|$)) get global group into init(?=\\s*;) join let nameof not notnull on or orderby partial remove select set unmanaged value when where with(?=\\s*{)",s="abstract as base break case catch checked const continue default delegate do else event explicit extern finally fixed for foreach goto if implicit in internal is lock namespace new null operator out override params private protected public readonly ref return sealed sizeof stackalloc static switch this throw try typeof unchecked unsafe using virtual volatile while yield";function l(e){return"\\b(?:"+e.trim().replace(/ /g,"|")+")\\b"}var c=l(i),u=RegExp(l(o+" "+i+" "+a+" "+s)),p=l(i+" "+a+" "+s),f=l(o+" "+i+" "+s),d=r(/<(?:[^<>;=+\-*/%&|^]|<<self>>)*>/.source,2),h=r(/\((?:[^()]|<<self>>)*\)/.source,2),m=/@?\b[A-Za-z_]\w*\b/.source,v=t(/<<0>>(?:\s*<<1>>)?/.source,[m,d]),g=t(/(?!<<0>>)<<1>>(?:\s*\.\s*<<1>>)*/.source,[p,v]),y=/\[\s*(?:,\s*)*\]/.source,b=t(/<<0>>(?:\s*(?:\?\s*)?<<1>>)*(?:\s*\?)?/.source,[g,y]),x=t(/[^,()<>[\];=+\-*/%&|^]|<<0>>|<<1>>|<<2>>/.source,[d,h,y]),w=t(/\(<<0>>+(?:,<<0>>+)+\)/.source,[x]),k=t(/(?:<<0>>|<<1>>)(?:\s*(?:\?\s*)?<<2>>)*(?:\s*\?)?/.source,[w,g,y]),E={keyword:u,punctuation:/[<>()?,.:[\]]/},S=/'(?:[^\r\n'\\]|\\.|\\[Uux][\da-fA-F]{1,8})'/.source,_=/"(?:\\.|[^\\"\r\n])*"/.source,O=/@"(?:""|\\[\s\S]|[^\\"])*"(?!")/.source;e.languages.csharp=e.languages.extend("clike",{string:[{pattern:n(/(^|[^$\\])<<0>>/.source,[O]),lookbehind:!0,greedy:!0},{pattern:n(/(^|[^@$\\])<<0>>/.source,[_]),lookbehind:!0,greedy:!0},{pattern:RegExp(S),greedy:!0,alias:"character"}],"class-name":[{pattern:n(/(\busing\s+static\s+)<<0>>(?=\s*;)/.source,[g]),lookbehind:!0,inside:E},{pattern:n(/(\busing\s+<<0>>\s*=\s*)<<1>>(?=\s*;)/.source,[m,k]),lookbehind:!0,inside:E},{pattern:n(/(\busing\s+)<<0>>(?=\s*=)/.source,[m]),lookbehind:!0},{pattern:n(/(\b<<0>>\s+)<<1>>/.source,[c,v]),lookbehind:!0,inside:E},{pattern:n(/(\bcatch\s*\(\s*)<<0>>/.source,[g]),lookbehind:!0,inside:E},{pattern:n(/(\bwhere\s+)<<0>>/.source,[m]),lookbehind:!0},{pattern:n(/(\b(?:is(?:\s+not)?|as)\s+)<<0>>/.source,[b]),lookbehind:!0,inside:E},{pattern:n(/\b<<0>>(?=\s+(?!<<1>>|with\s*\{)<<2>>(?:\s*[=,;:{)\]]|\s+(?:in|when)\b))/.source,[k,f,m]),inside:E}],keyword:u,number:/(?:\b0(?:x[\da-f_]*[\da-f]|b[01_]*[01])|(?:\B\.\d+(?:_+\d+)*|\b\d+(?:_+\d+)*(?:\.\d+(?:_+\d+)*)?)(?:e[-+]?\d+(?:_+\d+)*)?)(?:ul|lu|[dflmu])?\b/i,operator:/>>=?|<<=?|[-=]>|([-+&|])\1|~|\?\?=?|[-+*/%&|^!=<>]=?/,punctuation:/\?\.?|::|[{}[\];(),.:]/}),e.languages.insertBefore("csharp","number",{range:{pattern:/\.\./,alias:"operator"}}),e.languages.insertBefore("csharp","punctuation",{"named-parameter":{pattern:n(/([(,]\s*)<<0>>(?=\s*:)/.source,[m]),lookbehind:!0,alias:"punctuation"}}),e.languages.insertBefore("csharp","class-name",{namespace:{pattern:n(/(\b(?:namespace|using)\s+)<<0>>(?:\s*\.\s*<<0>>)*(?=\s*[;{])/.source,[m]),lookbehind:!0,inside:{punctuation:/\./}},"type-expression":{pattern:n(/(\b(?:default|typeof|sizeof)\s*\(\s*(?!\s))(?:[^()\s]|\s(?!\s)|<<0>>)*(?=\s*\))/.source,[h]),lookbehind:!0,alias:"class-name",inside:E},"return-type":{pattern:n(/<<0>>(?=\s+(?:<<1>>\s*(?:=>|[({]|\.\s*this\s*\[)|this\s*\[))/.source,[k,g]),inside:E,alias:"class-name"},"constructor-invocation":{pattern:n(/(\bnew\s+)<<0>>(?=\s*[[({])/.source,[k]),lookbehind:!0,inside:E,alias:"class-name"},"generic-method":{pattern:n(/<<0>>\s*<<1>>(?=\s*\()/.source,[m,d]),inside:{function:n(/^<<0>>/.source,[m]),generic:{pattern:RegExp(d),alias:"class-name",inside:E}}},"type-list":{pattern:n(/\b((?:<<0>>\s+<<1>>|record\s+<<1>>\s*<<5>>|where\s+<<2>>)\s*:\s*)(?:<<3>>|<<4>>|<<1>>\s*<<5>>|<<6>>)(?:\s*,\s*(?:<<3>>|<<4>>|<<6>>))*(?=\s*(?:where|[{;]|=>|$))/.source,[c,v,m,k,u.source,h,/\bnew\s*\(\s*\)/.source]),lookbehind:!0,inside:{"record-arguments":{pattern:n(/(^(?!new\s*\()<<0>>\s*)<<1>>/.source,[v,h]),lookbehind:!0,greedy:!0,inside:e.languages.csharp},keyword:u,"class-name":{pattern:RegExp(k),greedy:!0,inside:E},punctuation:/[,()]/}},preprocessor:{pattern:/(^[\t ]*)#.*/m,lookbehind:!0,alias:"property",inside:{directive:{pattern:/(#)\b(?:define|elif|else|endif|endregion|error|if|line|nullable|pragma|region|undef|warning)\b/,lookbehind:!0,alias:"keyword"}}}});var A=_+"|"+S,R=t(/\/(?![*/])|\/\/[^\r\n]*[\r\n]|\/\*(?:[^*]|\*(?!\/))*\*\/|<<0>>/.source,[A]),C=r(t(/[^"'/()]|<<0>>|\(<<self>>*\)/.source,[R]),2),T=/\b(?:assembly|event|field|method|module|param|property|return|type)\b/.source,P=t(/<<0>>(?:\s*\(<<1>>*\))?/.source,[g,C]);e.languages.insertBefore("csharp","class-name",{attribute:{pattern:n(/((?:^|[^\s\w>)?])\s*\[\s*)(?:<<0>>\s*:\s*)?<<1>>(?:\s*,\s*<<1>>)*(?=\s*\])/.source,[T,P]),lookbehind:!0,greedy:!0,inside:{target:{pattern:n(/^<<0>>(?=\s*:)/.source,[T]),alias:"keyword"},"attribute-arguments":{pattern:n(/\(<<0>>*\)/.source,[C]),inside:e.languages.csharp},"class-name":{pattern:RegExp(g),inside:{punctuation:/\./}},punctuation:/[:,]/}}});var I=/:[^}\r\n]+/.source,j=r(t(/[^"'/()]|<<0>>|\(<<self>>*\)/.source,[R]),2),L=t(/\{(?!\{)(?:(?![}:])<<0>>)*<<1>>?\}/.source,[j,I]),N=r(t(/[^"'/()]|\/(?!\*)|\/\*(?:[^*]|\*(?!\/))*\*\/|<<0>>|\(<<self>>*\)/.source,[A]),2),M=t(/\{(?!\{)(?:(?![}:])<<0>>)*<<1>>?\}/.source,[N,I]);function D(t,r){return{interpolation:{pattern:n(/((?:^|[^{])(?:\{\{)*)<<0>>/.source,[t]),lookbehind:!0,inside:{"format-string":{pattern:n(/(^\{(?:(?![}:])<<0>>)*)<<1>>(?=\}$)/.source,[r,I]),lookbehind:!0,inside:{punctuation:/^:/}},punctuation:/^\{|\}$/,expression:{pattern:/[\s\S]+/,alias:"language-csharp",inside:e.languages.csharp}}},string:/[\s\S]+/}}e.languages.insertBefore("csharp","string",{"interpolation-string":[{pattern:n(/(^|[^\\])(?:\$@|@\$)"(?:""|\\[\s\S]|\{\{|<<0>>|[^\\{"])*"/.source,[L]),lookbehind:!0,greedy:!0,inside:D(L,j)},{pattern:n(/(^|[^@\\])\$"(?:\\.|\{\{|<<0>>|[^\\"{])*"/.source,[M]),lookbehind:!0,greedy:!0,inside:D(M,N)}]})}(Prism),Prism.languages.dotnet=Prism.languages.cs=Prism.languages.csharp},7046:function(){Prism.languages.go=Prism.languages.extend("clike",{string:{pattern:/(["'`])(?:\\[\s\S]|(?!\1)[^\\])*\1/,greedy:!0},keyword:/\b(?:break|case|chan|const|continue|default|defer|else|fallthrough|for|func|go(?:to)?|if|import|interface|map|package|range|return|select|struct|switch|type|var)\b/,boolean:/\b(?:_|iota|nil|true|false)\b/,number:/(?:\b0x[a-f\d]+|(?:\b\d+(?:\.\d*)?|\B\.\d+)(?:e[-+]?\d+)?)i?/i,operator:/[*\/%^!=]=?|\+[=+]?|-[=-]?|\|[=|]?|&(?:=|&|\^=?)?|>(?:>=?|=)?|<(?:<=?|=|-)?|:=|\.\.\./,builtin:/\b(?:bool|byte|complex(?:64|128)|error|float(?:32|64)|rune|string|u?int(?:8|16|32|64)?|uintptr|append|cap|close|complex|copy|delete|imag|len|make|new|panic|print(?:ln)?|real|recover)\b/}),delete Prism.languages.go["class-name"]},57:function(){!function(e){e.languages.http={"request-line":{pattern:/^(?:GET|HEAD|POST|PUT|DELETE|CONNECT|OPTIONS|TRACE|PATCH|PRI|SEARCH)\s(?:https?:\/\/|\/)\S*\sHTTP\/[0-9.]+/m,inside:{method:{pattern:/^[A-Z]+\b/,alias:"property"},"request-target":{pattern:/^(\s)(?:https?:\/\/|\/)\S*(?=\s)/,lookbehind:!0,alias:"url",inside:e.languages.uri},"http-version":{pattern:/^(\s)HTTP\/[0-9.]+/,lookbehind:!0,alias:"property"}}},"response-status":{pattern:/^HTTP\/[0-9.]+ \d+ .+/m,inside:{"http-version":{pattern:/^HTTP\/[0-9.]+/,alias:"property"},"status-code":{pattern:/^(\s)\d+(?=\s)/,lookbehind:!0,alias:"number"},"reason-phrase":{pattern:/^(\s).+/,lookbehind:!0,alias:"string"}}},"header-name":{pattern:/^[\w-]+:(?=.)/m,alias:"keyword"}};var t,n=e.languages,r={"application/javascript":n.javascript,"application/json":n.json||n.javascript,"application/xml":n.xml,"text/xml":n.xml,"text/html":n.html,"text/css":n.css},o={"application/json":!0,"application/xml":!0};function i(e){var t=e.replace(/^[a-z]+\//,"");return"(?:"+e+"|\\w+/(?:[\\w.-]+\\+)+"+t+"(?![+\\w.-]))"}for(var a in r)if(r[a]){t=t||{};var s=o[a]?i(a):a;t[a.replace(/\//g,"-")]={pattern:RegExp("(content-type:\\s*"+s+"(?:(?:\\r\\n?|\\n).+)*)(?:\\r?\\n|\\r){2}[\\s\\S]*","i"),lookbehind:!0,inside:r[a]}}t&&e.languages.insertBefore("http","header-name",t)}(Prism)},2503:function(){!function(e){var t=/\b(?:abstract|assert|boolean|break|byte|case|catch|char|class|const|continue|default|do|double|else|enum|exports|extends|final|finally|float|for|goto|if|implements|import|instanceof|int|interface|long|module|native|new|non-sealed|null|open|opens|package|permits|private|protected|provides|public|record|requires|return|sealed|short|static|strictfp|super|switch|synchronized|this|throw|throws|to|transient|transitive|try|uses|var|void|volatile|while|with|yield)\b/,n=/(^|[^\w.])(?:[a-z]\w*\s*\.\s*)*(?:[A-Z]\w*\s*\.\s*)*/.source,r={pattern:RegExp(n+/[A-Z](?:[\d_A-Z]*[a-z]\w*)?\b/.source),lookbehind:!0,inside:{namespace:{pattern:/^[a-z]\w*(?:\s*\.\s*[a-z]\w*)*(?:\s*\.)?/,inside:{punctuation:/\./}},punctuation:/\./}};e.languages.java=e.languages.extend("clike",{"class-name":[r,{pattern:RegExp(n+/[A-Z]\w*(?=\s+\w+\s*[;,=()])/.source),lookbehind:!0,inside:r.inside}],keyword:t,function:[e.languages.clike.function,{pattern:/(::\s*)[a-z_]\w*/,lookbehind:!0}],number:/\b0b[01][01_]*L?\b|\b0x(?:\.[\da-f_p+-]+|[\da-f_]+(?:\.[\da-f_p+-]+)?)\b|(?:\b\d[\d_]*(?:\.[\d_]*)?|\B\.\d[\d_]*)(?:e[+-]?\d[\d_]*)?[dfl]?/i,operator:{pattern:/(^|[^.])(?:<<=?|>>>?=?|->|--|\+\+|&&|\|\||::|[?:~]|[-+*/%&|^!=<>]=?)/m,lookbehind:!0}}),e.languages.insertBefore("java","string",{"triple-quoted-string":{pattern:/"""[ \t]*[\r\n](?:(?:"|"")?(?:\\.|[^"\\]))*"""/,greedy:!0,alias:"string"}}),e.languages.insertBefore("java","class-name",{annotation:{pattern:/(^|[^.])@\w+(?:\s*\.\s*\w+)*/,lookbehind:!0,alias:"punctuation"},generics:{pattern:/<(?:[\w\s,.?]|&(?!&)|<(?:[\w\s,.?]|&(?!&)|<(?:[\w\s,.?]|&(?!&)|<(?:[\w\s,.?]|&(?!&))*>)*>)*>)*>/,inside:{"class-name":r,keyword:t,punctuation:/[<>(),.:]/,operator:/[?&|]/}},namespace:{pattern:RegExp(/(\b(?:exports|import(?:\s+static)?|module|open|opens|package|provides|requires|to|transitive|uses|with)\s+)(?!<keyword>)[a-z]\w*(?:\.[a-z]\w*)*\.?/.source.replace(/<keyword>/g,(function(){return t.source}))),lookbehind:!0,inside:{punctuation:/\./}}})}(Prism)},6841:function(){Prism.languages.lua={comment:/^#!.+|--(?:\[(=*)\[[\s\S]*?\]\1\]|.*)/m,string:{pattern:/(["'])(?:(?!\1)[^\\\r\n]|\\z(?:\r\n|\s)|\\(?:\r\n|[^z]))*\1|\[(=*)\[[\s\S]*?\]\2\]/,greedy:!0},number:/\b0x[a-f\d]+(?:\.[a-f\d]*)?(?:p[+-]?\d+)?\b|\b\d+(?:\.\B|(?:\.\d*)?(?:e[+-]?\d+)?\b)|\B\.\d+(?:e[+-]?\d+)?\b/i,keyword:/\b(?:and|break|do|else|elseif|end|false|for|function|goto|if|in|local|nil|not|or|repeat|return|then|true|until|while)\b/,function:/(?!\d)\w+(?=\s*(?:[({]))/,operator:[/[-+*%^&|#]|\/\/?|<[<=]?|>[>=]?|[=~]=?/,{pattern:/(^|[^.])\.\.(?!\.)/,lookbehind:!0}],punctuation:/[\[\](){},;]|\.+|:+/}},6854:function(){!function(e){function t(e,t){return"___"+e.toUpperCase()+t+"___"}Object.defineProperties(e.languages["markup-templating"]={},{buildPlaceholders:{value:function(n,r,o,i){if(n.language===r){var a=n.tokenStack=[];n.code=n.code.replace(o,(function(e){if("function"==typeof i&&!i(e))return e;for(var o,s=a.length;-1!==n.code.indexOf(o=t(r,s));)++s;return a[s]=e,o})),n.grammar=e.languages.markup}}},tokenizePlaceholders:{value:function(n,r){if(n.language===r&&n.tokenStack){n.grammar=e.languages[r];var o=0,i=Object.keys(n.tokenStack);!function a(s){for(var l=0;l<s.length&&!(o>=i.length);l++){var c=s[l];if("string"==typeof c||c.content&&"string"==typeof c.content){var u=i[o],p=n.tokenStack[u],f="string"==typeof c?c:c.content,d=t(r,u),h=f.indexOf(d);if(h>-1){++o;var m=f.substring(0,h),v=new e.Token(r,e.tokenize(p,n.grammar),"language-"+r,p),g=f.substring(h+d.length),y=[];m&&y.push.apply(y,a([m])),y.push(v),g&&y.push.apply(y,a([g])),"string"==typeof c?s.splice.apply(s,[l,1].concat(y)):c.content=y}}else c.content&&a(c.content)}return s}(n.tokens)}}}})}(Prism)},4335:function(){Prism.languages.markup={comment:{pattern:/<!--(?:(?!<!--)[\s\S])*?-->/,greedy:!0},prolog:{pattern:/<\?[\s\S]+?\?>/,greedy:!0},doctype:{pattern:/<!DOCTYPE(?:[^>"'[\]]|"[^"]*"|'[^']*')+(?:\[(?:[^<"'\]]|"[^"]*"|'[^']*'|<(?!!--)|<!--(?:[^-]|-(?!->))*-->)*\]\s*)?>/i,greedy:!0,inside:{"internal-subset":{pattern:/(^[^\[]*\[)[\s\S]+(?=\]>$)/,lookbehind:!0,greedy:!0,inside:null},string:{pattern:/"[^"]*"|'[^']*'/,greedy:!0},punctuation:/^<!|>$|[[\]]/,"doctype-tag":/^DOCTYPE/i,name:/[^\s<>'"]+/}},cdata:{pattern:/<!\[CDATA\[[\s\S]*?\]\]>/i,greedy:!0},tag:{pattern:/<\/?(?!\d)[^\s>\/=$<%]+(?:\s(?:\s*[^\s>\/=]+(?:\s*=\s*(?:"[^"]*"|'[^']*'|[^\s'">=]+(?=[\s>]))|(?=[\s/>])))+)?\s*\/?>/,greedy:!0,inside:{tag:{pattern:/^<\/?[^\s>\/]+/,inside:{punctuation:/^<\/?/,namespace:/^[^\s>\/:]+:/}},"special-attr":[],"attr-value":{pattern:/=\s*(?:"[^"]*"|'[^']*'|[^\s'">=]+)/,inside:{punctuation:[{pattern:/^=/,alias:"attr-equals"},/"|'/]}},punctuation:/\/?>/,"attr-name":{pattern:/[^\s>\/]+/,inside:{namespace:/^[^\s>\/:]+:/}}}},entity:[{pattern:/&[\da-z]{1,8};/i,alias:"named-entity"},/&#x?[\da-f]{1,8};/i]},Prism.languages.markup.tag.inside["attr-value"].inside.entity=Prism.languages.markup.entity,Prism.languages.markup.doctype.inside["internal-subset"].inside=Prism.languages.markup,Prism.hooks.add("wrap",(function(e){"entity"===e.type&&(e.attributes.title=e.content.replace(/&amp;/,"&"))})),Object.defineProperty(Prism.languages.markup.tag,"addInlined",{value:function(e,t){var n={};n["language-"+t]={pattern:/(^<!\[CDATA\[)[\s\S]+?(?=\]\]>$)/i,lookbehind:!0,inside:Prism.languages[t]},n.cdata=/^<!\[CDATA\[|\]\]>$/i;var r={"included-cdata":{pattern:/<!\[CDATA\[[\s\S]*?\]\]>/i,inside:n}};r["language-"+t]={pattern:/[\s\S]+/,inside:Prism.languages[t]};var o={};o[e]={pattern:RegExp(/(<__[^>]*>)(?:<!\[CDATA\[(?:[^\]]|\](?!\]>))*\]\]>|(?!<!\[CDATA\[)[\s\S])*?(?=<\/__>)/.source.replace(/__/g,(function(){return e})),"i"),lookbehind:!0,greedy:!0,inside:r},Prism.languages.insertBefore("markup","cdata",o)}}),Object.defineProperty(Prism.languages.markup.tag,"addAttribute",{value:function(e,t){Prism.languages.markup.tag.inside["special-attr"].push({pattern:RegExp(/(^|["'\s])/.source+"(?:"+e+")"+/\s*=\s*(?:"[^"]*"|'[^']*'|[^\s'">=]+(?=[\s>]))/.source,"i"),lookbehind:!0,inside:{"attr-name":/^[^\s=]+/,"attr-value":{pattern:/=[\s\S]+/,inside:{value:{pattern:/(^=\s*(["']|(?!["'])))\S[\s\S]*(?=\2$)/,lookbehind:!0,alias:[t,"language-"+t],inside:Prism.languages[t]},punctuation:[{pattern:/^=/,alias:"attr-equals"},/"|'/]}}}})}}),Prism.languages.html=Prism.languages.markup,Prism.languages.mathml=Prism.languages.markup,Prism.languages.svg=Prism.languages.markup,Prism.languages.xml=Prism.languages.extend("markup",{}),Prism.languages.ssml=Prism.languages.xml,Prism.languages.atom=Prism.languages.xml,Prism.languages.rss=Prism.languages.xml},1426:function(){Prism.languages.objectivec=Prism.languages.extend("c",{string:/("|')(?:\\(?:\r\n|[\s\S])|(?!\1)[^\\\r\n])*\1|@"(?:\\(?:\r\n|[\s\S])|[^"\\\r\n])*"/,keyword:/\b(?:asm|typeof|inline|auto|break|case|char|const|continue|default|do|double|else|enum|extern|float|for|goto|if|int|long|register|return|short|signed|sizeof|static|struct|switch|typedef|union|unsigned|void|volatile|while|in|self|super)\b|(?:@interface|@end|@implementation|@protocol|@class|@public|@protected|@private|@property|@try|@catch|@finally|@throw|@synthesize|@dynamic|@selector)\b/,operator:/-[->]?|\+\+?|!=?|<<?=?|>>?=?|==?|&&?|\|\|?|[~^%?*\/@]/}),delete Prism.languages.objectivec["class-name"],Prism.languages.objc=Prism.languages.objectivec},8246:function(){Prism.languages.perl={comment:[{pattern:/(^\s*)=\w[\s\S]*?=cut.*/m,lookbehind:!0},{pattern:/(^|[^\\$])#.*/,lookbehind:!0}],string:[{pattern:/\b(?:q|qq|qx|qw)\s*([^a-zA-Z0-9\s{(\[<])(?:(?!\1)[^\\]|\\[\s\S])*\1/,greedy:!0},{pattern:/\b(?:q|qq|qx|qw)\s+([a-zA-Z0-9])(?:(?!\1)[^\\]|\\[\s\S])*\1/,greedy:!0},{pattern:/\b(?:q|qq|qx|qw)\s*\((?:[^()\\]|\\[\s\S])*\)/,greedy:!0},{pattern:/\b(?:q|qq|qx|qw)\s*\{(?:[^{}\\]|\\[\s\S])*\}/,greedy:!0},{pattern:/\b(?:q|qq|qx|qw)\s*\[(?:[^[\]\\]|\\[\s\S])*\]/,greedy:!0},{pattern:/\b(?:q|qq|qx|qw)\s*<(?:[^<>\\]|\\[\s\S])*>/,greedy:!0},{pattern:/("|`)(?:(?!\1)[^\\]|\\[\s\S])*\1/,greedy:!0},{pattern:/'(?:[^'\\\r\n]|\\.)*'/,greedy:!0}],regex:[{pattern:/\b(?:m|qr)\s*([^a-zA-Z0-9\s{(\[<])(?:(?!\1)[^\\]|\\[\s\S])*\1[msixpodualngc]*/,greedy:!0},{pattern:/\b(?:m|qr)\s+([a-zA-Z0-9])(?:(?!\1)[^\\]|\\[\s\S])*\1[msixpodualngc]*/,greedy:!0},{pattern:/\b(?:m|qr)\s*\((?:[^()\\]|\\[\s\S])*\)[msixpodualngc]*/,greedy:!0},{pattern:/\b(?:m|qr)\s*\{(?:[^{}\\]|\\[\s\S])*\}[msixpodualngc]*/,greedy:!0},{pattern:/\b(?:m|qr)\s*\[(?:[^[\]\\]|\\[\s\S])*\][msixpodualngc]*/,greedy:!0},{pattern:/\b(?:m|qr)\s*<(?:[^<>\\]|\\[\s\S])*>[msixpodualngc]*/,greedy:!0},{pattern:/(^|[^-]\b)(?:s|tr|y)\s*([^a-zA-Z0-9\s{(\[<])(?:(?!\2)[^\\]|\\[\s\S])*\2(?:(?!\2)[^\\]|\\[\s\S])*\2[msixpodualngcer]*/,lookbehind:!0,greedy:!0},{pattern:/(^|[^-]\b)(?:s|tr|y)\s+([a-zA-Z0-9])(?:(?!\2)[^\\]|\\[\s\S])*\2(?:(?!\2)[^\\]|\\[\s\S])*\2[msixpodualngcer]*/,lookbehind:!0,greedy:!0},{pattern:/(^|[^-]\b)(?:s|tr|y)\s*\((?:[^()\\]|\\[\s\S])*\)\s*\((?:[^()\\]|\\[\s\S])*\)[msixpodualngcer]*/,lookbehind:!0,greedy:!0},{pattern:/(^|[^-]\b)(?:s|tr|y)\s*\{(?:[^{}\\]|\\[\s\S])*\}\s*\{(?:[^{}\\]|\\[\s\S])*\}[msixpodualngcer]*/,lookbehind:!0,greedy:!0},{pattern:/(^|[^-]\b)(?:s|tr|y)\s*\[(?:[^[\]\\]|\\[\s\S])*\]\s*\[(?:[^[\]\\]|\\[\s\S])*\][msixpodualngcer]*/,lookbehind:!0,greedy:!0},{pattern:/(^|[^-]\b)(?:s|tr|y)\s*<(?:[^<>\\]|\\[\s\S])*>\s*<(?:[^<>\\]|\\[\s\S])*>[msixpodualngcer]*/,lookbehind:!0,greedy:!0},{pattern:/\/(?:[^\/\\\r\n]|\\.)*\/[msixpodualngc]*(?=\s*(?:$|[\r\n,.;})&|\-+*~<>!?^]|(?:lt|gt|le|ge|eq|ne|cmp|not|and|or|xor|x)\b))/,greedy:!0}],variable:[/[&*$@%]\{\^[A-Z]+\}/,/[&*$@%]\^[A-Z_]/,/[&*$@%]#?(?=\{)/,/[&*$@%]#?(?:(?:::)*'?(?!\d)[\w$]+(?![\w$]))+(?:::)*/i,/[&*$@%]\d+/,/(?!%=)[$@%][!"#$%&'()*+,\-.\/:;<=>?@[\\\]^_`{|}~]/],filehandle:{pattern:/<(?![<=])\S*>|\b_\b/,alias:"symbol"},vstring:{pattern:/v\d+(?:\.\d+)*|\d+(?:\.\d+){2,}/,alias:"string"},function:{pattern:/sub \w+/i,inside:{keyword:/sub/}},keyword:/\b(?:any|break|continue|default|delete|die|do|else|elsif|eval|for|foreach|given|goto|if|last|local|my|next|our|package|print|redo|require|return|say|state|sub|switch|undef|unless|until|use|when|while)\b/,number:/\b(?:0x[\dA-Fa-f](?:_?[\dA-Fa-f])*|0b[01](?:_?[01])*|(?:(?:\d(?:_?\d)*)?\.)?\d(?:_?\d)*(?:[Ee][+-]?\d+)?)\b/,operator:/-[rwxoRWXOezsfdlpSbctugkTBMAC]\b|\+[+=]?|-[-=>]?|\*\*?=?|\/\/?=?|=[=~>]?|~[~=]?|\|\|?=?|&&?=?|<(?:=>?|<=?)?|>>?=?|![~=]?|[%^]=?|\.(?:=|\.\.?)?|[\\?]|\bx(?:=|\b)|\b(?:lt|gt|le|ge|eq|ne|cmp|not|and|or|xor)\b/,punctuation:/[{}[\];(),:]/}},9945:function(){!function(e){var t=/\/\*[\s\S]*?\*\/|\/\/.*|#(?!\[).*/,n=[{pattern:/\b(?:false|true)\b/i,alias:"boolean"},{pattern:/(::\s*)\b[a-z_]\w*\b(?!\s*\()/i,greedy:!0,lookbehind:!0},{pattern:/(\b(?:case|const)\s+)\b[a-z_]\w*(?=\s*[;=])/i,greedy:!0,lookbehind:!0},/\b(?:null)\b/i,/\b[A-Z_][A-Z0-9_]*\b(?!\s*\()/],r=/\b0b[01]+(?:_[01]+)*\b|\b0o[0-7]+(?:_[0-7]+)*\b|\b0x[\da-f]+(?:_[\da-f]+)*\b|(?:\b\d+(?:_\d+)*\.?(?:\d+(?:_\d+)*)?|\B\.\d+)(?:e[+-]?\d+)?/i,o=/<?=>|\?\?=?|\.{3}|\??->|[!=]=?=?|::|\*\*=?|--|\+\+|&&|\|\||<<|>>|[?~]|[/^|%*&<>.+-]=?/,i=/[{}\[\](),:;]/;e.languages.php={delimiter:{pattern:/\?>$|^<\?(?:php(?=\s)|=)?/i,alias:"important"},comment:t,variable:/\$+(?:\w+\b|(?=\{))/i,package:{pattern:/(namespace\s+|use\s+(?:function\s+)?)(?:\\?\b[a-z_]\w*)+\b(?!\\)/i,lookbehind:!0,inside:{punctuation:/\\/}},"class-name-definition":{pattern:/(\b(?:class|enum|interface|trait)\s+)\b[a-z_]\w*(?!\\)\b/i,lookbehind:!0,alias:"class-name"},"function-definition":{pattern:/(\bfunction\s+)[a-z_]\w*(?=\s*\()/i,lookbehind:!0,alias:"function"},keyword:[{pattern:/(\(\s*)\b(?:bool|boolean|int|integer|float|string|object|array)\b(?=\s*\))/i,alias:"type-casting",greedy:!0,lookbehind:!0},{pattern:/([(,?]\s*)\b(?:bool|int|float|string|object|array(?!\s*\()|mixed|self|static|callable|iterable|(?:null|false)(?=\s*\|))\b(?=\s*\$)/i,alias:"type-hint",greedy:!0,lookbehind:!0},{pattern:/([(,?]\s*[\w|]\|\s*)(?:null|false)\b(?=\s*\$)/i,alias:"type-hint",greedy:!0,lookbehind:!0},{pattern:/(\)\s*:\s*(?:\?\s*)?)\b(?:bool|int|float|string|object|void|array(?!\s*\()|mixed|self|static|callable|iterable|(?:null|false)(?=\s*\|))\b/i,alias:"return-type",greedy:!0,lookbehind:!0},{pattern:/(\)\s*:\s*(?:\?\s*)?[\w|]\|\s*)(?:null|false)\b/i,alias:"return-type",greedy:!0,lookbehind:!0},{pattern:/\b(?:bool|int|float|string|object|void|array(?!\s*\()|mixed|iterable|(?:null|false)(?=\s*\|))\b/i,alias:"type-declaration",greedy:!0},{pattern:/(\|\s*)(?:null|false)\b/i,alias:"type-declaration",greedy:!0,lookbehind:!0},{pattern:/\b(?:parent|self|static)(?=\s*::)/i,alias:"static-context",greedy:!0},{pattern:/(\byield\s+)from\b/i,lookbehind:!0},/\bclass\b/i,{pattern:/((?:^|[^\s>:]|(?:^|[^-])>|(?:^|[^:]):)\s*)\b(?:__halt_compiler|abstract|and|array|as|break|callable|case|catch|clone|const|continue|declare|default|die|do|echo|else|elseif|empty|enddeclare|endfor|endforeach|endif|endswitch|endwhile|enum|eval|exit|extends|final|finally|fn|for|foreach|function|global|goto|if|implements|include|include_once|instanceof|insteadof|interface|isset|list|namespace|match|new|or|parent|print|private|protected|public|require|require_once|return|self|static|switch|throw|trait|try|unset|use|var|while|xor|yield)\b/i,lookbehind:!0}],"argument-name":{pattern:/([(,]\s+)\b[a-z_]\w*(?=\s*:(?!:))/i,lookbehind:!0},"class-name":[{pattern:/(\b(?:extends|implements|instanceof|new(?!\s+self|\s+static))\s+|\bcatch\s*\()\b[a-z_]\w*(?!\\)\b/i,greedy:!0,lookbehind:!0},{pattern:/(\|\s*)\b[a-z_]\w*(?!\\)\b/i,greedy:!0,lookbehind:!0},{pattern:/\b[a-z_]\w*(?!\\)\b(?=\s*\|)/i,greedy:!0},{pattern:/(\|\s*)(?:\\?\b[a-z_]\w*)+\b/i,alias:"class-name-fully-qualified",greedy:!0,lookbehind:!0,inside:{punctuation:/\\/}},{pattern:/(?:\\?\b[a-z_]\w*)+\b(?=\s*\|)/i,alias:"class-name-fully-qualified",greedy:!0,inside:{punctuation:/\\/}},{pattern:/(\b(?:extends|implements|instanceof|new(?!\s+self\b|\s+static\b))\s+|\bcatch\s*\()(?:\\?\b[a-z_]\w*)+\b(?!\\)/i,alias:"class-name-fully-qualified",greedy:!0,lookbehind:!0,inside:{punctuation:/\\/}},{pattern:/\b[a-z_]\w*(?=\s*\$)/i,alias:"type-declaration",greedy:!0},{pattern:/(?:\\?\b[a-z_]\w*)+(?=\s*\$)/i,alias:["class-name-fully-qualified","type-declaration"],greedy:!0,inside:{punctuation:/\\/}},{pattern:/\b[a-z_]\w*(?=\s*::)/i,alias:"static-context",greedy:!0},{pattern:/(?:\\?\b[a-z_]\w*)+(?=\s*::)/i,alias:["class-name-fully-qualified","static-context"],greedy:!0,inside:{punctuation:/\\/}},{pattern:/([(,?]\s*)[a-z_]\w*(?=\s*\$)/i,alias:"type-hint",greedy:!0,lookbehind:!0},{pattern:/([(,?]\s*)(?:\\?\b[a-z_]\w*)+(?=\s*\$)/i,alias:["class-name-fully-qualified","type-hint"],greedy:!0,lookbehind:!0,inside:{punctuation:/\\/}},{pattern:/(\)\s*:\s*(?:\?\s*)?)\b[a-z_]\w*(?!\\)\b/i,alias:"return-type",greedy:!0,lookbehind:!0},{pattern:/(\)\s*:\s*(?:\?\s*)?)(?:\\?\b[a-z_]\w*)+\b(?!\\)/i,alias:["class-name-fully-qualified","return-type"],greedy:!0,lookbehind:!0,inside:{punctuation:/\\/}}],constant:n,function:{pattern:/(^|[^\\\w])\\?[a-z_](?:[\w\\]*\w)?(?=\s*\()/i,lookbehind:!0,inside:{punctuation:/\\/}},property:{pattern:/(->\s*)\w+/,lookbehind:!0},number:r,operator:o,punctuation:i};var a={pattern:/\{\$(?:\{(?:\{[^{}]+\}|[^{}]+)\}|[^{}])+\}|(^|[^\\{])\$+(?:\w+(?:\[[^\r\n\[\]]+\]|->\w+)?)/,lookbehind:!0,inside:e.languages.php},s=[{pattern:/<<<'([^']+)'[\r\n](?:.*[\r\n])*?\1;/,alias:"nowdoc-string",greedy:!0,inside:{delimiter:{pattern:/^<<<'[^']+'|[a-z_]\w*;$/i,alias:"symbol",inside:{punctuation:/^<<<'?|[';]$/}}}},{pattern:/<<<(?:"([^"]+)"[\r\n](?:.*[\r\n])*?\1;|([a-z_]\w*)[\r\n](?:.*[\r\n])*?\2;)/i,alias:"heredoc-string",greedy:!0,inside:{delimiter:{pattern:/^<<<(?:"[^"]+"|[a-z_]\w*)|[a-z_]\w*;$/i,alias:"symbol",inside:{punctuation:/^<<<"?|[";]$/}},interpolation:a}},{pattern:/`(?:\\[\s\S]|[^\\`])*`/,alias:"backtick-quoted-string",greedy:!0},{pattern:/'(?:\\[\s\S]|[^\\'])*'/,alias:"single-quoted-string",greedy:!0},{pattern:/"(?:\\[\s\S]|[^\\"])*"/,alias:"double-quoted-string",greedy:!0,inside:{interpolation:a}}];e.languages.insertBefore("php","variable",{string:s,attribute:{pattern:/#\[(?:[^"'\/#]|\/(?![*/])|\/\/.*$|#(?!\[).*$|\/\*(?:[^*]|\*(?!\/))*\*\/|"(?:\\[\s\S]|[^\\"])*"|'(?:\\[\s\S]|[^\\'])*')+\](?=\s*[a-z$#])/im,greedy:!0,inside:{"attribute-content":{pattern:/^(#\[)[\s\S]+(?=\]$)/,lookbehind:!0,inside:{comment:t,string:s,"attribute-class-name":[{pattern:/([^:]|^)\b[a-z_]\w*(?!\\)\b/i,alias:"class-name",greedy:!0,lookbehind:!0},{pattern:/([^:]|^)(?:\\?\b[a-z_]\w*)+/i,alias:["class-name","class-name-fully-qualified"],greedy:!0,lookbehind:!0,inside:{punctuation:/\\/}}],constant:n,number:r,operator:o,punctuation:i}},delimiter:{pattern:/^#\[|\]$/,alias:"punctuation"}}}}),e.hooks.add("before-tokenize",(function(t){/<\?/.test(t.code)&&e.languages["markup-templating"].buildPlaceholders(t,"php",/<\?(?:[^"'/#]|\/(?![*/])|("|')(?:\\[\s\S]|(?!\1)[^\\])*\1|(?:\/\/|#(?!\[))(?:[^?\n\r]|\?(?!>))*(?=$|\?>|[\r\n])|#\[|\/\*(?:[^*]|\*(?!\/))*(?:\*\/|$))*?(?:\?>|$)/gi)})),e.hooks.add("after-tokenize",(function(t){e.languages["markup-templating"].tokenizePlaceholders(t,"php")}))}(Prism)},366:function(){Prism.languages.python={comment:{pattern:/(^|[^\\])#.*/,lookbehind:!0},"string-interpolation":{pattern:/(?:f|rf|fr)(?:("""|''')[\s\S]*?\1|("|')(?:\\.|(?!\2)[^\\\r\n])*\2)/i,greedy:!0,inside:{interpolation:{pattern:/((?:^|[^{])(?:\{\{)*)\{(?!\{)(?:[^{}]|\{(?!\{)(?:[^{}]|\{(?!\{)(?:[^{}])+\})+\})+\}/,lookbehind:!0,inside:{"format-spec":{pattern:/(:)[^:(){}]+(?=\}$)/,lookbehind:!0},"conversion-option":{pattern:/![sra](?=[:}]$)/,alias:"punctuation"},rest:null}},string:/[\s\S]+/}},"triple-quoted-string":{pattern:/(?:[rub]|rb|br)?("""|''')[\s\S]*?\1/i,greedy:!0,alias:"string"},string:{pattern:/(?:[rub]|rb|br)?("|')(?:\\.|(?!\1)[^\\\r\n])*\1/i,greedy:!0},function:{pattern:/((?:^|\s)def[ \t]+)[a-zA-Z_]\w*(?=\s*\()/g,lookbehind:!0},"class-name":{pattern:/(\bclass\s+)\w+/i,lookbehind:!0},decorator:{pattern:/(^[\t ]*)@\w+(?:\.\w+)*/im,lookbehind:!0,alias:["annotation","punctuation"],inside:{punctuation:/\./}},keyword:/\b(?:and|as|assert|async|await|break|class|continue|def|del|elif|else|except|exec|finally|for|from|global|if|import|in|is|lambda|nonlocal|not|or|pass|print|raise|return|try|while|with|yield)\b/,builtin:/\b(?:__import__|abs|all|any|apply|ascii|basestring|bin|bool|buffer|bytearray|bytes|callable|chr|classmethod|cmp|coerce|compile|complex|delattr|dict|dir|divmod|enumerate|eval|execfile|file|filter|float|format|frozenset|getattr|globals|hasattr|hash|help|hex|id|input|int|intern|isinstance|issubclass|iter|len|list|locals|long|map|max|memoryview|min|next|object|oct|open|ord|pow|property|range|raw_input|reduce|reload|repr|reversed|round|set|setattr|slice|sorted|staticmethod|str|sum|super|tuple|type|unichr|unicode|vars|xrange|zip)\b/,boolean:/\b(?:True|False|None)\b/,number:/\b0(?:b(?:_?[01])+|o(?:_?[0-7])+|x(?:_?[a-f0-9])+)\b|(?:\b\d+(?:_\d+)*(?:\.(?:\d+(?:_\d+)*)?)?|\B\.\d+(?:_\d+)*)(?:e[+-]?\d+(?:_\d+)*)?j?\b/i,operator:/[-+%=]=?|!=|\*\*?=?|\/\/?=?|<[<=>]?|>[=>]?|[&|^~]/,punctuation:/[{}[\];(),.:]/},Prism.languages.python["string-interpolation"].inside.interpolation.inside.rest=Prism.languages.python,Prism.languages.py=Prism.languages.python},2939:function(){Prism.languages.q={string:/"(?:\\.|[^"\\\r\n])*"/,comment:[{pattern:/([\t )\]}])\/.*/,lookbehind:!0,greedy:!0},{pattern:/(^|\r?\n|\r)\/[\t ]*(?:(?:\r?\n|\r)(?:.*(?:\r?\n|\r(?!\n)))*?(?:\\(?=[\t ]*(?:\r?\n|\r))|$)|\S.*)/,lookbehind:!0,greedy:!0},{pattern:/^\\[\t ]*(?:\r?\n|\r)[\s\S]+/m,greedy:!0},{pattern:/^#!.+/m,greedy:!0}],symbol:/`(?::\S+|[\w.]*)/,datetime:{pattern:/0N[mdzuvt]|0W[dtz]|\d{4}\.\d\d(?:m|\.\d\d(?:T(?:\d\d(?::\d\d(?::\d\d(?:[.:]\d\d\d)?)?)?)?)?[dz]?)|\d\d:\d\d(?::\d\d(?:[.:]\d\d\d)?)?[uvt]?/,alias:"number"},number:/\b(?![01]:)(?:0[wn]|0W[hj]?|0N[hje]?|0x[\da-fA-F]+|\d+(?:\.\d*)?(?:e[+-]?\d+)?[hjfeb]?)/,keyword:/\\\w+\b|\b(?:abs|acos|aj0?|all|and|any|asc|asin|asof|atan|attr|avgs?|binr?|by|ceiling|cols|cor|cos|count|cov|cross|csv|cut|delete|deltas|desc|dev|differ|distinct|div|do|dsave|ej|enlist|eval|except|exec|exit|exp|fby|fills|first|fkeys|flip|floor|from|get|getenv|group|gtime|hclose|hcount|hdel|hopen|hsym|iasc|identity|idesc|if|ij|in|insert|inter|inv|keys?|last|like|list|ljf?|load|log|lower|lsq|ltime|ltrim|mavg|maxs?|mcount|md5|mdev|med|meta|mins?|mmax|mmin|mmu|mod|msum|neg|next|not|null|or|over|parse|peach|pj|plist|prds?|prev|prior|rand|rank|ratios|raze|read0|read1|reciprocal|reval|reverse|rload|rotate|rsave|rtrim|save|scan|scov|sdev|select|set|setenv|show|signum|sin|sqrt|ssr?|string|sublist|sums?|sv|svar|system|tables|tan|til|trim|txf|type|uj|ungroup|union|update|upper|upsert|value|var|views?|vs|wavg|where|while|within|wj1?|wsum|ww|xasc|xbar|xcols?|xdesc|xexp|xgroup|xkey|xlog|xprev|xrank)\b/,adverb:{pattern:/['\/\\]:?|\beach\b/,alias:"function"},verb:{pattern:/(?:\B\.\B|\b[01]:|<[=>]?|>=?|[:+\-*%,!?~=|$&#@^]):?|\b_\b:?/,alias:"operator"},punctuation:/[(){}\[\];.]/}},9385:function(){!function(e){e.languages.ruby=e.languages.extend("clike",{comment:[/#.*/,{pattern:/^=begin\s[\s\S]*?^=end/m,greedy:!0}],"class-name":{pattern:/(\b(?:class)\s+|\bcatch\s+\()[\w.\\]+/i,lookbehind:!0,inside:{punctuation:/[.\\]/}},keyword:/\b(?:alias|and|BEGIN|begin|break|case|class|def|define_method|defined|do|each|else|elsif|END|end|ensure|extend|for|if|in|include|module|new|next|nil|not|or|prepend|protected|private|public|raise|redo|require|rescue|retry|return|self|super|then|throw|undef|unless|until|when|while|yield)\b/});var t={pattern:/#\{[^}]+\}/,inside:{delimiter:{pattern:/^#\{|\}$/,alias:"tag"},rest:e.languages.ruby}};delete e.languages.ruby.function,e.languages.insertBefore("ruby","keyword",{regex:[{pattern:RegExp(/%r/.source+"(?:"+[/([^a-zA-Z0-9\s{(\[<])(?:(?!\1)[^\\]|\\[\s\S])*\1/.source,/\((?:[^()\\]|\\[\s\S])*\)/.source,/\{(?:[^#{}\\]|#(?:\{[^}]+\})?|\\[\s\S])*\}/.source,/\[(?:[^\[\]\\]|\\[\s\S])*\]/.source,/<(?:[^<>\\]|\\[\s\S])*>/.source].join("|")+")"+/[egimnosux]{0,6}/.source),greedy:!0,inside:{interpolation:t}},{pattern:/(^|[^/])\/(?!\/)(?:\[[^\r\n\]]+\]|\\.|[^[/\\\r\n])+\/[egimnosux]{0,6}(?=\s*(?:$|[\r\n,.;})#]))/,lookbehind:!0,greedy:!0,inside:{interpolation:t}}],variable:/[@$]+[a-zA-Z_]\w*(?:[?!]|\b)/,symbol:{pattern:/(^|[^:]):[a-zA-Z_]\w*(?:[?!]|\b)/,lookbehind:!0},"method-definition":{pattern:/(\bdef\s+)[\w.]+/,lookbehind:!0,inside:{function:/\w+$/,rest:e.languages.ruby}}}),e.languages.insertBefore("ruby","number",{builtin:/\b(?:Array|Bignum|Binding|Class|Continuation|Dir|Exception|FalseClass|File|Stat|Fixnum|Float|Hash|Integer|IO|MatchData|Method|Module|NilClass|Numeric|Object|Proc|Range|Regexp|String|Struct|TMS|Symbol|ThreadGroup|Thread|Time|TrueClass)\b/,constant:/\b[A-Z]\w*(?:[?!]|\b)/}),e.languages.ruby.string=[{pattern:RegExp(/%[qQiIwWxs]?/.source+"(?:"+[/([^a-zA-Z0-9\s{(\[<])(?:(?!\1)[^\\]|\\[\s\S])*\1/.source,/\((?:[^()\\]|\\[\s\S])*\)/.source,/\{(?:[^#{}\\]|#(?:\{[^}]+\})?|\\[\s\S])*\}/.source,/\[(?:[^\[\]\\]|\\[\s\S])*\]/.source,/<(?:[^<>\\]|\\[\s\S])*>/.source].join("|")+")"),greedy:!0,inside:{interpolation:t}},{pattern:/("|')(?:#\{[^}]+\}|#(?!\{)|\\(?:\r\n|[\s\S])|(?!\1)[^\\#\r\n])*\1/,greedy:!0,inside:{interpolation:t}},{pattern:/<<[-~]?([a-z_]\w*)[\r\n](?:.*[\r\n])*?[\t ]*\1/i,alias:"heredoc-string",greedy:!0,inside:{delimiter:{pattern:/^<<[-~]?[a-z_]\w*|[a-z_]\w*$/i,alias:"symbol",inside:{punctuation:/^<<[-~]?/}},interpolation:t}},{pattern:/<<[-~]?'([a-z_]\w*)'[\r\n](?:.*[\r\n])*?[\t ]*\1/i,alias:"heredoc-string",greedy:!0,inside:{delimiter:{pattern:/^<<[-~]?'[a-z_]\w*'|[a-z_]\w*$/i,alias:"symbol",inside:{punctuation:/^<<[-~]?'|'$/}}}}],e.languages.rb=e.languages.ruby}(Prism)},2886:function(){Prism.languages.scala=Prism.languages.extend("java",{"triple-quoted-string":{pattern:/"""[\s\S]*?"""/,greedy:!0,alias:"string"},string:{pattern:/("|')(?:\\.|(?!\1)[^\\\r\n])*\1/,greedy:!0},keyword:/<-|=>|\b(?:abstract|case|catch|class|def|do|else|extends|final|finally|for|forSome|if|implicit|import|lazy|match|new|null|object|override|package|private|protected|return|sealed|self|super|this|throw|trait|try|type|val|var|while|with|yield)\b/,number:/\b0x(?:[\da-f]*\.)?[\da-f]+|(?:\b\d+(?:\.\d*)?|\B\.\d+)(?:e\d+)?[dfl]?/i,builtin:/\b(?:String|Int|Long|Short|Byte|Boolean|Double|Float|Char|Any|AnyRef|AnyVal|Unit|Nothing)\b/,symbol:/'[^\d\s\\]\w*/}),delete Prism.languages.scala["class-name"],delete Prism.languages.scala.function},5266:function(){Prism.languages.sql={comment:{pattern:/(^|[^\\])(?:\/\*[\s\S]*?\*\/|(?:--|\/\/|#).*)/,lookbehind:!0},variable:[{pattern:/@(["'`])(?:\\[\s\S]|(?!\1)[^\\])+\1/,greedy:!0},/@[\w.$]+/],string:{pattern:/(^|[^@\\])("|')(?:\\[\s\S]|(?!\2)[^\\]|\2\2)*\2/,greedy:!0,lookbehind:!0},function:/\b(?:AVG|COUNT|FIRST|FORMAT|LAST|LCASE|LEN|MAX|MID|MIN|MOD|NOW|ROUND|SUM|UCASE)(?=\s*\()/i,keyword:/\b(?:ACTION|ADD|AFTER|ALGORITHM|ALL|ALTER|ANALYZE|ANY|APPLY|AS|ASC|AUTHORIZATION|AUTO_INCREMENT|BACKUP|BDB|BEGIN|BERKELEYDB|BIGINT|BINARY|BIT|BLOB|BOOL|BOOLEAN|BREAK|BROWSE|BTREE|BULK|BY|CALL|CASCADED?|CASE|CHAIN|CHAR(?:ACTER|SET)?|CHECK(?:POINT)?|CLOSE|CLUSTERED|COALESCE|COLLATE|COLUMNS?|COMMENT|COMMIT(?:TED)?|COMPUTE|CONNECT|CONSISTENT|CONSTRAINT|CONTAINS(?:TABLE)?|CONTINUE|CONVERT|CREATE|CROSS|CURRENT(?:_DATE|_TIME|_TIMESTAMP|_USER)?|CURSOR|CYCLE|DATA(?:BASES?)?|DATE(?:TIME)?|DAY|DBCC|DEALLOCATE|DEC|DECIMAL|DECLARE|DEFAULT|DEFINER|DELAYED|DELETE|DELIMITERS?|DENY|DESC|DESCRIBE|DETERMINISTIC|DISABLE|DISCARD|DISK|DISTINCT|DISTINCTROW|DISTRIBUTED|DO|DOUBLE|DROP|DUMMY|DUMP(?:FILE)?|DUPLICATE|ELSE(?:IF)?|ENABLE|ENCLOSED|END|ENGINE|ENUM|ERRLVL|ERRORS|ESCAPED?|EXCEPT|EXEC(?:UTE)?|EXISTS|EXIT|EXPLAIN|EXTENDED|FETCH|FIELDS|FILE|FILLFACTOR|FIRST|FIXED|FLOAT|FOLLOWING|FOR(?: EACH ROW)?|FORCE|FOREIGN|FREETEXT(?:TABLE)?|FROM|FULL|FUNCTION|GEOMETRY(?:COLLECTION)?|GLOBAL|GOTO|GRANT|GROUP|HANDLER|HASH|HAVING|HOLDLOCK|HOUR|IDENTITY(?:_INSERT|COL)?|IF|IGNORE|IMPORT|INDEX|INFILE|INNER|INNODB|INOUT|INSERT|INT|INTEGER|INTERSECT|INTERVAL|INTO|INVOKER|ISOLATION|ITERATE|JOIN|KEYS?|KILL|LANGUAGE|LAST|LEAVE|LEFT|LEVEL|LIMIT|LINENO|LINES|LINESTRING|LOAD|LOCAL|LOCK|LONG(?:BLOB|TEXT)|LOOP|MATCH(?:ED)?|MEDIUM(?:BLOB|INT|TEXT)|MERGE|MIDDLEINT|MINUTE|MODE|MODIFIES|MODIFY|MONTH|MULTI(?:LINESTRING|POINT|POLYGON)|NATIONAL|NATURAL|NCHAR|NEXT|NO|NONCLUSTERED|NULLIF|NUMERIC|OFF?|OFFSETS?|ON|OPEN(?:DATASOURCE|QUERY|ROWSET)?|OPTIMIZE|OPTION(?:ALLY)?|ORDER|OUT(?:ER|FILE)?|OVER|PARTIAL|PARTITION|PERCENT|PIVOT|PLAN|POINT|POLYGON|PRECEDING|PRECISION|PREPARE|PREV|PRIMARY|PRINT|PRIVILEGES|PROC(?:EDURE)?|PUBLIC|PURGE|QUICK|RAISERROR|READS?|REAL|RECONFIGURE|REFERENCES|RELEASE|RENAME|REPEAT(?:ABLE)?|REPLACE|REPLICATION|REQUIRE|RESIGNAL|RESTORE|RESTRICT|RETURN(?:S|ING)?|REVOKE|RIGHT|ROLLBACK|ROUTINE|ROW(?:COUNT|GUIDCOL|S)?|RTREE|RULE|SAVE(?:POINT)?|SCHEMA|SECOND|SELECT|SERIAL(?:IZABLE)?|SESSION(?:_USER)?|SET(?:USER)?|SHARE|SHOW|SHUTDOWN|SIMPLE|SMALLINT|SNAPSHOT|SOME|SONAME|SQL|START(?:ING)?|STATISTICS|STATUS|STRIPED|SYSTEM_USER|TABLES?|TABLESPACE|TEMP(?:ORARY|TABLE)?|TERMINATED|TEXT(?:SIZE)?|THEN|TIME(?:STAMP)?|TINY(?:BLOB|INT|TEXT)|TOP?|TRAN(?:SACTIONS?)?|TRIGGER|TRUNCATE|TSEQUAL|TYPES?|UNBOUNDED|UNCOMMITTED|UNDEFINED|UNION|UNIQUE|UNLOCK|UNPIVOT|UNSIGNED|UPDATE(?:TEXT)?|USAGE|USE|USER|USING|VALUES?|VAR(?:BINARY|CHAR|CHARACTER|YING)|VIEW|WAITFOR|WARNINGS|WHEN|WHERE|WHILE|WITH(?: ROLLUP|IN)?|WORK|WRITE(?:TEXT)?|YEAR)\b/i,boolean:/\b(?:TRUE|FALSE|NULL)\b/i,number:/\b0x[\da-f]+\b|\b\d+(?:\.\d*)?|\B\.\d+\b/i,operator:/[-+*\/=%^~]|&&?|\|\|?|!=?|<(?:=>?|<|>)?|>[>=]?|\b(?:AND|BETWEEN|DIV|IN|ILIKE|IS|LIKE|NOT|OR|REGEXP|RLIKE|SOUNDS LIKE|XOR)\b/i,punctuation:/[;[\]()`,.]/}},874:function(){Prism.languages.swift={comment:{pattern:/(^|[^\\:])(?:\/\/.*|\/\*(?:[^/*]|\/(?!\*)|\*(?!\/)|\/\*(?:[^*]|\*(?!\/))*\*\/)*\*\/)/,lookbehind:!0,greedy:!0},"string-literal":[{pattern:RegExp(/(^|[^"#])/.source+"(?:"+/"(?:\\(?:\((?:[^()]|\([^()]*\))*\)|\r\n|[^(])|[^\\\r\n"])*"/.source+"|"+/"""(?:\\(?:\((?:[^()]|\([^()]*\))*\)|[^(])|[^\\"]|"(?!""))*"""/.source+")"+/(?!["#])/.source),lookbehind:!0,greedy:!0,inside:{interpolation:{pattern:/(\\\()(?:[^()]|\([^()]*\))*(?=\))/,lookbehind:!0,inside:null},"interpolation-punctuation":{pattern:/^\)|\\\($/,alias:"punctuation"},punctuation:/\\(?=[\r\n])/,string:/[\s\S]+/}},{pattern:RegExp(/(^|[^"#])(#+)/.source+"(?:"+/"(?:\\(?:#+\((?:[^()]|\([^()]*\))*\)|\r\n|[^#])|[^\\\r\n])*?"/.source+"|"+/"""(?:\\(?:#+\((?:[^()]|\([^()]*\))*\)|[^#])|[^\\])*?"""/.source+")\\2"),lookbehind:!0,greedy:!0,inside:{interpolation:{pattern:/(\\#+\()(?:[^()]|\([^()]*\))*(?=\))/,lookbehind:!0,inside:null},"interpolation-punctuation":{pattern:/^\)|\\#+\($/,alias:"punctuation"},string:/[\s\S]+/}}],directive:{pattern:RegExp(/#/.source+"(?:"+/(?:elseif|if)\b/.source+"(?:[ \t]*"+/(?:![ \t]*)?(?:\b\w+\b(?:[ \t]*\((?:[^()]|\([^()]*\))*\))?|\((?:[^()]|\([^()]*\))*\))(?:[ \t]*(?:&&|\|\|))?/.source+")+|"+/(?:else|endif)\b/.source+")"),alias:"property",inside:{"directive-name":/^#\w+/,boolean:/\b(?:true|false)\b/,number:/\b\d+(?:\.\d+)*\b/,operator:/!|&&|\|\||[<>]=?/,punctuation:/[(),]/}},literal:{pattern:/#(?:colorLiteral|column|dsohandle|file(?:ID|Literal|Path)?|function|imageLiteral|line)\b/,alias:"constant"},"other-directive":{pattern:/#\w+\b/,alias:"property"},attribute:{pattern:/@\w+/,alias:"atrule"},"function-definition":{pattern:/(\bfunc\s+)\w+/,lookbehind:!0,alias:"function"},label:{pattern:/\b(break|continue)\s+\w+|\b[a-zA-Z_]\w*(?=\s*:\s*(?:for|repeat|while)\b)/,lookbehind:!0,alias:"important"},keyword:/\b(?:Any|Protocol|Self|Type|actor|as|assignment|associatedtype|associativity|async|await|break|case|catch|class|continue|convenience|default|defer|deinit|didSet|do|dynamic|else|enum|extension|fallthrough|fileprivate|final|for|func|get|guard|higherThan|if|import|in|indirect|infix|init|inout|internal|is|lazy|left|let|lowerThan|mutating|none|nonisolated|nonmutating|open|operator|optional|override|postfix|precedencegroup|prefix|private|protocol|public|repeat|required|rethrows|return|right|safe|self|set|some|static|struct|subscript|super|switch|throw|throws|try|typealias|unowned|unsafe|var|weak|where|while|willSet)\b/,boolean:/\b(?:true|false)\b/,nil:{pattern:/\bnil\b/,alias:"constant"},"short-argument":/\$\d+\b/,omit:{pattern:/\b_\b/,alias:"keyword"},number:/\b(?:[\d_]+(?:\.[\de_]+)?|0x[a-f0-9_]+(?:\.[a-f0-9p_]+)?|0b[01_]+|0o[0-7_]+)\b/i,"class-name":/\b[A-Z](?:[A-Z_\d]*[a-z]\w*)?\b/,function:/\b[a-z_]\w*(?=\s*\()/i,constant:/\b(?:[A-Z_]{2,}|k[A-Z][A-Za-z_]+)\b/,operator:/[-+*/%=!<>&|^~?]+|\.[.\-+*/%=!<>&|^~?]+/,punctuation:/[{}[\]();,.:\\]/},Prism.languages.swift["string-literal"].forEach((function(e){e.inside.interpolation.inside=Prism.languages.swift}))},3358:function(){!function(e){var t=/[*&][^\s[\]{},]+/,n=/!(?:<[\w\-%#;/?:@&=+$,.!~*'()[\]]+>|(?:[a-zA-Z\d-]*!)?[\w\-%#;/?:@&=+$.~*'()]+)?/,r="(?:"+n.source+"(?:[ \t]+"+t.source+")?|"+t.source+"(?:[ \t]+"+n.source+")?)",o=/(?:[^\s\x00-\x08\x0e-\x1f!"#%&'*,\-:>?@[\]`{|}\x7f-\x84\x86-\x9f\ud800-\udfff\ufffe\uffff]|[?:-]<PLAIN>)(?:[ \t]*(?:(?![#:])<PLAIN>|:<PLAIN>))*/.source.replace(/<PLAIN>/g,(function(){return/[^\s\x00-\x08\x0e-\x1f,[\]{}\x7f-\x84\x86-\x9f\ud800-\udfff\ufffe\uffff]/.source})),i=/"(?:[^"\\\r\n]|\\.)*"|'(?:[^'\\\r\n]|\\.)*'/.source;function a(e,t){t=(t||"").replace(/m/g,"")+"m";var n=/([:\-,[{]\s*(?:\s<<prop>>[ \t]+)?)(?:<<value>>)(?=[ \t]*(?:$|,|\]|\}|(?:[\r\n]\s*)?#))/.source.replace(/<<prop>>/g,(function(){return r})).replace(/<<value>>/g,(function(){return e}));return RegExp(n,t)}e.languages.yaml={scalar:{pattern:RegExp(/([\-:]\s*(?:\s<<prop>>[ \t]+)?[|>])[ \t]*(?:((?:\r?\n|\r)[ \t]+)\S[^\r\n]*(?:\2[^\r\n]+)*)/.source.replace(/<<prop>>/g,(function(){return r}))),lookbehind:!0,alias:"string"},comment:/#.*/,key:{pattern:RegExp(/((?:^|[:\-,[{\r\n?])[ \t]*(?:<<prop>>[ \t]+)?)<<key>>(?=\s*:\s)/.source.replace(/<<prop>>/g,(function(){return r})).replace(/<<key>>/g,(function(){return"(?:"+o+"|"+i+")"}))),lookbehind:!0,greedy:!0,alias:"atrule"},directive:{pattern:/(^[ \t]*)%.+/m,lookbehind:!0,alias:"important"},datetime:{pattern:a(/\d{4}-\d\d?-\d\d?(?:[tT]|[ \t]+)\d\d?:\d{2}:\d{2}(?:\.\d*)?(?:[ \t]*(?:Z|[-+]\d\d?(?::\d{2})?))?|\d{4}-\d{2}-\d{2}|\d\d?:\d{2}(?::\d{2}(?:\.\d*)?)?/.source),lookbehind:!0,alias:"number"},boolean:{pattern:a(/true|false/.source,"i"),lookbehind:!0,alias:"important"},null:{pattern:a(/null|~/.source,"i"),lookbehind:!0,alias:"important"},string:{pattern:a(i),lookbehind:!0,greedy:!0},number:{pattern:a(/[+-]?(?:0x[\da-f]+|0o[0-7]+|(?:\d+(?:\.\d*)?|\.\d+)(?:e[+-]?\d+)?|\.inf|\.nan)/.source,"i"),lookbehind:!0},tag:n,important:t,punctuation:/---|[:[\]{}\-,|>?]|\.\.\./},e.languages.yml=e.languages.yaml}(Prism)},5660:function(e,t,n){var r=function(e){var t=/\blang(?:uage)?-([\w-]+)\b/i,n=0,r={},o={manual:e.Prism&&e.Prism.manual,disableWorkerMessageHandler:e.Prism&&e.Prism.disableWorkerMessageHandler,util:{encode:function e(t){return t instanceof i?new i(t.type,e(t.content),t.alias):Array.isArray(t)?t.map(e):t.replace(/&/g,"&amp;").replace(/</g,"&lt;").replace(/\u00a0/g," ")},type:function(e){return Object.prototype.toString.call(e).slice(8,-1)},objId:function(e){return e.__id||Object.defineProperty(e,"__id",{value:++n}),e.__id},clone:function e(t,n){var r,i;switch(n=n||{},o.util.type(t)){case"Object":if(i=o.util.objId(t),n[i])return n[i];for(var a in r={},n[i]=r,t)t.hasOwnProperty(a)&&(r[a]=e(t[a],n));return r;case"Array":return i=o.util.objId(t),n[i]?n[i]:(r=[],n[i]=r,t.forEach((function(t,o){r[o]=e(t,n)})),r);default:return t}},getLanguage:function(e){for(;e&&!t.test(e.className);)e=e.parentElement;return e?(e.className.match(t)||[,"none"])[1].toLowerCase():"none"},currentScript:function(){if("undefined"==typeof document)return null;if("currentScript"in document)return document.currentScript;try{throw new Error}catch(r){var e=(/at [^(\r\n]*\((.*):[^:]+:[^:]+\)$/i.exec(r.stack)||[])[1];if(e){var t=document.getElementsByTagName("script");for(var n in t)if(t[n].src==e)return t[n]}return null}},isActive:function(e,t,n){for(var r="no-"+t;e;){var o=e.classList;if(o.contains(t))return!0;if(o.contains(r))return!1;e=e.parentElement}return!!n}},languages:{plain:r,plaintext:r,text:r,txt:r,extend:function(e,t){var n=o.util.clone(o.languages[e]);for(var r in t)n[r]=t[r];return n},insertBefore:function(e,t,n,r){var i=(r=r||o.languages)[e],a={};for(var s in i)if(i.hasOwnProperty(s)){if(s==t)for(var l in n)n.hasOwnProperty(l)&&(a[l]=n[l]);n.hasOwnProperty(s)||(a[s]=i[s])}var c=r[e];return r[e]=a,o.languages.DFS(o.languages,(function(t,n){n===c&&t!=e&&(this[t]=a)})),a},DFS:function e(t,n,r,i){i=i||{};var a=o.util.objId;for(var s in t)if(t.hasOwnProperty(s)){n.call(t,s,t[s],r||s);var l=t[s],c=o.util.type(l);"Object"!==c||i[a(l)]?"Array"!==c||i[a(l)]||(i[a(l)]=!0,e(l,n,s,i)):(i[a(l)]=!0,e(l,n,null,i))}}},plugins:{},highlightAll:function(e,t){o.highlightAllUnder(document,e,t)},highlightAllUnder:function(e,t,n){var r={callback:n,container:e,selector:'code[class*="language-"], [class*="language-"] code, code[class*="lang-"], [class*="lang-"] code'};o.hooks.run("before-highlightall",r),r.elements=Array.prototype.slice.apply(r.container.querySelectorAll(r.selector)),o.hooks.run("before-all-elements-highlight",r);for(var i,a=0;i=r.elements[a++];)o.highlightElement(i,!0===t,r.callback)},highlightElement:function(n,r,i){var a=o.util.getLanguage(n),s=o.languages[a];n.className=n.className.replace(t,"").replace(/\s+/g," ")+" language-"+a;var l=n.parentElement;l&&"pre"===l.nodeName.toLowerCase()&&(l.className=l.className.replace(t,"").replace(/\s+/g," ")+" language-"+a);var c={element:n,language:a,grammar:s,code:n.textContent};function u(e){c.highlightedCode=e,o.hooks.run("before-insert",c),c.element.innerHTML=c.highlightedCode,o.hooks.run("after-highlight",c),o.hooks.run("complete",c),i&&i.call(c.element)}if(o.hooks.run("before-sanity-check",c),(l=c.element.parentElement)&&"pre"===l.nodeName.toLowerCase()&&!l.hasAttribute("tabindex")&&l.setAttribute("tabindex","0"),!c.code)return o.hooks.run("complete",c),void(i&&i.call(c.element));if(o.hooks.run("before-highlight",c),c.grammar)if(r&&e.Worker){var p=new Worker(o.filename);p.onmessage=function(e){u(e.data)},p.postMessage(JSON.stringify({language:c.language,code:c.code,immediateClose:!0}))}else u(o.highlight(c.code,c.grammar,c.language));else u(o.util.encode(c.code))},highlight:function(e,t,n){var r={code:e,grammar:t,language:n};return o.hooks.run("before-tokenize",r),r.tokens=o.tokenize(r.code,r.grammar),o.hooks.run("after-tokenize",r),i.stringify(o.util.encode(r.tokens),r.language)},tokenize:function(e,t){var n=t.rest;if(n){for(var r in n)t[r]=n[r];delete t.rest}var o=new l;return c(o,o.head,e),s(e,o,t,o.head,0),function(e){for(var t=[],n=e.head.next;n!==e.tail;)t.push(n.value),n=n.next;return t}(o)},hooks:{all:{},add:function(e,t){var n=o.hooks.all;n[e]=n[e]||[],n[e].push(t)},run:function(e,t){var n=o.hooks.all[e];if(n&&n.length)for(var r,i=0;r=n[i++];)r(t)}},Token:i};function i(e,t,n,r){this.type=e,this.content=t,this.alias=n,this.length=0|(r||"").length}function a(e,t,n,r){e.lastIndex=t;var o=e.exec(n);if(o&&r&&o[1]){var i=o[1].length;o.index+=i,o[0]=o[0].slice(i)}return o}function s(e,t,n,r,l,p){for(var f in n)if(n.hasOwnProperty(f)&&n[f]){var d=n[f];d=Array.isArray(d)?d:[d];for(var h=0;h<d.length;++h){if(p&&p.cause==f+","+h)return;var m=d[h],v=m.inside,g=!!m.lookbehind,y=!!m.greedy,b=m.alias;if(y&&!m.pattern.global){var x=m.pattern.toString().match(/[imsuy]*$/)[0];m.pattern=RegExp(m.pattern.source,x+"g")}for(var w=m.pattern||m,k=r.next,E=l;k!==t.tail&&!(p&&E>=p.reach);E+=k.value.length,k=k.next){var S=k.value;if(t.length>e.length)return;if(!(S instanceof i)){var _,O=1;if(y){if(!(_=a(w,E,e,g)))break;var A=_.index,R=_.index+_[0].length,C=E;for(C+=k.value.length;A>=C;)C+=(k=k.next).value.length;if(E=C-=k.value.length,k.value instanceof i)continue;for(var T=k;T!==t.tail&&(C<R||"string"==typeof T.value);T=T.next)O++,C+=T.value.length;O--,S=e.slice(E,C),_.index-=E}else if(!(_=a(w,0,S,g)))continue;A=_.index;var P=_[0],I=S.slice(0,A),j=S.slice(A+P.length),L=E+S.length;p&&L>p.reach&&(p.reach=L);var N=k.prev;if(I&&(N=c(t,N,I),E+=I.length),u(t,N,O),k=c(t,N,new i(f,v?o.tokenize(P,v):P,b,P)),j&&c(t,k,j),O>1){var M={cause:f+","+h,reach:L};s(e,t,n,k.prev,E,M),p&&M.reach>p.reach&&(p.reach=M.reach)}}}}}}function l(){var e={value:null,prev:null,next:null},t={value:null,prev:e,next:null};e.next=t,this.head=e,this.tail=t,this.length=0}function c(e,t,n){var r=t.next,o={value:n,prev:t,next:r};return t.next=o,r.prev=o,e.length++,o}function u(e,t,n){for(var r=t.next,o=0;o<n&&r!==e.tail;o++)r=r.next;t.next=r,r.prev=t,e.length-=o}if(e.Prism=o,i.stringify=function e(t,n){if("string"==typeof t)return t;if(Array.isArray(t)){var r="";return t.forEach((function(t){r+=e(t,n)})),r}var i={type:t.type,content:e(t.content,n),tag:"span",classes:["token",t.type],attributes:{},language:n},a=t.alias;a&&(Array.isArray(a)?Array.prototype.push.apply(i.classes,a):i.classes.push(a)),o.hooks.run("wrap",i);var s="";for(var l in i.attributes)s+=" "+l+'="'+(i.attributes[l]||"").replace(/"/g,"&quot;")+'"';return"<"+i.tag+' class="'+i.classes.join(" ")+'"'+s+">"+i.content+"</"+i.tag+">"},!e.document)return e.addEventListener?(o.disableWorkerMessageHandler||e.addEventListener("message",(function(t){var n=JSON.parse(t.data),r=n.language,i=n.code,a=n.immediateClose;e.postMessage(o.highlight(i,o.languages[r],r)),a&&e.close()}),!1),o):o;var p=o.util.currentScript();function f(){o.manual||o.highlightAll()}if(p&&(o.filename=p.src,p.hasAttribute("data-manual")&&(o.manual=!0)),!o.manual){var d=document.readyState;"loading"===d||"interactive"===d&&p&&p.defer?document.addEventListener("DOMContentLoaded",f):window.requestAnimationFrame?window.requestAnimationFrame(f):window.setTimeout(f,16)}return o}("undefined"!=typeof window?window:"undefined"!=typeof WorkerGlobalScope&&self instanceof WorkerGlobalScope?self:{});e.exports&&(e.exports=r),void 0!==n.g&&(n.g.Prism=r),r.languages.markup={comment:{pattern:/<!--(?:(?!<!--)[\s\S])*?-->/,greedy:!0},prolog:{pattern:/<\?[\s\S]+?\?>/,greedy:!0},doctype:{pattern:/<!DOCTYPE(?:[^>"'[\]]|"[^"]*"|'[^']*')+(?:\[(?:[^<"'\]]|"[^"]*"|'[^']*'|<(?!!--)|<!--(?:[^-]|-(?!->))*-->)*\]\s*)?>/i,greedy:!0,inside:{"internal-subset":{pattern:/(^[^\[]*\[)[\s\S]+(?=\]>$)/,lookbehind:!0,greedy:!0,inside:null},string:{pattern:/"[^"]*"|'[^']*'/,greedy:!0},punctuation:/^<!|>$|[[\]]/,"doctype-tag":/^DOCTYPE/i,name:/[^\s<>'"]+/}},cdata:{pattern:/<!\[CDATA\[[\s\S]*?\]\]>/i,greedy:!0},tag:{pattern:/<\/?(?!\d)[^\s>\/=$<%]+(?:\s(?:\s*[^\s>\/=]+(?:\s*=\s*(?:"[^"]*"|'[^']*'|[^\s'">=]+(?=[\s>]))|(?=[\s/>])))+)?\s*\/?>/,greedy:!0,inside:{tag:{pattern:/^<\/?[^\s>\/]+/,inside:{punctuation:/^<\/?/,namespace:/^[^\s>\/:]+:/}},"special-attr":[],"attr-value":{pattern:/=\s*(?:"[^"]*"|'[^']*'|[^\s'">=]+)/,inside:{punctuation:[{pattern:/^=/,alias:"attr-equals"},/"|'/]}},punctuation:/\/?>/,"attr-name":{pattern:/[^\s>\/]+/,inside:{namespace:/^[^\s>\/:]+:/}}}},entity:[{pattern:/&[\da-z]{1,8};/i,alias:"named-entity"},/&#x?[\da-f]{1,8};/i]},r.languages.markup.tag.inside["attr-value"].inside.entity=r.languages.markup.entity,r.languages.markup.doctype.inside["internal-subset"].inside=r.languages.markup,r.hooks.add("wrap",(function(e){"entity"===e.type&&(e.attributes.title=e.content.replace(/&amp;/,"&"))})),Object.defineProperty(r.languages.markup.tag,"addInlined",{value:function(e,t){var n={};n["language-"+t]={pattern:/(^<!\[CDATA\[)[\s\S]+?(?=\]\]>$)/i,lookbehind:!0,inside:r.languages[t]},n.cdata=/^<!\[CDATA\[|\]\]>$/i;var o={"included-cdata":{pattern:/<!\[CDATA\[[\s\S]*?\]\]>/i,inside:n}};o["language-"+t]={pattern:/[\s\S]+/,inside:r.languages[t]};var i={};i[e]={pattern:RegExp(/(<__[^>]*>)(?:<!\[CDATA\[(?:[^\]]|\](?!\]>))*\]\]>|(?!<!\[CDATA\[)[\s\S])*?(?=<\/__>)/.source.replace(/__/g,(function(){return e})),"i"),lookbehind:!0,greedy:!0,inside:o},r.languages.insertBefore("markup","cdata",i)}}),Object.defineProperty(r.languages.markup.tag,"addAttribute",{value:function(e,t){r.languages.markup.tag.inside["special-attr"].push({pattern:RegExp(/(^|["'\s])/.source+"(?:"+e+")"+/\s*=\s*(?:"[^"]*"|'[^']*'|[^\s'">=]+(?=[\s>]))/.source,"i"),lookbehind:!0,inside:{"attr-name":/^[^\s=]+/,"attr-value":{pattern:/=[\s\S]+/,inside:{value:{pattern:/(^=\s*(["']|(?!["'])))\S[\s\S]*(?=\2$)/,lookbehind:!0,alias:[t,"language-"+t],inside:r.languages[t]},punctuation:[{pattern:/^=/,alias:"attr-equals"},/"|'/]}}}})}}),r.languages.html=r.languages.markup,r.languages.mathml=r.languages.markup,r.languages.svg=r.languages.markup,r.languages.xml=r.languages.extend("markup",{}),r.languages.ssml=r.languages.xml,r.languages.atom=r.languages.xml,r.languages.rss=r.languages.xml,function(e){var t=/(?:"(?:\\(?:\r\n|[\s\S])|[^"\\\r\n])*"|'(?:\\(?:\r\n|[\s\S])|[^'\\\r\n])*')/;e.languages.css={comment:/\/\*[\s\S]*?\*\//,atrule:{pattern:/@[\w-](?:[^;{\s]|\s+(?![\s{]))*(?:;|(?=\s*\{))/,inside:{rule:/^@[\w-]+/,"selector-function-argument":{pattern:/(\bselector\s*\(\s*(?![\s)]))(?:[^()\s]|\s+(?![\s)])|\((?:[^()]|\([^()]*\))*\))+(?=\s*\))/,lookbehind:!0,alias:"selector"},keyword:{pattern:/(^|[^\w-])(?:and|not|only|or)(?![\w-])/,lookbehind:!0}}},url:{pattern:RegExp("\\burl\\((?:"+t.source+"|"+/(?:[^\\\r\n()"']|\\[\s\S])*/.source+")\\)","i"),greedy:!0,inside:{function:/^url/i,punctuation:/^\(|\)$/,string:{pattern:RegExp("^"+t.source+"$"),alias:"url"}}},selector:{pattern:RegExp("(^|[{}\\s])[^{}\\s](?:[^{};\"'\\s]|\\s+(?![\\s{])|"+t.source+")*(?=\\s*\\{)"),lookbehind:!0},string:{pattern:t,greedy:!0},property:{pattern:/(^|[^-\w\xA0-\uFFFF])(?!\s)[-_a-z\xA0-\uFFFF](?:(?!\s)[-\w\xA0-\uFFFF])*(?=\s*:)/i,lookbehind:!0},important:/!important\b/i,function:{pattern:/(^|[^-a-z0-9])[-a-z0-9]+(?=\()/i,lookbehind:!0},punctuation:/[(){};:,]/},e.languages.css.atrule.inside.rest=e.languages.css;var n=e.languages.markup;n&&(n.tag.addInlined("style","css"),n.tag.addAttribute("style","css"))}(r),r.languages.clike={comment:[{pattern:/(^|[^\\])\/\*[\s\S]*?(?:\*\/|$)/,lookbehind:!0,greedy:!0},{pattern:/(^|[^\\:])\/\/.*/,lookbehind:!0,greedy:!0}],string:{pattern:/(["'])(?:\\(?:\r\n|[\s\S])|(?!\1)[^\\\r\n])*\1/,greedy:!0},"class-name":{pattern:/(\b(?:class|interface|extends|implements|trait|instanceof|new)\s+|\bcatch\s+\()[\w.\\]+/i,lookbehind:!0,inside:{punctuation:/[.\\]/}},keyword:/\b(?:if|else|while|do|for|return|in|instanceof|function|new|try|throw|catch|finally|null|break|continue)\b/,boolean:/\b(?:true|false)\b/,function:/\b\w+(?=\()/,number:/\b0x[\da-f]+\b|(?:\b\d+(?:\.\d*)?|\B\.\d+)(?:e[+-]?\d+)?/i,operator:/[<>]=?|[!=]=?=?|--?|\+\+?|&&?|\|\|?|[?*/~^%]/,punctuation:/[{}[\];(),.:]/},r.languages.javascript=r.languages.extend("clike",{"class-name":[r.languages.clike["class-name"],{pattern:/(^|[^$\w\xA0-\uFFFF])(?!\s)[_$A-Z\xA0-\uFFFF](?:(?!\s)[$\w\xA0-\uFFFF])*(?=\.(?:prototype|constructor))/,lookbehind:!0}],keyword:[{pattern:/((?:^|\})\s*)catch\b/,lookbehind:!0},{pattern:/(^|[^.]|\.\.\.\s*)\b(?:as|assert(?=\s*\{)|async(?=\s*(?:function\b|\(|[$\w\xA0-\uFFFF]|$))|await|break|case|class|const|continue|debugger|default|delete|do|else|enum|export|extends|finally(?=\s*(?:\{|$))|for|from(?=\s*(?:['"]|$))|function|(?:get|set)(?=\s*(?:[#\[$\w\xA0-\uFFFF]|$))|if|implements|import|in|instanceof|interface|let|new|null|of|package|private|protected|public|return|static|super|switch|this|throw|try|typeof|undefined|var|void|while|with|yield)\b/,lookbehind:!0}],function:/#?(?!\s)[_$a-zA-Z\xA0-\uFFFF](?:(?!\s)[$\w\xA0-\uFFFF])*(?=\s*(?:\.\s*(?:apply|bind|call)\s*)?\()/,number:/\b(?:(?:0[xX](?:[\dA-Fa-f](?:_[\dA-Fa-f])?)+|0[bB](?:[01](?:_[01])?)+|0[oO](?:[0-7](?:_[0-7])?)+)n?|(?:\d(?:_\d)?)+n|NaN|Infinity)\b|(?:\b(?:\d(?:_\d)?)+\.?(?:\d(?:_\d)?)*|\B\.(?:\d(?:_\d)?)+)(?:[Ee][+-]?(?:\d(?:_\d)?)+)?/,operator:/--|\+\+|\*\*=?|=>|&&=?|\|\|=?|[!=]==|<<=?|>>>?=?|[-+*/%&|^!=<>]=?|\.{3}|\?\?=?|\?\.?|[~:]/}),r.languages.javascript["class-name"][0].pattern=/(\b(?:class|interface|extends|implements|instanceof|new)\s+)[\w.\\]+/,r.languages.insertBefore("javascript","keyword",{regex:{pattern:/((?:^|[^$\w\xA0-\uFFFF."'\])\s]|\b(?:return|yield))\s*)\/(?:\[(?:[^\]\\\r\n]|\\.)*\]|\\.|[^/\\\[\r\n])+\/[dgimyus]{0,7}(?=(?:\s|\/\*(?:[^*]|\*(?!\/))*\*\/)*(?:$|[\r\n,.;:})\]]|\/\/))/,lookbehind:!0,greedy:!0,inside:{"regex-source":{pattern:/^(\/)[\s\S]+(?=\/[a-z]*$)/,lookbehind:!0,alias:"language-regex",inside:r.languages.regex},"regex-delimiter":/^\/|\/$/,"regex-flags":/^[a-z]+$/}},"function-variable":{pattern:/#?(?!\s)[_$a-zA-Z\xA0-\uFFFF](?:(?!\s)[$\w\xA0-\uFFFF])*(?=\s*[=:]\s*(?:async\s*)?(?:\bfunction\b|(?:\((?:[^()]|\([^()]*\))*\)|(?!\s)[_$a-zA-Z\xA0-\uFFFF](?:(?!\s)[$\w\xA0-\uFFFF])*)\s*=>))/,alias:"function"},parameter:[{pattern:/(function(?:\s+(?!\s)[_$a-zA-Z\xA0-\uFFFF](?:(?!\s)[$\w\xA0-\uFFFF])*)?\s*\(\s*)(?!\s)(?:[^()\s]|\s+(?![\s)])|\([^()]*\))+(?=\s*\))/,lookbehind:!0,inside:r.languages.javascript},{pattern:/(^|[^$\w\xA0-\uFFFF])(?!\s)[_$a-z\xA0-\uFFFF](?:(?!\s)[$\w\xA0-\uFFFF])*(?=\s*=>)/i,lookbehind:!0,inside:r.languages.javascript},{pattern:/(\(\s*)(?!\s)(?:[^()\s]|\s+(?![\s)])|\([^()]*\))+(?=\s*\)\s*=>)/,lookbehind:!0,inside:r.languages.javascript},{pattern:/((?:\b|\s|^)(?!(?:as|async|await|break|case|catch|class|const|continue|debugger|default|delete|do|else|enum|export|extends|finally|for|from|function|get|if|implements|import|in|instanceof|interface|let|new|null|of|package|private|protected|public|return|set|static|super|switch|this|throw|try|typeof|undefined|var|void|while|with|yield)(?![$\w\xA0-\uFFFF]))(?:(?!\s)[_$a-zA-Z\xA0-\uFFFF](?:(?!\s)[$\w\xA0-\uFFFF])*\s*)\(\s*|\]\s*\(\s*)(?!\s)(?:[^()\s]|\s+(?![\s)])|\([^()]*\))+(?=\s*\)\s*\{)/,lookbehind:!0,inside:r.languages.javascript}],constant:/\b[A-Z](?:[A-Z_]|\dx?)*\b/}),r.languages.insertBefore("javascript","string",{hashbang:{pattern:/^#!.*/,greedy:!0,alias:"comment"},"template-string":{pattern:/`(?:\\[\s\S]|\$\{(?:[^{}]|\{(?:[^{}]|\{[^}]*\})*\})+\}|(?!\$\{)[^\\`])*`/,greedy:!0,inside:{"template-punctuation":{pattern:/^`|`$/,alias:"string"},interpolation:{pattern:/((?:^|[^\\])(?:\\{2})*)\$\{(?:[^{}]|\{(?:[^{}]|\{[^}]*\})*\})+\}/,lookbehind:!0,inside:{"interpolation-punctuation":{pattern:/^\$\{|\}$/,alias:"punctuation"},rest:r.languages.javascript}},string:/[\s\S]+/}}}),r.languages.markup&&(r.languages.markup.tag.addInlined("script","javascript"),r.languages.markup.tag.addAttribute(/on(?:abort|blur|change|click|composition(?:end|start|update)|dblclick|error|focus(?:in|out)?|key(?:down|up)|load|mouse(?:down|enter|leave|move|out|over|up)|reset|resize|scroll|select|slotchange|submit|unload|wheel)/.source,"javascript")),r.languages.js=r.languages.javascript,function(){if(void 0!==r&&"undefined"!=typeof document){Element.prototype.matches||(Element.prototype.matches=Element.prototype.msMatchesSelector||Element.prototype.webkitMatchesSelector);var e={js:"javascript",py:"python",rb:"ruby",ps1:"powershell",psm1:"powershell",sh:"bash",bat:"batch",h:"c",tex:"latex"},t="data-src-status",n='pre[data-src]:not([data-src-status="loaded"]):not([data-src-status="loading"])',o=/\blang(?:uage)?-([\w-]+)\b/i;r.hooks.add("before-highlightall",(function(e){e.selector+=", "+n})),r.hooks.add("before-sanity-check",(function(o){var i=o.element;if(i.matches(n)){o.code="",i.setAttribute(t,"loading");var s=i.appendChild(document.createElement("CODE"));s.textContent="Loading…";var l=i.getAttribute("data-src"),c=o.language;if("none"===c){var u=(/\.(\w+)$/.exec(l)||[,"none"])[1];c=e[u]||u}a(s,c),a(i,c);var p=r.plugins.autoloader;p&&p.loadLanguages(c);var f=new XMLHttpRequest;f.open("GET",l,!0),f.onreadystatechange=function(){4==f.readyState&&(f.status<400&&f.responseText?(i.setAttribute(t,"loaded"),s.textContent=f.responseText,r.highlightElement(s)):(i.setAttribute(t,"failed"),f.status>=400?s.textContent="✖ Error "+f.status+" while fetching file: "+f.statusText:s.textContent="✖ Error: File does not exist or is empty"))},f.send(null)}})),r.plugins.fileHighlight={highlight:function(e){for(var t,o=(e||document).querySelectorAll(n),i=0;t=o[i++];)r.highlightElement(t)}};var i=!1;r.fileHighlight=function(){i||(console.warn("Prism.fileHighlight is deprecated. Use `Prism.plugins.fileHighlight.highlight` instead."),i=!0),r.plugins.fileHighlight.highlight.apply(this,arguments)}}function a(e,t){var n=e.className;n=n.replace(o," ")+" language-"+t,e.className=n.replace(/\s+/g," ").trim()}}()},2703:function(e,t,n){"use strict";var r=n(414);function o(){}function i(){}i.resetWarningCache=o,e.exports=function(){function e(e,t,n,o,i,a){if(a!==r){var s=new Error("Calling PropTypes validators directly is not supported by the `prop-types` package. Use PropTypes.checkPropTypes() to call them. Read more at http://fb.me/use-check-prop-types");throw s.name="Invariant Violation",s}}function t(){return e}e.isRequired=e;var n={array:e,bool:e,func:e,number:e,object:e,string:e,symbol:e,any:e,arrayOf:t,element:e,elementType:e,instanceOf:t,node:e,objectOf:t,oneOf:t,oneOfType:t,shape:t,exact:t,checkPropTypes:i,resetWarningCache:o};return n.PropTypes=n,n}},5697:function(e,t,n){e.exports=n(2703)()},414:function(e){"use strict";e.exports="SECRET_DO_NOT_PASS_THIS_OR_YOU_WILL_BE_FIRED"},2587:function(e){"use strict";function t(e,t){return Object.prototype.hasOwnProperty.call(e,t)}e.exports=function(e,n,r,o){n=n||"&",r=r||"=";var i={};if("string"!=typeof e||0===e.length)return i;var a=/\+/g;e=e.split(n);var s=1e3;o&&"number"==typeof o.maxKeys&&(s=o.maxKeys);var l=e.length;s>0&&l>s&&(l=s);for(var c=0;c<l;++c){var u,p,f,d,h=e[c].replace(a,"%20"),m=h.indexOf(r);m>=0?(u=h.substr(0,m),p=h.substr(m+1)):(u=h,p=""),f=decodeURIComponent(u),d=decodeURIComponent(p),t(i,f)?Array.isArray(i[f])?i[f].push(d):i[f]=[i[f],d]:i[f]=d}return i}},2361:function(e){"use strict";var t=function(e){switch(typeof e){case"string":return e;case"boolean":return e?"true":"false";case"number":return isFinite(e)?e:"";default:return""}};e.exports=function(e,n,r,o){return n=n||"&",r=r||"=",null===e&&(e=void 0),"object"==typeof e?Object.keys(e).map((function(o){var i=encodeURIComponent(t(o))+r;return Array.isArray(e[o])?e[o].map((function(e){return i+encodeURIComponent(t(e))})).join(n):i+encodeURIComponent(t(e[o]))})).join(n):o?encodeURIComponent(t(o))+r+encodeURIComponent(t(e)):""}},7673:function(e,t,n){"use strict";t.decode=t.parse=n(2587),t.encode=t.stringify=n(2361)},4448:function(e,t,n){"use strict";var r=n(7294),o=n(7418),i=n(3840);function a(e){for(var t="https://reactjs.org/docs/error-decoder.html?invariant="+e,n=1;n<arguments.length;n++)t+="&args[]="+encodeURIComponent(arguments[n]);return"Minified React error #"+e+"; visit "+t+" for the full message or use the non-minified dev environment for full errors and additional helpful warnings."}if(!r)throw Error(a(227));var s=new Set,l={};function c(e,t){u(e,t),u(e+"Capture",t)}function u(e,t){for(l[e]=t,e=0;e<t.length;e++)s.add(t[e])}var p=!("undefined"==typeof window||void 0===window.document||void 0===window.document.createElement),f=/^[:A-Z_a-z\u00C0-\u00D6\u00D8-\u00F6\u00F8-\u02FF\u0370-\u037D\u037F-\u1FFF\u200C-\u200D\u2070-\u218F\u2C00-\u2FEF\u3001-\uD7FF\uF900-\uFDCF\uFDF0-\uFFFD][:A-Z_a-z\u00C0-\u00D6\u00D8-\u00F6\u00F8-\u02FF\u0370-\u037D\u037F-\u1FFF\u200C-\u200D\u2070-\u218F\u2C00-\u2FEF\u3001-\uD7FF\uF900-\uFDCF\uFDF0-\uFFFD\-.0-9\u00B7\u0300-\u036F\u203F-\u2040]*$/,d=Object.prototype.hasOwnProperty,h={},m={};function v(e,t,n,r,o,i,a){this.acceptsBooleans=2===t||3===t||4===t,this.attributeName=r,this.attributeNamespace=o,this.mustUseProperty=n,this.propertyName=e,this.type=t,this.sanitizeURL=i,this.removeEmptyString=a}var g={};"children dangerouslySetInnerHTML defaultValue defaultChecked innerHTML suppressContentEditableWarning suppressHydrationWarning style".split(" ").forEach((function(e){g[e]=new v(e,0,!1,e,null,!1,!1)})),[["acceptCharset","accept-charset"],["className","class"],["htmlFor","for"],["httpEquiv","http-equiv"]].forEach((function(e){var t=e[0];g[t]=new v(t,1,!1,e[1],null,!1,!1)})),["contentEditable","draggable","spellCheck","value"].forEach((function(e){g[e]=new v(e,2,!1,e.toLowerCase(),null,!1,!1)})),["autoReverse","externalResourcesRequired","focusable","preserveAlpha"].forEach((function(e){g[e]=new v(e,2,!1,e,null,!1,!1)})),"allowFullScreen async autoFocus autoPlay controls default defer disabled disablePictureInPicture disableRemotePlayback formNoValidate hidden loop noModule noValidate open playsInline readOnly required reversed scoped seamless itemScope".split(" ").forEach((function(e){g[e]=new v(e,3,!1,e.toLowerCase(),null,!1,!1)})),["checked","multiple","muted","selected"].forEach((function(e){g[e]=new v(e,3,!0,e,null,!1,!1)})),["capture","download"].forEach((function(e){g[e]=new v(e,4,!1,e,null,!1,!1)})),["cols","rows","size","span"].forEach((function(e){g[e]=new v(e,6,!1,e,null,!1,!1)})),["rowSpan","start"].forEach((function(e){g[e]=new v(e,5,!1,e.toLowerCase(),null,!1,!1)}));var y=/[\-:]([a-z])/g;function b(e){return e[1].toUpperCase()}function x(e,t,n,r){var o=g.hasOwnProperty(t)?g[t]:null;(null!==o?0===o.type:!r&&2<t.length&&("o"===t[0]||"O"===t[0])&&("n"===t[1]||"N"===t[1]))||(function(e,t,n,r){if(null==t||function(e,t,n,r){if(null!==n&&0===n.type)return!1;switch(typeof t){case"function":case"symbol":return!0;case"boolean":return!r&&(null!==n?!n.acceptsBooleans:"data-"!==(e=e.toLowerCase().slice(0,5))&&"aria-"!==e);default:return!1}}(e,t,n,r))return!0;if(r)return!1;if(null!==n)switch(n.type){case 3:return!t;case 4:return!1===t;case 5:return isNaN(t);case 6:return isNaN(t)||1>t}return!1}(t,n,o,r)&&(n=null),r||null===o?function(e){return!!d.call(m,e)||!d.call(h,e)&&(f.test(e)?m[e]=!0:(h[e]=!0,!1))}(t)&&(null===n?e.removeAttribute(t):e.setAttribute(t,""+n)):o.mustUseProperty?e[o.propertyName]=null===n?3!==o.type&&"":n:(t=o.attributeName,r=o.attributeNamespace,null===n?e.removeAttribute(t):(n=3===(o=o.type)||4===o&&!0===n?"":""+n,r?e.setAttributeNS(r,t,n):e.setAttribute(t,n))))}"accent-height alignment-baseline arabic-form baseline-shift cap-height clip-path clip-rule color-interpolation color-interpolation-filters color-profile color-rendering dominant-baseline enable-background fill-opacity fill-rule flood-color flood-opacity font-family font-size font-size-adjust font-stretch font-style font-variant font-weight glyph-name glyph-orientation-horizontal glyph-orientation-vertical horiz-adv-x horiz-origin-x image-rendering letter-spacing lighting-color marker-end marker-mid marker-start overline-position overline-thickness paint-order panose-1 pointer-events rendering-intent shape-rendering stop-color stop-opacity strikethrough-position strikethrough-thickness stroke-dasharray stroke-dashoffset stroke-linecap stroke-linejoin stroke-miterlimit stroke-opacity stroke-width text-anchor text-decoration text-rendering underline-position underline-thickness unicode-bidi unicode-range units-per-em v-alphabetic v-hanging v-ideographic v-mathematical vector-effect vert-adv-y vert-origin-x vert-origin-y word-spacing writing-mode xmlns:xlink x-height".split(" ").forEach((function(e){var t=e.replace(y,b);g[t]=new v(t,1,!1,e,null,!1,!1)})),"xlink:actuate xlink:arcrole xlink:role xlink:show xlink:title xlink:type".split(" ").forEach((function(e){var t=e.replace(y,b);g[t]=new v(t,1,!1,e,"http://www.w3.org/1999/xlink",!1,!1)})),["xml:base","xml:lang","xml:space"].forEach((function(e){var t=e.replace(y,b);g[t]=new v(t,1,!1,e,"http://www.w3.org/XML/1998/namespace",!1,!1)})),["tabIndex","crossOrigin"].forEach((function(e){g[e]=new v(e,1,!1,e.toLowerCase(),null,!1,!1)})),g.xlinkHref=new v("xlinkHref",1,!1,"xlink:href","http://www.w3.org/1999/xlink",!0,!1),["src","href","action","formAction"].forEach((function(e){g[e]=new v(e,1,!1,e.toLowerCase(),null,!0,!0)}));var w=r.__SECRET_INTERNALS_DO_NOT_USE_OR_YOU_WILL_BE_FIRED,k=60103,E=60106,S=60107,_=60108,O=60114,A=60109,R=60110,C=60112,T=60113,P=60120,I=60115,j=60116,L=60121,N=60128,M=60129,D=60130,F=60131;if("function"==typeof Symbol&&Symbol.for){var z=Symbol.for;k=z("react.element"),E=z("react.portal"),S=z("react.fragment"),_=z("react.strict_mode"),O=z("react.profiler"),A=z("react.provider"),R=z("react.context"),C=z("react.forward_ref"),T=z("react.suspense"),P=z("react.suspense_list"),I=z("react.memo"),j=z("react.lazy"),L=z("react.block"),z("react.scope"),N=z("react.opaque.id"),M=z("react.debug_trace_mode"),D=z("react.offscreen"),F=z("react.legacy_hidden")}var U,B="function"==typeof Symbol&&Symbol.iterator;function $(e){return null===e||"object"!=typeof e?null:"function"==typeof(e=B&&e[B]||e["@@iterator"])?e:null}function q(e){if(void 0===U)try{throw Error()}catch(e){var t=e.stack.trim().match(/\n( *(at )?)/);U=t&&t[1]||""}return"\n"+U+e}var W=!1;function V(e,t){if(!e||W)return"";W=!0;var n=Error.prepareStackTrace;Error.prepareStackTrace=void 0;try{if(t)if(t=function(){throw Error()},Object.defineProperty(t.prototype,"props",{set:function(){throw Error()}}),"object"==typeof Reflect&&Reflect.construct){try{Reflect.construct(t,[])}catch(e){var r=e}Reflect.construct(e,[],t)}else{try{t.call()}catch(e){r=e}e.call(t.prototype)}else{try{throw Error()}catch(e){r=e}e()}}catch(e){if(e&&r&&"string"==typeof e.stack){for(var o=e.stack.split("\n"),i=r.stack.split("\n"),a=o.length-1,s=i.length-1;1<=a&&0<=s&&o[a]!==i[s];)s--;for(;1<=a&&0<=s;a--,s--)if(o[a]!==i[s]){if(1!==a||1!==s)do{if(a--,0>--s||o[a]!==i[s])return"\n"+o[a].replace(" at new "," at ")}while(1<=a&&0<=s);break}}}finally{W=!1,Error.prepareStackTrace=n}return(e=e?e.displayName||e.name:"")?q(e):""}function H(e){switch(e.tag){case 5:return q(e.type);case 16:return q("Lazy");case 13:return q("Suspense");case 19:return q("SuspenseList");case 0:case 2:case 15:return V(e.type,!1);case 11:return V(e.type.render,!1);case 22:return V(e.type._render,!1);case 1:return V(e.type,!0);default:return""}}function Y(e){if(null==e)return null;if("function"==typeof e)return e.displayName||e.name||null;if("string"==typeof e)return e;switch(e){case S:return"Fragment";case E:return"Portal";case O:return"Profiler";case _:return"StrictMode";case T:return"Suspense";case P:return"SuspenseList"}if("object"==typeof e)switch(e.$$typeof){case R:return(e.displayName||"Context")+".Consumer";case A:return(e._context.displayName||"Context")+".Provider";case C:var t=e.render;return t=t.displayName||t.name||"",e.displayName||(""!==t?"ForwardRef("+t+")":"ForwardRef");case I:return Y(e.type);case L:return Y(e._render);case j:t=e._payload,e=e._init;try{return Y(e(t))}catch(e){}}return null}function G(e){switch(typeof e){case"boolean":case"number":case"object":case"string":case"undefined":return e;default:return""}}function Q(e){var t=e.type;return(e=e.nodeName)&&"input"===e.toLowerCase()&&("checkbox"===t||"radio"===t)}function X(e){e._valueTracker||(e._valueTracker=function(e){var t=Q(e)?"checked":"value",n=Object.getOwnPropertyDescriptor(e.constructor.prototype,t),r=""+e[t];if(!e.hasOwnProperty(t)&&void 0!==n&&"function"==typeof n.get&&"function"==typeof n.set){var o=n.get,i=n.set;return Object.defineProperty(e,t,{configurable:!0,get:function(){return o.call(this)},set:function(e){r=""+e,i.call(this,e)}}),Object.defineProperty(e,t,{enumerable:n.enumerable}),{getValue:function(){return r},setValue:function(e){r=""+e},stopTracking:function(){e._valueTracker=null,delete e[t]}}}}(e))}function K(e){if(!e)return!1;var t=e._valueTracker;if(!t)return!0;var n=t.getValue(),r="";return e&&(r=Q(e)?e.checked?"true":"false":e.value),(e=r)!==n&&(t.setValue(e),!0)}function Z(e){if(void 0===(e=e||("undefined"!=typeof document?document:void 0)))return null;try{return e.activeElement||e.body}catch(t){return e.body}}function J(e,t){var n=t.checked;return o({},t,{defaultChecked:void 0,defaultValue:void 0,value:void 0,checked:null!=n?n:e._wrapperState.initialChecked})}function ee(e,t){var n=null==t.defaultValue?"":t.defaultValue,r=null!=t.checked?t.checked:t.defaultChecked;n=G(null!=t.value?t.value:n),e._wrapperState={initialChecked:r,initialValue:n,controlled:"checkbox"===t.type||"radio"===t.type?null!=t.checked:null!=t.value}}function te(e,t){null!=(t=t.checked)&&x(e,"checked",t,!1)}function ne(e,t){te(e,t);var n=G(t.value),r=t.type;if(null!=n)"number"===r?(0===n&&""===e.value||e.value!=n)&&(e.value=""+n):e.value!==""+n&&(e.value=""+n);else if("submit"===r||"reset"===r)return void e.removeAttribute("value");t.hasOwnProperty("value")?oe(e,t.type,n):t.hasOwnProperty("defaultValue")&&oe(e,t.type,G(t.defaultValue)),null==t.checked&&null!=t.defaultChecked&&(e.defaultChecked=!!t.defaultChecked)}function re(e,t,n){if(t.hasOwnProperty("value")||t.hasOwnProperty("defaultValue")){var r=t.type;if(!("submit"!==r&&"reset"!==r||void 0!==t.value&&null!==t.value))return;t=""+e._wrapperState.initialValue,n||t===e.value||(e.value=t),e.defaultValue=t}""!==(n=e.name)&&(e.name=""),e.defaultChecked=!!e._wrapperState.initialChecked,""!==n&&(e.name=n)}function oe(e,t,n){"number"===t&&Z(e.ownerDocument)===e||(null==n?e.defaultValue=""+e._wrapperState.initialValue:e.defaultValue!==""+n&&(e.defaultValue=""+n))}function ie(e,t){return e=o({children:void 0},t),(t=function(e){var t="";return r.Children.forEach(e,(function(e){null!=e&&(t+=e)})),t}(t.children))&&(e.children=t),e}function ae(e,t,n,r){if(e=e.options,t){t={};for(var o=0;o<n.length;o++)t["$"+n[o]]=!0;for(n=0;n<e.length;n++)o=t.hasOwnProperty("$"+e[n].value),e[n].selected!==o&&(e[n].selected=o),o&&r&&(e[n].defaultSelected=!0)}else{for(n=""+G(n),t=null,o=0;o<e.length;o++){if(e[o].value===n)return e[o].selected=!0,void(r&&(e[o].defaultSelected=!0));null!==t||e[o].disabled||(t=e[o])}null!==t&&(t.selected=!0)}}function se(e,t){if(null!=t.dangerouslySetInnerHTML)throw Error(a(91));return o({},t,{value:void 0,defaultValue:void 0,children:""+e._wrapperState.initialValue})}function le(e,t){var n=t.value;if(null==n){if(n=t.children,t=t.defaultValue,null!=n){if(null!=t)throw Error(a(92));if(Array.isArray(n)){if(!(1>=n.length))throw Error(a(93));n=n[0]}t=n}null==t&&(t=""),n=t}e._wrapperState={initialValue:G(n)}}function ce(e,t){var n=G(t.value),r=G(t.defaultValue);null!=n&&((n=""+n)!==e.value&&(e.value=n),null==t.defaultValue&&e.defaultValue!==n&&(e.defaultValue=n)),null!=r&&(e.defaultValue=""+r)}function ue(e){var t=e.textContent;t===e._wrapperState.initialValue&&""!==t&&null!==t&&(e.value=t)}var pe="http://www.w3.org/1999/xhtml";function fe(e){switch(e){case"svg":return"http://www.w3.org/2000/svg";case"math":return"http://www.w3.org/1998/Math/MathML";default:return"http://www.w3.org/1999/xhtml"}}function de(e,t){return null==e||"http://www.w3.org/1999/xhtml"===e?fe(t):"http://www.w3.org/2000/svg"===e&&"foreignObject"===t?"http://www.w3.org/1999/xhtml":e}var he,me,ve=(me=function(e,t){if("http://www.w3.org/2000/svg"!==e.namespaceURI||"innerHTML"in e)e.innerHTML=t;else{for((he=he||document.createElement("div")).innerHTML="<svg>"+t.valueOf().toString()+"</svg>",t=he.firstChild;e.firstChild;)e.removeChild(e.firstChild);for(;t.firstChild;)e.appendChild(t.firstChild)}},"undefined"!=typeof MSApp&&MSApp.execUnsafeLocalFunction?function(e,t,n,r){MSApp.execUnsafeLocalFunction((function(){return me(e,t)}))}:me);function ge(e,t){if(t){var n=e.firstChild;if(n&&n===e.lastChild&&3===n.nodeType)return void(n.nodeValue=t)}e.textContent=t}var ye={animationIterationCount:!0,borderImageOutset:!0,borderImageSlice:!0,borderImageWidth:!0,boxFlex:!0,boxFlexGroup:!0,boxOrdinalGroup:!0,columnCount:!0,columns:!0,flex:!0,flexGrow:!0,flexPositive:!0,flexShrink:!0,flexNegative:!0,flexOrder:!0,gridArea:!0,gridRow:!0,gridRowEnd:!0,gridRowSpan:!0,gridRowStart:!0,gridColumn:!0,gridColumnEnd:!0,gridColumnSpan:!0,gridColumnStart:!0,fontWeight:!0,lineClamp:!0,lineHeight:!0,opacity:!0,order:!0,orphans:!0,tabSize:!0,widows:!0,zIndex:!0,zoom:!0,fillOpacity:!0,floodOpacity:!0,stopOpacity:!0,strokeDasharray:!0,strokeDashoffset:!0,strokeMiterlimit:!0,strokeOpacity:!0,strokeWidth:!0},be=["Webkit","ms","Moz","O"];function xe(e,t,n){return null==t||"boolean"==typeof t||""===t?"":n||"number"!=typeof t||0===t||ye.hasOwnProperty(e)&&ye[e]?(""+t).trim():t+"px"}function we(e,t){for(var n in e=e.style,t)if(t.hasOwnProperty(n)){var r=0===n.indexOf("--"),o=xe(n,t[n],r);"float"===n&&(n="cssFloat"),r?e.setProperty(n,o):e[n]=o}}Object.keys(ye).forEach((function(e){be.forEach((function(t){t=t+e.charAt(0).toUpperCase()+e.substring(1),ye[t]=ye[e]}))}));var ke=o({menuitem:!0},{area:!0,base:!0,br:!0,col:!0,embed:!0,hr:!0,img:!0,input:!0,keygen:!0,link:!0,meta:!0,param:!0,source:!0,track:!0,wbr:!0});function Ee(e,t){if(t){if(ke[e]&&(null!=t.children||null!=t.dangerouslySetInnerHTML))throw Error(a(137,e));if(null!=t.dangerouslySetInnerHTML){if(null!=t.children)throw Error(a(60));if("object"!=typeof t.dangerouslySetInnerHTML||!("__html"in t.dangerouslySetInnerHTML))throw Error(a(61))}if(null!=t.style&&"object"!=typeof t.style)throw Error(a(62))}}function Se(e,t){if(-1===e.indexOf("-"))return"string"==typeof t.is;switch(e){case"annotation-xml":case"color-profile":case"font-face":case"font-face-src":case"font-face-uri":case"font-face-format":case"font-face-name":case"missing-glyph":return!1;default:return!0}}function _e(e){return(e=e.target||e.srcElement||window).correspondingUseElement&&(e=e.correspondingUseElement),3===e.nodeType?e.parentNode:e}var Oe=null,Ae=null,Re=null;function Ce(e){if(e=Zr(e)){if("function"!=typeof Oe)throw Error(a(280));var t=e.stateNode;t&&(t=eo(t),Oe(e.stateNode,e.type,t))}}function Te(e){Ae?Re?Re.push(e):Re=[e]:Ae=e}function Pe(){if(Ae){var e=Ae,t=Re;if(Re=Ae=null,Ce(e),t)for(e=0;e<t.length;e++)Ce(t[e])}}function Ie(e,t){return e(t)}function je(e,t,n,r,o){return e(t,n,r,o)}function Le(){}var Ne=Ie,Me=!1,De=!1;function Fe(){null===Ae&&null===Re||(Le(),Pe())}function ze(e,t){var n=e.stateNode;if(null===n)return null;var r=eo(n);if(null===r)return null;n=r[t];e:switch(t){case"onClick":case"onClickCapture":case"onDoubleClick":case"onDoubleClickCapture":case"onMouseDown":case"onMouseDownCapture":case"onMouseMove":case"onMouseMoveCapture":case"onMouseUp":case"onMouseUpCapture":case"onMouseEnter":(r=!r.disabled)||(r=!("button"===(e=e.type)||"input"===e||"select"===e||"textarea"===e)),e=!r;break e;default:e=!1}if(e)return null;if(n&&"function"!=typeof n)throw Error(a(231,t,typeof n));return n}var Ue=!1;if(p)try{var Be={};Object.defineProperty(Be,"passive",{get:function(){Ue=!0}}),window.addEventListener("test",Be,Be),window.removeEventListener("test",Be,Be)}catch(me){Ue=!1}function $e(e,t,n,r,o,i,a,s,l){var c=Array.prototype.slice.call(arguments,3);try{t.apply(n,c)}catch(e){this.onError(e)}}var qe=!1,We=null,Ve=!1,He=null,Ye={onError:function(e){qe=!0,We=e}};function Ge(e,t,n,r,o,i,a,s,l){qe=!1,We=null,$e.apply(Ye,arguments)}function Qe(e){var t=e,n=e;if(e.alternate)for(;t.return;)t=t.return;else{e=t;do{0!=(1026&(t=e).flags)&&(n=t.return),e=t.return}while(e)}return 3===t.tag?n:null}function Xe(e){if(13===e.tag){var t=e.memoizedState;if(null===t&&null!==(e=e.alternate)&&(t=e.memoizedState),null!==t)return t.dehydrated}return null}function Ke(e){if(Qe(e)!==e)throw Error(a(188))}function Ze(e,t){for(var n=e.alternate;null!==t;){if(t===e||t===n)return!0;t=t.return}return!1}var Je,et,tt,nt,rt=!1,ot=[],it=null,at=null,st=null,lt=new Map,ct=new Map,ut=[],pt="mousedown mouseup touchcancel touchend touchstart auxclick dblclick pointercancel pointerdown pointerup dragend dragstart drop compositionend compositionstart keydown keypress keyup input textInput copy cut paste click change contextmenu reset submit".split(" ");function ft(e,t,n,r,o){return{blockedOn:e,domEventName:t,eventSystemFlags:16|n,nativeEvent:o,targetContainers:[r]}}function dt(e,t){switch(e){case"focusin":case"focusout":it=null;break;case"dragenter":case"dragleave":at=null;break;case"mouseover":case"mouseout":st=null;break;case"pointerover":case"pointerout":lt.delete(t.pointerId);break;case"gotpointercapture":case"lostpointercapture":ct.delete(t.pointerId)}}function ht(e,t,n,r,o,i){return null===e||e.nativeEvent!==i?(e=ft(t,n,r,o,i),null!==t&&null!==(t=Zr(t))&&et(t),e):(e.eventSystemFlags|=r,t=e.targetContainers,null!==o&&-1===t.indexOf(o)&&t.push(o),e)}function mt(e){var t=Kr(e.target);if(null!==t){var n=Qe(t);if(null!==n)if(13===(t=n.tag)){if(null!==(t=Xe(n)))return e.blockedOn=t,void nt(e.lanePriority,(function(){i.unstable_runWithPriority(e.priority,(function(){tt(n)}))}))}else if(3===t&&n.stateNode.hydrate)return void(e.blockedOn=3===n.tag?n.stateNode.containerInfo:null)}e.blockedOn=null}function vt(e){if(null!==e.blockedOn)return!1;for(var t=e.targetContainers;0<t.length;){var n=Kt(e.domEventName,e.eventSystemFlags,t[0],e.nativeEvent);if(null!==n)return null!==(t=Zr(n))&&et(t),e.blockedOn=n,!1;t.shift()}return!0}function gt(e,t,n){vt(e)&&n.delete(t)}function yt(){for(rt=!1;0<ot.length;){var e=ot[0];if(null!==e.blockedOn){null!==(e=Zr(e.blockedOn))&&Je(e);break}for(var t=e.targetContainers;0<t.length;){var n=Kt(e.domEventName,e.eventSystemFlags,t[0],e.nativeEvent);if(null!==n){e.blockedOn=n;break}t.shift()}null===e.blockedOn&&ot.shift()}null!==it&&vt(it)&&(it=null),null!==at&&vt(at)&&(at=null),null!==st&&vt(st)&&(st=null),lt.forEach(gt),ct.forEach(gt)}function bt(e,t){e.blockedOn===t&&(e.blockedOn=null,rt||(rt=!0,i.unstable_scheduleCallback(i.unstable_NormalPriority,yt)))}function xt(e){function t(t){return bt(t,e)}if(0<ot.length){bt(ot[0],e);for(var n=1;n<ot.length;n++){var r=ot[n];r.blockedOn===e&&(r.blockedOn=null)}}for(null!==it&&bt(it,e),null!==at&&bt(at,e),null!==st&&bt(st,e),lt.forEach(t),ct.forEach(t),n=0;n<ut.length;n++)(r=ut[n]).blockedOn===e&&(r.blockedOn=null);for(;0<ut.length&&null===(n=ut[0]).blockedOn;)mt(n),null===n.blockedOn&&ut.shift()}function wt(e,t){var n={};return n[e.toLowerCase()]=t.toLowerCase(),n["Webkit"+e]="webkit"+t,n["Moz"+e]="moz"+t,n}var kt={animationend:wt("Animation","AnimationEnd"),animationiteration:wt("Animation","AnimationIteration"),animationstart:wt("Animation","AnimationStart"),transitionend:wt("Transition","TransitionEnd")},Et={},St={};function _t(e){if(Et[e])return Et[e];if(!kt[e])return e;var t,n=kt[e];for(t in n)if(n.hasOwnProperty(t)&&t in St)return Et[e]=n[t];return e}p&&(St=document.createElement("div").style,"AnimationEvent"in window||(delete kt.animationend.animation,delete kt.animationiteration.animation,delete kt.animationstart.animation),"TransitionEvent"in window||delete kt.transitionend.transition);var Ot=_t("animationend"),At=_t("animationiteration"),Rt=_t("animationstart"),Ct=_t("transitionend"),Tt=new Map,Pt=new Map,It=["abort","abort",Ot,"animationEnd",At,"animationIteration",Rt,"animationStart","canplay","canPlay","canplaythrough","canPlayThrough","durationchange","durationChange","emptied","emptied","encrypted","encrypted","ended","ended","error","error","gotpointercapture","gotPointerCapture","load","load","loadeddata","loadedData","loadedmetadata","loadedMetadata","loadstart","loadStart","lostpointercapture","lostPointerCapture","playing","playing","progress","progress","seeking","seeking","stalled","stalled","suspend","suspend","timeupdate","timeUpdate",Ct,"transitionEnd","waiting","waiting"];function jt(e,t){for(var n=0;n<e.length;n+=2){var r=e[n],o=e[n+1];o="on"+(o[0].toUpperCase()+o.slice(1)),Pt.set(r,t),Tt.set(r,o),c(o,[r])}}(0,i.unstable_now)();var Lt=8;function Nt(e){if(0!=(1&e))return Lt=15,1;if(0!=(2&e))return Lt=14,2;if(0!=(4&e))return Lt=13,4;var t=24&e;return 0!==t?(Lt=12,t):0!=(32&e)?(Lt=11,32):0!=(t=192&e)?(Lt=10,t):0!=(256&e)?(Lt=9,256):0!=(t=3584&e)?(Lt=8,t):0!=(4096&e)?(Lt=7,4096):0!=(t=4186112&e)?(Lt=6,t):0!=(t=62914560&e)?(Lt=5,t):67108864&e?(Lt=4,67108864):0!=(134217728&e)?(Lt=3,134217728):0!=(t=805306368&e)?(Lt=2,t):0!=(1073741824&e)?(Lt=1,1073741824):(Lt=8,e)}function Mt(e,t){var n=e.pendingLanes;if(0===n)return Lt=0;var r=0,o=0,i=e.expiredLanes,a=e.suspendedLanes,s=e.pingedLanes;if(0!==i)r=i,o=Lt=15;else if(0!=(i=134217727&n)){var l=i&~a;0!==l?(r=Nt(l),o=Lt):0!=(s&=i)&&(r=Nt(s),o=Lt)}else 0!=(i=n&~a)?(r=Nt(i),o=Lt):0!==s&&(r=Nt(s),o=Lt);if(0===r)return 0;if(r=n&((0>(r=31-$t(r))?0:1<<r)<<1)-1,0!==t&&t!==r&&0==(t&a)){if(Nt(t),o<=Lt)return t;Lt=o}if(0!==(t=e.entangledLanes))for(e=e.entanglements,t&=r;0<t;)o=1<<(n=31-$t(t)),r|=e[n],t&=~o;return r}function Dt(e){return 0!=(e=-1073741825&e.pendingLanes)?e:1073741824&e?1073741824:0}function Ft(e,t){switch(e){case 15:return 1;case 14:return 2;case 12:return 0===(e=zt(24&~t))?Ft(10,t):e;case 10:return 0===(e=zt(192&~t))?Ft(8,t):e;case 8:return 0===(e=zt(3584&~t))&&0===(e=zt(4186112&~t))&&(e=512),e;case 2:return 0===(t=zt(805306368&~t))&&(t=268435456),t}throw Error(a(358,e))}function zt(e){return e&-e}function Ut(e){for(var t=[],n=0;31>n;n++)t.push(e);return t}function Bt(e,t,n){e.pendingLanes|=t;var r=t-1;e.suspendedLanes&=r,e.pingedLanes&=r,(e=e.eventTimes)[t=31-$t(t)]=n}var $t=Math.clz32?Math.clz32:function(e){return 0===e?32:31-(qt(e)/Wt|0)|0},qt=Math.log,Wt=Math.LN2,Vt=i.unstable_UserBlockingPriority,Ht=i.unstable_runWithPriority,Yt=!0;function Gt(e,t,n,r){Me||Le();var o=Xt,i=Me;Me=!0;try{je(o,e,t,n,r)}finally{(Me=i)||Fe()}}function Qt(e,t,n,r){Ht(Vt,Xt.bind(null,e,t,n,r))}function Xt(e,t,n,r){var o;if(Yt)if((o=0==(4&t))&&0<ot.length&&-1<pt.indexOf(e))e=ft(null,e,t,n,r),ot.push(e);else{var i=Kt(e,t,n,r);if(null===i)o&&dt(e,r);else{if(o){if(-1<pt.indexOf(e))return e=ft(i,e,t,n,r),void ot.push(e);if(function(e,t,n,r,o){switch(t){case"focusin":return it=ht(it,e,t,n,r,o),!0;case"dragenter":return at=ht(at,e,t,n,r,o),!0;case"mouseover":return st=ht(st,e,t,n,r,o),!0;case"pointerover":var i=o.pointerId;return lt.set(i,ht(lt.get(i)||null,e,t,n,r,o)),!0;case"gotpointercapture":return i=o.pointerId,ct.set(i,ht(ct.get(i)||null,e,t,n,r,o)),!0}return!1}(i,e,t,n,r))return;dt(e,r)}Tr(e,t,r,null,n)}}}function Kt(e,t,n,r){var o=_e(r);if(null!==(o=Kr(o))){var i=Qe(o);if(null===i)o=null;else{var a=i.tag;if(13===a){if(null!==(o=Xe(i)))return o;o=null}else if(3===a){if(i.stateNode.hydrate)return 3===i.tag?i.stateNode.containerInfo:null;o=null}else i!==o&&(o=null)}}return Tr(e,t,r,o,n),null}var Zt=null,Jt=null,en=null;function tn(){if(en)return en;var e,t,n=Jt,r=n.length,o="value"in Zt?Zt.value:Zt.textContent,i=o.length;for(e=0;e<r&&n[e]===o[e];e++);var a=r-e;for(t=1;t<=a&&n[r-t]===o[i-t];t++);return en=o.slice(e,1<t?1-t:void 0)}function nn(e){var t=e.keyCode;return"charCode"in e?0===(e=e.charCode)&&13===t&&(e=13):e=t,10===e&&(e=13),32<=e||13===e?e:0}function rn(){return!0}function on(){return!1}function an(e){function t(t,n,r,o,i){for(var a in this._reactName=t,this._targetInst=r,this.type=n,this.nativeEvent=o,this.target=i,this.currentTarget=null,e)e.hasOwnProperty(a)&&(t=e[a],this[a]=t?t(o):o[a]);return this.isDefaultPrevented=(null!=o.defaultPrevented?o.defaultPrevented:!1===o.returnValue)?rn:on,this.isPropagationStopped=on,this}return o(t.prototype,{preventDefault:function(){this.defaultPrevented=!0;var e=this.nativeEvent;e&&(e.preventDefault?e.preventDefault():"unknown"!=typeof e.returnValue&&(e.returnValue=!1),this.isDefaultPrevented=rn)},stopPropagation:function(){var e=this.nativeEvent;e&&(e.stopPropagation?e.stopPropagation():"unknown"!=typeof e.cancelBubble&&(e.cancelBubble=!0),this.isPropagationStopped=rn)},persist:function(){},isPersistent:rn}),t}var sn,ln,cn,un={eventPhase:0,bubbles:0,cancelable:0,timeStamp:function(e){return e.timeStamp||Date.now()},defaultPrevented:0,isTrusted:0},pn=an(un),fn=o({},un,{view:0,detail:0}),dn=an(fn),hn=o({},fn,{screenX:0,screenY:0,clientX:0,clientY:0,pageX:0,pageY:0,ctrlKey:0,shiftKey:0,altKey:0,metaKey:0,getModifierState:_n,button:0,buttons:0,relatedTarget:function(e){return void 0===e.relatedTarget?e.fromElement===e.srcElement?e.toElement:e.fromElement:e.relatedTarget},movementX:function(e){return"movementX"in e?e.movementX:(e!==cn&&(cn&&"mousemove"===e.type?(sn=e.screenX-cn.screenX,ln=e.screenY-cn.screenY):ln=sn=0,cn=e),sn)},movementY:function(e){return"movementY"in e?e.movementY:ln}}),mn=an(hn),vn=an(o({},hn,{dataTransfer:0})),gn=an(o({},fn,{relatedTarget:0})),yn=an(o({},un,{animationName:0,elapsedTime:0,pseudoElement:0})),bn=an(o({},un,{clipboardData:function(e){return"clipboardData"in e?e.clipboardData:window.clipboardData}})),xn=an(o({},un,{data:0})),wn={Esc:"Escape",Spacebar:" ",Left:"ArrowLeft",Up:"ArrowUp",Right:"ArrowRight",Down:"ArrowDown",Del:"Delete",Win:"OS",Menu:"ContextMenu",Apps:"ContextMenu",Scroll:"ScrollLock",MozPrintableKey:"Unidentified"},kn={8:"Backspace",9:"Tab",12:"Clear",13:"Enter",16:"Shift",17:"Control",18:"Alt",19:"Pause",20:"CapsLock",27:"Escape",32:" ",33:"PageUp",34:"PageDown",35:"End",36:"Home",37:"ArrowLeft",38:"ArrowUp",39:"ArrowRight",40:"ArrowDown",45:"Insert",46:"Delete",112:"F1",113:"F2",114:"F3",115:"F4",116:"F5",117:"F6",118:"F7",119:"F8",120:"F9",121:"F10",122:"F11",123:"F12",144:"NumLock",145:"ScrollLock",224:"Meta"},En={Alt:"altKey",Control:"ctrlKey",Meta:"metaKey",Shift:"shiftKey"};function Sn(e){var t=this.nativeEvent;return t.getModifierState?t.getModifierState(e):!!(e=En[e])&&!!t[e]}function _n(){return Sn}var On=an(o({},fn,{key:function(e){if(e.key){var t=wn[e.key]||e.key;if("Unidentified"!==t)return t}return"keypress"===e.type?13===(e=nn(e))?"Enter":String.fromCharCode(e):"keydown"===e.type||"keyup"===e.type?kn[e.keyCode]||"Unidentified":""},code:0,location:0,ctrlKey:0,shiftKey:0,altKey:0,metaKey:0,repeat:0,locale:0,getModifierState:_n,charCode:function(e){return"keypress"===e.type?nn(e):0},keyCode:function(e){return"keydown"===e.type||"keyup"===e.type?e.keyCode:0},which:function(e){return"keypress"===e.type?nn(e):"keydown"===e.type||"keyup"===e.type?e.keyCode:0}})),An=an(o({},hn,{pointerId:0,width:0,height:0,pressure:0,tangentialPressure:0,tiltX:0,tiltY:0,twist:0,pointerType:0,isPrimary:0})),Rn=an(o({},fn,{touches:0,targetTouches:0,changedTouches:0,altKey:0,metaKey:0,ctrlKey:0,shiftKey:0,getModifierState:_n})),Cn=an(o({},un,{propertyName:0,elapsedTime:0,pseudoElement:0})),Tn=an(o({},hn,{deltaX:function(e){return"deltaX"in e?e.deltaX:"wheelDeltaX"in e?-e.wheelDeltaX:0},deltaY:function(e){return"deltaY"in e?e.deltaY:"wheelDeltaY"in e?-e.wheelDeltaY:"wheelDelta"in e?-e.wheelDelta:0},deltaZ:0,deltaMode:0})),Pn=[9,13,27,32],In=p&&"CompositionEvent"in window,jn=null;p&&"documentMode"in document&&(jn=document.documentMode);var Ln=p&&"TextEvent"in window&&!jn,Nn=p&&(!In||jn&&8<jn&&11>=jn),Mn=String.fromCharCode(32),Dn=!1;function Fn(e,t){switch(e){case"keyup":return-1!==Pn.indexOf(t.keyCode);case"keydown":return 229!==t.keyCode;case"keypress":case"mousedown":case"focusout":return!0;default:return!1}}function zn(e){return"object"==typeof(e=e.detail)&&"data"in e?e.data:null}var Un=!1,Bn={color:!0,date:!0,datetime:!0,"datetime-local":!0,email:!0,month:!0,number:!0,password:!0,range:!0,search:!0,tel:!0,text:!0,time:!0,url:!0,week:!0};function $n(e){var t=e&&e.nodeName&&e.nodeName.toLowerCase();return"input"===t?!!Bn[e.type]:"textarea"===t}function qn(e,t,n,r){Te(r),0<(t=Ir(t,"onChange")).length&&(n=new pn("onChange","change",null,n,r),e.push({event:n,listeners:t}))}var Wn=null,Vn=null;function Hn(e){Sr(e,0)}function Yn(e){if(K(Jr(e)))return e}function Gn(e,t){if("change"===e)return t}var Qn=!1;if(p){var Xn;if(p){var Kn="oninput"in document;if(!Kn){var Zn=document.createElement("div");Zn.setAttribute("oninput","return;"),Kn="function"==typeof Zn.oninput}Xn=Kn}else Xn=!1;Qn=Xn&&(!document.documentMode||9<document.documentMode)}function Jn(){Wn&&(Wn.detachEvent("onpropertychange",er),Vn=Wn=null)}function er(e){if("value"===e.propertyName&&Yn(Vn)){var t=[];if(qn(t,Vn,e,_e(e)),e=Hn,Me)e(t);else{Me=!0;try{Ie(e,t)}finally{Me=!1,Fe()}}}}function tr(e,t,n){"focusin"===e?(Jn(),Vn=n,(Wn=t).attachEvent("onpropertychange",er)):"focusout"===e&&Jn()}function nr(e){if("selectionchange"===e||"keyup"===e||"keydown"===e)return Yn(Vn)}function rr(e,t){if("click"===e)return Yn(t)}function or(e,t){if("input"===e||"change"===e)return Yn(t)}var ir="function"==typeof Object.is?Object.is:function(e,t){return e===t&&(0!==e||1/e==1/t)||e!=e&&t!=t},ar=Object.prototype.hasOwnProperty;function sr(e,t){if(ir(e,t))return!0;if("object"!=typeof e||null===e||"object"!=typeof t||null===t)return!1;var n=Object.keys(e),r=Object.keys(t);if(n.length!==r.length)return!1;for(r=0;r<n.length;r++)if(!ar.call(t,n[r])||!ir(e[n[r]],t[n[r]]))return!1;return!0}function lr(e){for(;e&&e.firstChild;)e=e.firstChild;return e}function cr(e,t){var n,r=lr(e);for(e=0;r;){if(3===r.nodeType){if(n=e+r.textContent.length,e<=t&&n>=t)return{node:r,offset:t-e};e=n}e:{for(;r;){if(r.nextSibling){r=r.nextSibling;break e}r=r.parentNode}r=void 0}r=lr(r)}}function ur(e,t){return!(!e||!t)&&(e===t||(!e||3!==e.nodeType)&&(t&&3===t.nodeType?ur(e,t.parentNode):"contains"in e?e.contains(t):!!e.compareDocumentPosition&&!!(16&e.compareDocumentPosition(t))))}function pr(){for(var e=window,t=Z();t instanceof e.HTMLIFrameElement;){try{var n="string"==typeof t.contentWindow.location.href}catch(e){n=!1}if(!n)break;t=Z((e=t.contentWindow).document)}return t}function fr(e){var t=e&&e.nodeName&&e.nodeName.toLowerCase();return t&&("input"===t&&("text"===e.type||"search"===e.type||"tel"===e.type||"url"===e.type||"password"===e.type)||"textarea"===t||"true"===e.contentEditable)}var dr=p&&"documentMode"in document&&11>=document.documentMode,hr=null,mr=null,vr=null,gr=!1;function yr(e,t,n){var r=n.window===n?n.document:9===n.nodeType?n:n.ownerDocument;gr||null==hr||hr!==Z(r)||(r="selectionStart"in(r=hr)&&fr(r)?{start:r.selectionStart,end:r.selectionEnd}:{anchorNode:(r=(r.ownerDocument&&r.ownerDocument.defaultView||window).getSelection()).anchorNode,anchorOffset:r.anchorOffset,focusNode:r.focusNode,focusOffset:r.focusOffset},vr&&sr(vr,r)||(vr=r,0<(r=Ir(mr,"onSelect")).length&&(t=new pn("onSelect","select",null,t,n),e.push({event:t,listeners:r}),t.target=hr)))}jt("cancel cancel click click close close contextmenu contextMenu copy copy cut cut auxclick auxClick dblclick doubleClick dragend dragEnd dragstart dragStart drop drop focusin focus focusout blur input input invalid invalid keydown keyDown keypress keyPress keyup keyUp mousedown mouseDown mouseup mouseUp paste paste pause pause play play pointercancel pointerCancel pointerdown pointerDown pointerup pointerUp ratechange rateChange reset reset seeked seeked submit submit touchcancel touchCancel touchend touchEnd touchstart touchStart volumechange volumeChange".split(" "),0),jt("drag drag dragenter dragEnter dragexit dragExit dragleave dragLeave dragover dragOver mousemove mouseMove mouseout mouseOut mouseover mouseOver pointermove pointerMove pointerout pointerOut pointerover pointerOver scroll scroll toggle toggle touchmove touchMove wheel wheel".split(" "),1),jt(It,2);for(var br="change selectionchange textInput compositionstart compositionend compositionupdate".split(" "),xr=0;xr<br.length;xr++)Pt.set(br[xr],0);u("onMouseEnter",["mouseout","mouseover"]),u("onMouseLeave",["mouseout","mouseover"]),u("onPointerEnter",["pointerout","pointerover"]),u("onPointerLeave",["pointerout","pointerover"]),c("onChange","change click focusin focusout input keydown keyup selectionchange".split(" ")),c("onSelect","focusout contextmenu dragend focusin keydown keyup mousedown mouseup selectionchange".split(" ")),c("onBeforeInput",["compositionend","keypress","textInput","paste"]),c("onCompositionEnd","compositionend focusout keydown keypress keyup mousedown".split(" ")),c("onCompositionStart","compositionstart focusout keydown keypress keyup mousedown".split(" ")),c("onCompositionUpdate","compositionupdate focusout keydown keypress keyup mousedown".split(" "));var wr="abort canplay canplaythrough durationchange emptied encrypted ended error loadeddata loadedmetadata loadstart pause play playing progress ratechange seeked seeking stalled suspend timeupdate volumechange waiting".split(" "),kr=new Set("cancel close invalid load scroll toggle".split(" ").concat(wr));function Er(e,t,n){var r=e.type||"unknown-event";e.currentTarget=n,function(e,t,n,r,o,i,s,l,c){if(Ge.apply(this,arguments),qe){if(!qe)throw Error(a(198));var u=We;qe=!1,We=null,Ve||(Ve=!0,He=u)}}(r,t,void 0,e),e.currentTarget=null}function Sr(e,t){t=0!=(4&t);for(var n=0;n<e.length;n++){var r=e[n],o=r.event;r=r.listeners;e:{var i=void 0;if(t)for(var a=r.length-1;0<=a;a--){var s=r[a],l=s.instance,c=s.currentTarget;if(s=s.listener,l!==i&&o.isPropagationStopped())break e;Er(o,s,c),i=l}else for(a=0;a<r.length;a++){if(l=(s=r[a]).instance,c=s.currentTarget,s=s.listener,l!==i&&o.isPropagationStopped())break e;Er(o,s,c),i=l}}}if(Ve)throw e=He,Ve=!1,He=null,e}function _r(e,t){var n=to(t),r=e+"__bubble";n.has(r)||(Cr(t,e,2,!1),n.add(r))}var Or="_reactListening"+Math.random().toString(36).slice(2);function Ar(e){e[Or]||(e[Or]=!0,s.forEach((function(t){kr.has(t)||Rr(t,!1,e,null),Rr(t,!0,e,null)})))}function Rr(e,t,n,r){var o=4<arguments.length&&void 0!==arguments[4]?arguments[4]:0,i=n;if("selectionchange"===e&&9!==n.nodeType&&(i=n.ownerDocument),null!==r&&!t&&kr.has(e)){if("scroll"!==e)return;o|=2,i=r}var a=to(i),s=e+"__"+(t?"capture":"bubble");a.has(s)||(t&&(o|=4),Cr(i,e,o,t),a.add(s))}function Cr(e,t,n,r){var o=Pt.get(t);switch(void 0===o?2:o){case 0:o=Gt;break;case 1:o=Qt;break;default:o=Xt}n=o.bind(null,t,n,e),o=void 0,!Ue||"touchstart"!==t&&"touchmove"!==t&&"wheel"!==t||(o=!0),r?void 0!==o?e.addEventListener(t,n,{capture:!0,passive:o}):e.addEventListener(t,n,!0):void 0!==o?e.addEventListener(t,n,{passive:o}):e.addEventListener(t,n,!1)}function Tr(e,t,n,r,o){var i=r;if(0==(1&t)&&0==(2&t)&&null!==r)e:for(;;){if(null===r)return;var a=r.tag;if(3===a||4===a){var s=r.stateNode.containerInfo;if(s===o||8===s.nodeType&&s.parentNode===o)break;if(4===a)for(a=r.return;null!==a;){var l=a.tag;if((3===l||4===l)&&((l=a.stateNode.containerInfo)===o||8===l.nodeType&&l.parentNode===o))return;a=a.return}for(;null!==s;){if(null===(a=Kr(s)))return;if(5===(l=a.tag)||6===l){r=i=a;continue e}s=s.parentNode}}r=r.return}!function(e,t,n){if(De)return e();De=!0;try{Ne(e,t,n)}finally{De=!1,Fe()}}((function(){var r=i,o=_e(n),a=[];e:{var s=Tt.get(e);if(void 0!==s){var l=pn,c=e;switch(e){case"keypress":if(0===nn(n))break e;case"keydown":case"keyup":l=On;break;case"focusin":c="focus",l=gn;break;case"focusout":c="blur",l=gn;break;case"beforeblur":case"afterblur":l=gn;break;case"click":if(2===n.button)break e;case"auxclick":case"dblclick":case"mousedown":case"mousemove":case"mouseup":case"mouseout":case"mouseover":case"contextmenu":l=mn;break;case"drag":case"dragend":case"dragenter":case"dragexit":case"dragleave":case"dragover":case"dragstart":case"drop":l=vn;break;case"touchcancel":case"touchend":case"touchmove":case"touchstart":l=Rn;break;case Ot:case At:case Rt:l=yn;break;case Ct:l=Cn;break;case"scroll":l=dn;break;case"wheel":l=Tn;break;case"copy":case"cut":case"paste":l=bn;break;case"gotpointercapture":case"lostpointercapture":case"pointercancel":case"pointerdown":case"pointermove":case"pointerout":case"pointerover":case"pointerup":l=An}var u=0!=(4&t),p=!u&&"scroll"===e,f=u?null!==s?s+"Capture":null:s;u=[];for(var d,h=r;null!==h;){var m=(d=h).stateNode;if(5===d.tag&&null!==m&&(d=m,null!==f&&null!=(m=ze(h,f))&&u.push(Pr(h,m,d))),p)break;h=h.return}0<u.length&&(s=new l(s,c,null,n,o),a.push({event:s,listeners:u}))}}if(0==(7&t)){if(l="mouseout"===e||"pointerout"===e,(!(s="mouseover"===e||"pointerover"===e)||0!=(16&t)||!(c=n.relatedTarget||n.fromElement)||!Kr(c)&&!c[Qr])&&(l||s)&&(s=o.window===o?o:(s=o.ownerDocument)?s.defaultView||s.parentWindow:window,l?(l=r,null!==(c=(c=n.relatedTarget||n.toElement)?Kr(c):null)&&(c!==(p=Qe(c))||5!==c.tag&&6!==c.tag)&&(c=null)):(l=null,c=r),l!==c)){if(u=mn,m="onMouseLeave",f="onMouseEnter",h="mouse","pointerout"!==e&&"pointerover"!==e||(u=An,m="onPointerLeave",f="onPointerEnter",h="pointer"),p=null==l?s:Jr(l),d=null==c?s:Jr(c),(s=new u(m,h+"leave",l,n,o)).target=p,s.relatedTarget=d,m=null,Kr(o)===r&&((u=new u(f,h+"enter",c,n,o)).target=d,u.relatedTarget=p,m=u),p=m,l&&c)e:{for(f=c,h=0,d=u=l;d;d=jr(d))h++;for(d=0,m=f;m;m=jr(m))d++;for(;0<h-d;)u=jr(u),h--;for(;0<d-h;)f=jr(f),d--;for(;h--;){if(u===f||null!==f&&u===f.alternate)break e;u=jr(u),f=jr(f)}u=null}else u=null;null!==l&&Lr(a,s,l,u,!1),null!==c&&null!==p&&Lr(a,p,c,u,!0)}if("select"===(l=(s=r?Jr(r):window).nodeName&&s.nodeName.toLowerCase())||"input"===l&&"file"===s.type)var v=Gn;else if($n(s))if(Qn)v=or;else{v=nr;var g=tr}else(l=s.nodeName)&&"input"===l.toLowerCase()&&("checkbox"===s.type||"radio"===s.type)&&(v=rr);switch(v&&(v=v(e,r))?qn(a,v,n,o):(g&&g(e,s,r),"focusout"===e&&(g=s._wrapperState)&&g.controlled&&"number"===s.type&&oe(s,"number",s.value)),g=r?Jr(r):window,e){case"focusin":($n(g)||"true"===g.contentEditable)&&(hr=g,mr=r,vr=null);break;case"focusout":vr=mr=hr=null;break;case"mousedown":gr=!0;break;case"contextmenu":case"mouseup":case"dragend":gr=!1,yr(a,n,o);break;case"selectionchange":if(dr)break;case"keydown":case"keyup":yr(a,n,o)}var y;if(In)e:{switch(e){case"compositionstart":var b="onCompositionStart";break e;case"compositionend":b="onCompositionEnd";break e;case"compositionupdate":b="onCompositionUpdate";break e}b=void 0}else Un?Fn(e,n)&&(b="onCompositionEnd"):"keydown"===e&&229===n.keyCode&&(b="onCompositionStart");b&&(Nn&&"ko"!==n.locale&&(Un||"onCompositionStart"!==b?"onCompositionEnd"===b&&Un&&(y=tn()):(Jt="value"in(Zt=o)?Zt.value:Zt.textContent,Un=!0)),0<(g=Ir(r,b)).length&&(b=new xn(b,e,null,n,o),a.push({event:b,listeners:g}),(y||null!==(y=zn(n)))&&(b.data=y))),(y=Ln?function(e,t){switch(e){case"compositionend":return zn(t);case"keypress":return 32!==t.which?null:(Dn=!0,Mn);case"textInput":return(e=t.data)===Mn&&Dn?null:e;default:return null}}(e,n):function(e,t){if(Un)return"compositionend"===e||!In&&Fn(e,t)?(e=tn(),en=Jt=Zt=null,Un=!1,e):null;switch(e){case"paste":return null;case"keypress":if(!(t.ctrlKey||t.altKey||t.metaKey)||t.ctrlKey&&t.altKey){if(t.char&&1<t.char.length)return t.char;if(t.which)return String.fromCharCode(t.which)}return null;case"compositionend":return Nn&&"ko"!==t.locale?null:t.data;default:return null}}(e,n))&&0<(r=Ir(r,"onBeforeInput")).length&&(o=new xn("onBeforeInput","beforeinput",null,n,o),a.push({event:o,listeners:r}),o.data=y)}Sr(a,t)}))}function Pr(e,t,n){return{instance:e,listener:t,currentTarget:n}}function Ir(e,t){for(var n=t+"Capture",r=[];null!==e;){var o=e,i=o.stateNode;5===o.tag&&null!==i&&(o=i,null!=(i=ze(e,n))&&r.unshift(Pr(e,i,o)),null!=(i=ze(e,t))&&r.push(Pr(e,i,o))),e=e.return}return r}function jr(e){if(null===e)return null;do{e=e.return}while(e&&5!==e.tag);return e||null}function Lr(e,t,n,r,o){for(var i=t._reactName,a=[];null!==n&&n!==r;){var s=n,l=s.alternate,c=s.stateNode;if(null!==l&&l===r)break;5===s.tag&&null!==c&&(s=c,o?null!=(l=ze(n,i))&&a.unshift(Pr(n,l,s)):o||null!=(l=ze(n,i))&&a.push(Pr(n,l,s))),n=n.return}0!==a.length&&e.push({event:t,listeners:a})}function Nr(){}var Mr=null,Dr=null;function Fr(e,t){switch(e){case"button":case"input":case"select":case"textarea":return!!t.autoFocus}return!1}function zr(e,t){return"textarea"===e||"option"===e||"noscript"===e||"string"==typeof t.children||"number"==typeof t.children||"object"==typeof t.dangerouslySetInnerHTML&&null!==t.dangerouslySetInnerHTML&&null!=t.dangerouslySetInnerHTML.__html}var Ur="function"==typeof setTimeout?setTimeout:void 0,Br="function"==typeof clearTimeout?clearTimeout:void 0;function $r(e){(1===e.nodeType||9===e.nodeType&&null!=(e=e.body))&&(e.textContent="")}function qr(e){for(;null!=e;e=e.nextSibling){var t=e.nodeType;if(1===t||3===t)break}return e}function Wr(e){e=e.previousSibling;for(var t=0;e;){if(8===e.nodeType){var n=e.data;if("$"===n||"$!"===n||"$?"===n){if(0===t)return e;t--}else"/$"===n&&t++}e=e.previousSibling}return null}var Vr=0,Hr=Math.random().toString(36).slice(2),Yr="__reactFiber$"+Hr,Gr="__reactProps$"+Hr,Qr="__reactContainer$"+Hr,Xr="__reactEvents$"+Hr;function Kr(e){var t=e[Yr];if(t)return t;for(var n=e.parentNode;n;){if(t=n[Qr]||n[Yr]){if(n=t.alternate,null!==t.child||null!==n&&null!==n.child)for(e=Wr(e);null!==e;){if(n=e[Yr])return n;e=Wr(e)}return t}n=(e=n).parentNode}return null}function Zr(e){return!(e=e[Yr]||e[Qr])||5!==e.tag&&6!==e.tag&&13!==e.tag&&3!==e.tag?null:e}function Jr(e){if(5===e.tag||6===e.tag)return e.stateNode;throw Error(a(33))}function eo(e){return e[Gr]||null}function to(e){var t=e[Xr];return void 0===t&&(t=e[Xr]=new Set),t}var no=[],ro=-1;function oo(e){return{current:e}}function io(e){0>ro||(e.current=no[ro],no[ro]=null,ro--)}function ao(e,t){ro++,no[ro]=e.current,e.current=t}var so={},lo=oo(so),co=oo(!1),uo=so;function po(e,t){var n=e.type.contextTypes;if(!n)return so;var r=e.stateNode;if(r&&r.__reactInternalMemoizedUnmaskedChildContext===t)return r.__reactInternalMemoizedMaskedChildContext;var o,i={};for(o in n)i[o]=t[o];return r&&((e=e.stateNode).__reactInternalMemoizedUnmaskedChildContext=t,e.__reactInternalMemoizedMaskedChildContext=i),i}function fo(e){return null!=e.childContextTypes}function ho(){io(co),io(lo)}function mo(e,t,n){if(lo.current!==so)throw Error(a(168));ao(lo,t),ao(co,n)}function vo(e,t,n){var r=e.stateNode;if(e=t.childContextTypes,"function"!=typeof r.getChildContext)return n;for(var i in r=r.getChildContext())if(!(i in e))throw Error(a(108,Y(t)||"Unknown",i));return o({},n,r)}function go(e){return e=(e=e.stateNode)&&e.__reactInternalMemoizedMergedChildContext||so,uo=lo.current,ao(lo,e),ao(co,co.current),!0}function yo(e,t,n){var r=e.stateNode;if(!r)throw Error(a(169));n?(e=vo(e,t,uo),r.__reactInternalMemoizedMergedChildContext=e,io(co),io(lo),ao(lo,e)):io(co),ao(co,n)}var bo=null,xo=null,wo=i.unstable_runWithPriority,ko=i.unstable_scheduleCallback,Eo=i.unstable_cancelCallback,So=i.unstable_shouldYield,_o=i.unstable_requestPaint,Oo=i.unstable_now,Ao=i.unstable_getCurrentPriorityLevel,Ro=i.unstable_ImmediatePriority,Co=i.unstable_UserBlockingPriority,To=i.unstable_NormalPriority,Po=i.unstable_LowPriority,Io=i.unstable_IdlePriority,jo={},Lo=void 0!==_o?_o:function(){},No=null,Mo=null,Do=!1,Fo=Oo(),zo=1e4>Fo?Oo:function(){return Oo()-Fo};function Uo(){switch(Ao()){case Ro:return 99;case Co:return 98;case To:return 97;case Po:return 96;case Io:return 95;default:throw Error(a(332))}}function Bo(e){switch(e){case 99:return Ro;case 98:return Co;case 97:return To;case 96:return Po;case 95:return Io;default:throw Error(a(332))}}function $o(e,t){return e=Bo(e),wo(e,t)}function qo(e,t,n){return e=Bo(e),ko(e,t,n)}function Wo(){if(null!==Mo){var e=Mo;Mo=null,Eo(e)}Vo()}function Vo(){if(!Do&&null!==No){Do=!0;var e=0;try{var t=No;$o(99,(function(){for(;e<t.length;e++){var n=t[e];do{n=n(!0)}while(null!==n)}})),No=null}catch(t){throw null!==No&&(No=No.slice(e+1)),ko(Ro,Wo),t}finally{Do=!1}}}var Ho=w.ReactCurrentBatchConfig;function Yo(e,t){if(e&&e.defaultProps){for(var n in t=o({},t),e=e.defaultProps)void 0===t[n]&&(t[n]=e[n]);return t}return t}var Go=oo(null),Qo=null,Xo=null,Ko=null;function Zo(){Ko=Xo=Qo=null}function Jo(e){var t=Go.current;io(Go),e.type._context._currentValue=t}function ei(e,t){for(;null!==e;){var n=e.alternate;if((e.childLanes&t)===t){if(null===n||(n.childLanes&t)===t)break;n.childLanes|=t}else e.childLanes|=t,null!==n&&(n.childLanes|=t);e=e.return}}function ti(e,t){Qo=e,Ko=Xo=null,null!==(e=e.dependencies)&&null!==e.firstContext&&(0!=(e.lanes&t)&&(Ia=!0),e.firstContext=null)}function ni(e,t){if(Ko!==e&&!1!==t&&0!==t)if("number"==typeof t&&1073741823!==t||(Ko=e,t=1073741823),t={context:e,observedBits:t,next:null},null===Xo){if(null===Qo)throw Error(a(308));Xo=t,Qo.dependencies={lanes:0,firstContext:t,responders:null}}else Xo=Xo.next=t;return e._currentValue}var ri=!1;function oi(e){e.updateQueue={baseState:e.memoizedState,firstBaseUpdate:null,lastBaseUpdate:null,shared:{pending:null},effects:null}}function ii(e,t){e=e.updateQueue,t.updateQueue===e&&(t.updateQueue={baseState:e.baseState,firstBaseUpdate:e.firstBaseUpdate,lastBaseUpdate:e.lastBaseUpdate,shared:e.shared,effects:e.effects})}function ai(e,t){return{eventTime:e,lane:t,tag:0,payload:null,callback:null,next:null}}function si(e,t){if(null!==(e=e.updateQueue)){var n=(e=e.shared).pending;null===n?t.next=t:(t.next=n.next,n.next=t),e.pending=t}}function li(e,t){var n=e.updateQueue,r=e.alternate;if(null!==r&&n===(r=r.updateQueue)){var o=null,i=null;if(null!==(n=n.firstBaseUpdate)){do{var a={eventTime:n.eventTime,lane:n.lane,tag:n.tag,payload:n.payload,callback:n.callback,next:null};null===i?o=i=a:i=i.next=a,n=n.next}while(null!==n);null===i?o=i=t:i=i.next=t}else o=i=t;return n={baseState:r.baseState,firstBaseUpdate:o,lastBaseUpdate:i,shared:r.shared,effects:r.effects},void(e.updateQueue=n)}null===(e=n.lastBaseUpdate)?n.firstBaseUpdate=t:e.next=t,n.lastBaseUpdate=t}function ci(e,t,n,r){var i=e.updateQueue;ri=!1;var a=i.firstBaseUpdate,s=i.lastBaseUpdate,l=i.shared.pending;if(null!==l){i.shared.pending=null;var c=l,u=c.next;c.next=null,null===s?a=u:s.next=u,s=c;var p=e.alternate;if(null!==p){var f=(p=p.updateQueue).lastBaseUpdate;f!==s&&(null===f?p.firstBaseUpdate=u:f.next=u,p.lastBaseUpdate=c)}}if(null!==a){for(f=i.baseState,s=0,p=u=c=null;;){l=a.lane;var d=a.eventTime;if((r&l)===l){null!==p&&(p=p.next={eventTime:d,lane:0,tag:a.tag,payload:a.payload,callback:a.callback,next:null});e:{var h=e,m=a;switch(l=t,d=n,m.tag){case 1:if("function"==typeof(h=m.payload)){f=h.call(d,f,l);break e}f=h;break e;case 3:h.flags=-4097&h.flags|64;case 0:if(null==(l="function"==typeof(h=m.payload)?h.call(d,f,l):h))break e;f=o({},f,l);break e;case 2:ri=!0}}null!==a.callback&&(e.flags|=32,null===(l=i.effects)?i.effects=[a]:l.push(a))}else d={eventTime:d,lane:l,tag:a.tag,payload:a.payload,callback:a.callback,next:null},null===p?(u=p=d,c=f):p=p.next=d,s|=l;if(null===(a=a.next)){if(null===(l=i.shared.pending))break;a=l.next,l.next=null,i.lastBaseUpdate=l,i.shared.pending=null}}null===p&&(c=f),i.baseState=c,i.firstBaseUpdate=u,i.lastBaseUpdate=p,Ls|=s,e.lanes=s,e.memoizedState=f}}function ui(e,t,n){if(e=t.effects,t.effects=null,null!==e)for(t=0;t<e.length;t++){var r=e[t],o=r.callback;if(null!==o){if(r.callback=null,r=n,"function"!=typeof o)throw Error(a(191,o));o.call(r)}}}var pi=(new r.Component).refs;function fi(e,t,n,r){n=null==(n=n(r,t=e.memoizedState))?t:o({},t,n),e.memoizedState=n,0===e.lanes&&(e.updateQueue.baseState=n)}var di={isMounted:function(e){return!!(e=e._reactInternals)&&Qe(e)===e},enqueueSetState:function(e,t,n){e=e._reactInternals;var r=il(),o=al(e),i=ai(r,o);i.payload=t,null!=n&&(i.callback=n),si(e,i),sl(e,o,r)},enqueueReplaceState:function(e,t,n){e=e._reactInternals;var r=il(),o=al(e),i=ai(r,o);i.tag=1,i.payload=t,null!=n&&(i.callback=n),si(e,i),sl(e,o,r)},enqueueForceUpdate:function(e,t){e=e._reactInternals;var n=il(),r=al(e),o=ai(n,r);o.tag=2,null!=t&&(o.callback=t),si(e,o),sl(e,r,n)}};function hi(e,t,n,r,o,i,a){return"function"==typeof(e=e.stateNode).shouldComponentUpdate?e.shouldComponentUpdate(r,i,a):!(t.prototype&&t.prototype.isPureReactComponent&&sr(n,r)&&sr(o,i))}function mi(e,t,n){var r=!1,o=so,i=t.contextType;return"object"==typeof i&&null!==i?i=ni(i):(o=fo(t)?uo:lo.current,i=(r=null!=(r=t.contextTypes))?po(e,o):so),t=new t(n,i),e.memoizedState=null!==t.state&&void 0!==t.state?t.state:null,t.updater=di,e.stateNode=t,t._reactInternals=e,r&&((e=e.stateNode).__reactInternalMemoizedUnmaskedChildContext=o,e.__reactInternalMemoizedMaskedChildContext=i),t}function vi(e,t,n,r){e=t.state,"function"==typeof t.componentWillReceiveProps&&t.componentWillReceiveProps(n,r),"function"==typeof t.UNSAFE_componentWillReceiveProps&&t.UNSAFE_componentWillReceiveProps(n,r),t.state!==e&&di.enqueueReplaceState(t,t.state,null)}function gi(e,t,n,r){var o=e.stateNode;o.props=n,o.state=e.memoizedState,o.refs=pi,oi(e);var i=t.contextType;"object"==typeof i&&null!==i?o.context=ni(i):(i=fo(t)?uo:lo.current,o.context=po(e,i)),ci(e,n,o,r),o.state=e.memoizedState,"function"==typeof(i=t.getDerivedStateFromProps)&&(fi(e,t,i,n),o.state=e.memoizedState),"function"==typeof t.getDerivedStateFromProps||"function"==typeof o.getSnapshotBeforeUpdate||"function"!=typeof o.UNSAFE_componentWillMount&&"function"!=typeof o.componentWillMount||(t=o.state,"function"==typeof o.componentWillMount&&o.componentWillMount(),"function"==typeof o.UNSAFE_componentWillMount&&o.UNSAFE_componentWillMount(),t!==o.state&&di.enqueueReplaceState(o,o.state,null),ci(e,n,o,r),o.state=e.memoizedState),"function"==typeof o.componentDidMount&&(e.flags|=4)}var yi=Array.isArray;function bi(e,t,n){if(null!==(e=n.ref)&&"function"!=typeof e&&"object"!=typeof e){if(n._owner){if(n=n._owner){if(1!==n.tag)throw Error(a(309));var r=n.stateNode}if(!r)throw Error(a(147,e));var o=""+e;return null!==t&&null!==t.ref&&"function"==typeof t.ref&&t.ref._stringRef===o?t.ref:((t=function(e){var t=r.refs;t===pi&&(t=r.refs={}),null===e?delete t[o]:t[o]=e})._stringRef=o,t)}if("string"!=typeof e)throw Error(a(284));if(!n._owner)throw Error(a(290,e))}return e}function xi(e,t){if("textarea"!==e.type)throw Error(a(31,"[object Object]"===Object.prototype.toString.call(t)?"object with keys {"+Object.keys(t).join(", ")+"}":t))}function wi(e){function t(t,n){if(e){var r=t.lastEffect;null!==r?(r.nextEffect=n,t.lastEffect=n):t.firstEffect=t.lastEffect=n,n.nextEffect=null,n.flags=8}}function n(n,r){if(!e)return null;for(;null!==r;)t(n,r),r=r.sibling;return null}function r(e,t){for(e=new Map;null!==t;)null!==t.key?e.set(t.key,t):e.set(t.index,t),t=t.sibling;return e}function o(e,t){return(e=Fl(e,t)).index=0,e.sibling=null,e}function i(t,n,r){return t.index=r,e?null!==(r=t.alternate)?(r=r.index)<n?(t.flags=2,n):r:(t.flags=2,n):n}function s(t){return e&&null===t.alternate&&(t.flags=2),t}function l(e,t,n,r){return null===t||6!==t.tag?((t=$l(n,e.mode,r)).return=e,t):((t=o(t,n)).return=e,t)}function c(e,t,n,r){return null!==t&&t.elementType===n.type?((r=o(t,n.props)).ref=bi(e,t,n),r.return=e,r):((r=zl(n.type,n.key,n.props,null,e.mode,r)).ref=bi(e,t,n),r.return=e,r)}function u(e,t,n,r){return null===t||4!==t.tag||t.stateNode.containerInfo!==n.containerInfo||t.stateNode.implementation!==n.implementation?((t=ql(n,e.mode,r)).return=e,t):((t=o(t,n.children||[])).return=e,t)}function p(e,t,n,r,i){return null===t||7!==t.tag?((t=Ul(n,e.mode,r,i)).return=e,t):((t=o(t,n)).return=e,t)}function f(e,t,n){if("string"==typeof t||"number"==typeof t)return(t=$l(""+t,e.mode,n)).return=e,t;if("object"==typeof t&&null!==t){switch(t.$$typeof){case k:return(n=zl(t.type,t.key,t.props,null,e.mode,n)).ref=bi(e,null,t),n.return=e,n;case E:return(t=ql(t,e.mode,n)).return=e,t}if(yi(t)||$(t))return(t=Ul(t,e.mode,n,null)).return=e,t;xi(e,t)}return null}function d(e,t,n,r){var o=null!==t?t.key:null;if("string"==typeof n||"number"==typeof n)return null!==o?null:l(e,t,""+n,r);if("object"==typeof n&&null!==n){switch(n.$$typeof){case k:return n.key===o?n.type===S?p(e,t,n.props.children,r,o):c(e,t,n,r):null;case E:return n.key===o?u(e,t,n,r):null}if(yi(n)||$(n))return null!==o?null:p(e,t,n,r,null);xi(e,n)}return null}function h(e,t,n,r,o){if("string"==typeof r||"number"==typeof r)return l(t,e=e.get(n)||null,""+r,o);if("object"==typeof r&&null!==r){switch(r.$$typeof){case k:return e=e.get(null===r.key?n:r.key)||null,r.type===S?p(t,e,r.props.children,o,r.key):c(t,e,r,o);case E:return u(t,e=e.get(null===r.key?n:r.key)||null,r,o)}if(yi(r)||$(r))return p(t,e=e.get(n)||null,r,o,null);xi(t,r)}return null}function m(o,a,s,l){for(var c=null,u=null,p=a,m=a=0,v=null;null!==p&&m<s.length;m++){p.index>m?(v=p,p=null):v=p.sibling;var g=d(o,p,s[m],l);if(null===g){null===p&&(p=v);break}e&&p&&null===g.alternate&&t(o,p),a=i(g,a,m),null===u?c=g:u.sibling=g,u=g,p=v}if(m===s.length)return n(o,p),c;if(null===p){for(;m<s.length;m++)null!==(p=f(o,s[m],l))&&(a=i(p,a,m),null===u?c=p:u.sibling=p,u=p);return c}for(p=r(o,p);m<s.length;m++)null!==(v=h(p,o,m,s[m],l))&&(e&&null!==v.alternate&&p.delete(null===v.key?m:v.key),a=i(v,a,m),null===u?c=v:u.sibling=v,u=v);return e&&p.forEach((function(e){return t(o,e)})),c}function v(o,s,l,c){var u=$(l);if("function"!=typeof u)throw Error(a(150));if(null==(l=u.call(l)))throw Error(a(151));for(var p=u=null,m=s,v=s=0,g=null,y=l.next();null!==m&&!y.done;v++,y=l.next()){m.index>v?(g=m,m=null):g=m.sibling;var b=d(o,m,y.value,c);if(null===b){null===m&&(m=g);break}e&&m&&null===b.alternate&&t(o,m),s=i(b,s,v),null===p?u=b:p.sibling=b,p=b,m=g}if(y.done)return n(o,m),u;if(null===m){for(;!y.done;v++,y=l.next())null!==(y=f(o,y.value,c))&&(s=i(y,s,v),null===p?u=y:p.sibling=y,p=y);return u}for(m=r(o,m);!y.done;v++,y=l.next())null!==(y=h(m,o,v,y.value,c))&&(e&&null!==y.alternate&&m.delete(null===y.key?v:y.key),s=i(y,s,v),null===p?u=y:p.sibling=y,p=y);return e&&m.forEach((function(e){return t(o,e)})),u}return function(e,r,i,l){var c="object"==typeof i&&null!==i&&i.type===S&&null===i.key;c&&(i=i.props.children);var u="object"==typeof i&&null!==i;if(u)switch(i.$$typeof){case k:e:{for(u=i.key,c=r;null!==c;){if(c.key===u){switch(c.tag){case 7:if(i.type===S){n(e,c.sibling),(r=o(c,i.props.children)).return=e,e=r;break e}break;default:if(c.elementType===i.type){n(e,c.sibling),(r=o(c,i.props)).ref=bi(e,c,i),r.return=e,e=r;break e}}n(e,c);break}t(e,c),c=c.sibling}i.type===S?((r=Ul(i.props.children,e.mode,l,i.key)).return=e,e=r):((l=zl(i.type,i.key,i.props,null,e.mode,l)).ref=bi(e,r,i),l.return=e,e=l)}return s(e);case E:e:{for(c=i.key;null!==r;){if(r.key===c){if(4===r.tag&&r.stateNode.containerInfo===i.containerInfo&&r.stateNode.implementation===i.implementation){n(e,r.sibling),(r=o(r,i.children||[])).return=e,e=r;break e}n(e,r);break}t(e,r),r=r.sibling}(r=ql(i,e.mode,l)).return=e,e=r}return s(e)}if("string"==typeof i||"number"==typeof i)return i=""+i,null!==r&&6===r.tag?(n(e,r.sibling),(r=o(r,i)).return=e,e=r):(n(e,r),(r=$l(i,e.mode,l)).return=e,e=r),s(e);if(yi(i))return m(e,r,i,l);if($(i))return v(e,r,i,l);if(u&&xi(e,i),void 0===i&&!c)switch(e.tag){case 1:case 22:case 0:case 11:case 15:throw Error(a(152,Y(e.type)||"Component"))}return n(e,r)}}var ki=wi(!0),Ei=wi(!1),Si={},_i=oo(Si),Oi=oo(Si),Ai=oo(Si);function Ri(e){if(e===Si)throw Error(a(174));return e}function Ci(e,t){switch(ao(Ai,t),ao(Oi,e),ao(_i,Si),e=t.nodeType){case 9:case 11:t=(t=t.documentElement)?t.namespaceURI:de(null,"");break;default:t=de(t=(e=8===e?t.parentNode:t).namespaceURI||null,e=e.tagName)}io(_i),ao(_i,t)}function Ti(){io(_i),io(Oi),io(Ai)}function Pi(e){Ri(Ai.current);var t=Ri(_i.current),n=de(t,e.type);t!==n&&(ao(Oi,e),ao(_i,n))}function Ii(e){Oi.current===e&&(io(_i),io(Oi))}var ji=oo(0);function Li(e){for(var t=e;null!==t;){if(13===t.tag){var n=t.memoizedState;if(null!==n&&(null===(n=n.dehydrated)||"$?"===n.data||"$!"===n.data))return t}else if(19===t.tag&&void 0!==t.memoizedProps.revealOrder){if(0!=(64&t.flags))return t}else if(null!==t.child){t.child.return=t,t=t.child;continue}if(t===e)break;for(;null===t.sibling;){if(null===t.return||t.return===e)return null;t=t.return}t.sibling.return=t.return,t=t.sibling}return null}var Ni=null,Mi=null,Di=!1;function Fi(e,t){var n=Ml(5,null,null,0);n.elementType="DELETED",n.type="DELETED",n.stateNode=t,n.return=e,n.flags=8,null!==e.lastEffect?(e.lastEffect.nextEffect=n,e.lastEffect=n):e.firstEffect=e.lastEffect=n}function zi(e,t){switch(e.tag){case 5:var n=e.type;return null!==(t=1!==t.nodeType||n.toLowerCase()!==t.nodeName.toLowerCase()?null:t)&&(e.stateNode=t,!0);case 6:return null!==(t=""===e.pendingProps||3!==t.nodeType?null:t)&&(e.stateNode=t,!0);case 13:default:return!1}}function Ui(e){if(Di){var t=Mi;if(t){var n=t;if(!zi(e,t)){if(!(t=qr(n.nextSibling))||!zi(e,t))return e.flags=-1025&e.flags|2,Di=!1,void(Ni=e);Fi(Ni,n)}Ni=e,Mi=qr(t.firstChild)}else e.flags=-1025&e.flags|2,Di=!1,Ni=e}}function Bi(e){for(e=e.return;null!==e&&5!==e.tag&&3!==e.tag&&13!==e.tag;)e=e.return;Ni=e}function $i(e){if(e!==Ni)return!1;if(!Di)return Bi(e),Di=!0,!1;var t=e.type;if(5!==e.tag||"head"!==t&&"body"!==t&&!zr(t,e.memoizedProps))for(t=Mi;t;)Fi(e,t),t=qr(t.nextSibling);if(Bi(e),13===e.tag){if(!(e=null!==(e=e.memoizedState)?e.dehydrated:null))throw Error(a(317));e:{for(e=e.nextSibling,t=0;e;){if(8===e.nodeType){var n=e.data;if("/$"===n){if(0===t){Mi=qr(e.nextSibling);break e}t--}else"$"!==n&&"$!"!==n&&"$?"!==n||t++}e=e.nextSibling}Mi=null}}else Mi=Ni?qr(e.stateNode.nextSibling):null;return!0}function qi(){Mi=Ni=null,Di=!1}var Wi=[];function Vi(){for(var e=0;e<Wi.length;e++)Wi[e]._workInProgressVersionPrimary=null;Wi.length=0}var Hi=w.ReactCurrentDispatcher,Yi=w.ReactCurrentBatchConfig,Gi=0,Qi=null,Xi=null,Ki=null,Zi=!1,Ji=!1;function ea(){throw Error(a(321))}function ta(e,t){if(null===t)return!1;for(var n=0;n<t.length&&n<e.length;n++)if(!ir(e[n],t[n]))return!1;return!0}function na(e,t,n,r,o,i){if(Gi=i,Qi=t,t.memoizedState=null,t.updateQueue=null,t.lanes=0,Hi.current=null===e||null===e.memoizedState?Ra:Ca,e=n(r,o),Ji){i=0;do{if(Ji=!1,!(25>i))throw Error(a(301));i+=1,Ki=Xi=null,t.updateQueue=null,Hi.current=Ta,e=n(r,o)}while(Ji)}if(Hi.current=Aa,t=null!==Xi&&null!==Xi.next,Gi=0,Ki=Xi=Qi=null,Zi=!1,t)throw Error(a(300));return e}function ra(){var e={memoizedState:null,baseState:null,baseQueue:null,queue:null,next:null};return null===Ki?Qi.memoizedState=Ki=e:Ki=Ki.next=e,Ki}function oa(){if(null===Xi){var e=Qi.alternate;e=null!==e?e.memoizedState:null}else e=Xi.next;var t=null===Ki?Qi.memoizedState:Ki.next;if(null!==t)Ki=t,Xi=e;else{if(null===e)throw Error(a(310));e={memoizedState:(Xi=e).memoizedState,baseState:Xi.baseState,baseQueue:Xi.baseQueue,queue:Xi.queue,next:null},null===Ki?Qi.memoizedState=Ki=e:Ki=Ki.next=e}return Ki}function ia(e,t){return"function"==typeof t?t(e):t}function aa(e){var t=oa(),n=t.queue;if(null===n)throw Error(a(311));n.lastRenderedReducer=e;var r=Xi,o=r.baseQueue,i=n.pending;if(null!==i){if(null!==o){var s=o.next;o.next=i.next,i.next=s}r.baseQueue=o=i,n.pending=null}if(null!==o){o=o.next,r=r.baseState;var l=s=i=null,c=o;do{var u=c.lane;if((Gi&u)===u)null!==l&&(l=l.next={lane:0,action:c.action,eagerReducer:c.eagerReducer,eagerState:c.eagerState,next:null}),r=c.eagerReducer===e?c.eagerState:e(r,c.action);else{var p={lane:u,action:c.action,eagerReducer:c.eagerReducer,eagerState:c.eagerState,next:null};null===l?(s=l=p,i=r):l=l.next=p,Qi.lanes|=u,Ls|=u}c=c.next}while(null!==c&&c!==o);null===l?i=r:l.next=s,ir(r,t.memoizedState)||(Ia=!0),t.memoizedState=r,t.baseState=i,t.baseQueue=l,n.lastRenderedState=r}return[t.memoizedState,n.dispatch]}function sa(e){var t=oa(),n=t.queue;if(null===n)throw Error(a(311));n.lastRenderedReducer=e;var r=n.dispatch,o=n.pending,i=t.memoizedState;if(null!==o){n.pending=null;var s=o=o.next;do{i=e(i,s.action),s=s.next}while(s!==o);ir(i,t.memoizedState)||(Ia=!0),t.memoizedState=i,null===t.baseQueue&&(t.baseState=i),n.lastRenderedState=i}return[i,r]}function la(e,t,n){var r=t._getVersion;r=r(t._source);var o=t._workInProgressVersionPrimary;if(null!==o?e=o===r:(e=e.mutableReadLanes,(e=(Gi&e)===e)&&(t._workInProgressVersionPrimary=r,Wi.push(t))),e)return n(t._source);throw Wi.push(t),Error(a(350))}function ca(e,t,n,r){var o=Os;if(null===o)throw Error(a(349));var i=t._getVersion,s=i(t._source),l=Hi.current,c=l.useState((function(){return la(o,t,n)})),u=c[1],p=c[0];c=Ki;var f=e.memoizedState,d=f.refs,h=d.getSnapshot,m=f.source;f=f.subscribe;var v=Qi;return e.memoizedState={refs:d,source:t,subscribe:r},l.useEffect((function(){d.getSnapshot=n,d.setSnapshot=u;var e=i(t._source);if(!ir(s,e)){e=n(t._source),ir(p,e)||(u(e),e=al(v),o.mutableReadLanes|=e&o.pendingLanes),e=o.mutableReadLanes,o.entangledLanes|=e;for(var r=o.entanglements,a=e;0<a;){var l=31-$t(a),c=1<<l;r[l]|=e,a&=~c}}}),[n,t,r]),l.useEffect((function(){return r(t._source,(function(){var e=d.getSnapshot,n=d.setSnapshot;try{n(e(t._source));var r=al(v);o.mutableReadLanes|=r&o.pendingLanes}catch(e){n((function(){throw e}))}}))}),[t,r]),ir(h,n)&&ir(m,t)&&ir(f,r)||((e={pending:null,dispatch:null,lastRenderedReducer:ia,lastRenderedState:p}).dispatch=u=Oa.bind(null,Qi,e),c.queue=e,c.baseQueue=null,p=la(o,t,n),c.memoizedState=c.baseState=p),p}function ua(e,t,n){return ca(oa(),e,t,n)}function pa(e){var t=ra();return"function"==typeof e&&(e=e()),t.memoizedState=t.baseState=e,e=(e=t.queue={pending:null,dispatch:null,lastRenderedReducer:ia,lastRenderedState:e}).dispatch=Oa.bind(null,Qi,e),[t.memoizedState,e]}function fa(e,t,n,r){return e={tag:e,create:t,destroy:n,deps:r,next:null},null===(t=Qi.updateQueue)?(t={lastEffect:null},Qi.updateQueue=t,t.lastEffect=e.next=e):null===(n=t.lastEffect)?t.lastEffect=e.next=e:(r=n.next,n.next=e,e.next=r,t.lastEffect=e),e}function da(e){return e={current:e},ra().memoizedState=e}function ha(){return oa().memoizedState}function ma(e,t,n,r){var o=ra();Qi.flags|=e,o.memoizedState=fa(1|t,n,void 0,void 0===r?null:r)}function va(e,t,n,r){var o=oa();r=void 0===r?null:r;var i=void 0;if(null!==Xi){var a=Xi.memoizedState;if(i=a.destroy,null!==r&&ta(r,a.deps))return void fa(t,n,i,r)}Qi.flags|=e,o.memoizedState=fa(1|t,n,i,r)}function ga(e,t){return ma(516,4,e,t)}function ya(e,t){return va(516,4,e,t)}function ba(e,t){return va(4,2,e,t)}function xa(e,t){return"function"==typeof t?(e=e(),t(e),function(){t(null)}):null!=t?(e=e(),t.current=e,function(){t.current=null}):void 0}function wa(e,t,n){return n=null!=n?n.concat([e]):null,va(4,2,xa.bind(null,t,e),n)}function ka(){}function Ea(e,t){var n=oa();t=void 0===t?null:t;var r=n.memoizedState;return null!==r&&null!==t&&ta(t,r[1])?r[0]:(n.memoizedState=[e,t],e)}function Sa(e,t){var n=oa();t=void 0===t?null:t;var r=n.memoizedState;return null!==r&&null!==t&&ta(t,r[1])?r[0]:(e=e(),n.memoizedState=[e,t],e)}function _a(e,t){var n=Uo();$o(98>n?98:n,(function(){e(!0)})),$o(97<n?97:n,(function(){var n=Yi.transition;Yi.transition=1;try{e(!1),t()}finally{Yi.transition=n}}))}function Oa(e,t,n){var r=il(),o=al(e),i={lane:o,action:n,eagerReducer:null,eagerState:null,next:null},a=t.pending;if(null===a?i.next=i:(i.next=a.next,a.next=i),t.pending=i,a=e.alternate,e===Qi||null!==a&&a===Qi)Ji=Zi=!0;else{if(0===e.lanes&&(null===a||0===a.lanes)&&null!==(a=t.lastRenderedReducer))try{var s=t.lastRenderedState,l=a(s,n);if(i.eagerReducer=a,i.eagerState=l,ir(l,s))return}catch(e){}sl(e,o,r)}}var Aa={readContext:ni,useCallback:ea,useContext:ea,useEffect:ea,useImperativeHandle:ea,useLayoutEffect:ea,useMemo:ea,useReducer:ea,useRef:ea,useState:ea,useDebugValue:ea,useDeferredValue:ea,useTransition:ea,useMutableSource:ea,useOpaqueIdentifier:ea,unstable_isNewReconciler:!1},Ra={readContext:ni,useCallback:function(e,t){return ra().memoizedState=[e,void 0===t?null:t],e},useContext:ni,useEffect:ga,useImperativeHandle:function(e,t,n){return n=null!=n?n.concat([e]):null,ma(4,2,xa.bind(null,t,e),n)},useLayoutEffect:function(e,t){return ma(4,2,e,t)},useMemo:function(e,t){var n=ra();return t=void 0===t?null:t,e=e(),n.memoizedState=[e,t],e},useReducer:function(e,t,n){var r=ra();return t=void 0!==n?n(t):t,r.memoizedState=r.baseState=t,e=(e=r.queue={pending:null,dispatch:null,lastRenderedReducer:e,lastRenderedState:t}).dispatch=Oa.bind(null,Qi,e),[r.memoizedState,e]},useRef:da,useState:pa,useDebugValue:ka,useDeferredValue:function(e){var t=pa(e),n=t[0],r=t[1];return ga((function(){var t=Yi.transition;Yi.transition=1;try{r(e)}finally{Yi.transition=t}}),[e]),n},useTransition:function(){var e=pa(!1),t=e[0];return da(e=_a.bind(null,e[1])),[e,t]},useMutableSource:function(e,t,n){var r=ra();return r.memoizedState={refs:{getSnapshot:t,setSnapshot:null},source:e,subscribe:n},ca(r,e,t,n)},useOpaqueIdentifier:function(){if(Di){var e=!1,t=function(e){return{$$typeof:N,toString:e,valueOf:e}}((function(){throw e||(e=!0,n("r:"+(Vr++).toString(36))),Error(a(355))})),n=pa(t)[1];return 0==(2&Qi.mode)&&(Qi.flags|=516,fa(5,(function(){n("r:"+(Vr++).toString(36))}),void 0,null)),t}return pa(t="r:"+(Vr++).toString(36)),t},unstable_isNewReconciler:!1},Ca={readContext:ni,useCallback:Ea,useContext:ni,useEffect:ya,useImperativeHandle:wa,useLayoutEffect:ba,useMemo:Sa,useReducer:aa,useRef:ha,useState:function(){return aa(ia)},useDebugValue:ka,useDeferredValue:function(e){var t=aa(ia),n=t[0],r=t[1];return ya((function(){var t=Yi.transition;Yi.transition=1;try{r(e)}finally{Yi.transition=t}}),[e]),n},useTransition:function(){var e=aa(ia)[0];return[ha().current,e]},useMutableSource:ua,useOpaqueIdentifier:function(){return aa(ia)[0]},unstable_isNewReconciler:!1},Ta={readContext:ni,useCallback:Ea,useContext:ni,useEffect:ya,useImperativeHandle:wa,useLayoutEffect:ba,useMemo:Sa,useReducer:sa,useRef:ha,useState:function(){return sa(ia)},useDebugValue:ka,useDeferredValue:function(e){var t=sa(ia),n=t[0],r=t[1];return ya((function(){var t=Yi.transition;Yi.transition=1;try{r(e)}finally{Yi.transition=t}}),[e]),n},useTransition:function(){var e=sa(ia)[0];return[ha().current,e]},useMutableSource:ua,useOpaqueIdentifier:function(){return sa(ia)[0]},unstable_isNewReconciler:!1},Pa=w.ReactCurrentOwner,Ia=!1;function ja(e,t,n,r){t.child=null===e?Ei(t,null,n,r):ki(t,e.child,n,r)}function La(e,t,n,r,o){n=n.render;var i=t.ref;return ti(t,o),r=na(e,t,n,r,i,o),null===e||Ia?(t.flags|=1,ja(e,t,r,o),t.child):(t.updateQueue=e.updateQueue,t.flags&=-517,e.lanes&=~o,Za(e,t,o))}function Na(e,t,n,r,o,i){if(null===e){var a=n.type;return"function"!=typeof a||Dl(a)||void 0!==a.defaultProps||null!==n.compare||void 0!==n.defaultProps?((e=zl(n.type,null,r,t,t.mode,i)).ref=t.ref,e.return=t,t.child=e):(t.tag=15,t.type=a,Ma(e,t,a,r,o,i))}return a=e.child,0==(o&i)&&(o=a.memoizedProps,(n=null!==(n=n.compare)?n:sr)(o,r)&&e.ref===t.ref)?Za(e,t,i):(t.flags|=1,(e=Fl(a,r)).ref=t.ref,e.return=t,t.child=e)}function Ma(e,t,n,r,o,i){if(null!==e&&sr(e.memoizedProps,r)&&e.ref===t.ref){if(Ia=!1,0==(i&o))return t.lanes=e.lanes,Za(e,t,i);0!=(16384&e.flags)&&(Ia=!0)}return za(e,t,n,r,i)}function Da(e,t,n){var r=t.pendingProps,o=r.children,i=null!==e?e.memoizedState:null;if("hidden"===r.mode||"unstable-defer-without-hiding"===r.mode)if(0==(4&t.mode))t.memoizedState={baseLanes:0},hl(0,n);else{if(0==(1073741824&n))return e=null!==i?i.baseLanes|n:n,t.lanes=t.childLanes=1073741824,t.memoizedState={baseLanes:e},hl(0,e),null;t.memoizedState={baseLanes:0},hl(0,null!==i?i.baseLanes:n)}else null!==i?(r=i.baseLanes|n,t.memoizedState=null):r=n,hl(0,r);return ja(e,t,o,n),t.child}function Fa(e,t){var n=t.ref;(null===e&&null!==n||null!==e&&e.ref!==n)&&(t.flags|=128)}function za(e,t,n,r,o){var i=fo(n)?uo:lo.current;return i=po(t,i),ti(t,o),n=na(e,t,n,r,i,o),null===e||Ia?(t.flags|=1,ja(e,t,n,o),t.child):(t.updateQueue=e.updateQueue,t.flags&=-517,e.lanes&=~o,Za(e,t,o))}function Ua(e,t,n,r,o){if(fo(n)){var i=!0;go(t)}else i=!1;if(ti(t,o),null===t.stateNode)null!==e&&(e.alternate=null,t.alternate=null,t.flags|=2),mi(t,n,r),gi(t,n,r,o),r=!0;else if(null===e){var a=t.stateNode,s=t.memoizedProps;a.props=s;var l=a.context,c=n.contextType;c="object"==typeof c&&null!==c?ni(c):po(t,c=fo(n)?uo:lo.current);var u=n.getDerivedStateFromProps,p="function"==typeof u||"function"==typeof a.getSnapshotBeforeUpdate;p||"function"!=typeof a.UNSAFE_componentWillReceiveProps&&"function"!=typeof a.componentWillReceiveProps||(s!==r||l!==c)&&vi(t,a,r,c),ri=!1;var f=t.memoizedState;a.state=f,ci(t,r,a,o),l=t.memoizedState,s!==r||f!==l||co.current||ri?("function"==typeof u&&(fi(t,n,u,r),l=t.memoizedState),(s=ri||hi(t,n,s,r,f,l,c))?(p||"function"!=typeof a.UNSAFE_componentWillMount&&"function"!=typeof a.componentWillMount||("function"==typeof a.componentWillMount&&a.componentWillMount(),"function"==typeof a.UNSAFE_componentWillMount&&a.UNSAFE_componentWillMount()),"function"==typeof a.componentDidMount&&(t.flags|=4)):("function"==typeof a.componentDidMount&&(t.flags|=4),t.memoizedProps=r,t.memoizedState=l),a.props=r,a.state=l,a.context=c,r=s):("function"==typeof a.componentDidMount&&(t.flags|=4),r=!1)}else{a=t.stateNode,ii(e,t),s=t.memoizedProps,c=t.type===t.elementType?s:Yo(t.type,s),a.props=c,p=t.pendingProps,f=a.context,l="object"==typeof(l=n.contextType)&&null!==l?ni(l):po(t,l=fo(n)?uo:lo.current);var d=n.getDerivedStateFromProps;(u="function"==typeof d||"function"==typeof a.getSnapshotBeforeUpdate)||"function"!=typeof a.UNSAFE_componentWillReceiveProps&&"function"!=typeof a.componentWillReceiveProps||(s!==p||f!==l)&&vi(t,a,r,l),ri=!1,f=t.memoizedState,a.state=f,ci(t,r,a,o);var h=t.memoizedState;s!==p||f!==h||co.current||ri?("function"==typeof d&&(fi(t,n,d,r),h=t.memoizedState),(c=ri||hi(t,n,c,r,f,h,l))?(u||"function"!=typeof a.UNSAFE_componentWillUpdate&&"function"!=typeof a.componentWillUpdate||("function"==typeof a.componentWillUpdate&&a.componentWillUpdate(r,h,l),"function"==typeof a.UNSAFE_componentWillUpdate&&a.UNSAFE_componentWillUpdate(r,h,l)),"function"==typeof a.componentDidUpdate&&(t.flags|=4),"function"==typeof a.getSnapshotBeforeUpdate&&(t.flags|=256)):("function"!=typeof a.componentDidUpdate||s===e.memoizedProps&&f===e.memoizedState||(t.flags|=4),"function"!=typeof a.getSnapshotBeforeUpdate||s===e.memoizedProps&&f===e.memoizedState||(t.flags|=256),t.memoizedProps=r,t.memoizedState=h),a.props=r,a.state=h,a.context=l,r=c):("function"!=typeof a.componentDidUpdate||s===e.memoizedProps&&f===e.memoizedState||(t.flags|=4),"function"!=typeof a.getSnapshotBeforeUpdate||s===e.memoizedProps&&f===e.memoizedState||(t.flags|=256),r=!1)}return Ba(e,t,n,r,i,o)}function Ba(e,t,n,r,o,i){Fa(e,t);var a=0!=(64&t.flags);if(!r&&!a)return o&&yo(t,n,!1),Za(e,t,i);r=t.stateNode,Pa.current=t;var s=a&&"function"!=typeof n.getDerivedStateFromError?null:r.render();return t.flags|=1,null!==e&&a?(t.child=ki(t,e.child,null,i),t.child=ki(t,null,s,i)):ja(e,t,s,i),t.memoizedState=r.state,o&&yo(t,n,!0),t.child}function $a(e){var t=e.stateNode;t.pendingContext?mo(0,t.pendingContext,t.pendingContext!==t.context):t.context&&mo(0,t.context,!1),Ci(e,t.containerInfo)}var qa,Wa,Va,Ha={dehydrated:null,retryLane:0};function Ya(e,t,n){var r,o=t.pendingProps,i=ji.current,a=!1;return(r=0!=(64&t.flags))||(r=(null===e||null!==e.memoizedState)&&0!=(2&i)),r?(a=!0,t.flags&=-65):null!==e&&null===e.memoizedState||void 0===o.fallback||!0===o.unstable_avoidThisFallback||(i|=1),ao(ji,1&i),null===e?(void 0!==o.fallback&&Ui(t),e=o.children,i=o.fallback,a?(e=Ga(t,e,i,n),t.child.memoizedState={baseLanes:n},t.memoizedState=Ha,e):"number"==typeof o.unstable_expectedLoadTime?(e=Ga(t,e,i,n),t.child.memoizedState={baseLanes:n},t.memoizedState=Ha,t.lanes=33554432,e):((n=Bl({mode:"visible",children:e},t.mode,n,null)).return=t,t.child=n)):(e.memoizedState,a?(o=function(e,t,n,r,o){var i=t.mode,a=e.child;e=a.sibling;var s={mode:"hidden",children:n};return 0==(2&i)&&t.child!==a?((n=t.child).childLanes=0,n.pendingProps=s,null!==(a=n.lastEffect)?(t.firstEffect=n.firstEffect,t.lastEffect=a,a.nextEffect=null):t.firstEffect=t.lastEffect=null):n=Fl(a,s),null!==e?r=Fl(e,r):(r=Ul(r,i,o,null)).flags|=2,r.return=t,n.return=t,n.sibling=r,t.child=n,r}(e,t,o.children,o.fallback,n),a=t.child,i=e.child.memoizedState,a.memoizedState=null===i?{baseLanes:n}:{baseLanes:i.baseLanes|n},a.childLanes=e.childLanes&~n,t.memoizedState=Ha,o):(n=function(e,t,n,r){var o=e.child;return e=o.sibling,n=Fl(o,{mode:"visible",children:n}),0==(2&t.mode)&&(n.lanes=r),n.return=t,n.sibling=null,null!==e&&(e.nextEffect=null,e.flags=8,t.firstEffect=t.lastEffect=e),t.child=n}(e,t,o.children,n),t.memoizedState=null,n))}function Ga(e,t,n,r){var o=e.mode,i=e.child;return t={mode:"hidden",children:t},0==(2&o)&&null!==i?(i.childLanes=0,i.pendingProps=t):i=Bl(t,o,0,null),n=Ul(n,o,r,null),i.return=e,n.return=e,i.sibling=n,e.child=i,n}function Qa(e,t){e.lanes|=t;var n=e.alternate;null!==n&&(n.lanes|=t),ei(e.return,t)}function Xa(e,t,n,r,o,i){var a=e.memoizedState;null===a?e.memoizedState={isBackwards:t,rendering:null,renderingStartTime:0,last:r,tail:n,tailMode:o,lastEffect:i}:(a.isBackwards=t,a.rendering=null,a.renderingStartTime=0,a.last=r,a.tail=n,a.tailMode=o,a.lastEffect=i)}function Ka(e,t,n){var r=t.pendingProps,o=r.revealOrder,i=r.tail;if(ja(e,t,r.children,n),0!=(2&(r=ji.current)))r=1&r|2,t.flags|=64;else{if(null!==e&&0!=(64&e.flags))e:for(e=t.child;null!==e;){if(13===e.tag)null!==e.memoizedState&&Qa(e,n);else if(19===e.tag)Qa(e,n);else if(null!==e.child){e.child.return=e,e=e.child;continue}if(e===t)break e;for(;null===e.sibling;){if(null===e.return||e.return===t)break e;e=e.return}e.sibling.return=e.return,e=e.sibling}r&=1}if(ao(ji,r),0==(2&t.mode))t.memoizedState=null;else switch(o){case"forwards":for(n=t.child,o=null;null!==n;)null!==(e=n.alternate)&&null===Li(e)&&(o=n),n=n.sibling;null===(n=o)?(o=t.child,t.child=null):(o=n.sibling,n.sibling=null),Xa(t,!1,o,n,i,t.lastEffect);break;case"backwards":for(n=null,o=t.child,t.child=null;null!==o;){if(null!==(e=o.alternate)&&null===Li(e)){t.child=o;break}e=o.sibling,o.sibling=n,n=o,o=e}Xa(t,!0,n,null,i,t.lastEffect);break;case"together":Xa(t,!1,null,null,void 0,t.lastEffect);break;default:t.memoizedState=null}return t.child}function Za(e,t,n){if(null!==e&&(t.dependencies=e.dependencies),Ls|=t.lanes,0!=(n&t.childLanes)){if(null!==e&&t.child!==e.child)throw Error(a(153));if(null!==t.child){for(n=Fl(e=t.child,e.pendingProps),t.child=n,n.return=t;null!==e.sibling;)e=e.sibling,(n=n.sibling=Fl(e,e.pendingProps)).return=t;n.sibling=null}return t.child}return null}function Ja(e,t){if(!Di)switch(e.tailMode){case"hidden":t=e.tail;for(var n=null;null!==t;)null!==t.alternate&&(n=t),t=t.sibling;null===n?e.tail=null:n.sibling=null;break;case"collapsed":n=e.tail;for(var r=null;null!==n;)null!==n.alternate&&(r=n),n=n.sibling;null===r?t||null===e.tail?e.tail=null:e.tail.sibling=null:r.sibling=null}}function es(e,t,n){var r=t.pendingProps;switch(t.tag){case 2:case 16:case 15:case 0:case 11:case 7:case 8:case 12:case 9:case 14:return null;case 1:return fo(t.type)&&ho(),null;case 3:return Ti(),io(co),io(lo),Vi(),(r=t.stateNode).pendingContext&&(r.context=r.pendingContext,r.pendingContext=null),null!==e&&null!==e.child||($i(t)?t.flags|=4:r.hydrate||(t.flags|=256)),null;case 5:Ii(t);var i=Ri(Ai.current);if(n=t.type,null!==e&&null!=t.stateNode)Wa(e,t,n,r),e.ref!==t.ref&&(t.flags|=128);else{if(!r){if(null===t.stateNode)throw Error(a(166));return null}if(e=Ri(_i.current),$i(t)){r=t.stateNode,n=t.type;var s=t.memoizedProps;switch(r[Yr]=t,r[Gr]=s,n){case"dialog":_r("cancel",r),_r("close",r);break;case"iframe":case"object":case"embed":_r("load",r);break;case"video":case"audio":for(e=0;e<wr.length;e++)_r(wr[e],r);break;case"source":_r("error",r);break;case"img":case"image":case"link":_r("error",r),_r("load",r);break;case"details":_r("toggle",r);break;case"input":ee(r,s),_r("invalid",r);break;case"select":r._wrapperState={wasMultiple:!!s.multiple},_r("invalid",r);break;case"textarea":le(r,s),_r("invalid",r)}for(var c in Ee(n,s),e=null,s)s.hasOwnProperty(c)&&(i=s[c],"children"===c?"string"==typeof i?r.textContent!==i&&(e=["children",i]):"number"==typeof i&&r.textContent!==""+i&&(e=["children",""+i]):l.hasOwnProperty(c)&&null!=i&&"onScroll"===c&&_r("scroll",r));switch(n){case"input":X(r),re(r,s,!0);break;case"textarea":X(r),ue(r);break;case"select":case"option":break;default:"function"==typeof s.onClick&&(r.onclick=Nr)}r=e,t.updateQueue=r,null!==r&&(t.flags|=4)}else{switch(c=9===i.nodeType?i:i.ownerDocument,e===pe&&(e=fe(n)),e===pe?"script"===n?((e=c.createElement("div")).innerHTML="<script><\/script>",e=e.removeChild(e.firstChild)):"string"==typeof r.is?e=c.createElement(n,{is:r.is}):(e=c.createElement(n),"select"===n&&(c=e,r.multiple?c.multiple=!0:r.size&&(c.size=r.size))):e=c.createElementNS(e,n),e[Yr]=t,e[Gr]=r,qa(e,t),t.stateNode=e,c=Se(n,r),n){case"dialog":_r("cancel",e),_r("close",e),i=r;break;case"iframe":case"object":case"embed":_r("load",e),i=r;break;case"video":case"audio":for(i=0;i<wr.length;i++)_r(wr[i],e);i=r;break;case"source":_r("error",e),i=r;break;case"img":case"image":case"link":_r("error",e),_r("load",e),i=r;break;case"details":_r("toggle",e),i=r;break;case"input":ee(e,r),i=J(e,r),_r("invalid",e);break;case"option":i=ie(e,r);break;case"select":e._wrapperState={wasMultiple:!!r.multiple},i=o({},r,{value:void 0}),_r("invalid",e);break;case"textarea":le(e,r),i=se(e,r),_r("invalid",e);break;default:i=r}Ee(n,i);var u=i;for(s in u)if(u.hasOwnProperty(s)){var p=u[s];"style"===s?we(e,p):"dangerouslySetInnerHTML"===s?null!=(p=p?p.__html:void 0)&&ve(e,p):"children"===s?"string"==typeof p?("textarea"!==n||""!==p)&&ge(e,p):"number"==typeof p&&ge(e,""+p):"suppressContentEditableWarning"!==s&&"suppressHydrationWarning"!==s&&"autoFocus"!==s&&(l.hasOwnProperty(s)?null!=p&&"onScroll"===s&&_r("scroll",e):null!=p&&x(e,s,p,c))}switch(n){case"input":X(e),re(e,r,!1);break;case"textarea":X(e),ue(e);break;case"option":null!=r.value&&e.setAttribute("value",""+G(r.value));break;case"select":e.multiple=!!r.multiple,null!=(s=r.value)?ae(e,!!r.multiple,s,!1):null!=r.defaultValue&&ae(e,!!r.multiple,r.defaultValue,!0);break;default:"function"==typeof i.onClick&&(e.onclick=Nr)}Fr(n,r)&&(t.flags|=4)}null!==t.ref&&(t.flags|=128)}return null;case 6:if(e&&null!=t.stateNode)Va(0,t,e.memoizedProps,r);else{if("string"!=typeof r&&null===t.stateNode)throw Error(a(166));n=Ri(Ai.current),Ri(_i.current),$i(t)?(r=t.stateNode,n=t.memoizedProps,r[Yr]=t,r.nodeValue!==n&&(t.flags|=4)):((r=(9===n.nodeType?n:n.ownerDocument).createTextNode(r))[Yr]=t,t.stateNode=r)}return null;case 13:return io(ji),r=t.memoizedState,0!=(64&t.flags)?(t.lanes=n,t):(r=null!==r,n=!1,null===e?void 0!==t.memoizedProps.fallback&&$i(t):n=null!==e.memoizedState,r&&!n&&0!=(2&t.mode)&&(null===e&&!0!==t.memoizedProps.unstable_avoidThisFallback||0!=(1&ji.current)?0===Ps&&(Ps=3):(0!==Ps&&3!==Ps||(Ps=4),null===Os||0==(134217727&Ls)&&0==(134217727&Ns)||pl(Os,Rs))),(r||n)&&(t.flags|=4),null);case 4:return Ti(),null===e&&Ar(t.stateNode.containerInfo),null;case 10:return Jo(t),null;case 17:return fo(t.type)&&ho(),null;case 19:if(io(ji),null===(r=t.memoizedState))return null;if(s=0!=(64&t.flags),null===(c=r.rendering))if(s)Ja(r,!1);else{if(0!==Ps||null!==e&&0!=(64&e.flags))for(e=t.child;null!==e;){if(null!==(c=Li(e))){for(t.flags|=64,Ja(r,!1),null!==(s=c.updateQueue)&&(t.updateQueue=s,t.flags|=4),null===r.lastEffect&&(t.firstEffect=null),t.lastEffect=r.lastEffect,r=n,n=t.child;null!==n;)e=r,(s=n).flags&=2,s.nextEffect=null,s.firstEffect=null,s.lastEffect=null,null===(c=s.alternate)?(s.childLanes=0,s.lanes=e,s.child=null,s.memoizedProps=null,s.memoizedState=null,s.updateQueue=null,s.dependencies=null,s.stateNode=null):(s.childLanes=c.childLanes,s.lanes=c.lanes,s.child=c.child,s.memoizedProps=c.memoizedProps,s.memoizedState=c.memoizedState,s.updateQueue=c.updateQueue,s.type=c.type,e=c.dependencies,s.dependencies=null===e?null:{lanes:e.lanes,firstContext:e.firstContext}),n=n.sibling;return ao(ji,1&ji.current|2),t.child}e=e.sibling}null!==r.tail&&zo()>zs&&(t.flags|=64,s=!0,Ja(r,!1),t.lanes=33554432)}else{if(!s)if(null!==(e=Li(c))){if(t.flags|=64,s=!0,null!==(n=e.updateQueue)&&(t.updateQueue=n,t.flags|=4),Ja(r,!0),null===r.tail&&"hidden"===r.tailMode&&!c.alternate&&!Di)return null!==(t=t.lastEffect=r.lastEffect)&&(t.nextEffect=null),null}else 2*zo()-r.renderingStartTime>zs&&1073741824!==n&&(t.flags|=64,s=!0,Ja(r,!1),t.lanes=33554432);r.isBackwards?(c.sibling=t.child,t.child=c):(null!==(n=r.last)?n.sibling=c:t.child=c,r.last=c)}return null!==r.tail?(n=r.tail,r.rendering=n,r.tail=n.sibling,r.lastEffect=t.lastEffect,r.renderingStartTime=zo(),n.sibling=null,t=ji.current,ao(ji,s?1&t|2:1&t),n):null;case 23:case 24:return ml(),null!==e&&null!==e.memoizedState!=(null!==t.memoizedState)&&"unstable-defer-without-hiding"!==r.mode&&(t.flags|=4),null}throw Error(a(156,t.tag))}function ts(e){switch(e.tag){case 1:fo(e.type)&&ho();var t=e.flags;return 4096&t?(e.flags=-4097&t|64,e):null;case 3:if(Ti(),io(co),io(lo),Vi(),0!=(64&(t=e.flags)))throw Error(a(285));return e.flags=-4097&t|64,e;case 5:return Ii(e),null;case 13:return io(ji),4096&(t=e.flags)?(e.flags=-4097&t|64,e):null;case 19:return io(ji),null;case 4:return Ti(),null;case 10:return Jo(e),null;case 23:case 24:return ml(),null;default:return null}}function ns(e,t){try{var n="",r=t;do{n+=H(r),r=r.return}while(r);var o=n}catch(e){o="\nError generating stack: "+e.message+"\n"+e.stack}return{value:e,source:t,stack:o}}function rs(e,t){try{console.error(t.value)}catch(e){setTimeout((function(){throw e}))}}qa=function(e,t){for(var n=t.child;null!==n;){if(5===n.tag||6===n.tag)e.appendChild(n.stateNode);else if(4!==n.tag&&null!==n.child){n.child.return=n,n=n.child;continue}if(n===t)break;for(;null===n.sibling;){if(null===n.return||n.return===t)return;n=n.return}n.sibling.return=n.return,n=n.sibling}},Wa=function(e,t,n,r){var i=e.memoizedProps;if(i!==r){e=t.stateNode,Ri(_i.current);var a,s=null;switch(n){case"input":i=J(e,i),r=J(e,r),s=[];break;case"option":i=ie(e,i),r=ie(e,r),s=[];break;case"select":i=o({},i,{value:void 0}),r=o({},r,{value:void 0}),s=[];break;case"textarea":i=se(e,i),r=se(e,r),s=[];break;default:"function"!=typeof i.onClick&&"function"==typeof r.onClick&&(e.onclick=Nr)}for(p in Ee(n,r),n=null,i)if(!r.hasOwnProperty(p)&&i.hasOwnProperty(p)&&null!=i[p])if("style"===p){var c=i[p];for(a in c)c.hasOwnProperty(a)&&(n||(n={}),n[a]="")}else"dangerouslySetInnerHTML"!==p&&"children"!==p&&"suppressContentEditableWarning"!==p&&"suppressHydrationWarning"!==p&&"autoFocus"!==p&&(l.hasOwnProperty(p)?s||(s=[]):(s=s||[]).push(p,null));for(p in r){var u=r[p];if(c=null!=i?i[p]:void 0,r.hasOwnProperty(p)&&u!==c&&(null!=u||null!=c))if("style"===p)if(c){for(a in c)!c.hasOwnProperty(a)||u&&u.hasOwnProperty(a)||(n||(n={}),n[a]="");for(a in u)u.hasOwnProperty(a)&&c[a]!==u[a]&&(n||(n={}),n[a]=u[a])}else n||(s||(s=[]),s.push(p,n)),n=u;else"dangerouslySetInnerHTML"===p?(u=u?u.__html:void 0,c=c?c.__html:void 0,null!=u&&c!==u&&(s=s||[]).push(p,u)):"children"===p?"string"!=typeof u&&"number"!=typeof u||(s=s||[]).push(p,""+u):"suppressContentEditableWarning"!==p&&"suppressHydrationWarning"!==p&&(l.hasOwnProperty(p)?(null!=u&&"onScroll"===p&&_r("scroll",e),s||c===u||(s=[])):"object"==typeof u&&null!==u&&u.$$typeof===N?u.toString():(s=s||[]).push(p,u))}n&&(s=s||[]).push("style",n);var p=s;(t.updateQueue=p)&&(t.flags|=4)}},Va=function(e,t,n,r){n!==r&&(t.flags|=4)};var os="function"==typeof WeakMap?WeakMap:Map;function is(e,t,n){(n=ai(-1,n)).tag=3,n.payload={element:null};var r=t.value;return n.callback=function(){qs||(qs=!0,Ws=r),rs(0,t)},n}function as(e,t,n){(n=ai(-1,n)).tag=3;var r=e.type.getDerivedStateFromError;if("function"==typeof r){var o=t.value;n.payload=function(){return rs(0,t),r(o)}}var i=e.stateNode;return null!==i&&"function"==typeof i.componentDidCatch&&(n.callback=function(){"function"!=typeof r&&(null===Vs?Vs=new Set([this]):Vs.add(this),rs(0,t));var e=t.stack;this.componentDidCatch(t.value,{componentStack:null!==e?e:""})}),n}var ss="function"==typeof WeakSet?WeakSet:Set;function ls(e){var t=e.ref;if(null!==t)if("function"==typeof t)try{t(null)}catch(t){Il(e,t)}else t.current=null}function cs(e,t){switch(t.tag){case 0:case 11:case 15:case 22:return;case 1:if(256&t.flags&&null!==e){var n=e.memoizedProps,r=e.memoizedState;t=(e=t.stateNode).getSnapshotBeforeUpdate(t.elementType===t.type?n:Yo(t.type,n),r),e.__reactInternalSnapshotBeforeUpdate=t}return;case 3:return void(256&t.flags&&$r(t.stateNode.containerInfo));case 5:case 6:case 4:case 17:return}throw Error(a(163))}function us(e,t,n){switch(n.tag){case 0:case 11:case 15:case 22:if(null!==(t=null!==(t=n.updateQueue)?t.lastEffect:null)){e=t=t.next;do{if(3==(3&e.tag)){var r=e.create;e.destroy=r()}e=e.next}while(e!==t)}if(null!==(t=null!==(t=n.updateQueue)?t.lastEffect:null)){e=t=t.next;do{var o=e;r=o.next,0!=(4&(o=o.tag))&&0!=(1&o)&&(Cl(n,e),Rl(n,e)),e=r}while(e!==t)}return;case 1:return e=n.stateNode,4&n.flags&&(null===t?e.componentDidMount():(r=n.elementType===n.type?t.memoizedProps:Yo(n.type,t.memoizedProps),e.componentDidUpdate(r,t.memoizedState,e.__reactInternalSnapshotBeforeUpdate))),void(null!==(t=n.updateQueue)&&ui(n,t,e));case 3:if(null!==(t=n.updateQueue)){if(e=null,null!==n.child)switch(n.child.tag){case 5:e=n.child.stateNode;break;case 1:e=n.child.stateNode}ui(n,t,e)}return;case 5:return e=n.stateNode,void(null===t&&4&n.flags&&Fr(n.type,n.memoizedProps)&&e.focus());case 6:case 4:case 12:return;case 13:return void(null===n.memoizedState&&(n=n.alternate,null!==n&&(n=n.memoizedState,null!==n&&(n=n.dehydrated,null!==n&&xt(n)))));case 19:case 17:case 20:case 21:case 23:case 24:return}throw Error(a(163))}function ps(e,t){for(var n=e;;){if(5===n.tag){var r=n.stateNode;if(t)"function"==typeof(r=r.style).setProperty?r.setProperty("display","none","important"):r.display="none";else{r=n.stateNode;var o=n.memoizedProps.style;o=null!=o&&o.hasOwnProperty("display")?o.display:null,r.style.display=xe("display",o)}}else if(6===n.tag)n.stateNode.nodeValue=t?"":n.memoizedProps;else if((23!==n.tag&&24!==n.tag||null===n.memoizedState||n===e)&&null!==n.child){n.child.return=n,n=n.child;continue}if(n===e)break;for(;null===n.sibling;){if(null===n.return||n.return===e)return;n=n.return}n.sibling.return=n.return,n=n.sibling}}function fs(e,t){if(xo&&"function"==typeof xo.onCommitFiberUnmount)try{xo.onCommitFiberUnmount(bo,t)}catch(e){}switch(t.tag){case 0:case 11:case 14:case 15:case 22:if(null!==(e=t.updateQueue)&&null!==(e=e.lastEffect)){var n=e=e.next;do{var r=n,o=r.destroy;if(r=r.tag,void 0!==o)if(0!=(4&r))Cl(t,n);else{r=t;try{o()}catch(e){Il(r,e)}}n=n.next}while(n!==e)}break;case 1:if(ls(t),"function"==typeof(e=t.stateNode).componentWillUnmount)try{e.props=t.memoizedProps,e.state=t.memoizedState,e.componentWillUnmount()}catch(e){Il(t,e)}break;case 5:ls(t);break;case 4:ys(e,t)}}function ds(e){e.alternate=null,e.child=null,e.dependencies=null,e.firstEffect=null,e.lastEffect=null,e.memoizedProps=null,e.memoizedState=null,e.pendingProps=null,e.return=null,e.updateQueue=null}function hs(e){return 5===e.tag||3===e.tag||4===e.tag}function ms(e){e:{for(var t=e.return;null!==t;){if(hs(t))break e;t=t.return}throw Error(a(160))}var n=t;switch(t=n.stateNode,n.tag){case 5:var r=!1;break;case 3:case 4:t=t.containerInfo,r=!0;break;default:throw Error(a(161))}16&n.flags&&(ge(t,""),n.flags&=-17);e:t:for(n=e;;){for(;null===n.sibling;){if(null===n.return||hs(n.return)){n=null;break e}n=n.return}for(n.sibling.return=n.return,n=n.sibling;5!==n.tag&&6!==n.tag&&18!==n.tag;){if(2&n.flags)continue t;if(null===n.child||4===n.tag)continue t;n.child.return=n,n=n.child}if(!(2&n.flags)){n=n.stateNode;break e}}r?vs(e,n,t):gs(e,n,t)}function vs(e,t,n){var r=e.tag,o=5===r||6===r;if(o)e=o?e.stateNode:e.stateNode.instance,t?8===n.nodeType?n.parentNode.insertBefore(e,t):n.insertBefore(e,t):(8===n.nodeType?(t=n.parentNode).insertBefore(e,n):(t=n).appendChild(e),null!=(n=n._reactRootContainer)||null!==t.onclick||(t.onclick=Nr));else if(4!==r&&null!==(e=e.child))for(vs(e,t,n),e=e.sibling;null!==e;)vs(e,t,n),e=e.sibling}function gs(e,t,n){var r=e.tag,o=5===r||6===r;if(o)e=o?e.stateNode:e.stateNode.instance,t?n.insertBefore(e,t):n.appendChild(e);else if(4!==r&&null!==(e=e.child))for(gs(e,t,n),e=e.sibling;null!==e;)gs(e,t,n),e=e.sibling}function ys(e,t){for(var n,r,o=t,i=!1;;){if(!i){i=o.return;e:for(;;){if(null===i)throw Error(a(160));switch(n=i.stateNode,i.tag){case 5:r=!1;break e;case 3:case 4:n=n.containerInfo,r=!0;break e}i=i.return}i=!0}if(5===o.tag||6===o.tag){e:for(var s=e,l=o,c=l;;)if(fs(s,c),null!==c.child&&4!==c.tag)c.child.return=c,c=c.child;else{if(c===l)break e;for(;null===c.sibling;){if(null===c.return||c.return===l)break e;c=c.return}c.sibling.return=c.return,c=c.sibling}r?(s=n,l=o.stateNode,8===s.nodeType?s.parentNode.removeChild(l):s.removeChild(l)):n.removeChild(o.stateNode)}else if(4===o.tag){if(null!==o.child){n=o.stateNode.containerInfo,r=!0,o.child.return=o,o=o.child;continue}}else if(fs(e,o),null!==o.child){o.child.return=o,o=o.child;continue}if(o===t)break;for(;null===o.sibling;){if(null===o.return||o.return===t)return;4===(o=o.return).tag&&(i=!1)}o.sibling.return=o.return,o=o.sibling}}function bs(e,t){switch(t.tag){case 0:case 11:case 14:case 15:case 22:var n=t.updateQueue;if(null!==(n=null!==n?n.lastEffect:null)){var r=n=n.next;do{3==(3&r.tag)&&(e=r.destroy,r.destroy=void 0,void 0!==e&&e()),r=r.next}while(r!==n)}return;case 1:return;case 5:if(null!=(n=t.stateNode)){r=t.memoizedProps;var o=null!==e?e.memoizedProps:r;e=t.type;var i=t.updateQueue;if(t.updateQueue=null,null!==i){for(n[Gr]=r,"input"===e&&"radio"===r.type&&null!=r.name&&te(n,r),Se(e,o),t=Se(e,r),o=0;o<i.length;o+=2){var s=i[o],l=i[o+1];"style"===s?we(n,l):"dangerouslySetInnerHTML"===s?ve(n,l):"children"===s?ge(n,l):x(n,s,l,t)}switch(e){case"input":ne(n,r);break;case"textarea":ce(n,r);break;case"select":e=n._wrapperState.wasMultiple,n._wrapperState.wasMultiple=!!r.multiple,null!=(i=r.value)?ae(n,!!r.multiple,i,!1):e!==!!r.multiple&&(null!=r.defaultValue?ae(n,!!r.multiple,r.defaultValue,!0):ae(n,!!r.multiple,r.multiple?[]:"",!1))}}}return;case 6:if(null===t.stateNode)throw Error(a(162));return void(t.stateNode.nodeValue=t.memoizedProps);case 3:return void((n=t.stateNode).hydrate&&(n.hydrate=!1,xt(n.containerInfo)));case 12:return;case 13:return null!==t.memoizedState&&(Fs=zo(),ps(t.child,!0)),void xs(t);case 19:return void xs(t);case 17:return;case 23:case 24:return void ps(t,null!==t.memoizedState)}throw Error(a(163))}function xs(e){var t=e.updateQueue;if(null!==t){e.updateQueue=null;var n=e.stateNode;null===n&&(n=e.stateNode=new ss),t.forEach((function(t){var r=Ll.bind(null,e,t);n.has(t)||(n.add(t),t.then(r,r))}))}}function ws(e,t){return null!==e&&(null===(e=e.memoizedState)||null!==e.dehydrated)&&null!==(t=t.memoizedState)&&null===t.dehydrated}var ks=Math.ceil,Es=w.ReactCurrentDispatcher,Ss=w.ReactCurrentOwner,_s=0,Os=null,As=null,Rs=0,Cs=0,Ts=oo(0),Ps=0,Is=null,js=0,Ls=0,Ns=0,Ms=0,Ds=null,Fs=0,zs=1/0;function Us(){zs=zo()+500}var Bs,$s=null,qs=!1,Ws=null,Vs=null,Hs=!1,Ys=null,Gs=90,Qs=[],Xs=[],Ks=null,Zs=0,Js=null,el=-1,tl=0,nl=0,rl=null,ol=!1;function il(){return 0!=(48&_s)?zo():-1!==el?el:el=zo()}function al(e){if(0==(2&(e=e.mode)))return 1;if(0==(4&e))return 99===Uo()?1:2;if(0===tl&&(tl=js),0!==Ho.transition){0!==nl&&(nl=null!==Ds?Ds.pendingLanes:0),e=tl;var t=4186112&~nl;return 0==(t&=-t)&&0==(t=(e=4186112&~e)&-e)&&(t=8192),t}return e=Uo(),e=Ft(0!=(4&_s)&&98===e?12:e=function(e){switch(e){case 99:return 15;case 98:return 10;case 97:case 96:return 8;case 95:return 2;default:return 0}}(e),tl)}function sl(e,t,n){if(50<Zs)throw Zs=0,Js=null,Error(a(185));if(null===(e=ll(e,t)))return null;Bt(e,t,n),e===Os&&(Ns|=t,4===Ps&&pl(e,Rs));var r=Uo();1===t?0!=(8&_s)&&0==(48&_s)?fl(e):(cl(e,n),0===_s&&(Us(),Wo())):(0==(4&_s)||98!==r&&99!==r||(null===Ks?Ks=new Set([e]):Ks.add(e)),cl(e,n)),Ds=e}function ll(e,t){e.lanes|=t;var n=e.alternate;for(null!==n&&(n.lanes|=t),n=e,e=e.return;null!==e;)e.childLanes|=t,null!==(n=e.alternate)&&(n.childLanes|=t),n=e,e=e.return;return 3===n.tag?n.stateNode:null}function cl(e,t){for(var n=e.callbackNode,r=e.suspendedLanes,o=e.pingedLanes,i=e.expirationTimes,s=e.pendingLanes;0<s;){var l=31-$t(s),c=1<<l,u=i[l];if(-1===u){if(0==(c&r)||0!=(c&o)){u=t,Nt(c);var p=Lt;i[l]=10<=p?u+250:6<=p?u+5e3:-1}}else u<=t&&(e.expiredLanes|=c);s&=~c}if(r=Mt(e,e===Os?Rs:0),t=Lt,0===r)null!==n&&(n!==jo&&Eo(n),e.callbackNode=null,e.callbackPriority=0);else{if(null!==n){if(e.callbackPriority===t)return;n!==jo&&Eo(n)}15===t?(n=fl.bind(null,e),null===No?(No=[n],Mo=ko(Ro,Vo)):No.push(n),n=jo):n=14===t?qo(99,fl.bind(null,e)):qo(n=function(e){switch(e){case 15:case 14:return 99;case 13:case 12:case 11:case 10:return 98;case 9:case 8:case 7:case 6:case 4:case 5:return 97;case 3:case 2:case 1:return 95;case 0:return 90;default:throw Error(a(358,e))}}(t),ul.bind(null,e)),e.callbackPriority=t,e.callbackNode=n}}function ul(e){if(el=-1,nl=tl=0,0!=(48&_s))throw Error(a(327));var t=e.callbackNode;if(Al()&&e.callbackNode!==t)return null;var n=Mt(e,e===Os?Rs:0);if(0===n)return null;var r=n,o=_s;_s|=16;var i=yl();for(Os===e&&Rs===r||(Us(),vl(e,r));;)try{wl();break}catch(t){gl(e,t)}if(Zo(),Es.current=i,_s=o,null!==As?r=0:(Os=null,Rs=0,r=Ps),0!=(js&Ns))vl(e,0);else if(0!==r){if(2===r&&(_s|=64,e.hydrate&&(e.hydrate=!1,$r(e.containerInfo)),0!==(n=Dt(e))&&(r=bl(e,n))),1===r)throw t=Is,vl(e,0),pl(e,n),cl(e,zo()),t;switch(e.finishedWork=e.current.alternate,e.finishedLanes=n,r){case 0:case 1:throw Error(a(345));case 2:Sl(e);break;case 3:if(pl(e,n),(62914560&n)===n&&10<(r=Fs+500-zo())){if(0!==Mt(e,0))break;if(((o=e.suspendedLanes)&n)!==n){il(),e.pingedLanes|=e.suspendedLanes&o;break}e.timeoutHandle=Ur(Sl.bind(null,e),r);break}Sl(e);break;case 4:if(pl(e,n),(4186112&n)===n)break;for(r=e.eventTimes,o=-1;0<n;){var s=31-$t(n);i=1<<s,(s=r[s])>o&&(o=s),n&=~i}if(n=o,10<(n=(120>(n=zo()-n)?120:480>n?480:1080>n?1080:1920>n?1920:3e3>n?3e3:4320>n?4320:1960*ks(n/1960))-n)){e.timeoutHandle=Ur(Sl.bind(null,e),n);break}Sl(e);break;case 5:Sl(e);break;default:throw Error(a(329))}}return cl(e,zo()),e.callbackNode===t?ul.bind(null,e):null}function pl(e,t){for(t&=~Ms,t&=~Ns,e.suspendedLanes|=t,e.pingedLanes&=~t,e=e.expirationTimes;0<t;){var n=31-$t(t),r=1<<n;e[n]=-1,t&=~r}}function fl(e){if(0!=(48&_s))throw Error(a(327));if(Al(),e===Os&&0!=(e.expiredLanes&Rs)){var t=Rs,n=bl(e,t);0!=(js&Ns)&&(n=bl(e,t=Mt(e,t)))}else n=bl(e,t=Mt(e,0));if(0!==e.tag&&2===n&&(_s|=64,e.hydrate&&(e.hydrate=!1,$r(e.containerInfo)),0!==(t=Dt(e))&&(n=bl(e,t))),1===n)throw n=Is,vl(e,0),pl(e,t),cl(e,zo()),n;return e.finishedWork=e.current.alternate,e.finishedLanes=t,Sl(e),cl(e,zo()),null}function dl(e,t){var n=_s;_s|=1;try{return e(t)}finally{0===(_s=n)&&(Us(),Wo())}}function hl(e,t){ao(Ts,Cs),Cs|=t,js|=t}function ml(){Cs=Ts.current,io(Ts)}function vl(e,t){e.finishedWork=null,e.finishedLanes=0;var n=e.timeoutHandle;if(-1!==n&&(e.timeoutHandle=-1,Br(n)),null!==As)for(n=As.return;null!==n;){var r=n;switch(r.tag){case 1:null!=(r=r.type.childContextTypes)&&ho();break;case 3:Ti(),io(co),io(lo),Vi();break;case 5:Ii(r);break;case 4:Ti();break;case 13:case 19:io(ji);break;case 10:Jo(r);break;case 23:case 24:ml()}n=n.return}Os=e,As=Fl(e.current,null),Rs=Cs=js=t,Ps=0,Is=null,Ms=Ns=Ls=0}function gl(e,t){for(;;){var n=As;try{if(Zo(),Hi.current=Aa,Zi){for(var r=Qi.memoizedState;null!==r;){var o=r.queue;null!==o&&(o.pending=null),r=r.next}Zi=!1}if(Gi=0,Ki=Xi=Qi=null,Ji=!1,Ss.current=null,null===n||null===n.return){Ps=1,Is=t,As=null;break}e:{var i=e,a=n.return,s=n,l=t;if(t=Rs,s.flags|=2048,s.firstEffect=s.lastEffect=null,null!==l&&"object"==typeof l&&"function"==typeof l.then){var c=l;if(0==(2&s.mode)){var u=s.alternate;u?(s.updateQueue=u.updateQueue,s.memoizedState=u.memoizedState,s.lanes=u.lanes):(s.updateQueue=null,s.memoizedState=null)}var p=0!=(1&ji.current),f=a;do{var d;if(d=13===f.tag){var h=f.memoizedState;if(null!==h)d=null!==h.dehydrated;else{var m=f.memoizedProps;d=void 0!==m.fallback&&(!0!==m.unstable_avoidThisFallback||!p)}}if(d){var v=f.updateQueue;if(null===v){var g=new Set;g.add(c),f.updateQueue=g}else v.add(c);if(0==(2&f.mode)){if(f.flags|=64,s.flags|=16384,s.flags&=-2981,1===s.tag)if(null===s.alternate)s.tag=17;else{var y=ai(-1,1);y.tag=2,si(s,y)}s.lanes|=1;break e}l=void 0,s=t;var b=i.pingCache;if(null===b?(b=i.pingCache=new os,l=new Set,b.set(c,l)):void 0===(l=b.get(c))&&(l=new Set,b.set(c,l)),!l.has(s)){l.add(s);var x=jl.bind(null,i,c,s);c.then(x,x)}f.flags|=4096,f.lanes=t;break e}f=f.return}while(null!==f);l=Error((Y(s.type)||"A React component")+" suspended while rendering, but no fallback UI was specified.\n\nAdd a <Suspense fallback=...> component higher in the tree to provide a loading indicator or placeholder to display.")}5!==Ps&&(Ps=2),l=ns(l,s),f=a;do{switch(f.tag){case 3:i=l,f.flags|=4096,t&=-t,f.lanes|=t,li(f,is(0,i,t));break e;case 1:i=l;var w=f.type,k=f.stateNode;if(0==(64&f.flags)&&("function"==typeof w.getDerivedStateFromError||null!==k&&"function"==typeof k.componentDidCatch&&(null===Vs||!Vs.has(k)))){f.flags|=4096,t&=-t,f.lanes|=t,li(f,as(f,i,t));break e}}f=f.return}while(null!==f)}El(n)}catch(e){t=e,As===n&&null!==n&&(As=n=n.return);continue}break}}function yl(){var e=Es.current;return Es.current=Aa,null===e?Aa:e}function bl(e,t){var n=_s;_s|=16;var r=yl();for(Os===e&&Rs===t||vl(e,t);;)try{xl();break}catch(t){gl(e,t)}if(Zo(),_s=n,Es.current=r,null!==As)throw Error(a(261));return Os=null,Rs=0,Ps}function xl(){for(;null!==As;)kl(As)}function wl(){for(;null!==As&&!So();)kl(As)}function kl(e){var t=Bs(e.alternate,e,Cs);e.memoizedProps=e.pendingProps,null===t?El(e):As=t,Ss.current=null}function El(e){var t=e;do{var n=t.alternate;if(e=t.return,0==(2048&t.flags)){if(null!==(n=es(n,t,Cs)))return void(As=n);if(24!==(n=t).tag&&23!==n.tag||null===n.memoizedState||0!=(1073741824&Cs)||0==(4&n.mode)){for(var r=0,o=n.child;null!==o;)r|=o.lanes|o.childLanes,o=o.sibling;n.childLanes=r}null!==e&&0==(2048&e.flags)&&(null===e.firstEffect&&(e.firstEffect=t.firstEffect),null!==t.lastEffect&&(null!==e.lastEffect&&(e.lastEffect.nextEffect=t.firstEffect),e.lastEffect=t.lastEffect),1<t.flags&&(null!==e.lastEffect?e.lastEffect.nextEffect=t:e.firstEffect=t,e.lastEffect=t))}else{if(null!==(n=ts(t)))return n.flags&=2047,void(As=n);null!==e&&(e.firstEffect=e.lastEffect=null,e.flags|=2048)}if(null!==(t=t.sibling))return void(As=t);As=t=e}while(null!==t);0===Ps&&(Ps=5)}function Sl(e){var t=Uo();return $o(99,_l.bind(null,e,t)),null}function _l(e,t){do{Al()}while(null!==Ys);if(0!=(48&_s))throw Error(a(327));var n=e.finishedWork;if(null===n)return null;if(e.finishedWork=null,e.finishedLanes=0,n===e.current)throw Error(a(177));e.callbackNode=null;var r=n.lanes|n.childLanes,o=r,i=e.pendingLanes&~o;e.pendingLanes=o,e.suspendedLanes=0,e.pingedLanes=0,e.expiredLanes&=o,e.mutableReadLanes&=o,e.entangledLanes&=o,o=e.entanglements;for(var s=e.eventTimes,l=e.expirationTimes;0<i;){var c=31-$t(i),u=1<<c;o[c]=0,s[c]=-1,l[c]=-1,i&=~u}if(null!==Ks&&0==(24&r)&&Ks.has(e)&&Ks.delete(e),e===Os&&(As=Os=null,Rs=0),1<n.flags?null!==n.lastEffect?(n.lastEffect.nextEffect=n,r=n.firstEffect):r=n:r=n.firstEffect,null!==r){if(o=_s,_s|=32,Ss.current=null,Mr=Yt,fr(s=pr())){if("selectionStart"in s)l={start:s.selectionStart,end:s.selectionEnd};else e:if(l=(l=s.ownerDocument)&&l.defaultView||window,(u=l.getSelection&&l.getSelection())&&0!==u.rangeCount){l=u.anchorNode,i=u.anchorOffset,c=u.focusNode,u=u.focusOffset;try{l.nodeType,c.nodeType}catch(e){l=null;break e}var p=0,f=-1,d=-1,h=0,m=0,v=s,g=null;t:for(;;){for(var y;v!==l||0!==i&&3!==v.nodeType||(f=p+i),v!==c||0!==u&&3!==v.nodeType||(d=p+u),3===v.nodeType&&(p+=v.nodeValue.length),null!==(y=v.firstChild);)g=v,v=y;for(;;){if(v===s)break t;if(g===l&&++h===i&&(f=p),g===c&&++m===u&&(d=p),null!==(y=v.nextSibling))break;g=(v=g).parentNode}v=y}l=-1===f||-1===d?null:{start:f,end:d}}else l=null;l=l||{start:0,end:0}}else l=null;Dr={focusedElem:s,selectionRange:l},Yt=!1,rl=null,ol=!1,$s=r;do{try{Ol()}catch(e){if(null===$s)throw Error(a(330));Il($s,e),$s=$s.nextEffect}}while(null!==$s);rl=null,$s=r;do{try{for(s=e;null!==$s;){var b=$s.flags;if(16&b&&ge($s.stateNode,""),128&b){var x=$s.alternate;if(null!==x){var w=x.ref;null!==w&&("function"==typeof w?w(null):w.current=null)}}switch(1038&b){case 2:ms($s),$s.flags&=-3;break;case 6:ms($s),$s.flags&=-3,bs($s.alternate,$s);break;case 1024:$s.flags&=-1025;break;case 1028:$s.flags&=-1025,bs($s.alternate,$s);break;case 4:bs($s.alternate,$s);break;case 8:ys(s,l=$s);var k=l.alternate;ds(l),null!==k&&ds(k)}$s=$s.nextEffect}}catch(e){if(null===$s)throw Error(a(330));Il($s,e),$s=$s.nextEffect}}while(null!==$s);if(w=Dr,x=pr(),b=w.focusedElem,s=w.selectionRange,x!==b&&b&&b.ownerDocument&&ur(b.ownerDocument.documentElement,b)){null!==s&&fr(b)&&(x=s.start,void 0===(w=s.end)&&(w=x),"selectionStart"in b?(b.selectionStart=x,b.selectionEnd=Math.min(w,b.value.length)):(w=(x=b.ownerDocument||document)&&x.defaultView||window).getSelection&&(w=w.getSelection(),l=b.textContent.length,k=Math.min(s.start,l),s=void 0===s.end?k:Math.min(s.end,l),!w.extend&&k>s&&(l=s,s=k,k=l),l=cr(b,k),i=cr(b,s),l&&i&&(1!==w.rangeCount||w.anchorNode!==l.node||w.anchorOffset!==l.offset||w.focusNode!==i.node||w.focusOffset!==i.offset)&&((x=x.createRange()).setStart(l.node,l.offset),w.removeAllRanges(),k>s?(w.addRange(x),w.extend(i.node,i.offset)):(x.setEnd(i.node,i.offset),w.addRange(x))))),x=[];for(w=b;w=w.parentNode;)1===w.nodeType&&x.push({element:w,left:w.scrollLeft,top:w.scrollTop});for("function"==typeof b.focus&&b.focus(),b=0;b<x.length;b++)(w=x[b]).element.scrollLeft=w.left,w.element.scrollTop=w.top}Yt=!!Mr,Dr=Mr=null,e.current=n,$s=r;do{try{for(b=e;null!==$s;){var E=$s.flags;if(36&E&&us(b,$s.alternate,$s),128&E){x=void 0;var S=$s.ref;if(null!==S){var _=$s.stateNode;switch($s.tag){case 5:x=_;break;default:x=_}"function"==typeof S?S(x):S.current=x}}$s=$s.nextEffect}}catch(e){if(null===$s)throw Error(a(330));Il($s,e),$s=$s.nextEffect}}while(null!==$s);$s=null,Lo(),_s=o}else e.current=n;if(Hs)Hs=!1,Ys=e,Gs=t;else for($s=r;null!==$s;)t=$s.nextEffect,$s.nextEffect=null,8&$s.flags&&((E=$s).sibling=null,E.stateNode=null),$s=t;if(0===(r=e.pendingLanes)&&(Vs=null),1===r?e===Js?Zs++:(Zs=0,Js=e):Zs=0,n=n.stateNode,xo&&"function"==typeof xo.onCommitFiberRoot)try{xo.onCommitFiberRoot(bo,n,void 0,64==(64&n.current.flags))}catch(e){}if(cl(e,zo()),qs)throw qs=!1,e=Ws,Ws=null,e;return 0!=(8&_s)||Wo(),null}function Ol(){for(;null!==$s;){var e=$s.alternate;ol||null===rl||(0!=(8&$s.flags)?Ze($s,rl)&&(ol=!0):13===$s.tag&&ws(e,$s)&&Ze($s,rl)&&(ol=!0));var t=$s.flags;0!=(256&t)&&cs(e,$s),0==(512&t)||Hs||(Hs=!0,qo(97,(function(){return Al(),null}))),$s=$s.nextEffect}}function Al(){if(90!==Gs){var e=97<Gs?97:Gs;return Gs=90,$o(e,Tl)}return!1}function Rl(e,t){Qs.push(t,e),Hs||(Hs=!0,qo(97,(function(){return Al(),null})))}function Cl(e,t){Xs.push(t,e),Hs||(Hs=!0,qo(97,(function(){return Al(),null})))}function Tl(){if(null===Ys)return!1;var e=Ys;if(Ys=null,0!=(48&_s))throw Error(a(331));var t=_s;_s|=32;var n=Xs;Xs=[];for(var r=0;r<n.length;r+=2){var o=n[r],i=n[r+1],s=o.destroy;if(o.destroy=void 0,"function"==typeof s)try{s()}catch(e){if(null===i)throw Error(a(330));Il(i,e)}}for(n=Qs,Qs=[],r=0;r<n.length;r+=2){o=n[r],i=n[r+1];try{var l=o.create;o.destroy=l()}catch(e){if(null===i)throw Error(a(330));Il(i,e)}}for(l=e.current.firstEffect;null!==l;)e=l.nextEffect,l.nextEffect=null,8&l.flags&&(l.sibling=null,l.stateNode=null),l=e;return _s=t,Wo(),!0}function Pl(e,t,n){si(e,t=is(0,t=ns(n,t),1)),t=il(),null!==(e=ll(e,1))&&(Bt(e,1,t),cl(e,t))}function Il(e,t){if(3===e.tag)Pl(e,e,t);else for(var n=e.return;null!==n;){if(3===n.tag){Pl(n,e,t);break}if(1===n.tag){var r=n.stateNode;if("function"==typeof n.type.getDerivedStateFromError||"function"==typeof r.componentDidCatch&&(null===Vs||!Vs.has(r))){var o=as(n,e=ns(t,e),1);if(si(n,o),o=il(),null!==(n=ll(n,1)))Bt(n,1,o),cl(n,o);else if("function"==typeof r.componentDidCatch&&(null===Vs||!Vs.has(r)))try{r.componentDidCatch(t,e)}catch(e){}break}}n=n.return}}function jl(e,t,n){var r=e.pingCache;null!==r&&r.delete(t),t=il(),e.pingedLanes|=e.suspendedLanes&n,Os===e&&(Rs&n)===n&&(4===Ps||3===Ps&&(62914560&Rs)===Rs&&500>zo()-Fs?vl(e,0):Ms|=n),cl(e,t)}function Ll(e,t){var n=e.stateNode;null!==n&&n.delete(t),0==(t=0)&&(0==(2&(t=e.mode))?t=1:0==(4&t)?t=99===Uo()?1:2:(0===tl&&(tl=js),0===(t=zt(62914560&~tl))&&(t=4194304))),n=il(),null!==(e=ll(e,t))&&(Bt(e,t,n),cl(e,n))}function Nl(e,t,n,r){this.tag=e,this.key=n,this.sibling=this.child=this.return=this.stateNode=this.type=this.elementType=null,this.index=0,this.ref=null,this.pendingProps=t,this.dependencies=this.memoizedState=this.updateQueue=this.memoizedProps=null,this.mode=r,this.flags=0,this.lastEffect=this.firstEffect=this.nextEffect=null,this.childLanes=this.lanes=0,this.alternate=null}function Ml(e,t,n,r){return new Nl(e,t,n,r)}function Dl(e){return!(!(e=e.prototype)||!e.isReactComponent)}function Fl(e,t){var n=e.alternate;return null===n?((n=Ml(e.tag,t,e.key,e.mode)).elementType=e.elementType,n.type=e.type,n.stateNode=e.stateNode,n.alternate=e,e.alternate=n):(n.pendingProps=t,n.type=e.type,n.flags=0,n.nextEffect=null,n.firstEffect=null,n.lastEffect=null),n.childLanes=e.childLanes,n.lanes=e.lanes,n.child=e.child,n.memoizedProps=e.memoizedProps,n.memoizedState=e.memoizedState,n.updateQueue=e.updateQueue,t=e.dependencies,n.dependencies=null===t?null:{lanes:t.lanes,firstContext:t.firstContext},n.sibling=e.sibling,n.index=e.index,n.ref=e.ref,n}function zl(e,t,n,r,o,i){var s=2;if(r=e,"function"==typeof e)Dl(e)&&(s=1);else if("string"==typeof e)s=5;else e:switch(e){case S:return Ul(n.children,o,i,t);case M:s=8,o|=16;break;case _:s=8,o|=1;break;case O:return(e=Ml(12,n,t,8|o)).elementType=O,e.type=O,e.lanes=i,e;case T:return(e=Ml(13,n,t,o)).type=T,e.elementType=T,e.lanes=i,e;case P:return(e=Ml(19,n,t,o)).elementType=P,e.lanes=i,e;case D:return Bl(n,o,i,t);case F:return(e=Ml(24,n,t,o)).elementType=F,e.lanes=i,e;default:if("object"==typeof e&&null!==e)switch(e.$$typeof){case A:s=10;break e;case R:s=9;break e;case C:s=11;break e;case I:s=14;break e;case j:s=16,r=null;break e;case L:s=22;break e}throw Error(a(130,null==e?e:typeof e,""))}return(t=Ml(s,n,t,o)).elementType=e,t.type=r,t.lanes=i,t}function Ul(e,t,n,r){return(e=Ml(7,e,r,t)).lanes=n,e}function Bl(e,t,n,r){return(e=Ml(23,e,r,t)).elementType=D,e.lanes=n,e}function $l(e,t,n){return(e=Ml(6,e,null,t)).lanes=n,e}function ql(e,t,n){return(t=Ml(4,null!==e.children?e.children:[],e.key,t)).lanes=n,t.stateNode={containerInfo:e.containerInfo,pendingChildren:null,implementation:e.implementation},t}function Wl(e,t,n){this.tag=t,this.containerInfo=e,this.finishedWork=this.pingCache=this.current=this.pendingChildren=null,this.timeoutHandle=-1,this.pendingContext=this.context=null,this.hydrate=n,this.callbackNode=null,this.callbackPriority=0,this.eventTimes=Ut(0),this.expirationTimes=Ut(-1),this.entangledLanes=this.finishedLanes=this.mutableReadLanes=this.expiredLanes=this.pingedLanes=this.suspendedLanes=this.pendingLanes=0,this.entanglements=Ut(0),this.mutableSourceEagerHydrationData=null}function Vl(e,t,n,r){var o=t.current,i=il(),s=al(o);e:if(n){t:{if(Qe(n=n._reactInternals)!==n||1!==n.tag)throw Error(a(170));var l=n;do{switch(l.tag){case 3:l=l.stateNode.context;break t;case 1:if(fo(l.type)){l=l.stateNode.__reactInternalMemoizedMergedChildContext;break t}}l=l.return}while(null!==l);throw Error(a(171))}if(1===n.tag){var c=n.type;if(fo(c)){n=vo(n,c,l);break e}}n=l}else n=so;return null===t.context?t.context=n:t.pendingContext=n,(t=ai(i,s)).payload={element:e},null!==(r=void 0===r?null:r)&&(t.callback=r),si(o,t),sl(o,s,i),s}function Hl(e){if(!(e=e.current).child)return null;switch(e.child.tag){case 5:default:return e.child.stateNode}}function Yl(e,t){if(null!==(e=e.memoizedState)&&null!==e.dehydrated){var n=e.retryLane;e.retryLane=0!==n&&n<t?n:t}}function Gl(e,t){Yl(e,t),(e=e.alternate)&&Yl(e,t)}function Ql(e,t,n){var r=null!=n&&null!=n.hydrationOptions&&n.hydrationOptions.mutableSources||null;if(n=new Wl(e,t,null!=n&&!0===n.hydrate),t=Ml(3,null,null,2===t?7:1===t?3:0),n.current=t,t.stateNode=n,oi(t),e[Qr]=n.current,Ar(8===e.nodeType?e.parentNode:e),r)for(e=0;e<r.length;e++){var o=(t=r[e])._getVersion;o=o(t._source),null==n.mutableSourceEagerHydrationData?n.mutableSourceEagerHydrationData=[t,o]:n.mutableSourceEagerHydrationData.push(t,o)}this._internalRoot=n}function Xl(e){return!(!e||1!==e.nodeType&&9!==e.nodeType&&11!==e.nodeType&&(8!==e.nodeType||" react-mount-point-unstable "!==e.nodeValue))}function Kl(e,t,n,r,o){var i=n._reactRootContainer;if(i){var a=i._internalRoot;if("function"==typeof o){var s=o;o=function(){var e=Hl(a);s.call(e)}}Vl(t,a,e,o)}else{if(i=n._reactRootContainer=function(e,t){if(t||(t=!(!(t=e?9===e.nodeType?e.documentElement:e.firstChild:null)||1!==t.nodeType||!t.hasAttribute("data-reactroot"))),!t)for(var n;n=e.lastChild;)e.removeChild(n);return new Ql(e,0,t?{hydrate:!0}:void 0)}(n,r),a=i._internalRoot,"function"==typeof o){var l=o;o=function(){var e=Hl(a);l.call(e)}}!function(e,t){var n=_s;_s&=-2,_s|=8;try{e(t)}finally{0===(_s=n)&&(Us(),Wo())}}((function(){Vl(t,a,e,o)}))}return Hl(a)}Bs=function(e,t,n){var r=t.lanes;if(null!==e)if(e.memoizedProps!==t.pendingProps||co.current)Ia=!0;else{if(0==(n&r)){switch(Ia=!1,t.tag){case 3:$a(t),qi();break;case 5:Pi(t);break;case 1:fo(t.type)&&go(t);break;case 4:Ci(t,t.stateNode.containerInfo);break;case 10:r=t.memoizedProps.value;var o=t.type._context;ao(Go,o._currentValue),o._currentValue=r;break;case 13:if(null!==t.memoizedState)return 0!=(n&t.child.childLanes)?Ya(e,t,n):(ao(ji,1&ji.current),null!==(t=Za(e,t,n))?t.sibling:null);ao(ji,1&ji.current);break;case 19:if(r=0!=(n&t.childLanes),0!=(64&e.flags)){if(r)return Ka(e,t,n);t.flags|=64}if(null!==(o=t.memoizedState)&&(o.rendering=null,o.tail=null,o.lastEffect=null),ao(ji,ji.current),r)break;return null;case 23:case 24:return t.lanes=0,Da(e,t,n)}return Za(e,t,n)}Ia=0!=(16384&e.flags)}else Ia=!1;switch(t.lanes=0,t.tag){case 2:if(r=t.type,null!==e&&(e.alternate=null,t.alternate=null,t.flags|=2),e=t.pendingProps,o=po(t,lo.current),ti(t,n),o=na(null,t,r,e,o,n),t.flags|=1,"object"==typeof o&&null!==o&&"function"==typeof o.render&&void 0===o.$$typeof){if(t.tag=1,t.memoizedState=null,t.updateQueue=null,fo(r)){var i=!0;go(t)}else i=!1;t.memoizedState=null!==o.state&&void 0!==o.state?o.state:null,oi(t);var s=r.getDerivedStateFromProps;"function"==typeof s&&fi(t,r,s,e),o.updater=di,t.stateNode=o,o._reactInternals=t,gi(t,r,e,n),t=Ba(null,t,r,!0,i,n)}else t.tag=0,ja(null,t,o,n),t=t.child;return t;case 16:o=t.elementType;e:{switch(null!==e&&(e.alternate=null,t.alternate=null,t.flags|=2),e=t.pendingProps,o=(i=o._init)(o._payload),t.type=o,i=t.tag=function(e){if("function"==typeof e)return Dl(e)?1:0;if(null!=e){if((e=e.$$typeof)===C)return 11;if(e===I)return 14}return 2}(o),e=Yo(o,e),i){case 0:t=za(null,t,o,e,n);break e;case 1:t=Ua(null,t,o,e,n);break e;case 11:t=La(null,t,o,e,n);break e;case 14:t=Na(null,t,o,Yo(o.type,e),r,n);break e}throw Error(a(306,o,""))}return t;case 0:return r=t.type,o=t.pendingProps,za(e,t,r,o=t.elementType===r?o:Yo(r,o),n);case 1:return r=t.type,o=t.pendingProps,Ua(e,t,r,o=t.elementType===r?o:Yo(r,o),n);case 3:if($a(t),r=t.updateQueue,null===e||null===r)throw Error(a(282));if(r=t.pendingProps,o=null!==(o=t.memoizedState)?o.element:null,ii(e,t),ci(t,r,null,n),(r=t.memoizedState.element)===o)qi(),t=Za(e,t,n);else{if((i=(o=t.stateNode).hydrate)&&(Mi=qr(t.stateNode.containerInfo.firstChild),Ni=t,i=Di=!0),i){if(null!=(e=o.mutableSourceEagerHydrationData))for(o=0;o<e.length;o+=2)(i=e[o])._workInProgressVersionPrimary=e[o+1],Wi.push(i);for(n=Ei(t,null,r,n),t.child=n;n;)n.flags=-3&n.flags|1024,n=n.sibling}else ja(e,t,r,n),qi();t=t.child}return t;case 5:return Pi(t),null===e&&Ui(t),r=t.type,o=t.pendingProps,i=null!==e?e.memoizedProps:null,s=o.children,zr(r,o)?s=null:null!==i&&zr(r,i)&&(t.flags|=16),Fa(e,t),ja(e,t,s,n),t.child;case 6:return null===e&&Ui(t),null;case 13:return Ya(e,t,n);case 4:return Ci(t,t.stateNode.containerInfo),r=t.pendingProps,null===e?t.child=ki(t,null,r,n):ja(e,t,r,n),t.child;case 11:return r=t.type,o=t.pendingProps,La(e,t,r,o=t.elementType===r?o:Yo(r,o),n);case 7:return ja(e,t,t.pendingProps,n),t.child;case 8:case 12:return ja(e,t,t.pendingProps.children,n),t.child;case 10:e:{r=t.type._context,o=t.pendingProps,s=t.memoizedProps,i=o.value;var l=t.type._context;if(ao(Go,l._currentValue),l._currentValue=i,null!==s)if(l=s.value,0==(i=ir(l,i)?0:0|("function"==typeof r._calculateChangedBits?r._calculateChangedBits(l,i):1073741823))){if(s.children===o.children&&!co.current){t=Za(e,t,n);break e}}else for(null!==(l=t.child)&&(l.return=t);null!==l;){var c=l.dependencies;if(null!==c){s=l.child;for(var u=c.firstContext;null!==u;){if(u.context===r&&0!=(u.observedBits&i)){1===l.tag&&((u=ai(-1,n&-n)).tag=2,si(l,u)),l.lanes|=n,null!==(u=l.alternate)&&(u.lanes|=n),ei(l.return,n),c.lanes|=n;break}u=u.next}}else s=10===l.tag&&l.type===t.type?null:l.child;if(null!==s)s.return=l;else for(s=l;null!==s;){if(s===t){s=null;break}if(null!==(l=s.sibling)){l.return=s.return,s=l;break}s=s.return}l=s}ja(e,t,o.children,n),t=t.child}return t;case 9:return o=t.type,r=(i=t.pendingProps).children,ti(t,n),r=r(o=ni(o,i.unstable_observedBits)),t.flags|=1,ja(e,t,r,n),t.child;case 14:return i=Yo(o=t.type,t.pendingProps),Na(e,t,o,i=Yo(o.type,i),r,n);case 15:return Ma(e,t,t.type,t.pendingProps,r,n);case 17:return r=t.type,o=t.pendingProps,o=t.elementType===r?o:Yo(r,o),null!==e&&(e.alternate=null,t.alternate=null,t.flags|=2),t.tag=1,fo(r)?(e=!0,go(t)):e=!1,ti(t,n),mi(t,r,o),gi(t,r,o,n),Ba(null,t,r,!0,e,n);case 19:return Ka(e,t,n);case 23:case 24:return Da(e,t,n)}throw Error(a(156,t.tag))},Ql.prototype.render=function(e){Vl(e,this._internalRoot,null,null)},Ql.prototype.unmount=function(){var e=this._internalRoot,t=e.containerInfo;Vl(null,e,null,(function(){t[Qr]=null}))},Je=function(e){13===e.tag&&(sl(e,4,il()),Gl(e,4))},et=function(e){13===e.tag&&(sl(e,67108864,il()),Gl(e,67108864))},tt=function(e){if(13===e.tag){var t=il(),n=al(e);sl(e,n,t),Gl(e,n)}},nt=function(e,t){return t()},Oe=function(e,t,n){switch(t){case"input":if(ne(e,n),t=n.name,"radio"===n.type&&null!=t){for(n=e;n.parentNode;)n=n.parentNode;for(n=n.querySelectorAll("input[name="+JSON.stringify(""+t)+'][type="radio"]'),t=0;t<n.length;t++){var r=n[t];if(r!==e&&r.form===e.form){var o=eo(r);if(!o)throw Error(a(90));K(r),ne(r,o)}}}break;case"textarea":ce(e,n);break;case"select":null!=(t=n.value)&&ae(e,!!n.multiple,t,!1)}},Ie=dl,je=function(e,t,n,r,o){var i=_s;_s|=4;try{return $o(98,e.bind(null,t,n,r,o))}finally{0===(_s=i)&&(Us(),Wo())}},Le=function(){0==(49&_s)&&(function(){if(null!==Ks){var e=Ks;Ks=null,e.forEach((function(e){e.expiredLanes|=24&e.pendingLanes,cl(e,zo())}))}Wo()}(),Al())},Ne=function(e,t){var n=_s;_s|=2;try{return e(t)}finally{0===(_s=n)&&(Us(),Wo())}};var Zl={findFiberByHostInstance:Kr,bundleType:0,version:"17.0.2",rendererPackageName:"react-dom"},Jl={bundleType:Zl.bundleType,version:Zl.version,rendererPackageName:Zl.rendererPackageName,rendererConfig:Zl.rendererConfig,overrideHookState:null,overrideHookStateDeletePath:null,overrideHookStateRenamePath:null,overrideProps:null,overridePropsDeletePath:null,overridePropsRenamePath:null,setSuspenseHandler:null,scheduleUpdate:null,currentDispatcherRef:w.ReactCurrentDispatcher,findHostInstanceByFiber:function(e){return null===(e=function(e){if(!(e=function(e){var t=e.alternate;if(!t){if(null===(t=Qe(e)))throw Error(a(188));return t!==e?null:e}for(var n=e,r=t;;){var o=n.return;if(null===o)break;var i=o.alternate;if(null===i){if(null!==(r=o.return)){n=r;continue}break}if(o.child===i.child){for(i=o.child;i;){if(i===n)return Ke(o),e;if(i===r)return Ke(o),t;i=i.sibling}throw Error(a(188))}if(n.return!==r.return)n=o,r=i;else{for(var s=!1,l=o.child;l;){if(l===n){s=!0,n=o,r=i;break}if(l===r){s=!0,r=o,n=i;break}l=l.sibling}if(!s){for(l=i.child;l;){if(l===n){s=!0,n=i,r=o;break}if(l===r){s=!0,r=i,n=o;break}l=l.sibling}if(!s)throw Error(a(189))}}if(n.alternate!==r)throw Error(a(190))}if(3!==n.tag)throw Error(a(188));return n.stateNode.current===n?e:t}(e)))return null;for(var t=e;;){if(5===t.tag||6===t.tag)return t;if(t.child)t.child.return=t,t=t.child;else{if(t===e)break;for(;!t.sibling;){if(!t.return||t.return===e)return null;t=t.return}t.sibling.return=t.return,t=t.sibling}}return null}(e))?null:e.stateNode},findFiberByHostInstance:Zl.findFiberByHostInstance||function(){return null},findHostInstancesForRefresh:null,scheduleRefresh:null,scheduleRoot:null,setRefreshHandler:null,getCurrentFiber:null};if("undefined"!=typeof __REACT_DEVTOOLS_GLOBAL_HOOK__){var ec=__REACT_DEVTOOLS_GLOBAL_HOOK__;if(!ec.isDisabled&&ec.supportsFiber)try{bo=ec.inject(Jl),xo=ec}catch(me){}}t.hydrate=function(e,t,n){if(!Xl(t))throw Error(a(200));return Kl(null,e,t,!0,n)},t.render=function(e,t,n){if(!Xl(t))throw Error(a(200));return Kl(null,e,t,!1,n)},t.unstable_batchedUpdates=dl},3935:function(e,t,n){"use strict";!function e(){if("undefined"!=typeof __REACT_DEVTOOLS_GLOBAL_HOOK__&&"function"==typeof __REACT_DEVTOOLS_GLOBAL_HOOK__.checkDCE)try{__REACT_DEVTOOLS_GLOBAL_HOOK__.checkDCE(e)}catch(e){console.error(e)}}(),e.exports=n(4448)},9921:function(e,t){"use strict";var n=60103,r=60106,o=60107,i=60108,a=60114,s=60109,l=60110,c=60112,u=60113,p=60120,f=60115,d=60116,h=60121,m=60122,v=60117,g=60129,y=60131;if("function"==typeof Symbol&&Symbol.for){var b=Symbol.for;n=b("react.element"),r=b("react.portal"),o=b("react.fragment"),i=b("react.strict_mode"),a=b("react.profiler"),s=b("react.provider"),l=b("react.context"),c=b("react.forward_ref"),u=b("react.suspense"),p=b("react.suspense_list"),f=b("react.memo"),d=b("react.lazy"),h=b("react.block"),m=b("react.server.block"),v=b("react.fundamental"),g=b("react.debug_trace_mode"),y=b("react.legacy_hidden")}t.isValidElementType=function(e){return"string"==typeof e||"function"==typeof e||e===o||e===a||e===g||e===i||e===u||e===p||e===y||"object"==typeof e&&null!==e&&(e.$$typeof===d||e.$$typeof===f||e.$$typeof===s||e.$$typeof===l||e.$$typeof===c||e.$$typeof===v||e.$$typeof===h||e[0]===m)},t.typeOf=function(e){if("object"==typeof e&&null!==e){var t=e.$$typeof;switch(t){case n:switch(e=e.type){case o:case a:case i:case u:case p:return e;default:switch(e=e&&e.$$typeof){case l:case c:case d:case f:case s:return e;default:return t}}case r:return t}}}},9864:function(e,t,n){"use strict";e.exports=n(9921)},2408:function(e,t,n){"use strict";var r=n(7418),o=60103,i=60106;t.Fragment=60107,t.StrictMode=60108,t.Profiler=60114;var a=60109,s=60110,l=60112;t.Suspense=60113;var c=60115,u=60116;if("function"==typeof Symbol&&Symbol.for){var p=Symbol.for;o=p("react.element"),i=p("react.portal"),t.Fragment=p("react.fragment"),t.StrictMode=p("react.strict_mode"),t.Profiler=p("react.profiler"),a=p("react.provider"),s=p("react.context"),l=p("react.forward_ref"),t.Suspense=p("react.suspense"),c=p("react.memo"),u=p("react.lazy")}var f="function"==typeof Symbol&&Symbol.iterator;function d(e){for(var t="https://reactjs.org/docs/error-decoder.html?invariant="+e,n=1;n<arguments.length;n++)t+="&args[]="+encodeURIComponent(arguments[n]);return"Minified React error #"+e+"; visit "+t+" for the full message or use the non-minified dev environment for full errors and additional helpful warnings."}var h={isMounted:function(){return!1},enqueueForceUpdate:function(){},enqueueReplaceState:function(){},enqueueSetState:function(){}},m={};function v(e,t,n){this.props=e,this.context=t,this.refs=m,this.updater=n||h}function g(){}function y(e,t,n){this.props=e,this.context=t,this.refs=m,this.updater=n||h}v.prototype.isReactComponent={},v.prototype.setState=function(e,t){if("object"!=typeof e&&"function"!=typeof e&&null!=e)throw Error(d(85));this.updater.enqueueSetState(this,e,t,"setState")},v.prototype.forceUpdate=function(e){this.updater.enqueueForceUpdate(this,e,"forceUpdate")},g.prototype=v.prototype;var b=y.prototype=new g;b.constructor=y,r(b,v.prototype),b.isPureReactComponent=!0;var x={current:null},w=Object.prototype.hasOwnProperty,k={key:!0,ref:!0,__self:!0,__source:!0};function E(e,t,n){var r,i={},a=null,s=null;if(null!=t)for(r in void 0!==t.ref&&(s=t.ref),void 0!==t.key&&(a=""+t.key),t)w.call(t,r)&&!k.hasOwnProperty(r)&&(i[r]=t[r]);var l=arguments.length-2;if(1===l)i.children=n;else if(1<l){for(var c=Array(l),u=0;u<l;u++)c[u]=arguments[u+2];i.children=c}if(e&&e.defaultProps)for(r in l=e.defaultProps)void 0===i[r]&&(i[r]=l[r]);return{$$typeof:o,type:e,key:a,ref:s,props:i,_owner:x.current}}function S(e){return"object"==typeof e&&null!==e&&e.$$typeof===o}var _=/\/+/g;function O(e,t){return"object"==typeof e&&null!==e&&null!=e.key?function(e){var t={"=":"=0",":":"=2"};return"$"+e.replace(/[=:]/g,(function(e){return t[e]}))}(""+e.key):t.toString(36)}function A(e,t,n,r,a){var s=typeof e;"undefined"!==s&&"boolean"!==s||(e=null);var l=!1;if(null===e)l=!0;else switch(s){case"string":case"number":l=!0;break;case"object":switch(e.$$typeof){case o:case i:l=!0}}if(l)return a=a(l=e),e=""===r?"."+O(l,0):r,Array.isArray(a)?(n="",null!=e&&(n=e.replace(_,"$&/")+"/"),A(a,t,n,"",(function(e){return e}))):null!=a&&(S(a)&&(a=function(e,t){return{$$typeof:o,type:e.type,key:t,ref:e.ref,props:e.props,_owner:e._owner}}(a,n+(!a.key||l&&l.key===a.key?"":(""+a.key).replace(_,"$&/")+"/")+e)),t.push(a)),1;if(l=0,r=""===r?".":r+":",Array.isArray(e))for(var c=0;c<e.length;c++){var u=r+O(s=e[c],c);l+=A(s,t,n,u,a)}else if("function"==typeof(u=function(e){return null===e||"object"!=typeof e?null:"function"==typeof(e=f&&e[f]||e["@@iterator"])?e:null}(e)))for(e=u.call(e),c=0;!(s=e.next()).done;)l+=A(s=s.value,t,n,u=r+O(s,c++),a);else if("object"===s)throw t=""+e,Error(d(31,"[object Object]"===t?"object with keys {"+Object.keys(e).join(", ")+"}":t));return l}function R(e,t,n){if(null==e)return e;var r=[],o=0;return A(e,r,"","",(function(e){return t.call(n,e,o++)})),r}function C(e){if(-1===e._status){var t=e._result;t=t(),e._status=0,e._result=t,t.then((function(t){0===e._status&&(t=t.default,e._status=1,e._result=t)}),(function(t){0===e._status&&(e._status=2,e._result=t)}))}if(1===e._status)return e._result;throw e._result}var T={current:null};function P(){var e=T.current;if(null===e)throw Error(d(321));return e}var I={ReactCurrentDispatcher:T,ReactCurrentBatchConfig:{transition:0},ReactCurrentOwner:x,IsSomeRendererActing:{current:!1},assign:r};t.Children={map:R,forEach:function(e,t,n){R(e,(function(){t.apply(this,arguments)}),n)},count:function(e){var t=0;return R(e,(function(){t++})),t},toArray:function(e){return R(e,(function(e){return e}))||[]},only:function(e){if(!S(e))throw Error(d(143));return e}},t.Component=v,t.PureComponent=y,t.__SECRET_INTERNALS_DO_NOT_USE_OR_YOU_WILL_BE_FIRED=I,t.cloneElement=function(e,t,n){if(null==e)throw Error(d(267,e));var i=r({},e.props),a=e.key,s=e.ref,l=e._owner;if(null!=t){if(void 0!==t.ref&&(s=t.ref,l=x.current),void 0!==t.key&&(a=""+t.key),e.type&&e.type.defaultProps)var c=e.type.defaultProps;for(u in t)w.call(t,u)&&!k.hasOwnProperty(u)&&(i[u]=void 0===t[u]&&void 0!==c?c[u]:t[u])}var u=arguments.length-2;if(1===u)i.children=n;else if(1<u){c=Array(u);for(var p=0;p<u;p++)c[p]=arguments[p+2];i.children=c}return{$$typeof:o,type:e.type,key:a,ref:s,props:i,_owner:l}},t.createContext=function(e,t){return void 0===t&&(t=null),(e={$$typeof:s,_calculateChangedBits:t,_currentValue:e,_currentValue2:e,_threadCount:0,Provider:null,Consumer:null}).Provider={$$typeof:a,_context:e},e.Consumer=e},t.createElement=E,t.createFactory=function(e){var t=E.bind(null,e);return t.type=e,t},t.createRef=function(){return{current:null}},t.forwardRef=function(e){return{$$typeof:l,render:e}},t.isValidElement=S,t.lazy=function(e){return{$$typeof:u,_payload:{_status:-1,_result:e},_init:C}},t.memo=function(e,t){return{$$typeof:c,type:e,compare:void 0===t?null:t}},t.useCallback=function(e,t){return P().useCallback(e,t)},t.useContext=function(e,t){return P().useContext(e,t)},t.useDebugValue=function(){},t.useEffect=function(e,t){return P().useEffect(e,t)},t.useImperativeHandle=function(e,t,n){return P().useImperativeHandle(e,t,n)},t.useLayoutEffect=function(e,t){return P().useLayoutEffect(e,t)},t.useMemo=function(e,t){return P().useMemo(e,t)},t.useReducer=function(e,t,n){return P().useReducer(e,t,n)},t.useRef=function(e){return P().useRef(e)},t.useState=function(e){return P().useState(e)},t.version="17.0.2"},7294:function(e,t,n){"use strict";e.exports=n(2408)},5666:function(e){var t=function(e){"use strict";var t,n=Object.prototype,r=n.hasOwnProperty,o="function"==typeof Symbol?Symbol:{},i=o.iterator||"@@iterator",a=o.asyncIterator||"@@asyncIterator",s=o.toStringTag||"@@toStringTag";function l(e,t,n){return Object.defineProperty(e,t,{value:n,enumerable:!0,configurable:!0,writable:!0}),e[t]}try{l({},"")}catch(e){l=function(e,t,n){return e[t]=n}}function c(e,t,n,r){var o=t&&t.prototype instanceof v?t:v,i=Object.create(o.prototype),a=new R(r||[]);return i._invoke=function(e,t,n){var r=p;return function(o,i){if(r===d)throw new Error("Generator is already running");if(r===h){if("throw"===o)throw i;return T()}for(n.method=o,n.arg=i;;){var a=n.delegate;if(a){var s=_(a,n);if(s){if(s===m)continue;return s}}if("next"===n.method)n.sent=n._sent=n.arg;else if("throw"===n.method){if(r===p)throw r=h,n.arg;n.dispatchException(n.arg)}else"return"===n.method&&n.abrupt("return",n.arg);r=d;var l=u(e,t,n);if("normal"===l.type){if(r=n.done?h:f,l.arg===m)continue;return{value:l.arg,done:n.done}}"throw"===l.type&&(r=h,n.method="throw",n.arg=l.arg)}}}(e,n,a),i}function u(e,t,n){try{return{type:"normal",arg:e.call(t,n)}}catch(e){return{type:"throw",arg:e}}}e.wrap=c;var p="suspendedStart",f="suspendedYield",d="executing",h="completed",m={};function v(){}function g(){}function y(){}var b={};b[i]=function(){return this};var x=Object.getPrototypeOf,w=x&&x(x(C([])));w&&w!==n&&r.call(w,i)&&(b=w);var k=y.prototype=v.prototype=Object.create(b);function E(e){["next","throw","return"].forEach((function(t){l(e,t,(function(e){return this._invoke(t,e)}))}))}function S(e,t){function n(o,i,a,s){var l=u(e[o],e,i);if("throw"!==l.type){var c=l.arg,p=c.value;return p&&"object"==typeof p&&r.call(p,"__await")?t.resolve(p.__await).then((function(e){n("next",e,a,s)}),(function(e){n("throw",e,a,s)})):t.resolve(p).then((function(e){c.value=e,a(c)}),(function(e){return n("throw",e,a,s)}))}s(l.arg)}var o;this._invoke=function(e,r){function i(){return new t((function(t,o){n(e,r,t,o)}))}return o=o?o.then(i,i):i()}}function _(e,n){var r=e.iterator[n.method];if(r===t){if(n.delegate=null,"throw"===n.method){if(e.iterator.return&&(n.method="return",n.arg=t,_(e,n),"throw"===n.method))return m;n.method="throw",n.arg=new TypeError("The iterator does not provide a 'throw' method")}return m}var o=u(r,e.iterator,n.arg);if("throw"===o.type)return n.method="throw",n.arg=o.arg,n.delegate=null,m;var i=o.arg;return i?i.done?(n[e.resultName]=i.value,n.next=e.nextLoc,"return"!==n.method&&(n.method="next",n.arg=t),n.delegate=null,m):i:(n.method="throw",n.arg=new TypeError("iterator result is not an object"),n.delegate=null,m)}function O(e){var t={tryLoc:e[0]};1 in e&&(t.catchLoc=e[1]),2 in e&&(t.finallyLoc=e[2],t.afterLoc=e[3]),this.tryEntries.push(t)}function A(e){var t=e.completion||{};t.type="normal",delete t.arg,e.completion=t}function R(e){this.tryEntries=[{tryLoc:"root"}],e.forEach(O,this),this.reset(!0)}function C(e){if(e){var n=e[i];if(n)return n.call(e);if("function"==typeof e.next)return e;if(!isNaN(e.length)){var o=-1,a=function n(){for(;++o<e.length;)if(r.call(e,o))return n.value=e[o],n.done=!1,n;return n.value=t,n.done=!0,n};return a.next=a}}return{next:T}}function T(){return{value:t,done:!0}}return g.prototype=k.constructor=y,y.constructor=g,g.displayName=l(y,s,"GeneratorFunction"),e.isGeneratorFunction=function(e){var t="function"==typeof e&&e.constructor;return!!t&&(t===g||"GeneratorFunction"===(t.displayName||t.name))},e.mark=function(e){return Object.setPrototypeOf?Object.setPrototypeOf(e,y):(e.__proto__=y,l(e,s,"GeneratorFunction")),e.prototype=Object.create(k),e},e.awrap=function(e){return{__await:e}},E(S.prototype),S.prototype[a]=function(){return this},e.AsyncIterator=S,e.async=function(t,n,r,o,i){void 0===i&&(i=Promise);var a=new S(c(t,n,r,o),i);return e.isGeneratorFunction(n)?a:a.next().then((function(e){return e.done?e.value:a.next()}))},E(k),l(k,s,"Generator"),k[i]=function(){return this},k.toString=function(){return"[object Generator]"},e.keys=function(e){var t=[];for(var n in e)t.push(n);return t.reverse(),function n(){for(;t.length;){var r=t.pop();if(r in e)return n.value=r,n.done=!1,n}return n.done=!0,n}},e.values=C,R.prototype={constructor:R,reset:function(e){if(this.prev=0,this.next=0,this.sent=this._sent=t,this.done=!1,this.delegate=null,this.method="next",this.arg=t,this.tryEntries.forEach(A),!e)for(var n in this)"t"===n.charAt(0)&&r.call(this,n)&&!isNaN(+n.slice(1))&&(this[n]=t)},stop:function(){this.done=!0;var e=this.tryEntries[0].completion;if("throw"===e.type)throw e.arg;return this.rval},dispatchException:function(e){if(this.done)throw e;var n=this;function o(r,o){return s.type="throw",s.arg=e,n.next=r,o&&(n.method="next",n.arg=t),!!o}for(var i=this.tryEntries.length-1;i>=0;--i){var a=this.tryEntries[i],s=a.completion;if("root"===a.tryLoc)return o("end");if(a.tryLoc<=this.prev){var l=r.call(a,"catchLoc"),c=r.call(a,"finallyLoc");if(l&&c){if(this.prev<a.catchLoc)return o(a.catchLoc,!0);if(this.prev<a.finallyLoc)return o(a.finallyLoc)}else if(l){if(this.prev<a.catchLoc)return o(a.catchLoc,!0)}else{if(!c)throw new Error("try statement without catch or finally");if(this.prev<a.finallyLoc)return o(a.finallyLoc)}}}},abrupt:function(e,t){for(var n=this.tryEntries.length-1;n>=0;--n){var o=this.tryEntries[n];if(o.tryLoc<=this.prev&&r.call(o,"finallyLoc")&&this.prev<o.finallyLoc){var i=o;break}}i&&("break"===e||"continue"===e)&&i.tryLoc<=t&&t<=i.finallyLoc&&(i=null);var a=i?i.completion:{};return a.type=e,a.arg=t,i?(this.method="next",this.next=i.finallyLoc,m):this.complete(a)},complete:function(e,t){if("throw"===e.type)throw e.arg;return"break"===e.type||"continue"===e.type?this.next=e.arg:"return"===e.type?(this.rval=this.arg=e.arg,this.method="return",this.next="end"):"normal"===e.type&&t&&(this.next=t),m},finish:function(e){for(var t=this.tryEntries.length-1;t>=0;--t){var n=this.tryEntries[t];if(n.finallyLoc===e)return this.complete(n.completion,n.afterLoc),A(n),m}},catch:function(e){for(var t=this.tryEntries.length-1;t>=0;--t){var n=this.tryEntries[t];if(n.tryLoc===e){var r=n.completion;if("throw"===r.type){var o=r.arg;A(n)}return o}}throw new Error("illegal catch attempt")},delegateYield:function(e,n,r){return this.delegate={iterator:C(e),resultName:n,nextLoc:r},"next"===this.method&&(this.arg=t),m}},e}(e.exports);try{regeneratorRuntime=t}catch(e){Function("r","regeneratorRuntime = r")(t)}},53:function(e,t){"use strict";var n,r,o,i;if("object"==typeof performance&&"function"==typeof performance.now){var a=performance;t.unstable_now=function(){return a.now()}}else{var s=Date,l=s.now();t.unstable_now=function(){return s.now()-l}}if("undefined"==typeof window||"function"!=typeof MessageChannel){var c=null,u=null,p=function(){if(null!==c)try{var e=t.unstable_now();c(!0,e),c=null}catch(e){throw setTimeout(p,0),e}};n=function(e){null!==c?setTimeout(n,0,e):(c=e,setTimeout(p,0))},r=function(e,t){u=setTimeout(e,t)},o=function(){clearTimeout(u)},t.unstable_shouldYield=function(){return!1},i=t.unstable_forceFrameRate=function(){}}else{var f=window.setTimeout,d=window.clearTimeout;if("undefined"!=typeof console){var h=window.cancelAnimationFrame;"function"!=typeof window.requestAnimationFrame&&console.error("This browser doesn't support requestAnimationFrame. Make sure that you load a polyfill in older browsers. https://reactjs.org/link/react-polyfills"),"function"!=typeof h&&console.error("This browser doesn't support cancelAnimationFrame. Make sure that you load a polyfill in older browsers. https://reactjs.org/link/react-polyfills")}var m=!1,v=null,g=-1,y=5,b=0;t.unstable_shouldYield=function(){return t.unstable_now()>=b},i=function(){},t.unstable_forceFrameRate=function(e){0>e||125<e?console.error("forceFrameRate takes a positive int between 0 and 125, forcing frame rates higher than 125 fps is not supported"):y=0<e?Math.floor(1e3/e):5};var x=new MessageChannel,w=x.port2;x.port1.onmessage=function(){if(null!==v){var e=t.unstable_now();b=e+y;try{v(!0,e)?w.postMessage(null):(m=!1,v=null)}catch(e){throw w.postMessage(null),e}}else m=!1},n=function(e){v=e,m||(m=!0,w.postMessage(null))},r=function(e,n){g=f((function(){e(t.unstable_now())}),n)},o=function(){d(g),g=-1}}function k(e,t){var n=e.length;e.push(t);e:for(;;){var r=n-1>>>1,o=e[r];if(!(void 0!==o&&0<_(o,t)))break e;e[r]=t,e[n]=o,n=r}}function E(e){return void 0===(e=e[0])?null:e}function S(e){var t=e[0];if(void 0!==t){var n=e.pop();if(n!==t){e[0]=n;e:for(var r=0,o=e.length;r<o;){var i=2*(r+1)-1,a=e[i],s=i+1,l=e[s];if(void 0!==a&&0>_(a,n))void 0!==l&&0>_(l,a)?(e[r]=l,e[s]=n,r=s):(e[r]=a,e[i]=n,r=i);else{if(!(void 0!==l&&0>_(l,n)))break e;e[r]=l,e[s]=n,r=s}}}return t}return null}function _(e,t){var n=e.sortIndex-t.sortIndex;return 0!==n?n:e.id-t.id}var O=[],A=[],R=1,C=null,T=3,P=!1,I=!1,j=!1;function L(e){for(var t=E(A);null!==t;){if(null===t.callback)S(A);else{if(!(t.startTime<=e))break;S(A),t.sortIndex=t.expirationTime,k(O,t)}t=E(A)}}function N(e){if(j=!1,L(e),!I)if(null!==E(O))I=!0,n(M);else{var t=E(A);null!==t&&r(N,t.startTime-e)}}function M(e,n){I=!1,j&&(j=!1,o()),P=!0;var i=T;try{for(L(n),C=E(O);null!==C&&(!(C.expirationTime>n)||e&&!t.unstable_shouldYield());){var a=C.callback;if("function"==typeof a){C.callback=null,T=C.priorityLevel;var s=a(C.expirationTime<=n);n=t.unstable_now(),"function"==typeof s?C.callback=s:C===E(O)&&S(O),L(n)}else S(O);C=E(O)}if(null!==C)var l=!0;else{var c=E(A);null!==c&&r(N,c.startTime-n),l=!1}return l}finally{C=null,T=i,P=!1}}var D=i;t.unstable_IdlePriority=5,t.unstable_ImmediatePriority=1,t.unstable_LowPriority=4,t.unstable_NormalPriority=3,t.unstable_Profiling=null,t.unstable_UserBlockingPriority=2,t.unstable_cancelCallback=function(e){e.callback=null},t.unstable_continueExecution=function(){I||P||(I=!0,n(M))},t.unstable_getCurrentPriorityLevel=function(){return T},t.unstable_getFirstCallbackNode=function(){return E(O)},t.unstable_next=function(e){switch(T){case 1:case 2:case 3:var t=3;break;default:t=T}var n=T;T=t;try{return e()}finally{T=n}},t.unstable_pauseExecution=function(){},t.unstable_requestPaint=D,t.unstable_runWithPriority=function(e,t){switch(e){case 1:case 2:case 3:case 4:case 5:break;default:e=3}var n=T;T=e;try{return t()}finally{T=n}},t.unstable_scheduleCallback=function(e,i,a){var s=t.unstable_now();switch(a="object"==typeof a&&null!==a&&"number"==typeof(a=a.delay)&&0<a?s+a:s,e){case 1:var l=-1;break;case 2:l=250;break;case 5:l=1073741823;break;case 4:l=1e4;break;default:l=5e3}return e={id:R++,callback:i,priorityLevel:e,startTime:a,expirationTime:l=a+l,sortIndex:-1},a>s?(e.sortIndex=a,k(A,e),null===E(O)&&e===E(A)&&(j?o():j=!0,r(N,a-s))):(e.sortIndex=l,k(O,e),I||P||(I=!0,n(M))),e},t.unstable_wrapCallback=function(e){var t=T;return function(){var n=T;T=t;try{return e.apply(this,arguments)}finally{T=n}}}},3840:function(e,t,n){"use strict";e.exports=n(53)},6774:function(e){e.exports=function(e,t,n,r){var o=n?n.call(r,e,t):void 0;if(void 0!==o)return!!o;if(e===t)return!0;if("object"!=typeof e||!e||"object"!=typeof t||!t)return!1;var i=Object.keys(e),a=Object.keys(t);if(i.length!==a.length)return!1;for(var s=Object.prototype.hasOwnProperty.bind(t),l=0;l<i.length;l++){var c=i[l];if(!s(c))return!1;var u=e[c],p=t[c];if(!1===(o=n?n.call(r,u,p,c):void 0)||void 0===o&&u!==p)return!1}return!0}},1304:function(e){var t;t=function(){var e=JSON.parse('{"$":"dollar","%":"percent","&":"and","<":"less",">":"greater","|":"or","¢":"cent","£":"pound","¤":"currency","¥":"yen","©":"(c)","ª":"a","®":"(r)","º":"o","À":"A","Á":"A","Â":"A","Ã":"A","Ä":"A","Å":"A","Æ":"AE","Ç":"C","È":"E","É":"E","Ê":"E","Ë":"E","Ì":"I","Í":"I","Î":"I","Ï":"I","Ð":"D","Ñ":"N","Ò":"O","Ó":"O","Ô":"O","Õ":"O","Ö":"O","Ø":"O","Ù":"U","Ú":"U","Û":"U","Ü":"U","Ý":"Y","Þ":"TH","ß":"ss","à":"a","á":"a","â":"a","ã":"a","ä":"a","å":"a","æ":"ae","ç":"c","è":"e","é":"e","ê":"e","ë":"e","ì":"i","í":"i","î":"i","ï":"i","ð":"d","ñ":"n","ò":"o","ó":"o","ô":"o","õ":"o","ö":"o","ø":"o","ù":"u","ú":"u","û":"u","ü":"u","ý":"y","þ":"th","ÿ":"y","Ā":"A","ā":"a","Ă":"A","ă":"a","Ą":"A","ą":"a","Ć":"C","ć":"c","Č":"C","č":"c","Ď":"D","ď":"d","Đ":"DJ","đ":"dj","Ē":"E","ē":"e","Ė":"E","ė":"e","Ę":"e","ę":"e","Ě":"E","ě":"e","Ğ":"G","ğ":"g","Ģ":"G","ģ":"g","Ĩ":"I","ĩ":"i","Ī":"i","ī":"i","Į":"I","į":"i","İ":"I","ı":"i","Ķ":"k","ķ":"k","Ļ":"L","ļ":"l","Ľ":"L","ľ":"l","Ł":"L","ł":"l","Ń":"N","ń":"n","Ņ":"N","ņ":"n","Ň":"N","ň":"n","Ō":"O","ō":"o","Ő":"O","ő":"o","Œ":"OE","œ":"oe","Ŕ":"R","ŕ":"r","Ř":"R","ř":"r","Ś":"S","ś":"s","Ş":"S","ş":"s","Š":"S","š":"s","Ţ":"T","ţ":"t","Ť":"T","ť":"t","Ũ":"U","ũ":"u","Ū":"u","ū":"u","Ů":"U","ů":"u","Ű":"U","ű":"u","Ų":"U","ų":"u","Ŵ":"W","ŵ":"w","Ŷ":"Y","ŷ":"y","Ÿ":"Y","Ź":"Z","ź":"z","Ż":"Z","ż":"z","Ž":"Z","ž":"z","Ə":"E","ƒ":"f","Ơ":"O","ơ":"o","Ư":"U","ư":"u","ǈ":"LJ","ǉ":"lj","ǋ":"NJ","ǌ":"nj","Ș":"S","ș":"s","Ț":"T","ț":"t","ə":"e","˚":"o","Ά":"A","Έ":"E","Ή":"H","Ί":"I","Ό":"O","Ύ":"Y","Ώ":"W","ΐ":"i","Α":"A","Β":"B","Γ":"G","Δ":"D","Ε":"E","Ζ":"Z","Η":"H","Θ":"8","Ι":"I","Κ":"K","Λ":"L","Μ":"M","Ν":"N","Ξ":"3","Ο":"O","Π":"P","Ρ":"R","Σ":"S","Τ":"T","Υ":"Y","Φ":"F","Χ":"X","Ψ":"PS","Ω":"W","Ϊ":"I","Ϋ":"Y","ά":"a","έ":"e","ή":"h","ί":"i","ΰ":"y","α":"a","β":"b","γ":"g","δ":"d","ε":"e","ζ":"z","η":"h","θ":"8","ι":"i","κ":"k","λ":"l","μ":"m","ν":"n","ξ":"3","ο":"o","π":"p","ρ":"r","ς":"s","σ":"s","τ":"t","υ":"y","φ":"f","χ":"x","ψ":"ps","ω":"w","ϊ":"i","ϋ":"y","ό":"o","ύ":"y","ώ":"w","Ё":"Yo","Ђ":"DJ","Є":"Ye","І":"I","Ї":"Yi","Ј":"J","Љ":"LJ","Њ":"NJ","Ћ":"C","Џ":"DZ","А":"A","Б":"B","В":"V","Г":"G","Д":"D","Е":"E","Ж":"Zh","З":"Z","И":"I","Й":"J","К":"K","Л":"L","М":"M","Н":"N","О":"O","П":"P","Р":"R","С":"S","Т":"T","У":"U","Ф":"F","Х":"H","Ц":"C","Ч":"Ch","Ш":"Sh","Щ":"Sh","Ъ":"U","Ы":"Y","Ь":"","Э":"E","Ю":"Yu","Я":"Ya","а":"a","б":"b","в":"v","г":"g","д":"d","е":"e","ж":"zh","з":"z","и":"i","й":"j","к":"k","л":"l","м":"m","н":"n","о":"o","п":"p","р":"r","с":"s","т":"t","у":"u","ф":"f","х":"h","ц":"c","ч":"ch","ш":"sh","щ":"sh","ъ":"u","ы":"y","ь":"","э":"e","ю":"yu","я":"ya","ё":"yo","ђ":"dj","є":"ye","і":"i","ї":"yi","ј":"j","љ":"lj","њ":"nj","ћ":"c","ѝ":"u","џ":"dz","Ґ":"G","ґ":"g","Ғ":"GH","ғ":"gh","Қ":"KH","қ":"kh","Ң":"NG","ң":"ng","Ү":"UE","ү":"ue","Ұ":"U","ұ":"u","Һ":"H","һ":"h","Ә":"AE","ә":"ae","Ө":"OE","ө":"oe","฿":"baht","ა":"a","ბ":"b","გ":"g","დ":"d","ე":"e","ვ":"v","ზ":"z","თ":"t","ი":"i","კ":"k","ლ":"l","მ":"m","ნ":"n","ო":"o","პ":"p","ჟ":"zh","რ":"r","ს":"s","ტ":"t","უ":"u","ფ":"f","ქ":"k","ღ":"gh","ყ":"q","შ":"sh","ჩ":"ch","ც":"ts","ძ":"dz","წ":"ts","ჭ":"ch","ხ":"kh","ჯ":"j","ჰ":"h","Ẁ":"W","ẁ":"w","Ẃ":"W","ẃ":"w","Ẅ":"W","ẅ":"w","ẞ":"SS","Ạ":"A","ạ":"a","Ả":"A","ả":"a","Ấ":"A","ấ":"a","Ầ":"A","ầ":"a","Ẩ":"A","ẩ":"a","Ẫ":"A","ẫ":"a","Ậ":"A","ậ":"a","Ắ":"A","ắ":"a","Ằ":"A","ằ":"a","Ẳ":"A","ẳ":"a","Ẵ":"A","ẵ":"a","Ặ":"A","ặ":"a","Ẹ":"E","ẹ":"e","Ẻ":"E","ẻ":"e","Ẽ":"E","ẽ":"e","Ế":"E","ế":"e","Ề":"E","ề":"e","Ể":"E","ể":"e","Ễ":"E","ễ":"e","Ệ":"E","ệ":"e","Ỉ":"I","ỉ":"i","Ị":"I","ị":"i","Ọ":"O","ọ":"o","Ỏ":"O","ỏ":"o","Ố":"O","ố":"o","Ồ":"O","ồ":"o","Ổ":"O","ổ":"o","Ỗ":"O","ỗ":"o","Ộ":"O","ộ":"o","Ớ":"O","ớ":"o","Ờ":"O","ờ":"o","Ở":"O","ở":"o","Ỡ":"O","ỡ":"o","Ợ":"O","ợ":"o","Ụ":"U","ụ":"u","Ủ":"U","ủ":"u","Ứ":"U","ứ":"u","Ừ":"U","ừ":"u","Ử":"U","ử":"u","Ữ":"U","ữ":"u","Ự":"U","ự":"u","Ỳ":"Y","ỳ":"y","Ỵ":"Y","ỵ":"y","Ỷ":"Y","ỷ":"y","Ỹ":"Y","ỹ":"y","‘":"\'","’":"\'","“":"\\"","”":"\\"","†":"+","•":"*","…":"...","₠":"ecu","₢":"cruzeiro","₣":"french franc","₤":"lira","₥":"mill","₦":"naira","₧":"peseta","₨":"rupee","₩":"won","₪":"new shequel","₫":"dong","€":"euro","₭":"kip","₮":"tugrik","₯":"drachma","₰":"penny","₱":"peso","₲":"guarani","₳":"austral","₴":"hryvnia","₵":"cedi","₸":"kazakhstani tenge","₹":"indian rupee","₺":"turkish lira","₽":"russian ruble","₿":"bitcoin","℠":"sm","™":"tm","∂":"d","∆":"delta","∑":"sum","∞":"infinity","♥":"love","元":"yuan","円":"yen","﷼":"rial"}'),t=JSON.parse('{"de":{"Ä":"AE","ä":"ae","Ö":"OE","ö":"oe","Ü":"UE","ü":"ue","%":"prozent","&":"und","|":"oder","∑":"summe","∞":"unendlich","♥":"liebe"},"vi":{"Đ":"D","đ":"d"},"fr":{"%":"pourcent","&":"et","<":"plus petit",">":"plus grand","|":"ou","¢":"centime","£":"livre","¤":"devise","₣":"franc","∑":"somme","∞":"infini","♥":"amour"}}');function n(n,r){if("string"!=typeof n)throw new Error("slugify: string argument expected");var o=t[(r="string"==typeof r?{replacement:r}:r||{}).locale]||{},i=void 0===r.replacement?"-":r.replacement,a=n.split("").reduce((function(t,n){return t+(o[n]||e[n]||n).replace(r.remove||/[^\w\s$*_+~.()'"!\-:@]+/g,"")}),"").trim().replace(new RegExp("[\\s"+i+"]+","g"),i);return r.lower&&(a=a.toLowerCase()),r.strict&&(a=a.replace(new RegExp("[^a-zA-Z0-9"+i+"]","g"),"").replace(new RegExp("[\\s"+i+"]+","g"),i)),a}return n.extend=function(t){for(var n in t)e[n]=t[n]},n},e.exports=t(),e.exports.default=t()},5352:function(e){e.exports=function(e,t){e||(e=document),t||(t=window);var n,r,o=[],i=!1,a=e.documentElement,s=function(){},l="hidden",c="visibilitychange";void 0!==e.webkitHidden&&(l="webkitHidden",c="webkitvisibilitychange"),t.getComputedStyle||d();for(var u=["","-webkit-","-moz-","-ms-"],p=document.createElement("div"),f=u.length-1;f>=0;f--){try{p.style.position=u[f]+"sticky"}catch(e){}""!=p.style.position&&d()}function d(){T=N=P=I=j=L=s}function h(e){return parseFloat(e)||0}function m(){n={top:t.pageYOffset,left:t.pageXOffset}}function v(){if(t.pageXOffset!=n.left)return m(),void P();t.pageYOffset!=n.top&&(m(),y())}function g(e){setTimeout((function(){t.pageYOffset!=n.top&&(n.top=t.pageYOffset,y())}),0)}function y(){for(var e=o.length-1;e>=0;e--)b(o[e])}function b(e){if(e.inited){var t=n.top<=e.limit.start?0:n.top>=e.limit.end?2:1;e.mode!=t&&function(e,t){var n=e.node.style;switch(t){case 0:n.position="absolute",n.left=e.offset.left+"px",n.right=e.offset.right+"px",n.top=e.offset.top+"px",n.bottom="auto",n.width="auto",n.marginLeft=0,n.marginRight=0,n.marginTop=0;break;case 1:n.position="fixed",n.left=e.box.left+"px",n.right=e.box.right+"px",n.top=e.css.top,n.bottom="auto",n.width="auto",n.marginLeft=0,n.marginRight=0,n.marginTop=0;break;case 2:n.position="absolute",n.left=e.offset.left+"px",n.right=e.offset.right+"px",n.top="auto",n.bottom=0,n.width="auto",n.marginLeft=0,n.marginRight=0}e.mode=t}(e,t)}}function x(e){isNaN(parseFloat(e.computed.top))||e.isCell||(e.inited=!0,e.clone||function(e){e.clone=document.createElement("div");var t=e.node.nextSibling||e.node,n=e.clone.style;n.height=e.height+"px",n.width=e.width+"px",n.marginTop=e.computed.marginTop,n.marginBottom=e.computed.marginBottom,n.marginLeft=e.computed.marginLeft,n.marginRight=e.computed.marginRight,n.padding=n.border=n.borderSpacing=0,n.fontSize="1em",n.position="static",n.cssFloat=e.computed.cssFloat,e.node.parentNode.insertBefore(e.clone,t)}(e),"absolute"!=e.parent.computed.position&&"relative"!=e.parent.computed.position&&(e.parent.node.style.position="relative"),b(e),e.parent.height=e.parent.node.offsetHeight,e.docOffsetTop=_(e.clone))}function w(e){var t=!0;e.clone&&function(e){e.clone.parentNode.removeChild(e.clone),e.clone=void 0}(e),function(e,t){for(key in t)t.hasOwnProperty(key)&&(e[key]=t[key])}(e.node.style,e.css);for(var n=o.length-1;n>=0;n--)if(o[n].node!==e.node&&o[n].parent.node===e.parent.node){t=!1;break}t&&(e.parent.node.style.position=e.parent.css.position),e.mode=-1}function k(){for(var e=o.length-1;e>=0;e--)x(o[e])}function E(){for(var e=o.length-1;e>=0;e--)w(o[e])}function S(e){var t=getComputedStyle(e),n=e.parentNode,r=getComputedStyle(n),o=e.style.position;e.style.position="relative";var i={top:t.top,marginTop:t.marginTop,marginBottom:t.marginBottom,marginLeft:t.marginLeft,marginRight:t.marginRight,cssFloat:t.cssFloat},s={top:h(t.top),marginBottom:h(t.marginBottom),paddingLeft:h(t.paddingLeft),paddingRight:h(t.paddingRight),borderLeftWidth:h(t.borderLeftWidth),borderRightWidth:h(t.borderRightWidth)};e.style.position=o;var l={position:e.style.position,top:e.style.top,bottom:e.style.bottom,left:e.style.left,right:e.style.right,width:e.style.width,marginTop:e.style.marginTop,marginLeft:e.style.marginLeft,marginRight:e.style.marginRight},c=O(e),u=O(n),p={node:n,css:{position:n.style.position},computed:{position:r.position},numeric:{borderLeftWidth:h(r.borderLeftWidth),borderRightWidth:h(r.borderRightWidth),borderTopWidth:h(r.borderTopWidth),borderBottomWidth:h(r.borderBottomWidth)}};return{node:e,box:{left:c.win.left,right:a.clientWidth-c.win.right},offset:{top:c.win.top-u.win.top-p.numeric.borderTopWidth,left:c.win.left-u.win.left-p.numeric.borderLeftWidth,right:-c.win.right+u.win.right-p.numeric.borderRightWidth},css:l,isCell:"table-cell"==t.display,computed:i,numeric:s,width:c.win.right-c.win.left,height:c.win.bottom-c.win.top,mode:-1,inited:!1,parent:p,limit:{start:c.doc.top-s.top,end:u.doc.top+n.offsetHeight-p.numeric.borderBottomWidth-e.offsetHeight-s.top-s.marginBottom}}}function _(e){for(var t=0;e;)t+=e.offsetTop,e=e.offsetParent;return t}function O(e){var n=e.getBoundingClientRect();return{doc:{top:n.top+t.pageYOffset,left:n.left+t.pageXOffset},win:n}}function A(){r=setInterval((function(){!function(){for(var e=o.length-1;e>=0;e--)if(o[e].inited){var t=Math.abs(_(o[e].clone)-o[e].docOffsetTop),n=Math.abs(o[e].parent.node.offsetHeight-o[e].parent.height);if(t>=2||n>=2)return!1}return!0}()&&P()}),500)}function R(){clearInterval(r)}function C(){i&&(document[l]?R():A())}function T(){i||(m(),k(),t.addEventListener("scroll",v),t.addEventListener("wheel",g),t.addEventListener("resize",P),t.addEventListener("orientationchange",P),e.addEventListener(c,C),A(),i=!0)}function P(){if(i){E();for(var e=o.length-1;e>=0;e--)o[e]=S(o[e].node);k()}}function I(){t.removeEventListener("scroll",v),t.removeEventListener("wheel",g),t.removeEventListener("resize",P),t.removeEventListener("orientationchange",P),e.removeEventListener(c,C),R(),i=!1}function j(){I(),E()}function L(){for(j();o.length;)o.pop()}function N(e){for(var t=o.length-1;t>=0;t--)if(o[t].node===e)return;var n=S(e);o.push(n),i?x(n):T()}return m(),{stickies:o,add:N,remove:function(e){for(var t=o.length-1;t>=0;t--)o[t].node===e&&(w(o[t]),o.splice(t,1))},init:T,rebuild:P,pause:I,stop:j,kill:L}}},8500:function(e){"use strict";e.exports={i8:"7.0.6"}},4609:function(){self.fetch||(self.fetch=function(e,t){return t=t||{},new Promise((function(n,r){var o=new XMLHttpRequest,i=[],a=[],s={},l=function(){return{ok:2==(o.status/100|0),statusText:o.statusText,status:o.status,url:o.responseURL,text:function(){return Promise.resolve(o.responseText)},json:function(){return Promise.resolve(o.responseText).then(JSON.parse)},blob:function(){return Promise.resolve(new Blob([o.response]))},clone:l,headers:{keys:function(){return i},entries:function(){return a},get:function(e){return s[e.toLowerCase()]},has:function(e){return e.toLowerCase()in s}}}};for(var c in o.open(t.method||"get",e,!0),o.onload=function(){o.getAllResponseHeaders().replace(/^(.*?):[^\S\n]*([\s\S]*?)$/gm,(function(e,t,n){i.push(t=t.toLowerCase()),a.push([t,n]),s[t]=s[t]?s[t]+","+n:n})),n(l())},o.onerror=r,o.withCredentials="include"==t.credentials,t.headers)o.setRequestHeader(c,t.headers[c]);o.send(t.body||null)}))})},3578:function(e){e.exports=function(){function e(){}return e.prototype.encodeReserved=function(e){return e.split(/(%[0-9A-Fa-f]{2})/g).map((function(e){return/%[0-9A-Fa-f]/.test(e)||(e=encodeURI(e).replace(/%5B/g,"[").replace(/%5D/g,"]")),e})).join("")},e.prototype.encodeUnreserved=function(e){return encodeURIComponent(e).replace(/[!'()*]/g,(function(e){return"%"+e.charCodeAt(0).toString(16).toUpperCase()}))},e.prototype.encodeValue=function(e,t,n){return t="+"===e||"#"===e?this.encodeReserved(t):this.encodeUnreserved(t),n?this.encodeUnreserved(n)+"="+t:t},e.prototype.isDefined=function(e){return null!=e},e.prototype.isKeyOperator=function(e){return";"===e||"&"===e||"?"===e},e.prototype.getValues=function(e,t,n,r){var o=e[n],i=[];if(this.isDefined(o)&&""!==o)if("string"==typeof o||"number"==typeof o||"boolean"==typeof o)o=o.toString(),r&&"*"!==r&&(o=o.substring(0,parseInt(r,10))),i.push(this.encodeValue(t,o,this.isKeyOperator(t)?n:null));else if("*"===r)Array.isArray(o)?o.filter(this.isDefined).forEach((function(e){i.push(this.encodeValue(t,e,this.isKeyOperator(t)?n:null))}),this):Object.keys(o).forEach((function(e){this.isDefined(o[e])&&i.push(this.encodeValue(t,o[e],e))}),this);else{var a=[];Array.isArray(o)?o.filter(this.isDefined).forEach((function(e){a.push(this.encodeValue(t,e))}),this):Object.keys(o).forEach((function(e){this.isDefined(o[e])&&(a.push(this.encodeUnreserved(e)),a.push(this.encodeValue(t,o[e].toString())))}),this),this.isKeyOperator(t)?i.push(this.encodeUnreserved(n)+"="+a.join(",")):0!==a.length&&i.push(a.join(","))}else";"===t?this.isDefined(o)&&i.push(this.encodeUnreserved(n)):""!==o||"&"!==t&&"?"!==t?""===o&&i.push(""):i.push(this.encodeUnreserved(n)+"=");return i},e.prototype.parse=function(e){var t=this,n=["+","#",".","/",";","?","&"];return{expand:function(r){return e.replace(/\{([^\{\}]+)\}|([^\{\}]+)/g,(function(e,o,i){if(o){var a=null,s=[];if(-1!==n.indexOf(o.charAt(0))&&(a=o.charAt(0),o=o.substr(1)),o.split(/,/g).forEach((function(e){var n=/([^:\*]*)(?::(\d+)|(\*))?/.exec(e);s.push.apply(s,t.getValues(r,a,n[1],n[2]||n[3]))})),a&&"+"!==a){var l=",";return"?"===a?l="&":"#"!==a&&(l=a),(0!==s.length?a:"")+s.join(l)}return s.join(",")}return t.encodeReserved(i)}))}}},new e}()},2511:function(e,t,n){var r;e=n.nmd(e),function(o){t&&t.nodeType,e&&e.nodeType;var i="object"==typeof n.g&&n.g;i.global!==i&&i.window!==i&&i.self;var a,s=2147483647,l=36,c=/^xn--/,u=/[^\x20-\x7E]/,p=/[\x2E\u3002\uFF0E\uFF61]/g,f={overflow:"Overflow: input needs wider integers to process","not-basic":"Illegal input >= 0x80 (not a basic code point)","invalid-input":"Invalid input"},d=Math.floor,h=String.fromCharCode;function m(e){throw RangeError(f[e])}function v(e,t){for(var n=e.length,r=[];n--;)r[n]=t(e[n]);return r}function g(e,t){var n=e.split("@"),r="";return n.length>1&&(r=n[0]+"@",e=n[1]),r+v((e=e.replace(p,".")).split("."),t).join(".")}function y(e){for(var t,n,r=[],o=0,i=e.length;o<i;)(t=e.charCodeAt(o++))>=55296&&t<=56319&&o<i?56320==(64512&(n=e.charCodeAt(o++)))?r.push(((1023&t)<<10)+(1023&n)+65536):(r.push(t),o--):r.push(t);return r}function b(e){return v(e,(function(e){var t="";return e>65535&&(t+=h((e-=65536)>>>10&1023|55296),e=56320|1023&e),t+h(e)})).join("")}function x(e,t){return e+22+75*(e<26)-((0!=t)<<5)}function w(e,t,n){var r=0;for(e=n?d(e/700):e>>1,e+=d(e/t);e>455;r+=l)e=d(e/35);return d(r+36*e/(e+38))}function k(e){var t,n,r,o,i,a,c,u,p,f,h,v=[],g=e.length,y=0,x=128,k=72;for((n=e.lastIndexOf("-"))<0&&(n=0),r=0;r<n;++r)e.charCodeAt(r)>=128&&m("not-basic"),v.push(e.charCodeAt(r));for(o=n>0?n+1:0;o<g;){for(i=y,a=1,c=l;o>=g&&m("invalid-input"),((u=(h=e.charCodeAt(o++))-48<10?h-22:h-65<26?h-65:h-97<26?h-97:l)>=l||u>d((s-y)/a))&&m("overflow"),y+=u*a,!(u<(p=c<=k?1:c>=k+26?26:c-k));c+=l)a>d(s/(f=l-p))&&m("overflow"),a*=f;k=w(y-i,t=v.length+1,0==i),d(y/t)>s-x&&m("overflow"),x+=d(y/t),y%=t,v.splice(y++,0,x)}return b(v)}function E(e){var t,n,r,o,i,a,c,u,p,f,v,g,b,k,E,S=[];for(g=(e=y(e)).length,t=128,n=0,i=72,a=0;a<g;++a)(v=e[a])<128&&S.push(h(v));for(r=o=S.length,o&&S.push("-");r<g;){for(c=s,a=0;a<g;++a)(v=e[a])>=t&&v<c&&(c=v);for(c-t>d((s-n)/(b=r+1))&&m("overflow"),n+=(c-t)*b,t=c,a=0;a<g;++a)if((v=e[a])<t&&++n>s&&m("overflow"),v==t){for(u=n,p=l;!(u<(f=p<=i?1:p>=i+26?26:p-i));p+=l)E=u-f,k=l-f,S.push(h(x(f+E%k,0))),u=d(E/k);S.push(h(x(u,0))),i=w(n,b,r==o),n=0,++r}++n,++t}return S.join("")}a={version:"1.3.2",ucs2:{decode:y,encode:b},decode:k,encode:E,toASCII:function(e){return g(e,(function(e){return u.test(e)?"xn--"+E(e):e}))},toUnicode:function(e){return g(e,(function(e){return c.test(e)?k(e.slice(4).toLowerCase()):e}))}},void 0===(r=function(){return a}.call(t,n,t,e))||(e.exports=r)}()},8575:function(e,t,n){"use strict";var r=n(2511),o=n(2502);function i(){this.protocol=null,this.slashes=null,this.auth=null,this.host=null,this.port=null,this.hostname=null,this.hash=null,this.search=null,this.query=null,this.pathname=null,this.path=null,this.href=null}t.parse=b,t.resolve=function(e,t){return b(e,!1,!0).resolve(t)},t.resolveObject=function(e,t){return e?b(e,!1,!0).resolveObject(t):t},t.format=function(e){return o.isString(e)&&(e=b(e)),e instanceof i?e.format():i.prototype.format.call(e)},t.Url=i;var a=/^([a-z0-9.+-]+:)/i,s=/:[0-9]*$/,l=/^(\/\/?(?!\/)[^\?\s]*)(\?[^\s]*)?$/,c=["{","}","|","\\","^","`"].concat(["<",">",'"',"`"," ","\r","\n","\t"]),u=["'"].concat(c),p=["%","/","?",";","#"].concat(u),f=["/","?","#"],d=/^[+a-z0-9A-Z_-]{0,63}$/,h=/^([+a-z0-9A-Z_-]{0,63})(.*)$/,m={javascript:!0,"javascript:":!0},v={javascript:!0,"javascript:":!0},g={http:!0,https:!0,ftp:!0,gopher:!0,file:!0,"http:":!0,"https:":!0,"ftp:":!0,"gopher:":!0,"file:":!0},y=n(7673);function b(e,t,n){if(e&&o.isObject(e)&&e instanceof i)return e;var r=new i;return r.parse(e,t,n),r}i.prototype.parse=function(e,t,n){if(!o.isString(e))throw new TypeError("Parameter 'url' must be a string, not "+typeof e);var i=e.indexOf("?"),s=-1!==i&&i<e.indexOf("#")?"?":"#",c=e.split(s);c[0]=c[0].replace(/\\/g,"/");var b=e=c.join(s);if(b=b.trim(),!n&&1===e.split("#").length){var x=l.exec(b);if(x)return this.path=b,this.href=b,this.pathname=x[1],x[2]?(this.search=x[2],this.query=t?y.parse(this.search.substr(1)):this.search.substr(1)):t&&(this.search="",this.query={}),this}var w=a.exec(b);if(w){var k=(w=w[0]).toLowerCase();this.protocol=k,b=b.substr(w.length)}if(n||w||b.match(/^\/\/[^@\/]+@[^@\/]+/)){var E="//"===b.substr(0,2);!E||w&&v[w]||(b=b.substr(2),this.slashes=!0)}if(!v[w]&&(E||w&&!g[w])){for(var S,_,O=-1,A=0;A<f.length;A++)-1!==(R=b.indexOf(f[A]))&&(-1===O||R<O)&&(O=R);for(-1!==(_=-1===O?b.lastIndexOf("@"):b.lastIndexOf("@",O))&&(S=b.slice(0,_),b=b.slice(_+1),this.auth=decodeURIComponent(S)),O=-1,A=0;A<p.length;A++){var R;-1!==(R=b.indexOf(p[A]))&&(-1===O||R<O)&&(O=R)}-1===O&&(O=b.length),this.host=b.slice(0,O),b=b.slice(O),this.parseHost(),this.hostname=this.hostname||"";var C="["===this.hostname[0]&&"]"===this.hostname[this.hostname.length-1];if(!C)for(var T=this.hostname.split(/\./),P=(A=0,T.length);A<P;A++){var I=T[A];if(I&&!I.match(d)){for(var j="",L=0,N=I.length;L<N;L++)I.charCodeAt(L)>127?j+="x":j+=I[L];if(!j.match(d)){var M=T.slice(0,A),D=T.slice(A+1),F=I.match(h);F&&(M.push(F[1]),D.unshift(F[2])),D.length&&(b="/"+D.join(".")+b),this.hostname=M.join(".");break}}}this.hostname.length>255?this.hostname="":this.hostname=this.hostname.toLowerCase(),C||(this.hostname=r.toASCII(this.hostname));var z=this.port?":"+this.port:"",U=this.hostname||"";this.host=U+z,this.href+=this.host,C&&(this.hostname=this.hostname.substr(1,this.hostname.length-2),"/"!==b[0]&&(b="/"+b))}if(!m[k])for(A=0,P=u.length;A<P;A++){var B=u[A];if(-1!==b.indexOf(B)){var $=encodeURIComponent(B);$===B&&($=escape(B)),b=b.split(B).join($)}}var q=b.indexOf("#");-1!==q&&(this.hash=b.substr(q),b=b.slice(0,q));var W=b.indexOf("?");if(-1!==W?(this.search=b.substr(W),this.query=b.substr(W+1),t&&(this.query=y.parse(this.query)),b=b.slice(0,W)):t&&(this.search="",this.query={}),b&&(this.pathname=b),g[k]&&this.hostname&&!this.pathname&&(this.pathname="/"),this.pathname||this.search){z=this.pathname||"";var V=this.search||"";this.path=z+V}return this.href=this.format(),this},i.prototype.format=function(){var e=this.auth||"";e&&(e=(e=encodeURIComponent(e)).replace(/%3A/i,":"),e+="@");var t=this.protocol||"",n=this.pathname||"",r=this.hash||"",i=!1,a="";this.host?i=e+this.host:this.hostname&&(i=e+(-1===this.hostname.indexOf(":")?this.hostname:"["+this.hostname+"]"),this.port&&(i+=":"+this.port)),this.query&&o.isObject(this.query)&&Object.keys(this.query).length&&(a=y.stringify(this.query));var s=this.search||a&&"?"+a||"";return t&&":"!==t.substr(-1)&&(t+=":"),this.slashes||(!t||g[t])&&!1!==i?(i="//"+(i||""),n&&"/"!==n.charAt(0)&&(n="/"+n)):i||(i=""),r&&"#"!==r.charAt(0)&&(r="#"+r),s&&"?"!==s.charAt(0)&&(s="?"+s),t+i+(n=n.replace(/[?#]/g,(function(e){return encodeURIComponent(e)})))+(s=s.replace("#","%23"))+r},i.prototype.resolve=function(e){return this.resolveObject(b(e,!1,!0)).format()},i.prototype.resolveObject=function(e){if(o.isString(e)){var t=new i;t.parse(e,!1,!0),e=t}for(var n=new i,r=Object.keys(this),a=0;a<r.length;a++){var s=r[a];n[s]=this[s]}if(n.hash=e.hash,""===e.href)return n.href=n.format(),n;if(e.slashes&&!e.protocol){for(var l=Object.keys(e),c=0;c<l.length;c++){var u=l[c];"protocol"!==u&&(n[u]=e[u])}return g[n.protocol]&&n.hostname&&!n.pathname&&(n.path=n.pathname="/"),n.href=n.format(),n}if(e.protocol&&e.protocol!==n.protocol){if(!g[e.protocol]){for(var p=Object.keys(e),f=0;f<p.length;f++){var d=p[f];n[d]=e[d]}return n.href=n.format(),n}if(n.protocol=e.protocol,e.host||v[e.protocol])n.pathname=e.pathname;else{for(var h=(e.pathname||"").split("/");h.length&&!(e.host=h.shift()););e.host||(e.host=""),e.hostname||(e.hostname=""),""!==h[0]&&h.unshift(""),h.length<2&&h.unshift(""),n.pathname=h.join("/")}if(n.search=e.search,n.query=e.query,n.host=e.host||"",n.auth=e.auth,n.hostname=e.hostname||e.host,n.port=e.port,n.pathname||n.search){var m=n.pathname||"",y=n.search||"";n.path=m+y}return n.slashes=n.slashes||e.slashes,n.href=n.format(),n}var b=n.pathname&&"/"===n.pathname.charAt(0),x=e.host||e.pathname&&"/"===e.pathname.charAt(0),w=x||b||n.host&&e.pathname,k=w,E=n.pathname&&n.pathname.split("/")||[],S=(h=e.pathname&&e.pathname.split("/")||[],n.protocol&&!g[n.protocol]);if(S&&(n.hostname="",n.port=null,n.host&&(""===E[0]?E[0]=n.host:E.unshift(n.host)),n.host="",e.protocol&&(e.hostname=null,e.port=null,e.host&&(""===h[0]?h[0]=e.host:h.unshift(e.host)),e.host=null),w=w&&(""===h[0]||""===E[0])),x)n.host=e.host||""===e.host?e.host:n.host,n.hostname=e.hostname||""===e.hostname?e.hostname:n.hostname,n.search=e.search,n.query=e.query,E=h;else if(h.length)E||(E=[]),E.pop(),E=E.concat(h),n.search=e.search,n.query=e.query;else if(!o.isNullOrUndefined(e.search))return S&&(n.hostname=n.host=E.shift(),(C=!!(n.host&&n.host.indexOf("@")>0)&&n.host.split("@"))&&(n.auth=C.shift(),n.host=n.hostname=C.shift())),n.search=e.search,n.query=e.query,o.isNull(n.pathname)&&o.isNull(n.search)||(n.path=(n.pathname?n.pathname:"")+(n.search?n.search:"")),n.href=n.format(),n;if(!E.length)return n.pathname=null,n.search?n.path="/"+n.search:n.path=null,n.href=n.format(),n;for(var _=E.slice(-1)[0],O=(n.host||e.host||E.length>1)&&("."===_||".."===_)||""===_,A=0,R=E.length;R>=0;R--)"."===(_=E[R])?E.splice(R,1):".."===_?(E.splice(R,1),A++):A&&(E.splice(R,1),A--);if(!w&&!k)for(;A--;A)E.unshift("..");!w||""===E[0]||E[0]&&"/"===E[0].charAt(0)||E.unshift(""),O&&"/"!==E.join("/").substr(-1)&&E.push("");var C,T=""===E[0]||E[0]&&"/"===E[0].charAt(0);return S&&(n.hostname=n.host=T?"":E.length?E.shift():"",(C=!!(n.host&&n.host.indexOf("@")>0)&&n.host.split("@"))&&(n.auth=C.shift(),n.host=n.hostname=C.shift())),(w=w||n.host&&E.length)&&!T&&E.unshift(""),E.length?n.pathname=E.join("/"):(n.pathname=null,n.path=null),o.isNull(n.pathname)&&o.isNull(n.search)||(n.path=(n.pathname?n.pathname:"")+(n.search?n.search:"")),n.auth=e.auth||n.auth,n.slashes=n.slashes||e.slashes,n.href=n.format(),n},i.prototype.parseHost=function(){var e=this.host,t=s.exec(e);t&&(":"!==(t=t[0])&&(this.port=t.substr(1)),e=e.substr(0,e.length-t.length)),e&&(this.hostname=e)}},2502:function(e){"use strict";e.exports={isString:function(e){return"string"==typeof e},isObject:function(e){return"object"==typeof e&&null!==e},isNull:function(e){return null===e},isNullOrUndefined:function(e){return null==e}}},5114:function(e,t,n){var r=n(6314),o=["add","done","toJS","fromExternalJS","load","dispose","search"];e.exports=function(){var e=new Worker(URL.createObjectURL(new Blob(['/*! For license information please see 574a25b96816f2c682b8.worker.js.LICENSE.txt */\n!function(){var e={7757:function(e,t,r){e.exports=r(5666)},3099:function(e){e.exports=function(e){if("function"!=typeof e)throw TypeError(String(e)+" is not a function");return e}},6077:function(e,t,r){var n=r(111);e.exports=function(e){if(!n(e)&&null!==e)throw TypeError("Can\'t set "+String(e)+" as a prototype");return e}},1530:function(e,t,r){"use strict";var n=r(8710).charAt;e.exports=function(e,t,r){return t+(r?n(e,t).length:1)}},5787:function(e){e.exports=function(e,t,r){if(!(e instanceof t))throw TypeError("Incorrect "+(r?r+" ":"")+"invocation");return e}},9670:function(e,t,r){var n=r(111);e.exports=function(e){if(!n(e))throw TypeError(String(e)+" is not an object");return e}},8533:function(e,t,r){"use strict";var n=r(2092).forEach,i=r(9341)("forEach");e.exports=i?[].forEach:function(e){return n(this,e,arguments.length>1?arguments[1]:void 0)}},1318:function(e,t,r){var n=r(5656),i=r(7466),o=r(1400),s=function(e){return function(t,r,s){var a,u=n(t),c=i(u.length),l=o(s,c);if(e&&r!=r){for(;c>l;)if((a=u[l++])!=a)return!0}else for(;c>l;l++)if((e||l in u)&&u[l]===r)return e||l||0;return!e&&-1}};e.exports={includes:s(!0),indexOf:s(!1)}},2092:function(e,t,r){var n=r(9974),i=r(8361),o=r(7908),s=r(7466),a=r(5417),u=[].push,c=function(e){var t=1==e,r=2==e,c=3==e,l=4==e,f=6==e,h=7==e,p=5==e||f;return function(d,v,y,g){for(var m,x,w=o(d),b=i(w),E=n(v,y,3),S=s(b.length),L=0,k=g||a,T=t?k(d,S):r||h?k(d,0):void 0;S>L;L++)if((p||L in b)&&(x=E(m=b[L],L,w),e))if(t)T[L]=x;else if(x)switch(e){case 3:return!0;case 5:return m;case 6:return L;case 2:u.call(T,m)}else switch(e){case 4:return!1;case 7:u.call(T,m)}return f?-1:c||l?l:T}};e.exports={forEach:c(0),map:c(1),filter:c(2),some:c(3),every:c(4),find:c(5),findIndex:c(6),filterOut:c(7)}},1194:function(e,t,r){var n=r(7293),i=r(5112),o=r(7392),s=i("species");e.exports=function(e){return o>=51||!n((function(){var t=[];return(t.constructor={})[s]=function(){return{foo:1}},1!==t[e](Boolean).foo}))}},9341:function(e,t,r){"use strict";var n=r(7293);e.exports=function(e,t){var r=[][e];return!!r&&n((function(){r.call(null,t||function(){throw 1},1)}))}},5417:function(e,t,r){var n=r(111),i=r(3157),o=r(5112)("species");e.exports=function(e,t){var r;return i(e)&&("function"!=typeof(r=e.constructor)||r!==Array&&!i(r.prototype)?n(r)&&null===(r=r[o])&&(r=void 0):r=void 0),new(void 0===r?Array:r)(0===t?0:t)}},7072:function(e,t,r){var n=r(5112)("iterator"),i=!1;try{var o=0,s={next:function(){return{done:!!o++}},return:function(){i=!0}};s[n]=function(){return this},Array.from(s,(function(){throw 2}))}catch(e){}e.exports=function(e,t){if(!t&&!i)return!1;var r=!1;try{var o={};o[n]=function(){return{next:function(){return{done:r=!0}}}},e(o)}catch(e){}return r}},4326:function(e){var t={}.toString;e.exports=function(e){return t.call(e).slice(8,-1)}},648:function(e,t,r){var n=r(1694),i=r(4326),o=r(5112)("toStringTag"),s="Arguments"==i(function(){return arguments}());e.exports=n?i:function(e){var t,r,n;return void 0===e?"Undefined":null===e?"Null":"string"==typeof(r=function(e,t){try{return e[t]}catch(e){}}(t=Object(e),o))?r:s?i(t):"Object"==(n=i(t))&&"function"==typeof t.callee?"Arguments":n}},9920:function(e,t,r){var n=r(6656),i=r(3887),o=r(1236),s=r(3070);e.exports=function(e,t){for(var r=i(t),a=s.f,u=o.f,c=0;c<r.length;c++){var l=r[c];n(e,l)||a(e,l,u(t,l))}}},8880:function(e,t,r){var n=r(9781),i=r(3070),o=r(9114);e.exports=n?function(e,t,r){return i.f(e,t,o(1,r))}:function(e,t,r){return e[t]=r,e}},9114:function(e){e.exports=function(e,t){return{enumerable:!(1&e),configurable:!(2&e),writable:!(4&e),value:t}}},6135:function(e,t,r){"use strict";var n=r(7593),i=r(3070),o=r(9114);e.exports=function(e,t,r){var s=n(t);s in e?i.f(e,s,o(0,r)):e[s]=r}},9781:function(e,t,r){var n=r(7293);e.exports=!n((function(){return 7!=Object.defineProperty({},1,{get:function(){return 7}})[1]}))},317:function(e,t,r){var n=r(7854),i=r(111),o=n.document,s=i(o)&&i(o.createElement);e.exports=function(e){return s?o.createElement(e):{}}},8324:function(e){e.exports={CSSRuleList:0,CSSStyleDeclaration:0,CSSValueList:0,ClientRectList:0,DOMRectList:0,DOMStringList:0,DOMTokenList:1,DataTransferItemList:0,FileList:0,HTMLAllCollection:0,HTMLCollection:0,HTMLFormElement:0,HTMLSelectElement:0,MediaList:0,MimeTypeArray:0,NamedNodeMap:0,NodeList:1,PaintRequestList:0,Plugin:0,PluginArray:0,SVGLengthList:0,SVGNumberList:0,SVGPathSegList:0,SVGPointList:0,SVGStringList:0,SVGTransformList:0,SourceBufferList:0,StyleSheetList:0,TextTrackCueList:0,TextTrackList:0,TouchList:0}},7871:function(e){e.exports="object"==typeof window},6833:function(e,t,r){var n=r(8113);e.exports=/(?:iphone|ipod|ipad).*applewebkit/i.test(n)},5268:function(e,t,r){var n=r(4326),i=r(7854);e.exports="process"==n(i.process)},1036:function(e,t,r){var n=r(8113);e.exports=/web0s(?!.*chrome)/i.test(n)},8113:function(e,t,r){var n=r(5005);e.exports=n("navigator","userAgent")||""},7392:function(e,t,r){var n,i,o=r(7854),s=r(8113),a=o.process,u=a&&a.versions,c=u&&u.v8;c?i=(n=c.split("."))[0]<4?1:n[0]+n[1]:s&&(!(n=s.match(/Edge\\/(\\d+)/))||n[1]>=74)&&(n=s.match(/Chrome\\/(\\d+)/))&&(i=n[1]),e.exports=i&&+i},748:function(e){e.exports=["constructor","hasOwnProperty","isPrototypeOf","propertyIsEnumerable","toLocaleString","toString","valueOf"]},2109:function(e,t,r){var n=r(7854),i=r(1236).f,o=r(8880),s=r(1320),a=r(3505),u=r(9920),c=r(4705);e.exports=function(e,t){var r,l,f,h,p,d=e.target,v=e.global,y=e.stat;if(r=v?n:y?n[d]||a(d,{}):(n[d]||{}).prototype)for(l in t){if(h=t[l],f=e.noTargetGet?(p=i(r,l))&&p.value:r[l],!c(v?l:d+(y?".":"#")+l,e.forced)&&void 0!==f){if(typeof h==typeof f)continue;u(h,f)}(e.sham||f&&f.sham)&&o(h,"sham",!0),s(r,l,h,e)}}},7293:function(e){e.exports=function(e){try{return!!e()}catch(e){return!0}}},7007:function(e,t,r){"use strict";r(4916);var n=r(1320),i=r(2261),o=r(7293),s=r(5112),a=r(8880),u=s("species"),c=RegExp.prototype,l=!o((function(){var e=/./;return e.exec=function(){var e=[];return e.groups={a:"7"},e},"7"!=="".replace(e,"$<a>")})),f="$0"==="a".replace(/./,"$0"),h=s("replace"),p=!!/./[h]&&""===/./[h]("a","$0"),d=!o((function(){var e=/(?:)/,t=e.exec;e.exec=function(){return t.apply(this,arguments)};var r="ab".split(e);return 2!==r.length||"a"!==r[0]||"b"!==r[1]}));e.exports=function(e,t,r,h){var v=s(e),y=!o((function(){var t={};return t[v]=function(){return 7},7!=""[e](t)})),g=y&&!o((function(){var t=!1,r=/a/;return"split"===e&&((r={}).constructor={},r.constructor[u]=function(){return r},r.flags="",r[v]=/./[v]),r.exec=function(){return t=!0,null},r[v](""),!t}));if(!y||!g||"replace"===e&&(!l||!f||p)||"split"===e&&!d){var m=/./[v],x=r(v,""[e],(function(e,t,r,n,o){var s=t.exec;return s===i||s===c.exec?y&&!o?{done:!0,value:m.call(t,r,n)}:{done:!0,value:e.call(r,t,n)}:{done:!1}}),{REPLACE_KEEPS_$0:f,REGEXP_REPLACE_SUBSTITUTES_UNDEFINED_CAPTURE:p}),w=x[0],b=x[1];n(String.prototype,e,w),n(c,v,2==t?function(e,t){return b.call(e,this,t)}:function(e){return b.call(e,this)})}h&&a(c[v],"sham",!0)}},9974:function(e,t,r){var n=r(3099);e.exports=function(e,t,r){if(n(e),void 0===t)return e;switch(r){case 0:return function(){return e.call(t)};case 1:return function(r){return e.call(t,r)};case 2:return function(r,n){return e.call(t,r,n)};case 3:return function(r,n,i){return e.call(t,r,n,i)}}return function(){return e.apply(t,arguments)}}},5005:function(e,t,r){var n=r(857),i=r(7854),o=function(e){return"function"==typeof e?e:void 0};e.exports=function(e,t){return arguments.length<2?o(n[e])||o(i[e]):n[e]&&n[e][t]||i[e]&&i[e][t]}},1246:function(e,t,r){var n=r(648),i=r(7497),o=r(5112)("iterator");e.exports=function(e){if(null!=e)return e[o]||e["@@iterator"]||i[n(e)]}},7854:function(e,t,r){var n=function(e){return e&&e.Math==Math&&e};e.exports=n("object"==typeof globalThis&&globalThis)||n("object"==typeof window&&window)||n("object"==typeof self&&self)||n("object"==typeof r.g&&r.g)||function(){return this}()||Function("return this")()},6656:function(e,t,r){var n=r(7908),i={}.hasOwnProperty;e.exports=Object.hasOwn||function(e,t){return i.call(n(e),t)}},3501:function(e){e.exports={}},842:function(e,t,r){var n=r(7854);e.exports=function(e,t){var r=n.console;r&&r.error&&(1===arguments.length?r.error(e):r.error(e,t))}},490:function(e,t,r){var n=r(5005);e.exports=n("document","documentElement")},4664:function(e,t,r){var n=r(9781),i=r(7293),o=r(317);e.exports=!n&&!i((function(){return 7!=Object.defineProperty(o("div"),"a",{get:function(){return 7}}).a}))},8361:function(e,t,r){var n=r(7293),i=r(4326),o="".split;e.exports=n((function(){return!Object("z").propertyIsEnumerable(0)}))?function(e){return"String"==i(e)?o.call(e,""):Object(e)}:Object},2788:function(e,t,r){var n=r(5465),i=Function.toString;"function"!=typeof n.inspectSource&&(n.inspectSource=function(e){return i.call(e)}),e.exports=n.inspectSource},9909:function(e,t,r){var n,i,o,s=r(8536),a=r(7854),u=r(111),c=r(8880),l=r(6656),f=r(5465),h=r(6200),p=r(3501),d="Object already initialized",v=a.WeakMap;if(s||f.state){var y=f.state||(f.state=new v),g=y.get,m=y.has,x=y.set;n=function(e,t){if(m.call(y,e))throw new TypeError(d);return t.facade=e,x.call(y,e,t),t},i=function(e){return g.call(y,e)||{}},o=function(e){return m.call(y,e)}}else{var w=h("state");p[w]=!0,n=function(e,t){if(l(e,w))throw new TypeError(d);return t.facade=e,c(e,w,t),t},i=function(e){return l(e,w)?e[w]:{}},o=function(e){return l(e,w)}}e.exports={set:n,get:i,has:o,enforce:function(e){return o(e)?i(e):n(e,{})},getterFor:function(e){return function(t){var r;if(!u(t)||(r=i(t)).type!==e)throw TypeError("Incompatible receiver, "+e+" required");return r}}}},7659:function(e,t,r){var n=r(5112),i=r(7497),o=n("iterator"),s=Array.prototype;e.exports=function(e){return void 0!==e&&(i.Array===e||s[o]===e)}},3157:function(e,t,r){var n=r(4326);e.exports=Array.isArray||function(e){return"Array"==n(e)}},4705:function(e,t,r){var n=r(7293),i=/#|\\.prototype\\./,o=function(e,t){var r=a[s(e)];return r==c||r!=u&&("function"==typeof t?n(t):!!t)},s=o.normalize=function(e){return String(e).replace(i,".").toLowerCase()},a=o.data={},u=o.NATIVE="N",c=o.POLYFILL="P";e.exports=o},111:function(e){e.exports=function(e){return"object"==typeof e?null!==e:"function"==typeof e}},1913:function(e){e.exports=!1},7850:function(e,t,r){var n=r(111),i=r(4326),o=r(5112)("match");e.exports=function(e){var t;return n(e)&&(void 0!==(t=e[o])?!!t:"RegExp"==i(e))}},408:function(e,t,r){var n=r(9670),i=r(7659),o=r(7466),s=r(9974),a=r(1246),u=r(9212),c=function(e,t){this.stopped=e,this.result=t};e.exports=function(e,t,r){var l,f,h,p,d,v,y,g=r&&r.that,m=!(!r||!r.AS_ENTRIES),x=!(!r||!r.IS_ITERATOR),w=!(!r||!r.INTERRUPTED),b=s(t,g,1+m+w),E=function(e){return l&&u(l),new c(!0,e)},S=function(e){return m?(n(e),w?b(e[0],e[1],E):b(e[0],e[1])):w?b(e,E):b(e)};if(x)l=e;else{if("function"!=typeof(f=a(e)))throw TypeError("Target is not iterable");if(i(f)){for(h=0,p=o(e.length);p>h;h++)if((d=S(e[h]))&&d instanceof c)return d;return new c(!1)}l=f.call(e)}for(v=l.next;!(y=v.call(l)).done;){try{d=S(y.value)}catch(e){throw u(l),e}if("object"==typeof d&&d&&d instanceof c)return d}return new c(!1)}},9212:function(e,t,r){var n=r(9670);e.exports=function(e){var t=e.return;if(void 0!==t)return n(t.call(e)).value}},7497:function(e){e.exports={}},5948:function(e,t,r){var n,i,o,s,a,u,c,l,f=r(7854),h=r(1236).f,p=r(261).set,d=r(6833),v=r(1036),y=r(5268),g=f.MutationObserver||f.WebKitMutationObserver,m=f.document,x=f.process,w=f.Promise,b=h(f,"queueMicrotask"),E=b&&b.value;E||(n=function(){var e,t;for(y&&(e=x.domain)&&e.exit();i;){t=i.fn,i=i.next;try{t()}catch(e){throw i?s():o=void 0,e}}o=void 0,e&&e.enter()},d||y||v||!g||!m?w&&w.resolve?((c=w.resolve(void 0)).constructor=w,l=c.then,s=function(){l.call(c,n)}):s=y?function(){x.nextTick(n)}:function(){p.call(f,n)}:(a=!0,u=m.createTextNode(""),new g(n).observe(u,{characterData:!0}),s=function(){u.data=a=!a})),e.exports=E||function(e){var t={fn:e,next:void 0};o&&(o.next=t),i||(i=t,s()),o=t}},3366:function(e,t,r){var n=r(7854);e.exports=n.Promise},133:function(e,t,r){var n=r(7392),i=r(7293);e.exports=!!Object.getOwnPropertySymbols&&!i((function(){var e=Symbol();return!String(e)||!(Object(e)instanceof Symbol)||!Symbol.sham&&n&&n<41}))},8536:function(e,t,r){var n=r(7854),i=r(2788),o=n.WeakMap;e.exports="function"==typeof o&&/native code/.test(i(o))},8523:function(e,t,r){"use strict";var n=r(3099),i=function(e){var t,r;this.promise=new e((function(e,n){if(void 0!==t||void 0!==r)throw TypeError("Bad Promise constructor");t=e,r=n})),this.resolve=n(t),this.reject=n(r)};e.exports.f=function(e){return new i(e)}},3070:function(e,t,r){var n=r(9781),i=r(4664),o=r(9670),s=r(7593),a=Object.defineProperty;t.f=n?a:function(e,t,r){if(o(e),t=s(t,!0),o(r),i)try{return a(e,t,r)}catch(e){}if("get"in r||"set"in r)throw TypeError("Accessors not supported");return"value"in r&&(e[t]=r.value),e}},1236:function(e,t,r){var n=r(9781),i=r(5296),o=r(9114),s=r(5656),a=r(7593),u=r(6656),c=r(4664),l=Object.getOwnPropertyDescriptor;t.f=n?l:function(e,t){if(e=s(e),t=a(t,!0),c)try{return l(e,t)}catch(e){}if(u(e,t))return o(!i.f.call(e,t),e[t])}},8006:function(e,t,r){var n=r(6324),i=r(748).concat("length","prototype");t.f=Object.getOwnPropertyNames||function(e){return n(e,i)}},5181:function(e,t){t.f=Object.getOwnPropertySymbols},6324:function(e,t,r){var n=r(6656),i=r(5656),o=r(1318).indexOf,s=r(3501);e.exports=function(e,t){var r,a=i(e),u=0,c=[];for(r in a)!n(s,r)&&n(a,r)&&c.push(r);for(;t.length>u;)n(a,r=t[u++])&&(~o(c,r)||c.push(r));return c}},5296:function(e,t){"use strict";var r={}.propertyIsEnumerable,n=Object.getOwnPropertyDescriptor,i=n&&!r.call({1:2},1);t.f=i?function(e){var t=n(this,e);return!!t&&t.enumerable}:r},7674:function(e,t,r){var n=r(9670),i=r(6077);e.exports=Object.setPrototypeOf||("__proto__"in{}?function(){var e,t=!1,r={};try{(e=Object.getOwnPropertyDescriptor(Object.prototype,"__proto__").set).call(r,[]),t=r instanceof Array}catch(e){}return function(r,o){return n(r),i(o),t?e.call(r,o):r.__proto__=o,r}}():void 0)},288:function(e,t,r){"use strict";var n=r(1694),i=r(648);e.exports=n?{}.toString:function(){return"[object "+i(this)+"]"}},3887:function(e,t,r){var n=r(5005),i=r(8006),o=r(5181),s=r(9670);e.exports=n("Reflect","ownKeys")||function(e){var t=i.f(s(e)),r=o.f;return r?t.concat(r(e)):t}},857:function(e,t,r){var n=r(7854);e.exports=n},2534:function(e){e.exports=function(e){try{return{error:!1,value:e()}}catch(e){return{error:!0,value:e}}}},9478:function(e,t,r){var n=r(9670),i=r(111),o=r(8523);e.exports=function(e,t){if(n(e),i(t)&&t.constructor===e)return t;var r=o.f(e);return(0,r.resolve)(t),r.promise}},2248:function(e,t,r){var n=r(1320);e.exports=function(e,t,r){for(var i in t)n(e,i,t[i],r);return e}},1320:function(e,t,r){var n=r(7854),i=r(8880),o=r(6656),s=r(3505),a=r(2788),u=r(9909),c=u.get,l=u.enforce,f=String(String).split("String");(e.exports=function(e,t,r,a){var u,c=!!a&&!!a.unsafe,h=!!a&&!!a.enumerable,p=!!a&&!!a.noTargetGet;"function"==typeof r&&("string"!=typeof t||o(r,"name")||i(r,"name",t),(u=l(r)).source||(u.source=f.join("string"==typeof t?t:""))),e!==n?(c?!p&&e[t]&&(h=!0):delete e[t],h?e[t]=r:i(e,t,r)):h?e[t]=r:s(t,r)})(Function.prototype,"toString",(function(){return"function"==typeof this&&c(this).source||a(this)}))},7651:function(e,t,r){var n=r(4326),i=r(2261);e.exports=function(e,t){var r=e.exec;if("function"==typeof r){var o=r.call(e,t);if("object"!=typeof o)throw TypeError("RegExp exec method returned something other than an Object or null");return o}if("RegExp"!==n(e))throw TypeError("RegExp#exec called on incompatible receiver");return i.call(e,t)}},2261:function(e,t,r){"use strict";var n,i,o=r(7066),s=r(2999),a=r(2309),u=RegExp.prototype.exec,c=a("native-string-replace",String.prototype.replace),l=u,f=(n=/a/,i=/b*/g,u.call(n,"a"),u.call(i,"a"),0!==n.lastIndex||0!==i.lastIndex),h=s.UNSUPPORTED_Y||s.BROKEN_CARET,p=void 0!==/()??/.exec("")[1];(f||p||h)&&(l=function(e){var t,r,n,i,s=this,a=h&&s.sticky,l=o.call(s),d=s.source,v=0,y=e;return a&&(-1===(l=l.replace("y","")).indexOf("g")&&(l+="g"),y=String(e).slice(s.lastIndex),s.lastIndex>0&&(!s.multiline||s.multiline&&"\\n"!==e[s.lastIndex-1])&&(d="(?: "+d+")",y=" "+y,v++),r=new RegExp("^(?:"+d+")",l)),p&&(r=new RegExp("^"+d+"$(?!\\\\s)",l)),f&&(t=s.lastIndex),n=u.call(a?r:s,y),a?n?(n.input=n.input.slice(v),n[0]=n[0].slice(v),n.index=s.lastIndex,s.lastIndex+=n[0].length):s.lastIndex=0:f&&n&&(s.lastIndex=s.global?n.index+n[0].length:t),p&&n&&n.length>1&&c.call(n[0],r,(function(){for(i=1;i<arguments.length-2;i++)void 0===arguments[i]&&(n[i]=void 0)})),n}),e.exports=l},7066:function(e,t,r){"use strict";var n=r(9670);e.exports=function(){var e=n(this),t="";return e.global&&(t+="g"),e.ignoreCase&&(t+="i"),e.multiline&&(t+="m"),e.dotAll&&(t+="s"),e.unicode&&(t+="u"),e.sticky&&(t+="y"),t}},2999:function(e,t,r){"use strict";var n=r(7293);function i(e,t){return RegExp(e,t)}t.UNSUPPORTED_Y=n((function(){var e=i("a","y");return e.lastIndex=2,null!=e.exec("abcd")})),t.BROKEN_CARET=n((function(){var e=i("^r","gy");return e.lastIndex=2,null!=e.exec("str")}))},4488:function(e){e.exports=function(e){if(null==e)throw TypeError("Can\'t call method on "+e);return e}},3505:function(e,t,r){var n=r(7854),i=r(8880);e.exports=function(e,t){try{i(n,e,t)}catch(r){n[e]=t}return t}},6340:function(e,t,r){"use strict";var n=r(5005),i=r(3070),o=r(5112),s=r(9781),a=o("species");e.exports=function(e){var t=n(e),r=i.f;s&&t&&!t[a]&&r(t,a,{configurable:!0,get:function(){return this}})}},8003:function(e,t,r){var n=r(3070).f,i=r(6656),o=r(5112)("toStringTag");e.exports=function(e,t,r){e&&!i(e=r?e:e.prototype,o)&&n(e,o,{configurable:!0,value:t})}},6200:function(e,t,r){var n=r(2309),i=r(9711),o=n("keys");e.exports=function(e){return o[e]||(o[e]=i(e))}},5465:function(e,t,r){var n=r(7854),i=r(3505),o="__core-js_shared__",s=n[o]||i(o,{});e.exports=s},2309:function(e,t,r){var n=r(1913),i=r(5465);(e.exports=function(e,t){return i[e]||(i[e]=void 0!==t?t:{})})("versions",[]).push({version:"3.14.0",mode:n?"pure":"global",copyright:"© 2021 Denis Pushkarev (zloirock.ru)"})},6707:function(e,t,r){var n=r(9670),i=r(3099),o=r(5112)("species");e.exports=function(e,t){var r,s=n(e).constructor;return void 0===s||null==(r=n(s)[o])?t:i(r)}},8710:function(e,t,r){var n=r(9958),i=r(4488),o=function(e){return function(t,r){var o,s,a=String(i(t)),u=n(r),c=a.length;return u<0||u>=c?e?"":void 0:(o=a.charCodeAt(u))<55296||o>56319||u+1===c||(s=a.charCodeAt(u+1))<56320||s>57343?e?a.charAt(u):o:e?a.slice(u,u+2):s-56320+(o-55296<<10)+65536}};e.exports={codeAt:o(!1),charAt:o(!0)}},6091:function(e,t,r){var n=r(7293),i=r(1361);e.exports=function(e){return n((function(){return!!i[e]()||"​᠎"!="​᠎"[e]()||i[e].name!==e}))}},3111:function(e,t,r){var n=r(4488),i="["+r(1361)+"]",o=RegExp("^"+i+i+"*"),s=RegExp(i+i+"*$"),a=function(e){return function(t){var r=String(n(t));return 1&e&&(r=r.replace(o,"")),2&e&&(r=r.replace(s,"")),r}};e.exports={start:a(1),end:a(2),trim:a(3)}},261:function(e,t,r){var n,i,o,s=r(7854),a=r(7293),u=r(9974),c=r(490),l=r(317),f=r(6833),h=r(5268),p=s.location,d=s.setImmediate,v=s.clearImmediate,y=s.process,g=s.MessageChannel,m=s.Dispatch,x=0,w={},b=function(e){if(w.hasOwnProperty(e)){var t=w[e];delete w[e],t()}},E=function(e){return function(){b(e)}},S=function(e){b(e.data)},L=function(e){s.postMessage(e+"",p.protocol+"//"+p.host)};d&&v||(d=function(e){for(var t=[],r=1;arguments.length>r;)t.push(arguments[r++]);return w[++x]=function(){("function"==typeof e?e:Function(e)).apply(void 0,t)},n(x),x},v=function(e){delete w[e]},h?n=function(e){y.nextTick(E(e))}:m&&m.now?n=function(e){m.now(E(e))}:g&&!f?(o=(i=new g).port2,i.port1.onmessage=S,n=u(o.postMessage,o,1)):s.addEventListener&&"function"==typeof postMessage&&!s.importScripts&&p&&"file:"!==p.protocol&&!a(L)?(n=L,s.addEventListener("message",S,!1)):n="onreadystatechange"in l("script")?function(e){c.appendChild(l("script")).onreadystatechange=function(){c.removeChild(this),b(e)}}:function(e){setTimeout(E(e),0)}),e.exports={set:d,clear:v}},1400:function(e,t,r){var n=r(9958),i=Math.max,o=Math.min;e.exports=function(e,t){var r=n(e);return r<0?i(r+t,0):o(r,t)}},5656:function(e,t,r){var n=r(8361),i=r(4488);e.exports=function(e){return n(i(e))}},9958:function(e){var t=Math.ceil,r=Math.floor;e.exports=function(e){return isNaN(e=+e)?0:(e>0?r:t)(e)}},7466:function(e,t,r){var n=r(9958),i=Math.min;e.exports=function(e){return e>0?i(n(e),9007199254740991):0}},7908:function(e,t,r){var n=r(4488);e.exports=function(e){return Object(n(e))}},7593:function(e,t,r){var n=r(111);e.exports=function(e,t){if(!n(e))return e;var r,i;if(t&&"function"==typeof(r=e.toString)&&!n(i=r.call(e)))return i;if("function"==typeof(r=e.valueOf)&&!n(i=r.call(e)))return i;if(!t&&"function"==typeof(r=e.toString)&&!n(i=r.call(e)))return i;throw TypeError("Can\'t convert object to primitive value")}},1694:function(e,t,r){var n={};n[r(5112)("toStringTag")]="z",e.exports="[object z]"===String(n)},9711:function(e){var t=0,r=Math.random();e.exports=function(e){return"Symbol("+String(void 0===e?"":e)+")_"+(++t+r).toString(36)}},3307:function(e,t,r){var n=r(133);e.exports=n&&!Symbol.sham&&"symbol"==typeof Symbol.iterator},5112:function(e,t,r){var n=r(7854),i=r(2309),o=r(6656),s=r(9711),a=r(133),u=r(3307),c=i("wks"),l=n.Symbol,f=u?l:l&&l.withoutSetter||s;e.exports=function(e){return o(c,e)&&(a||"string"==typeof c[e])||(a&&o(l,e)?c[e]=l[e]:c[e]=f("Symbol."+e)),c[e]}},1361:function(e){e.exports="\\t\\n\\v\\f\\r                　\\u2028\\u2029\\ufeff"},1249:function(e,t,r){"use strict";var n=r(2109),i=r(2092).map;n({target:"Array",proto:!0,forced:!r(1194)("map")},{map:function(e){return i(this,e,arguments.length>1?arguments[1]:void 0)}})},7042:function(e,t,r){"use strict";var n=r(2109),i=r(111),o=r(3157),s=r(1400),a=r(7466),u=r(5656),c=r(6135),l=r(5112),f=r(1194)("slice"),h=l("species"),p=[].slice,d=Math.max;n({target:"Array",proto:!0,forced:!f},{slice:function(e,t){var r,n,l,f=u(this),v=a(f.length),y=s(e,v),g=s(void 0===t?v:t,v);if(o(f)&&("function"!=typeof(r=f.constructor)||r!==Array&&!o(r.prototype)?i(r)&&null===(r=r[h])&&(r=void 0):r=void 0,r===Array||void 0===r))return p.call(f,y,g);for(n=new(void 0===r?Array:r)(d(g-y,0)),l=0;y<g;y++,l++)y in f&&c(n,l,f[y]);return n.length=l,n}})},1539:function(e,t,r){var n=r(1694),i=r(1320),o=r(288);n||i(Object.prototype,"toString",o,{unsafe:!0})},8674:function(e,t,r){"use strict";var n,i,o,s,a=r(2109),u=r(1913),c=r(7854),l=r(5005),f=r(3366),h=r(1320),p=r(2248),d=r(7674),v=r(8003),y=r(6340),g=r(111),m=r(3099),x=r(5787),w=r(2788),b=r(408),E=r(7072),S=r(6707),L=r(261).set,k=r(5948),T=r(9478),P=r(842),O=r(8523),Q=r(2534),j=r(9909),R=r(4705),I=r(5112),_=r(7871),C=r(5268),N=r(7392),F=I("species"),A="Promise",D=j.get,M=j.set,B=j.getterFor(A),V=f&&f.prototype,z=f,$=V,U=c.TypeError,G=c.document,q=c.process,W=O.f,H=W,J=!!(G&&G.createEvent&&c.dispatchEvent),Y="function"==typeof PromiseRejectionEvent,K="unhandledrejection",X=!1,Z=R(A,(function(){var e=w(z)!==String(z);if(!e&&66===N)return!0;if(u&&!$.finally)return!0;if(N>=51&&/native code/.test(z))return!1;var t=new z((function(e){e(1)})),r=function(e){e((function(){}),(function(){}))};return(t.constructor={})[F]=r,!(X=t.then((function(){}))instanceof r)||!e&&_&&!Y})),ee=Z||!E((function(e){z.all(e).catch((function(){}))})),te=function(e){var t;return!(!g(e)||"function"!=typeof(t=e.then))&&t},re=function(e,t){if(!e.notified){e.notified=!0;var r=e.reactions;k((function(){for(var n=e.value,i=1==e.state,o=0;r.length>o;){var s,a,u,c=r[o++],l=i?c.ok:c.fail,f=c.resolve,h=c.reject,p=c.domain;try{l?(i||(2===e.rejection&&se(e),e.rejection=1),!0===l?s=n:(p&&p.enter(),s=l(n),p&&(p.exit(),u=!0)),s===c.promise?h(U("Promise-chain cycle")):(a=te(s))?a.call(s,f,h):f(s)):h(n)}catch(e){p&&!u&&p.exit(),h(e)}}e.reactions=[],e.notified=!1,t&&!e.rejection&&ie(e)}))}},ne=function(e,t,r){var n,i;J?((n=G.createEvent("Event")).promise=t,n.reason=r,n.initEvent(e,!1,!0),c.dispatchEvent(n)):n={promise:t,reason:r},!Y&&(i=c["on"+e])?i(n):e===K&&P("Unhandled promise rejection",r)},ie=function(e){L.call(c,(function(){var t,r=e.facade,n=e.value;if(oe(e)&&(t=Q((function(){C?q.emit("unhandledRejection",n,r):ne(K,r,n)})),e.rejection=C||oe(e)?2:1,t.error))throw t.value}))},oe=function(e){return 1!==e.rejection&&!e.parent},se=function(e){L.call(c,(function(){var t=e.facade;C?q.emit("rejectionHandled",t):ne("rejectionhandled",t,e.value)}))},ae=function(e,t,r){return function(n){e(t,n,r)}},ue=function(e,t,r){e.done||(e.done=!0,r&&(e=r),e.value=t,e.state=2,re(e,!0))},ce=function(e,t,r){if(!e.done){e.done=!0,r&&(e=r);try{if(e.facade===t)throw U("Promise can\'t be resolved itself");var n=te(t);n?k((function(){var r={done:!1};try{n.call(t,ae(ce,r,e),ae(ue,r,e))}catch(t){ue(r,t,e)}})):(e.value=t,e.state=1,re(e,!1))}catch(t){ue({done:!1},t,e)}}};if(Z&&($=(z=function(e){x(this,z,A),m(e),n.call(this);var t=D(this);try{e(ae(ce,t),ae(ue,t))}catch(e){ue(t,e)}}).prototype,(n=function(e){M(this,{type:A,done:!1,notified:!1,parent:!1,reactions:[],rejection:!1,state:0,value:void 0})}).prototype=p($,{then:function(e,t){var r=B(this),n=W(S(this,z));return n.ok="function"!=typeof e||e,n.fail="function"==typeof t&&t,n.domain=C?q.domain:void 0,r.parent=!0,r.reactions.push(n),0!=r.state&&re(r,!1),n.promise},catch:function(e){return this.then(void 0,e)}}),i=function(){var e=new n,t=D(e);this.promise=e,this.resolve=ae(ce,t),this.reject=ae(ue,t)},O.f=W=function(e){return e===z||e===o?new i(e):H(e)},!u&&"function"==typeof f&&V!==Object.prototype)){s=V.then,X||(h(V,"then",(function(e,t){var r=this;return new z((function(e,t){s.call(r,e,t)})).then(e,t)}),{unsafe:!0}),h(V,"catch",$.catch,{unsafe:!0}));try{delete V.constructor}catch(e){}d&&d(V,$)}a({global:!0,wrap:!0,forced:Z},{Promise:z}),v(z,A,!1,!0),y(A),o=l(A),a({target:A,stat:!0,forced:Z},{reject:function(e){var t=W(this);return t.reject.call(void 0,e),t.promise}}),a({target:A,stat:!0,forced:u||Z},{resolve:function(e){return T(u&&this===o?z:this,e)}}),a({target:A,stat:!0,forced:ee},{all:function(e){var t=this,r=W(t),n=r.resolve,i=r.reject,o=Q((function(){var r=m(t.resolve),o=[],s=0,a=1;b(e,(function(e){var u=s++,c=!1;o.push(void 0),a++,r.call(t,e).then((function(e){c||(c=!0,o[u]=e,--a||n(o))}),i)})),--a||n(o)}));return o.error&&i(o.value),r.promise},race:function(e){var t=this,r=W(t),n=r.reject,i=Q((function(){var i=m(t.resolve);b(e,(function(e){i.call(t,e).then(r.resolve,n)}))}));return i.error&&n(i.value),r.promise}})},4916:function(e,t,r){"use strict";var n=r(2109),i=r(2261);n({target:"RegExp",proto:!0,forced:/./.exec!==i},{exec:i})},3123:function(e,t,r){"use strict";var n=r(7007),i=r(7850),o=r(9670),s=r(4488),a=r(6707),u=r(1530),c=r(7466),l=r(7651),f=r(2261),h=r(2999).UNSUPPORTED_Y,p=[].push,d=Math.min,v=4294967295;n("split",2,(function(e,t,r){var n;return n="c"=="abbc".split(/(b)*/)[1]||4!="test".split(/(?:)/,-1).length||2!="ab".split(/(?:ab)*/).length||4!=".".split(/(.?)(.?)/).length||".".split(/()()/).length>1||"".split(/.?/).length?function(e,r){var n=String(s(this)),o=void 0===r?v:r>>>0;if(0===o)return[];if(void 0===e)return[n];if(!i(e))return t.call(n,e,o);for(var a,u,c,l=[],h=(e.ignoreCase?"i":"")+(e.multiline?"m":"")+(e.unicode?"u":"")+(e.sticky?"y":""),d=0,y=new RegExp(e.source,h+"g");(a=f.call(y,n))&&!((u=y.lastIndex)>d&&(l.push(n.slice(d,a.index)),a.length>1&&a.index<n.length&&p.apply(l,a.slice(1)),c=a[0].length,d=u,l.length>=o));)y.lastIndex===a.index&&y.lastIndex++;return d===n.length?!c&&y.test("")||l.push(""):l.push(n.slice(d)),l.length>o?l.slice(0,o):l}:"0".split(void 0,0).length?function(e,r){return void 0===e&&0===r?[]:t.call(this,e,r)}:t,[function(t,r){var i=s(this),o=null==t?void 0:t[e];return void 0!==o?o.call(t,i,r):n.call(String(i),t,r)},function(e,i){var s=r(n,e,this,i,n!==t);if(s.done)return s.value;var f=o(e),p=String(this),y=a(f,RegExp),g=f.unicode,m=(f.ignoreCase?"i":"")+(f.multiline?"m":"")+(f.unicode?"u":"")+(h?"g":"y"),x=new y(h?"^(?:"+f.source+")":f,m),w=void 0===i?v:i>>>0;if(0===w)return[];if(0===p.length)return null===l(x,p)?[p]:[];for(var b=0,E=0,S=[];E<p.length;){x.lastIndex=h?0:E;var L,k=l(x,h?p.slice(E):p);if(null===k||(L=d(c(x.lastIndex+(h?E:0)),p.length))===b)E=u(p,E,g);else{if(S.push(p.slice(b,E)),S.length===w)return S;for(var T=1;T<=k.length-1;T++)if(S.push(k[T]),S.length===w)return S;E=b=L}}return S.push(p.slice(b)),S}]}),h)},3210:function(e,t,r){"use strict";var n=r(2109),i=r(3111).trim;n({target:"String",proto:!0,forced:r(6091)("trim")},{trim:function(){return i(this)}})},4747:function(e,t,r){var n=r(7854),i=r(8324),o=r(8533),s=r(8880);for(var a in i){var u=n[a],c=u&&u.prototype;if(c&&c.forEach!==o)try{s(c,"forEach",o)}catch(e){c.forEach=o}}},3753:function(e,t,r){"use strict";r(2109)({target:"URL",proto:!0,enumerable:!0},{toJSON:function(){return URL.prototype.toString.call(this)}})},1336:function(e,t,r){var n,i;!function(){var o,s,a,u,c,l,f,h,p,d,v,y,g,m,x,w,b,E,S,L,k,T,P,O,Q,j,R=function(e){var t=new R.Builder;return t.pipeline.add(R.trimmer,R.stopWordFilter,R.stemmer),t.searchPipeline.add(R.stemmer),e.call(t,t),t.build()};R.version="2.3.9",R.utils={},R.utils.warn=(o=this,function(e){o.console&&console.warn&&console.warn(e)}),R.utils.asString=function(e){return null==e?"":e.toString()},R.utils.clone=function(e){if(null==e)return e;for(var t=Object.create(null),r=Object.keys(e),n=0;n<r.length;n++){var i=r[n],o=e[i];if(Array.isArray(o))t[i]=o.slice();else{if("string"!=typeof o&&"number"!=typeof o&&"boolean"!=typeof o)throw new TypeError("clone is not deep and does not support nested objects");t[i]=o}}return t},R.FieldRef=function(e,t,r){this.docRef=e,this.fieldName=t,this._stringValue=r},R.FieldRef.joiner="/",R.FieldRef.fromString=function(e){var t=e.indexOf(R.FieldRef.joiner);if(-1===t)throw"malformed field ref string";var r=e.slice(0,t),n=e.slice(t+1);return new R.FieldRef(n,r,e)},R.FieldRef.prototype.toString=function(){return null==this._stringValue&&(this._stringValue=this.fieldName+R.FieldRef.joiner+this.docRef),this._stringValue},R.Set=function(e){if(this.elements=Object.create(null),e){this.length=e.length;for(var t=0;t<this.length;t++)this.elements[e[t]]=!0}else this.length=0},R.Set.complete={intersect:function(e){return e},union:function(){return this},contains:function(){return!0}},R.Set.empty={intersect:function(){return this},union:function(e){return e},contains:function(){return!1}},R.Set.prototype.contains=function(e){return!!this.elements[e]},R.Set.prototype.intersect=function(e){var t,r,n,i=[];if(e===R.Set.complete)return this;if(e===R.Set.empty)return e;this.length<e.length?(t=this,r=e):(t=e,r=this),n=Object.keys(t.elements);for(var o=0;o<n.length;o++){var s=n[o];s in r.elements&&i.push(s)}return new R.Set(i)},R.Set.prototype.union=function(e){return e===R.Set.complete?R.Set.complete:e===R.Set.empty?this:new R.Set(Object.keys(this.elements).concat(Object.keys(e.elements)))},R.idf=function(e,t){var r=0;for(var n in e)"_index"!=n&&(r+=Object.keys(e[n]).length);var i=(t-r+.5)/(r+.5);return Math.log(1+Math.abs(i))},R.Token=function(e,t){this.str=e||"",this.metadata=t||{}},R.Token.prototype.toString=function(){return this.str},R.Token.prototype.update=function(e){return this.str=e(this.str,this.metadata),this},R.Token.prototype.clone=function(e){return e=e||function(e){return e},new R.Token(e(this.str,this.metadata),this.metadata)},R.tokenizer=function(e,t){if(null==e||null==e)return[];if(Array.isArray(e))return e.map((function(e){return new R.Token(R.utils.asString(e).toLowerCase(),R.utils.clone(t))}));for(var r=e.toString().toLowerCase(),n=r.length,i=[],o=0,s=0;o<=n;o++){var a=o-s;if(r.charAt(o).match(R.tokenizer.separator)||o==n){if(a>0){var u=R.utils.clone(t)||{};u.position=[s,a],u.index=i.length,i.push(new R.Token(r.slice(s,o),u))}s=o+1}}return i},R.tokenizer.separator=/[\\s\\-]+/,R.Pipeline=function(){this._stack=[]},R.Pipeline.registeredFunctions=Object.create(null),R.Pipeline.registerFunction=function(e,t){t in this.registeredFunctions&&R.utils.warn("Overwriting existing registered function: "+t),e.label=t,R.Pipeline.registeredFunctions[e.label]=e},R.Pipeline.warnIfFunctionNotRegistered=function(e){e.label&&e.label in this.registeredFunctions||R.utils.warn("Function is not registered with pipeline. This may cause problems when serialising the index.\\n",e)},R.Pipeline.load=function(e){var t=new R.Pipeline;return e.forEach((function(e){var r=R.Pipeline.registeredFunctions[e];if(!r)throw new Error("Cannot load unregistered function: "+e);t.add(r)})),t},R.Pipeline.prototype.add=function(){var e=Array.prototype.slice.call(arguments);e.forEach((function(e){R.Pipeline.warnIfFunctionNotRegistered(e),this._stack.push(e)}),this)},R.Pipeline.prototype.after=function(e,t){R.Pipeline.warnIfFunctionNotRegistered(t);var r=this._stack.indexOf(e);if(-1==r)throw new Error("Cannot find existingFn");r+=1,this._stack.splice(r,0,t)},R.Pipeline.prototype.before=function(e,t){R.Pipeline.warnIfFunctionNotRegistered(t);var r=this._stack.indexOf(e);if(-1==r)throw new Error("Cannot find existingFn");this._stack.splice(r,0,t)},R.Pipeline.prototype.remove=function(e){var t=this._stack.indexOf(e);-1!=t&&this._stack.splice(t,1)},R.Pipeline.prototype.run=function(e){for(var t=this._stack.length,r=0;r<t;r++){for(var n=this._stack[r],i=[],o=0;o<e.length;o++){var s=n(e[o],o,e);if(null!=s&&""!==s)if(Array.isArray(s))for(var a=0;a<s.length;a++)i.push(s[a]);else i.push(s)}e=i}return e},R.Pipeline.prototype.runString=function(e,t){var r=new R.Token(e,t);return this.run([r]).map((function(e){return e.toString()}))},R.Pipeline.prototype.reset=function(){this._stack=[]},R.Pipeline.prototype.toJSON=function(){return this._stack.map((function(e){return R.Pipeline.warnIfFunctionNotRegistered(e),e.label}))},R.Vector=function(e){this._magnitude=0,this.elements=e||[]},R.Vector.prototype.positionForIndex=function(e){if(0==this.elements.length)return 0;for(var t=0,r=this.elements.length/2,n=r-t,i=Math.floor(n/2),o=this.elements[2*i];n>1&&(o<e&&(t=i),o>e&&(r=i),o!=e);)n=r-t,i=t+Math.floor(n/2),o=this.elements[2*i];return o==e||o>e?2*i:o<e?2*(i+1):void 0},R.Vector.prototype.insert=function(e,t){this.upsert(e,t,(function(){throw"duplicate index"}))},R.Vector.prototype.upsert=function(e,t,r){this._magnitude=0;var n=this.positionForIndex(e);this.elements[n]==e?this.elements[n+1]=r(this.elements[n+1],t):this.elements.splice(n,0,e,t)},R.Vector.prototype.magnitude=function(){if(this._magnitude)return this._magnitude;for(var e=0,t=this.elements.length,r=1;r<t;r+=2){var n=this.elements[r];e+=n*n}return this._magnitude=Math.sqrt(e)},R.Vector.prototype.dot=function(e){for(var t=0,r=this.elements,n=e.elements,i=r.length,o=n.length,s=0,a=0,u=0,c=0;u<i&&c<o;)(s=r[u])<(a=n[c])?u+=2:s>a?c+=2:s==a&&(t+=r[u+1]*n[c+1],u+=2,c+=2);return t},R.Vector.prototype.similarity=function(e){return this.dot(e)/this.magnitude()||0},R.Vector.prototype.toArray=function(){for(var e=new Array(this.elements.length/2),t=1,r=0;t<this.elements.length;t+=2,r++)e[r]=this.elements[t];return e},R.Vector.prototype.toJSON=function(){return this.elements},R.stemmer=(s={ational:"ate",tional:"tion",enci:"ence",anci:"ance",izer:"ize",bli:"ble",alli:"al",entli:"ent",eli:"e",ousli:"ous",ization:"ize",ation:"ate",ator:"ate",alism:"al",iveness:"ive",fulness:"ful",ousness:"ous",aliti:"al",iviti:"ive",biliti:"ble",logi:"log"},a={icate:"ic",ative:"",alize:"al",iciti:"ic",ical:"ic",ful:"",ness:""},u="[aeiouy]",c="[^aeiou][^aeiouy]*",l=new RegExp("^([^aeiou][^aeiouy]*)?[aeiouy][aeiou]*[^aeiou][^aeiouy]*"),f=new RegExp("^([^aeiou][^aeiouy]*)?[aeiouy][aeiou]*[^aeiou][^aeiouy]*[aeiouy][aeiou]*[^aeiou][^aeiouy]*"),h=new RegExp("^([^aeiou][^aeiouy]*)?[aeiouy][aeiou]*[^aeiou][^aeiouy]*([aeiouy][aeiou]*)?$"),p=new RegExp("^([^aeiou][^aeiouy]*)?[aeiouy]"),d=/^(.+?)(ss|i)es$/,v=/^(.+?)([^s])s$/,y=/^(.+?)eed$/,g=/^(.+?)(ed|ing)$/,m=/.$/,x=/(at|bl|iz)$/,w=new RegExp("([^aeiouylsz])\\\\1$"),b=new RegExp("^"+c+u+"[^aeiouwxy]$"),E=/^(.+?[^aeiou])y$/,S=/^(.+?)(ational|tional|enci|anci|izer|bli|alli|entli|eli|ousli|ization|ation|ator|alism|iveness|fulness|ousness|aliti|iviti|biliti|logi)$/,L=/^(.+?)(icate|ative|alize|iciti|ical|ful|ness)$/,k=/^(.+?)(al|ance|ence|er|ic|able|ible|ant|ement|ment|ent|ou|ism|ate|iti|ous|ive|ize)$/,T=/^(.+?)(s|t)(ion)$/,P=/^(.+?)e$/,O=/ll$/,Q=new RegExp("^"+c+u+"[^aeiouwxy]$"),j=function(e){var t,r,n,i,o,u,c;if(e.length<3)return e;if("y"==(n=e.substr(0,1))&&(e=n.toUpperCase()+e.substr(1)),o=v,(i=d).test(e)?e=e.replace(i,"$1$2"):o.test(e)&&(e=e.replace(o,"$1$2")),o=g,(i=y).test(e)){var j=i.exec(e);(i=l).test(j[1])&&(i=m,e=e.replace(i,""))}else o.test(e)&&(t=(j=o.exec(e))[1],(o=p).test(t)&&(u=w,c=b,(o=x).test(e=t)?e+="e":u.test(e)?(i=m,e=e.replace(i,"")):c.test(e)&&(e+="e")));return(i=E).test(e)&&(e=(t=(j=i.exec(e))[1])+"i"),(i=S).test(e)&&(t=(j=i.exec(e))[1],r=j[2],(i=l).test(t)&&(e=t+s[r])),(i=L).test(e)&&(t=(j=i.exec(e))[1],r=j[2],(i=l).test(t)&&(e=t+a[r])),o=T,(i=k).test(e)?(t=(j=i.exec(e))[1],(i=f).test(t)&&(e=t)):o.test(e)&&(t=(j=o.exec(e))[1]+j[2],(o=f).test(t)&&(e=t)),(i=P).test(e)&&(t=(j=i.exec(e))[1],o=h,u=Q,((i=f).test(t)||o.test(t)&&!u.test(t))&&(e=t)),o=f,(i=O).test(e)&&o.test(e)&&(i=m,e=e.replace(i,"")),"y"==n&&(e=n.toLowerCase()+e.substr(1)),e},function(e){return e.update(j)}),R.Pipeline.registerFunction(R.stemmer,"stemmer"),R.generateStopWordFilter=function(e){var t=e.reduce((function(e,t){return e[t]=t,e}),{});return function(e){if(e&&t[e.toString()]!==e.toString())return e}},R.stopWordFilter=R.generateStopWordFilter(["a","able","about","across","after","all","almost","also","am","among","an","and","any","are","as","at","be","because","been","but","by","can","cannot","could","dear","did","do","does","either","else","ever","every","for","from","get","got","had","has","have","he","her","hers","him","his","how","however","i","if","in","into","is","it","its","just","least","let","like","likely","may","me","might","most","must","my","neither","no","nor","not","of","off","often","on","only","or","other","our","own","rather","said","say","says","she","should","since","so","some","than","that","the","their","them","then","there","these","they","this","tis","to","too","twas","us","wants","was","we","were","what","when","where","which","while","who","whom","why","will","with","would","yet","you","your"]),R.Pipeline.registerFunction(R.stopWordFilter,"stopWordFilter"),R.trimmer=function(e){return e.update((function(e){return e.replace(/^\\W+/,"").replace(/\\W+$/,"")}))},R.Pipeline.registerFunction(R.trimmer,"trimmer"),R.TokenSet=function(){this.final=!1,this.edges={},this.id=R.TokenSet._nextId,R.TokenSet._nextId+=1},R.TokenSet._nextId=1,R.TokenSet.fromArray=function(e){for(var t=new R.TokenSet.Builder,r=0,n=e.length;r<n;r++)t.insert(e[r]);return t.finish(),t.root},R.TokenSet.fromClause=function(e){return"editDistance"in e?R.TokenSet.fromFuzzyString(e.term,e.editDistance):R.TokenSet.fromString(e.term)},R.TokenSet.fromFuzzyString=function(e,t){for(var r=new R.TokenSet,n=[{node:r,editsRemaining:t,str:e}];n.length;){var i=n.pop();if(i.str.length>0){var o,s=i.str.charAt(0);s in i.node.edges?o=i.node.edges[s]:(o=new R.TokenSet,i.node.edges[s]=o),1==i.str.length&&(o.final=!0),n.push({node:o,editsRemaining:i.editsRemaining,str:i.str.slice(1)})}if(0!=i.editsRemaining){if("*"in i.node.edges)var a=i.node.edges["*"];else a=new R.TokenSet,i.node.edges["*"]=a;if(0==i.str.length&&(a.final=!0),n.push({node:a,editsRemaining:i.editsRemaining-1,str:i.str}),i.str.length>1&&n.push({node:i.node,editsRemaining:i.editsRemaining-1,str:i.str.slice(1)}),1==i.str.length&&(i.node.final=!0),i.str.length>=1){if("*"in i.node.edges)var u=i.node.edges["*"];else u=new R.TokenSet,i.node.edges["*"]=u;1==i.str.length&&(u.final=!0),n.push({node:u,editsRemaining:i.editsRemaining-1,str:i.str.slice(1)})}if(i.str.length>1){var c,l=i.str.charAt(0),f=i.str.charAt(1);f in i.node.edges?c=i.node.edges[f]:(c=new R.TokenSet,i.node.edges[f]=c),1==i.str.length&&(c.final=!0),n.push({node:c,editsRemaining:i.editsRemaining-1,str:l+i.str.slice(2)})}}}return r},R.TokenSet.fromString=function(e){for(var t=new R.TokenSet,r=t,n=0,i=e.length;n<i;n++){var o=e[n],s=n==i-1;if("*"==o)t.edges[o]=t,t.final=s;else{var a=new R.TokenSet;a.final=s,t.edges[o]=a,t=a}}return r},R.TokenSet.prototype.toArray=function(){for(var e=[],t=[{prefix:"",node:this}];t.length;){var r=t.pop(),n=Object.keys(r.node.edges),i=n.length;r.node.final&&(r.prefix.charAt(0),e.push(r.prefix));for(var o=0;o<i;o++){var s=n[o];t.push({prefix:r.prefix.concat(s),node:r.node.edges[s]})}}return e},R.TokenSet.prototype.toString=function(){if(this._str)return this._str;for(var e=this.final?"1":"0",t=Object.keys(this.edges).sort(),r=t.length,n=0;n<r;n++){var i=t[n];e=e+i+this.edges[i].id}return e},R.TokenSet.prototype.intersect=function(e){for(var t=new R.TokenSet,r=void 0,n=[{qNode:e,output:t,node:this}];n.length;){r=n.pop();for(var i=Object.keys(r.qNode.edges),o=i.length,s=Object.keys(r.node.edges),a=s.length,u=0;u<o;u++)for(var c=i[u],l=0;l<a;l++){var f=s[l];if(f==c||"*"==c){var h=r.node.edges[f],p=r.qNode.edges[c],d=h.final&&p.final,v=void 0;f in r.output.edges?(v=r.output.edges[f]).final=v.final||d:((v=new R.TokenSet).final=d,r.output.edges[f]=v),n.push({qNode:p,output:v,node:h})}}}return t},R.TokenSet.Builder=function(){this.previousWord="",this.root=new R.TokenSet,this.uncheckedNodes=[],this.minimizedNodes={}},R.TokenSet.Builder.prototype.insert=function(e){var t,r=0;if(e<this.previousWord)throw new Error("Out of order word insertion");for(var n=0;n<e.length&&n<this.previousWord.length&&e[n]==this.previousWord[n];n++)r++;for(this.minimize(r),t=0==this.uncheckedNodes.length?this.root:this.uncheckedNodes[this.uncheckedNodes.length-1].child,n=r;n<e.length;n++){var i=new R.TokenSet,o=e[n];t.edges[o]=i,this.uncheckedNodes.push({parent:t,char:o,child:i}),t=i}t.final=!0,this.previousWord=e},R.TokenSet.Builder.prototype.finish=function(){this.minimize(0)},R.TokenSet.Builder.prototype.minimize=function(e){for(var t=this.uncheckedNodes.length-1;t>=e;t--){var r=this.uncheckedNodes[t],n=r.child.toString();n in this.minimizedNodes?r.parent.edges[r.char]=this.minimizedNodes[n]:(r.child._str=n,this.minimizedNodes[n]=r.child),this.uncheckedNodes.pop()}},R.Index=function(e){this.invertedIndex=e.invertedIndex,this.fieldVectors=e.fieldVectors,this.tokenSet=e.tokenSet,this.fields=e.fields,this.pipeline=e.pipeline},R.Index.prototype.search=function(e){return this.query((function(t){new R.QueryParser(e,t).parse()}))},R.Index.prototype.query=function(e){for(var t=new R.Query(this.fields),r=Object.create(null),n=Object.create(null),i=Object.create(null),o=Object.create(null),s=Object.create(null),a=0;a<this.fields.length;a++)n[this.fields[a]]=new R.Vector;for(e.call(t,t),a=0;a<t.clauses.length;a++){var u,c=t.clauses[a],l=R.Set.empty;u=c.usePipeline?this.pipeline.runString(c.term,{fields:c.fields}):[c.term];for(var f=0;f<u.length;f++){var h=u[f];c.term=h;var p=R.TokenSet.fromClause(c),d=this.tokenSet.intersect(p).toArray();if(0===d.length&&c.presence===R.Query.presence.REQUIRED){for(var v=0;v<c.fields.length;v++)o[I=c.fields[v]]=R.Set.empty;break}for(var y=0;y<d.length;y++){var g=d[y],m=this.invertedIndex[g],x=m._index;for(v=0;v<c.fields.length;v++){var w=m[I=c.fields[v]],b=Object.keys(w),E=g+"/"+I,S=new R.Set(b);if(c.presence==R.Query.presence.REQUIRED&&(l=l.union(S),void 0===o[I]&&(o[I]=R.Set.complete)),c.presence!=R.Query.presence.PROHIBITED){if(n[I].upsert(x,c.boost,(function(e,t){return e+t})),!i[E]){for(var L=0;L<b.length;L++){var k,T=b[L],P=new R.FieldRef(T,I),O=w[T];void 0===(k=r[P])?r[P]=new R.MatchData(g,I,O):k.add(g,I,O)}i[E]=!0}}else void 0===s[I]&&(s[I]=R.Set.empty),s[I]=s[I].union(S)}}}if(c.presence===R.Query.presence.REQUIRED)for(v=0;v<c.fields.length;v++)o[I=c.fields[v]]=o[I].intersect(l)}var Q=R.Set.complete,j=R.Set.empty;for(a=0;a<this.fields.length;a++){var I;o[I=this.fields[a]]&&(Q=Q.intersect(o[I])),s[I]&&(j=j.union(s[I]))}var _=Object.keys(r),C=[],N=Object.create(null);if(t.isNegated())for(_=Object.keys(this.fieldVectors),a=0;a<_.length;a++){P=_[a];var F=R.FieldRef.fromString(P);r[P]=new R.MatchData}for(a=0;a<_.length;a++){var A=(F=R.FieldRef.fromString(_[a])).docRef;if(Q.contains(A)&&!j.contains(A)){var D,M=this.fieldVectors[F],B=n[F.fieldName].similarity(M);if(void 0!==(D=N[A]))D.score+=B,D.matchData.combine(r[F]);else{var V={ref:A,score:B,matchData:r[F]};N[A]=V,C.push(V)}}}return C.sort((function(e,t){return t.score-e.score}))},R.Index.prototype.toJSON=function(){var e=Object.keys(this.invertedIndex).sort().map((function(e){return[e,this.invertedIndex[e]]}),this),t=Object.keys(this.fieldVectors).map((function(e){return[e,this.fieldVectors[e].toJSON()]}),this);return{version:R.version,fields:this.fields,fieldVectors:t,invertedIndex:e,pipeline:this.pipeline.toJSON()}},R.Index.load=function(e){var t={},r={},n=e.fieldVectors,i=Object.create(null),o=e.invertedIndex,s=new R.TokenSet.Builder,a=R.Pipeline.load(e.pipeline);e.version!=R.version&&R.utils.warn("Version mismatch when loading serialised index. Current version of lunr \'"+R.version+"\' does not match serialized index \'"+e.version+"\'");for(var u=0;u<n.length;u++){var c=(f=n[u])[0],l=f[1];r[c]=new R.Vector(l)}for(u=0;u<o.length;u++){var f,h=(f=o[u])[0],p=f[1];s.insert(h),i[h]=p}return s.finish(),t.fields=e.fields,t.fieldVectors=r,t.invertedIndex=i,t.tokenSet=s.root,t.pipeline=a,new R.Index(t)},R.Builder=function(){this._ref="id",this._fields=Object.create(null),this._documents=Object.create(null),this.invertedIndex=Object.create(null),this.fieldTermFrequencies={},this.fieldLengths={},this.tokenizer=R.tokenizer,this.pipeline=new R.Pipeline,this.searchPipeline=new R.Pipeline,this.documentCount=0,this._b=.75,this._k1=1.2,this.termIndex=0,this.metadataWhitelist=[]},R.Builder.prototype.ref=function(e){this._ref=e},R.Builder.prototype.field=function(e,t){if(/\\//.test(e))throw new RangeError("Field \'"+e+"\' contains illegal character \'/\'");this._fields[e]=t||{}},R.Builder.prototype.b=function(e){this._b=e<0?0:e>1?1:e},R.Builder.prototype.k1=function(e){this._k1=e},R.Builder.prototype.add=function(e,t){var r=e[this._ref],n=Object.keys(this._fields);this._documents[r]=t||{},this.documentCount+=1;for(var i=0;i<n.length;i++){var o=n[i],s=this._fields[o].extractor,a=s?s(e):e[o],u=this.tokenizer(a,{fields:[o]}),c=this.pipeline.run(u),l=new R.FieldRef(r,o),f=Object.create(null);this.fieldTermFrequencies[l]=f,this.fieldLengths[l]=0,this.fieldLengths[l]+=c.length;for(var h=0;h<c.length;h++){var p=c[h];if(null==f[p]&&(f[p]=0),f[p]+=1,null==this.invertedIndex[p]){var d=Object.create(null);d._index=this.termIndex,this.termIndex+=1;for(var v=0;v<n.length;v++)d[n[v]]=Object.create(null);this.invertedIndex[p]=d}null==this.invertedIndex[p][o][r]&&(this.invertedIndex[p][o][r]=Object.create(null));for(var y=0;y<this.metadataWhitelist.length;y++){var g=this.metadataWhitelist[y],m=p.metadata[g];null==this.invertedIndex[p][o][r][g]&&(this.invertedIndex[p][o][r][g]=[]),this.invertedIndex[p][o][r][g].push(m)}}}},R.Builder.prototype.calculateAverageFieldLengths=function(){for(var e=Object.keys(this.fieldLengths),t=e.length,r={},n={},i=0;i<t;i++){var o=R.FieldRef.fromString(e[i]),s=o.fieldName;n[s]||(n[s]=0),n[s]+=1,r[s]||(r[s]=0),r[s]+=this.fieldLengths[o]}var a=Object.keys(this._fields);for(i=0;i<a.length;i++){var u=a[i];r[u]=r[u]/n[u]}this.averageFieldLength=r},R.Builder.prototype.createFieldVectors=function(){for(var e={},t=Object.keys(this.fieldTermFrequencies),r=t.length,n=Object.create(null),i=0;i<r;i++){for(var o=R.FieldRef.fromString(t[i]),s=o.fieldName,a=this.fieldLengths[o],u=new R.Vector,c=this.fieldTermFrequencies[o],l=Object.keys(c),f=l.length,h=this._fields[s].boost||1,p=this._documents[o.docRef].boost||1,d=0;d<f;d++){var v,y,g,m=l[d],x=c[m],w=this.invertedIndex[m]._index;void 0===n[m]?(v=R.idf(this.invertedIndex[m],this.documentCount),n[m]=v):v=n[m],y=v*((this._k1+1)*x)/(this._k1*(1-this._b+this._b*(a/this.averageFieldLength[s]))+x),y*=h,y*=p,g=Math.round(1e3*y)/1e3,u.insert(w,g)}e[o]=u}this.fieldVectors=e},R.Builder.prototype.createTokenSet=function(){this.tokenSet=R.TokenSet.fromArray(Object.keys(this.invertedIndex).sort())},R.Builder.prototype.build=function(){return this.calculateAverageFieldLengths(),this.createFieldVectors(),this.createTokenSet(),new R.Index({invertedIndex:this.invertedIndex,fieldVectors:this.fieldVectors,tokenSet:this.tokenSet,fields:Object.keys(this._fields),pipeline:this.searchPipeline})},R.Builder.prototype.use=function(e){var t=Array.prototype.slice.call(arguments,1);t.unshift(this),e.apply(this,t)},R.MatchData=function(e,t,r){for(var n=Object.create(null),i=Object.keys(r||{}),o=0;o<i.length;o++){var s=i[o];n[s]=r[s].slice()}this.metadata=Object.create(null),void 0!==e&&(this.metadata[e]=Object.create(null),this.metadata[e][t]=n)},R.MatchData.prototype.combine=function(e){for(var t=Object.keys(e.metadata),r=0;r<t.length;r++){var n=t[r],i=Object.keys(e.metadata[n]);null==this.metadata[n]&&(this.metadata[n]=Object.create(null));for(var o=0;o<i.length;o++){var s=i[o],a=Object.keys(e.metadata[n][s]);null==this.metadata[n][s]&&(this.metadata[n][s]=Object.create(null));for(var u=0;u<a.length;u++){var c=a[u];null==this.metadata[n][s][c]?this.metadata[n][s][c]=e.metadata[n][s][c]:this.metadata[n][s][c]=this.metadata[n][s][c].concat(e.metadata[n][s][c])}}}},R.MatchData.prototype.add=function(e,t,r){if(!(e in this.metadata))return this.metadata[e]=Object.create(null),void(this.metadata[e][t]=r);if(t in this.metadata[e])for(var n=Object.keys(r),i=0;i<n.length;i++){var o=n[i];o in this.metadata[e][t]?this.metadata[e][t][o]=this.metadata[e][t][o].concat(r[o]):this.metadata[e][t][o]=r[o]}else this.metadata[e][t]=r},R.Query=function(e){this.clauses=[],this.allFields=e},R.Query.wildcard=new String("*"),R.Query.wildcard.NONE=0,R.Query.wildcard.LEADING=1,R.Query.wildcard.TRAILING=2,R.Query.presence={OPTIONAL:1,REQUIRED:2,PROHIBITED:3},R.Query.prototype.clause=function(e){return"fields"in e||(e.fields=this.allFields),"boost"in e||(e.boost=1),"usePipeline"in e||(e.usePipeline=!0),"wildcard"in e||(e.wildcard=R.Query.wildcard.NONE),e.wildcard&R.Query.wildcard.LEADING&&e.term.charAt(0)!=R.Query.wildcard&&(e.term="*"+e.term),e.wildcard&R.Query.wildcard.TRAILING&&e.term.slice(-1)!=R.Query.wildcard&&(e.term=e.term+"*"),"presence"in e||(e.presence=R.Query.presence.OPTIONAL),this.clauses.push(e),this},R.Query.prototype.isNegated=function(){for(var e=0;e<this.clauses.length;e++)if(this.clauses[e].presence!=R.Query.presence.PROHIBITED)return!1;return!0},R.Query.prototype.term=function(e,t){if(Array.isArray(e))return e.forEach((function(e){this.term(e,R.utils.clone(t))}),this),this;var r=t||{};return r.term=e.toString(),this.clause(r),this},R.QueryParseError=function(e,t,r){this.name="QueryParseError",this.message=e,this.start=t,this.end=r},R.QueryParseError.prototype=new Error,R.QueryLexer=function(e){this.lexemes=[],this.str=e,this.length=e.length,this.pos=0,this.start=0,this.escapeCharPositions=[]},R.QueryLexer.prototype.run=function(){for(var e=R.QueryLexer.lexText;e;)e=e(this)},R.QueryLexer.prototype.sliceString=function(){for(var e=[],t=this.start,r=this.pos,n=0;n<this.escapeCharPositions.length;n++)r=this.escapeCharPositions[n],e.push(this.str.slice(t,r)),t=r+1;return e.push(this.str.slice(t,this.pos)),this.escapeCharPositions.length=0,e.join("")},R.QueryLexer.prototype.emit=function(e){this.lexemes.push({type:e,str:this.sliceString(),start:this.start,end:this.pos}),this.start=this.pos},R.QueryLexer.prototype.escapeCharacter=function(){this.escapeCharPositions.push(this.pos-1),this.pos+=1},R.QueryLexer.prototype.next=function(){if(this.pos>=this.length)return R.QueryLexer.EOS;var e=this.str.charAt(this.pos);return this.pos+=1,e},R.QueryLexer.prototype.width=function(){return this.pos-this.start},R.QueryLexer.prototype.ignore=function(){this.start==this.pos&&(this.pos+=1),this.start=this.pos},R.QueryLexer.prototype.backup=function(){this.pos-=1},R.QueryLexer.prototype.acceptDigitRun=function(){var e,t;do{t=(e=this.next()).charCodeAt(0)}while(t>47&&t<58);e!=R.QueryLexer.EOS&&this.backup()},R.QueryLexer.prototype.more=function(){return this.pos<this.length},R.QueryLexer.EOS="EOS",R.QueryLexer.FIELD="FIELD",R.QueryLexer.TERM="TERM",R.QueryLexer.EDIT_DISTANCE="EDIT_DISTANCE",R.QueryLexer.BOOST="BOOST",R.QueryLexer.PRESENCE="PRESENCE",R.QueryLexer.lexField=function(e){return e.backup(),e.emit(R.QueryLexer.FIELD),e.ignore(),R.QueryLexer.lexText},R.QueryLexer.lexTerm=function(e){if(e.width()>1&&(e.backup(),e.emit(R.QueryLexer.TERM)),e.ignore(),e.more())return R.QueryLexer.lexText},R.QueryLexer.lexEditDistance=function(e){return e.ignore(),e.acceptDigitRun(),e.emit(R.QueryLexer.EDIT_DISTANCE),R.QueryLexer.lexText},R.QueryLexer.lexBoost=function(e){return e.ignore(),e.acceptDigitRun(),e.emit(R.QueryLexer.BOOST),R.QueryLexer.lexText},R.QueryLexer.lexEOS=function(e){e.width()>0&&e.emit(R.QueryLexer.TERM)},R.QueryLexer.termSeparator=R.tokenizer.separator,R.QueryLexer.lexText=function(e){for(;;){var t=e.next();if(t==R.QueryLexer.EOS)return R.QueryLexer.lexEOS;if(92!=t.charCodeAt(0)){if(":"==t)return R.QueryLexer.lexField;if("~"==t)return e.backup(),e.width()>0&&e.emit(R.QueryLexer.TERM),R.QueryLexer.lexEditDistance;if("^"==t)return e.backup(),e.width()>0&&e.emit(R.QueryLexer.TERM),R.QueryLexer.lexBoost;if("+"==t&&1===e.width())return e.emit(R.QueryLexer.PRESENCE),R.QueryLexer.lexText;if("-"==t&&1===e.width())return e.emit(R.QueryLexer.PRESENCE),R.QueryLexer.lexText;if(t.match(R.QueryLexer.termSeparator))return R.QueryLexer.lexTerm}else e.escapeCharacter()}},R.QueryParser=function(e,t){this.lexer=new R.QueryLexer(e),this.query=t,this.currentClause={},this.lexemeIdx=0},R.QueryParser.prototype.parse=function(){this.lexer.run(),this.lexemes=this.lexer.lexemes;for(var e=R.QueryParser.parseClause;e;)e=e(this);return this.query},R.QueryParser.prototype.peekLexeme=function(){return this.lexemes[this.lexemeIdx]},R.QueryParser.prototype.consumeLexeme=function(){var e=this.peekLexeme();return this.lexemeIdx+=1,e},R.QueryParser.prototype.nextClause=function(){var e=this.currentClause;this.query.clause(e),this.currentClause={}},R.QueryParser.parseClause=function(e){var t=e.peekLexeme();if(null!=t)switch(t.type){case R.QueryLexer.PRESENCE:return R.QueryParser.parsePresence;case R.QueryLexer.FIELD:return R.QueryParser.parseField;case R.QueryLexer.TERM:return R.QueryParser.parseTerm;default:var r="expected either a field or a term, found "+t.type;throw t.str.length>=1&&(r+=" with value \'"+t.str+"\'"),new R.QueryParseError(r,t.start,t.end)}},R.QueryParser.parsePresence=function(e){var t=e.consumeLexeme();if(null!=t){switch(t.str){case"-":e.currentClause.presence=R.Query.presence.PROHIBITED;break;case"+":e.currentClause.presence=R.Query.presence.REQUIRED;break;default:var r="unrecognised presence operator\'"+t.str+"\'";throw new R.QueryParseError(r,t.start,t.end)}var n=e.peekLexeme();if(null==n)throw r="expecting term or field, found nothing",new R.QueryParseError(r,t.start,t.end);switch(n.type){case R.QueryLexer.FIELD:return R.QueryParser.parseField;case R.QueryLexer.TERM:return R.QueryParser.parseTerm;default:throw r="expecting term or field, found \'"+n.type+"\'",new R.QueryParseError(r,n.start,n.end)}}},R.QueryParser.parseField=function(e){var t=e.consumeLexeme();if(null!=t){if(-1==e.query.allFields.indexOf(t.str)){var r=e.query.allFields.map((function(e){return"\'"+e+"\'"})).join(", "),n="unrecognised field \'"+t.str+"\', possible fields: "+r;throw new R.QueryParseError(n,t.start,t.end)}e.currentClause.fields=[t.str];var i=e.peekLexeme();if(null==i)throw n="expecting term, found nothing",new R.QueryParseError(n,t.start,t.end);switch(i.type){case R.QueryLexer.TERM:return R.QueryParser.parseTerm;default:throw n="expecting term, found \'"+i.type+"\'",new R.QueryParseError(n,i.start,i.end)}}},R.QueryParser.parseTerm=function(e){var t=e.consumeLexeme();if(null!=t){e.currentClause.term=t.str.toLowerCase(),-1!=t.str.indexOf("*")&&(e.currentClause.usePipeline=!1);var r=e.peekLexeme();if(null!=r)switch(r.type){case R.QueryLexer.TERM:return e.nextClause(),R.QueryParser.parseTerm;case R.QueryLexer.FIELD:return e.nextClause(),R.QueryParser.parseField;case R.QueryLexer.EDIT_DISTANCE:return R.QueryParser.parseEditDistance;case R.QueryLexer.BOOST:return R.QueryParser.parseBoost;case R.QueryLexer.PRESENCE:return e.nextClause(),R.QueryParser.parsePresence;default:var n="Unexpected lexeme type \'"+r.type+"\'";throw new R.QueryParseError(n,r.start,r.end)}else e.nextClause()}},R.QueryParser.parseEditDistance=function(e){var t=e.consumeLexeme();if(null!=t){var r=parseInt(t.str,10);if(isNaN(r)){var n="edit distance must be numeric";throw new R.QueryParseError(n,t.start,t.end)}e.currentClause.editDistance=r;var i=e.peekLexeme();if(null!=i)switch(i.type){case R.QueryLexer.TERM:return e.nextClause(),R.QueryParser.parseTerm;case R.QueryLexer.FIELD:return e.nextClause(),R.QueryParser.parseField;case R.QueryLexer.EDIT_DISTANCE:return R.QueryParser.parseEditDistance;case R.QueryLexer.BOOST:return R.QueryParser.parseBoost;case R.QueryLexer.PRESENCE:return e.nextClause(),R.QueryParser.parsePresence;default:throw n="Unexpected lexeme type \'"+i.type+"\'",new R.QueryParseError(n,i.start,i.end)}else e.nextClause()}},R.QueryParser.parseBoost=function(e){var t=e.consumeLexeme();if(null!=t){var r=parseInt(t.str,10);if(isNaN(r)){var n="boost must be numeric";throw new R.QueryParseError(n,t.start,t.end)}e.currentClause.boost=r;var i=e.peekLexeme();if(null!=i)switch(i.type){case R.QueryLexer.TERM:return e.nextClause(),R.QueryParser.parseTerm;case R.QueryLexer.FIELD:return e.nextClause(),R.QueryParser.parseField;case R.QueryLexer.EDIT_DISTANCE:return R.QueryParser.parseEditDistance;case R.QueryLexer.BOOST:return R.QueryParser.parseBoost;case R.QueryLexer.PRESENCE:return e.nextClause(),R.QueryParser.parsePresence;default:throw n="Unexpected lexeme type \'"+i.type+"\'",new R.QueryParseError(n,i.start,i.end)}else e.nextClause()}},void 0===(i="function"==typeof(n=function(){return R})?n.call(t,r,t,e):n)||(e.exports=i)}()},5666:function(e){var t=function(e){"use strict";var t,r=Object.prototype,n=r.hasOwnProperty,i="function"==typeof Symbol?Symbol:{},o=i.iterator||"@@iterator",s=i.asyncIterator||"@@asyncIterator",a=i.toStringTag||"@@toStringTag";function u(e,t,r){return Object.defineProperty(e,t,{value:r,enumerable:!0,configurable:!0,writable:!0}),e[t]}try{u({},"")}catch(e){u=function(e,t,r){return e[t]=r}}function c(e,t,r,n){var i=t&&t.prototype instanceof y?t:y,o=Object.create(i.prototype),s=new O(n||[]);return o._invoke=function(e,t,r){var n=f;return function(i,o){if(n===p)throw new Error("Generator is already running");if(n===d){if("throw"===i)throw o;return j()}for(r.method=i,r.arg=o;;){var s=r.delegate;if(s){var a=k(s,r);if(a){if(a===v)continue;return a}}if("next"===r.method)r.sent=r._sent=r.arg;else if("throw"===r.method){if(n===f)throw n=d,r.arg;r.dispatchException(r.arg)}else"return"===r.method&&r.abrupt("return",r.arg);n=p;var u=l(e,t,r);if("normal"===u.type){if(n=r.done?d:h,u.arg===v)continue;return{value:u.arg,done:r.done}}"throw"===u.type&&(n=d,r.method="throw",r.arg=u.arg)}}}(e,r,s),o}function l(e,t,r){try{return{type:"normal",arg:e.call(t,r)}}catch(e){return{type:"throw",arg:e}}}e.wrap=c;var f="suspendedStart",h="suspendedYield",p="executing",d="completed",v={};function y(){}function g(){}function m(){}var x={};x[o]=function(){return this};var w=Object.getPrototypeOf,b=w&&w(w(Q([])));b&&b!==r&&n.call(b,o)&&(x=b);var E=m.prototype=y.prototype=Object.create(x);function S(e){["next","throw","return"].forEach((function(t){u(e,t,(function(e){return this._invoke(t,e)}))}))}function L(e,t){function r(i,o,s,a){var u=l(e[i],e,o);if("throw"!==u.type){var c=u.arg,f=c.value;return f&&"object"==typeof f&&n.call(f,"__await")?t.resolve(f.__await).then((function(e){r("next",e,s,a)}),(function(e){r("throw",e,s,a)})):t.resolve(f).then((function(e){c.value=e,s(c)}),(function(e){return r("throw",e,s,a)}))}a(u.arg)}var i;this._invoke=function(e,n){function o(){return new t((function(t,i){r(e,n,t,i)}))}return i=i?i.then(o,o):o()}}function k(e,r){var n=e.iterator[r.method];if(n===t){if(r.delegate=null,"throw"===r.method){if(e.iterator.return&&(r.method="return",r.arg=t,k(e,r),"throw"===r.method))return v;r.method="throw",r.arg=new TypeError("The iterator does not provide a \'throw\' method")}return v}var i=l(n,e.iterator,r.arg);if("throw"===i.type)return r.method="throw",r.arg=i.arg,r.delegate=null,v;var o=i.arg;return o?o.done?(r[e.resultName]=o.value,r.next=e.nextLoc,"return"!==r.method&&(r.method="next",r.arg=t),r.delegate=null,v):o:(r.method="throw",r.arg=new TypeError("iterator result is not an object"),r.delegate=null,v)}function T(e){var t={tryLoc:e[0]};1 in e&&(t.catchLoc=e[1]),2 in e&&(t.finallyLoc=e[2],t.afterLoc=e[3]),this.tryEntries.push(t)}function P(e){var t=e.completion||{};t.type="normal",delete t.arg,e.completion=t}function O(e){this.tryEntries=[{tryLoc:"root"}],e.forEach(T,this),this.reset(!0)}function Q(e){if(e){var r=e[o];if(r)return r.call(e);if("function"==typeof e.next)return e;if(!isNaN(e.length)){var i=-1,s=function r(){for(;++i<e.length;)if(n.call(e,i))return r.value=e[i],r.done=!1,r;return r.value=t,r.done=!0,r};return s.next=s}}return{next:j}}function j(){return{value:t,done:!0}}return g.prototype=E.constructor=m,m.constructor=g,g.displayName=u(m,a,"GeneratorFunction"),e.isGeneratorFunction=function(e){var t="function"==typeof e&&e.constructor;return!!t&&(t===g||"GeneratorFunction"===(t.displayName||t.name))},e.mark=function(e){return Object.setPrototypeOf?Object.setPrototypeOf(e,m):(e.__proto__=m,u(e,a,"GeneratorFunction")),e.prototype=Object.create(E),e},e.awrap=function(e){return{__await:e}},S(L.prototype),L.prototype[s]=function(){return this},e.AsyncIterator=L,e.async=function(t,r,n,i,o){void 0===o&&(o=Promise);var s=new L(c(t,r,n,i),o);return e.isGeneratorFunction(r)?s:s.next().then((function(e){return e.done?e.value:s.next()}))},S(E),u(E,a,"Generator"),E[o]=function(){return this},E.toString=function(){return"[object Generator]"},e.keys=function(e){var t=[];for(var r in e)t.push(r);return t.reverse(),function r(){for(;t.length;){var n=t.pop();if(n in e)return r.value=n,r.done=!1,r}return r.done=!0,r}},e.values=Q,O.prototype={constructor:O,reset:function(e){if(this.prev=0,this.next=0,this.sent=this._sent=t,this.done=!1,this.delegate=null,this.method="next",this.arg=t,this.tryEntries.forEach(P),!e)for(var r in this)"t"===r.charAt(0)&&n.call(this,r)&&!isNaN(+r.slice(1))&&(this[r]=t)},stop:function(){this.done=!0;var e=this.tryEntries[0].completion;if("throw"===e.type)throw e.arg;return this.rval},dispatchException:function(e){if(this.done)throw e;var r=this;function i(n,i){return a.type="throw",a.arg=e,r.next=n,i&&(r.method="next",r.arg=t),!!i}for(var o=this.tryEntries.length-1;o>=0;--o){var s=this.tryEntries[o],a=s.completion;if("root"===s.tryLoc)return i("end");if(s.tryLoc<=this.prev){var u=n.call(s,"catchLoc"),c=n.call(s,"finallyLoc");if(u&&c){if(this.prev<s.catchLoc)return i(s.catchLoc,!0);if(this.prev<s.finallyLoc)return i(s.finallyLoc)}else if(u){if(this.prev<s.catchLoc)return i(s.catchLoc,!0)}else{if(!c)throw new Error("try statement without catch or finally");if(this.prev<s.finallyLoc)return i(s.finallyLoc)}}}},abrupt:function(e,t){for(var r=this.tryEntries.length-1;r>=0;--r){var i=this.tryEntries[r];if(i.tryLoc<=this.prev&&n.call(i,"finallyLoc")&&this.prev<i.finallyLoc){var o=i;break}}o&&("break"===e||"continue"===e)&&o.tryLoc<=t&&t<=o.finallyLoc&&(o=null);var s=o?o.completion:{};return s.type=e,s.arg=t,o?(this.method="next",this.next=o.finallyLoc,v):this.complete(s)},complete:function(e,t){if("throw"===e.type)throw e.arg;return"break"===e.type||"continue"===e.type?this.next=e.arg:"return"===e.type?(this.rval=this.arg=e.arg,this.method="return",this.next="end"):"normal"===e.type&&t&&(this.next=t),v},finish:function(e){for(var t=this.tryEntries.length-1;t>=0;--t){var r=this.tryEntries[t];if(r.finallyLoc===e)return this.complete(r.completion,r.afterLoc),P(r),v}},catch:function(e){for(var t=this.tryEntries.length-1;t>=0;--t){var r=this.tryEntries[t];if(r.tryLoc===e){var n=r.completion;if("throw"===n.type){var i=n.arg;P(r)}return i}}throw new Error("illegal catch attempt")},delegateYield:function(e,r,n){return this.delegate={iterator:Q(e),resultName:r,nextLoc:n},"next"===this.method&&(this.arg=t),v}},e}(e.exports);try{regeneratorRuntime=t}catch(e){Function("r","regeneratorRuntime = r")(t)}}},t={};function r(n){var i=t[n];if(void 0!==i)return i.exports;var o=t[n]={exports:{}};return e[n](o,o.exports,r),o.exports}r.n=function(e){var t=e&&e.__esModule?function(){return e.default}:function(){return e};return r.d(t,{a:t}),t},r.d=function(e,t){for(var n in t)r.o(t,n)&&!r.o(e,n)&&Object.defineProperty(e,n,{enumerable:!0,get:t[n]})},r.g=function(){if("object"==typeof globalThis)return globalThis;try{return this||new Function("return this")()}catch(e){if("object"==typeof window)return window}}(),r.o=function(e,t){return Object.prototype.hasOwnProperty.call(e,t)};var n={};!function(){"use strict";function e(e,t,r,n,i,o,s){try{var a=e[o](s),u=a.value}catch(e){return void r(e)}a.done?t(u):Promise.resolve(u).then(n,i)}function t(t){return function(){var r=this,n=arguments;return new Promise((function(i,o){var s=t.apply(r,n);function a(t){e(s,i,o,a,u,"next",t)}function u(t){e(s,i,o,a,u,"throw",t)}a(void 0)}))}}r.d(n,{add:function(){return p},dispose:function(){return E},done:function(){return d},fromExternalJS:function(){return m},load:function(){return w},search:function(){return L},toJS:function(){return y}});var i,o,s,a=r(7757),u=r.n(a),c=(r(1539),r(8674),r(3753),r(3210),r(4747),r(4916),r(3123),r(7042),r(1249),r(1336)),l=[];function f(){(i=new c.Builder).field("title"),i.field("description"),i.ref("ref"),i.pipeline.add(c.trimmer,c.stopWordFilter,c.stemmer),s=new Promise((function(e){o=e}))}c.tokenizer.separator=/\\s+/,f();var h=function(e){return"*"+c.stemmer(new c.Token(e,{}))+"*"};function p(e,t,r){var n=l.push(r)-1,o={title:e.toLowerCase(),description:t.toLowerCase(),ref:n};i.add(o)}function d(){return v.apply(this,arguments)}function v(){return(v=t(u().mark((function e(){return u().wrap((function(e){for(;;)switch(e.prev=e.next){case 0:o(i.build());case 1:case"end":return e.stop()}}),e)})))).apply(this,arguments)}function y(){return g.apply(this,arguments)}function g(){return(g=t(u().mark((function e(){return u().wrap((function(e){for(;;)switch(e.prev=e.next){case 0:return e.t0=l,e.next=3,s;case 3:return e.t1=e.sent.toJSON(),e.abrupt("return",{store:e.t0,index:e.t1});case 5:case"end":return e.stop()}}),e)})))).apply(this,arguments)}function m(e,t){return x.apply(this,arguments)}function x(){return(x=t(u().mark((function e(t,r){return u().wrap((function(e){for(;;)switch(e.prev=e.next){case 0:if(e.prev=0,importScripts(t),self[r]){e.next=4;break}throw new Error("Broken index file format");case 4:w(self[r]),e.next=10;break;case 7:e.prev=7,e.t0=e.catch(0),console.error("Failed to load search index: "+e.t0.message);case 10:case"end":return e.stop()}}),e,null,[[0,7]])})))).apply(this,arguments)}function w(e){return b.apply(this,arguments)}function b(){return(b=t(u().mark((function e(t){return u().wrap((function(e){for(;;)switch(e.prev=e.next){case 0:l=t.store,o(c.Index.load(t.index));case 2:case"end":return e.stop()}}),e)})))).apply(this,arguments)}function E(){return S.apply(this,arguments)}function S(){return(S=t(u().mark((function e(){return u().wrap((function(e){for(;;)switch(e.prev=e.next){case 0:l=[],f();case 2:case"end":return e.stop()}}),e)})))).apply(this,arguments)}function L(e){return k.apply(this,arguments)}function k(){return(k=t(u().mark((function e(t){var r,n,i=arguments;return u().wrap((function(e){for(;;)switch(e.prev=e.next){case 0:if(r=i.length>1&&void 0!==i[1]?i[1]:0,0!==t.trim().length){e.next=3;break}return e.abrupt("return",[]);case 3:return e.next=5,s;case 5:return n=e.sent.query((function(e){t.trim().toLowerCase().split(/\\s+/).forEach((function(t){if(1!==t.length){var r=h(t);e.term(r,{})}}))})),r>0&&(n=n.slice(0,r)),e.abrupt("return",n.map((function(e){return{meta:l[e.ref],score:e.score}})));case 8:case"end":return e.stop()}}),e)})))).apply(this,arguments)}addEventListener("message",(function(e){var t,r=e.data,i=r.type,o=r.method,s=r.id,a=r.params;"RPC"===i&&o&&((t=n[o])?Promise.resolve().then((function(){return t.apply(n,a)})):Promise.reject("No such method")).then((function(e){postMessage({type:"RPC",id:s,result:e})})).catch((function(e){var t={message:e};e.stack&&(t.message=e.message,t.stack=e.stack,t.name=e.name),postMessage({type:"RPC",id:s,error:t})}))})),postMessage({type:"RPC",method:"ready"})}()}();\n//# sourceMappingURL=574a25b96816f2c682b8.worker.js.map'])),{name:"[fullhash].worker.js"});return r(e,o),e}},6314:function(e){e.exports=function(e,t){var n=0,r={};e.addEventListener("message",(function(t){var n=t.data;if("RPC"===n.type)if(n.id){var o=r[n.id];o&&(delete r[n.id],n.error?o[1](Object.assign(Error(n.error.message),n.error)):o[0](n.result))}else{var i=document.createEvent("Event");i.initEvent(n.method,!1,!1),i.data=n.params,e.dispatchEvent(i)}})),t.forEach((function(t){e[t]=function(){var o=arguments;return new Promise((function(i,a){var s=++n;r[s]=[i,a],e.postMessage({type:"RPC",id:s,method:t,params:[].slice.call(o)})}))}}))}},7707:function(t){"use strict";t.exports=e},5101:function(){},3817:function(){},6177:function(){}},n={};function r(e){var o=n[e];if(void 0!==o)return o.exports;var i=n[e]={id:e,loaded:!1,exports:{}};return t[e].call(i.exports,i,i.exports,r),i.loaded=!0,i.exports}r.n=function(e){var t=e&&e.__esModule?function(){return e.default}:function(){return e};return r.d(t,{a:t}),t},r.d=function(e,t){for(var n in t)r.o(t,n)&&!r.o(e,n)&&Object.defineProperty(e,n,{enumerable:!0,get:t[n]})},r.g=function(){if("object"==typeof globalThis)return globalThis;try{return this||new Function("return this")()}catch(e){if("object"==typeof window)return window}}(),r.o=function(e,t){return Object.prototype.hasOwnProperty.call(e,t)},r.r=function(e){"undefined"!=typeof Symbol&&Symbol.toStringTag&&Object.defineProperty(e,Symbol.toStringTag,{value:"Module"}),Object.defineProperty(e,"__esModule",{value:!0})},r.nmd=function(e){return e.paths=[],e.children||(e.children=[]),e};var o={};return function(){"use strict";r(4609),r(9266)}(),function(){"use strict";r.r(o),r.d(o,{AppStore:function(){return mv},Redoc:function(){return Pb},hydrate:function(){return Db},init:function(){return Mb},revision:function(){return Lb},version:function(){return jb}});var e={};r.r(e),r.d(e,{ServerStyleSheet:function(){return ha},StyleSheetConsumer:function(){return Pi},StyleSheetContext:function(){return Ti},StyleSheetManager:function(){return Di},ThemeConsumer:function(){return oa},ThemeContext:function(){return ra},ThemeProvider:function(){return ia},__PRIVATE__:function(){return ga},createGlobalStyle:function(){return ua},css:function(){return Hi},default:function(){return ya},isStyledComponent:function(){return Yo},keyframes:function(){return pa},useTheme:function(){return va},version:function(){return Qo},withTheme:function(){return ma}});var t={};function n(e,t,n){return t in e?Object.defineProperty(e,t,{value:n,enumerable:!0,configurable:!0,writable:!0}):e[t]=n,e}function i(e,t){var n=Object.keys(e);if(Object.getOwnPropertySymbols){var r=Object.getOwnPropertySymbols(e);t&&(r=r.filter((function(t){return Object.getOwnPropertyDescriptor(e,t).enumerable}))),n.push.apply(n,r)}return n}function a(e){for(var t=1;t<arguments.length;t++){var r=null!=arguments[t]?arguments[t]:{};t%2?i(Object(r),!0).forEach((function(t){n(e,t,r[t])})):Object.getOwnPropertyDescriptors?Object.defineProperties(e,Object.getOwnPropertyDescriptors(r)):i(Object(r)).forEach((function(t){Object.defineProperty(e,t,Object.getOwnPropertyDescriptor(r,t))}))}return e}r.r(t),r.d(t,{default:function(){return bf}}),r(8309),r(4916),r(5306);var s=r(7294),l=r(3935);function c(e){for(var t=arguments.length,n=new Array(t>1?t-1:0),r=1;r<t;r++)n[r-1]=arguments[r];throw new Error("number"==typeof e?"[MobX] minified error nr: "+e+(n.length?" "+n.map(String).join(","):"")+". Find the full error at: https://github.com/mobxjs/mobx/blob/main/packages/mobx/src/errors.ts":"[MobX] "+e)}r(1539),r(9714),r(9600),r(1249),r(9601),r(5003),r(3371),r(489),r(6992),r(1532),r(8783),r(3948),r(189),r(2526),r(7941),r(2222),r(7327),r(7556),r(6210),r(9337),r(4747),r(7042),r(1038),r(1817),r(2165),r(3304),r(6649),r(6078),r(3753),r(561),r(8674),r(2707),r(9341),r(3706),r(408),r(3593),r(9596),r(2586),r(1825),r(9720);var u={};function p(){return"undefined"!=typeof globalThis?globalThis:"undefined"!=typeof window?window:void 0!==r.g?r.g:"undefined"!=typeof self?self:u}var f=Object.assign,d=Object.getOwnPropertyDescriptor,h=Object.defineProperty,m=Object.prototype,v=[];Object.freeze(v);var g={};Object.freeze(g);var y="undefined"!=typeof Proxy,b=Object.toString();function x(){y||c("Proxy not available")}function w(e){var t=!1;return function(){if(!t)return t=!0,e.apply(this,arguments)}}var k=function(){};function E(e){return"function"==typeof e}function S(e){switch(typeof e){case"string":case"symbol":case"number":return!0}return!1}function _(e){return null!==e&&"object"==typeof e}function O(e){var t;if(!_(e))return!1;var n=Object.getPrototypeOf(e);return null==n||(null==(t=n.constructor)?void 0:t.toString())===b}function A(e){var t=null==e?void 0:e.constructor;return!!t&&("GeneratorFunction"===t.name||"GeneratorFunction"===t.displayName)}function R(e,t,n){h(e,t,{enumerable:!1,writable:!0,configurable:!0,value:n})}function C(e,t,n){h(e,t,{enumerable:!1,writable:!1,configurable:!0,value:n})}function T(e,t){var n="isMobX"+e;return t.prototype[n]=!0,function(e){return _(e)&&!0===e[n]}}function P(e){return e instanceof Map}function I(e){return e instanceof Set}var j=void 0!==Object.getOwnPropertySymbols,L="undefined"!=typeof Reflect&&Reflect.ownKeys?Reflect.ownKeys:j?function(e){return Object.getOwnPropertyNames(e).concat(Object.getOwnPropertySymbols(e))}:Object.getOwnPropertyNames;function N(e){return null===e?null:"object"==typeof e?""+e:e}function M(e,t){return m.hasOwnProperty.call(e,t)}var D=Object.getOwnPropertyDescriptors||function(e){var t={};return L(e).forEach((function(n){t[n]=d(e,n)})),t};function F(e,t){for(var n=0;n<t.length;n++){var r=t[n];r.enumerable=r.enumerable||!1,r.configurable=!0,"value"in r&&(r.writable=!0),Object.defineProperty(e,r.key,r)}}function z(e,t,n){return t&&F(e.prototype,t),n&&F(e,n),e}function U(){return(U=Object.assign||function(e){for(var t=1;t<arguments.length;t++){var n=arguments[t];for(var r in n)Object.prototype.hasOwnProperty.call(n,r)&&(e[r]=n[r])}return e}).apply(this,arguments)}function B(e,t){e.prototype=Object.create(t.prototype),e.prototype.constructor=e,e.__proto__=t}function $(e){if(void 0===e)throw new ReferenceError("this hasn't been initialised - super() hasn't been called");return e}function q(e,t){(null==t||t>e.length)&&(t=e.length);for(var n=0,r=new Array(t);n<t;n++)r[n]=e[n];return r}function W(e,t){var n;if("undefined"==typeof Symbol||null==e[Symbol.iterator]){if(Array.isArray(e)||(n=function(e,t){if(e){if("string"==typeof e)return q(e,t);var n=Object.prototype.toString.call(e).slice(8,-1);return"Object"===n&&e.constructor&&(n=e.constructor.name),"Map"===n||"Set"===n?Array.from(e):"Arguments"===n||/^(?:Ui|I)nt(?:8|16|32)(?:Clamped)?Array$/.test(n)?q(e,t):void 0}}(e))||t&&e&&"number"==typeof e.length){n&&(e=n);var r=0;return function(){return r>=e.length?{done:!0}:{done:!1,value:e[r++]}}}throw new TypeError("Invalid attempt to iterate non-iterable instance.\nIn order to be iterable, non-array objects must have a [Symbol.iterator]() method.")}return(n=e[Symbol.iterator]()).next.bind(n)}var V=Symbol("mobx-stored-annotations");function H(e){return Object.assign((function(t,n){Y(t,n,e)}),e)}function Y(e,t,n){M(e,V)||R(e,V,U({},e[V])),function(e){return e.annotationType_===te}(n)||(e[V][t]=n)}var G=Symbol("mobx administration"),Q=function(){function e(e){void 0===e&&(e="Atom"),this.name_=void 0,this.isPendingUnobservation_=!1,this.isBeingObserved_=!1,this.observers_=new Set,this.diffValue_=0,this.lastAccessedBy_=0,this.lowestObserverState_=Ye.NOT_TRACKING_,this.onBOL=void 0,this.onBUOL=void 0,this.name_=e}var t=e.prototype;return t.onBO=function(){this.onBOL&&this.onBOL.forEach((function(e){return e()}))},t.onBUO=function(){this.onBUOL&&this.onBUOL.forEach((function(e){return e()}))},t.reportObserved=function(){return vt(this)},t.reportChanged=function(){ht(),gt(this),mt()},t.toString=function(){return this.name_},e}(),X=T("Atom",Q);function K(e,t,n){void 0===t&&(t=k),void 0===n&&(n=k);var r=new Q(e);return t!==k&&Mt(Lt,r,t,undefined),n!==k&&Nt(r,n),r}var Z={identity:function(e,t){return e===t},structural:function(e,t){return Kn(e,t)},default:function(e,t){return Object.is(e,t)},shallow:function(e,t){return Kn(e,t,1)}};function J(e,t,n){return Yt(e)?e:Array.isArray(e)?Pe.array(e,{name:n}):O(e)?Pe.object(e,void 0,{name:n}):P(e)?Pe.map(e,{name:n}):I(e)?Pe.set(e,{name:n}):"function"!=typeof e||It(e)||Ht(e)?e:A(e)?Wt(e):Pt(n,e)}function ee(e){return e}var te="override";function ne(e,t){return{annotationType_:e,options_:t,make_:re,extend_:oe}}function re(e,t,n,r){var o;if(null==(o=this.options_)?void 0:o.bound)return null===this.extend_(e,t,n,!1)?0:1;if(r===e.target_)return null===this.extend_(e,t,n,!1)?0:2;if(It(n.value))return 1;var i=ie(e,this,t,n,!1);return h(r,t,i),2}function oe(e,t,n,r){var o=ie(e,this,t,n);return e.defineProperty_(t,o,r)}function ie(e,t,n,r,o){var i,a,s,l,c,u;void 0===o&&(o=pt.safeDescriptors),u=r,t.annotationType_,u.value;var p,f=r.value;return(null==(i=t.options_)?void 0:i.bound)&&(f=f.bind(null!=(p=e.proxy_)?p:e.target_)),{value:Be(null!=(a=null==(s=t.options_)?void 0:s.name)?a:n.toString(),f,null!=(l=null==(c=t.options_)?void 0:c.autoAction)&&l),configurable:!o||e.isPlainObject_,enumerable:!1,writable:!o}}function ae(e,t){return{annotationType_:e,options_:t,make_:se,extend_:le}}function se(e,t,n,r){var o;if(r===e.target_)return null===this.extend_(e,t,n,!1)?0:2;if((null==(o=this.options_)?void 0:o.bound)&&!Ht(e.target_[t])&&null===this.extend_(e,t,n,!1))return 0;if(Ht(n.value))return 1;var i=ce(e,this,0,n,!1,!1);return h(r,t,i),2}function le(e,t,n,r){var o,i=ce(e,this,0,n,null==(o=this.options_)?void 0:o.bound);return e.defineProperty_(t,i,r)}function ce(e,t,n,r,o,i){var a;void 0===i&&(i=pt.safeDescriptors),a=r,t.annotationType_,a.value;var s,l=r.value;return o&&(l=l.bind(null!=(s=e.proxy_)?s:e.target_)),{value:Wt(l),configurable:!i||e.isPlainObject_,enumerable:!1,writable:!i}}function ue(e,t){return{annotationType_:e,options_:t,make_:pe,extend_:fe}}function pe(e,t,n){return null===this.extend_(e,t,n,!1)?0:1}function fe(e,t,n,r){return o=n,this.annotationType_,o.get,e.defineComputedProperty_(t,U({},this.options_,{get:n.get,set:n.set}),r);var o}function de(e,t){return{annotationType_:e,options_:t,make_:he,extend_:me}}function he(e,t,n){return null===this.extend_(e,t,n,!1)?0:1}function me(e,t,n,r){var o,i;return this.annotationType_,e.defineObservableProperty_(t,n.value,null!=(o=null==(i=this.options_)?void 0:i.enhancer)?o:J,r)}var ve=ge();function ge(e){return{annotationType_:"true",options_:e,make_:ye,extend_:be}}function ye(e,t,n,r){var o,i,a,s;if(n.get)return Ne.make_(e,t,n,r);if(n.set){var l=Be(t.toString(),n.set);return r===e.target_?null===e.defineProperty_(t,{configurable:!pt.safeDescriptors||e.isPlainObject_,set:l})?0:2:(h(r,t,{configurable:!0,set:l}),2)}if(r!==e.target_&&"function"==typeof n.value)return A(n.value)?((null==(s=this.options_)?void 0:s.autoBind)?Wt.bound:Wt).make_(e,t,n,r):((null==(a=this.options_)?void 0:a.autoBind)?Pt.bound:Pt).make_(e,t,n,r);var c,u=!1===(null==(o=this.options_)?void 0:o.deep)?Pe.ref:Pe;return"function"==typeof n.value&&(null==(i=this.options_)?void 0:i.autoBind)&&(n.value=n.value.bind(null!=(c=e.proxy_)?c:e.target_)),u.make_(e,t,n,r)}function be(e,t,n,r){var o,i,a;return n.get?Ne.extend_(e,t,n,r):n.set?e.defineProperty_(t,{configurable:!pt.safeDescriptors||e.isPlainObject_,set:Be(t.toString(),n.set)},r):("function"==typeof n.value&&(null==(o=this.options_)?void 0:o.autoBind)&&(n.value=n.value.bind(null!=(a=e.proxy_)?a:e.target_)),(!1===(null==(i=this.options_)?void 0:i.deep)?Pe.ref:Pe).extend_(e,t,n,r))}var xe={deep:!0,name:void 0,defaultDecorator:void 0,proxy:!0};function we(e){return e||xe}Object.freeze(xe);var ke=de("observable"),Ee=de("observable.ref",{enhancer:ee}),Se=de("observable.shallow",{enhancer:function(e,t,n){return null==e||Dn(e)||xn(e)||An(e)||Tn(e)?e:Array.isArray(e)?Pe.array(e,{name:n,deep:!1}):O(e)?Pe.object(e,void 0,{name:n,deep:!1}):P(e)?Pe.map(e,{name:n,deep:!1}):I(e)?Pe.set(e,{name:n,deep:!1}):void 0}}),_e=de("observable.struct",{enhancer:function(e,t){return Kn(e,t)?t:e}}),Oe=H(ke);function Ae(e){return!0===e.deep?J:!1===e.deep?ee:(t=e.defaultDecorator)&&null!=(n=null==(r=t.options_)?void 0:r.enhancer)?n:J;var t,n,r}function Re(e,t,n){if(!S(t))return Yt(e)?e:O(e)?Pe.object(e,t,n):Array.isArray(e)?Pe.array(e,t):P(e)?Pe.map(e,t):I(e)?Pe.set(e,t):"object"==typeof e&&null!==e?e:Pe.box(e,t);Y(e,t,ke)}Object.assign(Re,Oe);var Ce,Te,Pe=f(Re,{box:function(e,t){var n=we(t);return new He(e,Ae(n),n.name,!0,n.equals)},array:function(e,t){var n=we(t);return(!1===pt.useProxies||!1===n.proxy?Hn:pn)(e,Ae(n),n.name)},map:function(e,t){var n=we(t);return new On(e,Ae(n),n.name)},set:function(e,t){var n=we(t);return new Cn(e,Ae(n),n.name)},object:function(e,t,n){return function(e,t,n,r){var o=D(t),i=Ln(e,r)[G];ht();try{L(o).forEach((function(e){i.extend_(e,o[e],!n||!(e in n)||n[e])}))}finally{mt()}return e}(!1===pt.useProxies||!1===(null==n?void 0:n.proxy)?Ln({},n):function(e,t){var n,r;return x(),null!=(r=(n=(e=Ln(e,t))[G]).proxy_)?r:n.proxy_=new Proxy(e,Zt)}({},n),e,t)},ref:H(Ee),shallow:H(Se),deep:Oe,struct:H(_e)}),Ie="computed",je=ue(Ie),Le=ue("computed.struct",{equals:Z.structural}),Ne=function(e,t){if(S(t))return Y(e,t,je);if(O(e))return H(ue(Ie,e));var n=O(t)?t:{};return n.get=e,n.name||(n.name=e.name||""),new Qe(n)};Object.assign(Ne,je),Ne.struct=H(Le);var Me,De=0,Fe=1,ze=null!=(Ce=null==(Te=d((function(){}),"name"))?void 0:Te.configurable)&&Ce,Ue={value:"action",configurable:!0,writable:!1,enumerable:!1};function Be(e,t,n,r){function o(){return $e(0,n,t,r||this,arguments)}return void 0===n&&(n=!1),o.isMobxAction=!0,ze&&(Ue.value=e,Object.defineProperty(o,"name",Ue)),o}function $e(e,t,n,r,o){var i=function(e,t,n,r){var o=pt.trackingDerivation,i=!t||!o;ht();var a=pt.allowStateChanges;i&&(rt(),a=qe(!0));var s={runAsAction_:i,prevDerivation_:o,prevAllowStateChanges_:a,prevAllowStateReads_:it(!0),notifySpy_:!1,startTime_:0,actionId_:Fe++,parentActionId_:De};return De=s.actionId_,s}(0,t);try{return n.apply(r,o)}catch(e){throw i.error_=e,e}finally{!function(e){De!==e.actionId_&&c(30),De=e.parentActionId_,void 0!==e.error_&&(pt.suppressReactionErrors=!0),We(e.prevAllowStateChanges_),at(e.prevAllowStateReads_),mt(),e.runAsAction_&&ot(e.prevDerivation_),pt.suppressReactionErrors=!1}(i)}}function qe(e){var t=pt.allowStateChanges;return pt.allowStateChanges=e,t}function We(e){pt.allowStateChanges=e}Me=Symbol.toPrimitive;var Ve,He=function(e){function t(t,n,r,o,i){var a;return void 0===r&&(r="ObservableValue"),void 0===o&&(o=!0),void 0===i&&(i=Z.default),(a=e.call(this,r)||this).enhancer=void 0,a.name_=void 0,a.equals=void 0,a.hasUnreportedChange_=!1,a.interceptors_=void 0,a.changeListeners_=void 0,a.value_=void 0,a.dehancer=void 0,a.enhancer=n,a.name_=r,a.equals=i,a.value_=n(t,void 0,r),a}B(t,e);var n=t.prototype;return n.dehanceValue=function(e){return void 0!==this.dehancer?this.dehancer(e):e},n.set=function(e){this.value_,(e=this.prepareNewValue_(e))!==pt.UNCHANGED&&this.setNewValue_(e)},n.prepareNewValue_=function(e){if(Jt(this)){var t=tn(this,{object:this,type:ln,newValue:e});if(!t)return pt.UNCHANGED;e=t.newValue}return e=this.enhancer(e,this.value_,this.name_),this.equals(this.value_,e)?pt.UNCHANGED:e},n.setNewValue_=function(e){var t=this.value_;this.value_=e,this.reportChanged(),nn(this)&&on(this,{type:ln,object:this,newValue:e,oldValue:t})},n.get=function(){return this.reportObserved(),this.dehanceValue(this.value_)},n.intercept_=function(e){return en(this,e)},n.observe_=function(e,t){return t&&e({observableKind:"value",debugObjectName:this.name_,object:this,type:ln,newValue:this.value_,oldValue:void 0}),rn(this,e)},n.raw=function(){return this.value_},n.toJSON=function(){return this.get()},n.toString=function(){return this.name_+"["+this.value_+"]"},n.valueOf=function(){return N(this.get())},n[Me]=function(){return this.valueOf()},t}(Q);Ve=Symbol.toPrimitive;var Ye,Ge,Qe=function(){function e(e){this.dependenciesState_=Ye.NOT_TRACKING_,this.observing_=[],this.newObserving_=null,this.isBeingObserved_=!1,this.isPendingUnobservation_=!1,this.observers_=new Set,this.diffValue_=0,this.runId_=0,this.lastAccessedBy_=0,this.lowestObserverState_=Ye.UP_TO_DATE_,this.unboundDepsCount_=0,this.value_=new Ke(null),this.name_=void 0,this.triggeredBy_=void 0,this.isComputing_=!1,this.isRunningSetter_=!1,this.derivation=void 0,this.setter_=void 0,this.isTracing_=Ge.NONE,this.scope_=void 0,this.equals_=void 0,this.requiresReaction_=void 0,this.keepAlive_=void 0,this.onBOL=void 0,this.onBUOL=void 0,e.get||c(31),this.derivation=e.get,this.name_=e.name||"ComputedValue",e.set&&(this.setter_=Be("ComputedValue-setter",e.set)),this.equals_=e.equals||(e.compareStructural||e.struct?Z.structural:Z.default),this.scope_=e.context,this.requiresReaction_=!!e.requiresReaction,this.keepAlive_=!!e.keepAlive}var t=e.prototype;return t.onBecomeStale_=function(){var e;(e=this).lowestObserverState_===Ye.UP_TO_DATE_&&(e.lowestObserverState_=Ye.POSSIBLY_STALE_,e.observers_.forEach((function(e){e.dependenciesState_===Ye.UP_TO_DATE_&&(e.dependenciesState_=Ye.POSSIBLY_STALE_,e.onBecomeStale_())})))},t.onBO=function(){this.onBOL&&this.onBOL.forEach((function(e){return e()}))},t.onBUO=function(){this.onBUOL&&this.onBUOL.forEach((function(e){return e()}))},t.get=function(){if(this.isComputing_&&c(32,this.name_,this.derivation),0!==pt.inBatch||0!==this.observers_.size||this.keepAlive_){if(vt(this),Je(this)){var e=pt.trackingContext;this.keepAlive_&&!e&&(pt.trackingContext=this),this.trackAndCompute()&&((t=this).lowestObserverState_!==Ye.STALE_&&(t.lowestObserverState_=Ye.STALE_,t.observers_.forEach((function(e){e.dependenciesState_===Ye.POSSIBLY_STALE_?e.dependenciesState_=Ye.STALE_:e.dependenciesState_===Ye.UP_TO_DATE_&&(t.lowestObserverState_=Ye.UP_TO_DATE_)})))),pt.trackingContext=e}}else Je(this)&&(this.warnAboutUntrackedRead_(),ht(),this.value_=this.computeValue_(!1),mt());var t,n=this.value_;if(Ze(n))throw n.cause;return n},t.set=function(e){if(this.setter_){this.isRunningSetter_&&c(33,this.name_),this.isRunningSetter_=!0;try{this.setter_.call(this.scope_,e)}finally{this.isRunningSetter_=!1}}else c(34,this.name_)},t.trackAndCompute=function(){var e=this.value_,t=this.dependenciesState_===Ye.NOT_TRACKING_,n=this.computeValue_(!0),r=t||Ze(e)||Ze(n)||!this.equals_(e,n);return r&&(this.value_=n),r},t.computeValue_=function(e){this.isComputing_=!0;var t,n=qe(!1);if(e)t=et(this,this.derivation,this.scope_);else if(!0===pt.disableErrorBoundaries)t=this.derivation.call(this.scope_);else try{t=this.derivation.call(this.scope_)}catch(e){t=new Ke(e)}return We(n),this.isComputing_=!1,t},t.suspend_=function(){this.keepAlive_||(tt(this),this.value_=void 0)},t.observe_=function(e,t){var n=this,r=!0,o=void 0;return function(e,t){var n,r;void 0===t&&(t=g);var o,i=null!=(n=null==(r=t)?void 0:r.name)?n:"Autorun";if(t.scheduler||t.delay){var a=function(e){return e.scheduler?e.scheduler:e.delay?function(t){return setTimeout(t,e.delay)}:jt}(t),s=!1;o=new yt(i,(function(){s||(s=!0,a((function(){s=!1,o.isDisposed_||o.track(l)})))}),t.onError,t.requiresObservable)}else o=new yt(i,(function(){this.track(l)}),t.onError,t.requiresObservable);function l(){e(o)}return o.schedule_(),o.getDisposer_()}((function(){var i=n.get();if(!r||t){var a=rt();e({observableKind:"computed",debugObjectName:n.name_,type:ln,object:n,newValue:i,oldValue:o}),ot(a)}r=!1,o=i}))},t.warnAboutUntrackedRead_=function(){},t.toString=function(){return this.name_+"["+this.derivation.toString()+"]"},t.valueOf=function(){return N(this.get())},t[Ve]=function(){return this.valueOf()},e}(),Xe=T("ComputedValue",Qe);!function(e){e[e.NOT_TRACKING_=-1]="NOT_TRACKING_",e[e.UP_TO_DATE_=0]="UP_TO_DATE_",e[e.POSSIBLY_STALE_=1]="POSSIBLY_STALE_",e[e.STALE_=2]="STALE_"}(Ye||(Ye={})),function(e){e[e.NONE=0]="NONE",e[e.LOG=1]="LOG",e[e.BREAK=2]="BREAK"}(Ge||(Ge={}));var Ke=function(e){this.cause=void 0,this.cause=e};function Ze(e){return e instanceof Ke}function Je(e){switch(e.dependenciesState_){case Ye.UP_TO_DATE_:return!1;case Ye.NOT_TRACKING_:case Ye.STALE_:return!0;case Ye.POSSIBLY_STALE_:for(var t=it(!0),n=rt(),r=e.observing_,o=r.length,i=0;i<o;i++){var a=r[i];if(Xe(a)){if(pt.disableErrorBoundaries)a.get();else try{a.get()}catch(e){return ot(n),at(t),!0}if(e.dependenciesState_===Ye.STALE_)return ot(n),at(t),!0}}return st(e),ot(n),at(t),!1}}function et(e,t,n){var r=it(!0);st(e),e.newObserving_=new Array(e.observing_.length+100),e.unboundDepsCount_=0,e.runId_=++pt.runId;var o,i=pt.trackingDerivation;if(pt.trackingDerivation=e,pt.inBatch++,!0===pt.disableErrorBoundaries)o=t.call(n);else try{o=t.call(n)}catch(e){o=new Ke(e)}return pt.inBatch--,pt.trackingDerivation=i,function(e){for(var t=e.observing_,n=e.observing_=e.newObserving_,r=Ye.UP_TO_DATE_,o=0,i=e.unboundDepsCount_,a=0;a<i;a++){var s=n[a];0===s.diffValue_&&(s.diffValue_=1,o!==a&&(n[o]=s),o++),s.dependenciesState_>r&&(r=s.dependenciesState_)}for(n.length=o,e.newObserving_=null,i=t.length;i--;){var l=t[i];0===l.diffValue_&&ft(l,e),l.diffValue_=0}for(;o--;){var c=n[o];1===c.diffValue_&&(c.diffValue_=0,p=e,(u=c).observers_.add(p),u.lowestObserverState_>p.dependenciesState_&&(u.lowestObserverState_=p.dependenciesState_))}var u,p;r!==Ye.UP_TO_DATE_&&(e.dependenciesState_=r,e.onBecomeStale_())}(e),at(r),o}function tt(e){var t=e.observing_;e.observing_=[];for(var n=t.length;n--;)ft(t[n],e);e.dependenciesState_=Ye.NOT_TRACKING_}function nt(e){var t=rt();try{return e()}finally{ot(t)}}function rt(){var e=pt.trackingDerivation;return pt.trackingDerivation=null,e}function ot(e){pt.trackingDerivation=e}function it(e){var t=pt.allowStateReads;return pt.allowStateReads=e,t}function at(e){pt.allowStateReads=e}function st(e){if(e.dependenciesState_!==Ye.UP_TO_DATE_){e.dependenciesState_=Ye.UP_TO_DATE_;for(var t=e.observing_,n=t.length;n--;)t[n].lowestObserverState_=Ye.UP_TO_DATE_}}var lt=function(){this.version=6,this.UNCHANGED={},this.trackingDerivation=null,this.trackingContext=null,this.runId=0,this.mobxGuid=0,this.inBatch=0,this.pendingUnobservations=[],this.pendingReactions=[],this.isRunningReactions=!1,this.allowStateChanges=!1,this.allowStateReads=!0,this.enforceActions=!0,this.spyListeners=[],this.globalReactionErrorHandlers=[],this.computedRequiresReaction=!1,this.reactionRequiresObservable=!1,this.observableRequiresReaction=!1,this.disableErrorBoundaries=!1,this.suppressReactionErrors=!1,this.useProxies=!0,this.verifyProxies=!1,this.safeDescriptors=!0},ct=!0,ut=!1,pt=function(){var e=p();return e.__mobxInstanceCount>0&&!e.__mobxGlobals&&(ct=!1),e.__mobxGlobals&&e.__mobxGlobals.version!==(new lt).version&&(ct=!1),ct?e.__mobxGlobals?(e.__mobxInstanceCount+=1,e.__mobxGlobals.UNCHANGED||(e.__mobxGlobals.UNCHANGED={}),e.__mobxGlobals):(e.__mobxInstanceCount=1,e.__mobxGlobals=new lt):(setTimeout((function(){ut||c(35)}),1),new lt)}();function ft(e,t){e.observers_.delete(t),0===e.observers_.size&&dt(e)}function dt(e){!1===e.isPendingUnobservation_&&(e.isPendingUnobservation_=!0,pt.pendingUnobservations.push(e))}function ht(){pt.inBatch++}function mt(){if(0==--pt.inBatch){xt();for(var e=pt.pendingUnobservations,t=0;t<e.length;t++){var n=e[t];n.isPendingUnobservation_=!1,0===n.observers_.size&&(n.isBeingObserved_&&(n.isBeingObserved_=!1,n.onBUO()),n instanceof Qe&&n.suspend_())}pt.pendingUnobservations=[]}}function vt(e){var t=pt.trackingDerivation;return null!==t?(t.runId_!==e.lastAccessedBy_&&(e.lastAccessedBy_=t.runId_,t.newObserving_[t.unboundDepsCount_++]=e,!e.isBeingObserved_&&pt.trackingContext&&(e.isBeingObserved_=!0,e.onBO())),!0):(0===e.observers_.size&&pt.inBatch>0&&dt(e),!1)}function gt(e){e.lowestObserverState_!==Ye.STALE_&&(e.lowestObserverState_=Ye.STALE_,e.observers_.forEach((function(e){e.dependenciesState_===Ye.UP_TO_DATE_&&e.onBecomeStale_(),e.dependenciesState_=Ye.STALE_})))}var yt=function(){function e(e,t,n,r){void 0===e&&(e="Reaction"),void 0===r&&(r=!1),this.name_=void 0,this.onInvalidate_=void 0,this.errorHandler_=void 0,this.requiresObservable_=void 0,this.observing_=[],this.newObserving_=[],this.dependenciesState_=Ye.NOT_TRACKING_,this.diffValue_=0,this.runId_=0,this.unboundDepsCount_=0,this.isDisposed_=!1,this.isScheduled_=!1,this.isTrackPending_=!1,this.isRunning_=!1,this.isTracing_=Ge.NONE,this.name_=e,this.onInvalidate_=t,this.errorHandler_=n,this.requiresObservable_=r}var t=e.prototype;return t.onBecomeStale_=function(){this.schedule_()},t.schedule_=function(){this.isScheduled_||(this.isScheduled_=!0,pt.pendingReactions.push(this),xt())},t.isScheduled=function(){return this.isScheduled_},t.runReaction_=function(){if(!this.isDisposed_){ht(),this.isScheduled_=!1;var e=pt.trackingContext;if(pt.trackingContext=this,Je(this)){this.isTrackPending_=!0;try{this.onInvalidate_()}catch(e){this.reportExceptionInDerivation_(e)}}pt.trackingContext=e,mt()}},t.track=function(e){if(!this.isDisposed_){ht(),this.isRunning_=!0;var t=pt.trackingContext;pt.trackingContext=this;var n=et(this,e,void 0);pt.trackingContext=t,this.isRunning_=!1,this.isTrackPending_=!1,this.isDisposed_&&tt(this),Ze(n)&&this.reportExceptionInDerivation_(n.cause),mt()}},t.reportExceptionInDerivation_=function(e){var t=this;if(this.errorHandler_)this.errorHandler_(e,this);else{if(pt.disableErrorBoundaries)throw e;var n="[mobx] uncaught error in '"+this+"'";pt.suppressReactionErrors||console.error(n,e),pt.globalReactionErrorHandlers.forEach((function(n){return n(e,t)}))}},t.dispose=function(){this.isDisposed_||(this.isDisposed_=!0,this.isRunning_||(ht(),tt(this),mt()))},t.getDisposer_=function(){var e=this.dispose.bind(this);return e[G]=this,e},t.toString=function(){return"Reaction["+this.name_+"]"},t.trace=function(e){void 0===e&&(e=!1),function(){c("trace() is not available in production builds");for(var e=!1,t=arguments.length,n=new Array(t),r=0;r<t;r++)n[r]=arguments[r];"boolean"==typeof n[n.length-1]&&(e=n.pop());var o=Qt(n);if(!o)return c("'trace(break?)' can only be used inside a tracked computed value or a Reaction. Consider passing in the computed value or reaction explicitly");o.isTracing_===Ge.NONE&&console.log("[mobx.trace] '"+o.name_+"' tracing enabled"),o.isTracing_=e?Ge.BREAK:Ge.LOG}(this,e)},e}(),bt=function(e){return e()};function xt(){pt.inBatch>0||pt.isRunningReactions||bt(wt)}function wt(){pt.isRunningReactions=!0;for(var e=pt.pendingReactions,t=0;e.length>0;){100==++t&&(console.error("[mobx] cycle in reaction: "+e[0]),e.splice(0));for(var n=e.splice(0),r=0,o=n.length;r<o;r++)n[r].runReaction_()}pt.isRunningReactions=!1}var kt=T("Reaction",yt),Et="action",St="autoAction",_t=ne(Et),Ot=ne("action.bound",{bound:!0}),At=ne(St,{autoAction:!0}),Rt=ne("autoAction.bound",{autoAction:!0,bound:!0});function Ct(e){return function(t,n){return E(t)?Be(t.name||"<unnamed action>",t,e):E(n)?Be(t,n,e):S(n)?Y(t,n,e?At:_t):S(t)?H(ne(e?St:Et,{name:t,autoAction:e})):void 0}}var Tt=Ct(!1);Object.assign(Tt,_t);var Pt=Ct(!0);function It(e){return E(e)&&!0===e.isMobxAction}Object.assign(Pt,At),Tt.bound=H(Ot),Pt.bound=H(Rt);var jt=function(e){return e()};var Lt="onBO";function Nt(e,t,n){return Mt("onBUO",e,t,n)}function Mt(e,t,n,r){var o="function"==typeof r?Yn(t,n):Yn(t),i=E(r)?r:n,a=e+"L";return o[a]?o[a].add(i):o[a]=new Set([i]),function(){var e=o[a];e&&(e.delete(i),0===e.size&&delete o[a])}}var Dt="always";function Ft(e){!0===e.isolateGlobalState&&function(){if((pt.pendingReactions.length||pt.inBatch||pt.isRunningReactions)&&c(36),ut=!0,ct){var e=p();0==--e.__mobxInstanceCount&&(e.__mobxGlobals=void 0),pt=new lt}}();var t,n,r=e.useProxies,o=e.enforceActions;if(void 0!==r&&(pt.useProxies=r===Dt||"never"!==r&&"undefined"!=typeof Proxy),"ifavailable"===r&&(pt.verifyProxies=!0),void 0!==o){var i=o===Dt?Dt:"observed"===o;pt.enforceActions=i,pt.allowStateChanges=!0!==i&&i!==Dt}["computedRequiresReaction","reactionRequiresObservable","observableRequiresReaction","disableErrorBoundaries","safeDescriptors"].forEach((function(t){t in e&&(pt[t]=!!e[t])})),pt.allowStateReads=!pt.observableRequiresReaction,e.reactionScheduler&&(t=e.reactionScheduler,n=bt,bt=function(e){return t((function(){return n(e)}))})}function zt(e){var t,n={name:e.name_};return e.observing_&&e.observing_.length>0&&(n.dependencies=(t=e.observing_,Array.from(new Set(t))).map(zt)),n}var Ut=0;function Bt(){this.message="FLOW_CANCELLED"}Bt.prototype=Object.create(Error.prototype);var $t=ae("flow"),qt=ae("flow.bound",{bound:!0}),Wt=Object.assign((function(e,t){if(S(t))return Y(e,t,$t);var n=e,r=n.name||"<unnamed flow>",o=function(){var e,t=this,o=arguments,i=++Ut,a=Tt(r+" - runid: "+i+" - init",n).apply(t,o),s=void 0,l=new Promise((function(t,n){var o=0;function l(e){var t;s=void 0;try{t=Tt(r+" - runid: "+i+" - yield "+o++,a.next).call(a,e)}catch(e){return n(e)}u(t)}function c(e){var t;s=void 0;try{t=Tt(r+" - runid: "+i+" - yield "+o++,a.throw).call(a,e)}catch(e){return n(e)}u(t)}function u(e){if(!E(null==e?void 0:e.then))return e.done?t(e.value):(s=Promise.resolve(e.value)).then(l,c);e.then(u,n)}e=n,l(void 0)}));return l.cancel=Tt(r+" - runid: "+i+" - cancel",(function(){try{s&&Vt(s);var t=a.return(void 0),n=Promise.resolve(t.value);n.then(k,k),Vt(n),e(new Bt)}catch(t){e(t)}})),l};return o.isMobXFlow=!0,o}),$t);function Vt(e){E(e.cancel)&&e.cancel()}function Ht(e){return!0===(null==e?void 0:e.isMobXFlow)}function Yt(e){return function(e,t){return!!e&&(void 0!==t?!!Dn(e)&&e[G].values_.has(t):Dn(e)||!!e[G]||X(e)||kt(e)||Xe(e))}(e)}function Gt(e,t,n,r){return E(n)?function(e,t,n,r){return Gn(e,t).observe_(n,r)}(e,t,n,r):function(e,t,n){return Gn(e).observe_(t,n)}(e,t,n)}function Qt(e){switch(e.length){case 0:return pt.trackingDerivation;case 1:return Yn(e[0]);case 2:return Yn(e[0],e[1])}}function Xt(e,t){void 0===t&&(t=void 0),ht();try{return e.apply(t)}finally{mt()}}function Kt(e){return e[G]}Wt.bound=H(qt);var Zt={has:function(e,t){return Kt(e).has_(t)},get:function(e,t){return Kt(e).get_(t)},set:function(e,t,n){var r;return!!S(t)&&(null==(r=Kt(e).set_(t,n,!0))||r)},deleteProperty:function(e,t){var n;return!!S(t)&&(null==(n=Kt(e).delete_(t,!0))||n)},defineProperty:function(e,t,n){var r;return null==(r=Kt(e).defineProperty_(t,n))||r},ownKeys:function(e){return Kt(e).ownKeys_()},preventExtensions:function(e){c(13)}};function Jt(e){return void 0!==e.interceptors_&&e.interceptors_.length>0}function en(e,t){var n=e.interceptors_||(e.interceptors_=[]);return n.push(t),w((function(){var e=n.indexOf(t);-1!==e&&n.splice(e,1)}))}function tn(e,t){var n=rt();try{for(var r=[].concat(e.interceptors_||[]),o=0,i=r.length;o<i&&((t=r[o](t))&&!t.type&&c(14),t);o++);return t}finally{ot(n)}}function nn(e){return void 0!==e.changeListeners_&&e.changeListeners_.length>0}function rn(e,t){var n=e.changeListeners_||(e.changeListeners_=[]);return n.push(t),w((function(){var e=n.indexOf(t);-1!==e&&n.splice(e,1)}))}function on(e,t){var n=rt(),r=e.changeListeners_;if(r){for(var o=0,i=(r=r.slice()).length;o<i;o++)r[o](t);ot(n)}}function an(e,t,n){var r=Ln(e,n)[G];ht();try{null!=t||(t=function(e){return M(e,V)||R(e,V,U({},e[V])),e[V]}(e)),L(t).forEach((function(e){return r.make_(e,t[e])}))}finally{mt()}return e}var sn="splice",ln="update",cn={get:function(e,t){var n=e[G];return t===G?n:"length"===t?n.getArrayLength_():"string"!=typeof t||isNaN(t)?M(fn,t)?fn[t]:e[t]:n.get_(parseInt(t))},set:function(e,t,n){var r=e[G];return"length"===t&&r.setArrayLength_(n),"symbol"==typeof t||isNaN(t)?e[t]=n:r.set_(parseInt(t),n),!0},preventExtensions:function(){c(15)}},un=function(){function e(e,t,n,r){void 0===e&&(e="ObservableArray"),this.owned_=void 0,this.legacyMode_=void 0,this.atom_=void 0,this.values_=[],this.interceptors_=void 0,this.changeListeners_=void 0,this.enhancer_=void 0,this.dehancer=void 0,this.proxy_=void 0,this.lastKnownLength_=0,this.owned_=n,this.legacyMode_=r,this.atom_=new Q(e),this.enhancer_=function(e,n){return t(e,n,"ObservableArray[..]")}}var t=e.prototype;return t.dehanceValue_=function(e){return void 0!==this.dehancer?this.dehancer(e):e},t.dehanceValues_=function(e){return void 0!==this.dehancer&&e.length>0?e.map(this.dehancer):e},t.intercept_=function(e){return en(this,e)},t.observe_=function(e,t){return void 0===t&&(t=!1),t&&e({observableKind:"array",object:this.proxy_,debugObjectName:this.atom_.name_,type:"splice",index:0,added:this.values_.slice(),addedCount:this.values_.length,removed:[],removedCount:0}),rn(this,e)},t.getArrayLength_=function(){return this.atom_.reportObserved(),this.values_.length},t.setArrayLength_=function(e){("number"!=typeof e||e<0)&&c("Out of range: "+e);var t=this.values_.length;if(e!==t)if(e>t){for(var n=new Array(e-t),r=0;r<e-t;r++)n[r]=void 0;this.spliceWithArray_(t,0,n)}else this.spliceWithArray_(e,t-e)},t.updateArrayLength_=function(e,t){e!==this.lastKnownLength_&&c(16),this.lastKnownLength_+=t,this.legacyMode_&&t>0&&Vn(e+t+1)},t.spliceWithArray_=function(e,t,n){var r=this;this.atom_;var o=this.values_.length;if(void 0===e?e=0:e>o?e=o:e<0&&(e=Math.max(0,o+e)),t=1===arguments.length?o-e:null==t?0:Math.max(0,Math.min(t,o-e)),void 0===n&&(n=v),Jt(this)){var i=tn(this,{object:this.proxy_,type:sn,index:e,removedCount:t,added:n});if(!i)return v;t=i.removedCount,n=i.added}if(n=0===n.length?n:n.map((function(e){return r.enhancer_(e,void 0)})),this.legacyMode_){var a=n.length-t;this.updateArrayLength_(o,a)}var s=this.spliceItemsIntoValues_(e,t,n);return 0===t&&0===n.length||this.notifyArraySplice_(e,n,s),this.dehanceValues_(s)},t.spliceItemsIntoValues_=function(e,t,n){var r;if(n.length<1e4)return(r=this.values_).splice.apply(r,[e,t].concat(n));var o=this.values_.slice(e,e+t),i=this.values_.slice(e+t);this.values_.length=e+n.length-t;for(var a=0;a<n.length;a++)this.values_[e+a]=n[a];for(var s=0;s<i.length;s++)this.values_[e+n.length+s]=i[s];return o},t.notifyArrayChildUpdate_=function(e,t,n){var r=!this.owned_&&!1,o=nn(this),i=o||r?{observableKind:"array",object:this.proxy_,type:ln,debugObjectName:this.atom_.name_,index:e,newValue:t,oldValue:n}:null;this.atom_.reportChanged(),o&&on(this,i)},t.notifyArraySplice_=function(e,t,n){var r=!this.owned_&&!1,o=nn(this),i=o||r?{observableKind:"array",object:this.proxy_,debugObjectName:this.atom_.name_,type:sn,index:e,removed:n,added:t,removedCount:n.length,addedCount:t.length}:null;this.atom_.reportChanged(),o&&on(this,i)},t.get_=function(e){if(e<this.values_.length)return this.atom_.reportObserved(),this.dehanceValue_(this.values_[e]);console.warn("[mobx.array] Attempt to read an array index ("+e+") that is out of bounds ("+this.values_.length+"). Please check length first. Out of bound indices will not be tracked by MobX")},t.set_=function(e,t){var n=this.values_;if(e<n.length){this.atom_;var r=n[e];if(Jt(this)){var o=tn(this,{type:ln,object:this.proxy_,index:e,newValue:t});if(!o)return;t=o.newValue}(t=this.enhancer_(t,r))!==r&&(n[e]=t,this.notifyArrayChildUpdate_(e,t,r))}else e===n.length?this.spliceWithArray_(e,0,[t]):c(17,e,n.length)},e}();function pn(e,t,n,r){void 0===n&&(n="ObservableArray"),void 0===r&&(r=!1),x();var o=new un(n,t,r,!1);C(o.values_,G,o);var i=new Proxy(o.values_,cn);if(o.proxy_=i,e&&e.length){var a=qe(!0);o.spliceWithArray_(0,0,e),We(a)}return i}var fn={clear:function(){return this.splice(0)},replace:function(e){var t=this[G];return t.spliceWithArray_(0,t.values_.length,e)},toJSON:function(){return this.slice()},splice:function(e,t){for(var n=arguments.length,r=new Array(n>2?n-2:0),o=2;o<n;o++)r[o-2]=arguments[o];var i=this[G];switch(arguments.length){case 0:return[];case 1:return i.spliceWithArray_(e);case 2:return i.spliceWithArray_(e,t)}return i.spliceWithArray_(e,t,r)},spliceWithArray:function(e,t,n){return this[G].spliceWithArray_(e,t,n)},push:function(){for(var e=this[G],t=arguments.length,n=new Array(t),r=0;r<t;r++)n[r]=arguments[r];return e.spliceWithArray_(e.values_.length,0,n),e.values_.length},pop:function(){return this.splice(Math.max(this[G].values_.length-1,0),1)[0]},shift:function(){return this.splice(0,1)[0]},unshift:function(){for(var e=this[G],t=arguments.length,n=new Array(t),r=0;r<t;r++)n[r]=arguments[r];return e.spliceWithArray_(0,0,n),e.values_.length},reverse:function(){return pt.trackingDerivation&&c(37,"reverse"),this.replace(this.slice().reverse()),this},sort:function(){pt.trackingDerivation&&c(37,"sort");var e=this.slice();return e.sort.apply(e,arguments),this.replace(e),this},remove:function(e){var t=this[G],n=t.dehanceValues_(t.values_).indexOf(e);return n>-1&&(this.splice(n,1),!0)}};function dn(e,t){"function"==typeof Array.prototype[e]&&(fn[e]=t(e))}function hn(e){return function(){var t=this[G];t.atom_.reportObserved();var n=t.dehanceValues_(t.values_);return n[e].apply(n,arguments)}}function mn(e){return function(t,n){var r=this,o=this[G];return o.atom_.reportObserved(),o.dehanceValues_(o.values_)[e]((function(e,o){return t.call(n,e,o,r)}))}}function vn(e){return function(){var t=this,n=this[G];n.atom_.reportObserved();var r=n.dehanceValues_(n.values_),o=arguments[0];return arguments[0]=function(e,n,r){return o(e,n,r,t)},r[e].apply(r,arguments)}}dn("concat",hn),dn("flat",hn),dn("includes",hn),dn("indexOf",hn),dn("join",hn),dn("lastIndexOf",hn),dn("slice",hn),dn("toString",hn),dn("toLocaleString",hn),dn("every",mn),dn("filter",mn),dn("find",mn),dn("findIndex",mn),dn("flatMap",mn),dn("forEach",mn),dn("map",mn),dn("some",mn),dn("reduce",vn),dn("reduceRight",vn);var gn,yn,bn=T("ObservableArrayAdministration",un);function xn(e){return _(e)&&bn(e[G])}var wn={},kn="add",En="delete";gn=Symbol.iterator,yn=Symbol.toStringTag;var Sn,_n,On=function(){function e(e,t,n){void 0===t&&(t=J),void 0===n&&(n="ObservableMap"),this.enhancer_=void 0,this.name_=void 0,this[G]=wn,this.data_=void 0,this.hasMap_=void 0,this.keysAtom_=void 0,this.interceptors_=void 0,this.changeListeners_=void 0,this.dehancer=void 0,this.enhancer_=t,this.name_=n,E(Map)||c(18),this.keysAtom_=K("ObservableMap.keys()"),this.data_=new Map,this.hasMap_=new Map,this.merge(e)}var t=e.prototype;return t.has_=function(e){return this.data_.has(e)},t.has=function(e){var t=this;if(!pt.trackingDerivation)return this.has_(e);var n=this.hasMap_.get(e);if(!n){var r=n=new He(this.has_(e),ee,"ObservableMap.key?",!1);this.hasMap_.set(e,r),Nt(r,(function(){return t.hasMap_.delete(e)}))}return n.get()},t.set=function(e,t){var n=this.has_(e);if(Jt(this)){var r=tn(this,{type:n?ln:kn,object:this,newValue:t,name:e});if(!r)return this;t=r.newValue}return n?this.updateValue_(e,t):this.addValue_(e,t),this},t.delete=function(e){var t=this;if(this.keysAtom_,Jt(this)&&!tn(this,{type:En,object:this,name:e}))return!1;if(this.has_(e)){var n=nn(this),r=n?{observableKind:"map",debugObjectName:this.name_,type:En,object:this,oldValue:this.data_.get(e).value_,name:e}:null;return Xt((function(){t.keysAtom_.reportChanged(),t.updateHasMapEntry_(e,!1),t.data_.get(e).setNewValue_(void 0),t.data_.delete(e)})),n&&on(this,r),!0}return!1},t.updateHasMapEntry_=function(e,t){var n=this.hasMap_.get(e);n&&n.setNewValue_(t)},t.updateValue_=function(e,t){var n=this.data_.get(e);if((t=n.prepareNewValue_(t))!==pt.UNCHANGED){var r=nn(this),o=r?{observableKind:"map",debugObjectName:this.name_,type:ln,object:this,oldValue:n.value_,name:e,newValue:t}:null;n.setNewValue_(t),r&&on(this,o)}},t.addValue_=function(e,t){var n=this;this.keysAtom_,Xt((function(){var r=new He(t,n.enhancer_,"ObservableMap.key",!1);n.data_.set(e,r),t=r.value_,n.updateHasMapEntry_(e,!0),n.keysAtom_.reportChanged()}));var r=nn(this),o=r?{observableKind:"map",debugObjectName:this.name_,type:kn,object:this,name:e,newValue:t}:null;r&&on(this,o)},t.get=function(e){return this.has(e)?this.dehanceValue_(this.data_.get(e).get()):this.dehanceValue_(void 0)},t.dehanceValue_=function(e){return void 0!==this.dehancer?this.dehancer(e):e},t.keys=function(){return this.keysAtom_.reportObserved(),this.data_.keys()},t.values=function(){var e=this,t=this.keys();return er({next:function(){var n=t.next(),r=n.done,o=n.value;return{done:r,value:r?void 0:e.get(o)}}})},t.entries=function(){var e=this,t=this.keys();return er({next:function(){var n=t.next(),r=n.done,o=n.value;return{done:r,value:r?void 0:[o,e.get(o)]}}})},t[gn]=function(){return this.entries()},t.forEach=function(e,t){for(var n,r=W(this);!(n=r()).done;){var o=n.value,i=o[0],a=o[1];e.call(t,a,i,this)}},t.merge=function(e){var t=this;return An(e)&&(e=new Map(e)),Xt((function(){O(e)?function(e){var t=Object.keys(e);if(!j)return t;var n=Object.getOwnPropertySymbols(e);return n.length?[].concat(t,n.filter((function(t){return m.propertyIsEnumerable.call(e,t)}))):t}(e).forEach((function(n){return t.set(n,e[n])})):Array.isArray(e)?e.forEach((function(e){var n=e[0],r=e[1];return t.set(n,r)})):P(e)?(e.constructor!==Map&&c(19,e),e.forEach((function(e,n){return t.set(n,e)}))):null!=e&&c(20,e)})),this},t.clear=function(){var e=this;Xt((function(){nt((function(){for(var t,n=W(e.keys());!(t=n()).done;){var r=t.value;e.delete(r)}}))}))},t.replace=function(e){var t=this;return Xt((function(){for(var n,r=function(e){if(P(e)||An(e))return e;if(Array.isArray(e))return new Map(e);if(O(e)){var t=new Map;for(var n in e)t.set(n,e[n]);return t}return c(21,e)}(e),o=new Map,i=!1,a=W(t.data_.keys());!(n=a()).done;){var s=n.value;if(!r.has(s))if(t.delete(s))i=!0;else{var l=t.data_.get(s);o.set(s,l)}}for(var u,p=W(r.entries());!(u=p()).done;){var f=u.value,d=f[0],h=f[1],m=t.data_.has(d);if(t.set(d,h),t.data_.has(d)){var v=t.data_.get(d);o.set(d,v),m||(i=!0)}}if(!i)if(t.data_.size!==o.size)t.keysAtom_.reportChanged();else for(var g=t.data_.keys(),y=o.keys(),b=g.next(),x=y.next();!b.done;){if(b.value!==x.value){t.keysAtom_.reportChanged();break}b=g.next(),x=y.next()}t.data_=o})),this},t.toString=function(){return"[object ObservableMap]"},t.toJSON=function(){return Array.from(this)},t.observe_=function(e,t){return rn(this,e)},t.intercept_=function(e){return en(this,e)},z(e,[{key:"size",get:function(){return this.keysAtom_.reportObserved(),this.data_.size}},{key:yn,get:function(){return"Map"}}]),e}(),An=T("ObservableMap",On),Rn={};Sn=Symbol.iterator,_n=Symbol.toStringTag;var Cn=function(){function e(e,t,n){void 0===t&&(t=J),void 0===n&&(n="ObservableSet"),this.name_=void 0,this[G]=Rn,this.data_=new Set,this.atom_=void 0,this.changeListeners_=void 0,this.interceptors_=void 0,this.dehancer=void 0,this.enhancer_=void 0,this.name_=n,E(Set)||c(22),this.atom_=K(this.name_),this.enhancer_=function(e,r){return t(e,r,n)},e&&this.replace(e)}var t=e.prototype;return t.dehanceValue_=function(e){return void 0!==this.dehancer?this.dehancer(e):e},t.clear=function(){var e=this;Xt((function(){nt((function(){for(var t,n=W(e.data_.values());!(t=n()).done;){var r=t.value;e.delete(r)}}))}))},t.forEach=function(e,t){for(var n,r=W(this);!(n=r()).done;){var o=n.value;e.call(t,o,o,this)}},t.add=function(e){var t=this;if(this.atom_,Jt(this)&&!tn(this,{type:kn,object:this,newValue:e}))return this;if(!this.has(e)){Xt((function(){t.data_.add(t.enhancer_(e,void 0)),t.atom_.reportChanged()}));var n=nn(this),r=n?{observableKind:"set",debugObjectName:this.name_,type:kn,object:this,newValue:e}:null;n&&on(this,r)}return this},t.delete=function(e){var t=this;if(Jt(this)&&!tn(this,{type:En,object:this,oldValue:e}))return!1;if(this.has(e)){var n=nn(this),r=n?{observableKind:"set",debugObjectName:this.name_,type:En,object:this,oldValue:e}:null;return Xt((function(){t.atom_.reportChanged(),t.data_.delete(e)})),n&&on(this,r),!0}return!1},t.has=function(e){return this.atom_.reportObserved(),this.data_.has(this.dehanceValue_(e))},t.entries=function(){var e=0,t=Array.from(this.keys()),n=Array.from(this.values());return er({next:function(){var r=e;return e+=1,r<n.length?{value:[t[r],n[r]],done:!1}:{done:!0}}})},t.keys=function(){return this.values()},t.values=function(){this.atom_.reportObserved();var e=this,t=0,n=Array.from(this.data_.values());return er({next:function(){return t<n.length?{value:e.dehanceValue_(n[t++]),done:!1}:{done:!0}}})},t.replace=function(e){var t=this;return Tn(e)&&(e=new Set(e)),Xt((function(){Array.isArray(e)||I(e)?(t.clear(),e.forEach((function(e){return t.add(e)}))):null!=e&&c("Cannot initialize set from "+e)})),this},t.observe_=function(e,t){return rn(this,e)},t.intercept_=function(e){return en(this,e)},t.toJSON=function(){return Array.from(this)},t.toString=function(){return"[object ObservableSet]"},t[Sn]=function(){return this.values()},z(e,[{key:"size",get:function(){return this.atom_.reportObserved(),this.data_.size}},{key:_n,get:function(){return"Set"}}]),e}(),Tn=T("ObservableSet",Cn),Pn=Object.create(null),In="remove",jn=function(){function e(e,t,n,r){void 0===t&&(t=new Map),void 0===r&&(r=ve),this.target_=void 0,this.values_=void 0,this.name_=void 0,this.defaultAnnotation_=void 0,this.keysAtom_=void 0,this.changeListeners_=void 0,this.interceptors_=void 0,this.proxy_=void 0,this.isPlainObject_=void 0,this.appliedAnnotations_=void 0,this.pendingKeys_=void 0,this.target_=e,this.values_=t,this.name_=n,this.defaultAnnotation_=r,this.keysAtom_=new Q("ObservableObject.keys"),this.isPlainObject_=O(this.target_)}var t=e.prototype;return t.getObservablePropValue_=function(e){return this.values_.get(e).get()},t.setObservablePropValue_=function(e,t){var n=this.values_.get(e);if(n instanceof Qe)return n.set(t),!0;if(Jt(this)){var r=tn(this,{type:ln,object:this.proxy_||this.target_,name:e,newValue:t});if(!r)return null;t=r.newValue}if((t=n.prepareNewValue_(t))!==pt.UNCHANGED){var o=nn(this),i=o?{type:ln,observableKind:"object",debugObjectName:this.name_,object:this.proxy_||this.target_,oldValue:n.value_,name:e,newValue:t}:null;n.setNewValue_(t),o&&on(this,i)}return!0},t.get_=function(e){return pt.trackingDerivation&&!M(this.target_,e)&&this.has_(e),this.target_[e]},t.set_=function(e,t,n){return void 0===n&&(n=!1),M(this.target_,e)?this.values_.has(e)?this.setObservablePropValue_(e,t):n?Reflect.set(this.target_,e,t):(this.target_[e]=t,!0):this.extend_(e,{value:t,enumerable:!0,writable:!0,configurable:!0},this.defaultAnnotation_,n)},t.has_=function(e){if(!pt.trackingDerivation)return e in this.target_;this.pendingKeys_||(this.pendingKeys_=new Map);var t=this.pendingKeys_.get(e);return t||(t=new He(e in this.target_,ee,"ObservableObject.key?",!1),this.pendingKeys_.set(e,t)),t.get()},t.make_=function(e,t){if(!0===t&&(t=this.defaultAnnotation_),!1!==t){if(!(e in this.target_)){var n;if(null==(n=this.target_[V])?void 0:n[e])return;c(1,t.annotationType_,this.name_+"."+e.toString())}for(var r=this.target_;r&&r!==m;){var o=d(r,e);if(o){var i=t.make_(this,e,o,r);if(0===i)return;if(1===i)break}r=Object.getPrototypeOf(r)}Fn(this,0,e)}},t.extend_=function(e,t,n,r){if(void 0===r&&(r=!1),!0===n&&(n=this.defaultAnnotation_),!1===n)return this.defineProperty_(e,t,r);var o=n.extend_(this,e,t,r);return o&&Fn(this,0,e),o},t.defineProperty_=function(e,t,n){void 0===n&&(n=!1);try{ht();var r=this.delete_(e);if(!r)return r;if(Jt(this)){var o=tn(this,{object:this.proxy_||this.target_,name:e,type:kn,newValue:t.value});if(!o)return null;var i=o.newValue;t.value!==i&&(t=U({},t,{value:i}))}if(n){if(!Reflect.defineProperty(this.target_,e,t))return!1}else h(this.target_,e,t);this.notifyPropertyAddition_(e,t.value)}finally{mt()}return!0},t.defineObservableProperty_=function(e,t,n,r){void 0===r&&(r=!1);try{ht();var o=this.delete_(e);if(!o)return o;if(Jt(this)){var i=tn(this,{object:this.proxy_||this.target_,name:e,type:kn,newValue:t});if(!i)return null;t=i.newValue}var a=Mn(e),s={configurable:!pt.safeDescriptors||this.isPlainObject_,enumerable:!0,get:a.get,set:a.set};if(r){if(!Reflect.defineProperty(this.target_,e,s))return!1}else h(this.target_,e,s);var l=new He(t,n,"ObservableObject.key",!1);this.values_.set(e,l),this.notifyPropertyAddition_(e,l.value_)}finally{mt()}return!0},t.defineComputedProperty_=function(e,t,n){void 0===n&&(n=!1);try{ht();var r=this.delete_(e);if(!r)return r;if(Jt(this)&&!tn(this,{object:this.proxy_||this.target_,name:e,type:kn,newValue:void 0}))return null;t.name||(t.name="ObservableObject.key"),t.context=this.proxy_||this.target_;var o=Mn(e),i={configurable:!pt.safeDescriptors||this.isPlainObject_,enumerable:!1,get:o.get,set:o.set};if(n){if(!Reflect.defineProperty(this.target_,e,i))return!1}else h(this.target_,e,i);this.values_.set(e,new Qe(t)),this.notifyPropertyAddition_(e,void 0)}finally{mt()}return!0},t.delete_=function(e,t){if(void 0===t&&(t=!1),!M(this.target_,e))return!0;if(Jt(this)&&!tn(this,{object:this.proxy_||this.target_,name:e,type:In}))return null;try{var n,r;ht();var o,i=nn(this),a=this.values_.get(e),s=void 0;if(!a&&i&&(s=null==(o=d(this.target_,e))?void 0:o.value),t){if(!Reflect.deleteProperty(this.target_,e))return!1}else delete this.target_[e];if(a&&(this.values_.delete(e),a instanceof He&&(s=a.value_),gt(a)),this.keysAtom_.reportChanged(),null==(n=this.pendingKeys_)||null==(r=n.get(e))||r.set(e in this.target_),i){var l={type:In,observableKind:"object",object:this.proxy_||this.target_,debugObjectName:this.name_,oldValue:s,name:e};i&&on(this,l)}}finally{mt()}return!0},t.observe_=function(e,t){return rn(this,e)},t.intercept_=function(e){return en(this,e)},t.notifyPropertyAddition_=function(e,t){var n,r,o=nn(this);if(o){var i=o?{type:kn,observableKind:"object",debugObjectName:this.name_,object:this.proxy_||this.target_,name:e,newValue:t}:null;o&&on(this,i)}null==(n=this.pendingKeys_)||null==(r=n.get(e))||r.set(!0),this.keysAtom_.reportChanged()},t.ownKeys_=function(){return this.keysAtom_.reportObserved(),L(this.target_)},t.keys_=function(){return this.keysAtom_.reportObserved(),Object.keys(this.target_)},e}();function Ln(e,t){var n;if(M(e,G))return e;var r=null!=(n=null==t?void 0:t.name)?n:"ObservableObject",o=new jn(e,new Map,String(r),function(e){var t;return e?null!=(t=e.defaultDecorator)?t:ge(e):void 0}(t));return R(e,G,o),e}var Nn=T("ObservableObjectAdministration",jn);function Mn(e){return Pn[e]||(Pn[e]={get:function(){return this[G].getObservablePropValue_(e)},set:function(t){return this[G].setObservablePropValue_(e,t)}})}function Dn(e){return!!_(e)&&Nn(e[G])}function Fn(e,t,n){var r;null==(r=e.target_[V])||delete r[n]}var zn,Un,Bn=0,$n=function(){};zn=$n,Un=Array.prototype,Object.setPrototypeOf?Object.setPrototypeOf(zn.prototype,Un):void 0!==zn.prototype.__proto__?zn.prototype.__proto__=Un:zn.prototype=Un;var qn=function(e){function t(t,n,r,o){var i;void 0===r&&(r="ObservableArray"),void 0===o&&(o=!1),i=e.call(this)||this;var a=new un(r,n,o,!0);if(a.proxy_=$(i),C($(i),G,a),t&&t.length){var s=qe(!0);i.spliceWithArray(0,0,t),We(s)}return i}B(t,e);var n=t.prototype;return n.concat=function(){this[G].atom_.reportObserved();for(var e=arguments.length,t=new Array(e),n=0;n<e;n++)t[n]=arguments[n];return Array.prototype.concat.apply(this.slice(),t.map((function(e){return xn(e)?e.slice():e})))},n[Symbol.iterator]=function(){var e=this,t=0;return er({next:function(){return t<e.length?{value:e[t++],done:!1}:{done:!0,value:void 0}}})},z(t,[{key:"length",get:function(){return this[G].getArrayLength_()},set:function(e){this[G].setArrayLength_(e)}},{key:Symbol.toStringTag,get:function(){return"Array"}}]),t}($n);function Wn(e){h(qn.prototype,""+e,function(e){return{enumerable:!1,configurable:!0,get:function(){return this[G].get_(e)},set:function(t){this[G].set_(e,t)}}}(e))}function Vn(e){if(e>Bn){for(var t=Bn;t<e+100;t++)Wn(t);Bn=e}}function Hn(e,t,n){return new qn(e,t,n)}function Yn(e,t){if("object"==typeof e&&null!==e){if(xn(e))return void 0!==t&&c(23),e[G].atom_;if(Tn(e))return e[G];if(An(e)){if(void 0===t)return e.keysAtom_;var n=e.data_.get(t)||e.hasMap_.get(t);return n||c(25,t,Qn(e)),n}if(Dn(e)){if(!t)return c(26);var r=e[G].values_.get(t);return r||c(27,t,Qn(e)),r}if(X(e)||Xe(e)||kt(e))return e}else if(E(e)&&kt(e[G]))return e[G];c(28)}function Gn(e,t){return e||c(29),void 0!==t?Gn(Yn(e,t)):X(e)||Xe(e)||kt(e)||An(e)||Tn(e)?e:e[G]?e[G]:void c(24,e)}function Qn(e,t){var n;if(void 0!==t)n=Yn(e,t);else{if(It(e))return e.name;n=Dn(e)||An(e)||Tn(e)?Gn(e):Yn(e)}return n.name_}Object.entries(fn).forEach((function(e){var t=e[0],n=e[1];"concat"!==t&&R(qn.prototype,t,n)})),Vn(1e3);var Xn=m.toString;function Kn(e,t,n){return void 0===n&&(n=-1),Zn(e,t,n)}function Zn(e,t,n,r,o){if(e===t)return 0!==e||1/e==1/t;if(null==e||null==t)return!1;if(e!=e)return t!=t;var i=typeof e;if(!E(i)&&"object"!==i&&"object"!=typeof t)return!1;var a=Xn.call(e);if(a!==Xn.call(t))return!1;switch(a){case"[object RegExp]":case"[object String]":return""+e==""+t;case"[object Number]":return+e!=+e?+t!=+t:0==+e?1/+e==1/t:+e==+t;case"[object Date]":case"[object Boolean]":return+e==+t;case"[object Symbol]":return"undefined"!=typeof Symbol&&Symbol.valueOf.call(e)===Symbol.valueOf.call(t);case"[object Map]":case"[object Set]":n>=0&&n++}e=Jn(e),t=Jn(t);var s="[object Array]"===a;if(!s){if("object"!=typeof e||"object"!=typeof t)return!1;var l=e.constructor,c=t.constructor;if(l!==c&&!(E(l)&&l instanceof l&&E(c)&&c instanceof c)&&"constructor"in e&&"constructor"in t)return!1}if(0===n)return!1;n<0&&(n=-1),o=o||[];for(var u=(r=r||[]).length;u--;)if(r[u]===e)return o[u]===t;if(r.push(e),o.push(t),s){if((u=e.length)!==t.length)return!1;for(;u--;)if(!Zn(e[u],t[u],n-1,r,o))return!1}else{var p,f=Object.keys(e);if(u=f.length,Object.keys(t).length!==u)return!1;for(;u--;)if(!M(t,p=f[u])||!Zn(e[p],t[p],n-1,r,o))return!1}return r.pop(),o.pop(),!0}function Jn(e){return xn(e)?e.slice():P(e)||An(e)||I(e)||Tn(e)?Array.from(e.entries()):e}function er(e){return e[Symbol.iterator]=tr,e}function tr(){return this}function nr(e,t){if(!(e instanceof t))throw new TypeError("Cannot call a class as a function")}function rr(e,t){for(var n=0;n<t.length;n++){var r=t[n];r.enumerable=r.enumerable||!1,r.configurable=!0,"value"in r&&(r.writable=!0),Object.defineProperty(e,r.key,r)}}function or(e,t,n){return t&&rr(e.prototype,t),n&&rr(e,n),e}function ir(){return(ir=Object.assign||function(e){for(var t=1;t<arguments.length;t++){var n=arguments[t];for(var r in n)Object.prototype.hasOwnProperty.call(n,r)&&(e[r]=n[r])}return e}).apply(this,arguments)}function ar(e){if(void 0===e)throw new ReferenceError("this hasn't been initialised - super() hasn't been called");return e}function sr(e,t){return(sr=Object.setPrototypeOf||function(e,t){return e.__proto__=t,e})(e,t)}function lr(e){return(lr=Object.setPrototypeOf?Object.getPrototypeOf:function(e){return e.__proto__||Object.getPrototypeOf(e)})(e)}function cr(){if("undefined"==typeof Reflect||!Reflect.construct)return!1;if(Reflect.construct.sham)return!1;if("function"==typeof Proxy)return!0;try{return Boolean.prototype.valueOf.call(Reflect.construct(Boolean,[],(function(){}))),!0}catch(e){return!1}}function ur(e,t,n){return(ur=cr()?Reflect.construct:function(e,t,n){var r=[null];r.push.apply(r,t);var o=new(Function.bind.apply(e,r));return n&&sr(o,n.prototype),o}).apply(null,arguments)}function pr(e){var t="function"==typeof Map?new Map:void 0;return(pr=function(e){if(null===e||(n=e,-1===Function.toString.call(n).indexOf("[native code]")))return e;var n;if("function"!=typeof e)throw new TypeError("Super expression must either be null or a function");if(void 0!==t){if(t.has(e))return t.get(e);t.set(e,r)}function r(){return ur(e,arguments,lr(this).constructor)}return r.prototype=Object.create(e.prototype,{constructor:{value:r,enumerable:!1,writable:!0,configurable:!0}}),sr(r,e)})(e)}["Symbol","Map","Set","Symbol"].forEach((function(e){void 0===p()[e]&&c("MobX requires global '"+e+"' to be available or polyfilled")})),"object"==typeof __MOBX_DEVTOOLS_GLOBAL_HOOK__&&__MOBX_DEVTOOLS_GLOBAL_HOOK__.injectMobx({spy:function(e){return console.warn("[mobx.spy] Is a no-op in production builds"),function(){}},extras:{getDebugName:Qn},$mobx:G}),r(3123),r(3210),r(9653);var fr=function(e){var t,n;function r(t){return ar(e.call(this,"An error occurred. See https://github.com/styled-components/polished/blob/main/src/internalHelpers/errors.md#"+t+" for more information.")||this)}return n=e,(t=r).prototype=Object.create(n.prototype),t.prototype.constructor=t,sr(t,n),r}(pr(Error));function dr(e){return Math.round(255*e)}function hr(e,t,n){return dr(e)+","+dr(t)+","+dr(n)}function mr(e,t,n,r){if(void 0===r&&(r=hr),0===t)return r(n,n,n);var o=(e%360+360)%360/60,i=(1-Math.abs(2*n-1))*t,a=i*(1-Math.abs(o%2-1)),s=0,l=0,c=0;o>=0&&o<1?(s=i,l=a):o>=1&&o<2?(s=a,l=i):o>=2&&o<3?(l=i,c=a):o>=3&&o<4?(l=a,c=i):o>=4&&o<5?(s=a,c=i):o>=5&&o<6&&(s=i,c=a);var u=n-i/2;return r(s+u,l+u,c+u)}var vr={aliceblue:"f0f8ff",antiquewhite:"faebd7",aqua:"00ffff",aquamarine:"7fffd4",azure:"f0ffff",beige:"f5f5dc",bisque:"ffe4c4",black:"000",blanchedalmond:"ffebcd",blue:"0000ff",blueviolet:"8a2be2",brown:"a52a2a",burlywood:"deb887",cadetblue:"5f9ea0",chartreuse:"7fff00",chocolate:"d2691e",coral:"ff7f50",cornflowerblue:"6495ed",cornsilk:"fff8dc",crimson:"dc143c",cyan:"00ffff",darkblue:"00008b",darkcyan:"008b8b",darkgoldenrod:"b8860b",darkgray:"a9a9a9",darkgreen:"006400",darkgrey:"a9a9a9",darkkhaki:"bdb76b",darkmagenta:"8b008b",darkolivegreen:"556b2f",darkorange:"ff8c00",darkorchid:"9932cc",darkred:"8b0000",darksalmon:"e9967a",darkseagreen:"8fbc8f",darkslateblue:"483d8b",darkslategray:"2f4f4f",darkslategrey:"2f4f4f",darkturquoise:"00ced1",darkviolet:"9400d3",deeppink:"ff1493",deepskyblue:"00bfff",dimgray:"696969",dimgrey:"696969",dodgerblue:"1e90ff",firebrick:"b22222",floralwhite:"fffaf0",forestgreen:"228b22",fuchsia:"ff00ff",gainsboro:"dcdcdc",ghostwhite:"f8f8ff",gold:"ffd700",goldenrod:"daa520",gray:"808080",green:"008000",greenyellow:"adff2f",grey:"808080",honeydew:"f0fff0",hotpink:"ff69b4",indianred:"cd5c5c",indigo:"4b0082",ivory:"fffff0",khaki:"f0e68c",lavender:"e6e6fa",lavenderblush:"fff0f5",lawngreen:"7cfc00",lemonchiffon:"fffacd",lightblue:"add8e6",lightcoral:"f08080",lightcyan:"e0ffff",lightgoldenrodyellow:"fafad2",lightgray:"d3d3d3",lightgreen:"90ee90",lightgrey:"d3d3d3",lightpink:"ffb6c1",lightsalmon:"ffa07a",lightseagreen:"20b2aa",lightskyblue:"87cefa",lightslategray:"789",lightslategrey:"789",lightsteelblue:"b0c4de",lightyellow:"ffffe0",lime:"0f0",limegreen:"32cd32",linen:"faf0e6",magenta:"f0f",maroon:"800000",mediumaquamarine:"66cdaa",mediumblue:"0000cd",mediumorchid:"ba55d3",mediumpurple:"9370db",mediumseagreen:"3cb371",mediumslateblue:"7b68ee",mediumspringgreen:"00fa9a",mediumturquoise:"48d1cc",mediumvioletred:"c71585",midnightblue:"191970",mintcream:"f5fffa",mistyrose:"ffe4e1",moccasin:"ffe4b5",navajowhite:"ffdead",navy:"000080",oldlace:"fdf5e6",olive:"808000",olivedrab:"6b8e23",orange:"ffa500",orangered:"ff4500",orchid:"da70d6",palegoldenrod:"eee8aa",palegreen:"98fb98",paleturquoise:"afeeee",palevioletred:"db7093",papayawhip:"ffefd5",peachpuff:"ffdab9",peru:"cd853f",pink:"ffc0cb",plum:"dda0dd",powderblue:"b0e0e6",purple:"800080",rebeccapurple:"639",red:"f00",rosybrown:"bc8f8f",royalblue:"4169e1",saddlebrown:"8b4513",salmon:"fa8072",sandybrown:"f4a460",seagreen:"2e8b57",seashell:"fff5ee",sienna:"a0522d",silver:"c0c0c0",skyblue:"87ceeb",slateblue:"6a5acd",slategray:"708090",slategrey:"708090",snow:"fffafa",springgreen:"00ff7f",steelblue:"4682b4",tan:"d2b48c",teal:"008080",thistle:"d8bfd8",tomato:"ff6347",turquoise:"40e0d0",violet:"ee82ee",wheat:"f5deb3",white:"fff",whitesmoke:"f5f5f5",yellow:"ff0",yellowgreen:"9acd32"},gr=/^#[a-fA-F0-9]{6}$/,yr=/^#[a-fA-F0-9]{8}$/,br=/^#[a-fA-F0-9]{3}$/,xr=/^#[a-fA-F0-9]{4}$/,wr=/^rgb\(\s*(\d{1,3})\s*,\s*(\d{1,3})\s*,\s*(\d{1,3})\s*\)$/i,kr=/^rgba\(\s*(\d{1,3})\s*,\s*(\d{1,3})\s*,\s*(\d{1,3})\s*,\s*([-+]?[0-9]*[.]?[0-9]+)\s*\)$/i,Er=/^hsl\(\s*(\d{0,3}[.]?[0-9]+)\s*,\s*(\d{1,3}[.]?[0-9]?)%\s*,\s*(\d{1,3}[.]?[0-9]?)%\s*\)$/i,Sr=/^hsla\(\s*(\d{0,3}[.]?[0-9]+)\s*,\s*(\d{1,3}[.]?[0-9]?)%\s*,\s*(\d{1,3}[.]?[0-9]?)%\s*,\s*([-+]?[0-9]*[.]?[0-9]+)\s*\)$/i;function _r(e){if("string"!=typeof e)throw new fr(3);var t=function(e){if("string"!=typeof e)return e;var t=e.toLowerCase();return vr[t]?"#"+vr[t]:e}(e);if(t.match(gr))return{red:parseInt(""+t[1]+t[2],16),green:parseInt(""+t[3]+t[4],16),blue:parseInt(""+t[5]+t[6],16)};if(t.match(yr)){var n=parseFloat((parseInt(""+t[7]+t[8],16)/255).toFixed(2));return{red:parseInt(""+t[1]+t[2],16),green:parseInt(""+t[3]+t[4],16),blue:parseInt(""+t[5]+t[6],16),alpha:n}}if(t.match(br))return{red:parseInt(""+t[1]+t[1],16),green:parseInt(""+t[2]+t[2],16),blue:parseInt(""+t[3]+t[3],16)};if(t.match(xr)){var r=parseFloat((parseInt(""+t[4]+t[4],16)/255).toFixed(2));return{red:parseInt(""+t[1]+t[1],16),green:parseInt(""+t[2]+t[2],16),blue:parseInt(""+t[3]+t[3],16),alpha:r}}var o=wr.exec(t);if(o)return{red:parseInt(""+o[1],10),green:parseInt(""+o[2],10),blue:parseInt(""+o[3],10)};var i=kr.exec(t.substring(0,50));if(i)return{red:parseInt(""+i[1],10),green:parseInt(""+i[2],10),blue:parseInt(""+i[3],10),alpha:parseFloat(""+i[4])};var a=Er.exec(t);if(a){var s="rgb("+mr(parseInt(""+a[1],10),parseInt(""+a[2],10)/100,parseInt(""+a[3],10)/100)+")",l=wr.exec(s);if(!l)throw new fr(4,t,s);return{red:parseInt(""+l[1],10),green:parseInt(""+l[2],10),blue:parseInt(""+l[3],10)}}var c=Sr.exec(t.substring(0,50));if(c){var u="rgb("+mr(parseInt(""+c[1],10),parseInt(""+c[2],10)/100,parseInt(""+c[3],10)/100)+")",p=wr.exec(u);if(!p)throw new fr(4,t,u);return{red:parseInt(""+p[1],10),green:parseInt(""+p[2],10),blue:parseInt(""+p[3],10),alpha:parseFloat(""+c[4])}}throw new fr(5)}function Or(e){return function(e){var t,n=e.red/255,r=e.green/255,o=e.blue/255,i=Math.max(n,r,o),a=Math.min(n,r,o),s=(i+a)/2;if(i===a)return void 0!==e.alpha?{hue:0,saturation:0,lightness:s,alpha:e.alpha}:{hue:0,saturation:0,lightness:s};var l=i-a,c=s>.5?l/(2-i-a):l/(i+a);switch(i){case n:t=(r-o)/l+(r<o?6:0);break;case r:t=(o-n)/l+2;break;default:t=(n-r)/l+4}return t*=60,void 0!==e.alpha?{hue:t,saturation:c,lightness:s,alpha:e.alpha}:{hue:t,saturation:c,lightness:s}}(_r(e))}var Ar=function(e){return 7===e.length&&e[1]===e[2]&&e[3]===e[4]&&e[5]===e[6]?"#"+e[1]+e[3]+e[5]:e};function Rr(e){var t=e.toString(16);return 1===t.length?"0"+t:t}function Cr(e){return Rr(Math.round(255*e))}function Tr(e,t,n){return Ar("#"+Cr(e)+Cr(t)+Cr(n))}function Pr(e,t,n){return mr(e,t,n,Tr)}function Ir(e,t,n){if("number"==typeof e&&"number"==typeof t&&"number"==typeof n)return Ar("#"+Rr(e)+Rr(t)+Rr(n));if("object"==typeof e&&void 0===t&&void 0===n)return Ar("#"+Rr(e.red)+Rr(e.green)+Rr(e.blue));throw new fr(6)}function jr(e,t,n,r){if("string"==typeof e&&"number"==typeof t){var o=_r(e);return"rgba("+o.red+","+o.green+","+o.blue+","+t+")"}if("number"==typeof e&&"number"==typeof t&&"number"==typeof n&&"number"==typeof r)return r>=1?Ir(e,t,n):"rgba("+e+","+t+","+n+","+r+")";if("object"==typeof e&&void 0===t&&void 0===n&&void 0===r)return e.alpha>=1?Ir(e.red,e.green,e.blue):"rgba("+e.red+","+e.green+","+e.blue+","+e.alpha+")";throw new fr(7)}function Lr(e){if("object"!=typeof e)throw new fr(8);if(function(e){return"number"==typeof e.red&&"number"==typeof e.green&&"number"==typeof e.blue&&"number"==typeof e.alpha}(e))return jr(e);if(function(e){return"number"==typeof e.red&&"number"==typeof e.green&&"number"==typeof e.blue&&("number"!=typeof e.alpha||void 0===e.alpha)}(e))return Ir(e);if(function(e){return"number"==typeof e.hue&&"number"==typeof e.saturation&&"number"==typeof e.lightness&&"number"==typeof e.alpha}(e))return function(e,t,n,r){if("number"==typeof e&&"number"==typeof t&&"number"==typeof n&&"number"==typeof r)return r>=1?Pr(e,t,n):"rgba("+mr(e,t,n)+","+r+")";if("object"==typeof e&&void 0===t&&void 0===n&&void 0===r)return e.alpha>=1?Pr(e.hue,e.saturation,e.lightness):"rgba("+mr(e.hue,e.saturation,e.lightness)+","+e.alpha+")";throw new fr(2)}(e);if(function(e){return"number"==typeof e.hue&&"number"==typeof e.saturation&&"number"==typeof e.lightness&&("number"!=typeof e.alpha||void 0===e.alpha)}(e))return function(e,t,n){if("number"==typeof e&&"number"==typeof t&&"number"==typeof n)return Pr(e,t,n);if("object"==typeof e&&void 0===t&&void 0===n)return Pr(e.hue,e.saturation,e.lightness);throw new fr(1)}(e);throw new fr(8)}function Nr(e,t,n){return function(){var r=n.concat(Array.prototype.slice.call(arguments));return r.length>=t?e.apply(this,r):Nr(e,t,r)}}function Mr(e){return Nr(e,e.length,[])}function Dr(e,t,n){return Math.max(e,Math.min(t,n))}function Fr(e,t){if("transparent"===t)return t;var n=Or(t);return Lr(ir({},n,{lightness:Dr(0,1,n.lightness-parseFloat(e))}))}var zr=Mr(Fr);function Ur(e,t){if("transparent"===t)return t;var n=Or(t);return Lr(ir({},n,{saturation:Dr(0,1,n.saturation-parseFloat(e))}))}var Br=Mr(Ur);function $r(e){if("transparent"===e)return 0;var t=_r(e),n=Object.keys(t).map((function(e){var n=t[e]/255;return n<=.03928?n/12.92:Math.pow((n+.055)/1.055,2.4)})),r=n[0],o=n[1],i=n[2];return parseFloat((.2126*r+.7152*o+.0722*i).toFixed(3))}function qr(e,t){if("transparent"===t)return t;var n=Or(t);return Lr(ir({},n,{lightness:Dr(0,1,n.lightness+parseFloat(e))}))}var Wr=Mr(qr),Vr="#000",Hr="#fff";function Yr(e,t,n,r){void 0===t&&(t=Vr),void 0===n&&(n=Hr),void 0===r&&(r=!0);var o,i,a,s=$r(e)>.179,l=s?t:n;return!r||(o=l,i=$r(e),a=$r(o),parseFloat((i>a?(i+.05)/(a+.05):(a+.05)/(i+.05)).toFixed(2))>=4.5)?l:s?Vr:Hr}function Gr(e,t){if("transparent"===t)return t;var n=_r(t);return jr(ir({},n,{alpha:Dr(0,1,+(100*("number"==typeof n.alpha?n.alpha:1)-100*parseFloat(e)).toFixed(2)/100)}))}var Qr=Mr(Gr),Xr={spacing:{unit:5,sectionHorizontal:function(e){return 8*e.spacing.unit},sectionVertical:function(e){return 8*e.spacing.unit}},breakpoints:{small:"50rem",medium:"75rem",large:"105rem"},colors:{tonalOffset:.2,primary:{main:"#32329f",light:function(e){var t=e.colors;return Wr(t.tonalOffset,t.primary.main)},dark:function(e){var t=e.colors;return zr(t.tonalOffset,t.primary.main)},contrastText:function(e){return Yr(e.colors.primary.main)}},success:{main:"#1d8127",light:function(e){var t=e.colors;return Wr(2*t.tonalOffset,t.success.main)},dark:function(e){var t=e.colors;return zr(t.tonalOffset,t.success.main)},contrastText:function(e){return Yr(e.colors.success.main)}},warning:{main:"#ffa500",light:function(e){var t=e.colors;return Wr(t.tonalOffset,t.warning.main)},dark:function(e){var t=e.colors;return zr(t.tonalOffset,t.warning.main)},contrastText:"#ffffff"},error:{main:"#d41f1c",light:function(e){var t=e.colors;return Wr(t.tonalOffset,t.error.main)},dark:function(e){var t=e.colors;return zr(t.tonalOffset,t.error.main)},contrastText:function(e){return Yr(e.colors.error.main)}},gray:{50:"#FAFAFA",100:"#F5F5F5"},text:{primary:"#333333",secondary:function(e){var t=e.colors;return Wr(t.tonalOffset,t.text.primary)}},border:{dark:"rgba(0,0,0, 0.1)",light:"#ffffff"},responses:{success:{color:function(e){return e.colors.success.main},backgroundColor:function(e){var t=e.colors;return Qr(.93,t.success.main)},tabTextColor:function(e){return e.colors.responses.success.color}},error:{color:function(e){return e.colors.error.main},backgroundColor:function(e){var t=e.colors;return Qr(.93,t.error.main)},tabTextColor:function(e){return e.colors.responses.error.color}},redirect:{color:function(e){return e.colors.warning.main},backgroundColor:function(e){var t=e.colors;return Qr(.9,t.responses.redirect.color)},tabTextColor:function(e){return e.colors.responses.redirect.color}},info:{color:"#87ceeb",backgroundColor:function(e){var t=e.colors;return Qr(.9,t.responses.info.color)},tabTextColor:function(e){return e.colors.responses.info.color}}},http:{get:"#2F8132",post:"#186FAF",put:"#95507c",options:"#947014",patch:"#bf581d",delete:"#cc3333",basic:"#707070",link:"#07818F",head:"#A23DAD"}},schema:{linesColor:function(e){return Wr(e.colors.tonalOffset,Br(e.colors.tonalOffset,e.colors.primary.main))},defaultDetailsWidth:"75%",typeNameColor:function(e){return e.colors.text.secondary},typeTitleColor:function(e){return e.schema.typeNameColor},requireLabelColor:function(e){return e.colors.error.main},labelsTextSize:"0.9em",nestingSpacing:"1em",nestedBackground:"#fafafa",arrow:{size:"1.1em",color:function(e){return e.colors.text.secondary}}},typography:{fontSize:"14px",lineHeight:"1.5em",fontWeightRegular:"400",fontWeightBold:"600",fontWeightLight:"300",fontFamily:"Roboto, sans-serif",smoothing:"antialiased",optimizeSpeed:!0,headings:{fontFamily:"Montserrat, sans-serif",fontWeight:"400",lineHeight:"1.6em"},code:{fontSize:"13px",fontFamily:"Courier, monospace",lineHeight:function(e){return e.typography.lineHeight},fontWeight:function(e){return e.typography.fontWeightRegular},color:"#e53935",backgroundColor:"rgba(38, 50, 56, 0.05)",wrap:!1},links:{color:function(e){return e.colors.primary.main},visited:function(e){return e.typography.links.color},hover:function(e){var t=e.typography;return Wr(.2,t.links.color)}}},sidebar:{width:"260px",backgroundColor:"#fafafa",textColor:"#333333",activeTextColor:function(e){return e.sidebar.textColor!==Xr.sidebar.textColor?e.sidebar.textColor:e.colors.primary.main},groupItems:{textTransform:"uppercase"},level1Items:{textTransform:"none"},arrow:{size:"1.5em",color:function(e){return e.sidebar.textColor}}},logo:{maxHeight:function(e){return e.sidebar.width},maxWidth:function(e){return e.sidebar.width},gutter:"2px"},rightPanel:{backgroundColor:"#263238",width:"40%",textColor:"#ffffff"},codeBlock:{backgroundColor:function(e){var t=e.rightPanel;return zr(.1,t.backgroundColor)}}},Kr=Xr;function Zr(e){var t={},n=0;return function r(o,i){Object.keys(o).forEach((function(a){var s=(i?i+".":"")+a,l=o[a];"function"==typeof l?Object.defineProperty(o,a,{get:function(){if(!t[s]){if(++n>1e3)throw new Error("Theme probably contains circular dependency at ".concat(s,": ").concat(l.toString()));t[s]=l(e)}return t[s]},enumerable:!0}):"object"==typeof l&&r(l,s)}))}(e,""),JSON.parse(JSON.stringify(e))}var Jr="undefined"!=typeof window&&"HTMLElement"in window;function eo(e){return"undefined"!=typeof document?document.querySelector(e):null}function to(e){var t=!(arguments.length>1&&void 0!==arguments[1])||arguments[1],n=e.parentNode;if(n){var r=window.getComputedStyle(n,void 0),o=parseInt(r.getPropertyValue("border-top-width"),10),i=parseInt(r.getPropertyValue("border-left-width"),10),a=e.offsetTop-n.offsetTop<n.scrollTop,s=e.offsetTop-n.offsetTop+e.clientHeight-o>n.scrollTop+n.clientHeight,l=e.offsetLeft-n.offsetLeft<n.scrollLeft,c=e.offsetLeft-n.offsetLeft+e.clientWidth-i>n.scrollLeft+n.clientWidth,u=a&&!s;(a||s)&&t&&(n.scrollTop=e.offsetTop-n.offsetTop-n.clientHeight/2-o+e.clientHeight/2),(l||c)&&t&&(n.scrollLeft=e.offsetLeft-n.offsetLeft-n.clientWidth/2-i+e.clientWidth/2),(a||s||l||c)&&!t&&e.scrollIntoView(u)}}function no(e,t){(null==t||t>e.length)&&(t=e.length);for(var n=0,r=new Array(t);n<t;n++)r[n]=e[n];return r}function ro(e,t){if(e){if("string"==typeof e)return no(e,t);var n=Object.prototype.toString.call(e).slice(8,-1);return"Object"===n&&e.constructor&&(n=e.constructor.name),"Map"===n||"Set"===n?Array.from(e):"Arguments"===n||/^(?:Ui|I)nt(?:8|16|32)(?:Clamped)?Array$/.test(n)?no(e,t):void 0}}function oo(e,t){var n="undefined"!=typeof Symbol&&e[Symbol.iterator]||e["@@iterator"];if(!n){if(Array.isArray(e)||(n=ro(e))||t&&e&&"number"==typeof e.length){n&&(e=n);var r=0,o=function(){};return{s:o,n:function(){return r>=e.length?{done:!0}:{done:!1,value:e[r++]}},e:function(e){throw e},f:o}}throw new TypeError("Invalid attempt to iterate non-iterable instance.\nIn order to be iterable, non-array objects must have a [Symbol.iterator]() method.")}var i,a=!0,s=!1;return{s:function(){n=n.call(e)},n:function(){var e=n.next();return a=e.done,e},e:function(e){s=!0,i=e},f:function(){try{a||null==n.return||n.return()}finally{if(s)throw i}}}}r(7852),r(4603),r(6755),r(4765),r(285);var io=r(1304),ao=r.n(io),so=r(8575);function lo(e,t){for(var n=[],r=0;r<e.length-1;r++)n.push(t(e[r],!1));return 0!==e.length&&n.push(t(e[e.length-1],!0)),n}function co(e,t){var n={};for(var r in e)e.hasOwnProperty(r)&&(n[r]=t(e[r],r,e));return n}function uo(e){return e.endsWith("/")?e.substring(0,e.length-1):e}function po(e){return!isNaN(parseFloat(e))&&isFinite(e)}var fo=function e(t){for(var n=arguments.length,r=new Array(n>1?n-1:0),o=1;o<n;o++)r[o-1]=arguments[o];if(!r.length)return t;var i=r.shift();return void 0===i?t:(ho(t)&&ho(i)&&Object.keys(i).forEach((function(n){ho(i[n])?(t[n]||(t[n]={}),e(t[n],i[n])):t[n]=i[n]})),e.apply(void 0,[t].concat(r)))},ho=function(e){return function(e){return null!==e&&"object"==typeof e}(e)&&!Array.isArray(e)};function mo(e){return ao()(e)||e.toString().toLowerCase().replace(/\s+/g,"-").replace(/&/g,"-and-").replace(/\--+/g,"-").replace(/^-+/,"").replace(/-+$/,"")}function vo(e){return"undefined"==typeof URL?new(r(8575).URL)(e):new URL(e)}function go(e){return e.replace(/&#(\d+);/g,(function(e,t){return String.fromCharCode(parseInt(t,10))})).replace(/&amp;/g,"&")}var yo,bo={enum:"Enum",enumSingleValue:"Value",enumArray:"Items",default:"Default",deprecated:"Deprecated",example:"Example",examples:"Examples",recursive:"Recursive",arrayOf:"Array of ",webhook:"Event",const:"Value",noResultsFound:"No results found",download:"Download",downloadSpecification:"Download OpenAPI specification",responses:"Responses",callbackResponses:"Callback responses",requestSamples:"Request samples",responseSamples:"Response samples"};function xo(e){Object.assign(bo,e)}function wo(e,t){var n=bo[e];return void 0!==t?n[t]:n}function ko(e,t){return void 0===e?t||!1:"string"==typeof e?"false"!==e:e}function Eo(e){return"string"==typeof e?parseInt(e,10):"number"==typeof e?e:void 0}!function(e){e.SummaryOnly="summary-only",e.PathOnly="path-only"}(yo||(yo={}));var So=function(){function e(t){var r,o,i,s,l,c=arguments.length>1&&void 0!==arguments[1]?arguments[1]:{};nr(this,e),n(this,"theme",void 0),n(this,"scrollYOffset",void 0),n(this,"hideHostname",void 0),n(this,"expandResponses",void 0),n(this,"requiredPropsFirst",void 0),n(this,"sortPropsAlphabetically",void 0),n(this,"sortEnumValuesAlphabetically",void 0),n(this,"noAutoAuth",void 0),n(this,"nativeScrollbars",void 0),n(this,"pathInMiddlePanel",void 0),n(this,"untrustedSpec",void 0),n(this,"hideDownloadButton",void 0),n(this,"disableSearch",void 0),n(this,"onlyRequiredInSamples",void 0),n(this,"showExtensions",void 0),n(this,"sideNavStyle",void 0),n(this,"hideSingleRequestSampleTab",void 0),n(this,"menuToggle",void 0),n(this,"jsonSampleExpandLevel",void 0),n(this,"enumSkipQuotes",void 0),n(this,"hideSchemaTitles",void 0),n(this,"simpleOneOfTypeLabel",void 0),n(this,"payloadSampleIdx",void 0),n(this,"expandSingleSchemaField",void 0),n(this,"unstable_ignoreMimeParameters",void 0),n(this,"allowedMdComponents",void 0),n(this,"expandDefaultServerVariables",void 0),n(this,"maxDisplayedEnumValues",void 0),n(this,"ignoreNamedSchemas",void 0),n(this,"hideSchemaPattern",void 0),n(this,"generatedPayloadSamplesMaxDepth",void 0);var u=(t=a(a({},c),t)).theme&&t.theme.extensionsHook;null===(r=t.theme)||void 0===r||!r.menu||null!==(o=t.theme)&&void 0!==o&&o.sidebar||(console.warn('Theme setting "menu" is deprecated. Rename to "sidebar"'),t.theme.sidebar=t.theme.menu),null===(i=t.theme)||void 0===i||!i.codeSample||null!==(s=t.theme)&&void 0!==s&&s.codeBlock||(console.warn('Theme setting "codeSample" is deprecated. Rename to "codeBlock"'),t.theme.codeBlock=t.theme.codeSample),this.theme=Zr(fo({},Kr,a(a({},t.theme),{},{extensionsHook:void 0}))),this.theme.extensionsHook=u,xo(t.labels),this.scrollYOffset=e.normalizeScrollYOffset(t.scrollYOffset),this.hideHostname=e.normalizeHideHostname(t.hideHostname),this.expandResponses=e.normalizeExpandResponses(t.expandResponses),this.requiredPropsFirst=ko(t.requiredPropsFirst),this.sortPropsAlphabetically=ko(t.sortPropsAlphabetically),this.sortEnumValuesAlphabetically=ko(t.sortEnumValuesAlphabetically),this.noAutoAuth=ko(t.noAutoAuth),this.nativeScrollbars=ko(t.nativeScrollbars),this.pathInMiddlePanel=ko(t.pathInMiddlePanel),this.untrustedSpec=ko(t.untrustedSpec),this.hideDownloadButton=ko(t.hideDownloadButton),this.disableSearch=ko(t.disableSearch),this.onlyRequiredInSamples=ko(t.onlyRequiredInSamples),this.showExtensions=e.normalizeShowExtensions(t.showExtensions),this.sideNavStyle=e.normalizeSideNavStyle(t.sideNavStyle),this.hideSingleRequestSampleTab=ko(t.hideSingleRequestSampleTab),this.menuToggle=ko(t.menuToggle,!0),this.jsonSampleExpandLevel=e.normalizeJsonSampleExpandLevel(t.jsonSampleExpandLevel),this.enumSkipQuotes=ko(t.enumSkipQuotes),this.hideSchemaTitles=ko(t.hideSchemaTitles),this.simpleOneOfTypeLabel=ko(t.simpleOneOfTypeLabel),this.payloadSampleIdx=e.normalizePayloadSampleIdx(t.payloadSampleIdx),this.expandSingleSchemaField=ko(t.expandSingleSchemaField),this.unstable_ignoreMimeParameters=ko(t.unstable_ignoreMimeParameters),this.allowedMdComponents=t.allowedMdComponents||{},this.expandDefaultServerVariables=ko(t.expandDefaultServerVariables),this.maxDisplayedEnumValues=Eo(t.maxDisplayedEnumValues);var p=Array.isArray(t.ignoreNamedSchemas)?t.ignoreNamedSchemas:null===(l=t.ignoreNamedSchemas)||void 0===l?void 0:l.split(",").map((function(e){return e.trim()}));this.ignoreNamedSchemas=new Set(p),this.hideSchemaPattern=ko(t.hideSchemaPattern),this.generatedPayloadSamplesMaxDepth=e.normalizeGeneratedPayloadSamplesMaxDepth(t.generatedPayloadSamplesMaxDepth)}return or(e,null,[{key:"normalizeExpandResponses",value:function(e){if("all"===e)return"all";if("string"==typeof e){var t={};return e.split(",").forEach((function(e){t[e.trim()]=!0})),t}return void 0!==e&&console.warn('expandResponses must be a string but received value "'.concat(e,'" of type ').concat(typeof e)),{}}},{key:"normalizeHideHostname",value:function(e){return!!e}},{key:"normalizeScrollYOffset",value:function(e){if("string"==typeof e&&!po(e)){var t=eo(e);t||console.warn("scrollYOffset value is a selector to non-existing element. Using offset 0 by default");var n=t&&t.getBoundingClientRect().bottom||0;return function(){return n}}return"number"==typeof e||po(e)?function(){return"number"==typeof e?e:parseFloat(e)}:"function"==typeof e?function(){var t=e();return"number"!=typeof t&&console.warn('scrollYOffset should return number but returned value "'.concat(t,'" of type ').concat(typeof t)),t}:(void 0!==e&&console.warn("Wrong value for scrollYOffset ReDoc option: should be string, number or function"),function(){return 0})}},{key:"normalizeShowExtensions",value:function(e){if(void 0===e)return!1;if(""===e)return!0;if("string"!=typeof e)return e;switch(e){case"true":return!0;case"false":return!1;default:return e.split(",").map((function(e){return e.trim()}))}}},{key:"normalizeSideNavStyle",value:function(e){var t=yo.SummaryOnly;if("string"!=typeof e)return t;switch(e){case t:return e;case yo.PathOnly:return yo.PathOnly;default:return t}}},{key:"normalizePayloadSampleIdx",value:function(e){return"number"==typeof e?Math.max(0,e):"string"==typeof e&&isFinite(e)?parseInt(e,10):0}},{key:"normalizeJsonSampleExpandLevel",value:function(e){return"all"===e?1/0:isNaN(Number(e))?2:Math.ceil(Number(e))}},{key:"normalizeGeneratedPayloadSamplesMaxDepth",value:function(e){return isNaN(Number(e))?10:Math.max(0,Number(e))}}]),e}();function _o(e,t){if("function"!=typeof t&&null!==t)throw new TypeError("Super expression must either be null or a function");e.prototype=Object.create(t&&t.prototype,{constructor:{value:e,writable:!0,configurable:!0}}),t&&sr(e,t)}function Oo(e){return(Oo="function"==typeof Symbol&&"symbol"==typeof Symbol.iterator?function(e){return typeof e}:function(e){return e&&"function"==typeof Symbol&&e.constructor===Symbol&&e!==Symbol.prototype?"symbol":typeof e})(e)}function Ao(e,t){return!t||"object"!==Oo(t)&&"function"!=typeof t?ar(e):t}function Ro(e){var t=cr();return function(){var n,r=lr(e);if(t){var o=lr(this).constructor;n=Reflect.construct(r,arguments,o)}else n=r.apply(this,arguments);return Ao(this,n)}}function Co(e,t){return t||(t=e.slice(0)),Object.freeze(Object.defineProperties(e,{raw:{value:Object.freeze(t)}}))}var To,Po=r(9864),Io=r(6774),jo=r.n(Io),Lo=function(e){function t(e,r,l,c,f){for(var d,h,m,v,x,k=0,E=0,S=0,_=0,O=0,I=0,L=m=d=0,M=0,D=0,F=0,z=0,U=l.length,B=U-1,$="",q="",W="",V="";M<U;){if(h=l.charCodeAt(M),M===B&&0!==E+_+S+k&&(0!==E&&(h=47===E?10:47),_=S=k=0,U++,B++),0===E+_+S+k){if(M===B&&(0<D&&($=$.replace(p,"")),0<$.trim().length)){switch(h){case 32:case 9:case 59:case 13:case 10:break;default:$+=l.charAt(M)}h=59}switch(h){case 123:for(d=($=$.trim()).charCodeAt(0),m=1,z=++M;M<U;){switch(h=l.charCodeAt(M)){case 123:m++;break;case 125:m--;break;case 47:switch(h=l.charCodeAt(M+1)){case 42:case 47:e:{for(L=M+1;L<B;++L)switch(l.charCodeAt(L)){case 47:if(42===h&&42===l.charCodeAt(L-1)&&M+2!==L){M=L+1;break e}break;case 10:if(47===h){M=L+1;break e}}M=L}}break;case 91:h++;case 40:h++;case 34:case 39:for(;M++<B&&l.charCodeAt(M)!==h;);}if(0===m)break;M++}switch(m=l.substring(z,M),0===d&&(d=($=$.replace(u,"").trim()).charCodeAt(0)),d){case 64:switch(0<D&&($=$.replace(p,"")),h=$.charCodeAt(1)){case 100:case 109:case 115:case 45:D=r;break;default:D=P}if(z=(m=t(r,D,m,h,f+1)).length,0<j&&(x=s(3,m,D=n(P,$,F),r,R,A,z,h,f,c),$=D.join(""),void 0!==x&&0===(z=(m=x.trim()).length)&&(h=0,m="")),0<z)switch(h){case 115:$=$.replace(w,a);case 100:case 109:case 45:m=$+"{"+m+"}";break;case 107:m=($=$.replace(g,"$1 $2"))+"{"+m+"}",m=1===T||2===T&&i("@"+m,3)?"@-webkit-"+m+"@"+m:"@"+m;break;default:m=$+m,112===c&&(q+=m,m="")}else m="";break;default:m=t(r,n(r,$,F),m,c,f+1)}W+=m,m=F=D=L=d=0,$="",h=l.charCodeAt(++M);break;case 125:case 59:if(1<(z=($=(0<D?$.replace(p,""):$).trim()).length))switch(0===L&&(d=$.charCodeAt(0),45===d||96<d&&123>d)&&(z=($=$.replace(" ",":")).length),0<j&&void 0!==(x=s(1,$,r,e,R,A,q.length,c,f,c))&&0===(z=($=x.trim()).length)&&($="\0\0"),d=$.charCodeAt(0),h=$.charCodeAt(1),d){case 0:break;case 64:if(105===h||99===h){V+=$+l.charAt(M);break}default:58!==$.charCodeAt(z-1)&&(q+=o($,d,h,$.charCodeAt(2)))}F=D=L=d=0,$="",h=l.charCodeAt(++M)}}switch(h){case 13:case 10:47===E?E=0:0===1+d&&107!==c&&0<$.length&&(D=1,$+="\0"),0<j*N&&s(0,$,r,e,R,A,q.length,c,f,c),A=1,R++;break;case 59:case 125:if(0===E+_+S+k){A++;break}default:switch(A++,v=l.charAt(M),h){case 9:case 32:if(0===_+k+E)switch(O){case 44:case 58:case 9:case 32:v="";break;default:32!==h&&(v=" ")}break;case 0:v="\\0";break;case 12:v="\\f";break;case 11:v="\\v";break;case 38:0===_+E+k&&(D=F=1,v="\f"+v);break;case 108:if(0===_+E+k+C&&0<L)switch(M-L){case 2:112===O&&58===l.charCodeAt(M-3)&&(C=O);case 8:111===I&&(C=I)}break;case 58:0===_+E+k&&(L=M);break;case 44:0===E+S+_+k&&(D=1,v+="\r");break;case 34:case 39:0===E&&(_=_===h?0:0===_?h:_);break;case 91:0===_+E+S&&k++;break;case 93:0===_+E+S&&k--;break;case 41:0===_+E+k&&S--;break;case 40:if(0===_+E+k){if(0===d)switch(2*O+3*I){case 533:break;default:d=1}S++}break;case 64:0===E+S+_+k+L+m&&(m=1);break;case 42:case 47:if(!(0<_+k+S))switch(E){case 0:switch(2*h+3*l.charCodeAt(M+1)){case 235:E=47;break;case 220:z=M,E=42}break;case 42:47===h&&42===O&&z+2!==M&&(33===l.charCodeAt(z+2)&&(q+=l.substring(z,M+1)),v="",E=0)}}0===E&&($+=v)}I=O,O=h,M++}if(0<(z=q.length)){if(D=r,0<j&&void 0!==(x=s(2,q,D,e,R,A,z,c,f,c))&&0===(q=x).length)return V+q+W;if(q=D.join(",")+"{"+q+"}",0!=T*C){switch(2!==T||i(q,2)||(C=0),C){case 111:q=q.replace(b,":-moz-$1")+q;break;case 112:q=q.replace(y,"::-webkit-input-$1")+q.replace(y,"::-moz-$1")+q.replace(y,":-ms-input-$1")+q}C=0}}return V+q+W}function n(e,t,n){var o=t.trim().split(m);t=o;var i=o.length,a=e.length;switch(a){case 0:case 1:var s=0;for(e=0===a?"":e[0]+" ";s<i;++s)t[s]=r(e,t[s],n).trim();break;default:var l=s=0;for(t=[];s<i;++s)for(var c=0;c<a;++c)t[l++]=r(e[c]+" ",o[s],n).trim()}return t}function r(e,t,n){var r=t.charCodeAt(0);switch(33>r&&(r=(t=t.trim()).charCodeAt(0)),r){case 38:return t.replace(v,"$1"+e.trim());case 58:return e.trim()+t.replace(v,"$1"+e.trim());default:if(0<1*n&&0<t.indexOf("\f"))return t.replace(v,(58===e.charCodeAt(0)?"":"$1")+e.trim())}return e+t}function o(e,t,n,r){var a=e+";",s=2*t+3*n+4*r;if(944===s){e=a.indexOf(":",9)+1;var l=a.substring(e,a.length-1).trim();return l=a.substring(0,e).trim()+l+";",1===T||2===T&&i(l,1)?"-webkit-"+l+l:l}if(0===T||2===T&&!i(a,1))return a;switch(s){case 1015:return 97===a.charCodeAt(10)?"-webkit-"+a+a:a;case 951:return 116===a.charCodeAt(3)?"-webkit-"+a+a:a;case 963:return 110===a.charCodeAt(5)?"-webkit-"+a+a:a;case 1009:if(100!==a.charCodeAt(4))break;case 969:case 942:return"-webkit-"+a+a;case 978:return"-webkit-"+a+"-moz-"+a+a;case 1019:case 983:return"-webkit-"+a+"-moz-"+a+"-ms-"+a+a;case 883:if(45===a.charCodeAt(8))return"-webkit-"+a+a;if(0<a.indexOf("image-set(",11))return a.replace(O,"$1-webkit-$2")+a;break;case 932:if(45===a.charCodeAt(4))switch(a.charCodeAt(5)){case 103:return"-webkit-box-"+a.replace("-grow","")+"-webkit-"+a+"-ms-"+a.replace("grow","positive")+a;case 115:return"-webkit-"+a+"-ms-"+a.replace("shrink","negative")+a;case 98:return"-webkit-"+a+"-ms-"+a.replace("basis","preferred-size")+a}return"-webkit-"+a+"-ms-"+a+a;case 964:return"-webkit-"+a+"-ms-flex-"+a+a;case 1023:if(99!==a.charCodeAt(8))break;return"-webkit-box-pack"+(l=a.substring(a.indexOf(":",15)).replace("flex-","").replace("space-between","justify"))+"-webkit-"+a+"-ms-flex-pack"+l+a;case 1005:return d.test(a)?a.replace(f,":-webkit-")+a.replace(f,":-moz-")+a:a;case 1e3:switch(t=(l=a.substring(13).trim()).indexOf("-")+1,l.charCodeAt(0)+l.charCodeAt(t)){case 226:l=a.replace(x,"tb");break;case 232:l=a.replace(x,"tb-rl");break;case 220:l=a.replace(x,"lr");break;default:return a}return"-webkit-"+a+"-ms-"+l+a;case 1017:if(-1===a.indexOf("sticky",9))break;case 975:switch(t=(a=e).length-10,s=(l=(33===a.charCodeAt(t)?a.substring(0,t):a).substring(e.indexOf(":",7)+1).trim()).charCodeAt(0)+(0|l.charCodeAt(7))){case 203:if(111>l.charCodeAt(8))break;case 115:a=a.replace(l,"-webkit-"+l)+";"+a;break;case 207:case 102:a=a.replace(l,"-webkit-"+(102<s?"inline-":"")+"box")+";"+a.replace(l,"-webkit-"+l)+";"+a.replace(l,"-ms-"+l+"box")+";"+a}return a+";";case 938:if(45===a.charCodeAt(5))switch(a.charCodeAt(6)){case 105:return l=a.replace("-items",""),"-webkit-"+a+"-webkit-box-"+l+"-ms-flex-"+l+a;case 115:return"-webkit-"+a+"-ms-flex-item-"+a.replace(E,"")+a;default:return"-webkit-"+a+"-ms-flex-line-pack"+a.replace("align-content","").replace(E,"")+a}break;case 973:case 989:if(45!==a.charCodeAt(3)||122===a.charCodeAt(4))break;case 931:case 953:if(!0===_.test(e))return 115===(l=e.substring(e.indexOf(":")+1)).charCodeAt(0)?o(e.replace("stretch","fill-available"),t,n,r).replace(":fill-available",":stretch"):a.replace(l,"-webkit-"+l)+a.replace(l,"-moz-"+l.replace("fill-",""))+a;break;case 962:if(a="-webkit-"+a+(102===a.charCodeAt(5)?"-ms-"+a:"")+a,211===n+r&&105===a.charCodeAt(13)&&0<a.indexOf("transform",10))return a.substring(0,a.indexOf(";",27)+1).replace(h,"$1-webkit-$2")+a}return a}function i(e,t){var n=e.indexOf(1===t?":":"{"),r=e.substring(0,3!==t?n:10);return n=e.substring(n+1,e.length-1),L(2!==t?r:r.replace(S,"$1"),n,t)}function a(e,t){var n=o(t,t.charCodeAt(0),t.charCodeAt(1),t.charCodeAt(2));return n!==t+";"?n.replace(k," or ($1)").substring(4):"("+t+")"}function s(e,t,n,r,o,i,a,s,l,u){for(var p,f=0,d=t;f<j;++f)switch(p=I[f].call(c,e,d,n,r,o,i,a,s,l,u)){case void 0:case!1:case!0:case null:break;default:d=p}if(d!==t)return d}function l(e){return void 0!==(e=e.prefix)&&(L=null,e?"function"!=typeof e?T=1:(T=2,L=e):T=0),l}function c(e,n){var r=e;if(33>r.charCodeAt(0)&&(r=r.trim()),r=[r],0<j){var o=s(-1,n,r,r,R,A,0,0,0,0);void 0!==o&&"string"==typeof o&&(n=o)}var i=t(P,r,n,0,0);return 0<j&&void 0!==(o=s(-2,i,r,r,R,A,i.length,0,0,0))&&(i=o),C=0,A=R=1,i}var u=/^\0+/g,p=/[\0\r\f]/g,f=/: */g,d=/zoo|gra/,h=/([,: ])(transform)/g,m=/,\r+?/g,v=/([\t\r\n ])*\f?&/g,g=/@(k\w+)\s*(\S*)\s*/,y=/::(place)/g,b=/:(read-only)/g,x=/[svh]\w+-[tblr]{2}/,w=/\(\s*(.*)\s*\)/g,k=/([\s\S]*?);/g,E=/-self|flex-/g,S=/[^]*?(:[rp][el]a[\w-]+)[^]*/,_=/stretch|:\s*\w+\-(?:conte|avail)/,O=/([^-])(image-set\()/,A=1,R=1,C=0,T=1,P=[],I=[],j=0,L=null,N=0;return c.use=function e(t){switch(t){case void 0:case null:j=I.length=0;break;default:if("function"==typeof t)I[j++]=t;else if("object"==typeof t)for(var n=0,r=t.length;n<r;++n)e(t[n]);else N=0|!!t}return e},c.set=l,void 0!==e&&l(e),c},No={animationIterationCount:1,borderImageOutset:1,borderImageSlice:1,borderImageWidth:1,boxFlex:1,boxFlexGroup:1,boxOrdinalGroup:1,columnCount:1,columns:1,flex:1,flexGrow:1,flexPositive:1,flexShrink:1,flexNegative:1,flexOrder:1,gridRow:1,gridRowEnd:1,gridRowSpan:1,gridRowStart:1,gridColumn:1,gridColumnEnd:1,gridColumnSpan:1,gridColumnStart:1,msGridRow:1,msGridRowSpan:1,msGridColumn:1,msGridColumnSpan:1,fontWeight:1,lineHeight:1,opacity:1,order:1,orphans:1,tabSize:1,widows:1,zIndex:1,zoom:1,WebkitLineClamp:1,fillOpacity:1,floodOpacity:1,stopOpacity:1,strokeDasharray:1,strokeDashoffset:1,strokeMiterlimit:1,strokeOpacity:1,strokeWidth:1},Mo=/^((children|dangerouslySetInnerHTML|key|ref|autoFocus|defaultValue|defaultChecked|innerHTML|suppressContentEditableWarning|suppressHydrationWarning|valueLink|accept|acceptCharset|accessKey|action|allow|allowUserMedia|allowPaymentRequest|allowFullScreen|allowTransparency|alt|async|autoComplete|autoPlay|capture|cellPadding|cellSpacing|challenge|charSet|checked|cite|classID|className|cols|colSpan|content|contentEditable|contextMenu|controls|controlsList|coords|crossOrigin|data|dateTime|decoding|default|defer|dir|disabled|disablePictureInPicture|download|draggable|encType|form|formAction|formEncType|formMethod|formNoValidate|formTarget|frameBorder|headers|height|hidden|high|href|hrefLang|htmlFor|httpEquiv|id|inputMode|integrity|is|keyParams|keyType|kind|label|lang|list|loading|loop|low|marginHeight|marginWidth|max|maxLength|media|mediaGroup|method|min|minLength|multiple|muted|name|nonce|noValidate|open|optimum|pattern|placeholder|playsInline|poster|preload|profile|radioGroup|readOnly|referrerPolicy|rel|required|reversed|role|rows|rowSpan|sandbox|scope|scoped|scrolling|seamless|selected|shape|size|sizes|slot|span|spellCheck|src|srcDoc|srcLang|srcSet|start|step|style|summary|tabIndex|target|title|type|useMap|value|width|wmode|wrap|about|datatype|inlist|prefix|property|resource|typeof|vocab|autoCapitalize|autoCorrect|autoSave|color|inert|itemProp|itemScope|itemType|itemID|itemRef|on|results|security|unselectable|accentHeight|accumulate|additive|alignmentBaseline|allowReorder|alphabetic|amplitude|arabicForm|ascent|attributeName|attributeType|autoReverse|azimuth|baseFrequency|baselineShift|baseProfile|bbox|begin|bias|by|calcMode|capHeight|clip|clipPathUnits|clipPath|clipRule|colorInterpolation|colorInterpolationFilters|colorProfile|colorRendering|contentScriptType|contentStyleType|cursor|cx|cy|d|decelerate|descent|diffuseConstant|direction|display|divisor|dominantBaseline|dur|dx|dy|edgeMode|elevation|enableBackground|end|exponent|externalResourcesRequired|fill|fillOpacity|fillRule|filter|filterRes|filterUnits|floodColor|floodOpacity|focusable|fontFamily|fontSize|fontSizeAdjust|fontStretch|fontStyle|fontVariant|fontWeight|format|from|fr|fx|fy|g1|g2|glyphName|glyphOrientationHorizontal|glyphOrientationVertical|glyphRef|gradientTransform|gradientUnits|hanging|horizAdvX|horizOriginX|ideographic|imageRendering|in|in2|intercept|k|k1|k2|k3|k4|kernelMatrix|kernelUnitLength|kerning|keyPoints|keySplines|keyTimes|lengthAdjust|letterSpacing|lightingColor|limitingConeAngle|local|markerEnd|markerMid|markerStart|markerHeight|markerUnits|markerWidth|mask|maskContentUnits|maskUnits|mathematical|mode|numOctaves|offset|opacity|operator|order|orient|orientation|origin|overflow|overlinePosition|overlineThickness|panose1|paintOrder|pathLength|patternContentUnits|patternTransform|patternUnits|pointerEvents|points|pointsAtX|pointsAtY|pointsAtZ|preserveAlpha|preserveAspectRatio|primitiveUnits|r|radius|refX|refY|renderingIntent|repeatCount|repeatDur|requiredExtensions|requiredFeatures|restart|result|rotate|rx|ry|scale|seed|shapeRendering|slope|spacing|specularConstant|specularExponent|speed|spreadMethod|startOffset|stdDeviation|stemh|stemv|stitchTiles|stopColor|stopOpacity|strikethroughPosition|strikethroughThickness|string|stroke|strokeDasharray|strokeDashoffset|strokeLinecap|strokeLinejoin|strokeMiterlimit|strokeOpacity|strokeWidth|surfaceScale|systemLanguage|tableValues|targetX|targetY|textAnchor|textDecoration|textRendering|textLength|to|transform|u1|u2|underlinePosition|underlineThickness|unicode|unicodeBidi|unicodeRange|unitsPerEm|vAlphabetic|vHanging|vIdeographic|vMathematical|values|vectorEffect|version|vertAdvY|vertOriginX|vertOriginY|viewBox|viewTarget|visibility|widths|wordSpacing|writingMode|x|xHeight|x1|x2|xChannelSelector|xlinkActuate|xlinkArcrole|xlinkHref|xlinkRole|xlinkShow|xlinkTitle|xlinkType|xmlBase|xmlns|xmlnsXlink|xmlLang|xmlSpace|y|y1|y2|yChannelSelector|z|zoomAndPan|for|class|autofocus)|(([Dd][Aa][Tt][Aa]|[Aa][Rr][Ii][Aa]|x)-.*))$/,Do=(To={},function(e){return void 0===To[e]&&(To[e]=(t=e,Mo.test(t)||111===t.charCodeAt(0)&&110===t.charCodeAt(1)&&t.charCodeAt(2)<91)),To[e];var t}),Fo=r(8679),zo=r.n(Fo);function Uo(){return(Uo=Object.assign||function(e){for(var t=1;t<arguments.length;t++){var n=arguments[t];for(var r in n)Object.prototype.hasOwnProperty.call(n,r)&&(e[r]=n[r])}return e}).apply(this,arguments)}var Bo=function(e,t){for(var n=[e[0]],r=0,o=t.length;r<o;r+=1)n.push(t[r],e[r+1]);return n},$o=function(e){return null!==e&&"object"==typeof e&&"[object Object]"===(e.toString?e.toString():Object.prototype.toString.call(e))&&!(0,Po.typeOf)(e)},qo=Object.freeze([]),Wo=Object.freeze({});function Vo(e){return"function"==typeof e}function Ho(e){return e.displayName||e.name||"Component"}function Yo(e){return e&&"string"==typeof e.styledComponentId}var Go="undefined"!=typeof process&&({}.REACT_APP_SC_ATTR||{}.SC_ATTR)||"data-styled",Qo="5.3.0",Xo="undefined"!=typeof window&&"HTMLElement"in window,Ko=Boolean("boolean"==typeof SC_DISABLE_SPEEDY?SC_DISABLE_SPEEDY:"undefined"!=typeof process&&void 0!=={}.REACT_APP_SC_DISABLE_SPEEDY&&""!=={}.REACT_APP_SC_DISABLE_SPEEDY?"false"!=={}.REACT_APP_SC_DISABLE_SPEEDY&&{}.REACT_APP_SC_DISABLE_SPEEDY:"undefined"!=typeof process&&void 0!=={}.SC_DISABLE_SPEEDY&&""!=={}.SC_DISABLE_SPEEDY&&"false"!=={}.SC_DISABLE_SPEEDY&&{}.SC_DISABLE_SPEEDY),Zo={};function Jo(e){for(var t=arguments.length,n=new Array(t>1?t-1:0),r=1;r<t;r++)n[r-1]=arguments[r];throw new Error("An error occurred. See https://git.io/JUIaE#"+e+" for more information."+(n.length>0?" Args: "+n.join(", "):""))}var ei=function(){function e(e){this.groupSizes=new Uint32Array(512),this.length=512,this.tag=e}var t=e.prototype;return t.indexOfGroup=function(e){for(var t=0,n=0;n<e;n++)t+=this.groupSizes[n];return t},t.insertRules=function(e,t){if(e>=this.groupSizes.length){for(var n=this.groupSizes,r=n.length,o=r;e>=o;)(o<<=1)<0&&Jo(16,""+e);this.groupSizes=new Uint32Array(o),this.groupSizes.set(n),this.length=o;for(var i=r;i<o;i++)this.groupSizes[i]=0}for(var a=this.indexOfGroup(e+1),s=0,l=t.length;s<l;s++)this.tag.insertRule(a,t[s])&&(this.groupSizes[e]++,a++)},t.clearGroup=function(e){if(e<this.length){var t=this.groupSizes[e],n=this.indexOfGroup(e),r=n+t;this.groupSizes[e]=0;for(var o=n;o<r;o++)this.tag.deleteRule(n)}},t.getGroup=function(e){var t="";if(e>=this.length||0===this.groupSizes[e])return t;for(var n=this.groupSizes[e],r=this.indexOfGroup(e),o=r+n,i=r;i<o;i++)t+=this.tag.getRule(i)+"/*!sc*/\n";return t},e}(),ti=new Map,ni=new Map,ri=1,oi=function(e){if(ti.has(e))return ti.get(e);for(;ni.has(ri);)ri++;var t=ri++;return ti.set(e,t),ni.set(t,e),t},ii=function(e){return ni.get(e)},ai=function(e,t){ti.set(e,t),ni.set(t,e)},si="style["+Go+'][data-styled-version="5.3.0"]',li=new RegExp("^"+Go+'\\.g(\\d+)\\[id="([\\w\\d-]+)"\\].*?"([^"]*)'),ci=function(e,t,n){for(var r,o=n.split(","),i=0,a=o.length;i<a;i++)(r=o[i])&&e.registerName(t,r)},ui=function(e,t){for(var n=t.innerHTML.split("/*!sc*/\n"),r=[],o=0,i=n.length;o<i;o++){var a=n[o].trim();if(a){var s=a.match(li);if(s){var l=0|parseInt(s[1],10),c=s[2];0!==l&&(ai(c,l),ci(e,c,s[3]),e.getTag().insertRules(l,r)),r.length=0}else r.push(a)}}},pi=function(){return"undefined"!=typeof window&&void 0!==window.__webpack_nonce__?window.__webpack_nonce__:null},fi=function(e){var t=document.head,n=e||t,r=document.createElement("style"),o=function(e){for(var t=e.childNodes,n=t.length;n>=0;n--){var r=t[n];if(r&&1===r.nodeType&&r.hasAttribute(Go))return r}}(n),i=void 0!==o?o.nextSibling:null;r.setAttribute(Go,"active"),r.setAttribute("data-styled-version","5.3.0");var a=pi();return a&&r.setAttribute("nonce",a),n.insertBefore(r,i),r},di=function(){function e(e){var t=this.element=fi(e);t.appendChild(document.createTextNode("")),this.sheet=function(e){if(e.sheet)return e.sheet;for(var t=document.styleSheets,n=0,r=t.length;n<r;n++){var o=t[n];if(o.ownerNode===e)return o}Jo(17)}(t),this.length=0}var t=e.prototype;return t.insertRule=function(e,t){try{return this.sheet.insertRule(t,e),this.length++,!0}catch(e){return!1}},t.deleteRule=function(e){this.sheet.deleteRule(e),this.length--},t.getRule=function(e){var t=this.sheet.cssRules[e];return void 0!==t&&"string"==typeof t.cssText?t.cssText:""},e}(),hi=function(){function e(e){var t=this.element=fi(e);this.nodes=t.childNodes,this.length=0}var t=e.prototype;return t.insertRule=function(e,t){if(e<=this.length&&e>=0){var n=document.createTextNode(t),r=this.nodes[e];return this.element.insertBefore(n,r||null),this.length++,!0}return!1},t.deleteRule=function(e){this.element.removeChild(this.nodes[e]),this.length--},t.getRule=function(e){return e<this.length?this.nodes[e].textContent:""},e}(),mi=function(){function e(e){this.rules=[],this.length=0}var t=e.prototype;return t.insertRule=function(e,t){return e<=this.length&&(this.rules.splice(e,0,t),this.length++,!0)},t.deleteRule=function(e){this.rules.splice(e,1),this.length--},t.getRule=function(e){return e<this.length?this.rules[e]:""},e}(),vi=Xo,gi={isServer:!Xo,useCSSOMInjection:!Ko},yi=function(){function e(e,t,n){void 0===e&&(e=Wo),void 0===t&&(t={}),this.options=Uo({},gi,{},e),this.gs=t,this.names=new Map(n),!this.options.isServer&&Xo&&vi&&(vi=!1,function(e){for(var t=document.querySelectorAll(si),n=0,r=t.length;n<r;n++){var o=t[n];o&&"active"!==o.getAttribute(Go)&&(ui(e,o),o.parentNode&&o.parentNode.removeChild(o))}}(this))}e.registerId=function(e){return oi(e)};var t=e.prototype;return t.reconstructWithOptions=function(t,n){return void 0===n&&(n=!0),new e(Uo({},this.options,{},t),this.gs,n&&this.names||void 0)},t.allocateGSInstance=function(e){return this.gs[e]=(this.gs[e]||0)+1},t.getTag=function(){return this.tag||(this.tag=(n=(t=this.options).isServer,r=t.useCSSOMInjection,o=t.target,e=n?new mi(o):r?new di(o):new hi(o),new ei(e)));var e,t,n,r,o},t.hasNameForId=function(e,t){return this.names.has(e)&&this.names.get(e).has(t)},t.registerName=function(e,t){if(oi(e),this.names.has(e))this.names.get(e).add(t);else{var n=new Set;n.add(t),this.names.set(e,n)}},t.insertRules=function(e,t,n){this.registerName(e,t),this.getTag().insertRules(oi(e),n)},t.clearNames=function(e){this.names.has(e)&&this.names.get(e).clear()},t.clearRules=function(e){this.getTag().clearGroup(oi(e)),this.clearNames(e)},t.clearTag=function(){this.tag=void 0},t.toString=function(){return function(e){for(var t=e.getTag(),n=t.length,r="",o=0;o<n;o++){var i=ii(o);if(void 0!==i){var a=e.names.get(i),s=t.getGroup(o);if(void 0!==a&&0!==s.length){var l=Go+".g"+o+'[id="'+i+'"]',c="";void 0!==a&&a.forEach((function(e){e.length>0&&(c+=e+",")})),r+=""+s+l+'{content:"'+c+'"}/*!sc*/\n'}}}return r}(this)},e}(),bi=/(a)(d)/gi,xi=function(e){return String.fromCharCode(e+(e>25?39:97))};function wi(e){var t,n="";for(t=Math.abs(e);t>52;t=t/52|0)n=xi(t%52)+n;return(xi(t%52)+n).replace(bi,"$1-$2")}var ki=function(e,t){for(var n=t.length;n;)e=33*e^t.charCodeAt(--n);return e},Ei=function(e){return ki(5381,e)};function Si(e){for(var t=0;t<e.length;t+=1){var n=e[t];if(Vo(n)&&!Yo(n))return!1}return!0}var _i=Ei("5.3.0"),Oi=function(){function e(e,t,n){this.rules=e,this.staticRulesId="",this.isStatic=(void 0===n||n.isStatic)&&Si(e),this.componentId=t,this.baseHash=ki(_i,t),this.baseStyle=n,yi.registerId(t)}return e.prototype.generateAndInjectStyles=function(e,t,n){var r=this.componentId,o=[];if(this.baseStyle&&o.push(this.baseStyle.generateAndInjectStyles(e,t,n)),this.isStatic&&!n.hash)if(this.staticRulesId&&t.hasNameForId(r,this.staticRulesId))o.push(this.staticRulesId);else{var i=Vi(this.rules,e,t,n).join(""),a=wi(ki(this.baseHash,i.length)>>>0);if(!t.hasNameForId(r,a)){var s=n(i,"."+a,void 0,r);t.insertRules(r,a,s)}o.push(a),this.staticRulesId=a}else{for(var l=this.rules.length,c=ki(this.baseHash,n.hash),u="",p=0;p<l;p++){var f=this.rules[p];if("string"==typeof f)u+=f;else if(f){var d=Vi(f,e,t,n),h=Array.isArray(d)?d.join(""):d;c=ki(c,h+p),u+=h}}if(u){var m=wi(c>>>0);if(!t.hasNameForId(r,m)){var v=n(u,"."+m,void 0,r);t.insertRules(r,m,v)}o.push(m)}}return o.join(" ")},e}(),Ai=/^\s*\/\/.*$/gm,Ri=[":","[",".","#"];function Ci(e){var t,n,r,o,i=void 0===e?Wo:e,a=i.options,s=void 0===a?Wo:a,l=i.plugins,c=void 0===l?qo:l,u=new Lo(s),p=[],f=function(e){function t(t){if(t)try{e(t+"}")}catch(e){}}return function(n,r,o,i,a,s,l,c,u,p){switch(n){case 1:if(0===u&&64===r.charCodeAt(0))return e(r+";"),"";break;case 2:if(0===c)return r+"/*|*/";break;case 3:switch(c){case 102:case 112:return e(o[0]+r),"";default:return r+(0===p?"/*|*/":"")}case-2:r.split("/*|*/}").forEach(t)}}}((function(e){p.push(e)})),d=function(e,r,i){return 0===r&&-1!==Ri.indexOf(i[n.length])||i.match(o)?e:"."+t};function h(e,i,a,s){void 0===s&&(s="&");var l=e.replace(Ai,""),c=i&&a?a+" "+i+" { "+l+" }":l;return t=s,n=i,r=new RegExp("\\"+n+"\\b","g"),o=new RegExp("(\\"+n+"\\b){2,}"),u(a||!i?"":i,c)}return u.use([].concat(c,[function(e,t,o){2===e&&o.length&&o[0].lastIndexOf(n)>0&&(o[0]=o[0].replace(r,d))},f,function(e){if(-2===e){var t=p;return p=[],t}}])),h.hash=c.length?c.reduce((function(e,t){return t.name||Jo(15),ki(e,t.name)}),5381).toString():"",h}var Ti=s.createContext(),Pi=Ti.Consumer,Ii=s.createContext(),ji=(Ii.Consumer,new yi),Li=Ci();function Ni(){return(0,s.useContext)(Ti)||ji}function Mi(){return(0,s.useContext)(Ii)||Li}function Di(e){var t=(0,s.useState)(e.stylisPlugins),n=t[0],r=t[1],o=Ni(),i=(0,s.useMemo)((function(){var t=o;return e.sheet?t=e.sheet:e.target&&(t=t.reconstructWithOptions({target:e.target},!1)),e.disableCSSOMInjection&&(t=t.reconstructWithOptions({useCSSOMInjection:!1})),t}),[e.disableCSSOMInjection,e.sheet,e.target]),a=(0,s.useMemo)((function(){return Ci({options:{prefix:!e.disableVendorPrefixes},plugins:n})}),[e.disableVendorPrefixes,n]);return(0,s.useEffect)((function(){jo()(n,e.stylisPlugins)||r(e.stylisPlugins)}),[e.stylisPlugins]),s.createElement(Ti.Provider,{value:i},s.createElement(Ii.Provider,{value:a},e.children))}var Fi=function(){function e(e,t){var n=this;this.inject=function(e,t){void 0===t&&(t=Li);var r=n.name+t.hash;e.hasNameForId(n.id,r)||e.insertRules(n.id,r,t(n.rules,r,"@keyframes"))},this.toString=function(){return Jo(12,String(n.name))},this.name=e,this.id="sc-keyframes-"+e,this.rules=t}return e.prototype.getName=function(e){return void 0===e&&(e=Li),this.name+e.hash},e}(),zi=/([A-Z])/,Ui=/([A-Z])/g,Bi=/^ms-/,$i=function(e){return"-"+e.toLowerCase()};function qi(e){return zi.test(e)?e.replace(Ui,$i).replace(Bi,"-ms-"):e}var Wi=function(e){return null==e||!1===e||""===e};function Vi(e,t,n,r){if(Array.isArray(e)){for(var o,i=[],a=0,s=e.length;a<s;a+=1)""!==(o=Vi(e[a],t,n,r))&&(Array.isArray(o)?i.push.apply(i,o):i.push(o));return i}return Wi(e)?"":Yo(e)?"."+e.styledComponentId:Vo(e)?"function"!=typeof(l=e)||l.prototype&&l.prototype.isReactComponent||!t?e:Vi(e(t),t,n,r):e instanceof Fi?n?(e.inject(n,r),e.getName(r)):e:$o(e)?function e(t,n){var r,o,i=[];for(var a in t)t.hasOwnProperty(a)&&!Wi(t[a])&&($o(t[a])?i.push.apply(i,e(t[a],a)):Vo(t[a])?i.push(qi(a)+":",t[a],";"):i.push(qi(a)+": "+(r=a,(null==(o=t[a])||"boolean"==typeof o||""===o?"":"number"!=typeof o||0===o||r in No?String(o).trim():o+"px")+";")));return n?[n+" {"].concat(i,["}"]):i}(e):e.toString();var l}function Hi(e){for(var t=arguments.length,n=new Array(t>1?t-1:0),r=1;r<t;r++)n[r-1]=arguments[r];return Vo(e)||$o(e)?Vi(Bo(qo,[e].concat(n))):0===n.length&&1===e.length&&"string"==typeof e[0]?e:Vi(Bo(e,n))}new Set;var Yi=function(e,t,n){return void 0===n&&(n=Wo),e.theme!==n.theme&&e.theme||t||n.theme},Gi=/[!"#$%&'()*+,./:;<=>?@[\\\]^`{|}~-]+/g,Qi=/(^-|-$)/g;function Xi(e){return e.replace(Gi,"-").replace(Qi,"")}var Ki=function(e){return wi(Ei(e)>>>0)};function Zi(e){return"string"==typeof e&&!0}var Ji=function(e){return"function"==typeof e||"object"==typeof e&&null!==e&&!Array.isArray(e)},ea=function(e){return"__proto__"!==e&&"constructor"!==e&&"prototype"!==e};function ta(e,t,n){var r=e[n];Ji(t)&&Ji(r)?na(r,t):e[n]=t}function na(e){for(var t=arguments.length,n=new Array(t>1?t-1:0),r=1;r<t;r++)n[r-1]=arguments[r];for(var o=0,i=n;o<i.length;o++){var a=i[o];if(Ji(a))for(var s in a)ea(s)&&ta(e,a[s],s)}return e}var ra=s.createContext(),oa=ra.Consumer;function ia(e){var t=(0,s.useContext)(ra),n=(0,s.useMemo)((function(){return function(e,t){return e?Vo(e)?e(t):Array.isArray(e)||"object"!=typeof e?Jo(8):t?Uo({},t,{},e):e:Jo(14)}(e.theme,t)}),[e.theme,t]);return e.children?s.createElement(ra.Provider,{value:n},e.children):null}var aa={};function sa(e,t,n){var r=Yo(e),o=!Zi(e),i=t.attrs,a=void 0===i?qo:i,l=t.componentId,c=void 0===l?function(e,t){var n="string"!=typeof e?"sc":Xi(e);aa[n]=(aa[n]||0)+1;var r=n+"-"+Ki("5.3.0"+n+aa[n]);return t?t+"-"+r:r}(t.displayName,t.parentComponentId):l,u=t.displayName,p=void 0===u?function(e){return Zi(e)?"styled."+e:"Styled("+Ho(e)+")"}(e):u,f=t.displayName&&t.componentId?Xi(t.displayName)+"-"+t.componentId:t.componentId||c,d=r&&e.attrs?Array.prototype.concat(e.attrs,a).filter(Boolean):a,h=t.shouldForwardProp;r&&e.shouldForwardProp&&(h=t.shouldForwardProp?function(n,r,o){return e.shouldForwardProp(n,r,o)&&t.shouldForwardProp(n,r,o)}:e.shouldForwardProp);var m,v=new Oi(n,f,r?e.componentStyle:void 0),g=v.isStatic&&0===a.length,y=function(e,t){return function(e,t,n,r){var o=e.attrs,i=e.componentStyle,a=e.defaultProps,l=e.foldedComponentIds,c=e.shouldForwardProp,u=e.styledComponentId,p=e.target,f=function(e,t,n){void 0===e&&(e=Wo);var r=Uo({},t,{theme:e}),o={};return n.forEach((function(e){var t,n,i,a=e;for(t in Vo(a)&&(a=a(r)),a)r[t]=o[t]="className"===t?(n=o[t],i=a[t],n&&i?n+" "+i:n||i):a[t]})),[r,o]}(Yi(t,(0,s.useContext)(ra),a)||Wo,t,o),d=f[0],h=f[1],m=function(e,t,n,r){var o=Ni(),i=Mi();return t?e.generateAndInjectStyles(Wo,o,i):e.generateAndInjectStyles(n,o,i)}(i,r,d),v=n,g=h.$as||t.$as||h.as||t.as||p,y=Zi(g),b=h!==t?Uo({},t,{},h):t,x={};for(var w in b)"$"!==w[0]&&"as"!==w&&("forwardedAs"===w?x.as=b[w]:(c?c(w,Do,g):!y||Do(w))&&(x[w]=b[w]));return t.style&&h.style!==t.style&&(x.style=Uo({},t.style,{},h.style)),x.className=Array.prototype.concat(l,u,m!==u?m:null,t.className,h.className).filter(Boolean).join(" "),x.ref=v,(0,s.createElement)(g,x)}(m,e,t,g)};return y.displayName=p,(m=s.forwardRef(y)).attrs=d,m.componentStyle=v,m.displayName=p,m.shouldForwardProp=h,m.foldedComponentIds=r?Array.prototype.concat(e.foldedComponentIds,e.styledComponentId):qo,m.styledComponentId=f,m.target=r?e.target:e,m.withComponent=function(e){var r=t.componentId,o=function(e,t){if(null==e)return{};var n,r,o={},i=Object.keys(e);for(r=0;r<i.length;r++)n=i[r],t.indexOf(n)>=0||(o[n]=e[n]);return o}(t,["componentId"]),i=r&&r+"-"+(Zi(e)?e:Xi(Ho(e)));return sa(e,Uo({},o,{attrs:d,componentId:i}),n)},Object.defineProperty(m,"defaultProps",{get:function(){return this._foldedDefaultProps},set:function(t){this._foldedDefaultProps=r?na({},e.defaultProps,t):t}}),m.toString=function(){return"."+m.styledComponentId},o&&zo()(m,e,{attrs:!0,componentStyle:!0,displayName:!0,foldedComponentIds:!0,shouldForwardProp:!0,styledComponentId:!0,target:!0,withComponent:!0}),m}var la=function(e){return function e(t,n,r){if(void 0===r&&(r=Wo),!(0,Po.isValidElementType)(n))return Jo(1,String(n));var o=function(){return t(n,r,Hi.apply(void 0,arguments))};return o.withConfig=function(o){return e(t,n,Uo({},r,{},o))},o.attrs=function(o){return e(t,n,Uo({},r,{attrs:Array.prototype.concat(r.attrs,o).filter(Boolean)}))},o}(sa,e)};["a","abbr","address","area","article","aside","audio","b","base","bdi","bdo","big","blockquote","body","br","button","canvas","caption","cite","code","col","colgroup","data","datalist","dd","del","details","dfn","dialog","div","dl","dt","em","embed","fieldset","figcaption","figure","footer","form","h1","h2","h3","h4","h5","h6","head","header","hgroup","hr","html","i","iframe","img","input","ins","kbd","keygen","label","legend","li","link","main","map","mark","marquee","menu","menuitem","meta","meter","nav","noscript","object","ol","optgroup","option","output","p","param","picture","pre","progress","q","rp","rt","ruby","s","samp","script","section","select","small","source","span","strong","style","sub","summary","sup","table","tbody","td","textarea","tfoot","th","thead","time","title","tr","track","u","ul","var","video","wbr","circle","clipPath","defs","ellipse","foreignObject","g","image","line","linearGradient","marker","mask","path","pattern","polygon","polyline","radialGradient","rect","stop","svg","text","textPath","tspan"].forEach((function(e){la[e]=la(e)}));var ca=function(){function e(e,t){this.rules=e,this.componentId=t,this.isStatic=Si(e),yi.registerId(this.componentId+1)}var t=e.prototype;return t.createStyles=function(e,t,n,r){var o=r(Vi(this.rules,t,n,r).join(""),""),i=this.componentId+e;n.insertRules(i,i,o)},t.removeStyles=function(e,t){t.clearRules(this.componentId+e)},t.renderStyles=function(e,t,n,r){e>2&&yi.registerId(this.componentId+e),this.removeStyles(e,n),this.createStyles(e,t,n,r)},e}();function ua(e){for(var t=arguments.length,n=new Array(t>1?t-1:0),r=1;r<t;r++)n[r-1]=arguments[r];var o=Hi.apply(void 0,[e].concat(n)),i="sc-global-"+Ki(JSON.stringify(o)),a=new ca(o,i);function l(e){var t=Ni(),n=Mi(),r=(0,s.useContext)(ra),o=(0,s.useRef)(t.allocateGSInstance(i)).current;return(0,s.useLayoutEffect)((function(){return c(o,e,t,r,n),function(){return a.removeStyles(o,t)}}),[o,e,t,r,n]),null}function c(e,t,n,r,o){if(a.isStatic)a.renderStyles(e,Zo,n,o);else{var i=Uo({},t,{theme:Yi(t,r,l.defaultProps)});a.renderStyles(e,i,n,o)}}return s.memo(l)}function pa(e){for(var t=arguments.length,n=new Array(t>1?t-1:0),r=1;r<t;r++)n[r-1]=arguments[r];var o=Hi.apply(void 0,[e].concat(n)).join(""),i=Ki(o);return new Fi(i,o)}var fa,da,ha=function(){function e(){var e=this;this._emitSheetCSS=function(){var t=e.instance.toString(),n=pi();return"<style "+[n&&'nonce="'+n+'"',Go+'="true"','data-styled-version="5.3.0"'].filter(Boolean).join(" ")+">"+t+"</style>"},this.getStyleTags=function(){return e.sealed?Jo(2):e._emitSheetCSS()},this.getStyleElement=function(){var t;if(e.sealed)return Jo(2);var n=((t={})[Go]="",t["data-styled-version"]="5.3.0",t.dangerouslySetInnerHTML={__html:e.instance.toString()},t),r=pi();return r&&(n.nonce=r),[s.createElement("style",Uo({},n,{key:"sc-0-0"}))]},this.seal=function(){e.sealed=!0},this.instance=new yi({isServer:!0}),this.sealed=!1}var t=e.prototype;return t.collectStyles=function(e){return this.sealed?Jo(2):s.createElement(Di,{sheet:this.instance},e)},t.interleaveWithNodeStream=function(e){return Jo(3)},e}(),ma=function(e){var t=s.forwardRef((function(t,n){var r=(0,s.useContext)(ra),o=e.defaultProps,i=Yi(t,r,o);return s.createElement(e,Uo({},t,{theme:i,ref:n}))}));return zo()(t,e),t.displayName="WithTheme("+Ho(e)+")",t},va=function(){return(0,s.useContext)(ra)},ga={StyleSheet:yi,masterSheet:ji},ya=la,ba=e.css,xa=e.createGlobalStyle,wa=e.keyframes,ka=e.ThemeProvider,Ea=function(e,t,n){return function(){return ba(fa||(fa=Co(["\n      @media "," screen and (max-width: ",") "," {\n        ",";\n      }\n    "])),t?"print, ":"",(function(t){return t.theme.breakpoints[e]}),n||"",ba.apply(void 0,arguments))}},Sa=e.default;function _a(e){return function(t){if(t.theme.extensionsHook)return t.theme.extensionsHook(e,t)}}var Oa,Aa,Ra,Ca=Sa.div(da||(da=Co(["\n  padding: 20px;\n  color: red;\n"]))),Ta=function(e){_o(n,e);var t=Ro(n);function n(e){var r;return nr(this,n),(r=t.call(this,e)).state={error:void 0},r}return or(n,[{key:"componentDidCatch",value:function(e){return this.setState({error:e}),!1}},{key:"render",value:function(){return this.state.error?s.createElement(Ca,null,s.createElement("h1",null,"Something went wrong..."),s.createElement("small",null," ",this.state.error.message," "),s.createElement("p",null,s.createElement("details",null,s.createElement("summary",null,"Stack trace"),s.createElement("pre",null,this.state.error.stack))),s.createElement("small",null," ReDoc Version: ","2.0.0-rc.59")," ",s.createElement("br",null),s.createElement("small",null," Commit: ","9f564d3")):s.Children.only(this.props.children)}}]),n}(s.Component),Pa=wa(Oa||(Oa=Co(["\n  0% {\n    transform: rotate(0deg); }\n  100% {\n    transform: rotate(360deg);\n  }\n"]))),Ia=Sa((function(e){return s.createElement("svg",{className:e.className,version:"1.1",width:"512",height:"512",viewBox:"0 0 512 512"},s.createElement("path",{d:"M275.682 147.999c0 10.864-8.837 19.661-19.682 19.661v0c-10.875 0-19.681-8.796-19.681-19.661v-96.635c0-10.885 8.806-19.661 19.681-19.661v0c10.844 0 19.682 8.776 19.682 19.661v96.635z"}),s.createElement("path",{d:"M275.682 460.615c0 10.865-8.837 19.682-19.682 19.682v0c-10.875 0-19.681-8.817-19.681-19.682v-96.604c0-10.885 8.806-19.681 19.681-19.681v0c10.844 0 19.682 8.796 19.682 19.682v96.604z"}),s.createElement("path",{d:"M147.978 236.339c10.885 0 19.681 8.755 19.681 19.641v0c0 10.885-8.796 19.702-19.681 19.702h-96.624c-10.864 0-19.661-8.817-19.661-19.702v0c0-10.885 8.796-19.641 19.661-19.641h96.624z"}),s.createElement("path",{d:"M460.615 236.339c10.865 0 19.682 8.755 19.682 19.641v0c0 10.885-8.817 19.702-19.682 19.702h-96.584c-10.885 0-19.722-8.817-19.722-19.702v0c0-10.885 8.837-19.641 19.722-19.641h96.584z"}),s.createElement("path",{d:"M193.546 165.703c7.69 7.66 7.68 20.142 0 27.822v0c-7.701 7.701-20.162 7.701-27.853 0.020l-68.311-68.322c-7.68-7.701-7.68-20.142 0-27.863v0c7.68-7.68 20.121-7.68 27.822 0l68.342 68.342z"}),s.createElement("path",{d:"M414.597 386.775c7.7 7.68 7.7 20.163 0.021 27.863v0c-7.7 7.659-20.142 7.659-27.843-0.062l-68.311-68.26c-7.68-7.7-7.68-20.204 0-27.863v0c7.68-7.7 20.163-7.7 27.842 0l68.291 68.322z"}),s.createElement("path",{d:"M165.694 318.464c7.69-7.7 20.153-7.7 27.853 0v0c7.68 7.659 7.69 20.163 0 27.863l-68.342 68.322c-7.67 7.659-20.142 7.659-27.822-0.062v0c-7.68-7.68-7.68-20.122 0-27.801l68.311-68.322z"}),s.createElement("path",{d:"M386.775 97.362c7.7-7.68 20.142-7.68 27.822 0v0c7.7 7.68 7.7 20.183 0.021 27.863l-68.322 68.311c-7.68 7.68-20.163 7.68-27.843-0.020v0c-7.68-7.68-7.68-20.162 0-27.822l68.322-68.332z"}))}))(Aa||(Aa=Co(["\n  animation: 2s "," linear infinite;\n  width: 50px;\n  height: 50px;\n  content: '';\n  display: inline-block;\n  margin-left: -25px;\n\n  path {\n    fill: ",";\n  }\n"])),Pa,(function(e){return e.color})),ja=Sa.div(Ra||(Ra=Co(["\n  font-family: helvetica, sans;\n  width: 100%;\n  text-align: center;\n  font-size: 25px;\n  margin: 30px 0 20px 0;\n  color: ",";\n"])),(function(e){return e.color})),La=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){return s.createElement("div",{style:{textAlign:"center"}},s.createElement(ja,{color:this.props.color},"Loading ..."),s.createElement(Ia,{color:this.props.color}))}}]),n}(s.PureComponent),Na=r(5697),Ma=s.createContext(new So({})),Da=Ma.Provider,Fa=Ma.Consumer;function za(e,t,n,r,o,i,a){try{var s=e[i](a),l=s.value}catch(e){return void n(e)}s.done?t(l):Promise.resolve(l).then(r,o)}function Ua(e){return function(){var t=this,n=arguments;return new Promise((function(r,o){var i=e.apply(t,n);function a(e){za(i,r,o,a,s,"next",e)}function s(e){za(i,r,o,a,s,"throw",e)}a(void 0)}))}}var Ba=r(7757),$a=r.n(Ba),qa=r(2840),Wa=r(7306),Va=r(6399);function Ha(e){return Ya.apply(this,arguments)}function Ya(){return(Ya=Ua($a().mark((function e(t){var n,o,i,a;return $a().wrap((function(e){for(;;)switch(e.prev=e.next){case 0:return n=new Wa.De({}),o={config:n,base:Jr?window.location.href:process.cwd()},Jr&&(n.resolve.http.customFetch=r.g.fetch),"object"==typeof t&&null!==t?o.doc={source:{absoluteRef:""},parsed:t}:o.ref=t,e.next=6,(0,qa.bundle)(o);case 6:return i=e.sent,a=i.bundle.parsed,e.abrupt("return",void 0!==a.swagger?Ga(a):a);case 9:case"end":return e.stop()}}),e)})))).apply(this,arguments)}function Ga(e){return console.warn("[ReDoc Compatibility mode]: Converting OpenAPI 2.0 to OpenAPI 3.0"),new Promise((function(t,n){return(0,Va.convertObj)(e,{patch:!0,warnOnly:!0,text:"{}",anchors:!0},(function(e,r){if(e)return n(e);t(r&&r.openapi)}))}))}function Qa(e,t,n,r,o){var i={};return Object.keys(r).forEach((function(e){i[e]=r[e]})),i.enumerable=!!i.enumerable,i.configurable=!!i.configurable,("value"in i||i.initializer)&&(i.writable=!0),i=n.slice().reverse().reduce((function(n,r){return r(e,t,n)||n}),i),o&&void 0!==i.initializer&&(i.value=i.initializer?i.initializer.call(o):void 0,i.initializer=void 0),void 0===i.initializer&&(Object.defineProperty(e,t,i),i=null),i}var Xa=r(1851),Ka=r(6729),Za=r(3573),Ja=r.n(Za),es=Za.parse,ts=function(){function e(){nr(this,e)}return or(e,null,[{key:"baseName",value:function(t){var n=arguments.length>1&&void 0!==arguments[1]?arguments[1]:1,r=e.parse(t);return r[r.length-n]}},{key:"dirName",value:function(t){var n=arguments.length>1&&void 0!==arguments[1]?arguments[1]:1,r=e.parse(t);return Za.compile(r.slice(0,r.length-n))}},{key:"relative",value:function(t,n){var r=e.parse(t);return e.parse(n).slice(r.length)}},{key:"parse",value:function(e){var t=e;return"#"===t.charAt(0)&&(t=t.substring(1)),es(t)}},{key:"join",value:function(t,n){var r=e.parse(t).concat(n);return Za.compile(r)}},{key:"get",value:function(e,t){return Za.get(e,t)}},{key:"compile",value:function(e){return Za.compile(e)}},{key:"escape",value:function(e){return Za.escape(e)}}]),e}();function ns(e){return function(e){if(Array.isArray(e))return no(e)}(e)||function(e){if("undefined"!=typeof Symbol&&null!=e[Symbol.iterator]||null!=e["@@iterator"])return Array.from(e)}(e)||ro(e)||function(){throw new TypeError("Invalid attempt to spread non-iterable instance.\nIn order to be iterable, non-array objects must have a [Symbol.iterator]() method.")}()}Za.parse=ts.parse,Object.assign(ts,Za),r(6699),r(2023),r(4723);var rs=r(6470),os=r(3578);function is(e){return"string"==typeof e&&/\dxx/i.test(e)}function as(e){var t=arguments.length>1&&void 0!==arguments[1]&&arguments[1];if("default"===e)return t?"error":"success";var n="string"==typeof e?parseInt(e,10):e;if(is(e)&&(n*=100),n<100||n>599)throw new Error("invalid HTTP code");var r="success";return n>=300&&n<400?r="redirect":n>=400?r="error":n<200&&(r="info"),r}var ss={get:!0,post:!0,put:!0,head:!0,patch:!0,delete:!0,options:!0,$ref:!0};function ls(e){return e in ss}function cs(e){return e.summary||e.operationId||e.description&&e.description.substring(0,50)||e.pathName||"<no summary>"}var us={multipleOf:"number",maximum:"number",exclusiveMaximum:"number",minimum:"number",exclusiveMinimum:"number",maxLength:"string",minLength:"string",pattern:"string",contentEncoding:"string",contentMediaType:"string",items:"array",maxItems:"array",minItems:"array",uniqueItems:"array",maxProperties:"object",minProperties:"object",required:"object",additionalProperties:"object",properties:"object"};function ps(e){var t=arguments.length>1&&void 0!==arguments[1]?arguments[1]:e.type;if(void 0!==e.oneOf||void 0!==e.anyOf)return!1;var n=!0,r=Array.isArray(t);return("object"===t||r&&null!=t&&t.includes("object"))&&(n=void 0!==e.properties?0===Object.keys(e.properties).length:void 0===e.additionalProperties),void 0!==e.items&&("array"===t||r&&null!=t&&t.includes("array"))&&(n=ps(e.items,e.items.type)),n}function fs(e){return-1!==e.search(/json/i)}function ds(e,t,n){return Array.isArray(e)?e.map((function(e){return e.toString()})).join(n):"object"==typeof e?Object.keys(e).map((function(t){return"".concat(t).concat(n).concat(e[t])})).join(n):t+"="+e.toString()}function hs(e,t){return Array.isArray(e)?(console.warn("deepObject style cannot be used with array value:"+e.toString()),""):"object"==typeof e?Object.keys(e).map((function(n){return"".concat(t,"[").concat(n,"]=").concat(e[n])})).join("&"):(console.warn("deepObject style cannot be used with non-object value:"+e.toString()),"")}function ms(e,t,r){var o="__redoc_param_name__",i=t?"*":"";return os.parse("{?".concat(o).concat(i,"}")).expand(n({},o,r)).substring(1).replace(/__redoc_param_name__/g,e)}function vs(e,t){return fs(t)?JSON.stringify(e):(console.warn("Parameter serialization as ".concat(t," is not supported")),"")}function gs(e){return/^#\/components\/(schemas|pathItems)\/[^\/]+$/.test(e||"")}function ys(e){if(e){var t=e.match(/^#\/components\/(schemas|pathItems)\/([^\/]+)$/);return null===t?void 0:t[1]}}function bs(e,t,n){var r;return void 0!==t&&void 0!==n?r=t===n?"".concat(t," ").concat(e):"[ ".concat(t," .. ").concat(n," ] ").concat(e):void 0!==n?r="<= ".concat(n," ").concat(e):void 0!==t&&(r=1===t?"non-empty":">= ".concat(t," ").concat(e)),r}function xs(e){var t=[],n=bs("characters",e.minLength,e.maxLength);void 0!==n&&t.push(n);var r=bs("items",e.minItems,e.maxItems);void 0!==r&&t.push(r);var o=function(e){if(void 0!==e){var t=e.toString(10);return/^0\.0*1$/.test(t)?"decimal places <= ".concat(t.split(".")[1].length):"multiple of ".concat(t)}}(e.multipleOf);void 0!==o&&t.push(o);var i=function(e){var t,n,r="number"==typeof e.exclusiveMinimum?Math.min(e.exclusiveMinimum,null!==(t=e.minimum)&&void 0!==t?t:1/0):e.minimum,o="number"==typeof e.exclusiveMaximum?Math.max(e.exclusiveMaximum,null!==(n=e.maximum)&&void 0!==n?n:-1/0):e.maximum,i="number"==typeof e.exclusiveMinimum||e.exclusiveMinimum,a="number"==typeof e.exclusiveMaximum||e.exclusiveMaximum;return void 0!==r&&void 0!==o?"".concat(i?"( ":"[ ").concat(r," .. ").concat(o).concat(a?" )":" ]"):void 0!==o?"".concat(a?"< ":"<= ").concat(o):void 0!==r?"".concat(i?"> ":">= ").concat(r):void 0}(e);return void 0!==i&&t.push(i),e.uniqueItems&&t.push("unique"),t}function ws(e){var t=arguments.length>1&&void 0!==arguments[1]?arguments[1]:[],n=[],r=[],o=[];return e.forEach((function(e){e.required?t.includes(e.name)?r.push(e):o.push(e):n.push(e)})),r.sort((function(e,n){return t.indexOf(e.name)-t.indexOf(n.name)})),[].concat(r,o,n)}function ks(e,t){return ns(e).sort((function(e,n){return e[t].localeCompare(n[t])}))}function Es(e,t){var n=void 0===e?function(e){try{var t=vo(e);return t.search="",t.toString()}catch(t){return e}}(function(){if(!Jr)return"";var e=window.location.href;return e.endsWith(".html")?(0,rs.dirname)(e):e}()):(0,rs.dirname)(e);return 0===t.length&&(t=[{url:"/"}]),t.map((function(e){return a(a({},e),{},{url:(t=e.url,function(e,t){var n;if(t.startsWith("//")){var r=(0,so.parse)(e).protocol;n="".concat(r||"https:").concat(t)}else if(function(e){return/(?:^[a-z][a-z0-9+.-]*:|\/\/)/i.test(e)}(t))n=t;else if(t.startsWith("/")){var o=(0,so.parse)(e);n=(0,so.format)(a(a({},o),{},{pathname:t}))}else n=uo(e)+"/"+t;return uo(n)}(n,t)),description:e.description||""});var t}))}var Ss="security-definitions",_s="SecurityDefinitions",Os="section/Authentication/";function As(e){Os=e}var Rs=function(e){return{delete:"del",options:"opts"}[e]||e};function Cs(e,t){return Object.keys(e).filter((function(e){return!0===t?e.startsWith("x-")&&!function(e){return e in{"x-circular-ref":!0,"x-code-samples":!0,"x-codeSamples":!0,"x-displayName":!0,"x-examples":!0,"x-ignoredHeaderParameters":!0,"x-logo":!0,"x-nullable":!0,"x-servers":!0,"x-tagGroups":!0,"x-traitTag":!0,"x-additionalPropertiesName":!0,"x-explicitMappingOnly":!0}}(e):e.startsWith("x-")&&t.indexOf(e)>-1})).reduce((function(t,n){return t[n]=e[n],t}),{})}var Ts=r(5660),Ps=(r(7874),r(4279),r(5433),r(6213),r(2731),r(9016),r(7046),r(57),r(2503),r(6841),r(6854),r(4335),r(1426),r(8246),r(9945),r(366),r(2939),r(9385),r(2886),r(5266),r(874),r(3358),"clike");function Is(e){return{json:"js","c++":"cpp","c#":"csharp","objective-c":"objectivec",shell:"bash",viml:"vim"}[e]||Ps}function js(e){var t=arguments.length>1&&void 0!==arguments[1]?arguments[1]:Ps;t=t.toLowerCase();var n=Ts.languages[t];return n||(n=Ts.languages[Is(t)]),Ts.highlight(e.toString(),n,t)}Ts.languages.insertBefore("javascript","string",{"property string":{pattern:/([{,]\s*)"(?:\\.|[^\\"\r\n])*"(?=\s*:)/i,lookbehind:!0}},void 0),Ts.languages.insertBefore("javascript","punctuation",{property:{pattern:/([{,]\s*)[a-z]\w*(?=\s*:)/i,lookbehind:!0}},void 0);var Ls,Ns={};function Ms(e,t,n){if("function"==typeof n.value)return function(e,t,n){if(!n.value||n.value.length>0)throw new Error("@memoize decorator can only be applied to methods of zero arguments");var r="_memoized_".concat(t),o=n.value;return e[r]=Ns,a(a({},n),{},{value:function(){return this[r]===Ns&&(this[r]=o.call(this)),this[r]}})}(e,t,n);if("function"==typeof n.get)return function(e,t,n){var r="_memoized_".concat(t),o=n.get;return e[r]=Ns,a(a({},n),{},{get:function(){return this[r]===Ns&&(this[r]=o.call(this)),this[r]}})}(e,t,n);throw new Error("@memoize decorator can be applied to methods or getters, got "+String(n.value)+" instead")}var Ds="hashchange",Fs=new(Qa((Ls=function(){function e(){var t=this;nr(this,e),n(this,"_emiter",void 0),n(this,"emit",(function(){t._emiter.emit(Ds,t.currentId)})),this._emiter=new Ka.EventEmitter,this.bind()}return or(e,[{key:"currentId",get:function(){return Jr?decodeURIComponent(window.location.hash.substring(1)):""}},{key:"linkForId",value:function(e){return e?"#"+e:""}},{key:"subscribe",value:function(e){var t=this._emiter.addListener(Ds,e);return function(){return t.removeListener(Ds,e)}}},{key:"bind",value:function(){Jr&&window.addEventListener("hashchange",this.emit,!1)}},{key:"dispose",value:function(){Jr&&window.removeEventListener("hashchange",this.emit)}},{key:"replace",value:function(e){var t=arguments.length>1&&void 0!==arguments[1]&&arguments[1];Jr&&null!=e&&e!==this.currentId&&(t?window.history.replaceState(null,"",window.location.href.split("#")[0]+this.linkForId(e)):(window.history.pushState(null,"",window.location.href.split("#")[0]+this.linkForId(e)),this.emit()))}}]),e}()).prototype,"replace",[Xa.bind,Xa.debounce],Object.getOwnPropertyDescriptor(Ls.prototype,"replace"),Ls.prototype),Ls),zs=r(813),Us=function(){function e(){nr(this,e),n(this,"map",new Map),n(this,"prevTerm","")}return or(e,[{key:"add",value:function(e){this.map.set(e,new zs(e))}},{key:"delete",value:function(e){this.map.delete(e)}},{key:"addOnly",value:function(e){var t=this;this.map.forEach((function(n,r){-1===e.indexOf(r)&&(n.unmark(),t.map.delete(r))}));var n,r=oo(e);try{for(r.s();!(n=r.n()).done;){var o=n.value;this.map.has(o)||this.map.set(o,new zs(o))}}catch(e){r.e(e)}finally{r.f()}}},{key:"clearAll",value:function(){this.unmark(),this.map.clear()}},{key:"mark",value:function(e){var t=this;(e||this.prevTerm)&&(this.map.forEach((function(n){n.unmark(),n.mark(e||t.prevTerm)})),this.prevTerm=e||this.prevTerm)}},{key:"unmark",value:function(){this.map.forEach((function(e){return e.unmark()})),this.prevTerm=""}}]),e}();function Bs(e,t,n,r){n&&Object.defineProperty(e,t,{enumerable:n.enumerable,configurable:n.configurable,writable:n.writable,value:n.initializer?n.initializer.call(r):void 0})}r(9826);var $s=r(7084),qs=new $s.Renderer;$s.setOptions({renderer:qs,highlight:function(e,t){return js(e,t)}});var Ws="(?:^ {0,3}\x3c!-- ReDoc-Inject:\\s+?<({component}).*?/?>\\s+?--\x3e\\s*$|(?:^ {0,3}<({component})([\\s\\S]*?)>([\\s\\S]*?)</\\2>|^ {0,3}<({component})([\\s\\S]*?)(?:/>|\\n{2,})))",Vs=function(){function e(t){var r=this;nr(this,e),this.options=t,n(this,"headings",[]),n(this,"currentTopHeading",void 0),n(this,"headingEnhanceRenderer",void 0),n(this,"originalHeadingRule",void 0),n(this,"headingRule",(function(e,t,n,o){return 1===t?r.currentTopHeading=r.saveHeading(e,t):2===t&&r.saveHeading(e,t,r.currentTopHeading&&r.currentTopHeading.items,r.currentTopHeading&&r.currentTopHeading.id),r.originalHeadingRule(e,t,n,o)})),this.headingEnhanceRenderer=new $s.Renderer,this.originalHeadingRule=this.headingEnhanceRenderer.heading.bind(this.headingEnhanceRenderer),this.headingEnhanceRenderer.heading=this.headingRule}return or(e,[{key:"saveHeading",value:function(e,t){var n=arguments.length>2&&void 0!==arguments[2]?arguments[2]:this.headings,r=arguments.length>3?arguments[3]:void 0;e=go(e);var o={id:r?"".concat(r,"/").concat(mo(e)):"section/".concat(mo(e)),name:e,level:t,items:[]};return n.push(o),o}},{key:"flattenHeadings",value:function(e){if(void 0===e)return[];var t,n=[],r=oo(e);try{for(r.s();!(t=r.n()).done;){var o=t.value;n.push(o),n.push.apply(n,ns(this.flattenHeadings(o.items)))}}catch(e){r.e(e)}finally{r.f()}return n}},{key:"attachHeadingsDescriptions",value:function(e){var t=function(e){return new RegExp("##?\\s+".concat(e.name.replace(/[-\/\\^$*+?.()|[\]{}]/g,"\\$&")))},n=this.flattenHeadings(this.headings);if(!(n.length<1)){for(var r=n[0],o=t(r),i=e.search(o),a=1;a<n.length;a++){var s=n[a],l=t(s),c=e.substr(i+1).search(l)+i+1;r.description=e.substring(i,c).replace(o,"").trim(),r=s,o=l,i=c}r.description=e.substring(i).replace(o,"").trim()}}},{key:"renderMd",value:function(e){var t=arguments.length>1&&void 0!==arguments[1]&&arguments[1],n=t?{renderer:this.headingEnhanceRenderer}:void 0,r=$s(e.toString(),n);return r}},{key:"extractHeadings",value:function(e){this.renderMd(e,!0),this.attachHeadingsDescriptions(e);var t=this.headings;return this.headings=[],t}},{key:"renderMdWithComponents",value:function(e){var t=this.options&&this.options.allowedMdComponents;if(!t||0===Object.keys(t).length)return[this.renderMd(e)];for(var n=Object.keys(t).join("|"),r=new RegExp(Ws.replace(/{component}/g,n),"mig"),o=[],i=[],s=r.exec(e),l=0;s;){o.push(e.substring(l,s.index)),l=r.lastIndex;var c=t[s[1]||s[2]||s[5]],u=s[3]||s[6],p=s[4];c&&i.push({component:c.component,propsSelector:c.propsSelector,props:a(a(a({},Hs(u)),c.props),{},{children:p})}),s=r.exec(e)}o.push(e.substring(l));for(var f=[],d=0;d<o.length;d++){var h=o[d];h&&f.push(this.renderMd(h)),i[d]&&f.push(i[d])}return f}}],[{key:"containsComponent",value:function(e,t){return new RegExp(Ws.replace(/{component}/g,t),"gmi").test(e)}},{key:"getTextBeforeHading",value:function(e,t){var n=e.search(new RegExp("^##?\\s+".concat(t),"m"));return n>-1?e.substring(0,n):e}}]),e}();function Hs(e){if(!e)return{};for(var t,n=/([\w-]+)\s*=\s*(?:{([^}]+?)}|"([^"]+?)")/gim,r={};null!==(t=n.exec(e));)if(t[3])r[t[1]]=t[3];else if(t[2]){var o=void 0;try{o=JSON.parse(t[2])}catch(e){}r[t[1]]=o}return r}var Ys,Gs,Qs,Xs,Ks=function(){function e(t){nr(this,e),this.parser=t,n(this,"title",void 0),n(this,"version",void 0),n(this,"description",void 0),n(this,"summary",void 0),n(this,"termsOfService",void 0),n(this,"contact",void 0),n(this,"license",void 0),n(this,"downloadLink",void 0),n(this,"downloadFileName",void 0),Object.assign(this,t.spec.info),this.description=t.spec.info.description||"",this.summary=t.spec.info.summary||"";var r=this.description.search(/^##?\s+/m);r>-1&&(this.description=this.description.substring(0,r)),this.downloadLink=this.getDownloadLink(),this.downloadFileName=this.getDownloadFileName()}return or(e,[{key:"getDownloadLink",value:function(){if(this.parser.specUrl)return this.parser.specUrl;if(Jr&&window.Blob&&window.URL&&window.URL.createObjectURL){var e=new Blob([JSON.stringify(this.parser.spec,null,2)],{type:"application/json"});return window.URL.createObjectURL(e)}}},{key:"getDownloadFileName",value:function(){if(!this.parser.specUrl)return"swagger.json"}}]),e}(),Zs=function e(t,r){nr(this,e),n(this,"schemes",void 0);var o=r.spec.components&&r.spec.components.securitySchemes||{};this.schemes=Object.keys(t||{}).map((function(e){var n=r.deref(o[e]),i=t[e]||[];if(n)return a(a({},n),{},{id:e,sectionId:Os+e,scopes:i});console.warn("Non existing security scheme referenced: ".concat(e,". Skipping"))})).filter((function(e){return void 0!==e}))},Js=(Gs=Qa((Ys=function(){function e(t,r,o,i,s){nr(this,e),Bs(this,"expanded",Gs,this),n(this,"name",void 0),n(this,"operations",[]),an(this),this.name=r;var l=t.deref(o);t.exitRef(o);for(var c=0,u=Object.keys(l);c<u.length;c++){var p,f=u[c],d=l[f],h=oo(Object.keys(d).filter(ls));try{for(h.s();!(p=h.n()).done;){var m=p.value,v=d[m],g=new rc(t,a(a({},v),{},{pathName:f,pointer:ts.compile([i,r,f,m]),httpVerb:m,pathParameters:d.parameters||[],pathServers:d.servers}),void 0,s,!0);this.operations.push(g)}}catch(e){h.e(e)}finally{h.f()}}}return or(e,[{key:"toggle",value:function(){this.expanded=!this.expanded}}]),e}()).prototype,"expanded",[Pe],{configurable:!0,enumerable:!0,writable:!0,initializer:function(){return!1}}),Qa(Ys.prototype,"toggle",[Tt],Object.getOwnPropertyDescriptor(Ys.prototype,"toggle"),Ys.prototype),Ys),el=(Xs=Qa((Qs=function(){function e(t,r,o,i){var a=arguments.length>4&&void 0!==arguments[4]&&arguments[4];nr(this,e),this.options=i,n(this,"pointer",void 0),n(this,"type",void 0),n(this,"displayType",void 0),n(this,"typePrefix",""),n(this,"title",void 0),n(this,"description",void 0),n(this,"externalDocs",void 0),n(this,"isPrimitive",void 0),n(this,"isCircular",!1),n(this,"format",void 0),n(this,"displayFormat",void 0),n(this,"nullable",void 0),n(this,"deprecated",void 0),n(this,"pattern",void 0),n(this,"example",void 0),n(this,"enum",void 0),n(this,"default",void 0),n(this,"readOnly",void 0),n(this,"writeOnly",void 0),n(this,"constraints",void 0),n(this,"fields",void 0),n(this,"items",void 0),n(this,"oneOf",void 0),n(this,"oneOfType",void 0),n(this,"discriminatorProp",void 0),Bs(this,"activeOneOf",Xs,this),n(this,"rawSchema",void 0),n(this,"schema",void 0),n(this,"extensions",void 0),n(this,"const",void 0),n(this,"contentEncoding",void 0),n(this,"contentMediaType",void 0),n(this,"minItems",void 0),n(this,"maxItems",void 0),an(this),this.pointer=r.$ref||o||"",this.rawSchema=t.deref(r,!1,!0),this.schema=t.mergeAllOf(this.rawSchema,this.pointer,a),this.init(t,a),t.exitRef(r),t.exitParents(this.schema),i.showExtensions&&(this.extensions=Cs(this.schema,i.showExtensions))}return or(e,[{key:"activateOneOf",value:function(e){this.activeOneOf=e}},{key:"hasType",value:function(e){return this.type===e||Array.isArray(this.type)&&this.type.includes(e)}},{key:"init",value:function(t,n){var r=this,o=this.schema;if(this.isCircular=o["x-circular-ref"],this.title=o.title||gs(this.pointer)&&ts.baseName(this.pointer)||"",this.description=o.description||"",this.type=o.type||function(e){if(void 0!==e.type&&!Array.isArray(e.type))return e.type;for(var t=0,n=Object.keys(us);t<n.length;t++){var r=n[t],o=us[r];if(void 0!==e[r])return o}return"any"}(o),this.format=o.format,this.enum=o.enum||[],this.example=o.example,this.deprecated=!!o.deprecated,this.pattern=o.pattern,this.externalDocs=o.externalDocs,this.constraints=xs(o),this.displayFormat=this.format,this.isPrimitive=ps(o,this.type),this.default=o.default,this.readOnly=!!o.readOnly,this.writeOnly=!!o.writeOnly,this.const=o.const||"",this.contentEncoding=o.contentEncoding,this.contentMediaType=o.contentMediaType,this.minItems=o.minItems,this.maxItems=o.maxItems,(o.nullable||o["x-nullable"])&&(Array.isArray(this.type)&&!this.type.some((function(e){return null===e||"null"===e}))?this.type=[].concat(ns(this.type),["null"]):Array.isArray(this.type)||null===this.type&&"null"===this.type||(this.type=[this.type,"null"])),this.displayType=Array.isArray(this.type)?this.type.map((function(e){return null===e?"null":e})).join(" or "):this.type,!this.isCircular)if(n||void 0===tl(o)){if(n&&Array.isArray(o.oneOf)&&o.oneOf.find((function(e){return e.$ref===r.pointer}))&&delete o.oneOf,void 0!==o.oneOf)return this.initOneOf(o.oneOf,t),this.oneOfType="One of",void(void 0!==o.anyOf&&console.warn("oneOf and anyOf are not supported on the same level. Skipping anyOf at ".concat(this.pointer)));if(void 0!==o.anyOf)return this.initOneOf(o.anyOf,t),void(this.oneOfType="Any of");if(this.hasType("object"))this.fields=function(e,t,n,r){var o=t.properties||{},i=t.additionalProperties,s=t.default,l=Object.keys(o||[]).map((function(i){var l=o[i];l||(console.warn('Field "'.concat(i,'" is invalid, skipping.\n Field must be an object but got ').concat(typeof l,' at "').concat(n,'"')),l={});var c=void 0!==t.required&&t.required.indexOf(i)>-1;return new sl(e,{name:i,required:c,schema:a(a({},l),{},{default:void 0===l.default&&s?s[i]:l.default})},n+"/properties/"+i,r)}));return r.sortPropsAlphabetically&&(l=ks(l,"name")),r.requiredPropsFirst&&(l=ws(l,r.sortPropsAlphabetically?void 0:t.required)),"object"!=typeof i&&!0!==i||l.push(new sl(e,{name:("object"==typeof i&&i["x-additionalPropertiesName"]||"property name").concat("*"),required:!1,schema:!0===i?{}:i,kind:"additionalProperties"},n+"/additionalProperties",r)),l}(t,o,this.pointer,this.options);else if(this.hasType("array")&&o.items&&(this.items=new e(t,o.items,this.pointer+"/items",this.options),this.displayType=this.items.displayType.split(" or ").map((function(e){return e.replace(/^(string|object|number|integer|array|boolean)s?( ?.*)/,"$1s$2")})).join(" or "),this.displayFormat=this.items.format,this.typePrefix=this.items.typePrefix+wo("arrayOf"),this.title=this.title||this.items.title,this.isPrimitive=this.items.isPrimitive,void 0===this.example&&void 0!==this.items.example&&(this.example=[this.items.example]),this.items.isPrimitive&&(this.enum=this.items.enum),Array.isArray(this.type))){var i=this.type.filter((function(e){return"array"!==e}));i.length&&(this.displayType+=" or ".concat(i.join(" or ")))}this.enum.length&&this.options.sortEnumValuesAlphabetically&&this.enum.sort()}else this.initDiscriminator(o,t)}},{key:"initOneOf",value:function(t,n){var r,o,i=this;if(this.oneOf=t.map((function(t,r){var o=n.deref(t,!1,!0),s=n.mergeAllOf(o,i.pointer+"/oneOf/"+r),l=gs(t.$ref)&&!s.title?ts.baseName(t.$ref):"".concat(s.title||"").concat(s.const&&JSON.stringify(s.const)||""),c=new e(n,a(a({},s),{},{title:l,allOf:[a(a({},i.schema),{},{oneOf:void 0,anyOf:void 0})]}),i.pointer+"/oneOf/"+r,i.options);return n.exitRef(t),n.exitParents(s),c})),this.options.simpleOneOfTypeLabel){var s=(r=this,o=new Set,function e(t){var n,r=oo(t.oneOf||[]);try{for(r.s();!(n=r.n()).done;){var i=n.value;i.oneOf?e(i):i.type&&o.add(i.type)}}catch(e){r.e(e)}finally{r.f()}}(r),Array.from(o.values()));this.displayType=s.join(" or ")}else this.displayType=this.oneOf.map((function(e){var t=e.typePrefix+(e.title?"".concat(e.title," (").concat(e.displayType,")"):e.displayType);return t.indexOf(" or ")>-1&&(t="(".concat(t,")")),t})).join(" or ")}},{key:"initDiscriminator",value:function(t,n){var r=this,o=tl(t);this.discriminatorProp=o.propertyName;var i=n.findDerived([].concat(ns(t.parentRefs||[]),[this.pointer]));if(t.oneOf){var s,l=oo(t.oneOf);try{for(l.s();!(s=l.n()).done;){var c=s.value;if(void 0!==c.$ref){var u=ts.baseName(c.$ref);i[c.$ref]=u}}}catch(e){l.e(e)}finally{l.f()}}var p=o.mapping||{},f=o["x-explicitMappingOnly"]||!1;0===Object.keys(p).length&&(f=!1);var d={};for(var h in p){var m=p[h];Array.isArray(d[m])?d[m].push(h):d[m]=[h]}for(var v=a(f?{}:a({},i),d),g=[],y=0,b=Object.keys(v);y<b.length;y++){var x=b[y],w=v[x];if(Array.isArray(w)){var k,E=oo(w);try{for(E.s();!(k=E.n()).done;){var S=k.value;g.push({$ref:x,name:S})}}catch(e){E.e(e)}finally{E.f()}}else g.push({$ref:x,name:w})}var _=Object.keys(p);0!==_.length&&(g=g.sort((function(e,t){var n=_.indexOf(e.name),r=_.indexOf(t.name);return n<0&&r<0?e.name.localeCompare(t.name):n<0?1:r<0?-1:n-r}))),this.oneOf=g.map((function(t){var o=t.$ref,i=t.name,a=new e(n,n.byRef(o),o,r.options,!0);return a.title=i,a}))}}]),e}()).prototype,"activeOneOf",[Pe],{configurable:!0,enumerable:!0,writable:!0,initializer:function(){return 0}}),Qa(Qs.prototype,"activateOneOf",[Tt],Object.getOwnPropertyDescriptor(Qs.prototype,"activateOneOf"),Qs.prototype),Qs);function tl(e){return e.discriminator||e["x-discriminator"]}var nl,rl,ol={},il=function(){function e(t,r,o,i){nr(this,e),this.mime=o,n(this,"value",void 0),n(this,"summary",void 0),n(this,"description",void 0),n(this,"externalValueUrl",void 0);var a=t.deref(r);this.value=a.value,this.summary=a.summary,this.description=a.description,a.externalValue&&(this.externalValueUrl=(0,so.resolve)(t.specUrl||"",a.externalValue)),t.exitRef(r),"application/x-www-form-urlencoded"===o&&this.value&&"object"==typeof this.value&&(this.value=function(e){var t=arguments.length>1&&void 0!==arguments[1]?arguments[1]:{};if(Array.isArray(e))throw new Error("Payload must have fields: "+e.toString());return Object.keys(e).map((function(n){var r=e[n],o=t[n]||{},i=o.style,a=void 0===i?"form":i,s=o.explode,l=void 0===s||s;switch(a){case"form":return ms(n,l,r);case"spaceDelimited":return ds(r,n,"%20");case"pipeDelimited":return ds(r,n,"|");case"deepObject":return hs(r,n);default:return console.warn("Incorrect or unsupported encoding style: "+a),""}})).join("&")}(this.value,i))}return or(e,[{key:"getExternalValue",value:function(e){return this.externalValueUrl?(ol[this.externalValueUrl]||(ol[this.externalValueUrl]=fetch(this.externalValueUrl).then((function(t){return t.text().then((function(n){if(!t.ok)return Promise.reject(new Error(n));if(!fs(e))return n;try{return JSON.parse(n)}catch(e){return n}}))}))),ol[this.externalValueUrl]):Promise.resolve(void 0)}}]),e}(),al={path:{style:"simple",explode:!1},query:{style:"form",explode:!0},header:{style:"simple",explode:!1},cookie:{style:"form",explode:!0}},sl=(rl=Qa((nl=function(){function e(t,r,o,i){var a;nr(this,e),Bs(this,"expanded",rl,this),n(this,"schema",void 0),n(this,"name",void 0),n(this,"required",void 0),n(this,"description",void 0),n(this,"example",void 0),n(this,"examples",void 0),n(this,"deprecated",void 0),n(this,"in",void 0),n(this,"kind",void 0),n(this,"extensions",void 0),n(this,"explode",void 0),n(this,"style",void 0),n(this,"const",void 0),n(this,"serializationMime",void 0),an(this);var s=t.deref(r);this.kind=r.kind||"field",this.name=r.name||s.name,this.in=s.in,this.required=!!s.required;var l,c,u=s.schema,p="";if(!u&&s.in&&s.content&&(p=Object.keys(s.content)[0],u=s.content[p]&&s.content[p].schema),this.schema=new el(t,u||{},o,i),this.description=void 0===s.description?this.schema.description||"":s.description,this.example=s.example||this.schema.example,void 0!==s.examples&&(this.examples=co(s.examples,(function(e,n){return new il(t,e,n,s.encoding)}))),p)this.serializationMime=p;else if(s.style)this.style=s.style;else if(this.in){var f,d;this.style=null!==(f=null===(d=al[this.in])||void 0===d?void 0:d.style)&&void 0!==f?f:"form"}void 0===s.explode&&this.in?this.explode=null===(l=null===(c=al[this.in])||void 0===c?void 0:c.explode)||void 0===l||l:this.explode=!!s.explode,this.deprecated=void 0===s.deprecated?!!this.schema.deprecated:s.deprecated,t.exitRef(r),i.showExtensions&&(this.extensions=Cs(s,i.showExtensions)),this.const=(null===(a=this.schema)||void 0===a?void 0:a.const)||(null==s?void 0:s.const)||""}return or(e,[{key:"toggle",value:function(){this.expanded=!this.expanded}}]),e}()).prototype,"expanded",[Pe],{configurable:!0,enumerable:!0,writable:!0,initializer:function(){return!1}}),Qa(nl.prototype,"toggle",[Tt],Object.getOwnPropertyDescriptor(nl.prototype,"toggle"),nl.prototype),nl);function ll(e){return e<10?"0"+e:e}function cl(e,t){return t>e.length?e.repeat(Math.trunc(t/e.length)+1).substring(0,t):e}function ul(){for(var e=function(e){return e&&"object"==typeof e},t=arguments.length,n=new Array(t),r=0;r<t;r++)n[r]=arguments[r];return n.reduce((function(t,n){return Object.keys(n).forEach((function(r){var o=t[r],i=n[r];e(o)&&e(i)?t[r]=ul(o,i):t[r]=i})),t}),Array.isArray(n[n.length-1])?[]:{})}function pl(e){return{value:"object"===e?{}:"array"===e?[]:void 0}}function fl(e,t){t&&e.pop()}r(6977),r(2481),r(3689);var dl={multipleOf:"number",maximum:"number",exclusiveMaximum:"number",minimum:"number",exclusiveMinimum:"number",maxLength:"string",minLength:"string",pattern:"string",items:"array",maxItems:"array",minItems:"array",uniqueItems:"array",additionalItems:"array",maxProperties:"object",minProperties:"object",required:"object",additionalProperties:"object",properties:"object",patternProperties:"object",dependencies:"object"};function hl(e){if(void 0!==e.type)return Array.isArray(e.type)?0===e.type.length?null:e.type[0]:e.type;for(var t=Object.keys(dl),n=0;n<t.length;n++){var r=t[n],o=dl[r];if(void 0!==e[r])return o}return null}var ml={},vl=[];function gl(e){var t;return void 0!==e.const?t=e.const:void 0!==e.examples&&e.examples.length?t=e.examples[0]:void 0!==e.enum&&e.enum.length?t=e.enum[0]:void 0!==e.default&&(t=e.default),t}function yl(e){var t=gl(e);if(void 0!==t)return{value:t,readOnly:e.readOnly,writeOnly:e.writeOnly,type:null}}function bl(e,t,n,r){if(r){if(vl.includes(e))return pl(hl(e));vl.push(e)}if(r&&r.depth>t.maxSampleDepth)return fl(vl,r),pl(hl(e));if(e.$ref){if(!n)throw new Error("Your schema contains $ref. You must provide full specification in the third parameter.");var o=decodeURIComponent(e.$ref);o.startsWith("#")&&(o=o.substring(1));var i,s=Ja().get(n,o);return!0!==ml[o]?(ml[o]=!0,i=bl(s,t,n,r),ml[o]=!1):i=pl(hl(s)),fl(vl,r),i}if(void 0!==e.example)return fl(vl,r),{value:e.example,readOnly:e.readOnly,writeOnly:e.writeOnly,type:e.type};if(void 0!==e.allOf)return fl(vl,r),yl(e)||function(e,t,n,r,o){var i,s=bl(e,n,r),l=[],c=oo(t);try{for(c.s();!(i=c.n()).done;){var u=i.value,p=bl(a({type:s.type},u),n,r,o),f=p.type,d=p.readOnly,h=p.writeOnly,m=p.value;s.type&&f&&f!==s.type&&(console.warn("allOf: schemas with different types can't be merged"),s.type=f),s.type=s.type||f,s.readOnly=s.readOnly||d,s.writeOnly=s.writeOnly||h,null!=m&&l.push(m)}}catch(e){c.e(e)}finally{c.f()}if("object"===s.type)return s.value=ul.apply(void 0,[s.value||{}].concat(ns(l.filter((function(e){return"object"==typeof e}))))),s;"array"===s.type&&(n.quiet||console.warn('OpenAPI Sampler: found allOf with "array" type. Result may be incorrect'));var v=l[l.length-1];return s.value=null!=v?v:s.value,s}(a(a({},e),{},{allOf:void 0}),e.allOf,t,n,r);if(e.oneOf&&e.oneOf.length){e.anyOf&&(t.quiet||console.warn("oneOf and anyOf are not supported on the same level. Skipping anyOf")),fl(vl,r);var l=Object.assign({readOnly:e.readOnly,writeOnly:e.writeOnly},e.oneOf[0]);return yl(e)||bl(l,t,n,r)}if(e.anyOf&&e.anyOf.length)return fl(vl,r),yl(e)||bl(e.anyOf[0],t,n,r);if(e.if&&e.then)return fl(vl,r),yl(e)||bl(ul(e.if,e.then),t,n,r);var c=gl(e),u=null;if(void 0===c){c=null,u=e.type,Array.isArray(u)&&e.type.length>0&&(u=e.type[0]),u||(u=hl(e));var p=Sl[u];p&&(c=p(e,t,n,r))}return fl(vl,r),{value:c,readOnly:e.readOnly,writeOnly:e.writeOnly,type:u}}function xl(e){var t=0;if("boolean"==typeof e.exclusiveMinimum||"boolean"==typeof e.exclusiveMaximum){if(e.maximum&&e.minimum)return t=e.exclusiveMinimum?Math.floor(e.minimum)+1:e.minimum,(e.exclusiveMaximum&&t>=e.maximum||!e.exclusiveMaximum&&t>e.maximum)&&(t=(e.maximum+e.minimum)/2),t;if(e.minimum)return e.exclusiveMinimum?Math.floor(e.minimum)+1:e.minimum;if(e.maximum)return e.exclusiveMaximum?e.maximum>0?0:Math.floor(e.maximum)-1:e.maximum>0?0:e.maximum}else{if(e.minimum)return e.minimum;e.exclusiveMinimum?(t=Math.floor(e.exclusiveMinimum)+1)===e.exclusiveMaximum&&(t=(t+Math.floor(e.exclusiveMaximum)-1)/2):e.exclusiveMaximum?t=Math.floor(e.exclusiveMaximum)-1:e.maximum&&(t=e.maximum)}return t}function wl(e){var t=e.min,n=e.max,r=e.omitTime,o=e.omitDate,i=function(e,t,n,r){var o=n?"":e.getUTCFullYear()+"-"+ll(e.getUTCMonth()+1)+"-"+ll(e.getUTCDate());return t||(o+="T"+ll(e.getUTCHours())+":"+ll(e.getUTCMinutes())+":"+ll(e.getUTCSeconds())+"Z"),o}(new Date("2019-08-24T14:15:22.123Z"),r,o);return i.length<t&&console.warn("Using minLength = ".concat(t,' is incorrect with format "date-time"')),n&&i.length>n&&console.warn("Using maxLength = ".concat(n,' is incorrect with format "date-time"')),i}function kl(e,t){var n=cl("string",e);return t&&n.length>t&&(n=n.substring(0,t)),n}var El={email:function(){return"user@example.com"},"idn-email":function(){return"пошта@укр.нет"},password:function(e,t){var n="pa$$word";return e>n.length&&(n+="_",n+=cl("qwerty!@#$%^123456",e-n.length).substring(0,e-n.length)),n},"date-time":function(e,t){return wl({min:e,max:t,omitTime:!1,omitDate:!1})},date:function(e,t){return wl({min:e,max:t,omitTime:!0,omitDate:!1})},time:function(e,t){return wl({min:e,max:t,omitTime:!1,omitDate:!0}).slice(1)},ipv4:function(){return"192.168.0.1"},ipv6:function(){return"2001:0db8:85a3:0000:0000:8a2e:0370:7334"},hostname:function(){return"example.com"},"idn-hostname":function(){return"приклад.укр"},iri:function(){return"http://example.com"},"iri-reference":function(){return"../словник"},uri:function(){return"http://example.com"},"uri-reference":function(){return"../dictionary"},"uri-template":function(){return"http://example.com/{endpoint}"},uuid:function(e,t,n){return r=function(e){var t=0;if(0==e.length)return t;for(var n=0;n<e.length;n++)t=(t<<5)-t+e.charCodeAt(n),t&=t;return t}(n||"id"),o=function(e,t,n,r){return function(){var o=(e|=0)-((t|=0)<<27|t>>>5)|0;return e=t^((n|=0)<<17|n>>>15),t=n+(r|=0)|0,n=r+o|0,((r=e+o|0)>>>0)/4294967296}}(r,r,r,r),"xxxxxxxx-xxxx-4xxx-yxxx-xxxxxxxxxxxx".replace(/[xy]/g,(function(e){var t=16*o()%16|0;return("x"==e?t:3&t|8).toString(16)}));var r,o},default:kl,"json-pointer":function(){return"/json/pointer"},"relative-json-pointer":function(){return"1/relative/json/pointer"},regex:function(){return"/regex/"}},Sl={},_l={skipReadOnly:!1,maxSampleDepth:15};function Ol(e,t,n){var r=Object.assign({},_l,t);return ml={},vl=[],bl(e,r,n).value}function Al(e,t){Sl[e]=t}Al("array",(function(e){var t=arguments.length>1&&void 0!==arguments[1]?arguments[1]:{},n=arguments.length>2?arguments[2]:void 0,r=arguments.length>3?arguments[3]:void 0,o=r&&r.depth||1,i=Math.min(null!=e.maxItems?e.maxItems:1/0,e.minItems||1),a=e.items||e.contains;Array.isArray(a)&&(i=Math.max(i,a.length));var s=function(t){return Array.isArray(e.items)?a[t]||{}:a||{}},l=[];if(!a)return l;for(var c=0;c<i;c++){var u=s(c),p=bl(u,t,n,{depth:o+1}),f=p.value;l.push(f)}return l})),Al("boolean",(function(e){return!0})),Al("integer",xl),Al("number",xl),Al("object",(function(e){var t=arguments.length>1&&void 0!==arguments[1]?arguments[1]:{},n=arguments.length>2?arguments[2]:void 0,r=arguments.length>3?arguments[3]:void 0,o={},i=r&&r.depth||1;if(e&&"object"==typeof e.properties){var a=Array.isArray(e.required)?e.required:[],s=a.reduce((function(e,t){return e[t]=!0,e}),{});Object.keys(e.properties).forEach((function(r){if(!t.skipNonRequired||s.hasOwnProperty(r)){var a=bl(e.properties[r],t,n,{propertyName:r,depth:i+1});t.skipReadOnly&&a.readOnly||t.skipWriteOnly&&a.writeOnly||(o[r]=a.value)}}))}return e&&"object"==typeof e.additionalProperties&&(o.property1=bl(e.additionalProperties,t,n,{depth:i+1}).value,o.property2=bl(e.additionalProperties,t,n,{depth:i+1}).value),o})),Al("string",(function(e,t,n,r){var o=e.format||"default",i=El[o]||kl,a=r&&r.propertyName;return i(0|e.minLength,e.maxLength,a)}));var Rl,Cl,Tl,Pl,Il,jl,Ll,Nl,Ml=function(){function e(t,r,o,i,a){nr(this,e),n(this,"examples",void 0),n(this,"schema",void 0),n(this,"name",void 0),n(this,"isRequestType",void 0),n(this,"onlyRequiredInSamples",void 0),n(this,"generatedPayloadSamplesMaxDepth",void 0),this.name=r,this.isRequestType=o,this.schema=i.schema&&new el(t,i.schema,"",a),this.onlyRequiredInSamples=a.onlyRequiredInSamples,this.generatedPayloadSamplesMaxDepth=a.generatedPayloadSamplesMaxDepth,void 0!==i.examples?this.examples=co(i.examples,(function(e){return new il(t,e,r,i.encoding)})):void 0!==i.example?this.examples={default:new il(t,{value:t.shallowDeref(i.example)},r,i.encoding)}:fs(r)&&this.generateExample(t,i)}return or(e,[{key:"generateExample",value:function(e,t){var n={skipReadOnly:this.isRequestType,skipWriteOnly:!this.isRequestType,skipNonRequired:this.isRequestType&&this.onlyRequiredInSamples,maxSampleDepth:this.generatedPayloadSamplesMaxDepth};if(this.schema&&this.schema.oneOf){this.examples={};var r,o=oo(this.schema.oneOf);try{for(o.s();!(r=o.n()).done;){var i=r.value,a=Ol(i.rawSchema,n,e.spec);this.schema.discriminatorProp&&"object"==typeof a&&a&&(a[this.schema.discriminatorProp]=i.title),this.examples[i.title]=new il(e,{value:a},this.name,t.encoding)}}catch(e){o.e(e)}finally{o.f()}}else this.schema&&(this.examples={default:new il(e,{value:Ol(t.schema,n,e.spec)},this.name,t.encoding)})}}]),e}(),Dl=(Cl=Qa((Rl=function(){function e(t,r,o,i){var s,l;nr(this,e),this.isRequestType=o,n(this,"mediaTypes",void 0),Bs(this,"activeMimeIdx",Cl,this),an(this),i.unstable_ignoreMimeParameters&&(s=r,l={},Object.keys(s).forEach((function(e){var t=s[e],n=e.split(";")[0].trim();l[n]?l[n]=a(a({},l[n]),t):l[n]=t})),r=l),this.mediaTypes=Object.keys(r).map((function(e){var n=r[e];return t.resetVisited(),new Ml(t,e,o,n,i)}))}return or(e,[{key:"activate",value:function(e){this.activeMimeIdx=e}},{key:"active",get:function(){return this.mediaTypes[this.activeMimeIdx]}},{key:"hasSample",get:function(){return this.mediaTypes.filter((function(e){return!!e.examples})).length>0}}]),e}()).prototype,"activeMimeIdx",[Pe],{configurable:!0,enumerable:!0,writable:!0,initializer:function(){return 0}}),Qa(Rl.prototype,"activate",[Tt],Object.getOwnPropertyDescriptor(Rl.prototype,"activate"),Rl.prototype),Qa(Rl.prototype,"active",[Ne],Object.getOwnPropertyDescriptor(Rl.prototype,"active"),Rl.prototype),Rl),Fl=function e(t){nr(this,e),n(this,"description",void 0),n(this,"required",void 0),n(this,"content",void 0);var r=t.parser,o=t.infoOrRef,i=t.options,s=!t.isEvent,l=r.deref(o);this.description=l.description||"",this.required=!!l.required,r.exitRef(o);var c=function(e){var t=e.content,n=e["x-examples"],r=e["x-example"];if(n){t=a({},t);for(var o=0,i=Object.keys(n);o<i.length;o++){var s=i[o],l=n[s];t[s]=a(a({},t[s]),{},{examples:l})}}else if(r){t=a({},t);for(var c=0,u=Object.keys(r);c<u.length;c++){var p=u[c],f=r[p];t[p]=a(a({},t[p]),{},{example:f})}}return t}(l);void 0!==c&&(this.content=new Dl(r,c,s,i))},zl=(Pl=Qa((Tl=function(){function e(t){nr(this,e),Bs(this,"expanded",Pl,this),n(this,"content",void 0),n(this,"code",void 0),n(this,"summary",void 0),n(this,"description",void 0),n(this,"type",void 0),n(this,"headers",[]);var r=t.parser,o=t.code,i=t.defaultAsError,s=t.infoOrRef,l=t.options,c=!!t.isEvent;an(this),this.expanded="all"===l.expandResponses||l.expandResponses[o];var u=r.deref(s);r.exitRef(s),this.code=o,void 0!==u.content&&(this.content=new Dl(r,u.content,c,l)),void 0!==u["x-summary"]?(this.summary=u["x-summary"],this.description=u.description||""):(this.summary=u.description||"",this.description=""),this.type=as(o,i);var p=u.headers;void 0!==p&&(this.headers=Object.keys(p).map((function(e){var t=p[e];return new sl(r,a(a({},t),{},{name:e}),"",l)})))}return or(e,[{key:"toggle",value:function(){this.expanded=!this.expanded}}]),e}()).prototype,"expanded",[Pe],{configurable:!0,enumerable:!0,writable:!0,initializer:function(){return!1}}),Qa(Tl.prototype,"toggle",[Tt],Object.getOwnPropertyDescriptor(Tl.prototype,"toggle"),Tl.prototype),Tl);function Ul(e){return"payload"===e.lang&&e.requestBodyContent}var Bl,$l,ql,Wl,Vl,Hl,Yl,Gl,Ql,Xl,Kl,Zl,Jl,ec,tc,nc=!1,rc=(jl=Qa((Il=function(){function e(t,r,o,i){var a=arguments.length>4&&void 0!==arguments[4]&&arguments[4];nr(this,e),this.parser=t,this.operationSpec=r,this.options=i,n(this,"id",void 0),n(this,"absoluteIdx",void 0),n(this,"name",void 0),n(this,"sidebarLabel",void 0),n(this,"description",void 0),n(this,"type","operation"),n(this,"parent",void 0),n(this,"externalDocs",void 0),n(this,"items",[]),n(this,"depth",void 0),Bs(this,"ready",jl,this),Bs(this,"active",Ll,this),Bs(this,"expanded",Nl,this),n(this,"pointer",void 0),n(this,"operationId",void 0),n(this,"httpVerb",void 0),n(this,"deprecated",void 0),n(this,"path",void 0),n(this,"servers",void 0),n(this,"security",void 0),n(this,"extensions",void 0),n(this,"isCallback",void 0),n(this,"isWebhook",void 0),n(this,"isEvent",void 0),an(this),this.pointer=r.pointer,this.description=r.description,this.parent=o,this.externalDocs=r.externalDocs,this.deprecated=!!r.deprecated,this.httpVerb=r.httpVerb,this.deprecated=!!r.deprecated,this.operationId=r.operationId,this.path=r.pathName,this.isCallback=a,this.isWebhook=r.isWebhook,this.isEvent=this.isCallback||this.isWebhook,this.name=cs(r),this.sidebarLabel=i.sideNavStyle===yo.PathOnly?this.path:this.name,this.isCallback?(this.security=(r.security||[]).map((function(e){return new Zs(e,t)})),this.servers=Es("",r.servers||r.pathServers||[])):(this.id=void 0!==r.operationId?"operation/"+r.operationId:void 0!==o?o.id+this.pointer:this.pointer,this.security=(r.security||t.spec.security||[]).map((function(e){return new Zs(e,t)})),this.servers=Es(t.specUrl,r.servers||r.pathServers||t.spec.servers||[])),i.showExtensions&&(this.extensions=Cs(r,i.showExtensions))}return or(e,[{key:"activate",value:function(){this.active=!0}},{key:"deactivate",value:function(){this.active=!1}},{key:"toggle",value:function(){this.expanded=!this.expanded}},{key:"expand",value:function(){this.parent&&this.parent.expand()}},{key:"collapse",value:function(){}},{key:"requestBody",get:function(){return this.operationSpec.requestBody&&new Fl({parser:this.parser,infoOrRef:this.operationSpec.requestBody,options:this.options,isEvent:this.isEvent})}},{key:"codeSamples",get:function(){var e=this.operationSpec["x-codeSamples"]||this.operationSpec["x-code-samples"]||[];this.operationSpec["x-code-samples"]&&!nc&&(nc=!0,console.warn('"x-code-samples" is deprecated. Use "x-codeSamples" instead'));var t=this.requestBody&&this.requestBody.content;if(t&&t.hasSample){var n=Math.min(e.length,this.options.payloadSampleIdx);e=[].concat(ns(e.slice(0,n)),[{lang:"payload",label:"Payload",source:"",requestBodyContent:t}],ns(e.slice(n)))}return e}},{key:"parameters",get:function(){var e=this,t=function(e){var t=arguments.length>1&&void 0!==arguments[1]?arguments[1]:[],n=arguments.length>2&&void 0!==arguments[2]?arguments[2]:[],r={};return n.forEach((function(t){t=e.shallowDeref(t),r[t.name+"_"+t.in]=!0})),(t=t.filter((function(t){return t=e.shallowDeref(t),!r[t.name+"_"+t.in]}))).concat(n)}(this.parser,this.operationSpec.pathParameters,this.operationSpec.parameters).map((function(t){return new sl(e.parser,t,e.pointer,e.options)}));return this.options.sortPropsAlphabetically?ks(t,"name"):this.options.requiredPropsFirst?ws(t):t}},{key:"responses",get:function(){var e=this,t=!1;return Object.keys(this.operationSpec.responses||[]).filter((function(e){return"default"===e||("success"===as(e)&&(t=!0),"default"===(n=e)||po(n)||is(n));var n})).map((function(n){return new zl({parser:e.parser,code:n,defaultAsError:t,infoOrRef:e.operationSpec.responses[n],options:e.options,isEvent:e.isEvent})}))}},{key:"callbacks",get:function(){var e=this;return Object.keys(this.operationSpec.callbacks||[]).map((function(t){return new Js(e.parser,t,e.operationSpec.callbacks[t],e.pointer,e.options)}))}}]),e}()).prototype,"ready",[Pe],{configurable:!0,enumerable:!0,writable:!0,initializer:function(){return!0}}),Ll=Qa(Il.prototype,"active",[Pe],{configurable:!0,enumerable:!0,writable:!0,initializer:function(){return!1}}),Nl=Qa(Il.prototype,"expanded",[Pe],{configurable:!0,enumerable:!0,writable:!0,initializer:function(){return!1}}),Qa(Il.prototype,"activate",[Tt],Object.getOwnPropertyDescriptor(Il.prototype,"activate"),Il.prototype),Qa(Il.prototype,"deactivate",[Tt],Object.getOwnPropertyDescriptor(Il.prototype,"deactivate"),Il.prototype),Qa(Il.prototype,"toggle",[Tt],Object.getOwnPropertyDescriptor(Il.prototype,"toggle"),Il.prototype),Qa(Il.prototype,"requestBody",[Ms],Object.getOwnPropertyDescriptor(Il.prototype,"requestBody"),Il.prototype),Qa(Il.prototype,"codeSamples",[Ms],Object.getOwnPropertyDescriptor(Il.prototype,"codeSamples"),Il.prototype),Qa(Il.prototype,"parameters",[Ms],Object.getOwnPropertyDescriptor(Il.prototype,"parameters"),Il.prototype),Qa(Il.prototype,"responses",[Ms],Object.getOwnPropertyDescriptor(Il.prototype,"responses"),Il.prototype),Qa(Il.prototype,"callbacks",[Ms],Object.getOwnPropertyDescriptor(Il.prototype,"callbacks"),Il.prototype),Il),oc=Sa.div(Bl||(Bl=Co(["\n  width: calc(100% - ",");\n  padding: 0 ","px;\n\n  ",";\n"])),(function(e){return e.theme.rightPanel.width}),(function(e){return e.theme.spacing.sectionHorizontal}),(function(e){var t=e.compact,n=e.theme;return Ea("medium",!0)($l||($l=Co(["\n    width: 100%;\n    padding: ",";\n  "])),"".concat(t?0:n.spacing.sectionVertical,"px ").concat(n.spacing.sectionHorizontal,"px"))})),ic=Sa.div.attrs((function(e){return n({},Wf,e.id)}))(ql||(ql=Co(["\n  padding: ","px 0;\n\n  &:last-child {\n    min-height: calc(100vh + 1px);\n  }\n\n  & > &:last-child {\n    min-height: initial;\n  }\n\n  ","\n  ","\n"])),(function(e){return e.theme.spacing.sectionVertical}),Ea("medium",!0)(Wl||(Wl=Co(["\n    padding: 0;\n  "]))),(function(e){return e.underlined?"\n    position: relative;\n\n    &:not(:last-of-type):after {\n      position: absolute;\n      bottom: 0;\n      width: 100%;\n      display: block;\n      content: '';\n      border-bottom: 1px solid rgba(0, 0, 0, 0.2);\n    }\n  ":""})),ac=Sa.div(Vl||(Vl=Co(["\n  width: ",";\n  color: ",";\n  background-color: ",";\n  padding: 0 ","px;\n\n  ",";\n"])),(function(e){return e.theme.rightPanel.width}),(function(e){return e.theme.rightPanel.textColor}),(function(e){return e.theme.rightPanel.backgroundColor}),(function(e){return e.theme.spacing.sectionHorizontal}),Ea("medium",!0)(Hl||(Hl=Co(["\n    width: 100%;\n    padding: ",";\n  "])),(function(e){return"".concat(e.theme.spacing.sectionVertical,"px ").concat(e.theme.spacing.sectionHorizontal,"px")}))),sc=Sa(ac)(Yl||(Yl=Co(["\n  background-color: ",";\n"])),(function(e){return e.theme.rightPanel.backgroundColor})),lc=Sa.div(Gl||(Gl=Co(["\n  display: flex;\n  width: 100%;\n  padding: 0;\n\n  ",";\n"])),Ea("medium",!0)(Ql||(Ql=Co(["\n    flex-direction: column;\n  "])))),cc={1:"1.85714em",2:"1.57143em",3:"1.27em"},uc=function(e){return ba(Xl||(Xl=Co(["\n  font-family: ",";\n  font-weight: ",";\n  font-size: ",";\n  line-height: ",";\n"])),(function(e){return e.theme.typography.headings.fontFamily}),(function(e){return e.theme.typography.headings.fontWeight}),cc[e],(function(e){return e.theme.typography.headings.lineHeight}))},pc=Sa.h1(Kl||(Kl=Co(["\n  ",";\n  color: ",";\n\n  ",";\n"])),uc(1),(function(e){return e.theme.colors.primary.main}),_a("H1")),fc=Sa.h2(Zl||(Zl=Co(["\n  ",";\n  color: black;\n\n  ",";\n"])),uc(2),_a("H2")),dc=(Sa.h2(Jl||(Jl=Co(["\n  ",";\n  color: black;\n\n  ",";\n"])),uc(3),_a("H3")),Sa.h3(ec||(ec=Co(["\n  color: ",";\n\n  ",";\n"])),(function(e){return e.theme.rightPanel.textColor}),_a("RightPanelHeader"))),hc=Sa.h5(tc||(tc=Co(["\n  border-bottom: 1px solid rgba(38, 50, 56, 0.3);\n  margin: 1em 0 1em 0;\n  color: rgba(38, 50, 56, 0.5);\n  font-weight: normal;\n  text-transform: uppercase;\n  font-size: 0.929em;\n  line-height: 20px;\n\n  ",";\n"])),_a("UnderlinedHeader"));function mc(e,t){return function(e){if(Array.isArray(e))return e}(e)||function(e,t){var n=e&&("undefined"!=typeof Symbol&&e[Symbol.iterator]||e["@@iterator"]);if(null!=n){var r,o,i=[],a=!0,s=!1;try{for(n=n.call(e);!(a=(r=n.next()).done)&&(i.push(r.value),!t||i.length!==t);a=!0);}catch(e){s=!0,o=e}finally{try{a||null==n.return||n.return()}finally{if(s)throw o}}return i}}(e,t)||ro(e,t)||function(){throw new TypeError("Invalid attempt to destructure non-iterable instance.\nIn order to be iterable, non-array objects must have a [Symbol.iterator]() method.")}()}var vc,gc,yc=(0,s.createContext)(void 0),bc=yc.Provider,xc=yc.Consumer;function wc(e){var t=e.spec,n=e.specUrl,r=e.options,o=e.onLoaded,i=e.children,a=mc(s.useState(null),2),l=a[0],c=a[1];s.useEffect((function(){function e(){return(e=Ua($a().mark((function e(){var r;return $a().wrap((function(e){for(;;)switch(e.prev=e.next){case 0:if(t||n){e.next=2;break}return e.abrupt("return",void 0);case 2:return c(null),e.next=5,Ha(t||n);case 5:r=e.sent,c(r);case 7:case"end":return e.stop()}}),e)})))).apply(this,arguments)}!function(){e.apply(this,arguments)}()}),[t,n]);var u=s.useMemo((function(){if(!l)return null;try{return new mv(l,n,r)}catch(e){throw o&&o(e),e}}),[l,n,r]);return s.useEffect((function(){u&&o&&o()}),[u,o]),i({loading:!u,store:u})}var kc=function(e){return ba(vc||(vc=Co(["\n  "," {\n    cursor: pointer;\n    margin-left: -20px;\n    padding: 0;\n    line-height: 1;\n    width: 20px;\n    display: inline-block;\n    outline: 0;\n  }\n  ",":before {\n    content: '';\n    width: 15px;\n    height: 15px;\n    background-size: contain;\n    background-image: url('data:image/svg+xml;base64,PHN2ZyB4bWxucz0iaHR0cDovL3d3dy53My5vcmcvMjAwMC9zdmciIHZlcnNpb249IjEuMSIgeD0iMCIgeT0iMCIgd2lkdGg9IjUxMiIgaGVpZ2h0PSI1MTIiIHZpZXdCb3g9IjAgMCA1MTIgNTEyIiBlbmFibGUtYmFja2dyb3VuZD0ibmV3IDAgMCA1MTIgNTEyIiB4bWw6c3BhY2U9InByZXNlcnZlIj48cGF0aCBmaWxsPSIjMDEwMTAxIiBkPSJNNDU5LjcgMjMzLjRsLTkwLjUgOTAuNWMtNTAgNTAtMTMxIDUwLTE4MSAwIC03LjktNy44LTE0LTE2LjctMTkuNC0yNS44bDQyLjEtNDIuMWMyLTIgNC41LTMuMiA2LjgtNC41IDIuOSA5LjkgOCAxOS4zIDE1LjggMjcuMiAyNSAyNSA2NS42IDI0LjkgOTAuNSAwbDkwLjUtOTAuNWMyNS0yNSAyNS02NS42IDAtOTAuNSAtMjQuOS0yNS02NS41LTI1LTkwLjUgMGwtMzIuMiAzMi4yYy0yNi4xLTEwLjItNTQuMi0xMi45LTgxLjYtOC45bDY4LjYtNjguNmM1MC01MCAxMzEtNTAgMTgxIDBDNTA5LjYgMTAyLjMgNTA5LjYgMTgzLjQgNDU5LjcgMjMzLjR6TTIyMC4zIDM4Mi4ybC0zMi4yIDMyLjJjLTI1IDI0LjktNjUuNiAyNC45LTkwLjUgMCAtMjUtMjUtMjUtNjUuNiAwLTkwLjVsOTAuNS05MC41YzI1LTI1IDY1LjUtMjUgOTAuNSAwIDcuOCA3LjggMTIuOSAxNy4yIDE1LjggMjcuMSAyLjQtMS40IDQuOC0yLjUgNi44LTQuNWw0Mi4xLTQyYy01LjQtOS4yLTExLjYtMTgtMTkuNC0yNS44IC01MC01MC0xMzEtNTAtMTgxIDBsLTkwLjUgOTAuNWMtNTAgNTAtNTAgMTMxIDAgMTgxIDUwIDUwIDEzMSA1MCAxODEgMGw2OC42LTY4LjZDMjc0LjYgMzk1LjEgMjQ2LjQgMzkyLjMgMjIwLjMgMzgyLjJ6Ii8+PC9zdmc+Cg==');\n    opacity: 0.5;\n    visibility: hidden;\n    display: inline-block;\n    vertical-align: middle;\n  }\n\n  h1:hover > ","::before, h2:hover > ","::before, ",":hover::before {\n    visibility: visible;\n  }\n"])),e,e,e,e,e)};function Ec(e){var t=s.useContext(yc),n=s.useCallback((function(n){t&&function(e,t,n){t.defaultPrevented||0!==t.button||function(e){return!!(e.metaKey||e.altKey||e.ctrlKey||e.shiftKey)}(t)||(t.preventDefault(),e.replace(n))}(t.menu.history,n,e.to)}),[t]);return t?s.createElement("a",{className:e.className,href:t.menu.history.linkForId(e.to),onClick:n,"aria-label":e.to},e.children):null}var Sc,_c,Oc=Sa(Ec)(gc||(gc=Co(["\n  ",";\n"])),kc("&"));function Ac(e){return s.createElement(Oc,{to:e.to})}var Rc,Cc,Tc,Pc,Ic,jc,Lc,Nc,Mc,Dc,Fc,zc,Uc,Bc,$c,qc,Wc,Vc,Hc,Yc,Gc,Qc={left:"90deg",right:"-90deg",up:"-180deg",down:"0"},Xc=Sa(function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){return s.createElement("svg",{className:this.props.className,style:this.props.style,version:"1.1",viewBox:"0 0 24 24",x:"0",xmlns:"http://www.w3.org/2000/svg",y:"0","aria-hidden":"true"},s.createElement("polygon",{points:"17.3 8.3 12 13.6 6.7 8.3 5.3 9.7 12 16.4 18.7 9.7 "}))}}]),n}(s.PureComponent))(Sc||(Sc=Co(["\n  height: ",";\n  width: ",";\n  vertical-align: middle;\n  float: ",";\n  transition: transform 0.2s ease-out;\n  transform: rotateZ(",");\n\n  polygon {\n    fill: ",";\n  }\n"])),(function(e){return e.size||"18px"}),(function(e){return e.size||"18px"}),(function(e){return e.float||""}),(function(e){return Qc[e.direction||"down"]}),(function(e){var t=e.color,n=e.theme;return t&&n.colors.responses[t]&&n.colors.responses[t].color||t})),Kc=Sa.span(_c||(_c=Co(["\n  display: inline-block;\n  padding: 2px 8px;\n  margin: 0;\n  background-color: ",";\n  color: ",";\n  font-size: ",";\n  vertical-align: middle;\n  line-height: 1.6;\n  border-radius: 4px;\n  font-weight: ",";\n  font-size: 12px;\n  + span[type] {\n    margin-left: 4px;\n  }\n"])),(function(e){return e.theme.colors[e.type].main}),(function(e){return e.theme.colors[e.type].contrastText}),(function(e){return e.theme.typography.code.fontSize}),(function(e){return e.theme.typography.fontWeightBold})),Zc=ba(Rc||(Rc=Co(["\n  text-decoration: line-through;\n  color: #707070;\n"]))),Jc=Sa.caption(Cc||(Cc=Co(["\n  text-align: right;\n  font-size: 0.9em;\n  font-weight: normal;\n  color: ",";\n"])),(function(e){return e.theme.colors.text.secondary})),eu=Sa.td(Tc||(Tc=Co(["\n  border-left: 1px solid ",";\n  box-sizing: border-box;\n  position: relative;\n  padding: 10px 10px 10px 0;\n\n  ","\n\n  tr:first-of-type > &,\n  tr.last > & {\n    border-left-width: 0;\n    background-position: top left;\n    background-repeat: no-repeat;\n    background-size: 1px 100%;\n  }\n\n  tr:first-of-type > & {\n    background-image: linear-gradient(\n      to bottom,\n      transparent 0%,\n      transparent 22px,\n      "," 22px,\n      "," 100%\n    );\n  }\n\n  tr.last > & {\n    background-image: linear-gradient(\n      to bottom,\n      "," 0%,\n      "," 22px,\n      transparent 22px,\n      transparent 100%\n    );\n  }\n\n  tr.last + tr > & {\n    border-left-color: transparent;\n  }\n\n  tr.last:first-child > & {\n    background: none;\n    border-left-color: transparent;\n  }\n"])),(function(e){return e.theme.schema.linesColor}),Ea("small")(Pc||(Pc=Co(["\n    display: block;\n    overflow: hidden;\n  "]))),(function(e){return e.theme.schema.linesColor}),(function(e){return e.theme.schema.linesColor}),(function(e){return e.theme.schema.linesColor}),(function(e){return e.theme.schema.linesColor})),tu=Sa(eu)(Ic||(Ic=Co(["\n  padding: 0;\n"]))),nu=Sa(eu)(jc||(jc=Co(["\n  vertical-align: top;\n  line-height: 20px;\n  white-space: nowrap;\n  font-size: 13px;\n  font-family: ",";\n\n  &.deprecated {\n    ",";\n  }\n\n  ",";\n\n  ",";\n"])),(function(e){return e.theme.typography.code.fontFamily}),Zc,(function(e){return"field"!==e.kind?"font-style: italic":""}),_a("PropertyNameCell")),ru=Sa.td(Lc||(Lc=Co(["\n  border-bottom: 1px solid #9fb4be;\n  padding: 10px 0;\n  width: ",";\n  box-sizing: border-box;\n\n  tr.expanded & {\n    border-bottom: none;\n  }\n\n  ","\n\n  ",";\n"])),(function(e){return e.theme.schema.defaultDetailsWidth}),Ea("small")(Nc||(Nc=Co(["\n    padding: 0 20px;\n    border-bottom: none;\n    border-left: 1px solid ",";\n\n    tr.last > & {\n      border-left: none;\n    }\n  "])),(function(e){return e.theme.schema.linesColor})),_a("PropertyDetailsCell")),ou=Sa.span(Mc||(Mc=Co(["\n  color: ",";\n  font-family: ",";\n  margin-right: 10px;\n\n  &::before {\n    content: '';\n    display: inline-block;\n    vertical-align: middle;\n    width: 10px;\n    height: 1px;\n    background: ",";\n  }\n\n  &::after {\n    content: '';\n    display: inline-block;\n    vertical-align: middle;\n    width: 1px;\n    background: ",";\n    height: 7px;\n  }\n"])),(function(e){return e.theme.schema.linesColor}),(function(e){return e.theme.typography.code.fontFamily}),(function(e){return e.theme.schema.linesColor}),(function(e){return e.theme.schema.linesColor})),iu=Sa.div(Dc||(Dc=Co(["\n  padding: ",";\n"])),(function(e){return e.theme.schema.nestingSpacing})),au=Sa.table(Fc||(Fc=Co(["\n  border-collapse: separate;\n  border-radius: 3px;\n  font-size: ",";\n\n  border-spacing: 0;\n  width: 100%;\n\n  > tr {\n    vertical-align: middle;\n  }\n\n  ","\n\n  ","\n\n  &\n    ",",\n    &\n    ","\n    ","\n    ",",\n    &\n    ","\n    ","\n    ","\n    ","\n    "," {\n    margin: ",";\n    margin-right: 0;\n    background: ",";\n  }\n\n  &\n    ","\n    ",",\n    &\n    ","\n    ","\n    ","\n    ",",\n    &\n    ","\n    ","\n    ","\n    ","\n    ","\n    "," {\n    background: #ffffff;\n  }\n"])),(function(e){return e.theme.typography.fontSize}),Ea("small")(zc||(zc=Co(["\n    display: block;\n    > tr, > tbody > tr {\n      display: block;\n    }\n  "]))),Ea("small",!1," and (-ms-high-contrast:none)")(Uc||(Uc=Co(["\n    td {\n      float: left;\n      width: 100%;\n    }\n  "]))),iu,iu,iu,iu,iu,iu,iu,iu,iu,(function(e){return e.theme.schema.nestingSpacing}),(function(e){return e.theme.schema.nestedBackground}),iu,iu,iu,iu,iu,iu,iu,iu,iu,iu,iu,iu),su=Sa.div(Bc||(Bc=Co(["\n  margin: 0 0 3px 0;\n  display: inline-block;\n"]))),lu=Sa.span($c||($c=Co(["\n  font-size: 0.9em;\n  margin-right: 10px;\n  color: ",";\n  font-family: ",";\n}\n"])),(function(e){return e.theme.colors.primary.main}),(function(e){return e.theme.typography.headings.fontFamily})),cu=Sa.button(qc||(qc=Co(["\n  display: inline-block;\n  margin-right: 10px;\n  margin-bottom: 5px;\n  font-size: 0.8em;\n  cursor: pointer;\n  border: 1px solid ",";\n  padding: 2px 10px;\n  line-height: 1.5em;\n  outline: none;\n  &:focus {\n    box-shadow: 0 0 0 1px ",";\n  }\n\n  ","\n"])),(function(e){return e.theme.colors.primary.main}),(function(e){return e.theme.colors.primary.main}),(function(e){return e.active?"\n      color: white;\n      background-color: ".concat(e.theme.colors.primary.main,";\n      &:focus {\n        box-shadow: none;\n        background-color: ").concat(zr(.15,e.theme.colors.primary.main),";\n      }\n      "):"\n        color: ".concat(e.theme.colors.primary.main,";\n        background-color: white;\n      ")})),uu=Sa.div(Wc||(Wc=Co(["\n  font-size: 0.9em;\n  font-family: ",";\n  &::after {\n    content: ' [';\n  }\n"])),(function(e){return e.theme.typography.code.fontFamily})),pu=Sa.div(Vc||(Vc=Co(["\n  font-size: 0.9em;\n  font-family: ",";\n  &::after {\n    content: ']';\n  }\n"])),(function(e){return e.theme.typography.code.fontFamily})),fu=function(){return(fu=Object.assign||function(e){for(var t,n=1,r=arguments.length;n<r;n++)for(var o in t=arguments[n])Object.prototype.hasOwnProperty.call(t,o)&&(e[o]=t[o]);return e}).apply(this,arguments)};(Gc=Hc||(Hc={})).Arrow="Arrow",Gc.DropdownSelector="DropdownSelector",Gc.DropdownWrapper="DropdownWrapper",Gc.SelectorSearch="SelectorSearch",Gc.SelectedValue="SelectedValue",Gc.Placeholder="Placeholder",Gc.GroupContainer="GroupContainer",Gc.GroupDivider="GroupDivider",Gc.GroupHeading="GroupHeading",Gc.OptionContainer="OptionContainer",Gc.OptionItem="OptionItem",function(e){e[e.DOWN_ARROW=40]="DOWN_ARROW",e[e.ENTER=13]="ENTER",e[e.ESCAPE=27]="ESCAPE",e[e.PAGE_DOWN=34]="PAGE_DOWN",e[e.PAGE_UP=33]="PAGE_UP",e[e.TAB=9]="TAB",e[e.UP_ARROW=38]="UP_ARROW",e[e.SPACE=32]="SPACE"}(Yc||(Yc={}));var du=[Yc.ESCAPE,Yc.UP_ARROW,Yc.DOWN_ARROW,Yc.PAGE_UP,Yc.PAGE_DOWN],hu="#808080",mu="#7C7C7C",vu="#6e6d6d",gu="#CECECE",yu="#f5f5f5",bu="#0080bc";function xu(e){return void 0!==e.groupOptions}var wu=function(e,t){return xu(t)?e.concat(t.groupOptions):e.concat(t)},ku=function(e,t){return fu(fu({},e),{groupOptions:e.groupOptions.filter((function(e){return e.value.toLowerCase().includes(t)}))})};function Eu(){for(var e=[],t=0;t<arguments.length;t++)e[t]=arguments[t];return e.filter(Boolean).map((function(e){return"string"==typeof e?e:e?Object.keys(e).filter((function(t){return e[t]})).join(" "):""})).join(" ")}var Su=ya.div((function(e){var t=e.width,n=e.height,r=e.disabled,o=e.open,i=e.dropdownFocused;return{backgroundColor:r?gu:yu,border:"2px solid "+(o||i?bu:mu),borderRadius:"7",cursor:r?"not-allowed":"pointer",display:"flex",flexDirection:"column",fontSize:"1em",height:n,position:"relative",width:t,"&:hover":{border:"2px solid "+(o||i?bu:vu)},"&:disabled":{backgroundColor:"#ededed",cursor:"unset"}}})),_u=ya.div((function(e){var t=e.searchable;return{alignItems:"center",boxSizing:"border-box",cursor:e.open&&t?"text":"inherit",display:"flex",height:"32px",padding:"0 11px",position:"relative",width:"100%",input:{backgroundColor:"inherit",border:"none",fontSize:"inherit",height:"30px",outline:"none",width:"100%"}}})),Ou=ya.span((function(){return{bottom:0,left:"11px",position:"absolute",right:"25px",top:0}})),Au={bottom:0,left:"11px",lineHeight:"30px",overflow:"hidden",position:"absolute",right:"25px",textOverflow:"ellipsis",top:0,whiteSpace:"nowrap"},Ru=ya.span((function(e){return fu({textAlign:e.centerText?"center":"left",color:e.value&&!e.open?"black":hu},Au)})),Cu=ya.span((function(e){var t=e.centerText;return fu({color:hu,textAlign:t?"center":"left"},Au)})),Tu=ya.div((function(){return{alignItems:"center",bottom:0,color:hu,display:"flex",position:"absolute",right:"10px",top:0}})),Pu=ya.span((function(e){var t=e.openUp,n=e.maxContentHeight;return{backgroundColor:"#ffffff",border:"2px solid #6e6d6d",borderRadius:"4px",bottom:t?"105%":void 0,boxShadow:"0px "+(t?"-4px":"4px")+" 4px rgba(0, 0, 0, 0.25)",boxSizing:"border-box",color:"black",display:e.open?"block":"none",left:"0",listStyleType:"none",margin:"0",maxHeight:n||"175px",overflowX:"hidden",overflowY:"auto",padding:"2px 0",position:"absolute",top:t?void 0:"100%",width:"100%",zIndex:9999,".dropdown-selector-content--empty":{alignItems:"center",color:hu,display:"flex",flexDirection:"column",justifyContent:"center",padding:"4px 0"},"&::-webkit-scrollbar":{width:"5px"},"&::-webkit-scrollbar-track":{background:"#ddd"},"&::-webkit-scrollbar-thumb":{background:"#666"}}})),Iu=ya.div((function(){return{padding:"1em 0 0 0"}})),ju=ya.div((function(){return{color:"grey",display:"flex",flexDirection:"row",fontSize:"0.9em",padding:"0 10px 3px 5px"}})),Lu=ya.div((function(){return{borderBottom:"1px solid #7C7C7C",margin:"auto",paddingTop:10,width:"85%"}})),Nu=ya.div((function(e){var t=e.selected,n=e.focused,r=yu,o="inherit";return n&&t?(r=mu,o=yu):n?r="#e0e0e0":t&&(r=gu),{backgroundColor:r,border:"none",color:o,cursor:"pointer",fontSize:"0.95em",overflow:"hidden",padding:"5px 10px",textOverflow:"ellipsis",whiteSpace:"nowrap",width:"100%",display:"flex",alignItems:"center","&:hover":{backgroundColor:t?mu:gu,color:t?yu:void 0},".option-icon":{paddingRight:"5px"}}})),Mu=(0,s.memo)((function(e){var t=e.onOptionClicked,n=e.option,r=e.optionClass,o=e.itemRenderer,i=e.index,a=e.selected,l=e.focused,c=(0,s.useCallback)((function(e){e.stopPropagation(),t(n,!0)}),[t,n]),u=(0,s.createElement)(s.Fragment,null,n.iconClass&&(0,s.createElement)("i",{className:n.iconClass+" dropdown-option-icon"}),n.value);return o&&(u=o(e,i)),(0,s.createElement)(Nu,{"aria-label":n.ariaLabel,"aria-selected":a,className:Eu("dropdown-option",r,{selected:a,focused:l}),onClick:c,title:n.title,selected:a,focused:l},u)})),Du=function(e){var t=e.children,n=e.className,r=e.dim,o=void 0===r?24:r;return(0,s.createElement)("svg",{xmlns:"http://www.w3.org/2000/svg",width:o,height:o,viewBox:"0 0 24 24",fill:"none",stroke:"currentColor",strokeWidth:"2",strokeLinecap:"round",strokeLinejoin:"round",className:"feather "+n},t)},Fu=function(){return(0,s.createElement)(Du,{className:"feather-inbox"},(0,s.createElement)("polyline",{points:"22 12 16 12 14 15 10 15 8 12 2 12"}),(0,s.createElement)("path",{d:"M5.45 5.11L2 12v6a2 2 0 0 0 2 2h16a2 2 0 0 0 2-2v-6l-3.45-6.89A2 2 0 0 0 16.76 4H7.24a2 2 0 0 0-1.79 1.11z"}))},zu=function(){return(0,s.createElement)(Du,{className:"feather-chevron-down",dim:16},(0,s.createElement)("polyline",{points:"6 9 12 15 18 9"}))},Uu=function(){return(0,s.createElement)(Du,{className:"feather-search",dim:16},(0,s.createElement)("circle",{cx:"11",cy:"11",r:"8"}),(0,s.createElement)("line",{x1:"21",y1:"21",x2:"16.65",y2:"16.65"}))};function Bu(e){var t=e.selectedOption,n=e.options,r=e.focusedIndex,o=e.onOptionClicked,i=e.optionItemRenderer;if(e.empty)return(0,s.createElement)("div",{className:"dropdown-selector-content--empty"},(0,s.createElement)(Fu,null),"No data");var a=i?function(e,t){return i(e,t)}:void 0,l=0,c=n.map((function(e){if(xu(e)){var n=e.groupOptions,i=e.label,c=n.map((function(e){var n=e.value===t,i=l===r;return l+=1,(0,s.createElement)(Mu,{key:e.value,optionClass:e.className,onOptionClicked:o,option:e,itemRenderer:a,index:l-1,selected:n,focused:i})}));return c.length?(0,s.createElement)(Iu,{key:e.label},(0,s.createElement)(ju,null,(0,s.createElement)("span",null,i.toUpperCase()," |  "),(0,s.createElement)("span",null,n.length)),c,(0,s.createElement)(Lu,null)):null}var u=e.value,p=e.className,f=l===r;return(0,s.createElement)(Mu,{key:u,optionClass:p,onOptionClicked:o,option:e,itemRenderer:a,index:(l+=1)-1,selected:u===t,focused:f})}));return(0,s.createElement)(s.Fragment,null,c)}var $u={height:0,width:0,overflow:"hidden"},qu=function(e,t,n){var r=e.map((function(e,r){return(0,s.createElement)("div",{role:"option",id:n+"_list_"+r,key:n+"_list_"+r,"aria-selected":r===t,"aria-label":e.value})}));return(0,s.createElement)("div",{role:"listbox",id:n+"_list",style:$u},r)},Wu=function(e,t,n){var r=(0,s.useState)(""),o=r[0],i=r[1],a=(0,s.useMemo)((function(){return n&&o.trim()?function(e,t){for(var n=[],r=t.toLowerCase(),o=0,i=e;o<i.length;o++){var a=i[o];if(xu(a)){var s=ku(a,r);s.groupOptions.length>=0&&n.push(s)}else a.value.toLowerCase().includes(r)&&n.push(a)}return n}(t,o):t}),[t,o]),l=(0,s.useCallback)((function(t,n){void 0===n&&(n=!0),i(t),n&&e(0)}),[e,i]);return{searchTerm:o,setSearchTerm:l,filteredOptions:a}},Vu=0,Hu=function(e){var t,n=e.arrowRenderer,r=e.contentClassName,o=e.className,i=e.disabled,a=e.hideArrow,l=e.id,c=e.optionItemRenderer,u=e.pageKeyTraverseSize,p=e.placeholder,f=e.searchable,d=e.value,h=e.selectedValueClassName,m=(0,s.useMemo)((function(){return t||(e=Vu,Vu+=1,"react_dropdown_aria_"+e);var e}),[t=l]),v=function(e,t){var n=e.options,r=e.searchable,o=e.onChange,i=e.disabled,a=e.ariaDescribedBy,l=e.ariaLabel,c=e.ariaLabelledBy,u=e.value,p=e.defaultOpen,f=(0,s.useState)(0),d=f[0],h=f[1],m=(0,s.useState)(p),v=m[0],g=m[1],y=(0,s.useRef)(null),b=(0,s.useRef)(null),x=(0,s.useRef)(null),w=(0,s.useState)(!1),k=w[0],E=w[1],S=Wu(h,n,r),_=S.searchTerm,O=S.setSearchTerm,A=S.filteredOptions,R=(0,s.useMemo)((function(){return A.reduce(wu,[])}),[A]),C=(0,s.useMemo)((function(){return R.map((function(e){return e.value})).indexOf(u)}),[R,u]),T=(0,s.useCallback)((function(e){void 0===e&&(e=!1),O("",!1),g(!1),e&&b.current&&b.current.focus()}),[b.current,O,g]),P=(0,s.useCallback)((function(){h(C>0?C:0),g(!0)}),[g,h,C]),I=(0,s.useCallback)((function(e,t){void 0===t&&(t=!1),e&&(o(e),O("",!1)),t&&T(!0)}),[o,T,O]);!function(e,t){var n=function(n){t.current&&!t.current.contains(n.target)&&e()};(0,s.useEffect)((function(){return document.addEventListener("mouseup",n,!1),document.addEventListener("touchend",n,!1),function(){document.removeEventListener("mouseup",n),document.removeEventListener("touchend",n)}}),[])}(T,y),function(e,t){(0,s.useEffect)((function(){if(t.current&&e>=0){var n=t.current.getElementsByClassName("dropdown-option"),r=n&&n.length?n[e]:null;if(r&&r.getBoundingClientRect){var o=r.getBoundingClientRect().height,i=t.current.getBoundingClientRect().height,a=t.current.scrollTop,s=r.offsetTop<=a;r.offsetTop>=a&&r.offsetTop+o<=a+i||(s?t.current.scrollTo({top:r.offsetTop}):t.current.scrollTo({top:r.offsetTop-i+o+8}))}}}),[e])}(d,x);var j=(0,s.useMemo)((function(){return{"aria-hidden":i,"aria-expanded":v,"aria-haspopup":"listbox","aria-activedescendant":t+"_list_"+d,"aria-controls":t+"_list","aria-label":l,"aria-labelledby":c,"aria-describedby":a}}),[i,v,t,d,l,c,a]);return{focusedIndex:d,setFocusedIndex:h,open:v,setOpen:g,searchTerm:_,setSearchTerm:O,dropdownFocused:k,setDropdownFocused:E,setValue:I,filteredOptions:A,openDropdown:P,closeDropdown:T,flattenedOptions:R,container:y,inputRef:b,listWrapper:x,ariaProps:j,ariaList:qu(R,C,t)}}(e,m),g=v.open,y=v.dropdownFocused,b=v.focusedIndex,x=v.setFocusedIndex,w=v.setDropdownFocused,k=v.setValue,E=v.openDropdown,S=v.closeDropdown,_=v.searchTerm,O=v.setSearchTerm,A=v.filteredOptions,R=v.flattenedOptions,C=v.container,T=v.inputRef,P=v.listWrapper,I=v.ariaProps,j=v.ariaList,L=(0,s.useCallback)((function(){T.current&&T.current.focus()}),[T.current]),N=(0,s.useCallback)((function(){L(),i||g&&f||(g?S(!0):E())}),[g,i,f,S,E]),M=(0,s.useCallback)((function(e){switch(e){case Yc.UP_ARROW:x((function(e){return 0===e?R.length-1:e-1}));break;case Yc.DOWN_ARROW:x((function(e){return(e+1)%R.length}));break;case Yc.PAGE_UP:x((function(e){return e-u<0&&0!==e?0:e-u<0?R.length-1:e-u}));break;case Yc.PAGE_DOWN:x((function(e){return e===R.length-1?0:e+u>R.length-1?R.length-1:(e+u)%R.length}));break;case Yc.ESCAPE:S(!0)}}),[x,R,u,S]),D=(0,s.useCallback)((function(e){var t=e.keyCode;-1!==du.indexOf(t)?(e.preventDefault(),e.stopPropagation(),M(t)):t!==Yc.ENTER&&(t!==Yc.SPACE||f)||g?t!==Yc.TAB||f?(t===Yc.TAB||t===Yc.ENTER)&&R.length>0&&b>=0&&g&&(e.stopPropagation(),e.preventDefault(),k(R[b],!0)):S():(e.preventDefault(),E())}),[R,k,b,g,M,E,f,S]),F=(0,s.useCallback)((function(e){O(e.target.value)}),[O]),z=(0,s.useCallback)((function(){return w(!0)}),[w]),U=(0,s.useCallback)((function(){return w(!1)}),[w]),B=(0,s.useMemo)((function(){if(a)return null;if(n)return(0,s.createElement)(Tu,{className:"dropdown-arrow"},n(g));var e=g&&f;return(0,s.createElement)(Tu,{className:"dropdown-arrow"},e&&(0,s.createElement)(Uu,null),!e&&(0,s.createElement)(zu,null))}),[g,n,f,a]);return(0,s.createElement)(Su,{ref:C,onFocus:L,onClick:N,role:"button",width:e.width,height:e.height,disabled:i,open:g,dropdownFocused:y,className:Eu("dropdown",o)},(0,s.createElement)(_u,{className:"dropdown-selector",open:g,searchable:e.searchable},(0,s.createElement)(Ou,{className:"dropdown-selector-search"},(0,s.createElement)("input",fu({id:m,ref:T,value:_,onChange:F,onKeyDown:D,onFocus:z,onBlur:U,readOnly:!g||!f,disabled:i,autoComplete:"off",role:"combobox"},I))),!d&&!_&&(0,s.createElement)(Cu,{className:"dropdown-selector-placeholder",centerText:e.centerText},p),d&&!_&&(0,s.createElement)(Ru,{className:Eu("dropdown-selector-value",h),centerText:e.centerText,value:d,open:g},d),B),j,(0,s.createElement)(Pu,{maxContentHeight:e.maxContentHeight,openUp:e.openUp,open:g,className:Eu("dropdown-selector-content",r),ref:P},(0,s.createElement)(Bu,{selectedOption:d,options:A,focusedIndex:b,onOptionClicked:k,optionItemRenderer:c,empty:0===R.length})))};Hu.defaultProps={ariaDescribedBy:null,ariaLabel:null,ariaLabelledBy:null,arrowRenderer:void 0,centerText:!1,className:void 0,contentClassName:null,defaultOpen:!1,disabled:!1,height:null,hideArrow:!1,id:null,maxContentHeight:150,openUp:!1,optionItemRenderer:void 0,pageKeyTraverseSize:10,placeholder:"Select ...",searchable:!1,selectedValueClassName:null,style:{},value:void 0,width:null};var Yu,Gu,Qu,Xu=Sa(Hu)(Yu||(Yu=Co(["\n  && {\n    box-sizing: border-box;\n    min-width: 100px;\n    outline: none;\n    display: inline-block;\n    border-radius: 2px;\n    border: 1px solid rgba(38, 50, 56, 0.5);\n    vertical-align: bottom;\n    padding: 2px 0px 2px 6px;\n    position: relative;\n    width: auto;\n    background: white;\n    color: #263238;\n    font-family: ",";\n    font-size: 0.929em;\n    line-height: 1.5em;\n    cursor: pointer;\n    transition: border 0.25s ease, color 0.25s ease, box-shadow 0.25s ease;\n    &:hover,\n    &:focus-within {\n      border: 1px solid ",";\n      color: ",";\n      box-shadow: 0px 0px 0px 1px ",";\n    }\n    .dropdown-selector {\n      display: inline-flex;\n      padding: 0;\n      height: auto;\n      padding-right: 20px;\n      position: relative;\n      margin-bottom: 5px;\n    }\n    .dropdown-selector-value {\n      font-family: ",";\n      position: relative;\n      font-size: 0.929em;\n      width: 100%;\n      line-height: 1;\n      vertical-align: middle;\n      color: #263238;\n      left: 0;\n      transition: color 0.25s ease, text-shadow 0.25s ease;\n    }\n    .dropdown-arrow {\n      position: absolute;\n      right: 3px;\n      top: 50%;\n      transform: translateY(-50%);\n      border-color: "," transparent transparent;\n      border-style: solid;\n      border-width: 0.35em 0.35em 0;\n      width: 0;\n      svg {\n        display: none;\n      }\n    }\n\n    .dropdown-selector-content {\n      position: absolute;\n      margin-top: 2px;\n      left: -2px;\n      right: 0;\n\n      z-index: 10;\n      min-width: 100px;\n\n      background: white;\n      border: 1px solid rgba(38, 50, 56, 0.2);\n      box-shadow: 0px 2px 4px 0px rgba(34, 36, 38, 0.12), 0px 2px 10px 0px rgba(34, 36, 38, 0.08);\n\n      max-height: 220px;\n      overflow: auto;\n    }\n\n    .dropdown-option {\n      font-size: 0.9em;\n      color: #263238;\n      cursor: pointer;\n      padding: 0.4em;\n      background-color: #ffffff;\n\n      &[aria-selected='true'] {\n        background-color: rgba(0, 0, 0, 0.05);\n      }\n\n      &:hover {\n        background-color: rgba(38, 50, 56, 0.12);\n      }\n    }\n    input {\n      cursor: pointer;\n      height: 1px;\n      background-color: transparent;\n    }\n  }\n"])),(function(e){return e.theme.typography.headings.fontFamily}),(function(e){return e.theme.colors.primary.main}),(function(e){return e.theme.colors.primary.main}),(function(e){return e.theme.colors.primary.main}),(function(e){return e.theme.typography.headings.fontFamily}),(function(e){return e.theme.colors.primary.main})),Ku=Sa(Xu)(Gu||(Gu=Co(["\n  && {\n    margin-left: 10px;\n    text-transform: none;\n    font-size: 0.969em;\n\n    font-size: 1em;\n    border: none;\n    padding: 0 1.2em 0 0;\n    background: transparent;\n\n    &:hover,\n    &:focus-within {\n      border: none;\n      box-shadow: none;\n      .dropdown-selector-value {\n        color: ",";\n        text-shadow: 0px 0px 0px ",";\n      }\n    }\n  }\n"])),(function(e){return e.theme.colors.primary.main}),(function(e){return e.theme.colors.primary.main})),Zu=Sa.span(Qu||(Qu=Co(["\n  margin-left: 10px;\n  text-transform: none;\n  font-size: 0.929em;\n  color: black;\n"])));function Ju(e){return function(t){return!!t.type&&t.type.tabsRole===e}}var ep=Ju("Tab"),tp=Ju("TabList"),np=Ju("TabPanel");function rp(){return(rp=Object.assign||function(e){for(var t=1;t<arguments.length;t++){var n=arguments[t];for(var r in n)Object.prototype.hasOwnProperty.call(n,r)&&(e[r]=n[r])}return e}).apply(this,arguments)}function op(e,t){return s.Children.map(e,(function(e){return null===e?null:function(e){return ep(e)||tp(e)||np(e)}(e)?t(e):e.props&&e.props.children&&"object"==typeof e.props.children?(0,s.cloneElement)(e,rp({},e.props,{children:op(e.props.children,t)})):e}))}function ip(e,t){return s.Children.forEach(e,(function(e){null!==e&&(ep(e)||np(e)?t(e):e.props&&e.props.children&&"object"==typeof e.props.children&&(tp(e)&&t(e),ip(e.props.children,t)))}))}function ap(e){var t,n,r="";if("string"==typeof e||"number"==typeof e)r+=e;else if("object"==typeof e)if(Array.isArray(e))for(t=0;t<e.length;t++)e[t]&&(n=ap(e[t]))&&(r&&(r+=" "),r+=n);else for(t in e)e[t]&&(r&&(r+=" "),r+=t);return r}function sp(){for(var e,t,n=0,r="";n<arguments.length;)(e=arguments[n++])&&(t=ap(e))&&(r&&(r+=" "),r+=t);return r}var lp,cp=0;function up(){return"react-tabs-"+cp++}function pp(e){var t=0;return ip(e,(function(e){ep(e)&&t++})),t}function fp(){return(fp=Object.assign||function(e){for(var t=1;t<arguments.length;t++){var n=arguments[t];for(var r in n)Object.prototype.hasOwnProperty.call(n,r)&&(e[r]=n[r])}return e}).apply(this,arguments)}function dp(e,t){return(dp=Object.setPrototypeOf||function(e,t){return e.__proto__=t,e})(e,t)}function hp(e){return e&&"getAttribute"in e}function mp(e){return hp(e)&&"tab"===e.getAttribute("role")}function vp(e){return hp(e)&&"true"===e.getAttribute("aria-disabled")}var gp=function(e){var t,n;function r(){for(var t,n=arguments.length,r=new Array(n),o=0;o<n;o++)r[o]=arguments[o];return(t=e.call.apply(e,[this].concat(r))||this).tabNodes=[],t.handleKeyDown=function(e){var n=t.props,r=n.direction,o=n.disableUpDownKeys;if(t.isTabFromContainer(e.target)){var i=t.props.selectedIndex,a=!1,s=!1;32!==e.keyCode&&13!==e.keyCode||(a=!0,s=!1,t.handleClick(e)),37===e.keyCode||!o&&38===e.keyCode?(i="rtl"===r?t.getNextTab(i):t.getPrevTab(i),a=!0,s=!0):39===e.keyCode||!o&&40===e.keyCode?(i="rtl"===r?t.getPrevTab(i):t.getNextTab(i),a=!0,s=!0):35===e.keyCode?(i=t.getLastTab(),a=!0,s=!0):36===e.keyCode&&(i=t.getFirstTab(),a=!0,s=!0),a&&e.preventDefault(),s&&t.setSelected(i,e)}},t.handleClick=function(e){var n=e.target;do{if(t.isTabFromContainer(n)){if(vp(n))return;var r=[].slice.call(n.parentNode.children).filter(mp).indexOf(n);return void t.setSelected(r,e)}}while(null!=(n=n.parentNode))},t}n=e,(t=r).prototype=Object.create(n.prototype),t.prototype.constructor=t,dp(t,n);var o=r.prototype;return o.setSelected=function(e,t){if(!(e<0||e>=this.getTabsCount())){var n=this.props;(0,n.onSelect)(e,n.selectedIndex,t)}},o.getNextTab=function(e){for(var t=this.getTabsCount(),n=e+1;n<t;n++)if(!vp(this.getTab(n)))return n;for(var r=0;r<e;r++)if(!vp(this.getTab(r)))return r;return e},o.getPrevTab=function(e){for(var t=e;t--;)if(!vp(this.getTab(t)))return t;for(t=this.getTabsCount();t-- >e;)if(!vp(this.getTab(t)))return t;return e},o.getFirstTab=function(){for(var e=this.getTabsCount(),t=0;t<e;t++)if(!vp(this.getTab(t)))return t;return null},o.getLastTab=function(){for(var e=this.getTabsCount();e--;)if(!vp(this.getTab(e)))return e;return null},o.getTabsCount=function(){return pp(this.props.children)},o.getPanelsCount=function(){return e=this.props.children,t=0,ip(e,(function(e){np(e)&&t++})),t;var e,t},o.getTab=function(e){return this.tabNodes["tabs-"+e]},o.getChildren=function(){var e=this,t=0,n=this.props,r=n.children,o=n.disabledTabClassName,i=n.focus,a=n.forceRenderTabPanel,l=n.selectedIndex,c=n.selectedTabClassName,u=n.selectedTabPanelClassName,p=n.environment;this.tabIds=this.tabIds||[],this.panelIds=this.panelIds||[];for(var f=this.tabIds.length-this.getTabsCount();f++<0;)this.tabIds.push(up()),this.panelIds.push(up());return op(r,(function(n){var r=n;if(tp(n)){var f=0,d=!1;null==lp&&function(e){var t=e||("undefined"!=typeof window?window:void 0);try{lp=!(void 0===t||!t.document||!t.document.activeElement)}catch(e){lp=!1}}(p),lp&&(d=s.Children.toArray(n.props.children).filter(ep).some((function(t,n){var r=p||("undefined"!=typeof window?window:void 0);return r&&r.document.activeElement===e.getTab(n)}))),r=(0,s.cloneElement)(n,{children:op(n.props.children,(function(t){var n="tabs-"+f,r=l===f,a={tabRef:function(t){e.tabNodes[n]=t},id:e.tabIds[f],panelId:e.panelIds[f],selected:r,focus:r&&(i||d)};return c&&(a.selectedClassName=c),o&&(a.disabledClassName=o),f++,(0,s.cloneElement)(t,a)}))})}else if(np(n)){var h={id:e.panelIds[t],tabId:e.tabIds[t],selected:l===t};a&&(h.forceRender=a),u&&(h.selectedClassName=u),t++,r=(0,s.cloneElement)(n,h)}return r}))},o.isTabFromContainer=function(e){if(!mp(e))return!1;var t=e.parentElement;do{if(t===this.node)return!0;if(t.getAttribute("data-tabs"))break;t=t.parentElement}while(t);return!1},o.render=function(){var e=this,t=this.props,n=(t.children,t.className),r=(t.disabledTabClassName,t.domRef),o=(t.focus,t.forceRenderTabPanel,t.onSelect,t.selectedIndex,t.selectedTabClassName,t.selectedTabPanelClassName,t.environment,t.disableUpDownKeys,function(e,t){if(null==e)return{};var n,r,o={},i=Object.keys(e);for(r=0;r<i.length;r++)n=i[r],t.indexOf(n)>=0||(o[n]=e[n]);return o}(t,["children","className","disabledTabClassName","domRef","focus","forceRenderTabPanel","onSelect","selectedIndex","selectedTabClassName","selectedTabPanelClassName","environment","disableUpDownKeys"]));return s.createElement("div",fp({},o,{className:sp(n),onClick:this.handleClick,onKeyDown:this.handleKeyDown,ref:function(t){e.node=t,r&&r(t)},"data-tabs":!0}),this.getChildren())},r}(s.Component);function yp(e,t){return(yp=Object.setPrototypeOf||function(e,t){return e.__proto__=t,e})(e,t)}gp.defaultProps={className:"react-tabs",focus:!1},gp.propTypes={};var bp=function(e){var t,n;function r(t){var n;return(n=e.call(this,t)||this).handleSelected=function(e,t,r){var o=n.props.onSelect,i=n.state.mode;if("function"!=typeof o||!1!==o(e,t,r)){var a={focus:"keydown"===r.type};1===i&&(a.selectedIndex=e),n.setState(a)}},n.state=r.copyPropsToState(n.props,{},t.defaultFocus),n}return n=e,(t=r).prototype=Object.create(n.prototype),t.prototype.constructor=t,yp(t,n),r.getDerivedStateFromProps=function(e,t){return r.copyPropsToState(e,t)},r.getModeFromProps=function(e){return null===e.selectedIndex?1:0},r.copyPropsToState=function(e,t,n){void 0===n&&(n=!1);var o={focus:n,mode:r.getModeFromProps(e)};if(1===o.mode){var i,a=Math.max(0,pp(e.children)-1);i=null!=t.selectedIndex?Math.min(t.selectedIndex,a):e.defaultIndex||0,o.selectedIndex=i}return o},r.prototype.render=function(){var e=this.props,t=e.children,n=(e.defaultIndex,e.defaultFocus,function(e,t){if(null==e)return{};var n,r,o={},i=Object.keys(e);for(r=0;r<i.length;r++)n=i[r],t.indexOf(n)>=0||(o[n]=e[n]);return o}(e,["children","defaultIndex","defaultFocus"])),r=this.state,o=r.focus,i=r.selectedIndex;return n.focus=o,n.onSelect=this.handleSelected,null!=i&&(n.selectedIndex=i),s.createElement(gp,n,t)},r}(s.Component);function xp(){return(xp=Object.assign||function(e){for(var t=1;t<arguments.length;t++){var n=arguments[t];for(var r in n)Object.prototype.hasOwnProperty.call(n,r)&&(e[r]=n[r])}return e}).apply(this,arguments)}function wp(e,t){return(wp=Object.setPrototypeOf||function(e,t){return e.__proto__=t,e})(e,t)}bp.defaultProps={defaultFocus:!1,forceRenderTabPanel:!1,selectedIndex:null,defaultIndex:null,environment:null,disableUpDownKeys:!1},bp.propTypes={},bp.tabsRole="Tabs";var kp=function(e){var t,n;function r(){return e.apply(this,arguments)||this}return n=e,(t=r).prototype=Object.create(n.prototype),t.prototype.constructor=t,wp(t,n),r.prototype.render=function(){var e=this.props,t=e.children,n=e.className,r=function(e,t){if(null==e)return{};var n,r,o={},i=Object.keys(e);for(r=0;r<i.length;r++)n=i[r],t.indexOf(n)>=0||(o[n]=e[n]);return o}(e,["children","className"]);return s.createElement("ul",xp({},r,{className:sp(n),role:"tablist"}),t)},r}(s.Component);function Ep(){return(Ep=Object.assign||function(e){for(var t=1;t<arguments.length;t++){var n=arguments[t];for(var r in n)Object.prototype.hasOwnProperty.call(n,r)&&(e[r]=n[r])}return e}).apply(this,arguments)}function Sp(e,t){return(Sp=Object.setPrototypeOf||function(e,t){return e.__proto__=t,e})(e,t)}kp.defaultProps={className:"react-tabs__tab-list"},kp.propTypes={},kp.tabsRole="TabList";var _p="react-tabs__tab",Op=function(e){var t,n;function r(){return e.apply(this,arguments)||this}n=e,(t=r).prototype=Object.create(n.prototype),t.prototype.constructor=t,Sp(t,n);var o=r.prototype;return o.componentDidMount=function(){this.checkFocus()},o.componentDidUpdate=function(){this.checkFocus()},o.checkFocus=function(){var e=this.props,t=e.selected,n=e.focus;t&&n&&this.node.focus()},o.render=function(){var e,t=this,n=this.props,r=n.children,o=n.className,i=n.disabled,a=n.disabledClassName,l=(n.focus,n.id),c=n.panelId,u=n.selected,p=n.selectedClassName,f=n.tabIndex,d=n.tabRef,h=function(e,t){if(null==e)return{};var n,r,o={},i=Object.keys(e);for(r=0;r<i.length;r++)n=i[r],t.indexOf(n)>=0||(o[n]=e[n]);return o}(n,["children","className","disabled","disabledClassName","focus","id","panelId","selected","selectedClassName","tabIndex","tabRef"]);return s.createElement("li",Ep({},h,{className:sp(o,(e={},e[p]=u,e[a]=i,e)),ref:function(e){t.node=e,d&&d(e)},role:"tab",id:l,"aria-selected":u?"true":"false","aria-disabled":i?"true":"false","aria-controls":c,tabIndex:f||(u?"0":null)}),r)},r}(s.Component);function Ap(){return(Ap=Object.assign||function(e){for(var t=1;t<arguments.length;t++){var n=arguments[t];for(var r in n)Object.prototype.hasOwnProperty.call(n,r)&&(e[r]=n[r])}return e}).apply(this,arguments)}function Rp(e,t){return(Rp=Object.setPrototypeOf||function(e,t){return e.__proto__=t,e})(e,t)}Op.defaultProps={className:_p,disabledClassName:_p+"--disabled",focus:!1,id:null,panelId:null,selected:!1,selectedClassName:_p+"--selected"},Op.propTypes={},Op.tabsRole="Tab";var Cp,Tp,Pp=function(e){var t,n;function r(){return e.apply(this,arguments)||this}return n=e,(t=r).prototype=Object.create(n.prototype),t.prototype.constructor=t,Rp(t,n),r.prototype.render=function(){var e,t=this.props,n=t.children,r=t.className,o=t.forceRender,i=t.id,a=t.selected,l=t.selectedClassName,c=t.tabId,u=function(e,t){if(null==e)return{};var n,r,o={},i=Object.keys(e);for(r=0;r<i.length;r++)n=i[r],t.indexOf(n)>=0||(o[n]=e[n]);return o}(t,["children","className","forceRender","id","selected","selectedClassName","tabId"]);return s.createElement("div",Ap({},u,{className:sp(r,(e={},e[l]=a,e)),role:"tabpanel",id:i,"aria-labelledby":c}),o||a?n:null)},r}(s.Component);Pp.defaultProps={className:"react-tabs__tab-panel",forceRender:!1,selectedClassName:"react-tabs__tab-panel--selected"},Pp.propTypes={},Pp.tabsRole="TabPanel";var Ip,jp,Lp,Np,Mp=Sa(bp)(Cp||(Cp=Co(["\n  > ul {\n    list-style: none;\n    padding: 0;\n    margin: 0;\n    margin: 0 -5px;\n\n    > li {\n      padding: 5px 10px;\n      display: inline-block;\n\n      background-color: ",";\n      border-bottom: 1px solid rgba(0, 0, 0, 0.5);\n      cursor: pointer;\n      text-align: center;\n      outline: none;\n      color: ",";\n      margin: 0\n        ",";\n      border: 1px solid ",";\n      border-radius: 5px;\n      min-width: 60px;\n      font-size: 0.9em;\n      font-weight: bold;\n\n      &.react-tabs__tab--selected {\n        color: ",";\n        background: ",";\n        &:focus {\n          outline: auto;\n        }\n      }\n\n      &:only-child {\n        flex: none;\n        min-width: 100px;\n      }\n\n      &.tab-success {\n        color: ",";\n      }\n\n      &.tab-redirect {\n        color: ",";\n      }\n\n      &.tab-info {\n        color: ",";\n      }\n\n      &.tab-error {\n        color: ",";\n      }\n    }\n  }\n  > .react-tabs__tab-panel {\n    background: ",";\n    & > div,\n    & > pre {\n      padding: ","px;\n      margin: 0;\n    }\n\n    & > div > pre {\n      padding: 0;\n    }\n  }\n"])),(function(e){return e.theme.codeBlock.backgroundColor}),(function(e){var t=e.theme;return zr(t.colors.tonalOffset,t.rightPanel.textColor)}),(function(e){var t=e.theme;return"".concat(t.spacing.unit,"px ").concat(t.spacing.unit,"px ").concat(t.spacing.unit,"px")}),(function(e){var t=e.theme;return zr(.05,t.codeBlock.backgroundColor)}),(function(e){return e.theme.colors.text.primary}),(function(e){return e.theme.rightPanel.textColor}),(function(e){return e.theme.colors.responses.success.tabTextColor}),(function(e){return e.theme.colors.responses.redirect.tabTextColor}),(function(e){return e.theme.colors.responses.info.tabTextColor}),(function(e){return e.theme.colors.responses.error.tabTextColor}),(function(e){return e.theme.codeBlock.backgroundColor}),(function(e){return 4*e.theme.spacing.unit})),Dp=(Sa(Mp)(Tp||(Tp=Co(["\n  > ul {\n    display: block;\n    > li {\n      padding: 2px 5px;\n      min-width: auto;\n      margin: 0 15px 0 0;\n      font-size: 13px;\n      font-weight: normal;\n      border-bottom: 1px dashed;\n      color: ",";\n      border-radius: 0;\n      background: none;\n\n      &:last-child {\n        margin-right: 0;\n      }\n\n      &.react-tabs__tab--selected {\n        color: ",";\n        background: none;\n      }\n    }\n  }\n  > .react-tabs__tab-panel {\n    & > div,\n    & > pre {\n      padding: ","px 0;\n    }\n  }\n"])),(function(e){var t=e.theme;return zr(t.colors.tonalOffset,t.rightPanel.textColor)}),(function(e){return e.theme.rightPanel.textColor}),(function(e){return 2*e.theme.spacing.unit})),Sa.div(Ip||(Ip=Co(["\n  /**\n  * Based on prism-dark.css\n  */\n\n  code[class*='language-'],\n  pre[class*='language-'] {\n    /* color: white;\n    background: none; */\n    text-shadow: 0 -0.1em 0.2em black;\n    text-align: left;\n    white-space: pre;\n    word-spacing: normal;\n    word-break: normal;\n    word-wrap: normal;\n    line-height: 1.5;\n\n    -moz-tab-size: 4;\n    -o-tab-size: 4;\n    tab-size: 4;\n\n    -webkit-hyphens: none;\n    -moz-hyphens: none;\n    -ms-hyphens: none;\n    hyphens: none;\n  }\n\n  @media print {\n    code[class*='language-'],\n    pre[class*='language-'] {\n      text-shadow: none;\n    }\n  }\n\n  /* Code blocks */\n  pre[class*='language-'] {\n    padding: 1em;\n    margin: 0.5em 0;\n    overflow: auto;\n  }\n\n  .token.comment,\n  .token.prolog,\n  .token.doctype,\n  .token.cdata {\n    color: hsl(30, 20%, 50%);\n  }\n\n  .token.punctuation {\n    opacity: 0.7;\n  }\n\n  .namespace {\n    opacity: 0.7;\n  }\n\n  .token.property,\n  .token.tag,\n  .token.number,\n  .token.constant,\n  .token.symbol {\n    color: #4a8bb3;\n  }\n\n  .token.boolean {\n    color: #e64441;\n  }\n\n  .token.selector,\n  .token.attr-name,\n  .token.string,\n  .token.char,\n  .token.builtin,\n  .token.inserted {\n    color: #a0fbaa;\n    & + a,\n    & + a:visited {\n      color: #4ed2ba;\n      text-decoration: underline;\n    }\n  }\n\n  .token.property.string {\n    color: white;\n  }\n\n  .token.operator,\n  .token.entity,\n  .token.url,\n  .token.variable {\n    color: hsl(40, 90%, 60%);\n  }\n\n  .token.atrule,\n  .token.attr-value,\n  .token.keyword {\n    color: hsl(350, 40%, 70%);\n  }\n\n  .token.regex,\n  .token.important {\n    color: #e90;\n  }\n\n  .token.important,\n  .token.bold {\n    font-weight: bold;\n  }\n  .token.italic {\n    font-style: italic;\n  }\n\n  .token.entity {\n    cursor: help;\n  }\n\n  .token.deleted {\n    color: red;\n  }\n\n  ",";\n"])),_a("Prism"))),Fp=Sa.div(jp||(jp=Co(["\n  opacity: 0.7;\n  transition: opacity 0.3s ease;\n  text-align: right;\n  &:focus-within {\n    opacity: 1;\n  }\n  > button {\n    background-color: transparent;\n    border: 0;\n    color: inherit;\n    padding: 2px 10px;\n    font-family: ",";\n    font-size: ",";\n    line-height: ",";\n    cursor: pointer;\n    outline: 0;\n\n    :hover,\n    :focus {\n      background: rgba(255, 255, 255, 0.1);\n    }\n  }\n"])),(function(e){return e.theme.typography.fontFamily}),(function(e){return e.theme.typography.fontSize}),(function(e){return e.theme.typography.lineHeight})),zp=Sa.div(Lp||(Lp=Co(["\n  &:hover "," {\n    opacity: 1;\n  }\n"])),Fp),Up=Sa(Dp.withComponent("pre"))(Np||(Np=Co(["\n  font-family: ",";\n  font-size: ",";\n  overflow-x: auto;\n  margin: 0;\n\n  white-space: ",";\n"])),(function(e){return e.theme.typography.code.fontFamily}),(function(e){return e.theme.typography.code.fontSize}),(function(e){return e.theme.typography.code.wrap?"pre-wrap":"pre"}));function Bp(e){return getComputedStyle(e)}function $p(e,t){for(var n in t){var r=t[n];"number"==typeof r&&(r+="px"),e.style[n]=r}return e}function qp(e){var t=document.createElement("div");return t.className=e,t}var Wp="undefined"!=typeof Element&&(Element.prototype.matches||Element.prototype.webkitMatchesSelector||Element.prototype.mozMatchesSelector||Element.prototype.msMatchesSelector);function Vp(e,t){if(!Wp)throw new Error("No element matching method supported");return Wp.call(e,t)}function Hp(e){e.remove?e.remove():e.parentNode&&e.parentNode.removeChild(e)}function Yp(e,t){return Array.prototype.filter.call(e.children,(function(e){return Vp(e,t)}))}var Gp=function(e){return"ps__thumb-"+e},Qp=function(e){return"ps__rail-"+e},Xp="ps__child--consume",Kp="ps--focus",Zp="ps--clicking",Jp=function(e){return"ps--active-"+e},ef=function(e){return"ps--scrolling-"+e},tf={x:null,y:null};function nf(e,t){var n=e.element.classList,r=ef(t);n.contains(r)?clearTimeout(tf[t]):n.add(r)}function rf(e,t){tf[t]=setTimeout((function(){return e.isAlive&&e.element.classList.remove(ef(t))}),e.settings.scrollingThreshold)}var of=function(e){this.element=e,this.handlers={}},af={isEmpty:{configurable:!0}};of.prototype.bind=function(e,t){void 0===this.handlers[e]&&(this.handlers[e]=[]),this.handlers[e].push(t),this.element.addEventListener(e,t,!1)},of.prototype.unbind=function(e,t){var n=this;this.handlers[e]=this.handlers[e].filter((function(r){return!(!t||r===t)||(n.element.removeEventListener(e,r,!1),!1)}))},of.prototype.unbindAll=function(){for(var e in this.handlers)this.unbind(e)},af.isEmpty.get=function(){var e=this;return Object.keys(this.handlers).every((function(t){return 0===e.handlers[t].length}))},Object.defineProperties(of.prototype,af);var sf=function(){this.eventElements=[]};function lf(e){if("function"==typeof window.CustomEvent)return new CustomEvent(e);var t=document.createEvent("CustomEvent");return t.initCustomEvent(e,!1,!1,void 0),t}function cf(e,t,n,r,o){var i;if(void 0===r&&(r=!0),void 0===o&&(o=!1),"top"===t)i=["contentHeight","containerHeight","scrollTop","y","up","down"];else{if("left"!==t)throw new Error("A proper axis should be provided");i=["contentWidth","containerWidth","scrollLeft","x","left","right"]}!function(e,t,n,r,o){var i=n[0],a=n[1],s=n[2],l=n[3],c=n[4],u=n[5];void 0===r&&(r=!0),void 0===o&&(o=!1);var p=e.element;e.reach[l]=null,p[s]<1&&(e.reach[l]="start"),p[s]>e[i]-e[a]-1&&(e.reach[l]="end"),t&&(p.dispatchEvent(lf("ps-scroll-"+l)),t<0?p.dispatchEvent(lf("ps-scroll-"+c)):t>0&&p.dispatchEvent(lf("ps-scroll-"+u)),r&&function(e,t){nf(e,t),rf(e,t)}(e,l)),e.reach[l]&&(t||o)&&p.dispatchEvent(lf("ps-"+l+"-reach-"+e.reach[l]))}(e,n,i,r,o)}function uf(e){return parseInt(e,10)||0}sf.prototype.eventElement=function(e){var t=this.eventElements.filter((function(t){return t.element===e}))[0];return t||(t=new of(e),this.eventElements.push(t)),t},sf.prototype.bind=function(e,t,n){this.eventElement(e).bind(t,n)},sf.prototype.unbind=function(e,t,n){var r=this.eventElement(e);r.unbind(t,n),r.isEmpty&&this.eventElements.splice(this.eventElements.indexOf(r),1)},sf.prototype.unbindAll=function(){this.eventElements.forEach((function(e){return e.unbindAll()})),this.eventElements=[]},sf.prototype.once=function(e,t,n){var r=this.eventElement(e),o=function(e){r.unbind(t,o),n(e)};r.bind(t,o)};var pf={isWebKit:"undefined"!=typeof document&&"WebkitAppearance"in document.documentElement.style,supportsTouch:"undefined"!=typeof window&&("ontouchstart"in window||"maxTouchPoints"in window.navigator&&window.navigator.maxTouchPoints>0||window.DocumentTouch&&document instanceof window.DocumentTouch),supportsIePointer:"undefined"!=typeof navigator&&navigator.msMaxTouchPoints,isChrome:"undefined"!=typeof navigator&&/Chrome/i.test(navigator&&navigator.userAgent)};function ff(e){var t=e.element,n=Math.floor(t.scrollTop),r=t.getBoundingClientRect();e.containerWidth=Math.ceil(r.width),e.containerHeight=Math.ceil(r.height),e.contentWidth=t.scrollWidth,e.contentHeight=t.scrollHeight,t.contains(e.scrollbarXRail)||(Yp(t,Qp("x")).forEach((function(e){return Hp(e)})),t.appendChild(e.scrollbarXRail)),t.contains(e.scrollbarYRail)||(Yp(t,Qp("y")).forEach((function(e){return Hp(e)})),t.appendChild(e.scrollbarYRail)),!e.settings.suppressScrollX&&e.containerWidth+e.settings.scrollXMarginOffset<e.contentWidth?(e.scrollbarXActive=!0,e.railXWidth=e.containerWidth-e.railXMarginWidth,e.railXRatio=e.containerWidth/e.railXWidth,e.scrollbarXWidth=df(e,uf(e.railXWidth*e.containerWidth/e.contentWidth)),e.scrollbarXLeft=uf((e.negativeScrollAdjustment+t.scrollLeft)*(e.railXWidth-e.scrollbarXWidth)/(e.contentWidth-e.containerWidth))):e.scrollbarXActive=!1,!e.settings.suppressScrollY&&e.containerHeight+e.settings.scrollYMarginOffset<e.contentHeight?(e.scrollbarYActive=!0,e.railYHeight=e.containerHeight-e.railYMarginHeight,e.railYRatio=e.containerHeight/e.railYHeight,e.scrollbarYHeight=df(e,uf(e.railYHeight*e.containerHeight/e.contentHeight)),e.scrollbarYTop=uf(n*(e.railYHeight-e.scrollbarYHeight)/(e.contentHeight-e.containerHeight))):e.scrollbarYActive=!1,e.scrollbarXLeft>=e.railXWidth-e.scrollbarXWidth&&(e.scrollbarXLeft=e.railXWidth-e.scrollbarXWidth),e.scrollbarYTop>=e.railYHeight-e.scrollbarYHeight&&(e.scrollbarYTop=e.railYHeight-e.scrollbarYHeight),function(e,t){var n={width:t.railXWidth},r=Math.floor(e.scrollTop);t.isRtl?n.left=t.negativeScrollAdjustment+e.scrollLeft+t.containerWidth-t.contentWidth:n.left=e.scrollLeft,t.isScrollbarXUsingBottom?n.bottom=t.scrollbarXBottom-r:n.top=t.scrollbarXTop+r,$p(t.scrollbarXRail,n);var o={top:r,height:t.railYHeight};t.isScrollbarYUsingRight?t.isRtl?o.right=t.contentWidth-(t.negativeScrollAdjustment+e.scrollLeft)-t.scrollbarYRight-t.scrollbarYOuterWidth-9:o.right=t.scrollbarYRight-e.scrollLeft:t.isRtl?o.left=t.negativeScrollAdjustment+e.scrollLeft+2*t.containerWidth-t.contentWidth-t.scrollbarYLeft-t.scrollbarYOuterWidth:o.left=t.scrollbarYLeft+e.scrollLeft,$p(t.scrollbarYRail,o),$p(t.scrollbarX,{left:t.scrollbarXLeft,width:t.scrollbarXWidth-t.railBorderXWidth}),$p(t.scrollbarY,{top:t.scrollbarYTop,height:t.scrollbarYHeight-t.railBorderYWidth})}(t,e),e.scrollbarXActive?t.classList.add(Jp("x")):(t.classList.remove(Jp("x")),e.scrollbarXWidth=0,e.scrollbarXLeft=0,t.scrollLeft=!0===e.isRtl?e.contentWidth:0),e.scrollbarYActive?t.classList.add(Jp("y")):(t.classList.remove(Jp("y")),e.scrollbarYHeight=0,e.scrollbarYTop=0,t.scrollTop=0)}function df(e,t){return e.settings.minScrollbarLength&&(t=Math.max(t,e.settings.minScrollbarLength)),e.settings.maxScrollbarLength&&(t=Math.min(t,e.settings.maxScrollbarLength)),t}function hf(e,t){var n=t[0],r=t[1],o=t[2],i=t[3],a=t[4],s=t[5],l=t[6],c=t[7],u=t[8],p=e.element,f=null,d=null,h=null;function m(t){t.touches&&t.touches[0]&&(t[o]=t.touches[0].pageY),p[l]=f+h*(t[o]-d),nf(e,c),ff(e),t.stopPropagation(),t.preventDefault()}function v(){rf(e,c),e[u].classList.remove(Zp),e.event.unbind(e.ownerDocument,"mousemove",m)}function g(t,a){f=p[l],a&&t.touches&&(t[o]=t.touches[0].pageY),d=t[o],h=(e[r]-e[n])/(e[i]-e[s]),a?e.event.bind(e.ownerDocument,"touchmove",m):(e.event.bind(e.ownerDocument,"mousemove",m),e.event.once(e.ownerDocument,"mouseup",v),t.preventDefault()),e[u].classList.add(Zp),t.stopPropagation()}e.event.bind(e[a],"mousedown",(function(e){g(e)})),e.event.bind(e[a],"touchstart",(function(e){g(e,!0)}))}var mf={"click-rail":function(e){e.element,e.event.bind(e.scrollbarY,"mousedown",(function(e){return e.stopPropagation()})),e.event.bind(e.scrollbarYRail,"mousedown",(function(t){var n=t.pageY-window.pageYOffset-e.scrollbarYRail.getBoundingClientRect().top>e.scrollbarYTop?1:-1;e.element.scrollTop+=n*e.containerHeight,ff(e),t.stopPropagation()})),e.event.bind(e.scrollbarX,"mousedown",(function(e){return e.stopPropagation()})),e.event.bind(e.scrollbarXRail,"mousedown",(function(t){var n=t.pageX-window.pageXOffset-e.scrollbarXRail.getBoundingClientRect().left>e.scrollbarXLeft?1:-1;e.element.scrollLeft+=n*e.containerWidth,ff(e),t.stopPropagation()}))},"drag-thumb":function(e){hf(e,["containerWidth","contentWidth","pageX","railXWidth","scrollbarX","scrollbarXWidth","scrollLeft","x","scrollbarXRail"]),hf(e,["containerHeight","contentHeight","pageY","railYHeight","scrollbarY","scrollbarYHeight","scrollTop","y","scrollbarYRail"])},keyboard:function(e){var t=e.element;e.event.bind(e.ownerDocument,"keydown",(function(n){if(!(n.isDefaultPrevented&&n.isDefaultPrevented()||n.defaultPrevented)&&(Vp(t,":hover")||Vp(e.scrollbarX,":focus")||Vp(e.scrollbarY,":focus"))){var r,o=document.activeElement?document.activeElement:e.ownerDocument.activeElement;if(o){if("IFRAME"===o.tagName)o=o.contentDocument.activeElement;else for(;o.shadowRoot;)o=o.shadowRoot.activeElement;if(Vp(r=o,"input,[contenteditable]")||Vp(r,"select,[contenteditable]")||Vp(r,"textarea,[contenteditable]")||Vp(r,"button,[contenteditable]"))return}var i=0,a=0;switch(n.which){case 37:i=n.metaKey?-e.contentWidth:n.altKey?-e.containerWidth:-30;break;case 38:a=n.metaKey?e.contentHeight:n.altKey?e.containerHeight:30;break;case 39:i=n.metaKey?e.contentWidth:n.altKey?e.containerWidth:30;break;case 40:a=n.metaKey?-e.contentHeight:n.altKey?-e.containerHeight:-30;break;case 32:a=n.shiftKey?e.containerHeight:-e.containerHeight;break;case 33:a=e.containerHeight;break;case 34:a=-e.containerHeight;break;case 36:a=e.contentHeight;break;case 35:a=-e.contentHeight;break;default:return}e.settings.suppressScrollX&&0!==i||e.settings.suppressScrollY&&0!==a||(t.scrollTop-=a,t.scrollLeft+=i,ff(e),function(n,r){var o=Math.floor(t.scrollTop);if(0===n){if(!e.scrollbarYActive)return!1;if(0===o&&r>0||o>=e.contentHeight-e.containerHeight&&r<0)return!e.settings.wheelPropagation}var i=t.scrollLeft;if(0===r){if(!e.scrollbarXActive)return!1;if(0===i&&n<0||i>=e.contentWidth-e.containerWidth&&n>0)return!e.settings.wheelPropagation}return!0}(i,a)&&n.preventDefault())}}))},wheel:function(e){var t=e.element;function n(n){var r=function(e){var t=e.deltaX,n=-1*e.deltaY;return void 0!==t&&void 0!==n||(t=-1*e.wheelDeltaX/6,n=e.wheelDeltaY/6),e.deltaMode&&1===e.deltaMode&&(t*=10,n*=10),t!=t&&n!=n&&(t=0,n=e.wheelDelta),e.shiftKey?[-n,-t]:[t,n]}(n),o=r[0],i=r[1];if(!function(e,n,r){if(!pf.isWebKit&&t.querySelector("select:focus"))return!0;if(!t.contains(e))return!1;for(var o=e;o&&o!==t;){if(o.classList.contains(Xp))return!0;var i=Bp(o);if(r&&i.overflowY.match(/(scroll|auto)/)){var a=o.scrollHeight-o.clientHeight;if(a>0&&(o.scrollTop>0&&r<0||o.scrollTop<a&&r>0))return!0}if(n&&i.overflowX.match(/(scroll|auto)/)){var s=o.scrollWidth-o.clientWidth;if(s>0&&(o.scrollLeft>0&&n<0||o.scrollLeft<s&&n>0))return!0}o=o.parentNode}return!1}(n.target,o,i)){var a=!1;e.settings.useBothWheelAxes?e.scrollbarYActive&&!e.scrollbarXActive?(i?t.scrollTop-=i*e.settings.wheelSpeed:t.scrollTop+=o*e.settings.wheelSpeed,a=!0):e.scrollbarXActive&&!e.scrollbarYActive&&(o?t.scrollLeft+=o*e.settings.wheelSpeed:t.scrollLeft-=i*e.settings.wheelSpeed,a=!0):(t.scrollTop-=i*e.settings.wheelSpeed,t.scrollLeft+=o*e.settings.wheelSpeed),ff(e),(a=a||function(n,r){var o=Math.floor(t.scrollTop),i=0===t.scrollTop,a=o+t.offsetHeight===t.scrollHeight,s=0===t.scrollLeft,l=t.scrollLeft+t.offsetWidth===t.scrollWidth;return!(Math.abs(r)>Math.abs(n)?i||a:s||l)||!e.settings.wheelPropagation}(o,i))&&!n.ctrlKey&&(n.stopPropagation(),n.preventDefault())}}void 0!==window.onwheel?e.event.bind(t,"wheel",n):void 0!==window.onmousewheel&&e.event.bind(t,"mousewheel",n)},touch:function(e){if(pf.supportsTouch||pf.supportsIePointer){var t=e.element,n={},r=0,o={},i=null;pf.supportsTouch?(e.event.bind(t,"touchstart",c),e.event.bind(t,"touchmove",u),e.event.bind(t,"touchend",p)):pf.supportsIePointer&&(window.PointerEvent?(e.event.bind(t,"pointerdown",c),e.event.bind(t,"pointermove",u),e.event.bind(t,"pointerup",p)):window.MSPointerEvent&&(e.event.bind(t,"MSPointerDown",c),e.event.bind(t,"MSPointerMove",u),e.event.bind(t,"MSPointerUp",p)))}function a(n,r){t.scrollTop-=r,t.scrollLeft-=n,ff(e)}function s(e){return e.targetTouches?e.targetTouches[0]:e}function l(e){return!(e.pointerType&&"pen"===e.pointerType&&0===e.buttons||(!e.targetTouches||1!==e.targetTouches.length)&&(!e.pointerType||"mouse"===e.pointerType||e.pointerType===e.MSPOINTER_TYPE_MOUSE))}function c(e){if(l(e)){var t=s(e);n.pageX=t.pageX,n.pageY=t.pageY,r=(new Date).getTime(),null!==i&&clearInterval(i)}}function u(i){if(l(i)){var c=s(i),u={pageX:c.pageX,pageY:c.pageY},p=u.pageX-n.pageX,f=u.pageY-n.pageY;if(function(e,n,r){if(!t.contains(e))return!1;for(var o=e;o&&o!==t;){if(o.classList.contains(Xp))return!0;var i=Bp(o);if(r&&i.overflowY.match(/(scroll|auto)/)){var a=o.scrollHeight-o.clientHeight;if(a>0&&(o.scrollTop>0&&r<0||o.scrollTop<a&&r>0))return!0}if(n&&i.overflowX.match(/(scroll|auto)/)){var s=o.scrollWidth-o.clientWidth;if(s>0&&(o.scrollLeft>0&&n<0||o.scrollLeft<s&&n>0))return!0}o=o.parentNode}return!1}(i.target,p,f))return;a(p,f),n=u;var d=(new Date).getTime(),h=d-r;h>0&&(o.x=p/h,o.y=f/h,r=d),function(n,r){var o=Math.floor(t.scrollTop),i=t.scrollLeft,a=Math.abs(n),s=Math.abs(r);if(s>a){if(r<0&&o===e.contentHeight-e.containerHeight||r>0&&0===o)return 0===window.scrollY&&r>0&&pf.isChrome}else if(a>s&&(n<0&&i===e.contentWidth-e.containerWidth||n>0&&0===i))return!0;return!0}(p,f)&&i.preventDefault()}}function p(){e.settings.swipeEasing&&(clearInterval(i),i=setInterval((function(){e.isInitialized?clearInterval(i):o.x||o.y?Math.abs(o.x)<.01&&Math.abs(o.y)<.01?clearInterval(i):(a(30*o.x,30*o.y),o.x*=.8,o.y*=.8):clearInterval(i)}),10))}}},vf=function(e,t){var n=this;if(void 0===t&&(t={}),"string"==typeof e&&(e=document.querySelector(e)),!e||!e.nodeName)throw new Error("no element is specified to initialize PerfectScrollbar");for(var r in this.element=e,e.classList.add("ps"),this.settings={handlers:["click-rail","drag-thumb","keyboard","wheel","touch"],maxScrollbarLength:null,minScrollbarLength:null,scrollingThreshold:1e3,scrollXMarginOffset:0,scrollYMarginOffset:0,suppressScrollX:!1,suppressScrollY:!1,swipeEasing:!0,useBothWheelAxes:!1,wheelPropagation:!0,wheelSpeed:1},t)this.settings[r]=t[r];this.containerWidth=null,this.containerHeight=null,this.contentWidth=null,this.contentHeight=null;var o,i,a=function(){return e.classList.add(Kp)},s=function(){return e.classList.remove(Kp)};this.isRtl="rtl"===Bp(e).direction,!0===this.isRtl&&e.classList.add("ps__rtl"),this.isNegativeScroll=(i=e.scrollLeft,e.scrollLeft=-1,o=e.scrollLeft<0,e.scrollLeft=i,o),this.negativeScrollAdjustment=this.isNegativeScroll?e.scrollWidth-e.clientWidth:0,this.event=new sf,this.ownerDocument=e.ownerDocument||document,this.scrollbarXRail=qp(Qp("x")),e.appendChild(this.scrollbarXRail),this.scrollbarX=qp(Gp("x")),this.scrollbarXRail.appendChild(this.scrollbarX),this.scrollbarX.setAttribute("tabindex",0),this.event.bind(this.scrollbarX,"focus",a),this.event.bind(this.scrollbarX,"blur",s),this.scrollbarXActive=null,this.scrollbarXWidth=null,this.scrollbarXLeft=null;var l=Bp(this.scrollbarXRail);this.scrollbarXBottom=parseInt(l.bottom,10),isNaN(this.scrollbarXBottom)?(this.isScrollbarXUsingBottom=!1,this.scrollbarXTop=uf(l.top)):this.isScrollbarXUsingBottom=!0,this.railBorderXWidth=uf(l.borderLeftWidth)+uf(l.borderRightWidth),$p(this.scrollbarXRail,{display:"block"}),this.railXMarginWidth=uf(l.marginLeft)+uf(l.marginRight),$p(this.scrollbarXRail,{display:""}),this.railXWidth=null,this.railXRatio=null,this.scrollbarYRail=qp(Qp("y")),e.appendChild(this.scrollbarYRail),this.scrollbarY=qp(Gp("y")),this.scrollbarYRail.appendChild(this.scrollbarY),this.scrollbarY.setAttribute("tabindex",0),this.event.bind(this.scrollbarY,"focus",a),this.event.bind(this.scrollbarY,"blur",s),this.scrollbarYActive=null,this.scrollbarYHeight=null,this.scrollbarYTop=null;var c=Bp(this.scrollbarYRail);this.scrollbarYRight=parseInt(c.right,10),isNaN(this.scrollbarYRight)?(this.isScrollbarYUsingRight=!1,this.scrollbarYLeft=uf(c.left)):this.isScrollbarYUsingRight=!0,this.scrollbarYOuterWidth=this.isRtl?function(e){var t=Bp(e);return uf(t.width)+uf(t.paddingLeft)+uf(t.paddingRight)+uf(t.borderLeftWidth)+uf(t.borderRightWidth)}(this.scrollbarY):null,this.railBorderYWidth=uf(c.borderTopWidth)+uf(c.borderBottomWidth),$p(this.scrollbarYRail,{display:"block"}),this.railYMarginHeight=uf(c.marginTop)+uf(c.marginBottom),$p(this.scrollbarYRail,{display:""}),this.railYHeight=null,this.railYRatio=null,this.reach={x:e.scrollLeft<=0?"start":e.scrollLeft>=this.contentWidth-this.containerWidth?"end":null,y:e.scrollTop<=0?"start":e.scrollTop>=this.contentHeight-this.containerHeight?"end":null},this.isAlive=!0,this.settings.handlers.forEach((function(e){return mf[e](n)})),this.lastScrollTop=Math.floor(e.scrollTop),this.lastScrollLeft=e.scrollLeft,this.event.bind(this.element,"scroll",(function(e){return n.onScroll(e)})),ff(this)};vf.prototype.update=function(){this.isAlive&&(this.negativeScrollAdjustment=this.isNegativeScroll?this.element.scrollWidth-this.element.clientWidth:0,$p(this.scrollbarXRail,{display:"block"}),$p(this.scrollbarYRail,{display:"block"}),this.railXMarginWidth=uf(Bp(this.scrollbarXRail).marginLeft)+uf(Bp(this.scrollbarXRail).marginRight),this.railYMarginHeight=uf(Bp(this.scrollbarYRail).marginTop)+uf(Bp(this.scrollbarYRail).marginBottom),$p(this.scrollbarXRail,{display:"none"}),$p(this.scrollbarYRail,{display:"none"}),ff(this),cf(this,"top",0,!1,!0),cf(this,"left",0,!1,!0),$p(this.scrollbarXRail,{display:""}),$p(this.scrollbarYRail,{display:""}))},vf.prototype.onScroll=function(e){this.isAlive&&(ff(this),cf(this,"top",this.element.scrollTop-this.lastScrollTop),cf(this,"left",this.element.scrollLeft-this.lastScrollLeft),this.lastScrollTop=Math.floor(this.element.scrollTop),this.lastScrollLeft=this.element.scrollLeft)},vf.prototype.destroy=function(){this.isAlive&&(this.event.unbindAll(),Hp(this.scrollbarX),Hp(this.scrollbarY),Hp(this.scrollbarXRail),Hp(this.scrollbarYRail),this.removePsClasses(),this.element=null,this.scrollbarX=null,this.scrollbarY=null,this.scrollbarXRail=null,this.scrollbarYRail=null,this.isAlive=!1)},vf.prototype.removePsClasses=function(){this.element.className=this.element.className.split(" ").filter((function(e){return!e.match(/^ps([-_].+|)$/)})).join(" ")};var gf,yf,bf=vf,xf=r(4875),wf=bf||t,kf=xa(gf||(gf=Co(["",""])),xf.Z&&xf.Z.toString()),Ef=Sa.div(yf||(yf=Co(["\n  position: relative;\n"]))),Sf=function(e){_o(r,e);var t=Ro(r);function r(){var e;nr(this,r);for(var o=arguments.length,i=new Array(o),a=0;a<o;a++)i[a]=arguments[a];return n(ar(e=t.call.apply(t,[this].concat(i))),"_container",void 0),n(ar(e),"inst",void 0),n(ar(e),"handleRef",(function(t){e._container=t})),e}return or(r,[{key:"componentDidMount",value:function(){var e=this._container.parentElement&&this._container.parentElement.scrollTop||0;this.inst=new wf(this._container,this.props.options||{}),this._container.scrollTo&&this._container.scrollTo(0,e)}},{key:"componentDidUpdate",value:function(){this.inst.update()}},{key:"componentWillUnmount",value:function(){this.inst.destroy()}},{key:"render",value:function(){var e=this.props,t=e.children,n=e.className,r=e.updateFn;return r&&r(this.componentDidUpdate.bind(this)),s.createElement(s.Fragment,null,s.createElement(kf,null),s.createElement(Ef,{className:"scrollbar-container ".concat(n),ref:this.handleRef},t))}}]),r}(s.Component);function _f(e){return s.createElement(Ma.Consumer,null,(function(t){return t.nativeScrollbars?s.createElement("div",{style:{overflow:"auto",overscrollBehavior:"contain",msOverflowStyle:"-ms-autohiding-scrollbar"}},e.children):s.createElement(Sf,e,e.children)}))}var Of=function(){function e(t,r,o){nr(this,e),n(this,"operations",[]);var i=t.deref(o||{});t.exitRef(o),this.initWebhooks(t,i,r)}return or(e,[{key:"initWebhooks",value:function(e,t,r){for(var o=0,i=Object.keys(t);o<i.length;o++){var s,l=t[i[o]],c=oo(Object.keys(l).filter(ls));try{for(c.s();!(s=c.n()).done;){var u=s.value,p=l[u];if(l.$ref){var f=e.deref(l||{});this.initWebhooks(e,n({},u,f),r)}if(p){var d=new rc(e,a(a({},p),{},{httpVerb:u}),void 0,r,!1);this.operations.push(d)}}}catch(e){c.e(e)}finally{c.f()}}}}]),e}(),Af=function e(t,r,o){nr(this,e),n(this,"id",void 0),n(this,"sectionId",void 0),n(this,"type",void 0),n(this,"description",void 0),n(this,"apiKey",void 0),n(this,"http",void 0),n(this,"flows",void 0),n(this,"openId",void 0);var i=t.deref(o);this.id=r,this.sectionId=Os+r,this.type=i.type,this.description=i.description||"","apiKey"===i.type&&(this.apiKey={name:i.name,in:i.in}),"http"===i.type&&(this.http={scheme:i.scheme,bearerFormat:i.bearerFormat}),"openIdConnect"===i.type&&(this.openId={connectUrl:i.openIdConnectUrl}),"oauth2"===i.type&&i.flows&&(this.flows=i.flows)},Rf=function e(t){nr(this,e),n(this,"schemes",void 0);var r=t.spec.components&&t.spec.components.securitySchemes||{};this.schemes=Object.keys(r).map((function(e){return new Af(t,e,r[e])}))};var Cf,Tf,Pf,If,jf,Lf,Nf,Mf,Df,Ff=["$ref"],zf=function(){function e(){nr(this,e),n(this,"_counter",{})}return or(e,[{key:"reset",value:function(){this._counter={}}},{key:"visit",value:function(e){this._counter[e]=this._counter[e]?this._counter[e]+1:1}},{key:"exit",value:function(e){this._counter[e]=this._counter[e]&&this._counter[e]-1}},{key:"visited",value:function(e){return!!this._counter[e]}}]),e}(),Uf=function(){function e(t,r){var o=this,i=arguments.length>2&&void 0!==arguments[2]?arguments[2]:new So({});nr(this,e),this.options=i,n(this,"specUrl",void 0),n(this,"spec",void 0),n(this,"_refCounter",new zf),n(this,"allowMergeRefs",!1),n(this,"byRef",(function(e){var t;if(o.spec){"#"!==e.charAt(0)&&(e="#"+e),e=decodeURIComponent(e);try{t=ts.get(o.spec,e)}catch(e){}return t||{}}})),this.validate(t),this.preprocess(t),this.spec=t,this.allowMergeRefs=t.openapi.startsWith("3.1");var a=Jr?window.location.href:"";"string"==typeof r&&(this.specUrl=(0,so.resolve)(a,r))}return or(e,[{key:"validate",value:function(e){if(void 0===e.openapi)throw new Error("Document must be valid OpenAPI 3.0.0 definition")}},{key:"preprocess",value:function(e){if(!this.options.noAutoAuth&&e.info&&e.components&&e.components.securitySchemes){var t=e.info.description||"";if(!Vs.containsComponent(t,Ss)&&!Vs.containsComponent(t,_s)){var n="\x3c!-- ReDoc-Inject: <".concat(Ss,"> --\x3e");e.info.description=function(e,t,n){var r=new RegExp("(^|\\n)#\\s?".concat(t,"\\s*\\n"),"i"),o=new RegExp("((\\n|^)#\\s*".concat(t,"\\s*(\\n|$)(?:.|\\n)*?)(\\n#|$)"),"i");if(r.test(e))return e.replace(o,"$1\n\n".concat(n,"\n$4"));var i=""===e||e.endsWith("\n\n")?"":e.endsWith("\n")?"\n":"\n\n";return"".concat(e).concat(i,"# ").concat(t,"\n\n").concat(n)}(t,"Authentication",n)}}}},{key:"isRef",value:function(e){return!!e&&void 0!==e.$ref&&null!==e.$ref}},{key:"resetVisited",value:function(){this._refCounter=new zf}},{key:"exitRef",value:function(e){this.isRef(e)&&this._refCounter.exit(e.$ref)}},{key:"deref",value:function(e){var t=arguments.length>1&&void 0!==arguments[1]&&arguments[1],n=arguments.length>2&&void 0!==arguments[2]&&arguments[2];if(this.isRef(e)){var r=ys(e.$ref);if(r&&this.options.ignoreNamedSchemas.has(r))return{type:"object",title:r};var o=this.byRef(e.$ref),i=this._refCounter.visited(e.$ref);if(this._refCounter.visit(e.$ref),i&&!t)return Object.assign({},o,{"x-circular-ref":!0});var a=o;return this.isRef(o)&&(a=this.deref(o,!1,n),this.exitRef(o)),this.allowMergeRefs?this.mergeRefs(e,o,n):a}return e}},{key:"shallowDeref",value:function(e){if(this.isRef(e)){var t=ys(e.$ref);if(t&&this.options.ignoreNamedSchemas.has(t))return{type:"object",title:t};var n=this.byRef(e.$ref);return this.allowMergeRefs?this.mergeRefs(e,n,!1):n}return e}},{key:"mergeRefs",value:function(e,t,n){e.$ref;var r=function(e,t){if(null==e)return{};var n,r,o=function(e,t){if(null==e)return{};var n,r,o={},i=Object.keys(e);for(r=0;r<i.length;r++)n=i[r],t.indexOf(n)>=0||(o[n]=e[n]);return o}(e,t);if(Object.getOwnPropertySymbols){var i=Object.getOwnPropertySymbols(e);for(r=0;r<i.length;r++)n=i[r],t.indexOf(n)>=0||Object.prototype.propertyIsEnumerable.call(e,n)&&(o[n]=e[n])}return o}(e,Ff),o=Object.keys(r);return 0===o.length?t:n&&o.some((function(e){return"description"!==e&&"title"!==e&&"externalDocs"!==e}))?{allOf:[r,t]}:a(a({},t),r)}},{key:"mergeAllOf",value:function(e,t){var n=this,r=arguments.length>2&&void 0!==arguments[2]&&arguments[2],o=arguments.length>3&&void 0!==arguments[3]?arguments[3]:new Set;if(t&&o.add(t),void 0===(e=this.hoistOneOfs(e)).allOf)return e;var i=a(a({},e),{},{allOf:void 0,parentRefs:[],title:e.title||ys(t)});void 0!==i.properties&&"object"==typeof i.properties&&(i.properties=a({},i.properties)),void 0!==i.items&&"object"==typeof i.items&&(i.items=a({},i.items));var s,l=e.allOf.map((function(e){var t;if(!(e&&e.$ref&&o.has(e.$ref))){var a=n.deref(e,r,!0),s=e.$ref||void 0,l=n.mergeAllOf(a,s,r,o);return(t=i.parentRefs).push.apply(t,ns(l.parentRefs||[])),{$ref:s,schema:l}}})).filter((function(e){return void 0!==e})),c=oo(l);try{for(c.s();!(s=c.n()).done;){var u=s.value,p=u.$ref,f=u.schema;if(i.type!==f.type&&void 0!==i.type&&void 0!==f.type&&console.warn('Incompatible types in allOf at "'.concat(t,'": "').concat(i.type,'" and "').concat(f.type,'"')),void 0!==f.type&&(i.type=f.type),void 0!==f.properties)for(var d in i.properties=i.properties||{},f.properties)if(i.properties[d]){var h=this.mergeAllOf({allOf:[i.properties[d],f.properties[d]]},t+"/properties/"+d);i.properties[d]=h,this.exitParents(h)}else i.properties[d]=f.properties[d];void 0!==f.items&&(i.items=i.items||{},i.items=this.mergeAllOf({allOf:[i.items,f.items]},t+"/items")),void 0!==f.required&&(i.required=(i.required||[]).concat(f.required)),i=a(a({},f),i),p&&(i.parentRefs.push(p),void 0===i.title&&gs(p))}}catch(e){c.e(e)}finally{c.f()}return i}},{key:"findDerived",value:function(e){var t={},n=this.spec.components&&this.spec.components.schemas||{};for(var r in n){var o=this.deref(n[r]);void 0!==o.allOf&&o.allOf.find((function(t){return void 0!==t.$ref&&e.indexOf(t.$ref)>-1}))&&(t["#/components/schemas/"+r]=[o["x-discriminator-value"]||r])}return t}},{key:"exitParents",value:function(e){var t,n=oo(e.parentRefs||[]);try{for(n.s();!(t=n.n()).done;){var r=t.value;this.exitRef({$ref:r})}}catch(e){n.e(e)}finally{n.f()}}},{key:"hoistOneOfs",value:function(e){var t=this;if(void 0===e.allOf)return e;for(var n=e.allOf,r=0;r<n.length;r++){var o=n[r];if(Array.isArray(o.oneOf)){var i=function(){var e=n.slice(0,r),i=n.slice(r+1);return{v:{oneOf:o.oneOf.map((function(n){var r=t.mergeAllOf({allOf:[].concat(ns(e),[n],ns(i))});return t.exitParents(r),r}))}}}();if("object"==typeof i)return i.v}}return e}}]),e}(),Bf=function e(t,r,o){var i,s,l;nr(this,e),this.options=o,n(this,"parser",void 0),n(this,"info",void 0),n(this,"externalDocs",void 0),n(this,"contentItems",void 0),n(this,"securitySchemes",void 0),n(this,"webhooks",void 0),this.parser=new Uf(t,r,o),this.info=new Ks(this.parser),this.externalDocs=this.parser.spec.externalDocs,this.contentItems=qf.buildStructure(this.parser,this.options),this.securitySchemes=new Rf(this.parser);var c=a(a({},null===(i=this.parser)||void 0===i||null===(s=i.spec)||void 0===s?void 0:s["x-webhooks"]),null===(l=this.parser)||void 0===l?void 0:l.spec.webhooks);this.webhooks=new Of(this.parser,o,c)},$f=(Tf=Qa((Cf=function(){function e(t,r,o){nr(this,e),n(this,"id",void 0),n(this,"absoluteIdx",void 0),n(this,"name",void 0),n(this,"sidebarLabel",void 0),n(this,"description",void 0),n(this,"type",void 0),n(this,"items",[]),n(this,"parent",void 0),n(this,"externalDocs",void 0),Bs(this,"active",Tf,this),Bs(this,"expanded",Pf,this),n(this,"depth",void 0),n(this,"level",void 0),an(this),this.id=r.id||t+"/"+mo(r.name),this.type=t,this.name=r["x-displayName"]||r.name,this.level=r.level||1,this.sidebarLabel=this.name,this.description=r.description||"";var i=r.items;i&&i.length&&(this.description=Vs.getTextBeforeHading(this.description,i[0].name)),this.parent=o,this.externalDocs=r.externalDocs,"group"===this.type&&(this.expanded=!0)}return or(e,[{key:"activate",value:function(){this.active=!0}},{key:"expand",value:function(){this.parent&&this.parent.expand(),this.expanded=!0}},{key:"collapse",value:function(){"group"!==this.type&&(this.expanded=!1)}},{key:"deactivate",value:function(){this.active=!1}}]),e}()).prototype,"active",[Pe],{configurable:!0,enumerable:!0,writable:!0,initializer:function(){return!1}}),Pf=Qa(Cf.prototype,"expanded",[Pe],{configurable:!0,enumerable:!0,writable:!0,initializer:function(){return!1}}),Qa(Cf.prototype,"activate",[Tt],Object.getOwnPropertyDescriptor(Cf.prototype,"activate"),Cf.prototype),Qa(Cf.prototype,"expand",[Tt],Object.getOwnPropertyDescriptor(Cf.prototype,"expand"),Cf.prototype),Qa(Cf.prototype,"collapse",[Tt],Object.getOwnPropertyDescriptor(Cf.prototype,"collapse"),Cf.prototype),Qa(Cf.prototype,"deactivate",[Tt],Object.getOwnPropertyDescriptor(Cf.prototype,"deactivate"),Cf.prototype),Cf),qf=function(){function e(){nr(this,e)}return or(e,null,[{key:"buildStructure",value:function(t,n){var r=t.spec,o=[],i=e.getTagsWithOperations(t,r);return o.push.apply(o,ns(e.addMarkdownItems(r.info.description||"",void 0,1,n))),r["x-tagGroups"]&&r["x-tagGroups"].length>0?o.push.apply(o,ns(e.getTagGroupsItems(t,void 0,r["x-tagGroups"],i,n))):o.push.apply(o,ns(e.getTagsItems(t,i,void 0,void 0,n))),o}},{key:"addMarkdownItems",value:function(e,t,n,r){var o=new Vs(r).extractHeadings(e||"");return o.length&&t&&t.description&&(t.description=Vs.getTextBeforeHading(t.description,o[0].name)),function e(t,n){var r=arguments.length>2&&void 0!==arguments[2]?arguments[2]:1;return n.map((function(n){var o=new $f("section",n,t);return o.depth=r,n.items&&(o.items=e(o,n.items,r+1)),Vs.containsComponent(o.description||"",Ss)&&As(o.id+"/"),o}))}(t,o,n)}},{key:"getTagGroupsItems",value:function(t,n,r,o,i){var a,s=[],l=oo(r);try{for(l.s();!(a=l.n()).done;){var c=a.value,u=new $f("group",c,n);u.depth=0,u.items=e.getTagsItems(t,o,u,c,i),s.push(u)}}catch(e){l.e(e)}finally{l.f()}return s}},{key:"getTagsItems",value:function(t,n,r,o,i){var a,s=[],l=oo((void 0===o?Object.keys(n):o.tags).map((function(e){return n[e]?(n[e].used=!0,n[e]):(console.warn('Non-existing tag "'.concat(e,'" is added to the group "').concat(o.name,'"')),null)})));try{for(l.s();!(a=l.n()).done;){var c=a.value;if(c){var u=new $f("tag",c,r);if(u.depth=1,""!==c.name)u.items=[].concat(ns(e.addMarkdownItems(c.description||"",u,u.depth+1,i)),ns(this.getOperationsItems(t,u,c,u.depth+1,i))),s.push(u);else{var p=[].concat(ns(e.addMarkdownItems(c.description||"",u,u.depth+1,i)),ns(this.getOperationsItems(t,void 0,c,u.depth+1,i)));s.push.apply(s,ns(p))}}}}catch(e){l.e(e)}finally{l.f()}return s}},{key:"getOperationsItems",value:function(e,t,n,r,o){if(0===n.operations.length)return[];var i,a=[],s=oo(n.operations);try{for(s.s();!(i=s.n()).done;){var l=i.value,c=new rc(e,l,t,o);c.depth=r,a.push(c)}}catch(e){s.e(e)}finally{s.f()}return a}},{key:"getTagsWithOperations",value:function(e,t){var r,o={},i=t["x-webhooks"]||t.webhooks,s=oo(t.tags||[]);try{for(s.s();!(r=s.n()).done;){var l=r.value;o[l.name]=a(a({},l),{},{operations:[]})}}catch(e){s.e(e)}finally{s.f()}function c(e,t,r){for(var i=0,s=Object.keys(t);i<s.length;i++){var l,u=s[i],p=t[u],f=oo(Object.keys(p).filter(ls));try{for(f.s();!(l=f.n()).done;){var d=l.value,h=p[d];if(p.$ref){var m=e.deref(p);c(e,n({},u,m),r)}else{var v=null==h?void 0:h.tags;v&&v.length||(v=[""]);var g,y=oo(v);try{for(y.s();!(g=y.n()).done;){var b=g.value,x=o[b];void 0===x&&(x={name:b,operations:[]},o[b]=x),x["x-traitTag"]||x.operations.push(a(a({},h),{},{pathName:u,pointer:ts.compile(["paths",u,d]),httpVerb:d,pathParameters:p.parameters||[],pathServers:p.servers,isWebhook:!!r}))}}catch(e){y.e(e)}finally{y.f()}}}}catch(e){f.e(e)}finally{f.f()}}}return i&&c(e,i,!0),t.paths&&c(e,t.paths),o}}]),e}(),Wf="data-section-id",Vf=(If=Tt.bound,Lf=Qa((jf=function(){function e(t,r,o){var i,a,s=this;nr(this,e),this.scroll=r,this.history=o,Bs(this,"activeItemIdx",Lf,this),Bs(this,"sideBarOpened",Nf,this),n(this,"items",void 0),n(this,"flatItems",void 0),n(this,"_unsubscribe",void 0),n(this,"_hashUnsubscribe",void 0),n(this,"updateOnScroll",(function(e){for(var t=e?1:-1,n=s.activeItemIdx;(-1!==n||e)&&!(n>=s.flatItems.length-1&&e);){if(e){var r=s.getElementAtOrFirstChild(n+1);if(s.scroll.isElementBellow(r))break}else{var o=s.getElementAt(n);if(s.scroll.isElementAbove(o))break}n+=t}s.activate(s.flatItems[n],!0,!0)})),n(this,"updateOnHistory",(function(){var e,t=arguments.length>0&&void 0!==arguments[0]?arguments[0]:s.history.currentId;t&&((e=s.flatItems.find((function(e){return e.id===t})))?s.activateAndScroll(e,!1):(t.startsWith(Os)&&(e=s.flatItems.find((function(e){return Os.startsWith(e.id)})),s.activate(e)),s.scroll.scrollIntoViewBySelector("[".concat(Wf,'="').concat(t,'"]'))))})),n(this,"getItemById",(function(e){return s.flatItems.find((function(t){return t.id===e}))})),an(this),this.items=t.contentItems,this.flatItems=(i=this.items||[],"items",a=[],function e(t){var n,r=oo(t);try{for(r.s();!(n=r.n()).done;){var o=n.value;a.push(o),o.items&&e(o.items)}}catch(e){r.e(e)}finally{r.f()}}(i),a),this.flatItems.forEach((function(e,t){return e.absoluteIdx=t})),this.subscribe()}return or(e,[{key:"subscribe",value:function(){this._unsubscribe=this.scroll.subscribe(this.updateOnScroll),this._hashUnsubscribe=this.history.subscribe(this.updateOnHistory)}},{key:"toggleSidebar",value:function(){this.sideBarOpened=!this.sideBarOpened}},{key:"closeSidebar",value:function(){this.sideBarOpened=!1}},{key:"getElementAt",value:function(e){var t=this.flatItems[e];return t&&eo("[".concat(Wf,'="').concat(t.id,'"]'))||null}},{key:"getElementAtOrFirstChild",value:function(e){var t=this.flatItems[e];return t&&"group"===t.type&&(t=t.items[0]),t&&eo("[".concat(Wf,'="').concat(t.id,'"]'))||null}},{key:"activeItem",get:function(){return this.flatItems[this.activeItemIdx]||void 0}},{key:"activate",value:function(e){var t=!(arguments.length>1&&void 0!==arguments[1])||arguments[1],n=arguments.length>2&&void 0!==arguments[2]&&arguments[2];if((this.activeItem&&this.activeItem.id)!==(e&&e.id)&&(!e||"group"!==e.type)){if(this.deactivate(this.activeItem),!e)return this.activeItemIdx=-1,void this.history.replace("",n);e.depth<=0||(this.activeItemIdx=e.absoluteIdx,t&&this.history.replace(e.id,n),e.activate(),e.expand())}}},{key:"deactivate",value:function(e){if(void 0!==e)for(e.deactivate();void 0!==e;)e.collapse(),e=e.parent}},{key:"activateAndScroll",value:function(e,t,n){var r=e&&this.getItemById(e.id)||e;this.activate(r,t,n),this.scrollToActive(),r&&r.items.length||this.closeSidebar()}},{key:"scrollToActive",value:function(){this.scroll.scrollIntoView(this.getElementAt(this.activeItemIdx))}},{key:"dispose",value:function(){this._unsubscribe(),this._hashUnsubscribe()}}],[{key:"updateOnHistory",value:function(){var e=arguments.length>0&&void 0!==arguments[0]?arguments[0]:Fs.currentId,t=arguments.length>1?arguments[1]:void 0;e&&t.scrollIntoViewBySelector("[".concat(Wf,'="').concat(e,'"]'))}}]),e}()).prototype,"activeItemIdx",[Pe],{configurable:!0,enumerable:!0,writable:!0,initializer:function(){return-1}}),Nf=Qa(jf.prototype,"sideBarOpened",[Pe],{configurable:!0,enumerable:!0,writable:!0,initializer:function(){return!1}}),Qa(jf.prototype,"toggleSidebar",[Tt],Object.getOwnPropertyDescriptor(jf.prototype,"toggleSidebar"),jf.prototype),Qa(jf.prototype,"closeSidebar",[Tt],Object.getOwnPropertyDescriptor(jf.prototype,"closeSidebar"),jf.prototype),Qa(jf.prototype,"activate",[Tt],Object.getOwnPropertyDescriptor(jf.prototype,"activate"),jf.prototype),Qa(jf.prototype,"activateAndScroll",[If],Object.getOwnPropertyDescriptor(jf.prototype,"activateAndScroll"),jf.prototype),jf),Hf="scroll",Yf=(100,Mf=function(e,t,n){var r,o,i,a,s,l,c,u;n.value=(r=n.value,o=100,l=null,c=0,u=function(){c=(new Date).getTime(),l=null,s=r.apply(i,a),l||(i=a=null)},function(){var e=(new Date).getTime(),t=o-(e-c);return i=this,a=arguments,t<=0||t>o?(l&&(clearTimeout(l),l=null),c=e,s=r.apply(i,a),l||(i=a=null)):l||(l=setTimeout(u,t)),s})},Qa((Df=function(){function e(t){nr(this,e),this.options=t,n(this,"_scrollParent",void 0),n(this,"_emiter",void 0),n(this,"_prevOffsetY",0),this._scrollParent=Jr?window:void 0,this._emiter=new Ka,this.bind()}return or(e,[{key:"bind",value:function(){this._prevOffsetY=this.scrollY(),this._scrollParent&&this._scrollParent.addEventListener("scroll",this.handleScroll)}},{key:"dispose",value:function(){this._scrollParent&&this._scrollParent.removeEventListener("scroll",this.handleScroll),this._emiter.removeAllListeners(Hf)}},{key:"scrollY",value:function(){return"undefined"!=typeof HTMLElement&&this._scrollParent instanceof HTMLElement?this._scrollParent.scrollTop:void 0!==this._scrollParent?this._scrollParent.pageYOffset:0}},{key:"isElementBellow",value:function(e){if(null!==e)return e.getBoundingClientRect().top>this.options.scrollYOffset()}},{key:"isElementAbove",value:function(e){if(null!==e){var t=e.getBoundingClientRect().top;return(t>0?Math.floor(t):Math.ceil(t))<=this.options.scrollYOffset()}}},{key:"subscribe",value:function(e){var t=this._emiter.addListener(Hf,e);return function(){return t.removeListener(Hf,e)}}},{key:"scrollIntoView",value:function(e){null!==e&&(e.scrollIntoView(),this._scrollParent&&this._scrollParent.scrollBy&&this._scrollParent.scrollBy(0,1-this.options.scrollYOffset()))}},{key:"scrollIntoViewBySelector",value:function(e){var t=eo(e);this.scrollIntoView(t)}},{key:"handleScroll",value:function(){var e=this.scrollY()-this._prevOffsetY>0;this._prevOffsetY=this.scrollY(),this._emiter.emit(Hf,e)}}]),e}()).prototype,"handleScroll",[Xa.bind,Mf],Object.getOwnPropertyDescriptor(Df.prototype,"handleScroll"),Df.prototype),Df),Gf=function(){function e(){nr(this,e),n(this,"searchWorker",function(){var e;if(Jr)try{e=r(5114)}catch(t){e=r(308).default}else e=r(308).default;return new e}())}var t;return or(e,[{key:"indexItems",value:function(e){var t=this;!function e(n){n.forEach((function(n){"group"!==n.type&&t.add(n.name,n.description||"",n.id),e(n.items)}))}(e),this.searchWorker.done()}},{key:"add",value:function(e,t,n){this.searchWorker.add(e,t,n)}},{key:"dispose",value:function(){this.searchWorker.terminate(),this.searchWorker.dispose()}},{key:"search",value:function(e){return this.searchWorker.search(e)}},{key:"toJS",value:(t=Ua($a().mark((function e(){return $a().wrap((function(e){for(;;)switch(e.prev=e.next){case 0:return e.abrupt("return",this.searchWorker.toJS());case 1:case"end":return e.stop()}}),e,this)}))),function(){return t.apply(this,arguments)})},{key:"load",value:function(e){this.searchWorker.load(e)}},{key:"fromExternalJS",value:function(e,t){e&&t&&this.searchWorker.fromExternalJS(e,t)}}]),e}();function Qf(e){var t=e.Label,n=void 0===t?Zu:t,r=e.Dropdown,o=void 0===r?Ku:r;return 1===e.options.length?s.createElement(n,null,e.options[0].value):s.createElement(o,ir({},e,{searchable:!1}))}var Xf,Kf,Zf=r(7856),Jf=ba(Xf||(Xf=Co(["\n  a {\n    text-decoration: none;\n    color: ",";\n\n    &:visited {\n      color: ",";\n    }\n\n    &:hover {\n      color: ",";\n    }\n  }\n"])),(function(e){return e.theme.typography.links.color}),(function(e){return e.theme.typography.links.visited}),(function(e){return e.theme.typography.links.hover})),ed=Sa(Dp)(Kf||(Kf=Co(["\n  font-family: ",";\n  font-weight: ",";\n  line-height: ",";\n\n  p {\n    &:last-child {\n      margin-bottom: 0;\n    }\n  }\n\n  ","\n\n  ","\n\n  h1 {\n    ",";\n    color: ",";\n    margin-top: 0;\n  }\n\n  h2 {\n    ",";\n    color: ",";\n  }\n\n  code {\n    color: ",";\n    background-color: ",";\n\n    font-family: ",";\n    border-radius: 2px;\n    border: 1px solid rgba(38, 50, 56, 0.1);\n    padding: 0 ","px;\n    font-size: ",";\n    font-weight: ",";\n\n    word-break: break-word;\n  }\n\n  pre {\n    font-family: ",";\n    white-space: ",";\n    background-color: ",";\n    color: white;\n    padding: ","px;\n    overflow-x: auto;\n    line-height: normal;\n    border-radius: 0px;\n    border: 1px solid rgba(38, 50, 56, 0.1);\n\n    code {\n      background-color: transparent;\n      color: white;\n      padding: 0;\n\n      &:before,\n      &:after {\n        content: none;\n      }\n    }\n  }\n\n  blockquote {\n    margin: 0;\n    margin-bottom: 1em;\n    padding: 0 15px;\n    color: #777;\n    border-left: 4px solid #ddd;\n  }\n\n  img {\n    max-width: 100%;\n    box-sizing: content-box;\n  }\n\n  ul,\n  ol {\n    padding-left: 2em;\n    margin: 0;\n    margin-bottom: 1em;\n\n    ul,\n    ol {\n      margin-bottom: 0;\n      margin-top: 0;\n    }\n  }\n\n  table {\n    display: block;\n    width: 100%;\n    overflow: auto;\n    word-break: normal;\n    word-break: keep-all;\n    border-collapse: collapse;\n    border-spacing: 0;\n    margin-top: 1.5em;\n    margin-bottom: 1.5em;\n  }\n\n  table tr {\n    background-color: #fff;\n    border-top: 1px solid #ccc;\n\n    &:nth-child(2n) {\n      background-color: ",";\n    }\n  }\n\n  table th,\n  table td {\n    padding: 6px 13px;\n    border: 1px solid #ddd;\n  }\n\n  table th {\n    text-align: left;\n    font-weight: bold;\n  }\n\n  ",";\n\n  ","\n\n  ",";\n"])),(function(e){return e.theme.typography.fontFamily}),(function(e){return e.theme.typography.fontWeightRegular}),(function(e){return e.theme.typography.lineHeight}),(function(e){return e.compact&&"\n    p:first-child {\n      margin-top: 0;\n    }\n    p:last-child {\n      margin-bottom: 0;\n    }\n  "}),(function(e){return e.inline&&" p {\n    display: inline-block;\n  }"}),uc(1),(function(e){return e.theme.colors.primary.main}),uc(2),(function(e){return e.theme.colors.text.primary}),(function(e){return e.theme.typography.code.color}),(function(e){return e.theme.typography.code.backgroundColor}),(function(e){return e.theme.typography.code.fontFamily}),(function(e){return e.theme.spacing.unit}),(function(e){return e.theme.typography.code.fontSize}),(function(e){return e.theme.typography.code.fontWeight}),(function(e){return e.theme.typography.code.fontFamily}),(function(e){return e.theme.typography.code.wrap?"pre-wrap":"pre"}),(function(e){return e.theme.codeBlock.backgroundColor}),(function(e){return 4*e.theme.spacing.unit}),(function(e){return e.theme.schema.nestedBackground}),kc(".share-link"),Jf,_a("Markdown")),td=ed.withComponent("span");function nd(e){var t=e.inline?td:ed;return s.createElement(Fa,null,(function(n){return s.createElement(t,ir({className:"redoc-markdown "+(e.className||""),dangerouslySetInnerHTML:{__html:(r=n.untrustedSpec,o=e.html,r?Zf.sanitize(o):o)},"data-role":e["data-role"]},e));var r,o}))}var rd,od,id,ad,sd,ld,cd=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){var e=this.props,t=e.source,n=e.inline,r=e.compact,o=e.className,i=e["data-role"],a=new Vs;return s.createElement(nd,{html:a.renderMd(t),inline:n,compact:r,className:o,"data-role":i})}}]),n}(s.Component),ud=Sa.div(rd||(rd=Co(["\n  position: relative;\n"]))),pd=Sa.div(od||(od=Co(["\n  position: absolute;\n  min-width: 80px;\n  max-width: 500px;\n  background: #fff;\n  bottom: 100%;\n  left: 50%;\n  margin-bottom: 10px;\n  transform: translateX(-50%);\n\n  border-radius: 4px;\n  padding: 0.3em 0.6em;\n  text-align: center;\n  box-shadow: 0px 0px 5px 0px rgba(204, 204, 204, 1);\n"]))),fd=Sa.div(id||(id=Co(["\n  background: #fff;\n  color: #000;\n  display: inline;\n  font-size: 0.85em;\n  white-space: nowrap;\n"]))),dd=Sa.div(ad||(ad=Co(["\n  position: absolute;\n  width: 0;\n  height: 0;\n  bottom: -5px;\n  left: 50%;\n  margin-left: -5px;\n  border-left: solid transparent 5px;\n  border-right: solid transparent 5px;\n  border-top: solid #fff 5px;\n"]))),hd=Sa.div(sd||(sd=Co(["\n  position: absolute;\n  width: 100%;\n  height: 20px;\n  bottom: -20px;\n"]))),md=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){var e=this.props,t=e.open,n=e.title,r=e.children;return s.createElement(ud,null,r,t&&s.createElement(pd,null,s.createElement(fd,null,n),s.createElement(dd,null),s.createElement(hd,null)))}}]),n}(s.Component),vd="undefined"!=typeof document&&document.queryCommandSupported&&document.queryCommandSupported("copy"),gd=function(){function e(){nr(this,e)}return or(e,null,[{key:"isSupported",value:function(){return vd}},{key:"selectElement",value:function(e){var t,n;document.body.createTextRange?((t=document.body.createTextRange()).moveToElementText(e),t.select()):document.createRange&&window.getSelection&&(n=window.getSelection(),(t=document.createRange()).selectNodeContents(e),n.removeAllRanges(),n.addRange(t))}},{key:"deselect",value:function(){if(document.selection)document.selection.empty();else if(window.getSelection){var e=window.getSelection();e&&e.removeAllRanges()}}},{key:"copySelected",value:function(){var e;try{e=document.execCommand("copy")}catch(t){e=!1}return e}},{key:"copyElement",value:function(t){e.selectElement(t);var n=e.copySelected();return n&&e.deselect(),n}},{key:"copyCustom",value:function(t){var n=document.createElement("textarea");n.style.position="fixed",n.style.top="0",n.style.left="0",n.style.width="2em",n.style.height="2em",n.style.padding="0",n.style.border="none",n.style.outline="none",n.style.boxShadow="none",n.style.background="transparent",n.value=t,document.body.appendChild(n),n.select();var r=e.copySelected();return document.body.removeChild(n),r}}]),e}(),yd=function(e){_o(r,e);var t=Ro(r);function r(e){var o;return nr(this,r),n(ar(o=t.call(this,e)),"copy",(function(){var e="string"==typeof o.props.data?o.props.data:JSON.stringify(o.props.data,null,2);gd.copyCustom(e),o.showTooltip()})),n(ar(o),"renderCopyButton",(function(){return s.createElement("button",{onClick:o.copy},s.createElement(md,{title:gd.isSupported()?"Copied":"Not supported in your browser",open:o.state.tooltipShown},"Copy"))})),o.state={tooltipShown:!1},o}return or(r,[{key:"render",value:function(){return this.props.children({renderCopyButton:this.renderCopyButton})}},{key:"showTooltip",value:function(){var e=this;this.setState({tooltipShown:!0}),setTimeout((function(){e.setState({tooltipShown:!1})}),1500)}}]),r}(s.PureComponent),bd=1;function xd(e,t){bd=1;var n="";return n+='<div class="redoc-json">',n+="<code>",n+=_d(e,t),(n+="</code>")+"</div>"}function wd(e){return void 0!==e?e.toString().replace(/&/g,"&amp;").replace(/"/g,"&quot;").replace(/</g,"&lt;").replace(/>/g,"&gt;"):""}function kd(e){return JSON.stringify(e).slice(1,-1)}function Ed(e,t){return'<span class="'+t+'">'+wd(e)+"</span>"}function Sd(e){return'<span class="token punctuation">'+e+"</span>"}function _d(e,t){var n=typeof e,r="";return null==e?r+=Ed("null","token keyword"):e&&e.constructor===Array?(bd++,r+=function(e,t){for(var n=bd>t?"collapsed":"",r='<button class="collapser" aria-label="'.concat(bd>t+1?"expand":"collapse",'"></button>').concat(Sd("["),'<span class="ellipsis"></span><ul class="array collapsible">'),o=!1,i=e.length,a=0;a<i;a++)o=!0,r+='<li><div class="hoverable '+n+'">',r+=_d(e[a],t),a<i-1&&(r+=","),r+="</div></li>";return r+="</ul>".concat(Sd("]")),o||(r=Sd("[ ]")),r}(e,t),bd--):e&&e.constructor===Date?r+=Ed('"'+e.toISOString()+'"',"token string"):"object"===n?(bd++,r+=function(e,t){for(var n=bd>t?"collapsed":"",r=Object.keys(e),o=r.length,i='<button class="collapser" aria-label="'.concat(bd>t+1?"expand":"collapse",'"></button>').concat(Sd("{"),'<span class="ellipsis"></span><ul class="obj collapsible">'),a=!1,s=0;s<o;s++){var l=r[s];a=!0,i+='<li><div class="hoverable '+n+'">',i+='<span class="property token string">"'+wd(l)+'"</span>: ',i+=_d(e[l],t),s<o-1&&(i+=Sd(",")),i+="</div></li>"}return i+="</ul>".concat(Sd("}")),a||(i=Sd("{ }")),i}(e,t),bd--):"number"===n?r+=Ed(e,"token number"):"string"===n?/^(http|https):\/\/[^\s]+$/.test(e)?r+=Ed('"',"token string")+'<a href="'+encodeURI(e)+'">'+wd(kd(e))+"</a>"+Ed('"',"token string"):r+=Ed('"'+kd(e)+'"',"token string"):"boolean"===n&&(r+=Ed(e,"token boolean")),r}var Od,Ad,Rd,Cd,Td,Pd,Id,jd=ba(ld||(ld=Co(["\n  .redoc-json code > .collapser {\n    display: none;\n    pointer-events: none;\n  }\n\n  font-family: ",";\n  font-size: ",";\n\n  white-space: ",";\n  contain: content;\n  overflow-x: auto;\n\n  .callback-function {\n    color: gray;\n  }\n\n  .collapser:after {\n    content: '-';\n    cursor: pointer;\n  }\n\n  .collapsed > .collapser:after {\n    content: '+';\n    cursor: pointer;\n  }\n\n  .ellipsis:after {\n    content: ' … ';\n  }\n\n  .collapsible {\n    margin-left: 2em;\n  }\n\n  .hoverable {\n    padding-top: 1px;\n    padding-bottom: 1px;\n    padding-left: 2px;\n    padding-right: 2px;\n    border-radius: 2px;\n  }\n\n  .hovered {\n    background-color: rgba(235, 238, 249, 1);\n  }\n\n  .collapser {\n    background-color: transparent;\n    border: 0;\n    color: #fff;\n    font-family: ",";\n    font-size: ",";\n    padding-right: 6px;\n    padding-left: 6px;\n    padding-top: 0;\n    padding-bottom: 0;\n    display: flex;\n    align-items: center;\n    justify-content: center;\n    width: 15px;\n    height: 15px;\n    position: absolute;\n    top: 4px;\n    left: -1.5em;\n    cursor: default;\n    user-select: none;\n    -webkit-user-select: none;\n    padding: 2px;\n    &:focus {\n      outline-color: #fff;\n      outline-style: dotted;\n      outline-width: 1px;\n    }\n  }\n\n  ul {\n    list-style-type: none;\n    padding: 0px;\n    margin: 0px 0px 0px 26px;\n  }\n\n  li {\n    position: relative;\n    display: block;\n  }\n\n  .hoverable {\n    display: inline-block;\n  }\n\n  .selected {\n    outline-style: solid;\n    outline-width: 1px;\n    outline-style: dotted;\n  }\n\n  .collapsed > .collapsible {\n    display: none;\n  }\n\n  .ellipsis {\n    display: none;\n  }\n\n  .collapsed > .ellipsis {\n    display: inherit;\n  }\n"])),(function(e){return e.theme.typography.code.fontFamily}),(function(e){return e.theme.typography.code.fontSize}),(function(e){return e.theme.typography.code.wrap?"pre-wrap":"pre"}),(function(e){return e.theme.typography.code.fontFamily}),(function(e){return e.theme.typography.code.fontSize})),Ld=Sa.div(Od||(Od=Co(["\n  &:hover > "," {\n    opacity: 1;\n  }\n"])),Fp),Nd=Sa(function(e){_o(r,e);var t=Ro(r);function r(){var e;nr(this,r);for(var o=arguments.length,i=new Array(o),a=0;a<o;a++)i[a]=arguments[a];return n(ar(e=t.call.apply(t,[this].concat(i))),"node",void 0),n(ar(e),"renderInner",(function(t){var n=t.renderCopyButton;return s.createElement(Ld,null,s.createElement(Fp,null,n(),s.createElement("button",{onClick:e.expandAll}," Expand all "),s.createElement("button",{onClick:e.collapseAll}," Collapse all ")),s.createElement(Ma.Consumer,null,(function(t){return s.createElement(Dp,{className:e.props.className,ref:function(t){return e.node=t},dangerouslySetInnerHTML:{__html:xd(e.props.data,t.jsonSampleExpandLevel)}})})))})),n(ar(e),"expandAll",(function(){var t,n=e.node.getElementsByClassName("collapsible"),r=oo(Array.prototype.slice.call(n));try{for(r.s();!(t=r.n()).done;){var o=t.value.parentNode;o.classList.remove("collapsed"),o.querySelector(".collapser").setAttribute("aria-label","collapse")}}catch(e){r.e(e)}finally{r.f()}})),n(ar(e),"collapseAll",(function(){var t,n=e.node.getElementsByClassName("collapsible"),r=oo(Array.prototype.slice.call(n,1));try{for(r.s();!(t=r.n()).done;){var o=t.value.parentNode;o.classList.add("collapsed"),o.querySelector(".collapser").setAttribute("aria-label","expand")}}catch(e){r.e(e)}finally{r.f()}})),n(ar(e),"collapseElement",(function(e){var t;"collapser"===e.className&&((t=e.parentElement.getElementsByClassName("collapsible")[0]).parentElement.classList.contains("collapsed")?(t.parentElement.classList.remove("collapsed"),e.setAttribute("aria-label","collapse")):(t.parentElement.classList.add("collapsed"),e.setAttribute("aria-label","expand")))})),n(ar(e),"clickListener",(function(t){e.collapseElement(t.target)})),n(ar(e),"focusListener",(function(t){"Enter"===t.key&&e.collapseElement(t.target)})),e}return or(r,[{key:"render",value:function(){return s.createElement(yd,{data:this.props.data},this.renderInner)}},{key:"componentDidMount",value:function(){this.node.addEventListener("click",this.clickListener),this.node.addEventListener("focus",this.focusListener)}},{key:"componentWillUnmount",value:function(){this.node.removeEventListener("click",this.clickListener),this.node.removeEventListener("focus",this.focusListener)}}]),r}(s.PureComponent))(Ad||(Ad=Co(["\n  ",";\n"])),jd),Md=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){var e=this.props,t=e.source,n=e.lang;return s.createElement(Up,{dangerouslySetInnerHTML:{__html:js(t,n)}})}}]),n}(s.PureComponent),Dd=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){var e=this;return s.createElement(yd,{data:this.props.source},(function(t){var n=t.renderCopyButton;return s.createElement(zp,null,s.createElement(Fp,null,n()),s.createElement(Md,{lang:e.props.lang,source:e.props.source}))}))}}]),n}(s.Component);function Fd(e){var t,n=e.value,r=e.mimeType;return fs(r)?s.createElement(Nd,{data:n}):("object"==typeof n&&(n=JSON.stringify(n,null,2)),s.createElement(Dd,{lang:(t=r,-1!==t.search(/xml/i)?"xml":"clike"),source:n}))}function zd(e){var t=e.example,n=e.mimeType;return void 0===t.value&&t.externalValueUrl?s.createElement(Ud,{example:t,mimeType:n}):s.createElement(Fd,{value:t.value,mimeType:n})}function Ud(e){var t=e.example,n=e.mimeType,r=function(e,t){var n=mc((0,s.useState)(!0),2)[1],r=(0,s.useRef)(void 0),o=(0,s.useRef)(void 0);return o.current!==e&&(r.current=void 0),o.current=e,(0,s.useEffect)((function(){!function(){var o=Ua($a().mark((function o(){return $a().wrap((function(o){for(;;)switch(o.prev=o.next){case 0:return n(!0),o.prev=1,o.next=4,e.getExternalValue(t);case 4:r.current=o.sent,o.next=10;break;case 7:o.prev=7,o.t0=o.catch(1),r.current=o.t0;case 10:n(!1);case 11:case"end":return o.stop()}}),o,null,[[1,7]])})));return function(){return o.apply(this,arguments)}}()()}),[e,t]),r.current}(t,n);return void 0===r?s.createElement("span",null,"Loading..."):r instanceof Error?s.createElement(Up,null,"Error loading external example: ",s.createElement("br",null),s.createElement("a",{className:"token string",href:t.externalValueUrl,target:"_blank",rel:"noopener noreferrer"},t.externalValueUrl)):s.createElement(Fd,{value:r,mimeType:n})}var Bd,$d=Sa.div(Rd||(Rd=Co(["\n  padding: 0.9em;\n  background-color: ",";\n  margin: 0 0 10px 0;\n  display: block;\n  font-family: ",";\n  font-size: 0.929em;\n  line-height: 1.5em;\n"])),(function(e){var t=e.theme;return Qr(.6,t.rightPanel.backgroundColor)}),(function(e){return e.theme.typography.headings.fontFamily})),qd=Sa.span(Cd||(Cd=Co(["\n  font-family: ",";\n  font-size: 12px;\n  position: absolute;\n  z-index: 1;\n  top: -11px;\n  left: 12px;\n  font-weight: ",";\n  color: ",";\n"])),(function(e){return e.theme.typography.headings.fontFamily}),(function(e){return e.theme.typography.fontWeightBold}),(function(e){var t=e.theme;return Qr(.3,t.rightPanel.textColor)})),Wd=Sa.div(Td||(Td=Co(["\n  position: relative;\n"]))),Vd=Sa(Xu)(Pd||(Pd=Co(["\n  && {\n    margin-left: 10px;\n    text-transform: none;\n    font-size: 0.929em;\n    margin: 0 0 10px 0;\n    display: block;\n    background-color: ",";\n    font-size: 1em;\n    border: none;\n    padding: 0.9em 1.6em 0.9em 0.9em;\n    box-shadow: none;\n    &:hover,\n    &:focus-within {\n      border: none;\n      box-shadow: none;\n    }\n    &:focus-within {\n      background-color: ",";\n    }\n\n    .dropdown-arrow {\n      border-top-color: ",";\n    }\n    .dropdown-selector-value {\n      text-overflow: ellipsis;\n      white-space: nowrap;\n      overflow: hidden;\n      color: ",";\n    }\n\n    .dropdown-selector-content {\n      margin: 0;\n      margin-top: 2px;\n      .dropdown-option {\n        text-overflow: ellipsis;\n        white-space: nowrap;\n        overflow: hidden;\n      }\n    }\n  }\n"])),(function(e){var t=e.theme;return Qr(.6,t.rightPanel.backgroundColor)}),(function(e){var t=e.theme;return Qr(.3,t.rightPanel.backgroundColor)}),(function(e){return e.theme.rightPanel.textColor}),(function(e){return e.theme.rightPanel.textColor})),Hd=Sa.div(Id||(Id=Co(["\n  font-family: ",";\n  font-size: 12px;\n  color: #ee807f;\n"])),(function(e){return e.theme.typography.code.fontFamily})),Yd=function(e){_o(r,e);var t=Ro(r);function r(){var e;nr(this,r);for(var o=arguments.length,i=new Array(o),a=0;a<o;a++)i[a]=arguments[a];return n(ar(e=t.call.apply(t,[this].concat(i))),"state",{activeIdx:0}),n(ar(e),"switchMedia",(function(t){var n=t.idx;e.setState({activeIdx:n})})),e}return or(r,[{key:"render",value:function(){var e=this.state.activeIdx,t=this.props.mediaType.examples||{},n=this.props.mediaType.name,r=s.createElement(Hd,null,"No sample"),o=Object.keys(t);if(0===o.length)return r;if(o.length>1){var i=o.map((function(e,n){return{value:t[e].summary||e,idx:n}})),a=t[o[e]],l=a.description;return s.createElement(Gd,null,s.createElement(Wd,null,s.createElement(qd,null,"Example"),this.props.renderDropdown({value:i[e].value,options:i,onChange:this.switchMedia,ariaLabel:"Example"})),s.createElement("div",null,l&&s.createElement(cd,{source:l}),s.createElement(zd,{example:a,mimeType:n})))}var c=t[o[0]];return s.createElement(Gd,null,c.description&&s.createElement(cd,{source:c.description}),s.createElement(zd,{example:c,mimeType:n}))}}]),r}(s.Component),Gd=Sa.div(Bd||(Bd=Co(["\n  margin-top: 15px;\n"])));if(!s.useState)throw new Error("mobx-react-lite requires React with Hooks support");if(!an)throw new Error("mobx-react-lite@3 requires mobx at least version 6 to be available");function Qd(e){e()}var Xd=[];function Kd(e){return zt(Yn(e,t));var t}var Zd="undefined"==typeof FinalizationRegistry?void 0:FinalizationRegistry;function Jd(e){return{reaction:e,mounted:!1,changedBeforeMount:!1,cleanAt:Date.now()+eh}}var eh=1e4,th=Zd?function(e){var t=new Map,n=1,r=new e((function(e){var n=t.get(e);n&&(n.reaction.dispose(),t.delete(e))}));return{addReactionToTrack:function(e,o,i){var a=n++;return r.register(i,a,e),e.current=Jd(o),e.current.finalizationRegistryCleanupToken=a,t.set(a,e.current),e.current},recordReactionAsCommitted:function(e){r.unregister(e),e.current&&e.current.finalizationRegistryCleanupToken&&t.delete(e.current.finalizationRegistryCleanupToken)},forceCleanupTimerToRunNowForTests:function(){},resetCleanupScheduleForTests:function(){}}}(Zd):function(){var e,t=new Set;function n(){void 0===e&&(e=setTimeout(r,1e4))}function r(){e=void 0;var r=Date.now();t.forEach((function(e){var n=e.current;n&&r>=n.cleanAt&&(n.reaction.dispose(),e.current=null,t.delete(e))})),t.size>0&&n()}return{addReactionToTrack:function(e,r,o){var i;return e.current=Jd(r),i=e,t.add(i),n(),e.current},recordReactionAsCommitted:function(e){t.delete(e)},forceCleanupTimerToRunNowForTests:function(){e&&(clearTimeout(e),r())},resetCleanupScheduleForTests:function(){var n,r;if(t.size>0){try{for(var o=function(e){var t="function"==typeof Symbol&&Symbol.iterator,n=t&&e[t],r=0;if(n)return n.call(e);if(e&&"number"==typeof e.length)return{next:function(){return e&&r>=e.length&&(e=void 0),{value:e&&e[r++],done:!e}}};throw new TypeError(t?"Object is not iterable.":"Symbol.iterator is not defined.")}(t),i=o.next();!i.done;i=o.next()){var a=i.value,s=a.current;s&&(s.reaction.dispose(),a.current=null)}}catch(e){n={error:e}}finally{try{i&&!i.done&&(r=o.return)&&r.call(o)}finally{if(n)throw n.error}}t.clear()}e&&(clearTimeout(e),e=void 0)}}}(),nh=th.addReactionToTrack,rh=th.recordReactionAsCommitted,oh=(th.resetCleanupScheduleForTests,th.forceCleanupTimerToRunNowForTests,!1);function ih(){return oh}function ah(e){return"observer"+e}var sh=function(){};function lh(e,t){if(void 0===t&&(t="observed"),ih())return e();var n,r=function(e,t){var n="function"==typeof Symbol&&e[Symbol.iterator];if(!n)return e;var r,o,i=n.call(e),a=[];try{for(;(void 0===t||t-- >0)&&!(r=i.next()).done;)a.push(r.value)}catch(e){o={error:e}}finally{try{r&&!r.done&&(n=i.return)&&n.call(i)}finally{if(o)throw o.error}}return a}(s.useState(new sh),1)[0],o=(n=function(e,t){var n="function"==typeof Symbol&&e[Symbol.iterator];if(!n)return e;var r,o,i=n.call(e),a=[];try{for(;(void 0===t||t-- >0)&&!(r=i.next()).done;)a.push(r.value)}catch(e){o={error:e}}finally{try{r&&!r.done&&(n=i.return)&&n.call(i)}finally{if(o)throw o.error}}return a}((0,s.useState)(0),2)[1],(0,s.useCallback)((function(){n((function(e){return e+1}))}),Xd)),i=s.useRef(null);if(!i.current)var a=new yt(ah(t),(function(){l.mounted?o():l.changedBeforeMount=!0})),l=nh(i,a,r);var c,u,p=i.current.reaction;if(s.useDebugValue(p,Kd),s.useEffect((function(){return rh(i),i.current?(i.current.mounted=!0,i.current.changedBeforeMount&&(i.current.changedBeforeMount=!1,o())):(i.current={reaction:new yt(ah(t),(function(){o()})),mounted:!0,changedBeforeMount:!1,cleanAt:1/0},o()),function(){i.current.reaction.dispose(),i.current=null}}),[]),p.track((function(){try{c=e()}catch(e){u=e}})),u)throw u;return c}var ch=function(){return(ch=Object.assign||function(e){for(var t,n=1,r=arguments.length;n<r;n++)for(var o in t=arguments[n])Object.prototype.hasOwnProperty.call(t,o)&&(e[o]=t[o]);return e}).apply(this,arguments)};var uh={$$typeof:!0,render:!0,compare:!0,type:!0};function ph(e){var t=e.children,n=e.render,r=t||n;return"function"!=typeof r?null:lh(r)}ph.displayName="Observer",function(e){e||(e=Qd),Ft({reactionScheduler:e})}(l.unstable_batchedUpdates);var fh=0,dh={};function hh(e){return dh[e]||(dh[e]=function(e){if("function"==typeof Symbol)return Symbol(e);var t="__$mobx-react "+e+" ("+fh+")";return fh++,t}(e)),dh[e]}function mh(e,t){if(vh(e,t))return!0;if("object"!=typeof e||null===e||"object"!=typeof t||null===t)return!1;var n=Object.keys(e),r=Object.keys(t);if(n.length!==r.length)return!1;for(var o=0;o<n.length;o++)if(!Object.hasOwnProperty.call(t,n[o])||!vh(e[n[o]],t[n[o]]))return!1;return!0}function vh(e,t){return e===t?0!==e||1/e==1/t:e!=e&&t!=t}function gh(e,t,n){Object.hasOwnProperty.call(e,t)?e[t]=n:Object.defineProperty(e,t,{enumerable:!1,configurable:!0,writable:!0,value:n})}var yh=hh("patchMixins"),bh=hh("patchedDefinition");function xh(e,t){for(var n=this,r=arguments.length,o=new Array(r>2?r-2:0),i=2;i<r;i++)o[i-2]=arguments[i];t.locks++;try{var a;return null!=e&&(a=e.apply(this,o)),a}finally{t.locks--,0===t.locks&&t.methods.forEach((function(e){e.apply(n,o)}))}}function wh(e,t){return function(){for(var n=arguments.length,r=new Array(n),o=0;o<n;o++)r[o]=arguments[o];xh.call.apply(xh,[this,e,t].concat(r))}}function kh(e,t,n){var r=function(e,t){var n=e[yh]=e[yh]||{},r=n[t]=n[t]||{};return r.locks=r.locks||0,r.methods=r.methods||[],r}(e,t);r.methods.indexOf(n)<0&&r.methods.push(n);var o=Object.getOwnPropertyDescriptor(e,t);if(!o||!o[bh]){var i=e[t],a=Eh(e,t,o?o.enumerable:void 0,r,i);Object.defineProperty(e,t,a)}}function Eh(e,t,n,r,o){var i,a=wh(o,r);return(i={})[bh]=!0,i.get=function(){return a},i.set=function(o){if(this===e)a=wh(o,r);else{var i=Eh(this,t,n,r,o);Object.defineProperty(this,t,i)}},i.configurable=!0,i.enumerable=n,i}var Sh=G||"$mobx",_h=hh("isMobXReactObserver"),Oh=hh("isUnmounted"),Ah=hh("skipRender"),Rh=hh("isForcingUpdate");function Ch(e){var t=e.prototype;if(e[_h]){var n=Th(t);console.warn("The provided component class ("+n+") \n                has already been declared as an observer component.")}else e[_h]=!0;if(t.componentWillReact)throw new Error("The componentWillReact life-cycle event is no longer supported");if(e.__proto__!==s.PureComponent)if(t.shouldComponentUpdate){if(t.shouldComponentUpdate!==Ih)throw new Error("It is not allowed to use shouldComponentUpdate in observer based components.")}else t.shouldComponentUpdate=Ih;jh(t,"props"),jh(t,"state");var r=t.render;return t.render=function(){return Ph.call(this,r)},kh(t,"componentWillUnmount",(function(){var e;if(!0!==ih()&&(null==(e=this.render[Sh])||e.dispose(),this[Oh]=!0,!this.render[Sh])){var t=Th(this);console.warn("The reactive render of an observer class component ("+t+") \n                was overriden after MobX attached. This may result in a memory leak if the \n                overriden reactive render was not properly disposed.")}})),e}function Th(e){return e.displayName||e.name||e.constructor&&(e.constructor.displayName||e.constructor.name)||"<component>"}function Ph(e){var t=this;if(!0===ih())return e.call(this);gh(this,Ah,!1),gh(this,Rh,!1);var n=Th(this),r=e.bind(this),o=!1,i=new yt(n+".render()",(function(){if(!o&&(o=!0,!0!==t[Oh])){var e=!0;try{gh(t,Rh,!0),t[Ah]||s.Component.prototype.forceUpdate.call(t),e=!1}finally{gh(t,Rh,!1),e&&i.dispose()}}}));function a(){o=!1;var e=void 0,t=void 0;if(i.track((function(){try{t=function(e,t){var n=qe(e);try{return t()}finally{We(n)}}(!1,r)}catch(t){e=t}})),e)throw e;return t}return i.reactComponent=this,a[Sh]=i,this.render=a,a.call(this)}function Ih(e,t){return ih()&&console.warn("[mobx-react] It seems that a re-rendering of a React component is triggered while in static (server-side) mode. Please make sure components are rendered only once server-side."),this.state!==t||!mh(this.props,e)}function jh(e,t){var n=hh("reactProp_"+t+"_valueHolder"),r=hh("reactProp_"+t+"_atomHolder");function o(){return this[r]||gh(this,r,K("reactive "+t)),this[r]}Object.defineProperty(e,t,{configurable:!0,enumerable:!0,get:function(){var e=!1;return it&&at&&(e=it(!0)),o.call(this).reportObserved(),it&&at&&at(e),this[n]},set:function(e){this[Rh]||mh(this[n],e)?gh(this,n,e):(gh(this,n,e),gh(this,Ah,!0),o.call(this).reportChanged(),gh(this,Ah,!1))}})}var Lh,Nh,Mh,Dh,Fh,zh,Uh,Bh,$h,qh,Wh,Vh,Hh="function"==typeof Symbol&&Symbol.for,Yh=Hh?Symbol.for("react.forward_ref"):"function"==typeof s.forwardRef&&(0,s.forwardRef)((function(e){return null})).$$typeof,Gh=Hh?Symbol.for("react.memo"):"function"==typeof s.memo&&(0,s.memo)((function(e){return null})).$$typeof;function Qh(e){if(!0===e.isMobxInjector&&console.warn("Mobx observer: You are trying to use 'observer' on a component that already has 'inject'. Please apply 'observer' before applying 'inject'"),Gh&&e.$$typeof===Gh)throw new Error("Mobx observer: You are trying to use 'observer' on a function component wrapped in either another observer or 'React.memo'. The observer already applies 'React.memo' for you.");if(Yh&&e.$$typeof===Yh){var t=e.render;if("function"!=typeof t)throw new Error("render property of ForwardRef was not a function");return(0,s.forwardRef)((function(){var e=arguments;return(0,s.createElement)(ph,null,(function(){return t.apply(void 0,e)}))}))}return"function"!=typeof e||e.prototype&&e.prototype.render||e.isReactClass||Object.prototype.isPrototypeOf.call(s.Component,e)?Ch(e):function(e,t){if(ih())return e;var n,r,o,i=ch({forwardRef:!1},t),a=e.displayName||e.name,l=function(t,n){return lh((function(){return e(t,n)}),a)};return l.displayName=a,n=i.forwardRef?(0,s.memo)((0,s.forwardRef)(l)):(0,s.memo)(l),r=e,o=n,Object.keys(r).forEach((function(e){uh[e]||Object.defineProperty(o,e,Object.getOwnPropertyDescriptor(r,e))})),n.displayName=a,n}(e)}if(!s.Component)throw new Error("mobx-react requires React to be available");if(!Pe)throw new Error("mobx-react requires mobx to be available");var Xh,Kh,Zh,Jh=Sa(nu)(Lh||(Lh=Co(["\n  button {\n    background-color: transparent;\n    border: 0;\n    outline: 0;\n    font-size: 13px;\n    font-family: ",";\n    cursor: pointer;\n    padding: 0;\n    color: ",";\n    &:focus {\n      font-weight: ",";\n    }\n  }\n  "," {\n    height: ",";\n    width: ",";\n    polygon {\n      fill: ",";\n    }\n  }\n"])),(function(e){return e.theme.typography.code.fontFamily}),(function(e){return e.theme.colors.text.primary}),(function(e){return e.theme.typography.fontWeightBold}),Xc,(function(e){return e.theme.schema.arrow.size}),(function(e){return e.theme.schema.arrow.size}),(function(e){return e.theme.schema.arrow.color})),em=Sa.span(Nh||(Nh=Co(["\n  vertical-align: middle;\n  font-size: ",";\n  line-height: 20px;\n"])),(function(e){return e.theme.typography.code.fontSize})),tm=Sa(em)(Mh||(Mh=Co(["\n  color: ",";\n"])),(function(e){return Qr(.1,e.theme.schema.typeNameColor)})),nm=Sa(em)(Dh||(Dh=Co(["\n  color: ",";\n"])),(function(e){return e.theme.schema.typeNameColor})),rm=Sa(em)(Fh||(Fh=Co(["\n  color: ",";\n  word-break: break-word;\n"])),(function(e){return e.theme.schema.typeTitleColor})),om=nm,im=Sa(em.withComponent("div"))(zh||(zh=Co(["\n  color: ",";\n  font-size: ",";\n  font-weight: normal;\n  margin-left: 20px;\n  line-height: 1;\n"])),(function(e){return e.theme.schema.requireLabelColor}),(function(e){return e.theme.schema.labelsTextSize})),am=Sa(em)(Uh||(Uh=Co(["\n  color: ",";\n  font-size: 13px;\n"])),(function(e){return e.theme.colors.warning.main})),sm=Sa(em)(Bh||(Bh=Co(["\n  color: #0e7c86;\n  &::before,\n  &::after {\n    font-weight: bold;\n  }\n"]))),lm=Sa(em)($h||($h=Co(["\n  border-radius: 2px;\n  ",";\n  & + & {\n    margin-left: 0;\n  }\n  ",";\n"])),(function(e){var t=e.theme;return"\n    background-color: ".concat(Qr(.95,t.colors.text.primary),";\n    color: ").concat(Qr(.1,t.colors.text.primary),";\n\n    padding: 0 ").concat(t.spacing.unit,"px;\n    border: 1px solid ").concat(Qr(.9,t.colors.text.primary),";\n    font-family: ").concat(t.typography.code.fontFamily,";\n}")}),_a("ExampleValue")),cm=Sa(lm)(qh||(qh=Co([""]))),um=Sa(em)(Wh||(Wh=Co(["\n  border-radius: 2px;\n  ",";\n  & + & {\n    margin-left: 0;\n  }\n  ",";\n"])),(function(e){var t=e.theme;return"\n    background-color: ".concat(Qr(.95,t.colors.primary.light),";\n    color: ").concat(Qr(.1,t.colors.primary.main),";\n\n    margin: 0 ").concat(t.spacing.unit,"px;\n    padding: 0 ").concat(t.spacing.unit,"px;\n    border: 1px solid ").concat(Qr(.9,t.colors.primary.main),";\n    font-family: ").concat(t.typography.code.fontFamily,";\n}")}),_a("ConstraintItem")),pm=Sa.button(Vh||(Vh=Co(["\n  background-color: transparent;\n  border: 0;\n  color: ",";\n  margin-left: ","px;\n  border-radius: 2px;\n  cursor: pointer;\n  outline-color: ",";\n  font-size: 12px;\n"])),(function(e){return e.theme.colors.text.secondary}),(function(e){return e.theme.spacing.unit}),(function(e){return e.theme.colors.text.secondary})),fm=(r(2479),Sa.div(Xh||(Xh=Co(["\n  ",";\n  ","\n"])),Jf,(function(e){return e.compact?"":"margin: 1em 0"}))),dm=Qh(Kh=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){var e=this.props.externalDocs;return e&&e.url?s.createElement(fm,{compact:this.props.compact},s.createElement("a",{href:e.url},e.description||e.url)):null}}]),n}(s.Component))||Kh,hm=function(e){_o(r,e);var t=Ro(r);function r(){var e;nr(this,r);for(var o=arguments.length,i=new Array(o),a=0;a<o;a++)i[a]=arguments[a];return n(ar(e=t.call.apply(t,[this].concat(i))),"state",{collapsed:!0}),e}return or(r,[{key:"toggle",value:function(){this.setState({collapsed:!this.state.collapsed})}},{key:"render",value:function(){var e=this,t=this.props,n=t.values,r=t.type,o=this.state.collapsed,i=this.context,a=i.enumSkipQuotes,l=i.maxDisplayedEnumValues;if(!n.length)return null;var c=this.state.collapsed&&l?n.slice(0,l):n,u=!!l&&n.length>l,p=l?o?"… ".concat(n.length-l," more"):"Hide":"";return s.createElement("div",null,s.createElement(em,null,"array"===r?wo("enumArray"):""," ",1===n.length?wo("enumSingleValue"):wo("enum"),":")," ",c.map((function(e,t){var n=a?String(e):JSON.stringify(e);return s.createElement(s.Fragment,{key:t},s.createElement(lm,null,n)," ")})),u?s.createElement(gm,{onClick:function(){e.toggle()}},p):null)}}]),r}(s.PureComponent);n(hm,"contextType",Ma);var mm,vm,gm=Sa.span(Zh||(Zh=Co(["\n  color: ",";\n  vertical-align: middle;\n  font-size: 13px;\n  line-height: 20px;\n  padding: 0 5px;\n  cursor: pointer;\n"])),(function(e){return e.theme.colors.primary.main})),ym=Sa(ed)(mm||(mm=Co(["\n  margin: 2px 0;\n"]))),bm=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){var e=this.props.extensions;return s.createElement(Ma.Consumer,null,(function(t){return s.createElement(s.Fragment,null,t.showExtensions&&Object.keys(e).map((function(t){return s.createElement(ym,{key:t},s.createElement(em,null," ",t.substring(2),": ")," ",s.createElement(cm,null,"string"==typeof e[t]?e[t]:JSON.stringify(e[t])))})))}))}}]),n}(s.PureComponent),xm=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){return 0===this.props.constraints.length?null:s.createElement("span",null," ",this.props.constraints.map((function(e){return s.createElement(um,{key:e}," ",e," ")})))}}]),n}(s.PureComponent),wm=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){if(void 0===this.props.value)return null;var e=this.props.raw?String(this.props.value):JSON.stringify(this.props.value);return s.createElement("div",null,s.createElement(em,null," ",this.props.label," ")," ",s.createElement(lm,null,e))}}]),n}(s.PureComponent),km=function(e){_o(r,e);var t=Ro(r);function r(){var e;nr(this,r);for(var o=arguments.length,i=new Array(o),a=0;a<o;a++)i[a]=arguments[a];return n(ar(e=t.call.apply(t,[this].concat(i))),"state",{patternShown:!1}),n(ar(e),"togglePattern",(function(){e.setState({patternShown:!e.state.patternShown})})),e}return or(r,[{key:"render",value:function(){var e=this.props,t=e.showExamples,n=e.field,r=e.renderDiscriminatorSwitch,o=this.state.patternShown,i=this.context,l=i.enumSkipQuotes,c=i.hideSchemaTitles,u=i.hideSchemaPattern,p=n.schema,f=n.description,d=n.example,h=n.deprecated,m=n.examples,v=!!l||"header"===n.in,g=null;if(t&&(void 0!==d||void 0!==m))if(void 0!==m)g=s.createElement(Em,{field:n});else{var y=wo("example")+":",b=!!n.in;g=s.createElement(wm,{label:y,value:Sm(n,n.example),raw:b})}return s.createElement("div",null,s.createElement("div",null,s.createElement(tm,null,p.typePrefix),s.createElement(nm,null,p.displayType),p.displayFormat&&s.createElement(om,null," ","<",p.displayFormat,">"," "),p.contentEncoding&&s.createElement(om,null," ","<",p.contentEncoding,">"," "),p.contentMediaType&&s.createElement(om,null," ","<",p.contentMediaType,">"," "),p.title&&!c&&s.createElement(rm,null," (",p.title,") "),s.createElement(xm,{constraints:p.constraints}),p.pattern&&!u&&s.createElement(s.Fragment,null,s.createElement(sm,null,o||p.pattern.length<45?p.pattern:"".concat(p.pattern.substr(0,45),"...")),p.pattern.length>45&&s.createElement(pm,{onClick:this.togglePattern},o?"Hide pattern":"Show pattern")),p.isCircular&&s.createElement(am,null," ",wo("recursive")," ")),h&&s.createElement("div",null,s.createElement(Kc,{type:"warning"}," ",wo("deprecated")," ")),s.createElement(wm,{raw:v,label:wo("default")+":",value:p.default}),!r&&s.createElement(hm,{type:p.type,values:p.enum})," ",g,s.createElement(bm,{extensions:a(a({},n.extensions),p.extensions)}),s.createElement("div",null,s.createElement(cd,{compact:!0,source:f})),p.externalDocs&&s.createElement(dm,{externalDocs:p.externalDocs,compact:!0}),r&&r(this.props)||null,n.const&&s.createElement(wm,{label:wo("const")+":",value:n.const})||null)}}]),r}(s.PureComponent);function Em(e){var t=e.field;return t.examples?s.createElement(s.Fragment,null,s.createElement(em,null," ",wo("examples"),": "),s.createElement(tv,null,Object.values(t.examples).map((function(e,n){return s.createElement("li",{key:n},s.createElement(lm,null,Sm(t,e.value))," -"," ",e.summary||e.description)})))):null}function Sm(e,t){return e.in?decodeURIComponent(function(e,t){var r=e.name,o=e.style,i=e.explode,a=void 0!==i&&i,s=e.serializationMime;if(s)switch(e.in){case"path":case"header":return vs(t,s);case"cookie":case"query":return"".concat(r,"=").concat(vs(t,s));default:return console.warn("Unexpected parameter location: "+e.in),""}if(!o)return console.warn("Missing style attribute or content for parameter ".concat(r)),"";switch(e.in){case"path":return function(e,t,r,o){var i=r?"*":"",a="";"label"===t?a=".":"matrix"===t&&(a=";");var s="__redoc_param_name__";return os.parse("{".concat(a).concat(s).concat(i,"}")).expand(n({},s,o)).replace(/__redoc_param_name__/g,e)}(r,o,a,t);case"query":return function(e,t,n,r){switch(t){case"form":return ms(e,n,r);case"spaceDelimited":return Array.isArray(r)?n?ms(e,n,r):"".concat(e,"=").concat(r.join("%20")):(console.warn("The style spaceDelimited is only applicable to arrays"),"");case"pipeDelimited":return Array.isArray(r)?n?ms(e,n,r):"".concat(e,"=").concat(r.join("|")):(console.warn("The style pipeDelimited is only applicable to arrays"),"");case"deepObject":return!n||Array.isArray(r)||"object"!=typeof r?(console.warn("The style deepObject is only applicable for objects with explode=true"),""):hs(r,e);default:return console.warn("Unexpected style for query: "+t),""}}(r,o,a,t);case"header":return function(e,t,r){switch(e){case"simple":var o=t?"*":"",i="__redoc_param_name__",a=os.parse("{".concat(i).concat(o,"}"));return decodeURIComponent(a.expand(n({},i,r)));default:return console.warn("Unexpected style for header: "+e),""}}(o,a,t);case"cookie":return function(e,t,n,r){switch(t){case"form":return ms(e,n,r);default:return console.warn("Unexpected style for cookie: "+t),""}}(r,o,a,t);default:return console.warn("Unexpected parameter location: "+e.in),""}}(e,t)):t}n(km,"contextType",Ma);var _m,Om,Am,Rm,Cm,Tm,Pm,Im,jm,Lm,Nm,Mm,Dm,Fm,zm,Um,Bm,$m,qm,Wm,Vm,Hm,Ym,Gm,Qm,Xm,Km,Zm,Jm,ev,tv=Sa.ul(vm||(vm=Co(["\n  margin-top: 1em;\n  padding-left: 0;\n  list-style-position: inside;\n"]))),nv=Sa.div(_m||(_m=Co(["\n  padding-left: ","px;\n"])),(function(e){return 2*e.theme.spacing.unit})),rv=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){var e=this.props.schema,t=e.items,n=void 0===e.minItems&&void 0===e.maxItems?"":"(".concat(xs(e),")");return!e.displayType||t||n.length?s.createElement("div",null,s.createElement(uu,null," Array ",n),s.createElement(nv,null,s.createElement(cv,ir({},this.props,{schema:t}))),s.createElement(pu,null)):s.createElement("div",null,s.createElement(nm,null,e.displayType))}}]),n}(s.PureComponent),ov=Qh(Om=function(e){_o(r,e);var t=Ro(r);function r(){var e;nr(this,r);for(var o=arguments.length,i=new Array(o),a=0;a<o;a++)i[a]=arguments[a];return n(ar(e=t.call.apply(t,[this].concat(i))),"toggle",(function(){void 0===e.props.field.expanded&&e.props.expandByDefault?e.props.field.expanded=!1:e.props.field.toggle()})),n(ar(e),"handleKeyPress",(function(t){"Enter"===t.key&&(t.preventDefault(),e.toggle())})),e}return or(r,[{key:"render",value:function(){var e=this.props,t=e.className,n=e.field,r=e.isLast,o=e.expandByDefault,i=n.name,a=n.deprecated,l=n.required,c=n.kind,u=!n.schema.isPrimitive&&!n.schema.isCircular,p=void 0===n.expanded?o:n.expanded,f=u?s.createElement(Jh,{className:a?"deprecated":"",kind:c,title:i},s.createElement(ou,null),s.createElement("button",{onClick:this.toggle,onKeyPress:this.handleKeyPress,"aria-label":"expand properties"},s.createElement("span",null,i),s.createElement(Xc,{direction:p?"down":"right"})),l&&s.createElement(im,null," required ")):s.createElement(nu,{className:a?"deprecated":void 0,kind:c,title:i},s.createElement(ou,null),s.createElement("span",null,i),l&&s.createElement(im,null," required "));return s.createElement(s.Fragment,null,s.createElement("tr",{className:r?"last "+t:t},f,s.createElement(ru,null,s.createElement(km,this.props))),p&&u&&s.createElement("tr",{key:n.name+"inner"},s.createElement(tu,{colSpan:2},s.createElement(iu,null,s.createElement(cv,{schema:n.schema,skipReadOnly:this.props.skipReadOnly,skipWriteOnly:this.props.skipWriteOnly,showTitle:this.props.showTitle})))))}}]),r}(s.Component))||Om,iv=Qh(Am=function(e){_o(r,e);var t=Ro(r);function r(){var e;nr(this,r);for(var o=arguments.length,i=new Array(o),a=0;a<o;a++)i[a]=arguments[a];return n(ar(e=t.call.apply(t,[this].concat(i))),"changeActiveChild",(function(t){e.props.parent.activateOneOf(t.idx)})),e}return or(r,[{key:"sortOptions",value:function(e,t){if(0!==t.length){var n={};t.forEach((function(e,t){n[e]=t})),e.sort((function(e,t){return n[e.value]>n[t.value]?1:-1}))}}},{key:"render",value:function(){var e=this.props,t=e.parent,n=e.enumValues;if(void 0===t.oneOf)return null;var r=t.oneOf.map((function(e,t){return{value:e.title,idx:t}})),o=r[t.activeOneOf].value;return this.sortOptions(r,n),s.createElement(Xu,{value:o,options:r,onChange:this.changeActiveChild,ariaLabel:"Example"})}}]),r}(s.Component))||Am,av=Qh((Tm=Cm=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"parentSchema",get:function(){return this.props.discriminator.parentSchema}},{key:"render",value:function(){var e=this,t=this.props,n=t.schema.fields,r=void 0===n?[]:n,o=t.showTitle,i=t.discriminator,a=this.props.skipReadOnly||this.props.skipWriteOnly?r.filter((function(t){return!(e.props.skipReadOnly&&t.schema.readOnly||e.props.skipWriteOnly&&t.schema.writeOnly)})):r,l=this.context.expandSingleSchemaField&&1===a.length;return s.createElement(au,null,o&&s.createElement(Jc,null,this.props.schema.title),s.createElement("tbody",null,lo(a,(function(t,n){return s.createElement(ov,{key:t.name,isLast:n,field:t,expandByDefault:l,renderDiscriminatorSwitch:i&&i.fieldName===t.name&&function(){return s.createElement(iv,{parent:e.parentSchema,enumValues:t.schema.enum})}||void 0,className:t.expanded?"expanded":void 0,showExamples:!1,skipReadOnly:e.props.skipReadOnly,skipWriteOnly:e.props.skipWriteOnly,showTitle:e.props.showTitle})}))))}}]),n}(s.Component),n(Cm,"contextType",Ma),Rm=Tm))||Rm,sv=Qh(Pm=function(e){_o(r,e);var t=Ro(r);function r(){var e;nr(this,r);for(var o=arguments.length,i=new Array(o),a=0;a<o;a++)i[a]=arguments[a];return n(ar(e=t.call.apply(t,[this].concat(i))),"activateOneOf",(function(){e.props.schema.activateOneOf(e.props.idx)})),e}return or(r,[{key:"render",value:function(){var e=this.props,t=e.idx,n=e.schema,r=e.subSchema;return s.createElement(cu,{active:t===n.activeOneOf,onClick:this.activateOneOf},r.title||r.typePrefix+r.displayType)}}]),r}(s.Component))||Pm,lv=Qh(Im=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){var e=this.props,t=e.schema.oneOf,n=e.schema;return void 0===t?null:s.createElement("div",null,s.createElement(lu,null," ",n.oneOfType," "),s.createElement(su,null,t.map((function(e,t){return s.createElement(sv,{key:e.pointer,schema:n,subSchema:e,idx:t})}))),s.createElement(cv,ir({},this.props,{schema:t[n.activeOneOf]})))}}]),n}(s.Component))||Im,cv=Qh(jm=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){var e=this.props.schema;if(!e)return s.createElement("em",null," Schema not provided ");var t=e.type,n=e.oneOf,r=e.discriminatorProp;if(e.isCircular)return s.createElement("div",null,s.createElement(nm,null,e.displayType),e.title&&s.createElement(rm,null," ",e.title," "),s.createElement(am,null," ",wo("recursive")," "));if(void 0!==r)return n&&n.length?s.createElement(av,ir({},a(a({},this.props),{},{schema:n[e.activeOneOf]}),{discriminator:{fieldName:r,parentSchema:e}})):(console.warn("Looks like you are using discriminator wrong: you don't have any definition inherited from the ".concat(e.title)),null);if(void 0!==n)return s.createElement(lv,ir({schema:e},this.props));var o,i=Array.isArray(t)?t:[t];if(i.includes("object")){if(null!==(o=e.fields)&&void 0!==o&&o.length)return s.createElement(av,this.props)}else if(i.includes("array"))return s.createElement(rv,this.props);var l={schema:e,name:"",required:!1,description:e.description,externalDocs:e.externalDocs,deprecated:!1,toggle:function(){return null},expanded:!1};return s.createElement("div",null,s.createElement(km,{field:l}))}}]),n}(s.Component))||jm,uv=function(e){_o(r,e);var t=Ro(r);function r(){var e;nr(this,r);for(var o=arguments.length,i=new Array(o),a=0;a<o;a++)i[a]=arguments[a];return n(ar(e=t.call.apply(t,[this].concat(i))),"_mediaModel",void 0),n(ar(e),"renderDropdown",(function(e){return s.createElement(Qf,ir({Label:Zu,Dropdown:Vd},e))})),e}return or(r,[{key:"mediaModel",get:function(){var e=this.props,t=e.parser,n=e.schemaRef,o=e.exampleRef,i=e.options;return this._mediaModel||(this._mediaModel=new Ml(t,"json",!1,r.getMediaType(n,o),i)),this._mediaModel}},{key:"render",value:function(){var e=this.props,t=e.showReadOnly,n=void 0===t||t,r=e.showWriteOnly,o=void 0!==r&&r;return s.createElement(ic,null,s.createElement(lc,null,s.createElement(oc,null,s.createElement(cv,{skipWriteOnly:!o,skipReadOnly:!n,schema:this.mediaModel.schema})),s.createElement(sc,null,s.createElement(pv,null,s.createElement(Yd,{renderDropdown:this.renderDropdown,mediaType:this.mediaModel})))))}}],[{key:"getMediaType",value:function(e,t){if(!e)return{};var n={schema:{$ref:e}};return t&&(n.examples={example:{$ref:t}}),n}}]),r}(s.PureComponent),pv=Sa.div(Lm||(Lm=Co(["\n  background: ",";\n  & > div,\n  & > pre {\n    padding: ","px;\n    margin: 0;\n  }\n\n  & > div > pre {\n    padding: 0;\n  }\n"])),(function(e){return e.theme.codeBlock.backgroundColor}),(function(e){return 4*e.theme.spacing.unit})),fv={oauth2:"OAuth2",apiKey:"API Key",http:"HTTP",openIdConnect:"OpenID Connect"},dv=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){var e=this.props,t=e.type,n=e.flow;return s.createElement("tr",null,s.createElement("th",null," ",t," OAuth Flow "),s.createElement("td",null,"implicit"===t||"authorizationCode"===t?s.createElement("div",null,s.createElement("strong",null," Authorization URL: "),n.authorizationUrl):null,"password"===t||"clientCredentials"===t||"authorizationCode"===t?s.createElement("div",null,s.createElement("strong",null," Token URL: "),n.tokenUrl):null,n.refreshUrl&&s.createElement("div",null,s.createElement("strong",null," Refresh URL: "),n.refreshUrl),s.createElement("div",null,s.createElement("strong",null," Scopes: ")),s.createElement("ul",null,Object.keys(n.scopes||{}).map((function(e){return s.createElement("li",{key:e},s.createElement("code",null,e)," - ",s.createElement(cd,{inline:!0,source:n.scopes[e]||""}))})))))}}]),n}(s.PureComponent),hv=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){return this.props.securitySchemes.schemes.map((function(e){return s.createElement(ic,{id:e.sectionId,key:e.id},s.createElement(lc,null,s.createElement(oc,null,s.createElement(fc,null,s.createElement(Ac,{to:e.sectionId}),e.id),s.createElement(cd,{source:e.description||""}),s.createElement(ed,null,s.createElement("table",{className:"security-details"},s.createElement("tbody",null,s.createElement("tr",null,s.createElement("th",null," Security Scheme Type "),s.createElement("td",null," ",fv[e.type]||e.type," ")),e.apiKey?s.createElement("tr",null,s.createElement("th",null," ",(t=e.apiKey.in||"").charAt(0).toUpperCase()+t.slice(1)," parameter name:"),s.createElement("td",null," ",e.apiKey.name," ")):e.http?[s.createElement("tr",{key:"scheme"},s.createElement("th",null," HTTP Authorization Scheme "),s.createElement("td",null," ",e.http.scheme," ")),"bearer"===e.http.scheme&&e.http.bearerFormat&&s.createElement("tr",{key:"bearer"},s.createElement("th",null," Bearer format "),s.createElement("td",null,' "',e.http.bearerFormat,'" '))]:e.openId?s.createElement("tr",null,s.createElement("th",null," Connect URL "),s.createElement("td",null,s.createElement("a",{target:"_blank",rel:"noopener noreferrer",href:e.openId.connectUrl},e.openId.connectUrl))):e.flows?Object.keys(e.flows).map((function(t){return s.createElement(dv,{key:t,type:t,flow:e.flows[t]})})):null))))));var t}))}}]),n}(s.PureComponent),mv=function(){function e(t,r){var o=this,i=arguments.length>2&&void 0!==arguments[2]?arguments[2]:{},a=!(arguments.length>3&&void 0!==arguments[3])||arguments[3];nr(this,e),n(this,"menu",void 0),n(this,"spec",void 0),n(this,"rawOptions",void 0),n(this,"options",void 0),n(this,"search",void 0),n(this,"marker",new Us),n(this,"scroll",void 0),n(this,"disposer",null),this.rawOptions=i,this.options=new So(i,vv),this.scroll=new Yf(this.options),Vf.updateOnHistory(Fs.currentId,this.scroll),this.spec=new Bf(t,r,this.options),this.menu=new Vf(this.spec,this.scroll,Fs),this.options.disableSearch||(this.search=new Gf,a&&this.search.indexItems(this.menu.items),this.disposer=Gt(this.menu,"activeItemIdx",(function(e){o.updateMarkOnMenu(e.newValue)})))}var t;return or(e,[{key:"onDidMount",value:function(){this.menu.updateOnHistory(),this.updateMarkOnMenu(this.menu.activeItemIdx)}},{key:"dispose",value:function(){this.scroll.dispose(),this.menu.dispose(),this.search&&this.search.dispose(),null!=this.disposer&&this.disposer()}},{key:"toJS",value:(t=Ua($a().mark((function e(){return $a().wrap((function(e){for(;;)switch(e.prev=e.next){case 0:if(e.t0={activeItemIdx:this.menu.activeItemIdx},e.t1={url:this.spec.parser.specUrl,data:this.spec.parser.spec},!this.search){e.next=8;break}return e.next=5,this.search.toJS();case 5:e.t2=e.sent,e.next=9;break;case 8:e.t2=void 0;case 9:return e.t3=e.t2,e.t4=this.rawOptions,e.abrupt("return",{menu:e.t0,spec:e.t1,searchIndex:e.t3,options:e.t4});case 12:case"end":return e.stop()}}),e,this)}))),function(){return t.apply(this,arguments)})},{key:"updateMarkOnMenu",value:function(e){for(var t=Math.max(0,e),n=Math.min(this.menu.flatItems.length,t+5),r=[],o=t;o<n;o++){var i=this.menu.getElementAt(o);i&&r.push(i)}if(-1===e&&Jr){var a=document.querySelector('[data-role="redoc-description"]'),s=document.querySelector('[data-role="redoc-summary"]');a&&r.push(a),s&&r.push(s)}this.marker.addOnly(r),this.marker.mark()}}],[{key:"fromJS",value:function(t){var n=new e(t.spec.data,t.spec.url,t.options,!1);return n.menu.activeItemIdx=t.menu.activeItemIdx||0,n.menu.activate(n.menu.flatItems[n.menu.activeItemIdx]),n.options.disableSearch||n.search.load(t.searchIndex),n}}]),e}(),vv={allowedMdComponents:(Nm={},n(Nm,Ss,{component:hv,propsSelector:function(e){return{securitySchemes:e.spec.securitySchemes}}}),n(Nm,_s,{component:hv,propsSelector:function(e){return{securitySchemes:e.spec.securitySchemes}}}),n(Nm,"SchemaDefinition",{component:uv,propsSelector:function(e){return{parser:e.spec.parser,options:e.options}}}),Nm)},gv=Sa(pc)(Mm||(Mm=Co(["\n  margin-top: 0;\n  margin-bottom: 0.5em;\n\n  ",";\n"])),_a("ApiHeader")),yv=Sa.a(Dm||(Dm=Co(["\n  border: 1px solid ",";\n  color: ",";\n  font-weight: normal;\n  margin-left: 0.5em;\n  padding: 4px 8px 4px;\n  display: inline-block;\n  text-decoration: none;\n  cursor: pointer;\n\n  ",";\n"])),(function(e){return e.theme.colors.primary.main}),(function(e){return e.theme.colors.primary.main}),_a("DownloadButton")),bv=Sa.span(Fm||(Fm=Co(["\n  &::before {\n    content: '|';\n    display: inline-block;\n    opacity: 0.5;\n    width: ","px;\n    text-align: center;\n  }\n\n  &:last-child::after {\n    display: none;\n  }\n"])),15),xv=Sa.div(zm||(zm=Co(["\n  overflow: hidden;\n"]))),wv=Sa.div(Um||(Um=Co(["\n  display: flex;\n  flex-wrap: wrap;\n  // hide separator on new lines: idea from https://stackoverflow.com/a/31732902/1749888\n  margin-left: -","px;\n"])),15),kv=Qh(Bm=function(e){_o(r,e);var t=Ro(r);function r(){var e;nr(this,r);for(var o=arguments.length,i=new Array(o),a=0;a<o;a++)i[a]=arguments[a];return n(ar(e=t.call.apply(t,[this].concat(i))),"handleDownloadClick",(function(t){t.target.href||(t.target.href=e.props.store.spec.info.downloadLink)})),e}return or(r,[{key:"render",value:function(){var e=this.props.store,t=e.spec,n=t.info,r=t.externalDocs,o=e.options.hideDownloadButton,i=n.downloadFileName,a=n.downloadLink,l=n.license&&s.createElement(bv,null,"License:"," ",n.license.identifier?n.license.identifier:s.createElement("a",{href:n.license.url},n.license.name))||null,c=n.contact&&n.contact.url&&s.createElement(bv,null,"URL: ",s.createElement("a",{href:n.contact.url},n.contact.url))||null,u=n.contact&&n.contact.email&&s.createElement(bv,null,n.contact.name||"E-mail",":"," ",s.createElement("a",{href:"mailto:"+n.contact.email},n.contact.email))||null,p=n.termsOfService&&s.createElement(bv,null,s.createElement("a",{href:n.termsOfService},"Terms of Service"))||null,f=n.version&&s.createElement("span",null,"(",n.version,")")||null;return s.createElement(ic,null,s.createElement(lc,null,s.createElement(oc,{className:"api-info"},s.createElement(gv,null,n.title," ",f),!o&&s.createElement("p",null,wo("downloadSpecification"),":",s.createElement(yv,{download:i||!0,target:"_blank",href:a,onClick:this.handleDownloadClick},wo("download"))),s.createElement(ed,null,(n.license||n.contact||n.termsOfService)&&s.createElement(xv,null,s.createElement(wv,null,u," ",c," ",l," ",p))||null),s.createElement(cd,{source:e.spec.info.summary,"data-role":"redoc-summary"}),s.createElement(cd,{source:e.spec.info.description,"data-role":"redoc-description"}),r&&s.createElement(dm,{externalDocs:r}))))}}]),r}(s.Component))||Bm,Ev=Sa.img($m||($m=Co(["\n  max-height: ",";\n  max-width: ",";\n  padding: ",";\n  width: 100%;\n  display: block;\n"])),(function(e){return e.theme.logo.maxHeight}),(function(e){return e.theme.logo.maxWidth}),(function(e){return e.theme.logo.gutter})),Sv=Sa.div(qm||(qm=Co(["\n  text-align: center;\n"]))),_v=Sa.a(Wm||(Wm=Co(["\n  display: inline-block;\n"]))),Ov=Qh(Vm=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){var e=this.props.info,t=e["x-logo"];if(!t||!t.url)return null;var n,r=t.href||e.contact&&e.contact.url,o=t.altText?t.altText:"logo",i=s.createElement(Ev,{src:t.url,alt:o});return s.createElement(Sv,{style:{backgroundColor:t.backgroundColor}},r?(n=r,function(e){return s.createElement(_v,{href:n},e)})(i):i)}}]),n}(s.Component))||Vm,Av=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){var e=this;return s.createElement(Fa,null,(function(t){return s.createElement(xc,null,(function(n){return e.renderWithOptionsAndStore(t,n)}))}))}},{key:"renderWithOptionsAndStore",value:function(e,t){var n=this.props,r=n.source,o=n.htmlWrap,i=void 0===o?function(e){return e}:o;if(!t)throw new Error("When using components in markdown, store prop must be provided");var l=new Vs(e).renderMdWithComponents(r);return l.length?l.map((function(e,n){return"string"==typeof e?s.cloneElement(i(s.createElement(nd,{html:e,inline:!1,compact:!1})),{key:n}):s.createElement(e.component,ir({key:n},a(a({},e.props),e.propsSelector(t))))})):null}}]),n}(s.Component),Rv=(r(9254),r(4184)),Cv=r.n(Rv),Tv=Sa.span.attrs((function(e){return{className:"operation-type ".concat(e.type)}}))(Hm||(Hm=Co(["\n  width: 9ex;\n  display: inline-block;\n  height: ",";\n  line-height: ",";\n  background-color: #333;\n  border-radius: 3px;\n  background-repeat: no-repeat;\n  background-position: 6px 4px;\n  font-size: 7px;\n  font-family: Verdana, sans-serif; // web-safe\n  color: white;\n  text-transform: uppercase;\n  text-align: center;\n  font-weight: bold;\n  vertical-align: middle;\n  margin-right: 6px;\n  margin-top: 2px;\n\n  &.get {\n    background-color: ",";\n  }\n\n  &.post {\n    background-color: ",";\n  }\n\n  &.put {\n    background-color: ",";\n  }\n\n  &.options {\n    background-color: ",";\n  }\n\n  &.patch {\n    background-color: ",";\n  }\n\n  &.delete {\n    background-color: ",";\n  }\n\n  &.basic {\n    background-color: ",";\n  }\n\n  &.link {\n    background-color: ",";\n  }\n\n  &.head {\n    background-color: ",";\n  }\n\n  &.hook {\n    background-color: ",";\n  }\n"])),(function(e){return e.theme.typography.code.fontSize}),(function(e){return e.theme.typography.code.fontSize}),(function(e){return e.theme.colors.http.get}),(function(e){return e.theme.colors.http.post}),(function(e){return e.theme.colors.http.put}),(function(e){return e.theme.colors.http.options}),(function(e){return e.theme.colors.http.patch}),(function(e){return e.theme.colors.http.delete}),(function(e){return e.theme.colors.http.basic}),(function(e){return e.theme.colors.http.link}),(function(e){return e.theme.colors.http.head}),(function(e){return e.theme.colors.primary.main}));function Pv(e,t){var n=t.theme;return e>1?zr(.1,n.sidebar.backgroundColor):1===e?zr(.05,n.sidebar.backgroundColor):""}var Iv,jv,Lv,Nv,Mv,Dv,Fv,zv,Uv,Bv,$v,qv,Wv,Vv=Sa.ul(Ym||(Ym=Co(["\n  margin: 0;\n  padding: 0;\n\n  & & {\n    font-size: 0.929em;\n  }\n\n  ",";\n"])),(function(e){return e.expanded?"":"display: none;"})),Hv=Sa.li(Gm||(Gm=Co(["\n  list-style: none inside none;\n  overflow: hidden;\n  text-overflow: ellipsis;\n  padding: 0;\n  ",";\n"])),(function(e){return 0===e.depth?"margin-top: 15px":""})),Yv={0:ba(Qm||(Qm=Co(["\n    opacity: 0.7;\n    text-transform: ",";\n    font-size: 0.8em;\n    padding-bottom: 0;\n    cursor: default;\n    color: ",";\n  "])),(function(e){return e.theme.sidebar.groupItems.textTransform}),(function(e){return e.theme.sidebar.textColor})),1:ba(Xm||(Xm=Co(["\n    font-size: 0.929em;\n    text-transform: ",";\n    &:hover {\n      color: ",";\n    }\n  "])),(function(e){return e.theme.sidebar.level1Items.textTransform}),(function(e){return e.theme.sidebar.activeTextColor})),2:ba(Km||(Km=Co(["\n    color: ",";\n  "])),(function(e){return e.theme.sidebar.textColor}))},Gv=Sa.label.attrs((function(e){return{role:"menuitem",className:Cv()("-depth"+e.depth,{active:e.active})}}))(Zm||(Zm=Co(["\n  cursor: pointer;\n  color: ",";\n  margin: 0;\n  padding: 12.5px ","px;\n  ","\n  display: flex;\n  justify-content: space-between;\n  font-family: ",";\n  ",";\n  background-color: ",";\n\n  ",";\n\n  &:hover {\n    background-color: ",";\n  }\n\n  "," {\n    height: ",";\n    width: ",";\n    polygon {\n      fill: ",";\n    }\n  }\n"])),(function(e){return e.active?e.theme.sidebar.activeTextColor:e.theme.sidebar.textColor}),(function(e){return 4*e.theme.spacing.unit}),(function(e){var t=e.depth,n=e.type,r=e.theme;return"section"===n&&t>1&&"padding-left: "+8*r.spacing.unit+"px;"||""}),(function(e){return e.theme.typography.headings.fontFamily}),(function(e){return Yv[e.depth]}),(function(e){return e.active?Pv(e.depth,e):""}),(function(e){return e.deprecated&&Zc||""}),(function(e){return Pv(e.depth,e)}),Xc,(function(e){return e.theme.sidebar.arrow.size}),(function(e){return e.theme.sidebar.arrow.size}),(function(e){return e.theme.sidebar.arrow.color})),Qv=Sa.span(Jm||(Jm=Co(["\n  display: inline-block;\n  vertical-align: middle;\n  width: ",";\n  overflow: hidden;\n  text-overflow: ellipsis;\n"])),(function(e){return e.width?e.width:"auto"})),Xv=Sa.div(ev||(ev=Co(["\n  ",";\n"])),(function(e){var t=e.theme;return"\n  font-size: 0.8em;\n  margin-top: ".concat(2*t.spacing.unit,"px;\n  padding: 0 ").concat(4*t.spacing.unit,"px;\n  text-align: left;\n\n  opacity: 0.7;\n\n  a,\n  a:visited,\n  a:hover {\n    color: ").concat(t.sidebar.textColor," !important;\n    border-top: 1px solid ").concat(zr(.1,t.sidebar.backgroundColor),";\n    padding: ").concat(t.spacing.unit,"px 0;\n    display: block;\n  }\n")})),Kv=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){var e=this.props,t=e.name,n=e.opened,r=e.className,o=e.onClick,i=e.httpVerb,a=e.deprecated;return s.createElement(Zv,{className:r,onClick:o||void 0},s.createElement(eg,{type:i},Rs(i)),s.createElement(Xc,{size:"1.5em",direction:n?"down":"right",float:"left"}),s.createElement(Jv,{deprecated:a},t),a?s.createElement(Kc,{type:"warning"}," ",wo("deprecated")," "):null)}}]),n}(s.PureComponent),Zv=Sa.button(Iv||(Iv=Co(["\n  border: 0;\n  width: 100%;\n  text-align: left;\n  & > * {\n    vertical-align: middle;\n  }\n\n  "," {\n    polygon {\n      fill: ",";\n    }\n  }\n"])),Xc,(function(e){var t=e.theme;return zr(t.colors.tonalOffset,t.colors.gray[100])})),Jv=Sa.span(jv||(jv=Co(["\n  text-decoration: ",";\n  margin-right: 8px;\n"])),(function(e){return e.deprecated?"line-through":"none"})),eg=Sa(Tv)(Lv||(Lv=Co(["\n  margin: 0 5px 0 0;\n"]))),tg=Sa(Kv)(Nv||(Nv=Co(["\n  padding: 10px;\n  border-radius: 2px;\n  margin-bottom: 4px;\n  line-height: 1.5em;\n  background-color: ",";\n  cursor: pointer;\n  outline-color: ",";\n"])),(function(e){return e.theme.colors.gray[100]}),(function(e){var t=e.theme;return zr(t.colors.tonalOffset,t.colors.gray[100])})),ng=Sa.div(Mv||(Mv=Co(["\n  padding: 10px 25px;\n  background-color: ",";\n  margin-bottom: 5px;\n  margin-top: 5px;\n"])),(function(e){return e.theme.colors.gray[50]})),rg=function(e){_o(r,e);var t=Ro(r);function r(){var e;nr(this,r);for(var o=arguments.length,i=new Array(o),a=0;a<o;a++)i[a]=arguments[a];return n(ar(e=t.call.apply(t,[this].concat(i))),"child",void 0),n(ar(e),"selectElement",(function(){gd.selectElement(e.child)})),e}return or(r,[{key:"render",value:function(){var e=this,t=this.props.children;return s.createElement("div",{ref:function(t){return e.child=t},onClick:this.selectElement,onFocus:this.selectElement,tabIndex:0,role:"button"},t)}}]),r}(s.PureComponent),og=Sa.div(Dv||(Dv=Co(["\n  cursor: pointer;\n  position: relative;\n  margin-bottom: 5px;\n"]))),ig=Sa.span(Fv||(Fv=Co(["\n  font-family: ",";\n  margin-left: 10px;\n  flex: 1;\n  overflow-x: hidden;\n  text-overflow: ellipsis;\n"])),(function(e){return e.theme.typography.code.fontFamily})),ag=Sa.button(zv||(zv=Co(["\n  outline: 0;\n  color: inherit;\n  width: 100%;\n  text-align: left;\n  cursor: pointer;\n  padding: 10px 30px 10px ",";\n  border-radius: ",";\n  background-color: ",";\n  display: flex;\n  white-space: nowrap;\n  align-items: center;\n  border: ",";\n  border-bottom: ",";\n  transition: border-color 0.25s ease;\n\n  ","\n\n  ."," {\n    color: ",";\n  }\n  &:focus {\n    box-shadow: inset 0 2px 2px rgba(0, 0, 0, 0.45), 0 2px 0 rgba(128, 128, 128, 0.25);\n  }\n"])),(function(e){return e.inverted?"10px":"20px"}),(function(e){return e.inverted?"0":"4px 4px 0 0"}),(function(e){return e.inverted?"transparent":e.theme.codeBlock.backgroundColor}),(function(e){return e.inverted?"0":"1px solid transparent"}),(function(e){return e.inverted?"1px solid #ccc":"0"}),(function(e){return e.expanded&&!e.inverted&&"border-color: ".concat(e.theme.colors.border.dark,";")||""}),ig,(function(e){return e.inverted?e.theme.colors.text.primary:"#ffffff"})),sg=Sa.span.attrs((function(e){return{className:"http-verb ".concat(e.type)}}))(Uv||(Uv=Co(["\n  font-size: ",";\n  line-height: ",";\n  background-color: ",";\n  color: #ffffff;\n  padding: ",";\n  text-transform: uppercase;\n  font-family: ",";\n  margin: 0;\n"])),(function(e){return e.compact?"0.8em":"0.929em"}),(function(e){return e.compact?"18px":"20px"}),(function(e){return e.theme.colors.http[e.type]||"#999999"}),(function(e){return e.compact?"2px 8px":"3px 10px"}),(function(e){return e.theme.typography.headings.fontFamily})),lg=Sa.div(Bv||(Bv=Co(["\n  position: absolute;\n  width: 100%;\n  z-index: 100;\n  background: #fafafa;\n  color: #263238;\n  box-sizing: border-box;\n  box-shadow: 0px 0px 6px rgba(0, 0, 0, 0.33);\n  overflow: hidden;\n  border-bottom-left-radius: 4px;\n  border-bottom-right-radius: 4px;\n  transition: all 0.25s ease;\n  visibility: hidden;\n  ","\n"])),(function(e){return e.expanded?"visibility: visible;":"transform: translateY(-50%) scaleY(0);"})),cg=Sa.div($v||($v=Co(["\n  padding: 10px;\n"]))),ug=Sa.div(qv||(qv=Co(["\n  padding: 5px;\n  border: 1px solid #ccc;\n  background: #fff;\n  word-break: break-all;\n  color: ",";\n  > span {\n    color: ",";\n  }\n"])),(function(e){return e.theme.colors.primary.main}),(function(e){return e.theme.colors.text.primary})),pg=function(e){_o(r,e);var t=Ro(r);function r(e){var o;return nr(this,r),n(ar(o=t.call(this,e)),"toggle",(function(){o.setState({expanded:!o.state.expanded})})),o.state={expanded:!1},o}return or(r,[{key:"render",value:function(){var e=this,t=this.props,n=t.operation,r=t.inverted,o=t.hideHostname,i=this.state.expanded;return s.createElement(Ma.Consumer,null,(function(t){return s.createElement(og,null,s.createElement(ag,{onClick:e.toggle,expanded:i,inverted:r},s.createElement(sg,{type:n.httpVerb,compact:e.props.compact},n.httpVerb),s.createElement(ig,null,n.path),s.createElement(Xc,{float:"right",color:r?"black":"white",size:"20px",direction:i?"up":"down",style:{marginRight:"-25px"}})),s.createElement(lg,{expanded:i,"aria-hidden":!i},n.servers.map((function(e){var r=t.expandDefaultServerVariables?function(e){var t=arguments.length>1&&void 0!==arguments[1]?arguments[1]:{};return e.replace(/(?:{)([\w-.]+)(?:})/g,(function(e,n){return t[n]&&t[n].default||e}))}(e.url,e.variables):e.url,i=function(e){try{return vo(e).pathname}catch(t){return e}}(r);return s.createElement(cg,{key:r},s.createElement(cd,{source:e.description||"",compact:!0}),s.createElement(rg,null,s.createElement(ug,null,s.createElement("span",null,o||t.hideHostname?"/"===i?"":i:r),n.path)))}))))}))}}]),r}(s.Component),fg=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){var e=this.props,t=e.place,n=e.parameters;return n&&n.length?s.createElement("div",{key:t},s.createElement(hc,null,t," Parameters"),s.createElement(au,null,s.createElement("tbody",null,lo(n,(function(e,t){return s.createElement(ov,{key:e.name,isLast:t,field:e,showExamples:!0})}))))):null}}]),n}(s.PureComponent),dg=Qh(Wv=function(e){_o(r,e);var t=Ro(r);function r(){var e;nr(this,r);for(var o=arguments.length,i=new Array(o),a=0;a<o;a++)i[a]=arguments[a];return n(ar(e=t.call.apply(t,[this].concat(i))),"switchMedia",(function(t){var n=t.idx;e.props.content&&e.props.content.activate(n)})),e}return or(r,[{key:"render",value:function(){var e=this,t=this.props.content;if(!t||!t.mediaTypes||!t.mediaTypes.length)return null;var n=t.activeMimeIdx,r=t.mediaTypes.map((function(e,t){return{value:e.name,idx:t}})),o=function(t){var n=t.children;return e.props.withLabel?s.createElement(Wd,null,s.createElement(qd,null,"Content type"),n):n};return s.createElement(s.Fragment,null,s.createElement(o,null,this.props.renderDropdown({value:r[n].value,options:r,onChange:this.switchMedia,ariaLabel:"Content type"})),this.props.children(t.active))}}]),r}(s.Component))||Wv,hg=["path","query","cookie","header"],mg=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"orderParams",value:function(e){var t={};return e.forEach((function(e){var n,r,o;o=e,(n=t)[r=e.in]||(n[r]=[]),n[r].push(o)})),t}},{key:"render",value:function(){var e=this.props,t=e.body,n=e.parameters,r=void 0===n?[]:n;if(void 0===t&&void 0===r)return null;var o=this.orderParams(r),i=r.length>0?hg:[],a=t&&t.content,l=t&&t.description;return s.createElement(s.Fragment,null,i.map((function(e){return s.createElement(fg,{key:e,place:e,parameters:o[e]})})),a&&s.createElement(gg,{content:a,description:l}))}}]),n}(s.PureComponent);function vg(e){return s.createElement(hc,{key:"header"},"Request Body schema: ",s.createElement(Qf,e))}function gg(e){var t=e.content,n=e.description,r=t.isRequestType;return s.createElement(dg,{content:t,renderDropdown:vg},(function(e){var t=e.schema;return s.createElement(s.Fragment,null,void 0!==n&&s.createElement(cd,{source:n}),s.createElement(cv,{skipReadOnly:r,key:"schema",schema:t}))}))}var yg,bg,xg,wg,kg,Eg,Sg,_g,Og,Ag,Rg,Cg,Tg,Pg,Ig,jg,Lg,Ng,Mg,Dg,Fg,zg,Ug,Bg,$g,qg,Wg,Vg,Hg,Yg,Gg,Qg,Xg,Kg,Zg,Jg,ey,ty,ny,ry,oy,iy,ay,sy,ly,cy,uy,py,fy,dy,hy,my=Sa(function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){var e=this.props,t=e.title,n=e.type,r=e.empty,o=e.code,i=e.opened,a=e.className,l=e.onClick;return s.createElement("button",{className:a,onClick:!r&&l||void 0,"aria-expanded":i,disabled:r},!r&&s.createElement(Xc,{size:"1.5em",color:n,direction:i?"down":"right",float:"left"}),s.createElement(yy,null,o," "),s.createElement(cd,{compact:!0,inline:!0,source:t}))}}]),n}(s.PureComponent))(yg||(yg=Co(["\n  display: block;\n  border: 0;\n  width: 100%;\n  text-align: left;\n  padding: 10px;\n  border-radius: 2px;\n  margin-bottom: 4px;\n  line-height: 1.5em;\n  background-color: #f2f2f2;\n  cursor: pointer;\n\n  color: ",";\n  background-color: ",";\n  &:focus {\n    outline: auto;\n    outline-color: ",";\n  }\n  ",";\n"])),(function(e){return e.theme.colors.responses[e.type].color}),(function(e){return e.theme.colors.responses[e.type].backgroundColor}),(function(e){return e.theme.colors.responses[e.type].color}),(function(e){return e.empty?'\ncursor: default;\n&::before {\n  content: "—";\n  font-weight: bold;\n  width: 1.5em;\n  text-align: center;\n  display: inline-block;\n  vertical-align: top;\n}\n&:focus {\n  outline: 0;\n}\n':""})),vy=Sa.div(bg||(bg=Co(["\n  padding: 10px;\n"]))),gy=Sa(hc.withComponent("caption"))(xg||(xg=Co(["\n  text-align: left;\n  margin-top: 1em;\n  caption-side: top;\n"]))),yy=Sa.strong(wg||(wg=Co(["\n  vertical-align: top;\n"]))),by=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){var e=this.props.headers;return void 0===e||0===e.length?null:s.createElement(au,null,s.createElement(gy,null," Response Headers "),s.createElement("tbody",null,lo(e,(function(e,t){return s.createElement(ov,{isLast:t,key:e.name,field:e,showExamples:!0})}))))}}]),n}(s.PureComponent),xy=function(e){_o(r,e);var t=Ro(r);function r(){var e;nr(this,r);for(var o=arguments.length,i=new Array(o),a=0;a<o;a++)i[a]=arguments[a];return n(ar(e=t.call.apply(t,[this].concat(i))),"renderDropdown",(function(e){return s.createElement(hc,{key:"header"},"Response Schema: ",s.createElement(Qf,e))})),e}return or(r,[{key:"render",value:function(){var e=this.props.response,t=e.description,n=e.headers,r=e.content;return s.createElement(s.Fragment,null,t&&s.createElement(cd,{source:t}),s.createElement(by,{headers:n}),s.createElement(dg,{content:r,renderDropdown:this.renderDropdown},(function(e){var t=e.schema;return s.createElement(cv,{skipWriteOnly:!0,key:"schema",schema:t})})))}}]),r}(s.PureComponent),wy=Qh(kg=function(e){_o(r,e);var t=Ro(r);function r(){var e;nr(this,r);for(var o=arguments.length,i=new Array(o),a=0;a<o;a++)i[a]=arguments[a];return n(ar(e=t.call.apply(t,[this].concat(i))),"toggle",(function(){e.props.response.toggle()})),e}return or(r,[{key:"render",value:function(){var e=this.props.response,t=e.headers,n=e.type,r=e.summary,o=e.description,i=e.code,a=e.expanded,l=e.content,c=void 0===l?[]:l.mediaTypes.filter((function(e){return void 0!==e.schema})),u=0===t.length&&0===c.length&&!o;return s.createElement("div",null,s.createElement(my,{onClick:this.toggle,type:n,empty:u,title:r||"",code:i,opened:a}),a&&!u&&s.createElement(vy,null,s.createElement(xy,{response:this.props.response})))}}]),r}(s.Component))||kg,ky=Sa.h3(Eg||(Eg=Co(["\n  font-size: 1.3em;\n  padding: 0.2em 0;\n  margin: 3em 0 1.1em;\n  color: ",";\n  font-weight: normal;\n"])),(function(e){return e.theme.colors.text.primary})),Ey=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){var e=this.props,t=e.responses,n=e.isCallback;return t&&0!==t.length?s.createElement("div",null,s.createElement(ky,null,wo(n?"callbackResponses":"responses")),t.map((function(e){return s.createElement(wy,{key:e.code,response:e})}))):null}}]),n}(s.PureComponent),Sy=Sa.code(Sg||(Sg=Co(["\n  font-size: ",";\n  font-family: ",";\n  border: 1px solid ",";\n  margin: 0 3px;\n  padding: 0.2em;\n  display: inline-block;\n  line-height: 1;\n\n  &:after {\n    content: ',';\n  }\n  &:last-child:after {\n    content: none;\n  }\n"])),(function(e){return e.theme.typography.code.fontSize}),(function(e){return e.theme.typography.code.fontFamily}),(function(e){return e.theme.colors.border.dark})),_y=Sa.span(_g||(_g=Co(["\n  &:after {\n    content: ' AND ';\n    font-weight: bold;\n  }\n\n  &:last-child:after {\n    content: none;\n  }\n\n  ",";\n"])),Jf),Oy=Sa.span(Og||(Og=Co(["\n  &:before {\n    content: '( ';\n    font-weight: bold;\n  }\n  &:after {\n    content: ' ) OR ';\n    font-weight: bold;\n  }\n  &:last-child:after {\n    content: ' )';\n  }\n\n  &:only-child:before,\n  &:only-child:after {\n    content: none;\n  }\n\n  ",";\n"])),Jf),Ay=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){var e=this.props.security;return s.createElement(Oy,null,e.schemes.length?e.schemes.map((function(e){return s.createElement(_y,{key:e.id},s.createElement(Ec,{to:e.sectionId},e.id),e.scopes.length>0&&" (",e.scopes.map((function(e){return s.createElement(Sy,{key:e},e)})),e.scopes.length>0&&") ")})):s.createElement(_y,null,"None"))}}]),n}(s.PureComponent),Ry=Sa.div(Ag||(Ag=Co(["\n  flex: 1 1 auto;\n"]))),Cy=Sa.div(Rg||(Rg=Co(["\n  width: ",";\n  ","\n"])),(function(e){return e.theme.schema.defaultDetailsWidth}),Ea("small")(Cg||(Cg=Co(["\n    margin-top: 10px;\n  "])))),Ty=Sa(hc)(Tg||(Tg=Co(["\n  display: inline-block;\n  margin: 0;\n"]))),Py=Sa.div(Pg||(Pg=Co(["\n  width: 100%;\n  display: flex;\n  margin: 1em 0;\n\n  ","\n"])),Ea("small")(Ig||(Ig=Co(["\n    flex-direction: column;\n  "])))),Iy=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){var e=this.props.securities;return e.length?s.createElement(Py,null,s.createElement(Ry,null,s.createElement(Ty,null,"Authorizations: ")),s.createElement(Cy,null,e.map((function(e,t){return s.createElement(Ay,{key:t,security:e})})))):null}}]),n}(s.PureComponent),jy=Qh(jg=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){var e=this.props.operation,t=e.description,n=e.externalDocs,r=!(!t&&!n);return s.createElement(ng,null,r&&s.createElement(Ly,null,void 0!==t&&s.createElement(cd,{source:t}),n&&s.createElement(dm,{externalDocs:n})),s.createElement(pg,{operation:this.props.operation,inverted:!0,compact:!0}),s.createElement(bm,{extensions:e.extensions}),s.createElement(Iy,{securities:e.security}),s.createElement(mg,{parameters:e.parameters,body:e.requestBody}),s.createElement(Ey,{responses:e.responses,isCallback:e.isCallback}))}}]),n}(s.Component))||jg,Ly=Sa.div(Lg||(Lg=Co(["\n  margin-bottom: ","px;\n"])),(function(e){return 3*e.theme.spacing.unit})),Ny=Qh(Ng=function(e){_o(r,e);var t=Ro(r);function r(){var e;nr(this,r);for(var o=arguments.length,i=new Array(o),a=0;a<o;a++)i[a]=arguments[a];return n(ar(e=t.call.apply(t,[this].concat(i))),"toggle",(function(){e.props.callbackOperation.toggle()})),e}return or(r,[{key:"render",value:function(){var e=this.props.callbackOperation,t=e.name,n=e.expanded,r=e.httpVerb,o=e.deprecated;return s.createElement(s.Fragment,null,s.createElement(tg,{onClick:this.toggle,name:t,opened:n,httpVerb:r,deprecated:o}),n&&s.createElement(jy,{operation:this.props.callbackOperation}))}}]),r}(s.Component))||Ng,My=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){var e=this.props.callbacks;return e&&0!==e.length?s.createElement("div",null,s.createElement(Dy,null," Callbacks "),e.map((function(e){return e.operations.map((function(t,n){return s.createElement(Ny,{key:"".concat(e.name,"_").concat(n),callbackOperation:t})}))}))):null}}]),n}(s.PureComponent),Dy=Sa.h3(Mg||(Mg=Co(["\n  font-size: 1.3em;\n  padding: 0.2em 0;\n  margin: 3em 0 1.1em;\n  color: ",";\n  font-weight: normal;\n"])),(function(e){return e.theme.colors.text.primary})),Fy=Qh(Dg=function(e){_o(r,e);var t=Ro(r);function r(e){var o;return nr(this,r),n(ar(o=t.call(this,e)),"switchItem",(function(e){var t=e.idx;o.props.items&&o.setState({activeItemIdx:t})})),o.state={activeItemIdx:0},o}return or(r,[{key:"render",value:function(){var e=this,t=this.props.items;if(!t||!t.length)return null;var n=function(t){var n=t.children;return e.props.label?s.createElement(Wd,null,s.createElement(qd,null,e.props.label),n):n};return s.createElement(s.Fragment,null,s.createElement(n,null,this.props.renderDropdown({value:this.props.options[this.state.activeItemIdx].value,options:this.props.options,onChange:this.switchItem,ariaLabel:this.props.label||"Callback"})),this.props.children(t[this.state.activeItemIdx]))}}]),r}(s.Component))||Dg,zy=Qh(Fg=function(e){_o(r,e);var t=Ro(r);function r(){var e;nr(this,r);for(var o=arguments.length,i=new Array(o),a=0;a<o;a++)i[a]=arguments[a];return n(ar(e=t.call.apply(t,[this].concat(i))),"renderDropdown",(function(e){return s.createElement(Qf,ir({Label:$d,Dropdown:Vd},e))})),e}return or(r,[{key:"render",value:function(){var e=this,t=this.props.content;return void 0===t?null:s.createElement(dg,{content:t,renderDropdown:this.renderDropdown,withLabel:!0},(function(t){return s.createElement(Yd,{key:"samples",mediaType:t,renderDropdown:e.renderDropdown})}))}}]),r}(s.Component))||Fg,Uy=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){var e=this.props.callback.codeSamples.find((function(e){return Ul(e)}));return e?s.createElement(By,null,s.createElement(zy,{content:e.requestBodyContent})):null}}]),n}(s.Component),By=Sa.div(zg||(zg=Co(["\n  margin-top: 15px;\n"]))),$y=Qh(($g=Bg=function(e){_o(r,e);var t=Ro(r);function r(){var e;nr(this,r);for(var o=arguments.length,i=new Array(o),a=0;a<o;a++)i[a]=arguments[a];return n(ar(e=t.call.apply(t,[this].concat(i))),"context",void 0),n(ar(e),"renderDropdown",(function(e){return s.createElement(Qf,ir({Label:$d,Dropdown:Vd},e))})),e}return or(r,[{key:"render",value:function(){var e=this,t=this.props.callbacks;if(!t||0===t.length)return null;var n=t.map((function(e){return e.operations.map((function(e){return e}))})).reduce((function(e,t){return e.concat(t)}),[]);if(!n.some((function(e){return e.codeSamples.length>0})))return null;var r=n.map((function(e,t){return{value:"".concat(e.httpVerb.toUpperCase(),": ").concat(e.name),idx:t}}));return s.createElement("div",null,s.createElement(dc,null," Callback payload samples "),s.createElement(qy,null,s.createElement(Fy,{items:n,renderDropdown:this.renderDropdown,label:"Callback",options:r},(function(t){return s.createElement(Uy,{key:"callbackPayloadSample",callback:t,renderDropdown:e.renderDropdown})}))))}}]),r}(s.Component),n(Bg,"contextType",Ma),Ug=$g))||Ug,qy=Sa.div(qg||(qg=Co(["\n  background: ",";\n  padding: ","px;\n"])),(function(e){return e.theme.codeBlock.backgroundColor}),(function(e){return 4*e.theme.spacing.unit})),Wy=Qh((Hg=Vg=function(e){_o(r,e);var t=Ro(r);function r(){var e;nr(this,r);for(var o=arguments.length,i=new Array(o),a=0;a<o;a++)i[a]=arguments[a];return n(ar(e=t.call.apply(t,[this].concat(i))),"context",void 0),n(ar(e),"operation",void 0),e}return or(r,[{key:"render",value:function(){var e=this.props.operation.codeSamples,t=e.length>0,n=1===e.length&&this.context.hideSingleRequestSampleTab;return t&&s.createElement("div",null,s.createElement(dc,null," ",wo("requestSamples")," "),s.createElement(Mp,{defaultIndex:0},s.createElement(kp,{hidden:n},e.map((function(e){return s.createElement(Op,{key:e.lang+"_"+(e.label||"")},void 0!==e.label?e.label:e.lang)}))),e.map((function(e){return s.createElement(Pp,{key:e.lang+"_"+(e.label||"")},Ul(e)?s.createElement("div",null,s.createElement(zy,{content:e.requestBodyContent})):s.createElement(Dd,{lang:e.lang,source:e.source}))}))))||null}}]),r}(s.Component),n(Vg,"contextType",Ma),Wg=Hg))||Wg,Vy=Qh(Yg=function(e){_o(r,e);var t=Ro(r);function r(){var e;nr(this,r);for(var o=arguments.length,i=new Array(o),a=0;a<o;a++)i[a]=arguments[a];return n(ar(e=t.call.apply(t,[this].concat(i))),"operation",void 0),e}return or(r,[{key:"render",value:function(){var e=this.props.operation.responses.filter((function(e){return e.content&&e.content.hasSample}));return e.length>0&&s.createElement("div",null,s.createElement(dc,null," ",wo("responseSamples")," "),s.createElement(Mp,{defaultIndex:0},s.createElement(kp,null,e.map((function(e){return s.createElement(Op,{className:"tab-"+e.type,key:e.code},e.code)}))),e.map((function(e){return s.createElement(Pp,{key:e.code},s.createElement("div",null,s.createElement(zy,{content:e.content})))}))))||null}}]),r}(s.Component))||Yg,Hy=Sa.div(Gg||(Gg=Co(["\n  margin-bottom: ","px;\n"])),(function(e){return 6*e.theme.spacing.unit})),Yy=Qh(Qg=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){var e=this.props.operation,t=e.name,n=e.description,r=e.deprecated,o=e.externalDocs,i=e.isWebhook,a=!(!n&&!o);return s.createElement(Ma.Consumer,null,(function(l){return s.createElement(lc,null,s.createElement(oc,null,s.createElement(fc,null,s.createElement(Ac,{to:e.id}),t," ",r&&s.createElement(Kc,{type:"warning"}," Deprecated "),i&&s.createElement(Kc,{type:"primary"}," Webhook ")),l.pathInMiddlePanel&&!i&&s.createElement(pg,{operation:e,inverted:!0}),a&&s.createElement(Hy,null,void 0!==n&&s.createElement(cd,{source:n}),o&&s.createElement(dm,{externalDocs:o})),s.createElement(bm,{extensions:e.extensions}),s.createElement(Iy,{securities:e.security}),s.createElement(mg,{parameters:e.parameters,body:e.requestBody}),s.createElement(Ey,{responses:e.responses}),s.createElement(My,{callbacks:e.callbacks})),s.createElement(sc,null,!l.pathInMiddlePanel&&!i&&s.createElement(pg,{operation:e}),s.createElement(Wy,{operation:e}),s.createElement(Vy,{operation:e}),s.createElement($y,{callbacks:e.callbacks})))}))}}]),n}(s.Component))||Qg,Gy=Qh(Xg=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){var e=this.props.items;return 0===e.length?null:e.map((function(e){return s.createElement(Qy,{key:e.id,item:e})}))}}]),n}(s.Component))||Xg,Qy=Qh(Kg=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){var e,t=this.props.item;switch(t.type){case"group":e=null;break;case"tag":case"section":e=s.createElement(Ky,this.props);break;case"operation":e=s.createElement(Zy,{item:t});break;default:e=s.createElement(Ky,this.props)}return s.createElement(s.Fragment,null,e&&s.createElement(ic,{id:t.id,underlined:"operation"===t.type},e),t.items&&s.createElement(Gy,{items:t.items}))}}]),n}(s.Component))||Kg,Xy=function(e){return s.createElement(oc,{compact:!0},e)},Ky=Qh(Zg=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){var e=this.props.item,t=e.name,n=e.description,r=e.externalDocs,o=2===e.level?fc:pc;return s.createElement(s.Fragment,null,s.createElement(lc,null,s.createElement(oc,{compact:!1},s.createElement(o,null,s.createElement(Ac,{to:this.props.item.id}),t))),s.createElement(Av,{source:n||"",htmlWrap:Xy}),r&&s.createElement(lc,null,s.createElement(oc,null,s.createElement(dm,{externalDocs:r}))))}}]),n}(s.Component))||Zg,Zy=Qh(Jg=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){return s.createElement(Yy,{operation:this.props.item})}}]),n}(s.Component))||Jg,Jy=Qh(ey=function(e){_o(r,e);var t=Ro(r);function r(){var e;nr(this,r);for(var o=arguments.length,i=new Array(o),a=0;a<o;a++)i[a]=arguments[a];return n(ar(e=t.call.apply(t,[this].concat(i))),"ref",s.createRef()),n(ar(e),"activate",(function(t){e.props.onActivate(e.props.item),t.stopPropagation()})),e}return or(r,[{key:"componentDidMount",value:function(){this.scrollIntoViewIfActive()}},{key:"componentDidUpdate",value:function(){this.scrollIntoViewIfActive()}},{key:"scrollIntoViewIfActive",value:function(){this.props.item.active&&this.ref.current&&to(this.ref.current)}},{key:"render",value:function(){var e=this.props,t=e.item,n=e.withoutChildren;return s.createElement(Hv,{onClick:this.activate,depth:t.depth,"data-item-id":t.id},"operation"===t.type?s.createElement(eb,ir({},this.props,{item:t})):s.createElement(Gv,{depth:t.depth,active:t.active,type:t.type,ref:this.ref},s.createElement(Qv,{title:t.sidebarLabel},t.sidebarLabel,this.props.children),t.depth>0&&t.items.length>0&&s.createElement(Xc,{float:"right",direction:t.expanded?"down":"right"})||null),!n&&t.items&&t.items.length>0&&s.createElement(tb,{expanded:t.expanded,items:t.items,onActivate:this.props.onActivate}))}}]),r}(s.Component))||ey,eb=Qh(ty=function(e){_o(r,e);var t=Ro(r);function r(){var e;nr(this,r);for(var o=arguments.length,i=new Array(o),a=0;a<o;a++)i[a]=arguments[a];return n(ar(e=t.call.apply(t,[this].concat(i))),"ref",s.createRef()),e}return or(r,[{key:"componentDidUpdate",value:function(){this.props.item.active&&this.ref.current&&to(this.ref.current)}},{key:"render",value:function(){var e=this.props.item;return s.createElement(Gv,{depth:e.depth,active:e.active,deprecated:e.deprecated,ref:this.ref},e.isWebhook?s.createElement(Tv,{type:"hook"},wo("webhook")):s.createElement(Tv,{type:e.httpVerb},Rs(e.httpVerb)),s.createElement(Qv,{width:"calc(100% - 38px)"},e.sidebarLabel,this.props.children))}}]),r}(s.Component))||ty,tb=Qh(ny=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"render",value:function(){var e=this,t=this.props,n=t.items,r=t.root,o=t.className,i=null==this.props.expanded||this.props.expanded;return s.createElement(Vv,ir({className:o,style:this.props.style,expanded:i},r?{role:"navigation"}:{}),n.map((function(t,n){return s.createElement(Jy,{key:n,item:t,onActivate:e.props.onActivate})})))}}]),n}(s.Component))||ny,nb=Qh((iy=oy=function(e){_o(r,e);var t=Ro(r);function r(){var e;nr(this,r);for(var o=arguments.length,i=new Array(o),a=0;a<o;a++)i[a]=arguments[a];return n(ar(e=t.call.apply(t,[this].concat(i))),"_updateScroll",void 0),n(ar(e),"activate",(function(t){if(t&&t.active&&e.context.menuToggle)return t.expanded?t.collapse():t.expand();e.props.menu.activateAndScroll(t,!0),setTimeout((function(){e._updateScroll&&e._updateScroll()}))})),n(ar(e),"saveScrollUpdate",(function(t){e._updateScroll=t})),e}return or(r,[{key:"render",value:function(){var e=this.props.menu;return s.createElement(_f,{updateFn:this.saveScrollUpdate,className:this.props.className,options:{wheelPropagation:!1}},s.createElement(tb,{items:e.items,onActivate:this.activate,root:!0}),s.createElement(Xv,null,s.createElement("a",{target:"_blank",rel:"noopener noreferrer",href:"https://github.com/Redocly/redoc"},"Documentation Powered by ReDoc")))}}]),r}(s.Component),n(oy,"contextType",Ma),ry=iy))||ry,rb=function(e){var t=e.open?8:-4;return s.createElement(ib,null,s.createElement(ob,{size:15,style:{transform:"translate(2px, ".concat(t,"px) rotate(180deg)"),transition:"transform 0.2s ease"}}),s.createElement(ob,{size:15,style:{transform:"translate(2px, ".concat(0-t,"px)"),transition:"transform 0.2s ease"}}))},ob=function(e){var t=e.size,n=void 0===t?10:t,r=e.className,o=void 0===r?"":r,i=e.style;return s.createElement("svg",{className:o,style:i||{},viewBox:"0 0 926.23699 573.74994",version:"1.1",x:"0px",y:"0px",width:n,height:n},s.createElement("g",{transform:"translate(904.92214,-879.1482)"},s.createElement("path",{d:"\n          m -673.67664,1221.6502 -231.2455,-231.24803 55.6165,\n          -55.627 c 30.5891,-30.59485 56.1806,-55.627 56.8701,-55.627 0.6894,\n          0 79.8637,78.60862 175.9427,174.68583 l 174.6892,174.6858 174.6892,\n          -174.6858 c 96.079,-96.07721 175.253196,-174.68583 175.942696,\n          -174.68583 0.6895,0 26.281,25.03215 56.8701,\n          55.627 l 55.6165,55.627 -231.245496,231.24803 c -127.185,127.1864\n          -231.5279,231.248 -231.873,231.248 -0.3451,0 -104.688,\n          -104.0616 -231.873,-231.248 z\n        ",fill:"currentColor"})))},ib=Sa.div(ay||(ay=Co(["\n  user-select: none;\n  width: 20px;\n  height: 20px;\n  align-self: center;\n  display: flex;\n  flex-direction: column;\n  color: ",";\n"])),(function(e){return e.theme.colors.primary.main}));Jr&&(hy=r(5352));var ab,sb,lb,cb,ub,pb,fb,db,hb,mb,vb,gb,yb=hy&&hy(),bb=Sa.div(sy||(sy=Co(["\n  width: ",";\n  background-color: ",";\n  overflow: hidden;\n  display: flex;\n  flex-direction: column;\n\n  backface-visibility: hidden;\n  /* contain: strict; TODO: breaks layout since Chrome 80*/\n\n  height: 100vh;\n  position: sticky;\n  position: -webkit-sticky;\n  top: 0;\n\n  ",";\n\n  @media print {\n    display: none;\n  }\n"])),(function(e){return e.theme.sidebar.width}),(function(e){return e.theme.sidebar.backgroundColor}),Ea("small")(ly||(ly=Co(["\n    position: fixed;\n    z-index: 20;\n    width: 100%;\n    background: ",";\n    display: ",";\n  "])),(function(e){return e.theme.sidebar.backgroundColor}),(function(e){return e.open?"flex":"none"}))),xb=Sa.div(cy||(cy=Co(["\n  outline: none;\n  user-select: none;\n  background-color: #f2f2f2;\n  color: ",";\n  display: none;\n  cursor: pointer;\n  position: fixed;\n  right: 20px;\n  z-index: 100;\n  border-radius: 50%;\n  box-shadow: 0 0 20px rgba(0, 0, 0, 0.3);\n  ",";\n\n  bottom: 44px;\n\n  width: 60px;\n  height: 60px;\n  padding: 0 20px;\n\n  @media print {\n    display: none;\n  }\n"])),(function(e){return e.theme.colors.primary.main}),Ea("small")(uy||(uy=Co(["\n    display: flex;\n  "])))),wb=Qh((dy=fy=function(e){_o(r,e);var t=Ro(r);function r(){var e;nr(this,r);for(var o=arguments.length,i=new Array(o),a=0;a<o;a++)i[a]=arguments[a];return n(ar(e=t.call.apply(t,[this].concat(i))),"context",void 0),n(ar(e),"state",{offsetTop:"0px"}),n(ar(e),"stickyElement",void 0),n(ar(e),"toggleNavMenu",(function(){e.props.menu.toggleSidebar()})),e}return or(r,[{key:"componentDidMount",value:function(){yb&&yb.add(this.stickyElement),this.setState({offsetTop:this.getScrollYOffset(this.context)})}},{key:"componentWillUnmount",value:function(){yb&&yb.remove(this.stickyElement)}},{key:"getScrollYOffset",value:function(e){return(void 0!==this.props.scrollYOffset?So.normalizeScrollYOffset(this.props.scrollYOffset)():e.scrollYOffset())+"px"}},{key:"render",value:function(){var e=this,t=this.props.menu.sideBarOpened,n=this.state.offsetTop;return s.createElement(s.Fragment,null,s.createElement(bb,{open:t,className:this.props.className,style:{top:n,height:"calc(100vh - ".concat(n,")")},ref:function(t){e.stickyElement=t}},this.props.children),s.createElement(xb,{onClick:this.toggleNavMenu},s.createElement(rb,{open:t})))}}]),r}(s.Component),n(fy,"contextType",Ma),py=dy))||py,kb=Sa.div(ab||(ab=Co(["\n  ",";\n"])),(function(e){var t=e.theme;return"\n  font-family: ".concat(t.typography.fontFamily,";\n  font-size: ").concat(t.typography.fontSize,";\n  font-weight: ").concat(t.typography.fontWeightRegular,";\n  line-height: ").concat(t.typography.lineHeight,";\n  color: ").concat(t.colors.text.primary,";\n  display: flex;\n  position: relative;\n  text-align: left;\n\n  -webkit-font-smoothing: ").concat(t.typography.smoothing,";\n  font-smoothing: ").concat(t.typography.smoothing,";\n  ").concat(t.typography.optimizeSpeed?"text-rendering: optimizeSpeed !important":"",";\n\n  tap-highlight-color: rgba(0, 0, 0, 0);\n  text-size-adjust: 100%;\n\n  * {\n    box-sizing: border-box;\n    -webkit-tap-highlight-color: rgba(255, 255, 255, 0);\n  }\n")})),Eb=Sa.div(sb||(sb=Co(["\n  z-index: 1;\n  position: relative;\n  overflow: hidden;\n  width: calc(100% - ",");\n  ",";\n\n  contain: layout;\n"])),(function(e){return e.theme.sidebar.width}),Ea("small",!0)(lb||(lb=Co(["\n    width: 100%;\n  "])))),Sb=Sa.div(cb||(cb=Co(["\n  background: ",";\n  position: absolute;\n  top: 0;\n  bottom: 0;\n  right: 0;\n  width: ",";\n  ",";\n"])),(function(e){return e.theme.rightPanel.backgroundColor}),(function(e){var t=e.theme;if(t.rightPanel.width.endsWith("%")){var n=parseInt(t.rightPanel.width,10);return"calc((100% - ".concat(t.sidebar.width,") * ").concat(n/100,")")}return t.rightPanel.width}),Ea("medium",!0)(ub||(ub=Co(["\n    display: none;\n  "])))),_b=Sa.div(pb||(pb=Co(["\n  padding: 5px 0;\n"]))),Ob=Sa.input.attrs((function(){return{className:"search-input"}}))(fb||(fb=Co(["\n  width: calc(100% - ","px);\n  box-sizing: border-box;\n  margin: 0 ","px;\n  padding: 5px ","px 5px\n    ","px;\n  border: 0;\n  border-bottom: 1px solid\n    ",";\n  font-family: ",";\n  font-weight: bold;\n  font-size: 13px;\n  color: ",";\n  background-color: transparent;\n  outline: none;\n"])),(function(e){return 8*e.theme.spacing.unit}),(function(e){return 4*e.theme.spacing.unit}),(function(e){return 2*e.theme.spacing.unit}),(function(e){return 4*e.theme.spacing.unit}),(function(e){var t=e.theme;return($r(t.sidebar.backgroundColor)>.5?zr:Wr)(.1,t.sidebar.backgroundColor)}),(function(e){return e.theme.typography.fontFamily}),(function(e){return e.theme.sidebar.textColor})),Ab=Sa((function(e){return s.createElement("svg",{className:e.className,version:"1.1",viewBox:"0 0 1000 1000",x:"0px",xmlns:"http://www.w3.org/2000/svg",y:"0px"},s.createElement("path",{d:"M968.2,849.4L667.3,549c83.9-136.5,66.7-317.4-51.7-435.6C477.1-25,252.5-25,113.9,113.4c-138.5,138.3-138.5,362.6,0,501C219.2,730.1,413.2,743,547.6,666.5l301.9,301.4c43.6,43.6,76.9,14.9,104.2-12.4C981,928.3,1011.8,893,968.2,849.4z M524.5,522c-88.9,88.7-233,88.7-321.8,0c-88.9-88.7-88.9-232.6,0-321.3c88.9-88.7,233-88.7,321.8,0C613.4,289.4,613.4,433.3,524.5,522z"}))})).attrs({className:"search-icon"})(db||(db=Co(["\n  position: absolute;\n  left: ","px;\n  height: 1.8em;\n  width: 0.9em;\n\n  path {\n    fill: ",";\n  }\n"])),(function(e){return 4*e.theme.spacing.unit}),(function(e){return e.theme.sidebar.textColor})),Rb=Sa.div(hb||(hb=Co(["\n  padding: ","px 0;\n  background-color: ","};\n  color: ",";\n  min-height: 150px;\n  max-height: 250px;\n  border-top: ","};\n  border-bottom: ","};\n  margin-top: 10px;\n  line-height: 1.4;\n  font-size: 0.9em;\n  \n  li {\n    background-color: inherit;\n  }\n\n  "," {\n    padding-top: 6px;\n    padding-bottom: 6px;\n\n    &:hover,\n    &.active {\n      background-color: ",";\n    }\n\n    > svg {\n      display: none;\n    }\n  }\n"])),(function(e){return e.theme.spacing.unit}),(function(e){var t=e.theme;return zr(.05,t.sidebar.backgroundColor)}),(function(e){return e.theme.sidebar.textColor}),(function(e){var t=e.theme;return zr(.1,t.sidebar.backgroundColor)}),(function(e){var t=e.theme;return zr(.1,t.sidebar.backgroundColor)}),Gv,(function(e){var t=e.theme;return zr(.1,t.sidebar.backgroundColor)})),Cb=Sa.i(mb||(mb=Co(["\n  position: absolute;\n  display: inline-block;\n  width: ","px;\n  text-align: center;\n  right: ","px;\n  line-height: 2em;\n  vertical-align: middle;\n  margin-right: 2px;\n  cursor: pointer;\n  font-style: normal;\n  color: '#666';\n"])),(function(e){return 2*e.theme.spacing.unit}),(function(e){return 4*e.theme.spacing.unit})),Tb=(vb=(0,Xa.debounce)(400),Qa((gb=function(e){_o(r,e);var t=Ro(r);function r(e){var o;return nr(this,r),n(ar(o=t.call(this,e)),"activeItemRef",null),n(ar(o),"clear",(function(){o.setState({results:[],noResults:!1,term:"",activeItemIdx:-1}),o.props.marker.unmark()})),n(ar(o),"handleKeyDown",(function(e){if(27===e.keyCode&&o.clear(),40===e.keyCode&&(o.setState({activeItemIdx:Math.min(o.state.activeItemIdx+1,o.state.results.length-1)}),e.preventDefault()),38===e.keyCode&&(o.setState({activeItemIdx:Math.max(0,o.state.activeItemIdx-1)}),e.preventDefault()),13===e.keyCode){var t=o.state.results[o.state.activeItemIdx];if(t){var n=o.props.getItemById(t.meta);n&&o.props.onActivate(n)}}})),n(ar(o),"search",(function(e){var t=e.target.value;t.length<3?o.clearResults(t):o.setState({term:t},(function(){return o.searchCallback(o.state.term)}))})),o.state={results:[],noResults:!1,term:"",activeItemIdx:-1},o}return or(r,[{key:"clearResults",value:function(e){this.setState({results:[],noResults:!1,term:e}),this.props.marker.unmark()}},{key:"setResults",value:function(e,t){this.setState({results:e,noResults:0===e.length}),this.props.marker.mark(t)}},{key:"searchCallback",value:function(e){var t=this;this.props.search.search(e).then((function(n){t.setResults(n,e)}))}},{key:"render",value:function(){var e=this,t=this.state.activeItemIdx,n=this.state.results.map((function(t){return{item:e.props.getItemById(t.meta),score:t.score}}));return n.sort((function(e,t){return t.score-e.score})),s.createElement(_b,{role:"search"},this.state.term&&s.createElement(Cb,{onClick:this.clear},"×"),s.createElement(Ab,null),s.createElement(Ob,{value:this.state.term,onKeyDown:this.handleKeyDown,placeholder:"Search...","aria-label":"Search",type:"text",onChange:this.search}),n.length>0&&s.createElement(_f,{options:{wheelPropagation:!1}},s.createElement(Rb,{"data-role":"search:results"},n.map((function(n,r){return s.createElement(Jy,{item:Object.create(n.item,{active:{value:r===t}}),onActivate:e.props.onActivate,withoutChildren:!0,key:n.item.id,"data-role":"search:result"})})))),this.state.term&&this.state.noResults?s.createElement(Rb,{"data-role":"search:results"},wo("noResultsFound")):null)}}]),r}(s.PureComponent)).prototype,"searchCallback",[Xa.bind,vb],Object.getOwnPropertyDescriptor(gb.prototype,"searchCallback"),gb.prototype),gb),Pb=function(e){_o(n,e);var t=Ro(n);function n(){return nr(this,n),t.apply(this,arguments)}return or(n,[{key:"componentDidMount",value:function(){this.props.store.onDidMount()}},{key:"componentWillUnmount",value:function(){this.props.store.dispose()}},{key:"render",value:function(){var e=this.props.store,t=e.spec,n=e.menu,r=e.options,o=e.search,i=e.marker,a=this.props.store;return s.createElement(ka,{theme:r.theme},s.createElement(bc,{value:a},s.createElement(Da,{value:r},s.createElement(kb,{className:"redoc-wrap"},s.createElement(wb,{menu:n,className:"menu-content"},s.createElement(Ov,{info:t.info}),!r.disableSearch&&s.createElement(Tb,{search:o,marker:i,getItemById:n.getItemById,onActivate:n.activateAndScroll})||null,s.createElement(nb,{menu:n})),s.createElement(Eb,{className:"api-content"},s.createElement(kv,{store:a}),s.createElement(Gy,{items:n.items})),s.createElement(Sb,null)))))}}]),n}(s.Component);n(Pb,"propTypes",{store:Na.instanceOf(mv).isRequired});var Ib=function(e){var t=e.spec,n=e.specUrl,r=e.options,o=void 0===r?{}:r,i=e.onLoaded,a=ko(o.hideLoading,!1),l=new So(o);return s.createElement(Ta,null,s.createElement(wc,{spec:t,specUrl:n,options:o,onLoaded:i},(function(e){var t=e.loading,n=e.store;return t?a?null:s.createElement(La,{color:l.theme.colors.primary.main}):s.createElement(Pb,{store:n})})))};Ft({useProxies:"ifavailable"});var jb="2.0.0-rc.59",Lb="9f564d3";function Nb(e){var t=function(e){for(var t={},n=e.attributes,r=0;r<n.length;r++){var o=n[r];t[o.name]=o.value}return t}(e),n={};for(var r in t){var o=r.replace(/-(.)/g,(function(e,t){return t.toUpperCase()})),i=t[r];n[o]="theme"===r?JSON.parse(i):i}return n}function Mb(e){var t,n,r=arguments.length>1&&void 0!==arguments[1]?arguments[1]:{},o=arguments.length>2&&void 0!==arguments[2]?arguments[2]:eo("redoc"),i=arguments.length>3?arguments[3]:void 0;if(null===o)throw new Error('"element" argument is not provided and <redoc> tag is not found on the page');"string"==typeof e?t=e:"object"==typeof e&&(n=e),(0,l.render)(s.createElement(Ib,{spec:n,onLoaded:i,specUrl:t,options:a(a({},r),Nb(o))},["Loading..."]),o)}function Db(e){var t=arguments.length>1&&void 0!==arguments[1]?arguments[1]:eo("redoc"),n=arguments.length>2?arguments[2]:void 0,r=mv.fromJS(e);setTimeout((function(){(0,l.hydrate)(s.createElement(Pb,{store:r}),t,n)}),0)}!function(){var e=eo("redoc");if(e){var t=e.getAttribute("spec-url");t&&Mb(t,{},e)}}()}(),o}()}));
+//# sourceMappingURL=redoc.standalone.js.map
\ No newline at end of file
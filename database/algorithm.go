@@ -1,23 +1,48 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"slices"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 
+	"clawmark/blocks"
+	"clawmark/hashtags"
+	"clawmark/metrics"
+	"clawmark/posts"
 	"clawmark/state"
+	"clawmark/tracing"
 	"clawmark/types"
 )
 
-func GetUserFeed(userID uuid.UUID, limit int) ([]uuid.UUID, []types.Post, error) {
-	personalized, err := getPersonalizedFeed(userID, limit/2)
-	if err != nil {
+// Builds a feed for the user, ctx should be the request context so abandoned requests stop querying
+func GetUserFeed(ctx context.Context, userID uuid.UUID, limit int) ([]uuid.UUID, []types.Post, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "feed.GetUserFeed")
+	defer span.End()
+
+	start := time.Now()
+	defer func() {
+		metrics.FeedDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	personalizedLimit := limit * state.CurrentConfig().Feed.PersonalizedPercent / 100
+
+	personalized, err := getPersonalizedFeed(ctx, userID, personalizedLimit)
+	switch {
+	case err != nil:
 		log.Println("Error fetching personalized feed:", err)
+		metrics.FeedPersonalization.WithLabelValues("error").Inc()
+	case len(personalized) == 0:
+		metrics.FeedPersonalization.WithLabelValues("miss").Inc()
+	default:
+		metrics.FeedPersonalization.WithLabelValues("hit").Inc()
 	}
 
-	randomPosts, err := getRandomPosts(limit / 2)
+	randomPosts, err := getRandomPosts(ctx, userID, limit-personalizedLimit)
 	if err != nil {
 		log.Println("Error fetching random posts:", err)
 	}
@@ -36,7 +61,12 @@ func GetUserFeed(userID uuid.UUID, limit int) ([]uuid.UUID, []types.Post, error)
 		}
 	}
 
-	fullPosts, err := getPostsByID(finalFeed)
+	// Both lookups fail once the request is gone, don't hand back an empty feed as if it were real
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	fullPosts, err := getPostsByID(ctx, finalFeed)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -44,23 +74,39 @@ func GetUserFeed(userID uuid.UUID, limit int) ([]uuid.UUID, []types.Post, error)
 	return finalFeed, fullPosts, nil
 }
 
-func getPersonalizedFeed(userID uuid.UUID, limit int) ([]uuid.UUID, error) {
+func getPersonalizedFeed(ctx context.Context, userID uuid.UUID, limit int) ([]uuid.UUID, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "feed.personalized")
+	defer span.End()
+
 	var tags []string
-	err := state.Redis.ZRevRange(state.Context, fmt.Sprintf("user:%s:tag_scores", userID), 0, 4).ScanSlice(&tags)
+	err := state.Redis.ZRevRange(ctx, fmt.Sprintf("user:%s:tag_scores", userID), 0, 4).ScanSlice(&tags)
 	if err != nil {
 		return nil, err
 	}
 
-	var posts []types.Post
-	err = state.Pool.Where("tags && ?", tags).Order("created_at DESC").Limit(limit).Find(&posts).Error
+	// Followed tags are candidates even if the user has not interacted with them yet
+	followed, err := hashtags.Followed(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tags = append(tags, followed...)
+
+	var candidates []types.Post
+	err = state.Pool.WithContext(ctx).Scopes(posts.Listed(userID), blocks.ExcludeHidden(userID, "posts.user_id")).Where("tags && ARRAY[?]::text[]", tags).Order("created_at DESC").Limit(limit).Find(&candidates).Error
 	if err != nil {
 		return nil, err
 	}
 
 	var postIDs []uuid.UUID
-	for _, post := range posts {
-		score := computePersonalizedScore(userID, post.ID, post.Tags)
-		state.Redis.ZAdd(state.Context, fmt.Sprintf("user:%s:feed", userID), redis.Z{
+	for _, post := range candidates {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		score := computePersonalizedScore(ctx, userID, post.ID, post.Tags, followed)
+		metrics.FeedCandidatesScored.Inc()
+		state.Redis.ZAdd(ctx, fmt.Sprintf("user:%s:feed", userID), redis.Z{
 			Score:  score,
 			Member: post.ID,
 		})
@@ -70,28 +116,28 @@ func getPersonalizedFeed(userID uuid.UUID, limit int) ([]uuid.UUID, error) {
 	return postIDs, nil
 }
 
-func getRandomPosts(limit int) ([]uuid.UUID, error) {
-	var posts []types.Post
-	err := state.Pool.Order("RANDOM()").Limit(limit).Find(&posts).Error
+func getRandomPosts(ctx context.Context, userID uuid.UUID, limit int) ([]uuid.UUID, error) {
+	var candidates []types.Post
+	err := state.Pool.WithContext(ctx).Scopes(posts.Listed(userID), blocks.ExcludeHidden(userID, "posts.user_id")).Order("RANDOM()").Limit(limit).Find(&candidates).Error
 	if err != nil {
 		return nil, err
 	}
 
 	var postIDs []uuid.UUID
-	for _, post := range posts {
+	for _, post := range candidates {
 		postIDs = append(postIDs, post.ID)
 	}
 
 	return postIDs, nil
 }
 
-func getPostsByID(postIDs []uuid.UUID) ([]types.Post, error) {
+func getPostsByID(ctx context.Context, postIDs []uuid.UUID) ([]types.Post, error) {
 	if len(postIDs) == 0 {
 		return []types.Post{}, nil
 	}
 
 	var posts []types.Post
-	err := state.Pool.Where("id IN ?", postIDs).Find(&posts).Error
+	err := state.Pool.WithContext(ctx).Where("id IN ?", postIDs).Find(&posts).Error
 	if err != nil {
 		return nil, err
 	}
@@ -99,9 +145,14 @@ func getPostsByID(postIDs []uuid.UUID) ([]types.Post, error) {
 	return posts, nil
 }
 
-func computePersonalizedScore(userID uuid.UUID, postID uuid.UUID, tags []string) float64 {
+func computePersonalizedScore(ctx context.Context, userID uuid.UUID, postID uuid.UUID, tags []string, followed []string) float64 {
+	ctx, span := tracing.Tracer().Start(ctx, "feed.score")
+	defer span.End()
+
+	weights := state.CurrentConfig().Feed
+
 	var interactions []types.Like
-	err := state.Pool.Where("user_id = ?", userID).Find(&interactions).Error
+	err := state.Pool.WithContext(ctx).Where("user_id = ?", userID).Find(&interactions).Error
 	if err != nil {
 		log.Println("Error fetching interactions:", err)
 		return 0
@@ -110,22 +161,27 @@ func computePersonalizedScore(userID uuid.UUID, postID uuid.UUID, tags []string)
 	interactionBoost := 0.0
 	for _, interaction := range interactions {
 		if interaction.PostID == postID {
-			interactionBoost = 5.0
+			interactionBoost = float64(weights.InteractionBoost)
 			break
 		}
 	}
 
 	tagMatchScore := 0.0
+	followedBoost := 0.0
 	for _, tag := range tags {
-		count, _ := state.Redis.ZScore(state.Context, fmt.Sprintf("user:%s:tag_scores", userID), tag).Result()
+		count, _ := state.Redis.ZScore(ctx, fmt.Sprintf("user:%s:tag_scores", userID), tag).Result()
 		tagMatchScore += count
+
+		if slices.Contains(followed, tag) {
+			followedBoost += float64(weights.FollowedTagWeight)
+		}
 	}
 
-	return tagMatchScore + interactionBoost
+	return tagMatchScore*float64(weights.TagWeight) + followedBoost + interactionBoost
 }
 
-func notifyUser(userID uuid.UUID) {
-	feed, fullPosts, err := GetUserFeed(userID, 10)
+func notifyUser(ctx context.Context, userID uuid.UUID) {
+	feed, fullPosts, err := GetUserFeed(ctx, userID, 10)
 	if err != nil {
 		log.Println("Error notifying user:", err)
 		return
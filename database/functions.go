@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"time"
@@ -14,8 +15,9 @@ func main() {
 		log.Fatal("Invalid UUID:", err)
 	}
 	limit := 10
+	ctx := context.Background()
 
-	feed, fullPosts, err := GetUserFeed(userID, limit)
+	feed, fullPosts, err := GetUserFeed(ctx, userID, limit)
 	if err != nil {
 		log.Fatal("Failed to fetch feed:", err)
 	}
@@ -27,7 +29,7 @@ func main() {
 	go func() {
 		for {
 			time.Sleep(10 * time.Second)
-			notifyUser(userID)
+			notifyUser(ctx, userID)
 		}
 	}()
 }
@@ -12,10 +12,14 @@ import (
 )
 
 type SetupData struct {
-	URL             string
-	ErrorStruct     any
-	Info            Info
-	errorStructName string
+	Servers []Server
+	// Body of every error response
+	ErrorStruct any
+	// Optional, documented as the application/problem+json alternative to ErrorStruct
+	ProblemStruct     any
+	Info              Info
+	errorStructName   string
+	problemStructName string
 }
 
 var (
@@ -28,6 +32,10 @@ func Setup() {
 		panic("DocsSetupData is nil")
 	}
 
+	if len(DocsSetupData.Servers) == 0 {
+		panic("DocsSetupData has no servers")
+	}
+
 	var err error
 
 	badRequestSchema, err = openapi3gen.NewSchemaRefForValue(DocsSetupData.ErrorStruct, nil, SchemaInject(DocsSetupData.ErrorStruct))
@@ -54,22 +62,42 @@ func Setup() {
 		panic(err)
 	}
 
+	IntSchema, err = openapi3gen.NewSchemaRefForValue(0, nil)
+
+	if err != nil {
+		panic(err)
+	}
+
 	api.Components.Schemas[DocsSetupData.errorStructName] = badRequestSchema
 
+	if DocsSetupData.ProblemStruct != nil {
+		problemSchema, err := openapi3gen.NewSchemaRefForValue(DocsSetupData.ProblemStruct, nil, SchemaInject(DocsSetupData.ProblemStruct))
+
+		if err != nil {
+			panic(err)
+		}
+
+		DocsSetupData.problemStructName = strings.ReplaceAll(reflect.TypeOf(DocsSetupData.ProblemStruct).String(), "docs.", "")
+		api.Components.Schemas[DocsSetupData.problemStructName] = problemSchema
+	}
+
 	api.Info = DocsSetupData.Info
-	api.Servers[0].URL = DocsSetupData.URL
+	api.Servers = []Server{}
+
+	for _, server := range DocsSetupData.Servers {
+		if server.Variables == nil {
+			server.Variables = map[string]any{}
+		}
+
+		api.Servers = append(api.Servers, server)
+	}
+
 	api.Paths = orderedmap.New[string, Path]()
 	api.Webhooks = orderedmap.New[string, Path]()
 }
 
 var api = Openapi{
 	OpenAPI: "3.1.0",
-	Servers: []Server{
-		{
-			Description: "Luvix Social API",
-			Variables:   map[string]any{},
-		},
-	},
 	Components: Component{
 		Schemas:       make(map[string]any),
 		Security:      make(map[string]Security),
@@ -81,6 +109,17 @@ var badRequestSchema *openapi3.SchemaRef
 
 var IdSchema *openapi3.SchemaRef
 var BoolSchema *openapi3.SchemaRef
+var IntSchema *openapi3.SchemaRef
+
+// Returns the schema of a string which must be one of values
+func EnumSchema(values ...string) *openapi3.SchemaRef {
+	enum := make([]any, 0, len(values))
+	for _, v := range values {
+		enum = append(enum, v)
+	}
+
+	return openapi3.NewSchemaRef("", &openapi3.Schema{Type: &stringType, Enum: enum})
+}
 
 func AddTag(name, description string) {
 	api.Tags = append(api.Tags, Tag{
@@ -171,6 +210,10 @@ func SchemaInject(s any) openapi3gen.Option {
 			}
 		}
 
+		if ft.Kind() == reflect.Struct && len(schema.Properties) > 0 {
+			tsRequired[schema] = requiredJSONFields(ft)
+		}
+
 		switch ft.Name() {
 		case "Text":
 			schema.Type = &stringType
@@ -325,16 +368,7 @@ func Route(doc *Doc) {
 					},
 				},
 			},
-			"400": {
-				Description: "Bad Request",
-				Content: map[string]SchemaResp{
-					"application/json": {
-						Schema: Schema{
-							Ref: "#/components/schemas/" + DocsSetupData.errorStructName,
-						},
-					},
-				},
-			},
+			"default": errorResponse(),
 		},
 	}
 
@@ -378,6 +412,30 @@ func Route(doc *Doc) {
 	api.Paths.Set(doc.Pattern, op)
 }
 
+// All errors share one schema, the status and code fields say what went wrong
+func errorResponse() Response {
+	content := map[string]SchemaResp{
+		"application/json": {
+			Schema: Schema{
+				Ref: "#/components/schemas/" + DocsSetupData.errorStructName,
+			},
+		},
+	}
+
+	if DocsSetupData.problemStructName != "" {
+		content["application/problem+json"] = SchemaResp{
+			Schema: Schema{
+				Ref: "#/components/schemas/" + DocsSetupData.problemStructName,
+			},
+		}
+	}
+
+	return Response{
+		Description: "Error",
+		Content:     content,
+	}
+}
+
 func AddWebhook(wdoc *WebhookDoc) {
 	schemaRef, err := openapi3gen.NewSchemaRefForValue(wdoc.Format, nil, SchemaInject(wdoc.Format))
 
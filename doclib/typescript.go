@@ -0,0 +1,649 @@
+package doclib
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Generated typescript output for the current openapi schema
+type TypescriptOutput struct {
+	// Declaration file containing every schema, request body and webhook payload
+	Types string
+
+	// Fetch-based client, imports its types from ./types
+	Client string
+}
+
+// Scalar aliases emitted at the top of the declaration file, keyed by openapi format
+var tsFormatAliases = []struct {
+	Format string
+	Alias  string
+	Doc    string
+}{
+	{Format: "date-time", Alias: "DateTime", Doc: "RFC 3339 timestamp"},
+	{Format: "date", Alias: "DateString", Doc: "RFC 3339 full-date (YYYY-MM-DD)"},
+	{Format: "uuid", Alias: "UUID", Doc: "RFC 4122 UUID"},
+}
+
+type tsGen struct {
+	// Maps a schema key (e.g. types.Response) to its typescript name
+	names map[string]string
+	// Typescript names already in use, used to avoid collisions
+	used map[string]string
+	decl strings.Builder
+}
+
+// Converts a schema key such as types.Response or POST_types.CreatePost into a typescript identifier
+func tsName(key string) string {
+	key = stripMethod(key)
+
+	// Generics (e.g. types.Paged[clawmark/types.Post]) keep the base name and the last type argument
+	var suffix string
+	if i := strings.Index(key, "["); i != -1 {
+		suffix = key[i+1 : len(key)-1]
+		key = key[:i]
+
+		if j := strings.LastIndexAny(suffix, "./"); j != -1 {
+			suffix = suffix[j+1:]
+		}
+	}
+
+	if j := strings.LastIndex(key, "."); j != -1 {
+		key = key[j+1:]
+	}
+
+	return tsIdent(key + "_" + suffix)
+}
+
+// Turns an arbitrary string into a PascalCase typescript identifier
+func tsIdent(s string) string {
+	var sb strings.Builder
+	upper := true
+
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+
+		if upper {
+			sb.WriteRune(unicode.ToUpper(r))
+			upper = false
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+
+	out := sb.String()
+
+	if out == "" || unicode.IsDigit(rune(out[0])) {
+		out = "T" + out
+	}
+
+	return out
+}
+
+// Turns an operation id such as get_user_feed into a camelCase method name
+func tsMethodName(opId string) string {
+	ident := tsIdent(opId)
+	return strings.ToLower(ident[:1]) + ident[1:]
+}
+
+// Quotes a property name if it is not a valid identifier
+func tsPropName(name string) string {
+	for i, r := range name {
+		if !(unicode.IsLetter(r) || r == '_' || r == '$' || (i > 0 && unicode.IsDigit(r))) {
+			b, _ := json.Marshal(name)
+			return string(b)
+		}
+	}
+
+	if name == "" {
+		return `""`
+	}
+
+	return name
+}
+
+func tsDocComment(sb *strings.Builder, indent string, text string) {
+	if text == "" {
+		return
+	}
+
+	text = strings.ReplaceAll(text, "*/", "*\\/")
+	lines := strings.Split(text, "\n")
+
+	if len(lines) == 1 {
+		sb.WriteString(indent + "/** " + lines[0] + " */\n")
+		return
+	}
+
+	sb.WriteString(indent + "/**\n")
+	for _, line := range lines {
+		sb.WriteString(strings.TrimRight(indent+" * "+line, " ") + "\n")
+	}
+	sb.WriteString(indent + " */\n")
+}
+
+// Strips the METHOD_ prefix used when keying request bodies
+func stripMethod(key string) string {
+	for _, m := range []string{"HEAD_", "GET_", "POST_", "PUT_", "PATCH_", "DELETE_"} {
+		key = strings.TrimPrefix(key, m)
+	}
+
+	return key
+}
+
+// Register reserves a typescript name for a schema key, returning the name to use
+// and whether the declaration still needs to be emitted
+func (g *tsGen) register(key string) (string, bool) {
+	if name, ok := g.names[key]; ok {
+		return name, false
+	}
+
+	name := tsName(key)
+
+	if owner, ok := g.used[name]; ok {
+		// The same go type can be used as both a request body and a response
+		if stripMethod(owner) == stripMethod(key) {
+			g.names[key] = name
+			return name, false
+		}
+
+		name = tsIdent(stripMethod(key))
+	}
+
+	g.names[key] = name
+	g.used[name] = key
+	return name, true
+}
+
+func schemaOf(v any) *openapi3.Schema {
+	switch s := v.(type) {
+	case *openapi3.SchemaRef:
+		if s == nil {
+			return nil
+		}
+		return s.Value
+	case *openapi3.Schema:
+		return s
+	case openapi3.Schema:
+		return &s
+	}
+
+	return nil
+}
+
+// Returns the typescript type expression for a schema
+func (g *tsGen) typeOf(s *openapi3.Schema, indent string) string {
+	if s == nil {
+		return "unknown"
+	}
+
+	var typ string
+
+	switch {
+	case len(s.Enum) > 0:
+		vals := make([]string, 0, len(s.Enum))
+		for _, v := range s.Enum {
+			b, err := json.Marshal(v)
+			if err != nil {
+				panic(err)
+			}
+			vals = append(vals, string(b))
+		}
+		typ = strings.Join(vals, " | ")
+	case s.Type.Is("string"):
+		typ = "string"
+		for _, a := range tsFormatAliases {
+			if a.Format == s.Format {
+				typ = a.Alias
+			}
+		}
+	case s.Type.Is("integer"), s.Type.Is("number"):
+		typ = "number"
+	case s.Type.Is("boolean"):
+		typ = "boolean"
+	case s.Type.Is("null"):
+		typ = "null"
+	case s.Type.Is("array"):
+		var items *openapi3.Schema
+		if s.Items != nil {
+			items = s.Items.Value
+		}
+		typ = "Array<" + g.typeOf(items, indent) + ">"
+	case len(s.Properties) > 0:
+		typ = g.objectOf(s, indent)
+	case s.Type.Is("object"):
+		if s.AdditionalProperties.Schema != nil {
+			typ = "Record<string, " + g.typeOf(s.AdditionalProperties.Schema.Value, indent) + ">"
+		} else {
+			typ = "Record<string, unknown>"
+		}
+	default:
+		typ = "unknown"
+	}
+
+	if s.Nullable && typ != "unknown" && typ != "null" {
+		typ += " | null"
+	}
+
+	return typ
+}
+
+func (g *tsGen) objectOf(s *openapi3.Schema, indent string) string {
+	keys := make([]string, 0, len(s.Properties))
+	for k := range s.Properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString("{\n")
+
+	inner := indent + "  "
+	for _, k := range keys {
+		prop := s.Properties[k].Value
+
+		if prop != nil {
+			tsDocComment(&sb, inner, prop.Description)
+		}
+
+		opt := "?"
+		for _, r := range tsRequired[s] {
+			if r == k {
+				opt = ""
+			}
+		}
+
+		sb.WriteString(inner + tsPropName(k) + opt + ": " + g.typeOf(prop, inner) + ";\n")
+	}
+
+	sb.WriteString(indent + "}")
+	return sb.String()
+}
+
+func (g *tsGen) declare(key string, s *openapi3.Schema) string {
+	name, emit := g.register(key)
+
+	if !emit {
+		return name
+	}
+
+	if s != nil {
+		tsDocComment(&g.decl, "", s.Description)
+	}
+
+	if s != nil && len(s.Enum) == 0 && len(s.Properties) > 0 {
+		g.decl.WriteString("export interface " + name + " " + g.objectOf(s, "") + "\n\n")
+	} else {
+		g.decl.WriteString("export type " + name + " = " + g.typeOf(s, "") + ";\n\n")
+	}
+
+	return name
+}
+
+func refName(ref, prefix string) string {
+	if !strings.HasPrefix(ref, prefix) {
+		return ""
+	}
+
+	return strings.TrimPrefix(ref, prefix)
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func requestBodyType(g *tsGen, ref *Schema) string {
+	if ref == nil {
+		return ""
+	}
+
+	key := refName(ref.Ref, "#/components/requestBodies/")
+	body, ok := api.Components.RequestBodies[key]
+
+	if !ok {
+		return "unknown"
+	}
+
+	// Bodies which are not JSON are not described
+	content, ok := body.Content["application/json"]
+	schema := schemaOf(content.Schema)
+
+	if !ok || schema == nil {
+		return "unknown"
+	}
+
+	return g.declare(key, schema)
+}
+
+type tsOperation struct {
+	method string
+	path   string
+	op     *Operation
+}
+
+// Generates typescript declarations and a fetch client from the current openapi schema
+//
+// This must be called after all routes and webhooks have been loaded
+func GenerateTypescript() (*TypescriptOutput, error) {
+	if api.Paths == nil {
+		return nil, fmt.Errorf("doclib has not been setup yet")
+	}
+
+	g := &tsGen{
+		names: map[string]string{},
+		used:  map[string]string{},
+	}
+
+	for _, a := range tsFormatAliases {
+		tsDocComment(&g.decl, "", a.Doc)
+		g.decl.WriteString("export type " + a.Alias + " = string;\n\n")
+	}
+
+	for _, key := range sortedKeys(api.Components.Schemas) {
+		g.declare(key, schemaOf(api.Components.Schemas[key]))
+	}
+
+	// Operations, in path order
+	var ops []tsOperation
+	for pair := api.Paths.Oldest(); pair != nil; pair = pair.Next() {
+		p := pair.Value
+		for _, m := range []struct {
+			method string
+			op     *Operation
+		}{
+			{"HEAD", p.Head}, {"GET", p.Get}, {"POST", p.Post},
+			{"PUT", p.Put}, {"PATCH", p.Patch}, {"DELETE", p.Delete},
+		} {
+			if m.op != nil {
+				ops = append(ops, tsOperation{method: m.method, path: pair.Key, op: m.op})
+			}
+		}
+	}
+
+	var client strings.Builder
+
+	client.WriteString(tsHeader)
+	client.WriteString("import type * as T from \"./types\";\n\n")
+	client.WriteString(tsClientPrelude)
+	client.WriteString("export function createClient(options: ClientOptions) {\n")
+	client.WriteString("  const request = makeRequest(options);\n\n")
+	client.WriteString("  return {\n")
+
+	for i, o := range ops {
+		if i > 0 {
+			client.WriteString("\n")
+		}
+
+		if err := g.writeOperation(&client, o); err != nil {
+			return nil, err
+		}
+	}
+
+	client.WriteString("  };\n}\n\n")
+	client.WriteString("export type Client = ReturnType<typeof createClient>;\n")
+
+	// Webhook payloads
+	if api.Webhooks != nil && api.Webhooks.Len() > 0 {
+		var payloads strings.Builder
+		payloads.WriteString("/** Maps each webhook name to the payload that is POSTed to the receiver */\n")
+		payloads.WriteString("export interface WebhookPayloads {\n")
+
+		for pair := api.Webhooks.Oldest(); pair != nil; pair = pair.Next() {
+			if pair.Value.Post == nil {
+				continue
+			}
+
+			typ := requestBodyType(g, pair.Value.Post.RequestBody)
+			tsDocComment(&payloads, "  ", pair.Value.Post.Summary)
+			payloads.WriteString("  " + tsPropName(pair.Key) + ": " + typ + ";\n")
+		}
+
+		payloads.WriteString("}\n\n")
+		payloads.WriteString("export type WebhookName = keyof WebhookPayloads;\n")
+
+		g.decl.WriteString(payloads.String())
+	}
+
+	return &TypescriptOutput{
+		Types:  tsHeader + strings.TrimRight(g.decl.String(), "\n") + "\n",
+		Client: client.String(),
+	}, nil
+}
+
+func (g *tsGen) writeOperation(sb *strings.Builder, o tsOperation) error {
+	var pathParams, queryParams, headerParams []Parameter
+
+	for _, p := range o.op.Parameters {
+		switch p.In {
+		case "path":
+			pathParams = append(pathParams, p)
+		case "query":
+			queryParams = append(queryParams, p)
+		case "header":
+			headerParams = append(headerParams, p)
+		}
+	}
+
+	respType := "unknown"
+	if content, ok := o.op.Responses["200"].Content["application/json"]; ok {
+		key := refName(content.Schema.Ref, "#/components/schemas/")
+
+		if schema := schemaOf(api.Components.Schemas[key]); key != "" && schema != nil {
+			respType = g.declare(key, schema)
+		}
+	}
+
+	bodyType := requestBodyType(g, o.op.RequestBody)
+
+	var args []string
+	var paramsType strings.Builder
+
+	if len(pathParams)+len(queryParams)+len(headerParams) > 0 {
+		required := false
+		paramsType.WriteString("{ ")
+		for _, p := range append(append(pathParams, queryParams...), headerParams...) {
+			opt := "?"
+			if p.Required || p.In == "path" {
+				opt = ""
+				required = true
+			}
+			paramsType.WriteString(tsPropName(p.Name) + opt + ": " + g.typeOf(schemaOf(p.Schema), "    ") + "; ")
+		}
+		paramsType.WriteString("}")
+
+		if required {
+			args = append(args, "params: "+paramsType.String())
+		} else {
+			args = append(args, "params: "+paramsType.String()+" = {}")
+		}
+	}
+
+	if bodyType != "" {
+		args = append(args, "body: T."+bodyType)
+	}
+
+	args = append(args, "init?: RequestInit")
+
+	doc := o.op.Summary
+	if o.op.Description != "" {
+		if doc != "" {
+			doc += "\n\n"
+		}
+		doc += o.op.Description
+	}
+	doc += "\n\n" + "`" + o.method + " " + o.path + "`"
+	tsDocComment(sb, "    ", doc)
+
+	ret := "unknown"
+	if respType != "unknown" {
+		ret = "T." + respType
+	}
+
+	sb.WriteString("    " + tsMethodName(o.op.ID) + ": (" + strings.Join(args, ", ") + "): Promise<" + ret + "> =>\n")
+
+	// Build the path expression
+	path := "`" + strings.ReplaceAll(o.path, "`", "\\`") + "`"
+	for _, p := range pathParams {
+		path = strings.ReplaceAll(path, "{"+p.Name+"}", "${encodeURIComponent(String(params["+jsonString(p.Name)+"]))}")
+	}
+
+	call := "      request<" + ret + ">(" + jsonString(o.method) + ", " + path
+
+	call += ", {"
+	var opts []string
+	if len(queryParams) > 0 {
+		names := make([]string, 0, len(queryParams))
+		for _, p := range queryParams {
+			names = append(names, jsonString(p.Name))
+		}
+		opts = append(opts, " query: pick(params, ["+strings.Join(names, ", ")+"])")
+	}
+	if len(headerParams) > 0 {
+		names := make([]string, 0, len(headerParams))
+		for _, p := range headerParams {
+			names = append(names, jsonString(p.Name))
+		}
+		opts = append(opts, " headers: pick(params, ["+strings.Join(names, ", ")+"])")
+	}
+	if bodyType != "" {
+		opts = append(opts, " body")
+	}
+	opts = append(opts, " init ")
+	call += strings.Join(opts, ",") + "}),\n"
+
+	sb.WriteString(call)
+	return nil
+}
+
+func jsonString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// Fields of struct schemas which are always present in their JSON encoding, filled in by SchemaInject. They are
+// only used to make fields non-optional in the generated types and left out of the OpenAPI document, where
+// required would also claim that clients must send them in request bodies
+var tsRequired = map[*openapi3.Schema][]string{}
+
+// Returns the names of the fields of a struct which are always present in its JSON encoding
+func requiredJSONFields(t reflect.Type) []string {
+	var fields []string
+
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+
+		tag, ok := f.Tag.Lookup("json")
+		if !ok || tag == "-" {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		if parts[0] == "" || strings.Contains(tag, "omitempty") || strings.Contains(tag, "omitzero") {
+			continue
+		}
+
+		fields = append(fields, parts[0])
+	}
+
+	return fields
+}
+
+const tsHeader = "// Code generated by clawmark gen-ts. DO NOT EDIT.\n\n"
+
+const tsClientPrelude = `export interface ClientOptions {
+  /** Base URL of the API, e.g. https://api.example.com */
+  baseUrl: string;
+  /** Headers sent with every request (e.g. Authorization) */
+  headers?: Record<string, string>;
+  /** Custom fetch implementation, defaults to the global fetch */
+  fetch?: typeof fetch;
+}
+
+/** Thrown when the API responds with a non-2xx status */
+export class ApiError extends Error {
+  /** Stable machine-readable error code, such as not_found or validation_failed */
+  public readonly code?: string;
+  /** ID of the request, include this when reporting a problem */
+  public readonly requestId?: string;
+
+  constructor(
+    public readonly status: number,
+    public readonly body: unknown,
+  ) {
+    super(
+      typeof body === "object" && body !== null && "message" in body
+        ? String((body as { message: unknown }).message)
+        : "Request failed with status " + status,
+    );
+
+    if (typeof body === "object" && body !== null) {
+      const { code, request_id } = body as { code?: unknown; request_id?: unknown };
+      if (typeof code === "string") this.code = code;
+      if (typeof request_id === "string") this.requestId = request_id;
+    }
+  }
+}
+
+interface RequestOptions {
+  query?: Record<string, unknown>;
+  headers?: Record<string, unknown>;
+  body?: unknown;
+  init?: RequestInit;
+}
+
+function pick(params: Record<string, unknown>, keys: string[]): Record<string, unknown> {
+  const out: Record<string, unknown> = {};
+  for (const key of keys) {
+    if (params[key] !== undefined) out[key] = params[key];
+  }
+  return out;
+}
+
+function makeRequest(options: ClientOptions) {
+  const doFetch = options.fetch ?? fetch;
+  const base = options.baseUrl.replace(/\/+$/, "");
+
+  return async function request<R>(method: string, path: string, opts: RequestOptions): Promise<R> {
+    const url = new URL(base + path);
+    for (const [key, value] of Object.entries(opts.query ?? {})) {
+      url.searchParams.set(key, String(value));
+    }
+
+    const headers = new Headers(options.headers);
+    for (const [key, value] of Object.entries(opts.headers ?? {})) {
+      headers.set(key, String(value));
+    }
+    if (opts.body !== undefined) headers.set("Content-Type", "application/json");
+
+    const res = await doFetch(url, {
+      ...opts.init,
+      method,
+      headers,
+      body: opts.body === undefined ? undefined : JSON.stringify(opts.body),
+    });
+
+    const text = await res.text();
+    const data = text ? JSON.parse(text) : undefined;
+
+    if (!res.ok) throw new ApiError(res.status, data);
+    return data as R;
+  };
+}
+
+`
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"html/template"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"clawmark/state"
+
+	"github.com/go-chi/chi/v5"
+)
+
+//go:embed data/docs/index.html
+var docsHTML string
+
+//go:embed data/docs/assets
+var docsAssetsFS embed.FS
+
+const docsAssetsPath = "/docs/assets"
+
+// Assets are not fingerprinted, so cache for a day and let the ETag handle revalidation
+const docsAssetsCacheControl = "public, max-age=86400"
+
+type docsAsset struct {
+	data        []byte
+	etag        string
+	contentType string
+}
+
+// Loads all embedded docs assets in memory along with their ETag
+func loadDocsAssets() map[string]docsAsset {
+	assets := map[string]docsAsset{}
+
+	err := fs.WalkDir(docsAssetsFS, "data/docs/assets", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		data, err := docsAssetsFS.ReadFile(p)
+
+		if err != nil {
+			return err
+		}
+
+		sum := sha256.Sum256(data)
+
+		contentType := mime.TypeByExtension(path.Ext(p))
+
+		if contentType == "" {
+			contentType = http.DetectContentType(data)
+		}
+
+		assets[path.Base(p)] = docsAsset{
+			data:        data,
+			etag:        `"` + hex.EncodeToString(sum[:8]) + `"`,
+			contentType: contentType,
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		panic(err)
+	}
+
+	return assets
+}
+
+// Mounts the docs page and its self-hosted assets
+func docsRoutes(r *chi.Mux) {
+	assets := loadDocsAssets()
+
+	r.Get(docsAssetsPath+"/{file}", func(w http.ResponseWriter, r *http.Request) {
+		asset, ok := assets[chi.URLParam(r, "file")]
+
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", asset.contentType)
+		w.Header().Set("Cache-Control", docsAssetsCacheControl)
+		w.Header().Set("ETag", asset.etag)
+
+		if match := r.Header.Get("If-None-Match"); match != "" && strings.Contains(match, asset.etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Write(asset.data)
+	})
+
+	docsTempl := template.Must(template.New("docs").Parse(docsHTML))
+
+	r.Get("/docs", func(w http.ResponseWriter, r *http.Request) {
+		renderer := state.Config.Docs.Renderer
+
+		// Allow switching renderers without a config change, e.g. /docs?renderer=swagger
+		switch q := r.URL.Query().Get("renderer"); q {
+		case "redoc", "swagger":
+			renderer = q
+		}
+
+		if renderer == "" {
+			renderer = "redoc"
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+		docsTempl.Execute(w, map[string]string{
+			"url":      "/openapi",
+			"page":     strings.TrimSuffix(docsServers()[0].URL, "/") + "/docs",
+			"title":    state.Config.Docs.Title,
+			"desc":     state.Config.Docs.Description,
+			"assets":   docsAssetsPath,
+			"renderer": renderer,
+			"logo":     state.Config.Docs.Logo,
+		})
+	})
+}
@@ -0,0 +1,320 @@
+// Following users, follow requests and close friends
+//
+// Following a public account is instant. Following a private account
+// creates a follow request instead, which the account approves or denies.
+// Until it is approved the requester cannot read the account's posts, see
+// posts.VisibleTo. Making an account public approves its pending requests.
+// Close friends are a separate list of users who can read posts with the
+// close friends visibility, they do not need to be followers.
+package follows
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"clawmark/notifications"
+	"clawmark/state"
+	"clawmark/types"
+	"clawmark/webhooks"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// The user follows the account
+	StatusFollowing = "following"
+
+	// The account is private and has been asked to approve the follow
+	StatusRequested = "requested"
+)
+
+var (
+	ErrSelf            = errors.New("cannot follow or add yourself")
+	ErrUserNotFound    = errors.New("user not found")
+	ErrRequestNotFound = errors.New("follow request not found")
+)
+
+// Follows a user, or requests to follow them if their account is private. Returns the resulting status,
+// following or requesting twice is not an error and does not notify the user again. Fails with blocks.ErrBlocked if either user blocked the other
+func Follow(ctx context.Context, userID, targetID uuid.UUID) (string, error) {
+	if userID == targetID {
+		return "", ErrSelf
+	}
+
+	var status string
+	var created bool
+
+	err := state.Pool.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var target types.User
+		err := tx.Select("id", "private").First(&target, "id = ?", targetID).Error
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+
+		if err != nil {
+			return err
+		}
+
+		var following int64
+		err = tx.Model(&types.Follow{}).Where("follower_id = ? AND following_id = ?", userID, targetID).Count(&following).Error
+
+		if err != nil {
+			return err
+		}
+
+		if following > 0 {
+			status = StatusFollowing
+			return nil
+		}
+
+		if !target.Private {
+			status = StatusFollowing
+			created, err = follow(tx, userID, targetID)
+			return err
+		}
+
+		status = StatusRequested
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&types.FollowRequest{RequesterID: userID, TargetID: targetID})
+
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+
+		return notifications.Create(tx, types.Notification{
+			UserID:  targetID,
+			ActorID: userID,
+			Type:    notifications.TypeFollowRequest,
+		})
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	if created {
+		publish(ctx, userID, targetID)
+	}
+
+	return status, nil
+}
+
+// Creates the follow and notifies the followed user. Returns whether the follow is new, if so the caller
+// publishes the webhook once committed
+func follow(tx *gorm.DB, userID, targetID uuid.UUID) (bool, error) {
+	res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&types.Follow{FollowerID: userID, FollowingID: targetID})
+
+	if res.Error != nil || res.RowsAffected == 0 {
+		return false, res.Error
+	}
+
+	err := notifications.Create(tx, types.Notification{
+		UserID:  targetID,
+		ActorID: userID,
+		Type:    notifications.TypeFollow,
+	})
+
+	return err == nil, err
+}
+
+// Sends the new follower webhook to the followed user
+func publish(ctx context.Context, followerID, targetID uuid.UUID) {
+	var follower types.User
+	err := state.Pool.WithContext(ctx).Select("id", "username", "avatar_url").First(&follower, "id = ?", followerID).Error
+
+	if err == nil {
+		err = webhooks.Publish(ctx, targetID, webhooks.EventNewFollower, types.NewFollowerEvent{
+			Follower: types.PartialUser{
+				ID:        follower.ID,
+				Username:  follower.Username,
+				AvatarURL: follower.AvatarURL,
+			},
+			FollowedAt: time.Now(),
+		})
+	}
+
+	if err != nil {
+		state.Logger.Error("[follows] Failed to publish new follower", zap.Error(err), zap.String("user_id", targetID.String()))
+	}
+}
+
+// Unfollows a user and cancels any pending request to follow them
+func Unfollow(ctx context.Context, userID, targetID uuid.UUID) error {
+	return state.Pool.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("follower_id = ? AND following_id = ?", userID, targetID).Delete(&types.Follow{}).Error
+
+		if err != nil {
+			return err
+		}
+
+		return tx.Where("requester_id = ? AND target_id = ?", userID, targetID).Delete(&types.FollowRequest{}).Error
+	})
+}
+
+// Returns the pending requests to follow the user, oldest first
+func Requests(ctx context.Context, userID uuid.UUID) ([]types.FollowRequestEntry, error) {
+	var requests []types.FollowRequest
+	err := state.Pool.WithContext(ctx).Preload("Requester").Where("target_id = ?", userID).Order("created_at").Find(&requests).Error
+
+	entries := make([]types.FollowRequestEntry, 0, len(requests))
+
+	for _, r := range requests {
+		entries = append(entries, types.FollowRequestEntry{
+			ID: r.ID,
+			Requester: types.PartialUser{
+				ID:        r.Requester.ID,
+				Username:  r.Requester.Username,
+				AvatarURL: r.Requester.AvatarURL,
+			},
+			CreatedAt: r.CreatedAt,
+		})
+	}
+
+	return entries, err
+}
+
+// Approves a request to follow the user, the requester then follows them
+func Approve(ctx context.Context, userID, requestID uuid.UUID) error {
+	var request types.FollowRequest
+	var created bool
+
+	err := state.Pool.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&request, "id = ? AND target_id = ?", requestID, userID).Error
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRequestNotFound
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&request).Error; err != nil {
+			return err
+		}
+
+		created, err = follow(tx, request.RequesterID, userID)
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if created {
+		publish(ctx, request.RequesterID, userID)
+	}
+
+	return nil
+}
+
+// Denies a request to follow the user, the requester is not told
+func Deny(ctx context.Context, userID, requestID uuid.UUID) error {
+	res := state.Pool.WithContext(ctx).Where("id = ? AND target_id = ?", requestID, userID).Delete(&types.FollowRequest{})
+
+	if res.Error != nil {
+		return res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return ErrRequestNotFound
+	}
+
+	return nil
+}
+
+// Makes the account of the user private or public, making it public approves all pending follow requests
+func SetPrivate(ctx context.Context, userID uuid.UUID, private bool) error {
+	var approved []types.FollowRequest
+
+	// Requesters who did not follow the user yet
+	var followers []uuid.UUID
+
+	err := state.Pool.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&types.User{}).Where("id = ?", userID).Update("private", private).Error
+
+		if err != nil || private {
+			return err
+		}
+
+		err = tx.Clauses(clause.Returning{}).Where("target_id = ?", userID).Delete(&approved).Error
+
+		if err != nil {
+			return err
+		}
+
+		for _, request := range approved {
+			created, err := follow(tx, request.RequesterID, userID)
+
+			if err != nil {
+				return err
+			}
+
+			if created {
+				followers = append(followers, request.RequesterID)
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for _, followerID := range followers {
+		publish(ctx, followerID, userID)
+	}
+
+	return nil
+}
+
+// Adds a user to the close friends list of the user, adding them twice is not an error.
+// Fails with blocks.ErrBlocked if either user blocked the other
+func AddCloseFriend(ctx context.Context, userID, friendID uuid.UUID) error {
+	if userID == friendID {
+		return ErrSelf
+	}
+
+	db := state.Pool.WithContext(ctx)
+
+	var friend types.User
+	err := db.Select("id").First(&friend, "id = ?", friendID).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrUserNotFound
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&types.CloseFriend{UserID: userID, FriendID: friendID}).Error
+}
+
+// Removes a user from the close friends list of the user, they lose access to close friends posts
+func RemoveCloseFriend(ctx context.Context, userID, friendID uuid.UUID) error {
+	return state.Pool.WithContext(ctx).Where("user_id = ? AND friend_id = ?", userID, friendID).Delete(&types.CloseFriend{}).Error
+}
+
+// Returns the close friends of the user, most recently added first
+func CloseFriends(ctx context.Context, userID uuid.UUID) ([]types.PartialUser, error) {
+	var friends []types.CloseFriend
+	err := state.Pool.WithContext(ctx).Preload("Friend").Where("user_id = ?", userID).Order("created_at DESC").Find(&friends).Error
+
+	users := make([]types.PartialUser, 0, len(friends))
+
+	for _, f := range friends {
+		users = append(users, types.PartialUser{
+			ID:        f.Friend.ID,
+			Username:  f.Friend.Username,
+			AvatarURL: f.Friend.AvatarURL,
+		})
+	}
+
+	return users, err
+}
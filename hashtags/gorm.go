@@ -0,0 +1,91 @@
+package hashtags
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"clawmark/posts"
+	"clawmark/state"
+	"clawmark/types"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// GORM plugin counting the tags of posts towards trending tags when they are created, register with db.Use
+//
+// Tags added by later edits are not counted, trending tags reflect new posts. Trending tags are shown to everyone, so
+// only public posts of public accounts count towards them.
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string {
+	return "hashtags"
+}
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	return db.Callback().Create().After("gorm:create").Register("hashtags:after_create", record)
+}
+
+func record(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil || db.Statement.Schema.Table != "posts" {
+		return
+	}
+
+	field := db.Statement.Schema.LookUpField("Tags")
+	visibility := db.Statement.Schema.LookUpField("Visibility")
+	author := db.Statement.Schema.LookUpField("UserID")
+
+	if field == nil || visibility == nil || author == nil {
+		return
+	}
+
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	count := func(v reflect.Value) {
+		value, _ := field.ValueOf(ctx, v)
+		tags, _ := value.([]string)
+
+		if len(tags) == 0 {
+			return
+		}
+
+		// Posts created without a visibility get the column default, which is public
+		if value, zero := visibility.ValueOf(ctx, v); !zero && value != posts.VisibilityPublic {
+			return
+		}
+
+		userID, _ := author.ValueOf(ctx, v)
+
+		var private int64
+		err := db.Session(&gorm.Session{NewDB: true}).Model(&types.User{}).Where("id = ? AND private", userID).Count(&private).Error
+
+		if err != nil {
+			state.Logger.Error("[hashtags] Failed to look up the author of a post", zap.Error(err))
+			return
+		}
+
+		if private > 0 {
+			return
+		}
+
+		// Trending tags are not worth failing the post over
+		if err := Record(context.WithoutCancel(ctx), tags, time.Now()); err != nil {
+			state.Logger.Error("[hashtags] Failed to count tags", zap.Error(err))
+		}
+	}
+
+	rv := reflect.Indirect(db.Statement.ReflectValue)
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		count(rv)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			count(reflect.Indirect(rv.Index(i)))
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Tag pages, followed tags and trending tags
+//
+// Tags are stored normalized on posts (see posttext.NormalizeTag), so a tag
+// page is every post whose tags contain it. Users may follow tags, which
+// ranks posts with them higher in their feed.
+package hashtags
+
+import (
+	"context"
+	"errors"
+
+	"clawmark/blocks"
+	"clawmark/posts"
+	"clawmark/state"
+	"clawmark/types"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+// Maximum number of tags a user may follow
+const MaxFollowed = 200
+
+var ErrTooManyFollowed = errors.New("too many followed tags")
+
+// Registers the GORM plugin counting tags of new posts, state.Setup must be called first
+func Setup() error {
+	return state.Pool.Use(GormPlugin{})
+}
+
+// Returns the metadata of a tag as seen by the user, only posts the user would find on the tag page are counted
+func Get(ctx context.Context, userID uuid.UUID, tag string) (types.Tag, error) {
+	t := types.Tag{Name: tag}
+	db := state.Pool.WithContext(ctx)
+
+	err := db.Model(&types.Post{}).
+		Scopes(posts.Listed(userID), blocks.ExcludeHidden(userID, "posts.user_id")).
+		Where("tags @> ARRAY[?]::text[]", tag).
+		Count(&t.Posts).Error
+
+	if err != nil {
+		return types.Tag{}, err
+	}
+
+	err = db.Model(&types.TagFollow{}).Where("tag = ?", tag).Count(&t.Followers).Error
+
+	if err != nil {
+		return types.Tag{}, err
+	}
+
+	var following int64
+	err = db.Model(&types.TagFollow{}).Where("tag = ? AND user_id = ?", tag, userID).Count(&following).Error
+
+	if err != nil {
+		return types.Tag{}, err
+	}
+
+	t.Following = following > 0
+
+	return t, nil
+}
+
+// Returns the tags the user follows, oldest followed first
+func Followed(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	tags := []string{}
+	err := state.Pool.WithContext(ctx).Model(&types.TagFollow{}).Where("user_id = ?", userID).Order("created_at").Pluck("tag", &tags).Error
+
+	return tags, err
+}
+
+// Follows a tag, following a tag twice is not an error. Returns ErrTooManyFollowed if the user follows MaxFollowed tags
+func Follow(ctx context.Context, userID uuid.UUID, tag string) error {
+	db := state.Pool.WithContext(ctx)
+
+	var count int64
+	err := db.Model(&types.TagFollow{}).Where("user_id = ?", userID).Count(&count).Error
+
+	if err != nil {
+		return err
+	}
+
+	if count >= MaxFollowed {
+		var following int64
+		err = db.Model(&types.TagFollow{}).Where("user_id = ? AND tag = ?", userID, tag).Count(&following).Error
+
+		if err != nil || following > 0 {
+			return err
+		}
+
+		return ErrTooManyFollowed
+	}
+
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&types.TagFollow{UserID: userID, Tag: tag}).Error
+}
+
+// Unfollows a tag, unfollowing a tag which is not followed is not an error
+func Unfollow(ctx context.Context, userID uuid.UUID, tag string) error {
+	return state.Pool.WithContext(ctx).Where("user_id = ? AND tag = ?", userID, tag).Delete(&types.TagFollow{}).Error
+}
@@ -0,0 +1,150 @@
+package hashtags
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"clawmark/state"
+	"clawmark/types"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Trending tags are counted in Redis in time buckets, every new post adds
+// one to each of its tags in the current bucket of every window. A window
+// sums its most recent buckets, so it slides forward one bucket at a time,
+// and buckets expire once no window covers them.
+
+const (
+	trendingKeyPrefix = "tags:trending:"
+
+	// Summed buckets are cached for this long
+	trendingCacheTTL = time.Minute
+
+	// Maximum number of trending tags returned
+	MaxTrending = 50
+)
+
+type window struct {
+	Name    string
+	Bucket  time.Duration
+	Buckets int64
+}
+
+var windows = []window{
+	{Name: "hour", Bucket: 5 * time.Minute, Buckets: 12},
+	{Name: "day", Bucket: time.Hour, Buckets: 24},
+	{Name: "week", Bucket: 24 * time.Hour, Buckets: 7},
+}
+
+// Returns the names of all trending windows
+func Windows() []string {
+	names := make([]string, 0, len(windows))
+	for _, w := range windows {
+		names = append(names, w.Name)
+	}
+	return names
+}
+
+func getWindow(name string) (window, bool) {
+	for _, w := range windows {
+		if w.Name == name {
+			return w, true
+		}
+	}
+
+	return window{}, false
+}
+
+// Returns whether the trending window exists
+func IsWindow(name string) bool {
+	_, ok := getWindow(name)
+	return ok
+}
+
+func (w window) bucket(t time.Time) int64 {
+	return t.Unix() / int64(w.Bucket/time.Second)
+}
+
+func (w window) bucketKey(n int64) string {
+	return trendingKeyPrefix + w.Name + ":" + strconv.FormatInt(n, 10)
+}
+
+// Counts a post created at the time with the tags towards trending tags
+func Record(ctx context.Context, tags []string, at time.Time) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	_, err := state.Redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, w := range windows {
+			n := w.bucket(at)
+			key := w.bucketKey(n)
+
+			for _, tag := range tags {
+				pipe.ZIncrBy(ctx, key, 1, tag)
+			}
+
+			// Kept until the last window covering the bucket has moved past it
+			pipe.ExpireAt(ctx, key, time.Unix((n+w.Buckets+1)*int64(w.Bucket/time.Second), 0))
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+// Returns up to limit of the tags used in the most posts over the window, the window must exist
+func Trending(ctx context.Context, name string, limit int) (types.TrendingTagList, error) {
+	w, _ := getWindow(name)
+	list := types.TrendingTagList{Window: w.Name, Tags: []types.TrendingTag{}}
+	cacheKey := trendingKeyPrefix + w.Name
+
+	var exists *redis.IntCmd
+	var cached *redis.ZSliceCmd
+
+	_, err := state.Redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		exists = pipe.Exists(ctx, cacheKey)
+		cached = pipe.ZRevRangeWithScores(ctx, cacheKey, 0, int64(limit-1))
+		return nil
+	})
+
+	if err != nil {
+		return list, err
+	}
+
+	scores := cached.Val()
+
+	if exists.Val() == 0 {
+		current := w.bucket(time.Now())
+		keys := make([]string, 0, w.Buckets)
+
+		for n := current - w.Buckets + 1; n <= current; n++ {
+			keys = append(keys, w.bucketKey(n))
+		}
+
+		var summed *redis.ZSliceCmd
+
+		_, err = state.Redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.ZUnionStore(ctx, cacheKey, &redis.ZStore{Keys: keys})
+			pipe.Expire(ctx, cacheKey, trendingCacheTTL)
+			summed = pipe.ZRevRangeWithScores(ctx, cacheKey, 0, int64(limit-1))
+			return nil
+		})
+
+		if err != nil {
+			return list, err
+		}
+
+		scores = summed.Val()
+	}
+
+	for _, z := range scores {
+		tag, _ := z.Member.(string)
+		list.Tags = append(list.Tags, types.TrendingTag{Name: tag, Posts: int64(z.Score)})
+	}
+
+	return list, nil
+}
@@ -0,0 +1,106 @@
+// Translations for API error and validation messages
+//
+// Catalogs in catalogs/<locale>.json map the English message (as used in
+// constants and msg struct tags) to its translation, so English needs no
+// catalog and any message missing from a catalog falls back to English.
+// Validator messages for fields without a msg tag come from the validator's
+// own translations.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/es"
+	"github.com/go-playground/locales/fr"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+	es_translations "github.com/go-playground/validator/v10/translations/es"
+	fr_translations "github.com/go-playground/validator/v10/translations/fr"
+	"golang.org/x/text/language"
+)
+
+//go:embed catalogs/*.json
+var catalogs embed.FS
+
+type supportedLocale struct {
+	tag        language.Tag
+	locale     locales.Translator
+	validation func(v *validator.Validate, trans ut.Translator) error
+}
+
+// English must come first, it is the fallback
+var supported = []supportedLocale{
+	{language.English, en.New(), en_translations.RegisterDefaultTranslations},
+	{language.Spanish, es.New(), es_translations.RegisterDefaultTranslations},
+	{language.French, fr.New(), fr_translations.RegisterDefaultTranslations},
+}
+
+var (
+	uni     *ut.UniversalTranslator
+	matcher language.Matcher
+)
+
+// Loads the catalogs and registers validator translations for every supported locale on v
+func Setup(v *validator.Validate) error {
+	fallback := supported[0].locale
+	all := make([]locales.Translator, 0, len(supported))
+	tags := make([]language.Tag, 0, len(supported))
+
+	for _, s := range supported {
+		all = append(all, s.locale)
+		tags = append(tags, s.tag)
+	}
+
+	uni = ut.New(fallback, all...)
+	matcher = language.NewMatcher(tags)
+
+	for i, s := range supported {
+		trans, _ := uni.GetTranslator(s.locale.Locale())
+
+		if err := s.validation(v, trans); err != nil {
+			return fmt.Errorf("failed to register %s validator translations: %w", s.locale.Locale(), err)
+		}
+
+		if i == 0 {
+			continue
+		}
+
+		data, err := catalogs.ReadFile("catalogs/" + s.locale.Locale() + ".json")
+
+		if err != nil {
+			return fmt.Errorf("missing catalog for %s: %w", s.locale.Locale(), err)
+		}
+
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return fmt.Errorf("invalid catalog for %s: %w", s.locale.Locale(), err)
+		}
+
+		for msg, translated := range messages {
+			if err := trans.Add(msg, translated, true); err != nil {
+				return fmt.Errorf("invalid translation in %s catalog for %q: %w", s.locale.Locale(), msg, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Returns the translator best matching an Accept-Language header, English if nothing matches
+func Translator(acceptLanguage string) ut.Translator {
+	idx := 0
+
+	if tags, _, err := language.ParseAcceptLanguage(acceptLanguage); err == nil && len(tags) > 0 {
+		if _, i, conf := matcher.Match(tags...); conf != language.No {
+			idx = i
+		}
+	}
+
+	trans, _ := uni.GetTranslator(supported[idx].locale.Locale())
+	return trans
+}
@@ -3,55 +3,95 @@ package main
 import (
 	"compress/flate"
 	"compress/gzip"
-	"html/template"
+	"flag"
 	"io"
+	"net"
 	"net/http"
 	"strings"
 
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
+	"slices"
+	"sort"
 	"sync"
 	"syscall"
 	"time"
 
 	"clawmark/api"
+	"clawmark/blocks"
+	"clawmark/config"
 	"clawmark/constants"
 	docs "clawmark/doclib"
+	"clawmark/hashtags"
+	"clawmark/media"
+	"clawmark/mentions"
+	"clawmark/metrics"
+	"clawmark/previews"
 	"clawmark/state"
 	"clawmark/types"
 	"clawmark/uapi"
+	"clawmark/webhooks"
 
 	"github.com/cloudflare/tableflip"
 
 	"github.com/infinitybotlist/eureka/jsonimpl"
-	"github.com/infinitybotlist/eureka/zapchi"
 	"go.uber.org/zap"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"golang.org/x/time/rate"
 
+	blockroutes "clawmark/routes/blocks"
+	followroutes "clawmark/routes/follows"
+	"clawmark/routes/health"
+	mediaroutes "clawmark/routes/media"
+	notificationroutes "clawmark/routes/notifications"
+	pluginroutes "clawmark/routes/plugins"
+	postroutes "clawmark/routes/posts"
+	searchroutes "clawmark/routes/search"
+	tagroutes "clawmark/routes/tags"
 	"clawmark/routes/test"
-
-	_ "embed"
+	webhookroutes "clawmark/routes/webhooks"
 )
 
-//go:embed data/docs.html
-var docsHTML string
-
 var openapi []byte
 
+// Returns the Access-Control-Allow-Origin value for a request origin
+func allowedOrigin(origin string) (string, bool) {
+	var origins []string
+	for _, o := range state.CurrentConfig().Server.CorsOrigins {
+		if o != "" {
+			origins = append(origins, o)
+		}
+	}
+
+	if len(origins) == 0 || slices.Contains(origins, "*") {
+		return "*", true
+	}
+
+	if origin != "" && slices.Contains(origins, origin) {
+		return origin, true
+	}
+
+	return "", false
+}
+
 // Simple middleware to handle CORS
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// limit body to 10mb
 		r.Body = http.MaxBytesReader(w, r.Body, 50*1024*1024)
 
-		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if origin, ok := allowedOrigin(r.Header.Get("Origin")); ok {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+
+		w.Header().Add("Vary", "Origin")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 		w.Header().Set("Access-Control-Allow-Headers", "*")
-		w.Header().Set("Access-Control-Expose-Headers", "X-Session-Invalid, Retry-After")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Session-Invalid, Retry-After, "+uapi.RequestIDHeader+", "+media.OffsetHeader)
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE")
 
 		if r.Method == "OPTIONS" {
@@ -80,6 +120,12 @@ func CompressionMiddleware(next http.Handler) http.Handler {
 		// Check if the client supports compression
 		encoding := r.Header.Get("Accept-Encoding")
 
+		// Stored media is mostly compressed images, and its Content-Length and ranges are of the uncompressed file
+		if strings.HasPrefix(r.URL.Path, media.LocalFilesPath) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		// Avoid compressing already compressed content
 		contentType := w.Header().Get("Content-Type")
 		if strings.Contains(contentType, "image") || strings.Contains(contentType, "video") || strings.Contains(contentType, "zip") {
@@ -124,15 +170,81 @@ type RateLimiterMiddleware struct {
 }
 
 func NewRateLimiterMiddleware(rateLimit rate.Limit, burst int, paths []string) *RateLimiterMiddleware {
+	rl := &RateLimiterMiddleware{
+		limiter: rate.NewLimiter(rateLimit, burst),
+	}
+
+	rl.Update(rateLimit, burst, paths)
+
+	return rl
+}
+
+// Changes the limit and ratelimited paths, used on config reload
+func (rl *RateLimiterMiddleware) Update(rateLimit rate.Limit, burst int, paths []string) {
 	limitedPaths := make(map[string]struct{}, len(paths))
 	for _, path := range paths {
 		limitedPaths[path] = struct{}{}
 	}
 
-	return &RateLimiterMiddleware{
-		limiter: rate.NewLimiter(rateLimit, burst),
-		paths:   limitedPaths,
-	}
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.limiter.SetLimit(rateLimit)
+	rl.limiter.SetBurst(burst)
+	rl.paths = limitedPaths
+}
+
+// Logs every request along with its request ID
+func requestLogger(next http.Handler) http.Handler {
+	logger := state.Logger.Named("api")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+
+		next.ServeHTTP(ww, r)
+
+		logger.Info("Got Request",
+			zap.Int("status", ww.Status()),
+			zap.String("statusText", http.StatusText(ww.Status())),
+			zap.String("method", r.Method),
+			zap.String("url", r.URL.String()),
+			zap.String("reqIp", r.RemoteAddr),
+			zap.String("protocol", r.Proto),
+			zap.Int("size", ww.BytesWritten()),
+			zap.String("latency", time.Since(start).String()),
+			zap.String("userAgent", r.UserAgent()),
+			zap.String("reqId", uapi.RequestID(r.Context())),
+		)
+	})
+}
+
+// Recovers panics outside of route handlers (which uapi recovers itself), responding with a structured error
+func recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			err := recover()
+
+			if err == nil {
+				return
+			}
+
+			// Used by net/http to abort a response, must be passed on
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+
+			state.Logger.Error("Request panic'd", zap.String("reqId", uapi.RequestID(r.Context())), zap.String("method", r.Method), zap.String("path", r.URL.Path), zap.Any("error", err), zap.Stack("stack"))
+
+			uapi.WriteError(w, r, uapi.Error{
+				Code:    uapi.ErrInternal,
+				Status:  http.StatusInternalServerError,
+				Message: constants.InternalServerError,
+			})
+		}()
+
+		next.ServeHTTP(w, r)
+	})
 }
 
 func (rl *RateLimiterMiddleware) Middleware(next http.Handler) http.Handler {
@@ -143,7 +255,11 @@ func (rl *RateLimiterMiddleware) Middleware(next http.Handler) http.Handler {
 		rl.mu.Unlock()
 
 		if exists && !allow {
-			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			uapi.WriteError(w, r, uapi.Error{
+				Code:    uapi.ErrRatelimited,
+				Status:  http.StatusTooManyRequests,
+				Message: constants.Ratelimited,
+			})
 			return
 		}
 
@@ -151,54 +267,75 @@ func (rl *RateLimiterMiddleware) Middleware(next http.Handler) http.Handler {
 	})
 }
 
-func main() {
-	state.Setup()
+// Returns the configured docs servers, with the one for the current environment first
+func docsServers() []docs.Server {
+	envs := make([]string, 0, len(state.Config.Docs.Servers))
+	for env := range state.Config.Docs.Servers {
+		envs = append(envs, env)
+	}
 
-	var err error
+	sort.SliceStable(envs, func(i, j int) bool {
+		if envs[i] == state.Config.Server.Env || envs[j] == state.Config.Server.Env {
+			return envs[i] == state.Config.Server.Env
+		}
+
+		return envs[i] < envs[j]
+	})
+
+	servers := make([]docs.Server, 0, len(envs))
+	for _, env := range envs {
+		server := state.Config.Docs.Servers[env]
+		servers = append(servers, docs.Server{
+			URL:         server.URL,
+			Description: server.Description,
+		})
+	}
+
+	return servers
+}
+
+func setupDocs() {
+	cfg := state.Config.Docs
 
 	docs.DocsSetupData = &docs.SetupData{
-		URL:         "https://api.luvix.social/",
-		ErrorStruct: types.Response{},
+		Servers:       docsServers(),
+		ErrorStruct:   types.ApiError{},
+		ProblemStruct: types.Problem{},
 		Info: docs.Info{
-			Title:          "Luvix Social by Purrquinox",
-			TermsOfService: "https://luvix.social/legal/terms",
-			Version:        "1.0",
-			Description:    "Redefining Connection in a Seamless, Privacy-Focused World.",
+			Title:          cfg.Title,
+			TermsOfService: cfg.TermsOfService,
+			Version:        cfg.Version,
+			Description:    cfg.Description,
 			Contact: docs.Contact{
-				Name:  "Purrquinox",
-				URL:   "https://luvix.social/",
-				Email: "support@purrquinox.com",
+				Name:  cfg.Contact.Name,
+				URL:   cfg.Contact.URL,
+				Email: cfg.Contact.Email,
 			},
 			License: docs.License{
-				Name: "AGPL-3.0",
-				URL:  "https://opensource.org/licenses/AGPL-3.0",
+				Name: cfg.License.Name,
+				URL:  cfg.License.URL,
 			},
 		},
 	}
 
 	docs.Setup()
 	api.Setup()
+	webhooks.Docs()
+}
 
-	r := chi.NewRouter()
-
-	ratelimitedEndpoints := []string{}
-	ratelimit := NewRateLimiterMiddleware(rate.Every(1), 3, ratelimitedEndpoints)
-
-	r.Use(
-		middleware.Recoverer,
-		middleware.RealIP,
-		middleware.CleanPath,
-		middleware.Heartbeat("/ping"),
-		middleware.Compress(5),
-		middleware.Timeout(30*time.Second),
-		corsMiddleware,
-		CompressionMiddleware,
-		ratelimit.Middleware,
-		zapchi.Logger(state.Logger, "api"),
-	)
-
+func loadRouters(r *chi.Mux) {
 	routers := []uapi.APIRouter{
 		test.Router{},
+		health.Router{},
+		webhookroutes.Router{},
+		mediaroutes.Router{},
+		pluginroutes.Router{},
+		notificationroutes.Router{},
+		tagroutes.Router{},
+		searchroutes.Router{},
+		blockroutes.Router{},
+		followroutes.Router{},
+		postroutes.Router{},
 	}
 
 	for _, router := range routers {
@@ -212,20 +349,152 @@ func main() {
 
 		router.Routes(r)
 	}
+}
+
+// Generates the typescript types and client into the given directory, only the docs settings of the config are needed
+func genTypescript(configPath, dir string) {
+	state.LoadDocsConfig(configPath)
+	setupDocs()
+	loadRouters(chi.NewRouter())
+
+	out, err := docs.GenerateTypescript()
+
+	if err != nil {
+		panic(err)
+	}
+
+	err = os.MkdirAll(dir, 0755)
+
+	if err != nil {
+		panic(err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, "types.d.ts"), []byte(out.Types), 0644)
+
+	if err != nil {
+		panic(err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, "client.ts"), []byte(out.Client), 0644)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+func main() {
+	defaultConfig := config.DefaultPath
+
+	if path := os.Getenv(config.EnvPrefix + "CONFIG"); path != "" {
+		defaultConfig = path
+	}
+
+	configPath := flag.String("config", defaultConfig, "Path to the config file, values can be overridden with "+config.EnvPrefix+"* environment variables")
+	flag.Parse()
+
+	if flag.NArg() > 0 {
+		switch flag.Arg(0) {
+		case "gen-ts":
+			dir := "clients/ts"
+
+			if flag.NArg() > 1 {
+				dir = flag.Arg(1)
+			}
+
+			genTypescript(*configPath, dir)
+			return
+		case "migrate":
+			migrate(*configPath, flag.Args()[1:])
+			return
+		default:
+			panic("Unknown command: " + flag.Arg(0))
+		}
+	}
+
+	state.Setup(*configPath)
+
+	var err error
+
+	err = media.Setup()
+
+	if err != nil {
+		panic(err)
+	}
+
+	err = previews.Setup()
+
+	if err != nil {
+		panic(err)
+	}
+
+	err = mentions.Setup()
+
+	if err != nil {
+		panic(err)
+	}
+
+	err = hashtags.Setup()
+
+	if err != nil {
+		panic(err)
+	}
+
+	err = blocks.Setup()
+
+	if err != nil {
+		panic(err)
+	}
+
+	err = webhooks.Setup()
+
+	if err != nil {
+		panic(err)
+	}
+
+	// Background workers, stopped by shutdown hooks once the server has drained
+	webhooks.Start(state.Context)
+	media.Start(state.Context)
+	previews.Start(state.Context)
+
+	setupDocs()
+
+	r := chi.NewRouter()
+
+	ratelimit := NewRateLimiterMiddleware(rate.Every(state.Config.Ratelimit.Interval), state.Config.Ratelimit.Burst, state.Config.Ratelimit.Paths)
+
+	state.OnReload(func(cfg *config.Config) {
+		ratelimit.Update(rate.Every(cfg.Ratelimit.Interval), cfg.Ratelimit.Burst, cfg.Ratelimit.Paths)
+	})
+
+	go handleReloadSignal()
+
+	r.Use(
+		uapi.RequestIDMiddleware,
+		recoverer,
+		middleware.RealIP,
+		middleware.CleanPath,
+		middleware.Heartbeat("/ping"),
+		middleware.Compress(5),
+		middleware.Timeout(30*time.Second),
+		corsMiddleware,
+		CompressionMiddleware,
+		ratelimit.Middleware,
+		requestLogger,
+	)
+
+	loadRouters(r)
 
 	r.Get("/openapi", func(w http.ResponseWriter, r *http.Request) {
 		w.Write(openapi)
 	})
 
-	docsTempl := template.Must(template.New("docs").Parse(docsHTML))
+	r.Handle("/metrics", metrics.Handler())
 
-	r.Get("/docs", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if local, ok := media.Backend().(*media.LocalStorage); ok && state.Config.Media.Local.BaseURL == "" {
+		r.Handle(media.LocalFilesPath+"*", local.Handler())
+	}
 
-		docsTempl.Execute(w, map[string]string{
-			"url": "/openapi",
-		})
-	})
+	docsRoutes(r)
 
 	// Load openapi here to avoid large marshalling in every request
 	openapi, err = jsonimpl.Marshal(docs.GetSchema())
@@ -235,15 +504,30 @@ func main() {
 	}
 
 	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte(constants.EndpointNotFound))
+		uapi.WriteError(w, r, uapi.Error{
+			Code:    uapi.ErrEndpointNotFound,
+			Status:  http.StatusNotFound,
+			Message: constants.EndpointNotFound,
+		})
 	})
 
 	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		w.Write([]byte(constants.MethodNotAllowed))
+		uapi.WriteError(w, r, uapi.Error{
+			Code:    uapi.ErrMethodNotAllowed,
+			Status:  http.StatusMethodNotAllowed,
+			Message: constants.MethodNotAllowed,
+		})
 	})
 
+	server := &http.Server{
+		ReadTimeout: 30 * time.Second,
+		Handler:     r,
+	}
+
+	// Stops on SIGINT/SIGTERM, after which in-flight requests are drained
+	terminate, stopTerminate := signal.NotifyContext(state.Context, syscall.SIGINT, syscall.SIGTERM)
+	defer stopTerminate()
+
 	// If GOOS is windows, do normal http server
 	if runtime.GOOS == "linux" || runtime.GOOS == "darwin" {
 		upg, _ := tableflip.New(tableflip.Options{})
@@ -254,7 +538,11 @@ func main() {
 			signal.Notify(sig, syscall.SIGHUP)
 			for range sig {
 				state.Logger.Info("Received SIGHUP, upgrading server")
-				upg.Upgrade()
+				err := upg.Upgrade()
+
+				if err != nil {
+					state.Logger.Error("Upgrade failed, continuing with the current process", zap.Error(err))
+				}
 			}
 		}()
 
@@ -265,32 +553,33 @@ func main() {
 			state.Logger.Fatal("Error binding to socket", zap.Error(err))
 		}
 
-		defer ln.Close()
-
-		server := http.Server{
-			ReadTimeout: 30 * time.Second,
-			Handler:     r,
-		}
-
-		go func() {
-			err := server.Serve(ln)
-			if err != http.ErrServerClosed {
-				state.Logger.Error("Server failed due to unexpected error", zap.Error(err))
-			}
-		}()
+		go serve(server, ln)
 
+		// Tells the parent (if any) that we are serving, after which it drains and exits
 		if err := upg.Ready(); err != nil {
 			state.Logger.Fatal("Error calling upg.Ready", zap.Error(err))
 		}
 
-		<-upg.Exit()
+		select {
+		case <-upg.Exit():
+			state.Logger.Info("Upgrade complete, draining old process")
+		case <-terminate.Done():
+			state.Logger.Info("Received termination signal, shutting down")
+		}
 	} else {
 		// Tableflip not supported
 		state.Logger.Warn("Tableflip not supported on this platform, this is not a production-capable server.")
-		err = http.ListenAndServe(state.Config.Server.Port, r)
+		ln, err := net.Listen("tcp", state.Config.Server.Port)
 
 		if err != nil {
 			state.Logger.Fatal("Error binding to socket", zap.Error(err))
 		}
+
+		go serve(server, ln)
+
+		<-terminate.Done()
+		state.Logger.Info("Received termination signal, shutting down")
 	}
+
+	shutdown(server)
 }
@@ -0,0 +1,238 @@
+package media
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"clawmark/config"
+	"clawmark/state"
+)
+
+// Path local media is served from when no base URL is configured
+const LocalFilesPath = "/media/files/"
+
+// Stores media on the local filesystem, served by Handler
+//
+// Every URL is signed and expires, as the handler cannot tell which objects belong to media not everyone may see.
+// URLs of media anyone may see only change once a day so they can still be cached
+type LocalStorage struct {
+	dir     string
+	baseURL string
+	key     []byte
+
+	// Holds objects being written, next to dir so they can be renamed into it but are never served
+	tmpDir string
+}
+
+func NewLocalStorage(cfg config.MediaLocal) (*LocalStorage, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	tmpDir := filepath.Clean(cfg.Dir) + ".tmp"
+
+	if err := os.MkdirAll(tmpDir, 0o755); err != nil {
+		return nil, err
+	}
+
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = LocalFilesPath
+	}
+
+	key := []byte(cfg.SigningKey)
+
+	if len(key) == 0 {
+		key = make([]byte, 32)
+
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+
+		state.Logger.Warn("[media] No media.local.signing_key set, media URLs stop working on restart and are not shared between instances")
+	}
+
+	return &LocalStorage{
+		dir:     cfg.Dir,
+		baseURL: strings.TrimSuffix(baseURL, "/") + "/",
+		key:     key,
+		tmpDir:  tmpDir,
+	}, nil
+}
+
+func (s *LocalStorage) path(key string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(key))
+}
+
+func (s *LocalStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	path := s.path(key)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// Written to a temporary file first so readers never see a partial object
+	tmp, err := os.CreateTemp(s.tmpDir, "upload-*")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *LocalStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	return os.Open(s.path(key))
+}
+
+func (s *LocalStorage) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := os.Stat(s.path(key))
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	err := os.Remove(s.path(key))
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+// Returns the signature of the object URL expiring at the unix time
+func (s *LocalStorage) sign(key string, expires int64) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(key + "\n" + strconv.FormatInt(expires, 10)))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *LocalStorage) signedURL(key string, expires time.Time) string {
+	unix := expires.Unix()
+	return s.baseURL + key + "?expires=" + strconv.FormatInt(unix, 10) + "&sig=" + s.sign(key, unix)
+}
+
+// Valid for one to two days, the URL changes at most once a day
+func (s *LocalStorage) URL(key string) string {
+	return s.signedURL(key, time.Now().Truncate(24*time.Hour).Add(48*time.Hour))
+}
+
+func (s *LocalStorage) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	return s.signedURL(key, time.Now().Add(expiry)), nil
+}
+
+// Returns the time a request for the object expires at, ok is false if its signature is invalid or it expired
+func (s *LocalStorage) verify(r *http.Request, key string) (expires time.Time, ok bool) {
+	q := r.URL.Query()
+	unix, err := strconv.ParseInt(q.Get("expires"), 10, 64)
+
+	if err != nil || time.Now().Unix() >= unix {
+		return time.Time{}, false
+	}
+
+	if !hmac.Equal([]byte(q.Get("sig")), []byte(s.sign(key, unix))) {
+		return time.Time{}, false
+	}
+
+	return time.Unix(unix, 0), true
+}
+
+// Returns whether key could name a stored object, keys never contain empty, relative or hidden path segments
+func validKey(key string) bool {
+	if key == "" {
+		return false
+	}
+
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || strings.HasPrefix(segment, ".") || strings.Contains(segment, "\\") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Serves the stored object with the key and Cache-Control header, directories are never listed
+func (s *LocalStorage) serveObject(w http.ResponseWriter, r *http.Request, key string, cacheControl string) {
+	if !validKey(key) {
+		http.NotFound(w, r)
+		return
+	}
+
+	f, err := os.Open(s.path(key))
+
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	defer f.Close()
+
+	info, err := f.Stat()
+
+	if err != nil || !info.Mode().IsRegular() {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	// The content type is taken from the extension of the key, replacing the JSON type set by the CORS middleware.
+	// Without a known extension ServeContent detects it from the content
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	} else {
+		w.Header().Del("Content-Type")
+	}
+
+	http.ServeContent(w, r, path.Base(key), info.ModTime(), f)
+}
+
+// Serves stored objects to requests with a valid signature, mount under LocalFilesPath
+func (s *LocalStorage) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, LocalFilesPath)
+		expires, ok := s.verify(r, key)
+
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		// Objects are content addressed and so never change, but the URL stops working when it expires
+		maxAge := int(time.Until(expires).Seconds())
+		s.serveObject(w, r, key, "private, max-age="+strconv.Itoa(maxAge))
+	})
+}
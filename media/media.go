@@ -0,0 +1,223 @@
+// Uploaded media, such as images attached to posts
+//
+// Uploads are spooled to disk while they are hashed and their type is
+// sniffed from the content, so the type a client claims is never trusted.
+// Objects are stored under their SHA-256, identical content is only stored
+// once no matter how many times (or by whom) it is uploaded.
+//
+// Metadata is stripped from images before they are hashed and stored, so
+// uploads only differing in metadata share an object. Resized variants,
+// dimensions and a blurhash placeholder are then generated in the background,
+// see Process.
+package media
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"clawmark/state"
+	"clawmark/types"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTooLarge = errors.New("media is larger than the maximum upload size")
+	ErrEmpty    = errors.New("media is empty")
+	ErrCorrupt  = errors.New("media is corrupt")
+)
+
+// Returned when the detected type of an upload is not allowed
+type TypeError struct {
+	Type string
+}
+
+func (e TypeError) Error() string {
+	return "media of type " + e.Type + " may not be uploaded"
+}
+
+var backend Storage
+
+// Creates the storage backend and staging directory, state.Setup must be called first
+func Setup() error {
+	cfg := state.Config.Media
+
+	if err := os.MkdirAll(cfg.StagingDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+
+	var err error
+	backend, err = NewStorage(cfg)
+
+	return err
+}
+
+// Returns the storage backend
+func Backend() Storage {
+	return backend
+}
+
+// Checks that the storage backend is reachable, for backends that support it
+func Check(ctx context.Context) error {
+	if c, ok := backend.(interface{ Check(context.Context) error }); ok {
+		return c.Check(ctx)
+	}
+
+	return nil
+}
+
+// Returns the public URL of the media
+func URL(m types.Media) string {
+	return backend.URL(m.Key)
+}
+
+// Returns the maximum upload size in bytes
+func MaxSize() int64 {
+	return state.CurrentConfig().Media.MaxSize
+}
+
+// Returns the PostPlugin type for media of the content type
+func PluginType(contentType string) string {
+	if contentType == "image/gif" {
+		return "gif"
+	}
+
+	return "image"
+}
+
+// Stores the content of r for the user, reading at most the maximum upload size
+func Store(ctx context.Context, userID uuid.UUID, r io.Reader) (*types.Media, error) {
+	maxSize := state.CurrentConfig().Media.MaxSize
+
+	tmp, err := os.CreateTemp(state.Config.Media.StagingDir, "store-*")
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	size, err := io.Copy(tmp, io.LimitReader(r, maxSize+1))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if size > maxSize {
+		return nil, ErrTooLarge
+	}
+
+	return storeFile(ctx, userID, tmp, size)
+}
+
+// Sniffs, hashes and stores a spooled upload
+func storeFile(ctx context.Context, userID uuid.UUID, f *os.File, size int64) (*types.Media, error) {
+	if size == 0 {
+		return nil, ErrEmpty
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	mtype, err := mimetype.DetectReader(f)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !allowed(mtype) {
+		return nil, TypeError{Type: mtype.String()}
+	}
+
+	contentType, _, _ := strings.Cut(mtype.String(), ";")
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(f)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Stripped before storing so location data is never served, even while the rest of the processing is pending
+	data, err = Strip(contentType, data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Hashed after stripping so the hash and key describe the stored bytes, uploads only differing in metadata
+	// share an object
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	// The same user uploading the same content again gets the existing media back
+	var existing types.Media
+	err = state.Pool.WithContext(ctx).Where("user_id = ? AND hash = ?", userID, hash).First(&existing).Error
+
+	if err == nil {
+		return &existing, nil
+	}
+
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	key := hash[:2] + "/" + hash + mtype.Extension()
+
+	stored, err := backend.Exists(ctx, key)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for existing object: %w", err)
+	}
+
+	if !stored {
+		if err := backend.Put(ctx, key, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+			return nil, fmt.Errorf("failed to store object: %w", err)
+		}
+	}
+
+	m := types.Media{
+		UserID:      userID,
+		Hash:        hash,
+		Key:         key,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Status:      StatusPending,
+	}
+
+	if err := state.Pool.WithContext(ctx).Create(&m).Error; err != nil {
+		return nil, err
+	}
+
+	// Picked up by the requeue job if this fails
+	if err := Enqueue(ctx, m.ID); err != nil {
+		state.Logger.Error("[media] Failed to queue media for processing", zap.Error(err), zap.String("id", m.ID.String()))
+	}
+
+	return &m, nil
+}
+
+func allowed(mtype *mimetype.MIME) bool {
+	for _, t := range state.CurrentConfig().Media.AllowedTypes {
+		if mtype.Is(t) {
+			return true
+		}
+	}
+
+	return false
+}
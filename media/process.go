@@ -0,0 +1,571 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	_ "image/gif"
+
+	"clawmark/state"
+	"clawmark/types"
+
+	"github.com/buckket/go-blurhash"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"golang.org/x/image/draw"
+	"gorm.io/gorm"
+
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	StatusPending = "pending"
+	StatusReady   = "ready"
+	StatusFailed  = "failed"
+)
+
+// Name of the full size image when choosing a variant
+const SizeOriginal = "original"
+
+// A variant size, images are scaled so their longest side is at most Max pixels
+type Size struct {
+	Name string
+	Max  int
+}
+
+// Variant sizes, smallest first
+var Sizes = []Size{
+	{Name: "small", Max: 320},
+	{Name: "medium", Max: 720},
+	{Name: "large", Max: 1280},
+}
+
+// Returns the names of the variant sizes followed by the original
+func SizeNames() []string {
+	names := make([]string, 0, len(Sizes)+1)
+	for _, s := range Sizes {
+		names = append(names, s.Name)
+	}
+	return append(names, SizeOriginal)
+}
+
+// Returns whether name is a variant size or the original
+func IsSize(name string) bool {
+	if name == SizeOriginal {
+		return true
+	}
+
+	for _, s := range Sizes {
+		if s.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+const (
+	queueKey = "media:process"
+
+	// Number of concurrent processing workers
+	workers = 2
+
+	// Images with more pixels than this are not decoded, protecting against decompression bombs
+	maxPixels = 50_000_000
+
+	// Media still pending after this long is assumed to have been lost from the queue and is requeued
+	requeueAfter = 10 * time.Minute
+
+	// Held while processing so the same media is not processed twice at once
+	lockTTL = 5 * time.Minute
+
+	jpegQuality = 85
+
+	// Blurhash components along the longest side, the placeholder is computed from an image of blurhashSize pixels
+	blurhashComponents = 4
+	blurhashSize       = 32
+)
+
+func lockKey(id uuid.UUID) string {
+	return "media:process:lock:" + id.String()
+}
+
+// Queues media for processing
+func Enqueue(ctx context.Context, id uuid.UUID) error {
+	return state.Redis.LPush(ctx, queueKey, id.String()).Err()
+}
+
+var (
+	running sync.WaitGroup
+
+	// Number of processing workers currently running
+	activeWorkers atomic.Int32
+)
+
+// Starts the processing workers and cleanup jobs, they stop on shutdown or when ctx is cancelled
+func Start(ctx context.Context) {
+	ctx, stop := context.WithCancel(ctx)
+
+	// Registered after the connections, so they are stopped before those are closed
+	state.OnShutdown(func(ctx context.Context) {
+		stop()
+
+		if !wait(ctx) {
+			state.Logger.Error("[media] Workers did not stop before the shutdown deadline")
+		}
+	})
+
+	running.Add(workers + 1)
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer running.Done()
+
+			activeWorkers.Add(1)
+			defer activeWorkers.Add(-1)
+
+			worker(ctx)
+		}()
+	}
+
+	go func() {
+		defer running.Done()
+
+		ticker := time.NewTicker(requeueAfter)
+		defer ticker.Stop()
+
+		for {
+			requeue(ctx)
+			sweep()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Waits for the workers to stop after their context is cancelled, returns false if ctx expires first
+func wait(ctx context.Context) bool {
+	done := make(chan struct{})
+
+	go func() {
+		running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Returns an error if any processing worker has stopped
+func CheckWorkers() error {
+	if n := activeWorkers.Load(); n < workers {
+		return fmt.Errorf("%d of %d workers running", n, workers)
+	}
+
+	return nil
+}
+
+func worker(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		res, err := state.Redis.BRPop(ctx, 5*time.Second, queueKey).Result()
+
+		if err != nil {
+			if !errors.Is(err, redis.Nil) && ctx.Err() == nil {
+				state.Logger.Error("[media] Failed to pop media", zap.Error(err))
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+
+		id, err := uuid.Parse(res[1])
+
+		if err != nil {
+			state.Logger.Error("[media] Invalid media ID in queue", zap.String("id", res[1]))
+			continue
+		}
+
+		// Finished on shutdown rather than left half processed
+		ctx := context.WithoutCancel(ctx)
+
+		if err := Process(ctx, id); err != nil {
+			state.Logger.Error("[media] Failed to process media", zap.Error(err), zap.String("id", id.String()))
+		}
+	}
+}
+
+// Queues media which has been pending for too long again
+func requeue(ctx context.Context) {
+	var ids []uuid.UUID
+	err := state.Pool.WithContext(ctx).Model(&types.Media{}).Where("status = ? AND updated_at < ?", StatusPending, time.Now().Add(-requeueAfter)).Pluck("id", &ids).Error
+
+	if err != nil {
+		if ctx.Err() == nil {
+			state.Logger.Error("[media] Failed to fetch pending media", zap.Error(err))
+		}
+		return
+	}
+
+	for _, id := range ids {
+		if err := Enqueue(ctx, id); err != nil {
+			state.Logger.Error("[media] Failed to requeue media", zap.Error(err), zap.String("id", id.String()))
+		}
+	}
+}
+
+// Generates the variants and blurhash of pending media and records its dimensions
+//
+// Media which cannot be decoded is marked as failed and served as uploaded.
+func Process(ctx context.Context, id uuid.UUID) error {
+	locked, err := state.Redis.SetNX(ctx, lockKey(id), 1, lockTTL).Result()
+
+	if err != nil {
+		return err
+	}
+
+	if !locked {
+		return nil
+	}
+
+	defer state.Redis.Del(context.WithoutCancel(ctx), lockKey(id))
+
+	var m types.Media
+	err = state.Pool.WithContext(ctx).First(&m, "id = ?", id).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if m.Status != StatusPending {
+		return nil
+	}
+
+	variants, err := process(ctx, &m)
+
+	if err != nil {
+		// Storage errors are worth retrying, the requeue job picks the media up again
+		var decodeErr decodeError
+		if !errors.As(err, &decodeErr) {
+			return err
+		}
+
+		state.Logger.Warn("[media] Media could not be decoded", zap.Error(err), zap.String("id", id.String()))
+
+		return state.Pool.WithContext(ctx).Model(&m).Update("status", StatusFailed).Error
+	}
+
+	return state.Pool.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("media_id = ?", m.ID).Delete(&types.MediaVariant{}).Error; err != nil {
+			return err
+		}
+
+		if len(variants) > 0 {
+			if err := tx.Create(&variants).Error; err != nil {
+				return err
+			}
+		}
+
+		err := tx.Model(&m).Updates(map[string]any{
+			"status":   StatusReady,
+			"width":    m.Width,
+			"height":   m.Height,
+			"blurhash": m.Blurhash,
+		}).Error
+
+		if err != nil {
+			return err
+		}
+
+		// Plugins attached before processing finished
+		return tx.Model(&types.PostPlugin{}).Where("media_id = ?", m.ID).Updates(map[string]any{
+			"width":    m.Width,
+			"height":   m.Height,
+			"blurhash": m.Blurhash,
+		}).Error
+	})
+}
+
+type decodeError struct {
+	err error
+}
+
+func (e decodeError) Error() string {
+	return "failed to decode image: " + e.err.Error()
+}
+
+func (e decodeError) Unwrap() error {
+	return e.err
+}
+
+// Decodes the media, stores its variants and sets its dimensions and blurhash
+func process(ctx context.Context, m *types.Media) ([]types.MediaVariant, error) {
+	r, err := backend.Open(ctx, m.Key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(r)
+	r.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+
+	if err != nil {
+		return nil, decodeError{err}
+	}
+
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, decodeError{fmt.Errorf("image is %dx%d, more than %d pixels", cfg.Width, cfg.Height, maxPixels)}
+	}
+
+	// Only the first frame of GIFs is decoded
+	img, _, err := image.Decode(bytes.NewReader(data))
+
+	if err != nil {
+		return nil, decodeError{err}
+	}
+
+	orientation := 0
+	if m.ContentType == "image/jpeg" {
+		orientation = jpegOrientation(data)
+	}
+
+	width, height := cfg.Width, cfg.Height
+
+	// Orientations 5-8 rotate the image by 90 degrees
+	if orientation >= 5 {
+		width, height = height, width
+	}
+
+	m.Width, m.Height = &width, &height
+
+	x, y := blurhashComponentsFor(width, height)
+	hash, err := blurhash.Encode(x, y, orient(resize(img, blurhashSize), orientation))
+
+	if err != nil {
+		return nil, decodeError{err}
+	}
+
+	m.Blurhash = hash
+
+	// Resizing would lose the animation, so GIFs are always served as uploaded
+	if m.ContentType == "image/gif" {
+		return nil, nil
+	}
+
+	var variants []types.MediaVariant
+
+	for _, size := range Sizes {
+		if size.Max >= max(width, height) {
+			break
+		}
+
+		variant, err := storeVariant(ctx, m, orient(resize(img, size.Max), orientation), size.Name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		variants = append(variants, variant)
+	}
+
+	return variants, nil
+}
+
+// Returns the x and y blurhash components, keeping them roughly proportional to the image
+func blurhashComponentsFor(width, height int) (int, int) {
+	x, y := blurhashComponents, blurhashComponents
+
+	switch {
+	case width > height:
+		y = max(1, blurhashComponents*height/width)
+	case height > width:
+		x = max(1, blurhashComponents*width/height)
+	}
+
+	return x, y
+}
+
+func storeVariant(ctx context.Context, m *types.Media, img *image.NRGBA, name string) (types.MediaVariant, error) {
+	var buf bytes.Buffer
+	var contentType, ext string
+
+	// Transparency needs PNG, everything else is smaller as JPEG
+	if img.Opaque() {
+		contentType, ext = "image/jpeg", ".jpg"
+
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return types.MediaVariant{}, err
+		}
+	} else {
+		contentType, ext = "image/png", ".png"
+
+		if err := png.Encode(&buf, img); err != nil {
+			return types.MediaVariant{}, err
+		}
+	}
+
+	key := m.Hash[:2] + "/" + m.Hash + "_" + name + ext
+	size := int64(buf.Len())
+
+	if err := backend.Put(ctx, key, &buf, size, contentType); err != nil {
+		return types.MediaVariant{}, fmt.Errorf("failed to store variant: %w", err)
+	}
+
+	return types.MediaVariant{
+		MediaID:     m.ID,
+		Name:        name,
+		Key:         key,
+		ContentType: contentType,
+		Width:       img.Rect.Dx(),
+		Height:      img.Rect.Dy(),
+		Size:        size,
+	}, nil
+}
+
+// Scales the image so its longest side is at most maxSide pixels
+func resize(img image.Image, maxSide int) *image.NRGBA {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	if w > maxSide || h > maxSide {
+		if w >= h {
+			w, h = maxSide, max(1, h*maxSide/w)
+		} else {
+			w, h = max(1, w*maxSide/h), maxSide
+		}
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Rect, img, b, draw.Src, nil)
+
+	return dst
+}
+
+// Applies an EXIF orientation (1-8), returning the image as it should be displayed
+func orient(img *image.NRGBA, orientation int) *image.NRGBA {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+
+			switch orientation {
+			case 2: // Mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // Rotated 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // Mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // Transposed
+				dx, dy = y, x
+			case 6: // Rotated 90 clockwise
+				dx, dy = h-1-y, x
+			case 7: // Transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // Rotated 90 counter-clockwise
+				dx, dy = y, w-1-x
+			}
+
+			copy(dst.Pix[dst.PixOffset(dx, dy):][:4], img.Pix[img.PixOffset(x, y):][:4])
+		}
+	}
+
+	return dst
+}
+
+// Returns the EXIF orientation of a JPEG, or 0 if it has none
+func jpegOrientation(data []byte) int {
+	for i := 2; i+4 <= len(data) && data[i] == 0xff; {
+		marker := data[i+1]
+		end := i + 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+
+		if marker == jpegSOS || end > len(data) || end < i+4 {
+			return 0
+		}
+
+		if marker == jpegAPP1 {
+			if o := exifOrientation(data[i+4 : end]); o != 0 {
+				return o
+			}
+		}
+
+		i = end
+	}
+
+	return 0
+}
+
+// Returns the key of the object to serve for the size, falling back to the next larger variant and then the
+// original. Variants are only generated for sizes smaller than the original, so the original is the best fit
+// whenever the requested variant does not exist.
+func SelectKey(m types.Media, variants []types.MediaVariant, size string) string {
+	if size == "" || size == SizeOriginal {
+		return m.Key
+	}
+
+	byName := make(map[string]types.MediaVariant, len(variants))
+	for _, v := range variants {
+		byName[v.Name] = v
+	}
+
+	requested := false
+
+	for _, s := range Sizes {
+		if s.Name == size {
+			requested = true
+		}
+
+		if v, ok := byName[s.Name]; ok && requested {
+			return v.Key
+		}
+	}
+
+	return m.Key
+}
+
+// Returns the path of the endpoint redirecting to the media, which accepts a size query parameter
+func FileURL(id uuid.UUID) string {
+	return "/media/" + id.String() + "/file"
+}
@@ -0,0 +1,42 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"clawmark/config"
+)
+
+// A place uploaded media is stored, objects are immutable and addressed by key
+type Storage interface {
+	// Stores the object, replacing any object with the same key
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+
+	// Opens the object for reading
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Returns whether an object with the key exists
+	Exists(ctx context.Context, key string) (bool, error)
+
+	Delete(ctx context.Context, key string) error
+
+	// Returns a URL of the object for media anyone may see
+	URL(key string) string
+
+	// Returns a URL of the object which stops working after expiry, for media not everyone may see
+	SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+}
+
+// Creates the storage backend selected in the config
+func NewStorage(cfg config.Media) (Storage, error) {
+	switch cfg.Backend {
+	case "local":
+		return NewLocalStorage(cfg.Local)
+	case "s3":
+		return NewS3Storage(cfg.S3)
+	}
+
+	return nil, fmt.Errorf("unknown media backend %q", cfg.Backend)
+}
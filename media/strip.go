@@ -0,0 +1,254 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Removes metadata (EXIF, XMP, IPTC, text chunks) from an image without re-encoding it
+//
+// The EXIF orientation of JPEGs is kept so the image is still displayed the right way up. GIFs carry no
+// location metadata and are returned unchanged, as is any other content type.
+func Strip(contentType string, data []byte) ([]byte, error) {
+	switch contentType {
+	case "image/jpeg":
+		return stripJPEG(data)
+	case "image/png":
+		return stripPNG(data)
+	case "image/webp":
+		return stripWebP(data)
+	}
+
+	return data, nil
+}
+
+const (
+	jpegSOS  = 0xda
+	jpegEOI  = 0xd9
+	jpegAPP1 = 0xe1
+	jpegCOM  = 0xfe
+)
+
+// Segments which may hold personal data: EXIF and XMP (APP1), IPTC (APP13) and comments
+func dropJPEGSegment(marker byte) bool {
+	return marker == jpegAPP1 || marker == 0xed || marker == jpegCOM
+}
+
+func stripJPEG(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xff || data[1] != 0xd8 {
+		return nil, ErrCorrupt
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:2])
+
+	kept := false
+
+	for i := 2; ; {
+		if i+4 > len(data) || data[i] != 0xff {
+			return nil, ErrCorrupt
+		}
+
+		marker := data[i+1]
+
+		// Fill bytes
+		if marker == 0xff {
+			i++
+			continue
+		}
+
+		// Markers without a length
+		if marker == jpegEOI || (marker >= 0xd0 && marker <= 0xd7) {
+			out.Write(data[i : i+2])
+			i += 2
+			continue
+		}
+
+		end := i + 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+
+		if end > len(data) || end < i+4 {
+			return nil, ErrCorrupt
+		}
+
+		switch {
+		case !dropJPEGSegment(marker):
+			out.Write(data[i:end])
+		case marker == jpegAPP1 && !kept:
+			// The EXIF block is replaced in place by one holding only the orientation
+			if o := exifOrientation(data[i+4 : end]); o > 1 {
+				out.Write(orientationSegment(o))
+				kept = true
+			}
+		}
+
+		// Everything after the start of scan is entropy coded image data
+		if marker == jpegSOS {
+			out.Write(data[end:])
+			return out.Bytes(), nil
+		}
+
+		i = end
+	}
+}
+
+// Returns the orientation tag (1-8) of an APP1 payload, or 0 if it is not EXIF or has none
+func exifOrientation(payload []byte) int {
+	if !bytes.HasPrefix(payload, []byte("Exif\x00\x00")) {
+		return 0
+	}
+
+	tiff := payload[6:]
+
+	if len(tiff) < 8 {
+		return 0
+	}
+
+	var order binary.ByteOrder
+
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+
+	if ifd+2 > len(tiff) {
+		return 0
+	}
+
+	entries := int(order.Uint16(tiff[ifd:]))
+
+	for n := 0; n < entries; n++ {
+		entry := ifd + 2 + n*12
+
+		if entry+12 > len(tiff) {
+			return 0
+		}
+
+		// Orientation, stored as a SHORT
+		if order.Uint16(tiff[entry:]) == 0x0112 && order.Uint16(tiff[entry+2:]) == 3 {
+			o := int(order.Uint16(tiff[entry+8:]))
+
+			if o < 1 || o > 8 {
+				return 0
+			}
+
+			return o
+		}
+	}
+
+	return 0
+}
+
+// Returns an APP1 segment holding an EXIF block with nothing but the orientation
+func orientationSegment(orientation int) []byte {
+	tiff := []byte{
+		'M', 'M', 0, 42, 0, 0, 0, 8, // header, IFD0 at offset 8
+		0, 1, // one entry
+		0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, byte(orientation), 0, 0, // orientation SHORT
+		0, 0, 0, 0, // no next IFD
+	}
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+
+	seg := []byte{0xff, jpegAPP1, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(payload)+2))
+
+	return append(seg, payload...)
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// Ancillary chunks which may hold personal data
+var pngDropped = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"tIME": true,
+}
+
+func stripPNG(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, ErrCorrupt
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(pngSignature)
+
+	for i := len(pngSignature); i < len(data); {
+		if i+8 > len(data) {
+			return nil, ErrCorrupt
+		}
+
+		// Length, type, data and CRC
+		end := i + 12 + int(binary.BigEndian.Uint32(data[i:]))
+
+		if end > len(data) || end < i {
+			return nil, ErrCorrupt
+		}
+
+		if !pngDropped[string(data[i+4:i+8])] {
+			out.Write(data[i:end])
+		}
+
+		i = end
+	}
+
+	return out.Bytes(), nil
+}
+
+const (
+	webpFlagEXIF = 0x08
+	webpFlagXMP  = 0x04
+)
+
+func stripWebP(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, ErrCorrupt
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:12])
+
+	for i := 12; i < len(data); {
+		if i+8 > len(data) {
+			return nil, ErrCorrupt
+		}
+
+		fourcc := string(data[i : i+4])
+		size := int(binary.LittleEndian.Uint32(data[i+4:]))
+
+		// Chunks are padded to an even size, some encoders leave out the padding of the last one
+		end := min(i+8+size+size&1, len(data))
+
+		if i+8+size > len(data) || end < i {
+			return nil, ErrCorrupt
+		}
+
+		switch fourcc {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := bytes.Clone(data[i:end])
+
+			if len(chunk) > 8 {
+				chunk[8] &^= webpFlagEXIF | webpFlagXMP
+			}
+
+			out.Write(chunk)
+		default:
+			out.Write(data[i:end])
+		}
+
+		i = end
+	}
+
+	res := out.Bytes()
+	binary.LittleEndian.PutUint32(res[4:], uint32(len(res)-8))
+
+	return res, nil
+}
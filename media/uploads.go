@@ -0,0 +1,238 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"clawmark/state"
+	"clawmark/types"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Resumable uploads are staged on disk and tracked in Redis, clients create
+// an upload with its total size and then send it in chunks, each starting at
+// the offset the server has so far. A dropped connection only loses the
+// current chunk.
+
+// Header carrying the offset of a chunk, and the current offset in responses
+const OffsetHeader = "Upload-Offset"
+
+const (
+	uploadKeyPrefix = "media:upload:"
+
+	// Held while a chunk is written, expires in case the instance dies mid-chunk
+	lockTimeout = time.Minute
+)
+
+var (
+	ErrUploadNotFound = errors.New("upload not found")
+	ErrUploadBusy     = errors.New("a chunk is already being written to this upload")
+)
+
+// Returned when a chunk does not start where the upload left off
+type OffsetError struct {
+	Expected int64
+}
+
+func (e OffsetError) Error() string {
+	return "chunk must start at offset " + strconv.FormatInt(e.Expected, 10)
+}
+
+type Upload struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Size      int64
+	Offset    int64
+	ExpiresAt time.Time
+}
+
+func uploadKey(id uuid.UUID) string {
+	return uploadKeyPrefix + id.String()
+}
+
+func stagingPath(id uuid.UUID) string {
+	return filepath.Join(state.Config.Media.StagingDir, id.String())
+}
+
+// Starts a resumable upload of size bytes
+func CreateUpload(ctx context.Context, userID uuid.UUID, size int64) (*Upload, error) {
+	cfg := state.CurrentConfig().Media
+
+	if size > cfg.MaxSize {
+		return nil, ErrTooLarge
+	}
+
+	if size < 1 {
+		return nil, ErrEmpty
+	}
+
+	u := &Upload{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Size:      size,
+		ExpiresAt: time.Now().Add(cfg.UploadExpiry),
+	}
+
+	f, err := os.Create(stagingPath(u.ID))
+
+	if err != nil {
+		return nil, err
+	}
+
+	f.Close()
+
+	_, err = state.Redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, uploadKey(u.ID), map[string]any{
+			"user_id":    userID.String(),
+			"size":       size,
+			"expires_at": u.ExpiresAt.Unix(),
+		})
+		pipe.ExpireAt(ctx, uploadKey(u.ID), u.ExpiresAt)
+		return nil
+	})
+
+	if err != nil {
+		os.Remove(stagingPath(u.ID))
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// Returns an upload of the user, ErrUploadNotFound if it does not exist, has expired or belongs to someone else
+func GetUpload(ctx context.Context, userID, id uuid.UUID) (*Upload, error) {
+	fields, err := state.Redis.HGetAll(ctx, uploadKey(id)).Result()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(fields) == 0 || fields["user_id"] != userID.String() {
+		return nil, ErrUploadNotFound
+	}
+
+	size, _ := strconv.ParseInt(fields["size"], 10, 64)
+	expiresAt, _ := strconv.ParseInt(fields["expires_at"], 10, 64)
+
+	info, err := os.Stat(stagingPath(id))
+
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrUploadNotFound
+		}
+
+		return nil, err
+	}
+
+	return &Upload{
+		ID:        id,
+		UserID:    userID,
+		Size:      size,
+		Offset:    info.Size(),
+		ExpiresAt: time.Unix(expiresAt, 0),
+	}, nil
+}
+
+// Appends a chunk starting at offset to the upload. Once the upload is complete it is stored
+// and the resulting media is returned
+func AppendUpload(ctx context.Context, userID, id uuid.UUID, offset int64, r io.Reader) (*Upload, *types.Media, error) {
+	lock := uploadKey(id) + ":lock"
+
+	acquired, err := state.Redis.SetNX(ctx, lock, 1, lockTimeout).Result()
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !acquired {
+		return nil, nil, ErrUploadBusy
+	}
+
+	defer state.Redis.Del(context.WithoutCancel(ctx), lock)
+
+	u, err := GetUpload(ctx, userID, id)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if offset != u.Offset {
+		return nil, nil, OffsetError{Expected: u.Offset}
+	}
+
+	f, err := os.OpenFile(stagingPath(id), os.O_RDWR|os.O_APPEND, 0)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer f.Close()
+
+	remaining := u.Size - u.Offset
+	n, err := io.Copy(f, io.LimitReader(r, remaining+1))
+
+	if n > remaining {
+		// Keep what was there before this chunk so the client can retry it
+		f.Truncate(u.Offset)
+		return nil, nil, ErrTooLarge
+	}
+
+	// Whatever arrived before a dropped connection is kept, the client resumes from the new offset
+	u.Offset += n
+
+	if err != nil {
+		return u, nil, err
+	}
+
+	if u.Offset < u.Size {
+		return u, nil, nil
+	}
+
+	m, err := storeFile(ctx, userID, f, u.Size)
+
+	var typeErr TypeError
+	if err != nil && !errors.As(err, &typeErr) && !errors.Is(err, ErrCorrupt) {
+		// Storage or database failures can be retried by sending an empty chunk at the final offset
+		return u, nil, err
+	}
+
+	state.Redis.Del(ctx, uploadKey(id))
+	f.Close()
+	os.Remove(stagingPath(id))
+
+	return u, m, err
+}
+
+// Removes staging files of expired uploads
+func sweep() {
+	dir := state.Config.Media.StagingDir
+	expiry := state.CurrentConfig().Media.UploadExpiry
+
+	entries, err := os.ReadDir(dir)
+
+	if err != nil {
+		state.Logger.Error("[media] Failed to read staging directory", zap.Error(err))
+		return
+	}
+
+	for _, entry := range entries {
+		info, err := entry.Info()
+
+		// Files still being written to are recent, anything untouched for longer than the expiry belongs to an expired upload
+		if err != nil || time.Since(info.ModTime()) < expiry {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			state.Logger.Error("[media] Failed to remove expired upload", zap.Error(err), zap.String("file", entry.Name()))
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package mentions
+
+import (
+	"context"
+	"reflect"
+
+	"clawmark/webhooks"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GORM plugin updating the tags and mentions of posts whenever they are created or their content is saved,
+// register with db.Use
+//
+// Mentions are resolved in the same transaction as the post, so a post failing to save has no mentions. Their webhooks
+// are deferred with webhooks.Defer until the post has committed, register the webhooks plugin as well.
+// Updates only resolve mentions of posts whose IDs are known to the statement, i.e. when updating a loaded model.
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string {
+	return "mentions"
+}
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+
+	if err := cb.Create().Before("gorm:create").Register("mentions:before_create", tagCreated); err != nil {
+		return err
+	}
+
+	if err := cb.Update().Before("gorm:update").Register("mentions:before_update", tagUpdated); err != nil {
+		return err
+	}
+
+	if err := cb.Create().After("gorm:create").Register("mentions:after_create", syncCreated); err != nil {
+		return err
+	}
+
+	return cb.Update().After("gorm:update").Register("mentions:after_update", syncUpdated)
+}
+
+func isPost(db *gorm.DB) bool {
+	return db.Error == nil && db.Statement.Schema != nil && db.Statement.Schema.Table == "posts"
+}
+
+func statementContext(db *gorm.DB) context.Context {
+	if db.Statement.Context != nil {
+		return db.Statement.Context
+	}
+
+	return context.Background()
+}
+
+// Calls fn with every model in the statement
+func eachModel(db *gorm.DB, fn func(v reflect.Value)) {
+	rv := reflect.Indirect(db.Statement.ReflectValue)
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		fn(rv)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			fn(reflect.Indirect(rv.Index(i)))
+		}
+	}
+}
+
+func tagCreated(db *gorm.DB) {
+	if !isPost(db) {
+		return
+	}
+
+	ctx := statementContext(db)
+	content := db.Statement.Schema.LookUpField("Content")
+	tags := db.Statement.Schema.LookUpField("Tags")
+
+	if content == nil || tags == nil {
+		return
+	}
+
+	eachModel(db, func(v reflect.Value) {
+		c, _ := content.ValueOf(ctx, v)
+		t, _ := tags.ValueOf(ctx, v)
+
+		s, _ := c.(string)
+		existing, _ := t.([]string)
+
+		if err := tags.Set(ctx, v, Tags(s, existing)); err != nil {
+			db.AddError(err)
+		}
+	})
+}
+
+// Returns the content and tags being saved by an update, ok is false if the content is not updated
+func updatedContent(db *gorm.DB) (content string, tags []string, ok bool) {
+	switch dest := db.Statement.Dest.(type) {
+	case map[string]any:
+		for _, key := range []string{"content", "Content"} {
+			if content, ok = dest[key].(string); ok {
+				break
+			}
+		}
+
+		for _, key := range []string{"tags", "Tags"} {
+			if t, found := dest[key].([]string); found {
+				tags = t
+			}
+		}
+
+		return content, tags, ok
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(db.Statement.Dest))
+
+	if v.Kind() != reflect.Struct || v.Type() != db.Statement.Schema.ModelType {
+		return "", nil, false
+	}
+
+	return fieldValues(db, v)
+}
+
+func fieldValues(db *gorm.DB, v reflect.Value) (content string, tags []string, ok bool) {
+	ctx := statementContext(db)
+	contentField := db.Statement.Schema.LookUpField("Content")
+	tagsField := db.Statement.Schema.LookUpField("Tags")
+
+	if contentField == nil || tagsField == nil {
+		return "", nil, false
+	}
+
+	c, zero := contentField.ValueOf(ctx, v)
+
+	// Updating with a struct skips zero fields, so an empty content is never saved
+	if zero {
+		return "", nil, false
+	}
+
+	t, _ := tagsField.ValueOf(ctx, v)
+	content, _ = c.(string)
+	tags, _ = t.([]string)
+
+	return content, tags, true
+}
+
+func tagUpdated(db *gorm.DB) {
+	if !isPost(db) {
+		return
+	}
+
+	content, tags, ok := updatedContent(db)
+
+	if !ok {
+		return
+	}
+
+	db.Statement.SetColumn("Tags", Tags(content, tags))
+}
+
+func syncCreated(db *gorm.DB) {
+	if !isPost(db) {
+		return
+	}
+
+	sync(db)
+}
+
+func syncUpdated(db *gorm.DB) {
+	if !isPost(db) {
+		return
+	}
+
+	if _, _, ok := updatedContent(db); !ok {
+		return
+	}
+
+	sync(db)
+}
+
+// Resolves the mentions of every post in the statement, within its transaction
+func sync(db *gorm.DB) {
+	ctx := statementContext(db)
+	field := db.Statement.Schema.LookUpField("ID")
+
+	if field == nil {
+		return
+	}
+
+	tx := db.Session(&gorm.Session{NewDB: true})
+
+	eachModel(db, func(v reflect.Value) {
+		value, zero := field.ValueOf(ctx, v)
+
+		if zero || db.Error != nil {
+			return
+		}
+
+		if id, ok := value.(uuid.UUID); ok {
+			events, err := Sync(ctx, tx, id)
+
+			if err != nil {
+				db.AddError(err)
+				return
+			}
+
+			webhooks.Defer(db, events...)
+		}
+	})
+}
@@ -0,0 +1,128 @@
+// Hashtags and mentions of posts
+//
+// Whenever a post is created or its content is edited, the hashtags in the
+// content are added to Post.Tags and the @usernames are resolved to users.
+// Users mentioned for the first time in a post are notified, removing a
+// mention from the content removes it from the post but not the notification.
+package mentions
+
+import (
+	"context"
+	"slices"
+
+	"clawmark/blocks"
+	"clawmark/notifications"
+	"clawmark/posts"
+	"clawmark/posttext"
+	"clawmark/state"
+	"clawmark/types"
+	"clawmark/webhooks"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Registers the GORM plugin keeping posts in sync, state.Setup must be called first
+func Setup() error {
+	return state.Pool.Use(GormPlugin{})
+}
+
+// Returns the tags of a post with the content, the tags given by the client are kept and the hashtags in the content added
+func Tags(content string, tags []string) []string {
+	return posttext.MergeTags(tags, posttext.Hashtags(content))
+}
+
+// Resolves the mentions in the content of a post and stores them with db, which may be a transaction.
+// Newly mentioned users are notified, the returned webhook events must be published once db has committed
+func Sync(ctx context.Context, db *gorm.DB, postID uuid.UUID) ([]webhooks.Event, error) {
+	db = db.WithContext(ctx)
+
+	var post types.Post
+	err := db.Preload("User").Select("id", "user_id", "content", "visibility").First(&post, "id = ?", postID).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	var users []types.User
+
+	if usernames := posttext.Mentions(post.Content); len(usernames) > 0 {
+		// Authors mentioning themselves are not mentions, and users blocked by or blocking the author cannot be mentioned
+		query := db.Select("id").Scopes(blocks.ExcludeBlocked(post.UserID, "users.id")).
+			Where("lower(username) IN ? AND id <> ?", usernames, post.UserID)
+
+		// Only users who can read the post are mentioned, so the notification does not reveal it
+		err = query.Scopes(posts.Audience(post, "users.id")).Find(&users).Error
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var existing []uuid.UUID
+	err = db.Model(&types.Mention{}).Where("post_id = ?", postID).Pluck("user_id", &existing).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	mentioned := make([]uuid.UUID, 0, len(users))
+	for _, u := range users {
+		mentioned = append(mentioned, u.ID)
+	}
+
+	removed := db.Where("post_id = ?", postID)
+	if len(mentioned) > 0 {
+		removed = removed.Where("user_id NOT IN ?", mentioned)
+	}
+
+	if err := removed.Delete(&types.Mention{}).Error; err != nil {
+		return nil, err
+	}
+
+	var added []types.Mention
+	var notified []types.Notification
+
+	for _, id := range mentioned {
+		if slices.Contains(existing, id) {
+			continue
+		}
+
+		added = append(added, types.Mention{PostID: postID, UserID: id})
+		notified = append(notified, types.Notification{
+			UserID:  id,
+			ActorID: post.UserID,
+			Type:    notifications.TypeMention,
+			PostID:  &postID,
+		})
+	}
+
+	if len(added) == 0 {
+		return nil, nil
+	}
+
+	if err := db.Create(&added).Error; err != nil {
+		return nil, err
+	}
+
+	if err := notifications.Create(db, notified...); err != nil {
+		return nil, err
+	}
+
+	event := types.MentionedEvent{
+		PostID: postID,
+		Author: types.PartialUser{
+			ID:        post.User.ID,
+			Username:  post.User.Username,
+			AvatarURL: post.User.AvatarURL,
+		},
+		Content: post.Content,
+	}
+
+	events := make([]webhooks.Event, 0, len(added))
+	for _, m := range added {
+		events = append(events, webhooks.Event{UserID: m.UserID, Name: webhooks.EventMentioned, Data: event})
+	}
+
+	return events, nil
+}
@@ -0,0 +1,73 @@
+// Prometheus metrics for the API, storage and feed generation
+//
+// Metrics are registered on the default registry and served at /metrics
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"clawmark/uapi"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "clawmark"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of API requests by operation, method and status code (0 if the request was cancelled)",
+	}, []string{"op_id", "method", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Time taken to handle API requests by operation and method",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"op_id", "method"})
+
+	FeedCandidatesScored = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "feed",
+		Name:      "candidates_scored_total",
+		Help:      "Number of candidate posts scored for personalized feeds",
+	})
+
+	FeedPersonalization = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "feed",
+		Name:      "personalization_total",
+		Help:      "Personalized feed lookups by result, hit if any personalized posts were found, miss if none were and error if the lookup failed",
+	}, []string{"result"})
+
+	FeedDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "feed",
+		Name:      "generation_duration_seconds",
+		Help:      "Time taken by GetUserFeed",
+		Buckets:   prometheus.DefBuckets,
+	})
+)
+
+// Records a handled request, set as uapi.UAPIState.ObserveRequest
+func ObserveRequest(r uapi.Route, req *http.Request, status int, elapsed time.Duration) {
+	method := r.Method.String()
+
+	httpRequests.WithLabelValues(r.OpId, method, strconv.Itoa(status)).Inc()
+	httpDuration.WithLabelValues(r.OpId, method).Observe(elapsed.Seconds())
+}
+
+// Returns the handler serving /metrics
+func Handler() http.Handler {
+	// Responses are already compressed by the API middleware
+	return promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{
+		DisableCompression: true,
+	})
+}
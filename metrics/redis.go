@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	redisCommands = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "redis",
+		Name:      "commands_total",
+		Help:      "Number of Redis commands by command and result",
+	}, []string{"command", "result"})
+
+	redisDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "redis",
+		Name:      "command_duration_seconds",
+		Help:      "Time taken by Redis commands, pipelines are recorded as a single pipeline command",
+		Buckets:   []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 5},
+	}, []string{"command"})
+
+	redisDialErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "redis",
+		Name:      "dial_errors_total",
+		Help:      "Number of failed Redis connection attempts",
+	})
+)
+
+// go-redis hook recording command counts and timings, register with client.AddHook
+type RedisHook struct{}
+
+func (RedisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := next(ctx, network, addr)
+
+		if err != nil {
+			redisDialErrors.Inc()
+		}
+
+		return conn, err
+	}
+}
+
+func (RedisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+
+		observeRedis(cmd.Name(), err, time.Since(start))
+
+		return err
+	}
+}
+
+func (RedisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+
+		observeRedis("pipeline", err, time.Since(start))
+
+		return err
+	}
+}
+
+func observeRedis(command string, err error, elapsed time.Duration) {
+	result := "ok"
+	// Nil is a missing key (or a blocking pop timing out), not a failure
+	if err != nil && !errors.Is(err, redis.Nil) {
+		result = "error"
+	}
+
+	redisCommands.WithLabelValues(command, result).Inc()
+	redisDuration.WithLabelValues(command).Observe(elapsed.Seconds())
+}
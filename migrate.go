@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"clawmark/migrations"
+	"clawmark/state"
+
+	"go.uber.org/zap"
+)
+
+const migrateUsage = `Usage: clawmark [--config path] migrate <up|down|status> [--dry-run] [--steps n]
+
+  up      Applies all pending migrations
+  down    Reverts the last --steps applied migrations (default 1)
+  status  Lists all migrations and whether they have been applied
+`
+
+// Runs the migrate subcommand
+func migrate(configPath string, args []string) {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "Log the SQL that would be run without running it")
+	steps := fs.Int("steps", 1, "Number of migrations to revert with down")
+	fs.Usage = func() { fmt.Fprint(os.Stderr, migrateUsage) }
+	fs.Parse(args[1:])
+
+	state.LoadConfig(configPath)
+	state.Logger = state.CreateLogger()
+	state.ConnectDatabase()
+
+	opts := migrations.Options{
+		DryRun: *dryRun,
+		Logger: state.Logger,
+	}
+
+	switch args[0] {
+	case "up":
+		done, err := migrations.Up(state.Context, state.Pool, opts)
+
+		if err != nil {
+			state.Logger.Fatal("Failed to apply migrations", zap.Error(err))
+		}
+
+		state.Logger.Info("Migrations applied", zap.Int("count", len(done)), zap.Bool("dryRun", *dryRun))
+	case "down":
+		done, err := migrations.Down(state.Context, state.Pool, *steps, opts)
+
+		if err != nil {
+			state.Logger.Fatal("Failed to revert migrations", zap.Error(err))
+		}
+
+		state.Logger.Info("Migrations reverted", zap.Int("count", len(done)), zap.Bool("dryRun", *dryRun))
+	case "status":
+		status, err := migrations.Status(state.Context, state.Pool)
+
+		if err != nil {
+			state.Logger.Fatal("Failed to get migration status", zap.Error(err))
+		}
+
+		for _, s := range status {
+			applied := "pending"
+			if s.Applied {
+				applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+
+			fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, applied)
+		}
+	default:
+		fmt.Fprint(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,393 @@
+// Versioned SQL migrations
+//
+// Migrations are embedded from sql/ and named <version>_<name>.up.sql and
+// <version>_<name>.down.sql. Applied versions are tracked in schema_migrations
+// and a postgres advisory lock ensures only one process (e.g. one of several
+// tableflip children) migrates at a time.
+package migrations
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+//go:embed sql/*.sql
+var sqlFS embed.FS
+
+// Arbitrary key for pg_advisory_lock, must be the same across all instances
+const lockKey int64 = 0x636c61776d61726b // "clawmark"
+
+const createTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version bigint PRIMARY KEY,
+	name text NOT NULL,
+	applied_at timestamptz NOT NULL DEFAULT now()
+)`
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+type Options struct {
+	// Log the SQL that would be run without running it
+	DryRun bool
+	Logger *zap.Logger
+}
+
+func (o Options) logger() *zap.Logger {
+	if o.Logger == nil {
+		return zap.NewNop()
+	}
+
+	return o.Logger
+}
+
+// Returns all embedded migrations, sorted by version
+func Load() ([]Migration, error) {
+	files, err := fs.Glob(sqlFS, "sql/*.sql")
+
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+
+	for _, file := range files {
+		base := path.Base(file)
+
+		var direction string
+		switch {
+		case strings.HasSuffix(base, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(base, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("migration %s must end in .up.sql or .down.sql", base)
+		}
+
+		versionStr, name, ok := strings.Cut(strings.TrimSuffix(base, "."+direction+".sql"), "_")
+
+		if !ok {
+			return nil, fmt.Errorf("migration %s must be named <version>_<name>", base)
+		}
+
+		version, err := strconv.ParseInt(versionStr, 10, 64)
+
+		if err != nil {
+			return nil, fmt.Errorf("migration %s has an invalid version: %w", base, err)
+		}
+
+		data, err := sqlFS.ReadFile(file)
+
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration version %d is used by both %s and %s", version, m.Name, name)
+		}
+
+		if direction == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up migration", m.Version, m.Name)
+		}
+
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+type appliedRow struct {
+	Version   int64
+	AppliedAt time.Time
+}
+
+// Returns every migration along with whether it has been applied
+func Status(ctx context.Context, db *gorm.DB) ([]MigrationStatus, error) {
+	migrations, err := Load()
+
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedVersions(db.WithContext(ctx))
+
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, 0, len(migrations))
+
+	for _, m := range migrations {
+		s := MigrationStatus{Migration: m}
+
+		if at, ok := applied[m.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = &at
+		}
+
+		status = append(status, s)
+	}
+
+	return status, nil
+}
+
+// Returns the number of migrations which have not been applied yet
+func Pending(ctx context.Context, db *gorm.DB) (int, error) {
+	status, err := Status(ctx, db)
+
+	if err != nil {
+		return 0, err
+	}
+
+	pending := 0
+	for _, s := range status {
+		if !s.Applied {
+			pending++
+		}
+	}
+
+	return pending, nil
+}
+
+func appliedVersions(db *gorm.DB) (map[int64]time.Time, error) {
+	var exists bool
+	err := db.Raw("SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	applied := map[int64]time.Time{}
+
+	if !exists {
+		return applied, nil
+	}
+
+	var rows []appliedRow
+	err = db.Raw("SELECT version, applied_at FROM schema_migrations").Scan(&rows).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		applied[row.Version] = row.AppliedAt
+	}
+
+	return applied, nil
+}
+
+// Runs fn on a single connection holding the migration advisory lock
+func withLock(ctx context.Context, db *gorm.DB, fn func(conn *gorm.DB) error) error {
+	return db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		err := conn.Exec("SELECT pg_advisory_lock(?)", lockKey).Error
+
+		if err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+
+		defer conn.Exec("SELECT pg_advisory_unlock(?)", lockKey)
+
+		err = conn.Exec(createTable).Error
+
+		if err != nil {
+			return fmt.Errorf("failed to create schema_migrations: %w", err)
+		}
+
+		return fn(conn)
+	})
+}
+
+// Applies all pending migrations, returning the migrations that were (or in dry-run mode, would be) applied
+func Up(ctx context.Context, db *gorm.DB, opts Options) ([]Migration, error) {
+	migrations, err := Load()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.DryRun {
+		applied, err := appliedVersions(db.WithContext(ctx))
+
+		if err != nil {
+			return nil, err
+		}
+
+		var pending []Migration
+		for _, m := range migrations {
+			if _, ok := applied[m.Version]; !ok {
+				opts.logger().Info("[migrations] Would apply migration", zap.Int64("version", m.Version), zap.String("name", m.Name), zap.String("sql", m.Up))
+				pending = append(pending, m)
+			}
+		}
+
+		return pending, nil
+	}
+
+	var done []Migration
+
+	err = withLock(ctx, db, func(conn *gorm.DB) error {
+		// Re-read under the lock, another process may have migrated while we waited
+		applied, err := appliedVersions(conn)
+
+		if err != nil {
+			return err
+		}
+
+		for _, m := range migrations {
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+
+			opts.logger().Info("[migrations] Applying migration", zap.Int64("version", m.Version), zap.String("name", m.Name))
+
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(m.Up).Error; err != nil {
+					return err
+				}
+
+				return tx.Exec("INSERT INTO schema_migrations (version, name) VALUES (?, ?)", m.Version, m.Name).Error
+			})
+
+			if err != nil {
+				return fmt.Errorf("migration %d_%s failed: %w", m.Version, m.Name, err)
+			}
+
+			done = append(done, m)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+// Reverts the last steps applied migrations, returning the migrations that were (or in dry-run mode, would be) reverted
+func Down(ctx context.Context, db *gorm.DB, steps int, opts Options) ([]Migration, error) {
+	if steps < 1 {
+		return nil, fmt.Errorf("steps must be at least 1")
+	}
+
+	migrations, err := Load()
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Returns the applied migrations to revert, newest first
+	toRevert := func(applied map[int64]time.Time) ([]Migration, error) {
+		var out []Migration
+
+		for i := len(migrations) - 1; i >= 0 && len(out) < steps; i-- {
+			m := migrations[i]
+
+			if _, ok := applied[m.Version]; !ok {
+				continue
+			}
+
+			if m.Down == "" {
+				return nil, fmt.Errorf("migration %d_%s has no down migration", m.Version, m.Name)
+			}
+
+			out = append(out, m)
+		}
+
+		return out, nil
+	}
+
+	if opts.DryRun {
+		applied, err := appliedVersions(db.WithContext(ctx))
+
+		if err != nil {
+			return nil, err
+		}
+
+		revert, err := toRevert(applied)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range revert {
+			opts.logger().Info("[migrations] Would revert migration", zap.Int64("version", m.Version), zap.String("name", m.Name), zap.String("sql", m.Down))
+		}
+
+		return revert, nil
+	}
+
+	var done []Migration
+
+	err = withLock(ctx, db, func(conn *gorm.DB) error {
+		applied, err := appliedVersions(conn)
+
+		if err != nil {
+			return err
+		}
+
+		revert, err := toRevert(applied)
+
+		if err != nil {
+			return err
+		}
+
+		for _, m := range revert {
+			opts.logger().Info("[migrations] Reverting migration", zap.Int64("version", m.Version), zap.String("name", m.Name))
+
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(m.Down).Error; err != nil {
+					return err
+				}
+
+				return tx.Exec("DELETE FROM schema_migrations WHERE version = ?", m.Version).Error
+			})
+
+			if err != nil {
+				return fmt.Errorf("reverting migration %d_%s failed: %w", m.Version, m.Name, err)
+			}
+
+			done = append(done, m)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
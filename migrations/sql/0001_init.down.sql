@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS follows;
+DROP TABLE IF EXISTS dislikes;
+DROP TABLE IF EXISTS likes;
+DROP TABLE IF EXISTS comments;
+DROP TABLE IF EXISTS post_plugins;
+DROP TABLE IF EXISTS posts;
+DROP TABLE IF EXISTS users;
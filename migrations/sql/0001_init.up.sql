@@ -0,0 +1,85 @@
+-- Tables are created with IF NOT EXISTS so databases previously set up by
+-- GORM AutoMigrate can be adopted without changes
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+CREATE TABLE IF NOT EXISTS users (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at timestamptz,
+    updated_at timestamptz,
+    username text NOT NULL,
+    email text NOT NULL,
+    password text NOT NULL,
+    avatar_url text DEFAULT '',
+    bio text
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+
+CREATE TABLE IF NOT EXISTS posts (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at timestamptz,
+    updated_at timestamptz,
+    user_id uuid NOT NULL REFERENCES users (id),
+    content text NOT NULL,
+    tags text[]
+);
+
+CREATE INDEX IF NOT EXISTS idx_posts_user_id ON posts (user_id);
+
+CREATE TABLE IF NOT EXISTS post_plugins (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at timestamptz,
+    updated_at timestamptz,
+    post_id uuid NOT NULL REFERENCES posts (id),
+    type text NOT NULL,
+    url text NOT NULL,
+    html text
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_plugins_post_id ON post_plugins (post_id);
+
+CREATE TABLE IF NOT EXISTS comments (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at timestamptz,
+    updated_at timestamptz,
+    user_id uuid NOT NULL REFERENCES users (id),
+    post_id uuid NOT NULL REFERENCES posts (id),
+    content text NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_comments_user_id ON comments (user_id);
+CREATE INDEX IF NOT EXISTS idx_comments_post_id ON comments (post_id);
+
+CREATE TABLE IF NOT EXISTS likes (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at timestamptz,
+    updated_at timestamptz,
+    user_id uuid NOT NULL REFERENCES users (id),
+    post_id uuid NOT NULL REFERENCES posts (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_likes_user_id ON likes (user_id);
+CREATE INDEX IF NOT EXISTS idx_likes_post_id ON likes (post_id);
+
+CREATE TABLE IF NOT EXISTS dislikes (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at timestamptz,
+    updated_at timestamptz,
+    user_id uuid NOT NULL REFERENCES users (id),
+    post_id uuid NOT NULL REFERENCES posts (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_dislikes_user_id ON dislikes (user_id);
+CREATE INDEX IF NOT EXISTS idx_dislikes_post_id ON dislikes (post_id);
+
+CREATE TABLE IF NOT EXISTS follows (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at timestamptz,
+    updated_at timestamptz,
+    follower_id uuid NOT NULL REFERENCES users (id),
+    following_id uuid NOT NULL REFERENCES users (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_follows_follower_id ON follows (follower_id);
+CREATE INDEX IF NOT EXISTS idx_follows_following_id ON follows (following_id);
@@ -0,0 +1,3 @@
+ALTER TABLE post_plugins DROP COLUMN IF EXISTS media_id;
+
+DROP TABLE IF EXISTS media;
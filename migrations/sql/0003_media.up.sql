@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS media (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at timestamptz,
+    updated_at timestamptz,
+    user_id uuid NOT NULL REFERENCES users (id),
+    hash text NOT NULL,
+    key text NOT NULL,
+    content_type text NOT NULL,
+    size bigint NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_media_user_id ON media (user_id);
+CREATE INDEX IF NOT EXISTS idx_media_hash ON media (hash);
+
+ALTER TABLE post_plugins ADD COLUMN IF NOT EXISTS media_id uuid REFERENCES media (id);
+
+CREATE INDEX IF NOT EXISTS idx_post_plugins_media_id ON post_plugins (media_id);
@@ -0,0 +1,14 @@
+ALTER TABLE post_plugins
+    DROP COLUMN IF EXISTS width,
+    DROP COLUMN IF EXISTS height,
+    DROP COLUMN IF EXISTS blurhash;
+
+DROP TABLE IF EXISTS media_variants;
+
+DROP INDEX IF EXISTS idx_media_status;
+
+ALTER TABLE media
+    DROP COLUMN IF EXISTS status,
+    DROP COLUMN IF EXISTS width,
+    DROP COLUMN IF EXISTS height,
+    DROP COLUMN IF EXISTS blurhash;
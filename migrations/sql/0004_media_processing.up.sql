@@ -0,0 +1,28 @@
+ALTER TABLE media
+    ADD COLUMN IF NOT EXISTS status text NOT NULL DEFAULT 'pending',
+    ADD COLUMN IF NOT EXISTS width integer,
+    ADD COLUMN IF NOT EXISTS height integer,
+    ADD COLUMN IF NOT EXISTS blurhash text NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_media_status ON media (status) WHERE status = 'pending';
+
+CREATE TABLE IF NOT EXISTS media_variants (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at timestamptz,
+    updated_at timestamptz,
+    media_id uuid NOT NULL REFERENCES media (id) ON DELETE CASCADE,
+    name text NOT NULL,
+    key text NOT NULL,
+    content_type text NOT NULL,
+    width integer NOT NULL,
+    height integer NOT NULL,
+    size bigint NOT NULL,
+    UNIQUE (media_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_media_variants_media_id ON media_variants (media_id);
+
+ALTER TABLE post_plugins
+    ADD COLUMN IF NOT EXISTS width integer,
+    ADD COLUMN IF NOT EXISTS height integer,
+    ADD COLUMN IF NOT EXISTS blurhash text NOT NULL DEFAULT '';
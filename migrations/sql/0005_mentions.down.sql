@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_users_username_lower;
+
+DROP TABLE IF EXISTS notifications;
+
+DROP TABLE IF EXISTS mentions;
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS mentions (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at timestamptz,
+    updated_at timestamptz,
+    post_id uuid NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mentions_post_user ON mentions (post_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_mentions_user_id ON mentions (user_id);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at timestamptz,
+    updated_at timestamptz,
+    user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    actor_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    type text NOT NULL,
+    post_id uuid REFERENCES posts (id) ON DELETE CASCADE,
+    read_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_notifications_unread ON notifications (user_id) WHERE read_at IS NULL;
+
+-- Mentions are resolved case-insensitively
+CREATE INDEX IF NOT EXISTS idx_users_username_lower ON users (lower(username));
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_posts_created_at_id;
+
+DROP INDEX IF EXISTS idx_posts_tags;
+
+DROP TABLE IF EXISTS tag_follows;
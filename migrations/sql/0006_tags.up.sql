@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS tag_follows (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at timestamptz,
+    updated_at timestamptz,
+    user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    tag text NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tag_follows_user_tag ON tag_follows (user_id, tag);
+CREATE INDEX IF NOT EXISTS idx_tag_follows_tag ON tag_follows (tag);
+
+-- Tag pages filter with tags @> ARRAY[tag] and page by creation time
+CREATE INDEX IF NOT EXISTS idx_posts_tags ON posts USING gin (tags);
+CREATE INDEX IF NOT EXISTS idx_posts_created_at_id ON posts (created_at DESC, id DESC);
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS blocks (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at timestamptz,
+    updated_at timestamptz,
+    blocker_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    blocked_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    CHECK (blocker_id <> blocked_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_blocks_blocker_blocked ON blocks (blocker_id, blocked_id);
+CREATE INDEX IF NOT EXISTS idx_blocks_blocked_id ON blocks (blocked_id);
+
+CREATE TABLE IF NOT EXISTS mutes (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at timestamptz,
+    updated_at timestamptz,
+    muter_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    muted_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    CHECK (muter_id <> muted_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mutes_muter_muted ON mutes (muter_id, muted_id);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_follows_follower_following;
+
+DROP TABLE IF EXISTS follow_requests;
+
+DROP INDEX IF EXISTS idx_users_private;
+
+ALTER TABLE users DROP COLUMN IF EXISTS private;
@@ -0,0 +1,21 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS private boolean NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_users_private ON users (id) WHERE private;
+
+CREATE TABLE IF NOT EXISTS follow_requests (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at timestamptz,
+    updated_at timestamptz,
+    requester_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    target_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    CHECK (requester_id <> target_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_follow_requests_requester_target ON follow_requests (requester_id, target_id);
+CREATE INDEX IF NOT EXISTS idx_follow_requests_target_id ON follow_requests (target_id);
+
+-- Follows are now created idempotently, duplicates left by earlier code are removed first
+DELETE FROM follows a USING follows b
+    WHERE a.follower_id = b.follower_id AND a.following_id = b.following_id AND a.id > b.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_follows_follower_following ON follows (follower_id, following_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS close_friends;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS visibility;
@@ -0,0 +1,14 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS visibility text NOT NULL DEFAULT 'public'
+    CHECK (visibility IN ('public', 'followers', 'mutuals', 'close_friends', 'unlisted'));
+
+CREATE TABLE IF NOT EXISTS close_friends (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at timestamptz,
+    updated_at timestamptz,
+    user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    friend_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    CHECK (user_id <> friend_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_close_friends_user_friend ON close_friends (user_id, friend_id);
+CREATE INDEX IF NOT EXISTS idx_close_friends_friend_id ON close_friends (friend_id);
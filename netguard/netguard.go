@@ -0,0 +1,99 @@
+// Guards outgoing requests to user supplied URLs
+//
+// Link previews and webhook deliveries connect to hosts chosen by users, which
+// must never reach loopback, private or link-local addresses of our network.
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"syscall"
+)
+
+var ErrBlocked = errors.New("address is not allowed")
+
+// Special purpose ranges not covered by the netip predicates
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),   // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // Documentation
+	netip.MustParsePrefix("198.18.0.0/15"),   // Benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // Documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // Documentation
+	netip.MustParsePrefix("240.0.0.0/4"),     // Reserved
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64, may map to private IPv4 addresses
+	netip.MustParsePrefix("2001:db8::/32"),   // Documentation
+}
+
+// Returns whether outgoing requests may connect to the address, only public unicast addresses are allowed
+func Allowed(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast() {
+		return false
+	}
+
+	for _, p := range blockedPrefixes {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Returns a net.Dialer Control function rejecting connections to disallowed addresses, and to ports other than
+// the given ones if any are given. It runs after DNS resolution, so hostnames resolving (or rebinding) to internal
+// addresses are caught as well as literal IPs
+func Control(ports ...string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, _ syscall.RawConn) error {
+		host, port, err := net.SplitHostPort(address)
+
+		if err != nil {
+			return err
+		}
+
+		addr, err := netip.ParseAddr(host)
+
+		if err != nil {
+			return err
+		}
+
+		if (len(ports) > 0 && !slices.Contains(ports, port)) || !Allowed(addr) {
+			return fmt.Errorf("%w: %s", ErrBlocked, address)
+		}
+
+		return nil
+	}
+}
+
+// Resolves the host and fails with ErrBlocked if any of its addresses is disallowed, for rejecting URLs early.
+// Connections must still be made with Control as the host may resolve differently later
+func CheckHost(ctx context.Context, host string) error {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !Allowed(addr) {
+			return fmt.Errorf("%w: %s", ErrBlocked, host)
+		}
+
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+
+	if err != nil {
+		return err
+	}
+
+	for _, addr := range addrs {
+		if !Allowed(addr) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrBlocked, host, addr)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+// In-app notifications, listed by the notifications routes
+package notifications
+
+import (
+	"clawmark/types"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// Someone mentioned the user in a post
+	TypeMention = "mention"
+
+	// Someone followed the user
+	TypeFollow = "follow"
+
+	// Someone asked to follow the private account of the user
+	TypeFollowRequest = "follow_request"
+)
+
+// Creates notifications with db, which may be a transaction. Notifications of users about their own actions are skipped
+func Create(db *gorm.DB, notifications ...types.Notification) error {
+	var filtered []types.Notification
+
+	for _, n := range notifications {
+		if n.UserID != n.ActorID {
+			filtered = append(filtered, n)
+		}
+	}
+
+	if len(filtered) == 0 {
+		return nil
+	}
+
+	return db.Create(&filtered).Error
+}
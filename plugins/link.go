@@ -0,0 +1,43 @@
+package plugins
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"clawmark/types"
+
+	"github.com/google/uuid"
+)
+
+func buildLink(ctx context.Context, userID uuid.UUID, in types.CreatePlugin, p *types.PostPlugin) error {
+	u, err := url.Parse(in.URL)
+
+	if in.URL == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ValidationError{Field: "URL", Message: "URL must be a valid http(s) URL"}
+	}
+
+	title := strings.TrimSpace(in.Title)
+	if title == "" {
+		title = u.String()
+	}
+
+	p.URL = u.String()
+	p.HTML = element("a", title, "href", p.URL, "rel", "nofollow noopener noreferrer ugc", "target", "_blank")
+
+	if in.Image != "" {
+		img, err := url.Parse(in.Image)
+
+		if err != nil || img.Scheme != "https" || img.Host == "" {
+			return ValidationError{Field: "Image", Message: "Image must be a valid https URL"}
+		}
+
+		p.HTML += element("img", "", "src", img.String(), "alt", "", "loading", "lazy", "decoding", "async")
+	}
+
+	if description := strings.TrimSpace(userPolicy.Sanitize(in.Description)); description != "" {
+		p.HTML += "<div>" + description + "</div>"
+	}
+
+	return nil
+}
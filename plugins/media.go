@@ -0,0 +1,51 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+
+	"clawmark/media"
+	"clawmark/state"
+	"clawmark/types"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Returns the builder of a plugin type backed by uploaded media
+func buildMedia(pluginType string) func(ctx context.Context, userID uuid.UUID, in types.CreatePlugin, p *types.PostPlugin) error {
+	return func(ctx context.Context, userID uuid.UUID, in types.CreatePlugin, p *types.PostPlugin) error {
+		if in.MediaID == nil {
+			return ValidationError{Field: "MediaID", Message: "A media ID is required"}
+		}
+
+		var m types.Media
+		err := state.Pool.WithContext(ctx).Where("id = ? AND user_id = ?", *in.MediaID, userID).First(&m).Error
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ValidationError{Field: "MediaID", Message: "Media not found"}
+		}
+
+		if err != nil {
+			return err
+		}
+
+		// Animated GIFs are never resized, so they must not end up in image plugins where clients expect variants
+		if (pluginType == TypeGIF) != (m.ContentType == "image/gif") && pluginType != TypeSticker {
+			if pluginType == TypeGIF {
+				return ValidationError{Field: "MediaID", Message: "GIF plugins must use a GIF"}
+			}
+
+			return ValidationError{Field: "MediaID", Message: "GIFs must be attached as gif or sticker plugins"}
+		}
+
+		p.URL = media.FileURL(m.ID)
+		p.MediaID = &m.ID
+		p.Width = m.Width
+		p.Height = m.Height
+		p.Blurhash = m.Blurhash
+		p.HTML = element("img", "", "src", p.URL, "alt", in.Title, "loading", "lazy", "decoding", "async")
+
+		return nil
+	}
+}
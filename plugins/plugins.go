@@ -0,0 +1,187 @@
+// Post plugins, content embedded in posts such as images and videos
+//
+// Every plugin type validates its own input and renders the HTML clients
+// display, so no HTML written by users is stored as is. Anything user
+// provided that ends up in the HTML is escaped or, for descriptions,
+// sanitized against a strict allowlist. Stored HTML is sanitized again
+// whenever it is returned.
+package plugins
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"clawmark/state"
+	"clawmark/types"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	TypeImage   = "image"
+	TypeGIF     = "gif"
+	TypeSticker = "sticker"
+	TypeYouTube = "youtube"
+	TypeLink    = "link"
+)
+
+// Maximum number of plugins a post may have
+const MaxPerPost = 10
+
+var (
+	ErrPostNotFound = errors.New("post not found")
+	ErrTooMany      = errors.New("too many plugins")
+)
+
+// Returned when the input is not valid for the plugin type
+type ValidationError struct {
+	// The CreatePlugin field which is invalid
+	Field   string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return e.Message
+}
+
+type pluginType struct {
+	Name        string
+	Description string
+
+	// Validates the input and fills in the URL, HTML and any other fields of the plugin
+	Build func(ctx context.Context, userID uuid.UUID, in types.CreatePlugin, p *types.PostPlugin) error
+}
+
+var pluginTypes = []pluginType{
+	{
+		Name:        TypeImage,
+		Description: "An uploaded image, set `media_id` and optionally `title` as alt text.",
+		Build:       buildMedia(TypeImage),
+	},
+	{
+		Name:        TypeGIF,
+		Description: "An uploaded GIF, set `media_id` and optionally `title` as alt text.",
+		Build:       buildMedia(TypeGIF),
+	},
+	{
+		Name:        TypeSticker,
+		Description: "An uploaded sticker, set `media_id` and optionally `title` as alt text.",
+		Build:       buildMedia(TypeSticker),
+	},
+	{
+		Name:        TypeYouTube,
+		Description: "A YouTube video, set `url` to a youtube.com or youtu.be link and optionally `title`.",
+		Build:       buildYouTube,
+	},
+	{
+		Name:        TypeLink,
+		Description: "A link, set `url` and optionally `title`, `description` and `image`. Links in post content are added automatically with a preview of the page.",
+		Build:       buildLink,
+	},
+}
+
+// Returns the names of all plugin types
+func Types() []string {
+	names := make([]string, 0, len(pluginTypes))
+	for _, t := range pluginTypes {
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+// Returns whether the plugin type is supported
+func IsType(name string) bool {
+	return slices.Contains(Types(), name)
+}
+
+// Returns every plugin type with its description
+func List() types.PluginTypeList {
+	list := types.PluginTypeList{Types: make([]types.PluginType, 0, len(pluginTypes))}
+
+	for _, t := range pluginTypes {
+		list.Types = append(list.Types, types.PluginType{
+			Name:        t.Name,
+			Description: t.Description,
+		})
+	}
+
+	return list
+}
+
+func get(name string) (pluginType, bool) {
+	for _, t := range pluginTypes {
+		if t.Name == name {
+			return t, true
+		}
+	}
+
+	return pluginType{}, false
+}
+
+// Validates the input with its plugin type and adds the plugin to a post of the user
+func Create(ctx context.Context, userID, postID uuid.UUID, in types.CreatePlugin) (*types.PostPlugin, error) {
+	t, ok := get(in.Type)
+
+	if !ok {
+		return nil, ValidationError{Field: "Type", Message: "Unknown plugin type: " + in.Type}
+	}
+
+	var post types.Post
+	err := state.Pool.WithContext(ctx).Select("id").Where("id = ? AND user_id = ?", postID, userID).First(&post).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPostNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var count int64
+	err = state.Pool.WithContext(ctx).Model(&types.PostPlugin{}).Where("post_id = ?", postID).Count(&count).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	if count >= MaxPerPost {
+		return nil, ErrTooMany
+	}
+
+	p := types.PostPlugin{
+		PostID: postID,
+		Type:   t.Name,
+	}
+
+	if err := t.Build(ctx, userID, in, &p); err != nil {
+		return nil, err
+	}
+
+	if err := state.Pool.WithContext(ctx).Create(&p).Error; err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// Converts a stored plugin for a response, the HTML of unknown types is dropped and everything else is sanitized again
+func ToPlugin(p types.PostPlugin) types.Plugin {
+	html := ""
+	if IsType(p.Type) {
+		html = outputPolicy.Sanitize(p.HTML)
+	}
+
+	return types.Plugin{
+		ID:       p.ID,
+		PostID:   p.PostID,
+		Type:     p.Type,
+		URL:      p.URL,
+		HTML:     html,
+		MediaID:  p.MediaID,
+		Width:    p.Width,
+		Height:   p.Height,
+		Blurhash: p.Blurhash,
+	}
+}
@@ -0,0 +1,80 @@
+package plugins
+
+import (
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+var (
+	// Applied to HTML written by users, basic formatting and links only
+	userPolicy = formattingPolicy()
+
+	// Applied to the stored HTML of every plugin before it is returned, allows exactly what the plugin types render
+	outputPolicy = renderedPolicy()
+)
+
+var youtubeEmbed = regexp.MustCompile(`^https://www\.youtube-nocookie\.com/embed/[A-Za-z0-9_-]{11}(\?start=[0-9]+)?$`)
+
+func formattingPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+
+	p.AllowElements("p", "br", "b", "i", "em", "strong", "u", "s", "code", "pre", "blockquote", "ul", "ol", "li")
+
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowURLSchemes("http", "https")
+	p.RequireParseableURLs(true)
+	p.RequireNoFollowOnLinks(true)
+	p.RequireNoReferrerOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+
+	return p
+}
+
+func renderedPolicy() *bluemonday.Policy {
+	p := formattingPolicy()
+
+	p.AllowElements("div")
+	p.AllowAttrs("target").Matching(regexp.MustCompile(`^_blank$`)).OnElements("a")
+
+	// Media is served through a relative redirect
+	p.AllowRelativeURLs(true)
+	p.AllowAttrs("src", "alt").OnElements("img")
+	p.AllowAttrs("loading").Matching(regexp.MustCompile(`^lazy$`)).OnElements("img", "iframe")
+	p.AllowAttrs("decoding").Matching(regexp.MustCompile(`^async$`)).OnElements("img")
+
+	p.AllowAttrs("src").Matching(youtubeEmbed).OnElements("iframe")
+	p.AllowAttrs("title").OnElements("iframe")
+	p.AllowAttrs("width", "height").Matching(bluemonday.Integer).OnElements("iframe")
+	p.AllowAttrs("sandbox").Matching(regexp.MustCompile(`^[a-z -]*$`)).OnElements("iframe")
+	p.AllowAttrs("allow").Matching(regexp.MustCompile(`^[a-z-; ]*$`)).OnElements("iframe")
+	p.AllowAttrs("referrerpolicy").Matching(regexp.MustCompile(`^[a-z-]+$`)).OnElements("iframe")
+	p.AllowAttrs("allowfullscreen").OnElements("iframe")
+
+	return p
+}
+
+// Renders an element, escaping attribute values and the text content. Attributes are given as name, value pairs
+func element(tag, text string, attrs ...string) string {
+	var b strings.Builder
+
+	b.WriteString("<" + tag)
+
+	for i := 0; i+1 < len(attrs); i += 2 {
+		b.WriteString(" " + attrs[i] + `="` + html.EscapeString(attrs[i+1]) + `"`)
+	}
+
+	b.WriteString(">")
+
+	// Void elements have no content or closing tag
+	if tag == "img" || tag == "br" {
+		return b.String()
+	}
+
+	b.WriteString(html.EscapeString(text))
+	b.WriteString("</" + tag + ">")
+
+	return b.String()
+}
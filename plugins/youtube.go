@@ -0,0 +1,99 @@
+package plugins
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"clawmark/types"
+
+	"github.com/google/uuid"
+)
+
+var youtubeID = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+var youtubeHosts = map[string]bool{
+	"youtube.com":              true,
+	"www.youtube.com":          true,
+	"m.youtube.com":            true,
+	"music.youtube.com":        true,
+	"youtube-nocookie.com":     true,
+	"www.youtube-nocookie.com": true,
+}
+
+// Returns the video ID and start time in seconds of a YouTube link
+func parseYouTube(raw string) (id string, start int, ok bool) {
+	u, err := url.Parse(raw)
+
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", 0, false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	path := strings.Trim(u.Path, "/")
+
+	switch {
+	case host == "youtu.be":
+		id = path
+	case youtubeHosts[host] && path == "watch":
+		id = u.Query().Get("v")
+	case youtubeHosts[host]:
+		// /shorts/<id>, /embed/<id>, /live/<id>
+		prefix, rest, found := strings.Cut(path, "/")
+
+		if found && (prefix == "shorts" || prefix == "embed" || prefix == "live") {
+			id = rest
+		}
+	}
+
+	if !youtubeID.MatchString(id) {
+		return "", 0, false
+	}
+
+	t := u.Query().Get("t")
+	if t == "" {
+		t = u.Query().Get("start")
+	}
+
+	// Only plain seconds are kept, e.g. t=90 or t=90s
+	start, _ = strconv.Atoi(strings.TrimSuffix(t, "s"))
+
+	return id, max(start, 0), true
+}
+
+func buildYouTube(ctx context.Context, userID uuid.UUID, in types.CreatePlugin, p *types.PostPlugin) error {
+	id, start, ok := parseYouTube(in.URL)
+
+	if !ok {
+		return ValidationError{Field: "URL", Message: "URL must be a YouTube video link"}
+	}
+
+	title := in.Title
+	if title == "" {
+		title = "YouTube video"
+	}
+
+	p.URL = "https://www.youtube.com/watch?v=" + id
+	embed := "https://www.youtube-nocookie.com/embed/" + id
+
+	if start > 0 {
+		p.URL += "&t=" + strconv.Itoa(start)
+		embed += "?start=" + strconv.Itoa(start)
+	}
+
+	p.HTML = element("iframe", "",
+		"src", embed,
+		"title", title,
+		"width", "560",
+		"height", "315",
+		"loading", "lazy",
+		"sandbox", "allow-scripts allow-same-origin allow-presentation allow-popups",
+		"allow", "encrypted-media; picture-in-picture; fullscreen",
+		"referrerpolicy", "strict-origin-when-cross-origin",
+		"allowfullscreen", "",
+	)
+
+	return nil
+}
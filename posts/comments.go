@@ -0,0 +1,39 @@
+package posts
+
+import (
+	"clawmark/types"
+
+	"gorm.io/gorm"
+)
+
+// Like Paginate, for comments
+func PaginateComments(db *gorm.DB, cursor string, limit int) (*gorm.DB, error) {
+	return paginate(db, "comments", cursor, limit)
+}
+
+// Converts the comments fetched by a query from PaginateComments into a page, their authors must be loaded
+func CommentPage(found []types.Comment, limit int) types.CommentList {
+	list := types.CommentList{Comments: []types.CommentEntry{}}
+
+	if len(found) > limit {
+		found = found[:limit]
+		last := found[len(found)-1]
+		list.NextCursor = cursor(last.CreatedAt, last.ID)
+	}
+
+	for _, c := range found {
+		list.Comments = append(list.Comments, types.CommentEntry{
+			ID:     c.ID,
+			PostID: c.PostID,
+			Author: types.PartialUser{
+				ID:        c.User.ID,
+				Username:  c.User.Username,
+				AvatarURL: c.User.AvatarURL,
+			},
+			Content:   c.Content,
+			CreatedAt: c.CreatedAt,
+		})
+	}
+
+	return list
+}
@@ -0,0 +1,129 @@
+// Posts as returned by the API
+//
+// Lists of posts are paginated with opaque cursors pointing after the last
+// post of a page, so posts created while paging do not shift later pages.
+package posts
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"clawmark/plugins"
+	"clawmark/types"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Loads the author and plugins of the posts, which ToPost needs
+func Preload(db *gorm.DB) *gorm.DB {
+	return db.Preload("User").Preload("PostPlugins", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at")
+	})
+}
+
+// Converts a post loaded with Preload for a response
+func ToPost(p types.Post) types.PostEntry {
+	entry := types.PostEntry{
+		ID: p.ID,
+		Author: types.PartialUser{
+			ID:        p.User.ID,
+			Username:  p.User.Username,
+			AvatarURL: p.User.AvatarURL,
+		},
+		Content:    p.Content,
+		Tags:       p.Tags,
+		Visibility: p.Visibility,
+		Plugins:    make([]types.Plugin, 0, len(p.PostPlugins)),
+		CreatedAt:  p.CreatedAt,
+		UpdatedAt:  p.UpdatedAt,
+	}
+
+	if entry.Tags == nil {
+		entry.Tags = []string{}
+	}
+
+	for _, plugin := range p.PostPlugins {
+		entry.Plugins = append(entry.Plugins, plugins.ToPlugin(plugin))
+	}
+
+	return entry
+}
+
+// Returns the cursor of the page after the row created at the time with the ID
+func cursor(createdAt time.Time, id uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(createdAt.UnixMicro(), 10) + "_" + id.String()))
+}
+
+func parseCursor(s string) (time.Time, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+
+	micros, id, ok := strings.Cut(string(raw), "_")
+
+	if !ok {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+
+	t, err := strconv.ParseInt(micros, 10, 64)
+
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+
+	postID, err := uuid.Parse(id)
+
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+
+	return time.UnixMicro(t), postID, nil
+}
+
+// Orders the query newest first and limits it to the page after cursor, which may be empty for the first page.
+// One post more than limit is fetched to tell whether there is a next page, pass the result to Page.
+// Returns ErrInvalidCursor if the cursor is malformed
+func Paginate(db *gorm.DB, cursor string, limit int) (*gorm.DB, error) {
+	return paginate(db, "posts", cursor, limit)
+}
+
+func paginate(db *gorm.DB, table string, cursor string, limit int) (*gorm.DB, error) {
+	db = db.Order(table + ".created_at DESC, " + table + ".id DESC").Limit(limit + 1)
+
+	if cursor == "" {
+		return db, nil
+	}
+
+	createdAt, id, err := parseCursor(cursor)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return db.Where("("+table+".created_at, "+table+".id) < (?, ?)", createdAt, id), nil
+}
+
+// Converts the posts fetched by a query from Paginate into a page
+func Page(found []types.Post, limit int) types.PostList {
+	list := types.PostList{Posts: []types.PostEntry{}}
+
+	if len(found) > limit {
+		found = found[:limit]
+		last := found[len(found)-1]
+		list.NextCursor = cursor(last.CreatedAt, last.ID)
+	}
+
+	for _, p := range found {
+		list.Posts = append(list.Posts, ToPost(p))
+	}
+
+	return list
+}
@@ -0,0 +1,95 @@
+package posts
+
+import (
+	"clawmark/types"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Who can read a post, besides its author. Posts of private accounts are
+// only ever readable by approved followers, whatever their visibility
+const (
+	// Anyone
+	VisibilityPublic = "public"
+
+	// Followers of the author
+	VisibilityFollowers = "followers"
+
+	// Followers of the author whom the author follows back
+	VisibilityMutuals = "mutuals"
+
+	// Users on the close friends list of the author
+	VisibilityCloseFriends = "close_friends"
+
+	// Anyone with the link, but left out of feeds, tag pages and search
+	VisibilityUnlisted = "unlisted"
+)
+
+// Returns the names of all visibility levels
+func Visibilities() []string {
+	return []string{VisibilityPublic, VisibilityFollowers, VisibilityMutuals, VisibilityCloseFriends, VisibilityUnlisted}
+}
+
+// Subqueries of the users related to the viewer
+const (
+	followedSQL     = "SELECT following_id FROM follows WHERE follower_id = @viewer"
+	followersSQL    = "SELECT follower_id FROM follows WHERE following_id = @viewer"
+	closeFriendsSQL = "SELECT user_id FROM close_friends WHERE friend_id = @viewer"
+)
+
+const visibleSQL = "(posts.user_id = @viewer OR (" +
+	"(posts.user_id NOT IN (SELECT id FROM users WHERE private) OR posts.user_id IN (" + followedSQL + ")) AND (" +
+	"posts.visibility IN ('" + VisibilityPublic + "', '" + VisibilityUnlisted + "') OR " +
+	"(posts.visibility = '" + VisibilityFollowers + "' AND posts.user_id IN (" + followedSQL + ")) OR " +
+	"(posts.visibility = '" + VisibilityMutuals + "' AND posts.user_id IN (" + followedSQL + ") AND posts.user_id IN (" + followersSQL + ")) OR " +
+	"(posts.visibility = '" + VisibilityCloseFriends + "' AND posts.user_id IN (" + closeFriendsSQL + "))" +
+	")))"
+
+// Scope leaving out posts the viewer may not read, for fetching posts directly. Listings use Listed instead
+func VisibleTo(viewerID uuid.UUID) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(visibleSQL, map[string]any{"viewer": viewerID})
+	}
+}
+
+// Scope leaving out posts the viewer may not read and unlisted posts. Every query listing posts to a user must use it
+func Listed(viewerID uuid.UUID) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Scopes(VisibleTo(viewerID)).Where("posts.visibility <> ?", VisibilityUnlisted)
+	}
+}
+
+// Scope leaving out posts not everyone may read, including anonymous users
+func Public() func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("posts.visibility IN ? AND posts.user_id NOT IN (SELECT id FROM users WHERE private)", []string{VisibilityPublic, VisibilityUnlisted})
+	}
+}
+
+// Scope leaving out users who may not read the post, column is the ID column of the users. The author of the post
+// must be loaded
+func Audience(post types.Post, column string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		author := map[string]any{"author": post.UserID}
+		followers := column + " IN (SELECT follower_id FROM follows WHERE following_id = @author)"
+
+		if post.User.Private {
+			db = db.Where(followers, author)
+		}
+
+		switch post.Visibility {
+		case VisibilityPublic, VisibilityUnlisted:
+			return db
+		case VisibilityFollowers:
+			return db.Where(followers, author)
+		case VisibilityMutuals:
+			return db.Where(followers+" AND "+column+" IN (SELECT following_id FROM follows WHERE follower_id = @author)", author)
+		case VisibilityCloseFriends:
+			return db.Where(column+" IN (SELECT friend_id FROM close_friends WHERE user_id = @author)", author)
+		}
+
+		// Unknown levels are readable by no one but the author
+		return db.Where("false")
+	}
+}
@@ -0,0 +1,153 @@
+package posts
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"clawmark/types"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// A session building statements without running them, the DSN is never connected to
+func dryRun(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost dbname=test"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+var placeholder = regexp.MustCompile(`\$\d+`)
+
+// Returns the SQL, with placeholders replaced by ?, and vars of a query for a post with the scopes applied. Scopes
+// are applied last, so the ID of the post is always the first var
+func build(t *testing.T, scopes ...func(*gorm.DB) *gorm.DB) (string, []any) {
+	t.Helper()
+
+	stmt := dryRun(t).Scopes(scopes...).Where("posts.id = ?", "post").Find(&[]types.Post{}).Statement
+	return placeholder.ReplaceAllString(stmt.SQL.String(), "?"), stmt.Vars
+}
+
+func TestVisibleSQL(t *testing.T) {
+	depth := 0
+
+	for i, c := range visibleSQL {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+
+		// The whole condition is one group, so it cannot be split by the ORs when combined with other conditions
+		if depth == 0 && i != len(visibleSQL)-1 {
+			t.Fatalf("parentheses close early at %d in %q", i, visibleSQL)
+		}
+	}
+
+	if depth != 0 {
+		t.Fatalf("unbalanced parentheses in %q", visibleSQL)
+	}
+
+	for _, v := range Visibilities() {
+		if !strings.Contains(visibleSQL, "'"+v+"'") {
+			t.Errorf("visibility %q is not handled", v)
+		}
+	}
+
+	// Posts of private accounts need the viewer to follow the author, whatever their visibility
+	if !strings.Contains(visibleSQL, "(posts.user_id NOT IN (SELECT id FROM users WHERE private) OR posts.user_id IN ("+followedSQL+")) AND (") {
+		t.Errorf("private accounts are not checked for every visibility in %q", visibleSQL)
+	}
+}
+
+func TestVisibleTo(t *testing.T) {
+	viewer := uuid.New()
+	sql, vars := build(t, VisibleTo(viewer))
+
+	tests := []string{
+		"(posts.user_id = ? OR",
+		"posts.visibility IN ('public', 'unlisted')",
+		"posts.visibility = 'followers' AND posts.user_id IN (SELECT following_id FROM follows WHERE follower_id = ?",
+		"posts.visibility = 'mutuals'",
+		"SELECT follower_id FROM follows WHERE following_id = ?",
+		"posts.visibility = 'close_friends' AND posts.user_id IN (SELECT user_id FROM close_friends WHERE friend_id = ?",
+	}
+
+	for _, want := range tests {
+		if !strings.Contains(sql, want) {
+			t.Errorf("expected %q in %q", want, sql)
+		}
+	}
+
+	// Only the viewer is bound by the scope
+	if len(vars) < 2 {
+		t.Fatalf("expected the post and viewer to be bound, got %v", vars)
+	}
+
+	for i, v := range vars[1:] {
+		if v != viewer {
+			t.Errorf("expected var %d to be the viewer, got %v", i+1, v)
+		}
+	}
+
+	if strings.Contains(sql, "<> 'unlisted'") || strings.Contains(sql, "visibility <>") {
+		t.Errorf("unlisted posts must stay visible when fetched directly: %q", sql)
+	}
+}
+
+func TestListed(t *testing.T) {
+	viewer := uuid.New()
+	visible, _ := build(t, VisibleTo(viewer))
+	sql, vars := build(t, Listed(viewer))
+
+	_, cond, ok := strings.Cut(visible, "WHERE posts.id = ? AND ")
+
+	if !ok || cond == "" {
+		t.Fatalf("unexpected VisibleTo query %q", visible)
+	}
+
+	if !strings.Contains(sql, cond) {
+		t.Errorf("expected the VisibleTo condition %q in %q", cond, sql)
+	}
+
+	if !strings.Contains(sql, "posts.visibility <> ?") {
+		t.Errorf("expected unlisted posts to be left out in %q", sql)
+	}
+
+	found := false
+	for _, v := range vars {
+		if v == VisibilityUnlisted {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Errorf("expected %q to be bound, got %v", VisibilityUnlisted, vars)
+	}
+}
+
+func TestPublic(t *testing.T) {
+	sql, vars := build(t, Public())
+
+	if !strings.Contains(sql, "posts.visibility IN (?,?) AND posts.user_id NOT IN (SELECT id FROM users WHERE private)") {
+		t.Errorf("unexpected condition in %q", sql)
+	}
+
+	if len(vars) != 3 || vars[1] != VisibilityPublic || vars[2] != VisibilityUnlisted {
+		t.Errorf("expected public and unlisted to be bound, got %v", vars)
+	}
+}
@@ -0,0 +1,125 @@
+// Parsing of hashtags and mentions in post content
+//
+// Both are Unicode-aware: tags and usernames may contain letters, marks and
+// numbers of any script. A # or @ only starts a tag or mention at the start
+// of the text or after a character which cannot be part of a word, so URL
+// fragments, HTML entities and email addresses are ignored.
+package posttext
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// Longer tags and usernames are ignored rather than truncated
+	MaxTagLength      = 64
+	MaxUsernameLength = 64
+
+	// Maximum number of tags kept per post
+	MaxTags = 30
+
+	// Maximum number of users mentioned per post, further mentions are not resolved
+	MaxMentions = 20
+)
+
+var (
+	hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{M}\p{N}_&/#])[#＃]([\p{L}\p{M}\p{N}_]+)`)
+	mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{M}\p{N}_.@/])[@＠]([\p{L}\p{M}\p{N}_.-]+)`)
+)
+
+// Returns the canonical form of a tag: NFKC normalized, lower case and without a leading #.
+// Returns an empty string if the tag is invalid
+func NormalizeTag(tag string) string {
+	tag = strings.ToLower(norm.NFKC.String(strings.TrimSpace(tag)))
+	tag = strings.TrimPrefix(tag, "#")
+
+	if tag == "" || utf8.RuneCountInString(tag) > MaxTagLength {
+		return ""
+	}
+
+	digits := true
+
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsMark(r) && !unicode.IsNumber(r) && r != '_' {
+			return ""
+		}
+
+		if !unicode.IsDigit(r) {
+			digits = false
+		}
+	}
+
+	// Numbers such as #1 are references, not tags
+	if digits {
+		return ""
+	}
+
+	return tag
+}
+
+// Returns the normalized hashtags in the text, deduplicated in the order they appear
+func Hashtags(text string) []string {
+	return MergeTags(nil, matches(hashtagPattern, text))
+}
+
+// Merges tags into existing ones, normalizing and deduplicating them and keeping at most MaxTags
+func MergeTags(existing []string, tags []string) []string {
+	merged := []string{}
+	seen := map[string]bool{}
+
+	for _, tag := range append(existing, tags...) {
+		tag = NormalizeTag(tag)
+
+		if tag == "" || seen[tag] {
+			continue
+		}
+
+		seen[tag] = true
+		merged = append(merged, tag)
+
+		if len(merged) == MaxTags {
+			break
+		}
+	}
+
+	return merged
+}
+
+// Returns the lower case usernames mentioned in the text, deduplicated in the order they appear
+func Mentions(text string) []string {
+	usernames := []string{}
+	seen := map[string]bool{}
+
+	for _, username := range matches(mentionPattern, text) {
+		// Dots and dashes ending a sentence are not part of the username
+		username = strings.ToLower(norm.NFKC.String(strings.TrimRight(username, ".-")))
+
+		if username == "" || utf8.RuneCountInString(username) > MaxUsernameLength || seen[username] {
+			continue
+		}
+
+		seen[username] = true
+		usernames = append(usernames, username)
+
+		if len(usernames) == MaxMentions {
+			break
+		}
+	}
+
+	return usernames
+}
+
+func matches(pattern *regexp.Regexp, text string) []string {
+	var found []string
+
+	for _, m := range pattern.FindAllStringSubmatch(text, -1) {
+		found = append(found, m[1])
+	}
+
+	return found
+}
@@ -0,0 +1,246 @@
+package previews
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"clawmark/netguard"
+	"clawmark/state"
+
+	"github.com/infinitybotlist/eureka/jsonimpl"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	maxRedirects = 3
+
+	// oEmbed responses are small, anything larger is not worth reading
+	maxOEmbedBytes = 64 * 1024
+)
+
+// Rejects connections to disallowed addresses and ports. Replaced to fetch from local servers
+var checkConn = netguard.Control("80", "443")
+
+var client = &http.Client{
+	Transport: &http.Transport{
+		// Never go through a proxy from the environment, the proxy would make the connection instead of us
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: func(network, address string, c syscall.RawConn) error {
+				return checkConn(network, address, c)
+			},
+		}).DialContext,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: 5 * time.Second,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return errors.New("too many redirects")
+		}
+
+		if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+			return fmt.Errorf("%w: redirect to %s", netguard.ErrBlocked, req.URL.Scheme)
+		}
+
+		return nil
+	},
+}
+
+// Fetches a URL, returning at most maxBytes of the body
+func get(ctx context.Context, u string, accept string, maxBytes int64) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req.Header.Set("User-Agent", state.CurrentConfig().Previews.UserAgent)
+	req.Header.Set("Accept", accept)
+
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes))
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return resp, body, nil
+}
+
+// Fetches the page and builds a preview from its OpenGraph, Twitter card and oEmbed metadata
+func fetch(ctx context.Context, u string) (Preview, error) {
+	cfg := state.CurrentConfig().Previews
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	resp, body, err := get(ctx, u, "text/html,application/xhtml+xml", cfg.MaxBytes)
+
+	if err != nil {
+		return Preview{}, err
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return Preview{}, fmt.Errorf("unsupported content type %q", contentType)
+	}
+
+	r, err := charset.NewReader(bytes.NewReader(body), contentType)
+
+	if err != nil {
+		return Preview{}, err
+	}
+
+	// Relative URLs are resolved against the final URL after redirects
+	base := resp.Request.URL
+	m := parseMeta(r)
+
+	p := Preview{
+		Title:       first(m["og:title"], m["twitter:title"], m["title"]),
+		Description: first(m["og:description"], m["twitter:description"], m["description"]),
+		Image:       resolveImage(base, first(m["og:image"], m["og:image:url"], m["twitter:image"], m["twitter:image:src"])),
+		SiteName:    m["og:site_name"],
+	}
+
+	if oembed := m["oembed"]; oembed != "" && (p.Title == "" || p.Image == "") {
+		if ref, err := base.Parse(oembed); err == nil {
+			o, err := fetchOEmbed(ctx, ref.String())
+
+			// oEmbed only fills gaps, the page is still previewed without it
+			if err == nil {
+				p.Title = first(p.Title, o.Title)
+				p.Image = first(p.Image, resolveImage(base, o.ThumbnailURL))
+				p.SiteName = first(p.SiteName, o.ProviderName)
+			}
+		}
+	}
+
+	return p.truncate(), nil
+}
+
+type oembed struct {
+	Title        string `json:"title"`
+	ProviderName string `json:"provider_name"`
+	ThumbnailURL string `json:"thumbnail_url"`
+}
+
+func fetchOEmbed(ctx context.Context, u string) (oembed, error) {
+	_, body, err := get(ctx, u, "application/json", maxOEmbedBytes)
+
+	if err != nil {
+		return oembed{}, err
+	}
+
+	var o oembed
+	err = jsonimpl.Unmarshal(body, &o)
+
+	return o, err
+}
+
+// Collects the metadata in the head of a page, keyed by meta property or name. The title element is stored
+// as "title" and the JSON oEmbed discovery link as "oembed"
+func parseMeta(r io.Reader) map[string]string {
+	m := map[string]string{}
+	z := html.NewTokenizer(r)
+	inTitle := false
+
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return m
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+
+			switch t.Data {
+			case "body":
+				return m
+			case "title":
+				inTitle = true
+			case "meta":
+				key := strings.ToLower(first(attr(t, "property"), attr(t, "name")))
+
+				if _, ok := m[key]; key != "" && !ok {
+					m[key] = strings.TrimSpace(attr(t, "content"))
+				}
+			case "link":
+				if strings.EqualFold(attr(t, "type"), "application/json+oembed") && m["oembed"] == "" {
+					m["oembed"] = attr(t, "href")
+				}
+			}
+		case html.TextToken:
+			if inTitle && m["title"] == "" {
+				m["title"] = strings.TrimSpace(string(z.Text()))
+			}
+		case html.EndTagToken:
+			switch z.Token().Data {
+			case "title":
+				inTitle = false
+			case "head":
+				return m
+			}
+		}
+	}
+}
+
+func attr(t html.Token, name string) string {
+	for _, a := range t.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+
+	return ""
+}
+
+// Returns the first non-empty value
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
+
+// Resolves an image URL against the page, only https images are kept so viewers never load them over plain HTTP
+func resolveImage(base *url.URL, image string) string {
+	if image == "" {
+		return ""
+	}
+
+	u, err := base.Parse(image)
+
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return ""
+	}
+
+	return u.String()
+}
@@ -0,0 +1,335 @@
+package previews
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"clawmark/config"
+	"clawmark/netguard"
+	"clawmark/state"
+)
+
+// Sets the previews config for the test
+func withConfig(t *testing.T, cfg config.Previews) {
+	t.Helper()
+
+	prev := state.Config
+	state.Config = &config.Config{Previews: cfg}
+	t.Cleanup(func() { state.Config = prev })
+}
+
+func defaultConfig() config.Previews {
+	return config.Previews{
+		Timeout:   2 * time.Second,
+		MaxBytes:  1 << 20,
+		UserAgent: "ClawmarkBot/test",
+	}
+}
+
+// Lets the fetcher connect to the servers, every other address is still checked by the real guard
+func allow(t *testing.T, servers ...*httptest.Server) {
+	t.Helper()
+
+	allowed := map[string]bool{}
+	for _, s := range servers {
+		allowed[s.Listener.Addr().String()] = true
+	}
+
+	prev := checkConn
+	checkConn = func(network, address string, c syscall.RawConn) error {
+		if allowed[address] {
+			return nil
+		}
+
+		return prev(network, address, c)
+	}
+
+	t.Cleanup(func() { checkConn = prev })
+}
+
+func TestCheckConn(t *testing.T) {
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{"93.184.216.34:80", true},
+		{"93.184.216.34:443", true},
+		{"[2606:2800:220:1:248:1893:25c8:1946]:443", true},
+		{"93.184.216.34:8080", false},
+		{"127.0.0.1:80", false},
+		{"[::1]:443", false},
+		{"10.0.0.1:80", false},
+		{"172.16.0.1:80", false},
+		{"192.168.1.1:443", false},
+		{"169.254.169.254:80", false},
+		{"[fe80::1]:80", false},
+		{"[fc00::1]:443", false},
+		{"0.0.0.0:80", false},
+		{"100.64.0.1:80", false},
+		{"[::ffff:127.0.0.1]:80", false},
+	}
+
+	for _, tt := range tests {
+		err := checkConn("tcp", tt.address, nil)
+
+		if tt.allowed && err != nil {
+			t.Errorf("%s: expected to be allowed, got %v", tt.address, err)
+		}
+
+		if !tt.allowed && !errors.Is(err, netguard.ErrBlocked) {
+			t.Errorf("%s: expected ErrBlocked, got %v", tt.address, err)
+		}
+	}
+}
+
+func TestFetchBlocksLoopback(t *testing.T) {
+	withConfig(t, defaultConfig())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the server should never be reached")
+	}))
+	defer srv.Close()
+
+	_, err := fetch(context.Background(), srv.URL)
+
+	if !errors.Is(err, netguard.ErrBlocked) {
+		t.Fatalf("expected ErrBlocked, got %v", err)
+	}
+}
+
+func TestFetchBlocksRedirectToPrivate(t *testing.T) {
+	withConfig(t, defaultConfig())
+
+	private := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the redirect target should never be reached")
+	}))
+	defer private.Close()
+
+	public := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, private.URL+"/latest/meta-data", http.StatusFound)
+	}))
+	defer public.Close()
+
+	allow(t, public)
+
+	_, err := fetch(context.Background(), public.URL)
+
+	if !errors.Is(err, netguard.ErrBlocked) {
+		t.Fatalf("expected ErrBlocked, got %v", err)
+	}
+}
+
+func TestFetchTooManyRedirects(t *testing.T) {
+	withConfig(t, defaultConfig())
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, srv.URL+"/again", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	allow(t, srv)
+
+	_, err := fetch(context.Background(), srv.URL)
+
+	if err == nil || !strings.Contains(err.Error(), "too many redirects") {
+		t.Fatalf("expected too many redirects, got %v", err)
+	}
+}
+
+func TestGetLimitsSize(t *testing.T) {
+	withConfig(t, defaultConfig())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("a", 64*1024)))
+	}))
+	defer srv.Close()
+
+	allow(t, srv)
+
+	_, body, err := get(context.Background(), srv.URL, "text/html", 100)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(body) != 100 {
+		t.Fatalf("expected 100 bytes, got %d", len(body))
+	}
+}
+
+func TestFetchIgnoresMetadataPastLimit(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.MaxBytes = 256
+	withConfig(t, cfg)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, `<html><head><title>Early</title><!-- %s --><meta property="og:title" content="Late"></head></html>`, strings.Repeat("x", 512))
+	}))
+	defer srv.Close()
+
+	allow(t, srv)
+
+	p, err := fetch(context.Background(), srv.URL)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Title != "Early" {
+		t.Fatalf("expected the title before the limit, got %q", p.Title)
+	}
+}
+
+func TestFetchTimesOut(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Timeout = 100 * time.Millisecond
+	withConfig(t, cfg)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	allow(t, srv)
+
+	start := time.Now()
+	_, err := fetch(context.Background(), srv.URL)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a deadline error, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("fetch took %s despite the timeout", elapsed)
+	}
+}
+
+func TestFetchOpenGraph(t *testing.T) {
+	withConfig(t, defaultConfig())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ua := r.Header.Get("User-Agent"); ua != "ClawmarkBot/test" {
+			t.Errorf("unexpected user agent %q", ua)
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<!doctype html>
+<html><head>
+<title>Fallback title</title>
+<meta property="og:title" content=" A &amp; B ">
+<meta property="og:description" content="The description">
+<meta name="twitter:description" content="Ignored">
+<meta property="og:image" content="https://cdn.example.com/a.png">
+<meta property="og:site_name" content="Example">
+</head><body><meta property="og:title" content="In the body"></body></html>`))
+	}))
+	defer srv.Close()
+
+	allow(t, srv)
+
+	p, err := fetch(context.Background(), srv.URL)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Preview{
+		Title:       "A & B",
+		Description: "The description",
+		Image:       "https://cdn.example.com/a.png",
+		SiteName:    "Example",
+	}
+
+	if p != want {
+		t.Fatalf("expected %+v, got %+v", want, p)
+	}
+}
+
+func TestFetchOEmbedFillsGaps(t *testing.T) {
+	withConfig(t, defaultConfig())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head>
+<meta name="twitter:title" content="Card title">
+<meta property="og:image" content="/relative.png">
+<link rel="alternate" type="application/json+oembed" href="/oembed?url=page">
+</head></html>`))
+	})
+	mux.HandleFunc("/oembed", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"title":"oEmbed title","provider_name":"Provider","thumbnail_url":"https://cdn.example.com/thumb.jpg"}`))
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	allow(t, srv)
+
+	p, err := fetch(context.Background(), srv.URL+"/page")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Plain HTTP images are dropped, so the oEmbed thumbnail is used
+	want := Preview{
+		Title:    "Card title",
+		Image:    "https://cdn.example.com/thumb.jpg",
+		SiteName: "Provider",
+	}
+
+	if p != want {
+		t.Fatalf("expected %+v, got %+v", want, p)
+	}
+}
+
+func TestFetchRejectsNonHTML(t *testing.T) {
+	withConfig(t, defaultConfig())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("\x89PNG"))
+	}))
+	defer srv.Close()
+
+	allow(t, srv)
+
+	if _, err := fetch(context.Background(), srv.URL); err == nil {
+		t.Fatal("expected an error for a non-HTML page")
+	}
+}
+
+// The dialer must refuse hosts resolving to internal addresses, not only literal IPs
+func TestFetchBlocksLocalhostName(t *testing.T) {
+	withConfig(t, defaultConfig())
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ln.Close()
+
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	_, err = fetch(context.Background(), "http://localhost:"+port)
+
+	if !errors.Is(err, netguard.ErrBlocked) {
+		t.Fatalf("expected ErrBlocked, got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package previews
+
+import (
+	"context"
+	"reflect"
+
+	"clawmark/state"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// GORM plugin scheduling posts for unfurling whenever they are created or saved, register with db.Use
+//
+// Updates only schedule posts whose IDs are known to the statement, i.e. when updating a loaded model.
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string {
+	return "previews"
+}
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+
+	if err := cb.Create().After("gorm:create").Register("previews:after_create", schedule); err != nil {
+		return err
+	}
+
+	return cb.Update().After("gorm:update").Register("previews:after_update", schedule)
+}
+
+func schedule(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil || db.Statement.Schema.Table != "posts" {
+		return
+	}
+
+	field := db.Statement.Schema.LookUpField("ID")
+
+	if field == nil {
+		return
+	}
+
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	enqueue := func(v reflect.Value) {
+		value, zero := field.ValueOf(ctx, v)
+
+		if zero {
+			return
+		}
+
+		if id, ok := value.(uuid.UUID); ok {
+			if err := Enqueue(context.WithoutCancel(ctx), id); err != nil {
+				state.Logger.Error("[previews] Failed to schedule post", zap.Error(err), zap.String("id", id.String()))
+			}
+		}
+	}
+
+	rv := reflect.Indirect(db.Statement.ReflectValue)
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		enqueue(rv)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			enqueue(reflect.Indirect(rv.Index(i)))
+		}
+	}
+}
@@ -0,0 +1,377 @@
+// Link previews of URLs in posts
+//
+// Creating or editing a post schedules it in Redis, a worker then extracts
+// the URLs from its content and fetches each page, turning its OpenGraph,
+// Twitter card or oEmbed metadata into a link plugin. Pages are only fetched
+// from public addresses and previews are cached by URL.
+package previews
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"clawmark/plugins"
+	"clawmark/state"
+	"clawmark/types"
+
+	"github.com/google/uuid"
+	"github.com/infinitybotlist/eureka/jsonimpl"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	queueKey       = "previews:queue"
+	cacheKeyPrefix = "previews:url:"
+
+	// Number of posts unfurled at once
+	workers = 2
+
+	// Posts are processed shortly after being saved, so the transaction creating them has committed
+	delay = 2 * time.Second
+
+	maxTitle       = 200
+	maxDescription = 500
+)
+
+// The metadata of a page
+type Preview struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Image       string `json:"image"`
+	SiteName    string `json:"site_name"`
+}
+
+func (p Preview) truncate() Preview {
+	p.Title = truncate(p.Title, maxTitle)
+	p.Description = truncate(p.Description, maxDescription)
+	p.SiteName = truncate(p.SiteName, maxTitle)
+	return p
+}
+
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+
+	return string([]rune(s)[:n-1]) + "…"
+}
+
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"'` + "`" + `]+`)
+
+// Returns up to limit distinct http(s) URLs in the text, in the order they appear
+func ExtractURLs(text string, limit int) []string {
+	var urls []string
+	seen := map[string]bool{}
+
+	for _, match := range urlPattern.FindAllString(text, -1) {
+		// Punctuation ending a sentence is not part of the URL
+		match = strings.TrimRight(match, ".,!?;:)]}*_")
+
+		u, err := url.Parse(match)
+
+		if err != nil || u.Host == "" {
+			continue
+		}
+
+		if s := u.String(); !seen[s] {
+			seen[s] = true
+			urls = append(urls, s)
+		}
+
+		if len(urls) == limit {
+			break
+		}
+	}
+
+	return urls
+}
+
+func cacheKey(u string) string {
+	sum := sha256.Sum256([]byte(u))
+	return cacheKeyPrefix + hex.EncodeToString(sum[:])
+}
+
+// Returns the preview of a URL, from the cache if possible. Pages without a title have no preview and
+// an empty preview is returned
+func Get(ctx context.Context, u string) (Preview, error) {
+	cfg := state.CurrentConfig().Previews
+
+	cached, err := state.Redis.Get(ctx, cacheKey(u)).Bytes()
+
+	if err == nil {
+		var p Preview
+		if err := jsonimpl.Unmarshal(cached, &p); err == nil {
+			return p, nil
+		}
+	} else if !errors.Is(err, redis.Nil) {
+		return Preview{}, err
+	}
+
+	p, fetchErr := fetch(ctx, u)
+	ttl := cfg.CacheTTL
+
+	// Failures are cached too so unreachable pages are not fetched for every post linking them
+	if fetchErr != nil || p.Title == "" {
+		p = Preview{}
+		ttl /= 10
+	}
+
+	data, err := jsonimpl.Marshal(p)
+
+	if err != nil {
+		return Preview{}, err
+	}
+
+	if err := state.Redis.Set(ctx, cacheKey(u), data, ttl).Err(); err != nil {
+		state.Logger.Error("[previews] Failed to cache preview", zap.Error(err))
+	}
+
+	return p, fetchErr
+}
+
+// Registers the GORM plugin scheduling posts, state.Setup must be called first
+func Setup() error {
+	return state.Pool.Use(GormPlugin{})
+}
+
+// Schedules a post to have the links in its content unfurled
+func Enqueue(ctx context.Context, postID uuid.UUID) error {
+	return state.Redis.ZAdd(ctx, queueKey, redis.Z{
+		Score:  float64(time.Now().Add(delay).Unix()),
+		Member: postID.String(),
+	}).Err()
+}
+
+// Adds a link plugin for every URL in the content of the post without one
+func Process(ctx context.Context, postID uuid.UUID) error {
+	cfg := state.CurrentConfig().Previews
+
+	if cfg.Disabled {
+		return nil
+	}
+
+	var post types.Post
+	err := state.Pool.WithContext(ctx).Select("id", "user_id", "content").First(&post, "id = ?", postID).Error
+
+	// Deleted, or created in a transaction which was rolled back
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var existing []string
+	err = state.Pool.WithContext(ctx).Model(&types.PostPlugin{}).Where("post_id = ? AND type = ?", postID, plugins.TypeLink).Pluck("url", &existing).Error
+
+	if err != nil {
+		return err
+	}
+
+	for _, u := range ExtractURLs(post.Content, cfg.MaxPerPost) {
+		if slices.Contains(existing, u) {
+			continue
+		}
+
+		p, err := Get(ctx, u)
+
+		if err != nil {
+			state.Logger.Debug("[previews] Failed to fetch preview", zap.Error(err), zap.String("url", u))
+		}
+
+		if p.Title == "" {
+			continue
+		}
+
+		_, err = plugins.Create(ctx, post.UserID, post.ID, types.CreatePlugin{
+			Type:  plugins.TypeLink,
+			URL:   u,
+			Title: p.Title,
+			// Metadata is plain text, escaped so it is not mistaken for markup
+			Description: html.EscapeString(p.Description),
+			Image:       p.Image,
+		})
+
+		var validationErr plugins.ValidationError
+
+		switch {
+		case errors.Is(err, plugins.ErrTooMany), errors.Is(err, plugins.ErrPostNotFound):
+			return nil
+		case errors.As(err, &validationErr):
+			state.Logger.Debug("[previews] Preview rejected", zap.Error(err), zap.String("url", u))
+		case err != nil:
+			return err
+		}
+	}
+
+	return nil
+}
+
+var (
+	running sync.WaitGroup
+
+	// Number of workers currently running
+	activeWorkers atomic.Int32
+
+	// Number of workers processing a post
+	busyWorkers atomic.Int32
+
+	// Unix time of the last scheduler tick
+	lastTick atomic.Int64
+)
+
+// Starts the workers and scheduler, they stop on shutdown or when ctx is cancelled
+func Start(ctx context.Context) {
+	ctx, stop := context.WithCancel(ctx)
+
+	// Registered after the connections, so they are stopped before those are closed
+	state.OnShutdown(func(ctx context.Context) {
+		stop()
+
+		if !wait(ctx) {
+			state.Logger.Error("[previews] Workers did not stop before the shutdown deadline")
+		}
+	})
+
+	due := make(chan uuid.UUID, workers)
+
+	running.Add(workers + 1)
+	lastTick.Store(time.Now().Unix())
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer running.Done()
+
+			activeWorkers.Add(1)
+			defer activeWorkers.Add(-1)
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case id := <-due:
+					busyWorkers.Add(1)
+
+					// Finished on shutdown rather than dropped, it has already left the queue
+					if err := Process(context.WithoutCancel(ctx), id); err != nil {
+						state.Logger.Error("[previews] Failed to process post", zap.Error(err), zap.String("id", id.String()))
+					}
+
+					busyWorkers.Add(-1)
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer running.Done()
+		scheduler(ctx, due)
+	}()
+}
+
+// Hands posts which are due to idle workers, the rest stay queued for other instances
+func scheduler(ctx context.Context, due chan uuid.UUID) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Posts no worker picked up are put back for the next instance
+			for {
+				select {
+				case id := <-due:
+					Enqueue(context.WithoutCancel(ctx), id)
+				default:
+					return
+				}
+			}
+		case <-ticker.C:
+		}
+
+		lastTick.Store(time.Now().Unix())
+
+		idle := workers - int(busyWorkers.Load()) - len(due)
+
+		if idle <= 0 {
+			continue
+		}
+
+		ids, err := state.Redis.ZRangeByScore(ctx, queueKey, &redis.ZRangeBy{
+			Min:   "-inf",
+			Max:   strconv.FormatInt(time.Now().Unix(), 10),
+			Count: int64(idle),
+		}).Result()
+
+		if err != nil {
+			if ctx.Err() == nil {
+				state.Logger.Error("[previews] Failed to fetch due posts", zap.Error(err))
+			}
+			continue
+		}
+
+		for _, member := range ids {
+			// Only the instance which removes the entry processes it
+			removed, err := state.Redis.ZRem(ctx, queueKey, member).Result()
+
+			if err != nil || removed == 0 {
+				continue
+			}
+
+			id, err := uuid.Parse(member)
+
+			if err != nil {
+				state.Logger.Error("[previews] Invalid post ID in queue", zap.String("id", member))
+				continue
+			}
+
+			due <- id
+		}
+	}
+}
+
+// Waits for the workers to stop after their context is cancelled, returns false if ctx expires first
+func wait(ctx context.Context) bool {
+	done := make(chan struct{})
+
+	go func() {
+		running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Returns an error if any worker or the scheduler has stopped
+func Check() error {
+	if n := activeWorkers.Load(); n < workers {
+		return fmt.Errorf("%d of %d workers running", n, workers)
+	}
+
+	if since := time.Since(time.Unix(lastTick.Load(), 0)); since > 10*time.Second {
+		return fmt.Errorf("scheduler last ran %s ago", since.Truncate(time.Second))
+	}
+
+	return nil
+}
@@ -0,0 +1,27 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"clawmark/state"
+
+	"go.uber.org/zap"
+)
+
+// SIGHUP is used by tableflip for binary upgrades, so config reloads use SIGUSR1
+func handleReloadSignal() {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGUSR1)
+
+	for range sig {
+		state.Logger.Info("Received SIGUSR1, reloading config")
+
+		if err := state.ReloadConfig(); err != nil {
+			state.Logger.Error("Failed to reload config, keeping the current config", zap.Error(err))
+		}
+	}
+}
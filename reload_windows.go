@@ -0,0 +1,6 @@
+//go:build windows
+
+package main
+
+// Windows has no SIGUSR1, config reloads are not supported
+func handleReloadSignal() {}
@@ -0,0 +1,42 @@
+package blocks
+
+import (
+	"net/http"
+
+	"clawmark/api"
+	"clawmark/blocks"
+	"clawmark/types"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"go.uber.org/zap"
+)
+
+func GetBlocksDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Get Blocks",
+		Description: "Returns the users blocked by the authorized user.",
+		Params:      []docs.Parameter{},
+		Resp:        types.UserList{},
+	}
+}
+
+func GetBlocksRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	users, err := blocks.Blocked(d.Context, userID)
+
+	if err != nil {
+		d.Logger.Error("Failed to fetch blocks", zap.Error(err))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	return uapi.HttpResponse{
+		Json: types.UserList{Users: users},
+	}
+}
@@ -0,0 +1,42 @@
+package blocks
+
+import (
+	"net/http"
+
+	"clawmark/api"
+	"clawmark/blocks"
+	"clawmark/types"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"go.uber.org/zap"
+)
+
+func GetMutesDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Get Mutes",
+		Description: "Returns the users muted by the authorized user.",
+		Params:      []docs.Parameter{},
+		Resp:        types.UserList{},
+	}
+}
+
+func GetMutesRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	users, err := blocks.Muted(d.Context, userID)
+
+	if err != nil {
+		d.Logger.Error("Failed to fetch mutes", zap.Error(err))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	return uapi.HttpResponse{
+		Json: types.UserList{Users: users},
+	}
+}
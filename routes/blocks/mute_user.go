@@ -0,0 +1,42 @@
+package blocks
+
+import (
+	"net/http"
+
+	"clawmark/api"
+	"clawmark/blocks"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func MuteUserDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Mute User",
+		Description: "Mutes a user, hiding their posts and notifications from you. They are not told.",
+		Params:      []docs.Parameter{userParamDoc},
+	}
+}
+
+func MuteUserRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	targetID, err := uuid.Parse(chi.URLParam(r, "id"))
+
+	if err != nil {
+		return uapi.DefaultResponse(http.StatusNotFound)
+	}
+
+	if err := blocks.Mute(d.Context, userID, targetID); err != nil {
+		return relationError(d, err)
+	}
+
+	return uapi.DefaultResponse(http.StatusNoContent)
+}
@@ -0,0 +1,98 @@
+package blocks
+
+import (
+	"errors"
+	"net/http"
+
+	"clawmark/api"
+	"clawmark/blocks"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+type Router struct{}
+
+func (b Router) Tag() (string, string) {
+	return "Blocking and Muting", "Blocked users cannot follow you, comment on or react to your posts or mention you, and you do not see each other's posts. Muted users' posts and notifications are hidden from you only."
+}
+
+func (b Router) Routes(r *chi.Mux) {
+	uapi.Route{
+		Pattern: "/blocks",
+		OpId:    "get_blocks",
+		Method:  uapi.GET,
+		Docs:    GetBlocksDocs,
+		Handler: GetBlocksRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+
+	uapi.Route{
+		Pattern: "/users/{id}/block",
+		OpId:    "block_user",
+		Method:  uapi.PUT,
+		Docs:    BlockUserDocs,
+		Handler: BlockUserRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+
+	uapi.Route{
+		Pattern: "/users/{id}/block",
+		OpId:    "unblock_user",
+		Method:  uapi.DELETE,
+		Docs:    UnblockUserDocs,
+		Handler: UnblockUserRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+
+	uapi.Route{
+		Pattern: "/mutes",
+		OpId:    "get_mutes",
+		Method:  uapi.GET,
+		Docs:    GetMutesDocs,
+		Handler: GetMutesRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+
+	uapi.Route{
+		Pattern: "/users/{id}/mute",
+		OpId:    "mute_user",
+		Method:  uapi.PUT,
+		Docs:    MuteUserDocs,
+		Handler: MuteUserRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+
+	uapi.Route{
+		Pattern: "/users/{id}/mute",
+		OpId:    "unmute_user",
+		Method:  uapi.DELETE,
+		Docs:    UnmuteUserDocs,
+		Handler: UnmuteUserRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+}
+
+var userParamDoc = docs.Parameter{
+	Name:        "id",
+	Description: "The ID of the user",
+	Required:    true,
+	In:          "path",
+	Schema:      docs.IdSchema,
+}
+
+// Converts an error of blocking or muting into a response
+func relationError(d uapi.RouteData, err error) uapi.HttpResponse {
+	switch {
+	case errors.Is(err, blocks.ErrUserNotFound):
+		return uapi.DefaultResponse(http.StatusNotFound)
+	case errors.Is(err, blocks.ErrSelf):
+		return uapi.NewError(http.StatusBadRequest, uapi.ErrBadRequest, "You cannot block or mute yourself")
+	}
+
+	d.Logger.Error("Failed to update blocks or mutes", zap.Error(err))
+	return uapi.DefaultResponse(http.StatusInternalServerError)
+}
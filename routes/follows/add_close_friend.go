@@ -0,0 +1,50 @@
+package follows
+
+import (
+	"net/http"
+
+	"clawmark/api"
+	"clawmark/follows"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func AddCloseFriendDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Add Close Friend",
+		Description: "Adds a user to your close friends, they can then read your posts shared with close friends. They do not need to follow you.",
+		Params: []docs.Parameter{
+			{
+				Name:        "id",
+				Description: "The ID of the user",
+				Required:    true,
+				In:          "path",
+				Schema:      docs.IdSchema,
+			},
+		},
+	}
+}
+
+func AddCloseFriendRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	friendID, err := uuid.Parse(chi.URLParam(r, "id"))
+
+	if err != nil {
+		return uapi.DefaultResponse(http.StatusNotFound)
+	}
+
+	if err := follows.AddCloseFriend(d.Context, userID, friendID); err != nil {
+		return followError(d, err)
+	}
+
+	return uapi.DefaultResponse(http.StatusNoContent)
+}
@@ -0,0 +1,42 @@
+package follows
+
+import (
+	"net/http"
+
+	"clawmark/api"
+	"clawmark/follows"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func ApproveFollowRequestDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Approve Follow Request",
+		Description: "Approves a request to follow the authorized user, the requester then follows them.",
+		Params:      []docs.Parameter{requestParamDoc},
+	}
+}
+
+func ApproveFollowRequestRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	requestID, err := uuid.Parse(chi.URLParam(r, "id"))
+
+	if err != nil {
+		return uapi.DefaultResponse(http.StatusNotFound)
+	}
+
+	if err := follows.Approve(d.Context, userID, requestID); err != nil {
+		return followError(d, err)
+	}
+
+	return uapi.DefaultResponse(http.StatusNoContent)
+}
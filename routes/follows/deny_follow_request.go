@@ -0,0 +1,42 @@
+package follows
+
+import (
+	"net/http"
+
+	"clawmark/api"
+	"clawmark/follows"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func DenyFollowRequestDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Deny Follow Request",
+		Description: "Denies a request to follow the authorized user. The requester is not told and may ask again.",
+		Params:      []docs.Parameter{requestParamDoc},
+	}
+}
+
+func DenyFollowRequestRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	requestID, err := uuid.Parse(chi.URLParam(r, "id"))
+
+	if err != nil {
+		return uapi.DefaultResponse(http.StatusNotFound)
+	}
+
+	if err := follows.Deny(d.Context, userID, requestID); err != nil {
+		return followError(d, err)
+	}
+
+	return uapi.DefaultResponse(http.StatusNoContent)
+}
@@ -0,0 +1,56 @@
+package follows
+
+import (
+	"net/http"
+
+	"clawmark/api"
+	"clawmark/follows"
+	"clawmark/types"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func FollowUserDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Follow User",
+		Description: "Follows a user. If their account is private a follow request is sent instead and `status` is `requested` until they approve it.",
+		Params: []docs.Parameter{
+			{
+				Name:        "id",
+				Description: "The ID of the user",
+				Required:    true,
+				In:          "path",
+				Schema:      docs.IdSchema,
+			},
+		},
+		Resp: types.FollowStatus{},
+	}
+}
+
+func FollowUserRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	targetID, err := uuid.Parse(chi.URLParam(r, "id"))
+
+	if err != nil {
+		return uapi.DefaultResponse(http.StatusNotFound)
+	}
+
+	status, err := follows.Follow(d.Context, userID, targetID)
+
+	if err != nil {
+		return followError(d, err)
+	}
+
+	return uapi.HttpResponse{
+		Json: types.FollowStatus{Status: status},
+	}
+}
@@ -0,0 +1,42 @@
+package follows
+
+import (
+	"net/http"
+
+	"clawmark/api"
+	"clawmark/follows"
+	"clawmark/types"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"go.uber.org/zap"
+)
+
+func GetCloseFriendsDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Get Close Friends",
+		Description: "Returns the close friends of the authorized user.",
+		Params:      []docs.Parameter{},
+		Resp:        types.UserList{},
+	}
+}
+
+func GetCloseFriendsRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	users, err := follows.CloseFriends(d.Context, userID)
+
+	if err != nil {
+		d.Logger.Error("Failed to fetch close friends", zap.Error(err))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	return uapi.HttpResponse{
+		Json: types.UserList{Users: users},
+	}
+}
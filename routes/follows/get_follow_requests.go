@@ -0,0 +1,42 @@
+package follows
+
+import (
+	"net/http"
+
+	"clawmark/api"
+	"clawmark/follows"
+	"clawmark/types"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"go.uber.org/zap"
+)
+
+func GetFollowRequestsDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Get Follow Requests",
+		Description: "Returns the pending requests to follow the authorized user.",
+		Params:      []docs.Parameter{},
+		Resp:        types.FollowRequestList{},
+	}
+}
+
+func GetFollowRequestsRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	requests, err := follows.Requests(d.Context, userID)
+
+	if err != nil {
+		d.Logger.Error("Failed to fetch follow requests", zap.Error(err))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	return uapi.HttpResponse{
+		Json: types.FollowRequestList{Requests: requests},
+	}
+}
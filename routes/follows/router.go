@@ -0,0 +1,128 @@
+package follows
+
+import (
+	"errors"
+	"net/http"
+
+	"clawmark/api"
+	"clawmark/blocks"
+	"clawmark/follows"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+type Router struct{}
+
+func (b Router) Tag() (string, string) {
+	return "Follows", "Following users, follow requests and close friends. Following a private account sends a follow request, its posts are only readable once the request is approved. Close friends can read posts shared with close friends."
+}
+
+func (b Router) Routes(r *chi.Mux) {
+	uapi.Route{
+		Pattern: "/users/{id}/follow",
+		OpId:    "follow_user",
+		Method:  uapi.PUT,
+		Docs:    FollowUserDocs,
+		Handler: FollowUserRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+
+	uapi.Route{
+		Pattern: "/users/{id}/follow",
+		OpId:    "unfollow_user",
+		Method:  uapi.DELETE,
+		Docs:    UnfollowUserDocs,
+		Handler: UnfollowUserRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+
+	uapi.Route{
+		Pattern: "/follow-requests",
+		OpId:    "get_follow_requests",
+		Method:  uapi.GET,
+		Docs:    GetFollowRequestsDocs,
+		Handler: GetFollowRequestsRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+
+	uapi.Route{
+		Pattern: "/follow-requests/{id}/approve",
+		OpId:    "approve_follow_request",
+		Method:  uapi.POST,
+		Docs:    ApproveFollowRequestDocs,
+		Handler: ApproveFollowRequestRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+
+	uapi.Route{
+		Pattern: "/follow-requests/{id}/deny",
+		OpId:    "deny_follow_request",
+		Method:  uapi.POST,
+		Docs:    DenyFollowRequestDocs,
+		Handler: DenyFollowRequestRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+
+	uapi.Route{
+		Pattern: "/close-friends",
+		OpId:    "get_close_friends",
+		Method:  uapi.GET,
+		Docs:    GetCloseFriendsDocs,
+		Handler: GetCloseFriendsRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+
+	uapi.Route{
+		Pattern: "/users/{id}/close-friend",
+		OpId:    "add_close_friend",
+		Method:  uapi.PUT,
+		Docs:    AddCloseFriendDocs,
+		Handler: AddCloseFriendRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+
+	uapi.Route{
+		Pattern: "/users/{id}/close-friend",
+		OpId:    "remove_close_friend",
+		Method:  uapi.DELETE,
+		Docs:    RemoveCloseFriendDocs,
+		Handler: RemoveCloseFriendRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+
+	uapi.Route{
+		Pattern: "/account/privacy",
+		OpId:    "update_privacy",
+		Method:  uapi.PUT,
+		Docs:    UpdatePrivacyDocs,
+		Handler: UpdatePrivacyRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+}
+
+var requestParamDoc = docs.Parameter{
+	Name:        "id",
+	Description: "The ID of the follow request",
+	Required:    true,
+	In:          "path",
+	Schema:      docs.IdSchema,
+}
+
+// Converts an error of following into a response
+func followError(d uapi.RouteData, err error) uapi.HttpResponse {
+	switch {
+	case errors.Is(err, follows.ErrUserNotFound), errors.Is(err, follows.ErrRequestNotFound):
+		return uapi.DefaultResponse(http.StatusNotFound)
+	case errors.Is(err, follows.ErrSelf):
+		return uapi.NewError(http.StatusBadRequest, uapi.ErrBadRequest, "You cannot follow or add yourself")
+	case errors.Is(err, blocks.ErrBlocked):
+		return uapi.NewError(http.StatusForbidden, uapi.ErrForbidden, "You cannot follow or add this user")
+	}
+
+	d.Logger.Error("Failed to update follows", zap.Error(err))
+	return uapi.DefaultResponse(http.StatusInternalServerError)
+}
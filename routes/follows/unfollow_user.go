@@ -0,0 +1,50 @@
+package follows
+
+import (
+	"net/http"
+
+	"clawmark/api"
+	"clawmark/follows"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func UnfollowUserDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Unfollow User",
+		Description: "Unfollows a user, or cancels a pending request to follow them.",
+		Params: []docs.Parameter{
+			{
+				Name:        "id",
+				Description: "The ID of the user",
+				Required:    true,
+				In:          "path",
+				Schema:      docs.IdSchema,
+			},
+		},
+	}
+}
+
+func UnfollowUserRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	targetID, err := uuid.Parse(chi.URLParam(r, "id"))
+
+	if err != nil {
+		return uapi.DefaultResponse(http.StatusNotFound)
+	}
+
+	if err := follows.Unfollow(d.Context, userID, targetID); err != nil {
+		return followError(d, err)
+	}
+
+	return uapi.DefaultResponse(http.StatusNoContent)
+}
@@ -0,0 +1,58 @@
+package follows
+
+import (
+	"net/http"
+
+	"clawmark/api"
+	"clawmark/follows"
+	"clawmark/state"
+	"clawmark/types"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+var compiledMessages = uapi.CompileValidationErrors(types.UpdatePrivacy{})
+
+func UpdatePrivacyDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Update Privacy",
+		Description: "Makes the account of the authorized user private or public. Posts of private accounts are only readable by approved followers, and following them requires a follow request. Making an account public approves all pending follow requests.",
+		Params:      []docs.Parameter{},
+		Req:         types.UpdatePrivacy{},
+		Resp:        types.Privacy{},
+	}
+}
+
+func UpdatePrivacyRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	var payload types.UpdatePrivacy
+
+	hresp, ok := uapi.MarshalReq(r, &payload)
+
+	if !ok {
+		return hresp
+	}
+
+	if err := state.Validator.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		return uapi.ValidatorErrorResponse(compiledMessages, errors)
+	}
+
+	if err := follows.SetPrivate(d.Context, userID, *payload.Private); err != nil {
+		d.Logger.Error("Failed to update privacy", zap.Error(err))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	return uapi.HttpResponse{
+		Json: types.Privacy{Private: *payload.Private},
+	}
+}
@@ -0,0 +1,30 @@
+package health
+
+import (
+	"net/http"
+
+	"clawmark/types"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+)
+
+func HealthzDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Liveness",
+		Description: "Returns 200 as long as the process is able to serve requests. This does not check any dependencies, use /readyz for that.",
+		Params:      []docs.Parameter{},
+		Resp:        types.Health{},
+	}
+}
+
+func HealthzRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	return uapi.HttpResponse{
+		Headers: map[string]string{
+			"Cache-Control": "no-store",
+		},
+		Json: types.Health{
+			Status: "ok",
+		},
+	}
+}
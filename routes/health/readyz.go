@@ -0,0 +1,156 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"clawmark/media"
+	"clawmark/migrations"
+	"clawmark/previews"
+	"clawmark/state"
+	"clawmark/types"
+	"clawmark/uapi"
+	"clawmark/webhooks"
+
+	docs "clawmark/doclib"
+
+	"go.uber.org/zap"
+)
+
+// Time allowed for each check
+const checkTimeout = 2 * time.Second
+
+var checks = map[string]func(ctx context.Context) error{
+	"postgres": func(ctx context.Context) error {
+		db, err := state.Pool.DB()
+
+		if err != nil {
+			return err
+		}
+
+		return db.PingContext(ctx)
+	},
+	"redis": func(ctx context.Context) error {
+		return state.Redis.Ping(ctx).Err()
+	},
+	"migrations": func(ctx context.Context) error {
+		pending, err := migrations.Pending(ctx, state.Pool)
+
+		if err != nil {
+			return err
+		}
+
+		if pending > 0 {
+			return fmt.Errorf("%d pending migrations", pending)
+		}
+
+		return nil
+	},
+	"media": func(ctx context.Context) error {
+		return media.Check(ctx)
+	},
+	"media_workers": func(ctx context.Context) error {
+		return media.CheckWorkers()
+	},
+	"preview_workers": func(ctx context.Context) error {
+		return previews.Check()
+	},
+	"webhook_workers": func(ctx context.Context) error {
+		return webhooks.Check()
+	},
+	"draining": func(ctx context.Context) error {
+		if state.Draining.Load() {
+			return errors.New("server is shutting down")
+		}
+
+		return nil
+	},
+}
+
+func ReadyzDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Readiness",
+		Description: "Checks Postgres, Redis, migrations, media storage and background workers. Returns 200 if every check passed and 503 otherwise, along with the status of each check. Why a check failed is only logged.",
+		Params:      []docs.Parameter{},
+		Resp:        types.Health{},
+	}
+}
+
+func ReadyzRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	health := types.Health{
+		Status: "ok",
+		Checks: make(map[string]types.HealthCheck, len(checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(d.Context, checkTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := runCheck(ctx, check)
+
+			result := types.HealthCheck{
+				Status:    "ok",
+				LatencyMs: time.Since(start).Milliseconds(),
+			}
+
+			// The error may name hosts and ports, so it is only logged
+			if err != nil {
+				result.Status = "fail"
+				d.Logger.Warn("Readiness check failed", zap.String("check", name), zap.Error(err))
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			health.Checks[name] = result
+
+			if err != nil {
+				health.Status = "fail"
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	status := http.StatusOK
+
+	if health.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+
+	return uapi.HttpResponse{
+		Status: status,
+		Headers: map[string]string{
+			"Cache-Control": "no-store",
+		},
+		Json: health,
+	}
+}
+
+// Runs a check, giving up once ctx expires even if the check does not respect it
+func runCheck(ctx context.Context, check func(ctx context.Context) error) error {
+	res := make(chan error, 1)
+
+	go func() {
+		res <- check(ctx)
+	}()
+
+	select {
+	case err := <-res:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out after %s", checkTimeout)
+	}
+}
@@ -0,0 +1,31 @@
+package health
+
+import (
+	"clawmark/uapi"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type Router struct{}
+
+func (b Router) Tag() (string, string) {
+	return "Health", "These endpoints are used by orchestrators and load balancers to check whether the API is alive and ready to serve requests."
+}
+
+func (b Router) Routes(r *chi.Mux) {
+	uapi.Route{
+		Pattern: "/healthz",
+		OpId:    "healthz",
+		Method:  uapi.GET,
+		Docs:    HealthzDocs,
+		Handler: HealthzRoute,
+	}.Route(r)
+
+	uapi.Route{
+		Pattern: "/readyz",
+		OpId:    "readyz",
+		Method:  uapi.GET,
+		Docs:    ReadyzDocs,
+		Handler: ReadyzRoute,
+	}.Route(r)
+}
@@ -0,0 +1,75 @@
+package media
+
+import (
+	"net/http"
+	"strconv"
+
+	"clawmark/api"
+	uploads "clawmark/media"
+	"clawmark/types"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func AppendUploadDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Append Upload",
+		Description: "Appends a chunk to a resumable upload. The body is the raw chunk (`application/offset+octet-stream`) and `Upload-Offset` must be the current offset of the upload, otherwise a conflict is returned with the expected offset in the `Upload-Offset` header.\n\nOnce the final chunk is received the upload is stored and the resulting media is returned with the upload.",
+		Params: []docs.Parameter{
+			{
+				Name:        "id",
+				Description: "The ID of the upload",
+				Required:    true,
+				In:          "path",
+				Schema:      docs.IdSchema,
+			},
+			{
+				Name:        uploads.OffsetHeader,
+				Description: "The offset this chunk starts at",
+				Required:    true,
+				In:          "header",
+				Schema:      docs.IntSchema,
+			},
+		},
+		Resp: types.Upload{},
+	}
+}
+
+func AppendUploadRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+
+	if err != nil {
+		return uapi.DefaultResponse(http.StatusNotFound)
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get(uploads.OffsetHeader), 10, 64)
+
+	if err != nil || offset < 0 {
+		return uapi.NewError(http.StatusBadRequest, uapi.ErrBadRequest, "The Upload-Offset header must be set to the offset of the chunk")
+	}
+
+	defer r.Body.Close()
+
+	u, m, err := uploads.AppendUpload(d.Context, userID, id, offset, r.Body)
+
+	if err != nil {
+		return mediaError(d, err)
+	}
+
+	return uapi.HttpResponse{
+		Headers: map[string]string{
+			uploads.OffsetHeader: strconv.FormatInt(u.Offset, 10),
+		},
+		Json: toUpload(u, m),
+	}
+}
@@ -0,0 +1,119 @@
+package media
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"clawmark/api"
+	uploads "clawmark/media"
+	"clawmark/plugins"
+	"clawmark/state"
+	"clawmark/types"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var compiledAttachMessages = uapi.CompileValidationErrors(types.AttachMedia{})
+
+func AttachMediaDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Attach Media",
+		Description: "Attaches media uploaded by the authorized user to one of their posts as a post plugin. The plugin URL accepts a `size` query parameter, see Get Media File. Dimensions and the blurhash are filled in once the media has been processed.",
+		Params: []docs.Parameter{
+			{
+				Name:        "id",
+				Description: "The ID of the post",
+				Required:    true,
+				In:          "path",
+				Schema:      docs.IdSchema,
+			},
+		},
+		Req:  types.AttachMedia{},
+		Resp: types.Plugin{},
+	}
+}
+
+func AttachMediaRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	postID, err := uuid.Parse(chi.URLParam(r, "id"))
+
+	if err != nil {
+		return uapi.DefaultResponse(http.StatusNotFound)
+	}
+
+	var payload types.AttachMedia
+
+	hresp, ok := uapi.MarshalReq(r, &payload)
+
+	if !ok {
+		return hresp
+	}
+
+	err = state.Validator.Struct(payload)
+
+	if err != nil {
+		errors := err.(validator.ValidationErrors)
+		return uapi.ValidatorErrorResponse(compiledAttachMessages, errors)
+	}
+
+	pluginType := payload.Type
+
+	if pluginType == "" {
+		var m types.Media
+		err = state.Pool.WithContext(d.Context).Select("content_type").Where("id = ? AND user_id = ?", payload.MediaID, userID).First(&m).Error
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return uapi.NewError(http.StatusBadRequest, uapi.ErrBadRequest, "Media not found")
+		}
+
+		if err != nil {
+			d.Logger.Error("Failed to fetch media", zap.Error(err))
+			return uapi.DefaultResponse(http.StatusInternalServerError)
+		}
+
+		pluginType = uploads.PluginType(m.ContentType)
+	}
+
+	plugin, err := plugins.Create(d.Context, userID, postID, types.CreatePlugin{
+		Type:    pluginType,
+		MediaID: &payload.MediaID,
+		Title:   payload.Alt,
+	})
+
+	var validationErr plugins.ValidationError
+
+	switch {
+	case errors.As(err, &validationErr):
+		return uapi.ErrorResponse(uapi.Error{
+			Code:    uapi.ErrValidation,
+			Status:  http.StatusBadRequest,
+			Message: validationErr.Message,
+			Fields:  map[string]string{validationErr.Field: validationErr.Message},
+		})
+	case errors.Is(err, plugins.ErrPostNotFound):
+		return uapi.DefaultResponse(http.StatusNotFound)
+	case errors.Is(err, plugins.ErrTooMany):
+		return uapi.NewError(http.StatusBadRequest, uapi.ErrBadRequest, "A post cannot have more than "+strconv.Itoa(plugins.MaxPerPost)+" plugins")
+	case err != nil:
+		d.Logger.Error("Failed to create post plugin", zap.Error(err))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	return uapi.HttpResponse{
+		Status: http.StatusCreated,
+		Json:   plugins.ToPlugin(*plugin),
+	}
+}
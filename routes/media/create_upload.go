@@ -0,0 +1,61 @@
+package media
+
+import (
+	"net/http"
+
+	"clawmark/api"
+	uploads "clawmark/media"
+	"clawmark/state"
+	"clawmark/types"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var compiledMessages = uapi.CompileValidationErrors(types.CreateUpload{})
+
+func CreateUploadDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Create Upload",
+		Description: "Starts a resumable upload. Send the content in one or more chunks with `PATCH /media/uploads/{id}`, the upload expires if it is not completed in time.",
+		Params:      []docs.Parameter{},
+		Req:         types.CreateUpload{},
+		Resp:        types.Upload{},
+	}
+}
+
+func CreateUploadRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	var payload types.CreateUpload
+
+	hresp, ok := uapi.MarshalReq(r, &payload)
+
+	if !ok {
+		return hresp
+	}
+
+	err := state.Validator.Struct(payload)
+
+	if err != nil {
+		errors := err.(validator.ValidationErrors)
+		return uapi.ValidatorErrorResponse(compiledMessages, errors)
+	}
+
+	u, err := uploads.CreateUpload(d.Context, userID, payload.Size)
+
+	if err != nil {
+		return mediaError(d, err)
+	}
+
+	return uapi.HttpResponse{
+		Status: http.StatusCreated,
+		Json:   toUpload(u, nil),
+	}
+}
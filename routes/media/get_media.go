@@ -0,0 +1,82 @@
+package media
+
+import (
+	"errors"
+	"net/http"
+
+	"clawmark/api"
+	uploads "clawmark/media"
+	"clawmark/state"
+	"clawmark/types"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func GetMediaDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Get Media",
+		Description: "Returns media uploaded by the authorized user along with its variants. Images are processed in the background after upload, until then `status` is `pending` and there are no variants or dimensions.",
+		Params: []docs.Parameter{
+			{
+				Name:        "id",
+				Description: "The ID of the media",
+				Required:    true,
+				In:          "path",
+				Schema:      docs.IdSchema,
+			},
+			{
+				Name:        "size",
+				Description: "The size `url` should point at: small, medium, large or original (the default). Falls back to the next larger size that exists",
+				Required:    false,
+				In:          "query",
+				Schema:      docs.EnumSchema(uploads.SizeNames()...),
+			},
+		},
+		Resp: types.MediaFile{},
+	}
+}
+
+func GetMediaRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+
+	if err != nil {
+		return uapi.DefaultResponse(http.StatusNotFound)
+	}
+
+	size := r.URL.Query().Get("size")
+
+	if size != "" && !uploads.IsSize(size) {
+		return uapi.NewError(http.StatusBadRequest, uapi.ErrBadRequest, "size must be one of small, medium, large or original")
+	}
+
+	var m types.Media
+	err = state.Pool.WithContext(d.Context).Preload("Variants").Where("id = ? AND user_id = ?", id, userID).First(&m).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return uapi.DefaultResponse(http.StatusNotFound)
+	}
+
+	if err != nil {
+		d.Logger.Error("Failed to fetch media", zap.Error(err))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	f := toMediaFile(m)
+	f.URL = uploads.Backend().URL(uploads.SelectKey(m, m.Variants, size))
+
+	return uapi.HttpResponse{
+		Json: f,
+	}
+}
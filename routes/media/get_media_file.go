@@ -0,0 +1,128 @@
+package media
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"clawmark/api"
+	"clawmark/blocks"
+	uploads "clawmark/media"
+	postview "clawmark/posts"
+	"clawmark/state"
+	"clawmark/types"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func GetMediaFileDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Get Media File",
+		Description: "Redirects to the file of the media in the requested size. Media of public posts is found by anyone, including anonymous users, so it can be embedded. Other media is only found by its uploader and users who may read a post it is attached to. Media of posts not everyone may read redirects to a URL expiring after a few minutes, so do not store it. Post plugins backed by uploaded media point here so clients can pick a size. If the size has not been generated (yet) the next larger one is used, falling back to the original.",
+		Params: []docs.Parameter{
+			{
+				Name:        "id",
+				Description: "The ID of the media",
+				Required:    true,
+				In:          "path",
+				Schema:      docs.IdSchema,
+			},
+			{
+				Name:        "size",
+				Description: "small, medium, large or original (the default)",
+				Required:    false,
+				In:          "query",
+				Schema:      docs.EnumSchema(uploads.SizeNames()...),
+			},
+		},
+	}
+}
+
+// Time the URLs of media of non-public posts stay valid
+const signedURLExpiry = 5 * time.Minute
+
+func GetMediaFileRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	// Anonymous users only find media of public posts
+	userID, ok := api.UserID(d)
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+
+	if err != nil {
+		return uapi.DefaultResponse(http.StatusNotFound)
+	}
+
+	size := r.URL.Query().Get("size")
+
+	if size != "" && !uploads.IsSize(size) {
+		return uapi.NewError(http.StatusBadRequest, uapi.ErrBadRequest, "size must be one of small, medium, large or original")
+	}
+
+	var m types.Media
+	err = state.Pool.WithContext(d.Context).Preload("Variants").First(&m, "id = ?", id).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return uapi.DefaultResponse(http.StatusNotFound)
+	}
+
+	if err != nil {
+		d.Logger.Error("Failed to fetch media", zap.Error(err))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	// Posts the media is attached to
+	attached := state.Pool.WithContext(d.Context).Model(&types.Post{}).
+		Where("posts.id IN (SELECT post_id FROM post_plugins WHERE media_id = ?)", m.ID)
+
+	var public int64
+	err = attached.Session(&gorm.Session{}).Scopes(postview.Public()).Count(&public).Error
+
+	if err != nil {
+		d.Logger.Error("Failed to check media visibility", zap.Error(err))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	key := uploads.SelectKey(m, m.Variants, size)
+
+	// Anyone may see media of public posts, including browsers loading the image of a plugin without a session
+	if public > 0 {
+		return uapi.HttpResponse{
+			Redirect: uploads.Backend().URL(key),
+		}
+	}
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusNotFound)
+	}
+
+	var visible int64
+	err = attached.Session(&gorm.Session{}).
+		Scopes(postview.VisibleTo(userID), blocks.ExcludeHidden(userID, "posts.user_id")).
+		Count(&visible).Error
+
+	if err != nil {
+		d.Logger.Error("Failed to check media visibility", zap.Error(err))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	if visible == 0 && m.UserID != userID {
+		return uapi.DefaultResponse(http.StatusNotFound)
+	}
+
+	url, err := uploads.Backend().SignedURL(d.Context, key, signedURLExpiry)
+
+	if err != nil {
+		d.Logger.Error("Failed to sign media URL", zap.Error(err))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	return uapi.HttpResponse{
+		Redirect: url,
+		Headers:  map[string]string{"Cache-Control": "no-store"},
+	}
+}
@@ -0,0 +1,56 @@
+package media
+
+import (
+	"net/http"
+
+	"clawmark/api"
+	uploads "clawmark/media"
+	"clawmark/types"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func GetUploadDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Get Upload",
+		Description: "Returns a resumable upload of the authorized user. After a dropped connection, use the offset to find where to resume from.",
+		Params: []docs.Parameter{
+			{
+				Name:        "id",
+				Description: "The ID of the upload",
+				Required:    true,
+				In:          "path",
+				Schema:      docs.IdSchema,
+			},
+		},
+		Resp: types.Upload{},
+	}
+}
+
+func GetUploadRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+
+	if err != nil {
+		return uapi.DefaultResponse(http.StatusNotFound)
+	}
+
+	u, err := uploads.GetUpload(d.Context, userID, id)
+
+	if err != nil {
+		return mediaError(d, err)
+	}
+
+	return uapi.HttpResponse{
+		Json: toUpload(u, nil),
+	}
+}
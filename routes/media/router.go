@@ -0,0 +1,162 @@
+package media
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"clawmark/api"
+	uploads "clawmark/media"
+	"clawmark/types"
+	"clawmark/uapi"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+type Router struct{}
+
+func (b Router) Tag() (string, string) {
+	return "Media", "Upload images, GIFs and stickers and attach them to posts. Small files can be uploaded in one request, larger ones should use a resumable upload."
+}
+
+func (b Router) Routes(r *chi.Mux) {
+	uapi.Route{
+		Pattern: "/media",
+		OpId:    "upload_media",
+		Method:  uapi.POST,
+		Docs:    UploadMediaDocs,
+		Handler: UploadMediaRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+
+	uapi.Route{
+		Pattern: "/media/{id}",
+		OpId:    "get_media",
+		Method:  uapi.GET,
+		Docs:    GetMediaDocs,
+		Handler: GetMediaRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+
+	uapi.Route{
+		Pattern:      "/media/{id}/file",
+		OpId:         "get_media_file",
+		Method:       uapi.GET,
+		Docs:         GetMediaFileDocs,
+		Handler:      GetMediaFileRoute,
+		Auth:         []uapi.AuthType{{Type: api.TargetTypeUser}},
+		AuthOptional: true,
+	}.Route(r)
+
+	uapi.Route{
+		Pattern: "/media/uploads",
+		OpId:    "create_upload",
+		Method:  uapi.POST,
+		Docs:    CreateUploadDocs,
+		Handler: CreateUploadRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+
+	uapi.Route{
+		Pattern: "/media/uploads/{id}",
+		OpId:    "get_upload",
+		Method:  uapi.GET,
+		Docs:    GetUploadDocs,
+		Handler: GetUploadRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+
+	uapi.Route{
+		Pattern: "/media/uploads/{id}",
+		OpId:    "append_upload",
+		Method:  uapi.PATCH,
+		Docs:    AppendUploadDocs,
+		Handler: AppendUploadRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+
+	uapi.Route{
+		Pattern: "/posts/{id}/media",
+		OpId:    "attach_media",
+		Method:  uapi.POST,
+		Docs:    AttachMediaDocs,
+		Handler: AttachMediaRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+}
+
+func toMediaFile(m types.Media) types.MediaFile {
+	f := types.MediaFile{
+		ID:          m.ID,
+		URL:         uploads.URL(m),
+		ContentType: m.ContentType,
+		Size:        m.Size,
+		Hash:        m.Hash,
+		Status:      m.Status,
+		Width:       m.Width,
+		Height:      m.Height,
+		Blurhash:    m.Blurhash,
+		Variants:    []types.Variant{},
+		CreatedAt:   m.CreatedAt,
+	}
+
+	for _, v := range m.Variants {
+		f.Variants = append(f.Variants, types.Variant{
+			Name:        v.Name,
+			URL:         uploads.Backend().URL(v.Key),
+			ContentType: v.ContentType,
+			Width:       v.Width,
+			Height:      v.Height,
+			Size:        v.Size,
+		})
+	}
+
+	return f
+}
+
+func toUpload(u *uploads.Upload, m *types.Media) types.Upload {
+	upload := types.Upload{
+		ID:        u.ID,
+		Size:      u.Size,
+		Offset:    u.Offset,
+		ExpiresAt: u.ExpiresAt,
+	}
+
+	if m != nil {
+		f := toMediaFile(*m)
+		upload.Media = &f
+	}
+
+	return upload
+}
+
+// Maps errors from the media package to responses, logging unexpected ones
+func mediaError(d uapi.RouteData, err error) uapi.HttpResponse {
+	var typeErr uploads.TypeError
+	var offsetErr uploads.OffsetError
+
+	switch {
+	case errors.Is(err, uploads.ErrTooLarge):
+		return uapi.NewError(http.StatusRequestEntityTooLarge, uapi.ErrTooLarge, "Media may be at most "+strconv.FormatInt(uploads.MaxSize(), 10)+" bytes")
+	case errors.Is(err, uploads.ErrEmpty):
+		return uapi.NewError(http.StatusBadRequest, uapi.ErrBadRequest, "Media must not be empty")
+	case errors.Is(err, uploads.ErrCorrupt):
+		return uapi.NewError(http.StatusBadRequest, uapi.ErrBadRequest, "Media is corrupt")
+	case errors.As(err, &typeErr):
+		return uapi.NewError(http.StatusUnsupportedMediaType, uapi.ErrUnsupportedType, typeErr.Error())
+	case errors.Is(err, uploads.ErrUploadNotFound):
+		return uapi.DefaultResponse(http.StatusNotFound)
+	case errors.Is(err, uploads.ErrUploadBusy):
+		return uapi.NewError(http.StatusConflict, uapi.ErrConflict, err.Error())
+	case errors.As(err, &offsetErr):
+		resp := uapi.NewError(http.StatusConflict, uapi.ErrConflict, offsetErr.Error())
+		resp.Headers = map[string]string{
+			uploads.OffsetHeader: strconv.FormatInt(offsetErr.Expected, 10),
+		}
+		return resp
+	}
+
+	d.Logger.Error("Failed to handle media", zap.Error(err))
+	return uapi.DefaultResponse(http.StatusInternalServerError)
+}
@@ -0,0 +1,66 @@
+package media
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"clawmark/api"
+	uploads "clawmark/media"
+	"clawmark/types"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+)
+
+func UploadMediaDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Upload Media",
+		Description: "Uploads media in a single `multipart/form-data` request with the content in the `file` field. The type is detected from the content, only allowed image types are accepted.\n\nUploading content the user has uploaded before returns the existing media. Use a resumable upload for large files or unreliable connections.",
+		Params:      []docs.Parameter{},
+		Resp:        types.MediaFile{},
+	}
+}
+
+func UploadMediaRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	mr, err := r.MultipartReader()
+
+	if err != nil {
+		return uapi.NewError(http.StatusBadRequest, uapi.ErrBadRequest, "Request must be multipart/form-data")
+	}
+
+	for {
+		part, err := mr.NextPart()
+
+		if errors.Is(err, io.EOF) {
+			return uapi.NewError(http.StatusBadRequest, uapi.ErrBadRequest, "The file field is required")
+		}
+
+		if err != nil {
+			return uapi.NewError(http.StatusBadRequest, uapi.ErrBadRequest, "Invalid multipart body")
+		}
+
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		m, err := uploads.Store(d.Context, userID, part)
+		part.Close()
+
+		if err != nil {
+			return mediaError(d, err)
+		}
+
+		return uapi.HttpResponse{
+			Status: http.StatusCreated,
+			Json:   toMediaFile(*m),
+		}
+	}
+}
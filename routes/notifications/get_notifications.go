@@ -0,0 +1,93 @@
+package notifications
+
+import (
+	"net/http"
+	"strconv"
+
+	"clawmark/api"
+	"clawmark/blocks"
+	"clawmark/state"
+	"clawmark/types"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 100
+)
+
+func GetNotificationsDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Get Notifications",
+		Description: "Returns the notifications of the authorized user, newest first, with the number of unread notifications.",
+		Params: []docs.Parameter{
+			{
+				Name:        "limit",
+				Description: "The maximum number of notifications to return (1-100, default 50)",
+				Required:    false,
+				In:          "query",
+				Schema:      docs.IntSchema,
+			},
+		},
+		Resp: types.NotificationList{},
+	}
+}
+
+func GetNotificationsRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	limit := defaultLimit
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		var err error
+		limit, err = strconv.Atoi(l)
+
+		if err != nil || limit < 1 || limit > maxLimit {
+			return uapi.NewError(http.StatusBadRequest, uapi.ErrBadRequest, "limit must be between 1 and 100")
+		}
+	}
+
+	var notifications []types.Notification
+	err := state.Pool.WithContext(d.Context).Preload("Actor").Scopes(blocks.ExcludeHidden(userID, "notifications.actor_id")).Where("user_id = ?", userID).Order("created_at DESC").Limit(limit).Find(&notifications).Error
+
+	if err != nil {
+		d.Logger.Error("Failed to fetch notifications", zap.Error(err))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	list := types.NotificationList{Notifications: []types.NotificationEntry{}}
+
+	err = state.Pool.WithContext(d.Context).Model(&types.Notification{}).Scopes(blocks.ExcludeHidden(userID, "notifications.actor_id")).Where("user_id = ? AND read_at IS NULL", userID).Count(&list.Unread).Error
+
+	if err != nil {
+		d.Logger.Error("Failed to count unread notifications", zap.Error(err))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	for _, n := range notifications {
+		list.Notifications = append(list.Notifications, types.NotificationEntry{
+			ID:   n.ID,
+			Type: n.Type,
+			Actor: types.PartialUser{
+				ID:        n.Actor.ID,
+				Username:  n.Actor.Username,
+				AvatarURL: n.Actor.AvatarURL,
+			},
+			PostID:    n.PostID,
+			Read:      n.ReadAt != nil,
+			CreatedAt: n.CreatedAt,
+		})
+	}
+
+	return uapi.HttpResponse{
+		Json: list,
+	}
+}
@@ -0,0 +1,40 @@
+package notifications
+
+import (
+	"net/http"
+	"time"
+
+	"clawmark/api"
+	"clawmark/state"
+	"clawmark/types"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"go.uber.org/zap"
+)
+
+func ReadNotificationsDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Read Notifications",
+		Description: "Marks every notification of the authorized user as read.",
+		Params:      []docs.Parameter{},
+	}
+}
+
+func ReadNotificationsRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	err := state.Pool.WithContext(d.Context).Model(&types.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Update("read_at", time.Now()).Error
+
+	if err != nil {
+		d.Logger.Error("Failed to mark notifications as read", zap.Error(err))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	return uapi.DefaultResponse(http.StatusNoContent)
+}
@@ -0,0 +1,34 @@
+package notifications
+
+import (
+	"clawmark/api"
+	"clawmark/uapi"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type Router struct{}
+
+func (b Router) Tag() (string, string) {
+	return "Notifications", "Notifications tell you about activity involving you, such as being mentioned in a post."
+}
+
+func (b Router) Routes(r *chi.Mux) {
+	uapi.Route{
+		Pattern: "/notifications",
+		OpId:    "get_notifications",
+		Method:  uapi.GET,
+		Docs:    GetNotificationsDocs,
+		Handler: GetNotificationsRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+
+	uapi.Route{
+		Pattern: "/notifications/read",
+		OpId:    "read_notifications",
+		Method:  uapi.POST,
+		Docs:    ReadNotificationsDocs,
+		Handler: ReadNotificationsRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+}
@@ -0,0 +1,78 @@
+package plugins
+
+import (
+	"net/http"
+	"strings"
+
+	"clawmark/api"
+	"clawmark/plugins"
+	"clawmark/state"
+	"clawmark/types"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+var compiledMessages = uapi.CompileValidationErrors(types.CreatePlugin{})
+
+func CreatePluginDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Create Plugin",
+		Description: "Adds a plugin to a post of the authorized user. Supported types are: " + strings.Join(plugins.Types(), ", ") + ", unknown types are rejected.\n\nThe HTML of the plugin is rendered by the server from the fields of its type.",
+		Params: []docs.Parameter{
+			{
+				Name:        "id",
+				Description: "The ID of the post",
+				Required:    true,
+				In:          "path",
+				Schema:      docs.IdSchema,
+			},
+		},
+		Req:  types.CreatePlugin{},
+		Resp: types.Plugin{},
+	}
+}
+
+func CreatePluginRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	postID, err := uuid.Parse(chi.URLParam(r, "id"))
+
+	if err != nil {
+		return uapi.DefaultResponse(http.StatusNotFound)
+	}
+
+	var payload types.CreatePlugin
+
+	hresp, ok := uapi.MarshalReq(r, &payload)
+
+	if !ok {
+		return hresp
+	}
+
+	err = state.Validator.Struct(payload)
+
+	if err != nil {
+		errors := err.(validator.ValidationErrors)
+		return uapi.ValidatorErrorResponse(compiledMessages, errors)
+	}
+
+	plugin, err := plugins.Create(d.Context, userID, postID, payload)
+
+	if err != nil {
+		return pluginError(d, err)
+	}
+
+	return uapi.HttpResponse{
+		Status: http.StatusCreated,
+		Json:   plugins.ToPlugin(*plugin),
+	}
+}
@@ -0,0 +1,26 @@
+package plugins
+
+import (
+	"net/http"
+
+	"clawmark/plugins"
+	"clawmark/types"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+)
+
+func GetPluginTypesDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Get Plugin Types",
+		Description: "Returns the supported plugin types and the fields each of them uses.",
+		Params:      []docs.Parameter{},
+		Resp:        types.PluginTypeList{},
+	}
+}
+
+func GetPluginTypesRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	return uapi.HttpResponse{
+		Json: plugins.List(),
+	}
+}
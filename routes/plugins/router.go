@@ -0,0 +1,61 @@
+package plugins
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"clawmark/api"
+	"clawmark/plugins"
+	"clawmark/uapi"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+type Router struct{}
+
+func (b Router) Tag() (string, string) {
+	return "Plugins", "Embed images, videos and links in posts. The HTML of every plugin is rendered by the server, HTML from users is never stored as is."
+}
+
+func (b Router) Routes(r *chi.Mux) {
+	uapi.Route{
+		Pattern: "/plugins/types",
+		OpId:    "get_plugin_types",
+		Method:  uapi.GET,
+		Docs:    GetPluginTypesDocs,
+		Handler: GetPluginTypesRoute,
+	}.Route(r)
+
+	uapi.Route{
+		Pattern: "/posts/{id}/plugins",
+		OpId:    "create_plugin",
+		Method:  uapi.POST,
+		Docs:    CreatePluginDocs,
+		Handler: CreatePluginRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+}
+
+// Maps errors from the plugins package to responses, logging unexpected ones
+func pluginError(d uapi.RouteData, err error) uapi.HttpResponse {
+	var validationErr plugins.ValidationError
+
+	switch {
+	case errors.As(err, &validationErr):
+		return uapi.ErrorResponse(uapi.Error{
+			Code:    uapi.ErrValidation,
+			Status:  http.StatusBadRequest,
+			Message: validationErr.Message,
+			Fields:  map[string]string{validationErr.Field: validationErr.Message},
+		})
+	case errors.Is(err, plugins.ErrPostNotFound):
+		return uapi.DefaultResponse(http.StatusNotFound)
+	case errors.Is(err, plugins.ErrTooMany):
+		return uapi.NewError(http.StatusBadRequest, uapi.ErrBadRequest, "A post cannot have more than "+strconv.Itoa(plugins.MaxPerPost)+" plugins")
+	}
+
+	d.Logger.Error("Failed to create plugin", zap.Error(err))
+	return uapi.DefaultResponse(http.StatusInternalServerError)
+}
@@ -0,0 +1,61 @@
+package posts
+
+import (
+	"errors"
+	"net/http"
+
+	"clawmark/api"
+	"clawmark/blocks"
+	postview "clawmark/posts"
+	"clawmark/state"
+	"clawmark/types"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func GetPostDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Get Post",
+		Description: "Returns a post, including unlisted posts. Posts the authorized user may not read are not found.",
+		Params:      []docs.Parameter{postParamDoc},
+		Resp:        types.PostEntry{},
+	}
+}
+
+func GetPostRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+
+	if err != nil {
+		return uapi.DefaultResponse(http.StatusNotFound)
+	}
+
+	var post types.Post
+	err = postview.Preload(state.Pool.WithContext(d.Context)).
+		Scopes(postview.VisibleTo(userID), blocks.ExcludeBlocked(userID, "posts.user_id")).
+		First(&post, "posts.id = ?", id).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return uapi.DefaultResponse(http.StatusNotFound)
+	}
+
+	if err != nil {
+		d.Logger.Error("Failed to fetch post", zap.Error(err))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	return uapi.HttpResponse{
+		Json: postview.ToPost(post),
+	}
+}
@@ -0,0 +1,112 @@
+package posts
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"clawmark/api"
+	"clawmark/blocks"
+	postview "clawmark/posts"
+	"clawmark/state"
+	"clawmark/types"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultCommentLimit = 50
+	maxCommentLimit     = 100
+)
+
+func GetPostCommentsDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Get Post Comments",
+		Description: "Returns the comments of a post, newest first. Comments by users you blocked, muted or were blocked by are left out. Pass `next_cursor` of a page as `cursor` to fetch the next one.",
+		Params: []docs.Parameter{
+			postParamDoc,
+			{
+				Name:        "limit",
+				Description: "The maximum number of comments to return (1-100, default 50)",
+				Required:    false,
+				In:          "query",
+				Schema:      docs.IntSchema,
+			},
+			{
+				Name:        "cursor",
+				Description: "The cursor of the page to fetch, omit for the first page",
+				Required:    false,
+				In:          "query",
+				Schema:      docs.IdSchema,
+			},
+		},
+		Resp: types.CommentList{},
+	}
+}
+
+func GetPostCommentsRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+
+	if err != nil {
+		return uapi.DefaultResponse(http.StatusNotFound)
+	}
+
+	limit := defaultCommentLimit
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		limit, err = strconv.Atoi(l)
+
+		if err != nil || limit < 1 || limit > maxCommentLimit {
+			return uapi.NewError(http.StatusBadRequest, uapi.ErrBadRequest, "limit must be between 1 and 100")
+		}
+	}
+
+	// Comments are only readable by those who can read the post
+	var post types.Post
+	err = state.Pool.WithContext(d.Context).Select("posts.id").
+		Scopes(postview.VisibleTo(userID), blocks.ExcludeBlocked(userID, "posts.user_id")).
+		First(&post, "posts.id = ?", id).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return uapi.DefaultResponse(http.StatusNotFound)
+	}
+
+	if err != nil {
+		d.Logger.Error("Failed to fetch post", zap.Error(err))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	query, err := postview.PaginateComments(
+		state.Pool.WithContext(d.Context).Preload("User").
+			Scopes(blocks.ExcludeHidden(userID, "comments.user_id")).
+			Where("comments.post_id = ?", id),
+		r.URL.Query().Get("cursor"),
+		limit,
+	)
+
+	if err != nil {
+		return uapi.NewError(http.StatusBadRequest, uapi.ErrBadRequest, "cursor is invalid")
+	}
+
+	var comments []types.Comment
+	if err := query.Find(&comments).Error; err != nil {
+		d.Logger.Error("Failed to fetch comments", zap.Error(err))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	return uapi.HttpResponse{
+		Json: postview.CommentPage(comments, limit),
+	}
+}
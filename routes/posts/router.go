@@ -0,0 +1,53 @@
+package posts
+
+import (
+	"clawmark/api"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type Router struct{}
+
+func (b Router) Tag() (string, string) {
+	return "Posts", "Posts and their comments. Every post has a visibility deciding who can read it: public, followers, mutuals, close_friends or unlisted (anyone with the link, but left out of feeds, tag pages and search)."
+}
+
+func (b Router) Routes(r *chi.Mux) {
+	uapi.Route{
+		Pattern: "/posts/{id}",
+		OpId:    "get_post",
+		Method:  uapi.GET,
+		Docs:    GetPostDocs,
+		Handler: GetPostRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+
+	uapi.Route{
+		Pattern: "/posts/{id}/comments",
+		OpId:    "get_post_comments",
+		Method:  uapi.GET,
+		Docs:    GetPostCommentsDocs,
+		Handler: GetPostCommentsRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+
+	uapi.Route{
+		Pattern: "/posts/{id}/visibility",
+		OpId:    "update_post_visibility",
+		Method:  uapi.PUT,
+		Docs:    UpdatePostVisibilityDocs,
+		Handler: UpdatePostVisibilityRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+}
+
+var postParamDoc = docs.Parameter{
+	Name:        "id",
+	Description: "The ID of the post",
+	Required:    true,
+	In:          "path",
+	Schema:      docs.IdSchema,
+}
@@ -0,0 +1,68 @@
+package posts
+
+import (
+	"net/http"
+
+	"clawmark/api"
+	"clawmark/state"
+	"clawmark/types"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var compiledMessages = uapi.CompileValidationErrors(types.UpdateVisibility{})
+
+func UpdatePostVisibilityDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Update Post Visibility",
+		Description: "Changes who can read a post of the authorized user. Users already notified of a mention keep the notification but can no longer read the post if they are outside the new audience.",
+		Params:      []docs.Parameter{postParamDoc},
+		Req:         types.UpdateVisibility{},
+	}
+}
+
+func UpdatePostVisibilityRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+
+	if err != nil {
+		return uapi.DefaultResponse(http.StatusNotFound)
+	}
+
+	var payload types.UpdateVisibility
+
+	hresp, ok := uapi.MarshalReq(r, &payload)
+
+	if !ok {
+		return hresp
+	}
+
+	if err := state.Validator.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		return uapi.ValidatorErrorResponse(compiledMessages, errors)
+	}
+
+	res := state.Pool.WithContext(d.Context).Model(&types.Post{}).Where("id = ? AND user_id = ?", id, userID).Update("visibility", payload.Visibility)
+
+	if res.Error != nil {
+		d.Logger.Error("Failed to update post visibility", zap.Error(res.Error))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	if res.RowsAffected == 0 {
+		return uapi.DefaultResponse(http.StatusNotFound)
+	}
+
+	return uapi.DefaultResponse(http.StatusNoContent)
+}
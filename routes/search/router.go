@@ -0,0 +1,94 @@
+package search
+
+import (
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+
+	"clawmark/api"
+	"clawmark/search"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 50
+)
+
+type Router struct{}
+
+func (b Router) Tag() (string, string) {
+	return "Search", "Full-text search of posts and users. Queries support quoted phrases, OR and -excluded words."
+}
+
+func (b Router) Routes(r *chi.Mux) {
+	uapi.Route{
+		Pattern: "/search/posts",
+		OpId:    "search_posts",
+		Method:  uapi.GET,
+		Docs:    SearchPostsDocs,
+		Handler: SearchPostsRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+
+	uapi.Route{
+		Pattern: "/search/users",
+		OpId:    "search_users",
+		Method:  uapi.GET,
+		Docs:    SearchUsersDocs,
+		Handler: SearchUsersRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+}
+
+var queryParams = []docs.Parameter{
+	{
+		Name:        "q",
+		Description: "The search query, at most 200 characters",
+		Required:    true,
+		In:          "query",
+		Schema:      docs.IdSchema,
+	},
+	{
+		Name:        "limit",
+		Description: "The maximum number of results to return (1-50, default 20)",
+		Required:    false,
+		In:          "query",
+		Schema:      docs.IntSchema,
+	},
+	{
+		Name:        "cursor",
+		Description: "The cursor of the page to fetch, omit for the first page",
+		Required:    false,
+		In:          "query",
+		Schema:      docs.IdSchema,
+	},
+}
+
+// Returns the query and limit of a search, or the error response if either is invalid
+func parseQuery(r *http.Request) (string, int, *uapi.HttpResponse) {
+	q := r.URL.Query().Get("q")
+
+	if q == "" || utf8.RuneCountInString(q) > search.MaxQueryLength {
+		resp := uapi.NewError(http.StatusBadRequest, uapi.ErrBadRequest, "q must be between 1 and 200 characters")
+		return "", 0, &resp
+	}
+
+	limit := defaultLimit
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		var err error
+		limit, err = strconv.Atoi(l)
+
+		if err != nil || limit < 1 || limit > maxLimit {
+			resp := uapi.NewError(http.StatusBadRequest, uapi.ErrBadRequest, "limit must be between 1 and 50")
+			return "", 0, &resp
+		}
+	}
+
+	return q, limit, nil
+}
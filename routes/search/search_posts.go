@@ -0,0 +1,142 @@
+package search
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"clawmark/api"
+	"clawmark/posttext"
+	"clawmark/search"
+	"clawmark/types"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"go.uber.org/zap"
+)
+
+func SearchPostsDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Search Posts",
+		Description: "Searches the content of posts, by relevance or newest first. Each result has a snippet of the content with the matching words highlighted.",
+		Params: slices.Concat(queryParams, []docs.Parameter{
+			{
+				Name:        "sort",
+				Description: "The order of the results: relevance (the default) or recent",
+				Required:    false,
+				In:          "query",
+				Schema:      docs.IdSchema,
+			},
+			{
+				Name:        "tag",
+				Description: "Only return posts with the tag",
+				Required:    false,
+				In:          "query",
+				Schema:      docs.IdSchema,
+			},
+			{
+				Name:        "author",
+				Description: "Only return posts by the user with this username",
+				Required:    false,
+				In:          "query",
+				Schema:      docs.IdSchema,
+			},
+			{
+				Name:        "since",
+				Description: "Only return posts created at or after this RFC 3339 timestamp or date (YYYY-MM-DD)",
+				Required:    false,
+				In:          "query",
+				Schema:      docs.IdSchema,
+			},
+			{
+				Name:        "until",
+				Description: "Only return posts created before this RFC 3339 timestamp or date (YYYY-MM-DD)",
+				Required:    false,
+				In:          "query",
+				Schema:      docs.IdSchema,
+			},
+		}),
+		Resp: types.PostSearchResults{},
+	}
+}
+
+// Parses an RFC 3339 timestamp or a date, which is midnight UTC
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.DateOnly, s)
+}
+
+func SearchPostsRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	text, limit, errResp := parseQuery(r)
+
+	if errResp != nil {
+		return *errResp
+	}
+
+	params := r.URL.Query()
+
+	q := search.PostQuery{
+		Viewer: userID,
+		Text:   text,
+		Author: params.Get("author"),
+		Sort:   params.Get("sort"),
+		Cursor: params.Get("cursor"),
+		Limit:  limit,
+	}
+
+	if q.Sort == "" {
+		q.Sort = search.SortRelevance
+	}
+
+	if !search.IsSort(q.Sort) {
+		return uapi.NewError(http.StatusBadRequest, uapi.ErrBadRequest, "sort must be one of relevance, recent")
+	}
+
+	if tag := params.Get("tag"); tag != "" {
+		q.Tag = posttext.NormalizeTag(tag)
+
+		if q.Tag == "" {
+			return uapi.NewError(http.StatusBadRequest, uapi.ErrBadRequest, "tag is invalid")
+		}
+	}
+
+	var err error
+
+	if since := params.Get("since"); since != "" {
+		if q.Since, err = parseTime(since); err != nil {
+			return uapi.NewError(http.StatusBadRequest, uapi.ErrBadRequest, "since must be an RFC 3339 timestamp or a date")
+		}
+	}
+
+	if until := params.Get("until"); until != "" {
+		if q.Until, err = parseTime(until); err != nil {
+			return uapi.NewError(http.StatusBadRequest, uapi.ErrBadRequest, "until must be an RFC 3339 timestamp or a date")
+		}
+	}
+
+	results, err := search.Posts(d.Context, q)
+
+	if errors.Is(err, search.ErrInvalidCursor) {
+		return uapi.NewError(http.StatusBadRequest, uapi.ErrBadRequest, "cursor is invalid")
+	}
+
+	if err != nil {
+		d.Logger.Error("Failed to search posts", zap.Error(err))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	return uapi.HttpResponse{
+		Json: results,
+	}
+}
@@ -0,0 +1,53 @@
+package search
+
+import (
+	"errors"
+	"net/http"
+
+	"clawmark/api"
+	"clawmark/search"
+	"clawmark/types"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"go.uber.org/zap"
+)
+
+func SearchUsersDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Search Users",
+		Description: "Searches users by username and bio, best match first. Usernames also match partially or when slightly misspelled.",
+		Params:      queryParams,
+		Resp:        types.UserSearchResults{},
+	}
+}
+
+func SearchUsersRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	text, limit, errResp := parseQuery(r)
+
+	if errResp != nil {
+		return *errResp
+	}
+
+	results, err := search.Users(d.Context, userID, text, r.URL.Query().Get("cursor"), limit)
+
+	if errors.Is(err, search.ErrInvalidCursor) {
+		return uapi.NewError(http.StatusBadRequest, uapi.ErrBadRequest, "cursor is invalid")
+	}
+
+	if err != nil {
+		d.Logger.Error("Failed to search users", zap.Error(err))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	return uapi.HttpResponse{
+		Json: results,
+	}
+}
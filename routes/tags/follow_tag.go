@@ -0,0 +1,49 @@
+package tags
+
+import (
+	"errors"
+	"net/http"
+
+	"clawmark/api"
+	"clawmark/hashtags"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"go.uber.org/zap"
+)
+
+func FollowTagDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Follow Tag",
+		Description: "Follows a tag, posts with it rank higher in your feed. Up to 200 tags can be followed.",
+		Params:      []docs.Parameter{tagParamDoc},
+	}
+}
+
+func FollowTagRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	tag, ok := tagParam(r)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusNotFound)
+	}
+
+	err := hashtags.Follow(d.Context, userID, tag)
+
+	if errors.Is(err, hashtags.ErrTooManyFollowed) {
+		return uapi.NewError(http.StatusConflict, uapi.ErrConflict, "You can follow at most 200 tags")
+	}
+
+	if err != nil {
+		d.Logger.Error("Failed to follow tag", zap.Error(err))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	return uapi.DefaultResponse(http.StatusNoContent)
+}
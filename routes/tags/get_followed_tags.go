@@ -0,0 +1,42 @@
+package tags
+
+import (
+	"net/http"
+
+	"clawmark/api"
+	"clawmark/hashtags"
+	"clawmark/types"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"go.uber.org/zap"
+)
+
+func GetFollowedTagsDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Get Followed Tags",
+		Description: "Returns the tags the authorized user follows.",
+		Params:      []docs.Parameter{},
+		Resp:        types.TagList{},
+	}
+}
+
+func GetFollowedTagsRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	followed, err := hashtags.Followed(d.Context, userID)
+
+	if err != nil {
+		d.Logger.Error("Failed to fetch followed tags", zap.Error(err))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	return uapi.HttpResponse{
+		Json: types.TagList{Tags: followed},
+	}
+}
@@ -0,0 +1,56 @@
+package tags
+
+import (
+	"net/http"
+
+	"clawmark/api"
+	"clawmark/hashtags"
+	"clawmark/types"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"go.uber.org/zap"
+)
+
+var tagParamDoc = docs.Parameter{
+	Name:        "tag",
+	Description: "The tag, with or without the #. Tags are case-insensitive",
+	Required:    true,
+	In:          "path",
+	Schema:      docs.IdSchema,
+}
+
+func GetTagDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Get Tag",
+		Description: "Returns a tag with the number of posts and followers it has. Only posts the authorized user would find on the tag page are counted.",
+		Params:      []docs.Parameter{tagParamDoc},
+		Resp:        types.Tag{},
+	}
+}
+
+func GetTagRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	tag, ok := tagParam(r)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusNotFound)
+	}
+
+	t, err := hashtags.Get(d.Context, userID, tag)
+
+	if err != nil {
+		d.Logger.Error("Failed to fetch tag", zap.Error(err))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	return uapi.HttpResponse{
+		Json: t,
+	}
+}
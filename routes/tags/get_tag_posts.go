@@ -0,0 +1,88 @@
+package tags
+
+import (
+	"net/http"
+	"strconv"
+
+	"clawmark/api"
+	"clawmark/blocks"
+	"clawmark/posts"
+	"clawmark/state"
+	"clawmark/types"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultPostLimit = 20
+	maxPostLimit     = 100
+)
+
+func GetTagPostsDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Get Tag Posts",
+		Description: "Returns the posts with a tag, newest first. Pass `next_cursor` of a page as `cursor` to fetch the next one.",
+		Params: []docs.Parameter{
+			tagParamDoc,
+			{
+				Name:        "limit",
+				Description: "The maximum number of posts to return (1-100, default 20)",
+				Required:    false,
+				In:          "query",
+				Schema:      docs.IntSchema,
+			},
+			{
+				Name:        "cursor",
+				Description: "The cursor of the page to fetch, omit for the first page",
+				Required:    false,
+				In:          "query",
+				Schema:      docs.IdSchema,
+			},
+		},
+		Resp: types.PostList{},
+	}
+}
+
+func GetTagPostsRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	tag, ok := tagParam(r)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusNotFound)
+	}
+
+	limit := defaultPostLimit
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		var err error
+		limit, err = strconv.Atoi(l)
+
+		if err != nil || limit < 1 || limit > maxPostLimit {
+			return uapi.NewError(http.StatusBadRequest, uapi.ErrBadRequest, "limit must be between 1 and 100")
+		}
+	}
+
+	query, err := posts.Paginate(state.Pool.WithContext(d.Context).Scopes(posts.Listed(userID), blocks.ExcludeHidden(userID, "posts.user_id")).Where("tags @> ARRAY[?]::text[]", tag), r.URL.Query().Get("cursor"), limit)
+
+	if err != nil {
+		return uapi.NewError(http.StatusBadRequest, uapi.ErrBadRequest, "cursor is invalid")
+	}
+
+	var found []types.Post
+	if err := posts.Preload(query).Find(&found).Error; err != nil {
+		d.Logger.Error("Failed to fetch tag posts", zap.Error(err))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	return uapi.HttpResponse{
+		Json: posts.Page(found, limit),
+	}
+}
@@ -0,0 +1,80 @@
+package tags
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"clawmark/api"
+	"clawmark/hashtags"
+	"clawmark/types"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"go.uber.org/zap"
+)
+
+const defaultTrendingLimit = 10
+
+func GetTrendingTagsDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Get Trending Tags",
+		Description: "Returns the tags used in the most new public posts of public accounts over a sliding window. Results are cached for up to a minute.",
+		Params: []docs.Parameter{
+			{
+				Name:        "window",
+				Description: "The window to count posts over: " + strings.Join(hashtags.Windows(), ", ") + " (default day)",
+				Required:    false,
+				In:          "query",
+				Schema:      docs.IdSchema,
+			},
+			{
+				Name:        "limit",
+				Description: "The maximum number of tags to return (1-50, default 10)",
+				Required:    false,
+				In:          "query",
+				Schema:      docs.IntSchema,
+			},
+		},
+		Resp: types.TrendingTagList{},
+	}
+}
+
+func GetTrendingTagsRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	if _, ok := api.UserID(d); !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	window := r.URL.Query().Get("window")
+
+	if window == "" {
+		window = "day"
+	}
+
+	if !hashtags.IsWindow(window) {
+		return uapi.NewError(http.StatusBadRequest, uapi.ErrBadRequest, "window must be one of "+strings.Join(hashtags.Windows(), ", "))
+	}
+
+	limit := defaultTrendingLimit
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		var err error
+		limit, err = strconv.Atoi(l)
+
+		if err != nil || limit < 1 || limit > hashtags.MaxTrending {
+			return uapi.NewError(http.StatusBadRequest, uapi.ErrBadRequest, "limit must be between 1 and 50")
+		}
+	}
+
+	list, err := hashtags.Trending(d.Context, window, limit)
+
+	if err != nil {
+		d.Logger.Error("Failed to fetch trending tags", zap.Error(err))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	return uapi.HttpResponse{
+		Json: list,
+	}
+}
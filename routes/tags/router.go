@@ -0,0 +1,87 @@
+package tags
+
+import (
+	"net/http"
+	"net/url"
+
+	"clawmark/api"
+	"clawmark/posttext"
+	"clawmark/uapi"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type Router struct{}
+
+func (b Router) Tag() (string, string) {
+	return "Tags", "Tags group posts by topic. Hashtags in post content become tags automatically, and tags can be followed to see more of them in your feed."
+}
+
+func (b Router) Routes(r *chi.Mux) {
+	uapi.Route{
+		Pattern: "/trending/tags",
+		OpId:    "get_trending_tags",
+		Method:  uapi.GET,
+		Docs:    GetTrendingTagsDocs,
+		Handler: GetTrendingTagsRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+
+	uapi.Route{
+		Pattern: "/followed/tags",
+		OpId:    "get_followed_tags",
+		Method:  uapi.GET,
+		Docs:    GetFollowedTagsDocs,
+		Handler: GetFollowedTagsRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+
+	uapi.Route{
+		Pattern: "/tags/{tag}",
+		OpId:    "get_tag",
+		Method:  uapi.GET,
+		Docs:    GetTagDocs,
+		Handler: GetTagRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+
+	uapi.Route{
+		Pattern: "/tags/{tag}/posts",
+		OpId:    "get_tag_posts",
+		Method:  uapi.GET,
+		Docs:    GetTagPostsDocs,
+		Handler: GetTagPostsRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+
+	uapi.Route{
+		Pattern: "/tags/{tag}/follow",
+		OpId:    "follow_tag",
+		Method:  uapi.PUT,
+		Docs:    FollowTagDocs,
+		Handler: FollowTagRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+
+	uapi.Route{
+		Pattern: "/tags/{tag}/follow",
+		OpId:    "unfollow_tag",
+		Method:  uapi.DELETE,
+		Docs:    UnfollowTagDocs,
+		Handler: UnfollowTagRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+}
+
+// Returns the normalized tag in the path, ok is false if it is not a valid tag
+func tagParam(r *http.Request) (string, bool) {
+	tag := chi.URLParam(r, "tag")
+
+	if unescaped, err := url.PathUnescape(tag); err == nil {
+		tag = unescaped
+	}
+
+	tag = posttext.NormalizeTag(tag)
+
+	return tag, tag != ""
+}
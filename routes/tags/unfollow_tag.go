@@ -0,0 +1,42 @@
+package tags
+
+import (
+	"net/http"
+
+	"clawmark/api"
+	"clawmark/hashtags"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"go.uber.org/zap"
+)
+
+func UnfollowTagDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Unfollow Tag",
+		Description: "Stops following a tag.",
+		Params:      []docs.Parameter{tagParamDoc},
+	}
+}
+
+func UnfollowTagRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	tag, ok := tagParam(r)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusNotFound)
+	}
+
+	if err := hashtags.Unfollow(d.Context, userID, tag); err != nil {
+		d.Logger.Error("Failed to unfollow tag", zap.Error(err))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	return uapi.DefaultResponse(http.StatusNoContent)
+}
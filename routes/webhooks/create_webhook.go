@@ -0,0 +1,127 @@
+package webhooks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"clawmark/api"
+	"clawmark/netguard"
+	"clawmark/state"
+	"clawmark/types"
+	"clawmark/uapi"
+	hooks "clawmark/webhooks"
+
+	docs "clawmark/doclib"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+// Maximum number of webhooks a user may register
+const maxWebhooks = 10
+
+var compiledMessages = uapi.CompileValidationErrors(types.CreateWebhook{})
+
+func CreateWebhookDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Create Webhook",
+		Description: "Registers a webhook for the authorized user, the URL must point to a public host. Supported event types are: " + strings.Join(hooks.Events(), ", ") + ".\n\nThe signing secret is only returned once.",
+		Params:      []docs.Parameter{},
+		Req:         types.CreateWebhook{},
+		Resp:        types.CreatedWebhook{},
+	}
+}
+
+func CreateWebhookRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	var payload types.CreateWebhook
+
+	hresp, ok := uapi.MarshalReq(r, &payload)
+
+	if !ok {
+		return hresp
+	}
+
+	err := state.Validator.Struct(payload)
+
+	if err != nil {
+		errors := err.(validator.ValidationErrors)
+		return uapi.ValidatorErrorResponse(compiledMessages, errors)
+	}
+
+	for _, event := range payload.Events {
+		if !hooks.IsEvent(event) {
+			msg := "Unknown event type: " + event
+			return uapi.ErrorResponse(uapi.Error{
+				Code:    uapi.ErrValidation,
+				Status:  http.StatusBadRequest,
+				Message: msg,
+				Fields:  map[string]string{"Events": msg},
+			})
+		}
+	}
+
+	// Deliveries are refused at connection time as well, this only reports the mistake early
+	if u, err := url.Parse(payload.URL); err != nil || netguard.CheckHost(d.Context, u.Hostname()) != nil {
+		msg := "URL must point to a public host"
+		return uapi.ErrorResponse(uapi.Error{
+			Code:    uapi.ErrValidation,
+			Status:  http.StatusBadRequest,
+			Message: msg,
+			Fields:  map[string]string{"URL": msg},
+		})
+	}
+
+	var count int64
+	err = state.Pool.WithContext(d.Context).Model(&types.WebhookEndpoint{}).Where("user_id = ?", userID).Count(&count).Error
+
+	if err != nil {
+		d.Logger.Error("Failed to count webhooks", zap.Error(err))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	if count >= maxWebhooks {
+		return uapi.NewError(http.StatusBadRequest, uapi.ErrBadRequest, "You cannot have more than "+strconv.Itoa(maxWebhooks)+" webhooks")
+	}
+
+	secret := make([]byte, 32)
+
+	_, err = rand.Read(secret)
+
+	if err != nil {
+		d.Logger.Error("Failed to generate webhook secret", zap.Error(err))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	endpoint := types.WebhookEndpoint{
+		UserID:  userID,
+		URL:     payload.URL,
+		Secret:  hex.EncodeToString(secret),
+		Events:  payload.Events,
+		Enabled: true,
+	}
+
+	err = state.Pool.WithContext(d.Context).Create(&endpoint).Error
+
+	if err != nil {
+		d.Logger.Error("Failed to create webhook", zap.Error(err))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	return uapi.HttpResponse{
+		Status: http.StatusCreated,
+		Json: types.CreatedWebhook{
+			Webhook: toWebhook(endpoint),
+			Secret:  endpoint.Secret,
+		},
+	}
+}
@@ -0,0 +1,59 @@
+package webhooks
+
+import (
+	"net/http"
+
+	"clawmark/api"
+	"clawmark/state"
+	"clawmark/types"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func DeleteWebhookDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Delete Webhook",
+		Description: "Deletes a webhook of the authorized user. Its pending deliveries and delivery log are removed.",
+		Params: []docs.Parameter{
+			{
+				Name:        "id",
+				Description: "The ID of the webhook",
+				Required:    true,
+				In:          "path",
+				Schema:      docs.IdSchema,
+			},
+		},
+	}
+}
+
+func DeleteWebhookRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+
+	if err != nil {
+		return uapi.DefaultResponse(http.StatusNotFound)
+	}
+
+	res := state.Pool.WithContext(d.Context).Where("id = ? AND user_id = ?", id, userID).Delete(&types.WebhookEndpoint{})
+
+	if res.Error != nil {
+		d.Logger.Error("Failed to delete webhook", zap.Error(res.Error))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	if res.RowsAffected == 0 {
+		return uapi.DefaultResponse(http.StatusNotFound)
+	}
+
+	return uapi.DefaultResponse(http.StatusNoContent)
+}
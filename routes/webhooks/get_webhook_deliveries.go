@@ -0,0 +1,112 @@
+package webhooks
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"clawmark/api"
+	"clawmark/state"
+	"clawmark/types"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 100
+)
+
+func GetWebhookDeliveriesDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Get Webhook Deliveries",
+		Description: "Returns the delivery log of a webhook, newest first.",
+		Params: []docs.Parameter{
+			{
+				Name:        "id",
+				Description: "The ID of the webhook",
+				Required:    true,
+				In:          "path",
+				Schema:      docs.IdSchema,
+			},
+			{
+				Name:        "limit",
+				Description: "The maximum number of deliveries to return (1-100, default 50)",
+				Required:    false,
+				In:          "query",
+				Schema:      docs.IntSchema,
+			},
+		},
+		Resp: types.WebhookDeliveryList{},
+	}
+}
+
+func GetWebhookDeliveriesRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+
+	if err != nil {
+		return uapi.DefaultResponse(http.StatusNotFound)
+	}
+
+	limit := defaultDeliveryLimit
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		limit, err = strconv.Atoi(l)
+
+		if err != nil || limit < 1 || limit > maxDeliveryLimit {
+			return uapi.NewError(http.StatusBadRequest, uapi.ErrBadRequest, "limit must be between 1 and 100")
+		}
+	}
+
+	var endpoint types.WebhookEndpoint
+	err = state.Pool.WithContext(d.Context).Select("id").First(&endpoint, "id = ? AND user_id = ?", id, userID).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return uapi.DefaultResponse(http.StatusNotFound)
+	}
+
+	if err != nil {
+		d.Logger.Error("Failed to fetch webhook", zap.Error(err))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	var deliveries []types.WebhookDelivery
+	err = state.Pool.WithContext(d.Context).Where("endpoint_id = ?", id).Order("created_at DESC").Limit(limit).Find(&deliveries).Error
+
+	if err != nil {
+		d.Logger.Error("Failed to fetch webhook deliveries", zap.Error(err))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	list := types.WebhookDeliveryList{Deliveries: []types.WebhookDeliveryLog{}}
+
+	for _, delivery := range deliveries {
+		list.Deliveries = append(list.Deliveries, types.WebhookDeliveryLog{
+			ID:             delivery.ID,
+			Event:          delivery.Event,
+			Status:         delivery.Status,
+			Attempts:       delivery.Attempts,
+			LastStatusCode: delivery.LastStatusCode,
+			LastError:      delivery.LastError,
+			NextAttemptAt:  delivery.NextAttemptAt,
+			DeliveredAt:    delivery.DeliveredAt,
+			CreatedAt:      delivery.CreatedAt,
+		})
+	}
+
+	return uapi.HttpResponse{
+		Json: list,
+	}
+}
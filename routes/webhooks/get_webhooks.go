@@ -0,0 +1,59 @@
+package webhooks
+
+import (
+	"net/http"
+
+	"clawmark/api"
+	"clawmark/state"
+	"clawmark/types"
+	"clawmark/uapi"
+
+	docs "clawmark/doclib"
+
+	"go.uber.org/zap"
+)
+
+func GetWebhooksDocs() *docs.Doc {
+	return &docs.Doc{
+		Summary:     "Get Webhooks",
+		Description: "Returns the webhooks of the authorized user.",
+		Params:      []docs.Parameter{},
+		Resp:        types.WebhookList{},
+	}
+}
+
+func GetWebhooksRoute(d uapi.RouteData, r *http.Request) uapi.HttpResponse {
+	userID, ok := api.UserID(d)
+
+	if !ok {
+		return uapi.DefaultResponse(http.StatusUnauthorized)
+	}
+
+	var endpoints []types.WebhookEndpoint
+	err := state.Pool.WithContext(d.Context).Where("user_id = ?", userID).Order("created_at DESC").Find(&endpoints).Error
+
+	if err != nil {
+		d.Logger.Error("Failed to fetch webhooks", zap.Error(err))
+		return uapi.DefaultResponse(http.StatusInternalServerError)
+	}
+
+	list := types.WebhookList{Webhooks: []types.Webhook{}}
+
+	for _, endpoint := range endpoints {
+		list.Webhooks = append(list.Webhooks, toWebhook(endpoint))
+	}
+
+	return uapi.HttpResponse{
+		Json: list,
+	}
+}
+
+func toWebhook(endpoint types.WebhookEndpoint) types.Webhook {
+	return types.Webhook{
+		ID:        endpoint.ID,
+		URL:       endpoint.URL,
+		Events:    endpoint.Events,
+		Enabled:   endpoint.Enabled,
+		CreatedAt: endpoint.CreatedAt,
+	}
+}
@@ -0,0 +1,52 @@
+package webhooks
+
+import (
+	"clawmark/api"
+	"clawmark/uapi"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type Router struct{}
+
+func (b Router) Tag() (string, string) {
+	return "Webhooks", "Webhooks let you receive events (such as new followers, comments and likes) as signed HTTP requests to your own server."
+}
+
+func (b Router) Routes(r *chi.Mux) {
+	uapi.Route{
+		Pattern: "/webhooks",
+		OpId:    "get_webhooks",
+		Method:  uapi.GET,
+		Docs:    GetWebhooksDocs,
+		Handler: GetWebhooksRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+
+	uapi.Route{
+		Pattern: "/webhooks",
+		OpId:    "create_webhook",
+		Method:  uapi.POST,
+		Docs:    CreateWebhookDocs,
+		Handler: CreateWebhookRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+
+	uapi.Route{
+		Pattern: "/webhooks/{id}",
+		OpId:    "delete_webhook",
+		Method:  uapi.DELETE,
+		Docs:    DeleteWebhookDocs,
+		Handler: DeleteWebhookRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+
+	uapi.Route{
+		Pattern: "/webhooks/{id}/deliveries",
+		OpId:    "get_webhook_deliveries",
+		Method:  uapi.GET,
+		Docs:    GetWebhookDeliveriesDocs,
+		Handler: GetWebhookDeliveriesRoute,
+		Auth:    []uapi.AuthType{{Type: api.TargetTypeUser}},
+	}.Route(r)
+}
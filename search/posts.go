@@ -0,0 +1,137 @@
+package search
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"clawmark/blocks"
+	"clawmark/posts"
+	"clawmark/state"
+	"clawmark/types"
+
+	"github.com/google/uuid"
+)
+
+// A search of posts, all filters are optional
+type PostQuery struct {
+	// The user searching, unlisted posts, posts they may not read and posts of users they blocked, muted or were blocked by are left out
+	Viewer uuid.UUID
+
+	Text string
+
+	// Normalized tag the posts must have
+	Tag string
+
+	// Username of the author, case-insensitive
+	Author string
+
+	// Only posts created at or after Since and before Until
+	Since time.Time
+	Until time.Time
+
+	Sort   string
+	Cursor string
+	Limit  int
+}
+
+type postHit struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	Rank      float32
+	Headline  string
+}
+
+// Searches posts, returns ErrInvalidCursor if the cursor is malformed or from another sort order
+func Posts(ctx context.Context, q PostQuery) (types.PostSearchResults, error) {
+	results := types.PostSearchResults{Results: []types.PostSearchResult{}}
+	rank := "ts_rank_cd(posts.search_vector, query)"
+
+	db := state.Pool.WithContext(ctx).Table("posts").
+		Select("posts.id, posts.created_at, "+rank+" AS rank, ts_headline('simple', translate(posts.content, ?, ''), query, ?) AS headline", startSel+stopSel, headlineOptions).
+		Joins("CROSS JOIN websearch_to_tsquery('simple', ?) AS query", q.Text).
+		Where("posts.search_vector @@ query").
+		Scopes(posts.Listed(q.Viewer), blocks.ExcludeHidden(q.Viewer, "posts.user_id"))
+
+	if q.Tag != "" {
+		db = db.Where("posts.tags @> ARRAY[?]::text[]", q.Tag)
+	}
+
+	if q.Author != "" {
+		db = db.Joins("JOIN users ON users.id = posts.user_id").Where("lower(users.username) = ?", strings.ToLower(q.Author))
+	}
+
+	if !q.Since.IsZero() {
+		db = db.Where("posts.created_at >= ?", q.Since)
+	}
+
+	if !q.Until.IsZero() {
+		db = db.Where("posts.created_at < ?", q.Until)
+	}
+
+	if q.Cursor != "" {
+		c, err := parseCursor(q.Cursor, q.Sort)
+
+		if err != nil {
+			return results, err
+		}
+
+		if q.Sort == SortRecent {
+			db = db.Where("(posts.created_at, posts.id) < (?, ?)", c.CreatedAt, c.ID)
+		} else {
+			db = db.Where("("+rank+", posts.id) < (?, ?)", c.Rank, c.ID)
+		}
+	}
+
+	if q.Sort == SortRecent {
+		db = db.Order("posts.created_at DESC, posts.id DESC")
+	} else {
+		db = db.Order("rank DESC, posts.id DESC")
+	}
+
+	var hits []postHit
+	if err := db.Limit(q.Limit + 1).Scan(&hits).Error; err != nil {
+		return results, err
+	}
+
+	if len(hits) > q.Limit {
+		hits = hits[:q.Limit]
+		last := hits[len(hits)-1]
+		results.NextCursor = cursor{Sort: q.Sort, Rank: last.Rank, CreatedAt: last.CreatedAt, ID: last.ID}.String()
+	}
+
+	if len(hits) == 0 {
+		return results, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(hits))
+	for _, h := range hits {
+		ids = append(ids, h.ID)
+	}
+
+	var found []types.Post
+	if err := posts.Preload(state.Pool.WithContext(ctx)).Where("id IN ?", ids).Find(&found).Error; err != nil {
+		return results, err
+	}
+
+	byID := make(map[uuid.UUID]types.Post, len(found))
+	for _, p := range found {
+		byID[p.ID] = p
+	}
+
+	for _, h := range hits {
+		// Deleted since the search
+		p, ok := byID[h.ID]
+		if !ok {
+			continue
+		}
+
+		results.Results = append(results.Results, types.PostSearchResult{
+			Post:    posts.ToPost(p),
+			Snippet: snippet(h.Headline),
+			Rank:    h.Rank,
+		})
+	}
+
+	return results, nil
+}
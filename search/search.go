@@ -0,0 +1,105 @@
+// Full-text search of posts and users
+//
+// Posts and users have generated tsvector columns (see the 0007_search
+// migration) queried with websearch_to_tsquery, so quoted phrases, OR and
+// -excluded words work as in web search engines. Usernames are also matched
+// by trigram similarity, finding users from partial or misspelled names.
+package search
+
+import (
+	"encoding/base64"
+	"errors"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	SortRelevance = "relevance"
+	SortRecent    = "recent"
+
+	MaxQueryLength = 200
+
+	// Marks matches in headlines, replaced by <mark> once the headline is escaped. Stripped from
+	// the text first so content cannot forge them
+	startSel = "\x01"
+	stopSel  = "\x02"
+
+	headlineOptions = `StartSel="` + startSel + `", StopSel="` + stopSel + `", MaxWords=35, MinWords=15, MaxFragments=2, FragmentDelimiter=" … "`
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Returns whether the sort order exists
+func IsSort(sort string) bool {
+	return sort == SortRelevance || sort == SortRecent
+}
+
+// Escapes a headline from ts_headline and marks its matches
+func snippet(headline string) string {
+	s := html.EscapeString(headline)
+	s = strings.ReplaceAll(s, startSel, "<mark>")
+	return strings.ReplaceAll(s, stopSel, "</mark>")
+}
+
+// Position after the last result of a page, results are ordered by either rank or creation time and then ID
+type cursor struct {
+	Sort      string
+	Rank      float32
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (c cursor) String() string {
+	key := strconv.FormatFloat(float64(c.Rank), 'g', -1, 32)
+
+	if c.Sort == SortRecent {
+		key = strconv.FormatInt(c.CreatedAt.UnixMicro(), 10)
+	}
+
+	return base64.RawURLEncoding.EncodeToString([]byte(c.Sort + "_" + key + "_" + c.ID.String()))
+}
+
+// Parses a cursor of a page sorted by sort, cursors of other sort orders are invalid
+func parseCursor(s, sort string) (cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+
+	if err != nil {
+		return cursor{}, ErrInvalidCursor
+	}
+
+	parts := strings.Split(string(raw), "_")
+
+	if len(parts) != 3 || parts[0] != sort {
+		return cursor{}, ErrInvalidCursor
+	}
+
+	c := cursor{Sort: sort}
+
+	if c.ID, err = uuid.Parse(parts[2]); err != nil {
+		return cursor{}, ErrInvalidCursor
+	}
+
+	if sort == SortRecent {
+		micros, err := strconv.ParseInt(parts[1], 10, 64)
+
+		if err != nil {
+			return cursor{}, ErrInvalidCursor
+		}
+
+		c.CreatedAt = time.UnixMicro(micros)
+		return c, nil
+	}
+
+	rank, err := strconv.ParseFloat(parts[1], 32)
+
+	if err != nil {
+		return cursor{}, ErrInvalidCursor
+	}
+
+	c.Rank = float32(rank)
+	return c, nil
+}
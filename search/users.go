@@ -0,0 +1,72 @@
+package search
+
+import (
+	"context"
+	"strings"
+
+	"clawmark/blocks"
+	"clawmark/state"
+	"clawmark/types"
+
+	"github.com/google/uuid"
+)
+
+type userHit struct {
+	ID        uuid.UUID
+	Username  string
+	AvatarURL string
+	Bio       string
+	Rank      float32
+	Headline  string
+}
+
+// Searches users by username and bio, ranked by the better of the full-text rank and the similarity of the username.
+// Users the viewer blocked or was blocked by are left out, muted users are still found so they can be unmuted.
+// Returns ErrInvalidCursor if the cursor is malformed
+func Users(ctx context.Context, viewerID uuid.UUID, text string, after string, limit int) (types.UserSearchResults, error) {
+	results := types.UserSearchResults{Results: []types.UserSearchResult{}}
+	rank := "GREATEST(ts_rank_cd(users.search_vector, query), similarity(lower(users.username), t.term))"
+
+	db := state.Pool.WithContext(ctx).Table("users").
+		Select("users.id, users.username, users.avatar_url, users.bio, "+rank+" AS rank, ts_headline('simple', translate(coalesce(users.bio, ''), ?, ''), query, ?) AS headline", startSel+stopSel, headlineOptions).
+		Joins("CROSS JOIN websearch_to_tsquery('simple', ?) AS query", text).
+		Joins("CROSS JOIN lower(?::text) AS t(term)", strings.ToLower(text)).
+		Where("(users.search_vector @@ query OR lower(users.username) % t.term)").
+		Scopes(blocks.ExcludeBlocked(viewerID, "users.id"))
+
+	if after != "" {
+		c, err := parseCursor(after, SortRelevance)
+
+		if err != nil {
+			return results, err
+		}
+
+		db = db.Where("("+rank+", users.id) < (?, ?)", c.Rank, c.ID)
+	}
+
+	var hits []userHit
+	if err := db.Order("rank DESC, users.id DESC").Limit(limit + 1).Scan(&hits).Error; err != nil {
+		return results, err
+	}
+
+	if len(hits) > limit {
+		hits = hits[:limit]
+		last := hits[len(hits)-1]
+		results.NextCursor = cursor{Sort: SortRelevance, Rank: last.Rank, ID: last.ID}.String()
+	}
+
+	for _, h := range hits {
+		results.Results = append(results.Results, types.UserSearchResult{
+			User: types.PartialUser{
+				ID:        h.ID,
+				Username:  h.Username,
+				AvatarURL: h.AvatarURL,
+			},
+			Bio:     h.Bio,
+			Snippet: snippet(h.Headline),
+			Rank:    h.Rank,
+		})
+	}
+
+	return results, nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"clawmark/state"
+
+	"go.uber.org/zap"
+)
+
+func serve(server *http.Server, ln net.Listener) {
+	err := server.Serve(ln)
+
+	if !errors.Is(err, http.ErrServerClosed) {
+		state.Logger.Error("Server failed due to unexpected error", zap.Error(err))
+	}
+}
+
+// Stops accepting connections and drains in-flight requests, then runs the shutdown hooks stopping background
+// workers and closing the database and Redis, all within the shutdown timeout
+func shutdown(server *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), state.Config.Server.ShutdownTimeout)
+	defer cancel()
+
+	// Fails readiness checks so load balancers stop routing to us while we drain
+	state.Draining.Store(true)
+
+	err := server.Shutdown(ctx)
+
+	if err != nil {
+		state.Logger.Error("Failed to drain in-flight requests before the deadline", zap.Error(err))
+		server.Close()
+	}
+
+	state.RunShutdownHooks(ctx)
+
+	state.Logger.Info("Shutdown complete")
+	state.Logger.Sync()
+}
@@ -0,0 +1,106 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"clawmark/config"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	// Level of Logger, changed on config reload
+	LogLevel = zap.NewAtomicLevelAt(zap.DebugLevel)
+
+	configPath  string
+	liveConfig  atomic.Pointer[config.Config]
+	reloadMu    sync.Mutex
+	reloadHooks []func(cfg *config.Config)
+)
+
+// Same as snippets.CreateZap but with a level that can be changed at runtime
+func CreateLogger() *zap.Logger {
+	core := zapcore.NewCore(
+		zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+		zapcore.AddSync(os.Stdout),
+		LogLevel,
+	)
+
+	return zap.New(core)
+}
+
+func setLogLevel(level string) {
+	lvl, err := zapcore.ParseLevel(level)
+
+	if err != nil {
+		lvl = zap.DebugLevel
+	}
+
+	LogLevel.SetLevel(lvl)
+}
+
+// Returns the current config
+//
+// Unlike Config, this reflects settings changed by a reload. Anything reading
+// a setting tagged reload:"true" should use this
+func CurrentConfig() *config.Config {
+	if cfg := liveConfig.Load(); cfg != nil {
+		return cfg
+	}
+
+	return Config
+}
+
+// Registers a function to be called with the new config after a successful reload
+func OnReload(fn func(cfg *config.Config)) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	reloadHooks = append(reloadHooks, fn)
+}
+
+// Re-reads and validates the config, swapping in reloadable settings
+//
+// Connection settings and other settings requiring a restart are left untouched.
+// On error the current config is kept
+func ReloadConfig() error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	next, err := config.Load(configPath)
+
+	if err != nil {
+		return err
+	}
+
+	err = Validator.Struct(next)
+
+	if err != nil {
+		return fmt.Errorf("config validation error: %w", err)
+	}
+
+	merged, changes := config.Merge(liveConfig.Load(), next)
+
+	setLogLevel(merged.Server.LogLevel)
+	liveConfig.Store(merged)
+
+	for _, change := range changes {
+		if change.Applied {
+			Logger.Info("[state/reload] Setting changed", zap.String("setting", change.Path), zap.Any("old", change.Old), zap.Any("new", change.New))
+		} else {
+			Logger.Warn("[state/reload] Setting changed but requires a restart", zap.String("setting", change.Path), zap.Any("old", change.Old), zap.Any("new", change.New))
+		}
+	}
+
+	Logger.Info("[state/reload] Config reloaded", zap.Int("changes", len(changes)))
+
+	for _, hook := range reloadHooks {
+		hook(merged)
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package state
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// Set once the server has started draining for shutdown or an upgrade
+	Draining atomic.Bool
+
+	shutdownMu    sync.Mutex
+	shutdownHooks []func(ctx context.Context)
+)
+
+// Registers a function to be called on shutdown once in-flight requests have drained, used to stop background
+// workers and close connections. Hooks run in reverse order of registration like defers, so whatever was set up
+// first is torn down last. ctx is cancelled at the shutdown deadline
+func OnShutdown(fn func(ctx context.Context)) {
+	shutdownMu.Lock()
+	defer shutdownMu.Unlock()
+
+	shutdownHooks = append(shutdownHooks, fn)
+}
+
+// Runs all shutdown hooks, last registered first
+func RunShutdownHooks(ctx context.Context) {
+	shutdownMu.Lock()
+	hooks := shutdownHooks
+	shutdownMu.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i](ctx)
+	}
+}
+
+// Closes the database pool and Redis client
+func closeConnections() {
+	if Pool != nil {
+		if db, err := Pool.DB(); err == nil {
+			if err := db.Close(); err != nil {
+				Logger.Error("[state/closeConnections] Failed to close database pool", zap.Error(err))
+			}
+		}
+	}
+
+	if Redis != nil {
+		if err := Redis.Close(); err != nil {
+			Logger.Error("[state/closeConnections] Failed to close Redis client", zap.Error(err))
+		}
+	}
+}
@@ -2,10 +2,11 @@ package state
 
 import (
 	"context"
-	"os"
 
 	"clawmark/config"
-	"clawmark/types"
+	"clawmark/metrics"
+	"clawmark/migrations"
+	"clawmark/tracing"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/go-playground/validator/v10/non-standard/validators"
@@ -14,7 +15,6 @@ import (
 	"github.com/redis/go-redis/v9"
 
 	"go.uber.org/zap"
-	"gopkg.in/yaml.v3"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -28,43 +28,83 @@ var (
 	Config    *config.Config
 )
 
-func Setup() {
-	Validator.RegisterValidation("notblank", validators.NotBlank)
-	Validator.RegisterValidation("nospaces", snippets.ValidatorNoSpaces)
-	Validator.RegisterValidation("https", snippets.ValidatorIsHttps)
-	Validator.RegisterValidation("httporhttps", snippets.ValidatorIsHttpOrHttps)
+// Loads and validates the config, this does not connect to any services
+//
+// See config.Load for how the config file and environment variables are layered
+func LoadConfig(path string) {
+	registerValidations()
 
-	genconfig.GenConfig(config.Config{})
+	genconfig.GenConfig(config.Sample)
 
-	cfg, err := os.ReadFile("config.yaml")
+	var err error
+	Config, err = config.Load(path)
 	if err != nil {
-		panic("Failed to read config file: " + err.Error())
+		panic(err.Error())
 	}
 
-	err = yaml.Unmarshal(cfg, &Config)
+	err = Validator.Struct(Config)
 	if err != nil {
-		panic("Failed to parse config file: " + err.Error())
+		panic("config validation error: " + err.Error())
 	}
 
-	err = Validator.Struct(Config)
+	configPath = path
+	liveConfig.Store(Config)
+	setLogLevel(Config.Server.LogLevel)
+}
+
+// Loads the config like LoadConfig but only validates the docs settings, so commands which only describe the API
+// such as gen-ts run without database or Redis URLs and other secrets
+func LoadDocsConfig(path string) {
+	registerValidations()
+
+	var err error
+	Config, err = config.Load(path)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	err = Validator.Struct(Config.Docs)
 	if err != nil {
 		panic("config validation error: " + err.Error())
 	}
 
-	// Initalize Gorm connection
-	Pool, err = gorm.Open(postgres.Open(Config.Database.DatabaseURL), &gorm.Config{})
-    if err != nil {
-        panic("Failed to connect to database: %v" + err.Error())
-    }
-
-	Pool.AutoMigrate(&types.User{})
-	Pool.AutoMigrate(&types.Post{})
-	Pool.AutoMigrate(&types.PostPlugin{})
-	Pool.AutoMigrate(&types.Comment{})
-	Pool.AutoMigrate(&types.Like{})
-	Pool.AutoMigrate(&types.Dislike{})
-	Pool.AutoMigrate(&types.Follow{})
-	
+	liveConfig.Store(Config)
+}
+
+func registerValidations() {
+	Validator.RegisterValidation("notblank", validators.NotBlank)
+	Validator.RegisterValidation("nospaces", snippets.ValidatorNoSpaces)
+	Validator.RegisterValidation("https", snippets.ValidatorIsHttps)
+	Validator.RegisterValidation("httporhttps", snippets.ValidatorIsHttpOrHttps)
+}
+
+func Setup(configPath string) {
+	LoadConfig(configPath)
+
+	// Initialize Logger
+	Logger = CreateLogger()
+
+	err := tracing.Setup(Config.Tracing, Config.Server.Env)
+	if err != nil {
+		panic("Failed to set up tracing: " + err.Error())
+	}
+
+	// Flushed last so spans from draining requests and workers are exported
+	OnShutdown(func(ctx context.Context) {
+		if err := tracing.Shutdown(ctx); err != nil {
+			Logger.Error("[state/Setup] Failed to flush traces", zap.Error(err))
+		}
+	})
+
+	ConnectDatabase()
+
+	if !Config.Database.SkipMigrations {
+		_, err = migrations.Up(Context, Pool, migrations.Options{Logger: Logger})
+		if err != nil {
+			panic("Failed to run migrations: " + err.Error())
+		}
+	}
+
 	// Initialize Redis connection
 	rOptions, err := redis.ParseURL(Config.Database.RedisURL)
 	if err != nil {
@@ -72,10 +112,33 @@ func Setup() {
 	}
 
 	Redis = redis.NewClient(rOptions)
+	Redis.AddHook(metrics.RedisHook{})
+	Redis.AddHook(tracing.RedisHook{})
+
 	if err := Redis.Ping(Context).Err(); err != nil {
 		panic("Failed to connect to Redis: " + err.Error())
 	}
 
-	// Initialize Logger
-	Logger = snippets.CreateZap()
+	OnShutdown(func(context.Context) {
+		closeConnections()
+	})
+}
+
+// Initalizes the Gorm connection, LoadConfig must be called first
+func ConnectDatabase() {
+	var err error
+	Pool, err = gorm.Open(postgres.Open(Config.Database.DatabaseURL), &gorm.Config{})
+	if err != nil {
+		panic("Failed to connect to database: " + err.Error())
+	}
+
+	err = Pool.Use(metrics.GormPlugin{})
+	if err != nil {
+		panic("Failed to register database metrics: " + err.Error())
+	}
+
+	err = Pool.Use(tracing.GormPlugin{})
+	if err != nil {
+		panic("Failed to register database tracing: " + err.Error())
+	}
 }
@@ -0,0 +1,48 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"clawmark/uapi"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Starts the server span for a request named by its OpId, continuing any incoming traceparent.
+// Set as uapi.UAPIState.StartRequest
+func StartRequest(r uapi.Route, req *http.Request) (context.Context, func(status int)) {
+	ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+
+	ctx, span := Tracer().Start(ctx, r.OpId,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.HTTPRoute(r.Pattern),
+			semconv.URLPath(req.URL.Path),
+			semconv.UserAgentOriginal(req.UserAgent()),
+			attribute.String("http.request.id", uapi.RequestID(req.Context())),
+		),
+	)
+
+	return ctx, func(status int) {
+		if status == 0 {
+			// The client went away or the request timed out before a response was sent
+			span.SetAttributes(attribute.Bool("http.request.cancelled", true))
+			span.SetStatus(codes.Error, "request cancelled")
+		} else {
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+
+			if status >= 500 {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+		}
+
+		span.End()
+	}
+}
@@ -0,0 +1,66 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// go-redis hook creating a span per command or pipeline as a child of the span in ctx, register with client.AddHook
+type RedisHook struct{}
+
+func (RedisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (RedisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, span := startCommand(ctx, "redis."+cmd.Name(), attribute.String("db.operation.name", cmd.Name()))
+		err := next(ctx, cmd)
+		endCommand(span, err)
+
+		return err
+	}
+}
+
+func (RedisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		ctx, span := startCommand(ctx, "redis.pipeline", attribute.Int("db.operation.batch.size", len(cmds)))
+		err := next(ctx, cmds)
+		endCommand(span, err)
+
+		return err
+	}
+}
+
+// Commands outside a traced request (e.g. background workers polling) are not traced, they would each start a new trace
+func startCommand(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+
+	return Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemRedis),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+func endCommand(span trace.Span, err error) {
+	if !span.IsRecording() {
+		return
+	}
+
+	// Nil is a missing key (or a blocking pop timing out), not a failure
+	if err != nil && !errors.Is(err, redis.Nil) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
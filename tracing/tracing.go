@@ -0,0 +1,114 @@
+// OpenTelemetry tracing for API requests, database queries and Redis commands
+//
+// Spans are exported as configured in the tracing section of the config, W3C
+// traceparent headers on incoming requests are always honoured so traces
+// started by a caller are continued.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"clawmark/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentation = "clawmark"
+
+var provider *sdktrace.TracerProvider
+
+// Returns the tracer used for all clawmark spans
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentation)
+}
+
+// Installs the W3C trace context propagator and, unless the exporter is none, a tracer provider exporting spans
+func Setup(cfg config.Tracing, env string) error {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+
+	switch cfg.Exporter {
+	case "none":
+		return nil
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case "otlp":
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+
+		if len(cfg.Headers) > 0 {
+			headers := map[string]string{}
+
+			for _, h := range cfg.Headers {
+				if strings.TrimSpace(h) == "" {
+					continue
+				}
+
+				name, value, ok := strings.Cut(h, "=")
+
+				if !ok {
+					return fmt.Errorf("tracing header %q must be of the form name=value", h)
+				}
+
+				headers[strings.TrimSpace(name)] = strings.TrimSpace(value)
+			}
+
+			opts = append(opts, otlptracehttp.WithHeaders(headers))
+		}
+
+		// Exporting starts lazily, so a collector being down does not block startup
+		exporter, err = otlptracehttp.New(context.Background(), opts...)
+	default:
+		return fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to create %s trace exporter: %w", cfg.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+		semconv.DeploymentEnvironment(env),
+	))
+
+	if err != nil {
+		return fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(float64(cfg.SamplePercent)/100))),
+	)
+
+	otel.SetTracerProvider(provider)
+
+	return nil
+}
+
+// Flushes buffered spans and stops the exporter, does nothing if tracing is disabled
+func Shutdown(ctx context.Context) error {
+	if provider == nil {
+		return nil
+	}
+
+	return provider.Shutdown(ctx)
+}
@@ -0,0 +1,5 @@
+package types
+
+type UserList struct {
+	Users []PartialUser `json:"users" description:"The users, most recent first"`
+}
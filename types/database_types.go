@@ -8,7 +8,7 @@ import (
 
 // Base model to include ID as UUID
 type BaseModel struct {
-	ID uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	ID        uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
@@ -20,16 +20,18 @@ type User struct {
 	Password  string `gorm:"not null"`
 	AvatarURL string `gorm:"default:''"`
 	Bio       string `gorm:"type:text"`
+	Private   bool   `gorm:"not null;default:false"` // Posts are only readable by approved followers, see the follows package
 	Posts     []Post `gorm:"foreignKey:UserID"`
 }
 
 type Post struct {
 	BaseModel
-	UserID      uuid.UUID `gorm:"not null;index"`
-	Content     string    `gorm:"type:text;not null"`
-	Tags 	  []string  `gorm:"type:text[]"`
-	User        User      `gorm:"foreignKey:UserID"`
-	Comments    []Comment `gorm:"foreignKey:PostID"`
+	UserID      uuid.UUID    `gorm:"not null;index"`
+	Content     string       `gorm:"type:text;not null"`
+	Tags        []string     `gorm:"type:text[]"`
+	Visibility  string       `gorm:"not null;default:public"` // "public", "followers", "mutuals", "close_friends" or "unlisted", see posts.VisibleTo
+	User        User         `gorm:"foreignKey:UserID"`
+	Comments    []Comment    `gorm:"foreignKey:PostID"`
 	PostPlugins []PostPlugin `gorm:"foreignKey:PostID"`
 }
 
@@ -44,33 +46,154 @@ type Comment struct {
 
 type PostPlugin struct {
 	BaseModel
-	PostID    uuid.UUID   `gorm:"not null;index"`
-	Type      string `gorm:"not null"` // e.g., "image", "gif", "sticker"
-	URL       string `gorm:"not null"`
-	HTML      string
-	Post      Post `gorm:"foreignKey:PostID"`
+	PostID   uuid.UUID `gorm:"not null;index"`
+	Type     string    `gorm:"not null"` // e.g., "image", "gif", "sticker"
+	URL      string    `gorm:"not null"`
+	HTML     string
+	MediaID  *uuid.UUID `gorm:"index"` // Set if the URL points at uploaded media
+	Width    *int       // Dimensions and placeholder of uploaded images, set once processed
+	Height   *int
+	Blurhash string
+	Post     Post   `gorm:"foreignKey:PostID"`
+	Media    *Media `gorm:"foreignKey:MediaID"`
+}
+
+type Media struct {
+	BaseModel
+	UserID      uuid.UUID `gorm:"not null;index"`
+	Hash        string    `gorm:"not null;index"` // Hex SHA-256 of the stored content, uploads only differing in metadata share a stored object
+	Key         string    `gorm:"not null"`       // Key of the object in the storage backend
+	ContentType string    `gorm:"not null"`
+	Size        int64     `gorm:"not null"`
+	Status      string    `gorm:"not null;default:pending"` // "pending", "ready" or "failed", see media.Process
+	Width       *int      // Display dimensions, after applying the EXIF orientation
+	Height      *int
+	Blurhash    string
+	User        User           `gorm:"foreignKey:UserID"`
+	Variants    []MediaVariant `gorm:"foreignKey:MediaID"`
+}
+
+// A resized copy of an image
+type MediaVariant struct {
+	BaseModel
+	MediaID     uuid.UUID `gorm:"not null;index"`
+	Name        string    `gorm:"not null"` // e.g., "small", "medium", "large"
+	Key         string    `gorm:"not null"`
+	ContentType string    `gorm:"not null"`
+	Width       int       `gorm:"not null"`
+	Height      int       `gorm:"not null"`
+	Size        int64     `gorm:"not null"`
+}
+
+// A user mentioned in a post
+type Mention struct {
+	BaseModel
+	PostID uuid.UUID `gorm:"not null;uniqueIndex:idx_mentions_post_user"`
+	UserID uuid.UUID `gorm:"not null;index"`
+	Post   Post      `gorm:"foreignKey:PostID"`
+	User   User      `gorm:"foreignKey:UserID"`
+}
+
+type Notification struct {
+	BaseModel
+	UserID  uuid.UUID  `gorm:"not null;index"` // The user notified
+	ActorID uuid.UUID  `gorm:"not null"`       // The user whose action caused the notification
+	Type    string     `gorm:"not null"`       // e.g., "mention"
+	PostID  *uuid.UUID // The post the notification is about, if any
+	ReadAt  *time.Time
+	User    User  `gorm:"foreignKey:UserID"`
+	Actor   User  `gorm:"foreignKey:ActorID"`
+	Post    *Post `gorm:"foreignKey:PostID"`
+}
+
+// A tag followed by a user, posts with followed tags rank higher in the users feed
+type TagFollow struct {
+	BaseModel
+	UserID uuid.UUID `gorm:"not null;uniqueIndex:idx_tag_follows_user_tag"`
+	Tag    string    `gorm:"not null;uniqueIndex:idx_tag_follows_user_tag;index"` // Normalized, see posttext.NormalizeTag
+	User   User      `gorm:"foreignKey:UserID"`
 }
 
 type Like struct {
 	BaseModel
-	UserID    uuid.UUID `gorm:"not null;index"`
-	PostID    uuid.UUID `gorm:"not null;index"`
-	User      User `gorm:"foreignKey:UserID"`
-	Post      Post `gorm:"foreignKey:PostID"`
+	UserID uuid.UUID `gorm:"not null;index"`
+	PostID uuid.UUID `gorm:"not null;index"`
+	User   User      `gorm:"foreignKey:UserID"`
+	Post   Post      `gorm:"foreignKey:PostID"`
 }
 
 type Dislike struct {
 	BaseModel
-	UserID    uuid.UUID `gorm:"not null;index"`
-	PostID    uuid.UUID `gorm:"not null;index"`
-	User      User `gorm:"foreignKey:UserID"`
-	Post      Post `gorm:"foreignKey:PostID"`
+	UserID uuid.UUID `gorm:"not null;index"`
+	PostID uuid.UUID `gorm:"not null;index"`
+	User   User      `gorm:"foreignKey:UserID"`
+	Post   Post      `gorm:"foreignKey:PostID"`
 }
 
 type Follow struct {
 	BaseModel
 	FollowerID  uuid.UUID `gorm:"not null;index"`
 	FollowingID uuid.UUID `gorm:"not null;index"`
-	Follower    User `gorm:"foreignKey:FollowerID"`
-	Following   User `gorm:"foreignKey:FollowingID"`
+	Follower    User      `gorm:"foreignKey:FollowerID"`
+	Following   User      `gorm:"foreignKey:FollowingID"`
+}
+
+// A user blocked by another, neither can interact with the other or see their posts
+type Block struct {
+	BaseModel
+	BlockerID uuid.UUID `gorm:"not null;uniqueIndex:idx_blocks_blocker_blocked"`
+	BlockedID uuid.UUID `gorm:"not null;index"`
+	Blocker   User      `gorm:"foreignKey:BlockerID"`
+	Blocked   User      `gorm:"foreignKey:BlockedID"`
+}
+
+// A user muted by another, their posts and notifications are hidden from the muter only
+type Mute struct {
+	BaseModel
+	MuterID uuid.UUID `gorm:"not null;uniqueIndex:idx_mutes_muter_muted"`
+	MutedID uuid.UUID `gorm:"not null"`
+	Muter   User      `gorm:"foreignKey:MuterID"`
+	Muted   User      `gorm:"foreignKey:MutedID"`
+}
+
+// A request to follow a private account, deleted once approved or denied
+type FollowRequest struct {
+	BaseModel
+	RequesterID uuid.UUID `gorm:"not null;uniqueIndex:idx_follow_requests_requester_target"`
+	TargetID    uuid.UUID `gorm:"not null;index"`
+	Requester   User      `gorm:"foreignKey:RequesterID"`
+	Target      User      `gorm:"foreignKey:TargetID"`
+}
+
+// A user on the close friends list of another, who can read their close friends posts
+type CloseFriend struct {
+	BaseModel
+	UserID   uuid.UUID `gorm:"not null;uniqueIndex:idx_close_friends_user_friend"`
+	FriendID uuid.UUID `gorm:"not null;index"`
+	User     User      `gorm:"foreignKey:UserID"`
+	Friend   User      `gorm:"foreignKey:FriendID"`
+}
+
+type WebhookEndpoint struct {
+	BaseModel
+	UserID  uuid.UUID `gorm:"not null;index"`
+	URL     string    `gorm:"not null"`
+	Secret  string    `gorm:"not null"`
+	Events  []string  `gorm:"type:text[]"`
+	Enabled bool      `gorm:"not null;default:true"`
+	User    User      `gorm:"foreignKey:UserID"`
+}
+
+type WebhookDelivery struct {
+	BaseModel
+	EndpointID     uuid.UUID `gorm:"not null;index"`
+	Event          string    `gorm:"not null"`
+	Payload        string    `gorm:"type:jsonb;not null"`
+	Status         string    `gorm:"not null;index"` // e.g., "pending", "retrying", "delivered", "failed", "cancelled"
+	Attempts       int       `gorm:"not null;default:0"`
+	LastStatusCode int
+	LastError      string `gorm:"type:text"`
+	NextAttemptAt  *time.Time
+	DeliveredAt    *time.Time
+	Endpoint       WebhookEndpoint `gorm:"foreignKey:EndpointID;constraint:OnDelete:CASCADE"`
 }
@@ -0,0 +1,29 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type FollowStatus struct {
+	Status string `json:"status" enum:"following,requested" description:"Whether you now follow the user, or have asked to as their account is private"`
+}
+
+type FollowRequestEntry struct {
+	ID        uuid.UUID   `json:"id" description:"The ID of the follow request"`
+	Requester PartialUser `json:"requester" description:"The user asking to follow you"`
+	CreatedAt time.Time   `json:"created_at" description:"When the request was made"`
+}
+
+type FollowRequestList struct {
+	Requests []FollowRequestEntry `json:"requests" description:"The pending follow requests, oldest first"`
+}
+
+type UpdatePrivacy struct {
+	Private *bool `json:"private" validate:"required" msg:"Private is required" description:"Whether the account is private, making it public approves all pending follow requests"`
+}
+
+type Privacy struct {
+	Private bool `json:"private" description:"Whether the account is private"`
+}
@@ -0,0 +1,11 @@
+package types
+
+type HealthCheck struct {
+	Status    string `json:"status" enum:"ok,fail" description:"The status of the dependency"`
+	LatencyMs int64  `json:"latency_ms" description:"How long the check took in milliseconds"`
+}
+
+type Health struct {
+	Status string                 `json:"status" enum:"ok,fail" description:"ok if every check passed"`
+	Checks map[string]HealthCheck `json:"checks,omitempty" description:"The result of each dependency check"`
+}
@@ -0,0 +1,48 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type MediaFile struct {
+	ID          uuid.UUID `json:"id" description:"The ID of the media"`
+	URL         string    `json:"url" description:"The public URL of the media"`
+	ContentType string    `json:"content_type" description:"The MIME type, detected from the content"`
+	Size        int64     `json:"size" description:"The size in bytes"`
+	Hash        string    `json:"hash" description:"Hex SHA-256 of the stored content, after metadata such as EXIF is stripped"`
+	Status      string    `json:"status" enum:"pending,ready,failed" description:"Whether variants, dimensions and the blurhash have been generated, failed media is served as uploaded"`
+	Width       *int      `json:"width" description:"The width in pixels, once processed"`
+	Height      *int      `json:"height" description:"The height in pixels, once processed"`
+	Blurhash    string    `json:"blurhash,omitempty" description:"A blurhash placeholder, once processed"`
+	Variants    []Variant `json:"variants" description:"Resized copies, only generated for sizes smaller than the original and never for GIFs"`
+	CreatedAt   time.Time `json:"created_at" description:"When the media was uploaded"`
+}
+
+type Variant struct {
+	Name        string `json:"name" enum:"small,medium,large" description:"The size of the variant"`
+	URL         string `json:"url" description:"The public URL of the variant"`
+	ContentType string `json:"content_type" description:"The MIME type of the variant"`
+	Width       int    `json:"width" description:"The width in pixels"`
+	Height      int    `json:"height" description:"The height in pixels"`
+	Size        int64  `json:"size" description:"The size in bytes"`
+}
+
+type CreateUpload struct {
+	Size int64 `json:"size" validate:"required,min=1" msg:"Size must be at least 1 byte" description:"The total size of the upload in bytes"`
+}
+
+type Upload struct {
+	ID        uuid.UUID  `json:"id" description:"The ID of the upload"`
+	Size      int64      `json:"size" description:"The total size of the upload in bytes"`
+	Offset    int64      `json:"offset" description:"The number of bytes received so far, the next chunk must start here"`
+	ExpiresAt time.Time  `json:"expires_at" description:"When the upload is discarded if it has not been completed"`
+	Media     *MediaFile `json:"media,omitempty" description:"The uploaded media, set once the upload is complete"`
+}
+
+type AttachMedia struct {
+	MediaID uuid.UUID `json:"media_id" validate:"required" msg:"A media ID is required" description:"The ID of the media to attach"`
+	Type    string    `json:"type" validate:"omitempty,oneof=image gif sticker" msg:"Type must be one of image, gif or sticker" description:"The plugin type, defaults to gif for GIFs and image otherwise"`
+	Alt     string    `json:"alt" validate:"max=500" msg:"Alt text must be at most 500 characters" description:"Alt text describing the image"`
+}
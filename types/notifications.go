@@ -0,0 +1,21 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type NotificationEntry struct {
+	ID        uuid.UUID   `json:"id" description:"The ID of the notification"`
+	Type      string      `json:"type" enum:"mention,follow,follow_request" description:"What happened"`
+	Actor     PartialUser `json:"actor" description:"The user whose action caused the notification"`
+	PostID    *uuid.UUID  `json:"post_id,omitempty" description:"The post the notification is about, if any"`
+	Read      bool        `json:"read" description:"Whether the notification has been read"`
+	CreatedAt time.Time   `json:"created_at" description:"When the notification was created"`
+}
+
+type NotificationList struct {
+	Notifications []NotificationEntry `json:"notifications" description:"The most recent notifications, newest first"`
+	Unread        int64               `json:"unread" description:"The total number of unread notifications"`
+}
@@ -0,0 +1,33 @@
+package types
+
+import "github.com/google/uuid"
+
+type CreatePlugin struct {
+	Type        string     `json:"type" validate:"required" msg:"A plugin type is required" description:"The plugin type, see Get Plugin Types"`
+	URL         string     `json:"url" validate:"omitempty,url,httporhttps,max=2048" msg:"URL must be a valid http(s) URL" description:"The URL to embed, for youtube and link plugins"`
+	MediaID     *uuid.UUID `json:"media_id" description:"The ID of uploaded media, for image, gif and sticker plugins"`
+	Title       string     `json:"title" validate:"max=200" msg:"Title must be at most 200 characters" description:"Plain text title of the embed or alt text of the image"`
+	Description string     `json:"description" validate:"max=2000" msg:"Description must be at most 2000 characters" description:"Description of a link, basic formatting HTML is allowed and everything else is removed"`
+	Image       string     `json:"image" validate:"omitempty,url,https,max=2048" msg:"Image must be a valid https URL" description:"Image shown in the preview of a link"`
+}
+
+type Plugin struct {
+	ID       uuid.UUID  `json:"id" description:"The ID of the plugin"`
+	PostID   uuid.UUID  `json:"post_id" description:"The ID of the post"`
+	Type     string     `json:"type" description:"The plugin type, e.g. image, gif or sticker"`
+	URL      string     `json:"url" description:"The URL of the plugin content, for uploaded media this accepts a size query parameter (small, medium, large or original)"`
+	HTML     string     `json:"html,omitempty" description:"HTML to render for the plugin"`
+	MediaID  *uuid.UUID `json:"media_id,omitempty" description:"The ID of the uploaded media, if the plugin is backed by an upload"`
+	Width    *int       `json:"width,omitempty" description:"The width of the uploaded image in pixels, once processed"`
+	Height   *int       `json:"height,omitempty" description:"The height of the uploaded image in pixels, once processed"`
+	Blurhash string     `json:"blurhash,omitempty" description:"A blurhash placeholder of the uploaded image, once processed"`
+}
+
+type PluginType struct {
+	Name        string `json:"name" description:"The name of the plugin type"`
+	Description string `json:"description" description:"What the plugin embeds and the fields it uses"`
+}
+
+type PluginTypeList struct {
+	Types []PluginType `json:"types" description:"The supported plugin types"`
+}
@@ -0,0 +1,40 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PostEntry struct {
+	ID         uuid.UUID   `json:"id" description:"The ID of the post"`
+	Author     PartialUser `json:"author" description:"The author of the post"`
+	Content    string      `json:"content" description:"The content of the post"`
+	Tags       []string    `json:"tags" description:"The normalized tags of the post, including the hashtags in its content"`
+	Visibility string      `json:"visibility" enum:"public,followers,mutuals,close_friends,unlisted" description:"Who can read the post besides its author, posts of private accounts are only readable by approved followers"`
+	Plugins    []Plugin    `json:"plugins" description:"Content embedded in the post"`
+	CreatedAt  time.Time   `json:"created_at" description:"When the post was created"`
+	UpdatedAt  time.Time   `json:"updated_at" description:"When the post was last edited"`
+}
+
+type PostList struct {
+	Posts      []PostEntry `json:"posts" description:"The posts of this page"`
+	NextCursor string      `json:"next_cursor,omitempty" description:"Pass as cursor to fetch the next page, absent on the last page"`
+}
+
+type UpdateVisibility struct {
+	Visibility string `json:"visibility" validate:"required,oneof=public followers mutuals close_friends unlisted" msg:"Visibility must be one of public, followers, mutuals, close_friends, unlisted" description:"Who can read the post besides its author"`
+}
+
+type CommentEntry struct {
+	ID        uuid.UUID   `json:"id" description:"The ID of the comment"`
+	PostID    uuid.UUID   `json:"post_id" description:"The ID of the post"`
+	Author    PartialUser `json:"author" description:"The author of the comment"`
+	Content   string      `json:"content" description:"The content of the comment"`
+	CreatedAt time.Time   `json:"created_at" description:"When the comment was created"`
+}
+
+type CommentList struct {
+	Comments   []CommentEntry `json:"comments" description:"The comments of this page, newest first"`
+	NextCursor string         `json:"next_cursor,omitempty" description:"Pass as cursor to fetch the next page, absent on the last page"`
+}
@@ -0,0 +1,24 @@
+package types
+
+type PostSearchResult struct {
+	Post    PostEntry `json:"post" description:"The matching post"`
+	Snippet string    `json:"snippet" description:"HTML excerpt of the content with the matching words wrapped in <mark>, everything else is escaped"`
+	Rank    float32   `json:"rank" description:"How well the post matches the query, higher is better"`
+}
+
+type PostSearchResults struct {
+	Results    []PostSearchResult `json:"results" description:"The matching posts of this page"`
+	NextCursor string             `json:"next_cursor,omitempty" description:"Pass as cursor to fetch the next page, absent on the last page"`
+}
+
+type UserSearchResult struct {
+	User    PartialUser `json:"user" description:"The matching user"`
+	Bio     string      `json:"bio" description:"The bio of the user"`
+	Snippet string      `json:"snippet" description:"HTML excerpt of the bio with the matching words wrapped in <mark>, everything else is escaped"`
+	Rank    float32     `json:"rank" description:"How well the user matches the query, higher is better"`
+}
+
+type UserSearchResults struct {
+	Results    []UserSearchResult `json:"results" description:"The matching users of this page"`
+	NextCursor string             `json:"next_cursor,omitempty" description:"Pass as cursor to fetch the next page, absent on the last page"`
+}
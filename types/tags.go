@@ -0,0 +1,22 @@
+package types
+
+type Tag struct {
+	Name      string `json:"name" description:"The normalized tag, without the #"`
+	Posts     int64  `json:"posts" description:"The number of posts with the tag"`
+	Followers int64  `json:"followers" description:"The number of users following the tag"`
+	Following bool   `json:"following" description:"Whether the authorized user follows the tag"`
+}
+
+type TagList struct {
+	Tags []string `json:"tags" description:"The tags, oldest followed first"`
+}
+
+type TrendingTag struct {
+	Name  string `json:"name" description:"The normalized tag, without the #"`
+	Posts int64  `json:"posts" description:"The number of posts with the tag created in the window"`
+}
+
+type TrendingTagList struct {
+	Window string        `json:"window" enum:"hour,day,week" description:"The window the tags were counted over"`
+	Tags   []TrendingTag `json:"tags" description:"The trending tags, most used first"`
+}
@@ -6,3 +6,25 @@ type Response struct {
 	Message *string           `json:"message,omitempty" description:"Message of the response"`
 	JSON    any               `json:"json,omitempty" description:"JSON data of the response"`
 }
+
+// Body of every error response
+type ApiError struct {
+	Success   bool              `json:"success" description:"Always false for errors"`
+	Code      string            `json:"code" description:"Stable machine-readable error code" enum:"bad_request,validation_failed,invalid_json,body_required,unauthorized,forbidden,not_found,endpoint_not_found,method_not_allowed,conflict,payload_too_large,unsupported_media_type,ratelimited,internal_error"`
+	Status    int               `json:"status" description:"HTTP status code of the response"`
+	Message   string            `json:"message" description:"Human-readable description of the error"`
+	Fields    map[string]string `json:"fields,omitempty" description:"Per-field messages for validation errors, keyed by field name"`
+	RequestID string            `json:"request_id,omitempty" description:"ID of the request, include this when reporting a problem"`
+}
+
+// RFC 9457 problem details, sent instead of ApiError when the client accepts application/problem+json
+type Problem struct {
+	Type      string            `json:"type" description:"URI identifying the error code, urn:clawmark:error:<code>"`
+	Title     string            `json:"title" description:"Short summary of the HTTP status"`
+	Status    int               `json:"status" description:"HTTP status code of the response"`
+	Detail    string            `json:"detail" description:"Human-readable description of the error"`
+	Instance  string            `json:"instance,omitempty" description:"Path of the request"`
+	Code      string            `json:"code" description:"Stable machine-readable error code, as in ApiError" enum:"bad_request,validation_failed,invalid_json,body_required,unauthorized,forbidden,not_found,endpoint_not_found,method_not_allowed,conflict,payload_too_large,unsupported_media_type,ratelimited,internal_error"`
+	Fields    map[string]string `json:"fields,omitempty" description:"Per-field messages for validation errors, keyed by field name"`
+	RequestID string            `json:"request_id,omitempty" description:"ID of the request, include this when reporting a problem"`
+}
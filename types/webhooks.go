@@ -0,0 +1,82 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PartialUser struct {
+	ID        uuid.UUID `json:"id" description:"The ID of the user"`
+	Username  string    `json:"username" description:"The username of the user"`
+	AvatarURL string    `json:"avatar_url" description:"The avatar URL of the user"`
+}
+
+// Envelope POSTed to webhook receivers
+type WebhookEvent[T any] struct {
+	ID        uuid.UUID `json:"id" description:"The ID of the delivery, stable across retries"`
+	Event     string    `json:"event" description:"The event type"`
+	CreatedAt time.Time `json:"created_at" description:"When the event occurred"`
+	Data      T         `json:"data" description:"Event specific data"`
+}
+
+type NewFollowerEvent struct {
+	Follower   PartialUser `json:"follower" description:"The user who followed you"`
+	FollowedAt time.Time   `json:"followed_at" description:"When the follow happened"`
+}
+
+type NewCommentEvent struct {
+	CommentID uuid.UUID   `json:"comment_id" description:"The ID of the comment"`
+	PostID    uuid.UUID   `json:"post_id" description:"The ID of the post that was commented on"`
+	Author    PartialUser `json:"author" description:"The author of the comment"`
+	Content   string      `json:"content" description:"The content of the comment"`
+}
+
+type PostLikedEvent struct {
+	PostID uuid.UUID   `json:"post_id" description:"The ID of the post that was liked"`
+	User   PartialUser `json:"user" description:"The user who liked the post"`
+}
+
+type MentionedEvent struct {
+	PostID  uuid.UUID   `json:"post_id" description:"The ID of the post you were mentioned in"`
+	Author  PartialUser `json:"author" description:"The author of the post"`
+	Content string      `json:"content" description:"The content of the post"`
+}
+
+type CreateWebhook struct {
+	URL    string   `json:"url" validate:"required,url,httporhttps" msg:"URL must be a valid http(s) URL" description:"The URL to deliver events to"`
+	Events []string `json:"events" validate:"required,min=1,unique" msg:"At least one event type is required" description:"The event types to subscribe to"`
+}
+
+type Webhook struct {
+	ID        uuid.UUID `json:"id" description:"The ID of the webhook"`
+	URL       string    `json:"url" description:"The URL events are delivered to"`
+	Events    []string  `json:"events" description:"The event types this webhook is subscribed to"`
+	Enabled   bool      `json:"enabled" description:"Whether the webhook is enabled"`
+	CreatedAt time.Time `json:"created_at" description:"When the webhook was created"`
+}
+
+type CreatedWebhook struct {
+	Webhook Webhook `json:"webhook" description:"The created webhook"`
+	Secret  string  `json:"secret" description:"The HMAC-SHA256 signing secret, this is only shown once"`
+}
+
+type WebhookList struct {
+	Webhooks []Webhook `json:"webhooks" description:"The webhooks of the user"`
+}
+
+type WebhookDeliveryLog struct {
+	ID             uuid.UUID  `json:"id" description:"The ID of the delivery"`
+	Event          string     `json:"event" description:"The event type"`
+	Status         string     `json:"status" enum:"pending,retrying,delivered,failed,cancelled" description:"The status of the delivery"`
+	Attempts       int        `json:"attempts" description:"The number of delivery attempts made"`
+	LastStatusCode int        `json:"last_status_code,omitempty" description:"The HTTP status code of the last attempt"`
+	LastError      string     `json:"last_error,omitempty" description:"The error of the last attempt"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty" description:"When the next attempt is scheduled"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty" description:"When the delivery succeeded"`
+	CreatedAt      time.Time  `json:"created_at" description:"When the event was queued"`
+}
+
+type WebhookDeliveryList struct {
+	Deliveries []WebhookDeliveryLog `json:"deliveries" description:"The most recent deliveries, newest first"`
+}
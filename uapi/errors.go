@@ -0,0 +1,195 @@
+package uapi
+
+import (
+	"net/http"
+	"strings"
+
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	"github.com/infinitybotlist/eureka/jsonimpl"
+	"go.uber.org/zap"
+)
+
+// A stable, machine-readable error code, clients should branch on these rather than on messages
+//
+// New codes must also be added to the enum on types.ApiError
+type ErrorCode string
+
+const (
+	ErrBadRequest       ErrorCode = "bad_request"
+	ErrValidation       ErrorCode = "validation_failed"
+	ErrInvalidJSON      ErrorCode = "invalid_json"
+	ErrBodyRequired     ErrorCode = "body_required"
+	ErrUnauthorized     ErrorCode = "unauthorized"
+	ErrForbidden        ErrorCode = "forbidden"
+	ErrNotFound         ErrorCode = "not_found"
+	ErrEndpointNotFound ErrorCode = "endpoint_not_found"
+	ErrMethodNotAllowed ErrorCode = "method_not_allowed"
+	ErrConflict         ErrorCode = "conflict"
+	ErrTooLarge         ErrorCode = "payload_too_large"
+	ErrUnsupportedType  ErrorCode = "unsupported_media_type"
+	ErrRatelimited      ErrorCode = "ratelimited"
+	ErrInternal         ErrorCode = "internal_error"
+)
+
+// A structured API error, turned into a response body by the DefaultResponder
+type Error struct {
+	Code    ErrorCode
+	Status  int
+	Message string
+	// Per-field messages for validation errors, keyed by field name
+	Fields map[string]string
+
+	// Set by ValidatorErrorResponse so messages can be rebuilt in the client's language
+	validation []fieldError
+}
+
+type fieldError struct {
+	// Message from the fields msg tag, if any
+	msg string
+	err validator.FieldError
+}
+
+// Builds the first and per-field messages for validation errors, in English if trans is nil
+func validationMessages(fields []fieldError, trans ut.Translator) (string, map[string]string) {
+	first := ""
+	messages := make(map[string]string, len(fields))
+
+	for i, f := range fields {
+		var msg string
+		switch {
+		case f.msg != "":
+			msg = translate(trans, f.msg) + " [" + f.err.Tag() + "]"
+		case trans != nil:
+			msg = f.err.Translate(trans)
+		default:
+			msg = f.err.Error()
+		}
+
+		if i == 0 {
+			first = msg
+		}
+
+		messages[f.err.StructField()] = msg
+	}
+
+	return first, messages
+}
+
+// Returns the translation of an English message, or the message itself if there is none
+func translate(trans ut.Translator, msg string) string {
+	if trans == nil {
+		return msg
+	}
+
+	translated, err := trans.T(msg)
+
+	if err != nil || translated == "" {
+		return msg
+	}
+
+	return translated
+}
+
+// Returns a copy of the error with its messages in the language the request asked for,
+// along with the locale used (empty if UAPIState.Translator is not set)
+func (e Error) Localize(req *http.Request) (Error, string) {
+	if State.Translator == nil || req == nil {
+		return e, ""
+	}
+
+	trans := State.Translator(req.Header.Get("Accept-Language"))
+
+	if len(e.validation) > 0 {
+		e.Message, e.Fields = validationMessages(e.validation, trans)
+		return e, trans.Locale()
+	}
+
+	e.Message = translate(trans, e.Message)
+
+	if len(e.Fields) > 0 {
+		fields := make(map[string]string, len(e.Fields))
+
+		for k, v := range e.Fields {
+			fields[k] = translate(trans, v)
+		}
+
+		e.Fields = fields
+	}
+
+	return e, trans.Locale()
+}
+
+// Sets headers on the response, Vary is added to rather than replaced as middleware may have set it
+func setHeaders(w http.ResponseWriter, headers map[string]string) {
+	for k, v := range headers {
+		if k == "Vary" {
+			w.Header().Add(k, v)
+		} else {
+			w.Header().Set(k, v)
+		}
+	}
+}
+
+// Localizes the error and returns its body and the headers to send with it
+func renderError(e Error, req *http.Request) (any, map[string]string) {
+	e, locale := e.Localize(req)
+	body, contentType := State.DefaultResponder.New(e, req)
+
+	headers := map[string]string{
+		"Content-Type": contentType,
+	}
+
+	if locale != "" {
+		headers["Content-Language"] = strings.ReplaceAll(locale, "_", "-")
+		headers["Vary"] = "Accept-Language"
+	}
+
+	return body, headers
+}
+
+func (e Error) Error() string {
+	return string(e.Code) + ": " + e.Message
+}
+
+// Returns a response for the error
+func ErrorResponse(e Error) HttpResponse {
+	return HttpResponse{
+		Status: e.Status,
+		Err:    &e,
+	}
+}
+
+// Shorthand for an ErrorResponse without field errors
+func NewError(status int, code ErrorCode, msg string) HttpResponse {
+	return ErrorResponse(Error{
+		Code:    code,
+		Status:  status,
+		Message: msg,
+	})
+}
+
+// Returns whether the client asked for RFC 9457 problem details
+func WantsProblem(req *http.Request) bool {
+	return req != nil && strings.Contains(req.Header.Get("Accept"), ProblemContentType)
+}
+
+const ProblemContentType = "application/problem+json"
+
+// Writes an error outside of a route handler, e.g. from middleware or the router's NotFound handler
+func WriteError(w http.ResponseWriter, req *http.Request, e Error) {
+	body, headers := renderError(e, req)
+
+	bytes, err := jsonimpl.Marshal(body)
+
+	if err != nil {
+		State.Logger.Error("[uapi/WriteError] Failed to marshal error", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	setHeaders(w, headers)
+
+	w.WriteHeader(e.Status)
+	w.Write(bytes)
+}
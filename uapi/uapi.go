@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"reflect"
 	"strings"
+	"time"
 
 	docs "clawmark/doclib"
 
@@ -14,36 +15,38 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/go-chi/chi/v5"
+	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
 	"golang.org/x/exp/slices"
 )
 
+// Messages used for the default error responses
 type UAPIConstants struct {
-	// String returned when the resource could not be found
+	// Message returned when the resource could not be found
 	ResourceNotFound string
 
-	// String returned when the request is invalid
+	// Message returned when the request is invalid
 	BadRequest string
 
-	// String returned when the user is not authorized (403)
+	// Message returned when the user is not authorized (403)
 	Forbidden string
 
-	// String returned when the user is not authorized (401)
+	// Message returned when the user is not authorized (401)
 	Unauthorized string
 
-	// String returned when the server encounters an internal error
+	// Message returned when the server encounters an internal error
 	InternalServerError string
 
-	// String returned when the method is not allowed
+	// Message returned when the method is not allowed
 	MethodNotAllowed string
 
-	// String returned when the body is required
+	// Message returned when the body is required
 	BodyRequired string
 }
 
 type UAPIDefaultResponder interface {
-	// Returns the msg with the response type
-	New(msg string, ctx map[string]string) any
+	// Returns the response body for an error and its content type, req may be used for content negotiation
+	New(e Error, req *http.Request) (body any, contentType string)
 }
 
 // This struct contains initialization data while loading UAPI (such as the current tag etc.)
@@ -61,6 +64,17 @@ type UAPIState struct {
 	BaseSanityCheck     func(r Route) error
 	PatchDocs           func(d *docs.Doc) *docs.Doc
 
+	// Called before every request, the returned context replaces the request context (e.g. to carry a trace span)
+	// and end is called with the status code sent (0 if the request was cancelled)
+	StartRequest func(r Route, req *http.Request) (ctx context.Context, end func(status int))
+
+	// Returns the translator for an Accept-Language header, used to localize error messages.
+	// If nil, errors are sent as is
+	Translator func(acceptLanguage string) ut.Translator
+
+	// Called after every request with the status code sent (0 if the request was cancelled) and how long it took
+	ObserveRequest func(r Route, req *http.Request, status int, elapsed time.Duration)
+
 	// Used in cache algo
 	Context context.Context
 
@@ -165,9 +179,11 @@ type Route struct {
 }
 
 type RouteData struct {
-	Context context.Context
-	Auth    AuthData
-	Props   map[string]string // Stores additional properties
+	Context   context.Context
+	Auth      AuthData
+	Props     map[string]string // Stores additional properties
+	RequestID string
+	Logger    *zap.Logger // Tags every line with the request ID and OpId
 }
 
 type Router interface {
@@ -337,39 +353,52 @@ func createRouteHandler(r Route, ro Router, pat string) {
 	}
 }
 
-func respond(ctx context.Context, w http.ResponseWriter, data chan HttpResponse) {
+// Writes the response, returning the status code sent (or 0 if the request was cancelled first)
+func respond(ctx context.Context, w http.ResponseWriter, req *http.Request, data chan HttpResponse) int {
 	select {
 	case <-ctx.Done():
-		return
+		return 0
 	case msg, ok := <-data:
 		if !ok {
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte(State.Constants.InternalServerError))
+			WriteError(w, req, internalError())
+			return http.StatusInternalServerError
+		}
+
+		if msg.Err != nil {
+			body, headers := renderError(*msg.Err, req)
+
+			if msg.Headers == nil {
+				msg.Headers = map[string]string{}
+			}
+
+			for k, v := range headers {
+				msg.Headers[k] = v
+			}
+
+			msg.Status = msg.Err.Status
+			msg.Json = body
 		}
 
 		if msg.Redirect != "" {
-			msg.Headers = map[string]string{
-				"Location":     msg.Redirect,
-				"Content-Type": "text/html; charset=utf-8",
+			if msg.Headers == nil {
+				msg.Headers = map[string]string{}
 			}
+
+			msg.Headers["Location"] = msg.Redirect
+			msg.Headers["Content-Type"] = "text/html; charset=utf-8"
 			msg.Data = "<a href=\"" + msg.Redirect + "\">Found</a>.\n"
 			msg.Status = http.StatusFound
 		}
 
-		if len(msg.Headers) > 0 {
-			for k, v := range msg.Headers {
-				w.Header().Set(k, v)
-			}
-		}
+		setHeaders(w, msg.Headers)
 
 		if msg.Json != nil {
 			bytes, err := jsonimpl.Marshal(msg.Json)
 
 			if err != nil {
 				State.Logger.Error("[uapi.respond] Failed to unmarshal JSON response", zap.Error(err), zap.Int("size", len(msg.Data)))
-				w.WriteHeader(http.StatusInternalServerError)
-				w.Write([]byte(State.Constants.InternalServerError))
-				return
+				WriteError(w, req, internalError())
+				return http.StatusInternalServerError
 			}
 
 			msg.Json = nil
@@ -377,17 +406,17 @@ func respond(ctx context.Context, w http.ResponseWriter, data chan HttpResponse)
 		}
 
 		if msg.Status == 0 {
-			w.WriteHeader(http.StatusOK)
-		} else {
-			w.WriteHeader(msg.Status)
+			msg.Status = http.StatusOK
 		}
 
+		w.WriteHeader(msg.Status)
+
 		if len(msg.Bytes) > 0 {
 			w.Write(msg.Bytes)
 		}
 
 		w.Write([]byte(msg.Data))
-		return
+		return msg.Status
 	}
 }
 
@@ -404,6 +433,8 @@ type HttpResponse struct {
 	Status int
 	// Redirect to a URL
 	Redirect string
+	// Structured error, rendered by the DefaultResponder in place of Json
+	Err *Error
 }
 
 func CompileValidationErrors(payload any) map[string]string {
@@ -425,37 +456,30 @@ func CompileValidationErrors(payload any) map[string]string {
 }
 
 func ValidatorErrorResponse(compiled map[string]string, v validator.ValidationErrors) HttpResponse {
-	var errors = make(map[string]string)
+	fields := make([]fieldError, 0, len(v))
 
-	firstError := ""
-
-	for i, err := range v {
+	for _, err := range v {
 		fname := err.StructField()
 		if strings.Contains(err.Field(), "[") {
 			// We have a array response, so we need to get the array name
 			fname = strings.Split(err.Field(), "[")[0] + "$arr"
 		}
 
-		field := compiled[fname]
-
-		var errorMsg string
-		if field != "" {
-			errorMsg = field + " [" + err.Tag() + "]"
-		} else {
-			errorMsg = err.Error()
-		}
-
-		if i == 0 {
-			firstError = errorMsg
-		}
-
-		errors[err.StructField()] = errorMsg
+		fields = append(fields, fieldError{
+			msg: compiled[fname],
+			err: err,
+		})
 	}
 
-	return HttpResponse{
-		Status: http.StatusBadRequest,
-		Json:   State.DefaultResponder.New(firstError, errors),
-	}
+	firstError, errors := validationMessages(fields, nil)
+
+	return ErrorResponse(Error{
+		Code:       ErrValidation,
+		Status:     http.StatusBadRequest,
+		Message:    firstError,
+		Fields:     errors,
+		validation: fields,
+	})
 }
 
 // Creates a default HTTP response based on the status code
@@ -463,61 +487,65 @@ func ValidatorErrorResponse(compiled map[string]string, v validator.ValidationEr
 func DefaultResponse(statusCode int) HttpResponse {
 	switch statusCode {
 	case http.StatusForbidden:
-		return HttpResponse{
-			Status: statusCode,
-			Data:   State.Constants.Forbidden,
-		}
+		return NewError(statusCode, ErrForbidden, State.Constants.Forbidden)
 	case http.StatusUnauthorized:
-		return HttpResponse{
-			Status: statusCode,
-			Data:   State.Constants.Unauthorized,
-		}
+		return NewError(statusCode, ErrUnauthorized, State.Constants.Unauthorized)
 	case http.StatusNotFound:
-		return HttpResponse{
-			Status: statusCode,
-			Data:   State.Constants.ResourceNotFound,
-		}
+		return NewError(statusCode, ErrNotFound, State.Constants.ResourceNotFound)
 	case http.StatusBadRequest:
-		return HttpResponse{
-			Status: statusCode,
-			Data:   State.Constants.BadRequest,
-		}
+		return NewError(statusCode, ErrBadRequest, State.Constants.BadRequest)
 	case http.StatusInternalServerError:
-		return HttpResponse{
-			Status: statusCode,
-			Data:   State.Constants.InternalServerError,
-		}
+		return NewError(statusCode, ErrInternal, State.Constants.InternalServerError)
 	case http.StatusMethodNotAllowed:
-		return HttpResponse{
-			Status: statusCode,
-			Data:   State.Constants.MethodNotAllowed,
-		}
+		return NewError(statusCode, ErrMethodNotAllowed, State.Constants.MethodNotAllowed)
+	case http.StatusConflict:
+		return NewError(statusCode, ErrConflict, http.StatusText(statusCode))
+	case http.StatusTooManyRequests:
+		return NewError(statusCode, ErrRatelimited, http.StatusText(statusCode))
 	case http.StatusNoContent, http.StatusOK:
 		return HttpResponse{
 			Status: http.StatusNoContent,
 		}
 	}
 
-	return HttpResponse{
-		Status: statusCode,
-		Data:   State.Constants.InternalServerError,
+	return NewError(statusCode, ErrInternal, State.Constants.InternalServerError)
+}
+
+func internalError() Error {
+	return Error{
+		Code:    ErrInternal,
+		Status:  http.StatusInternalServerError,
+		Message: State.Constants.InternalServerError,
 	}
 }
 
 func handle(r Route, w http.ResponseWriter, req *http.Request) {
+	var status int
+
+	start := time.Now()
+
+	if State.StartRequest != nil {
+		reqCtx, end := State.StartRequest(r, req)
+		req = req.WithContext(reqCtx)
+		defer func() {
+			end(status)
+		}()
+	}
+
 	ctx := req.Context()
-	resp := make(chan HttpResponse)
+	requestID := RequestID(ctx)
+	logger := State.Logger.With(zap.String("reqId", requestID), zap.String("operationId", r.OpId))
+
+	// Buffered so the handler can finish (and be collected) after the request is cancelled
+	resp := make(chan HttpResponse, 1)
 
 	go func() {
 		defer func() {
 			err := recover()
 
 			if err != nil {
-				State.Logger.Error("[uapi/handle] Request handler panic'd", zap.String("operationId", r.OpId), zap.String("method", req.Method), zap.String("endpointPattern", r.Pattern), zap.String("path", req.URL.Path), zap.Any("error", err))
-				resp <- HttpResponse{
-					Status: http.StatusInternalServerError,
-					Data:   State.Constants.InternalServerError,
-				}
+				logger.Error("[uapi/handle] Request handler panic'd", zap.String("method", req.Method), zap.String("endpointPattern", r.Pattern), zap.String("path", req.URL.Path), zap.Any("error", err), zap.Stack("stack"))
+				resp <- ErrorResponse(internalError())
 			}
 		}()
 
@@ -529,8 +557,10 @@ func handle(r Route, w http.ResponseWriter, req *http.Request) {
 		}
 
 		rd := &RouteData{
-			Context: ctx,
-			Auth:    authData,
+			Context:   ctx,
+			Auth:      authData,
+			RequestID: requestID,
+			Logger:    logger,
 		}
 
 		if State.RouteDataMiddleware != nil {
@@ -538,10 +568,7 @@ func handle(r Route, w http.ResponseWriter, req *http.Request) {
 			rd, err = State.RouteDataMiddleware(rd, req)
 
 			if err != nil {
-				resp <- HttpResponse{
-					Status: http.StatusInternalServerError,
-					Json:   State.DefaultResponder.New(err.Error(), nil),
-				}
+				resp <- NewError(http.StatusInternalServerError, ErrInternal, err.Error())
 				return
 			}
 		}
@@ -549,7 +576,11 @@ func handle(r Route, w http.ResponseWriter, req *http.Request) {
 		resp <- r.Handler(*rd, req)
 	}()
 
-	respond(ctx, w, resp)
+	status = respond(ctx, w, req, resp)
+
+	if State.ObserveRequest != nil {
+		State.ObserveRequest(r, req, status, time.Since(start))
+	}
 }
 
 // Read body
@@ -564,22 +595,14 @@ func marshalReq(r *http.Request, dst interface{}) (resp HttpResponse, ok bool) {
 	}
 
 	if len(bodyBytes) == 0 {
-		return HttpResponse{
-			Status: http.StatusBadRequest,
-			Data:   State.Constants.BodyRequired,
-		}, false
+		return NewError(http.StatusBadRequest, ErrBodyRequired, State.Constants.BodyRequired), false
 	}
 
 	err = jsonimpl.Unmarshal(bodyBytes, &dst)
 
 	if err != nil {
 		State.Logger.Error("[uapi/marshalReq] Failed to unmarshal JSON", zap.Error(err), zap.Int("size", len(bodyBytes)))
-		return HttpResponse{
-			Status: http.StatusBadRequest,
-			Json: State.DefaultResponder.New("Invalid JSON", map[string]string{
-				"error": err.Error(),
-			}),
-		}, false
+		return NewError(http.StatusBadRequest, ErrInvalidJSON, "Invalid JSON: "+err.Error()), false
 	}
 
 	return HttpResponse{}, true
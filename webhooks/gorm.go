@@ -0,0 +1,181 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"clawmark/types"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Key of the events deferred by a statement
+const deferredKey = "webhooks:deferred"
+
+// GORM plugin publishing new comment and post liked events for created comments and likes, and the events other
+// callbacks defer with Defer, once the statement has committed, register with db.Use
+//
+// Statements run in a transaction of the caller are committed by the caller, their events are not published.
+// Publish the events of Deferred after committing instead.
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string {
+	return "webhooks"
+}
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+
+	if err := cb.Create().After("gorm:after_create").Register("webhooks:after_create", deferCreated); err != nil {
+		return err
+	}
+
+	if err := cb.Create().After("gorm:commit_or_rollback_transaction").Register("webhooks:publish_create", publishDeferred); err != nil {
+		return err
+	}
+
+	return cb.Update().After("gorm:commit_or_rollback_transaction").Register("webhooks:publish_update", publishDeferred)
+}
+
+// Defers events until the statement of db commits, for GORM callbacks running within its transaction
+func Defer(db *gorm.DB, events ...Event) {
+	db.InstanceSet(deferredKey, append(Deferred(db), events...))
+}
+
+// Returns the events deferred by the statement of db which were not published yet
+func Deferred(db *gorm.DB) []Event {
+	v, _ := db.InstanceGet(deferredKey)
+	events, _ := v.([]Event)
+	return events
+}
+
+func statementContext(db *gorm.DB) context.Context {
+	if db.Statement.Context != nil {
+		return db.Statement.Context
+	}
+
+	return context.Background()
+}
+
+func publishDeferred(db *gorm.DB) {
+	events := Deferred(db)
+
+	if len(events) == 0 {
+		return
+	}
+
+	// A rolled back statement never happened, and one in a transaction of the caller has not committed yet
+	if db.Error != nil {
+		db.InstanceSet(deferredKey, []Event(nil))
+		return
+	}
+
+	if _, ok := db.Statement.ConnPool.(gorm.TxCommitter); ok {
+		return
+	}
+
+	db.InstanceSet(deferredKey, []Event(nil))
+	PublishAll(context.WithoutCancel(statementContext(db)), events)
+}
+
+func deferCreated(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return
+	}
+
+	table := db.Statement.Schema.Table
+
+	if table != "comments" && table != "likes" {
+		return
+	}
+
+	ctx := statementContext(db)
+	tx := db.Session(&gorm.Session{NewDB: true})
+
+	value := func(v reflect.Value, name string) any {
+		field := db.Statement.Schema.LookUpField(name)
+
+		if field == nil {
+			return nil
+		}
+
+		value, _ := field.ValueOf(ctx, v)
+		return value
+	}
+
+	eachModel := func(fn func(v reflect.Value)) {
+		rv := reflect.Indirect(db.Statement.ReflectValue)
+
+		switch rv.Kind() {
+		case reflect.Struct:
+			fn(rv)
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < rv.Len(); i++ {
+				fn(reflect.Indirect(rv.Index(i)))
+			}
+		}
+	}
+
+	eachModel(func(v reflect.Value) {
+		if db.Error != nil {
+			return
+		}
+
+		userID, _ := value(v, "UserID").(uuid.UUID)
+		postID, _ := value(v, "PostID").(uuid.UUID)
+
+		var post types.Post
+		err := tx.Select("id", "user_id").First(&post, "id = ?", postID).Error
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return
+		}
+
+		if err != nil {
+			db.AddError(err)
+			return
+		}
+
+		// Nobody needs to be told about interacting with their own post
+		if post.UserID == userID {
+			return
+		}
+
+		var user types.User
+		if err := tx.Select("id", "username", "avatar_url").First(&user, "id = ?", userID).Error; err != nil {
+			db.AddError(err)
+			return
+		}
+
+		author := types.PartialUser{
+			ID:        user.ID,
+			Username:  user.Username,
+			AvatarURL: user.AvatarURL,
+		}
+
+		event := Event{UserID: post.UserID}
+
+		if table == "comments" {
+			commentID, _ := value(v, "ID").(uuid.UUID)
+			content, _ := value(v, "Content").(string)
+
+			event.Name = EventNewComment
+			event.Data = types.NewCommentEvent{
+				CommentID: commentID,
+				PostID:    postID,
+				Author:    author,
+				Content:   content,
+			}
+		} else {
+			event.Name = EventPostLiked
+			event.Data = types.PostLikedEvent{
+				PostID: postID,
+				User:   author,
+			}
+		}
+
+		Defer(db, event)
+	})
+}
@@ -0,0 +1,184 @@
+// Outgoing webhook delivery
+//
+// Events are stored as WebhookDelivery rows and their IDs are queued in Redis,
+// workers then deliver them with a HMAC-SHA256 signature, retrying with
+// exponential backoff before moving them to a dead-letter list.
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	docs "clawmark/doclib"
+	"clawmark/state"
+	"clawmark/types"
+
+	"github.com/google/uuid"
+	"github.com/infinitybotlist/eureka/jsonimpl"
+	"go.uber.org/zap"
+)
+
+const (
+	EventNewFollower = "new_follower"
+	EventNewComment  = "new_comment"
+	EventPostLiked   = "post_liked"
+	EventMentioned   = "mentioned"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusRetrying  = "retrying"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+// Redis keys
+const (
+	queueKey = "webhooks:queue"
+	retryKey = "webhooks:retry"
+	// Deliveries taken off the queue by a worker and not finished yet
+	processingKey = "webhooks:processing"
+	// Prefix of the keys marking deliveries a worker is currently sending
+	claimPrefix = "webhooks:claim:"
+	// Deliveries which exhausted all attempts
+	DeadLetterKey = "webhooks:dead"
+)
+
+type event struct {
+	Name        string
+	Summary     string
+	Description string
+	Format      any
+	FormatName  string
+}
+
+var events = []event{
+	{
+		Name:        EventNewFollower,
+		Summary:     "New Follower",
+		Description: "Sent when a user follows you.",
+		Format:      types.WebhookEvent[types.NewFollowerEvent]{},
+		FormatName:  "NewFollowerWebhook",
+	},
+	{
+		Name:        EventNewComment,
+		Summary:     "New Comment",
+		Description: "Sent when a user comments on one of your posts.",
+		Format:      types.WebhookEvent[types.NewCommentEvent]{},
+		FormatName:  "NewCommentWebhook",
+	},
+	{
+		Name:        EventPostLiked,
+		Summary:     "Post Liked",
+		Description: "Sent when a user likes one of your posts.",
+		Format:      types.WebhookEvent[types.PostLikedEvent]{},
+		FormatName:  "PostLikedWebhook",
+	},
+	{
+		Name:        EventMentioned,
+		Summary:     "Mentioned",
+		Description: "Sent when a user mentions you in a post.",
+		Format:      types.WebhookEvent[types.MentionedEvent]{},
+		FormatName:  "MentionedWebhook",
+	},
+}
+
+// Returns the names of all supported event types
+func Events() []string {
+	names := make([]string, 0, len(events))
+	for _, e := range events {
+		names = append(names, e.Name)
+	}
+	return names
+}
+
+// Returns whether the event type is supported
+func IsEvent(name string) bool {
+	return slices.Contains(Events(), name)
+}
+
+// Documents every event type, must be called after docs.Setup
+func Docs() {
+	for _, e := range events {
+		docs.AddWebhook(&docs.WebhookDoc{
+			Name:       e.Name,
+			Format:     e.Format,
+			FormatName: e.FormatName,
+			Summary:    e.Summary,
+			Description: e.Description + "\n\nEvery request is signed, the `" + SignatureHeader + "` header contains `t=<unix timestamp>,v1=<signature>`" +
+				" where the signature is the hex encoded HMAC-SHA256 of `<timestamp>.<body>` using the webhook secret.",
+			Tags: []string{"Webhooks"},
+		})
+	}
+}
+
+// An event for a user, see Defer
+type Event struct {
+	UserID uuid.UUID
+	Name   string
+	Data   any
+}
+
+// Registers the GORM plugin publishing events once they are committed, state.Setup must be called first
+func Setup() error {
+	return state.Pool.Use(GormPlugin{})
+}
+
+// Queues an event for every webhook of the user subscribed to it
+func Publish(ctx context.Context, userID uuid.UUID, eventName string, data any) error {
+	if !IsEvent(eventName) {
+		return fmt.Errorf("unknown webhook event: %s", eventName)
+	}
+
+	var endpoints []types.WebhookEndpoint
+	err := state.Pool.WithContext(ctx).Where("user_id = ? AND enabled AND ? = ANY(events)", userID, eventName).Find(&endpoints).Error
+	if err != nil {
+		return err
+	}
+
+	for _, endpoint := range endpoints {
+		id := uuid.New()
+
+		payload, err := jsonimpl.Marshal(types.WebhookEvent[any]{
+			ID:        id,
+			Event:     eventName,
+			CreatedAt: time.Now(),
+			Data:      data,
+		})
+		if err != nil {
+			return err
+		}
+
+		delivery := types.WebhookDelivery{
+			BaseModel:  types.BaseModel{ID: id},
+			EndpointID: endpoint.ID,
+			Event:      eventName,
+			Payload:    string(payload),
+			Status:     StatusPending,
+		}
+
+		err = state.Pool.WithContext(ctx).Create(&delivery).Error
+		if err != nil {
+			return err
+		}
+
+		err = state.Redis.LPush(ctx, queueKey, id.String()).Err()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Publishes the events, failures are logged as the events already happened
+func PublishAll(ctx context.Context, events []Event) {
+	for _, e := range events {
+		if err := Publish(ctx, e.UserID, e.Name, e.Data); err != nil {
+			state.Logger.Error("[webhooks] Failed to publish event", zap.Error(err), zap.String("event", e.Name), zap.String("user_id", e.UserID.String()))
+		}
+	}
+}
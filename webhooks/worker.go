@@ -0,0 +1,427 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"clawmark/netguard"
+	"clawmark/state"
+	"clawmark/types"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	SignatureHeader = "X-Clawmark-Signature"
+	EventHeader     = "X-Clawmark-Event"
+	DeliveryHeader  = "X-Clawmark-Delivery"
+)
+
+const (
+	// Number of concurrent delivery workers
+	workers = 4
+
+	// Attempts before a delivery is dead-lettered
+	maxAttempts = 8
+
+	// Delay before the first retry, doubled on every attempt
+	baseBackoff = 30 * time.Second
+	maxBackoff  = 6 * time.Hour
+
+	requestTimeout = 10 * time.Second
+
+	// Longer than any delivery takes, claims older than this belong to instances that are gone
+	claimTTL = 2 * time.Minute
+
+	// Pending deliveries older than this were never queued and retries this far past due were lost, e.g. because
+	// Redis failed after they were stored. The scheduler checks for them at this interval
+	staleAfter = time.Minute
+
+	// Only this much of the receivers response is read, so the connection can be reused
+	maxDrainBody = 512
+)
+
+// Rejects connections to internal addresses, receivers are chosen by users
+var checkConn = netguard.Control()
+
+var client = &http.Client{
+	Timeout: requestTimeout,
+	Transport: &http.Transport{
+		// Never go through a proxy from the environment, the proxy would make the connection instead of us
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: func(network, address string, c syscall.RawConn) error {
+				return checkConn(network, address, c)
+			},
+		}).DialContext,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: requestTimeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+	},
+	// Receivers should respond directly, following redirects would allow bouncing to other hosts
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// Returns the value of the signature header for a body
+func Sign(secret string, timestamp int64, body []byte) string {
+	ts := strconv.FormatInt(timestamp, 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Returns the delay before the given (1-indexed) retry
+func backoff(attempt int) time.Duration {
+	d := baseBackoff << (attempt - 1)
+
+	if d <= 0 || d > maxBackoff {
+		return maxBackoff
+	}
+
+	return d
+}
+
+var (
+	running sync.WaitGroup
+
+	// Number of workers currently running
+	activeWorkers atomic.Int32
+
+	// Unix time of the last scheduler tick
+	lastTick atomic.Int64
+)
+
+// Starts the delivery workers and retry scheduler, they stop on shutdown or when ctx is cancelled.
+// Deliveries abandoned by a previous instance are queued again first
+func Start(ctx context.Context) {
+	ctx, stop := context.WithCancel(ctx)
+
+	// Registered after the connections, so they are stopped before those are closed
+	state.OnShutdown(func(ctx context.Context) {
+		stop()
+
+		if !wait(ctx) {
+			state.Logger.Error("[webhooks] Workers did not stop before the shutdown deadline")
+		}
+	})
+
+	requeueAbandoned(ctx)
+
+	running.Add(workers + 1)
+	lastTick.Store(time.Now().Unix())
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer running.Done()
+
+			activeWorkers.Add(1)
+			defer activeWorkers.Add(-1)
+
+			worker(ctx)
+		}()
+	}
+
+	go func() {
+		defer running.Done()
+		scheduler(ctx)
+	}()
+}
+
+// Waits for the workers to stop after their context is cancelled, returns false if ctx expires first
+func wait(ctx context.Context) bool {
+	done := make(chan struct{})
+
+	go func() {
+		running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Returns an error if any worker or the retry scheduler has stopped
+func Check() error {
+	if n := activeWorkers.Load(); n < workers {
+		return fmt.Errorf("%d of %d workers running", n, workers)
+	}
+
+	if since := time.Since(time.Unix(lastTick.Load(), 0)); since > 10*time.Second {
+		return fmt.Errorf("retry scheduler last ran %s ago", since.Truncate(time.Second))
+	}
+
+	return nil
+}
+
+func worker(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		// The delivery stays in the processing list until it is finished, so a crash does not lose it
+		res, err := state.Redis.BLMove(ctx, queueKey, processingKey, "RIGHT", "LEFT", 5*time.Second).Result()
+
+		if err != nil {
+			if !errors.Is(err, redis.Nil) && ctx.Err() == nil {
+				state.Logger.Error("[webhooks] Failed to pop delivery", zap.Error(err))
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+
+		// In-flight deliveries are finished on shutdown rather than counted as a failed attempt
+		process(context.WithoutCancel(ctx), res)
+	}
+}
+
+func process(ctx context.Context, res string) {
+	state.Redis.Set(ctx, claimPrefix+res, 1, claimTTL)
+
+	defer func() {
+		state.Redis.LRem(ctx, processingKey, 1, res)
+		state.Redis.Del(ctx, claimPrefix+res)
+	}()
+
+	id, err := uuid.Parse(res)
+
+	if err != nil {
+		state.Logger.Error("[webhooks] Invalid delivery ID in queue", zap.String("id", res))
+		return
+	}
+
+	deliver(ctx, id)
+}
+
+// Queues deliveries which a crashed or killed instance took off the queue without finishing, and stale deliveries.
+// Receivers may see a delivery twice, which they can tell by its ID
+func requeueAbandoned(ctx context.Context) {
+	processing, err := state.Redis.LRange(ctx, processingKey, 0, -1).Result()
+
+	if err != nil {
+		state.Logger.Error("[webhooks] Failed to fetch processing deliveries", zap.Error(err))
+		return
+	}
+
+	for _, id := range processing {
+		// Deliveries still claimed are being sent by another instance, e.g. the parent during an upgrade
+		if claimed, err := state.Redis.Exists(ctx, claimPrefix+id).Result(); err != nil || claimed > 0 {
+			continue
+		}
+
+		// Only the instance which removes the entry requeues it
+		if removed, err := state.Redis.LRem(ctx, processingKey, 1, id).Result(); err == nil && removed > 0 {
+			state.Redis.LPush(ctx, queueKey, id)
+		}
+	}
+
+	requeueStale(ctx)
+}
+
+// Queues pending deliveries which were never queued and retries which are past due but no longer scheduled, e.g.
+// because Redis failed after they were stored or lost its data
+func requeueStale(ctx context.Context) {
+	cutoff := time.Now().Add(-staleAfter)
+
+	var stale []uuid.UUID
+	err := state.Pool.WithContext(ctx).Model(&types.WebhookDelivery{}).
+		Where("(status = ? AND created_at < ?) OR (status = ? AND next_attempt_at < ?)", StatusPending, cutoff, StatusRetrying, cutoff).
+		Pluck("id", &stale).Error
+
+	if err != nil {
+		state.Logger.Error("[webhooks] Failed to fetch stale deliveries", zap.Error(err))
+		return
+	}
+
+	for _, id := range stale {
+		// Checked in the order deliveries move through, so one moving on while it is checked is still found
+		_, err := state.Redis.ZScore(ctx, retryKey, id.String()).Result()
+		queued := !errors.Is(err, redis.Nil)
+
+		for _, key := range []string{queueKey, processingKey} {
+			if _, err := state.Redis.LPos(ctx, key, id.String(), redis.LPosArgs{}).Result(); !errors.Is(err, redis.Nil) {
+				queued = true
+			}
+		}
+
+		if !queued {
+			state.Logger.Info("[webhooks] Requeueing stale delivery", zap.String("id", id.String()))
+			state.Redis.LPush(ctx, queueKey, id.String())
+		}
+	}
+}
+
+// Moves due retries back onto the queue, and requeues stale deliveries every staleAfter
+func scheduler(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	lastStale := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		lastTick.Store(time.Now().Unix())
+
+		if time.Since(lastStale) >= staleAfter {
+			lastStale = time.Now()
+			requeueStale(ctx)
+		}
+
+		due, err := state.Redis.ZRangeByScore(ctx, retryKey, &redis.ZRangeBy{
+			Min: "-inf",
+			Max: strconv.FormatInt(time.Now().Unix(), 10),
+		}).Result()
+
+		if err != nil {
+			if ctx.Err() == nil {
+				state.Logger.Error("[webhooks] Failed to fetch due retries", zap.Error(err))
+			}
+			continue
+		}
+
+		for _, id := range due {
+			// Only the instance which removes the entry requeues it
+			removed, err := state.Redis.ZRem(ctx, retryKey, id).Result()
+
+			if err != nil || removed == 0 {
+				continue
+			}
+
+			state.Redis.LPush(ctx, queueKey, id)
+		}
+	}
+}
+
+func deliver(ctx context.Context, id uuid.UUID) {
+	var delivery types.WebhookDelivery
+	err := state.Pool.WithContext(ctx).Preload("Endpoint").First(&delivery, "id = ?", id).Error
+
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			state.Logger.Error("[webhooks] Failed to fetch delivery", zap.Error(err), zap.String("id", id.String()))
+		}
+		return
+	}
+
+	if delivery.Status == StatusDelivered || delivery.Status == StatusCancelled {
+		return
+	}
+
+	if delivery.Endpoint.ID == uuid.Nil || !delivery.Endpoint.Enabled {
+		state.Pool.WithContext(ctx).Model(&delivery).Updates(map[string]any{
+			"status":          StatusCancelled,
+			"next_attempt_at": nil,
+		})
+		return
+	}
+
+	statusCode, err := send(ctx, delivery)
+
+	now := time.Now()
+	updates := map[string]any{
+		"attempts":         delivery.Attempts + 1,
+		"last_status_code": statusCode,
+		"last_error":       "",
+		"next_attempt_at":  nil,
+	}
+
+	if err == nil {
+		updates["status"] = StatusDelivered
+		updates["delivered_at"] = now
+	} else {
+		updates["last_error"] = err.Error()
+
+		if delivery.Attempts+1 >= maxAttempts {
+			updates["status"] = StatusFailed
+			state.Redis.LPush(ctx, DeadLetterKey, id.String())
+		} else {
+			next := now.Add(backoff(delivery.Attempts + 1))
+			updates["status"] = StatusRetrying
+			updates["next_attempt_at"] = next
+			state.Redis.ZAdd(ctx, retryKey, redis.Z{
+				Score:  float64(next.Unix()),
+				Member: id.String(),
+			})
+		}
+	}
+
+	err = state.Pool.WithContext(ctx).Model(&delivery).Updates(updates).Error
+
+	if err != nil {
+		state.Logger.Error("[webhooks] Failed to update delivery", zap.Error(err), zap.String("id", id.String()))
+	}
+}
+
+// The body of the response is not kept, the delivery log must not expose what the receiver returned
+type statusError struct {
+	code int
+}
+
+func (e statusError) Error() string {
+	return "receiver responded with " + strconv.Itoa(e.code)
+}
+
+// Sends a delivery, returning the status code of the receiver (if any)
+func send(ctx context.Context, delivery types.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Endpoint.URL, bytes.NewReader(body))
+
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Clawmark-Webhooks/1.0")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, delivery.ID.String())
+	req.Header.Set(SignatureHeader, Sign(delivery.Endpoint.Secret, time.Now().Unix(), body))
+
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer resp.Body.Close()
+
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainBody))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, statusError{code: resp.StatusCode}
+	}
+
+	return resp.StatusCode, nil
+}